@@ -18,6 +18,8 @@ package common
 
 import (
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
@@ -27,12 +29,50 @@ import (
 type Kube struct {
 	// Template defines the raw Kubernetes resource
 	// +kubebuilder:pruning:PreserveUnknownFields
-	Template runtime.RawExtension `json:"template"`
+	// +optional
+	Template runtime.RawExtension `json:"template,omitempty"`
+
+	// URLs refer to remote manifests of raw Kubernetes resources, which are fetched at render time
+	// and treated exactly like the template
+	// +optional
+	URLs []KubeURLSource `json:"urls,omitempty"`
+
+	// Git refers to manifests of raw Kubernetes resources in Git repositories, which are fetched at render time
+	// and treated exactly like the template
+	// +optional
+	Git []KubeGitSource `json:"git,omitempty"`
 
 	// Parameters defines configurable parameters
 	Parameters []KubeParameter `json:"parameters,omitempty"`
 }
 
+// KubeURLSource is a remote manifest of raw Kubernetes resources
+type KubeURLSource struct {
+	// URL of the manifest, it can contain multiple resources separated by "---"
+	URL string `json:"url"`
+
+	// SHA256 pins the checksum of the manifest in hex format, the manifest is rejected if it doesn't match
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// KubeGitSource is a manifest of raw Kubernetes resources in a Git repository
+type KubeGitSource struct {
+	// Repo is the http or https URL of the Git repository, it can be hosted on any Git server
+	Repo string `json:"repo"`
+
+	// Ref is the branch, tag or commit of the repository, default to master
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path of the manifest in the repository, it can contain multiple resources separated by "---"
+	Path string `json:"path"`
+
+	// SHA256 pins the checksum of the manifest in hex format, the manifest is rejected if it doesn't match
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+}
+
 // ParameterValueType refers to a data type of parameter
 type ParameterValueType string
 
@@ -85,6 +125,79 @@ type Schematic struct {
 	HELM *Helm `json:"helm,omitempty"`
 
 	Terraform *Terraform `json:"terraform,omitempty"`
+
+	Kustomize *Kustomize `json:"kustomize,omitempty"`
+
+	Git *Git `json:"git,omitempty"`
+}
+
+// Git defines the encapsulation of raw Kubernetes resources or a CUE template in a directory of a Git repository,
+// which is synced at an interval and rendered along with the application without any GitOps controller
+type Git struct {
+	// Repo is the http or https URL of the Git repository, it can be hosted on any Git server
+	Repo string `json:"repo"`
+
+	// Branch to sync, default to master if none of the branch, tag and commit is set
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Tag pins the repository to a tag, it's exclusive with the branch and the commit
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// Commit pins the repository to a commit SHA, it's exclusive with the branch and the tag
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// Path of the directory in the repository, default to the root. The directory contains either the manifests of
+	// raw Kubernetes resources in YAML or JSON files, or the CUE template of the component in CUE files.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Interval is how often the branch or tag is synced, default to 5m. A commit is immutable so it's never synced.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// SecretRef refers to the Secret in the namespace of the definition holding the username and password keys to
+	// clone the repository over HTTP(S), the password can be a personal access token
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// Kustomize defines the encapsulation of a kustomize base, which is pulled from a Git repository or an OCI registry
+// and built with the patches from the properties of the component at render time
+type Kustomize struct {
+	// Git refers to the kustomize base in a Git repository
+	// +optional
+	Git *KustomizeGitSource `json:"git,omitempty"`
+
+	// OCI refers to the kustomize base in an OCI artifact
+	// +optional
+	OCI *KustomizeOCISource `json:"oci,omitempty"`
+}
+
+// KustomizeGitSource is a kustomize base in a Git repository
+type KustomizeGitSource struct {
+	// Repo is the http or https URL of the Git repository, it can be hosted on any Git server
+	Repo string `json:"repo"`
+
+	// Ref is the branch, tag or commit of the repository, default to master
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path of the directory containing the kustomization file in the repository, default to the root
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// KustomizeOCISource is a kustomize base in an OCI artifact, whose first layer is a gzipped tarball of the files
+type KustomizeOCISource struct {
+	// Image is the reference of the artifact, e.g., ghcr.io/org/manifests:v1.0.0 or ghcr.io/org/manifests@sha256:...
+	Image string `json:"image"`
+
+	// Path of the directory containing the kustomization file in the artifact, default to the root
+	// +optional
+	Path string `json:"path,omitempty"`
 }
 
 // A Helm represents resources used by a Helm module
@@ -133,6 +246,26 @@ type DefinitionReference struct {
 	Version string `json:"version,omitempty"`
 }
 
+// TraitStage is the stage of a trait relative to its workload. It only groups the traits of a component in the
+// assembled resources, so the consumers, e.g., the dry-run and the topology of applications, can list them in order.
+// The controller doesn't dispatch the traits by their stages nor wait for the workload to become ready.
+type TraitStage string
+
+const (
+	// PreDispatchStage traits are listed before all the resources of the component, e.g., Namespace or Secret
+	PreDispatchStage TraitStage = "PreDispatch"
+	// PreWorkloadStage traits are listed right before the workload, e.g., PersistentVolumeClaim
+	PreWorkloadStage TraitStage = "PreWorkload"
+	// PostWorkloadStage traits are listed after the workload, e.g., Ingress
+	PostWorkloadStage TraitStage = "PostWorkload"
+)
+
+// TraitStages are all the trait stages in order
+var TraitStages = []TraitStage{PreDispatchStage, PreWorkloadStage, PostWorkloadStage}
+
+// DefaultTraitStage is the stage of traits whose definition doesn't specify one
+const DefaultTraitStage = PostWorkloadStage
+
 // A ChildResourceKind defines a child Kubernetes resource kind with a selector
 type ChildResourceKind struct {
 	// APIVersion of the child resource
@@ -169,6 +302,8 @@ const (
 	ApplicationRunning ApplicationPhase = "running"
 	// ApplicationHealthChecking means the app finished rendering and applied result to the cluster, but still unhealthy
 	ApplicationHealthChecking ApplicationPhase = "healthChecking"
+	// ApplicationWorkflowSuspended means the workflow of the app is suspended, no more resources are applied until it's resumed
+	ApplicationWorkflowSuspended ApplicationPhase = "workflowSuspended"
 )
 
 // ApplicationComponentStatus record the health status of App component
@@ -180,6 +315,9 @@ type ApplicationComponentStatus struct {
 	Message            string                           `json:"message,omitempty"`
 	Traits             []ApplicationTraitStatus         `json:"traits,omitempty"`
 	Scopes             []runtimev1alpha1.TypedReference `json:"scopes,omitempty"`
+	// Outputs are the non-sensitive outputs of the cloud resources provisioned by the component, e.g., the outputs
+	// of a Terraform module, the sensitive ones are only written to the connection secret
+	Outputs map[string]string `json:"outputs,omitempty"`
 }
 
 // ApplicationTraitStatus records the trait health status
@@ -211,14 +349,81 @@ type WorkflowStepStatus struct {
 	Type        string                         `json:"type,omitempty"`
 	Phase       WorkflowStepPhase              `json:"phase,omitempty"`
 	ResourceRef runtimev1alpha1.TypedReference `json:"resourceRef,omitempty"`
+
+	// Message is the reason why the step failed, timed out or is retried
+	Message string `json:"message,omitempty"`
+	// Retries is the number of times the step has been retried
+	Retries int `json:"retries,omitempty"`
+	// StartedAt is the time the current attempt of the step started running
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// FailedAt is the time the last attempt of the step failed or timed out, the next retry backs off from it
+	FailedAt *metav1.Time `json:"failedAt,omitempty"`
+	// SubSteps record the status of the sub-steps of a step-group step
+	SubSteps []WorkflowSubStepStatus `json:"subSteps,omitempty"`
+	// Approval records who may approve a suspend-for-approval step and who approved it
+	Approval *WorkflowStepApproval `json:"approval,omitempty"`
+}
+
+// WorkflowStepApproval records the approval of a suspend-for-approval workflow step for audit
+type WorkflowStepApproval struct {
+	// Users are the names of the users who may approve the step
+	Users []string `json:"users,omitempty"`
+	// Groups are the groups whose members may approve the step
+	Groups []string `json:"groups,omitempty"`
+	// ApprovedBy is the name of the user who approved the step
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// ApprovedAt is the time the step was approved
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+}
+
+// WorkflowSubStepStatus record the status of a sub-step of a step-group workflow step
+type WorkflowSubStepStatus struct {
+	Name        string                         `json:"name,omitempty"`
+	Type        string                         `json:"type,omitempty"`
+	Phase       WorkflowStepPhase              `json:"phase,omitempty"`
+	ResourceRef runtimev1alpha1.TypedReference `json:"resourceRef,omitempty"`
+	// Message is the reason why the sub-step is pending or failed
+	Message string `json:"message,omitempty"`
+}
+
+// WorkflowStepRetryPolicy defines how a workflow step is retried if it fails or times out
+type WorkflowStepRetryPolicy struct {
+	// MaxRetries is the maximum number of times the step is retried, it's not retried if zero
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// Backoff is the duration to wait before the first retry, e.g., 10s, it doubles for each following retry.
+	// The step is retried immediately if empty.
+	// +optional
+	Backoff string `json:"backoff,omitempty"`
 }
 
+const (
+	// ApplicationParsed reports whether the application and its definitions are parsed and its revision is generated
+	ApplicationParsed runtimev1alpha1.ConditionType = "Parsed"
+	// ApplicationRendered reports whether the components and traits are rendered
+	ApplicationRendered runtimev1alpha1.ConditionType = "Rendered"
+	// ApplicationPolicyApplied reports whether the rendered resources pass the spec limits, the quotas and the
+	// admission preflight
+	ApplicationPolicyApplied runtimev1alpha1.ConditionType = "PolicyApplied"
+	// ApplicationDispatched reports whether the rendered resources are applied, dispatched and rolled out
+	ApplicationDispatched runtimev1alpha1.ConditionType = "Dispatched"
+	// ApplicationHealthy reports whether all the components are healthy
+	ApplicationHealthy runtimev1alpha1.ConditionType = "Healthy"
+)
+
 // AppStatus defines the observed state of Application
 type AppStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
 
+	// ConditionGenerations records the generation of the application each condition is observed in, keyed by the
+	// type of the condition. A condition is outdated if its generation is less than the generation of the application
+	ConditionGenerations map[string]int64 `json:"conditionGenerations,omitempty"`
+
+	// ObservedGeneration is the generation of the application the status reflects, the status is outdated if it's
+	// less than the generation of the application
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	Rollout AppRolloutStatus `json:"rollout,omitempty"`
 
 	Phase ApplicationPhase `json:"status,omitempty"`
@@ -235,11 +440,233 @@ type AppStatus struct {
 	// Workflow record the status of workflow steps
 	Workflow []WorkflowStepStatus `json:"workflow,omitempty"`
 
+	// WorkflowSuspend records the suspension of the workflow, it's empty if the workflow is not suspended
+	WorkflowSuspend *WorkflowSuspendStatus `json:"workflowSuspend,omitempty"`
+
+	// DependencyGraph records the dependencies between the components and whether they are dispatched
+	DependencyGraph []ComponentDependency `json:"dependencyGraph,omitempty"`
+
+	// AppDependencies records whether the applications this application depends on are healthy
+	AppDependencies []AppDependencyStatus `json:"appDependencies,omitempty"`
+
+	// LintWarnings records the best practice issues found in the rendered workloads, they don't block the deployment
+	LintWarnings []LintWarning `json:"lintWarnings,omitempty"`
+
+	// BlueGreen records the revisions of the components switched by the blue-green trait
+	BlueGreen []BlueGreenStatus `json:"blueGreen,omitempty"`
+
+	// Notifications records the delivery of the latest notification to each endpoint notified of the state
+	// transitions of the application, it's updated by the notifier only
+	Notifications []NotificationStatus `json:"notifications,omitempty"`
+
+	// ImagePolicies records the image tags selected by the image policies of the components
+	ImagePolicies []ImagePolicyStatus `json:"imagePolicies,omitempty"`
+
+	// ValueSources records the Secrets and the Vault secrets the properties are resolved from, the application is
+	// re-rendered once the Secrets change
+	ValueSources []ValueSource `json:"valueSources,omitempty"`
+
+	// ResourceUsage is the sum of the CPU and memory requests and the replicas of the rendered workloads, it's
+	// summed across the applications to enforce the quotas of the namespaces and projects
+	ResourceUsage corev1.ResourceList `json:"resourceUsage,omitempty"`
+
 	// LatestRevision of the application configuration it generates
 	// +optional
 	LatestRevision *Revision `json:"latestRevision,omitempty"`
 }
 
+// WorkflowSuspend describes why the workflow of an application is suspended
+type WorkflowSuspend struct {
+	// Reason is why the workflow is suspended, e.g., waiting for a manual verification
+	Reason string `json:"reason,omitempty"`
+}
+
+// WorkflowSuspendStatus records the suspension of the workflow of an application
+type WorkflowSuspendStatus struct {
+	// CurrentStep is the step the workflow is suspended before, i.e., the first workflow step not succeeded or the
+	// first component not dispatched
+	CurrentStep string `json:"currentStep,omitempty"`
+	// Reason is why the workflow is suspended
+	Reason string `json:"reason,omitempty"`
+	// SuspendedAt is the time the workflow is suspended
+	SuspendedAt metav1.Time `json:"suspendedAt,omitempty"`
+}
+
+// NotificationEvent is a state transition of an application notified to the endpoints
+type NotificationEvent string
+
+const (
+	// NotificationEventRunning means the application turns running, e.g., after the new revision is rendered and healthy
+	NotificationEventRunning NotificationEvent = "Running"
+	// NotificationEventUnhealthy means the running application turns unhealthy
+	NotificationEventUnhealthy NotificationEvent = "Unhealthy"
+	// NotificationEventRolloutPaused means the rollout of the application is paused
+	NotificationEventRolloutPaused NotificationEvent = "RolloutPaused"
+)
+
+// NotificationDeliveryPhase is the phase of the delivery of a notification
+type NotificationDeliveryPhase string
+
+const (
+	// NotificationRetrying means the delivery failed and will be retried
+	NotificationRetrying NotificationDeliveryPhase = "retrying"
+	// NotificationDelivered means the notification is delivered
+	NotificationDelivered NotificationDeliveryPhase = "delivered"
+	// NotificationFailed means the notification is not delivered after all the attempts
+	NotificationFailed NotificationDeliveryPhase = "failed"
+)
+
+// NotificationStatus records the delivery of a notification to an endpoint
+type NotificationStatus struct {
+	// Endpoint is the name of the endpoint notified
+	Endpoint string `json:"endpoint"`
+	// Event is the state transition notified
+	Event NotificationEvent `json:"event"`
+	// Phase is the phase of the delivery
+	Phase NotificationDeliveryPhase `json:"phase"`
+	// Attempts is the number of the attempts to deliver the notification
+	Attempts int `json:"attempts"`
+	// LastAttemptTime is the time of the last attempt
+	LastAttemptTime metav1.Time `json:"lastAttemptTime,omitempty"`
+	// Message is the error of the last failed attempt
+	Message string `json:"message,omitempty"`
+}
+
+// PendingDeletion is a resource no longer rendered by the application, it's deleted by garbage collection after the
+// grace period declared by its label gc.oam.dev/grace-period
+type PendingDeletion struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Cluster is the managed cluster the resource is dispatched to, it's in the control plane cluster if empty
+	Cluster string `json:"cluster,omitempty"`
+	// Since is the time the resource is found no longer rendered, the grace period starts from it
+	Since metav1.Time `json:"since"`
+}
+
+// BlueGreenStatus records the revisions of a component switched by the blue-green trait
+type BlueGreenStatus struct {
+	Component string `json:"component"`
+	// Active is the revision of the workload selected by the stable Service
+	Active string `json:"active"`
+	// Preview is the newest revision of the workload, it's selected by the preview Service
+	Preview string `json:"preview,omitempty"`
+	// PromotedAt is the time the active revision is promoted
+	PromotedAt *metav1.Time `json:"promotedAt,omitempty"`
+	// Retiring is the workload of the revision replaced by the active one, it's deleted after the soak period
+	Retiring *runtimev1alpha1.TypedReference `json:"retiring,omitempty"`
+	// RetireAt is the time the retiring workload is deleted
+	RetireAt *metav1.Time `json:"retireAt,omitempty"`
+}
+
+// ImagePolicyType is how the tag of the image is selected from the tags in the registry
+type ImagePolicyType string
+
+const (
+	// ImagePolicySemver selects the highest semantic version in the range
+	ImagePolicySemver ImagePolicyType = "semver"
+	// ImagePolicyTimestamp selects the tag of the image created most recently
+	ImagePolicyTimestamp ImagePolicyType = "timestamp"
+)
+
+// ImagePolicy keeps the image of a component up to date with the tags pushed to the registry, the tag selected by
+// the policy is set to the image property of the component
+type ImagePolicy struct {
+	// Image is the repository of the image without tag, e.g., myrepo/app
+	Image string `json:"image"`
+	// Policy is how the tag is selected
+	// +kubebuilder:validation:Enum=semver;timestamp
+	Policy ImagePolicyType `json:"policy"`
+	// Range is the semantic version constraint the tags must satisfy, e.g., ">=1.0.0 <2.0.0" or "~1.2", it applies
+	// to the semver policy only and defaults to any version except the pre-releases
+	// +optional
+	Range string `json:"range,omitempty"`
+	// Pattern is the regular expression the tags must match to be selected, e.g., "^main-"
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+	// Interval is how often the tags are listed from the registry, it defaults to 5m
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// SecretRef refers to the Secret holding the credentials of the registry, either the username and password keys
+	// or the docker config of type kubernetes.io/dockerconfigjson
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// ImagePolicyStatus records the image tag selected for a component by its image policy
+type ImagePolicyStatus struct {
+	Component string `json:"component"`
+	// Image is the image the component is updated to, e.g., myrepo/app:1.2.0
+	Image string `json:"image,omitempty"`
+	// Previous is the image the component used before the last update
+	Previous string `json:"previous,omitempty"`
+	// UpdatedAt is the time of the last update
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+	// LastScanTime is the time the tags are listed from the registry the last time
+	LastScanTime metav1.Time `json:"lastScanTime,omitempty"`
+	// Message is the error of the last scan
+	Message string `json:"message,omitempty"`
+}
+
+// ValueSource is an external source a property value of a component is resolved from at render time
+type ValueSource struct {
+	Component string `json:"component"`
+	// Secret is the name of the Secret in the namespace of the application
+	Secret string `json:"secret,omitempty"`
+	// VaultPath is the path and the key of the secret in Vault, e.g., secret/data/<namespace>/db#password
+	VaultPath string `json:"vaultPath,omitempty"`
+}
+
+// LintWarning is a best practice issue found in the rendered workload of a component
+type LintWarning struct {
+	Component string `json:"component"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+// ComponentDispatchPhase describes whether a component is dispatched following the dependency graph.
+type ComponentDispatchPhase string
+
+const (
+	// ComponentDispatchPending means the component is waiting for its dependencies to be healthy.
+	ComponentDispatchPending ComponentDispatchPhase = "pending"
+	// ComponentDispatchDispatched means the component is rendered and applied.
+	ComponentDispatchDispatched ComponentDispatchPhase = "dispatched"
+)
+
+// ComponentDependency is a node of the component dependency graph of an application
+type ComponentDependency struct {
+	Name      string                 `json:"name"`
+	DependsOn []string               `json:"dependsOn,omitempty"`
+	Phase     ComponentDispatchPhase `json:"phase"`
+	Message   string                 `json:"message,omitempty"`
+}
+
+// AppDependencyPhase describes whether an application this application depends on is healthy.
+type AppDependencyPhase string
+
+const (
+	// AppDependencyWaiting means the application is waiting for the dependency to be healthy.
+	AppDependencyWaiting AppDependencyPhase = "waiting"
+	// AppDependencySatisfied means the dependency is healthy.
+	AppDependencySatisfied AppDependencyPhase = "satisfied"
+	// AppDependencyTimeout means the dependency is not healthy within the timeout, the application keeps waiting.
+	AppDependencyTimeout AppDependencyPhase = "timeout"
+)
+
+// AppDependencyStatus records the status of an application this application depends on
+type AppDependencyStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Components is the names of the components waiting for the application, all components wait for it if empty
+	Components []string           `json:"components,omitempty"`
+	Phase      AppDependencyPhase `json:"phase"`
+	Message    string             `json:"message,omitempty"`
+	// WaitingSince is the time the application started to wait for the dependency
+	WaitingSince *metav1.Time `json:"waitingSince,omitempty"`
+}
+
 // WorkflowStepPhase describes the phase of a workflow step.
 type WorkflowStepPhase string
 
@@ -252,6 +679,15 @@ const (
 	WorkflowStepPhaseStopped WorkflowStepPhase = "stopped"
 	// WorkflowStepPhaseRunning will make the controller continue the workflow.
 	WorkflowStepPhaseRunning WorkflowStepPhase = "running"
+	// WorkflowStepPhaseTimedOut means the step is still running after its timeout, it's handled as a failed step.
+	WorkflowStepPhaseTimedOut WorkflowStepPhase = "timedOut"
+	// WorkflowStepPhaseRetrying means the step failed or timed out and is waiting for the backoff of the next retry.
+	WorkflowStepPhaseRetrying WorkflowStepPhase = "retrying"
+	// WorkflowStepPhasePending means the sub-step of a step group is waiting for the other sub-steps under the
+	// concurrency limit of the group to finish.
+	WorkflowStepPhasePending WorkflowStepPhase = "pending"
+	// WorkflowStepPhaseSkipped means the step is skipped since its if condition is false, the workflow continues.
+	WorkflowStepPhaseSkipped WorkflowStepPhase = "skipped"
 )
 
 // DefinitionType describes the type of DefinitionRevision.
@@ -276,6 +712,10 @@ const (
 type AppRolloutStatus struct {
 	v1alpha1.RolloutStatus `json:",inline"`
 
+	// ObservedGeneration is the generation of the rollout the status reflects, the status is outdated if it's less
+	// than the generation of the rollout
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// LastUpgradedTargetAppRevision contains the name of the app that we upgraded to
 	// We will restart the rollout if this is not the same as the spec
 	LastUpgradedTargetAppRevision string `json:"lastTargetAppRevision"`
@@ -122,3 +122,13 @@ func (app *Application) GetComponent(workloadType string) *ApplicationComponent
 	}
 	return nil
 }
+
+// GetObservedGeneration gets the generation of the Application its status reflects
+func (app *Application) GetObservedGeneration() int64 {
+	return app.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the Application its status reflects
+func (app *Application) SetObservedGeneration(generation int64) {
+	app.Status.ObservedGeneration = generation
+}
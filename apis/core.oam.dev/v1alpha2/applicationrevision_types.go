@@ -46,6 +46,11 @@ type ApplicationRevisionSpec struct {
 
 	Components []common.RawComponent `json:"components,omitempty"`
 
+	// CompressedManifests records the Components and the ApplicationConfiguration compressed by the algorithm in the
+	// app.oam.dev/manifest-compression annotation, the Components are empty and the ApplicationConfiguration is a
+	// stub in the revision then
+	CompressedManifests []byte `json:"compressedManifests,omitempty"`
+
 	// ApplicationConfiguration records the rendered applicationConfiguration from Application,
 	// it will contains the whole K8s CR of trait and the reference component in it.
 	// +kubebuilder:validation:EmbeddedResource
@@ -50,6 +50,10 @@ type AppRolloutSpec struct {
 type AppRolloutStatus struct {
 	v1alpha1.RolloutStatus `json:",inline"`
 
+	// ObservedGeneration is the generation of the rollout the status reflects, the status is outdated if it's less
+	// than the generation of the rollout
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// LastUpgradedTargetAppRevision contains the name of the app that we upgraded to
 	// We will restart the rollout if this is not the same as the spec
 	LastUpgradedTargetAppRevision string `json:"lastTargetAppRevision"`
@@ -77,6 +81,16 @@ type AppRollout struct {
 	Status AppRolloutStatus `json:"status,omitempty"`
 }
 
+// GetObservedGeneration gets the generation of the AppRollout its status reflects
+func (r *AppRollout) GetObservedGeneration() int64 {
+	return r.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the AppRollout its status reflects
+func (r *AppRollout) SetObservedGeneration(generation int64) {
+	r.Status.ObservedGeneration = generation
+}
+
 // AppRolloutList contains a list of AppRollout
 // +kubebuilder:object:root=true
 type AppRolloutList struct {
@@ -60,6 +60,8 @@ type ComponentDefinitionSpec struct {
 type ComponentDefinitionStatus struct {
 	// ConditionedStatus reflects the observed status of a resource
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
+	// ObservedGeneration is the generation of the definition the status reflects
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// ConfigMapRef refer to a ConfigMap which contains OpenAPI V3 JSON schema of Component parameters.
 	ConfigMapRef string `json:"configMapRef,omitempty"`
 	// LatestRevision of the component definition
@@ -92,6 +94,16 @@ func (cd *ComponentDefinition) GetCondition(conditionType runtimev1alpha1.Condit
 	return cd.Status.GetCondition(conditionType)
 }
 
+// GetObservedGeneration gets the generation of the ComponentDefinition its status reflects
+func (cd *ComponentDefinition) GetObservedGeneration() int64 {
+	return cd.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the ComponentDefinition its status reflects
+func (cd *ComponentDefinition) SetObservedGeneration(generation int64) {
+	cd.Status.ObservedGeneration = generation
+}
+
 // +kubebuilder:object:root=true
 
 // ComponentDefinitionList contains a list of ComponentDefinition
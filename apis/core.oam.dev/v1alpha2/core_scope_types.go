@@ -53,6 +53,9 @@ type HealthScopeSpec struct {
 type HealthScopeStatus struct {
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
 
+	// ObservedGeneration is the generation of the scope the status reflects
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// ScopeHealthCondition represents health condition summary of the scope
 	ScopeHealthCondition ScopeHealthCondition `json:"scopeHealthCondition"`
 
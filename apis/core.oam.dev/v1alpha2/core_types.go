@@ -138,6 +138,12 @@ type TraitDefinitionSpec struct {
 	// +optional
 	Schematic *common.Schematic `json:"schematic,omitempty"`
 
+	// Stage defines the stage of the trait relative to the workload, it only groups the trait in the assembled
+	// resources and doesn't order the dispatching. The trait is grouped after the workload if it's not specified
+	// +optional
+	// +kubebuilder:validation:Enum=PreDispatch;PreWorkload;PostWorkload
+	Stage common.TraitStage `json:"stage,omitempty"`
+
 	// Status defines the custom health policy and status message for trait
 	// +optional
 	Status *common.Status `json:"status,omitempty"`
@@ -152,6 +158,8 @@ type TraitDefinitionSpec struct {
 type TraitDefinitionStatus struct {
 	// ConditionedStatus reflects the observed status of a resource
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
+	// ObservedGeneration is the generation of the definition the status reflects
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// ConfigMapRef refer to a ConfigMap which contains OpenAPI V3 JSON schema of Component parameters.
 	ConfigMapRef string `json:"configMapRef,omitempty"`
 	// LatestRevision of the trait definition
@@ -187,6 +195,16 @@ func (td *TraitDefinition) GetCondition(conditionType runtimev1alpha1.ConditionT
 	return td.Status.GetCondition(conditionType)
 }
 
+// GetObservedGeneration gets the generation of the TraitDefinition its status reflects
+func (td *TraitDefinition) GetObservedGeneration() int64 {
+	return td.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the TraitDefinition its status reflects
+func (td *TraitDefinition) SetObservedGeneration(generation int64) {
+	td.Status.ObservedGeneration = generation
+}
+
 // +kubebuilder:object:root=true
 
 // TraitDefinitionList contains a list of TraitDefinition.
@@ -500,6 +518,15 @@ type ApplicationConfigurationStatus struct {
 
 	// HistoryWorkloads will record history but still working revision workloads.
 	HistoryWorkloads []HistoryWorkload `json:"historyWorkloads,omitempty"`
+
+	// PendingDeletions are the workloads and traits no longer rendered, waiting for their garbage collection grace periods
+	PendingDeletions []common.PendingDeletion `json:"pendingDeletions,omitempty"`
+
+	// TraitHashCollisionCount is the count of hash collisions of the generated trait names. The
+	// ApplicationConfiguration controller uses it as a collision avoidance mechanism when it needs
+	// to name a newly created trait.
+	// +optional
+	TraitHashCollisionCount *int32 `json:"traitHashCollisionCount,omitempty"`
 }
 
 // DependencyStatus represents the observed state of the dependency of
@@ -91,3 +91,13 @@ func (hs *HealthScope) GetWorkloadReferences() []runtimev1alpha1.TypedReference
 func (hs *HealthScope) AddWorkloadReference(r runtimev1alpha1.TypedReference) {
 	hs.Spec.WorkloadReferences = append(hs.Spec.WorkloadReferences, r)
 }
+
+// GetObservedGeneration gets the generation of the HealthScope its status reflects
+func (hs *HealthScope) GetObservedGeneration() int64 {
+	return hs.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the HealthScope its status reflects
+func (hs *HealthScope) SetObservedGeneration(generation int64) {
+	hs.Status.ObservedGeneration = generation
+}
@@ -368,6 +368,18 @@ func (in *ApplicationConfigurationStatus) DeepCopyInto(out *ApplicationConfigura
 		*out = make([]HistoryWorkload, len(*in))
 		copy(*out, *in)
 	}
+	if in.PendingDeletions != nil {
+		in, out := &in.PendingDeletions, &out.PendingDeletions
+		*out = make([]common.PendingDeletion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TraitHashCollisionCount != nil {
+		in, out := &in.TraitHashCollisionCount, &out.TraitHashCollisionCount
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationConfigurationStatus.
@@ -583,6 +595,11 @@ func (in *ApplicationRevisionSpec) DeepCopyInto(out *ApplicationRevisionSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CompressedManifests != nil {
+		in, out := &in.CompressedManifests, &out.CompressedManifests
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
 	in.ApplicationConfiguration.DeepCopyInto(&out.ApplicationConfiguration)
 }
 
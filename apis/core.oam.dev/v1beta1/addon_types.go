@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddonCatalogType is the type of the catalog addons are discovered from
+type AddonCatalogType string
+
+const (
+	// AddonCatalogGit discovers the addons in the directories of a Git repository
+	AddonCatalogGit AddonCatalogType = "git"
+	// AddonCatalogOCI discovers the addons in the repositories of an OCI registry, the tags are the versions
+	AddonCatalogOCI AddonCatalogType = "oci"
+	// AddonCatalogHTTP discovers the addons in the index.yaml served by an HTTP server or in a local mirror directory
+	AddonCatalogHTTP AddonCatalogType = "http"
+)
+
+// AddonCatalog is the catalog an addon and its dependencies are discovered from
+type AddonCatalog struct {
+	// Type is the type of the catalog, i.e., git, oci or http
+	// +kubebuilder:validation:Enum=git;oci;http
+	Type AddonCatalogType `json:"type"`
+
+	// URL is the http or https URL of the Git repository of the git type, e.g., https://github.com/org/catalog,
+	// the repository prefix of the oci type, e.g., ghcr.io/org/addons, or the base URL serving index.yaml of the http type,
+	// or the file:// URL of a local mirror directory in air-gapped environments
+	URL string `json:"url"`
+
+	// Ref is the branch, tag or commit of the git type, default is master
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Path is the directory of the addons in the repository of the git type
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// PlainHTTP accesses the registry of the oci type through HTTP rather than HTTPS, e.g., a registry inside the
+	// cluster
+	// +optional
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+
+	// SecretRef refers to the Secret in the namespace of the addon with the credentials of the catalog, either the
+	// username and password keys or the docker config
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// AddonSpec defines the addon to enable
+type AddonSpec struct {
+	// Catalog is the catalog the addon and its dependencies are discovered from
+	Catalog AddonCatalog `json:"catalog"`
+
+	// Version is the semantic version constraint of the addon, e.g., ^1.2, the latest version is enabled if it's
+	// empty. The addon is upgraded automatically once a newer version matching it is published.
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// AddonPhase is the phase of an addon
+type AddonPhase string
+
+const (
+	// AddonEnabling means the addon is being enabled, e.g., its dependencies or its application are not ready
+	AddonEnabling AddonPhase = "enabling"
+	// AddonUpgrading means a newer version of the addon enabled is being rolled out
+	AddonUpgrading AddonPhase = "upgrading"
+	// AddonEnabled means the application of the addon is running
+	AddonEnabled AddonPhase = "enabled"
+	// AddonDisabling means the addon is being disabled, it waits for the addons depending on it to be disabled first
+	AddonDisabling AddonPhase = "disabling"
+	// AddonFailed means the addon cannot be resolved or rendered from the catalog
+	AddonFailed AddonPhase = "failed"
+)
+
+// AddonStatus is the observed state of an addon
+type AddonStatus struct {
+	// Phase is the phase of the addon
+	Phase AddonPhase `json:"phase,omitempty"`
+
+	// Version is the version of the addon last enabled
+	Version string `json:"version,omitempty"`
+
+	// TargetVersion is the version of the addon being enabled, it's the same as Version once enabled
+	TargetVersion string `json:"targetVersion,omitempty"`
+
+	// LatestVersion is the latest version of the addon in the catalog, an upgrade is available if it's newer
+	// than Version but not allowed by the version constraint
+	LatestVersion string `json:"latestVersion,omitempty"`
+
+	// Dependencies are the names of the addons the target version depends on
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Application is the name of the application rendered from the addon
+	Application string `json:"application,omitempty"`
+
+	// Message explains why the addon is not enabled yet
+	Message string `json:"message,omitempty"`
+
+	// LastSyncTime is the last time the addon is synced with the catalog
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="VERSION",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="LATEST",type=string,JSONPath=`.status.latestVersion`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=".metadata.creationTimestamp"
+
+// Addon enables the addon of its name from a catalog. The definitions and auxiliary resources of the addon are
+// rendered as an application, and the addons it depends on are enabled from the same catalog first.
+// +kubebuilder:resource:categories={oam}
+type Addon struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AddonSpec   `json:"spec,omitempty"`
+	Status AddonStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AddonList contains a list of Addon
+type AddonList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Addon `json:"items"`
+}
@@ -142,6 +142,10 @@ type ClusterPlacementStatus struct {
 
 	// Replicas indicates the replica number of an app revision to deploy to a cluster.
 	Replicas int `json:"replicas,omitempty"`
+
+	// Cordoned indicates the cluster is cordoned after the app revision is placed to it,
+	// the resources dispatched are kept as they are.
+	Cordoned bool `json:"cordoned,omitempty"`
 }
 
 // PlacementStatus shows the cluster placement results of an app revision.
@@ -153,6 +157,21 @@ type PlacementStatus struct {
 	Clusters []ClusterPlacementStatus `json:"clusters,omitempty"`
 }
 
+// UpgradePolicy defines how to upgrade the clusters to the new placement in batches.
+type UpgradePolicy struct {
+	// MaxSkew is the max number of clusters upgraded concurrently, i.e., the size of a batch.
+	// The next batch starts only after all clusters of the current batch are healthy.
+	// If not specified, all clusters are upgraded at once.
+	MaxSkew int `json:"maxSkew,omitempty"`
+
+	// PauseSeconds is the duration to pause between two batches after the former one is healthy.
+	PauseSeconds int32 `json:"pauseSeconds,omitempty"`
+
+	// HealthCheckTimeoutSeconds is the max duration to wait for the clusters of a batch to become healthy.
+	// The upgrade is halted if any of them is still unhealthy after the timeout. Defaults to 600.
+	HealthCheckTimeoutSeconds int32 `json:"healthCheckTimeoutSeconds,omitempty"`
+}
+
 // AppDeploymentSpec defines how to describe an upgrade between different apps
 type AppDeploymentSpec struct {
 
@@ -161,6 +180,36 @@ type AppDeploymentSpec struct {
 
 	// AppRevision specifies  AppRevision resources to and the rules to apply to them.
 	AppRevisions []AppRevision `json:"appRevisions,omitempty"`
+
+	// UpgradePolicy defines how to upgrade the clusters in batches.
+	// If not specified, all clusters are upgraded at once.
+	UpgradePolicy *UpgradePolicy `json:"upgradePolicy,omitempty"`
+}
+
+// UpgradeStatus shows the progress of upgrading the clusters in batches.
+type UpgradeStatus struct {
+	// Batch is the number of the current batch, starting from 1.
+	Batch int `json:"batch,omitempty"`
+
+	// Clusters are the clusters upgraded in the current batch.
+	// An empty name indicates the host cluster per se.
+	Clusters []string `json:"clusters,omitempty"`
+
+	// BatchStartTime is the time when the current batch started.
+	BatchStartTime metav1.Time `json:"batchStartTime,omitempty"`
+
+	// BatchHealthyTime is the time when all clusters of the current batch became healthy.
+	BatchHealthyTime *metav1.Time `json:"batchHealthyTime,omitempty"`
+
+	// ObservedGeneration is the generation of the AppDeployment the current batch is upgraded to.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Halted indicates the upgrade is halted because the clusters of the current batch are unhealthy.
+	// The upgrade is resumed once the AppDeployment is updated.
+	Halted bool `json:"halted,omitempty"`
+
+	// Message explains the state of the current batch.
+	Message string `json:"message,omitempty"`
 }
 
 // AppDeploymentStatus defines the observed state of AppDeployment
@@ -174,6 +223,9 @@ type AppDeploymentStatus struct {
 
 	// Placement shows the cluster placement results of the app revisions.
 	Placement []PlacementStatus `json:"placement,omitempty"`
+
+	// Upgrade shows the progress of upgrading the clusters in batches.
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 }
 
 // AppDeployment is the Schema for the AppDeployment API
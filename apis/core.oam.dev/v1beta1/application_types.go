@@ -32,6 +32,10 @@ type ApplicationTrait struct {
 	Type string `json:"type"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Properties runtime.RawExtension `json:"properties,omitempty"`
+
+	// If is a CUE expression over the application context, environment and policies, the trait is rendered only if it's evaluated to true
+	// +optional
+	If string `json:"if,omitempty"`
 }
 
 // ApplicationComponent describe the component of application
@@ -48,6 +52,19 @@ type ApplicationComponent struct {
 	// scopes in ApplicationComponent defines the component-level scopes
 	// the format is <scope-type:scope-instance-name> pairs, the key represents type of `ScopeDefinition` while the value represent the name of scope instance.
 	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// If is a CUE expression over the application context, environment and policies, the component is rendered only if it's evaluated to true
+	// +optional
+	If string `json:"if,omitempty"`
+
+	// DependsOn is the names of the components this component depends on, the component is rendered and applied
+	// only after all of them are healthy
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// ImagePolicy updates the image property of the component to the tag selected from the registry
+	// +optional
+	ImagePolicy *common.ImagePolicy `json:"imagePolicy,omitempty"`
 }
 
 // AppPolicy defines a global policy for all components in the app.
@@ -69,12 +86,101 @@ type WorkflowStep struct {
 
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Properties runtime.RawExtension `json:"properties,omitempty"`
+
+	// Timeout is the duration each attempt of the step is allowed to run, e.g., 5m, the step is marked timedOut if
+	// it's still running after it. It overrides the default timeout in the WorkflowStepDefinition.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// RetryPolicy defines how the step is retried if it fails or times out, it overrides the default retry policy
+	// in the WorkflowStepDefinition
+	// +optional
+	RetryPolicy *common.WorkflowStepRetryPolicy `json:"retryPolicy,omitempty"`
+
+	// OnFailure is the name of a following step the workflow continues with if the step still fails after all the
+	// retries, the workflow stops at the failed step if empty
+	// +optional
+	OnFailure string `json:"onFailure,omitempty"`
+
+	// If is a CUE expression deciding whether the step is executed or skipped, it's evaluated once all the previous
+	// steps finish against the step statuses, e.g., status.deploy-staging.succeeded, the application context, e.g.,
+	// context.env == "prod", and the properties of the step as inputs. The step is always executed if empty.
+	// +optional
+	If string `json:"if,omitempty"`
+
+	// Inputs import the outputs of the previous steps into the properties of the step, the step waits until all of
+	// them are exported
+	// +optional
+	Inputs []WorkflowStepInput `json:"inputs,omitempty"`
+
+	// Outputs export the values of the resource referred by the step to the workflow context once the step succeeds
+	// +optional
+	Outputs []WorkflowStepOutput `json:"outputs,omitempty"`
+
+	// SubSteps are the steps of a step-group step, they're executed concurrently and the step succeeds once all of
+	// them succeed
+	// +optional
+	SubSteps []WorkflowSubStep `json:"subSteps,omitempty"`
+}
+
+// WorkflowStepInput imports an output of the previous steps into the properties of a step.
+type WorkflowStepInput struct {
+	// From is the name of the output
+	From string `json:"from"`
+
+	// ParameterKey is the path of the property the value is set to, e.g., name or config.replicas
+	ParameterKey string `json:"parameterKey"`
+}
+
+// WorkflowStepOutput exports a value of the resource referred by a step to the workflow context.
+type WorkflowStepOutput struct {
+	// Name is the unique name of the output in the workflow
+	Name string `json:"name"`
+
+	// ValueFrom is the CUE path of the value in the resource, e.g., status.services[0].port
+	ValueFrom string `json:"valueFrom"`
+}
+
+// WorkflowSubStep defines how to execute a sub-step of a step-group workflow step.
+type WorkflowSubStep struct {
+	// Name is the unique name of the sub-step in the step group.
+	Name string `json:"name"`
+
+	Type string `json:"type"`
+
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Properties runtime.RawExtension `json:"properties,omitempty"`
+}
+
+// ApplicationParameter is a parameter of the application substituted into the properties of the components and traits.
+type ApplicationParameter struct {
+	// Name is the name of the parameter, it's referred by ${name}
+	Name string `json:"name"`
+
+	// Description describes the parameter
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Value is the value of the parameter, the value of the environment of the application takes precedence
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Value *runtime.RawExtension `json:"value,omitempty"`
 }
 
 // ApplicationSpec is the spec of Application
 type ApplicationSpec struct {
 	Components []ApplicationComponent `json:"components"`
 
+	// Environment is the name of the Environment the application is deployed to, its targets and default policies
+	// are expanded into the placement and override policies of the application
+	// +optional
+	Environment string `json:"environment,omitempty"`
+
+	// Parameters are the parameters of the application referred by ${name} in the properties of the components and
+	// traits, so the application can be instantiated with the values of different environments
+	// +optional
+	Parameters []ApplicationParameter `json:"parameters,omitempty"`
+
 	// Policies defines the global policies for all components in the app, e.g. security, metrics, gitops,
 	// multi-cluster placement rules, etc.
 	// Policies are applied after components are rendered and before workflow steps are executed.
@@ -87,12 +193,23 @@ type ApplicationSpec struct {
 	// - should mark "finish" phase in status.conditions.
 	Workflow []WorkflowStep `json:"workflow,omitempty"`
 
+	// WorkflowSuspend pauses the deployment of the application before its next workflow step until it's removed,
+	// the resources deployed by the finished steps are kept as they are
+	// +optional
+	WorkflowSuspend *common.WorkflowSuspend `json:"workflowSuspend,omitempty"`
+
 	// TODO(wonderflow): we should have application level scopes supported here
 
 	// RolloutPlan is the details on how to rollout the resources
 	// The controller simply replace the old resources with the new one if there is no rollout plan involved
 	// +optional
 	RolloutPlan *v1alpha1.RolloutPlan `json:"rolloutPlan,omitempty"`
+
+	// RevisionHistoryLimit is the number of old revisions of the application and its components to keep, the
+	// revisions in use, e.g., referred by a live rollout, are kept besides. It defaults to the application revision
+	// limit of the controller.
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -133,3 +250,13 @@ func (app *Application) GetComponent(workloadType string) *ApplicationComponent
 	}
 	return nil
 }
+
+// GetObservedGeneration gets the generation of the Application its status reflects
+func (app *Application) GetObservedGeneration() int64 {
+	return app.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the Application its status reflects
+func (app *Application) SetObservedGeneration(generation int64) {
+	app.Status.ObservedGeneration = generation
+}
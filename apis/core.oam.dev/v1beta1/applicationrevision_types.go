@@ -43,9 +43,24 @@ type ApplicationRevisionSpec struct {
 	// ScopeDefinitions records the snapshot of the scopeDefinitions related with the created/modified Application
 	ScopeDefinitions map[string]ScopeDefinition `json:"scopeDefinitions,omitempty"`
 
+	// PolicyDefinitions records the snapshot of the policyDefinitions related with the created/modified Application
+	PolicyDefinitions map[string]PolicyDefinition `json:"policyDefinitions,omitempty"`
+
+	// WorkflowStepDefinitions records the snapshot of the workflowStepDefinitions related with the created/modified Application
+	WorkflowStepDefinitions map[string]WorkflowStepDefinition `json:"workflowStepDefinitions,omitempty"`
+
+	// CUEPackages records the snapshot of the CUE packages imported by the templates of the definitions, keyed by
+	// import paths, so the application can be rendered from the revision exactly as it was
+	CUEPackages map[string]string `json:"cuePackages,omitempty"`
+
 	// Components records the rendered components from Application, it will contains the whole K8s CR of workload in it.
 	Components []common.RawComponent `json:"components,omitempty"`
 
+	// CompressedManifests records the Components and the ApplicationConfiguration compressed by the algorithm in the
+	// app.oam.dev/manifest-compression annotation, the Components are empty and the ApplicationConfiguration is a
+	// stub in the revision then
+	CompressedManifests []byte `json:"compressedManifests,omitempty"`
+
 	// ApplicationConfiguration records the rendered applicationConfiguration from Application,
 	// it will contains the whole K8s CR of trait and the reference component in it.
 	// +kubebuilder:validation:EmbeddedResource
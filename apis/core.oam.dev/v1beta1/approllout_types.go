@@ -68,6 +68,16 @@ type AppRollout struct {
 	Status common.AppRolloutStatus `json:"status,omitempty"`
 }
 
+// GetObservedGeneration gets the generation of the AppRollout its status reflects
+func (r *AppRollout) GetObservedGeneration() int64 {
+	return r.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the AppRollout its status reflects
+func (r *AppRollout) SetObservedGeneration(generation int64) {
+	r.Status.ObservedGeneration = generation
+}
+
 // AppRolloutList contains a list of AppRollout
 // +kubebuilder:object:root=true
 type AppRolloutList struct {
@@ -27,6 +27,10 @@ type ClusterSpec struct {
 	// KubeconfigSecretRef specifies the reference to the secret
 	// that contains the kubeconfig in field `config`.
 	KubeconfigSecretRef LocalSecretReference `json:"kubeconfigSecretRef,omitempty"`
+
+	// Cordoned marks the cluster unschedulable, new placements skip it while the resources dispatched to it
+	// before are kept and flagged in the placement status.
+	Cordoned bool `json:"cordoned,omitempty"`
 }
 
 // LocalSecretReference is a reference to a secret within the enclosing
@@ -36,12 +40,60 @@ type LocalSecretReference struct {
 	Name string `json:"name"`
 }
 
+// ClusterHealthPhase is the health of a cluster probed by the cluster controller
+type ClusterHealthPhase string
+
+const (
+	// ClusterHealthy means the API server of the cluster is reachable and its nodes are ready
+	ClusterHealthy ClusterHealthPhase = "Healthy"
+	// ClusterUnhealthy means the API server of the cluster is unreachable or none of its nodes is ready
+	ClusterUnhealthy ClusterHealthPhase = "Unhealthy"
+	// ClusterUnknown means the cluster is not probed yet
+	ClusterUnknown ClusterHealthPhase = "Unknown"
+)
+
 // ClusterStatus defines the observed state of Cluster
 type ClusterStatus struct {
+	// Health is the health of the cluster probed last time
+	Health ClusterHealthPhase `json:"health,omitempty"`
+
+	// Message explains why the cluster is unhealthy or under resource pressure
+	Message string `json:"message,omitempty"`
+
+	// Version is the version of the API server of the cluster
+	Version string `json:"version,omitempty"`
+
+	// Nodes is the number of the nodes in the cluster
+	Nodes int `json:"nodes,omitempty"`
+
+	// NotReadyNodes is the number of the nodes not ready
+	NotReadyNodes int `json:"notReadyNodes,omitempty"`
+
+	// PressureNodes are the names of the nodes under memory, disk or PID pressure
+	PressureNodes []string `json:"pressureNodes,omitempty"`
+
+	// ConsecutiveFailures is the number of the consecutive probes the cluster is found unhealthy
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+
+	// Cordoned indicates new placements skip the cluster, either it's cordoned in the spec or it's cordoned
+	// automatically after being unhealthy for several consecutive probes
+	Cordoned bool `json:"cordoned,omitempty"`
+
+	// CordonReason explains why the cluster is cordoned
+	CordonReason string `json:"cordonReason,omitempty"`
+
+	// LastProbeTime is the time the cluster is probed last time
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="HEALTH",type=string,JSONPath=`.status.health`
+// +kubebuilder:printcolumn:name="VERSION",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="CORDONED",type=boolean,JSONPath=`.status.cordoned`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=".metadata.creationTimestamp"
+
 // Cluster is the Schema for the clusters API
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -139,6 +139,12 @@ type TraitDefinitionSpec struct {
 	// +optional
 	Schematic *common.Schematic `json:"schematic,omitempty"`
 
+	// Stage defines the stage of the trait relative to the workload, it only groups the trait in the assembled
+	// resources and doesn't order the dispatching. The trait is grouped after the workload if it's not specified
+	// +optional
+	// +kubebuilder:validation:Enum=PreDispatch;PreWorkload;PostWorkload
+	Stage common.TraitStage `json:"stage,omitempty"`
+
 	// Status defines the custom health policy and status message for trait
 	// +optional
 	Status *common.Status `json:"status,omitempty"`
@@ -153,6 +159,8 @@ type TraitDefinitionSpec struct {
 type TraitDefinitionStatus struct {
 	// ConditionedStatus reflects the observed status of a resource
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
+	// ObservedGeneration is the generation of the definition the status reflects
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// ConfigMapRef refer to a ConfigMap which contains OpenAPI V3 JSON schema of Component parameters.
 	ConfigMapRef string `json:"configMapRef,omitempty"`
 	// LatestRevision of the component definition
@@ -189,6 +197,16 @@ func (td *TraitDefinition) GetCondition(conditionType runtimev1alpha1.ConditionT
 	return td.Status.GetCondition(conditionType)
 }
 
+// GetObservedGeneration gets the generation of the TraitDefinition its status reflects
+func (td *TraitDefinition) GetObservedGeneration() int64 {
+	return td.Status.ObservedGeneration
+}
+
+// SetObservedGeneration sets the generation of the TraitDefinition its status reflects
+func (td *TraitDefinition) SetObservedGeneration(generation int64) {
+	td.Status.ObservedGeneration = generation
+}
+
 // +kubebuilder:object:root=true
 
 // TraitDefinitionList contains a list of TraitDefinition.
@@ -255,7 +273,22 @@ type ResourceTracker struct {
 
 // ResourceTrackerStatus define the status of resourceTracker
 type ResourceTrackerStatus struct {
+	// AppRevision is the revision of the application which applied the tracked resources
+	AppRevision string `json:"appRevision,omitempty"`
+
 	TrackedResources []TypedReference `json:"trackedResources,omitempty"`
+
+	// PendingDeletions are the resources no longer rendered, waiting for their garbage collection grace periods
+	PendingDeletions []common.PendingDeletion `json:"pendingDeletions,omitempty"`
+
+	// Compression is the algorithm the tracked resources and the pending deletions are compressed by, i.e., gzip or
+	// zstd, they're not compressed if it's empty. It's recorded in the status rather than an annotation as the
+	// status is written alone.
+	Compression string `json:"compression,omitempty"`
+
+	// CompressedResources records the TrackedResources and the PendingDeletions compressed by Compression, they're
+	// empty then
+	CompressedResources []byte `json:"compressedResources,omitempty"`
 }
 
 // A TypedReference refers to an object by Name, Kind, and APIVersion. It is
@@ -277,6 +310,10 @@ type TypedReference struct {
 	// UID of the referenced object.
 	// +optional
 	UID types.UID `json:"uid,omitempty"`
+
+	// Cluster is the managed cluster the object is dispatched to, it's in the control plane cluster if empty.
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -44,6 +44,54 @@ type DefinitionRevisionSpec struct {
 
 	// WorkflowStepDefinition records the snapshot of the created/modified WorkflowStepDefinition
 	WorkflowStepDefinition WorkflowStepDefinition `json:"workflowStepDefinition,omitempty"`
+
+	// Diff records the changes of the definition from the previous revision, it's empty for the first revision
+	// +optional
+	Diff *DefinitionRevisionDiff `json:"diff,omitempty"`
+}
+
+// DefinitionRevisionDiff records the changes of a definition from its previous revision
+type DefinitionRevisionDiff struct {
+	// PreviousRevision is the name of the revision the definition is compared with
+	PreviousRevision string `json:"previousRevision"`
+
+	// Changes are the changed fields of the definition
+	Changes []DefinitionFieldChange `json:"changes,omitempty"`
+}
+
+// DefinitionFieldChangeType is the type of the change of a field
+type DefinitionFieldChangeType string
+
+const (
+	// DefinitionFieldAdded means the field is added
+	DefinitionFieldAdded DefinitionFieldChangeType = "Added"
+	// DefinitionFieldRemoved means the field is removed
+	DefinitionFieldRemoved DefinitionFieldChangeType = "Removed"
+	// DefinitionFieldModified means the value of the field is modified
+	DefinitionFieldModified DefinitionFieldChangeType = "Modified"
+)
+
+// DefinitionFieldChange is the change of a field of a definition
+type DefinitionFieldChange struct {
+	// Path is the path of the field, e.g., spec.schematic.cue.template
+	Path string `json:"path"`
+
+	// Type is the type of the change
+	// +kubebuilder:validation:Enum=Added;Removed;Modified
+	Type DefinitionFieldChangeType `json:"type"`
+
+	// Old is the previous value of the field in JSON, it's omitted for the modified multi-line strings
+	// +optional
+	Old string `json:"old,omitempty"`
+
+	// New is the current value of the field in JSON, it's omitted for the modified multi-line strings
+	// +optional
+	New string `json:"new,omitempty"`
+
+	// LineDiff is the line diff of a modified multi-line string, e.g., the CUE template, the lines removed are
+	// prefixed with "-" and the lines added are prefixed with "+", the unchanged lines are omitted
+	// +optional
+	LineDiff string `json:"lineDiff,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnvironmentTarget defines a cluster and namespace the applications of an environment are deployed to.
+type EnvironmentTarget struct {
+	// ClusterSelector selects the cluster to deploy apps to.
+	// If not specified, it indicates the host cluster per se.
+	ClusterSelector *ClusterSelector `json:"clusterSelector,omitempty"`
+
+	// Namespace is the namespace to deploy apps to in the cluster.
+	// If not specified, it indicates the namespace of the application.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// EnvironmentSpec defines the desired state of Environment
+type EnvironmentSpec struct {
+	// Targets are the clusters and namespaces the applications of the environment are deployed to.
+	Targets []EnvironmentTarget `json:"targets,omitempty"`
+
+	// Policies are the default policies of the applications of the environment,
+	// the policy of an application with the same name takes precedence.
+	Policies []AppPolicy `json:"policies,omitempty"`
+
+	// Parameters are the values of the parameters of the applications of the environment, they override the values
+	// declared by the applications. The parameters not declared by an application are ignored.
+	Parameters []ApplicationParameter `json:"parameters,omitempty"`
+}
+
+// EnvironmentStatus defines the observed state of Environment
+type EnvironmentStatus struct {
+}
+
+// +kubebuilder:object:root=true
+
+// Environment maps a logical environment, e.g., prod, to the target clusters and namespaces and the default policies,
+// applications refer to it by name so multi-cluster mechanics are hidden from app developers.
+// +kubebuilder:resource:categories={oam},shortName=env
+type Environment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EnvironmentSpec   `json:"spec,omitempty"`
+	Status EnvironmentStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EnvironmentList contains a list of Environment
+type EnvironmentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Environment `json:"items"`
+}
@@ -141,6 +141,38 @@ var (
 	ClusterKindVersionKind = SchemeGroupVersion.WithKind(ClusterKind)
 )
 
+// Environment type metadata.
+var (
+	EnvironmentKind             = reflect.TypeOf(Environment{}).Name()
+	EnvironmentGroupKind        = schema.GroupKind{Group: Group, Kind: EnvironmentKind}.String()
+	EnvironmentKindAPIVersion   = EnvironmentKind + "." + SchemeGroupVersion.String()
+	EnvironmentGroupVersionKind = SchemeGroupVersion.WithKind(EnvironmentKind)
+)
+
+// UsageReport type metadata.
+var (
+	UsageReportKind             = reflect.TypeOf(UsageReport{}).Name()
+	UsageReportGroupKind        = schema.GroupKind{Group: Group, Kind: UsageReportKind}.String()
+	UsageReportKindAPIVersion   = UsageReportKind + "." + SchemeGroupVersion.String()
+	UsageReportGroupVersionKind = SchemeGroupVersion.WithKind(UsageReportKind)
+)
+
+// Trigger type metadata.
+var (
+	TriggerKind             = reflect.TypeOf(Trigger{}).Name()
+	TriggerGroupKind        = schema.GroupKind{Group: Group, Kind: TriggerKind}.String()
+	TriggerKindAPIVersion   = TriggerKind + "." + SchemeGroupVersion.String()
+	TriggerGroupVersionKind = SchemeGroupVersion.WithKind(TriggerKind)
+)
+
+// Addon type metadata.
+var (
+	AddonKind             = reflect.TypeOf(Addon{}).Name()
+	AddonGroupKind        = schema.GroupKind{Group: Group, Kind: AddonKind}.String()
+	AddonKindAPIVersion   = AddonKind + "." + SchemeGroupVersion.String()
+	AddonGroupVersionKind = SchemeGroupVersion.WithKind(AddonKind)
+)
+
 func init() {
 	SchemeBuilder.Register(&ComponentDefinition{}, &ComponentDefinitionList{})
 	SchemeBuilder.Register(&WorkloadDefinition{}, &WorkloadDefinitionList{})
@@ -154,5 +186,9 @@ func init() {
 	SchemeBuilder.Register(&ApplicationRevision{}, &ApplicationRevisionList{})
 	SchemeBuilder.Register(&AppDeployment{}, &AppDeploymentList{})
 	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
+	SchemeBuilder.Register(&Environment{}, &EnvironmentList{})
 	SchemeBuilder.Register(&ResourceTracker{}, &ResourceTrackerList{})
+	SchemeBuilder.Register(&UsageReport{}, &UsageReportList{})
+	SchemeBuilder.Register(&Trigger{}, &TriggerList{})
+	SchemeBuilder.Register(&Addon{}, &AddonList{})
 }
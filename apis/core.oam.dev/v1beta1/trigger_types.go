@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TriggerType is the type of the push events a Trigger receives
+type TriggerType string
+
+const (
+	// TriggerTypeImage receives the push events of image registries, i.e., Docker Hub, Harbor or the generic payload
+	TriggerTypeImage TriggerType = "image"
+	// TriggerTypeGit receives the push events of Git repositories, i.e., GitHub or GitLab
+	TriggerTypeGit TriggerType = "git"
+)
+
+// TriggerSpec defines the webhooks a Trigger receives
+type TriggerSpec struct {
+	// Type is the type of the push events received, i.e., image or git
+	// +kubebuilder:validation:Enum=image;git
+	Type TriggerType `json:"type"`
+
+	// SecretRef refers to the Secret in the namespace of the Trigger whose token key verifies the webhooks, i.e., the
+	// HMAC secret of GitHub, the secret token of GitLab or the token sent by the other senders
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// TagPattern is the regular expression the pushed image tags must match, e.g., ^v\d+\.\d+\.\d+$, all the tags
+	// are accepted if it's empty. It's ignored by the git type.
+	// +optional
+	TagPattern string `json:"tagPattern,omitempty"`
+}
+
+// TriggerStatus records the last push event received by a Trigger
+type TriggerStatus struct {
+	// LastEvent describes the last push event received, e.g., ghcr.io/org/web:v1.0.0 or
+	// https://github.com/org/app@main
+	LastEvent string `json:"lastEvent,omitempty"`
+
+	// LastTriggeredAt is the time the last push event is received
+	LastTriggeredAt *metav1.Time `json:"lastTriggeredAt,omitempty"`
+
+	// Applications are the applications redeployed by the last push event
+	Applications []string `json:"applications,omitempty"`
+
+	// Message is the error of handling the last push event
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="TYPE",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="LAST-EVENT",type=string,JSONPath=`.status.lastEvent`
+// +kubebuilder:printcolumn:name="LAST-TRIGGERED",type=date,JSONPath=`.status.lastTriggeredAt`
+
+// Trigger receives the webhooks of image registries or Git repositories at /triggers/<namespace>/<name> of the
+// trigger server of the controller. The applications in its namespace subscribing it by the app.oam.dev/trigger
+// annotation get the images of their components updated and their workflows restarted on the push events.
+// +kubebuilder:resource:categories={oam}
+type Trigger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TriggerSpec   `json:"spec,omitempty"`
+	Status TriggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TriggerList contains a list of Trigger
+type TriggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Trigger `json:"items"`
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Usage is the aggregated usage of applications
+type Usage struct {
+	// Applications is the number of applications
+	Applications int `json:"applications"`
+
+	// Components is the number of components of the applications
+	Components int `json:"components"`
+
+	// DispatchedObjects is the number of workloads and traits dispatched by the applications, including the ones
+	// dispatched to managed clusters
+	DispatchedObjects int `json:"dispatchedObjects"`
+
+	// Requests is the sum of the resources requested by the containers of the components running in the control
+	// plane cluster
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+}
+
+// NamespaceUsage is the usage of applications in a namespace
+type NamespaceUsage struct {
+	// Namespace is the name of the namespace
+	Namespace string `json:"namespace"`
+
+	// Team is the team owning the namespace, it's read from the metering.oam.dev/team label of the namespace
+	Team string `json:"team,omitempty"`
+
+	Usage `json:",inline"`
+}
+
+// TeamUsage is the usage of applications in all namespaces owned by a team
+type TeamUsage struct {
+	// Team is the name of the team
+	Team string `json:"team"`
+
+	Usage `json:",inline"`
+}
+
+// UsageReportSpec is a snapshot of the usage of applications
+type UsageReportSpec struct {
+	// CollectedAt is the time the usage is collected
+	CollectedAt metav1.Time `json:"collectedAt"`
+
+	// Namespaces are the usage of each namespace having applications
+	Namespaces []NamespaceUsage `json:"namespaces,omitempty"`
+
+	// Teams are the usage aggregated by the team owning the namespaces, namespaces without a team are not included
+	Teams []TeamUsage `json:"teams,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UsageReport is a snapshot of the usage of applications per namespace and team collected by the metering module
+// periodically, it's used for chargeback or showback by platform teams.
+// +kubebuilder:resource:scope=Cluster,categories={oam}
+// +kubebuilder:printcolumn:name="COLLECTED-AT",type=date,JSONPath=".spec.collectedAt"
+type UsageReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec UsageReportSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UsageReportList contains a list of UsageReport
+type UsageReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UsageReport `json:"items"`
+}
@@ -31,6 +31,15 @@ type WorkflowStepDefinitionSpec struct {
 	// Schematic defines the data format and template of the encapsulation of the workflow step definition
 	// +optional
 	Schematic *common.Schematic `json:"schematic,omitempty"`
+
+	// Timeout is the default duration each attempt of the steps of this type is allowed to run, e.g., 5m,
+	// the steps run until they finish if empty
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+
+	// RetryPolicy is the default retry policy of the steps of this type, the steps are not retried if empty
+	// +optional
+	RetryPolicy *common.WorkflowStepRetryPolicy `json:"retryPolicy,omitempty"`
 }
 
 // WorkflowStepDefinitionStatus is the status of WorkflowStepDefinition
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// AutoscalerBackend is the implementation the workload is scaled with
+type AutoscalerBackend string
+
+const (
+	// HPAAutoscalerBackend scales the workload with a HorizontalPodAutoscaler on its CPU and memory utilization
+	HPAAutoscalerBackend AutoscalerBackend = "HPA"
+	// KEDAAutoscalerBackend scales the workload with a KEDA ScaledObject on its event sources
+	KEDAAutoscalerBackend AutoscalerBackend = "KEDA"
+)
+
+// AutoscalerSpec defines the desired state of Autoscaler
+type AutoscalerSpec struct {
+	// MinReplicas is the lower limit of the replicas of the workload, default is 1
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit of the replicas of the workload
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// CPUUtilization is the target average CPU utilization of the pods, in percent of their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPUUtilization *int32 `json:"cpuUtilization,omitempty"`
+
+	// MemoryUtilization is the target average memory utilization of the pods, in percent of their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MemoryUtilization *int32 `json:"memoryUtilization,omitempty"`
+
+	// Triggers are the KEDA event sources the workload is scaled on, the workload is scaled by KEDA rather than a
+	// HorizontalPodAutoscaler if there is any
+	// +optional
+	Triggers []ScaleTrigger `json:"triggers,omitempty"`
+
+	// PollingInterval is the interval in seconds KEDA checks the triggers at, default is 30
+	// +optional
+	PollingInterval *int32 `json:"pollingInterval,omitempty"`
+
+	// CooldownPeriod is the period in seconds KEDA waits after the last trigger is active before scaling the
+	// workload down to MinReplicas, default is 300
+	// +optional
+	CooldownPeriod *int32 `json:"cooldownPeriod,omitempty"`
+
+	// WorkloadReference to the workload this trait applies to
+	WorkloadReference runtimev1alpha1.TypedReference `json:"workloadRef,omitempty"`
+}
+
+// ScaleTrigger is a KEDA event source
+type ScaleTrigger struct {
+	// Name of the trigger
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Type of the KEDA scaler, e.g. kafka, prometheus or cron
+	Type string `json:"type"`
+
+	// Metadata configures the KEDA scaler, its keys depend on the type
+	Metadata map[string]string `json:"metadata"`
+
+	// AuthenticationRef is the name of the KEDA TriggerAuthentication in the namespace of the workload
+	// +optional
+	AuthenticationRef string `json:"authenticationRef,omitempty"`
+}
+
+// AutoscalerStatus defines the observed state of Autoscaler
+type AutoscalerStatus struct {
+	runtimev1alpha1.ConditionedStatus `json:",inline"`
+
+	// Backend is the implementation the workload is scaled with
+	// +optional
+	Backend AutoscalerBackend `json:"backend,omitempty"`
+
+	// ScalerRef references the HorizontalPodAutoscaler or the KEDA ScaledObject scaling the workload
+	// +optional
+	ScalerRef *runtimev1alpha1.TypedReference `json:"scalerRef,omitempty"`
+
+	// SuspendedBy is the controller leasing the workload, e.g. a rollout, the replicas of the workload are pinned
+	// until the lease is released so the autoscaler doesn't fight over them
+	// +optional
+	SuspendedBy string `json:"suspendedBy,omitempty"`
+}
+
+// Autoscaler is the Schema for the Autoscaler API, it scales the workload with a HorizontalPodAutoscaler or
+// a KEDA ScaledObject
+// +kubebuilder:object:root=true
+// +genclient
+// +kubebuilder:resource:categories={oam}
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="BACKEND",type=string,JSONPath=`.status.backend`
+// +kubebuilder:printcolumn:name="SUSPENDED-BY",type=string,JSONPath=`.status.suspendedBy`
+type Autoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AutoscalerSpec   `json:"spec,omitempty"`
+	Status AutoscalerStatus `json:"status,omitempty"`
+}
+
+// AutoscalerList contains a list of Autoscaler
+// +kubebuilder:object:root=true
+// +genclient
+type AutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Autoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Autoscaler{}, &AutoscalerList{})
+}
+
+var _ oam.Trait = &Autoscaler{}
+
+// SetConditions for set CR condition
+func (as *Autoscaler) SetConditions(c ...runtimev1alpha1.Condition) {
+	as.Status.SetConditions(c...)
+}
+
+// GetCondition for get CR condition
+func (as *Autoscaler) GetCondition(c runtimev1alpha1.ConditionType) runtimev1alpha1.Condition {
+	return as.Status.GetCondition(c)
+}
+
+// GetWorkloadReference of this Autoscaler.
+func (as *Autoscaler) GetWorkloadReference() runtimev1alpha1.TypedReference {
+	return as.Spec.WorkloadReference
+}
+
+// SetWorkloadReference of this Autoscaler.
+func (as *Autoscaler) SetWorkloadReference(r runtimev1alpha1.TypedReference) {
+	as.Spec.WorkloadReference = r
+}
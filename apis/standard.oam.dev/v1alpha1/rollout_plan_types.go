@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -30,6 +31,24 @@ const (
 
 	// DecreaseFirstRolloutStrategyType indicates that we decrease the source resources first
 	DecreaseFirstRolloutStrategyType RolloutStrategyType = "DecreaseFirst"
+
+	// BlueGreenRolloutStrategyType indicates that we provision the whole target next to the source
+	// and switch all the traffic to the target at once
+	BlueGreenRolloutStrategyType RolloutStrategyType = "BlueGreen"
+
+	// ABTestingRolloutStrategyType indicates that we provision the whole target next to the source
+	// and route the requests matching the A/B testing rules to the target until it's promoted
+	ABTestingRolloutStrategyType RolloutStrategyType = "ABTesting"
+)
+
+// HeaderMatchType is how the value of an HTTP header is matched
+type HeaderMatchType string
+
+const (
+	// ExactHeaderMatch matches the headers equal to the value
+	ExactHeaderMatch HeaderMatchType = "Exact"
+	// RegexHeaderMatch matches the headers matching the regular expression
+	RegexHeaderMatch HeaderMatchType = "Regex"
 )
 
 // HookType can be pre, post or during rollout
@@ -44,6 +63,74 @@ const (
 	PostBatchRolloutHook HookType = "post-batch-rollout"
 	// FinalizeRolloutHook execute the webhook during the rollout finalizing phase
 	FinalizeRolloutHook HookType = "finalize-rollout"
+	// PreRollbackHook execute the webhook before the batch failing the canary analysis is rolled back
+	PreRollbackHook HookType = "pre-rollback"
+)
+
+// WebhookFailurePolicyType defines what the rollout does when a webhook still fails after all its retries
+type WebhookFailurePolicyType string
+
+const (
+	// FailWebhookFailurePolicy fails the rollout if the webhook still fails after all its retries
+	FailWebhookFailurePolicy WebhookFailurePolicyType = "Fail"
+	// IgnoreWebhookFailurePolicy moves the rollout on as if the webhook succeeded
+	IgnoreWebhookFailurePolicy WebhookFailurePolicyType = "Ignore"
+)
+
+// RolloutWebhookPhase is the phase of the invocation of a webhook
+type RolloutWebhookPhase string
+
+const (
+	// RolloutWebhookRetrying indicates that the webhook failed and is invoked again in the next reconcile
+	RolloutWebhookRetrying RolloutWebhookPhase = "Retrying"
+	// RolloutWebhookSucceeded indicates that the webhook accepted the invocation
+	RolloutWebhookSucceeded RolloutWebhookPhase = "Succeeded"
+	// RolloutWebhookIgnored indicates that the webhook failed after all its retries but its failure is ignored
+	RolloutWebhookIgnored RolloutWebhookPhase = "Ignored"
+	// RolloutWebhookFailed indicates that the webhook failed after all its retries and failed the rollout
+	RolloutWebhookFailed RolloutWebhookPhase = "Failed"
+)
+
+// CanaryFailurePolicyType defines what the rollout does when a canary metric is out of its range
+type CanaryFailurePolicyType string
+
+const (
+	// PauseCanaryFailurePolicy holds the rollout at the analysed batch until the metrics are back in range
+	PauseCanaryFailurePolicy CanaryFailurePolicyType = "Pause"
+	// RollbackCanaryFailurePolicy reverts the upgraded part of the resource to the source and fails the rollout
+	RollbackCanaryFailurePolicy CanaryFailurePolicyType = "Rollback"
+)
+
+// MetricProviderType is the type of the metrics provider queried by the canary analysis
+type MetricProviderType string
+
+const (
+	// PrometheusMetricProvider queries the metrics from a Prometheus server with PromQL
+	PrometheusMetricProvider MetricProviderType = "prometheus"
+)
+
+// CanaryAnalysisPhase is the phase of the canary analysis of a batch
+type CanaryAnalysisPhase string
+
+const (
+	// CanaryAnalysisRunning indicates that the metrics of the batch are being collected
+	CanaryAnalysisRunning CanaryAnalysisPhase = "Running"
+	// CanaryAnalysisSucceeded indicates that all the metrics of the batch are in range
+	CanaryAnalysisSucceeded CanaryAnalysisPhase = "Succeeded"
+	// CanaryAnalysisFailed indicates that some metrics of the batch are out of range
+	CanaryAnalysisFailed CanaryAnalysisPhase = "Failed"
+)
+
+// TrafficRoutingProvider is the service mesh or gateway implementation the rollout shifts the traffic with
+type TrafficRoutingProvider string
+
+const (
+	// IstioTrafficRoutingProvider shifts the traffic with the weights of an Istio VirtualService
+	IstioTrafficRoutingProvider TrafficRoutingProvider = "istio"
+	// SMITrafficRoutingProvider shifts the traffic with the backends of an SMI TrafficSplit
+	SMITrafficRoutingProvider TrafficRoutingProvider = "smi"
+	// GatewayAPITrafficRoutingProvider shifts the traffic with the backend weights of a Gateway API HTTPRoute
+	GatewayAPITrafficRoutingProvider TrafficRoutingProvider = "gateway-api"
 )
 
 // RollingState is the overall rollout state
@@ -140,6 +227,130 @@ type RolloutPlan struct {
 	// before complete the process
 	// +optional
 	CanaryMetric []CanaryMetric `json:"canaryMetric,omitempty"`
+
+	// CanaryFailurePolicy is the action taken when a canary metric is out of its range, default is Pause
+	// +kubebuilder:validation:Enum=Pause;Rollback
+	// +optional
+	CanaryFailurePolicy CanaryFailurePolicyType `json:"canaryFailurePolicy,omitempty"`
+
+	// TrafficRouting shifts the traffic from the source to the target batch by batch
+	// +optional
+	TrafficRouting *TrafficRouting `json:"trafficRouting,omitempty"`
+
+	// BlueGreen configures the promotion of the target when the rollout strategy is BlueGreen
+	// +optional
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+
+	// ABTesting configures the routing and the promotion of the target when the rollout strategy is ABTesting
+	// +optional
+	ABTesting *ABTestingStrategy `json:"abTesting,omitempty"`
+}
+
+// BlueGreenStrategy defines how the target is verified and promoted in a blue-green rollout
+type BlueGreenStrategy struct {
+	// ActiveService is the name of the service the clients call, its selector is switched to the pods
+	// of the target on promotion. It can be omitted if the traffic routing switches the traffic instead
+	// +optional
+	ActiveService string `json:"activeService,omitempty"`
+
+	// PreviewService is the name of a service switched to the pods of the target once they are available,
+	// so that the target can be verified before it's promoted
+	// +optional
+	PreviewService string `json:"previewService,omitempty"`
+
+	PromotionPolicy `json:",inline"`
+}
+
+// ABTestingStrategy defines which requests are routed to the target in an A/B testing rollout
+// and how the target is promoted
+type ABTestingStrategy struct {
+	// Rules select the requests routed to the target, the other requests go to the source
+	Rules []TrafficRule `json:"rules"`
+
+	PromotionPolicy `json:",inline"`
+}
+
+// PromotionPolicy defines when the target is promoted and the source is scaled down
+// by the strategies switching all the traffic to the target at once
+type PromotionPolicy struct {
+	// AutoPromotionEnabled promotes the target as soon as it's available and passed the canary analysis,
+	// default is true. Otherwise the rollout waits for Promote to be set
+	// +optional
+	AutoPromotionEnabled *bool `json:"autoPromotionEnabled,omitempty"`
+
+	// Promote approves the promotion of the target when the auto promotion is disabled
+	// +optional
+	Promote bool `json:"promote,omitempty"`
+
+	// ScaleDownDelaySeconds is the number of seconds the source keeps running after the promotion
+	// before it's scaled down, default is 30
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleDownDelaySeconds *int32 `json:"scaleDownDelaySeconds,omitempty"`
+}
+
+// TrafficRule routes the requests matching all its conditions to the target
+type TrafficRule struct {
+	// Name identifies the rule in the routes and the status
+	Name string `json:"name"`
+
+	// Headers are the HTTP headers the requests have to match
+	// +optional
+	Headers []HeaderMatch `json:"headers,omitempty"`
+
+	// Cookie is the cookie the requests have to carry
+	// +optional
+	Cookie *CookieMatch `json:"cookie,omitempty"`
+
+	// Weight is the percent of the matching requests routed to the target, default is 100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// HeaderMatch matches the value of an HTTP header
+type HeaderMatch struct {
+	// Name is the name of the header
+	Name string `json:"name"`
+
+	// Value is the value or the regular expression the header has to match
+	Value string `json:"value"`
+
+	// Type is how the value is matched, default is Exact
+	// +kubebuilder:validation:Enum=Exact;Regex
+	// +optional
+	Type HeaderMatchType `json:"type,omitempty"`
+}
+
+// CookieMatch matches a cookie of the request
+type CookieMatch struct {
+	// Name is the name of the cookie
+	Name string `json:"name"`
+
+	// Value is the value of the cookie
+	Value string `json:"value"`
+}
+
+// TrafficRouting defines how the traffic is split between the source and the target during the rollout
+type TrafficRouting struct {
+	// Provider is the service mesh or gateway implementation programmed by the rollout
+	// +kubebuilder:validation:Enum=istio;smi;gateway-api
+	Provider TrafficRoutingProvider `json:"provider"`
+
+	// Service is the name of the service the clients call
+	Service string `json:"service"`
+
+	// StableService is the name of the service selecting the pods of the source
+	StableService string `json:"stableService"`
+
+	// CanaryService is the name of the service selecting the pods of the target
+	CanaryService string `json:"canaryService"`
+
+	// Route is the name of the VirtualService, TrafficSplit or HTTPRoute, default is the name of the service.
+	// The VirtualService and the TrafficSplit are created if they don't exist, the HTTPRoute has to exist
+	// +optional
+	Route string `json:"route,omitempty"`
 }
 
 // RolloutBatch is used to describe how the each batch rollout should be
@@ -175,6 +386,13 @@ type RolloutBatch struct {
 	// before moving to the next batch
 	// +optional
 	CanaryMetric []CanaryMetric `json:"canaryMetric,omitempty"`
+
+	// TrafficWeight is the percent of the traffic routed to the target once the batch is available,
+	// default is the percent of the replicas upgraded
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	TrafficWeight *int32 `json:"trafficWeight,omitempty"`
 }
 
 // RolloutWebhook holds the reference to external checks used for canary analysis
@@ -197,6 +415,22 @@ type RolloutWebhook struct {
 	// Metadata (key-value pairs) for this webhook
 	// +optional
 	Metadata *map[string]string `json:"metadata,omitempty"`
+
+	// TimeoutSeconds is the timeout of one invocation of this webhook, default is 10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Retries is the number of times this webhook is invoked again after it fails, one per reconcile.
+	// It's retried until it succeeds if it's not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+
+	// FailurePolicy is the action taken when this webhook still fails after all its retries, default is Fail
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	// +optional
+	FailurePolicy WebhookFailurePolicyType `json:"failurePolicy,omitempty"`
 }
 
 // RolloutWebhookPayload holds the info and metadata sent to webhooks
@@ -226,11 +460,32 @@ type CanaryMetric struct {
 	// +optional
 	MetricsRange *MetricsExpectedRange `json:"metricsRange,omitempty"`
 
+	// Provider is the metrics provider to query, it overrides the provider of the template
+	// +optional
+	Provider *MetricProvider `json:"provider,omitempty"`
+
+	// Query is the query sent to the metrics provider, it overrides the query of the template.
+	// It's a Go template rendered with the Name, Namespace, Interval and Batch of the rollout
+	// +optional
+	Query string `json:"query,omitempty"`
+
 	// TemplateRef references a metric template object
+	// only ConfigMaps in the namespace of the rollout are supported, their `provider`, `address`
+	// and `query` data keys are used as the defaults of the metric
 	// +optional
 	TemplateRef *runtimev1alpha1.TypedReference `json:"templateRef,omitempty"`
 }
 
+// MetricProvider is where the canary metrics are queried from
+type MetricProvider struct {
+	// Type of the metrics provider, default is prometheus
+	// +optional
+	Type MetricProviderType `json:"type,omitempty"`
+
+	// Address of the metrics provider, e.g. http://prometheus.monitoring:9090
+	Address string `json:"address"`
+}
+
 // MetricsExpectedRange defines the range used for metrics validation
 type MetricsExpectedRange struct {
 	// Minimum value
@@ -280,4 +535,107 @@ type RolloutStatus struct {
 
 	// UpgradedReadyReplicas is the number of Pods upgraded by the rollout controller that have a Ready Condition.
 	UpgradedReadyReplicas int32 `json:"upgradedReadyReplicas"`
+
+	// CanaryAnalysis is the canary analysis of the current batch
+	// +optional
+	CanaryAnalysis *CanaryAnalysisStatus `json:"canaryAnalysis,omitempty"`
+
+	// TrafficWeight is the percent of the traffic routed to the target
+	// +optional
+	TrafficWeight int32 `json:"trafficWeight,omitempty"`
+
+	// PromotedTime is the time the target of a blue-green rollout was promoted
+	// +optional
+	PromotedTime *metav1.Time `json:"promotedTime,omitempty"`
+
+	// TrafficRules is the status of the A/B testing rules
+	// +optional
+	TrafficRules []TrafficRuleStatus `json:"trafficRules,omitempty"`
+
+	// Webhooks is the status of the webhooks invoked in the current phase of the rollout
+	// +optional
+	Webhooks []RolloutWebhookStatus `json:"webhooks,omitempty"`
+}
+
+// CanaryAnalysisStatus is the result of the canary analysis of a batch
+type CanaryAnalysisStatus struct {
+	// Batch is the batch analysed
+	Batch int32 `json:"batch"`
+
+	// Phase of the analysis
+	Phase CanaryAnalysisPhase `json:"phase"`
+
+	// StartTime is when the pods of the batch became available and the metrics started to be collected
+	StartTime metav1.Time `json:"startTime"`
+
+	// LastAnalysisTime is when the metrics were last queried
+	// +optional
+	LastAnalysisTime *metav1.Time `json:"lastAnalysisTime,omitempty"`
+
+	// Metrics are the results of the canary metrics
+	// +optional
+	Metrics []CanaryMetricStatus `json:"metrics,omitempty"`
+
+	// Message explains why the analysis failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CanaryMetricStatus is the result of one canary metric
+type CanaryMetricStatus struct {
+	// Name of the metric
+	Name string `json:"name"`
+
+	// Value returned by the metrics provider
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// InRange is whether the value is in the expected range of the metric
+	InRange bool `json:"inRange"`
+
+	// Message is the error of the query if the value can't be fetched
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// TrafficRuleStatus is the status of an A/B testing rule
+type TrafficRuleStatus struct {
+	// Name is the name of the rule
+	Name string `json:"name"`
+
+	// Weight is the percent of the matching requests routed to the target
+	Weight int32 `json:"weight"`
+
+	// Applied is true if the rule is programmed in the route
+	Applied bool `json:"applied"`
+
+	// Message explains why the rule isn't applied
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutWebhookStatus is the status of the invocation of a webhook
+type RolloutWebhookStatus struct {
+	// Name is the name of the webhook
+	Name string `json:"name"`
+
+	// Type is the type of the webhook
+	Type HookType `json:"type"`
+
+	// Batch is the batch the webhook is invoked for
+	Batch int32 `json:"batch"`
+
+	// Phase of the invocation
+	Phase RolloutWebhookPhase `json:"phase"`
+
+	// Attempts is the number of times the webhook has been invoked
+	Attempts int32 `json:"attempts"`
+
+	// LastAttemptTime is when the webhook was last invoked
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Message is the error of the last failed invocation
+	// +optional
+	Message string `json:"message,omitempty"`
 }
@@ -216,6 +216,11 @@ func (r *RolloutStatus) ResetStatus() {
 	r.CurrentBatch = 0
 	r.UpgradedReplicas = 0
 	r.UpgradedReadyReplicas = 0
+	r.CanaryAnalysis = nil
+	r.TrafficWeight = 0
+	r.PromotedTime = nil
+	r.TrafficRules = nil
+	r.Webhooks = nil
 }
 
 // SetRolloutCondition sets the supplied condition, replacing any existing condition
@@ -113,6 +113,12 @@ const (
 	OpenapiV3JSONSchema string = "openapi-v3-json-schema"
 )
 
+// LocalizedOpenapiV3JSONSchema is the key to store the OpenAPI v3 JSON schema with the titles and descriptions in the
+// locale in ConfigMap
+func LocalizedOpenapiV3JSONSchema(locale string) string {
+	return OpenapiV3JSONSchema + "." + locale
+}
+
 // CapabilityCategory defines the category of a capability
 type CapabilityCategory string
 
@@ -124,6 +130,10 @@ const (
 
 	KubeCategory CapabilityCategory = "kube"
 
+	KustomizeCategory CapabilityCategory = "kustomize"
+
+	GitCategory CapabilityCategory = "git"
+
 	CUECategory CapabilityCategory = "cue"
 )
 
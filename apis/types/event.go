@@ -18,12 +18,26 @@ package types
 
 // reason for Application
 const (
-	ReasonParsed      = "Parsed"
-	ReasonRendered    = "Rendered"
-	ReasonApplied     = "Applied"
-	ReasonHealthCheck = "HealthChecked"
-	ReasonDeployed    = "Deployed"
-	ReasonRollout     = "Rollout"
+	ReasonParsed       = "Parsed"
+	ReasonRendered     = "Rendered"
+	ReasonApplied      = "Applied"
+	ReasonHealthCheck  = "HealthChecked"
+	ReasonDeployed     = "Deployed"
+	ReasonRollout      = "Rollout"
+	ReasonSigned       = "ManifestSigned"
+	ReasonVerified     = "ManifestVerified"
+	ReasonSuspended    = "WorkflowSuspended"
+	ReasonResumed      = "WorkflowResumed"
+	ReasonProtected    = "ProtectedFromGC"
+	ReasonApproval     = "WaitingForApproval"
+	ReasonApproved     = "WorkflowStepApproved"
+	ReasonDispatched   = "DispatchedToClusters"
+	ReasonPromoted     = "BlueGreenPromoted"
+	ReasonRetired      = "BlueGreenRetired"
+	ReasonScaledDown   = "ScaledDownByQuota"
+	ReasonRestarted    = "WorkflowRestarted"
+	ReasonRolledBack   = "RolledBack"
+	ReasonPhaseChanged = "PhaseChanged"
 
 	ReasonFailedParse       = "FailedParse"
 	ReasonFailedRender      = "FailedRender"
@@ -32,20 +46,70 @@ const (
 	ReasonFailedHealthCheck = "FailedHealthCheck"
 	ReasonFailedGC          = "FailedGC"
 	ReasonFailedRollout     = "FailedRollout"
+	ReasonFailedSign        = "FailedSign"
+	ReasonFailedVerify      = "FailedVerify"
+	ReasonFailedDependency  = "FailedDependency"
+	ReasonFailedRefer       = "FailedReferWorkload"
+	ReasonFailedDispatch    = "FailedDispatch"
+	ReasonFailedRetire      = "FailedRetire"
+	ReasonFailedQuota       = "FailedQuota"
+	ReasonFailedRollback    = "FailedRollback"
+	ReasonFailedAdmission   = "FailedAdmission"
+	ReasonHealthRegressed   = "HealthRegressed"
 )
 
 // event message for Application
 const (
-	MessageParsed      = "Parsed successfully"
-	MessageRendered    = "Rendered successfully"
-	MessageApplied     = "Applied successfully"
-	MessageHealthCheck = "Health checked healthy"
-	MessageDeployed    = "Deployed successfully"
-	MessageRollout     = "Rollout successfully"
+	MessageParsed       = "Parsed successfully"
+	MessageRendered     = "Rendered successfully"
+	MessageApplied      = "Applied successfully"
+	MessageHealthCheck  = "Health checked healthy"
+	MessageDeployed     = "Deployed successfully"
+	MessageRollout      = "Rollout successfully"
+	MessageSigned       = "Manifests of revision %s signed, %s"
+	MessageVerified     = "Manifests of revision %s verified, %s"
+	MessageSuspended    = "Workflow suspended before step %q, reason: %s"
+	MessageResumed      = "Workflow resumed"
+	MessageApproval     = "Workflow step %q is waiting for approval by %s"
+	MessageApproved     = "Workflow step %q approved by %s"
+	MessageDispatched   = "Workflow step %q deployed to clusters %s"
+	MessagePromoted     = "Revision %s of component %s promoted, revision %s retires after %s"
+	MessageRetired      = "Revision %s of component %s retired"
+	MessageScaledDown   = "Workloads scaled down to %s replicas in total to fit in the quotas"
+	MessageRestarted    = "Workflow restarted, reason: %s"
+	MessageRolledBack   = "Rolled back to revision %s as revision %s"
+	MessagePhaseChanged = "Phase changed from %s to %s"
 
 	MessageFailedParse       = "fail to parse application, err: %v"
 	MessageFailedRender      = "fail to render application, err: %v"
 	MessageFailedApply       = "fail to apply component, err: %v"
 	MessageFailedHealthCheck = "fail to health check, err: %v"
 	MessageFailedGC          = "fail to garbage collection, err: %v"
+	MessageHealthRegressed   = "Application turned unhealthy, %s"
+)
+
+// reason and event message for Cluster
+const (
+	ReasonClusterUnhealthy  = "ClusterUnhealthy"
+	ReasonClusterCordoned   = "ClusterCordoned"
+	ReasonClusterUncordoned = "ClusterUncordoned"
+
+	MessageClusterCordoned   = "Cluster cordoned, new placements skip it: %s"
+	MessageClusterUncordoned = "Cluster uncordoned"
+)
+
+// reason and event message for definitions
+const (
+	ReasonDefinitionUnhealthy = "DefinitionUnhealthy"
+	ReasonDefinitionHealthy   = "DefinitionHealthy"
+
+	MessageDefinitionHealthy = "Definition passed the health check"
+)
+
+// reason and event message for addons
+const (
+	ReasonAddonEnabled = "AddonEnabled"
+	ReasonAddonFailed  = "AddonFailed"
+
+	MessageAddonEnabled = "Version %s of the addon enabled"
 )
@@ -36,6 +36,13 @@ const (
 const (
 	// AnnDescription is the annotation which describe what is the capability used for in a WorkloadDefinition/TraitDefinition Object
 	AnnDescription = "definition.oam.dev/description"
+	// AnnI18nPrefix is the prefix of the annotations of the localized titles and descriptions of the parameters in a
+	// definition, followed by the locale, e.g., i18n.oam.dev/zh. The value is a YAML map from the paths of the
+	// parameters, e.g., ports.port, to their titles and descriptions.
+	AnnI18nPrefix = "i18n.oam.dev/"
+	// AnnExample is the annotation of an example of using the definition in YAML, it's shown in the reference docs
+	// of the definition
+	AnnExample = "definition.oam.dev/example"
 )
 
 const (
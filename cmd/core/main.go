@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -30,19 +31,32 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	standardcontroller "github.com/oam-dev/kubevela/pkg/controller"
 	oamcontroller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	oamv1alpha2 "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/trigger"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/utils/recorder"
+	"github.com/oam-dev/kubevela/pkg/utils/requeue"
+	"github.com/oam-dev/kubevela/pkg/utils/sharding"
+	"github.com/oam-dev/kubevela/pkg/utils/speclimit"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
+	"github.com/oam-dev/kubevela/pkg/utils/tracing"
+	"github.com/oam-dev/kubevela/pkg/webhook/common/certrotation"
 	oamwebhook "github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev"
 	velawebhook "github.com/oam-dev/kubevela/pkg/webhook/standard.oam.dev"
 	"github.com/oam-dev/kubevela/version"
@@ -66,16 +80,35 @@ func main() {
 	var certDir string
 	var webhookPort int
 	var useWebhook bool
+	var certRotation bool
+	var certSecret, certDNSNames, webhookConfigurations string
+	var certRotator certrotation.Rotator
 	var controllerArgs oamcontroller.Args
 	var healthAddr string
 	var disableCaps string
 	var storageDriver string
 	var syncPeriod time.Duration
 	var applyOnceOnly string
+	var triggerAddr string
+	var schedulableShards string
+	var requeueJitter float64
+	var tracingOpts tracing.Options
 
 	flag.BoolVar(&useWebhook, "use-webhook", false, "Enable Admission Webhook")
 	flag.StringVar(&certDir, "webhook-cert-dir", "/k8s-webhook-server/serving-certs", "Admission webhook cert/key dir.")
 	flag.IntVar(&webhookPort, "webhook-port", 9443, "admission webhook listen address")
+	flag.BoolVar(&certRotation, "webhook-cert-rotation", false,
+		"Generate the webhook serving certificates into the cert dir and rotate them before they expire, the CA bundle of the webhook configurations is patched accordingly. cert-manager or the certificate patching jobs are not needed if enabled.")
+	flag.StringVar(&certSecret, "webhook-cert-secret", "vela-system/kubevela-vela-core-admission",
+		"The secret (namespace/name) keeping the webhook serving certificates generated by webhook-cert-rotation.")
+	flag.StringVar(&certDNSNames, "webhook-cert-dns-names", "vela-core-webhook.vela-system.svc",
+		"The comma separated DNS names of the webhook service the certificates are generated for.")
+	flag.StringVar(&webhookConfigurations, "webhook-configurations", "kubevela-vela-core-admission",
+		"The comma separated names of the mutating and validating webhook configurations whose CA bundle is patched.")
+	flag.DurationVar(&certRotator.Validity, "webhook-cert-validity", certrotation.DefaultValidity,
+		"The validity of the webhook serving certificates generated.")
+	flag.DurationVar(&certRotator.RefreshBefore, "webhook-cert-refresh-before", certrotation.DefaultRefreshBefore,
+		"How long ahead of the expiry the webhook serving certificates are rotated.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
@@ -89,6 +122,12 @@ func main() {
 		"RevisionLimit is the maximum number of revisions that will be maintained. The default value is 50.")
 	flag.IntVar(&controllerArgs.AppRevisionLimit, "application-revision-limit", 10,
 		"application-revision-limit is the maximum number of application useless revisions that will be maintained, if the useless revisions exceed this number, older ones will be GCed first.The default value is 10.")
+	flag.StringVar(&controllerArgs.AppRevisionManifestStore, "application-revision-manifest-store", "",
+		"The backend the rendered manifests of application revisions are moved to if they're larger than the threshold, so the revisions of large applications stay under the size limit of etcd, available options: configmap. The manifests are kept in the revisions if empty.")
+	flag.IntVar(&controllerArgs.AppRevisionManifestThreshold, "application-revision-manifest-threshold", manifeststore.DefaultThreshold,
+		"The size in bytes of the rendered manifests, after compressed if they are, above which they're moved out of the application revisions.")
+	flag.StringVar(&controllerArgs.AppRevisionManifestCompression, "application-revision-manifest-compression", "",
+		"The algorithm to compress the rendered manifests of application revisions by, available options: gzip, zstd. The revisions record the algorithm in the app.oam.dev/manifest-compression annotation, so the ones created before are read as they are. The manifests are not compressed if empty.")
 	flag.IntVar(&controllerArgs.DefRevisionLimit, "definition-revision-limit", 20,
 		"definition-revision-limit is the maximum number of component/trait definition useless revisions that will be maintained, if the useless revisions exceed this number, older ones will be GCed first.The default value is 20.")
 	flag.StringVar(&controllerArgs.CustomRevisionHookURL, "custom-revision-hook-url", "",
@@ -98,12 +137,72 @@ func main() {
 	flag.StringVar(&healthAddr, "health-addr", ":9440", "The address the health endpoint binds to.")
 	flag.StringVar(&applyOnceOnly, "apply-once-only", "false",
 		"For the purpose of some production environment that workload or trait should not be affected if no spec change, available options: on, off, force.")
+	flag.BoolVar(&controllerArgs.EnableServerSideApply, "enable-server-side-apply", false,
+		"Apply resources with server-side apply by default, the strategy of an application can be overridden by the app.oam.dev/apply-strategy annotation.")
+	flag.BoolVar(&controllerArgs.EnableAdmissionPreflight, "enable-admission-preflight", false,
+		"Check the rendered resources of applications by server-side dry-run before any of them is applied, so the rejections of the validating webhooks, policy engines and quotas are aggregated into the AdmissionCheck condition of the applications. It can be overridden by the app.oam.dev/admission-preflight annotation.")
+	flag.StringVar(&controllerArgs.ResourceTrackerCompression, "resource-tracker-compression", "",
+		"The algorithm to compress the resources tracked by the ResourceTrackers by, available options: gzip, zstd. The ResourceTrackers record the algorithm in their status, so the ones written before are read as they are. The resources are not compressed if empty.")
+	var maxDecompressedSize int64
+	flag.Int64Var(&maxDecompressedSize, "max-decompressed-size", manifeststore.DefaultMaxDecompressedSize,
+		"The maximum size in bytes of the compressed manifests of application revisions and resources of ResourceTrackers after decompressed, the larger ones are rejected.")
+	flag.StringVar(&controllerArgs.ManifestSigningKeySecret, "manifest-signing-key-secret", "",
+		"The secret (namespace/name) holding the ed25519 key to sign the rendered manifests of application revisions, the manifests are verified before applied and the verifications are recorded in the app.oam.dev/manifest-verification annotation of the revisions. A vela-manifest-signing-key-<namespace> secret in the same namespace overrides it for the applications of the namespace. Signing is disabled if empty.")
+	flag.DurationVar(&controllerArgs.HelmWorkloadDiscoveryTimeout, "helm-workload-discovery-timeout", assemble.DefaultHelmWorkloadDiscoveryTimeout,
+		"The duration to wait for Helm to create the workloads of Helm-based components since their HelmReleases are created, the components are pending until then and fail afterwards. Zero waits forever.")
+	flag.DurationVar(&controllerArgs.DefinitionHealthInterval, "definition-health-interval", time.Minute,
+		"The interval to check whether the installed definitions are usable, i.e., their CUE templates compile and the CRDs they refer to exist. The result is maintained in the Ready condition of the definitions. Zero disables the checks.")
+	flag.DurationVar(&controllerArgs.MeteringInterval, "metering-interval", 0,
+		"The interval to collect the usage of applications per namespace and team, i.e., the number of applications, components, dispatched objects and requested resources. The usage is stored as UsageReports and exported as metrics. Zero disables the metering.")
+	flag.IntVar(&controllerArgs.MeteringHistoryLimit, "metering-history-limit", 24,
+		"The maximum number of UsageReports kept, the oldest ones are deleted first.")
+	flag.Float64Var(&controllerArgs.ApplyRateLimit.QPS, "apply-qps", 50,
+		"The maximum number of rendered resources applied per second to a cluster, zero means unlimited.")
+	flag.IntVar(&controllerArgs.ApplyRateLimit.Burst, "apply-burst", 100,
+		"The maximum number of rendered resources applied to a cluster in a burst above apply-qps.")
+	flag.IntVar(&controllerArgs.ApplyRateLimit.BatchSize, "apply-batch-size", 10,
+		"The number of rendered resources applied to a managed cluster concurrently, resources are applied in the order of namespaces, CRDs, RBAC, configurations and workloads.")
+	flag.IntVar(&controllerArgs.AppSpecLimits.MaxComponents, "app-max-components", 0,
+		"The maximum number of components of an application, zero means unlimited. It can be overridden by the app.oam.dev/max-components annotation of the namespace.")
+	flag.IntVar(&controllerArgs.AppSpecLimits.MaxTraitsPerComponent, "app-max-traits-per-component", 0,
+		"The maximum number of traits of a component of an application, zero means unlimited. It can be overridden by the app.oam.dev/max-traits-per-component annotation of the namespace.")
+	flag.IntVar(&controllerArgs.AppSpecLimits.MaxRenderedObjects, "app-max-rendered-objects", 0,
+		"The maximum number of workloads and traits rendered from an application, zero means unlimited. It can be overridden by the app.oam.dev/max-rendered-objects annotation of the namespace.")
+	flag.IntVar(&controllerArgs.AppSpecLimits.MaxPropertiesSize, "app-max-properties-size", speclimit.DefaultMaxPropertiesSize,
+		"The maximum size in bytes of the properties of a component, trait, policy or workflow step of an application, zero means unlimited. It can be overridden by the app.oam.dev/max-properties-size annotation of the namespace.")
+	flag.BoolVar(&controllerArgs.RequireImpersonation, "require-impersonation", false,
+		"Require the applications and ApplicationConfigurations to declare the identities to impersonate to apply their resources by the annotations app.oam.dev/service-account-name or app.oam.dev/impersonate-user, instead of applying them with the permissions of the controller.")
+	flag.BoolVar(&controllerArgs.Sharding.Enabled, "enable-sharding", false,
+		"Shard the application controller, each replica only reconciles the applications labeled with its shard-id. The master shard schedules the applications to the schedulable-shards and runs the webhooks and the other controllers.")
+	flag.StringVar(&controllerArgs.Sharding.ShardID, "shard-id", sharding.MasterShardID,
+		"The id of the shard of the replica if enable-sharding is true.")
+	flag.StringVar(&schedulableShards, "schedulable-shards", "",
+		"The comma separated ids of the shards the master shard schedules the applications to, they're scheduled to the master shard if empty.")
+	flag.DurationVar(&controllerArgs.ApplicationRequeue.Active, "application-requeue-interval", 10*time.Second,
+		"The interval to reconcile again the applications executing their workflows, not healthy or failing to reconcile.")
+	flag.DurationVar(&controllerArgs.ApplicationRequeue.Stable, "application-stable-requeue-interval", 0,
+		"The maximum interval to reconcile again the healthy applications, the interval grows from application-requeue-interval to it the longer the applications stay healthy. The healthy applications are not requeued if it's zero.")
+	flag.DurationVar(&oamutil.ReconcileWaitIntervals.Active, "workload-requeue-interval", 30*time.Second,
+		"The interval to reconcile again the workloads and traits of the built-in controllers waiting for their resources, e.g., ContainerizedWorkload and ManualScalerTrait.")
+	flag.Float64Var(&requeueJitter, "requeue-jitter", requeue.DefaultJitter,
+		"The maximum fraction of the requeue intervals added at random, so the objects reconciled together are not requeued at the same time.")
+	flag.DurationVar(&controllerArgs.EventDedupInterval, "event-dedup-interval", recorder.DefaultDedupInterval,
+		"The interval the same event of an application or a resource it dispatches is recorded at most once in, the distinct events of an object are rate limited as well. Zero disables the deduplication.")
+	flag.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The URL of the Jaeger collector the spans of the reconciliations of the applications are exported to, e.g., http://jaeger-collector.observability:14268/api/traces. Tracing is disabled if empty.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "tracing-sample-ratio", tracing.DefaultSampleRatio,
+		"The fraction of the reconciliations of the applications traced, from 0 to 1.")
+	flag.StringVar(&triggerAddr, "trigger-addr", "",
+		"The address the server receiving the webhooks of image registries and Git repositories for the Triggers binds to, e.g., :9445. The server is disabled if empty.")
 	flag.StringVar(&disableCaps, "disable-caps", "", "To be disabled builtin capability list.")
 	flag.StringVar(&storageDriver, "storage-driver", "Local", "Application file save to the storage driver")
 	flag.DurationVar(&syncPeriod, "informer-re-sync-interval", 60*time.Minute,
 		"controller shared informer lister full re-sync period")
 	flag.StringVar(&oam.SystemDefinitonNamespace, "system-definition-namespace", "vela-system", "define the namespace of the system-level definition")
 	flag.Parse()
+	controllerArgs.Sharding.SchedulableShards = sharding.ParseShards(schedulableShards)
+	controllerArgs.ApplicationRequeue.Jitter = requeueJitter
+	oamutil.ReconcileWaitIntervals.Jitter = requeueJitter
 
 	// setup logging
 	var w io.Writer
@@ -127,6 +226,22 @@ func main() {
 	setupLog.Info(fmt.Sprintf("Disable Capabilities: %s.", disableCaps))
 	setupLog.Info(fmt.Sprintf("core init with definition namespace %s", oam.SystemDefinitonNamespace))
 
+	// the replicas of each shard elect their own leader
+	leaderElectionID := kubevelaName
+	if !controllerArgs.Sharding.IsMaster() {
+		leaderElectionID = kubevelaName + "-" + controllerArgs.Sharding.ShardID
+	}
+	if controllerArgs.Sharding.Enabled {
+		setupLog.Info("sharding enabled", "shard", controllerArgs.Sharding.ShardID,
+			"schedulableShards", controllerArgs.Sharding.SchedulableShards)
+	}
+
+	shutdownTracing, err := tracing.Setup(tracingOpts, kubevelaName)
+	if err != nil {
+		setupLog.Error(err, "unable to setup tracing")
+		os.Exit(1)
+	}
+
 	restConfig := ctrl.GetConfigOrDie()
 	restConfig.UserAgent = kubevelaName + "/" + version.GitRevision
 
@@ -135,7 +250,7 @@ func main() {
 		MetricsBindAddress:      metricsAddr,
 		LeaderElection:          enableLeaderElection,
 		LeaderElectionNamespace: leaderElectionNamespace,
-		LeaderElectionID:        kubevelaName,
+		LeaderElectionID:        leaderElectionID,
 		Port:                    webhookPort,
 		CertDir:                 certDir,
 		HealthProbeBindAddress:  healthAddr,
@@ -151,11 +266,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// serve the decision logs of the applications on the metrics endpoint
+	if err := mgr.AddMetricsExtraHandler("/debug/decisions", decision.DefaultStore); err != nil {
+		setupLog.Error(err, "unable to register the decision log handler")
+		os.Exit(1)
+	}
+
+	// receive the push events redeploying the applications subscribing the Triggers on every replica
+	if len(triggerAddr) != 0 {
+		if err := mgr.Add(trigger.NewServer(triggerAddr, mgr.GetClient(), mgr.GetAPIReader())); err != nil {
+			setupLog.Error(err, "unable to add the trigger server to the manager")
+			os.Exit(1)
+		}
+	}
+
 	if err := utils.CheckDisabledCapabilities(disableCaps); err != nil {
 		setupLog.Error(err, "unable to get enabled capabilities")
 		os.Exit(1)
 	}
 
+	controllerArgs.ApplyRateLimiter = controllerArgs.ApplyRateLimit.NewLimiter()
+
 	switch strings.ToLower(applyOnceOnly) {
 	case "", "false", string(oamcontroller.ApplyOnceOnlyOff):
 		controllerArgs.ApplyMode = oamcontroller.ApplyOnceOnlyOff
@@ -172,6 +303,21 @@ func main() {
 			"valid apply-once-only value:", "on/off/force, by default it's off")
 		os.Exit(1)
 	}
+	if err := (manifeststore.Options{Type: controllerArgs.AppRevisionManifestStore,
+		Compression:        controllerArgs.AppRevisionManifestCompression,
+		TrackerCompression: controllerArgs.ResourceTrackerCompression}).Validate(); err != nil {
+		setupLog.Error(err, "invalid application-revision-manifest-store, application-revision-manifest-compression or resource-tracker-compression")
+		os.Exit(1)
+	}
+	manifeststore.SetMaxDecompressedSize(maxDecompressedSize)
+	if err := controllerArgs.ApplicationRequeue.Validate(); err != nil {
+		setupLog.Error(err, "invalid application-requeue-interval, application-stable-requeue-interval or requeue-jitter")
+		os.Exit(1)
+	}
+	if err := oamutil.ReconcileWaitIntervals.Validate(); err != nil {
+		setupLog.Error(err, "invalid workload-requeue-interval or requeue-jitter")
+		os.Exit(1)
+	}
 
 	dm, err := discoverymapper.New(mgr.GetConfig())
 	if err != nil {
@@ -179,6 +325,15 @@ func main() {
 		os.Exit(1)
 	}
 	controllerArgs.DiscoveryMapper = dm
+	crdWatcher, err := discoverymapper.NewCRDWatcher(mgr.GetConfig(), dm)
+	if err != nil {
+		setupLog.Error(err, "failed to create CRD watcher")
+		os.Exit(1)
+	}
+	if err := mgr.Add(crdWatcher); err != nil {
+		setupLog.Error(err, "failed to add CRD watcher to the manager")
+		os.Exit(1)
+	}
 	pd, err := definition.NewPackageDiscover(mgr.GetConfig())
 	if err != nil {
 		setupLog.Error(err, "failed to create CRD discovery for CUE package client")
@@ -188,10 +343,17 @@ func main() {
 	}
 	controllerArgs.PackageDiscover = pd
 
-	if useWebhook {
+	// the webhooks are served by the master shard only
+	if useWebhook && controllerArgs.Sharding.IsMaster() {
 		setupLog.Info("vela webhook enabled, will serving at :" + strconv.Itoa(webhookPort))
 		oamwebhook.Register(mgr, controllerArgs)
 		velawebhook.Register(mgr, disableCaps)
+		if certRotation {
+			if err := setupCertRotation(mgr, &certRotator, certDir, certSecret, certDNSNames, webhookConfigurations); err != nil {
+				setupLog.Error(err, "unable to issue webhook serving certificates")
+				os.Exit(1)
+			}
+		}
 		if err := waitWebhookSecretVolume(certDir, waitSecretTimeout, waitSecretInterval); err != nil {
 			setupLog.Error(err, "unable to get webhook secret")
 			os.Exit(1)
@@ -203,9 +365,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = standardcontroller.Setup(mgr, disableCaps); err != nil {
-		setupLog.Error(err, "unable to setup the vela core controller")
-		os.Exit(1)
+	if controllerArgs.Sharding.IsMaster() {
+		if err = standardcontroller.Setup(mgr, disableCaps); err != nil {
+			setupLog.Error(err, "unable to setup the vela core controller")
+			os.Exit(1)
+		}
 	}
 	if driver := os.Getenv(system.StorageDriverEnv); len(driver) == 0 {
 		// first use system environment,
@@ -223,6 +387,10 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	// flush the spans not exported yet
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to shutdown tracing")
+	}
 	setupLog.Info("program safely stops...")
 }
 
@@ -239,6 +407,35 @@ func registerHealthChecks(mgr ctrl.Manager) error {
 	return nil
 }
 
+// setupCertRotation issues the webhook serving certificates before the webhook server starts and rotates them
+// with the manager
+func setupCertRotation(mgr ctrl.Manager, rotator *certrotation.Rotator, certDir, secret, dnsNames, configurations string) error {
+	ns, name := "", secret
+	if i := strings.Index(secret, "/"); i >= 0 {
+		ns, name = secret[:i], secret[i+1:]
+	}
+	if len(ns) == 0 || len(name) == 0 {
+		return fmt.Errorf("invalid webhook cert secret %q, it must be namespace/name", secret)
+	}
+	// the manager cache is not started yet, and the secrets are not to be cached
+	c, err := ctrlclient.New(mgr.GetConfig(), ctrlclient.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	rotator.Client = c
+	rotator.SecretKey = types.NamespacedName{Namespace: ns, Name: name}
+	rotator.CertDir = certDir
+	rotator.DNSNames = strings.Split(dnsNames, ",")
+	rotator.WebhookConfigurations = strings.Split(configurations, ",")
+	if err := rotator.Refresh(context.Background()); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("webhook-cert", rotator.Check); err != nil {
+		return err
+	}
+	return mgr.Add(rotator)
+}
+
 // waitWebhookSecretVolume waits for webhook secret ready to avoid mgr running crash
 func waitWebhookSecretVolume(certDir string, timeout, interval time.Duration) error {
 	start := time.Now()
@@ -24,6 +24,9 @@ module.exports = {
       collapsed: false,
       items:[
         'end-user/application',
+        'end-user/trigger',
+        'end-user/image-policy',
+        'end-user/sensitive-values',
         {
           'Components': [
             'end-user/components/webservice',
@@ -37,10 +40,12 @@ module.exports = {
           'Traits': [
             'end-user/traits/ingress',
             'end-user/traits/scaler',
+            'end-user/traits/autoscale',
             'end-user/traits/annotations-and-labels',
             'end-user/traits/sidecar',
             'end-user/traits/volumes',
             'end-user/traits/service-binding',
+            'end-user/traits/blue-green',
             'end-user/traits/more',
           ]
         },
@@ -49,6 +54,7 @@ module.exports = {
         {
           'Observability': [
             'end-user/scopes/health',
+            'end-user/notification',
           ]
         },
         {
@@ -66,6 +72,23 @@ module.exports = {
         'platform-engineers/overview',
         'platform-engineers/definition-and-templates',
         'platform-engineers/openapi-v3-json-schema',
+        'platform-engineers/definition-bundles',
+        'platform-engineers/definition-authoring',
+        'platform-engineers/definition-testing',
+        'platform-engineers/definition-docs',
+        'platform-engineers/addons',
+        'platform-engineers/metering',
+        'platform-engineers/gc-strategy',
+        'platform-engineers/impersonation',
+        'platform-engineers/quota',
+        'platform-engineers/admission-preflight',
+        'platform-engineers/migration',
+        'platform-engineers/revision-manifests',
+        'platform-engineers/sharding',
+        'platform-engineers/requeue',
+        'platform-engineers/reconcile-tracing',
+        'platform-engineers/events',
+        'platform-engineers/conditions',
         {
           type: 'category',
           label: 'Defining Components',
@@ -89,6 +112,16 @@ module.exports = {
                   'platform-engineers/kube/trait',
               ]
             },
+            {
+              'Kustomize': [
+                  'platform-engineers/kustomize/component',
+              ]
+            },
+            {
+              'Git': [
+                  'platform-engineers/git/component',
+              ]
+            },
             {
               type: 'category',
               label: 'Cloud Services',
@@ -157,11 +190,14 @@ module.exports = {
             'cli/vela_up',
             'cli/vela_version',
             'cli/vela_exec',
+            'cli/vela_debug',
             'cli/vela_logs',
             'cli/vela_ls',
             'cli/vela_port-forward',
+            'cli/vela_rollback',
             'cli/vela_show',
             'cli/vela_status',
+            'cli/vela_top',
             'cli/vela_workloads',
             'cli/vela_traits',
             'cli/vela_system',
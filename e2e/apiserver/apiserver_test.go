@@ -256,4 +256,53 @@ var _ = ginkgo.Describe("API", func() {
 			gomega.Expect(r.Data.(string)).To(gomega.ContainSubstring("deleted from env"))
 		})
 	})
+
+	ginkgo.Context("Applications in namespaces", func() {
+		appPath := "/namespaces/default/apps/"
+		request := func(method, url string, body interface{}) apis.Response {
+			data, err := json.Marshal(body)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			req, err := http.NewRequest(method, util.URL(url), strings.NewReader(string(data)))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			defer resp.Body.Close()
+			result, err := ioutil.ReadAll(resp.Body)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			var r apis.Response
+			gomega.Expect(json.Unmarshal(result, &r)).Should(gomega.Succeed())
+			gomega.Expect(r.Code).Should(gomega.Equal(http.StatusOK), string(result))
+			return r
+		}
+
+		ginkgo.It("should create, update and delete an application", func() {
+			app := map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "app-e2e-api-namespaced"},
+				"spec": map[string]interface{}{"components": []interface{}{map[string]interface{}{
+					"name": svcName, "type": webserviceWorkloadType,
+					"properties": map[string]interface{}{"image": "crccheck/hello-world", "port": 8000},
+				}}},
+			}
+			request("POST", appPath, app)
+			app["metadata"].(map[string]interface{})["labels"] = map[string]string{"tier": "frontend"}
+			request("PUT", appPath+"app-e2e-api-namespaced", app)
+			r := request("GET", appPath+"app-e2e-api-namespaced", nil)
+			labels := r.Data.(map[string]interface{})["metadata"].(map[string]interface{})["labels"]
+			gomega.Expect(labels).Should(gomega.HaveKeyWithValue("tier", "frontend"))
+			r = request("DELETE", appPath+"app-e2e-api-namespaced", nil)
+			gomega.Expect(r.Data.(string)).To(gomega.ContainSubstring("deleted"))
+		})
+
+		ginkgo.It("should list definitions with their schemas", func() {
+			r := request("GET", "/definitions?type=component", nil)
+			var names []interface{}
+			for _, i := range r.Data.([]interface{}) {
+				def := i.(map[string]interface{})
+				gomega.Expect(def["type"]).Should(gomega.Equal("component"))
+				names = append(names, def["name"])
+			}
+			gomega.Expect(names).Should(gomega.ContainElement(webserviceWorkloadType))
+		})
+	})
 })
@@ -597,18 +597,18 @@ spec:
           description: "target port num for service provider."
 `
 
-var dryRunResult = `---
-# Application(test-vela-app) -- Comopnent(express-server) 
----
-
-apiVersion: apps/v1
+var dryRunResult = `apiVersion: apps/v1
 kind: Deployment
 metadata:
   labels:
-    app.oam.dev/appRevision: ""
+    app.oam.dev/appRevision: test-vela-app-v1
     app.oam.dev/component: express-server
     app.oam.dev/name: test-vela-app
+    app.oam.dev/resourceType: WORKLOAD
+    app.oam.dev/revision: express-server-v1
     workload.oam.dev/type: test-webservice
+  name: express-server
+  namespace: default
 spec:
   selector:
     matchLabels:
@@ -623,36 +623,40 @@ spec:
         name: express-server
         ports:
         - containerPort: 80
-
 ---
 apiVersion: v1
 kind: Service
 metadata:
   labels:
-    app.oam.dev/appRevision: ""
+    app.oam.dev/appRevision: test-vela-app-v1
     app.oam.dev/component: express-server
     app.oam.dev/name: test-vela-app
+    app.oam.dev/resourceType: TRAIT
+    app.oam.dev/revision: express-server-v1
     trait.oam.dev/resource: service
     trait.oam.dev/type: test-ingress
   name: express-server
+  namespace: default
 spec:
   ports:
   - port: 80
     targetPort: 80
   selector:
     app.oam.dev/component: express-server
-
 ---
 apiVersion: networking.k8s.io/v1beta1
 kind: Ingress
 metadata:
   labels:
-    app.oam.dev/appRevision: ""
+    app.oam.dev/appRevision: test-vela-app-v1
     app.oam.dev/component: express-server
     app.oam.dev/name: test-vela-app
+    app.oam.dev/resourceType: TRAIT
+    app.oam.dev/revision: express-server-v1
     trait.oam.dev/resource: ingress
     trait.oam.dev/type: test-ingress
   name: express-server
+  namespace: default
 spec:
   rules:
   - host: testsvc.example.com
@@ -663,14 +667,16 @@ spec:
           servicePort: 80
         path: /
 
----
-
-
 `
 
 var livediffResult = `---
 # Application (test-vela-app) has been modified(*)
 ---
+# (*) spec.components[0].name: express-server -> new-express-server
+# (+) spec.components[0].properties.cpu
+# (*) spec.components[0].properties.port: 80 -> 5000
+# (*) spec.components[0].traits[0].properties.domain: testsvc.example.com -> new-testsvc.example.com
+# (*) spec.components[0].traits[0].properties.http./: 80 -> 8080
   apiVersion: core.oam.dev/v1beta1
   kind: Application
   metadata:
@@ -0,0 +1,54 @@
+outputs: autoscaler: {
+	apiVersion: "standard.oam.dev/v1alpha1"
+	kind:       "Autoscaler"
+	spec: {
+		minReplicas: parameter.min
+		maxReplicas: parameter.max
+		if parameter["cpuUtil"] != _|_ {
+			cpuUtilization: parameter.cpuUtil
+		}
+		if parameter["memoryUtil"] != _|_ {
+			memoryUtilization: parameter.memoryUtil
+		}
+		if parameter["triggers"] != _|_ {
+			triggers: parameter.triggers
+		}
+		if parameter["pollingInterval"] != _|_ {
+			pollingInterval: parameter.pollingInterval
+		}
+		if parameter["cooldownPeriod"] != _|_ {
+			cooldownPeriod: parameter.cooldownPeriod
+		}
+	}
+}
+parameter: {
+	// +usage=Specify the minimal number of replicas to which the autoscaler can scale down
+	min: *1 | int
+
+	// +usage=Specify the maximum number of of replicas to which the autoscaler can scale up
+	max: *10 | int
+
+	// +usage=Specify the average cpu utilization, for example, 50 means the CPU usage is 50%
+	cpuUtil?: int
+
+	// +usage=Specify the average memory utilization, for example, 50 means the memory usage is 50%
+	memoryUtil?: int
+
+	// +usage=Specify the KEDA event sources to scale on, the component is scaled by KEDA if there is any
+	triggers?: [...{
+		// +usage=Specify the name of the trigger
+		name?: string
+		// +usage=Specify the type of the KEDA scaler, for example, kafka, prometheus or cron
+		type: string
+		// +usage=Specify the metadata of the KEDA scaler
+		metadata: [string]: string
+		// +usage=Specify the name of the KEDA TriggerAuthentication
+		authenticationRef?: string
+	}]
+
+	// +usage=Specify the interval in seconds KEDA checks the triggers at
+	pollingInterval?: int
+
+	// +usage=Specify the period in seconds KEDA waits after the last trigger is active before scaling down
+	cooldownPeriod?: int
+}
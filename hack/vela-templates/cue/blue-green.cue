@@ -0,0 +1,10 @@
+// the trait is rendered by the built-in blue-green handler declared by the definition, the template declares its
+// parameter only
+parameter: {
+	// +usage=The port of the Services, the ports declared by the containers are exposed if it's not set
+	port?: int
+	// +usage=The port of the pods the Services forward to, it's the same as port if not set
+	targetPort?: int
+	// +usage=How long the workload of the retired revision is kept after the promotion for a quick rollback
+	soak: *"10m" | string
+}
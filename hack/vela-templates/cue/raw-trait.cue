@@ -0,0 +1,2 @@
+outputs: trait: parameter
+parameter: {...}
@@ -0,0 +1,2 @@
+output: parameter
+parameter: {...}
@@ -1,3 +1,5 @@
+// the trait is rendered by the built-in service-binding handler declared by the definition, the patch binds the
+// envMappings for the controllers without the handler
 patch: {
 	spec: template: spec: {
 		// +patchKey=name
@@ -5,7 +7,7 @@ patch: {
 			name: context.name
 			// +patchKey=name
 			env: [
-				for envName, v in parameter.envMappings {
+				if parameter["envMappings"] != _|_ for envName, v in parameter.envMappings {
 					name: envName
 					valueFrom: {
 						secretKeyRef: {
@@ -26,5 +28,23 @@ patch: {
 
 parameter: {
 	// +usage=The mapping of environment variables to secret
-	envMappings: [string]: [string]: string
+	envMappings?: [string]: {
+		secret: string
+		key?:   string
+	}
+	// +usage=The secrets bound as environment variables or mounted files
+	secrets?: [...{
+		// +usage=The name of the secret
+		name: string
+		// +usage=The keys bound mapped to their names in the containers, an empty name keeps the key, all the keys are bound if empty
+		keys?: [string]: string
+		// +usage=Inject the keys as environment variables, it's true unless mountPath is set
+		env?: bool
+		// +usage=The prefix of the environment variables
+		envPrefix?: string
+		// +usage=The directory the keys are mounted in as files
+		mountPath?: string
+	}]
+	// +usage=The names of the containers injected, all the containers are injected if empty
+	containers?: [...string]
 }
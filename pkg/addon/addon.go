@@ -0,0 +1,269 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package addon discovers addons from catalogs, resolves the dependencies between them and renders them as
+// applications.
+//
+// An addon is a directory containing the metadata file metadata.yaml, the definitions in the definitions directory,
+// and the auxiliary resources in the resources directory, e.g., the CRDs and the operators the definitions rely on.
+package addon
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+)
+
+const (
+	// MetadataFile is the file of the metadata at the root of an addon
+	MetadataFile = "metadata.yaml"
+	// DefinitionsDir is the directory of the definitions of an addon
+	DefinitionsDir = "definitions"
+	// ResourcesDir is the directory of the auxiliary resources of an addon
+	ResourcesDir = "resources"
+	// ConfigMediaType is the media type of the config of the addon artifacts in OCI catalogs, i.e., the metadata in
+	// JSON
+	ConfigMediaType = "application/vnd.oam.dev.addon.config.v1+json"
+	// ContentMediaType is the media type of the layer of the addon artifacts in OCI catalogs, i.e., the gzipped
+	// tarball of the directory of the addon
+	ContentMediaType = "application/vnd.oam.dev.addon.content.v1.tar+gzip"
+)
+
+var (
+	// MaxSize is the maximum size in bytes of the files of an addon or a catalog fetched
+	MaxSize int64 = 16 << 20
+	// MaxGitTransferSize is the maximum size in bytes of the data transferred to clone the repository of a catalog
+	MaxGitTransferSize int64 = 64 << 20
+)
+
+// Metadata describes an addon
+type Metadata struct {
+	// Name is the name of the addon, it's a DNS label
+	Name string `json:"name"`
+	// Version is the semantic version of the addon, e.g., 1.2.0
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Dependencies are the addons which must be enabled before the addon
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is an addon the other addon depends on
+type Dependency struct {
+	Name string `json:"name"`
+	// Version is the semantic version constraint of the addon, e.g., ^1.2, any version satisfies it if it's empty
+	Version string `json:"version,omitempty"`
+}
+
+// Addon is the content of a version of an addon
+type Addon struct {
+	Metadata
+	Definitions []*unstructured.Unstructured
+	Resources   []*unstructured.Unstructured
+}
+
+// Validate checks the name, the version and the dependencies of the addon
+func (m Metadata) Validate() error {
+	// the name is part of the name of the application rendered
+	if errs := validation.IsDNS1123Label(m.Name); len(errs) != 0 {
+		return errors.Errorf("invalid addon name %q: %s", m.Name, strings.Join(errs, ", "))
+	}
+	if _, err := semver.StrictNewVersion(m.Version); err != nil {
+		return errors.Wrapf(err, "invalid version %q of addon %s", m.Version, m.Name)
+	}
+	for _, d := range m.Dependencies {
+		if len(d.Name) == 0 {
+			return errors.Errorf("dependency of addon %s must have a name", m.Name)
+		}
+		if d.Name == m.Name {
+			return errors.Errorf("addon %s cannot depend on itself", m.Name)
+		}
+		if len(d.Version) == 0 {
+			continue
+		}
+		if _, err := semver.NewConstraint(d.Version); err != nil {
+			return errors.Wrapf(err, "invalid version %q of dependency %s", d.Version, d.Name)
+		}
+	}
+	return nil
+}
+
+// Decode decodes the addon from its files keyed by the paths relative to the directory of the addon, the files
+// other than the metadata, the definitions and the resources are ignored
+func Decode(files map[string][]byte) (*Addon, error) {
+	data, ok := files[MetadataFile]
+	if !ok {
+		return nil, errors.Errorf("addon has no %s", MetadataFile)
+	}
+	a := &Addon{}
+	if err := yaml.Unmarshal(data, &a.Metadata); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode %s of addon", MetadataFile)
+	}
+	if err := a.Metadata.Validate(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := map[string]bool{}
+	for _, name := range names {
+		var objs *[]*unstructured.Unstructured
+		switch strings.SplitN(name, "/", 2)[0] {
+		case DefinitionsDir:
+			objs = &a.Definitions
+		case ResourcesDir:
+			objs = &a.Resources
+		default:
+			continue
+		}
+		decoded, err := kube.DecodeManifest(files[name])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "file %s of addon %s", name, a.Name)
+		}
+		for _, o := range decoded {
+			key := componentName(o)
+			if seen[key] {
+				return nil, errors.Errorf("%s %s is duplicated in addon %s", o.GetKind(), o.GetName(), a.Name)
+			}
+			seen[key] = true
+		}
+		*objs = append(*objs, decoded...)
+	}
+	if len(a.Definitions) == 0 && len(a.Resources) == 0 {
+		return nil, errors.Errorf("addon %s has neither definitions nor resources", a.Name)
+	}
+	return a, nil
+}
+
+// Unpack decodes the addon from the gzipped tarball of its directory
+func Unpack(data []byte) (*Addon, error) {
+	files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(files)
+}
+
+// Pack packs the addon into the gzipped tarball of its directory, the definitions and the resources are packed into a
+// file each to keep their order
+func Pack(a *Addon) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	metadata, err := yaml.Marshal(a.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(MetadataFile, metadata); err != nil {
+		return nil, err
+	}
+	// the files are packed in a fixed order, so the same addon is always packed into the same tarball
+	for _, dir := range []string{DefinitionsDir, ResourcesDir} {
+		objs := a.Definitions
+		if dir == ResourcesDir {
+			objs = a.Resources
+		}
+		if len(objs) == 0 {
+			continue
+		}
+		var docs [][]byte
+		for _, o := range objs {
+			data, err := yaml.Marshal(o.Object)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, data)
+		}
+		if err := add(path.Join(dir, dir+".yaml"), bytes.Join(docs, []byte("---\n"))); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readArchive reads the YAML and JSON files in the gzipped tarball keyed by their paths
+func readArchive(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decompress archive")
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read archive")
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if !isManifest(name) {
+			continue
+		}
+		total += hdr.Size
+		if total > MaxSize {
+			return nil, fmt.Errorf("archive exceeds the size limit of %d bytes", MaxSize)
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", name)
+		}
+		files[name] = content
+	}
+}
+
+// isManifest returns whether the file is a YAML or JSON file, the other files of addons are ignored
+func isManifest(name string) bool {
+	switch path.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	testMetadata = `name: ingress
+version: 1.2.0
+description: Ingress controller and the ingress trait
+dependencies:
+- name: cert-manager
+  version: ^1.0
+`
+	testDefinition = `apiVersion: core.oam.dev/v1beta1
+kind: TraitDefinition
+metadata:
+  name: ingress-nginx
+spec:
+  schematic:
+    cue:
+      template: |
+        parameter: host: string
+`
+	testResources = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: ingress-nginx
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: ingressclasses.example.com
+`
+)
+
+func testFiles() map[string]string {
+	return map[string]string{
+		MetadataFile:                     testMetadata,
+		"README.md":                      "# ingress",
+		DefinitionsDir + "/ingress.yaml": testDefinition,
+		ResourcesDir + "/setup.yaml":     testResources,
+	}
+}
+
+// tarball returns the gzipped tarball of the files under the directory, the files are at the root if it's empty
+func tarball(t *testing.T, dir string, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		content, p := files[name], name
+		if len(dir) != 0 {
+			p = dir + "/" + name
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: p, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestUnpack(t *testing.T) {
+	a, err := Unpack(tarball(t, "", testFiles()))
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{
+		Name:         "ingress",
+		Version:      "1.2.0",
+		Description:  "Ingress controller and the ingress trait",
+		Dependencies: []Dependency{{Name: "cert-manager", Version: "^1.0"}},
+	}, a.Metadata)
+	require.Len(t, a.Definitions, 1)
+	assert.Equal(t, "ingress-nginx", a.Definitions[0].GetName())
+	require.Len(t, a.Resources, 2)
+	assert.Equal(t, "Namespace", a.Resources[0].GetKind())
+	assert.Equal(t, "CustomResourceDefinition", a.Resources[1].GetKind())
+
+	_, err = Unpack([]byte("not an addon"))
+	assert.Error(t, err)
+}
+
+func TestDecodeInvalidAddon(t *testing.T) {
+	cases := map[string]struct {
+		metadata string
+		files    map[string]string
+		reason   string
+	}{
+		"no metadata": {
+			files:  map[string]string{MetadataFile: ""},
+			reason: "addon has no metadata.yaml",
+		},
+		"invalid name": {
+			metadata: "name: Ingress\nversion: 1.0.0",
+			reason:   `invalid addon name "Ingress"`,
+		},
+		"invalid version": {
+			metadata: "name: ingress\nversion: v1",
+			reason:   `invalid version "v1" of addon ingress`,
+		},
+		"depend on itself": {
+			metadata: "name: ingress\nversion: 1.0.0\ndependencies:\n- name: ingress",
+			reason:   "addon ingress cannot depend on itself",
+		},
+		"invalid dependency version": {
+			metadata: "name: ingress\nversion: 1.0.0\ndependencies:\n- name: cert-manager\n  version: one",
+			reason:   `invalid version "one" of dependency cert-manager`,
+		},
+		"duplicated object": {
+			metadata: "name: ingress\nversion: 1.0.0",
+			files:    map[string]string{ResourcesDir + "/more.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ingress-nginx"},
+			reason:   "Namespace ingress-nginx is duplicated in addon ingress",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			files := map[string][]byte{}
+			for p, content := range testFiles() {
+				files[p] = []byte(content)
+			}
+			if len(c.metadata) != 0 {
+				files[MetadataFile] = []byte(c.metadata)
+			}
+			for p, content := range c.files {
+				if len(content) == 0 {
+					delete(files, p)
+					continue
+				}
+				files[p] = []byte(content)
+			}
+			_, err := Decode(files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.reason)
+		})
+	}
+
+	_, err := Decode(map[string][]byte{MetadataFile: []byte("name: empty\nversion: 1.0.0")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "addon empty has neither definitions nor resources")
+}
+
+func TestRenderApplication(t *testing.T) {
+	a, err := Unpack(tarball(t, "", testFiles()))
+	require.NoError(t, err)
+	app, err := RenderApplication(a, "vela-system")
+	require.NoError(t, err)
+	assert.Equal(t, "addon-ingress", app.Name)
+	assert.Equal(t, "vela-system", app.Namespace)
+	assert.Equal(t, "ingress", app.Labels[oam.LabelAddonName])
+	assert.Equal(t, "1.2.0", app.Annotations[oam.AnnotationAddonVersion])
+
+	var names []string
+	for _, comp := range app.Spec.Components {
+		assert.Equal(t, RawComponentType, comp.Type)
+		names = append(names, comp.Name)
+	}
+	// the resources are rendered before the definitions
+	assert.Equal(t, []string{
+		"namespace-ingress-nginx",
+		"customresourcedefinition-ingressclasses-example-com",
+		"traitdefinition-ingress-nginx",
+	}, names)
+
+	def := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(app.Spec.Components[2].Properties.Raw, &def))
+	assert.Equal(t, "vela-system", def["metadata"].(map[string]interface{})["namespace"])
+	// the definitions of the addon are not modified
+	assert.Empty(t, a.Definitions[0].GetNamespace())
+	ns := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(app.Spec.Components[0].Properties.Raw, &ns))
+	assert.NotContains(t, ns["metadata"], "namespace")
+}
@@ -0,0 +1,450 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+// IndexFile is the index of the addons served at the base URL of the http catalogs
+const IndexFile = "index.yaml"
+
+// Catalog discovers addons and their versions
+type Catalog interface {
+	// List lists the names of the addons in the catalog
+	List(ctx context.Context) ([]string, error)
+	// Versions lists the versions of the addon, it fails if the addon is not in the catalog
+	Versions(ctx context.Context, name string) ([]string, error)
+	// Get fetches the version of the addon
+	Get(ctx context.Context, name, version string) (*Addon, error)
+}
+
+// NewCatalog returns the catalog of the spec, the credentials are used to access the catalog if they're not nil
+func NewCatalog(spec v1beta1.AddonCatalog, cred *oci.Credentials) (Catalog, error) {
+	if len(spec.URL) == 0 {
+		return nil, errors.New("url of the addon catalog is empty")
+	}
+	switch spec.Type {
+	case v1beta1.AddonCatalogGit:
+		ref := spec.Ref
+		if len(ref) == 0 {
+			ref = kube.DefaultGitRef
+		}
+		dir := strings.Trim(spec.Path, "/")
+		opts := remote.GitOptions{
+			Repo:            spec.URL,
+			Ref:             ref,
+			Dir:             dir,
+			Accept:          isManifest,
+			MaxTransferSize: MaxGitTransferSize,
+			MaxSize:         MaxSize,
+		}
+		if cred != nil {
+			opts.Auth = &remote.GitAuth{Username: cred.Username, Password: cred.Password}
+		}
+		return &gitCatalog{opts: opts, dir: dir, read: remote.ReadGit}, nil
+	case v1beta1.AddonCatalogOCI:
+		c := &oci.Client{PlainHTTP: spec.PlainHTTP, HTTPClient: remote.HTTPClient()}
+		if cred != nil {
+			c.Username, c.Password = cred.Username, cred.Password
+		}
+		return &ociCatalog{client: c, repository: strings.TrimSuffix(strings.TrimPrefix(spec.URL, oci.SchemePrefix), "/")}, nil
+	case v1beta1.AddonCatalogHTTP:
+		base, err := url.Parse(strings.TrimSuffix(spec.URL, "/") + "/")
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid url of the addon catalog %q", spec.URL)
+		}
+		return &httpCatalog{base: base, cred: cred}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q of the addon catalog", spec.Type)
+	}
+}
+
+// NotFoundError means the addon or the version of it is not in the catalog
+type NotFoundError struct {
+	Name    string
+	Version string
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Version) == 0 {
+		return fmt.Sprintf("addon %s is not found in the catalog", e.Name)
+	}
+	return fmt.Sprintf("version %s of addon %s is not found in the catalog", e.Version, e.Name)
+}
+
+// IsNotFound returns whether the error means the addon or the version of it is not in the catalog
+func IsNotFound(err error) bool {
+	var e *NotFoundError
+	return errors.As(err, &e)
+}
+
+// checkAddon checks the addon fetched is the one asked for
+func checkAddon(a *Addon, name, version string) error {
+	if a.Name != name {
+		return fmt.Errorf("addon %s is fetched while addon %s is expected", a.Name, name)
+	}
+	if a.Version != version {
+		return fmt.Errorf("version %s of addon %s is fetched while version %s is expected", a.Version, name, version)
+	}
+	return nil
+}
+
+// Index is the index of the http catalogs
+type Index struct {
+	Addons []IndexEntry `json:"addons"`
+}
+
+// IndexEntry lists the versions of an addon in the index
+type IndexEntry struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Versions    []IndexVersion `json:"versions"`
+}
+
+// IndexVersion locates the gzipped tarball of a version of an addon
+type IndexVersion struct {
+	Version string `json:"version"`
+	// URL is the URL of the tarball, it's relative to the base URL of the catalog unless it's absolute
+	URL string `json:"url"`
+	// Digest is the sha256 digest of the tarball, e.g., sha256:..., the tarball is not verified if it's empty
+	Digest string `json:"digest,omitempty"`
+}
+
+// httpCatalog discovers the addons in the index served by an HTTP server, or in the index of a local mirror directory
+// if the base URL is a file:// URL
+type httpCatalog struct {
+	base   *url.URL
+	cred   *oci.Credentials
+	client *http.Client
+}
+
+func (c *httpCatalog) List(ctx context.Context) ([]string, error) {
+	index, err := c.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(index.Addons))
+	for _, e := range index.Addons {
+		names = append(names, e.Name)
+	}
+	return names, nil
+}
+
+func (c *httpCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	e, err := c.entry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(e.Versions))
+	for _, v := range e.Versions {
+		versions = append(versions, v.Version)
+	}
+	return versions, nil
+}
+
+func (c *httpCatalog) Get(ctx context.Context, name, version string) (*Addon, error) {
+	e, err := c.entry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range e.Versions {
+		if v.Version != version {
+			continue
+		}
+		u, err := c.base.Parse(v.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid url of version %s of addon %s", version, name)
+		}
+		data, err := c.fetch(ctx, u)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot fetch version %s of addon %s", version, name)
+		}
+		if len(v.Digest) != 0 && digestOf(data) != v.Digest {
+			return nil, fmt.Errorf("digest of version %s of addon %s mismatches, expect %s", version, name, v.Digest)
+		}
+		a, err := Unpack(data)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "version %s of addon %s", version, name)
+		}
+		return a, checkAddon(a, name, version)
+	}
+	return nil, &NotFoundError{Name: name, Version: version}
+}
+
+func (c *httpCatalog) entry(ctx context.Context, name string) (*IndexEntry, error) {
+	index, err := c.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range index.Addons {
+		if index.Addons[i].Name == name {
+			return &index.Addons[i], nil
+		}
+	}
+	return nil, &NotFoundError{Name: name}
+}
+
+func (c *httpCatalog) index(ctx context.Context) (*Index, error) {
+	u, err := c.base.Parse(IndexFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.fetch(ctx, u)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot fetch the index of the addon catalog")
+	}
+	index := &Index{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, errors.Wrap(err, "cannot decode the index of the addon catalog")
+	}
+	return index, nil
+}
+
+func (c *httpCatalog) fetch(ctx context.Context, u *url.URL) ([]byte, error) {
+	if u.Scheme == "file" {
+		return readFile(u.Path)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cred != nil {
+		req.SetBasicAuth(c.cred.Username, c.cred.Password)
+	}
+	return doFetch(c.client, req)
+}
+
+// ociCatalog discovers the addons in the repositories under a prefix of an OCI registry, i.e., <prefix>/<name>, the
+// tags of the repositories are the versions
+type ociCatalog struct {
+	client     *oci.Client
+	repository string
+}
+
+func (c *ociCatalog) List(context.Context) ([]string, error) {
+	return nil, errors.New("listing addons is not supported by oci catalogs")
+}
+
+func (c *ociCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	ref, err := c.reference(name, "")
+	if err != nil {
+		return nil, err
+	}
+	tags, err := c.client.ListTags(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	// the tags other than versions are ignored, e.g., latest
+	var versions []string
+	for _, t := range tags {
+		if _, err := semver.StrictNewVersion(t); err == nil {
+			versions = append(versions, t)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, &NotFoundError{Name: name}
+	}
+	return versions, nil
+}
+
+func (c *ociCatalog) Get(ctx context.Context, name, version string) (*Addon, error) {
+	ref, err := c.reference(name, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.PullLayer(ctx, ref, ContentMediaType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot pull version %s of addon %s", version, name)
+	}
+	a, err := Unpack(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "version %s of addon %s", version, name)
+	}
+	return a, checkAddon(a, name, version)
+}
+
+func (c *ociCatalog) reference(name, version string) (oci.Reference, error) {
+	ref := c.repository + "/" + name
+	if len(version) != 0 {
+		ref += ":" + version
+	}
+	return oci.ParseReference(ref)
+}
+
+// gitCatalog discovers the addons in the directories of a Git repository, i.e., <path>/<name>, each addon has a
+// single version at the ref of the repository
+type gitCatalog struct {
+	opts remote.GitOptions
+	dir  string
+	// read clones the repository and reads the files of the options
+	read func(ctx context.Context, o remote.GitOptions) (map[string][]byte, error)
+
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+func (c *gitCatalog) List(ctx context.Context) ([]string, error) {
+	files, err := c.repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for p := range files {
+		if dir, file := path.Split(p); file == MetadataFile && path.Dir(path.Clean(dir)) == c.root() {
+			names = append(names, path.Base(dir))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (c *gitCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	files, err := c.files(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	m := Metadata{}
+	if err := yaml.Unmarshal(files[MetadataFile], &m); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode %s of addon %s", MetadataFile, name)
+	}
+	return []string{m.Version}, nil
+}
+
+func (c *gitCatalog) Get(ctx context.Context, name, version string) (*Addon, error) {
+	files, err := c.files(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := Decode(files)
+	if err != nil {
+		return nil, err
+	}
+	if a.Version != version {
+		return nil, &NotFoundError{Name: name, Version: version}
+	}
+	return a, checkAddon(a, name, version)
+}
+
+// files returns the files of the addon keyed by the paths relative to the directory of the addon
+func (c *gitCatalog) files(ctx context.Context, name string) (map[string][]byte, error) {
+	all, err := c.repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := path.Join(c.root(), name) + "/"
+	files := map[string][]byte{}
+	for p, data := range all {
+		if strings.HasPrefix(p, prefix) {
+			files[strings.TrimPrefix(p, prefix)] = data
+		}
+	}
+	if _, ok := files[MetadataFile]; !ok {
+		return nil, &NotFoundError{Name: name}
+	}
+	return files, nil
+}
+
+func (c *gitCatalog) root() string {
+	if len(c.dir) == 0 {
+		return "."
+	}
+	return c.dir
+}
+
+// repository clones the repository once, the catalogs are created per reconciliation so the cache lives as long
+func (c *gitCatalog) repository(ctx context.Context) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache != nil {
+		return c.cache, nil
+	}
+	files, err := c.read(ctx, c.opts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot clone the repository of the addon catalog")
+	}
+	c.cache = files
+	return files, nil
+}
+
+func doFetch(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s of %s", resp.Status, req.URL)
+	}
+	// read one more byte to detect the response exceeding the limit
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxSize {
+		return nil, fmt.Errorf("%s exceeds the size limit of %d bytes", req.URL, MaxSize)
+	}
+	return data, nil
+}
+
+func readFile(name string) ([]byte, error) {
+	f, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer f.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(f, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxSize {
+		return nil, fmt.Errorf("%s exceeds the size limit of %d bytes", name, MaxSize)
+	}
+	return data, nil
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
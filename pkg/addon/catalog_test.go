@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+func TestNewCatalog(t *testing.T) {
+	c, err := NewCatalog(v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogGit, URL: "https://git.example.com/org/catalog.git", Path: "/addons/"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://git.example.com/org/catalog.git", c.(*gitCatalog).opts.Repo)
+	assert.Equal(t, "master", c.(*gitCatalog).opts.Ref)
+	assert.Equal(t, "addons", c.(*gitCatalog).dir)
+	assert.Nil(t, c.(*gitCatalog).opts.Auth)
+
+	c, err = NewCatalog(v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogGit, URL: "https://git.example.com/org/catalog.git", Ref: "v1.0.0"}, &oci.Credentials{Username: "user", Password: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", c.(*gitCatalog).opts.Ref)
+	assert.Equal(t, "token", c.(*gitCatalog).opts.Auth.Password)
+
+	c, err = NewCatalog(v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogOCI, URL: "oci://ghcr.io/org/addons/"}, &oci.Credentials{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+	assert.Equal(t, "ghcr.io/org/addons", c.(*ociCatalog).repository)
+	assert.Equal(t, "user", c.(*ociCatalog).client.Username)
+
+	_, err = NewCatalog(v1beta1.AddonCatalog{Type: "svn", URL: "https://example.com"}, nil)
+	assert.Error(t, err)
+	_, err = NewCatalog(v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogHTTP}, nil)
+	assert.Error(t, err)
+}
+
+func TestHTTPCatalog(t *testing.T) {
+	content := tarball(t, "", testFiles())
+	index := fmt.Sprintf(`addons:
+- name: ingress
+  versions:
+  - version: 1.2.0
+    url: ingress/ingress-1.2.0.tgz
+    digest: %s
+  - version: 1.3.0
+    url: ingress/ingress-1.3.0.tgz
+    digest: sha256:0000000000000000000000000000000000000000000000000000000000000000
+`, digestOf(content))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/catalog/index.yaml":
+			_, _ = w.Write([]byte(index))
+		case "/catalog/ingress/ingress-1.2.0.tgz", "/catalog/ingress/ingress-1.3.0.tgz":
+			_, _ = w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	spec := v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogHTTP, URL: srv.URL + "/catalog"}
+	c, err := NewCatalog(spec, &oci.Credentials{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+	names, err := c.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ingress"}, names)
+	versions, err := c.Versions(ctx, "ingress")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0", "1.3.0"}, versions)
+	a, err := c.Get(ctx, "ingress", "1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, "ingress", a.Name)
+	assert.Len(t, a.Resources, 2)
+
+	_, err = c.Get(ctx, "ingress", "1.3.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "digest of version 1.3.0 of addon ingress mismatches")
+	_, err = c.Get(ctx, "ingress", "1.4.0")
+	assert.True(t, IsNotFound(err))
+	_, err = c.Versions(ctx, "prometheus")
+	assert.True(t, IsNotFound(err))
+
+	c, err = NewCatalog(spec, nil)
+	require.NoError(t, err)
+	_, err = c.List(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestGitCatalog(t *testing.T) {
+	files := map[string][]byte{}
+	for p, content := range testFiles() {
+		files["addons/ingress/"+p] = []byte(content)
+	}
+	// the name in the metadata mismatches the directory
+	files["addons/broken/"+MetadataFile] = []byte("name: other\nversion: 1.0.0")
+	files["addons/broken/"+ResourcesDir+"/ns.yaml"] = []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: broken")
+	clones := 0
+	read := func(ctx context.Context, o remote.GitOptions) (map[string][]byte, error) {
+		clones++
+		return files, nil
+	}
+	ctx := context.Background()
+
+	c := &gitCatalog{opts: remote.GitOptions{Repo: "https://git.example.com/org/catalog.git", Dir: "addons"}, dir: "addons", read: read}
+	names, err := c.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken", "ingress"}, names)
+	versions, err := c.Versions(ctx, "ingress")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0"}, versions)
+	a, err := c.Get(ctx, "ingress", "1.2.0")
+	require.NoError(t, err)
+	assert.Len(t, a.Definitions, 1)
+	// the repository is cloned once
+	assert.Equal(t, 1, clones)
+
+	_, err = c.Get(ctx, "ingress", "1.1.0")
+	assert.True(t, IsNotFound(err))
+	_, err = c.Versions(ctx, "prometheus")
+	assert.True(t, IsNotFound(err))
+	_, err = c.Get(ctx, "broken", "1.0.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "addon other is fetched while addon broken is expected")
+}
+
+func TestOCICatalog(t *testing.T) {
+	content := tarball(t, "", testFiles())
+	manifest, err := json.Marshal(oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.ManifestMediaType,
+		Layers:        []oci.Descriptor{{MediaType: ContentMediaType, Digest: digestOf(content), Size: int64(len(content))}},
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/addons/ingress/tags/list":
+			_, _ = w.Write([]byte(`{"tags":["latest","1.1.0","1.2.0"]}`))
+		case "/v2/addons/ingress/manifests/1.2.0":
+			w.Header().Set("Content-Type", oci.ManifestMediaType)
+			_, _ = w.Write(manifest)
+		case "/v2/addons/ingress/blobs/" + digestOf(content):
+			_, _ = w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	c := &ociCatalog{client: &oci.Client{PlainHTTP: true}, repository: u.Host + "/addons"}
+	versions, err := c.Versions(ctx, "ingress")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.1.0", "1.2.0"}, versions)
+	a, err := c.Get(ctx, "ingress", "1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", a.Version)
+
+	_, err = c.Get(ctx, "ingress", "1.1.0")
+	assert.Error(t, err)
+	_, err = c.List(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+}
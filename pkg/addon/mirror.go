@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+// Collect resolves the addons and the addons they depend on transitively in the catalog, i.e., everything needed
+// to enable them, for the mirrors of air-gapped environments. The addons are given as <name> or
+// <name>@<constraint>, e.g., ingress@^1.2, the latest versions are resolved if the constraints are not given.
+func Collect(ctx context.Context, catalog Catalog, addons []string) ([]*Addon, error) {
+	var result []*Addon
+	seen := map[string]bool{}
+	for _, a := range addons {
+		name, constraint := a, ""
+		if i := strings.Index(a, "@"); i >= 0 {
+			name, constraint = a[:i], a[i+1:]
+		}
+		resolved, err := ResolveDependencies(ctx, catalog, name, constraint)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resolved {
+			key := r.Name + "@" + r.Version
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, r.Addon)
+			}
+		}
+	}
+	return result, nil
+}
+
+// tarballPath returns the path of the tarball of the version of the addon relative to the mirror directory
+func tarballPath(name, version string) string {
+	return path.Join(name, fmt.Sprintf("%s-%s.tgz", name, version))
+}
+
+// WriteMirror writes the addons into the local mirror directory, which is laid out as an http catalog, i.e., the
+// tarballs of the addons and the index.yaml listing them with their digests. The index existing in the directory is
+// merged, so a mirror can be built up by multiple runs. The mirror is read by the http catalog of its file:// URL,
+// or served by any HTTP server.
+func WriteMirror(dir string, addons []*Addon) error {
+	index := &Index{}
+	data, err := ioutil.ReadFile(filepath.Clean(filepath.Join(dir, IndexFile)))
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, index); err != nil {
+			return errors.Wrapf(err, "cannot decode the index of the mirror %s", dir)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	for _, a := range addons {
+		content, err := Pack(a)
+		if err != nil {
+			return errors.Wrapf(err, "cannot pack version %s of addon %s", a.Version, a.Name)
+		}
+		p := tarballPath(a.Name, a.Version)
+		if err := os.MkdirAll(filepath.Join(dir, a.Name), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(p)), content, 0600); err != nil {
+			return err
+		}
+		index.add(a, IndexVersion{Version: a.Version, URL: p, Digest: digestOf(content)})
+	}
+	index.sort()
+	data, err = yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, IndexFile), data, 0600)
+}
+
+// add adds or replaces the version of the addon in the index
+func (index *Index) add(a *Addon, v IndexVersion) {
+	for i := range index.Addons {
+		e := &index.Addons[i]
+		if e.Name != a.Name {
+			continue
+		}
+		e.Description = a.Description
+		for j := range e.Versions {
+			if e.Versions[j].Version == v.Version {
+				e.Versions[j] = v
+				return
+			}
+		}
+		e.Versions = append(e.Versions, v)
+		return
+	}
+	index.Addons = append(index.Addons, IndexEntry{Name: a.Name, Description: a.Description, Versions: []IndexVersion{v}})
+}
+
+// sort sorts the addons by name and their versions in ascending order
+func (index *Index) sort() {
+	sort.Slice(index.Addons, func(i, j int) bool { return index.Addons[i].Name < index.Addons[j].Name })
+	for _, e := range index.Addons {
+		versions := e.Versions
+		sort.Slice(versions, func(i, j int) bool {
+			vi, erri := semver.NewVersion(versions[i].Version)
+			vj, errj := semver.NewVersion(versions[j].Version)
+			if erri != nil || errj != nil {
+				return versions[i].Version < versions[j].Version
+			}
+			return vi.LessThan(vj)
+		})
+	}
+}
+
+// Push pushes the addon to the OCI registry with the tag of the reference, the metadata is pushed as the config of
+// the artifact. It returns the digest of the artifact.
+func Push(ctx context.Context, c *oci.Client, ref oci.Reference, a *Addon) (string, error) {
+	content, err := Pack(a)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot pack addon %s", a.Name)
+	}
+	config, err := json.Marshal(a.Metadata)
+	if err != nil {
+		return "", err
+	}
+	return c.PushArtifact(ctx, ref,
+		oci.Blob{MediaType: ConfigMediaType, Data: config},
+		oci.Blob{MediaType: ContentMediaType, Data: content})
+}
+
+// PushMirror pushes the addons to the repositories under the prefix of the OCI registry, e.g., a registry inside the
+// air-gapped environment, which is read by the oci catalog of the prefix then
+func PushMirror(ctx context.Context, c *oci.Client, prefix string, addons []*Addon) error {
+	for _, a := range addons {
+		ref, err := oci.ParseReference(strings.TrimSuffix(strings.TrimPrefix(prefix, oci.SchemePrefix), "/") + "/" + a.Name + ":" + a.Version)
+		if err != nil {
+			return err
+		}
+		if _, err := Push(ctx, c, ref, a); err != nil {
+			return errors.WithMessagef(err, "cannot push version %s of addon %s", a.Version, a.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	ingress, err := Unpack(tarball(t, "", testFiles()))
+	require.NoError(t, err)
+	ns := &unstructured.Unstructured{}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName("cert-manager")
+	catalog := memCatalog{}.add("cert-manager", "1.0.0").add("cert-manager", "1.1.0").add("cert-manager", "2.0.0")
+	for _, a := range catalog["cert-manager"] {
+		a.Resources = []*unstructured.Unstructured{ns}
+	}
+	catalog["ingress"] = map[string]*Addon{"1.2.0": ingress}
+
+	keys := func(addons []*Addon) []string {
+		var result []string
+		for _, a := range addons {
+			result = append(result, a.Name+"@"+a.Version)
+		}
+		return result
+	}
+	addons, err := Collect(ctx, catalog, []string{"ingress", "cert-manager@~1.0", "cert-manager@^1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert-manager@1.1.0", "ingress@1.2.0", "cert-manager@1.0.0"}, keys(addons))
+	_, err = Collect(ctx, catalog, []string{"prometheus"})
+	assert.True(t, IsNotFound(err))
+
+	dir, err := ioutil.TempDir("", "addon-mirror")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, WriteMirror(dir, addons[:2]))
+	// the mirror is built up by multiple runs
+	require.NoError(t, WriteMirror(dir, addons[2:]))
+
+	mirror, err := NewCatalog(v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogHTTP, URL: "file://" + dir}, nil)
+	require.NoError(t, err)
+	names, err := mirror.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert-manager", "ingress"}, names)
+	versions, err := mirror.Versions(ctx, "cert-manager")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+	a, err := mirror.Get(ctx, "ingress", "1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, ingress.Metadata, a.Metadata)
+	assert.Equal(t, ingress.Definitions, a.Definitions)
+	assert.Equal(t, ingress.Resources, a.Resources)
+
+	// the tarballs are verified against the digests in the index
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cert-manager", "cert-manager-1.0.0.tgz"), []byte("tampered"), 0600))
+	_, err = mirror.Get(ctx, "cert-manager", "1.0.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "digest of version 1.0.0 of addon cert-manager mismatches")
+}
+
+func TestPackIsReproducible(t *testing.T) {
+	a, err := Unpack(tarball(t, "", testFiles()))
+	require.NoError(t, err)
+	first, err := Pack(a)
+	require.NoError(t, err)
+	second, err := Pack(a)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	unpacked, err := Unpack(first)
+	require.NoError(t, err)
+	assert.Equal(t, a, unpacked)
+}
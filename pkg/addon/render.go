@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"encoding/json"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// RawComponentType is the type of the components deploying the objects in their properties as they are
+const RawComponentType = "raw"
+
+// ApplicationName returns the name of the application rendered from the addon
+func ApplicationName(name string) string {
+	return "addon-" + name
+}
+
+// RenderApplication renders the addon as an application in the namespace, each definition or auxiliary resource
+// is a raw component of the application. The auxiliary resources are rendered before the definitions since the
+// definitions may rely on them, e.g., the CRDs of the workloads. The definitions without namespace are put into
+// the namespace of the application.
+func RenderApplication(a *Addon, namespace string) (*v1beta1.Application, error) {
+	app := &v1beta1.Application{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1beta1.SchemeGroupVersion.String(),
+			Kind:       v1beta1.ApplicationKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ApplicationName(a.Name),
+			Namespace:   namespace,
+			Labels:      map[string]string{oam.LabelAddonName: a.Name},
+			Annotations: map[string]string{oam.AnnotationAddonVersion: a.Version},
+		},
+	}
+	for _, o := range a.Resources {
+		comp, err := rawComponent(o)
+		if err != nil {
+			return nil, err
+		}
+		app.Spec.Components = append(app.Spec.Components, comp)
+	}
+	for _, o := range a.Definitions {
+		def := o.DeepCopy()
+		if len(def.GetNamespace()) == 0 {
+			def.SetNamespace(namespace)
+		}
+		comp, err := rawComponent(def)
+		if err != nil {
+			return nil, err
+		}
+		app.Spec.Components = append(app.Spec.Components, comp)
+	}
+	return app, nil
+}
+
+func rawComponent(o *unstructured.Unstructured) (v1beta1.ApplicationComponent, error) {
+	raw, err := json.Marshal(o.Object)
+	if err != nil {
+		return v1beta1.ApplicationComponent{}, err
+	}
+	return v1beta1.ApplicationComponent{
+		Name:       componentName(o),
+		Type:       RawComponentType,
+		Properties: runtime.RawExtension{Raw: raw},
+	}, nil
+}
+
+// componentName returns the name of the component of the object, e.g., customresourcedefinition-foos-example-com
+func componentName(o *unstructured.Unstructured) string {
+	return strings.ToLower(o.GetKind()) + "-" + strings.ReplaceAll(o.GetName(), ".", "-")
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+// Resolved is an addon resolved to a version
+type Resolved struct {
+	*Addon
+	// Constraints are the version constraints the version is resolved against, i.e., the one asked for and the ones
+	// of the addons depending on it
+	Constraints []string
+}
+
+// ResolveVersion returns the highest version satisfying the constraint, the versions not in the semantic format
+// are ignored. Any release version satisfies the empty constraint, prereleases are resolved only if the constraint
+// asks for them, e.g., ">=2.1.0-0".
+func ResolveVersion(versions []string, constraint string) (string, error) {
+	var c *semver.Constraints
+	if len(constraint) != 0 {
+		var err error
+		if c, err = semver.NewConstraint(constraint); err != nil {
+			return "", errors.Wrapf(err, "invalid version constraint %q", constraint)
+		}
+	}
+	var best *semver.Version
+	var resolved string
+	for _, v := range versions {
+		sv, err := semver.StrictNewVersion(v)
+		if err != nil {
+			continue
+		}
+		if c == nil && len(sv.Prerelease()) != 0 {
+			continue
+		}
+		if c != nil && !c.Check(sv) {
+			continue
+		}
+		if best == nil || sv.GreaterThan(best) {
+			best, resolved = sv, v
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no version satisfies %q", constraint)
+	}
+	return resolved, nil
+}
+
+// LatestVersion returns the highest version, it's empty if none of the versions is in the semantic format
+func LatestVersion(versions []string) string {
+	v, _ := ResolveVersion(versions, "")
+	return v
+}
+
+// Satisfies returns whether the version satisfies the constraint, any version satisfies the empty constraint
+func Satisfies(version, constraint string) bool {
+	if len(constraint) == 0 {
+		return true
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := semver.StrictNewVersion(version)
+	return err == nil && c.Check(v)
+}
+
+// ResolveDependencies resolves the addon and the addons it depends on transitively in the catalog. The addons are
+// returned in the order they should be enabled, i.e., an addon is after the ones it depends on, and the addon asked
+// for is the last one. It fails if the dependencies are circular or an addon is required with conflicting
+// version constraints.
+func ResolveDependencies(ctx context.Context, catalog Catalog, name, constraint string) ([]Resolved, error) {
+	r := &resolver{catalog: catalog, resolved: map[string]*Resolved{}}
+	if err := r.resolve(ctx, name, constraint, nil); err != nil {
+		return nil, err
+	}
+	result := make([]Resolved, 0, len(r.order))
+	for _, n := range r.order {
+		result = append(result, *r.resolved[n])
+	}
+	return result, nil
+}
+
+type resolver struct {
+	catalog  Catalog
+	resolved map[string]*Resolved
+	order    []string
+}
+
+// resolve resolves the addon after its dependencies in depth-first order, the chain is the addons transitively
+// depending on it to detect the cycles
+func (r *resolver) resolve(ctx context.Context, name, constraint string, chain []string) error {
+	for i, n := range chain {
+		if n == name {
+			return fmt.Errorf("circular dependency of addons: %s", strings.Join(append(chain[i:], name), " -> "))
+		}
+	}
+	if res, ok := r.resolved[name]; ok {
+		// the addon is resolved already, the version has to satisfy the constraint of the other addon too
+		if !Satisfies(res.Version, constraint) {
+			return fmt.Errorf("addon %s is required with conflicting versions %s", name, strings.Join(append(res.Constraints, constraint), ", "))
+		}
+		res.Constraints = append(res.Constraints, constraint)
+		return nil
+	}
+	versions, err := r.catalog.Versions(ctx, name)
+	if err != nil {
+		return err
+	}
+	version, err := ResolveVersion(versions, constraint)
+	if err != nil {
+		return errors.WithMessagef(err, "cannot resolve the version of addon %s", name)
+	}
+	a, err := r.catalog.Get(ctx, name, version)
+	if err != nil {
+		return err
+	}
+	chain = append(chain, name)
+	for _, d := range a.Dependencies {
+		if err := r.resolve(ctx, d.Name, d.Version, chain); err != nil {
+			return err
+		}
+	}
+	r.resolved[name] = &Resolved{Addon: a, Constraints: []string{constraint}}
+	r.order = append(r.order, name)
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memCatalog is the catalog of the addons in memory keyed by name and version
+type memCatalog map[string]map[string]*Addon
+
+func (c memCatalog) add(name, version string, deps ...Dependency) memCatalog {
+	if c[name] == nil {
+		c[name] = map[string]*Addon{}
+	}
+	c[name][version] = &Addon{Metadata: Metadata{Name: name, Version: version, Dependencies: deps}}
+	return c
+}
+
+func (c memCatalog) List(context.Context) ([]string, error) {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (c memCatalog) Versions(_ context.Context, name string) ([]string, error) {
+	if c[name] == nil {
+		return nil, &NotFoundError{Name: name}
+	}
+	var versions []string
+	for v := range c[name] {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (c memCatalog) Get(_ context.Context, name, version string) (*Addon, error) {
+	a, ok := c[name][version]
+	if !ok {
+		return nil, &NotFoundError{Name: name, Version: version}
+	}
+	return a, nil
+}
+
+func TestResolveVersion(t *testing.T) {
+	versions := []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0", "latest", "2.1.0-rc.1"}
+	v, err := ResolveVersion(versions, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", v)
+	v, err = ResolveVersion(versions, "^1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", v)
+	v, err = ResolveVersion(versions, "~1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", v)
+	v, err = ResolveVersion(versions, ">=2.1.0-0")
+	require.NoError(t, err)
+	assert.Equal(t, "2.1.0-rc.1", v)
+
+	_, err = ResolveVersion(versions, "^3")
+	assert.Error(t, err)
+	_, err = ResolveVersion(versions, "one")
+	assert.Error(t, err)
+
+	assert.Equal(t, "2.0.0", LatestVersion(versions))
+	assert.Empty(t, LatestVersion([]string{"latest"}))
+}
+
+func names(resolved []Resolved) []string {
+	var result []string
+	for _, r := range resolved {
+		result = append(result, r.Name+"@"+r.Version)
+	}
+	return result
+}
+
+func TestResolveDependencies(t *testing.T) {
+	ctx := context.Background()
+	catalog := memCatalog{}.
+		add("cert-manager", "1.0.0").
+		add("cert-manager", "1.4.0").
+		add("cert-manager", "2.0.0").
+		add("prometheus", "1.0.0", Dependency{Name: "cert-manager", Version: "^1.2"}).
+		add("ingress", "1.0.0", Dependency{Name: "prometheus"}, Dependency{Name: "cert-manager"}).
+		add("observability", "1.0.0", Dependency{Name: "prometheus"}, Dependency{Name: "cert-manager", Version: "^2"}).
+		add("a", "1.0.0", Dependency{Name: "b"}).
+		add("b", "1.0.0", Dependency{Name: "c"}).
+		add("c", "1.0.0", Dependency{Name: "a"}).
+		add("broken", "1.0.0", Dependency{Name: "missing"})
+
+	resolved, err := ResolveDependencies(ctx, catalog, "cert-manager", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert-manager@2.0.0"}, names(resolved))
+
+	resolved, err = ResolveDependencies(ctx, catalog, "prometheus", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert-manager@1.4.0", "prometheus@1.0.0"}, names(resolved))
+
+	// the version resolved first satisfies the constraints of the other addons depending on it
+	resolved, err = ResolveDependencies(ctx, catalog, "ingress", "^1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert-manager@1.4.0", "prometheus@1.0.0", "ingress@1.0.0"}, names(resolved))
+	assert.Equal(t, []string{"^1.2", ""}, resolved[0].Constraints)
+
+	_, err = ResolveDependencies(ctx, catalog, "observability", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "addon cert-manager is required with conflicting versions ^1.2, ^2")
+
+	_, err = ResolveDependencies(ctx, catalog, "a", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circular dependency of addons: a -> b -> c -> a")
+
+	_, err = ResolveDependencies(ctx, catalog, "broken", "")
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+
+	_, err = ResolveDependencies(ctx, catalog, "prometheus", "^2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot resolve the version of addon prometheus")
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package appfile
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/format"
@@ -37,18 +39,24 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile/gitsource"
 	"github.com/oam-dev/kubevela/pkg/appfile/helm"
+	kubesource "github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/appfile/kustomize"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/dsl/process"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 // constant error information
 const (
 	errInvalidValueType                                = "require %q type parameter value"
 	errTerraformConfigurationIsNotSet                  = "terraform configuration is not set"
+	errKubeTemplateIsNotSet                            = "neither template nor remote sources of Kube schematic is set"
 	errFailToConvertTerraformComponentProperties       = "failed to convert Terraform component properties"
 	errTerraformNameOfWriteConnectionSecretToRefNotSet = "the name of writeConnectionSecretToRef of terraform component is not set"
 )
@@ -71,6 +79,18 @@ type Workload struct {
 	// RequiredSecrets stores secret names which the workload needs from cloud resource component and its context
 	RequiredSecrets []process.RequiredSecrets
 	UserConfigs     []map[string]string
+	// DependsOn is the names of the workloads which must be healthy before this workload is dispatched
+	DependsOn []string
+	// BlueGreen is the built-in blue-green trait of the workload, it's nil if the trait is not attached
+	BlueGreen *BlueGreen
+	// ServiceBinding is the built-in service-binding trait of the workload, it's nil if the trait is not attached
+	ServiceBinding *ServiceBinding
+	// ValueSources are the Secrets and the Vault secrets the properties of the workload and its traits are resolved from
+	ValueSources []common.ValueSource
+	// HelmCredentials are the credentials of the OCI registry storing the chart of the Helm schematic
+	HelmCredentials *helm.Credentials
+	// GitCredentials are the credentials of the repository of the Git schematic
+	GitCredentials *gitsource.Credentials
 }
 
 // GetUserConfigName get user config from AppFile, it will contain config file in it.
@@ -104,6 +124,20 @@ func (wl *Workload) EvalHealth(ctx process.Context, client client.Client, namesp
 	return wl.engine.HealthCheck(ctx, client, namespace, wl.FullTemplate.Health)
 }
 
+// podSpecPath returns the path of the pod spec in the rendered workload, the podSpecPath declared in the definition
+// takes precedence over the known workload kinds
+func (wl *Workload) podSpecPath(obj *unstructured.Unstructured) []string {
+	if tmpl := wl.FullTemplate; tmpl != nil {
+		switch {
+		case tmpl.ComponentDefinition != nil && len(tmpl.ComponentDefinition.Spec.PodSpecPath) != 0:
+			return strings.Split(tmpl.ComponentDefinition.Spec.PodSpecPath, ".")
+		case tmpl.WorkloadDefinition != nil && len(tmpl.WorkloadDefinition.Spec.PodSpecPath) != 0:
+			return strings.Split(tmpl.WorkloadDefinition.Spec.PodSpecPath, ".")
+		}
+	}
+	return util.PodSpecPath(obj.GetKind())
+}
+
 // IsCloudResourceProducer checks whether a workload is cloud resource producer role
 func (wl *Workload) IsCloudResourceProducer() bool {
 	var existed bool
@@ -163,6 +197,70 @@ type Appfile struct {
 	Namespace    string
 	RevisionName string
 	Workloads    []*Workload
+	// ApplyOnceRules selects the rendered resources which are applied only once
+	ApplyOnceRules []ApplyOncePolicyRule
+	// SharedResourceRules selects the rendered resources which are shared with other applications
+	SharedResourceRules []SharedResourcePolicyRule
+	// GarbageCollectRules declares how the rendered resources are garbage collected
+	GarbageCollectRules []GarbageCollectPolicyRule
+	// WindowsNodeRules select the components scheduled to Windows nodes
+	WindowsNodeRules []WindowsNodePolicySpec
+	// AutoExposeRules select the components whose container ports are exposed by generated Services
+	AutoExposeRules []AutoExposePolicySpec
+	// RenderWarnings are the issues found when rendering the components, they don't block the deployment
+	RenderWarnings []common.LintWarning
+	// Placement is the clusters and namespaces the application is deployed to
+	Placement []v1beta1.EnvironmentTarget
+	// AppDependencies are the applications which must be healthy before the components are deployed
+	AppDependencies []AppDependency
+	// Topologies are the topology policies selecting the managed clusters, keyed by policy names
+	Topologies map[string]*TopologyPolicySpec
+	// Overrides are the override policies patching the components deployed by the deploy workflow steps, keyed by
+	// policy names
+	Overrides map[string]*OverridePolicySpec
+	// DeploySteps are the deploy workflow steps dispatching the components to the managed clusters, keyed by step names
+	DeploySteps map[string]*DeploySpec
+	// BlueGreenPromoteSteps are the workflow steps promoting the newest revisions of the blue-green components, keyed
+	// by step names
+	BlueGreenPromoteSteps map[string]*BlueGreenPromoteSpec
+	// BlueGreenActive are the revisions of the blue-green components selected by their stable Services, keyed by
+	// component names. The newest revisions are selected for the components not in it, e.g., the promoted ones.
+	BlueGreenActive map[string]string
+	// BlueGreenRevisions are the newest revisions of the blue-green components rendered, keyed by component names
+	BlueGreenRevisions map[string]string
+	// PolicyTemplates are the templates of the policies defined by PolicyDefinitions, keyed by policy types
+	PolicyTemplates map[string]*Template
+	// WorkflowStepTemplates are the templates of the workflow steps defined by WorkflowStepDefinitions, keyed by step types
+	WorkflowStepTemplates map[string]*Template
+	// WorkflowStepPolicies are the timeout and retry policies of the workflow steps, keyed by step names
+	WorkflowStepPolicies map[string]*workflow.StepPolicy
+	// WorkflowStepGroups are the concurrency limits of the step-group workflow steps, keyed by step names
+	WorkflowStepGroups map[string]int
+}
+
+// Templates returns the CUE templates of the workloads, traits, policies and workflow steps of the appfile
+func (af *Appfile) Templates() []string {
+	var templates []string
+	for _, wl := range af.Workloads {
+		if wl == nil {
+			continue
+		}
+		if wl.FullTemplate != nil {
+			templates = append(templates, wl.FullTemplate.TemplateStr)
+		}
+		for _, tr := range wl.Traits {
+			if tr != nil {
+				templates = append(templates, tr.Template)
+			}
+		}
+	}
+	for _, tmpl := range af.PolicyTemplates {
+		templates = append(templates, tmpl.TemplateStr)
+	}
+	for _, tmpl := range af.WorkflowStepTemplates {
+		templates = append(templates, tmpl.TemplateStr)
+	}
+	return templates
 }
 
 // GenerateApplicationConfiguration converts an appFile to applicationConfig & Components
@@ -179,6 +277,8 @@ func (af *Appfile) GenerateApplicationConfiguration() (*v1alpha2.ApplicationConf
 	appconfig.Labels[oam.LabelAppName] = af.Name
 
 	var components []*v1alpha2.Component
+	af.RenderWarnings = nil
+	af.BlueGreenRevisions = nil
 
 	for _, wl := range af.Workloads {
 		var (
@@ -202,12 +302,44 @@ func (af *Appfile) GenerateApplicationConfiguration() (*v1alpha2.ApplicationConf
 			if err != nil {
 				return nil, nil, err
 			}
+		case types.KustomizeCategory:
+			comp, acComp, err = generateComponentFromKustomizeModule(wl, af.Name, af.RevisionName, af.Namespace)
+			if err != nil {
+				return nil, nil, err
+			}
+		case types.GitCategory:
+			comp, acComp, err = generateComponentFromGitModule(wl, af.Name, af.RevisionName, af.Namespace)
+			if err != nil {
+				return nil, nil, err
+			}
 		default:
 			comp, acComp, err = generateComponentFromCUEModule(wl, af.Name, af.RevisionName, af.Namespace)
 			if err != nil {
 				return nil, nil, err
 			}
 		}
+		// the secrets are bound before the revision of a blue-green workload is computed
+		if err := af.injectServiceBinding(wl, comp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.prepareBlueGreen(wl, comp, acComp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.autoExpose(wl, comp, acComp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.markApplyOnce(comp, acComp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.markSharedResource(comp, acComp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.markGarbageCollect(comp, acComp); err != nil {
+			return nil, nil, err
+		}
+		if err := af.prepareWindowsComponent(wl, comp); err != nil {
+			return nil, nil, err
+		}
 		components = append(components, comp)
 		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComp)
 	}
@@ -349,11 +481,12 @@ func evalWorkloadWithContext(pCtx process.Context, wl *Workload, ns, appName, co
 }
 
 func generateComponentFromKubeModule(wl *Workload, appName, revision, ns string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
-	kubeObj := &unstructured.Unstructured{}
-	err := json.Unmarshal(wl.FullTemplate.Kube.Template.Raw, kubeObj)
+	kubeObjs, err := loadKubeObjects(wl.FullTemplate.Kube)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot decode Kube template into K8s object")
+		return nil, nil, err
 	}
+	// the first object is the workload, while the others are treated as auxiliary outputs
+	kubeObj := kubeObjs[0]
 
 	paramValues, err := resolveKubeParameters(wl.FullTemplate.Kube.Parameters, wl.Params)
 	if err != nil {
@@ -362,26 +495,35 @@ func generateComponentFromKubeModule(wl *Workload, appName, revision, ns string)
 	if err := setParameterValuesToKubeObj(kubeObj, paramValues); err != nil {
 		return nil, nil, errors.WithMessage(err, "cannot set parameters value")
 	}
+	return generateComponentFromKubeObjects(wl, kubeObjs, appName, revision, ns)
+}
 
-	// convert structured kube obj into CUE (go ==marshal==> json ==decoder==> cue)
-	objRaw, err := kubeObj.MarshalJSON()
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot marshal kube object")
-	}
-	ins, err := json2cue.Decode(&cue.Runtime{}, "", objRaw)
+// generateComponentFromKubeObjects generates the component whose workload is the first K8s object, while the others
+// are the auxiliary outputs
+func generateComponentFromKubeObjects(wl *Workload, kubeObjs []*unstructured.Unstructured, appName, revision, ns string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
+	cueRaw, err := kubeObj2CUE(kubeObjs[0])
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot decode object into CUE")
+		return nil, nil, err
 	}
-	cueRaw, err := format.Node(ins.Value().Syntax())
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot format CUE")
+	var outputs strings.Builder
+	for i, o := range kubeObjs[1:] {
+		outputRaw, err := kubeObj2CUE(o)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Fprintf(&outputs, "\t%q: {\n\t%s\n\t}\n", kubeOutputName(i, o), outputRaw)
 	}
 
 	// NOTE a hack way to enable using CUE capabilities on KUBE schematic workload
 	wl.FullTemplate.TemplateStr = fmt.Sprintf(`
 output: { 
 	%s 
-}`, string(cueRaw))
+}`, cueRaw)
+	if outputs.Len() > 0 {
+		wl.FullTemplate.TemplateStr += fmt.Sprintf(`
+outputs: {
+%s}`, outputs.String())
+	}
 
 	// re-use the way CUE module generates comp & acComp
 	comp, acComp, err := generateComponentFromCUEModule(wl, appName, revision, ns)
@@ -391,6 +533,131 @@ output: {
 	return comp, acComp, nil
 }
 
+func generateComponentFromKustomizeModule(wl *Workload, appName, revision, ns string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
+	kubeObjs, err := kustomize.DefaultRenderer.Render(context.Background(), wl.FullTemplate.Kustomize, wl.Params)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "cannot render kustomize component %q", wl.Name)
+	}
+	if len(kubeObjs) == 0 {
+		return nil, nil, errors.Errorf("kustomize component %q renders no resources", wl.Name)
+	}
+	// kustomize sorts the resources by kind, so the workload is moved ahead of the others, e.g., Namespaces,
+	// ConfigMaps and Services
+	moveWorkloadFirst(kubeObjs, isWorkloadKind)
+	return generateComponentFromKubeObjects(wl, kubeObjs, appName, revision, ns)
+}
+
+func generateComponentFromGitModule(wl *Workload, appName, revision, ns string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
+	content, err := gitsource.DefaultLoader.Load(context.Background(), wl.FullTemplate.Git, wl.GitCredentials)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "cannot load git component %q", wl.Name)
+	}
+	if len(content.Template) != 0 {
+		// the CUE template in the repository is rendered with the properties as if it's inlined in the definition
+		wl.FullTemplate.TemplateStr = content.Template
+		return generateComponentFromCUEModule(wl, appName, revision, ns)
+	}
+	kubeObjs := make([]*unstructured.Unstructured, 0, len(content.Manifests))
+	for _, o := range content.Manifests {
+		// the loaded manifests are cached and shared, so they're copied before being rendered
+		kubeObjs = append(kubeObjs, o.DeepCopy())
+	}
+	moveWorkloadFirst(kubeObjs, isWorkloadKind)
+	return generateComponentFromKubeObjects(wl, kubeObjs, appName, revision, ns)
+}
+
+// GitSyncInterval returns the shortest sync interval of the components of the Git schematic, so the application is
+// re-rendered to pick up new commits of the branches and tags. It's zero if none needs to be synced.
+func (af *Appfile) GitSyncInterval() time.Duration {
+	var interval time.Duration
+	for _, wl := range af.Workloads {
+		if wl.CapabilityCategory != types.GitCategory || wl.FullTemplate == nil || wl.FullTemplate.Git == nil {
+			continue
+		}
+		if i := gitsource.SyncInterval(wl.FullTemplate.Git); i > 0 && (interval == 0 || i < interval) {
+			interval = i
+		}
+	}
+	return interval
+}
+
+// ResyncGitSources drops the cached directories of the components of the Git schematic, so they're fetched again from
+// the repositories when the application is rendered
+func (af *Appfile) ResyncGitSources() {
+	for _, wl := range af.Workloads {
+		if wl.CapabilityCategory == types.GitCategory && wl.FullTemplate != nil && wl.FullTemplate.Git != nil {
+			gitsource.DefaultLoader.Invalidate(wl.FullTemplate.Git)
+		}
+	}
+}
+
+// moveWorkloadFirst moves the first object matching the workload ahead of the others, the objects are kept as they
+// are if none matches
+func moveWorkloadFirst(kubeObjs []*unstructured.Unstructured, isWorkload func(*unstructured.Unstructured) bool) {
+	for i, o := range kubeObjs {
+		if isWorkload(o) {
+			kubeObjs[0], kubeObjs[i] = kubeObjs[i], kubeObjs[0]
+			return
+		}
+	}
+}
+
+// isWorkloadKind returns whether the object is one of the built-in workloads running pods
+func isWorkloadKind(o *unstructured.Unstructured) bool {
+	switch o.GetKind() {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadKubeObjects returns the K8s objects of the KUBE schematic, the inline template comes first,
+// followed by the objects fetched from the remote URL and Git sources in order.
+func loadKubeObjects(kube *common.Kube) ([]*unstructured.Unstructured, error) {
+	var kubeObjs []*unstructured.Unstructured
+	if len(kube.Template.Raw) > 0 {
+		kubeObj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(kube.Template.Raw, kubeObj); err != nil {
+			return nil, errors.Wrap(err, "cannot decode Kube template into K8s object")
+		}
+		kubeObjs = append(kubeObjs, kubeObj)
+	}
+	if len(kube.URLs) > 0 || len(kube.Git) > 0 {
+		remoteObjs, err := kubesource.DefaultSourceLoader.Load(context.Background(), kube)
+		if err != nil {
+			return nil, errors.WithMessage(err, "cannot load Kube objects from remote sources")
+		}
+		kubeObjs = append(kubeObjs, remoteObjs...)
+	}
+	if len(kubeObjs) == 0 {
+		return nil, errors.New(errKubeTemplateIsNotSet)
+	}
+	return kubeObjs, nil
+}
+
+// kubeObj2CUE converts structured kube obj into CUE (go ==marshal==> json ==decoder==> cue)
+func kubeObj2CUE(kubeObj *unstructured.Unstructured) (string, error) {
+	objRaw, err := kubeObj.MarshalJSON()
+	if err != nil {
+		return "", errors.Wrap(err, "cannot marshal kube object")
+	}
+	ins, err := json2cue.Decode(&cue.Runtime{}, "", objRaw)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot decode object into CUE")
+	}
+	cueRaw, err := format.Node(ins.Value().Syntax())
+	if err != nil {
+		return "", errors.Wrap(err, "cannot format CUE")
+	}
+	return string(cueRaw), nil
+}
+
+// kubeOutputName generates a unique name of an auxiliary output for the K8s object
+func kubeOutputName(index int, o *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s-%s-%d", strings.ToLower(o.GetKind()), o.GetName(), index)
+}
+
 func generateTerraformConfigurationWorkload(wl *Workload, ns string) (*unstructured.Unstructured, error) {
 	if wl.FullTemplate.Terraform.Configuration == "" {
 		return nil, errors.New(errTerraformConfigurationIsNotSet)
@@ -529,6 +796,9 @@ func generateComponentFromHelmModule(wl *Workload, appName, revision, ns string)
 		return nil, nil, err
 	}
 	targetWorkloadGVK := gv.WithKind(wl.FullTemplate.Reference.Definition.Kind)
+	if helm.IsOCIRepository(wl.FullTemplate.Helm) {
+		return generateComponentFromOCIHelmModule(wl, targetWorkloadGVK.Kind, appName, revision, ns)
+	}
 
 	// NOTE this is a hack way to enable using CUE module capabilities on Helm module workload
 	// construct an empty base workload according to its GVK
@@ -566,3 +836,23 @@ output: {
 	}
 	return comp, acComp, nil
 }
+
+// generateComponentFromOCIHelmModule renders the chart in the OCI registry into K8s objects since Flux doesn't support
+// OCI registries, the object of the workload kind of the definition is the workload while the others are the
+// auxiliary outputs
+func generateComponentFromOCIHelmModule(wl *Workload, workloadKind, appName, revision, ns string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
+	kubeObjs, err := helm.RenderOCIChart(context.Background(), wl.FullTemplate.Helm, wl.Name, appName, ns, wl.Params, wl.HelmCredentials)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "cannot render Helm component %q", wl.Name)
+	}
+	if len(kubeObjs) == 0 {
+		return nil, nil, errors.Errorf("Helm component %q renders no resources", wl.Name)
+	}
+	moveWorkloadFirst(kubeObjs, func(o *unstructured.Unstructured) bool {
+		if len(workloadKind) == 0 {
+			return isWorkloadKind(o)
+		}
+		return o.GetKind() == workloadKind
+	})
+	return generateComponentFromKubeObjects(wl, kubeObjs, appName, revision, ns)
+}
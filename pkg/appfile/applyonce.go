@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// PolicyTypeApplyOnce is the type of the policy declaring the rendered resources which are applied on creation
+// but never reconciled afterwards, so other controllers, e.g., HPA, can mutate them without a reconcile war
+const PolicyTypeApplyOnce = "apply-once"
+
+// ApplyOncePolicySpec is the properties of the apply-once policy
+type ApplyOncePolicySpec struct {
+	Rules []ApplyOncePolicyRule `json:"rules"`
+}
+
+// ApplyOncePolicyRule selects the resources applied only once
+type ApplyOncePolicyRule struct {
+	Selector ResourceSelector `json:"selector"`
+}
+
+// ResourceSelector selects the rendered resources of an application, a resource is selected if it matches all the
+// specified fields, an empty selector selects all the resources
+type ResourceSelector struct {
+	// CompNames selects the resources rendered from the components
+	CompNames []string `json:"componentNames,omitempty"`
+	// ResourceTypes selects the resources by kind, e.g., Deployment
+	ResourceTypes []string `json:"resourceTypes,omitempty"`
+	// TraitTypes selects the resources rendered from the traits, e.g., ingress
+	TraitTypes []string `json:"traitTypes,omitempty"`
+}
+
+func (s *ResourceSelector) matches(compName, traitType string, res *unstructured.Unstructured) bool {
+	contains := func(list []string, v string) bool {
+		if len(list) == 0 {
+			return true
+		}
+		for _, item := range list {
+			if item == v {
+				return true
+			}
+		}
+		return false
+	}
+	if len(s.TraitTypes) != 0 && len(traitType) == 0 {
+		// the workload is not selected by a trait selector
+		return false
+	}
+	return contains(s.CompNames, compName) && contains(s.ResourceTypes, res.GetKind()) && contains(s.TraitTypes, traitType)
+}
+
+func parseApplyOncePolicies(app *v1beta1.Application) ([]ApplyOncePolicyRule, error) {
+	var rules []ApplyOncePolicyRule
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeApplyOnce {
+			continue
+		}
+		spec := &ApplyOncePolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		rules = append(rules, spec.Rules...)
+	}
+	return rules, nil
+}
+
+// markApplyOnce annotates the rendered workload and traits selected by the apply-once policies,
+// the ApplicationConfiguration controller won't update them once they are created
+func (af *Appfile) markApplyOnce(comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) error {
+	var selectors []ResourceSelector
+	for _, rule := range af.ApplyOnceRules {
+		selectors = append(selectors, rule.Selector)
+	}
+	return annotateSelectedResources(comp, acComp, selectors, oam.AnnotationApplyOnce)
+}
+
+// annotateSelectedResources sets the annotation to "true" on the rendered workload and traits matching any of the selectors
+func annotateSelectedResources(comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent,
+	selectors []ResourceSelector, annotation string) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+	if err := annotateSelectedResource(comp.Name, &comp.Spec.Workload, selectors, annotation); err != nil {
+		return errors.WithMessagef(err, "component(%s)", comp.Name)
+	}
+	for i := range acComp.Traits {
+		if err := annotateSelectedResource(comp.Name, &acComp.Traits[i].Trait, selectors, annotation); err != nil {
+			return errors.WithMessagef(err, "component(%s)", comp.Name)
+		}
+	}
+	return nil
+}
+
+func annotateSelectedResource(compName string, raw *runtime.RawExtension, selectors []ResourceSelector, annotation string) error {
+	if len(raw.Raw) == 0 {
+		return nil
+	}
+	res := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw.Raw, &res.Object); err != nil {
+		return err
+	}
+	traitType := res.GetLabels()[oam.TraitTypeLabel]
+	for _, selector := range selectors {
+		if !selector.matches(compName, traitType, res) {
+			continue
+		}
+		annotations := res.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotation] = "true"
+		res.SetAnnotations(annotations)
+		b, err := json.Marshal(res.Object)
+		if err != nil {
+			return err
+		}
+		raw.Raw = b
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestMarkApplyOnce(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Policies: []v1beta1.AppPolicy{{
+		Name: "hpa-friendly",
+		Type: PolicyTypeApplyOnce,
+		Properties: runtime.RawExtension{Raw: []byte(`{"rules":[
+			{"selector":{"componentNames":["web"],"resourceTypes":["Deployment"]}},
+			{"selector":{"traitTypes":["scaler"]}}]}`)},
+	}, {
+		Name:       "other",
+		Type:       "debug",
+		Properties: runtime.RawExtension{Raw: []byte(`{"rules":"not apply-once"}`)},
+	}}}}
+	rules, err := parseApplyOncePolicies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rules))
+	af := &Appfile{ApplyOnceRules: rules}
+
+	newComp := func(name string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent) {
+		comp := &v1alpha2.Component{}
+		comp.Name = name
+		comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","spec":{"replicas":3}}`)}
+		acComp := &v1alpha2.ApplicationConfigurationComponent{Traits: []v1alpha2.ComponentTrait{
+			{Trait: runtime.RawExtension{Raw: []byte(`{"kind":"HorizontalPodAutoscaler","metadata":{"labels":{"trait.oam.dev/type":"scaler"}}}`)}},
+			{Trait: runtime.RawExtension{Raw: []byte(`{"kind":"Ingress","metadata":{"labels":{"trait.oam.dev/type":"ingress"}}}`)}},
+		}}
+		return comp, acComp
+	}
+	applyOnce := func(raw runtime.RawExtension) bool {
+		obj := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(raw.Raw, &obj))
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		return annotations[oam.AnnotationApplyOnce] == "true"
+	}
+
+	comp, acComp := newComp("web")
+	assert.NoError(t, af.markApplyOnce(comp, acComp))
+	assert.True(t, applyOnce(comp.Spec.Workload))
+	assert.True(t, applyOnce(acComp.Traits[0].Trait))
+	assert.False(t, applyOnce(acComp.Traits[1].Trait))
+
+	comp, acComp = newComp("db")
+	assert.NoError(t, af.markApplyOnce(comp, acComp))
+	assert.False(t, applyOnce(comp.Spec.Workload))
+	assert.True(t, applyOnce(acComp.Traits[0].Trait))
+	assert.False(t, applyOnce(acComp.Traits[1].Trait))
+}
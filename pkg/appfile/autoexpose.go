@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// PolicyTypeAutoExpose is the type of the policy generating a Service, and optionally a NetworkPolicy, for the
+// ports declared by the containers of the selected components. They're rendered as traits of this type.
+const PolicyTypeAutoExpose = "auto-expose"
+
+// WarningAutoExposeNoPods reports the workloads whose pods cannot be located to generate the Service
+const WarningAutoExposeNoPods = "auto-expose-no-pods"
+
+// AutoExposePolicySpec is the properties of the auto-expose policy
+type AutoExposePolicySpec struct {
+	// Components are the names of the components exposed, all the components are selected if it's empty
+	Components []string `json:"components,omitempty"`
+	// ServiceType is the type of the generated Service, it's ClusterIP if empty
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// NetworkPolicy generates a NetworkPolicy denying the ingress traffic to the pods except for the declared ports
+	NetworkPolicy bool `json:"networkPolicy,omitempty"`
+}
+
+func (p *AutoExposePolicySpec) selects(compName string) bool {
+	if len(p.Components) == 0 {
+		return true
+	}
+	for _, c := range p.Components {
+		if c == compName {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAutoExposePolicies(app *v1beta1.Application) ([]AutoExposePolicySpec, error) {
+	var specs []AutoExposePolicySpec
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeAutoExpose {
+			continue
+		}
+		spec := AutoExposePolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, &spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		switch spec.ServiceType {
+		case "":
+			spec.ServiceType = corev1.ServiceTypeClusterIP
+		case corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+		default:
+			return nil, errors.Errorf("invalid service type %s of policy %s", spec.ServiceType, p.Name)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// autoExpose generates the Service and the NetworkPolicy for the ports declared by the containers of the component
+// selected by the auto-expose policies, the first matching policy wins. Neither is generated if the component
+// already renders one of the same kind by its traits.
+func (af *Appfile) autoExpose(wl *Workload, comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) error {
+	var policy *AutoExposePolicySpec
+	for i := range af.AutoExposeRules {
+		if af.AutoExposeRules[i].selects(wl.Name) {
+			policy = &af.AutoExposeRules[i]
+			break
+		}
+	}
+	if policy == nil || len(comp.Spec.Workload.Raw) == 0 {
+		return nil
+	}
+	obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	if err != nil {
+		return errors.Wrapf(err, "cannot convert workload of component(%s)", wl.Name)
+	}
+	// the components without pods, e.g., cloud resources, are only reported if they're selected explicitly
+	explicit := len(policy.Components) != 0
+	path := wl.podSpecPath(obj)
+	if path == nil {
+		if !explicit {
+			return nil
+		}
+		af.RenderWarnings = append(af.RenderWarnings, common.LintWarning{
+			Component: wl.Name,
+			Rule:      WarningAutoExposeNoPods,
+			Message:   fmt.Sprintf("cannot locate the pod spec of %s to expose its ports", obj.GetKind()),
+		})
+		return nil
+	}
+	ports, err := containerPorts(obj, path)
+	if err != nil {
+		return errors.Wrapf(err, "invalid container ports of component(%s)", wl.Name)
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	// the pod template is the parent of the pod spec, or the workload itself if it's a Pod
+	podLabels, _, _ := unstructured.NestedStringMap(obj.Object, append(path[:len(path)-1], "metadata", "labels")...)
+	if len(podLabels) == 0 {
+		af.RenderWarnings = append(af.RenderWarnings, common.LintWarning{
+			Component: wl.Name,
+			Rule:      WarningAutoExposeNoPods,
+			Message:   fmt.Sprintf("the pods of %s have no labels to be selected by the Service", obj.GetKind()),
+		})
+		return nil
+	}
+
+	declared := map[string]bool{}
+	for _, tr := range acComp.Traits {
+		if t, err := util.RawExtension2Unstructured(&tr.Trait); err == nil {
+			declared[t.GetKind()] = true
+		}
+	}
+	labels := map[string]string{oam.TraitTypeLabel: PolicyTypeAutoExpose}
+	for _, k := range []string{oam.LabelAppName, oam.LabelAppComponent, oam.LabelAppRevision} {
+		if v, ok := obj.GetLabels()[k]; ok {
+			labels[k] = v
+		}
+	}
+	if !declared["Service"] {
+		svc := &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: wl.Name, Labels: util.MergeMapOverrideWithDst(labels, map[string]string{oam.TraitResource: "service"})},
+			Spec:       corev1.ServiceSpec{Type: policy.ServiceType, Selector: podLabels},
+		}
+		for _, p := range ports {
+			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+				Name:       p.Name,
+				Protocol:   p.Protocol,
+				Port:       p.ContainerPort,
+				TargetPort: intstr.FromInt(int(p.ContainerPort)),
+			})
+		}
+		acComp.Traits = append(acComp.Traits, v1alpha2.ComponentTrait{Trait: util.Object2RawExtension(svc)})
+	}
+	if policy.NetworkPolicy && !declared["NetworkPolicy"] {
+		np := &networkingv1.NetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+			ObjectMeta: metav1.ObjectMeta{Name: wl.Name, Labels: util.MergeMapOverrideWithDst(labels, map[string]string{oam.TraitResource: "networkpolicy"})},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}
+		// the traffic from any source is allowed to the declared ports only
+		rule := networkingv1.NetworkPolicyIngressRule{}
+		for _, p := range ports {
+			port, protocol := intstr.FromInt(int(p.ContainerPort)), p.Protocol
+			rule.Ports = append(rule.Ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+		}
+		np.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{rule}
+		acComp.Traits = append(acComp.Traits, v1alpha2.ComponentTrait{Trait: util.Object2RawExtension(np)})
+	}
+	return nil
+}
+
+// containerPorts returns the ports declared by the containers in the pod spec, they're named after their protocols
+// and numbers if not named since the ports of a Service with multiple ports must be named
+func containerPorts(obj *unstructured.Unstructured, podSpecPath []string) ([]corev1.ContainerPort, error) {
+	raw, _, err := unstructured.NestedSlice(obj.Object, append(podSpecPath, "containers")...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var containers []corev1.Container
+	if err := json.Unmarshal(b, &containers); err != nil {
+		return nil, err
+	}
+	var ports []corev1.ContainerPort
+	seen := map[string]bool{}
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.Protocol == "" {
+				p.Protocol = corev1.ProtocolTCP
+			}
+			key := fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if len(p.Name) == 0 {
+				p.Name = fmt.Sprintf("%s-%d", strings.ToLower(string(p.Protocol)), p.ContainerPort)
+			}
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestAutoExpose(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Policies: []v1beta1.AppPolicy{{
+		Name:       "expose",
+		Type:       PolicyTypeAutoExpose,
+		Properties: runtime.RawExtension{Raw: []byte(`{"networkPolicy":true}`)},
+	}}}}
+	rules, err := parseAutoExposePolicies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, rules[0].ServiceType)
+	af := &Appfile{AutoExposeRules: rules}
+
+	newComp := func() *v1alpha2.Component {
+		comp := &v1alpha2.Component{}
+		comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment",
+			"metadata":{"labels":{"app.oam.dev/name":"app","app.oam.dev/component":"web"}},
+			"spec":{"template":{"metadata":{"labels":{"app.oam.dev/component":"web"}},"spec":{"containers":[
+			{"name":"web","ports":[{"containerPort":80},{"containerPort":53,"protocol":"UDP"}]},
+			{"name":"sidecar","ports":[{"name":"metrics","containerPort":9090},{"containerPort":80}]}]}}}}`)}
+		return comp
+	}
+	wl := &Workload{Name: "web"}
+	acComp := &v1alpha2.ApplicationConfigurationComponent{}
+	assert.NoError(t, af.autoExpose(wl, newComp(), acComp))
+	assert.Equal(t, 2, len(acComp.Traits))
+
+	svc, err := util.RawExtension2Unstructured(&acComp.Traits[0].Trait)
+	assert.NoError(t, err)
+	assert.Equal(t, "Service", svc.GetKind())
+	assert.Equal(t, "web", svc.GetName())
+	assert.Equal(t, PolicyTypeAutoExpose, svc.GetLabels()[oam.TraitTypeLabel])
+	assert.Equal(t, "app", svc.GetLabels()[oam.LabelAppName])
+	ports, _, _ := unstructured.NestedSlice(svc.Object, "spec", "ports")
+	assert.Equal(t, 3, len(ports))
+	names := []interface{}{}
+	for _, p := range ports {
+		names = append(names, p.(map[string]interface{})["name"])
+	}
+	assert.Equal(t, []interface{}{"tcp-80", "udp-53", "metrics"}, names)
+	selector, _, _ := unstructured.NestedStringMap(svc.Object, "spec", "selector")
+	assert.Equal(t, map[string]string{oam.LabelAppComponent: "web"}, selector)
+
+	np, err := util.RawExtension2Unstructured(&acComp.Traits[1].Trait)
+	assert.NoError(t, err)
+	assert.Equal(t, "NetworkPolicy", np.GetKind())
+	ingress, _, _ := unstructured.NestedSlice(np.Object, "spec", "ingress")
+	assert.Equal(t, 1, len(ingress))
+
+	// the Service declared by the traits is kept
+	acComp = &v1alpha2.ApplicationConfigurationComponent{Traits: []v1alpha2.ComponentTrait{
+		{Trait: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Service","metadata":{"name":"custom"}}`)}},
+	}}
+	assert.NoError(t, af.autoExpose(wl, newComp(), acComp))
+	assert.Equal(t, 2, len(acComp.Traits))
+	np, err = util.RawExtension2Unstructured(&acComp.Traits[1].Trait)
+	assert.NoError(t, err)
+	assert.Equal(t, "NetworkPolicy", np.GetKind())
+
+	app.Spec.Policies[0].Properties = runtime.RawExtension{Raw: []byte(`{"serviceType":"ExternalName"}`)}
+	_, err = parseAutoExposePolicies(app)
+	assert.Error(t, err)
+}
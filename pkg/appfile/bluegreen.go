@@ -0,0 +1,322 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// TraitHandlerBlueGreen is the built-in trait handler keeping the workloads of two revisions of a component, the
+// stable Service selects the pods of the active revision and the preview Service selects the ones of the newest
+const TraitHandlerBlueGreen = "blue-green"
+
+// defaultBlueGreenSoak is how long the workload of the retired revision is kept after the promotion by default
+const defaultBlueGreenSoak = 10 * time.Minute
+
+// previewServiceSuffix is appended to the name of the stable Service to name the preview one
+const previewServiceSuffix = "-preview"
+
+// BlueGreenTraitSpec is the properties of the blue-green trait
+type BlueGreenTraitSpec struct {
+	// Port is the port of the Services, the ports declared by the containers are exposed if it's not set
+	Port int32 `json:"port,omitempty"`
+	// TargetPort is the port of the pods the Services forward to, it's the same as Port if not set
+	TargetPort int32 `json:"targetPort,omitempty"`
+	// Soak is how long the workload of the retired revision is kept after the promotion for a quick switch back,
+	// e.g., 30m, it's 10m if empty
+	Soak string `json:"soak,omitempty"`
+}
+
+// BlueGreenPromoteSpec is the properties of the blue-green-promote workflow step, the stable Services are switched to
+// the newest revisions once the previous steps finish and the step is approved if it declares approvers
+type BlueGreenPromoteSpec struct {
+	// Components are the names of the components promoted, all the components with the blue-green trait are
+	// promoted if it's empty
+	Components []string `json:"components,omitempty"`
+	workflow.ApprovalSpec
+}
+
+// Selects returns true if the step promotes the component
+func (s *BlueGreenPromoteSpec) Selects(compName string) bool {
+	return containsOrEmpty(s.Components, compName)
+}
+
+// BlueGreen is the blue-green trait of a component
+type BlueGreen struct {
+	// Type is the type of the trait, i.e., the name of its definition
+	Type       string
+	Port       int32
+	TargetPort int32
+	Soak       time.Duration
+}
+
+// handleBlueGreenTrait is the trait handler of the blue-green trait
+func handleBlueGreenTrait(wl *Workload, trait *Trait, raw runtime.RawExtension) error {
+	bg, err := parseBlueGreenTrait(raw)
+	if err != nil {
+		return err
+	}
+	bg.Type = trait.Name
+	wl.BlueGreen = bg
+	return nil
+}
+
+func parseBlueGreenTrait(raw runtime.RawExtension) (*BlueGreen, error) {
+	spec := &BlueGreenTraitSpec{}
+	if len(raw.Raw) != 0 {
+		if err := json.Unmarshal(raw.Raw, spec); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Port < 0 || spec.TargetPort < 0 {
+		return nil, errors.Errorf("invalid port %d or target port %d", spec.Port, spec.TargetPort)
+	}
+	if spec.Port == 0 && spec.TargetPort != 0 {
+		return nil, errors.New("the target port is set without the port")
+	}
+	bg := &BlueGreen{Port: spec.Port, TargetPort: spec.TargetPort, Soak: defaultBlueGreenSoak}
+	if bg.TargetPort == 0 {
+		bg.TargetPort = bg.Port
+	}
+	if len(spec.Soak) != 0 {
+		soak, err := time.ParseDuration(spec.Soak)
+		if err != nil || soak < 0 {
+			return nil, errors.Errorf("invalid soak %q", spec.Soak)
+		}
+		bg.Soak = soak
+	}
+	return bg, nil
+}
+
+// parseBlueGreenPromoteSteps parses the blue-green-promote workflow steps, keyed by step names. They can only promote
+// the components with the blue-green trait.
+func parseBlueGreenPromoteSteps(app *v1beta1.Application, workloads []*Workload) (map[string]*BlueGreenPromoteSpec, error) {
+	blueGreen := map[string]bool{}
+	for _, wl := range workloads {
+		if wl.BlueGreen != nil {
+			blueGreen[wl.Name] = true
+		}
+	}
+	steps := map[string]*BlueGreenPromoteSpec{}
+	for _, step := range app.Spec.Workflow {
+		for _, sub := range step.SubSteps {
+			if sub.Type == workflow.TypeBlueGreenPromote {
+				return nil, errors.Errorf("sub-step %s of workflow step %s cannot be a %s step", sub.Name, step.Name, workflow.TypeBlueGreenPromote)
+			}
+		}
+		if step.Type != workflow.TypeBlueGreenPromote {
+			continue
+		}
+		spec := &BlueGreenPromoteSpec{}
+		if len(step.Properties.Raw) != 0 {
+			if err := json.Unmarshal(step.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+			}
+		}
+		for _, name := range spec.Components {
+			if !blueGreen[name] {
+				return nil, errors.Errorf("workflow step %s promotes component %s which has no %s trait", step.Name, name, TraitHandlerBlueGreen)
+			}
+		}
+		steps[step.Name] = spec
+	}
+	return steps, nil
+}
+
+// BlueGreenWorkloadName is the name of the workload of a revision of the component with the blue-green trait
+func BlueGreenWorkloadName(compName, revision string) string {
+	return compName + "-" + revision
+}
+
+// selectorPaths returns the paths of the label maps selecting the pods in the rendered workload, the ones declared
+// in the annotation oam.AnnotationSelectorPaths of the definition take precedence. Otherwise they're the labels of
+// the pod template and spec.selector.matchLabels if the workload has one.
+func (wl *Workload) selectorPaths(obj *unstructured.Unstructured) [][]string {
+	if tmpl := wl.FullTemplate; tmpl != nil {
+		var annotations map[string]string
+		switch {
+		case tmpl.ComponentDefinition != nil:
+			annotations = tmpl.ComponentDefinition.Annotations
+		case tmpl.WorkloadDefinition != nil:
+			annotations = tmpl.WorkloadDefinition.Annotations
+		}
+		if value := annotations[oam.AnnotationSelectorPaths]; len(value) != 0 {
+			var paths [][]string
+			for _, p := range strings.Split(value, ",") {
+				if p = strings.TrimSpace(p); len(p) != 0 {
+					paths = append(paths, strings.Split(p, "."))
+				}
+			}
+			return paths
+		}
+	}
+	podSpec := wl.podSpecPath(obj)
+	if podSpec == nil {
+		return nil
+	}
+	// the pod template is the parent of the pod spec, or the workload itself if it's a Pod
+	paths := [][]string{append(append([]string{}, podSpec[:len(podSpec)-1]...), "metadata", "labels")}
+	if _, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels"); found {
+		paths = append(paths, []string{"spec", "selector", "matchLabels"})
+	}
+	return paths
+}
+
+// blueGreenRevision computes the revision of the rendered workload, it only changes with the spec of the workload
+// so a new application revision not changing the component keeps the same workload
+func blueGreenRevision(obj *unstructured.Unstructured, selectorPaths [][]string) (string, error) {
+	o := obj.DeepCopy()
+	for _, path := range selectorPaths {
+		unstructured.RemoveNestedField(o.Object, append(path, oam.LabelAppRevision)...)
+	}
+	unstructured.RemoveNestedField(o.Object, "metadata")
+	b, err := json.Marshal(o.Object)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:10], nil
+}
+
+// prepareBlueGreen names the workload of the component with the blue-green trait after its revision and labels its
+// pods with the revision at the selector paths, so the workloads of the revisions run side by side. The stable
+// Service selecting the active revision in BlueGreenActive and the preview Service selecting the newest one are
+// rendered as traits of the component.
+func (af *Appfile) prepareBlueGreen(wl *Workload, comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) error {
+	if wl.BlueGreen == nil || len(comp.Spec.Workload.Raw) == 0 {
+		return nil
+	}
+	obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	if err != nil {
+		return errors.Wrapf(err, "cannot convert workload of component(%s)", wl.Name)
+	}
+	if util.IsReferredWorkload(obj) {
+		return errors.Errorf("component(%s) refers to an existing workload which cannot be switched by the %s trait", wl.Name, wl.BlueGreen.Type)
+	}
+	paths := wl.selectorPaths(obj)
+	if len(paths) == 0 {
+		return errors.Errorf("cannot locate the pods of %s of component(%s) to switch, declare the selector paths by annotation %s in the definition",
+			obj.GetKind(), wl.Name, oam.AnnotationSelectorPaths)
+	}
+	revision, err := blueGreenRevision(obj, paths)
+	if err != nil {
+		return errors.Wrapf(err, "cannot compute the revision of component(%s)", wl.Name)
+	}
+	selector := map[string]string{
+		oam.LabelAppName:           af.Name,
+		oam.LabelAppComponent:      wl.Name,
+		oam.LabelBlueGreenRevision: revision,
+	}
+	for _, path := range paths {
+		labels, _, err := unstructured.NestedStringMap(obj.Object, path...)
+		if err != nil {
+			return errors.Wrapf(err, "invalid labels at %s of component(%s)", strings.Join(path, "."), wl.Name)
+		}
+		if err := unstructured.SetNestedStringMap(obj.Object, util.MergeMapOverrideWithDst(labels, selector), path...); err != nil {
+			return errors.Wrapf(err, "cannot set labels at %s of component(%s)", strings.Join(path, "."), wl.Name)
+		}
+	}
+	// the workloads of the revisions are kept by the ApplicationConfiguration since they're prefixed by the component
+	obj.SetName(BlueGreenWorkloadName(wl.Name, revision))
+	util.AddLabels(obj, map[string]string{oam.LabelBlueGreenRevision: revision})
+	comp.Spec.Workload = util.Object2RawExtension(obj)
+
+	ports, err := wl.BlueGreen.servicePorts(obj, wl.podSpecPath(obj))
+	if err != nil {
+		return errors.Wrapf(err, "invalid container ports of component(%s)", wl.Name)
+	}
+	if len(ports) == 0 {
+		return errors.Errorf("component(%s) exposes no port to switch, set the port of the %s trait", wl.Name, wl.BlueGreen.Type)
+	}
+	active, ok := af.BlueGreenActive[wl.Name]
+	if !ok {
+		active = revision
+	}
+	if af.BlueGreenRevisions == nil {
+		af.BlueGreenRevisions = map[string]string{}
+	}
+	af.BlueGreenRevisions[wl.Name] = revision
+	services := []struct{ name, resource, revision string }{
+		{name: wl.Name, resource: "service", revision: active},
+		{name: wl.Name + previewServiceSuffix, resource: "preview-service", revision: revision},
+	}
+	for _, s := range services {
+		svc := &corev1.Service{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Labels: map[string]string{
+				oam.TraitTypeLabel:    wl.BlueGreen.Type,
+				oam.TraitResource:     s.resource,
+				oam.LabelAppName:      af.Name,
+				oam.LabelAppComponent: wl.Name,
+			}},
+			Spec: corev1.ServiceSpec{
+				Type:     corev1.ServiceTypeClusterIP,
+				Selector: util.MergeMapOverrideWithDst(selector, map[string]string{oam.LabelBlueGreenRevision: s.revision}),
+				Ports:    ports,
+			},
+		}
+		acComp.Traits = append(acComp.Traits, v1alpha2.ComponentTrait{Trait: util.Object2RawExtension(svc)})
+	}
+	return nil
+}
+
+// servicePorts returns the ports of the Services, the ports declared by the containers are exposed if the port of
+// the trait is not set
+func (bg *BlueGreen) servicePorts(obj *unstructured.Unstructured, podSpecPath []string) ([]corev1.ServicePort, error) {
+	if bg.Port != 0 {
+		return []corev1.ServicePort{{
+			Name:       "tcp",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       bg.Port,
+			TargetPort: intstr.FromInt(int(bg.TargetPort)),
+		}}, nil
+	}
+	if podSpecPath == nil {
+		return nil, nil
+	}
+	containers, err := containerPorts(obj, podSpecPath)
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]corev1.ServicePort, 0, len(containers))
+	for _, p := range containers {
+		ports = append(ports, corev1.ServicePort{
+			Name:       p.Name,
+			Protocol:   p.Protocol,
+			Port:       p.ContainerPort,
+			TargetPort: intstr.FromInt(int(p.ContainerPort)),
+		})
+	}
+	return ports, nil
+}
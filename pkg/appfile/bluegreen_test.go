@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+func TestParseBlueGreenTrait(t *testing.T) {
+	bg, err := parseBlueGreenTrait(runtime.RawExtension{})
+	assert.NoError(t, err)
+	assert.Equal(t, &BlueGreen{Soak: defaultBlueGreenSoak}, bg)
+
+	bg, err = parseBlueGreenTrait(runtime.RawExtension{Raw: []byte(`{"port":80,"soak":"30m"}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, &BlueGreen{Port: 80, TargetPort: 80, Soak: 30 * time.Minute}, bg)
+
+	for _, raw := range []string{`{"targetPort":8080}`, `{"port":-1}`, `{"soak":"soon"}`} {
+		_, err = parseBlueGreenTrait(runtime.RawExtension{Raw: []byte(raw)})
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestParseBlueGreenPromoteSteps(t *testing.T) {
+	workloads := []*Workload{{Name: "web", BlueGreen: &BlueGreen{}}, {Name: "db"}}
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Workflow: []v1beta1.WorkflowStep{
+		{Name: "promote", Type: workflow.TypeBlueGreenPromote, Properties: runtime.RawExtension{Raw: []byte(`{"components":["web"],"users":["alice"]}`)}},
+	}}}
+	steps, err := parseBlueGreenPromoteSteps(app, workloads)
+	assert.NoError(t, err)
+	assert.True(t, steps["promote"].Selects("web"))
+	assert.Equal(t, []string{"alice"}, steps["promote"].Users)
+
+	app.Spec.Workflow[0].Properties = runtime.RawExtension{Raw: []byte(`{"components":["db"]}`)}
+	_, err = parseBlueGreenPromoteSteps(app, workloads)
+	assert.EqualError(t, err, "workflow step promote promotes component db which has no blue-green trait")
+
+	app.Spec.Workflow = []v1beta1.WorkflowStep{{Name: "group", Type: "step-group", SubSteps: []v1beta1.WorkflowSubStep{
+		{Name: "promote", Type: workflow.TypeBlueGreenPromote},
+	}}}
+	_, err = parseBlueGreenPromoteSteps(app, workloads)
+	assert.Error(t, err)
+}
+
+func TestPrepareBlueGreen(t *testing.T) {
+	newComp := func(appRevision, image string) *v1alpha2.Component {
+		comp := &v1alpha2.Component{}
+		comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment",
+			"metadata":{"labels":{"app.oam.dev/appRevision":"` + appRevision + `"}},
+			"spec":{"selector":{"matchLabels":{"app.oam.dev/component":"web"}},
+			"template":{"metadata":{"labels":{"app.oam.dev/component":"web","app.oam.dev/appRevision":"` + appRevision + `"}},
+			"spec":{"containers":[{"name":"web","image":"` + image + `","ports":[{"containerPort":80}]}]}}}}`)}
+		return comp
+	}
+	render := func(af *Appfile, wl *Workload, comp *v1alpha2.Component) (*unstructured.Unstructured, []*unstructured.Unstructured) {
+		acComp := &v1alpha2.ApplicationConfigurationComponent{}
+		assert.NoError(t, af.prepareBlueGreen(wl, comp, acComp))
+		obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		assert.NoError(t, err)
+		var traits []*unstructured.Unstructured
+		for _, tr := range acComp.Traits {
+			trait, err := util.RawExtension2Unstructured(&tr.Trait)
+			assert.NoError(t, err)
+			traits = append(traits, trait)
+		}
+		return obj, traits
+	}
+	selectorOf := func(svc *unstructured.Unstructured) string {
+		selector, _, _ := unstructured.NestedStringMap(svc.Object, "spec", "selector")
+		return selector[oam.LabelBlueGreenRevision]
+	}
+
+	af := &Appfile{Name: "app"}
+	wl := &Workload{Name: "web", BlueGreen: &BlueGreen{Type: "blue-green", Soak: time.Minute}}
+	v1, traits := render(af, wl, newComp("app-v1", "nginx:1.20"))
+	rev1 := af.BlueGreenRevisions["web"]
+	assert.Equal(t, BlueGreenWorkloadName("web", rev1), v1.GetName())
+	for _, path := range [][]string{{"spec", "selector", "matchLabels"}, {"spec", "template", "metadata", "labels"}} {
+		labels, _, _ := unstructured.NestedStringMap(v1.Object, path...)
+		assert.Equal(t, rev1, labels[oam.LabelBlueGreenRevision])
+		assert.Equal(t, "app", labels[oam.LabelAppName])
+	}
+	assert.Equal(t, 2, len(traits))
+	assert.Equal(t, "web", traits[0].GetName())
+	assert.Equal(t, "web-preview", traits[1].GetName())
+	assert.Equal(t, rev1, selectorOf(traits[0]))
+	assert.Equal(t, rev1, selectorOf(traits[1]))
+	ports, _, _ := unstructured.NestedSlice(traits[0].Object, "spec", "ports")
+	assert.Equal(t, 1, len(ports))
+
+	// a new application revision not changing the component keeps the workload
+	render(af, wl, newComp("app-v2", "nginx:1.20"))
+	assert.Equal(t, rev1, af.BlueGreenRevisions["web"])
+
+	// the stable Service keeps selecting the active revision until the newest one is promoted
+	af.BlueGreenActive = map[string]string{"web": rev1}
+	v2, traits := render(af, wl, newComp("app-v3", "nginx:1.21"))
+	rev2 := af.BlueGreenRevisions["web"]
+	assert.NotEqual(t, rev1, rev2)
+	assert.Equal(t, BlueGreenWorkloadName("web", rev2), v2.GetName())
+	assert.Equal(t, rev1, selectorOf(traits[0]))
+	assert.Equal(t, rev2, selectorOf(traits[1]))
+
+	// the selector paths declared by the definition are used for unknown kinds
+	crd := &v1alpha2.Component{}
+	crd.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Server","spec":{"podLabels":{"app":"web"}}}`)}
+	wl = &Workload{Name: "web", BlueGreen: &BlueGreen{Type: "blue-green", Port: 80, TargetPort: 8080}}
+	assert.Error(t, af.prepareBlueGreen(wl, crd, &v1alpha2.ApplicationConfigurationComponent{}))
+	wl.FullTemplate = &Template{ComponentDefinition: &v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{oam.AnnotationSelectorPaths: "spec.podLabels"},
+	}}}
+	obj, traits := render(af, wl, crd)
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "podLabels")
+	assert.Equal(t, af.BlueGreenRevisions["web"], labels[oam.LabelBlueGreenRevision])
+	assert.Equal(t, "web", labels["app"])
+	port, _, _ := unstructured.NestedSlice(traits[0].Object, "spec", "ports")
+	assert.Equal(t, float64(8080), port[0].(map[string]interface{})["targetPort"])
+}
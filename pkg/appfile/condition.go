@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// ConditionContext is the field of the application context in the condition of components and traits
+	ConditionContext = "context"
+	// ConditionPolicies is the field of the policy properties in the condition of components and traits,
+	// the key is the name of policy
+	ConditionPolicies = "policies"
+	// ConditionLabels is the field of the application labels in the condition context
+	ConditionLabels = "labels"
+	// ConditionAnnotations is the field of the application annotations in the condition context
+	ConditionAnnotations = "annotations"
+	// ConditionEnvironment is the field of the application environment. It's the name of the environment in the
+	// condition context, and the environment with its name, labels and targets in the condition of components and
+	// traits, which are empty if the application has no environment.
+	ConditionEnvironment = "environment"
+
+	conditionResult = "result"
+)
+
+// conditionEvaluator evaluates the `if` expressions of components and traits
+type conditionEvaluator struct {
+	base string
+}
+
+// conditionEnvironment is the environment the conditions can refer to
+type conditionEnvironment struct {
+	Name    string                      `json:"name"`
+	Labels  map[string]string           `json:"labels"`
+	Targets []v1beta1.EnvironmentTarget `json:"targets"`
+}
+
+// newConditionEvaluator prepares the app context, the environment and policy parameters which the conditions can
+// refer to, e.g., `context.namespace == "dev"`, `context.labels["env"] != "prod"`, `environment.name == "dev"`,
+// `len(environment.targets) > 1` or `policies.debug.enabled`. The environment is nil if the application has none.
+func newConditionEvaluator(app *v1beta1.Application, env *v1beta1.Environment) (*conditionEvaluator, error) {
+	appCtx := map[string]interface{}{
+		process.ContextAppName:   app.Name,
+		process.ContextNamespace: app.Namespace,
+		ConditionLabels:          emptyIfNil(app.Labels),
+		ConditionAnnotations:     emptyIfNil(app.Annotations),
+		ConditionEnvironment:     app.Spec.Environment,
+	}
+	environment := conditionEnvironment{Name: app.Spec.Environment, Labels: map[string]string{}, Targets: []v1beta1.EnvironmentTarget{}}
+	if env != nil {
+		environment.Labels = emptyIfNil(env.Labels)
+		if len(env.Spec.Targets) != 0 {
+			environment.Targets = env.Spec.Targets
+		}
+	}
+	policies := map[string]interface{}{}
+	for _, p := range app.Spec.Policies {
+		properties, err := util.RawExtension2Map(&p.Properties)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "fail to parse properties of policy %s", p.Name)
+		}
+		if properties == nil {
+			properties = map[string]interface{}{}
+		}
+		policies[p.Name] = properties
+	}
+	ctxJSON, err := json.Marshal(appCtx)
+	if err != nil {
+		return nil, err
+	}
+	policiesJSON, err := json.Marshal(policies)
+	if err != nil {
+		return nil, err
+	}
+	envJSON, err := json.Marshal(environment)
+	if err != nil {
+		return nil, err
+	}
+	return &conditionEvaluator{
+		base: fmt.Sprintf("%s: %s\n%s: %s\n%s: %s\n", ConditionContext, ctxJSON, ConditionEnvironment, envJSON,
+			ConditionPolicies, policiesJSON),
+	}, nil
+}
+
+// eval returns true if the condition is empty or evaluated to true
+func (e *conditionEvaluator) eval(condition string) (bool, error) {
+	if len(strings.TrimSpace(condition)) == 0 {
+		return true, nil
+	}
+	var r cue.Runtime
+	ins, err := r.Compile("-", fmt.Sprintf("%s%s: (%s)\n", e.base, conditionResult, condition))
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid condition %q", condition)
+	}
+	result, err := ins.Lookup(conditionResult).Bool()
+	if err != nil {
+		return false, errors.Wrapf(err, "condition %q must be evaluated to a bool", condition)
+	}
+	return result, nil
+}
+
+func emptyIfNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
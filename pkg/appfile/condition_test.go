@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestConditionEvaluator(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "dev",
+			Labels:    map[string]string{"env": "dev"},
+		},
+		Spec: v1beta1.ApplicationSpec{
+			Policies: []v1beta1.AppPolicy{{
+				Name:       "debug",
+				Type:       "debug",
+				Properties: runtime.RawExtension{Raw: []byte(`{"enabled":true,"replicas":2}`)},
+			}},
+		},
+	}
+	conditions, err := newConditionEvaluator(app, nil)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		"empty condition":    {condition: "", want: true},
+		"namespace":          {condition: `context.namespace == "dev"`, want: true},
+		"app name":           {condition: `context.appName != "app"`, want: false},
+		"label":              {condition: `context.labels["env"] == "prod"`, want: false},
+		"policy parameter":   {condition: `policies.debug.enabled && policies.debug.replicas > 1`, want: true},
+		"no environment":     {condition: `environment.name == "" && len(environment.targets) == 0`, want: true},
+		"not a bool":         {condition: `context.namespace`, wantErr: true},
+		"invalid expression": {condition: `context.namespace ==`, wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := conditions.eval(tc.condition)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConditionEvaluatorEnvironment(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       v1beta1.ApplicationSpec{Environment: "prod"},
+	}
+	env := &v1beta1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"tier": "critical"}},
+		Spec: v1beta1.EnvironmentSpec{Targets: []v1beta1.EnvironmentTarget{
+			{Namespace: "prod-hangzhou"}, {Namespace: "prod-beijing"},
+		}},
+	}
+	conditions, err := newConditionEvaluator(app, env)
+	assert.NoError(t, err)
+
+	for condition, want := range map[string]bool{
+		`context.environment == "prod"`:                       true,
+		`environment.name == "dev"`:                           false,
+		`environment.labels["tier"] == "critical"`:            true,
+		`len(environment.targets) > 1`:                        true,
+		`environment.targets[0].namespace == "prod-hangzhou"`: true,
+	} {
+		got, err := conditions.eval(condition)
+		assert.NoError(t, err, condition)
+		assert.Equal(t, want, got, condition)
+	}
+}
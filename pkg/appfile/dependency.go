@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// resolveDependencies checks every dependency refers to a component of the application and there's no cycle
+// in the dependency graph. The dependencies on the components disabled by `if` are dropped.
+func resolveDependencies(app *v1beta1.Application, wds []*Workload) error {
+	defined := map[string]bool{}
+	for _, comp := range app.Spec.Components {
+		defined[comp.Name] = true
+	}
+	enabled := map[string]bool{}
+	for _, wd := range wds {
+		enabled[wd.Name] = true
+	}
+	for _, wd := range wds {
+		var deps []string
+		for _, dep := range wd.DependsOn {
+			switch {
+			case dep == wd.Name:
+				return errors.Errorf("component(%s) depends on itself", wd.Name)
+			case !defined[dep]:
+				return errors.Errorf("component(%s) depends on unknown component %s", wd.Name, dep)
+			case enabled[dep]:
+				deps = append(deps, dep)
+			}
+		}
+		wd.DependsOn = deps
+	}
+	return checkDependencyCycle(wds)
+}
+
+func checkDependencyCycle(wds []*Workload) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	deps := map[string][]string{}
+	for _, wd := range wds {
+		deps[wd.Name] = wd.DependsOn
+	}
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return errors.Errorf("dependency cycle detected: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, wd := range wds {
+		if err := visit(wd.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
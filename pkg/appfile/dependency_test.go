@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestResolveDependencies(t *testing.T) {
+	testCases := map[string]struct {
+		deps     map[string][]string
+		disabled []string
+		want     map[string][]string
+		wantErr  string
+	}{
+		"no dependency": {
+			deps: map[string][]string{"a": nil, "b": nil},
+			want: map[string][]string{"a": nil, "b": nil},
+		},
+		"chain": {
+			deps: map[string][]string{"a": nil, "b": {"a"}, "c": {"a", "b"}},
+			want: map[string][]string{"a": nil, "b": {"a"}, "c": {"a", "b"}},
+		},
+		"disabled dependency": {
+			deps:     map[string][]string{"a": nil, "b": {"a"}},
+			disabled: []string{"a"},
+			want:     map[string][]string{"b": nil},
+		},
+		"unknown dependency": {
+			deps:    map[string][]string{"a": {"db"}},
+			wantErr: "component(a) depends on unknown component db",
+		},
+		"self dependency": {
+			deps:    map[string][]string{"a": {"a"}},
+			wantErr: "component(a) depends on itself",
+		},
+		"cycle": {
+			deps:    map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}},
+			wantErr: "dependency cycle detected",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			app := &v1beta1.Application{}
+			var wds []*Workload
+			for _, comp := range []string{"a", "b", "c"} {
+				deps, ok := tc.deps[comp]
+				if !ok {
+					continue
+				}
+				app.Spec.Components = append(app.Spec.Components, v1beta1.ApplicationComponent{Name: comp, DependsOn: deps})
+				disabled := false
+				for _, d := range tc.disabled {
+					disabled = disabled || d == comp
+				}
+				if !disabled {
+					wds = append(wds, &Workload{Name: comp, DependsOn: deps})
+				}
+			}
+			err := resolveDependencies(app, wds)
+			if len(tc.wantErr) != 0 {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			got := map[string][]string{}
+			for _, wd := range wds {
+				got[wd.Name] = wd.DependsOn
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// TypeDependsOnApp is the type of the policy and the workflow step declaring another application which must be
+// healthy before the components of this application are deployed, e.g., a platform service of a tenant application
+const TypeDependsOnApp = "depends-on-app"
+
+// DependsOnAppSpec is the properties of the depends-on-app policy and workflow step
+type DependsOnAppSpec struct {
+	// Name is the name of the application depended on
+	Name string `json:"name"`
+	// Namespace is the namespace of the application depended on, it's the namespace of this application if empty
+	Namespace string `json:"namespace,omitempty"`
+	// Components is the names of the components waiting for the application, all components wait for it if empty
+	Components []string `json:"components,omitempty"`
+	// Timeout is the duration to wait before reporting the dependency as timed out, e.g., 10m, it waits forever
+	// silently if empty
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// AppDependency is an application which must be healthy before the components of this application are deployed
+type AppDependency struct {
+	Name       string
+	Namespace  string
+	Components []string
+	Timeout    time.Duration
+	// StepName is the name of the workflow step declaring the dependency, it's empty if declared by a policy
+	StepName string
+	// SubStepName is the name of the sub-step declaring the dependency if it's declared in a step group
+	SubStepName string
+}
+
+func parseAppDependencies(app *v1beta1.Application) ([]AppDependency, error) {
+	var deps []AppDependency
+	for _, p := range app.Spec.Policies {
+		if p.Type != TypeDependsOnApp {
+			continue
+		}
+		dep, err := newAppDependency(app, p.Properties.Raw)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid properties of policy %s", p.Name)
+		}
+		deps = append(deps, *dep)
+	}
+	for _, step := range app.Spec.Workflow {
+		if step.Type != TypeDependsOnApp {
+			continue
+		}
+		dep, err := newAppDependency(app, step.Properties.Raw)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid properties of workflow step %s", step.Name)
+		}
+		dep.StepName = step.Name
+		deps = append(deps, *dep)
+	}
+	for _, step := range app.Spec.Workflow {
+		for _, sub := range step.SubSteps {
+			if sub.Type != TypeDependsOnApp {
+				continue
+			}
+			dep, err := newAppDependency(app, sub.Properties.Raw)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid properties of sub-step %s of workflow step %s", sub.Name, step.Name)
+			}
+			dep.StepName, dep.SubStepName = step.Name, sub.Name
+			deps = append(deps, *dep)
+		}
+	}
+	return deps, nil
+}
+
+func newAppDependency(app *v1beta1.Application, raw []byte) (*AppDependency, error) {
+	spec := &DependsOnAppSpec{}
+	if len(raw) != 0 {
+		if err := json.Unmarshal(raw, spec); err != nil {
+			return nil, err
+		}
+	}
+	if len(spec.Name) == 0 {
+		return nil, errors.New("the name of the application depended on is required")
+	}
+	dep := &AppDependency{
+		Name:       spec.Name,
+		Namespace:  spec.Namespace,
+		Components: spec.Components,
+	}
+	if len(dep.Namespace) == 0 {
+		dep.Namespace = app.Namespace
+	}
+	if dep.Name == app.Name && dep.Namespace == app.Namespace {
+		return nil, errors.New("an application cannot depend on itself")
+	}
+	if len(spec.Timeout) != 0 {
+		timeout, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid timeout %q", spec.Timeout)
+		}
+		dep.Timeout = timeout
+	}
+	return dep, nil
+}
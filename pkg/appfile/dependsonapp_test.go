@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestParseAppDependencies(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "default"},
+		Spec: v1beta1.ApplicationSpec{
+			Policies: []v1beta1.AppPolicy{{
+				Name:       "platform",
+				Type:       TypeDependsOnApp,
+				Properties: runtime.RawExtension{Raw: []byte(`{"name":"platform","namespace":"vela-system","timeout":"10m"}`)},
+			}},
+			Workflow: []v1beta1.WorkflowStep{{
+				Name:       "wait-db",
+				Type:       TypeDependsOnApp,
+				Properties: runtime.RawExtension{Raw: []byte(`{"name":"db","components":["backend"]}`)},
+			}, {
+				Name: "other",
+				Type: "suspend",
+			}, {
+				Name: "wait-regions",
+				Type: "step-group",
+				SubSteps: []v1beta1.WorkflowSubStep{{
+					Name:       "wait-cache",
+					Type:       TypeDependsOnApp,
+					Properties: runtime.RawExtension{Raw: []byte(`{"name":"cache"}`)},
+				}},
+			}},
+		},
+	}
+	deps, err := parseAppDependencies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, []AppDependency{
+		{Name: "platform", Namespace: "vela-system", Timeout: 10 * time.Minute},
+		{Name: "db", Namespace: "default", Components: []string{"backend"}, StepName: "wait-db"},
+		{Name: "cache", Namespace: "default", StepName: "wait-regions", SubStepName: "wait-cache"},
+	}, deps)
+
+	for _, props := range []string{`{}`, `{"name":"tenant"}`, `{"name":"db","timeout":"forever"}`} {
+		app.Spec.Policies[0].Properties = runtime.RawExtension{Raw: []byte(props)}
+		_, err := parseAppDependencies(app)
+		assert.Error(t, err, props)
+	}
+}
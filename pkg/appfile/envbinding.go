@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// PolicyTypeEnvBinding is the type of the policy declaring named environments, each of them is a namespace in the
+// selected clusters and a patch to the components, the deploy workflow steps deploy to them by names
+const PolicyTypeEnvBinding = "env-binding"
+
+// EnvBindingSpec is the properties of the env-binding policy
+type EnvBindingSpec struct {
+	Envs []EnvConfig `json:"envs"`
+}
+
+// EnvConfig is an environment the components are deployed to
+type EnvConfig struct {
+	// Name is the name of the environment, it's unique in all the env-binding policies of the application
+	Name string `json:"name"`
+	// Placement selects the clusters and the namespace of the environment
+	Placement TopologyPolicySpec `json:"placement"`
+	// Patch patches the components deployed to the environment
+	Patch *OverridePolicySpec `json:"patch,omitempty"`
+
+	policy string
+}
+
+// key is the name the environment is deployed under as a topology policy and an override policy, it's qualified by
+// the name of the env-binding policy so it doesn't conflict with the names of the other policies
+func (e *EnvConfig) key() string {
+	return e.policy + "/" + e.Name
+}
+
+func parseEnvBindings(app *v1beta1.Application) (map[string]*EnvConfig, error) {
+	compNames := componentNames(app)
+	envs := map[string]*EnvConfig{}
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeEnvBinding {
+			continue
+		}
+		spec := &EnvBindingSpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		if len(spec.Envs) == 0 {
+			return nil, errors.Errorf("policy %s declares no environment", p.Name)
+		}
+		for i := range spec.Envs {
+			env := &spec.Envs[i]
+			if len(env.Name) == 0 {
+				return nil, errors.Errorf("environment %d of policy %s has no name", i, p.Name)
+			}
+			if last, ok := envs[env.Name]; ok {
+				return nil, errors.Errorf("environment %s is declared by both policy %s and %s", env.Name, last.policy, p.Name)
+			}
+			if err := validateTopology(&env.Placement); err != nil {
+				return nil, errors.WithMessagef(err, "placement of environment %s of policy %s", env.Name, p.Name)
+			}
+			if env.Patch != nil {
+				if err := validateOverride(env.Patch, compNames); err != nil {
+					return nil, errors.WithMessagef(err, "patch of environment %s of policy %s", env.Name, p.Name)
+				}
+			}
+			env.policy = p.Name
+			envs[env.Name] = env
+		}
+	}
+	return envs, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestDeployToEnv(t *testing.T) {
+	newApp := func(envs, deploy string) *v1beta1.Application {
+		return &v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+			Components: []v1beta1.ApplicationComponent{{Name: "web", Type: "webservice"}},
+			Policies: []v1beta1.AppPolicy{{
+				Name:       "envs",
+				Type:       PolicyTypeEnvBinding,
+				Properties: runtime.RawExtension{Raw: []byte(envs)},
+			}, {
+				Name:       "debug",
+				Type:       PolicyTypeOverride,
+				Properties: runtime.RawExtension{Raw: []byte(`{"components":[{"traits":[{"type":"sidecar"}]}]}`)},
+			}},
+			Workflow: []v1beta1.WorkflowStep{{
+				Name:       "deploy",
+				Type:       TypeDeploy,
+				Properties: runtime.RawExtension{Raw: []byte(deploy)},
+			}},
+		}}
+	}
+	parse := func(app *v1beta1.Application) (*Appfile, error) {
+		af := &Appfile{}
+		var err error
+		if af.Topologies, err = parseTopologyPolicies(app); err != nil {
+			return nil, err
+		}
+		if af.Overrides, err = parseOverridePolicies(app); err != nil {
+			return nil, err
+		}
+		af.DeploySteps, err = parseDeploySteps(app, af)
+		return af, err
+	}
+
+	envs := `{"envs":[
+		{"name":"staging","placement":{"clusters":["dev"],"namespace":"staging"}},
+		{"name":"prod","placement":{"clusterLabelSelector":{"env":"prod"}},"patch":{"components":[{"name":"web","properties":{"replicas":3}}]}}]}`
+	af, err := parse(newApp(envs, `{"env":"prod","policies":["debug"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"envs/prod", "debug"}, af.DeploySteps["deploy"].Policies)
+	assert.Equal(t, map[string]string{"env": "prod"}, af.Topologies["envs/prod"].ClusterLabelSelector)
+	assert.JSONEq(t, `{"replicas":3}`, string(af.Overrides["envs/prod"].Components[0].Properties.Raw))
+
+	af, err = parse(newApp(envs, `{"env":"staging"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"envs/staging"}, af.DeploySteps["deploy"].Policies)
+	assert.Equal(t, "staging", af.Topologies["envs/staging"].Namespace)
+	assert.NotContains(t, af.Overrides, "envs/staging")
+
+	testCases := map[string]struct {
+		envs   string
+		deploy string
+		err    string
+	}{
+		"env not found": {
+			envs:   envs,
+			deploy: `{"env":"qa"}`,
+			err:    "environment qa which is not found",
+		},
+		"duplicated env": {
+			envs:   `{"envs":[{"name":"qa","placement":{"clusters":["a"]}},{"name":"qa","placement":{"clusters":["b"]}}]}`,
+			deploy: `{"env":"qa"}`,
+			err:    "environment qa is declared by both policy envs and envs",
+		},
+		"no cluster selected": {
+			envs:   `{"envs":[{"name":"qa","placement":{"namespace":"qa"}}]}`,
+			deploy: `{"env":"qa"}`,
+			err:    "placement of environment qa of policy envs",
+		},
+		"component of patch not found": {
+			envs:   `{"envs":[{"name":"qa","placement":{"clusters":["a"]},"patch":{"components":[{"name":"db"}]}}]}`,
+			deploy: `{"env":"qa"}`,
+			err:    "component db overridden is not found",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parse(newApp(tc.envs, tc.deploy))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
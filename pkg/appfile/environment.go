@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+const (
+	// PolicyTypePlacement is the type of the policy declaring the clusters and namespaces the application is deployed to
+	PolicyTypePlacement = "placement"
+	// PolicyNamePlacement is the name of the placement policy expanded from the environment of the application
+	PolicyNamePlacement = "placement"
+)
+
+// PlacementPolicySpec is the properties of the placement policy
+type PlacementPolicySpec struct {
+	// Environment is the name of the environment the placement is expanded from
+	Environment string `json:"environment,omitempty"`
+	// Targets are the clusters and namespaces the application is deployed to
+	Targets []v1beta1.EnvironmentTarget `json:"targets,omitempty"`
+}
+
+// GetEnvironment gets the Environment from the namespace of the application, or the system namespace
+// if it's not found there, so platform teams can share environments across namespaces
+func GetEnvironment(ctx context.Context, cli client.Reader, namespace, name string) (*v1beta1.Environment, error) {
+	env := new(v1beta1.Environment)
+	err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, env)
+	if err == nil || !kerrors.IsNotFound(err) || namespace == oam.SystemDefinitonNamespace {
+		return env, err
+	}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: oam.SystemDefinitonNamespace, Name: name}, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// expandEnvironment returns a copy of the application whose policies are expanded from its environment,
+// i.e., the default policies of the environment not overridden by the application and a placement policy
+// recording the targets of the environment. The values of the parameters of the environment override the ones
+// declared by the application. The environment is returned as well, it's nil if the application has none.
+func expandEnvironment(ctx context.Context, cli client.Reader, app *v1beta1.Application) (*v1beta1.Application, *v1beta1.Environment, error) {
+	if len(app.Spec.Environment) == 0 {
+		return app, nil, nil
+	}
+	env, err := GetEnvironment(ctx, cli, app.Namespace, app.Spec.Environment)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "get environment %s", app.Spec.Environment)
+	}
+	defined := map[string]bool{}
+	for _, p := range app.Spec.Policies {
+		defined[p.Name] = true
+	}
+	var policies []v1beta1.AppPolicy
+	for _, p := range env.Spec.Policies {
+		if defined[p.Name] {
+			decision.Record(ctx, decision.StageParse, p.Name, "default policy of environment %s overridden by the application", env.Name)
+			continue
+		}
+		policies = append(policies, *p.DeepCopy())
+	}
+	if !defined[PolicyNamePlacement] {
+		properties, err := json.Marshal(PlacementPolicySpec{Environment: env.Name, Targets: env.Spec.Targets})
+		if err != nil {
+			return nil, nil, err
+		}
+		policies = append(policies, v1beta1.AppPolicy{
+			Name:       PolicyNamePlacement,
+			Type:       PolicyTypePlacement,
+			Properties: runtime.RawExtension{Raw: properties},
+		})
+	}
+	expanded := app.DeepCopy()
+	expanded.Spec.Policies = append(policies, expanded.Spec.Policies...)
+	var overridden []string
+	expanded.Spec.Parameters, overridden = mergeParameters(expanded.Spec.Parameters, env.Spec.Parameters)
+	for _, name := range overridden {
+		decision.Record(ctx, decision.StageParse, name, "value of parameter overridden by environment %s", env.Name)
+	}
+	return expanded, env, nil
+}
+
+// parsePlacement returns the targets of the placement policies
+func parsePlacement(app *v1beta1.Application) ([]v1beta1.EnvironmentTarget, error) {
+	var targets []v1beta1.EnvironmentTarget
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypePlacement {
+			continue
+		}
+		spec := &PlacementPolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		targets = append(targets, spec.Targets...)
+	}
+	return targets, nil
+}
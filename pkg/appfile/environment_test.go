@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestExpandEnvironment(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, v1beta1.SchemeBuilder.AddToScheme(s))
+	cli := fake.NewFakeClientWithScheme(s, &v1beta1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: oam.SystemDefinitonNamespace},
+		Spec: v1beta1.EnvironmentSpec{
+			Targets: []v1beta1.EnvironmentTarget{{
+				ClusterSelector: &v1beta1.ClusterSelector{Labels: map[string]string{"region": "hangzhou"}},
+				Namespace:       "prod",
+			}},
+			Policies: []v1beta1.AppPolicy{
+				{Name: "security", Type: "security", Properties: runtime.RawExtension{Raw: []byte(`{"level":"high"}`)}},
+				{Name: "debug", Type: "debug", Properties: runtime.RawExtension{Raw: []byte(`{"enabled":false}`)}},
+			},
+		},
+	})
+
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta1.ApplicationSpec{
+			Environment: "prod",
+			Policies: []v1beta1.AppPolicy{
+				{Name: "debug", Type: "debug", Properties: runtime.RawExtension{Raw: []byte(`{"enabled":true}`)}},
+			},
+		},
+	}
+	expanded, env, err := expandEnvironment(context.Background(), cli, app)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", env.Name)
+	// the application itself is not changed
+	assert.Equal(t, 1, len(app.Spec.Policies))
+	var names []string
+	for _, p := range expanded.Spec.Policies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"security", PolicyNamePlacement, "debug"}, names)
+	assert.Equal(t, `{"enabled":true}`, string(expanded.Spec.Policies[2].Properties.Raw))
+
+	targets, err := parsePlacement(expanded)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(targets))
+	assert.Equal(t, "prod", targets[0].Namespace)
+	assert.Equal(t, "hangzhou", targets[0].ClusterSelector.Labels["region"])
+
+	// the environment is required if it's referred
+	app.Spec.Environment = "staging"
+	_, _, err = expandEnvironment(context.Background(), cli, app)
+	assert.Error(t, err)
+
+	// nothing to expand
+	app.Spec.Environment = ""
+	expanded, env, err = expandEnvironment(context.Background(), cli, app)
+	assert.NoError(t, err)
+	assert.Equal(t, app, expanded)
+	assert.Nil(t, env)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// PolicyTypeGarbageCollect is the type of the policy declaring how the rendered resources are garbage collected
+// once they're no longer rendered by the application. Traits can also set the labels oam.LabelGCGracePeriod and
+// oam.LabelGCProtect on the resources they render directly.
+const PolicyTypeGarbageCollect = "garbage-collect"
+
+// GarbageCollectPolicySpec is the properties of the garbage-collect policy
+type GarbageCollectPolicySpec struct {
+	Rules []GarbageCollectPolicyRule `json:"rules"`
+}
+
+// GarbageCollectPolicyRule declares the garbage collection policy of the selected resources
+type GarbageCollectPolicyRule struct {
+	Selector ResourceSelector `json:"selector"`
+	// GracePeriod is the duration to wait before deleting the resources, e.g., 10m
+	GracePeriod string `json:"gracePeriod,omitempty"`
+	// Protect the resources from being deleted, they're orphaned and reported instead
+	Protect bool `json:"protect,omitempty"`
+}
+
+func parseGarbageCollectPolicies(app *v1beta1.Application) ([]GarbageCollectPolicyRule, error) {
+	var rules []GarbageCollectPolicyRule
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeGarbageCollect {
+			continue
+		}
+		spec := &GarbageCollectPolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		for _, rule := range spec.Rules {
+			if len(rule.GracePeriod) == 0 {
+				continue
+			}
+			if _, err := time.ParseDuration(rule.GracePeriod); err != nil {
+				return nil, errors.Wrapf(err, "invalid grace period of policy %s", p.Name)
+			}
+		}
+		rules = append(rules, spec.Rules...)
+	}
+	return rules, nil
+}
+
+// markGarbageCollect labels the rendered workload and traits selected by the garbage-collect policies, the first
+// matching rule wins
+func (af *Appfile) markGarbageCollect(comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) error {
+	if len(af.GarbageCollectRules) == 0 {
+		return nil
+	}
+	if err := af.labelGarbageCollect(comp.Name, &comp.Spec.Workload); err != nil {
+		return errors.WithMessagef(err, "component(%s)", comp.Name)
+	}
+	for i := range acComp.Traits {
+		if err := af.labelGarbageCollect(comp.Name, &acComp.Traits[i].Trait); err != nil {
+			return errors.WithMessagef(err, "component(%s)", comp.Name)
+		}
+	}
+	return nil
+}
+
+func (af *Appfile) labelGarbageCollect(compName string, raw *runtime.RawExtension) error {
+	if len(raw.Raw) == 0 {
+		return nil
+	}
+	res := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw.Raw, &res.Object); err != nil {
+		return err
+	}
+	traitType := res.GetLabels()[oam.TraitTypeLabel]
+	for _, rule := range af.GarbageCollectRules {
+		if !rule.Selector.matches(compName, traitType, res) {
+			continue
+		}
+		labels := res.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if rule.Protect {
+			labels[oam.LabelGCProtect] = "true"
+		}
+		if len(rule.GracePeriod) != 0 {
+			labels[oam.LabelGCGracePeriod] = rule.GracePeriod
+		}
+		res.SetLabels(labels)
+		b, err := json.Marshal(res.Object)
+		if err != nil {
+			return err
+		}
+		raw.Raw = b
+		return nil
+	}
+	return nil
+}
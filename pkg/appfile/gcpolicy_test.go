@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestMarkGarbageCollect(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Policies: []v1beta1.AppPolicy{{
+		Name: "keep-data",
+		Type: PolicyTypeGarbageCollect,
+		Properties: runtime.RawExtension{Raw: []byte(`{"rules":[
+			{"selector":{"resourceTypes":["PersistentVolumeClaim"]},"protect":true},
+			{"selector":{"componentNames":["web"]},"gracePeriod":"10m"}]}`)},
+	}}}}
+	rules, err := parseGarbageCollectPolicies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rules))
+	af := &Appfile{GarbageCollectRules: rules}
+
+	comp := &v1alpha2.Component{}
+	comp.Name = "web"
+	comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment"}`)}
+	acComp := &v1alpha2.ApplicationConfigurationComponent{Traits: []v1alpha2.ComponentTrait{
+		{Trait: runtime.RawExtension{Raw: []byte(`{"kind":"PersistentVolumeClaim","metadata":{"labels":{"trait.oam.dev/type":"storage"}}}`)}},
+	}}
+	labels := func(raw runtime.RawExtension) map[string]interface{} {
+		obj := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(raw.Raw, &obj))
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		labels, _ := metadata["labels"].(map[string]interface{})
+		return labels
+	}
+	assert.NoError(t, af.markGarbageCollect(comp, acComp))
+	assert.Equal(t, map[string]interface{}{oam.LabelGCGracePeriod: "10m"}, labels(comp.Spec.Workload))
+	assert.Equal(t, "true", labels(acComp.Traits[0].Trait)[oam.LabelGCProtect])
+	assert.NotContains(t, labels(acComp.Traits[0].Trait), oam.LabelGCGracePeriod)
+
+	app.Spec.Policies[0].Properties = runtime.RawExtension{Raw: []byte(`{"rules":[{"gracePeriod":"ten minutes"}]}`)}
+	_, err = parseGarbageCollectPolicies(app)
+	assert.Error(t, err)
+}
@@ -0,0 +1,342 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitsource loads the components of the Git schematic, i.e., the manifests of raw Kubernetes resources or
+// the CUE template in a directory of a Git repository pinned to a branch, tag or commit.
+package gitsource
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/load"
+	"cuelang.org/go/cue/token"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+)
+
+var (
+	// DefaultInterval is the interval to sync the branch or tag if it's unspecified
+	DefaultInterval = 5 * time.Minute
+	// MinInterval is the minimum interval to sync the branch or tag, so the repository isn't fetched too frequently
+	MinInterval = 30 * time.Second
+	// MaxTransferSize is the maximum size in bytes of the data transferred to clone a repository
+	MaxTransferSize int64 = 32 << 20
+	// MaxDirectorySize is the maximum total size in bytes of the files loaded from the directory
+	MaxDirectorySize int64 = 4 << 20
+	// FetchTimeout is the timeout of cloning a repository
+	FetchTimeout = 60 * time.Second
+	// MaxCacheEntries is the maximum number of directories in the cache
+	MaxCacheEntries = 64
+)
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// Credentials are the username and password to clone a repository over HTTP(S)
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Fetcher fetches the YAML, JSON and CUE files in the directory of the Git schematic and its sub-directories, the
+// files are keyed by their paths in the repository
+type Fetcher func(ctx context.Context, g *common.Git, cred *Credentials) (map[string][]byte, error)
+
+// Content is the content loaded from the directory, either the manifests or the CUE template is set
+type Content struct {
+	// Manifests are the raw Kubernetes resources in the YAML or JSON files, in the order of the file paths
+	Manifests []*unstructured.Unstructured
+	// Template is the CUE template merged from the CUE files by the CUE loader
+	Template string
+}
+
+// Loader loads the directories of the Git schematic, the directories loaded are cached until their sync intervals
+// elapse, or forever if they're pinned to a commit
+type Loader struct {
+	fetch Fetcher
+	cache *remote.Cache
+}
+
+// NewLoader creates a Loader with the given Fetcher, the default Fetcher cloning the repository is used if it's nil
+func NewLoader(fetch Fetcher) *Loader {
+	if fetch == nil {
+		fetch = gitFetch
+	}
+	return &Loader{fetch: fetch, cache: remote.NewCache()}
+}
+
+// DefaultLoader is the Loader shared by all applications, so the cache takes effect across renderings
+var DefaultLoader = NewLoader(nil)
+
+// Validate checks that at most one of the branch, tag and commit is set, the commit is a SHA, the interval is not
+// less than MinInterval and the repository is an http or https URL
+func Validate(g *common.Git) error {
+	pins := 0
+	for _, p := range []string{g.Branch, g.Tag, g.Commit} {
+		if len(p) != 0 {
+			pins++
+		}
+	}
+	if pins > 1 {
+		return errors.New("only one of branch, tag and commit can be set")
+	}
+	if len(g.Commit) != 0 && !commitPattern.MatchString(g.Commit) {
+		return fmt.Errorf("commit %q is not a SHA", g.Commit)
+	}
+	if g.Interval != nil && g.Interval.Duration < MinInterval {
+		return fmt.Errorf("interval %s is less than the minimum %s", g.Interval.Duration, MinInterval)
+	}
+	u, err := url.Parse(g.Repo)
+	if err != nil {
+		return errors.Wrapf(err, "invalid git repo %q", g.Repo)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("git repo %q is not supported, only http and https URLs are supported", g.Repo)
+	}
+	return nil
+}
+
+// LoadCredentials loads the credentials of the repository from the Secret referred by the Git schematic in the
+// namespace, which is the namespace of the definition. It returns nil if the schematic doesn't refer to a Secret.
+func LoadCredentials(ctx context.Context, c client.Reader, ns string, g *common.Git) (*Credentials, error) {
+	if g.SecretRef == nil || len(g.SecretRef.Name) == 0 {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: g.SecretRef.Name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "cannot get the secret %s of the git repo", g.SecretRef.Name)
+	}
+	password, ok := secret.Data[corev1.BasicAuthPasswordKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s of the git repo has no %s key", g.SecretRef.Name, corev1.BasicAuthPasswordKey)
+	}
+	return &Credentials{Username: string(secret.Data[corev1.BasicAuthUsernameKey]), Password: string(password)}, nil
+}
+
+// Ref returns the ref of the repository, i.e., the commit, tag or branch, default to master
+func Ref(g *common.Git) string {
+	switch {
+	case len(g.Commit) != 0:
+		return g.Commit
+	case len(g.Tag) != 0:
+		return g.Tag
+	case len(g.Branch) != 0:
+		return g.Branch
+	default:
+		return kube.DefaultGitRef
+	}
+}
+
+// SyncInterval returns how often the directory is synced, it's zero if the repository is pinned to a commit
+func SyncInterval(g *common.Git) time.Duration {
+	switch {
+	case len(g.Commit) != 0:
+		return 0
+	case g.Interval != nil && g.Interval.Duration >= MinInterval:
+		return g.Interval.Duration
+	case g.Interval != nil:
+		return MinInterval
+	default:
+		return DefaultInterval
+	}
+}
+
+// Load loads the manifests or the CUE template in the directory of the Git schematic, the repository is cloned with
+// the credentials if they're not nil
+func (l *Loader) Load(ctx context.Context, g *common.Git, cred *Credentials) (*Content, error) {
+	if err := Validate(g); err != nil {
+		return nil, err
+	}
+	key := cacheKey(g)
+	if cred != nil {
+		// the directory cloned with the credentials isn't shared with the definitions without them
+		key += "@" + cred.Username
+	}
+	if content, ok := l.cache.Get(key); ok {
+		return content.(*Content), nil
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	files, err := l.fetch(fetchCtx, g, cred)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot fetch path %q of git repo %q at %s", g.Path, g.Repo, Ref(g))
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no YAML, JSON or CUE files found in path %q of git repo %q", g.Path, g.Repo)
+	}
+	content, err := parseContent(files)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "path %q of git repo %q", g.Path, g.Repo)
+	}
+
+	l.cache.Set(key, content, SyncInterval(g), MaxCacheEntries)
+	return content, nil
+}
+
+// Invalidate removes the directory of the Git schematic from the cache, so it's fetched again on the next load
+func (l *Loader) Invalidate(g *common.Git) {
+	l.cache.Delete(cacheKey(g))
+}
+
+func directory(g *common.Git) string {
+	return strings.Trim(path.Clean("/"+g.Path), "/")
+}
+
+func cacheKey(g *common.Git) string {
+	return fmt.Sprintf("%s@%s:%s", g.Repo, Ref(g), directory(g))
+}
+
+// gitFetch clones the repository at the ref into memory and reads the YAML, JSON and CUE files in the directory
+func gitFetch(ctx context.Context, g *common.Git, cred *Credentials) (map[string][]byte, error) {
+	o := remote.GitOptions{
+		Repo: g.Repo,
+		Ref:  Ref(g),
+		Dir:  directory(g),
+		Accept: func(name string) bool {
+			switch path.Ext(name) {
+			case ".yaml", ".yml", ".json", ".cue":
+				return true
+			default:
+				return false
+			}
+		},
+		MaxTransferSize: MaxTransferSize,
+		MaxSize:         MaxDirectorySize,
+	}
+	if cred != nil {
+		o.Auth = &remote.GitAuth{Username: cred.Username, Password: cred.Password}
+	}
+	return remote.ReadGit(ctx, o)
+}
+
+// parseContent decodes the manifests or merges the CUE template of the files, the directory can't contain both
+func parseContent(files map[string][]byte) (*Content, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	content := &Content{}
+	var cueFiles []string
+	for _, name := range names {
+		if path.Ext(name) == ".cue" {
+			cueFiles = append(cueFiles, name)
+			continue
+		}
+		objs, err := kube.DecodeManifest(files[name])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid manifest %s", name)
+		}
+		content.Manifests = append(content.Manifests, objs...)
+	}
+	if len(cueFiles) != 0 && len(content.Manifests) != 0 {
+		return nil, errors.New("it contains both manifests and CUE files")
+	}
+	if len(cueFiles) != 0 {
+		template, err := mergeCUEFiles(cueFiles, files)
+		if err != nil {
+			return nil, err
+		}
+		content.Template = template
+	}
+	if len(content.Template) == 0 && len(content.Manifests) == 0 {
+		return nil, errors.New("no resources found")
+	}
+	return content, nil
+}
+
+// mergeCUEFiles loads the CUE files with the CUE loader, so they must belong to the same package unless they have no
+// package clause, and merges them into one template. The package clauses are dropped, and the imports are hoisted to
+// the top of the template and deduplicated.
+func mergeCUEFiles(names []string, files map[string][]byte) (string, error) {
+	const dir = "/git"
+	overlay := map[string]load.Source{}
+	args := make([]string, 0, len(names))
+	origin := map[string]string{}
+	for i, name := range names {
+		// the files named for the loader must be in one directory, so the files of the sub-directories are flattened
+		file := fmt.Sprintf("%d-%s", i, path.Base(name))
+		overlay[path.Join(dir, file)] = load.FromBytes(files[name])
+		args = append(args, "./"+file)
+		origin[file] = name
+	}
+	insts := load.Instances(args, &load.Config{Dir: dir, Overlay: overlay})
+	if len(insts) != 1 {
+		return "", fmt.Errorf("CUE files are loaded into %d instances, expected one", len(insts))
+	}
+	if err := insts[0].Err; err != nil {
+		return "", errors.Wrapf(err, "invalid CUE files %s", strings.Join(names, ", "))
+	}
+
+	var specs []*ast.ImportSpec
+	var decls []ast.Decl
+	imported := map[string]bool{}
+	for _, f := range insts[0].Files {
+		first := true
+		for _, d := range f.Decls {
+			switch x := d.(type) {
+			case *ast.Package:
+				continue
+			case *ast.ImportDecl:
+				for _, spec := range x.Specs {
+					key := spec.Path.Value
+					if spec.Name != nil {
+						key = spec.Name.Name + " " + key
+					}
+					if !imported[key] {
+						imported[key] = true
+						ast.SetRelPos(spec, token.Newline)
+						specs = append(specs, spec)
+					}
+				}
+				continue
+			}
+			if first {
+				// keep the declarations of the files apart
+				ast.SetRelPos(d, token.NewSection)
+				ast.AddComment(d, &ast.CommentGroup{Doc: true, List: []*ast.Comment{{Text: "// " + origin[path.Base(f.Filename)]}}})
+				first = false
+			}
+			decls = append(decls, d)
+		}
+	}
+	merged := &ast.File{}
+	if len(specs) != 0 {
+		merged.Decls = append(merged.Decls, &ast.ImportDecl{Lparen: token.NoSpace.Pos(), Specs: specs, Rparen: token.Newline.Pos()})
+	}
+	merged.Decls = append(merged.Decls, decls...)
+	b, err := format.Node(merged)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot format the merged CUE template")
+	}
+	return string(b), nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cuelang.org/go/cue"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const (
+	deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+`
+	service = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+	template = `output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+}
+parameter: image: string
+`
+)
+
+func TestLoad(t *testing.T) {
+	repo := map[string]map[string]string{
+		"deploy": {
+			"deploy/b-service.yaml":    service,
+			"deploy/a-deployment.yaml": deployment,
+		},
+		"cue":   {"cue/template.cue": template},
+		"mixed": {"mixed/template.cue": template, "mixed/service.yaml": service},
+		"empty": {},
+	}
+	var fetched []string
+	l := NewLoader(func(ctx context.Context, g *common.Git, cred *Credentials) (map[string][]byte, error) {
+		fetched = append(fetched, g.Repo+"@"+Ref(g)+":"+directory(g))
+		files := map[string][]byte{}
+		for name, content := range repo[directory(g)] {
+			files[name] = []byte(content)
+		}
+		return files, nil
+	})
+
+	content, err := l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Tag: "v1.0.0", Path: "deploy"}, nil)
+	require.NoError(t, err)
+	require.Len(t, content.Manifests, 2)
+	assert.Equal(t, "Deployment", content.Manifests[0].GetKind())
+	assert.Equal(t, "Service", content.Manifests[1].GetKind())
+	assert.Empty(t, content.Template)
+	assert.Equal(t, []string{"https://gitlab.com/org/app@v1.0.0:deploy"}, fetched)
+
+	_, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Tag: "v1.0.0", Path: "deploy"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, fetched, 1, "the directory should be cached")
+
+	_, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Tag: "v1.0.0", Path: "deploy"},
+		&Credentials{Username: "bot", Password: "token"})
+	require.NoError(t, err)
+	assert.Len(t, fetched, 2, "the directory cloned with the credentials should be cached apart")
+
+	l.Invalidate(&common.Git{Repo: "https://gitlab.com/org/app", Tag: "v1.0.0", Path: "deploy/"})
+	_, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Tag: "v1.0.0", Path: "deploy"}, nil)
+	require.NoError(t, err)
+	assert.Len(t, fetched, 3, "the directory should be fetched again once invalidated")
+
+	content, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Commit: "0123abc", Path: "/cue/"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, content.Manifests)
+	assert.Contains(t, content.Template, "parameter: image: string")
+
+	_, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Path: "mixed"}, nil)
+	assert.Error(t, err)
+	_, err = l.Load(context.Background(), &common.Git{Repo: "https://gitlab.com/org/app", Path: "empty"}, nil)
+	assert.Error(t, err)
+}
+
+func TestMergeCUEFiles(t *testing.T) {
+	testCases := map[string]struct {
+		files   map[string]string
+		wantErr bool
+	}{
+		"same package": {
+			files: map[string]string{
+				"cue/output.cue": "package app\n\nimport \"strings\"\n\noutput: metadata: name: strings.ToLower(parameter.name)\n",
+				"cue/sub/parameter.cue": "package app\n\nimport (\n\t\"strings\"\n\t\"list\"\n)\n\n" +
+					"parameter: {\n\tname: string\n\tports: [...int]\n}\noutput: spec: ports: list.Sort(parameter.ports, list.Ascending)\n" +
+					"output: metadata: labels: app: strings.ToUpper(parameter.name)\n",
+			},
+		},
+		"no package clauses": {
+			files: map[string]string{
+				"output.cue":    "output: metadata: name: parameter.name\n",
+				"parameter.cue": "parameter: name: string\n",
+			},
+		},
+		"different packages": {
+			files: map[string]string{
+				"output.cue":    "package output\n\noutput: metadata: name: parameter.name\n",
+				"parameter.cue": "package parameter\n\nparameter: name: string\n",
+			},
+			wantErr: true,
+		},
+		"syntax error": {
+			files:   map[string]string{"output.cue": "output: {\n"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			files := map[string][]byte{}
+			for name, content := range tc.files {
+				files[name] = []byte(content)
+			}
+			content, err := parseContent(files)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, content.Template, "package ")
+
+			// the merged template is valid CUE rendering with the parameter
+			var r cue.Runtime
+			inst, err := r.Compile("-", content.Template+"\nparameter: {name: \"Web\", ports: [443, 80]}\n")
+			require.NoError(t, err, content.Template)
+			got, err := inst.Lookup("output", "metadata", "name").String()
+			require.NoError(t, err)
+			assert.Contains(t, []string{"web", "Web"}, got)
+		})
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "vela-system", Name: "git-creds"},
+		Data:       map[string][]byte{corev1.BasicAuthUsernameKey: []byte("bot"), corev1.BasicAuthPasswordKey: []byte("token")},
+	}
+	c := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+		if key.Namespace != secret.Namespace || key.Name != secret.Name {
+			return kerrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+		}
+		secret.DeepCopyInto(obj.(*corev1.Secret))
+		return nil
+	}}
+	g := &common.Git{Repo: "https://gitlab.com/org/app"}
+
+	cred, err := LoadCredentials(context.Background(), c, "vela-system", g)
+	require.NoError(t, err)
+	assert.Nil(t, cred)
+
+	g.SecretRef = &corev1.LocalObjectReference{Name: "git-creds"}
+	cred, err = LoadCredentials(context.Background(), c, "vela-system", g)
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "bot", Password: "token"}, cred)
+
+	// the Secret is only looked up in the namespace of the definition
+	_, err = LoadCredentials(context.Background(), c, "tenant", g)
+	assert.Error(t, err)
+
+	delete(secret.Data, corev1.BasicAuthPasswordKey)
+	_, err = LoadCredentials(context.Background(), c, "vela-system", g)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	testCases := map[string]struct {
+		git     common.Git
+		wantErr bool
+	}{
+		"branch": {git: common.Git{Repo: "https://github.com/org/app", Branch: "main"}},
+		"commit": {git: common.Git{Repo: "https://github.com/org/app", Commit: "0123abcd"}},
+		"branch and tag": {
+			git:     common.Git{Repo: "https://github.com/org/app", Branch: "main", Tag: "v1"},
+			wantErr: true,
+		},
+		"commit not sha": {
+			git:     common.Git{Repo: "https://github.com/org/app", Commit: "main"},
+			wantErr: true,
+		},
+		"interval too short": {
+			git:     common.Git{Repo: "https://github.com/org/app", Interval: &metav1.Duration{Duration: time.Second}},
+			wantErr: true,
+		},
+		"gitlab": {git: common.Git{Repo: "https://gitlab.com/org/app"}},
+		"ssh": {
+			git:     common.Git{Repo: "git@github.com:org/app.git"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(&tc.git)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	assert.Equal(t, time.Duration(0), SyncInterval(&common.Git{Commit: "0123abc", Interval: &metav1.Duration{Duration: time.Hour}}))
+	assert.Equal(t, time.Hour, SyncInterval(&common.Git{Branch: "main", Interval: &metav1.Duration{Duration: time.Hour}}))
+	assert.Equal(t, DefaultInterval, SyncInterval(&common.Git{Tag: "v1"}))
+	assert.Equal(t, "master", Ref(&common.Git{}))
+	assert.Equal(t, "v1", Ref(&common.Git{Tag: "v1"}))
+}
@@ -38,6 +38,8 @@ import (
 // HelmRepositorySpec defines the reference to a Helm repository.
 type HelmRepositorySpec struct {
 	// The Helm repository URL, a valid URL contains at least a protocol and host.
+	// The charts in OCI registries are referred by the oci:// scheme, e.g., oci://ghcr.io/org/charts,
+	// they're rendered by KubeVela rather than Flux.
 	// +required
 	URL string `json:"url"`
 
@@ -47,8 +49,9 @@ type HelmRepositorySpec struct {
 	// password fields.
 	// For TLS the secret must contain a certFile and keyFile, and/or
 	// caCert fields.
+	// For OCI registries, the secret can also be of type kubernetes.io/dockerconfigjson.
 	// +optional
-	// SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
 
 	// The interval at which to check the upstream for updates.
 	// make it optional in KubeVela
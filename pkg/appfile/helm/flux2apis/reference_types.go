@@ -89,3 +89,11 @@ type ValuesReference struct {
 	// +optional
 	Optional bool `json:"optional,omitempty"`
 }
+
+// LocalObjectReference contains enough information to locate the referenced Kubernetes resource object in the same
+// namespace.
+type LocalObjectReference struct {
+	// Name of the referent.
+	// +required
+	Name string `json:"name"`
+}
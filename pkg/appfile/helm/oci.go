@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+// ChartLayerMediaType is the media type of the layer containing the chart archive in an OCI artifact
+const ChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+var (
+	// ChartCacheTTL is how long a chart pulled from an OCI registry is cached
+	ChartCacheTTL = 5 * time.Minute
+	// MaxChartCacheEntries is the maximum number of charts in the cache
+	MaxChartCacheEntries = 64
+
+	// newOCIClient creates the client pulling charts from OCI registries
+	newOCIClient = func() *oci.Client { return &oci.Client{HTTPClient: remote.HTTPClient()} }
+
+	chartCache = struct {
+		sync.Mutex
+		entries map[string]chartCacheEntry
+	}{entries: map[string]chartCacheEntry{}}
+)
+
+type chartCacheEntry struct {
+	files     []*loader.BufferedFile
+	fetchedAt time.Time
+}
+
+// Credentials are the credentials of an OCI registry
+type Credentials = oci.Credentials
+
+// IsOCIRepository returns whether the repository of the Helm schematic is an OCI registry, i.e., its URL has the
+// oci:// scheme
+func IsOCIRepository(h *common.Helm) bool {
+	_, repoSpec, err := decodeHelmSpec(h)
+	return err == nil && isOCIURL(repoSpec.URL)
+}
+
+func isOCIURL(u string) bool {
+	return strings.HasPrefix(u, oci.SchemePrefix)
+}
+
+// LoadRepositoryCredentials loads the credentials of the OCI registry from the Secret referred by the repository of
+// the Helm schematic in the namespace. It returns nil if the repository doesn't refer to a Secret. The Secret holds
+// either the username and password keys or the docker config of type kubernetes.io/dockerconfigjson.
+func LoadRepositoryCredentials(ctx context.Context, c client.Reader, ns string, h *common.Helm) (*Credentials, error) {
+	_, repoSpec, err := decodeHelmSpec(h)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Helm spec is invalid")
+	}
+	if repoSpec.SecretRef == nil || len(repoSpec.SecretRef.Name) == 0 {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: repoSpec.SecretRef.Name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "cannot get the secret %s of the Helm repository", repoSpec.SecretRef.Name)
+	}
+	ref, err := oci.ParseReference(strings.TrimSuffix(repoSpec.URL, "/") + "/chart")
+	if err != nil {
+		return nil, err
+	}
+	return oci.CredentialsFromSecret(secret, ref.Registry)
+}
+
+// chartReference returns the reference of the chart in the OCI registry, e.g., oci://ghcr.io/org/charts with chart
+// podinfo and version 6.0.0 refers to ghcr.io/org/charts/podinfo:6.0.0
+func chartReference(repoURL, chart, version string) (oci.Reference, error) {
+	if len(version) == 0 {
+		return oci.Reference{}, fmt.Errorf("the version of chart %s in OCI registry is required", chart)
+	}
+	ref := strings.TrimSuffix(repoURL, "/") + "/" + chart
+	if strings.HasPrefix(version, "sha256:") {
+		return oci.ParseReference(ref + "@" + version)
+	}
+	// OCI tags don't allow "+", so Helm replaces it with "_" when pushing charts
+	return oci.ParseReference(ref + ":" + strings.ReplaceAll(version, "+", "_"))
+}
+
+// PullOCIChart pulls the files of the chart from the OCI registry, the charts pulled are cached for ChartCacheTTL
+func PullOCIChart(ctx context.Context, repoURL, chart, version string, cred *Credentials) ([]*loader.BufferedFile, error) {
+	ref, err := chartReference(repoURL, chart, version)
+	if err != nil {
+		return nil, err
+	}
+	c := newOCIClient()
+	if cred != nil {
+		c.Username, c.Password = cred.Username, cred.Password
+	}
+	key := ref.String() + "@" + c.Username
+	chartCache.Lock()
+	entry, ok := chartCache.entries[key]
+	chartCache.Unlock()
+	if ok && (ref.Pinned() || time.Since(entry.fetchedAt) < ChartCacheTTL) {
+		return entry.files, nil
+	}
+
+	data, err := c.PullLayer(ctx, ref, ChartLayerMediaType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot pull Chart from OCI registry")
+	}
+	files, err := loader.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load Chart files")
+	}
+
+	chartCache.Lock()
+	defer chartCache.Unlock()
+	if len(chartCache.entries) >= MaxChartCacheEntries {
+		// the charts are small, so the cache is simply reset rather than evicting the oldest one
+		chartCache.entries = map[string]chartCacheEntry{}
+	}
+	chartCache.entries[key] = chartCacheEntry{files: files, fetchedAt: time.Now()}
+	return files, nil
+}
+
+// RenderOCIChart pulls the chart of the Helm schematic from the OCI registry and renders its templates with the
+// values, which override the values of the release spec. The CRDs of the chart come first, followed by the other
+// resources in the order of the template names. Flux doesn't support OCI registries, so the resources are rendered
+// by KubeVela and applied like raw K8s resources.
+func RenderOCIChart(ctx context.Context, helmSpec *common.Helm, compName, appName, ns string, values map[string]interface{}, cred *Credentials) ([]*unstructured.Unstructured, error) {
+	releaseSpec, repoSpec, err := decodeHelmSpec(helmSpec)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Helm spec is invalid")
+	}
+	chartSpec := releaseSpec.Chart.Spec
+	files, err := PullOCIChart(ctx, repoSpec.URL, chartSpec.Chart, chartSpec.Version, cred)
+	if err != nil {
+		return nil, err
+	}
+	chrt, err := loader.LoadFiles(files)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load Chart")
+	}
+
+	chartValues := map[string]interface{}{}
+	if releaseSpec.Values != nil {
+		if err := json.Unmarshal(releaseSpec.Values.Raw, &chartValues); err != nil {
+			return nil, errors.Wrap(err, "cannot get chart values")
+		}
+	}
+	for k, v := range values {
+		// override values with settings from application
+		chartValues[k] = v
+	}
+	releaseName := releaseSpec.ReleaseName
+	if len(releaseName) == 0 {
+		releaseName = fmt.Sprintf("%s-%s", appName, compName)
+	}
+	renderValues, err := chartutil.ToRenderValues(chrt, chartValues, chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: ns,
+		Revision:  1,
+		IsInstall: true,
+	}, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid chart values")
+	}
+	rendered, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot render Chart")
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, crd := range chrt.CRDs() {
+		o, err := kube.DecodeManifest(crd.Data)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid CRD %s", crd.Name)
+		}
+		objs = append(objs, o...)
+	}
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ext := path.Ext(name); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			// e.g., NOTES.txt
+			continue
+		}
+		o, err := kube.DecodeManifest([]byte(rendered[name]))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid template %s", name)
+		}
+		objs = append(objs, o...)
+	}
+	return objs, nil
+}
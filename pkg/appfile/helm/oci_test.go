@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+func TestChartReference(t *testing.T) {
+	ref, err := chartReference("oci://ghcr.io/org/charts/", "podinfo", "6.0.0+build.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "ghcr.io/org/charts/podinfo:6.0.0_build.1", ref.String())
+
+	_, err = chartReference("oci://ghcr.io/org/charts", "podinfo", "")
+	assert.Error(t, err)
+}
+
+func TestRenderOCIChart(t *testing.T) {
+	files := map[string]string{
+		"podinfo/Chart.yaml":  "apiVersion: v2\nname: podinfo\nversion: 6.0.0\n",
+		"podinfo/values.yaml": "replicaCount: 1\nimage: nginx:1.19\n",
+		"podinfo/templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    spec:
+      containers:
+      - name: web
+        image: {{ .Values.image }}
+`,
+		"podinfo/templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  ports:
+  - port: 80
+`,
+		"podinfo/templates/NOTES.txt": "Installed {{ .Release.Name }}",
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	layer := buf.Bytes()
+	sum := sha256.Sum256(layer)
+	layerDigest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest, err := json.Marshal(oci.Manifest{Layers: []oci.Descriptor{{MediaType: ChartLayerMediaType, Digest: layerDigest, Size: int64(len(layer))}}})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/charts/podinfo/manifests/6.0.0":
+			_, _ = w.Write(manifest)
+		case "/v2/charts/podinfo/blobs/" + layerDigest:
+			_, _ = w.Write(layer)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defaultClient := newOCIClient
+	defer func() { newOCIClient = defaultClient }()
+	newOCIClient = func() *oci.Client { return &oci.Client{PlainHTTP: true} }
+
+	h := testData("podinfo", "6.0.0", "oci://"+strings.TrimPrefix(server.URL, "http://")+"/charts")
+	assert.True(t, IsOCIRepository(h))
+	objs, err := RenderOCIChart(context.Background(), h, "test-comp", "test-app", "test-ns", map[string]interface{}{"replicaCount": 3}, nil)
+	require.NoError(t, err)
+	require.Len(t, objs, 2)
+
+	assert.Equal(t, "Deployment", objs[0].GetKind())
+	assert.Equal(t, "test-app-test-comp", objs[0].GetName())
+	replicas, _, _ := unstructured.NestedInt64(objs[0].Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+	assert.Equal(t, "Service", objs[1].GetKind())
+
+	_, err = RenderOCIChart(context.Background(), testData("podinfo", "7.0.0", "oci://"+strings.TrimPrefix(server.URL, "http://")+"/charts"), "test-comp", "test-app", "test-ns", nil, nil)
+	assert.Error(t, err)
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/format"
@@ -48,13 +49,19 @@ var (
 // GetChartValuesJSONSchema fetched the Chart bundle and get JSON schema of Values
 // file.  If the Chart provides a 'values.json.schema' file, use it directly.
 // Otherwise, try to generate a JSON schema based on the Values file.
-func GetChartValuesJSONSchema(ctx context.Context, h *common.Helm) ([]byte, error) {
+// The credentials are used to pull the Chart from an OCI registry, it's pulled anonymously if they're nil.
+func GetChartValuesJSONSchema(ctx context.Context, h *common.Helm, cred *Credentials) ([]byte, error) {
 	releaseSpec, repoSpec, err := decodeHelmSpec(h)
 	if err != nil {
 		return nil, errors.WithMessage(err, "Helm spec is invalid")
 	}
 	chartSpec := releaseSpec.Chart.Spec
-	files, err := loadChartFiles(ctx, repoSpec.URL, chartSpec.Chart, chartSpec.Version)
+	var files []*loader.BufferedFile
+	if isOCIURL(repoSpec.URL) {
+		files, err = PullOCIChart(ctx, repoSpec.URL, chartSpec.Chart, chartSpec.Version, cred)
+	} else {
+		files, err = loadChartFiles(ctx, repoSpec.URL, chartSpec.Chart, chartSpec.Version)
+	}
 	if err != nil {
 		return nil, errors.WithMessage(err, "cannot load Chart files")
 	}
@@ -64,8 +71,12 @@ func GetChartValuesJSONSchema(ctx context.Context, h *common.Helm) ([]byte, erro
 		case "values.yaml", "values.yml":
 			values = f
 		case "values.schema.json":
-			// use the JSON schema file if exists
-			return f.Data, nil
+			// use the JSON schema file if exists, it's converted since it's a JSON schema rather than OpenAPI v3 one
+			schema, err := convertJSONSchema(f.Data)
+			if err != nil {
+				return nil, errors.WithMessage(err, "cannot convert 'values.schema.json' to OpenAPI v3 schema")
+			}
+			return schema, nil
 		default:
 			continue
 		}
@@ -218,3 +229,144 @@ func handleItemsOfArrayType(t map[string]interface{}) {
 		}
 	}
 }
+
+// maxRefDepth limits the depth of the nested $refs resolved, the recursive schemas are cut off at it
+const maxRefDepth = 10
+
+// unsupportedKeywords are the JSON schema keywords having no counterpart in OpenAPI v3 schema
+var unsupportedKeywords = []string{"$schema", "$id", "id", "$comment", "definitions", "$defs", "patternProperties",
+	"propertyNames", "dependencies", "contains", "if", "then", "else", "additionalItems"}
+
+// convertJSONSchema converts the JSON schema of Chart Values, i.e., 'values.schema.json', to OpenAPI v3 schema.
+// OpenAPI v3 schema is a subset of JSON schema, so the local $refs are inlined, multiple types are converted to
+// nullable and the unsupported keywords are dropped.
+func convertJSONSchema(data []byte) ([]byte, error) {
+	root := map[string]interface{}{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal JSON schema")
+	}
+	converted := convertJSONSchemaNode(root, root, 0)
+	b, err := json.Marshal(map[string]interface{}{
+		"components": map[string]interface{}{"schemas": map[string]interface{}{"values": converted}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal converted schema")
+	}
+	// load schema into Swagger to validate it compatible with Swagger OpenAPIv3
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load schema by SwaggerLoader")
+	}
+	return swagger.Components.Schemas["values"].Value.MarshalJSON()
+}
+
+func convertJSONSchemaNode(node, root map[string]interface{}, depth int) map[string]interface{} {
+	if ref, ok := node["$ref"].(string); ok {
+		// the keywords besides $ref are ignored as JSON schema draft 7 does
+		target := resolveLocalRef(root, ref)
+		if target == nil || depth >= maxRefDepth {
+			return map[string]interface{}{}
+		}
+		return convertJSONSchemaNode(target, root, depth+1)
+	}
+	out := make(map[string]interface{}, len(node))
+	for k, v := range node {
+		out[k] = v
+	}
+	for _, k := range unsupportedKeywords {
+		delete(out, k)
+	}
+	if types, ok := out["type"].([]interface{}); ok {
+		var nonNull []interface{}
+		for _, t := range types {
+			if t == "null" {
+				out["nullable"] = true
+				continue
+			}
+			nonNull = append(nonNull, t)
+		}
+		delete(out, "type")
+		if len(nonNull) == 1 {
+			out["type"] = nonNull[0]
+		}
+	}
+	if c, ok := out["const"]; ok {
+		out["enum"] = []interface{}{c}
+		delete(out, "const")
+	}
+	if examples, ok := out["examples"].([]interface{}); ok {
+		if len(examples) > 0 {
+			out["example"] = examples[0]
+		}
+		delete(out, "examples")
+	}
+	// exclusiveMinimum and exclusiveMaximum are numbers since JSON schema draft 6, but booleans in OpenAPI v3
+	for keyword, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+		if v, ok := out[keyword].(float64); ok {
+			out[bound] = v
+			out[keyword] = true
+		}
+	}
+	switch items := out["items"].(type) {
+	case []interface{}:
+		// tuple validation is not supported, the schema of the first item is used
+		delete(out, "items")
+		if len(items) > 0 {
+			if first, ok := items[0].(map[string]interface{}); ok {
+				out["items"] = convertJSONSchemaNode(first, root, depth)
+			}
+		}
+	case map[string]interface{}:
+		out["items"] = convertJSONSchemaNode(items, root, depth)
+	}
+	if props, ok := out["properties"].(map[string]interface{}); ok {
+		converted := make(map[string]interface{}, len(props))
+		for name, p := range props {
+			if ps, ok := p.(map[string]interface{}); ok {
+				converted[name] = convertJSONSchemaNode(ps, root, depth)
+			}
+		}
+		out["properties"] = converted
+	}
+	if ap, ok := out["additionalProperties"].(map[string]interface{}); ok {
+		out["additionalProperties"] = convertJSONSchemaNode(ap, root, depth)
+	}
+	if not, ok := out["not"].(map[string]interface{}); ok {
+		out["not"] = convertJSONSchemaNode(not, root, depth)
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		list, ok := out[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		converted := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			if is, ok := item.(map[string]interface{}); ok {
+				converted = append(converted, convertJSONSchemaNode(is, root, depth))
+			}
+		}
+		out[keyword] = converted
+	}
+	return out
+}
+
+// resolveLocalRef resolves a $ref in the same document, e.g., #/definitions/image, nil is returned for the remote
+// ones or the broken ones
+func resolveLocalRef(root map[string]interface{}, ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	current := root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if len(token) == 0 {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		next, ok := current[token].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
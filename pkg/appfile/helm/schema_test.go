@@ -61,7 +61,7 @@ func TestGetChartValuesJSONSchema(t *testing.T) {
 	wantSchemaMap := map[string]interface{}{}
 	// convert bytes to map for diff converience
 	_ = json.Unmarshal(wantSchema, &wantSchemaMap)
-	result, err := GetChartValuesJSONSchema(context.Background(), testHelm)
+	result, err := GetChartValuesJSONSchema(context.Background(), testHelm, nil)
 	if err != nil {
 		t.Error(err, "failed get schema")
 	}
@@ -293,3 +293,63 @@ func TestMakeSwaggerCompatible(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertJSONSchema(t *testing.T) {
+	testdata := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "definitions": {
+    "image": {
+      "type": "object",
+      "properties": {
+        "repository": {"type": "string", "examples": ["nginx"]},
+        "tag": {"type": ["string", "null"]}
+      },
+      "required": ["repository"]
+    }
+  },
+  "type": "object",
+  "properties": {
+    "image": {"$ref": "#/definitions/image", "description": "ignored"},
+    "replicaCount": {"type": "integer", "exclusiveMinimum": 0},
+    "mode": {"const": "standalone"},
+    "ports": {"type": "array", "items": [{"type": "integer"}, {"type": "string"}]},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}, "patternProperties": {"^x-": {}}}
+  }
+}`
+	want := `{
+  "type": "object",
+  "properties": {
+    "image": {
+      "type": "object",
+      "properties": {
+        "repository": {"type": "string", "example": "nginx"},
+        "tag": {"type": "string", "nullable": true}
+      },
+      "required": ["repository"]
+    },
+    "replicaCount": {"type": "integer", "minimum": 0, "exclusiveMinimum": true},
+    "mode": {"enum": ["standalone"]},
+    "ports": {"type": "array", "items": {"type": "integer"}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  }
+}`
+	result, err := convertJSONSchema([]byte(testdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultMap := map[string]interface{}{}
+	if err := json.Unmarshal(result, &resultMap); err != nil {
+		t.Fatal(err)
+	}
+	wantMap := map[string]interface{}{}
+	_ = json.Unmarshal([]byte(want), &wantMap)
+	if diff := cmp.Diff(wantMap, resultMap); diff != "" {
+		t.Fatalf("\nconvertJSONSchema(...) -want +get %s\n", diff)
+	}
+
+	recursive := `{"definitions": {"node": {"type": "object", "properties": {"child": {"$ref": "#/definitions/node"}}}},
+"$ref": "#/definitions/node"}`
+	if _, err := convertJSONSchema([]byte(recursive)); err != nil {
+		t.Fatal(err)
+	}
+}
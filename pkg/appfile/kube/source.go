@@ -0,0 +1,210 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+)
+
+var (
+	// MaxManifestSize is the maximum size in bytes of a remote manifest
+	MaxManifestSize int64 = 4 << 20
+	// MaxGitTransferSize is the maximum size in bytes of the data transferred to clone the repository of a Git source
+	MaxGitTransferSize int64 = 64 << 20
+	// FetchTimeout is the timeout of fetching a remote manifest
+	FetchTimeout = 30 * time.Second
+	// CacheTTL is how long a fetched manifest without checksum is cached,
+	// a manifest pinned by checksum is immutable so it's cached until evicted
+	CacheTTL = 5 * time.Minute
+	// MaxCacheEntries is the maximum number of manifests in the cache
+	MaxCacheEntries = 256
+)
+
+// DefaultGitRef is the ref used if it's unspecified in a Git source
+const DefaultGitRef = "master"
+
+// Fetcher fetches the content of a remote manifest
+type Fetcher func(ctx context.Context, url string) ([]byte, error)
+
+// GitFetcher fetches the content of the manifest of a Git source
+type GitFetcher func(ctx context.Context, s common.KubeGitSource) ([]byte, error)
+
+// SourceLoader loads raw Kubernetes resources from the remote sources of a KUBE schematic
+type SourceLoader struct {
+	fetch    Fetcher
+	gitFetch GitFetcher
+	cache    *remote.Cache
+}
+
+// NewSourceLoader creates a SourceLoader with the given Fetcher and GitFetcher, the default fetchers are used if
+// they're nil
+func NewSourceLoader(fetch Fetcher, gitFetch GitFetcher) *SourceLoader {
+	if fetch == nil {
+		fetch = httpFetch
+	}
+	if gitFetch == nil {
+		gitFetch = gitFetchManifest
+	}
+	return &SourceLoader{fetch: fetch, gitFetch: gitFetch, cache: remote.NewCache()}
+}
+
+// DefaultSourceLoader is the SourceLoader shared by all applications, so the cache takes effect across renderings
+var DefaultSourceLoader = NewSourceLoader(nil, nil)
+
+// Load fetches all the URL and Git sources of the KUBE schematic in order and decodes them into K8s objects
+func (l *SourceLoader) Load(ctx context.Context, kube *common.Kube) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, s := range kube.URLs {
+		u := s.URL
+		o, err := l.load(ctx, u, s.SHA256, func(ctx context.Context) ([]byte, error) {
+			return l.fetch(ctx, u)
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot load manifest from url %q", s.URL)
+		}
+		objs = append(objs, o...)
+	}
+	for _, s := range kube.Git {
+		g := s
+		key := fmt.Sprintf("%s@%s:%s", g.Repo, GitRef(g), gitPath(g))
+		o, err := l.load(ctx, key, g.SHA256, func(ctx context.Context) ([]byte, error) {
+			return l.gitFetch(ctx, g)
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot load manifest %q from git repo %q", s.Path, s.Repo)
+		}
+		objs = append(objs, o...)
+	}
+	return objs, nil
+}
+
+func (l *SourceLoader) load(ctx context.Context, key, checksum string, fetch func(context.Context) ([]byte, error)) ([]*unstructured.Unstructured, error) {
+	data, err := l.get(ctx, key, strings.ToLower(checksum), fetch)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeManifest(data)
+}
+
+func (l *SourceLoader) get(ctx context.Context, key, checksum string, fetch func(context.Context) ([]byte, error)) ([]byte, error) {
+	key += "@" + checksum
+	if data, ok := l.cache.Get(key); ok {
+		return data.([]byte), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	data, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxManifestSize {
+		return nil, fmt.Errorf("manifest exceeds the size limit of %d bytes", MaxManifestSize)
+	}
+	ttl := CacheTTL
+	if len(checksum) != 0 {
+		sum := sha256.Sum256(data)
+		if actual := hex.EncodeToString(sum[:]); actual != checksum {
+			return nil, fmt.Errorf("checksum mismatch, expected sha256 %s but got %s", checksum, actual)
+		}
+		ttl = 0
+	}
+	l.cache.Set(key, data, ttl, MaxCacheEntries)
+	return data, nil
+}
+
+// GitRef returns the branch, tag or commit of the Git source, default to master
+func GitRef(s common.KubeGitSource) string {
+	if len(s.Ref) == 0 {
+		return DefaultGitRef
+	}
+	return s.Ref
+}
+
+func gitPath(s common.KubeGitSource) string {
+	return strings.Trim(path.Clean("/"+s.Path), "/")
+}
+
+// DecodeManifest decodes the YAML or JSON manifest with multiple documents into K8s objects, empty documents are skipped
+func DecodeManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrap(err, "cannot decode manifest")
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, errors.Wrap(err, "cannot decode object in manifest")
+		}
+		if len(obj.GetAPIVersion()) == 0 {
+			return nil, errors.Errorf("object %q in manifest must have apiVersion", obj.GetName())
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func httpFetch(ctx context.Context, u string) ([]byte, error) {
+	return remote.Get(ctx, u, MaxManifestSize)
+}
+
+// gitFetchManifest clones the repository of the Git source over HTTP(S) and reads the manifest, any Git server is
+// supported
+func gitFetchManifest(ctx context.Context, s common.KubeGitSource) ([]byte, error) {
+	name := gitPath(s)
+	files, err := remote.ReadGit(ctx, remote.GitOptions{
+		Repo:            s.Repo,
+		Ref:             GitRef(s),
+		Dir:             path.Dir(name),
+		Accept:          func(n string) bool { return n == name },
+		MaxTransferSize: MaxGitTransferSize,
+		MaxSize:         MaxManifestSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("file %q is not found", name)
+	}
+	return data, nil
+}
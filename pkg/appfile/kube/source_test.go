@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const testManifest = `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: operator
+---
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+spec:
+  replicas: 1
+`
+
+func TestSourceLoader(t *testing.T) {
+	var fetched []string
+	loader := NewSourceLoader(func(_ context.Context, url string) ([]byte, error) {
+		fetched = append(fetched, url)
+		return []byte(testManifest), nil
+	}, func(_ context.Context, s common.KubeGitSource) ([]byte, error) {
+		fetched = append(fetched, s.Repo+"@"+GitRef(s)+":"+s.Path)
+		return []byte(testManifest), nil
+	})
+	sum := sha256.Sum256([]byte(testManifest))
+	checksum := hex.EncodeToString(sum[:])
+
+	kube := &common.Kube{
+		URLs: []common.KubeURLSource{{URL: "https://example.com/install.yaml", SHA256: checksum}},
+		Git:  []common.KubeGitSource{{Repo: "https://gitlab.com/oam-dev/kubevela.git", Path: "/deploy/install.yaml"}},
+	}
+	objs, err := loader.Load(context.Background(), kube)
+	if err != nil {
+		t.Fatalf("want: nil, got: %v", err)
+	}
+	if len(objs) != 4 {
+		t.Fatalf("want: 4 objects, got: %d", len(objs))
+	}
+	if objs[1].GetKind() != "Deployment" || objs[1].Object["spec"].(map[string]interface{})["replicas"] != int64(1) {
+		t.Errorf("unexpected object %v", objs[1].Object)
+	}
+	wantFetched := []string{
+		"https://example.com/install.yaml",
+		"https://gitlab.com/oam-dev/kubevela.git@master:/deploy/install.yaml",
+	}
+	if strings.Join(fetched, ",") != strings.Join(wantFetched, ",") {
+		t.Errorf("want fetched: %v, got: %v", wantFetched, fetched)
+	}
+
+	// the manifests are cached
+	if _, err = loader.Load(context.Background(), kube); err != nil {
+		t.Fatalf("want: nil, got: %v", err)
+	}
+	if len(fetched) != 2 {
+		t.Errorf("want: manifests are cached, got fetched %d times", len(fetched))
+	}
+
+	// checksum mismatch
+	kube = &common.Kube{URLs: []common.KubeURLSource{{URL: "https://example.com/other.yaml", SHA256: "abc"}}}
+	if _, err = loader.Load(context.Background(), kube); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("want: checksum mismatch error, got: %v", err)
+	}
+}
+
+func TestSourceLoaderSizeLimit(t *testing.T) {
+	loader := NewSourceLoader(func(_ context.Context, url string) ([]byte, error) {
+		return make([]byte, MaxManifestSize+1), nil
+	}, nil)
+	kube := &common.Kube{URLs: []common.KubeURLSource{{URL: "https://example.com/large.yaml"}}}
+	if _, err := loader.Load(context.Background(), kube); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("want: size limit error, got: %v", err)
+	}
+}
+
+func TestGitRef(t *testing.T) {
+	if ref := GitRef(common.KubeGitSource{Repo: "https://github.com/oam-dev/kubevela"}); ref != DefaultGitRef {
+		t.Errorf("want: %s, got: %s", DefaultGitRef, ref)
+	}
+	if ref := GitRef(common.KubeGitSource{Repo: "https://github.com/oam-dev/kubevela", Ref: "v1.0.0"}); ref != "v1.0.0" {
+		t.Errorf("want: v1.0.0, got: %s", ref)
+	}
+}
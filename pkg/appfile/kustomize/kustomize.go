@@ -0,0 +1,228 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kustomize renders the components of the Kustomize schematic, the kustomize base is pulled from a Git
+// repository or an OCI registry and built with an overlay generated from the properties of the component.
+package kustomize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clikustomize "k8s.io/cli-runtime/pkg/kustomize"
+	"sigs.k8s.io/kustomize/pkg/fs"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+)
+
+// Properties are the properties of a component of the Kustomize schematic
+type Properties struct {
+	// PatchesStrategicMerge are the strategic merge patches applied to the resources of the base
+	PatchesStrategicMerge []map[string]interface{} `json:"patchesStrategicMerge,omitempty"`
+	// PatchesJSON6902 are the JSON patches applied to the resources of the base
+	PatchesJSON6902 []JSON6902Patch `json:"patchesJson6902,omitempty"`
+	// Images override the names, tags or digests of the images in the resources of the base
+	Images []Image `json:"images,omitempty"`
+}
+
+// JSON6902Patch is a JSON patch applied to the resource of the target
+type JSON6902Patch struct {
+	Target PatchTarget              `json:"target"`
+	Patch  []map[string]interface{} `json:"patch"`
+}
+
+// PatchTarget selects the resource patched by a JSON patch
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Image overrides an image in the resources of the base
+type Image struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// Renderer builds the kustomize bases of the Kustomize schematic, the files fetched are cached across renderings
+type Renderer struct {
+	fetch Fetcher
+	cache *remote.Cache
+}
+
+// NewRenderer creates a Renderer with the given Fetcher, the default fetcher cloning Git repositories and pulling OCI
+// artifacts is used if it's nil
+func NewRenderer(fetch Fetcher) *Renderer {
+	if fetch == nil {
+		fetch = defaultFetch
+	}
+	return &Renderer{fetch: fetch, cache: remote.NewCache()}
+}
+
+// DefaultRenderer is the Renderer shared by all applications, so the cache takes effect across renderings
+var DefaultRenderer = NewRenderer(nil)
+
+// Render builds the kustomize base of the schematic with the patches and images in the properties, and decodes the
+// output into K8s objects
+func (r *Renderer) Render(ctx context.Context, source *common.Kustomize, properties map[string]interface{}) ([]*unstructured.Unstructured, error) {
+	props, err := parseProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+	key, pinned, err := sourceKey(source)
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.get(ctx, source, key, pinned)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot fetch kustomize base %s", key)
+	}
+
+	dir, err := ioutil.TempDir("", "kustomize")
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer os.RemoveAll(dir)
+	srcDir := filepath.Join(dir, "src")
+	if err := writeFiles(srcDir, files); err != nil {
+		return nil, errors.WithMessagef(err, "cannot write kustomize base %s", key)
+	}
+	base, err := securePath(srcDir, basePath(source))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(base); err != nil {
+		return nil, errors.Wrapf(err, "cannot find path %q in kustomize base %s", basePath(source), key)
+	}
+
+	overlayDir := filepath.Join(dir, "overlay")
+	if err := writeOverlay(overlayDir, base, props); err != nil {
+		return nil, errors.WithMessage(err, "cannot generate kustomize overlay")
+	}
+	var out bytes.Buffer
+	if err := clikustomize.RunKustomizeBuild(&out, fs.MakeRealFS(), overlayDir); err != nil {
+		return nil, errors.Wrapf(err, "cannot build kustomize base %s", key)
+	}
+	return kube.DecodeManifest(out.Bytes())
+}
+
+func parseProperties(properties map[string]interface{}) (*Properties, error) {
+	props := &Properties{}
+	if len(properties) == 0 {
+		return props, nil
+	}
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal properties")
+	}
+	if err := json.Unmarshal(raw, props); err != nil {
+		return nil, errors.Wrap(err, "invalid properties of kustomize component")
+	}
+	for i, p := range props.PatchesJSON6902 {
+		if len(p.Target.Kind) == 0 || len(p.Target.Name) == 0 {
+			return nil, fmt.Errorf("the kind and name of the target of patchesJson6902[%d] are required", i)
+		}
+	}
+	for i, img := range props.Images {
+		if len(img.Name) == 0 {
+			return nil, fmt.Errorf("the name of images[%d] is required", i)
+		}
+	}
+	return props, nil
+}
+
+// writeOverlay writes the kustomization file referring to the base with the patches and images of the properties
+func writeOverlay(dir, base string, props *Properties) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, base)
+	if err != nil {
+		return err
+	}
+	kustomization := map[string]interface{}{"bases": []string{filepath.ToSlash(rel)}}
+
+	var strategicMerge []string
+	for i, p := range props.PatchesStrategicMerge {
+		name := fmt.Sprintf("patch-%d.yaml", i)
+		if err := writeYAML(filepath.Join(dir, name), p); err != nil {
+			return err
+		}
+		strategicMerge = append(strategicMerge, name)
+	}
+	if len(strategicMerge) > 0 {
+		kustomization["patchesStrategicMerge"] = strategicMerge
+	}
+
+	var json6902 []map[string]interface{}
+	for i, p := range props.PatchesJSON6902 {
+		name := fmt.Sprintf("json6902-%d.yaml", i)
+		if err := writeYAML(filepath.Join(dir, name), p.Patch); err != nil {
+			return err
+		}
+		json6902 = append(json6902, map[string]interface{}{"target": p.Target, "path": name})
+	}
+	if len(json6902) > 0 {
+		kustomization["patchesJson6902"] = json6902
+	}
+
+	if len(props.Images) > 0 {
+		kustomization["images"] = props.Images
+	}
+	return writeYAML(filepath.Join(dir, "kustomization.yaml"), kustomization)
+}
+
+func writeYAML(path string, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (r *Renderer) get(ctx context.Context, source *common.Kustomize, key string, pinned bool) (map[string][]byte, error) {
+	if files, ok := r.cache.Get(key); ok {
+		return files.(map[string][]byte), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	files, err := r.fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	ttl := CacheTTL
+	if pinned {
+		ttl = 0
+	}
+	r.cache.Set(key, files, ttl, MaxCacheEntries)
+	return files, nil
+}
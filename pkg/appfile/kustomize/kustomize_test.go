@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const (
+	baseKustomization = `resources:
+- deployment.yaml
+- service.yaml
+`
+	baseDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+`
+	baseService = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+`
+)
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func find(objs []*unstructured.Unstructured, kind string) *unstructured.Unstructured {
+	for _, o := range objs {
+		if o.GetKind() == kind {
+			return o
+		}
+	}
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	files := map[string][]byte{
+		"deploy/kustomization.yaml": []byte(baseKustomization),
+		"deploy/deployment.yaml":    []byte(baseDeployment),
+		"deploy/service.yaml":       []byte(baseService),
+	}
+	fetched := 0
+	r := NewRenderer(func(ctx context.Context, source *common.Kustomize) (map[string][]byte, error) {
+		fetched++
+		return files, nil
+	})
+	source := &common.Kustomize{Git: &common.KustomizeGitSource{Repo: "https://git.example.com/org/app.git", Ref: "main", Path: "deploy"}}
+
+	objs, err := r.Render(context.Background(), source, nil)
+	require.NoError(t, err)
+	assert.Len(t, objs, 2)
+
+	objs, err = r.Render(context.Background(), source, map[string]interface{}{
+		"patchesStrategicMerge": []interface{}{map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "web"},
+			"spec":       map[string]interface{}{"replicas": 3},
+		}},
+		"patchesJson6902": []interface{}{map[string]interface{}{
+			"target": map[string]interface{}{"version": "v1", "kind": "Service", "name": "web"},
+			"patch": []interface{}{
+				map[string]interface{}{"op": "replace", "path": "/spec/ports/0/port", "value": 8080},
+			},
+		}},
+		"images": []interface{}{map[string]interface{}{"name": "nginx", "newTag": "1.21"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetched, "the files should be cached")
+
+	deploy := find(objs, "Deployment")
+	require.NotNil(t, deploy)
+	replicas, _, _ := unstructured.NestedInt64(deploy.Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+	containers, _, _ := unstructured.NestedSlice(deploy.Object, "spec", "template", "spec", "containers")
+	require.Len(t, containers, 1)
+	assert.Equal(t, "nginx:1.21", containers[0].(map[string]interface{})["image"])
+
+	svc := find(objs, "Service")
+	require.NotNil(t, svc)
+	ports, _, _ := unstructured.NestedSlice(svc.Object, "spec", "ports")
+	require.Len(t, ports, 1)
+	assert.EqualValues(t, 8080, ports[0].(map[string]interface{})["port"])
+
+	_, err = r.Render(context.Background(), &common.Kustomize{Git: &common.KustomizeGitSource{Repo: "https://git.example.com/org/app.git", Ref: "main", Path: "missing"}}, nil)
+	assert.Error(t, err)
+
+	_, err = r.Render(context.Background(), source, map[string]interface{}{
+		"patchesJson6902": []interface{}{map[string]interface{}{"target": map[string]interface{}{"kind": "Service"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestReadArchive(t *testing.T) {
+	files, err := readArchive(tarball(t, map[string]string{"./a/b.yaml": "b", "c.yaml": "c"}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a/b.yaml": []byte("b"), "c.yaml": []byte("c")}, files)
+
+	_, err = readArchive([]byte("not a tarball"))
+	assert.Error(t, err)
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kustomize")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, writeFiles(dir, map[string][]byte{"a/b.yaml": []byte("b")}))
+	data, err := ioutil.ReadFile(dir + "/a/b.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+
+	assert.Error(t, writeFiles(dir, map[string][]byte{"../escape.yaml": []byte("x")}))
+}
+
+func TestSourceKey(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	_, pinned, err := sourceKey(&common.Kustomize{Git: &common.KustomizeGitSource{Repo: "https://github.com/org/app", Ref: commit}})
+	assert.NoError(t, err)
+	assert.True(t, pinned)
+
+	key, pinned, err := sourceKey(&common.Kustomize{OCI: &common.KustomizeOCISource{Image: "ghcr.io/org/app:v1"}})
+	assert.NoError(t, err)
+	assert.False(t, pinned)
+	assert.Equal(t, "oci:ghcr.io/org/app:v1", key)
+
+	_, _, err = sourceKey(&common.Kustomize{})
+	assert.Error(t, err)
+}
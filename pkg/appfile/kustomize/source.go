@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+var (
+	// MaxArchiveSize is the maximum size in bytes of the files of a kustomize base, and of the archive of an OCI
+	// artifact, compressed or not
+	MaxArchiveSize int64 = 32 << 20
+	// MaxGitTransferSize is the maximum size in bytes of the data transferred to clone the repository of a Git source
+	MaxGitTransferSize int64 = 64 << 20
+	// FetchTimeout is the timeout of fetching the files of a kustomize base
+	FetchTimeout = 60 * time.Second
+	// CacheTTL is how long the fetched files are cached, the files pinned by a digest or a commit are immutable so
+	// they're cached until evicted
+	CacheTTL = 5 * time.Minute
+	// MaxCacheEntries is the maximum number of kustomize bases in the cache
+	MaxCacheEntries = 32
+)
+
+// Fetcher fetches the files containing the kustomize base of the source, they're keyed by their paths relative to the
+// root of the repository or the artifact
+type Fetcher func(ctx context.Context, source *common.Kustomize) (map[string][]byte, error)
+
+// sourceKey returns the cache key of the source and whether the content it refers to is immutable
+func sourceKey(source *common.Kustomize) (string, bool, error) {
+	switch {
+	case source.Git != nil:
+		ref := gitRef(source.Git)
+		return "git:" + source.Git.Repo + "@" + ref, isCommit(ref), nil
+	case source.OCI != nil:
+		ref, err := oci.ParseReference(source.OCI.Image)
+		if err != nil {
+			return "", false, err
+		}
+		return "oci:" + ref.String(), ref.Pinned(), nil
+	default:
+		return "", false, errors.New("neither git nor oci source of Kustomize schematic is set")
+	}
+}
+
+// basePath returns the path of the kustomize base in the archive of the source
+func basePath(source *common.Kustomize) string {
+	if source.Git != nil {
+		return source.Git.Path
+	}
+	return source.OCI.Path
+}
+
+func isCommit(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultFetch clones the Git repository over HTTP(S), any Git server is supported, or pulls the first layer of the
+// OCI artifact. The whole repository is read, so the kustomize base can refer to the other directories of it.
+func defaultFetch(ctx context.Context, source *common.Kustomize) (map[string][]byte, error) {
+	if source.Git != nil {
+		return remote.ReadGit(ctx, remote.GitOptions{
+			Repo:            source.Git.Repo,
+			Ref:             gitRef(source.Git),
+			MaxTransferSize: MaxGitTransferSize,
+			MaxSize:         MaxArchiveSize,
+		})
+	}
+	ref, err := oci.ParseReference(source.OCI.Image)
+	if err != nil {
+		return nil, err
+	}
+	c := &oci.Client{MaxSize: MaxArchiveSize, HTTPClient: remote.HTTPClient()}
+	data, err := c.PullLayer(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return readArchive(data)
+}
+
+func gitRef(s *common.KustomizeGitSource) string {
+	if len(s.Ref) == 0 {
+		return kube.DefaultGitRef
+	}
+	return s.Ref
+}
+
+// readArchive reads the regular files of the gzipped tarball, the directories and other types of entries are skipped
+func readArchive(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decompress archive")
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read archive")
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		total += hdr.Size
+		if total > MaxArchiveSize {
+			return nil, fmt.Errorf("archive exceeds the size limit of %d bytes", MaxArchiveSize)
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", hdr.Name)
+		}
+		files[strings.TrimPrefix(filepath.ToSlash(hdr.Name), "./")] = content
+	}
+}
+
+// writeFiles writes the files into the directory, the files escaping the directory are rejected
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, content := range files {
+		target, err := securePath(dir, strings.Trim(name, "/"))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, content, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// securePath joins the relative path to the directory, it fails if the result escapes the directory
+func securePath(dir, rel string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(rel))
+	if target != dir && !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q escapes the directory", rel)
+	}
+	return target, nil
+}
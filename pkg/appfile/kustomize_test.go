@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	oamtypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile/kustomize"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+var _ = Describe("Test Kustomize schematic appfile", func() {
+	const (
+		appName  = "test-app"
+		compName = "test-comp"
+	)
+	files := map[string]string{
+		"kustomization.yaml": "resources:\n- deployment.yaml\n- service.yaml\n",
+		"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+`,
+		"service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+`,
+	}
+	base := map[string][]byte{}
+	for name, content := range files {
+		base["base/"+name] = []byte(content)
+	}
+
+	It("Test generate AppConfig resources from Kustomize schematic", func() {
+		defaultRenderer := kustomize.DefaultRenderer
+		defer func() { kustomize.DefaultRenderer = defaultRenderer }()
+		kustomize.DefaultRenderer = kustomize.NewRenderer(func(ctx context.Context, source *common.Kustomize) (map[string][]byte, error) {
+			return base, nil
+		})
+
+		af := &Appfile{
+			RevisionName: appName + "-v1",
+			Name:         appName,
+			Namespace:    "default",
+			Workloads: []*Workload{{
+				Name:               compName,
+				Type:               "web",
+				CapabilityCategory: oamtypes.KustomizeCategory,
+				Params: map[string]interface{}{
+					"images": []interface{}{map[string]interface{}{"name": "nginx", "newTag": "1.21"}},
+				},
+				engine: definition.NewWorkloadAbstractEngine(compName, pd),
+				FullTemplate: &Template{
+					Kustomize: &common.Kustomize{OCI: &common.KustomizeOCISource{Image: "ghcr.io/org/web:v1", Path: "base"}},
+				},
+			}},
+		}
+		ac, components, err := af.GenerateApplicationConfiguration()
+		Expect(err).Should(BeNil())
+		Expect(components).Should(HaveLen(1))
+
+		By("Verify the Deployment is the workload though kustomize sorts the Service ahead")
+		wl, err := util.RawExtension2Unstructured(&components[0].Spec.Workload)
+		Expect(err).Should(BeNil())
+		Expect(wl.GetKind()).Should(Equal("Deployment"))
+		containers, _, _ := unstructured.NestedSlice(wl.Object, "spec", "template", "spec", "containers")
+		Expect(containers).Should(HaveLen(1))
+		Expect(containers[0].(map[string]interface{})["image"]).Should(Equal("nginx:1.21"))
+
+		By("Verify the Service is an auxiliary output")
+		Expect(ac.Spec.Components).Should(HaveLen(1))
+		Expect(ac.Spec.Components[0].Traits).Should(HaveLen(1))
+		svc, err := util.RawExtension2Unstructured(&ac.Spec.Components[0].Traits[0].Trait)
+		Expect(err).Should(BeNil())
+		Expect(svc.GetKind()).Should(Equal("Service"))
+	})
+
+	It("Test the kustomize base failing to render", func() {
+		defaultRenderer := kustomize.DefaultRenderer
+		defer func() { kustomize.DefaultRenderer = defaultRenderer }()
+		kustomize.DefaultRenderer = kustomize.NewRenderer(func(ctx context.Context, source *common.Kustomize) (map[string][]byte, error) {
+			return base, nil
+		})
+		af := &Appfile{
+			Name:      appName,
+			Namespace: "default",
+			Workloads: []*Workload{{
+				Name:               compName,
+				CapabilityCategory: oamtypes.KustomizeCategory,
+				engine:             definition.NewWorkloadAbstractEngine(compName, pd),
+				FullTemplate: &Template{
+					Kustomize: &common.Kustomize{OCI: &common.KustomizeOCISource{Image: "ghcr.io/org/web:v1", Path: "missing"}},
+				},
+			}},
+		}
+		_, _, err := af.GenerateApplicationConfiguration()
+		Expect(err).Should(HaveOccurred())
+	})
+})
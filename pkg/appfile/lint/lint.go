@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const (
+	// ConfigMapName is the name of the configmap configuring the lint rules of a namespace
+	ConfigMapName = "vela-lint-config"
+	// ConfigDisabled disables linting in the namespace if it's "true"
+	ConfigDisabled = "disabled"
+	// ConfigDisabledRules is the comma separated names of the rules disabled in the namespace
+	ConfigDisabledRules = "disabled-rules"
+	// ConfigEnvironment is the environment of the namespace, e.g., production
+	ConfigEnvironment = "environment"
+)
+
+// Context is the context a rule checks the workload in
+type Context struct {
+	// Environment is the environment of the namespace the workload is deployed to
+	Environment string
+}
+
+// IsProduction checks if the workload is deployed to a production environment
+func (c *Context) IsProduction() bool {
+	env := strings.ToLower(c.Environment)
+	return env == "prod" || env == "production"
+}
+
+// Rule checks a rendered workload against a best practice and reports the issues as warnings
+type Rule interface {
+	// Name is the unique name of the rule, it's used to disable the rule in the config
+	Name() string
+	// Check returns the issues found in the workload, nil if there is none
+	Check(ctx *Context, workload *unstructured.Unstructured) []string
+}
+
+var (
+	rulesLock sync.RWMutex
+	rules     []Rule
+)
+
+// RegisterRule registers a rule, the rules are checked in the order they are registered
+func RegisterRule(rule Rule) {
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
+	rules = append(rules, rule)
+}
+
+// Rules returns the registered rules
+func Rules() []Rule {
+	rulesLock.RLock()
+	defer rulesLock.RUnlock()
+	return append([]Rule{}, rules...)
+}
+
+// Config is the lint config of a namespace
+type Config struct {
+	Disabled      bool
+	DisabledRules map[string]bool
+	Context
+}
+
+// LoadConfig loads the lint config of the namespace, the default config enabling all rules is returned
+// if the namespace is not configured
+func LoadConfig(ctx context.Context, c client.Reader, namespace string) (*Config, error) {
+	cfg := &Config{DisabledRules: map[string]bool{}}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ConfigMapName}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return cfg, nil
+		}
+		return nil, errors.Wrapf(err, "fail to get lint config of namespace %s", namespace)
+	}
+	cfg.Disabled = cm.Data[ConfigDisabled] == "true"
+	cfg.Environment = cm.Data[ConfigEnvironment]
+	for _, rule := range strings.Split(cm.Data[ConfigDisabledRules], ",") {
+		if rule = strings.TrimSpace(rule); len(rule) != 0 {
+			cfg.DisabledRules[rule] = true
+		}
+	}
+	return cfg, nil
+}
+
+// Lint checks the rendered workload of a component with the rules enabled by the config
+func Lint(cfg *Config, component string, workload *unstructured.Unstructured) []common.LintWarning {
+	if cfg.Disabled || workload == nil {
+		return nil
+	}
+	var warnings []common.LintWarning
+	for _, rule := range Rules() {
+		if cfg.DisabledRules[rule.Name()] {
+			continue
+		}
+		for _, msg := range rule.Check(&cfg.Context, workload) {
+			warnings = append(warnings, common.LintWarning{Component: component, Rule: rule.Name(), Message: msg})
+		}
+	}
+	return warnings
+}
+
+// Filter drops the warnings of the rules disabled by the config, it's used for the warnings reported when rendering
+// the components rather than by the registered rules
+func Filter(cfg *Config, warnings []common.LintWarning) []common.LintWarning {
+	if cfg.Disabled {
+		return nil
+	}
+	var filtered []common.LintWarning
+	for _, w := range warnings {
+		if !cfg.DisabledRules[w.Rule] {
+			filtered = append(filtered, w)
+		}
+	}
+	return filtered
+}
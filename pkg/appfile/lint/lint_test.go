@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const testDeployment = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {"name": "web"},
+  "spec": {
+    "template": {
+      "spec": {
+        "containers": [{
+          "name": "web",
+          "image": "nginx",
+          "readinessProbe": {"httpGet": {"path": "/", "port": 80}},
+          "resources": {"limits": {"cpu": "100m"}}
+        }, {
+          "name": "sidecar",
+          "image": "oamdev/sidecar:v1.0.0@sha256:abc",
+          "livenessProbe": {"httpGet": {"path": "/", "port": 8080}},
+          "readinessProbe": {"httpGet": {"path": "/", "port": 8080}},
+          "resources": {"limits": {"cpu": "100m", "memory": "64Mi"}}
+        }]
+      }
+    }
+  }
+}`
+
+func TestLint(t *testing.T) {
+	workload := &unstructured.Unstructured{}
+	assert.NoError(t, json.Unmarshal([]byte(testDeployment), &workload.Object))
+
+	warnings := Lint(&Config{}, "web", workload)
+	assert.Equal(t, 3, len(warnings))
+	for i, rule := range []string{RuleMissingProbes, RuleNoResourceLimits, RuleLatestTag} {
+		assert.Equal(t, rule, warnings[i].Rule)
+		assert.Equal(t, "web", warnings[i].Component)
+	}
+	assert.Equal(t, "container web has no livenessProbe", warnings[0].Message)
+	assert.Equal(t, "container web has no memory limits", warnings[1].Message)
+
+	// single replica in production
+	warnings = Lint(&Config{Context: Context{Environment: "production"}}, "web", workload)
+	assert.Equal(t, 4, len(warnings))
+	assert.Equal(t, RuleSingleReplica, warnings[3].Rule)
+	assert.NoError(t, unstructured.SetNestedField(workload.Object, float64(3), "spec", "replicas"))
+	warnings = Lint(&Config{Context: Context{Environment: "production"}}, "web", workload)
+	assert.Equal(t, 3, len(warnings))
+
+	// disabled rules
+	cfg := &Config{DisabledRules: map[string]bool{RuleMissingProbes: true, RuleLatestTag: true}}
+	warnings = Lint(cfg, "web", workload)
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, RuleNoResourceLimits, warnings[0].Rule)
+	assert.Empty(t, Lint(&Config{Disabled: true}, "web", workload))
+
+	// unknown workloads are not checked
+	assert.Empty(t, Lint(&Config{}, "web", &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Foo"}}))
+}
+
+func TestLoadConfig(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "prod"},
+		Data: map[string]string{
+			ConfigEnvironment:   "production",
+			ConfigDisabledRules: "latest-tag, missing-probes",
+		},
+	})
+	cfg, err := LoadConfig(context.Background(), c, "prod")
+	assert.NoError(t, err)
+	assert.False(t, cfg.Disabled)
+	assert.True(t, cfg.IsProduction())
+	assert.Equal(t, map[string]bool{RuleLatestTag: true, RuleMissingProbes: true}, cfg.DisabledRules)
+
+	cfg, err = LoadConfig(context.Background(), c, "default")
+	assert.NoError(t, err)
+	assert.False(t, cfg.IsProduction())
+	assert.Empty(t, cfg.DisabledRules)
+}
+
+func TestWindowsLinuxOnly(t *testing.T) {
+	workload := &unstructured.Unstructured{}
+	assert.NoError(t, json.Unmarshal([]byte(testDeployment), &workload.Object))
+	assert.NoError(t, unstructured.SetNestedField(workload.Object, true, "spec", "template", "spec", "securityContext", "runAsNonRoot"))
+	assert.NoError(t, unstructured.SetNestedField(workload.Object, int64(1000), "spec", "template", "spec", "securityContext", "fsGroup"))
+	cfg := &Config{DisabledRules: map[string]bool{RuleMissingProbes: true, RuleNoResourceLimits: true, RuleLatestTag: true}}
+
+	// linux workloads are not checked
+	assert.Empty(t, Lint(cfg, "web", workload))
+
+	assert.NoError(t, unstructured.SetNestedStringMap(workload.Object, map[string]string{corev1.LabelOSStable: "windows"},
+		"spec", "template", "spec", "nodeSelector"))
+	warnings := Lint(cfg, "web", workload)
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, RuleWindowsLinuxOnly, warnings[0].Rule)
+	assert.Equal(t, "pod securityContext.fsGroup is not supported by Windows containers", warnings[0].Message)
+}
+
+func TestFilter(t *testing.T) {
+	warnings := []common.LintWarning{
+		{Component: "web", Rule: "foo", Message: "foo"},
+		{Component: "web", Rule: "bar", Message: "bar"},
+	}
+	assert.Equal(t, warnings, Filter(&Config{}, warnings))
+	assert.Equal(t, warnings[1:], Filter(&Config{DisabledRules: map[string]bool{"foo": true}}, warnings))
+	assert.Empty(t, Filter(&Config{Disabled: true}, warnings))
+}
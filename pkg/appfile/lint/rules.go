@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// names of the builtin rules
+const (
+	RuleMissingProbes    = "missing-probes"
+	RuleNoResourceLimits = "no-resource-limits"
+	RuleLatestTag        = "latest-tag"
+	RuleSingleReplica    = "single-replica"
+	// RuleWindowsLinuxOnly reports the Linux only security settings of the workloads scheduled to Windows nodes,
+	// they're usually patched by traits not aware of Windows containers, e.g., a securityContext trait
+	RuleWindowsLinuxOnly = "windows-linux-only"
+)
+
+func init() {
+	RegisterRule(&ruleFunc{name: RuleMissingProbes, check: checkProbes})
+	RegisterRule(&ruleFunc{name: RuleNoResourceLimits, check: checkResourceLimits})
+	RegisterRule(&ruleFunc{name: RuleLatestTag, check: checkLatestTag})
+	RegisterRule(&ruleFunc{name: RuleSingleReplica, check: checkSingleReplica})
+	RegisterRule(&ruleFunc{name: RuleWindowsLinuxOnly, check: checkWindowsLinuxOnly})
+}
+
+type ruleFunc struct {
+	name  string
+	check func(ctx *Context, workload *unstructured.Unstructured) []string
+}
+
+func (r *ruleFunc) Name() string {
+	return r.name
+}
+
+func (r *ruleFunc) Check(ctx *Context, workload *unstructured.Unstructured) []string {
+	return r.check(ctx, workload)
+}
+
+func containers(workload *unstructured.Unstructured) []map[string]interface{} {
+	path := util.PodSpecPath(workload.GetKind())
+	if path == nil {
+		return nil
+	}
+	raw, _, _ := unstructured.NestedSlice(workload.Object, append(path, "containers")...)
+	var cs []map[string]interface{}
+	for _, c := range raw {
+		if container, ok := c.(map[string]interface{}); ok {
+			cs = append(cs, container)
+		}
+	}
+	return cs
+}
+
+func checkProbes(_ *Context, workload *unstructured.Unstructured) []string {
+	if workload.GetKind() == "Job" || workload.GetKind() == "CronJob" {
+		return nil
+	}
+	var warnings []string
+	for _, c := range containers(workload) {
+		var missing []string
+		for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+			if _, ok := c[probe]; !ok {
+				missing = append(missing, probe)
+			}
+		}
+		if len(missing) != 0 {
+			warnings = append(warnings, fmt.Sprintf("container %v has no %s", c["name"], strings.Join(missing, " or ")))
+		}
+	}
+	return warnings
+}
+
+func checkResourceLimits(_ *Context, workload *unstructured.Unstructured) []string {
+	var warnings []string
+	for _, c := range containers(workload) {
+		limits, _, _ := unstructured.NestedMap(c, "resources", "limits")
+		var missing []string
+		for _, res := range []string{"cpu", "memory"} {
+			if _, ok := limits[res]; !ok {
+				missing = append(missing, res)
+			}
+		}
+		if len(missing) != 0 {
+			warnings = append(warnings, fmt.Sprintf("container %v has no %s limits", c["name"], strings.Join(missing, " or ")))
+		}
+	}
+	return warnings
+}
+
+func checkLatestTag(_ *Context, workload *unstructured.Unstructured) []string {
+	var warnings []string
+	for _, c := range containers(workload) {
+		image, _ := c["image"].(string)
+		if len(image) == 0 || strings.Contains(image, "@") {
+			// the image is pinned by digest
+			continue
+		}
+		name := image[strings.LastIndex(image, "/")+1:]
+		if i := strings.LastIndex(name, ":"); i < 0 || name[i+1:] == "latest" {
+			warnings = append(warnings, fmt.Sprintf("container %v uses image %s with the latest tag", c["name"], image))
+		}
+	}
+	return warnings
+}
+
+func checkSingleReplica(ctx *Context, workload *unstructured.Unstructured) []string {
+	if !ctx.IsProduction() {
+		return nil
+	}
+	switch workload.GetKind() {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+	default:
+		return nil
+	}
+	// the workload may be decoded by either the unstructured or the standard json decoder
+	replicas, _, _ := unstructured.NestedFieldNoCopy(workload.Object, "spec", "replicas")
+	switch r := replicas.(type) {
+	case int64:
+		if r != 1 {
+			return nil
+		}
+	case float64:
+		if r != 1 {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("%s runs a single replica in the %s environment", workload.GetKind(), ctx.Environment)}
+}
+
+var (
+	// linuxOnlyPodSecurityFields are the fields of the pod securityContext not supported by Windows containers
+	linuxOnlyPodSecurityFields = []string{"runAsUser", "runAsGroup", "fsGroup", "supplementalGroups", "seLinuxOptions", "sysctls", "seccompProfile"}
+	// linuxOnlyContainerSecurityFields are the fields of the container securityContext not supported by Windows containers
+	linuxOnlyContainerSecurityFields = []string{"runAsUser", "runAsGroup", "seLinuxOptions", "privileged", "capabilities",
+		"allowPrivilegeEscalation", "procMount", "readOnlyRootFilesystem", "seccompProfile"}
+)
+
+func checkWindowsLinuxOnly(_ *Context, workload *unstructured.Unstructured) []string {
+	path := util.PodSpecPath(workload.GetKind())
+	if path == nil {
+		return nil
+	}
+	if os, _, _ := unstructured.NestedString(workload.Object, append(path, "nodeSelector", corev1.LabelOSStable)...); os != "windows" {
+		return nil
+	}
+	var warnings []string
+	podSecurity, _, _ := unstructured.NestedMap(workload.Object, append(path, "securityContext")...)
+	for _, field := range linuxOnlyPodSecurityFields {
+		if _, ok := podSecurity[field]; ok {
+			warnings = append(warnings, fmt.Sprintf("pod securityContext.%s is not supported by Windows containers", field))
+		}
+	}
+	for _, c := range containers(workload) {
+		security, _, _ := unstructured.NestedMap(c, "securityContext")
+		for _, field := range linuxOnlyContainerSecurityFields {
+			if _, ok := security[field]; ok {
+				warnings = append(warnings, fmt.Sprintf("securityContext.%s of container %v is not supported by Windows containers", field, c["name"]))
+			}
+		}
+	}
+	return warnings
+}
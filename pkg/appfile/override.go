@@ -0,0 +1,193 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// PolicyTypeOverride is the type of the policy patching the components of the application deployed by the deploy
+// workflow steps referring to it, so a single application can describe the variants of different environments
+const PolicyTypeOverride = "override"
+
+// OverridePolicySpec is the properties of the override policy
+type OverridePolicySpec struct {
+	// Components are the patches to the components applied in order
+	Components []ComponentOverride `json:"components"`
+}
+
+// ComponentOverride patches the type, properties and traits of components
+type ComponentOverride struct {
+	// Name is the name of the component patched, all the components are patched if it's empty
+	Name string `json:"name,omitempty"`
+	// Type swaps the type of the component if it's set
+	Type string `json:"type,omitempty"`
+	// Properties is the JSON merge patch to the properties of the component
+	Properties *runtime.RawExtension `json:"properties,omitempty"`
+	// Traits patch the traits of the component
+	Traits []TraitOverride `json:"traits,omitempty"`
+}
+
+// TraitOverride patches the properties of the trait of the type, the trait is added if the component doesn't have
+// it, or removed if it's disabled
+type TraitOverride struct {
+	Type string `json:"type"`
+	// Properties is the JSON merge patch to the properties of the trait
+	Properties *runtime.RawExtension `json:"properties,omitempty"`
+	// Disable removes the trait from the component
+	Disable bool `json:"disable,omitempty"`
+}
+
+func parseOverridePolicies(app *v1beta1.Application) (map[string]*OverridePolicySpec, error) {
+	compNames := componentNames(app)
+	overrides := map[string]*OverridePolicySpec{}
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeOverride {
+			continue
+		}
+		spec := &OverridePolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		if err := validateOverride(spec, compNames); err != nil {
+			return nil, errors.WithMessagef(err, "policy %s", p.Name)
+		}
+		overrides[p.Name] = spec
+	}
+	return overrides, nil
+}
+
+func validateOverride(spec *OverridePolicySpec, compNames map[string]bool) error {
+	if len(spec.Components) == 0 {
+		return errors.New("no component is overridden")
+	}
+	for _, o := range spec.Components {
+		if len(o.Name) != 0 && !compNames[o.Name] {
+			return errors.Errorf("component %s overridden is not found", o.Name)
+		}
+		if err := validateMergePatch(o.Properties); err != nil {
+			return errors.WithMessagef(err, "component %s", o.Name)
+		}
+		for _, t := range o.Traits {
+			if len(t.Type) == 0 {
+				return errors.Errorf("a trait of component %s is overridden without type", o.Name)
+			}
+			if err := validateMergePatch(t.Properties); err != nil {
+				return errors.WithMessagef(err, "trait %s of component %s", t.Type, o.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func componentNames(app *v1beta1.Application) map[string]bool {
+	names := make(map[string]bool, len(app.Spec.Components))
+	for _, comp := range app.Spec.Components {
+		names[comp.Name] = true
+	}
+	return names
+}
+
+func validateMergePatch(patch *runtime.RawExtension) error {
+	if patch == nil {
+		return nil
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(patch.Raw, &obj); err != nil {
+		return errors.Wrap(err, "the properties patch is not a JSON object")
+	}
+	return nil
+}
+
+// OverrideApplication returns a copy of the application whose components are patched by the override policies in
+// order, the application itself is left as it is
+func OverrideApplication(app *v1beta1.Application, overrides ...*OverridePolicySpec) (*v1beta1.Application, error) {
+	overridden := app.DeepCopy()
+	for _, spec := range overrides {
+		for _, o := range spec.Components {
+			for i := range overridden.Spec.Components {
+				comp := &overridden.Spec.Components[i]
+				if len(o.Name) != 0 && o.Name != comp.Name {
+					continue
+				}
+				if err := overrideComponent(comp, o); err != nil {
+					return nil, errors.WithMessagef(err, "cannot override component %s", comp.Name)
+				}
+			}
+		}
+	}
+	return overridden, nil
+}
+
+func overrideComponent(comp *v1beta1.ApplicationComponent, o ComponentOverride) error {
+	if len(o.Type) != 0 {
+		comp.Type = o.Type
+	}
+	if err := mergeProperties(&comp.Properties, o.Properties); err != nil {
+		return err
+	}
+	for _, to := range o.Traits {
+		idx := -1
+		for i := range comp.Traits {
+			if comp.Traits[i].Type == to.Type {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case to.Disable && idx >= 0:
+			comp.Traits = append(comp.Traits[:idx], comp.Traits[idx+1:]...)
+		case to.Disable:
+			// the trait to remove is not there
+		case idx >= 0:
+			if err := mergeProperties(&comp.Traits[idx].Properties, to.Properties); err != nil {
+				return errors.WithMessagef(err, "trait %s", to.Type)
+			}
+		default:
+			trait := v1beta1.ApplicationTrait{Type: to.Type}
+			if err := mergeProperties(&trait.Properties, to.Properties); err != nil {
+				return errors.WithMessagef(err, "trait %s", to.Type)
+			}
+			comp.Traits = append(comp.Traits, trait)
+		}
+	}
+	return nil
+}
+
+func mergeProperties(properties *runtime.RawExtension, patch *runtime.RawExtension) error {
+	if patch == nil {
+		return nil
+	}
+	original := properties.Raw
+	if len(original) == 0 {
+		original = []byte("{}")
+	}
+	merged, err := jsonpatch.MergePatch(original, patch.Raw)
+	if err != nil {
+		return errors.Wrap(err, "cannot merge the properties patch")
+	}
+	*properties = runtime.RawExtension{Raw: merged}
+	return nil
+}
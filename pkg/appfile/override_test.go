@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestOverrideApplication(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+		Components: []v1beta1.ApplicationComponent{{
+			Name:       "web",
+			Type:       "webservice",
+			Properties: runtime.RawExtension{Raw: []byte(`{"image":"nginx","port":80}`)},
+			Traits: []v1beta1.ApplicationTrait{
+				{Type: "scaler", Properties: runtime.RawExtension{Raw: []byte(`{"replicas":1}`)}},
+				{Type: "sidecar", Properties: runtime.RawExtension{Raw: []byte(`{"image":"debug"}`)}},
+			},
+		}, {
+			Name: "db",
+			Type: "worker",
+		}},
+		Policies: []v1beta1.AppPolicy{{
+			Name: "prod",
+			Type: PolicyTypeOverride,
+			Properties: runtime.RawExtension{Raw: []byte(`{"components":[
+				{"name":"web","properties":{"image":"nginx:1.20","port":null},"traits":[
+					{"type":"scaler","properties":{"replicas":3}},
+					{"type":"sidecar","disable":true},
+					{"type":"ingress","properties":{"domain":"example.com"}}]},
+				{"name":"db","type":"cloud-db"}]}`)},
+		}},
+	}}
+	overrides, err := parseOverridePolicies(app)
+	assert.NoError(t, err)
+	overridden, err := OverrideApplication(app, overrides["prod"])
+	assert.NoError(t, err)
+
+	web := overridden.Spec.Components[0]
+	assert.Equal(t, "webservice", web.Type)
+	assert.JSONEq(t, `{"image":"nginx:1.20"}`, string(web.Properties.Raw))
+	assert.Len(t, web.Traits, 2)
+	assert.Equal(t, "scaler", web.Traits[0].Type)
+	assert.JSONEq(t, `{"replicas":3}`, string(web.Traits[0].Properties.Raw))
+	assert.Equal(t, "ingress", web.Traits[1].Type)
+	assert.JSONEq(t, `{"domain":"example.com"}`, string(web.Traits[1].Properties.Raw))
+	assert.Equal(t, "cloud-db", overridden.Spec.Components[1].Type)
+
+	// the application itself is left as it is
+	assert.Equal(t, "worker", app.Spec.Components[1].Type)
+	assert.Len(t, app.Spec.Components[0].Traits, 2)
+	assert.JSONEq(t, `{"image":"nginx","port":80}`, string(app.Spec.Components[0].Properties.Raw))
+}
+
+func TestParseOverridePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		properties string
+		err        string
+	}{
+		"no component": {
+			properties: `{"components":[]}`,
+			err:        "no component is overridden",
+		},
+		"component not found": {
+			properties: `{"components":[{"name":"db"}]}`,
+			err:        "component db overridden is not found",
+		},
+		"trait without type": {
+			properties: `{"components":[{"traits":[{"disable":true}]}]}`,
+			err:        "overridden without type",
+		},
+		"properties not an object": {
+			properties: `{"components":[{"name":"web","properties":"nginx"}]}`,
+			err:        "not a JSON object",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+				Components: []v1beta1.ApplicationComponent{{Name: "web", Type: "webservice"}},
+				Policies: []v1beta1.AppPolicy{{
+					Name:       "prod",
+					Type:       PolicyTypeOverride,
+					Properties: runtime.RawExtension{Raw: []byte(tc.properties)},
+				}},
+			}}
+			_, err := parseOverridePolicies(app)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// parameterPattern matches the references to the parameters, i.e., ${name}, and the escaped ones, i.e., $${name}
+var parameterPattern = regexp.MustCompile(`\$?\$\{([a-zA-Z_][a-zA-Z0-9_.-]*)\}`)
+
+// exactParameterPattern matches a string referring to a single parameter, it's substituted by the typed value
+var exactParameterPattern = regexp.MustCompile(`^\$\{([a-zA-Z_][a-zA-Z0-9_.-]*)\}$`)
+
+// mergeParameters overrides the values of the parameters declared by the application by the ones of the environment
+func mergeParameters(declared, env []v1beta1.ApplicationParameter) ([]v1beta1.ApplicationParameter, []string) {
+	values := map[string]*runtime.RawExtension{}
+	for _, p := range env {
+		if p.Value != nil {
+			values[p.Name] = p.Value
+		}
+	}
+	var overridden []string
+	merged := make([]v1beta1.ApplicationParameter, 0, len(declared))
+	for _, p := range declared {
+		p = *p.DeepCopy()
+		if v, ok := values[p.Name]; ok {
+			p.Value = v.DeepCopy()
+			overridden = append(overridden, p.Name)
+		}
+		merged = append(merged, p)
+	}
+	return merged, overridden
+}
+
+// parameterValues returns the values of the parameters keyed by their names, the ones without values are nil
+func parameterValues(params []v1beta1.ApplicationParameter) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		if len(p.Name) == 0 {
+			return nil, errors.New("the name of a parameter is not set")
+		}
+		if _, ok := values[p.Name]; ok {
+			return nil, errors.Errorf("parameter %s is declared more than once", p.Name)
+		}
+		values[p.Name] = nil
+		if p.Value == nil || len(p.Value.Raw) == 0 {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(p.Value.Raw, &v); err != nil {
+			return nil, errors.Wrapf(err, "invalid value of parameter %s", p.Name)
+		}
+		values[p.Name] = v
+	}
+	return values, nil
+}
+
+// substituteParameters returns a copy of the application whose properties of the components and traits referring to
+// the parameters by ${name} are substituted by their values. A string referring to a single parameter is substituted
+// by the typed value, and $${name} is kept as ${name}. The application is returned as it is if it declares no
+// parameter, so the existing properties containing ${...} are kept.
+func substituteParameters(app *v1beta1.Application) (*v1beta1.Application, error) {
+	if len(app.Spec.Parameters) == 0 {
+		return app, nil
+	}
+	values, err := parameterValues(app.Spec.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	substituted := app.DeepCopy()
+	for i := range substituted.Spec.Components {
+		comp := &substituted.Spec.Components[i]
+		if comp.Properties, err = substituteProperties(comp.Properties, values); err != nil {
+			return nil, errors.WithMessagef(err, "component(%s)", comp.Name)
+		}
+		for j := range comp.Traits {
+			tr := &comp.Traits[j]
+			if tr.Properties, err = substituteProperties(tr.Properties, values); err != nil {
+				return nil, errors.WithMessagef(err, "component(%s) trait(%s)", comp.Name, tr.Type)
+			}
+		}
+	}
+	return substituted, nil
+}
+
+func substituteProperties(raw runtime.RawExtension, values map[string]interface{}) (runtime.RawExtension, error) {
+	if !bytes.Contains(raw.Raw, []byte("${")) {
+		return raw, nil
+	}
+	var props interface{}
+	if err := json.Unmarshal(raw.Raw, &props); err != nil {
+		return raw, err
+	}
+	props, err := substitute(props, values)
+	if err != nil {
+		return raw, err
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return raw, err
+	}
+	return runtime.RawExtension{Raw: b}, nil
+}
+
+func substitute(v interface{}, values map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			res, err := substitute(item, values)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = res
+		}
+		return val, nil
+	case []interface{}:
+		for i, item := range val {
+			res, err := substitute(item, values)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = res
+		}
+		return val, nil
+	case string:
+		return substituteString(val, values)
+	default:
+		return v, nil
+	}
+}
+
+func substituteString(s string, values map[string]interface{}) (interface{}, error) {
+	if m := exactParameterPattern.FindStringSubmatch(s); m != nil {
+		return parameterValue(m[1], values)
+	}
+	var err error
+	res := parameterPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref[1] == '$' {
+			// the escaped reference is kept without the escaping $
+			return ref[1:]
+		}
+		v, e := parameterValue(ref[2:len(ref)-1], values)
+		if e != nil {
+			err = e
+			return ref
+		}
+		if str, ok := v.(string); ok {
+			return str
+		}
+		b, e := json.Marshal(v)
+		if e != nil {
+			err = e
+			return ref
+		}
+		return string(b)
+	})
+	return res, err
+}
+
+func parameterValue(name string, values map[string]interface{}) (interface{}, error) {
+	v, ok := values[name]
+	if !ok {
+		return nil, errors.Errorf("parameter %s is not declared, escape it by $${%s} if it's not a parameter", name, name)
+	}
+	if v == nil {
+		return nil, errors.Errorf("parameter %s has no value", name)
+	}
+	return v, nil
+}
+
+// ApplyParameterValues sets the values of the parameters declared by the application, e.g., from a values file of an
+// environment, the values of the parameters not declared are rejected
+func ApplyParameterValues(app *v1beta1.Application, values map[string]interface{}) error {
+	index := map[string]int{}
+	for i, p := range app.Spec.Parameters {
+		index[p.Name] = i
+	}
+	for name, v := range values {
+		i, ok := index[name]
+		if !ok {
+			return errors.Errorf("parameter %s is not declared by application %s", name, app.Name)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value of parameter %s", name)
+		}
+		app.Spec.Parameters[i].Value = &runtime.RawExtension{Raw: b}
+	}
+	return nil
+}
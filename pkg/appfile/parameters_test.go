@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestSubstituteParameters(t *testing.T) {
+	raw := func(s string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(s)}
+	}
+	value := func(s string) *runtime.RawExtension {
+		r := raw(s)
+		return &r
+	}
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+		Parameters: []v1beta1.ApplicationParameter{
+			{Name: "image", Value: value(`"nginx:1.21"`)},
+			{Name: "replicas", Value: value(`3`)},
+			{Name: "domain", Value: value(`"example.com"`)},
+		},
+		Components: []v1beta1.ApplicationComponent{{
+			Name:       "web",
+			Properties: raw(`{"image":"${image}","cmd":["sh","-c","echo $${HOME}"],"env":[{"name":"URL","value":"https://${domain}:${replicas}"}]}`),
+			Traits:     []v1beta1.ApplicationTrait{{Type: "scaler", Properties: raw(`{"replicas":"${replicas}"}`)}},
+		}},
+	}}
+	substituted, err := substituteParameters(app)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"image":"nginx:1.21","cmd":["sh","-c","echo ${HOME}"],"env":[{"name":"URL","value":"https://example.com:3"}]}`,
+		string(substituted.Spec.Components[0].Properties.Raw))
+	// a string referring to a single parameter is substituted by the typed value
+	assert.JSONEq(t, `{"replicas":3}`, string(substituted.Spec.Components[0].Traits[0].Properties.Raw))
+	// the application is not changed
+	assert.Contains(t, string(app.Spec.Components[0].Properties.Raw), "${image}")
+
+	// the properties are kept as they are if no parameter is declared
+	noParams := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{{
+		Name: "web", Properties: raw(`{"cmd":"echo ${HOME}"}`),
+	}}}}
+	substituted, err = substituteParameters(noParams)
+	assert.NoError(t, err)
+	assert.Equal(t, noParams, substituted)
+
+	for _, props := range []string{`{"cmd":"echo ${HOME}"}`, `{"size":"${size}"}`} {
+		invalid := app.DeepCopy()
+		invalid.Spec.Parameters = append(invalid.Spec.Parameters, v1beta1.ApplicationParameter{Name: "size"})
+		invalid.Spec.Components[0].Properties = raw(props)
+		_, err = substituteParameters(invalid)
+		assert.Error(t, err, props)
+	}
+	duplicated := app.DeepCopy()
+	duplicated.Spec.Parameters = append(duplicated.Spec.Parameters, v1beta1.ApplicationParameter{Name: "image"})
+	_, err = substituteParameters(duplicated)
+	assert.EqualError(t, err, "parameter image is declared more than once")
+}
+
+func TestMergeParameters(t *testing.T) {
+	declared := []v1beta1.ApplicationParameter{
+		{Name: "image", Value: &runtime.RawExtension{Raw: []byte(`"nginx:1.21"`)}},
+		{Name: "replicas", Value: &runtime.RawExtension{Raw: []byte(`1`)}},
+	}
+	env := []v1beta1.ApplicationParameter{
+		{Name: "replicas", Value: &runtime.RawExtension{Raw: []byte(`5`)}},
+		{Name: "region", Value: &runtime.RawExtension{Raw: []byte(`"eu"`)}},
+	}
+	merged, overridden := mergeParameters(declared, env)
+	assert.Equal(t, []string{"replicas"}, overridden)
+	assert.Equal(t, 2, len(merged))
+	assert.Equal(t, `"nginx:1.21"`, string(merged[0].Value.Raw))
+	assert.Equal(t, `5`, string(merged[1].Value.Raw))
+	assert.Equal(t, `1`, string(declared[1].Value.Raw))
+}
+
+func TestApplyParameterValues(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Parameters: []v1beta1.ApplicationParameter{{Name: "replicas"}}}}
+	assert.NoError(t, ApplyParameterValues(app, map[string]interface{}{"replicas": 3}))
+	assert.Equal(t, `3`, string(app.Spec.Parameters[0].Value.Raw))
+	assert.Error(t, ApplyParameterValues(app, map[string]interface{}{"image": "nginx"}))
+}
@@ -25,20 +25,27 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile/config"
+	"github.com/oam-dev/kubevela/pkg/appfile/gitsource"
+	"github.com/oam-dev/kubevela/pkg/appfile/helm"
 	velacue "github.com/oam-dev/kubevela/pkg/cue"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/dsl/process"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/utils/vault"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 const (
@@ -60,6 +67,11 @@ type Parser struct {
 	dm         discoverymapper.DiscoveryMapper
 	pd         *definition.PackageDiscover
 	tmplLoader TemplateLoaderFn
+	// vault reads the property values from Vault, they cannot be resolved if it's nil
+	vault *vault.Client
+	// valueFromPlaceholders replaces the property values from the external sources by placeholders instead of
+	// reading them
+	valueFromPlaceholders bool
 }
 
 // NewApplicationParser create appfile parser
@@ -69,6 +81,7 @@ func NewApplicationParser(cli client.Client, dm discoverymapper.DiscoveryMapper,
 		dm:         dm,
 		pd:         pd,
 		tmplLoader: LoadTemplate,
+		vault:      vault.DefaultClient,
 	}
 }
 
@@ -79,29 +92,172 @@ func NewDryRunApplicationParser(cli client.Client, dm discoverymapper.DiscoveryM
 		dm:         dm,
 		pd:         pd,
 		tmplLoader: DryRunTemplateLoader(defs),
+		// the sensitive values are never printed by the dry run
+		valueFromPlaceholders: true,
 	}
 }
 
+// NewRevisionApplicationParser creates an appfile parser rendering strictly from the definitions and CUE packages
+// snapshotted in the ApplicationRevision, the application in the revision is parsed by GenerateAppFile with it to
+// re-render exactly the same resources, e.g., for audits
+func NewRevisionApplicationParser(cli client.Client, dm discoverymapper.DiscoveryMapper, appRev *v1beta1.ApplicationRevision) (*Parser, error) {
+	pd, err := definition.NewPackageDiscoverFromSnapshot(appRev.Spec.CUEPackages)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot load CUE packages of application revision %s", appRev.Name)
+	}
+	return &Parser{
+		client:     cli,
+		dm:         dm,
+		pd:         pd,
+		tmplLoader: RevisionTemplateLoader(appRev),
+		vault:      vault.DefaultClient,
+	}, nil
+}
+
+// WithValueFromPlaceholders makes the parser replace the property values from the external sources, i.e., Secrets
+// and Vault, by placeholders instead of reading them, e.g., on admission when the sources may not exist yet
+func (p *Parser) WithValueFromPlaceholders() *Parser {
+	p.valueFromPlaceholders = true
+	return p
+}
+
 // GenerateAppFile converts an application to an Appfile
 func (p *Parser) GenerateAppFile(ctx context.Context, app *v1beta1.Application) (*Appfile, error) {
 	ns := app.Namespace
 	appName := app.Name
 
+	// the policies of the application are expanded from its environment
+	app, env, err := expandEnvironment(ctx, p.client, app)
+	if err != nil {
+		return nil, err
+	}
+	// the parameters are substituted with the values of the environment
+	if app, err = substituteParameters(app); err != nil {
+		return nil, err
+	}
+
 	appfile := new(Appfile)
 	appfile.Name = appName
 	appfile.Namespace = ns
+	conditions, err := newConditionEvaluator(app, env)
+	if err != nil {
+		return nil, err
+	}
+	resolver := &valueResolver{cli: p.client, namespace: ns, vault: p.vault, placeholders: p.valueFromPlaceholders}
 	var wds []*Workload
 	for _, comp := range app.Spec.Components {
-		wd, err := p.parseWorkload(ctx, comp, appName, ns)
+		enabled, err := conditions.eval(comp.If)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "component(%s)", comp.Name)
+		}
+		if !enabled {
+			decision.Record(ctx, decision.StageParse, comp.Name, "component skipped since the condition %q is false", comp.If)
+			continue
+		}
+		wd, err := p.parseWorkload(ctx, comp, appName, ns, conditions, resolver)
 		if err != nil {
 			return nil, err
 		}
+		wd.DependsOn = comp.DependsOn
 		wds = append(wds, wd)
 	}
+	bindServiceDependencies(wds)
+	if err := resolveDependencies(app, wds); err != nil {
+		return nil, err
+	}
 	appfile.Workloads = wds
+	if appfile.ApplyOnceRules, err = parseApplyOncePolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.SharedResourceRules, err = parseSharedResourcePolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.GarbageCollectRules, err = parseGarbageCollectPolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.WindowsNodeRules, err = parseWindowsNodePolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.AutoExposeRules, err = parseAutoExposePolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.Placement, err = parsePlacement(app); err != nil {
+		return nil, err
+	}
+	if appfile.AppDependencies, err = parseAppDependencies(app); err != nil {
+		return nil, err
+	}
+	if appfile.Topologies, err = parseTopologyPolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.Overrides, err = parseOverridePolicies(app); err != nil {
+		return nil, err
+	}
+	if appfile.DeploySteps, err = parseDeploySteps(app, appfile); err != nil {
+		return nil, err
+	}
+	if appfile.BlueGreenPromoteSteps, err = parseBlueGreenPromoteSteps(app, wds); err != nil {
+		return nil, err
+	}
+	policyTypes := make([]string, 0, len(app.Spec.Policies))
+	for _, policy := range app.Spec.Policies {
+		policyTypes = append(policyTypes, policy.Type)
+	}
+	if appfile.PolicyTemplates, err = p.loadDefinedTemplates(ctx, policyTypes, types.TypePolicy); err != nil {
+		return nil, err
+	}
+	stepTypes := make([]string, 0, len(app.Spec.Workflow))
+	for _, step := range app.Spec.Workflow {
+		stepTypes = append(stepTypes, step.Type)
+		for _, sub := range step.SubSteps {
+			stepTypes = append(stepTypes, sub.Type)
+		}
+	}
+	if appfile.WorkflowStepTemplates, err = p.loadDefinedTemplates(ctx, stepTypes, types.TypeWorkflowStep); err != nil {
+		return nil, err
+	}
+	stepDefs := make(map[string]*v1beta1.WorkflowStepDefinition, len(appfile.WorkflowStepTemplates))
+	for typ, tmpl := range appfile.WorkflowStepTemplates {
+		stepDefs[typ] = tmpl.WorkflowStepDefinition
+	}
+	if appfile.WorkflowStepPolicies, err = workflow.ParseStepPolicies(app.Spec.Workflow, stepDefs); err != nil {
+		return nil, err
+	}
+	if appfile.WorkflowStepGroups, err = workflow.ParseStepGroups(app.Spec.Workflow); err != nil {
+		return nil, err
+	}
+	if err := workflow.ValidateStepConditions(app.Spec.Workflow); err != nil {
+		return nil, err
+	}
+	if err := workflow.ValidateStepIO(app.Spec.Workflow); err != nil {
+		return nil, err
+	}
+	if _, err := workflow.ParseApprovals(app.Spec.Workflow); err != nil {
+		return nil, err
+	}
 	return appfile, nil
 }
 
+// loadDefinedTemplates loads the templates of the types defined by definitions, the types without definitions are
+// skipped since they're built-in ones handled by the controller
+func (p *Parser) loadDefinedTemplates(ctx context.Context, typs []string, capType types.CapType) (map[string]*Template, error) {
+	templates := make(map[string]*Template)
+	for _, typ := range typs {
+		if _, ok := templates[typ]; ok {
+			continue
+		}
+		templ, err := p.tmplLoader.LoadTemplate(ctx, p.dm, p.client, typ, capType)
+		if err != nil {
+			if cause := errors.Cause(err); kerrors.IsNotFound(cause) || meta.IsNoMatchError(cause) {
+				continue
+			}
+			return nil, errors.WithMessagef(err, "fetch %s definition of %s", capType, typ)
+		}
+		templates[typ] = templ
+	}
+	return templates, nil
+}
+
 func (p *Parser) makeWorkload(ctx context.Context, appName, ns, name, typ string, capType types.CapType, props runtime.RawExtension) (*Workload, error) {
 	templ, err := p.tmplLoader.LoadTemplate(ctx, p.dm, p.client, typ, capType)
 	if err != nil && !kerrors.IsNotFound(err) {
@@ -115,6 +271,12 @@ func (p *Parser) makeWorkload(ctx context.Context, appName, ns, name, typ string
 	wlType, err := util.ConvertDefinitionRevName(typ)
 	if err != nil {
 		wlType = typ
+		// the semantic version constraint, e.g., webservice@^1.2, is resolved to the name of the revision matching it
+		if strings.Contains(typ, "@") && p.client != nil {
+			if revName, err := util.ResolveDefinitionRevName(ctx, p.client, typ, common.ComponentType); err == nil {
+				wlType = revName
+			}
+		}
 	}
 	workload := &Workload{
 		Traits:             []*Trait{},
@@ -126,6 +288,24 @@ func (p *Parser) makeWorkload(ctx context.Context, appName, ns, name, typ string
 		engine:             definition.NewWorkloadAbstractEngine(name, p.pd),
 	}
 
+	if templ.CapabilityCategory == types.HelmCategory && helm.IsOCIRepository(templ.Helm) && p.client != nil {
+		cred, err := helm.LoadRepositoryCredentials(ctx, p.client, ns, templ.Helm)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "fail to load the registry credentials for %s", name)
+		}
+		workload.HelmCredentials = cred
+	}
+
+	if templ.CapabilityCategory == types.GitCategory && templ.Git != nil && p.client != nil {
+		// the credentials of the repository are loaded from the namespace of the definition rather than the
+		// application, so the applications can't use the credentials of others by naming their Secrets
+		cred, err := gitsource.LoadCredentials(ctx, p.client, definitionNamespace(templ, ns), templ.Git)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "fail to load the git credentials for %s", name)
+		}
+		workload.GitCredentials = cred
+	}
+
 	if workload.IsCloudResourceConsumer() {
 		requiredSecrets, err := parseWorkloadInsertSecretTo(ctx, p.client, ns, workload)
 		if err != nil {
@@ -150,13 +330,31 @@ func (p *Parser) makeWorkload(ctx context.Context, appName, ns, name, typ string
 
 // parseWorkload resolve an ApplicationComponent and generate a Workload
 // containing ALL information required by an Appfile.
-func (p *Parser) parseWorkload(ctx context.Context, comp v1beta1.ApplicationComponent, appName, ns string) (*Workload, error) {
-	workload, err := p.makeWorkload(ctx, appName, ns, comp.Name, comp.Type, types.TypeComponentDefinition, comp.Properties)
+func (p *Parser) parseWorkload(ctx context.Context, comp v1beta1.ApplicationComponent, appName, ns string, conditions *conditionEvaluator, resolver *valueResolver) (*Workload, error) {
+	// the sensitive values are resolved from the external sources at render time, so they never appear in the
+	// application spec
+	resolver.sources = nil
+	properties, err := resolver.resolveProperties(ctx, comp.Name, comp.Properties)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "component(%s) resolve properties", comp.Name)
+	}
+	workload, err := p.makeWorkload(ctx, appName, ns, comp.Name, comp.Type, types.TypeComponentDefinition, properties)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, traitValue := range comp.Traits {
+		enabled, err := conditions.eval(traitValue.If)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "component(%s) trait(%s)", comp.Name, traitValue.Type)
+		}
+		if !enabled {
+			decision.Record(ctx, decision.StageParse, comp.Name, "trait %s skipped since the condition %q is false", traitValue.Type, traitValue.If)
+			continue
+		}
+		if traitValue.Properties, err = resolver.resolveProperties(ctx, comp.Name, traitValue.Properties); err != nil {
+			return nil, errors.WithMessagef(err, "component(%s) trait(%s) resolve properties", comp.Name, traitValue.Type)
+		}
 		properties, err := util.RawExtension2Map(&traitValue.Properties)
 		if err != nil {
 			return nil, errors.Errorf("fail to parse properties of %s for %s", traitValue.Type, comp.Name)
@@ -165,6 +363,17 @@ func (p *Parser) parseWorkload(ctx context.Context, comp v1beta1.ApplicationComp
 		if err != nil {
 			return nil, errors.WithMessagef(err, "component(%s) parse trait(%s)", comp.Name, traitValue.Type)
 		}
+		// the trait is rendered with the workload by the built-in handler its definition declares, if any
+		if handler := traitHandlerOf(trait.FullTemplate); len(handler) != 0 {
+			handle, ok := traitHandlers[handler]
+			if !ok {
+				return nil, errors.Errorf("component(%s) trait(%s) declares unknown handler %q", comp.Name, traitValue.Type, handler)
+			}
+			if err := handle(workload, trait, traitValue.Properties); err != nil {
+				return nil, errors.WithMessagef(err, "component(%s) parse trait(%s)", comp.Name, traitValue.Type)
+			}
+			continue
+		}
 
 		workload.Traits = append(workload.Traits, trait)
 	}
@@ -178,8 +387,39 @@ func (p *Parser) parseWorkload(ctx context.Context, comp v1beta1.ApplicationComp
 			GVK:  gvk,
 		})
 	}
+	workload.ValueSources = resolver.sources
 	return workload, nil
 }
+
+// traitHandlers are the built-in handlers rendering the traits with the workloads, keyed by the names declared by the
+// definitions through the definition.oam.dev/trait-handler annotation
+var traitHandlers = map[string]func(wl *Workload, trait *Trait, raw runtime.RawExtension) error{
+	TraitHandlerBlueGreen:      handleBlueGreenTrait,
+	TraitHandlerServiceBinding: handleServiceBindingTrait,
+}
+
+// definitionNamespace returns the namespace of the definition of the template, or the namespace of the application if
+// the template is not loaded from a definition
+func definitionNamespace(templ *Template, ns string) string {
+	switch {
+	case templ.ComponentDefinition != nil && len(templ.ComponentDefinition.Namespace) != 0:
+		return templ.ComponentDefinition.Namespace
+	case templ.WorkloadDefinition != nil && len(templ.WorkloadDefinition.Namespace) != 0:
+		return templ.WorkloadDefinition.Namespace
+	default:
+		return ns
+	}
+}
+
+// traitHandlerOf returns the built-in handler declared by the definition of the trait, it's empty if the trait is
+// rendered by its template
+func traitHandlerOf(templ *Template) string {
+	if templ == nil || templ.TraitDefinition == nil {
+		return ""
+	}
+	return templ.TraitDefinition.GetAnnotations()[oam.AnnotationTraitHandler]
+}
+
 func (p *Parser) parseTrait(ctx context.Context, name string, properties map[string]interface{}) (*Trait, error) {
 	templ, err := p.tmplLoader.LoadTemplate(ctx, p.dm, p.client, name, types.TypeTrait)
 	if kerrors.IsNotFound(err) {
@@ -0,0 +1,398 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// TraitHandlerServiceBinding is the built-in trait handler injecting the keys of Secrets, e.g., the connection
+// secrets of the cloud resources, into the containers of the workload as env vars or mounted files
+const TraitHandlerServiceBinding = "service-binding"
+
+// serviceBindingVolumePrefix is prepended to the names of the Secrets to name the volumes mounting them
+const serviceBindingVolumePrefix = "binding-"
+
+// SecretKeySelector selects a key of a Secret
+type SecretKeySelector struct {
+	// Secret is the name of the Secret
+	Secret string `json:"secret"`
+	// Key is the key of the Secret, it's the name of the env var if empty
+	Key string `json:"key,omitempty"`
+}
+
+// SecretBinding binds the keys of a Secret to the containers
+type SecretBinding struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+	// Keys maps the keys of the Secret bound to their names in the containers, i.e., the names of the env vars or
+	// the files. A key is not renamed if it's mapped to an empty name. All the keys are bound if it's empty.
+	Keys map[string]string `json:"keys,omitempty"`
+	// Env injects the keys as env vars, it's true unless MountPath is set
+	Env *bool `json:"env,omitempty"`
+	// EnvPrefix is prepended to the names of the env vars
+	EnvPrefix string `json:"envPrefix,omitempty"`
+	// MountPath is the directory the keys are mounted in as files, they're not mounted if it's empty
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+func (b *SecretBinding) injectsEnv() bool {
+	if b.Env != nil {
+		return *b.Env
+	}
+	return len(b.MountPath) == 0
+}
+
+// ServiceBindingTraitSpec is the properties of the service-binding trait
+type ServiceBindingTraitSpec struct {
+	// EnvMappings maps the names of the env vars to the keys of the Secrets
+	EnvMappings map[string]SecretKeySelector `json:"envMappings,omitempty"`
+	// Secrets are the Secrets bound as env vars or mounted files
+	Secrets []SecretBinding `json:"secrets,omitempty"`
+	// Containers are the names of the containers injected, all the containers are injected if it's empty
+	Containers []string `json:"containers,omitempty"`
+}
+
+// ServiceBinding is the service-binding trait of a component
+type ServiceBinding struct {
+	// Type is the type of the trait, i.e., the name of its definition
+	Type string
+	ServiceBindingTraitSpec
+}
+
+// handleServiceBindingTrait is the trait handler of the service-binding trait
+func handleServiceBindingTrait(wl *Workload, trait *Trait, raw runtime.RawExtension) error {
+	sb, err := parseServiceBindingTrait(raw)
+	if err != nil {
+		return err
+	}
+	sb.Type = trait.Name
+	wl.ServiceBinding = sb
+	return nil
+}
+
+func parseServiceBindingTrait(raw runtime.RawExtension) (*ServiceBinding, error) {
+	sb := &ServiceBinding{}
+	if len(raw.Raw) != 0 {
+		if err := json.Unmarshal(raw.Raw, &sb.ServiceBindingTraitSpec); err != nil {
+			return nil, err
+		}
+	}
+	if len(sb.EnvMappings) == 0 && len(sb.Secrets) == 0 {
+		return nil, errors.New("neither envMappings nor secrets is set")
+	}
+	for env, sel := range sb.EnvMappings {
+		if len(sel.Secret) == 0 {
+			return nil, errors.Errorf("the secret of env %s is not set", env)
+		}
+	}
+	mounted := map[string]bool{}
+	for _, b := range sb.Secrets {
+		if len(b.Name) == 0 {
+			return nil, errors.New("the name of a secret is not set")
+		}
+		if !b.injectsEnv() && len(b.MountPath) == 0 {
+			return nil, errors.Errorf("secret %s is neither injected as env nor mounted", b.Name)
+		}
+		if len(b.MountPath) != 0 {
+			if mounted[b.Name] {
+				return nil, errors.Errorf("secret %s is mounted more than once", b.Name)
+			}
+			mounted[b.Name] = true
+		}
+		for key := range b.Keys {
+			if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+				return nil, errors.Errorf("invalid key %q of secret %s: %s", key, b.Name, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return sb, nil
+}
+
+// secretNames returns the names of the Secrets bound
+func (sb *ServiceBinding) secretNames() []string {
+	names := map[string]bool{}
+	for _, sel := range sb.EnvMappings {
+		names[sel.Secret] = true
+	}
+	for _, b := range sb.Secrets {
+		names[b.Name] = true
+	}
+	var res []string
+	for n := range names {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// envVars returns the env vars referring to the keys of the Secrets and the Secrets injected as a whole, the keys
+// are renamed as they're mapped
+func (sb *ServiceBinding) envVars() ([]corev1.EnvVar, []corev1.EnvFromSource) {
+	var (
+		env     []corev1.EnvVar
+		envFrom []corev1.EnvFromSource
+	)
+	envNames := make([]string, 0, len(sb.EnvMappings))
+	for name := range sb.EnvMappings {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		sel := sb.EnvMappings[name]
+		key := sel.Key
+		if len(key) == 0 {
+			key = name
+		}
+		env = append(env, secretKeyEnvVar(name, sel.Secret, key))
+	}
+	for _, b := range sb.Secrets {
+		if !b.injectsEnv() {
+			continue
+		}
+		if len(b.Keys) == 0 {
+			envFrom = append(envFrom, corev1.EnvFromSource{
+				Prefix:    b.EnvPrefix,
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: b.Name}},
+			})
+			continue
+		}
+		for _, key := range sortedKeys(b.Keys) {
+			env = append(env, secretKeyEnvVar(b.EnvPrefix+renamed(b.Keys, key), b.Name, key))
+		}
+	}
+	return env, envFrom
+}
+
+// volumes returns the volumes of the Secrets mounted as files and their mounts, the keys are renamed as they're
+// mapped
+func (sb *ServiceBinding) volumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	var (
+		volumes []corev1.Volume
+		mounts  []corev1.VolumeMount
+	)
+	for _, b := range sb.Secrets {
+		if len(b.MountPath) == 0 {
+			continue
+		}
+		source := &corev1.SecretVolumeSource{SecretName: b.Name}
+		for _, key := range sortedKeys(b.Keys) {
+			source.Items = append(source.Items, corev1.KeyToPath{Key: key, Path: renamed(b.Keys, key)})
+		}
+		name := serviceBindingVolumePrefix + b.Name
+		volumes = append(volumes, corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{Secret: source}})
+		mounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: b.MountPath, ReadOnly: true})
+	}
+	return volumes, mounts
+}
+
+func secretKeyEnvVar(name, secret, key string) corev1.EnvVar {
+	return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+		Key:                  key,
+	}}}
+}
+
+func renamed(keys map[string]string, key string) string {
+	if name := keys[key]; len(name) != 0 {
+		return name
+	}
+	return key
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// producedSecretName returns the name of the connection Secret written by the workload of a cloud resource, it's
+// empty if the workload writes no Secret
+func producedSecretName(wl *Workload) string {
+	if name, ok := wl.Params[process.OutputSecretName].(string); ok {
+		return name
+	}
+	if ref, ok := wl.Params[WriteConnectionSecretToRefKey].(map[string]interface{}); ok {
+		if name, ok := ref["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// bindServiceDependencies makes the workloads with the service-binding trait depend on the workloads writing the
+// Secrets they bind, so they're dispatched once the cloud resources are ready and the Secrets are written
+func bindServiceDependencies(wds []*Workload) {
+	producers := map[string]string{}
+	for _, wd := range wds {
+		if name := producedSecretName(wd); len(name) != 0 {
+			producers[name] = wd.Name
+		}
+	}
+	for _, wd := range wds {
+		if wd.ServiceBinding == nil {
+			continue
+		}
+		deps := map[string]bool{wd.Name: true}
+		for _, dep := range wd.DependsOn {
+			deps[dep] = true
+		}
+		for _, secret := range wd.ServiceBinding.secretNames() {
+			if producer, ok := producers[secret]; ok && !deps[producer] {
+				deps[producer] = true
+				wd.DependsOn = append(wd.DependsOn, producer)
+			}
+		}
+	}
+}
+
+// injectServiceBinding injects the keys of the Secrets bound by the service-binding trait into the containers of
+// the workload as env vars or mounted files. The pod spec is located by the podSpecPath of the definition or the
+// known workload kinds, no patch in CUE is needed.
+func (af *Appfile) injectServiceBinding(wl *Workload, comp *v1alpha2.Component) error {
+	if wl.ServiceBinding == nil || len(comp.Spec.Workload.Raw) == 0 {
+		return nil
+	}
+	obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	if err != nil {
+		return errors.Wrapf(err, "cannot convert workload of component(%s)", wl.Name)
+	}
+	if util.IsReferredWorkload(obj) {
+		return errors.Errorf("component(%s) refers to an existing workload which cannot be injected by the %s trait", wl.Name, wl.ServiceBinding.Type)
+	}
+	path := wl.podSpecPath(obj)
+	if path == nil {
+		return errors.Errorf("cannot locate the pod spec of %s of component(%s) to bind the secrets, declare the podSpecPath in the definition",
+			obj.GetKind(), wl.Name)
+	}
+	podSpec := corev1.PodSpec{}
+	raw, _, err := unstructured.NestedMap(obj.Object, path...)
+	if err != nil {
+		return errors.Wrapf(err, "invalid pod spec at %s of component(%s)", strings.Join(path, "."), wl.Name)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &podSpec); err != nil {
+		return errors.Wrapf(err, "invalid pod spec at %s of component(%s)", strings.Join(path, "."), wl.Name)
+	}
+
+	env, envFrom := wl.ServiceBinding.envVars()
+	volumes, mounts := wl.ServiceBinding.volumes()
+	injected := 0
+	for i := range podSpec.Containers {
+		c := &podSpec.Containers[i]
+		if !containsOrEmpty(wl.ServiceBinding.Containers, c.Name) {
+			continue
+		}
+		c.Env = mergeEnvVars(c.Env, env)
+		c.EnvFrom = mergeEnvFrom(c.EnvFrom, envFrom)
+		c.VolumeMounts = mergeVolumeMounts(c.VolumeMounts, mounts)
+		injected++
+	}
+	if injected == 0 {
+		return errors.Errorf("no container of component(%s) matches %s to bind the secrets", wl.Name, strings.Join(wl.ServiceBinding.Containers, ", "))
+	}
+	podSpec.Volumes = mergeVolumes(podSpec.Volumes, volumes)
+
+	res, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&podSpec)
+	if err != nil {
+		return errors.Wrapf(err, "cannot convert pod spec of component(%s)", wl.Name)
+	}
+	if err := unstructured.SetNestedMap(obj.Object, res, path...); err != nil {
+		return errors.Wrapf(err, "cannot set pod spec at %s of component(%s)", strings.Join(path, "."), wl.Name)
+	}
+	util.AddAnnotations(obj, map[string]string{oam.AnnotationBoundSecrets: strings.Join(wl.ServiceBinding.secretNames(), ",")})
+	comp.Spec.Workload = util.Object2RawExtension(obj)
+	return nil
+}
+
+// mergeEnvVars sets the bound env vars, the ones of the same names declared by the container are overridden
+func mergeEnvVars(declared, bound []corev1.EnvVar) []corev1.EnvVar {
+	index := map[string]int{}
+	for i, e := range declared {
+		index[e.Name] = i
+	}
+	for _, e := range bound {
+		if i, ok := index[e.Name]; ok {
+			declared[i] = e
+			continue
+		}
+		index[e.Name] = len(declared)
+		declared = append(declared, e)
+	}
+	return declared
+}
+
+func mergeEnvFrom(declared, bound []corev1.EnvFromSource) []corev1.EnvFromSource {
+	for _, b := range bound {
+		found := false
+		for _, d := range declared {
+			if d.SecretRef != nil && d.SecretRef.Name == b.SecretRef.Name && d.Prefix == b.Prefix {
+				found = true
+				break
+			}
+		}
+		if !found {
+			declared = append(declared, b)
+		}
+	}
+	return declared
+}
+
+func mergeVolumeMounts(declared, bound []corev1.VolumeMount) []corev1.VolumeMount {
+	index := map[string]int{}
+	for i, m := range declared {
+		index[m.Name] = i
+	}
+	for _, m := range bound {
+		if i, ok := index[m.Name]; ok {
+			declared[i] = m
+			continue
+		}
+		declared = append(declared, m)
+	}
+	return declared
+}
+
+func mergeVolumes(declared, bound []corev1.Volume) []corev1.Volume {
+	index := map[string]int{}
+	for i, v := range declared {
+		index[v.Name] = i
+	}
+	for _, v := range bound {
+		if i, ok := index[v.Name]; ok {
+			declared[i] = v
+			continue
+		}
+		declared = append(declared, v)
+	}
+	return declared
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestParseServiceBindingTrait(t *testing.T) {
+	sb, err := parseServiceBindingTrait(runtime.RawExtension{Raw: []byte(`{"envMappings":{"DB_HOST":{"secret":"db-conn"}}}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db-conn"}, sb.secretNames())
+
+	for _, raw := range []string{
+		`{}`,
+		`{"envMappings":{"DB_HOST":{"key":"host"}}}`,
+		`{"secrets":[{"keys":{"host":""}}]}`,
+		`{"secrets":[{"name":"db-conn","env":false}]}`,
+		`{"secrets":[{"name":"db-conn","mountPath":"/a"},{"name":"db-conn","mountPath":"/b"}]}`,
+		`{"secrets":[{"name":"db-conn","keys":{"../host":""}}]}`,
+	} {
+		_, err = parseServiceBindingTrait(runtime.RawExtension{Raw: []byte(raw)})
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestServiceBindingTraitHandler(t *testing.T) {
+	ctx := context.Background()
+	compDef := &v1beta1.ComponentDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ComponentDefinitionKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec: v1beta1.ComponentDefinitionSpec{Schematic: &common.Schematic{CUE: &common.CUE{
+			Template: "output: {\n\tapiVersion: \"apps/v1\"\n\tkind: \"Deployment\"\n}\nparameter: {}\n"}}},
+	}
+	traitDef := func(annotations map[string]string) *v1beta1.TraitDefinition {
+		return &v1beta1.TraitDefinition{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.TraitDefinitionKind},
+			ObjectMeta: metav1.ObjectMeta{Name: "bind-db", Annotations: annotations},
+			Spec: v1beta1.TraitDefinitionSpec{Schematic: &common.Schematic{CUE: &common.CUE{
+				Template: "patch: {}\nparameter: {...}\n"}}},
+		}
+	}
+	comp := v1beta1.ApplicationComponent{Name: "web", Type: "worker", Traits: []v1beta1.ApplicationTrait{{
+		Type: "bind-db", Properties: runtime.RawExtension{Raw: []byte(`{"envMappings":{"DB_HOST":{"secret":"db-conn"}}}`)},
+	}}}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	app.Spec.Components = []v1beta1.ApplicationComponent{comp}
+	parse := func(td *v1beta1.TraitDefinition) (*Workload, error) {
+		var defs []oam.Object
+		for _, def := range []interface{}{compDef, td} {
+			obj, err := util.Object2Unstructured(def)
+			require.NoError(t, err)
+			defs = append(defs, obj)
+		}
+		conditions, err := newConditionEvaluator(app, nil)
+		require.NoError(t, err)
+		p := &Parser{tmplLoader: DryRunTemplateLoader(defs)}
+		return p.parseWorkload(ctx, comp, app.Name, app.Namespace, conditions, &valueResolver{})
+	}
+
+	// the definition declaring the handler binds the secrets with the workload whatever it's named
+	wl, err := parse(traitDef(map[string]string{oam.AnnotationTraitHandler: TraitHandlerServiceBinding}))
+	require.NoError(t, err)
+	assert.Empty(t, wl.Traits)
+	require.NotNil(t, wl.ServiceBinding)
+	assert.Equal(t, "bind-db", wl.ServiceBinding.Type)
+	assert.Equal(t, []string{"db-conn"}, wl.ServiceBinding.secretNames())
+
+	// the definition without the handler is rendered by its template
+	wl, err = parse(traitDef(nil))
+	require.NoError(t, err)
+	assert.Nil(t, wl.ServiceBinding)
+	assert.Len(t, wl.Traits, 1)
+
+	_, err = parse(traitDef(map[string]string{oam.AnnotationTraitHandler: "unknown"}))
+	assert.Error(t, err)
+}
+
+func TestBindServiceDependencies(t *testing.T) {
+	wds := []*Workload{
+		{Name: "web", ServiceBinding: &ServiceBinding{ServiceBindingTraitSpec: ServiceBindingTraitSpec{
+			EnvMappings: map[string]SecretKeySelector{"DB_HOST": {Secret: "db-conn"}},
+			Secrets:     []SecretBinding{{Name: "oss-conn"}, {Name: "tls"}},
+		}}, DependsOn: []string{"db"}},
+		{Name: "db", Params: map[string]interface{}{WriteConnectionSecretToRefKey: map[string]interface{}{"name": "db-conn"}}},
+		{Name: "oss", Params: map[string]interface{}{"outputSecretName": "oss-conn"}},
+	}
+	bindServiceDependencies(wds)
+	assert.Equal(t, []string{"db", "oss"}, wds[0].DependsOn)
+	assert.Empty(t, wds[1].DependsOn)
+}
+
+func TestInjectServiceBinding(t *testing.T) {
+	newComp := func() *v1alpha2.Component {
+		comp := &v1alpha2.Component{}
+		comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment",
+			"spec":{"template":{"spec":{"containers":[
+			{"name":"web","image":"nginx","env":[{"name":"DB_HOST","value":"localhost"},{"name":"MODE","value":"prod"}]},
+			{"name":"sidecar","image":"envoy"}]}}}}`)}
+		return comp
+	}
+	containersOf := func(comp *v1alpha2.Component) (*unstructured.Unstructured, corev1.PodSpec) {
+		obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		assert.NoError(t, err)
+		raw, _, _ := unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+		podSpec := corev1.PodSpec{}
+		assert.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &podSpec))
+		return obj, podSpec
+	}
+
+	af := &Appfile{Name: "app"}
+	wl := &Workload{Name: "web", ServiceBinding: &ServiceBinding{ServiceBindingTraitSpec: ServiceBindingTraitSpec{
+		EnvMappings: map[string]SecretKeySelector{"DB_HOST": {Secret: "db-conn", Key: "host"}},
+		Secrets: []SecretBinding{
+			{Name: "oss-conn", EnvPrefix: "OSS_"},
+			{Name: "db-conn", Keys: map[string]string{"user": "DB_USER", "password": ""}, EnvPrefix: "APP_"},
+			{Name: "db-tls", MountPath: "/etc/tls", Keys: map[string]string{"tls.crt": "client.crt"}},
+		},
+		Containers: []string{"web"},
+	}}}
+	comp := newComp()
+	assert.NoError(t, af.injectServiceBinding(wl, comp))
+	obj, podSpec := containersOf(comp)
+	assert.Equal(t, "db-conn,db-tls,oss-conn", obj.GetAnnotations()[oam.AnnotationBoundSecrets])
+
+	web := podSpec.Containers[0]
+	var names []string
+	for _, e := range web.Env {
+		names = append(names, e.Name)
+	}
+	// the declared env var is overridden in place
+	assert.Equal(t, []string{"DB_HOST", "MODE", "APP_password", "APP_DB_USER"}, names)
+	assert.Equal(t, "host", web.Env[0].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, "user", web.Env[3].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, []corev1.EnvFromSource{{Prefix: "OSS_", SecretRef: &corev1.SecretEnvSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "oss-conn"}}}}, web.EnvFrom)
+	assert.Equal(t, []corev1.VolumeMount{{Name: "binding-db-tls", MountPath: "/etc/tls", ReadOnly: true}}, web.VolumeMounts)
+	assert.Equal(t, []corev1.KeyToPath{{Key: "tls.crt", Path: "client.crt"}}, podSpec.Volumes[0].Secret.Items)
+	// the containers not selected are kept as they are
+	assert.Empty(t, podSpec.Containers[1].Env)
+	assert.Empty(t, podSpec.Containers[1].VolumeMounts)
+
+	// the injection is idempotent
+	assert.NoError(t, af.injectServiceBinding(wl, comp))
+	_, podSpec = containersOf(comp)
+	assert.Equal(t, 4, len(podSpec.Containers[0].Env))
+	assert.Equal(t, 1, len(podSpec.Containers[0].EnvFrom))
+	assert.Equal(t, 1, len(podSpec.Containers[0].VolumeMounts))
+	assert.Equal(t, 1, len(podSpec.Volumes))
+
+	wl.ServiceBinding.Containers = []string{"missing"}
+	assert.Error(t, af.injectServiceBinding(wl, newComp()))
+
+	// the pod spec path declared by the definition is used for unknown kinds
+	crd := &v1alpha2.Component{}
+	crd.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Server","spec":{"pod":{"containers":[{"name":"main"}]}}}`)}
+	wl = &Workload{Name: "server", ServiceBinding: &ServiceBinding{ServiceBindingTraitSpec: ServiceBindingTraitSpec{Secrets: []SecretBinding{{Name: "db-conn"}}}}}
+	assert.Error(t, af.injectServiceBinding(wl, crd))
+	wl.FullTemplate = &Template{ComponentDefinition: &v1beta1.ComponentDefinition{Spec: v1beta1.ComponentDefinitionSpec{PodSpecPath: "spec.pod"}}}
+	assert.NoError(t, af.injectServiceBinding(wl, crd))
+	obj, err := util.RawExtension2Unstructured(&crd.Spec.Workload)
+	assert.NoError(t, err)
+	envFrom, _, _ := unstructured.NestedSlice(obj.Object, "spec", "pod", "containers")
+	assert.Equal(t, 1, len(envFrom))
+	assert.Contains(t, envFrom[0].(map[string]interface{}), "envFrom")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// PolicyTypeSharedResource is the type of the policy declaring the rendered resources which can be shared with
+// other applications, e.g., a Namespace or a ConfigMap rendered by several applications
+const PolicyTypeSharedResource = "shared-resource"
+
+// SharedResourcePolicySpec is the properties of the shared-resource policy
+type SharedResourcePolicySpec struct {
+	Rules []SharedResourcePolicyRule `json:"rules"`
+}
+
+// SharedResourcePolicyRule selects the resources shared with other applications
+type SharedResourcePolicyRule struct {
+	Selector ResourceSelector `json:"selector"`
+}
+
+func parseSharedResourcePolicies(app *v1beta1.Application) ([]SharedResourcePolicyRule, error) {
+	var rules []SharedResourcePolicyRule
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeSharedResource {
+			continue
+		}
+		spec := &SharedResourcePolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		rules = append(rules, spec.Rules...)
+	}
+	return rules, nil
+}
+
+// markSharedResource annotates the rendered workload and traits selected by the shared-resource policies,
+// they are owned by all the applications rendering them and deleted when the last one is removed
+func (af *Appfile) markSharedResource(comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) error {
+	var selectors []ResourceSelector
+	for _, rule := range af.SharedResourceRules {
+		selectors = append(selectors, rule.Selector)
+	}
+	return annotateSelectedResources(comp, acComp, selectors, oam.AnnotationSharedResource)
+}
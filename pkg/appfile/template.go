@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -56,6 +57,8 @@ type Template struct {
 	Helm               *common.Helm
 	Kube               *common.Kube
 	Terraform          *common.Terraform
+	Kustomize          *common.Kustomize
+	Git                *common.Git
 	// TODO: Add scope definition too
 	ComponentDefinition    *v1beta1.ComponentDefinition
 	WorkloadDefinition     *v1beta1.WorkloadDefinition
@@ -189,6 +192,63 @@ func DryRunTemplateLoader(defs []oam.Object) TemplateLoaderFn {
 	})
 }
 
+// RevisionTemplateLoader returns a function that loads templates strictly from the definitions snapshotted in the
+// ApplicationRevision, it never loads definitions from the cluster so that rendering an application from its
+// revision always results in the same resources
+func RevisionTemplateLoader(appRev *v1beta1.ApplicationRevision) TemplateLoaderFn {
+	return TemplateLoaderFn(func(ctx context.Context, dm discoverymapper.DiscoveryMapper, _ client.Reader, capName string, capType types.CapType) (*Template, error) {
+		// the snapshot records the definition resolved for the type, no matter whether a revision is specified
+		defName := capName
+		if i := strings.LastIndex(capName, "@"); i > 0 {
+			defName = capName[:i]
+		}
+		snapshot := appRev.Spec
+		switch capType {
+		case types.TypeComponentDefinition:
+			if cd, ok := snapshot.ComponentDefinitions[defName]; ok {
+				return newTemplateOfCompDefinition(cd.DeepCopy())
+			}
+			if wd, ok := snapshot.WorkloadDefinitions[defName]; ok {
+				tmpl, err := newTemplateOfWorkloadDefinition(wd.DeepCopy())
+				if err != nil {
+					return nil, err
+				}
+				gvk, err := oamutil.GetGVKFromDefinition(dm, wd.Spec.Reference)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "Get GVK from workload definition [%s]", capName)
+				}
+				tmpl.Reference = common.WorkloadTypeDescriptor{
+					Definition: common.WorkloadGVK{
+						APIVersion: gvk.GroupVersion().String(),
+						Kind:       gvk.Kind,
+					},
+				}
+				return tmpl, nil
+			}
+			return nil, errors.Errorf("component type %s is not found in the snapshot of application revision %s", capName, appRev.Name)
+		case types.TypeTrait:
+			if td, ok := snapshot.TraitDefinitions[defName]; ok {
+				return newTemplateOfTraitDefinition(td.DeepCopy())
+			}
+			return nil, errors.Errorf("trait type %s is not found in the snapshot of application revision %s", capName, appRev.Name)
+		case types.TypePolicy:
+			if d, ok := snapshot.PolicyDefinitions[defName]; ok {
+				return newTemplateOfPolicyDefinition(d.DeepCopy())
+			}
+			// built-in policies are not defined by definitions
+			return nil, kerrors.NewNotFound(v1beta1.SchemeGroupVersion.WithResource("policydefinitions").GroupResource(), capName)
+		case types.TypeWorkflowStep:
+			if d, ok := snapshot.WorkflowStepDefinitions[defName]; ok {
+				return newTemplateOfWorkflowStepDefinition(d.DeepCopy())
+			}
+			// built-in workflow steps are not defined by definitions
+			return nil, kerrors.NewNotFound(v1beta1.SchemeGroupVersion.WithResource("workflowstepdefinitions").GroupResource(), capName)
+		default:
+			return nil, fmt.Errorf("kind(%s) of %s not supported", capType, capName)
+		}
+	})
+}
+
 func newTemplateOfCompDefinition(compDef *v1beta1.ComponentDefinition) (*Template, error) {
 	tmpl := &Template{
 		Reference:           compDef.Spec.Workload,
@@ -270,6 +330,16 @@ func loadSchematicToTemplate(tmpl *Template, status *common.Status, schematic *c
 			tmpl.Terraform = schematic.Terraform
 			return nil
 		}
+		if schematic.Kustomize != nil {
+			tmpl.CapabilityCategory = types.KustomizeCategory
+			tmpl.Kustomize = schematic.Kustomize
+			return nil
+		}
+		if schematic.Git != nil {
+			tmpl.CapabilityCategory = types.GitCategory
+			tmpl.Git = schematic.Git
+			return nil
+		}
 	}
 
 	if tmpl.TemplateStr == "" && ext != nil {
@@ -24,6 +24,8 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ktypes "k8s.io/apimachinery/pkg/types"
 
@@ -524,3 +526,41 @@ spec:
 		t.Fatal("failed load template of trait definition ", diff)
 	}
 }
+
+func TestRevisionTemplateLoader(t *testing.T) {
+	schematic := &common.Schematic{CUE: &common.CUE{Template: "testCUE"}}
+	appRev := &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v1"},
+		Spec: v1beta1.ApplicationRevisionSpec{
+			ComponentDefinitions: map[string]v1beta1.ComponentDefinition{
+				"worker": {Spec: v1beta1.ComponentDefinitionSpec{Schematic: schematic}},
+			},
+			TraitDefinitions: map[string]v1beta1.TraitDefinition{
+				"scaler": {Spec: v1beta1.TraitDefinitionSpec{Schematic: schematic}},
+			},
+			PolicyDefinitions: map[string]v1beta1.PolicyDefinition{
+				"override": {Spec: v1beta1.PolicyDefinitionSpec{Schematic: schematic}},
+			},
+		},
+	}
+	loader := RevisionTemplateLoader(appRev)
+
+	compTmpl, err := loader(context.Background(), nil, nil, "worker@v2", types.TypeComponentDefinition)
+	assert.NoError(t, err)
+	assert.Equal(t, "testCUE", compTmpl.TemplateStr)
+
+	traitTmpl, err := loader(context.Background(), nil, nil, "scaler", types.TypeTrait)
+	assert.NoError(t, err)
+	assert.Equal(t, "testCUE", traitTmpl.TemplateStr)
+
+	policyTmpl, err := loader(context.Background(), nil, nil, "override", types.TypePolicy)
+	assert.NoError(t, err)
+	assert.Equal(t, "testCUE", policyTmpl.TemplateStr)
+
+	_, err = loader(context.Background(), nil, nil, "suspend", types.TypeWorkflowStep)
+	assert.True(t, kerrors.IsNotFound(err))
+
+	_, err = loader(context.Background(), nil, nil, "unknown", types.TypeTrait)
+	assert.Error(t, err)
+	assert.False(t, kerrors.IsNotFound(err))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/oam-dev/terraform-config-inspect/tfconfig"
+	terraformapi "github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+// TerraformJSONType is the type of the Terraform configuration in JSON syntax, it's HCL syntax otherwise
+const TerraformJSONType = "json"
+
+// ParseTerraformModule parses the variables and outputs declared by the Terraform configuration
+func ParseTerraformModule(tf *common.Terraform) (*tfconfig.Module, error) {
+	if tf == nil || len(tf.Configuration) == 0 {
+		return nil, errors.New(errTerraformConfigurationIsNotSet)
+	}
+	var (
+		file  *hcl.File
+		diags hcl.Diagnostics
+	)
+	p := hclparse.NewParser()
+	if tf.Type == TerraformJSONType {
+		file, diags = p.ParseJSON([]byte(tf.Configuration), "main.tf.json")
+	} else {
+		file, diags = p.ParseHCL([]byte(tf.Configuration), "main.tf")
+	}
+	if diags.HasErrors() {
+		return nil, errors.Wrap(diags, "cannot parse the Terraform configuration")
+	}
+	mod := &tfconfig.Module{
+		Variables: map[string]*tfconfig.Variable{},
+		Outputs:   map[string]*tfconfig.Output{},
+	}
+	if diags := tfconfig.LoadModuleFromFile(file, mod); diags.HasErrors() {
+		return nil, errors.Wrap(diags, "cannot load the Terraform module")
+	}
+	return mod, nil
+}
+
+// TerraformOutputs returns the non-sensitive outputs of the Terraform module, the sensitive ones are only written
+// to the connection secret
+func TerraformOutputs(mod *tfconfig.Module, outputs map[string]terraformapi.Property) map[string]string {
+	if len(outputs) == 0 {
+		return nil
+	}
+	r := make(map[string]string, len(outputs))
+	for name, o := range outputs {
+		if declared, ok := mod.Outputs[name]; !ok || declared.Sensitive {
+			continue
+		}
+		r[name] = o.Value
+	}
+	return r
+}
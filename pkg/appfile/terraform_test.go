@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	terraformapi "github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestParseTerraformModule(t *testing.T) {
+	hcl := `
+variable "bucket" {
+  type = string
+}
+
+variable "acl" {
+  default = "private"
+}
+
+output "endpoint" {
+  value = "oss.example.com"
+}
+
+output "password" {
+  value     = "secret"
+  sensitive = true
+}
+`
+	mod, err := ParseTerraformModule(&common.Terraform{Configuration: hcl, Type: "hcl"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mod.Variables))
+	assert.True(t, mod.Variables["bucket"].Required)
+	assert.False(t, mod.Variables["acl"].Required)
+
+	outputs := TerraformOutputs(mod, map[string]terraformapi.Property{
+		"endpoint":   {Value: "oss.example.com"},
+		"password":   {Value: "secret"},
+		"undeclared": {Value: "x"},
+	})
+	assert.Equal(t, map[string]string{"endpoint": "oss.example.com"}, outputs)
+
+	json := `{"variable": {"bucket": {"type": "string"}}}`
+	mod, err = ParseTerraformModule(&common.Terraform{Configuration: json, Type: TerraformJSONType})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mod.Variables))
+
+	_, err = ParseTerraformModule(&common.Terraform{Configuration: `variable "bucket" {`})
+	assert.Error(t, err)
+	_, err = ParseTerraformModule(&common.Terraform{})
+	assert.Error(t, err)
+}
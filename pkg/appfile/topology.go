@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// PolicyTypeTopology is the type of the policy selecting the managed clusters the components are deployed to by
+// the deploy workflow steps
+const PolicyTypeTopology = "topology"
+
+// TypeDeploy is the type of the built-in workflow step dispatching the rendered components to the managed clusters
+// selected by topology policies
+const TypeDeploy = "deploy"
+
+// TopologyPolicySpec is the properties of the topology policy
+type TopologyPolicySpec struct {
+	// Clusters are the names of the selected clusters
+	Clusters []string `json:"clusters,omitempty"`
+	// ClusterLabelSelector selects the clusters by labels, it's exclusive with Clusters
+	ClusterLabelSelector map[string]string `json:"clusterLabelSelector,omitempty"`
+	// Namespace is the namespace the components are deployed to in the clusters, it's the namespace of the
+	// application if empty
+	Namespace string `json:"namespace,omitempty"`
+	// Overrides patch the workloads of the components deployed to specific clusters
+	Overrides []ClusterOverride `json:"overrides,omitempty"`
+}
+
+// ClusterOverride is a JSON merge patch to the workloads of the components deployed to the clusters
+type ClusterOverride struct {
+	// Clusters are the names of the clusters patched, all the selected clusters are patched if it's empty
+	Clusters []string `json:"clusters,omitempty"`
+	// Components are the names of the components patched, all the components are patched if it's empty
+	Components []string `json:"components,omitempty"`
+	// Patch is the JSON merge patch to the workload, e.g., {"spec":{"replicas":5}}
+	Patch runtime.RawExtension `json:"patch"`
+}
+
+// DeploySpec is the properties of the deploy workflow step
+type DeploySpec struct {
+	// Env is the name of the environment declared in an env-binding policy, the components are deployed to its
+	// clusters and namespace and patched by its patch
+	Env string `json:"env,omitempty"`
+	// Policies are the names of the topology policies selecting the clusters, and the override policies patching
+	// the components deployed to them, they're applied after the environment
+	Policies []string `json:"policies,omitempty"`
+	// Parallelism is the maximum number of clusters deployed to at the same time, all the clusters are deployed to
+	// at the same time if zero
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// Selects returns true if the override patches the component deployed to the cluster
+func (o *ClusterOverride) Selects(cluster, compName string) bool {
+	return containsOrEmpty(o.Clusters, cluster) && containsOrEmpty(o.Components, compName)
+}
+
+func containsOrEmpty(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTopologyPolicies(app *v1beta1.Application) (map[string]*TopologyPolicySpec, error) {
+	topologies := map[string]*TopologyPolicySpec{}
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeTopology {
+			continue
+		}
+		spec := &TopologyPolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		if err := validateTopology(spec); err != nil {
+			return nil, errors.WithMessagef(err, "policy %s", p.Name)
+		}
+		topologies[p.Name] = spec
+	}
+	return topologies, nil
+}
+
+func validateTopology(spec *TopologyPolicySpec) error {
+	if (len(spec.Clusters) == 0) == (len(spec.ClusterLabelSelector) == 0) {
+		return errors.New("the clusters must be selected by either clusters or clusterLabelSelector")
+	}
+	for i, o := range spec.Overrides {
+		patch := map[string]interface{}{}
+		if err := json.Unmarshal(o.Patch.Raw, &patch); err != nil {
+			return errors.Wrapf(err, "the patch of override %d is not a JSON object", i)
+		}
+	}
+	return nil
+}
+
+func parseDeploySteps(app *v1beta1.Application, af *Appfile) (map[string]*DeploySpec, error) {
+	envs, err := parseEnvBindings(app)
+	if err != nil {
+		return nil, err
+	}
+	steps := map[string]*DeploySpec{}
+	for _, step := range app.Spec.Workflow {
+		for _, sub := range step.SubSteps {
+			if sub.Type == TypeDeploy {
+				return nil, errors.Errorf("sub-step %s of workflow step %s cannot be a %s step", sub.Name, step.Name, TypeDeploy)
+			}
+		}
+		if step.Type != TypeDeploy {
+			continue
+		}
+		spec := &DeploySpec{}
+		if len(step.Properties.Raw) != 0 {
+			if err := json.Unmarshal(step.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+			}
+		}
+		if len(spec.Env) != 0 {
+			env, ok := envs[spec.Env]
+			if !ok {
+				return nil, errors.Errorf("workflow step %s deploys to environment %s which is not found in %s policies", step.Name, spec.Env, PolicyTypeEnvBinding)
+			}
+			// the environment is deployed as a topology policy and an override policy under the same name
+			key := env.key()
+			af.Topologies[key] = &env.Placement
+			if env.Patch != nil {
+				af.Overrides[key] = env.Patch
+			}
+			spec.Policies = append([]string{key}, spec.Policies...)
+		}
+		hasTopology := false
+		for _, name := range spec.Policies {
+			_, isTopology := af.Topologies[name]
+			if _, isOverride := af.Overrides[name]; !isTopology && !isOverride {
+				return nil, errors.Errorf("workflow step %s refers to policy %s which is neither a %s nor an %s policy",
+					step.Name, name, PolicyTypeTopology, PolicyTypeOverride)
+			}
+			hasTopology = hasTopology || isTopology
+		}
+		if !hasTopology {
+			return nil, errors.Errorf("workflow step %s refers to no %s policy or environment", step.Name, PolicyTypeTopology)
+		}
+		if spec.Parallelism < 0 {
+			return nil, errors.Errorf("invalid parallelism %d of workflow step %s", spec.Parallelism, step.Name)
+		}
+		steps[step.Name] = spec
+	}
+	return steps, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestParseDeploySteps(t *testing.T) {
+	newApp := func(topology, deploy string) *v1beta1.Application {
+		return &v1beta1.Application{
+			Spec: v1beta1.ApplicationSpec{
+				Policies: []v1beta1.AppPolicy{{
+					Name:       "prod-clusters",
+					Type:       PolicyTypeTopology,
+					Properties: runtime.RawExtension{Raw: []byte(topology)},
+				}},
+				Workflow: []v1beta1.WorkflowStep{{
+					Name:       "deploy-prod",
+					Type:       TypeDeploy,
+					Properties: runtime.RawExtension{Raw: []byte(deploy)},
+				}},
+			},
+		}
+	}
+
+	app := newApp(`{"clusterLabelSelector":{"env":"prod"},"namespace":"prod","overrides":[{"clusters":["east"],"patch":{"spec":{"replicas":3}}}]}`,
+		`{"policies":["prod-clusters"],"parallelism":2}`)
+	topologies, err := parseTopologyPolicies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, topologies["prod-clusters"].ClusterLabelSelector)
+	assert.Equal(t, "prod", topologies["prod-clusters"].Namespace)
+	override := topologies["prod-clusters"].Overrides[0]
+	assert.True(t, override.Selects("east", "web"))
+	assert.False(t, override.Selects("west", "web"))
+	steps, err := parseDeploySteps(app, &Appfile{Topologies: topologies})
+	assert.NoError(t, err)
+	assert.Equal(t, &DeploySpec{Policies: []string{"prod-clusters"}, Parallelism: 2}, steps["deploy-prod"])
+
+	testCases := map[string]struct {
+		topology string
+		deploy   string
+		err      string
+	}{
+		"no cluster selected": {
+			topology: `{"namespace":"prod"}`,
+			deploy:   `{"policies":["prod-clusters"]}`,
+			err:      "must be selected by either clusters or clusterLabelSelector",
+		},
+		"both names and labels": {
+			topology: `{"clusters":["east"],"clusterLabelSelector":{"env":"prod"}}`,
+			deploy:   `{"policies":["prod-clusters"]}`,
+			err:      "must be selected by either clusters or clusterLabelSelector",
+		},
+		"patch not an object": {
+			topology: `{"clusters":["east"],"overrides":[{"patch":[1]}]}`,
+			deploy:   `{"policies":["prod-clusters"]}`,
+			err:      "is not a JSON object",
+		},
+		"policy not found": {
+			topology: `{"clusters":["east"]}`,
+			deploy:   `{"policies":["dev-clusters"]}`,
+			err:      "refers to policy dev-clusters which is neither a topology nor an override policy",
+		},
+		"no policy": {
+			topology: `{"clusters":["east"]}`,
+			deploy:   `{}`,
+			err:      "refers to no topology policy or environment",
+		},
+		"negative parallelism": {
+			topology: `{"clusters":["east"]}`,
+			deploy:   `{"policies":["prod-clusters"],"parallelism":-1}`,
+			err:      "invalid parallelism -1",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			app := newApp(tc.topology, tc.deploy)
+			topologies, err := parseTopologyPolicies(app)
+			if err == nil {
+				_, err = parseDeploySteps(app, &Appfile{Topologies: topologies})
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/utils/vault"
+)
+
+// ValueFromKey is the key of the object in the properties whose value is resolved from an external source at render
+// time, e.g., {"password": {"valueFrom": {"secretKeyRef": {"name": "db", "key": "password"}}}}
+const ValueFromKey = "valueFrom"
+
+// ValueFromPlaceholder replaces the values from the external sources if they're not read, e.g., on admission
+const ValueFromPlaceholder = "(valueFrom)"
+
+// VaultRefreshInterval is the interval the applications resolving values from Vault are re-rendered at to pick up
+// the rotated secrets, the ones from Secrets are re-rendered once the Secrets change
+var VaultRefreshInterval = 5 * time.Minute
+
+// ValueFrom is the external source of a property value, only one of the sources can be set
+type ValueFrom struct {
+	// SecretKeyRef selects a key of a Secret in the namespace of the application, the property is dropped if the
+	// Secret or the key is missing and it's optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// VaultPath refers to a key of a secret in Vault in the form of <path>#<key>, the path must be under the root of
+	// the namespace of the application, e.g., secret/data/<namespace>/db#password
+	VaultPath string `json:"vaultPath,omitempty"`
+}
+
+// valueResolver resolves the values from the external sources in the properties of a component and records the
+// sources, the Secrets read are cached in one parse
+type valueResolver struct {
+	cli          client.Reader
+	namespace    string
+	vault        *vault.Client
+	placeholders bool
+	secrets      map[string]*corev1.Secret
+	sources      []common.ValueSource
+}
+
+// resolveProperties resolves the values from the external sources in the properties, the properties are returned as
+// they are if none refers to an external source
+func (r *valueResolver) resolveProperties(ctx context.Context, comp string, raw runtime.RawExtension) (runtime.RawExtension, error) {
+	if !bytes.Contains(raw.Raw, []byte(`"`+ValueFromKey+`"`)) {
+		return raw, nil
+	}
+	var props interface{}
+	if err := json.Unmarshal(raw.Raw, &props); err != nil {
+		return raw, err
+	}
+	resolved, _, err := r.resolve(ctx, comp, props)
+	if err != nil {
+		return raw, err
+	}
+	b, err := json.Marshal(resolved)
+	if err != nil {
+		return raw, err
+	}
+	return runtime.RawExtension{Raw: b}, nil
+}
+
+// resolve replaces the objects referring to the external sources by their values recursively, it returns false if
+// the value is dropped since its optional source is missing
+func (r *valueResolver) resolve(ctx context.Context, comp string, v interface{}) (interface{}, bool, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if from, ok := val[ValueFromKey]; ok && len(val) == 1 {
+			return r.resolveValueFrom(ctx, comp, from)
+		}
+		for k, item := range val {
+			resolved, ok, err := r.resolve(ctx, comp, item)
+			if err != nil {
+				return nil, false, errors.WithMessage(err, k)
+			}
+			if !ok {
+				delete(val, k)
+				continue
+			}
+			val[k] = resolved
+		}
+		return val, true, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for i, item := range val {
+			resolved, ok, err := r.resolve(ctx, comp, item)
+			if err != nil {
+				return nil, false, errors.WithMessagef(err, "[%d]", i)
+			}
+			if ok {
+				res = append(res, resolved)
+			}
+		}
+		return res, true, nil
+	default:
+		return v, true, nil
+	}
+}
+
+func (r *valueResolver) resolveValueFrom(ctx context.Context, comp string, v interface{}) (interface{}, bool, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false, err
+	}
+	from := &ValueFrom{}
+	if err := json.Unmarshal(b, from); err != nil {
+		return nil, false, errors.Wrapf(err, "invalid %s", ValueFromKey)
+	}
+	switch {
+	case from.SecretKeyRef != nil && len(from.VaultPath) == 0:
+		ref := from.SecretKeyRef
+		if len(ref.Name) == 0 || len(ref.Key) == 0 {
+			return nil, false, errors.Errorf("the name or the key of %s.secretKeyRef is not set", ValueFromKey)
+		}
+		r.sources = append(r.sources, common.ValueSource{Component: comp, Secret: ref.Name})
+		if r.placeholders {
+			return ValueFromPlaceholder, true, nil
+		}
+		return r.secretValue(ctx, ref)
+	case from.SecretKeyRef == nil && len(from.VaultPath) != 0:
+		if _, _, err := vault.SplitPath(from.VaultPath); err != nil {
+			return nil, false, err
+		}
+		r.sources = append(r.sources, common.ValueSource{Component: comp, VaultPath: from.VaultPath})
+		if r.placeholders {
+			// the paths out of the namespace are rejected on admission, dry-run may not know the namespace
+			if r.vault != nil && len(r.namespace) != 0 {
+				if err := r.vault.CheckScope(r.namespace, from.VaultPath); err != nil {
+					return nil, false, err
+				}
+			}
+			return ValueFromPlaceholder, true, nil
+		}
+		if r.vault == nil {
+			return nil, false, errors.Errorf("cannot read %s since the vault address is not configured by %s", from.VaultPath, vault.EnvAddress)
+		}
+		value, err := r.vault.ReadKey(ctx, r.namespace, from.VaultPath)
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	default:
+		return nil, false, errors.Errorf("exactly one of secretKeyRef and vaultPath of %s must be set", ValueFromKey)
+	}
+}
+
+func (r *valueResolver) secretValue(ctx context.Context, ref *corev1.SecretKeySelector) (interface{}, bool, error) {
+	optional := ref.Optional != nil && *ref.Optional
+	secret, ok := r.secrets[ref.Name]
+	if !ok {
+		secret = &corev1.Secret{}
+		if err := r.cli.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: ref.Name}, secret); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, false, errors.Wrapf(err, "cannot get secret %s", ref.Name)
+			}
+			secret = nil
+		}
+		if r.secrets == nil {
+			r.secrets = map[string]*corev1.Secret{}
+		}
+		r.secrets[ref.Name] = secret
+	}
+	if secret == nil {
+		if optional {
+			return nil, false, nil
+		}
+		return nil, false, errors.Errorf("secret %s not found", ref.Name)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		if optional {
+			return nil, false, nil
+		}
+		return nil, false, errors.Errorf("key %s not found in secret %s", ref.Key, ref.Name)
+	}
+	return string(data), true, nil
+}
+
+// ValueSources returns the Secrets and the Vault secrets the properties of the components are resolved from, sorted
+// by the components
+func (af *Appfile) ValueSources() []common.ValueSource {
+	var sources []common.ValueSource
+	seen := map[common.ValueSource]bool{}
+	for _, wl := range af.Workloads {
+		for _, s := range wl.ValueSources {
+			if !seen[s] {
+				seen[s] = true
+				sources = append(sources, s)
+			}
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Component < sources[j].Component
+	})
+	return sources
+}
+
+// VaultRefreshInterval returns the interval the application is re-rendered at to pick up the rotated secrets in
+// Vault, it's zero if no property is resolved from Vault
+func (af *Appfile) VaultRefreshInterval() time.Duration {
+	for _, s := range af.ValueSources() {
+		if len(s.VaultPath) != 0 {
+			return VaultRefreshInterval
+		}
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/utils/vault"
+)
+
+func TestResolveProperties(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(s))
+	cli := fake.NewFakeClientWithScheme(s, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-conn", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("s3cret")},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" || r.URL.Path != "/v1/secret/data/default/api" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"key":"abc"},"metadata":{"version":2}}}`))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	resolve := func(r *valueResolver, raw string) (string, error) {
+		res, err := r.resolveProperties(ctx, "web", runtime.RawExtension{Raw: []byte(raw)})
+		return string(res.Raw), err
+	}
+
+	r := &valueResolver{cli: cli, namespace: "default", vault: &vault.Client{Address: server.URL, Token: "token"}}
+	res, err := resolve(r, `{"image":"nginx","env":[{"name":"PASSWORD","value":{"valueFrom":{"secretKeyRef":{"name":"db-conn","key":"password"}}}}],
+		"apiKey":{"valueFrom":{"vaultPath":"secret/data/default/api#key"}},
+		"cert":{"valueFrom":{"secretKeyRef":{"name":"tls","key":"tls.crt","optional":true}}}}`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"image":"nginx","env":[{"name":"PASSWORD","value":"s3cret"}],"apiKey":"abc"}`, res)
+	assert.ElementsMatch(t, []common.ValueSource{
+		{Component: "web", Secret: "db-conn"},
+		{Component: "web", VaultPath: "secret/data/default/api#key"},
+		{Component: "web", Secret: "tls"},
+	}, r.sources)
+
+	// the properties without external sources are kept as they are
+	res, err = resolve(r, `{"image": "nginx"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"image": "nginx"}`, res)
+
+	// the sources are recorded without being read on admission
+	placeholder := &valueResolver{placeholders: true}
+	res, err = resolve(placeholder, `{"password":{"valueFrom":{"secretKeyRef":{"name":"missing","key":"password"}}}}`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"password":"`+ValueFromPlaceholder+`"}`, res)
+	assert.Equal(t, []common.ValueSource{{Component: "web", Secret: "missing"}}, placeholder.sources)
+
+	for _, raw := range []string{
+		`{"password":{"valueFrom":{"secretKeyRef":{"name":"db-conn","key":"user"}}}}`,
+		`{"password":{"valueFrom":{"secretKeyRef":{"name":"missing","key":"password"}}}}`,
+		`{"password":{"valueFrom":{"secretKeyRef":{"name":"db-conn"}}}}`,
+		`{"password":{"valueFrom":{"vaultPath":"secret/data/default/api"}}}`,
+		`{"password":{"valueFrom":{"vaultPath":"secret/data/default/db#password"}}}`,
+		`{"password":{"valueFrom":{"vaultPath":"secret/data/other/api#key"}}}`,
+		`{"password":{"valueFrom":{"vaultPath":"secret/data/default/../../other/api#key"}}}`,
+		`{"password":{"valueFrom":{"vaultPath":"secret/data/default/api#key","secretKeyRef":{"name":"db-conn","key":"password"}}}}`,
+	} {
+		_, err = resolve(r, raw)
+		assert.Error(t, err, raw)
+	}
+	// the paths out of the namespace are rejected on admission
+	_, err = resolve(&valueResolver{namespace: "default", vault: &vault.Client{}, placeholders: true}, `{"apiKey":{"valueFrom":{"vaultPath":"secret/data/other/api#key"}}}`)
+	assert.Error(t, err)
+	_, err = resolve(&valueResolver{cli: cli, namespace: "default"}, `{"apiKey":{"valueFrom":{"vaultPath":"secret/data/default/api#key"}}}`)
+	assert.Error(t, err)
+}
+
+func TestValueSources(t *testing.T) {
+	af := &Appfile{Workloads: []*Workload{
+		{Name: "web", ValueSources: []common.ValueSource{{Component: "web", Secret: "db-conn"}, {Component: "web", Secret: "db-conn"}}},
+		{Name: "api", ValueSources: []common.ValueSource{{Component: "api", VaultPath: "secret/data/default/api#key"}}},
+	}}
+	assert.Equal(t, []common.ValueSource{
+		{Component: "api", VaultPath: "secret/data/default/api#key"},
+		{Component: "web", Secret: "db-conn"},
+	}, af.ValueSources())
+	assert.Equal(t, VaultRefreshInterval, af.VaultRefreshInterval())
+	af.Workloads = af.Workloads[:1]
+	assert.Zero(t, af.VaultRefreshInterval())
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// PolicyTypeWindowsNode is the type of the policy scheduling the selected components to Windows nodes
+const PolicyTypeWindowsNode = "windows-node"
+
+const osWindows = "windows"
+
+// names of the issues found when rendering the Windows components, they're reported as lint warnings
+const (
+	// WarningLinuxOnlyTrait reports the traits whose definitions only support Linux
+	WarningLinuxOnlyTrait = "linux-only-trait"
+	// WarningWindowsNoPodSpec reports the workloads whose pod spec cannot be located to inject the node selector
+	WarningWindowsNoPodSpec = "windows-no-podspec"
+)
+
+// windowsTaintToleration tolerates the taint usually set on Windows nodes to keep Linux pods away
+var windowsTaintToleration = corev1.Toleration{
+	Key:      "os",
+	Operator: corev1.TolerationOpEqual,
+	Value:    osWindows,
+	Effect:   corev1.TaintEffectNoSchedule,
+}
+
+// WindowsNodePolicySpec is the properties of the windows-node policy
+type WindowsNodePolicySpec struct {
+	// Components are the names of the Windows components, all the components are selected if it's empty
+	Components []string `json:"components,omitempty"`
+	// OSBuild is the Windows build the nodes must run, e.g., 10.0.17763, since the Windows container images must
+	// match the build of the host
+	OSBuild string `json:"osBuild,omitempty"`
+	// Tolerations tolerate the taints of the Windows nodes, the taint os=windows:NoSchedule is tolerated if not set
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+func (p *WindowsNodePolicySpec) selects(compName string) bool {
+	if len(p.Components) == 0 {
+		return true
+	}
+	for _, c := range p.Components {
+		if c == compName {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWindowsNodePolicies(app *v1beta1.Application) ([]WindowsNodePolicySpec, error) {
+	var specs []WindowsNodePolicySpec
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeWindowsNode {
+			continue
+		}
+		spec := WindowsNodePolicySpec{}
+		if len(p.Properties.Raw) != 0 {
+			if err := json.Unmarshal(p.Properties.Raw, &spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		if spec.Tolerations == nil {
+			spec.Tolerations = []corev1.Toleration{windowsTaintToleration}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// supportedOS returns the operating systems declared in the annotation oam.AnnotationSupportedOS of a definition,
+// nil if it's not declared
+func supportedOS(annotations map[string]string) []string {
+	value, ok := annotations[oam.AnnotationSupportedOS]
+	if !ok {
+		return nil
+	}
+	var oses []string
+	for _, os := range strings.Split(value, ",") {
+		if os = strings.ToLower(strings.TrimSpace(os)); len(os) != 0 {
+			oses = append(oses, os)
+		}
+	}
+	return oses
+}
+
+func supportsWindows(oses []string) bool {
+	if oses == nil {
+		return true
+	}
+	for _, os := range oses {
+		if os == osWindows {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareWindowsComponent schedules the workload of the component selected by the windows-node policies to Windows
+// nodes, the first matching policy wins. The component is rejected if its definition declares it doesn't support
+// Windows, e.g., its images are built for Linux only, and the traits only supporting Linux are reported as warnings.
+func (af *Appfile) prepareWindowsComponent(wl *Workload, comp *v1alpha2.Component) error {
+	var policy *WindowsNodePolicySpec
+	for i := range af.WindowsNodeRules {
+		if af.WindowsNodeRules[i].selects(wl.Name) {
+			policy = &af.WindowsNodeRules[i]
+			break
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+	if tmpl := wl.FullTemplate; tmpl != nil {
+		var annotations map[string]string
+		switch {
+		case tmpl.ComponentDefinition != nil:
+			annotations = tmpl.ComponentDefinition.Annotations
+		case tmpl.WorkloadDefinition != nil:
+			annotations = tmpl.WorkloadDefinition.Annotations
+		}
+		if oses := supportedOS(annotations); !supportsWindows(oses) {
+			return errors.Errorf("component(%s) of type %s cannot run on Windows nodes, it only supports %s",
+				wl.Name, wl.Type, strings.Join(oses, ","))
+		}
+	}
+	for _, tr := range wl.Traits {
+		if tr.FullTemplate == nil || tr.FullTemplate.TraitDefinition == nil {
+			continue
+		}
+		if oses := supportedOS(tr.FullTemplate.TraitDefinition.Annotations); !supportsWindows(oses) {
+			af.RenderWarnings = append(af.RenderWarnings, common.LintWarning{
+				Component: wl.Name,
+				Rule:      WarningLinuxOnlyTrait,
+				Message:   fmt.Sprintf("trait %s only supports %s, it may not work with Windows containers", tr.Name, strings.Join(oses, ",")),
+			})
+		}
+	}
+	return af.injectWindowsNodeSelection(wl, policy, &comp.Spec.Workload)
+}
+
+func (af *Appfile) injectWindowsNodeSelection(w *Workload, policy *WindowsNodePolicySpec, raw *runtime.RawExtension) error {
+	compName := w.Name
+	if len(raw.Raw) == 0 {
+		return nil
+	}
+	wl := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw.Raw, &wl.Object); err != nil {
+		return errors.Wrapf(err, "cannot convert workload of component(%s)", compName)
+	}
+	if util.IsReferredWorkload(wl) {
+		// the existing workload is not managed by the application
+		return nil
+	}
+	path := w.podSpecPath(wl)
+	if path == nil {
+		af.RenderWarnings = append(af.RenderWarnings, common.LintWarning{
+			Component: compName,
+			Rule:      WarningWindowsNoPodSpec,
+			Message:   fmt.Sprintf("cannot locate the pod spec of %s to schedule it to Windows nodes", wl.GetKind()),
+		})
+		return nil
+	}
+
+	nodeSelector, _, err := unstructured.NestedStringMap(wl.Object, append(path, "nodeSelector")...)
+	if err != nil {
+		return errors.Wrapf(err, "invalid nodeSelector of component(%s)", compName)
+	}
+	if nodeSelector == nil {
+		nodeSelector = map[string]string{}
+	}
+	nodeSelector[corev1.LabelOSStable] = osWindows
+	if len(policy.OSBuild) != 0 {
+		nodeSelector[corev1.LabelWindowsBuild] = policy.OSBuild
+	}
+	if err := unstructured.SetNestedStringMap(wl.Object, nodeSelector, append(path, "nodeSelector")...); err != nil {
+		return err
+	}
+
+	tolerations, _, err := unstructured.NestedSlice(wl.Object, append(path, "tolerations")...)
+	if err != nil {
+		return errors.Wrapf(err, "invalid tolerations of component(%s)", compName)
+	}
+	for _, t := range policy.Tolerations {
+		toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&t)
+		if err != nil {
+			return err
+		}
+		if !containsToleration(tolerations, toleration) {
+			tolerations = append(tolerations, toleration)
+		}
+	}
+	if err := unstructured.SetNestedSlice(wl.Object, tolerations, append(path, "tolerations")...); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(wl.Object)
+	if err != nil {
+		return err
+	}
+	raw.Raw = b
+	return nil
+}
+
+func containsToleration(tolerations []interface{}, toleration map[string]interface{}) bool {
+	for _, t := range tolerations {
+		if reflect.DeepEqual(t, toleration) {
+			return true
+		}
+	}
+	return false
+}
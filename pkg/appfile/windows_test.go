@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appfile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestPrepareWindowsComponent(t *testing.T) {
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{Policies: []v1beta1.AppPolicy{{
+		Name:       "windows",
+		Type:       PolicyTypeWindowsNode,
+		Properties: runtime.RawExtension{Raw: []byte(`{"components":["iis"],"osBuild":"10.0.17763"}`)},
+	}}}}
+	rules, err := parseWindowsNodePolicies(app)
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{windowsTaintToleration}, rules[0].Tolerations)
+	af := &Appfile{WindowsNodeRules: rules}
+
+	securityContext := &Trait{Name: "security-context", FullTemplate: &Template{TraitDefinition: &v1beta1.TraitDefinition{}}}
+	securityContext.FullTemplate.TraitDefinition.Annotations = map[string]string{oam.AnnotationSupportedOS: "linux"}
+	wl := &Workload{Name: "iis", Type: "webservice", FullTemplate: &Template{ComponentDefinition: &v1beta1.ComponentDefinition{}},
+		Traits: []*Trait{securityContext}}
+	comp := &v1alpha2.Component{}
+	comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","spec":{"template":{"spec":{
+		"nodeSelector":{"disk":"ssd"},"tolerations":[{"key":"os","operator":"Equal","value":"windows","effect":"NoSchedule"}]}}}}`)}
+	assert.NoError(t, af.prepareWindowsComponent(wl, comp))
+
+	obj := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(comp.Spec.Workload.Raw, &obj))
+	nodeSelector, _, _ := unstructured.NestedStringMap(obj, "spec", "template", "spec", "nodeSelector")
+	assert.Equal(t, map[string]string{"disk": "ssd", corev1.LabelOSStable: "windows", corev1.LabelWindowsBuild: "10.0.17763"}, nodeSelector)
+	tolerations, _, _ := unstructured.NestedSlice(obj, "spec", "template", "spec", "tolerations")
+	assert.Equal(t, 1, len(tolerations))
+	assert.Equal(t, 1, len(af.RenderWarnings))
+	assert.Equal(t, WarningLinuxOnlyTrait, af.RenderWarnings[0].Rule)
+
+	// components not selected are untouched
+	other := &v1alpha2.Component{}
+	other.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment"}`)}
+	assert.NoError(t, af.prepareWindowsComponent(&Workload{Name: "nginx"}, other))
+	assert.Equal(t, `{"apiVersion":"apps/v1","kind":"Deployment"}`, string(other.Spec.Workload.Raw))
+
+	// the workload not supporting Windows is rejected
+	wl.FullTemplate.ComponentDefinition.Annotations = map[string]string{oam.AnnotationSupportedOS: "linux"}
+	assert.Error(t, af.prepareWindowsComponent(wl, comp))
+
+	// the workload without pod spec is reported
+	af.RenderWarnings = nil
+	wl.FullTemplate.ComponentDefinition.Annotations = map[string]string{oam.AnnotationSupportedOS: "linux, windows"}
+	comp.Spec.Workload = runtime.RawExtension{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Foo"}`)}
+	assert.NoError(t, af.prepareWindowsComponent(wl, comp))
+	assert.Equal(t, WarningWindowsNoPodSpec, af.RenderWarnings[1].Rule)
+}
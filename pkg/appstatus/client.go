@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appstatus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Client subscribes to the status events of applications served by the vela apiserver as server-sent events, it's
+// for the clients that can't access the Kubernetes API server directly
+type Client struct {
+	// BaseURL is the URL of the apiserver API, e.g., http://127.0.0.1:38081/api
+	BaseURL string
+	// HTTPClient is used to send the requests, http.DefaultClient is used if it's nil
+	HTTPClient *http.Client
+}
+
+// Watch subscribes to the status events of the application, the channel is closed after the last event or if the
+// context is done. The current phase, workflow steps and health are sent first.
+func (c *Client) Watch(ctx context.Context, namespace, name string) (<-chan Event, error) {
+	u := fmt.Sprintf("%s/namespaces/%s/apps/%s/events", strings.TrimSuffix(c.BaseURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		defer resp.Body.Close()
+		var r struct {
+			Data interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&r); err == nil && r.Data != nil {
+			return nil, errors.Errorf("cannot watch application %s: %v", name, r.Data)
+		}
+		return nil, errors.Errorf("cannot watch application %s: %s", name, resp.Status)
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		var data strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line != "" {
+				// the event type is also in the data, only the data lines are read
+				if strings.HasPrefix(line, "data:") {
+					if data.Len() > 0 {
+						data.WriteByte('\n')
+					}
+					data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+				}
+				continue
+			}
+			if data.Len() == 0 {
+				continue
+			}
+			var e Event
+			err := json.Unmarshal([]byte(data.String()), &e)
+			data.Reset()
+			if err != nil {
+				e = Event{Type: EventError, Namespace: namespace, Application: name, Message: "invalid event: " + err.Error()}
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+			if e.Type == EventDeleted || e.Type == EventError {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appstatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestClientWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/namespaces/default/apps/app/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event:Phase\ndata:{\"type\":\"Phase\",\"namespace\":\"default\",\"application\":\"app\",\"phase\":\"running\"}\n\n")
+			fmt.Fprint(w, "event:Health\ndata:{\"type\":\"Health\",\"namespace\":\"default\",\"application\":\"app\",\"component\":\"web\",\"healthy\":true}\n\n")
+			fmt.Fprint(w, "event:Deleted\ndata:{\"type\":\"Deleted\",\"namespace\":\"default\",\"application\":\"app\"}\n\n")
+			// the events after the last one are dropped
+			fmt.Fprint(w, "event:Phase\ndata:{\"type\":\"Phase\"}\n\n")
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code":500,"data":"applications.core.oam.dev \"missing\" not found"}`)
+		}
+	}))
+	defer server.Close()
+	c := &Client{BaseURL: server.URL + "/api/"}
+
+	events, err := c.Watch(context.Background(), "default", "app")
+	require.NoError(t, err)
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	assert.Equal(t, []Event{
+		{Type: EventPhase, Namespace: "default", Application: "app", Phase: common.ApplicationRunning},
+		{Type: EventHealth, Namespace: "default", Application: "app", Component: "web", Healthy: true},
+		{Type: EventDeleted, Namespace: "default", Application: "app"},
+	}, got)
+
+	_, err = c.Watch(context.Background(), "default", "missing")
+	assert.EqualError(t, err, `cannot watch application missing: applications.core.oam.dev "missing" not found`)
+}
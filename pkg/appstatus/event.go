@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appstatus
+
+import (
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// EventType is the type of the application status events
+type EventType string
+
+const (
+	// EventPhase is sent when the phase of the application changes
+	EventPhase EventType = "Phase"
+	// EventWorkflowStep is sent when the phase or the message of a workflow step changes
+	EventWorkflowStep EventType = "WorkflowStep"
+	// EventHealth is sent when the health of a workload or a trait changes
+	EventHealth EventType = "Health"
+	// EventDeleted is sent when the application is deleted, it's the last event
+	EventDeleted EventType = "Deleted"
+	// EventError is sent when the application cannot be watched any more, it's the last event
+	EventError EventType = "Error"
+)
+
+// Event is a change of the status of an application
+type Event struct {
+	Type        EventType `json:"type"`
+	Namespace   string    `json:"namespace"`
+	Application string    `json:"application"`
+	// Generation is the generation of the application when the change is observed
+	Generation int64 `json:"generation,omitempty"`
+
+	// Phase is the phase of the application of the Phase events
+	Phase common.ApplicationPhase `json:"phase,omitempty"`
+
+	// Step is the name of the workflow step of the WorkflowStep events, it's "<group>/<sub-step>" for the sub-steps
+	// of the step groups
+	Step      string                   `json:"step,omitempty"`
+	StepPhase common.WorkflowStepPhase `json:"stepPhase,omitempty"`
+
+	// Component is the component of the Health events, and Trait is the type of the trait if it's the health of a
+	// trait rather than the workload
+	Component string `json:"component,omitempty"`
+	Trait     string `json:"trait,omitempty"`
+	Healthy   bool   `json:"healthy,omitempty"`
+
+	// Message is the message of the workflow step or the health, or the error of the Error events
+	Message string `json:"message,omitempty"`
+}
+
+type stepState struct {
+	name    string
+	phase   common.WorkflowStepPhase
+	message string
+}
+
+type healthState struct {
+	component string
+	trait     string
+	healthy   bool
+	message   string
+}
+
+// Diff returns the events of the changes from the old status of the application to the current one. All of the
+// phase, the workflow steps and the health are sent as changes if the old one is nil.
+func Diff(old, cur *v1beta1.Application) []Event {
+	newEvent := func(t EventType) Event {
+		return Event{Type: t, Namespace: cur.Namespace, Application: cur.Name, Generation: cur.Generation}
+	}
+	var oldStatus common.AppStatus
+	if old != nil {
+		oldStatus = old.Status
+	}
+	var events []Event
+	if old == nil || oldStatus.Phase != cur.Status.Phase {
+		e := newEvent(EventPhase)
+		e.Phase = cur.Status.Phase
+		events = append(events, e)
+	}
+
+	oldSteps := map[string]stepState{}
+	for _, s := range workflowSteps(oldStatus.Workflow) {
+		oldSteps[s.name] = s
+	}
+	for _, s := range workflowSteps(cur.Status.Workflow) {
+		if o, ok := oldSteps[s.name]; ok && o == s {
+			continue
+		}
+		e := newEvent(EventWorkflowStep)
+		e.Step, e.StepPhase, e.Message = s.name, s.phase, s.message
+		events = append(events, e)
+	}
+
+	oldHealth := map[string]healthState{}
+	for _, h := range health(oldStatus.Services) {
+		oldHealth[h.component+"/"+h.trait] = h
+	}
+	for _, h := range health(cur.Status.Services) {
+		if o, ok := oldHealth[h.component+"/"+h.trait]; ok && o == h {
+			continue
+		}
+		e := newEvent(EventHealth)
+		e.Component, e.Trait, e.Healthy, e.Message = h.component, h.trait, h.healthy, h.message
+		events = append(events, e)
+	}
+	return events
+}
+
+func workflowSteps(steps []common.WorkflowStepStatus) []stepState {
+	var states []stepState
+	for _, s := range steps {
+		states = append(states, stepState{name: s.Name, phase: s.Phase, message: s.Message})
+		for _, sub := range s.SubSteps {
+			states = append(states, stepState{name: s.Name + "/" + sub.Name, phase: sub.Phase, message: sub.Message})
+		}
+	}
+	return states
+}
+
+func health(services []common.ApplicationComponentStatus) []healthState {
+	var states []healthState
+	for _, svc := range services {
+		states = append(states, healthState{component: svc.Name, healthy: svc.Healthy, message: svc.Message})
+		for _, t := range svc.Traits {
+			states = append(states, healthState{component: svc.Name, trait: t.Type, healthy: t.Healthy, message: t.Message})
+		}
+	}
+	return states
+}
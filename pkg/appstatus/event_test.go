@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appstatus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestDiff(t *testing.T) {
+	old := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Generation: 1},
+		Status: common.AppStatus{
+			Phase: common.ApplicationRunningWorkflow,
+			Workflow: []common.WorkflowStepStatus{
+				{Name: "deploy", Phase: common.WorkflowStepPhaseSucceeded},
+				{Name: "group", Phase: common.WorkflowStepPhaseRunning, SubSteps: []common.WorkflowSubStepStatus{
+					{Name: "a", Phase: common.WorkflowStepPhaseRunning},
+				}},
+			},
+			Services: []common.ApplicationComponentStatus{{
+				Name: "web", Healthy: false, Message: "Ready:0/1",
+				Traits: []common.ApplicationTraitStatus{{Type: "ingress", Healthy: true}},
+			}},
+		},
+	}
+	cur := old.DeepCopy()
+	cur.Generation = 2
+	cur.Status.Phase = common.ApplicationRunning
+	cur.Status.Workflow[1].Phase = common.WorkflowStepPhaseSucceeded
+	cur.Status.Workflow[1].SubSteps[0].Phase = common.WorkflowStepPhaseSucceeded
+	cur.Status.Services[0].Healthy, cur.Status.Services[0].Message = true, "Ready:1/1"
+
+	newEvent := func(e Event) Event {
+		e.Namespace, e.Application, e.Generation = "default", "app", 2
+		return e
+	}
+	assert.Equal(t, []Event{
+		newEvent(Event{Type: EventPhase, Phase: common.ApplicationRunning}),
+		newEvent(Event{Type: EventWorkflowStep, Step: "group", StepPhase: common.WorkflowStepPhaseSucceeded}),
+		newEvent(Event{Type: EventWorkflowStep, Step: "group/a", StepPhase: common.WorkflowStepPhaseSucceeded}),
+		newEvent(Event{Type: EventHealth, Component: "web", Healthy: true, Message: "Ready:1/1"}),
+	}, Diff(old, cur))
+
+	assert.Empty(t, Diff(cur, cur.DeepCopy()))
+
+	// all of the status is sent on start
+	assert.Len(t, Diff(nil, cur), 6)
+}
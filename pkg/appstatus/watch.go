@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appstatus
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+var applicationGVR = v1beta1.SchemeGroupVersion.WithResource("applications")
+
+// Watcher watches applications and sends the changes of their status as events
+type Watcher struct {
+	client dynamic.Interface
+}
+
+// NewWatcher creates a Watcher
+func NewWatcher(c *rest.Config) (*Watcher, error) {
+	client, err := dynamic.NewForConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{client: client}, nil
+}
+
+// Watch watches the application and sends the changes of its status until the context is done, the application is
+// deleted or it cannot be watched. The current phase, workflow steps and health are sent first. The channel is
+// closed when the watch ends.
+func (w *Watcher) Watch(ctx context.Context, namespace, name string) (<-chan Event, error) {
+	res := w.client.Resource(applicationGVR).Namespace(namespace)
+	u, err := res.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	last, err := toApplication(u)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		send := func(e Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, e := range Diff(nil, last) {
+			if !send(e) {
+				return
+			}
+		}
+		selector := fields.OneTermEqualSelector("metadata.name", name).String()
+		rv := last.ResourceVersion
+		for ctx.Err() == nil {
+			wi, err := res.Watch(ctx, metav1.ListOptions{FieldSelector: selector, ResourceVersion: rv})
+			if err != nil {
+				send(Event{Type: EventError, Namespace: namespace, Application: name, Message: err.Error()})
+				return
+			}
+			// the watch is closed by the API server after a while, it's restarted from the last resource version
+			for we := range wi.ResultChan() {
+				switch we.Type {
+				case watch.Added, watch.Modified:
+					u, ok := we.Object.(*unstructured.Unstructured)
+					if !ok {
+						continue
+					}
+					app, err := toApplication(u)
+					if err != nil {
+						wi.Stop()
+						send(Event{Type: EventError, Namespace: namespace, Application: name, Message: err.Error()})
+						return
+					}
+					rv = app.ResourceVersion
+					for _, e := range Diff(last, app) {
+						if !send(e) {
+							wi.Stop()
+							return
+						}
+					}
+					last = app
+				case watch.Deleted:
+					wi.Stop()
+					send(Event{Type: EventDeleted, Namespace: namespace, Application: name, Generation: last.Generation})
+					return
+				case watch.Error:
+					// the resource version is too old to watch from, the watch is restarted from the latest one
+					if status, ok := we.Object.(*metav1.Status); ok && status.Code == http.StatusGone {
+						rv = ""
+						continue
+					}
+					wi.Stop()
+					send(Event{Type: EventError, Namespace: namespace, Application: name,
+						Message: apierrors.FromObject(we.Object).Error()})
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func toApplication(u *unstructured.Unstructured) (*v1beta1.Application, error) {
+	app := new(v1beta1.Application)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, app); err != nil {
+		return nil, errors.Wrapf(err, "cannot convert application %s", u.GetName())
+	}
+	return app, nil
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bundle packages definitions into capability bundles distributed as OCI artifacts, and installs the bundles
+// into clusters with the versions pinned and the dependencies checked.
+//
+// A bundle is a directory containing the metadata file bundle.yaml and the definitions in the definitions directory:
+//
+//	bundle.yaml
+//	definitions/
+//	  webservice.yaml
+//	  ingress.yaml
+//
+// When the bundle is loaded, the CUE packages imported by the templates are recorded in the metadata and the OpenAPI
+// schemas of the parameters are generated into the schemas directory.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue/parser"
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+)
+
+const (
+	// MetadataFile is the file of the metadata at the root of a bundle
+	MetadataFile = "bundle.yaml"
+	// DefinitionsDir is the directory of the definitions in a bundle
+	DefinitionsDir = "definitions"
+	// SchemasDir is the directory of the OpenAPI schemas of the parameters in a packed bundle
+	SchemasDir = "schemas"
+
+	// ConfigMediaType is the media type of the config of the bundle artifacts, i.e., the metadata in JSON
+	ConfigMediaType = "application/vnd.oam.dev.bundle.config.v1+json"
+	// ContentMediaType is the media type of the layer of the bundle artifacts, i.e., the packed bundle
+	ContentMediaType = "application/vnd.oam.dev.bundle.content.v1.tar+gzip"
+)
+
+// MaxSize is the maximum size in bytes of the files in a packed bundle
+var MaxSize int64 = 8 << 20
+
+// definitionKinds are the kinds of the definitions allowed in a bundle
+var definitionKinds = []string{
+	v1beta1.ComponentDefinitionKind,
+	v1beta1.TraitDefinitionKind,
+	v1beta1.PolicyDefinitionKind,
+	v1beta1.WorkflowStepDefinitionKind,
+}
+
+var parameterRegexp = regexp.MustCompile(`(?m)^\s*parameter:`)
+
+// Metadata describes a bundle
+type Metadata struct {
+	// Name is the name of the bundle, it labels the definitions installed
+	Name string `json:"name"`
+	// Version is the semantic version of the bundle, e.g., 1.2.0
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Dependencies are the bundles which must be installed before the bundle
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+	// Packages are the CUE packages of the CRDs imported by the templates, e.g., kube/apps/v1, which must exist
+	// in the cluster the bundle is installed into. They're discovered when the bundle is loaded.
+	Packages []string `json:"packages,omitempty"`
+}
+
+// Dependency is a bundle the other bundle depends on
+type Dependency struct {
+	Name string `json:"name"`
+	// Version is the semantic version constraint of the bundle, e.g., ^1.2, any version satisfies it if it's empty
+	Version string `json:"version,omitempty"`
+}
+
+// Bundle is a set of definitions packaged together
+type Bundle struct {
+	Metadata    Metadata
+	Definitions []*unstructured.Unstructured
+	// Schemas are the OpenAPI schemas of the parameters of the CUE definitions keyed by the file names,
+	// i.e., <kind>-<name>.json in lower case
+	Schemas map[string][]byte
+}
+
+// Load loads the bundle in the directory, discovers the CUE packages imported by the templates and generates the
+// schemas of the parameters. The CUE packages of the CRDs in the cluster are used to generate the schemas if pd is
+// not nil, otherwise the templates importing them are not supported.
+func Load(dir string, pd *definition.PackageDiscover) (*Bundle, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filepath.Join(dir, MetadataFile)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s of bundle", MetadataFile)
+	}
+	b := &Bundle{}
+	if err := yaml.Unmarshal(data, &b.Metadata); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode %s of bundle", MetadataFile)
+	}
+	defDir := filepath.Join(dir, DefinitionsDir)
+	err = filepath.Walk(defDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(p) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := ioutil.ReadFile(filepath.Clean(p))
+		if err != nil {
+			return err
+		}
+		objs, err := kube.DecodeManifest(data)
+		if err != nil {
+			return errors.WithMessagef(err, "definitions file %s", p)
+		}
+		b.Definitions = append(b.Definitions, objs...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read definitions of bundle")
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	if b.Metadata.Packages, err = b.importedPackages(); err != nil {
+		return nil, err
+	}
+	if err := b.generateSchemas(pd); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save writes the bundle into the directory in the layout Load reads, e.g., to install a bundle pulled from a registry
+// in an air-gapped environment
+func Save(b *Bundle, dir string) error {
+	defDir := filepath.Join(dir, DefinitionsDir)
+	if err := os.MkdirAll(defDir, 0750); err != nil {
+		return err
+	}
+	metadata, err := yaml.Marshal(b.Metadata)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, MetadataFile), metadata, 0600); err != nil {
+		return err
+	}
+	for _, def := range b.Definitions {
+		data, err := yaml.Marshal(def.Object)
+		if err != nil {
+			return err
+		}
+		name := strings.ToLower(def.GetKind()) + "-" + def.GetName() + ".yaml"
+		if err := ioutil.WriteFile(filepath.Join(defDir, name), data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the metadata and the definitions of the bundle
+func (b *Bundle) Validate() error {
+	m := b.Metadata
+	if len(m.Name) == 0 {
+		return errors.New("bundle must have a name")
+	}
+	// the name labels the definitions installed
+	if errs := validation.IsValidLabelValue(m.Name); len(errs) != 0 {
+		return errors.Errorf("invalid bundle name %q: %s", m.Name, strings.Join(errs, ", "))
+	}
+	if _, err := semver.StrictNewVersion(m.Version); err != nil {
+		return errors.Wrapf(err, "invalid version %q of bundle %s", m.Version, m.Name)
+	}
+	for _, d := range m.Dependencies {
+		if len(d.Name) == 0 {
+			return errors.Errorf("dependency of bundle %s must have a name", m.Name)
+		}
+		if d.Name == m.Name {
+			return errors.Errorf("bundle %s cannot depend on itself", m.Name)
+		}
+		if len(d.Version) == 0 {
+			continue
+		}
+		if _, err := semver.NewConstraint(d.Version); err != nil {
+			return errors.Wrapf(err, "invalid version %q of dependency %s", d.Version, d.Name)
+		}
+	}
+	if len(b.Definitions) == 0 {
+		return errors.Errorf("bundle %s has no definitions", m.Name)
+	}
+	seen := map[string]bool{}
+	for _, def := range b.Definitions {
+		if !isDefinitionKind(def.GetKind()) || def.GroupVersionKind().Group != v1beta1.Group {
+			return errors.Errorf("%s %s is not a definition, only %s are allowed in bundle", def.GetAPIVersion(), def.GetKind(),
+				strings.Join(definitionKinds, ", "))
+		}
+		if len(def.GetName()) == 0 {
+			return errors.Errorf("%s in bundle %s must have a name", def.GetKind(), m.Name)
+		}
+		key := definitionKey(def)
+		if seen[key] {
+			return errors.Errorf("%s is duplicated in bundle %s", key, m.Name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// importedPackages returns the CUE packages of the CRDs imported by the templates, the standard packages of CUE,
+// e.g., strings, are skipped
+func (b *Bundle) importedPackages() ([]string, error) {
+	packages := map[string]bool{}
+	for _, def := range b.Definitions {
+		template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+		if len(template) == 0 {
+			continue
+		}
+		f, err := parser.ParseFile(definitionKey(def), template, parser.ImportsOnly)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse template of %s", definitionKey(def))
+		}
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid import in template of %s", definitionKey(def))
+			}
+			// the packages of the CRDs are either in the kube domain, e.g., kube/apps/v1, or in the domain of
+			// the group, e.g., example.com/v1
+			first := strings.Split(p, "/")[0]
+			if first == definition.BuiltinPackageDomain || strings.Contains(first, ".") {
+				packages[p] = true
+			}
+		}
+	}
+	var list []string
+	for p := range packages {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+func (b *Bundle) generateSchemas(pd *definition.PackageDiscover) error {
+	b.Schemas = map[string][]byte{}
+	for _, def := range b.Definitions {
+		template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+		if !parameterRegexp.MatchString(template) {
+			continue
+		}
+		schema, err := utils.GenerateParameterSchema(pd, def.GetName(), template)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot generate schema of %s", definitionKey(def))
+		}
+		b.Schemas[schemaFile(def)] = schema
+	}
+	return nil
+}
+
+// Pack packs the bundle into a gzipped tarball
+func Pack(b *Bundle) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	metadata, err := yaml.Marshal(b.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(MetadataFile, metadata); err != nil {
+		return nil, err
+	}
+	for _, def := range b.Definitions {
+		data, err := yaml.Marshal(def.Object)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(def.GetKind()) + "-" + def.GetName() + ".yaml"
+		if err := add(path.Join(DefinitionsDir, name), data); err != nil {
+			return nil, err
+		}
+	}
+	var schemas []string
+	for name := range b.Schemas {
+		schemas = append(schemas, name)
+	}
+	sort.Strings(schemas)
+	for _, name := range schemas {
+		if err := add(path.Join(SchemasDir, name), b.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unpack unpacks the bundle from the gzipped tarball packed by Pack and validates it
+func Unpack(data []byte) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decompress bundle")
+	}
+	tr := tar.NewReader(gz)
+	b := &Bundle{Schemas: map[string][]byte{}}
+	var total int64
+	hasMetadata := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read bundle")
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		total += hdr.Size
+		if total > MaxSize {
+			return nil, fmt.Errorf("bundle exceeds the size limit of %d bytes", MaxSize)
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s of bundle", hdr.Name)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		switch {
+		case name == MetadataFile:
+			if err := yaml.Unmarshal(content, &b.Metadata); err != nil {
+				return nil, errors.Wrapf(err, "cannot decode %s of bundle", MetadataFile)
+			}
+			hasMetadata = true
+		case path.Dir(name) == DefinitionsDir:
+			objs, err := kube.DecodeManifest(content)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "definitions file %s", name)
+			}
+			b.Definitions = append(b.Definitions, objs...)
+		case path.Dir(name) == SchemasDir:
+			b.Schemas[path.Base(name)] = content
+		}
+	}
+	if !hasMetadata {
+		return nil, errors.Errorf("bundle has no %s", MetadataFile)
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func isDefinitionKind(kind string) bool {
+	for _, k := range definitionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func definitionKey(def *unstructured.Unstructured) string {
+	return def.GetKind() + "/" + def.GetName()
+}
+
+func schemaFile(def *unstructured.Unstructured) string {
+	return strings.ToLower(def.GetKind()) + "-" + def.GetName() + ".json"
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDefinition(kind, name, template string) *unstructured.Unstructured {
+	def := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "core.oam.dev/v1beta1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{},
+	}}
+	if len(template) != 0 {
+		_ = unstructured.SetNestedField(def.Object, template, "spec", "schematic", "cue", "template")
+	}
+	return def
+}
+
+func TestLoadAndPack(t *testing.T) {
+	b, err := Load("./testdata/bundle", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{
+		Name:         "web",
+		Version:      "1.2.0",
+		Description:  "Components and traits of web services",
+		Dependencies: []Dependency{{Name: "base", Version: "^1.0"}},
+	}, b.Metadata)
+	require.Len(t, b.Definitions, 3)
+	// the policy has no parameter
+	assert.Len(t, b.Schemas, 2)
+	assert.Contains(t, string(b.Schemas["componentdefinition-web-worker.json"]), `"image"`)
+	assert.Contains(t, string(b.Schemas["traitdefinition-web-labels.json"]), `"labels"`)
+
+	data, err := Pack(b)
+	require.NoError(t, err)
+	unpacked, err := Unpack(data)
+	require.NoError(t, err)
+	assert.Equal(t, b.Metadata, unpacked.Metadata)
+	assert.Equal(t, b.Schemas, unpacked.Schemas)
+	var names []string
+	for _, def := range unpacked.Definitions {
+		names = append(names, definitionKey(def))
+	}
+	assert.ElementsMatch(t, []string{"ComponentDefinition/web-worker", "TraitDefinition/web-labels", "PolicyDefinition/web-placement"}, names)
+
+	_, err = Unpack([]byte("not a bundle"))
+	assert.Error(t, err)
+}
+
+func TestImportedPackages(t *testing.T) {
+	b := &Bundle{Definitions: []*unstructured.Unstructured{
+		newDefinition("ComponentDefinition", "worker", `import (
+	"strings"
+	apps "kube/apps/v1"
+)
+output: apps.#Deployment`),
+		newDefinition("TraitDefinition", "cert", `import "example.com/v1"
+outputs: cert: v1.#Certificate`),
+		newDefinition("TraitDefinition", "plain", ""),
+	}}
+	packages, err := b.importedPackages()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/v1", "kube/apps/v1"}, packages)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Bundle {
+		return &Bundle{
+			Metadata:    Metadata{Name: "web", Version: "1.0.0"},
+			Definitions: []*unstructured.Unstructured{newDefinition("ComponentDefinition", "worker", "")},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	cases := map[string]func(b *Bundle){
+		"no name":            func(b *Bundle) { b.Metadata.Name = "" },
+		"invalid name":       func(b *Bundle) { b.Metadata.Name = "web/bundle" },
+		"invalid version":    func(b *Bundle) { b.Metadata.Version = "v1" },
+		"self dependency":    func(b *Bundle) { b.Metadata.Dependencies = []Dependency{{Name: "web"}} },
+		"invalid constraint": func(b *Bundle) { b.Metadata.Dependencies = []Dependency{{Name: "base", Version: "one"}} },
+		"no definitions":     func(b *Bundle) { b.Definitions = nil },
+		"not definition": func(b *Bundle) {
+			b.Definitions = append(b.Definitions, &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "cm"}}})
+		},
+		"duplicated": func(b *Bundle) {
+			b.Definitions = append(b.Definitions, newDefinition("ComponentDefinition", "worker", ""))
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := valid()
+			mutate(b)
+			assert.Error(t, b.Validate())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// InstalledBundle is a bundle installed in a namespace, it's recorded by the labels and annotations of the
+// definitions installed from it
+type InstalledBundle struct {
+	Name      string
+	Namespace string
+	Version   string
+	// Ref is the reference pinned by digest the bundle is pulled from, it's empty if the bundle is installed from
+	// a local directory
+	Ref string
+	// Definitions are the definitions installed from the bundle in <kind>/<name> format
+	Definitions []string
+}
+
+// ListInstalled lists the bundles installed in the namespace
+func ListInstalled(ctx context.Context, c client.Reader, namespace string) (map[string]*InstalledBundle, error) {
+	installed := map[string]*InstalledBundle{}
+	for _, kind := range definitionKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind(kind + "List"))
+		if err := c.List(ctx, list, client.InNamespace(namespace), client.HasLabels{oam.LabelDefinitionBundle}); err != nil {
+			return nil, errors.Wrapf(err, "cannot list %s in namespace %s", kind, namespace)
+		}
+		for i := range list.Items {
+			def := &list.Items[i]
+			name := def.GetLabels()[oam.LabelDefinitionBundle]
+			b, ok := installed[name]
+			if !ok {
+				b = &InstalledBundle{Name: name, Namespace: namespace}
+				installed[name] = b
+			}
+			// the definitions of an interrupted installation can have different versions, the highest wins
+			version := def.GetAnnotations()[oam.AnnotationDefinitionBundleVersion]
+			if len(b.Version) == 0 || versionGreater(version, b.Version) {
+				b.Version, b.Ref = version, def.GetAnnotations()[oam.AnnotationDefinitionBundleRef]
+			}
+			b.Definitions = append(b.Definitions, definitionKey(def))
+		}
+	}
+	for _, b := range installed {
+		sort.Strings(b.Definitions)
+	}
+	return installed, nil
+}
+
+// Installer installs bundles into a namespace of the cluster
+type Installer struct {
+	Client client.Client
+	// Packages discovers the CUE packages of the CRDs in the cluster, the packages imported by the bundles are not
+	// checked if it's nil
+	Packages *definition.PackageDiscover
+	// Namespace is the namespace the definitions are installed into, it's oam.SystemDefinitonNamespace if empty
+	Namespace string
+	// Force overwrites the definitions not installed from the bundle, and allows to downgrade the bundle or to
+	// install a different artifact of the version installed
+	Force bool
+}
+
+// Install installs the definitions of the bundle. ref is the reference pinned by digest the bundle is pulled from,
+// it's empty if the bundle is loaded from a local directory. All the checks are done before any definition is
+// applied: the dependencies must be installed with the versions required, the CUE packages imported must exist in
+// the cluster, and the definitions must not be owned by others. The definitions of the version installed before
+// which are no longer in the bundle are detached from it rather than deleted, as applications may still use them.
+func (i *Installer) Install(ctx context.Context, b *Bundle, ref string) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	ns := i.namespace()
+	installed, err := i.listVisible(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkDependencies(b, installed); err != nil {
+		return err
+	}
+	if err := i.checkPackages(b); err != nil {
+		return err
+	}
+	current, err := ListInstalled(ctx, i.Client, ns)
+	if err != nil {
+		return err
+	}
+	if prev, ok := current[b.Metadata.Name]; ok && !i.Force {
+		if err := checkUpgrade(b, prev, ref); err != nil {
+			return err
+		}
+	}
+
+	objs := make([]*unstructured.Unstructured, len(b.Definitions))
+	existing := make([]*unstructured.Unstructured, len(b.Definitions))
+	for idx, def := range b.Definitions {
+		obj := i.render(b, def, ref)
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err := i.Client.Get(ctx, client.ObjectKey{Namespace: ns, Name: obj.GetName()}, live)
+		switch {
+		case apierrors.IsNotFound(err):
+			live = nil
+		case err != nil:
+			return errors.Wrapf(err, "cannot get %s", definitionKey(obj))
+		default:
+			if owner := live.GetLabels()[oam.LabelDefinitionBundle]; owner != b.Metadata.Name && !i.Force {
+				if len(owner) == 0 {
+					return errors.Errorf("%s already exists in namespace %s and is not installed from bundle %s", definitionKey(obj), ns, b.Metadata.Name)
+				}
+				return errors.Errorf("%s in namespace %s is installed from bundle %s", definitionKey(obj), ns, owner)
+			}
+		}
+		objs[idx], existing[idx] = obj, live
+	}
+
+	for idx, obj := range objs {
+		if existing[idx] == nil {
+			if err := i.Client.Create(ctx, obj); err != nil {
+				return errors.Wrapf(err, "cannot create %s", definitionKey(obj))
+			}
+			continue
+		}
+		obj.SetResourceVersion(existing[idx].GetResourceVersion())
+		if err := i.Client.Update(ctx, obj); err != nil {
+			return errors.Wrapf(err, "cannot update %s", definitionKey(obj))
+		}
+	}
+	if prev, ok := current[b.Metadata.Name]; ok {
+		return i.detachStale(ctx, b, prev)
+	}
+	return nil
+}
+
+func (i *Installer) namespace() string {
+	if len(i.Namespace) != 0 {
+		return i.Namespace
+	}
+	return oam.SystemDefinitonNamespace
+}
+
+// listVisible lists the bundles whose definitions are visible in the namespace, i.e., the bundles installed in the
+// namespace and in the system namespace
+func (i *Installer) listVisible(ctx context.Context) (map[string]*InstalledBundle, error) {
+	installed, err := ListInstalled(ctx, i.Client, oam.SystemDefinitonNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if i.namespace() == oam.SystemDefinitonNamespace {
+		return installed, nil
+	}
+	local, err := ListInstalled(ctx, i.Client, i.namespace())
+	if err != nil {
+		return nil, err
+	}
+	for name, b := range local {
+		installed[name] = b
+	}
+	return installed, nil
+}
+
+func (i *Installer) checkPackages(b *Bundle) error {
+	if i.Packages == nil {
+		return nil
+	}
+	kinds := i.Packages.ListPackageKinds()
+	var missing []string
+	for _, p := range b.Metadata.Packages {
+		if _, ok := kinds[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) != 0 {
+		return errors.Errorf("bundle %s imports CUE packages %s which don't exist in the cluster, the CRDs of them must be installed first",
+			b.Metadata.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// render sets the namespace of the definition and records the bundle in it, the version of the definition defaults
+// to the version of the bundle so applications can refer to it by a version constraint
+func (i *Installer) render(b *Bundle, def *unstructured.Unstructured, ref string) *unstructured.Unstructured {
+	obj := def.DeepCopy()
+	obj.SetNamespace(i.namespace())
+	obj.SetResourceVersion("")
+	labels := map[string]string{oam.LabelDefinitionBundle: b.Metadata.Name}
+	if _, ok := obj.GetLabels()[oam.LabelDefinitionVersion]; !ok && len(validation.IsValidLabelValue(b.Metadata.Version)) == 0 {
+		labels[oam.LabelDefinitionVersion] = b.Metadata.Version
+	}
+	util.AddLabels(obj, labels)
+	annotations := map[string]string{oam.AnnotationDefinitionBundleVersion: b.Metadata.Version}
+	if len(ref) != 0 {
+		annotations[oam.AnnotationDefinitionBundleRef] = ref
+	}
+	util.AddAnnotations(obj, annotations)
+	return obj
+}
+
+// detachStale removes the labels and annotations of the bundle from the definitions installed from the version
+// before but no longer in the bundle
+func (i *Installer) detachStale(ctx context.Context, b *Bundle, prev *InstalledBundle) error {
+	keep := map[string]bool{}
+	for _, def := range b.Definitions {
+		keep[definitionKey(def)] = true
+	}
+	for _, key := range prev.Definitions {
+		if keep[key] {
+			continue
+		}
+		parts := strings.SplitN(key, "/", 2)
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind(parts[0]))
+		if err := i.Client.Get(ctx, client.ObjectKey{Namespace: prev.Namespace, Name: parts[1]}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "cannot get %s", key)
+		}
+		util.RemoveLabels(obj, []string{oam.LabelDefinitionBundle})
+		util.RemoveAnnotations(obj, []string{oam.AnnotationDefinitionBundleVersion, oam.AnnotationDefinitionBundleRef})
+		if err := i.Client.Update(ctx, obj); err != nil {
+			return errors.Wrapf(err, "cannot detach %s from bundle %s", key, b.Metadata.Name)
+		}
+	}
+	return nil
+}
+
+func checkDependencies(b *Bundle, installed map[string]*InstalledBundle) error {
+	for _, d := range b.Metadata.Dependencies {
+		dep, ok := installed[d.Name]
+		if !ok {
+			return errors.Errorf("bundle %s depends on bundle %s which is not installed", b.Metadata.Name, d.Name)
+		}
+		if len(d.Version) == 0 {
+			continue
+		}
+		constraint, err := semver.NewConstraint(d.Version)
+		if err != nil {
+			return errors.Wrapf(err, "invalid version %q of dependency %s", d.Version, d.Name)
+		}
+		v, err := semver.NewVersion(dep.Version)
+		if err != nil || !constraint.Check(v) {
+			return errors.Errorf("bundle %s depends on bundle %s %s, but version %q is installed", b.Metadata.Name, d.Name, d.Version, dep.Version)
+		}
+	}
+	return nil
+}
+
+// checkUpgrade prevents the bundle installed from being downgraded, and the artifact of the version installed from
+// being replaced by a different one, as a version of a bundle is immutable once it's installed
+func checkUpgrade(b *Bundle, prev *InstalledBundle, ref string) error {
+	if versionGreater(prev.Version, b.Metadata.Version) {
+		return errors.Errorf("bundle %s %s is installed, it cannot be downgraded to %s", b.Metadata.Name, prev.Version, b.Metadata.Version)
+	}
+	if prev.Version == b.Metadata.Version && len(prev.Ref) != 0 && len(ref) != 0 && digestOf(prev.Ref) != digestOf(ref) {
+		return errors.Errorf("bundle %s %s is installed from %s, it's a different artifact from %s", b.Metadata.Name, prev.Version, prev.Ref, ref)
+	}
+	return nil
+}
+
+func versionGreater(a, b string) bool {
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return false
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return true
+	}
+	return va.GreaterThan(vb)
+}
+
+func digestOf(ref string) string {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ref
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// typedClient stores the unstructured objects created or updated as the typed ones, the fake client keeps them as
+// they are and cannot list them into the typed lists then
+type typedClient struct {
+	client.Client
+}
+
+func (c typedClient) typed(obj runtime.Object) (runtime.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	typed, err := common.Scheme.New(u.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+func (c typedClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	typed, err := c.typed(obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, typed, opts...)
+}
+
+func (c typedClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	typed, err := c.typed(obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, typed, opts...)
+}
+
+func TestInstall(t *testing.T) {
+	ctx := context.Background()
+	const ref = "ghcr.io/org/web@sha256:aaaa"
+	newBundle := func(name, version string, defs ...*unstructured.Unstructured) *Bundle {
+		return &Bundle{Metadata: Metadata{Name: name, Version: version}, Definitions: defs}
+	}
+	getTrait := func(c client.Client, name string) *v1beta1.TraitDefinition {
+		td := &v1beta1.TraitDefinition{}
+		require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: oam.SystemDefinitonNamespace, Name: name}, td))
+		return td
+	}
+
+	manual := &v1beta1.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: oam.SystemDefinitonNamespace}}
+	c := typedClient{fake.NewFakeClientWithScheme(common.Scheme, manual)}
+	i := &Installer{Client: c}
+
+	web := newBundle("web", "1.2.0", newDefinition("TraitDefinition", "ingress", ""), newDefinition("TraitDefinition", "labels", ""))
+	web.Metadata.Dependencies = []Dependency{{Name: "base", Version: "^1.0"}}
+	assert.EqualError(t, i.Install(ctx, web, ref), "bundle web depends on bundle base which is not installed")
+
+	require.NoError(t, i.Install(ctx, newBundle("base", "0.9.0", newDefinition("ComponentDefinition", "worker", "")), ""))
+	assert.EqualError(t, i.Install(ctx, web, ref), `bundle web depends on bundle base ^1.0, but version "0.9.0" is installed`)
+	require.NoError(t, i.Install(ctx, newBundle("base", "1.0.0", newDefinition("ComponentDefinition", "worker", "")), ""))
+	require.NoError(t, i.Install(ctx, web, ref))
+
+	ingress := getTrait(c, "ingress")
+	assert.Equal(t, "web", ingress.Labels[oam.LabelDefinitionBundle])
+	assert.Equal(t, "1.2.0", ingress.Labels[oam.LabelDefinitionVersion])
+	assert.Equal(t, "1.2.0", ingress.Annotations[oam.AnnotationDefinitionBundleVersion])
+	assert.Equal(t, ref, ingress.Annotations[oam.AnnotationDefinitionBundleRef])
+
+	installed, err := ListInstalled(ctx, c, oam.SystemDefinitonNamespace)
+	require.NoError(t, err)
+	require.Contains(t, installed, "web")
+	assert.Equal(t, &InstalledBundle{Name: "web", Namespace: oam.SystemDefinitonNamespace, Version: "1.2.0", Ref: ref,
+		Definitions: []string{"TraitDefinition/ingress", "TraitDefinition/labels"}}, installed["web"])
+	assert.Equal(t, "1.0.0", installed["base"].Version)
+
+	msg := "the version installed is immutable and cannot be downgraded"
+	assert.Error(t, i.Install(ctx, web, "ghcr.io/org/web@sha256:bbbb"), msg)
+	assert.Error(t, i.Install(ctx, newBundle("web", "1.1.0", newDefinition("TraitDefinition", "ingress", "")), ""), msg)
+
+	msg = "the definitions not installed from the bundle are not overwritten"
+	assert.Error(t, i.Install(ctx, newBundle("other", "1.0.0", newDefinition("TraitDefinition", "ingress", "")), ""), msg)
+	assert.Error(t, i.Install(ctx, newBundle("other", "1.0.0", newDefinition("TraitDefinition", "manual", "")), ""), msg)
+	assert.Empty(t, getTrait(c, "manual").Labels[oam.LabelDefinitionBundle])
+
+	msg = "the definitions no longer in the bundle are detached on upgrade"
+	require.NoError(t, i.Install(ctx, newBundle("web", "1.3.0", newDefinition("TraitDefinition", "ingress", "")), ""))
+	assert.Equal(t, "1.3.0", getTrait(c, "ingress").Annotations[oam.AnnotationDefinitionBundleVersion])
+	assert.Empty(t, getTrait(c, "ingress").Annotations[oam.AnnotationDefinitionBundleRef])
+	labels := getTrait(c, "labels")
+	assert.Empty(t, labels.Labels[oam.LabelDefinitionBundle])
+	assert.Empty(t, labels.Annotations[oam.AnnotationDefinitionBundleVersion])
+
+	msg = "force overwrites the definitions of others"
+	i.Force = true
+	require.NoError(t, i.Install(ctx, newBundle("other", "1.0.0", newDefinition("TraitDefinition", "manual", "")), ""))
+	assert.Equal(t, "other", getTrait(c, "manual").Labels[oam.LabelDefinitionBundle])
+}
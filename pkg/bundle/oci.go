@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+// Push packs the bundle and pushes it to the OCI registry with the tag of the reference, the metadata is pushed as
+// the config of the artifact. It returns the digest of the artifact to pin the bundle.
+func Push(ctx context.Context, c *oci.Client, ref oci.Reference, b *Bundle) (string, error) {
+	content, err := Pack(b)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot pack bundle %s", b.Metadata.Name)
+	}
+	config, err := json.Marshal(b.Metadata)
+	if err != nil {
+		return "", err
+	}
+	return c.PushArtifact(ctx, ref,
+		oci.Blob{MediaType: ConfigMediaType, Data: config},
+		oci.Blob{MediaType: ContentMediaType, Data: content})
+}
+
+// Pull pulls the bundle from the OCI registry. It returns the reference pinned by the digest of the artifact
+// pulled as well, so the same content is pulled again with it even if the tag is moved.
+func Pull(ctx context.Context, c *oci.Client, ref oci.Reference) (*Bundle, oci.Reference, error) {
+	pinned, err := c.Resolve(ctx, ref)
+	if err != nil {
+		return nil, ref, err
+	}
+	content, err := c.PullLayer(ctx, pinned, ContentMediaType)
+	if err != nil {
+		return nil, ref, err
+	}
+	b, err := Unpack(content)
+	if err != nil {
+		return nil, ref, errors.WithMessagef(err, "bundle %s", ref)
+	}
+	return b, pinned, nil
+}
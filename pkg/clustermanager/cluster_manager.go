@@ -17,21 +17,94 @@ limitations under the License.
 package clustermanager
 
 import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 )
 
+// KubeConfigKey is the key of the kubeconfig in the secret referred by a cluster
+const KubeConfigKey = "config"
+
 // GetClient returns a kube client for given kubeConfigData
 func GetClient(kubeConfigData []byte) (client.Client, error) {
-	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeConfigData)
+	restConfig, err := GetRestConfig(kubeConfigData)
 	if err != nil {
 		return nil, err
 	}
-	restConfig, err := clientConfig.ClientConfig()
+	return client.New(restConfig, client.Options{Scheme: common.Scheme})
+}
+
+// GetRestConfig returns the rest config for given kubeConfigData
+func GetRestConfig(kubeConfigData []byte) (*rest.Config, error) {
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeConfigData)
 	if err != nil {
 		return nil, err
 	}
-	return client.New(restConfig, client.Options{Scheme: common.Scheme})
+	return clientConfig.ClientConfig()
+}
+
+// GetKubeConfig returns the kubeconfig in the secret referred by the cluster
+func GetKubeConfig(ctx context.Context, c client.Reader, cluster *v1beta1.Cluster) ([]byte, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: cluster.Namespace, Name: cluster.Spec.KubeconfigSecretRef.Name}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, errors.Wrapf(err, "cannot get kubeconfig secret of cluster %s", cluster.Name)
+	}
+	return secret.Data[KubeConfigKey], nil
+}
+
+// IsCordoned checks whether new placements should skip the cluster, it's cordoned either manually or
+// automatically by the cluster controller after being unhealthy
+func IsCordoned(cluster *v1beta1.Cluster) bool {
+	return cluster.Spec.Cordoned || cluster.Status.Cordoned
+}
+
+// SelectClusters returns the clusters in the namespace matching the selector by name or labels, the cordoned
+// clusters are skipped
+func SelectClusters(ctx context.Context, c client.Reader, ns string, selector *v1beta1.ClusterSelector) ([]v1beta1.Cluster, error) {
+	var candidates []v1beta1.Cluster
+	if len(selector.Name) != 0 {
+		cluster := v1beta1.Cluster{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: selector.Name}, &cluster); err != nil {
+			return nil, errors.Wrapf(err, "cannot get cluster %s", selector.Name)
+		}
+		candidates = append(candidates, cluster)
+	} else {
+		clusters := &v1beta1.ClusterList{}
+		if err := c.List(ctx, clusters, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(selector.Labels)}); err != nil {
+			return nil, errors.Wrap(err, "cannot list clusters")
+		}
+		candidates = clusters.Items
+	}
+	selected := make([]v1beta1.Cluster, 0, len(candidates))
+	for i := range candidates {
+		if IsCordoned(&candidates[i]) || !labels.SelectorFromSet(selector.Labels).Matches(labels.Set(candidates[i].Labels)) {
+			continue
+		}
+		selected = append(selected, candidates[i])
+	}
+	return selected, nil
+}
+
+// CordonedClusters returns the names of the cordoned clusters in the namespace
+func CordonedClusters(ctx context.Context, c client.Reader, ns string) (map[string]bool, error) {
+	clusters := &v1beta1.ClusterList{}
+	if err := c.List(ctx, clusters, client.InNamespace(ns)); err != nil {
+		return nil, errors.Wrap(err, "cannot list clusters")
+	}
+	cordoned := map[string]bool{}
+	for i := range clusters.Items {
+		if IsCordoned(&clusters.Items[i]) {
+			cordoned[clusters.Items[i].Name] = true
+		}
+	}
+	return cordoned, nil
 }
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/traffic"
+)
+
+func (r *Controller) isABTesting() bool {
+	return r.rolloutSpec.RolloutStrategy == v1alpha1.ABTestingRolloutStrategyType
+}
+
+func (r *Controller) abTesting() v1alpha1.ABTestingStrategy {
+	if r.rolloutSpec.ABTesting == nil {
+		return v1alpha1.ABTestingStrategy{}
+	}
+	return *r.rolloutSpec.ABTesting
+}
+
+// applyTrafficRules routes the requests matching the rules of an A/B testing rollout to the target,
+// the rules are removed if there is none. It returns if the rules are applied
+func (r *Controller) applyTrafficRules(ctx context.Context, rules []v1alpha1.TrafficRule) bool {
+	if !r.isABTesting() || r.rolloutSpec.TrafficRouting == nil {
+		return true
+	}
+	router, err := r.getTrafficRouter()
+	if err == nil {
+		err = router.SetRules(ctx, rules)
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to apply the traffic rules", "provider", r.rolloutSpec.TrafficRouting.Provider)
+		r.recorder.Event(r.parentController, event.Warning("Failed to apply the traffic rules", err))
+		r.rolloutStatus.RolloutRetry(err.Error())
+		r.setTrafficRuleStatus(rules, false, err.Error())
+		return false
+	}
+	if len(rules) == 0 {
+		// the rules are removed once the target is promoted or rolled back
+		r.setTrafficRuleStatus(r.abTesting().Rules, false, "the rule is removed")
+		return true
+	}
+	if r.setTrafficRuleStatus(rules, true, "") {
+		klog.InfoS("applied the traffic rules", "rules", len(rules))
+		r.recorder.Event(r.parentController, event.Normal("Traffic Rules Applied",
+			fmt.Sprintf("Routed the requests matching %d rules to the target", len(rules))))
+	}
+	return true
+}
+
+// setTrafficRuleStatus records the status of the rules, it returns if the status is changed
+func (r *Controller) setTrafficRuleStatus(rules []v1alpha1.TrafficRule, applied bool, message string) bool {
+	status := make([]v1alpha1.TrafficRuleStatus, 0, len(rules))
+	for _, rule := range rules {
+		status = append(status, v1alpha1.TrafficRuleStatus{
+			Name:    rule.Name,
+			Weight:  traffic.RuleWeight(rule),
+			Applied: applied,
+			Message: message,
+		})
+	}
+	if len(status) == 0 || reflect.DeepEqual(status, r.rolloutStatus.TrafficRules) {
+		return false
+	}
+	r.rolloutStatus.TrafficRules = status
+	return true
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// DefaultInterval is the window of a canary metric without interval
+const DefaultInterval = "1m"
+
+// the data keys of a ConfigMap metric template
+const (
+	TemplateKeyProvider = "provider"
+	TemplateKeyAddress  = "address"
+	TemplateKeyQuery    = "query"
+)
+
+// Analyzer queries the canary metrics of a rollout and checks them against their expected ranges
+type Analyzer struct {
+	// Client reads the metric templates
+	Client client.Reader
+	// NewProvider creates the metrics provider a metric is queried from
+	NewProvider func(p v1alpha1.MetricProvider) (Provider, error)
+}
+
+// NewAnalyzer creates an analyzer using the registered metrics providers
+func NewAnalyzer(c client.Reader) *Analyzer {
+	return &Analyzer{Client: c, NewProvider: NewProvider}
+}
+
+// QueryContext is the data the metric queries are rendered with
+type QueryContext struct {
+	// Name of the resource being rolled out
+	Name string
+	// Namespace of the resource being rolled out
+	Namespace string
+	// Interval is the window of the metric
+	Interval string
+	// Batch is the batch being analysed
+	Batch int32
+}
+
+// Window returns the longest interval of the metrics, a batch is analysed once it has been available that long
+func Window(metrics []v1alpha1.CanaryMetric) time.Duration {
+	var window time.Duration
+	for _, m := range metrics {
+		interval, err := time.ParseDuration(metricInterval(m))
+		if err == nil && interval > window {
+			window = interval
+		}
+	}
+	return window
+}
+
+// Analyze queries all the metrics and checks if they are in range. It returns an error if any of the metrics can't be
+// queried, the analysis is inconclusive and should be retried in that case
+func (a *Analyzer) Analyze(ctx context.Context, metrics []v1alpha1.CanaryMetric,
+	qctx QueryContext) ([]v1alpha1.CanaryMetricStatus, bool, error) {
+	results := make([]v1alpha1.CanaryMetricStatus, 0, len(metrics))
+	passed := true
+	var queryErr error
+	for _, m := range metrics {
+		result := v1alpha1.CanaryMetricStatus{Name: m.Name}
+		value, err := a.query(ctx, m, qctx)
+		if err != nil {
+			result.Message = err.Error()
+			if queryErr == nil {
+				queryErr = errors.Wrapf(err, "failed to query the canary metric %s", m.Name)
+			}
+			results = append(results, result)
+			continue
+		}
+		result.Value = strconv.FormatFloat(value, 'f', -1, 64)
+		if result.InRange, err = InRange(value, m.MetricsRange); err != nil {
+			result.Message = err.Error()
+		}
+		passed = passed && result.InRange
+		results = append(results, result)
+	}
+	if queryErr != nil {
+		return results, false, queryErr
+	}
+	return results, passed, nil
+}
+
+func (a *Analyzer) query(ctx context.Context, m v1alpha1.CanaryMetric, qctx QueryContext) (float64, error) {
+	provider, query, err := a.resolve(ctx, m, qctx.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	qctx.Interval = metricInterval(m)
+	rendered, err := RenderQuery(query, qctx)
+	if err != nil {
+		return 0, err
+	}
+	p, err := a.NewProvider(provider)
+	if err != nil {
+		return 0, err
+	}
+	return p.Query(ctx, rendered)
+}
+
+// resolve merges the provider and the query of a metric with its template
+func (a *Analyzer) resolve(ctx context.Context, m v1alpha1.CanaryMetric,
+	namespace string) (v1alpha1.MetricProvider, string, error) {
+	var provider v1alpha1.MetricProvider
+	var query string
+	if m.TemplateRef != nil {
+		if m.TemplateRef.Kind != "ConfigMap" {
+			return provider, "", fmt.Errorf("unsupported metric template kind %s", m.TemplateRef.Kind)
+		}
+		cm := &corev1.ConfigMap{}
+		if err := a.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: m.TemplateRef.Name}, cm); err != nil {
+			return provider, "", errors.Wrapf(err, "failed to get the metric template %s", m.TemplateRef.Name)
+		}
+		provider.Type = v1alpha1.MetricProviderType(cm.Data[TemplateKeyProvider])
+		provider.Address = cm.Data[TemplateKeyAddress]
+		query = cm.Data[TemplateKeyQuery]
+	}
+	if m.Provider != nil {
+		provider = *m.Provider
+	}
+	if len(m.Query) != 0 {
+		query = m.Query
+	}
+	if len(strings.TrimSpace(query)) == 0 {
+		return provider, "", errors.New("the metric has no query")
+	}
+	return provider, query, nil
+}
+
+// ValidateMetric checks that the metric can be analysed without looking up its template
+func ValidateMetric(m v1alpha1.CanaryMetric) error {
+	if len(m.Name) == 0 {
+		return errors.New("the metric name is empty")
+	}
+	if _, err := time.ParseDuration(metricInterval(m)); err != nil {
+		return fmt.Errorf("invalid interval %q", m.Interval)
+	}
+	if len(m.Query) == 0 && m.TemplateRef == nil {
+		return errors.New("either the query or the templateRef of the metric has to be set")
+	}
+	if m.TemplateRef != nil && m.TemplateRef.Kind != "ConfigMap" {
+		return fmt.Errorf("unsupported metric template kind %s", m.TemplateRef.Kind)
+	}
+	if len(m.Query) != 0 {
+		if _, err := template.New("query").Parse(m.Query); err != nil {
+			return errors.Wrap(err, "invalid metric query template")
+		}
+	}
+	if m.Provider != nil && len(m.Provider.Type) != 0 {
+		providersMu.RLock()
+		_, ok := providers[m.Provider.Type]
+		providersMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unsupported metrics provider type %q", m.Provider.Type)
+		}
+	}
+	if r := m.MetricsRange; r != nil {
+		var bounds []float64
+		for _, b := range []*intstr.IntOrString{r.Min, r.Max} {
+			if b == nil {
+				continue
+			}
+			v, err := ParseBound(b)
+			if err != nil {
+				return err
+			}
+			bounds = append(bounds, v)
+		}
+		if len(bounds) == 2 && bounds[0] > bounds[1] {
+			return errors.New("the min of the metric range is greater than the max")
+		}
+	}
+	return nil
+}
+
+// RenderQuery renders the Go template of a metric query
+func RenderQuery(query string, qctx QueryContext) (string, error) {
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(query)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid metric query template")
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, qctx); err != nil {
+		return "", errors.Wrap(err, "failed to render the metric query")
+	}
+	return buf.String(), nil
+}
+
+// InRange checks the value against the expected range, a metric without range is always in range
+func InRange(value float64, r *v1alpha1.MetricsExpectedRange) (bool, error) {
+	if r == nil {
+		return true, nil
+	}
+	if r.Min != nil {
+		lower, err := ParseBound(r.Min)
+		if err != nil {
+			return false, err
+		}
+		if value < lower {
+			return false, nil
+		}
+	}
+	if r.Max != nil {
+		upper, err := ParseBound(r.Max)
+		if err != nil {
+			return false, err
+		}
+		if value > upper {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ParseBound parses the bound of a metric range, string bounds are decimals such as "0.99"
+func ParseBound(bound *intstr.IntOrString) (float64, error) {
+	if bound.Type == intstr.Int {
+		return float64(bound.IntVal), nil
+	}
+	v, err := strconv.ParseFloat(bound.StrVal, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid metric range bound %q, it has to be a number", bound.StrVal)
+	}
+	return v, nil
+}
+
+// Describe explains which metrics failed the analysis
+func Describe(results []v1alpha1.CanaryMetricStatus) string {
+	var failed []string
+	for _, r := range results {
+		switch {
+		case len(r.Message) != 0:
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Name, r.Message))
+		case !r.InRange:
+			failed = append(failed, fmt.Sprintf("%s = %s is out of range", r.Name, r.Value))
+		}
+	}
+	return strings.Join(failed, ", ")
+}
+
+func metricInterval(m v1alpha1.CanaryMetric) string {
+	if len(m.Interval) == 0 {
+		return DefaultInterval
+	}
+	return m.Interval
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestPrometheusQuery(t *testing.T) {
+	testCases := map[string]struct {
+		response string
+		value    float64
+		err      string
+	}{
+		"vector": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1625000000,"0.995"]}]}}`,
+			value:    0.995,
+		},
+		"scalar": {
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1625000000,"42"]}}`,
+			value:    42,
+		},
+		"no data": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			err:      "returned no data",
+		},
+		"many series": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"value":[1,"1"]},{"value":[1,"2"]}]}}`,
+			err:      "returned 2 series",
+		},
+		"NaN": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"value":[1,"NaN"]}]}}`,
+			err:      "NaN",
+		},
+		"bad query": {
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			err:      "bad_data: parse error",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/query", r.URL.Path)
+				assert.Equal(t, `sum(up{app="test"})`, r.URL.Query().Get("query"))
+				_, _ = fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+			p, err := NewPrometheusProvider(server.URL)
+			assert.NoError(t, err)
+			value, err := p.Query(context.Background(), `sum(up{app="test"})`)
+			if len(tc.err) != 0 {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+
+	_, err := NewPrometheusProvider("prometheus:9090")
+	assert.Error(t, err)
+}
+
+type fakeProvider map[string]float64
+
+func (f fakeProvider) Query(_ context.Context, query string) (float64, error) {
+	v, ok := f[query]
+	if !ok {
+		return 0, fmt.Errorf("unexpected query %s", query)
+	}
+	return v, nil
+}
+
+func TestAnalyze(t *testing.T) {
+	tmpl := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "success-rate", Namespace: "default"},
+		Data: map[string]string{
+			TemplateKeyAddress: "http://prometheus:9090",
+			TemplateKeyQuery:   `success_rate{app="{{ .Name }}",batch="{{ .Batch }}"}[{{ .Interval }}]`,
+		},
+	}
+	var addresses []string
+	a := &Analyzer{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, tmpl),
+		NewProvider: func(p v1alpha1.MetricProvider) (Provider, error) {
+			addresses = append(addresses, p.Address)
+			return fakeProvider{
+				`success_rate{app="test",batch="1"}[5m]`:  0.98,
+				`latency{app="test",namespace="default"}`: 0.2,
+			}, nil
+		},
+	}
+	minRate, maxLatency := intstr.FromString("0.99"), intstr.FromString("0.5")
+	metrics := []v1alpha1.CanaryMetric{
+		{
+			Name:         "success-rate",
+			Interval:     "5m",
+			TemplateRef:  &runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Name: "success-rate"},
+			MetricsRange: &v1alpha1.MetricsExpectedRange{Min: &minRate},
+		},
+		{
+			Name:         "latency",
+			Query:        `latency{app="{{ .Name }}",namespace="{{ .Namespace }}"}`,
+			Provider:     &v1alpha1.MetricProvider{Address: "http://other:9090"},
+			MetricsRange: &v1alpha1.MetricsExpectedRange{Max: &maxLatency},
+		},
+	}
+	qctx := QueryContext{Name: "test", Namespace: "default", Batch: 1}
+	results, passed, err := a.Analyze(context.Background(), metrics, qctx)
+	assert.NoError(t, err)
+	assert.False(t, passed)
+	assert.Equal(t, []v1alpha1.CanaryMetricStatus{
+		{Name: "success-rate", Value: "0.98", InRange: false},
+		{Name: "latency", Value: "0.2", InRange: true},
+	}, results)
+	assert.Equal(t, []string{"http://prometheus:9090", "http://other:9090"}, addresses)
+	assert.Equal(t, "success-rate = 0.98 is out of range", Describe(results))
+	assert.Equal(t, 5*time.Minute, Window(metrics))
+
+	// a metric that can't be queried makes the analysis inconclusive
+	metrics[0].TemplateRef.Name = "missing"
+	results, passed, err = a.Analyze(context.Background(), metrics, qctx)
+	assert.Error(t, err)
+	assert.False(t, passed)
+	assert.Contains(t, results[0].Message, "failed to get the metric template missing")
+}
+
+func TestInRange(t *testing.T) {
+	lower, upper := intstr.FromInt(10), intstr.FromString("20.5")
+	r := &v1alpha1.MetricsExpectedRange{Min: &lower, Max: &upper}
+	for value, want := range map[float64]bool{9.9: false, 10: true, 20.5: true, 20.6: false} {
+		got, err := InRange(value, r)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "value %v", value)
+	}
+	got, err := InRange(100, nil)
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	invalid := intstr.FromString("99%")
+	_, err = InRange(1, &v1alpha1.MetricsExpectedRange{Max: &invalid})
+	assert.Error(t, err)
+}
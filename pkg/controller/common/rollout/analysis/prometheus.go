@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// prometheusQueryTimeout bounds a single instant query to the Prometheus server
+const prometheusQueryTimeout = 30 * time.Second
+
+// PrometheusProvider queries the canary metrics with PromQL from the HTTP API of a Prometheus server
+type PrometheusProvider struct {
+	address *url.URL
+	client  *http.Client
+}
+
+// NewPrometheusProvider creates a metrics provider querying the Prometheus server at the address
+func NewPrometheusProvider(address string) (Provider, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid prometheus address %s", address)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid prometheus address %s, the scheme has to be http or https", address)
+	}
+	return &PrometheusProvider{address: u, client: &http.Client{Timeout: prometheusQueryTimeout}}, nil
+}
+
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// Query runs an instant query, the query has to evaluate to a scalar or a vector with a single sample
+func (p *PrometheusProvider) Query(ctx context.Context, query string) (float64, error) {
+	u := *p.address
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/query"
+	u.RawQuery = url.Values{"query": []string{query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to query prometheus")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read the prometheus response")
+	}
+	var result prometheusResponse
+	if err = json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("invalid prometheus response with http status %d", resp.StatusCode)
+	}
+	if result.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed with %s: %s", result.ErrorType, result.Error)
+	}
+	var value []interface{}
+	switch result.Data.ResultType {
+	case "scalar":
+		if err = json.Unmarshal(result.Data.Result, &value); err != nil {
+			return 0, errors.Wrap(err, "invalid prometheus scalar")
+		}
+	case "vector":
+		var samples []prometheusSample
+		if err = json.Unmarshal(result.Data.Result, &samples); err != nil {
+			return 0, errors.Wrap(err, "invalid prometheus vector")
+		}
+		if len(samples) == 0 {
+			return 0, errors.New("the prometheus query returned no data")
+		}
+		if len(samples) > 1 {
+			return 0, fmt.Errorf("the prometheus query returned %d series, aggregate them into one", len(samples))
+		}
+		value = samples[0].Value
+	default:
+		return 0, fmt.Errorf("unsupported prometheus result type %q", result.Data.ResultType)
+	}
+	return parsePrometheusValue(value)
+}
+
+// the value of a sample is a pair of the timestamp and the value as a string
+func parsePrometheusValue(value []interface{}) (float64, error) {
+	if len(value) != 2 {
+		return 0, errors.New("invalid prometheus sample")
+	}
+	raw, ok := value[1].(string)
+	if !ok {
+		return 0, errors.New("invalid prometheus sample")
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid prometheus sample value %s", raw)
+	}
+	if math.IsNaN(v) {
+		// e.g. a success rate divided by zero requests
+		return 0, errors.New("the prometheus query returned NaN")
+	}
+	return v, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// Provider queries the value of a canary metric from a metrics backend
+type Provider interface {
+	// Query returns the single value the query evaluates to
+	Query(ctx context.Context, query string) (float64, error)
+}
+
+// ProviderFactory creates a metrics provider talking to the given address
+type ProviderFactory func(address string) (Provider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[v1alpha1.MetricProviderType]ProviderFactory{
+		v1alpha1.PrometheusMetricProvider: NewPrometheusProvider,
+	}
+)
+
+// RegisterProvider makes a metrics provider type available to the canary metrics,
+// it replaces the factory already registered for the type
+func RegisterProvider(providerType v1alpha1.MetricProviderType, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[providerType] = factory
+}
+
+// NewProvider creates the metrics provider of the given type, the type defaults to prometheus
+func NewProvider(p v1alpha1.MetricProvider) (Provider, error) {
+	providerType := p.Type
+	if len(providerType) == 0 {
+		providerType = v1alpha1.PrometheusMetricProvider
+	}
+	providersMu.RLock()
+	factory, ok := providers[providerType]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported metrics provider type %q", providerType)
+	}
+	if len(p.Address) == 0 {
+		return nil, fmt.Errorf("the address of the %s metrics provider is empty", providerType)
+	}
+	return factory(p.Address)
+}
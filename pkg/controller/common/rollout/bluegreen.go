@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/workloads"
+)
+
+// the default time the source keeps running after the target of a blue-green or an A/B testing rollout is promoted
+const defaultScaleDownDelay = 30 * time.Second
+
+func (r *Controller) isBlueGreen() bool {
+	return r.rolloutSpec.RolloutStrategy == v1alpha1.BlueGreenRolloutStrategyType
+}
+
+// promotesTarget returns if the rollout provisions the whole target next to the source and switches
+// all the traffic to it at once when it's promoted, i.e. the blue-green and the A/B testing rollouts
+func (r *Controller) promotesTarget() bool {
+	return r.isBlueGreen() || r.isABTesting()
+}
+
+// verifyPromotion makes sure that the workload can keep the source next to the whole target
+// and the traffic can be switched
+func (r *Controller) verifyPromotion(workloadController workloads.WorkloadController) error {
+	if !r.promotesTarget() {
+		return nil
+	}
+	if _, ok := workloadController.(workloads.BlueGreenController); !ok {
+		return fmt.Errorf("the workload kind `%s` doesn't support the %s rollout", r.targetWorkload.GetKind(),
+			r.rolloutSpec.RolloutStrategy)
+	}
+	if r.isBlueGreen() && r.blueGreen().ActiveService == "" && r.rolloutSpec.TrafficRouting == nil {
+		return fmt.Errorf("the blue-green rollout needs an active service or a traffic routing to switch the traffic")
+	}
+	if r.isABTesting() && r.rolloutSpec.TrafficRouting == nil {
+		return fmt.Errorf("the A/B testing rollout needs a traffic routing to route the requests")
+	}
+	return nil
+}
+
+func (r *Controller) blueGreen() v1alpha1.BlueGreenStrategy {
+	if r.rolloutSpec.BlueGreen == nil {
+		return v1alpha1.BlueGreenStrategy{}
+	}
+	return *r.rolloutSpec.BlueGreen
+}
+
+func (r *Controller) promotionPolicy() v1alpha1.PromotionPolicy {
+	if r.isABTesting() {
+		return r.abTesting().PromotionPolicy
+	}
+	return r.blueGreen().PromotionPolicy
+}
+
+// previewBlueGreen switches the preview service to the pods of the target once they are available,
+// it returns if the target can be previewed
+func (r *Controller) previewBlueGreen(ctx context.Context) bool {
+	if !r.isBlueGreen() || r.blueGreen().PreviewService == "" {
+		return true
+	}
+	if err := r.switchService(ctx, r.blueGreen().PreviewService); err != nil {
+		klog.ErrorS(err, "failed to switch the preview service", "service", r.blueGreen().PreviewService)
+		r.recorder.Event(r.parentController, event.Warning("Failed to switch the preview service", err))
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	return true
+}
+
+// promotionApproved returns if the target of a blue-green or an A/B testing rollout can be promoted
+func (r *Controller) promotionApproved() bool {
+	if !r.promotesTarget() {
+		return true
+	}
+	policy := r.promotionPolicy()
+	if policy.AutoPromotionEnabled == nil || *policy.AutoPromotionEnabled || policy.Promote {
+		return true
+	}
+	r.rolloutStatus.RolloutRetry("the target is available, waiting for the promotion to be approved")
+	return false
+}
+
+// promoteTarget switches the active service of a blue-green rollout to the pods of the target and scales
+// the source down once the scale down delay passes, it returns if the source is scaled down
+func (r *Controller) promoteTarget(ctx context.Context, workloadController workloads.WorkloadController) bool {
+	if !r.promotesTarget() {
+		return true
+	}
+	if r.rolloutStatus.PromotedTime == nil {
+		if activeService := r.blueGreen().ActiveService; activeService != "" {
+			if err := r.switchService(ctx, activeService); err != nil {
+				klog.ErrorS(err, "failed to switch the active service", "service", activeService)
+				r.recorder.Event(r.parentController, event.Warning("Failed to switch the active service", err))
+				r.rolloutStatus.RolloutRetry(err.Error())
+				return false
+			}
+		}
+		now := metav1.Now()
+		r.rolloutStatus.PromotedTime = &now
+		klog.InfoS("promoted the target", "target workload", klog.KObj(r.targetWorkload))
+		r.recorder.Event(r.parentController, event.Normal("Target Promoted",
+			fmt.Sprintf("All the traffic is switched to the target %s", r.targetWorkload.GetName())))
+	}
+	// keep the source for a while so that the clients with open connections can drain
+	delay := defaultScaleDownDelay
+	if policy := r.promotionPolicy(); policy.ScaleDownDelaySeconds != nil {
+		delay = time.Duration(*policy.ScaleDownDelaySeconds) * time.Second
+	}
+	if wait := time.Until(r.rolloutStatus.PromotedTime.Add(delay)); wait > 0 {
+		r.rolloutStatus.RolloutRetry(fmt.Sprintf("the target is promoted, scaling down the source in %s",
+			wait.Round(time.Second)))
+		return false
+	}
+	scaledDown, err := workloadController.(workloads.BlueGreenController).ScaleDownSource(ctx)
+	if err != nil {
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	return scaledDown
+}
+
+// switchService points the selector of the service to the pods of the target
+func (r *Controller) switchService(ctx context.Context, name string) error {
+	selector, found, err := unstructured.NestedStringMap(r.targetWorkload.Object, "spec", "selector", "matchLabels")
+	if err != nil || !found || len(selector) == 0 {
+		return fmt.Errorf("cannot find the pod selector of the target %s", r.targetWorkload.GetName())
+	}
+	var svc corev1.Service
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.targetWorkload.GetNamespace(), Name: name},
+		&svc); err != nil {
+		return errors.Wrapf(err, "cannot get the service %s", name)
+	}
+	if reflect.DeepEqual(svc.Spec.Selector, selector) {
+		return nil
+	}
+	svc.Spec.Selector = selector
+	if err := r.client.Update(ctx, &svc); err != nil {
+		return errors.Wrapf(err, "cannot switch the service %s", name)
+	}
+	klog.InfoS("switched the service to the target", "service", name, "selector", selector)
+	r.recorder.Event(r.parentController, event.Normal("Service Switched",
+		fmt.Sprintf("The service %s selects the pods of the target %s", name, r.targetWorkload.GetName())))
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// runHooks invokes the webhooks of the hook type in order, each of them is invoked once per reconcile until it
+// succeeds. It returns true once all of them succeeded or their failures are ignored, and an error if one of them
+// still fails after all its retries and fails the rollout
+func (r *Controller) runHooks(ctx context.Context, hookType v1alpha1.HookType, phase string,
+	hooks []v1alpha1.RolloutWebhook) (bool, error) {
+	for _, rw := range hooks {
+		if rw.Type != hookType {
+			continue
+		}
+		status := r.webhookStatus(rw)
+		switch status.Phase {
+		case v1alpha1.RolloutWebhookSucceeded, v1alpha1.RolloutWebhookIgnored:
+			// the webhook is not invoked again in the same phase of the rollout
+			continue
+		case v1alpha1.RolloutWebhookFailed:
+			return false, fmt.Errorf("webhook %s failed after %d attempts, %s", rw.Name, status.Attempts,
+				status.Message)
+		}
+		now := metav1.Now()
+		status.Attempts++
+		status.LastAttemptTime = &now
+		err := callWebhook(ctx, r.parentController, phase, rw)
+		if err == nil {
+			klog.InfoS("successfully invoked a webhook", "webhook name", rw.Name, "webhook type", rw.Type,
+				"webhook end point", rw.URL)
+			status.Phase = v1alpha1.RolloutWebhookSucceeded
+			status.Message = ""
+			continue
+		}
+		klog.ErrorS(err, "failed to invoke a webhook", "webhook name", rw.Name, "webhook type", rw.Type,
+			"webhook end point", rw.URL, "attempts", status.Attempts)
+		status.Message = err.Error()
+		if rw.Retries == nil || status.Attempts <= *rw.Retries {
+			status.Phase = v1alpha1.RolloutWebhookRetrying
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to invoke the webhook %s", rw.Name))
+			return false, nil
+		}
+		err = fmt.Errorf("webhook %s failed after %d attempts, %w", rw.Name, status.Attempts, err)
+		if rw.FailurePolicy == v1alpha1.IgnoreWebhookFailurePolicy {
+			status.Phase = v1alpha1.RolloutWebhookIgnored
+			r.recorder.Event(r.parentController, event.Warning("Webhook Failure Ignored", err))
+			continue
+		}
+		status.Phase = v1alpha1.RolloutWebhookFailed
+		r.recorder.Event(r.parentController, event.Warning("Webhook Failed", err))
+		return false, err
+	}
+	return true, nil
+}
+
+// webhookStatus returns the status of the webhook in the current batch, it's reset once the batch moves on
+func (r *Controller) webhookStatus(rw v1alpha1.RolloutWebhook) *v1alpha1.RolloutWebhookStatus {
+	currentBatch := r.rolloutStatus.CurrentBatch
+	for i := range r.rolloutStatus.Webhooks {
+		status := &r.rolloutStatus.Webhooks[i]
+		if status.Name != rw.Name || status.Type != rw.Type {
+			continue
+		}
+		if status.Batch != currentBatch {
+			*status = v1alpha1.RolloutWebhookStatus{Name: rw.Name, Type: rw.Type, Batch: currentBatch}
+		}
+		return status
+	}
+	r.rolloutStatus.Webhooks = append(r.rolloutStatus.Webhooks, v1alpha1.RolloutWebhookStatus{
+		Name:  rw.Name,
+		Type:  rw.Type,
+		Batch: currentBatch,
+	})
+	return &r.rolloutStatus.Webhooks[len(r.rolloutStatus.Webhooks)-1]
+}
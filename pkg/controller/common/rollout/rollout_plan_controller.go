@@ -19,12 +19,15 @@ package rollout
 import (
 	"context"
 	"fmt"
+	"math"
 	"reflect"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	kruisev1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/pkg/errors"
 	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
@@ -33,8 +36,11 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/analysis"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/traffic"
 	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/workloads"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // the default time to check back if we still have work to do
@@ -51,6 +57,9 @@ type Controller struct {
 
 	targetWorkload *unstructured.Unstructured
 	sourceWorkload *unstructured.Unstructured
+
+	analyzer      *analysis.Analyzer
+	trafficRouter traffic.Router
 }
 
 // NewRolloutPlanController creates a RolloutPlanController
@@ -69,6 +78,7 @@ func NewRolloutPlanController(client client.Client, parentController oam.Object,
 		rolloutStatus:    initializedRolloutStatus,
 		targetWorkload:   targetWorkload,
 		sourceWorkload:   sourceWorkload,
+		analyzer:         analysis.NewAnalyzer(client),
 	}
 }
 
@@ -112,6 +122,10 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 
 	switch r.rolloutStatus.RollingState {
 	case v1alpha1.VerifyingSpecState:
+		if err = r.verifyPromotion(workloadController); err != nil {
+			r.rolloutStatus.RolloutFailed(err.Error())
+			break
+		}
 		verified, err := workloadController.VerifySpec(ctx)
 		if err != nil {
 			// we can fail it right away, everything after initialized need to be finalized
@@ -121,7 +135,8 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		}
 
 	case v1alpha1.InitializingState:
-		if err = r.initializeRollout(ctx); err == nil {
+		// route all the traffic to the source before any pod of the target serves
+		if r.initializeRollout(ctx) && r.routeTraffic(ctx, 0) {
 			initialized, err := workloadController.Initialize(ctx)
 			if err != nil {
 				r.rolloutStatus.RolloutFailing(err.Error())
@@ -139,6 +154,12 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		}
 
 	case v1alpha1.FinalisingState:
+		// switch all the traffic to the target, the blue-green and the A/B testing rollouts scale the source
+		// down afterwards
+		if !r.routeTraffic(ctx, traffic.MaxWeight) || !r.applyTrafficRules(ctx, nil) ||
+			!r.promoteTarget(ctx, workloadController) {
+			break
+		}
 		if succeed := workloadController.Finalize(ctx, true); succeed {
 			r.finalizeRollout(ctx)
 		}
@@ -178,14 +199,20 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 		}
 
 	case v1alpha1.BatchVerifyingState:
+		// the batch failed the canary analysis, keep reverting it until the source takes over again
+		if r.isRollingBack() {
+			r.rollbackOneBatch(ctx, workloadController)
+			return
+		}
 		// verifying if the application is ready to roll
 		// need to check if they meet the availability requirements in the rollout spec.
-		// TODO: evaluate any metrics/analysis
 		// TODO: We may need to go back to rollout again if the size of the resource can change behind our back
 		verified, err := workloadController.CheckOneBatchPods(ctx)
 		if err != nil {
 			r.rolloutStatus.RolloutFailing(err.Error())
-		} else if verified {
+		} else if verified && r.previewBlueGreen(ctx) && r.routeTraffic(ctx, r.batchTrafficWeight()) &&
+			r.applyTrafficRules(ctx, r.abTesting().Rules) && r.analyzeOneBatch(ctx, workloadController) &&
+			r.promotionApproved() {
 			r.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
 		}
 
@@ -208,45 +235,30 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	}
 }
 
-// all the common initialize work before we rollout
-// TODO: fail the rollout if the webhook call is explicitly rejected (through http status code)
-func (r *Controller) initializeRollout(ctx context.Context) error {
+// all the common initialize work before we rollout, it returns true once the initialize hooks succeeded
+func (r *Controller) initializeRollout(ctx context.Context) bool {
 	// call the pre-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.InitializeRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.InitializingState), rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return err
-			}
-			klog.InfoS("successfully invoked a pre rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
-		}
+	done, err := r.runHooks(ctx, v1alpha1.InitializeRolloutHook, string(v1alpha1.InitializingState),
+		r.rolloutSpec.RolloutWebhooks)
+	if err != nil {
+		r.rolloutStatus.RolloutFailing(err.Error())
+		return false
 	}
-
-	return nil
+	return done
 }
 
 // all the common initialize work before we rollout one batch of resources
 func (r *Controller) initializeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the pre-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PreBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.BatchInitializingState), rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a pre batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
-		}
+	done, err := r.runHooks(ctx, v1alpha1.PreBatchRolloutHook, string(v1alpha1.BatchInitializingState),
+		r.gatherAllWebhooks())
+	if err != nil {
+		r.rolloutStatus.RolloutFailing(err.Error())
+		return
+	}
+	if done {
+		r.rolloutStatus.StateTransition(v1alpha1.InitializedOneBatchEvent)
 	}
-	r.rolloutStatus.StateTransition(v1alpha1.InitializedOneBatchEvent)
 }
 
 func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
@@ -259,6 +271,201 @@ func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
 	return rolloutHooks
 }
 
+func (r *Controller) gatherAllCanaryMetrics() []v1alpha1.CanaryMetric {
+	// the rollout level metrics are analysed for every batch along with the batch specific ones
+	metrics := append([]v1alpha1.CanaryMetric{}, r.rolloutSpec.CanaryMetric...)
+	currentBatch := int(r.rolloutStatus.CurrentBatch)
+	return append(metrics, r.rolloutSpec.RolloutBatches[currentBatch].CanaryMetric...)
+}
+
+// analyzeOneBatch runs the canary analysis of the current batch once its pods are available,
+// it returns if the batch passed the analysis and the rollout can move on
+func (r *Controller) analyzeOneBatch(ctx context.Context, workloadController workloads.WorkloadController) bool {
+	metrics := r.gatherAllCanaryMetrics()
+	if len(metrics) == 0 {
+		return true
+	}
+	currentBatch := r.rolloutStatus.CurrentBatch
+	now := metav1.Now()
+	status := r.rolloutStatus.CanaryAnalysis
+	if status == nil || status.Batch != currentBatch {
+		// start collecting the metrics of the batch
+		status = &v1alpha1.CanaryAnalysisStatus{
+			Batch:     currentBatch,
+			Phase:     v1alpha1.CanaryAnalysisRunning,
+			StartTime: now,
+		}
+		r.rolloutStatus.CanaryAnalysis = status
+		r.recorder.Event(r.parentController, event.Normal("Canary Analysis Started",
+			fmt.Sprintf("Batch %d is available, start the canary analysis", currentBatch)))
+	}
+	if status.Phase == v1alpha1.CanaryAnalysisSucceeded {
+		return true
+	}
+	// the metrics of the batch are only meaningful after a full window, which is also the pace of re-analysing
+	// a failed batch held by the pause policy
+	window := analysis.Window(metrics)
+	since := status.StartTime.Time
+	if status.LastAnalysisTime != nil && status.Phase == v1alpha1.CanaryAnalysisFailed {
+		since = status.LastAnalysisTime.Time
+	}
+	if now.Sub(since) < window {
+		klog.V(common.LogDebug).InfoS("waiting for the canary metrics to be collected", "current batch",
+			currentBatch, "window", window)
+		if status.Phase == v1alpha1.CanaryAnalysisRunning {
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("collecting the canary metrics of batch %d", currentBatch))
+		}
+		return false
+	}
+
+	results, passed, err := r.analyzer.Analyze(ctx, metrics, analysis.QueryContext{
+		Name:      r.parentController.GetName(),
+		Namespace: r.parentController.GetNamespace(),
+		Batch:     currentBatch,
+	})
+	status.LastAnalysisTime = &now
+	status.Metrics = results
+	if err != nil {
+		// the analysis is inconclusive, query again in the next round
+		klog.ErrorS(err, "failed to analyse the canary metrics", "current batch", currentBatch)
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if passed {
+		if status.Phase == v1alpha1.CanaryAnalysisFailed {
+			r.rolloutStatus.SetConditions(v1alpha1.NewNegativeCondition(v1alpha1.BatchPaused,
+				"the canary metrics are back in range"))
+		}
+		status.Phase = v1alpha1.CanaryAnalysisSucceeded
+		status.Message = ""
+		klog.InfoS("the batch passed the canary analysis", "current batch", currentBatch)
+		r.recorder.Event(r.parentController, event.Normal("Canary Analysis Succeeded",
+			fmt.Sprintf("Batch %d passed the canary analysis", currentBatch)))
+		return true
+	}
+
+	status.Phase = v1alpha1.CanaryAnalysisFailed
+	status.Message = analysis.Describe(results)
+	klog.InfoS("the batch failed the canary analysis", "current batch", currentBatch, "reason", status.Message,
+		"failure policy", r.rolloutSpec.CanaryFailurePolicy)
+	r.recorder.Event(r.parentController, event.Warning("Canary Analysis Failed",
+		errors.Errorf("batch %d failed the canary analysis, %s", currentBatch, status.Message)))
+	if r.rolloutSpec.CanaryFailurePolicy == v1alpha1.RollbackCanaryFailurePolicy {
+		r.rollbackOneBatch(ctx, workloadController)
+		return false
+	}
+	// hold the rollout at the batch until the metrics are back in range
+	paused := v1alpha1.NewPositiveCondition(v1alpha1.BatchPaused)
+	paused.Message = fmt.Sprintf("batch %d failed the canary analysis, %s", currentBatch, status.Message)
+	r.rolloutStatus.SetConditions(paused)
+	return false
+}
+
+// isRollingBack checks if the current batch failed the canary analysis and has to be rolled back
+func (r *Controller) isRollingBack() bool {
+	status := r.rolloutStatus.CanaryAnalysis
+	return r.rolloutSpec.CanaryFailurePolicy == v1alpha1.RollbackCanaryFailurePolicy && status != nil &&
+		status.Batch == r.rolloutStatus.CurrentBatch && status.Phase == v1alpha1.CanaryAnalysisFailed
+}
+
+// rollbackOneBatch reverts the upgraded part of the resources and fails the rollout once the source takes over
+func (r *Controller) rollbackOneBatch(ctx context.Context, workloadController workloads.WorkloadController) {
+	reason := fmt.Sprintf("batch %d failed the canary analysis, %s", r.rolloutStatus.CurrentBatch,
+		r.rolloutStatus.CanaryAnalysis.Message)
+	// call the pre-rollback webhooks, the batch stays as it is if one of them rejects the rollback
+	done, err := r.runHooks(ctx, v1alpha1.PreRollbackHook, string(r.rolloutStatus.BatchRollingState),
+		r.gatherAllWebhooks())
+	if err != nil {
+		r.rolloutStatus.RolloutFailing(fmt.Sprintf("%s, the rollback is rejected: %s", reason, err.Error()))
+		return
+	}
+	if !done {
+		return
+	}
+	// move the traffic back first so that no request goes to the target while it's being scaled down
+	if !r.routeTraffic(ctx, 0) || !r.applyTrafficRules(ctx, nil) {
+		return
+	}
+	rollbackController, ok := workloadController.(workloads.RollbackController)
+	if !ok {
+		// the workload can't be reverted in the middle of the rollout, stop it at the current batch
+		r.rolloutStatus.RolloutFailing(reason)
+		return
+	}
+	rolledBack, err := rollbackController.Rollback(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to roll back the batch", "current batch", r.rolloutStatus.CurrentBatch)
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return
+	}
+	if rolledBack {
+		r.recorder.Event(r.parentController, event.Normal("Rollout Rolled Back",
+			fmt.Sprintf("Rolled back to the source as %s", reason)))
+		r.rolloutStatus.RolloutFailing(reason)
+	}
+}
+
+// batchTrafficWeight returns the percent of the traffic routed to the target in the current batch
+func (r *Controller) batchTrafficWeight() int32 {
+	if r.promotesTarget() {
+		// the blue-green and the A/B testing rollouts switch all the traffic at once when the target is promoted
+		return 0
+	}
+	batch := r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch]
+	if batch.TrafficWeight != nil {
+		return *batch.TrafficWeight
+	}
+	if r.rolloutStatus.RolloutTargetSize <= 0 {
+		return 0
+	}
+	// follow the share of the upgraded replicas
+	weight := int32(math.Round(float64(traffic.MaxWeight*r.rolloutStatus.UpgradedReplicas) /
+		float64(r.rolloutStatus.RolloutTargetSize)))
+	if weight > traffic.MaxWeight {
+		return traffic.MaxWeight
+	}
+	return weight
+}
+
+// routeTraffic routes the percent of the traffic to the target if the rollout plan has traffic routing,
+// it returns if the traffic is routed
+func (r *Controller) routeTraffic(ctx context.Context, weight int32) bool {
+	if r.rolloutSpec.TrafficRouting == nil {
+		return true
+	}
+	router, err := r.getTrafficRouter()
+	if err != nil {
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if err := router.SetWeight(ctx, weight); err != nil {
+		klog.ErrorS(err, "failed to shift the traffic", "target weight", weight,
+			"provider", r.rolloutSpec.TrafficRouting.Provider)
+		r.recorder.Event(r.parentController, event.Warning("Failed to shift the traffic", err))
+		r.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	if r.rolloutStatus.TrafficWeight != weight {
+		klog.InfoS("shifted the traffic", "current batch", r.rolloutStatus.CurrentBatch, "target weight", weight)
+		r.recorder.Event(r.parentController, event.Normal("Traffic Shifted",
+			fmt.Sprintf("Routed %d%% of the traffic to the target", weight)))
+		r.rolloutStatus.TrafficWeight = weight
+	}
+	return true
+}
+
+// getTrafficRouter returns the router programming the traffic routing of the rollout plan
+func (r *Controller) getTrafficRouter() (traffic.Router, error) {
+	if r.trafficRouter == nil {
+		router, err := traffic.NewRouter(r.client, r.parentController.GetNamespace(), *r.rolloutSpec.TrafficRouting)
+		if err != nil {
+			return nil, err
+		}
+		r.trafficRouter = router
+	}
+	return r.trafficRouter, nil
+}
+
 // check if we can move to the next batch
 func (r *Controller) tryMovingToNextBatch() {
 	if r.rolloutSpec.BatchPartition == nil || *r.rolloutSpec.BatchPartition > r.rolloutStatus.CurrentBatch {
@@ -271,20 +478,15 @@ func (r *Controller) tryMovingToNextBatch() {
 }
 
 func (r *Controller) finalizeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the post-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PostBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(v1alpha1.BatchFinalizingState), rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a post batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
-		}
+	done, err := r.runHooks(ctx, v1alpha1.PostBatchRolloutHook, string(v1alpha1.BatchFinalizingState),
+		r.gatherAllWebhooks())
+	if err != nil {
+		r.rolloutStatus.RolloutFailing(err.Error())
+		return
+	}
+	if !done {
+		return
 	}
 	// calculate the next phase
 	currentBatch := int(r.rolloutStatus.CurrentBatch)
@@ -306,20 +508,16 @@ func (r *Controller) finalizeOneBatch(ctx context.Context) {
 // all the common finalize work after we rollout
 func (r *Controller) finalizeRollout(ctx context.Context) {
 	// call the post-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.FinalizeRolloutHook {
-			err := callWebhook(ctx, r.parentController, string(r.rolloutStatus.RollingState), rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutRetry("failed to invoke a post rollout webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a post rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
-		}
+	done, err := r.runHooks(ctx, v1alpha1.FinalizeRolloutHook, string(r.rolloutStatus.RollingState),
+		r.rolloutSpec.RolloutWebhooks)
+	if err != nil && r.rolloutStatus.RollingState == v1alpha1.FinalisingState {
+		// the rollout can't succeed if a finalize hook rejects it, the failing rollouts are finalized regardless
+		r.rolloutStatus.RolloutFailed(err.Error())
+		return
+	}
+	if done || err != nil {
+		r.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
 	}
-	r.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
 }
 
 // GetWorkloadController pick the right workload controller to work on the workload
@@ -358,5 +556,10 @@ func (r *Controller) GetWorkloadController() (workloads.WorkloadController, erro
 				r.rolloutSpec, r.rolloutStatus, target), nil
 		}
 	}
+	if util.IsKnativeService(r.targetWorkload.GroupVersionKind()) {
+		// knative service is upgraded in place, the rollout shifts the traffic between its revisions
+		return workloads.NewKnativeServiceRolloutController(r.client, r.recorder, r.parentController,
+			r.rolloutSpec, r.rolloutStatus, target), nil
+	}
 	return nil, fmt.Errorf("the workload kind `%s` is not supported", kind)
 }
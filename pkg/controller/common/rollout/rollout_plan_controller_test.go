@@ -17,11 +17,27 @@ limitations under the License.
 package rollout
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/analysis"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/workloads"
 )
 
 func Test_TryMovingToNextBatch(t *testing.T) {
@@ -63,3 +79,371 @@ func Test_TryMovingToNextBatch(t *testing.T) {
 		})
 	}
 }
+
+type constantProvider float64
+
+func (c constantProvider) Query(context.Context, string) (float64, error) {
+	return float64(c), nil
+}
+
+type fakeWorkloadController struct {
+	workloads.WorkloadController
+}
+
+type fakeRollbackController struct {
+	fakeWorkloadController
+	rolledBack bool
+}
+
+func (f *fakeRollbackController) Rollback(context.Context) (bool, error) {
+	f.rolledBack = true
+	return true, nil
+}
+
+func Test_AnalyzeOneBatch(t *testing.T) {
+	maxErrorRate := intstr.FromString("0.05")
+	metric := v1alpha1.CanaryMetric{
+		Name:         "error-rate",
+		Interval:     "1m",
+		Query:        "error_rate",
+		Provider:     &v1alpha1.MetricProvider{Address: "http://prometheus:9090"},
+		MetricsRange: &v1alpha1.MetricsExpectedRange{Max: &maxErrorRate},
+	}
+	started := &v1alpha1.CanaryAnalysisStatus{
+		Batch:     1,
+		Phase:     v1alpha1.CanaryAnalysisRunning,
+		StartTime: metav1.NewTime(time.Now().Add(-2 * time.Minute)),
+	}
+	tests := map[string]struct {
+		metrics            []v1alpha1.CanaryMetric
+		policy             v1alpha1.CanaryFailurePolicyType
+		analysis           *v1alpha1.CanaryAnalysisStatus
+		value              float64
+		workloadController workloads.WorkloadController
+		wantPassed         bool
+		wantPhase          v1alpha1.CanaryAnalysisPhase
+		wantRollingState   v1alpha1.RollingState
+		wantPaused         bool
+	}{
+		"no canary metrics": {
+			wantPassed:       true,
+			wantRollingState: v1alpha1.RollingInBatchesState,
+		},
+		"wait for the metrics to be collected": {
+			metrics:          []v1alpha1.CanaryMetric{metric},
+			wantPhase:        v1alpha1.CanaryAnalysisRunning,
+			wantRollingState: v1alpha1.RollingInBatchesState,
+		},
+		"metrics in range": {
+			metrics:          []v1alpha1.CanaryMetric{metric},
+			analysis:         started,
+			value:            0.01,
+			wantPassed:       true,
+			wantPhase:        v1alpha1.CanaryAnalysisSucceeded,
+			wantRollingState: v1alpha1.RollingInBatchesState,
+		},
+		"pause when the metrics are out of range": {
+			metrics:          []v1alpha1.CanaryMetric{metric},
+			analysis:         started,
+			value:            0.2,
+			wantPhase:        v1alpha1.CanaryAnalysisFailed,
+			wantRollingState: v1alpha1.RollingInBatchesState,
+			wantPaused:       true,
+		},
+		"roll back when the metrics are out of range": {
+			metrics:            []v1alpha1.CanaryMetric{metric},
+			policy:             v1alpha1.RollbackCanaryFailurePolicy,
+			analysis:           started,
+			value:              0.2,
+			workloadController: &fakeRollbackController{},
+			wantPhase:          v1alpha1.CanaryAnalysisFailed,
+			wantRollingState:   v1alpha1.RolloutFailingState,
+		},
+		"stop the workload that can't be rolled back": {
+			metrics:          []v1alpha1.CanaryMetric{metric},
+			policy:           v1alpha1.RollbackCanaryFailurePolicy,
+			analysis:         started,
+			value:            0.2,
+			wantPhase:        v1alpha1.CanaryAnalysisFailed,
+			wantRollingState: v1alpha1.RolloutFailingState,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &Controller{
+				recorder:         event.NewNopRecorder(),
+				parentController: &v1beta1.AppRollout{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+				rolloutSpec: &v1alpha1.RolloutPlan{
+					RolloutBatches:      []v1alpha1.RolloutBatch{{}, {CanaryMetric: tt.metrics}},
+					CanaryFailurePolicy: tt.policy,
+				},
+				rolloutStatus: &v1alpha1.RolloutStatus{
+					CurrentBatch:      1,
+					RollingState:      v1alpha1.RollingInBatchesState,
+					BatchRollingState: v1alpha1.BatchVerifyingState,
+					CanaryAnalysis:    tt.analysis.DeepCopy(),
+				},
+				analyzer: &analysis.Analyzer{
+					NewProvider: func(v1alpha1.MetricProvider) (analysis.Provider, error) {
+						return constantProvider(tt.value), nil
+					},
+				},
+			}
+			if tt.workloadController == nil {
+				tt.workloadController = fakeWorkloadController{}
+			}
+			if passed := r.analyzeOneBatch(context.Background(), tt.workloadController); passed != tt.wantPassed {
+				t.Errorf("want passed `%t`, got `%t`", tt.wantPassed, passed)
+			}
+			if len(tt.wantPhase) != 0 && r.rolloutStatus.CanaryAnalysis.Phase != tt.wantPhase {
+				t.Errorf("want analysis phase `%s`, got `%s`", tt.wantPhase, r.rolloutStatus.CanaryAnalysis.Phase)
+			}
+			if r.rolloutStatus.RollingState != tt.wantRollingState {
+				t.Errorf("want rolling state `%s`, got `%s`", tt.wantRollingState, r.rolloutStatus.RollingState)
+			}
+			paused := r.rolloutStatus.GetCondition(v1alpha1.BatchPaused).Status == corev1.ConditionTrue
+			if paused != tt.wantPaused {
+				t.Errorf("want paused `%t`, got `%t`", tt.wantPaused, paused)
+			}
+			if rc, ok := tt.workloadController.(*fakeRollbackController); ok && !rc.rolledBack {
+				t.Errorf("the workload is not rolled back")
+			}
+		})
+	}
+}
+
+type fakeBlueGreenController struct {
+	fakeWorkloadController
+	scaledDown bool
+}
+
+func (f *fakeBlueGreenController) ScaleDownSource(context.Context) (bool, error) {
+	f.scaledDown = true
+	return true, nil
+}
+
+func Test_PromoteBlueGreen(t *testing.T) {
+	target := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web-v2", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "web", "app.oam.dev/appRevision": "web-v2"},
+			},
+		},
+	}}
+	tests := map[string]struct {
+		delay          int32
+		promoted       bool
+		wantDone       bool
+		wantScaledDown bool
+	}{
+		"keep the source during the scale down delay": {
+			delay: 60,
+		},
+		"scale down the source after the delay": {
+			delay:          0,
+			wantDone:       true,
+			wantScaledDown: true,
+		},
+		"scale down the source promoted before": {
+			delay:          60,
+			promoted:       true,
+			wantDone:       true,
+			wantScaledDown: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: corev1.ServiceSpec{
+					Selector: map[string]string{"app": "web", "app.oam.dev/appRevision": "web-v1"},
+				},
+			}
+			c := fake.NewFakeClientWithScheme(scheme.Scheme, svc)
+			r := &Controller{
+				client:           c,
+				recorder:         event.NewNopRecorder(),
+				parentController: &v1beta1.AppRollout{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+				rolloutSpec: &v1alpha1.RolloutPlan{
+					RolloutStrategy: v1alpha1.BlueGreenRolloutStrategyType,
+					BlueGreen: &v1alpha1.BlueGreenStrategy{
+						ActiveService:   "web",
+						PromotionPolicy: v1alpha1.PromotionPolicy{ScaleDownDelaySeconds: pointer.Int32Ptr(tt.delay)},
+					},
+				},
+				rolloutStatus:  &v1alpha1.RolloutStatus{RollingState: v1alpha1.FinalisingState},
+				targetWorkload: target,
+			}
+			if tt.promoted {
+				promotedTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+				r.rolloutStatus.PromotedTime = &promotedTime
+			}
+			workloadController := &fakeBlueGreenController{}
+			if done := r.promoteTarget(context.Background(), workloadController); done != tt.wantDone {
+				t.Errorf("want done `%t`, got `%t`", tt.wantDone, done)
+			}
+			if workloadController.scaledDown != tt.wantScaledDown {
+				t.Errorf("want the source scaled down `%t`, got `%t`", tt.wantScaledDown, workloadController.scaledDown)
+			}
+			if r.rolloutStatus.PromotedTime == nil {
+				t.Errorf("the promoted time is not recorded")
+			}
+			var got corev1.Service
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "web"}, &got); err != nil {
+				t.Fatal(err)
+			}
+			// the active service is switched only once, when the target is promoted
+			wantRevision := "web-v2"
+			if tt.promoted {
+				wantRevision = "web-v1"
+			}
+			if got.Spec.Selector["app.oam.dev/appRevision"] != wantRevision {
+				t.Errorf("want the service selecting `%s`, got %v", wantRevision, got.Spec.Selector)
+			}
+		})
+	}
+}
+
+type fakeRouter struct {
+	rules []v1alpha1.TrafficRule
+}
+
+func (f *fakeRouter) SetWeight(context.Context, int32) error {
+	return nil
+}
+
+func (f *fakeRouter) SetRules(_ context.Context, rules []v1alpha1.TrafficRule) error {
+	f.rules = rules
+	return nil
+}
+
+func Test_ApplyTrafficRules(t *testing.T) {
+	rules := []v1alpha1.TrafficRule{{Name: "beta"}, {Name: "mobile", Weight: pointer.Int32Ptr(50)}}
+	router := &fakeRouter{}
+	r := &Controller{
+		recorder:         event.NewNopRecorder(),
+		parentController: &v1beta1.AppRollout{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			RolloutStrategy: v1alpha1.ABTestingRolloutStrategyType,
+			TrafficRouting:  &v1alpha1.TrafficRouting{Provider: v1alpha1.IstioTrafficRoutingProvider},
+			ABTesting:       &v1alpha1.ABTestingStrategy{Rules: rules},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{},
+		trafficRouter: router,
+	}
+	if !r.applyTrafficRules(context.Background(), rules) {
+		t.Fatal("the rules are not applied")
+	}
+	if len(router.rules) != 2 {
+		t.Errorf("want the rules routed, got %v", router.rules)
+	}
+	want := []v1alpha1.TrafficRuleStatus{
+		{Name: "beta", Weight: 100, Applied: true},
+		{Name: "mobile", Weight: 50, Applied: true},
+	}
+	if !reflect.DeepEqual(r.rolloutStatus.TrafficRules, want) {
+		t.Errorf("want the rule status %v, got %v", want, r.rolloutStatus.TrafficRules)
+	}
+
+	// the rules are removed once the target is promoted
+	if !r.applyTrafficRules(context.Background(), nil) {
+		t.Fatal("the rules are not removed")
+	}
+	if len(router.rules) != 0 {
+		t.Errorf("want the rules removed, got %v", router.rules)
+	}
+	for _, status := range r.rolloutStatus.TrafficRules {
+		if status.Applied {
+			t.Errorf("want the rule %s removed", status.Name)
+		}
+	}
+}
+
+func Test_RunHooks(t *testing.T) {
+	var invoked int
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		invoked++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	accepting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		invoked++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer accepting.Close()
+
+	tests := map[string]struct {
+		hook        v1alpha1.RolloutWebhook
+		wantDone    []bool
+		wantErr     bool
+		wantPhase   v1alpha1.RolloutWebhookPhase
+		wantInvoked int
+	}{
+		"invoke an accepted hook only once": {
+			hook:        v1alpha1.RolloutWebhook{URL: accepting.URL},
+			wantDone:    []bool{true, true},
+			wantPhase:   v1alpha1.RolloutWebhookSucceeded,
+			wantInvoked: 1,
+		},
+		"retry a rejected hook until it fails the rollout": {
+			hook:        v1alpha1.RolloutWebhook{URL: rejecting.URL, Retries: pointer.Int32Ptr(1)},
+			wantDone:    []bool{false, false},
+			wantErr:     true,
+			wantPhase:   v1alpha1.RolloutWebhookFailed,
+			wantInvoked: 2,
+		},
+		"ignore a rejected hook after its retries": {
+			hook: v1alpha1.RolloutWebhook{URL: rejecting.URL, Retries: pointer.Int32Ptr(0),
+				FailurePolicy: v1alpha1.IgnoreWebhookFailurePolicy},
+			wantDone:    []bool{true, true},
+			wantPhase:   v1alpha1.RolloutWebhookIgnored,
+			wantInvoked: 1,
+		},
+		"retry a rejected hook without retries forever": {
+			hook:        v1alpha1.RolloutWebhook{URL: rejecting.URL},
+			wantDone:    []bool{false, false},
+			wantPhase:   v1alpha1.RolloutWebhookRetrying,
+			wantInvoked: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			invoked = 0
+			tt.hook.Name = "hook"
+			tt.hook.Type = v1alpha1.PreBatchRolloutHook
+			r := &Controller{
+				recorder:         event.NewNopRecorder(),
+				parentController: &v1beta1.AppRollout{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+				rolloutSpec:      &v1alpha1.RolloutPlan{},
+				rolloutStatus:    &v1alpha1.RolloutStatus{},
+			}
+			var err error
+			for i, wantDone := range tt.wantDone {
+				var done bool
+				done, err = r.runHooks(context.Background(), v1alpha1.PreBatchRolloutHook, "test",
+					[]v1alpha1.RolloutWebhook{tt.hook})
+				if done != wantDone {
+					t.Errorf("want done `%t` in reconcile %d, got `%t`", wantDone, i, done)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("want error `%t`, got `%v`", tt.wantErr, err)
+			}
+			if invoked != tt.wantInvoked {
+				t.Errorf("want the hook invoked %d times, got %d", tt.wantInvoked, invoked)
+			}
+			if len(r.rolloutStatus.Webhooks) != 1 || r.rolloutStatus.Webhooks[0].Phase != tt.wantPhase {
+				t.Fatalf("want the hook %s, got %v", tt.wantPhase, r.rolloutStatus.Webhooks)
+			}
+
+			// the hook is invoked again for the next batch
+			r.rolloutStatus.CurrentBatch++
+			if status := r.webhookStatus(tt.hook); status.Attempts != 0 || len(status.Phase) != 0 {
+				t.Errorf("want the hook status reset for the next batch, got %v", status)
+			}
+		})
+	}
+}
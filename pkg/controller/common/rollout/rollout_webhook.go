@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
@@ -32,6 +33,9 @@ import (
 	"github.com/oam-dev/kubevela/pkg/controller/common"
 )
 
+// defaultWebhookTimeout is the timeout of a webhook invocation if the webhook doesn't set one
+const defaultWebhookTimeout = 10 * time.Second
+
 // issue an http call to the an end ponit
 func makeHTTPRequest(ctx context.Context, webhookEndPoint, method string, payload interface{}) ([]byte, int, error) {
 	payloadBin, err := json.Marshal(payload)
@@ -91,6 +95,13 @@ func makeHTTPRequest(ctx context.Context, webhookEndPoint, method string, payloa
 // callWebhook does a HTTP POST to an external service and
 // returns an error if the response status code is non-2xx
 func callWebhook(ctx context.Context, resource klog.KMetadata, phase string, rw v1alpha1.RolloutWebhook) error {
+	timeout := defaultWebhookTimeout
+	if rw.TimeoutSeconds != nil {
+		timeout = time.Duration(*rw.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	payload := v1alpha1.RolloutWebhookPayload{
 		Name:      resource.GetName(),
 		Namespace: resource.GetNamespace(),
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// HTTPRouteGVK is the GVK of the Gateway API HTTPRoute
+var HTTPRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Kind: "HTTPRoute"}
+
+// gatewayRouter shifts the traffic with the backend weights of a Gateway API HTTPRoute
+type gatewayRouter struct {
+	route
+}
+
+// SetWeight sets the weights of the stable and canary backends in all the rules of the HTTPRoute,
+// the HTTPRoute has to exist since it's attached to the gateways by the user
+func (r *gatewayRouter) SetWeight(ctx context.Context, canaryWeight int32) error {
+	stableWeight, canaryWeight64 := weights(canaryWeight)
+	hr, err := r.get(ctx, HTTPRouteGVK)
+	if err != nil {
+		return err
+	}
+	if hr == nil {
+		return fmt.Errorf("the HTTPRoute %s doesn't exist", r.routing.Route)
+	}
+	spec, _, _ := unstructured.NestedMap(hr.Object, "spec")
+	rules, _, _ := unstructured.NestedSlice(spec, "rules")
+	found := false
+	for i, rl := range rules {
+		rule, ok := rl.(map[string]interface{})
+		// the rules of the A/B testing have their own weights
+		if !ok || i < abRuleCount(hr) {
+			continue
+		}
+		backends, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		var stable map[string]interface{}
+		canary := false
+		for _, b := range backends {
+			backend, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch backend["name"] {
+			case r.routing.StableService:
+				backend["weight"] = stableWeight
+				stable = backend
+			case r.routing.CanaryService:
+				backend["weight"] = canaryWeight64
+				canary = true
+			}
+		}
+		if stable == nil {
+			continue
+		}
+		if !canary {
+			// the canary backend listens on the same port as the stable one
+			backend := map[string]interface{}{"name": r.routing.CanaryService, "weight": canaryWeight64}
+			if port, ok := stable["port"]; ok {
+				backend["port"] = port
+			}
+			backends = append(backends, backend)
+		}
+		rule["backendRefs"] = backends
+		rules[i] = rule
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no rule of the HTTPRoute %s routes to the stable service %s",
+			hr.GetName(), r.routing.StableService)
+	}
+	spec["rules"] = rules
+	return r.patchSpec(ctx, hr, spec)
+}
+
+// SetRules replaces the rules of the A/B testing at the top of the HTTPRoute, their number is recorded
+// in an annotation since the rules of the HTTPRoute have no names
+func (r *gatewayRouter) SetRules(ctx context.Context, rules []v1alpha1.TrafficRule) error {
+	hr, err := r.get(ctx, HTTPRouteGVK)
+	if err != nil {
+		return err
+	}
+	if hr == nil {
+		return fmt.Errorf("the HTTPRoute %s doesn't exist", r.routing.Route)
+	}
+	spec, _, _ := unstructured.NestedMap(hr.Object, "spec")
+	current, _, _ := unstructured.NestedSlice(spec, "rules")
+	count := abRuleCount(hr)
+	if count > len(current) {
+		count = len(current)
+	}
+	stable := r.stableBackend(current[count:])
+	newRules := make([]interface{}, 0, len(rules)+len(current)-count)
+	for _, rule := range rules {
+		stableWeight, canaryWeight := weights(RuleWeight(rule))
+		var headers []interface{}
+		for _, h := range rule.Headers {
+			matchType := "Exact"
+			if h.Type == v1alpha1.RegexHeaderMatch {
+				matchType = "RegularExpression"
+			}
+			headers = append(headers, map[string]interface{}{"type": matchType, "name": h.Name, "value": h.Value})
+		}
+		if rule.Cookie != nil {
+			headers = append(headers, map[string]interface{}{"type": "RegularExpression", "name": "Cookie",
+				"value": cookieRegex(*rule.Cookie)})
+		}
+		newRules = append(newRules, map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{"headers": headers}},
+			"backendRefs": []interface{}{
+				r.backend(stable, r.routing.StableService, stableWeight),
+				r.backend(stable, r.routing.CanaryService, canaryWeight),
+			},
+		})
+	}
+	newRules = append(newRules, current[count:]...)
+	if reflect.DeepEqual(current, newRules) && abRuleCount(hr) == len(rules) {
+		return nil
+	}
+	patch := client.MergeFrom(hr.DeepCopyObject())
+	spec["rules"] = newRules
+	if err = unstructured.SetNestedMap(hr.Object, spec, "spec"); err != nil {
+		return err
+	}
+	annotations := hr.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationABTestingRules] = strconv.Itoa(len(rules))
+	hr.SetAnnotations(annotations)
+	return errors.Wrapf(r.client.Patch(ctx, hr, patch), "failed to update the HTTPRoute %s", hr.GetName())
+}
+
+// stableBackend returns the first backend of the stable service in the rules
+func (r *gatewayRouter) stableBackend(rules []interface{}) map[string]interface{} {
+	for _, rl := range rules {
+		rule, ok := rl.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		backends, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range backends {
+			if backend, ok := b.(map[string]interface{}); ok && backend["name"] == r.routing.StableService {
+				return backend
+			}
+		}
+	}
+	return nil
+}
+
+// backend refers to the service on the port of the stable backend
+func (r *gatewayRouter) backend(stable map[string]interface{}, service string, weight int64) map[string]interface{} {
+	backend := map[string]interface{}{"name": service, "weight": weight}
+	if port, ok := stable["port"]; ok {
+		backend["port"] = port
+	}
+	return backend
+}
+
+// abRuleCount returns the number of the rules of the A/B testing at the top of the HTTPRoute
+func abRuleCount(hr *unstructured.Unstructured) int {
+	count, _ := strconv.Atoi(hr.GetAnnotations()[oam.AnnotationABTestingRules])
+	return count
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// VirtualServiceGVK is the GVK of the Istio VirtualService
+var VirtualServiceGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "VirtualService"}
+
+// abRoutePrefix prefixes the names of the HTTP routes of the A/B testing rules
+const abRoutePrefix = "vela-ab-"
+
+// istioRouter shifts the traffic with the destination weights of an Istio VirtualService
+type istioRouter struct {
+	route
+}
+
+// SetWeight sets the weights of the stable and canary destinations in all the HTTP routes of the VirtualService,
+// a VirtualService routing the service to the two destinations is created if it doesn't exist
+func (r *istioRouter) SetWeight(ctx context.Context, canaryWeight int32) error {
+	stableWeight, canaryWeight64 := weights(canaryWeight)
+	vs, err := r.get(ctx, VirtualServiceGVK)
+	if err != nil {
+		return err
+	}
+	if vs == nil {
+		return r.create(ctx, VirtualServiceGVK, map[string]interface{}{
+			"hosts": []interface{}{r.routing.Service},
+			"http": []interface{}{
+				map[string]interface{}{
+					"route": []interface{}{
+						r.destination(r.routing.StableService, stableWeight),
+						r.destination(r.routing.CanaryService, canaryWeight64),
+					},
+				},
+			},
+		})
+	}
+	spec, _, _ := unstructured.NestedMap(vs.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	httpRoutes, _, _ := unstructured.NestedSlice(spec, "http")
+	found := false
+	for i, h := range httpRoutes {
+		httpRoute, ok := h.(map[string]interface{})
+		if !ok || isABRoute(httpRoute) {
+			continue
+		}
+		destinations, _, _ := unstructured.NestedSlice(httpRoute, "route")
+		var stable, canary bool
+		for _, d := range destinations {
+			dest, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			host, _, _ := unstructured.NestedString(dest, "destination", "host")
+			switch {
+			case matchService(host, r.routing.StableService):
+				dest["weight"] = stableWeight
+				stable = true
+			case matchService(host, r.routing.CanaryService):
+				dest["weight"] = canaryWeight64
+				canary = true
+			}
+		}
+		if !stable {
+			continue
+		}
+		if !canary {
+			destinations = append(destinations, r.destination(r.routing.CanaryService, canaryWeight64))
+		}
+		httpRoute["route"] = destinations
+		httpRoutes[i] = httpRoute
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no http route of the VirtualService %s routes to the stable service %s",
+			vs.GetName(), r.routing.StableService)
+	}
+	spec["http"] = httpRoutes
+	return r.patchSpec(ctx, vs, spec)
+}
+
+func (r *istioRouter) destination(service string, weight int64) map[string]interface{} {
+	return map[string]interface{}{
+		"destination": map[string]interface{}{"host": service},
+		"weight":      weight,
+	}
+}
+
+// SetRules replaces the HTTP routes of the A/B testing rules at the top of the VirtualService,
+// the VirtualService is created by SetWeight before
+func (r *istioRouter) SetRules(ctx context.Context, rules []v1alpha1.TrafficRule) error {
+	vs, err := r.get(ctx, VirtualServiceGVK)
+	if err != nil {
+		return err
+	}
+	if vs == nil {
+		return fmt.Errorf("the VirtualService %s doesn't exist", r.routing.Route)
+	}
+	spec, _, _ := unstructured.NestedMap(vs.Object, "spec")
+	httpRoutes, _, _ := unstructured.NestedSlice(spec, "http")
+	routes := make([]interface{}, 0, len(rules)+len(httpRoutes))
+	for _, rule := range rules {
+		stableWeight, canaryWeight := weights(RuleWeight(rule))
+		match := map[string]interface{}{}
+		for _, h := range rule.Headers {
+			key := "exact"
+			if h.Type == v1alpha1.RegexHeaderMatch {
+				key = "regex"
+			}
+			match[strings.ToLower(h.Name)] = map[string]interface{}{key: h.Value}
+		}
+		if rule.Cookie != nil {
+			match["cookie"] = map[string]interface{}{"regex": cookieRegex(*rule.Cookie)}
+		}
+		routes = append(routes, map[string]interface{}{
+			"name":  abRoutePrefix + rule.Name,
+			"match": []interface{}{map[string]interface{}{"headers": match}},
+			"route": []interface{}{
+				r.destination(r.routing.StableService, stableWeight),
+				r.destination(r.routing.CanaryService, canaryWeight),
+			},
+		})
+	}
+	// the routes of the previous rules are dropped
+	for _, h := range httpRoutes {
+		if httpRoute, ok := h.(map[string]interface{}); !ok || !isABRoute(httpRoute) {
+			routes = append(routes, h)
+		}
+	}
+	spec["http"] = routes
+	return r.patchSpec(ctx, vs, spec)
+}
+
+func isABRoute(httpRoute map[string]interface{}) bool {
+	name, _ := httpRoute["name"].(string)
+	return strings.HasPrefix(name, abRoutePrefix)
+}
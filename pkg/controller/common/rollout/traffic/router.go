@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// MaxWeight is the weight routing all the traffic to one service
+const MaxWeight = 100
+
+// Router shifts the traffic between the stable and the canary service of a rollout
+type Router interface {
+	// SetWeight routes the percent of the traffic to the canary service and the rest to the stable service,
+	// the route is only updated if its weights are different
+	SetWeight(ctx context.Context, canaryWeight int32) error
+
+	// SetRules routes the requests matching the A/B testing rules to the canary service, the routes of the
+	// rules are put before the weighted ones and the ones of the previous rules are replaced
+	SetRules(ctx context.Context, rules []v1alpha1.TrafficRule) error
+}
+
+// NewRouter creates the router programming the route of the traffic routing in the namespace
+func NewRouter(c client.Client, namespace string, routing v1alpha1.TrafficRouting) (Router, error) {
+	r := route{
+		client:    c,
+		namespace: namespace,
+		routing:   routing,
+	}
+	if len(r.routing.Route) == 0 {
+		r.routing.Route = routing.Service
+	}
+	switch routing.Provider {
+	case v1alpha1.IstioTrafficRoutingProvider:
+		return &istioRouter{route: r}, nil
+	case v1alpha1.SMITrafficRoutingProvider:
+		return &smiRouter{route: r}, nil
+	case v1alpha1.GatewayAPITrafficRoutingProvider:
+		return &gatewayRouter{route: r}, nil
+	default:
+		return nil, fmt.Errorf("unsupported traffic routing provider %q", routing.Provider)
+	}
+}
+
+// route holds what all the routers share, they program an unstructured route object
+type route struct {
+	client    client.Client
+	namespace string
+	routing   v1alpha1.TrafficRouting
+}
+
+// get fetches the route object, it returns nil if the route doesn't exist
+func (r *route) get(ctx context.Context, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: r.routing.Route}, obj)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the %s %s", gvk.Kind, r.routing.Route)
+	}
+	return obj, nil
+}
+
+// create creates the route object with the spec
+func (r *route) create(ctx context.Context, gvk schema.GroupVersionKind, spec map[string]interface{}) error {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(r.namespace)
+	obj.SetName(r.routing.Route)
+	return errors.Wrapf(r.client.Create(ctx, obj), "failed to create the %s %s", gvk.Kind, r.routing.Route)
+}
+
+// patchSpec patches the spec of the route object if it's changed
+func (r *route) patchSpec(ctx context.Context, obj *unstructured.Unstructured, spec map[string]interface{}) error {
+	old, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if reflect.DeepEqual(old, spec) {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject())
+	if err := unstructured.SetNestedMap(obj.Object, spec, "spec"); err != nil {
+		return err
+	}
+	return errors.Wrapf(r.client.Patch(ctx, obj, patch), "failed to update the %s %s", obj.GetKind(), obj.GetName())
+}
+
+// weights returns the weight of the stable and the canary service
+func weights(canaryWeight int32) (int64, int64) {
+	return int64(MaxWeight - canaryWeight), int64(canaryWeight)
+}
+
+// matchService checks if the host refers to the service, it can be the short or the fully qualified name
+func matchService(host, service string) bool {
+	return host == service || strings.HasPrefix(host, service+".")
+}
+
+// RuleWeight returns the percent of the requests matching the rule routed to the canary service
+func RuleWeight(rule v1alpha1.TrafficRule) int32 {
+	if rule.Weight == nil {
+		return MaxWeight
+	}
+	return *rule.Weight
+}
+
+// cookieRegex matches the cookie header carrying the cookie
+func cookieRegex(cookie v1alpha1.CookieMatch) string {
+	return "^(.*?;\\s*)?" + regexp.QuoteMeta(cookie.Name) + "=" + regexp.QuoteMeta(cookie.Value) + "(;.*)?$"
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newRouting(provider v1alpha1.TrafficRoutingProvider) v1alpha1.TrafficRouting {
+	return v1alpha1.TrafficRouting{
+		Provider:      provider,
+		Service:       "web",
+		StableService: "web-stable",
+		CanaryService: "web-canary",
+	}
+}
+
+func getSpec(t *testing.T, c client.Client, gvk schema.GroupVersionKind, name string) map[string]interface{} {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, obj))
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	return spec
+}
+
+func TestIstioRouter(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	r, err := NewRouter(c, "default", newRouting(v1alpha1.IstioTrafficRoutingProvider))
+	assert.NoError(t, err)
+
+	setWeight := func(weight int32) {
+		assert.NoError(t, r.SetWeight(ctx, weight))
+	}
+	setWeight(0)
+	assert.Equal(t, map[string]interface{}{
+		"hosts": []interface{}{"web"},
+		"http": []interface{}{map[string]interface{}{"route": []interface{}{
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-stable"}, "weight": int64(100)},
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-canary"}, "weight": int64(0)},
+		}}},
+	}, getSpec(t, c, VirtualServiceGVK, "web"))
+
+	setWeight(30)
+	routes, _, _ := unstructured.NestedSlice(getSpec(t, c, VirtualServiceGVK, "web"), "http")
+	destinations := routes[0].(map[string]interface{})["route"].([]interface{})
+	assert.Equal(t, int64(70), destinations[0].(map[string]interface{})["weight"])
+	assert.Equal(t, int64(30), destinations[1].(map[string]interface{})["weight"])
+
+	// the user defined VirtualService routing to the stable service by its fully qualified name
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(VirtualServiceGVK)
+	vs.SetNamespace("default")
+	vs.SetName("web-vs")
+	assert.NoError(t, unstructured.SetNestedSlice(vs.Object, []interface{}{
+		map[string]interface{}{
+			"name": "primary",
+			"route": []interface{}{
+				map[string]interface{}{"destination": map[string]interface{}{"host": "web-stable.default.svc.cluster.local"}},
+			},
+		},
+	}, "spec", "http"))
+	assert.NoError(t, c.Create(ctx, vs))
+	routing := newRouting(v1alpha1.IstioTrafficRoutingProvider)
+	routing.Route = "web-vs"
+	r, err = NewRouter(c, "default", routing)
+	assert.NoError(t, err)
+	setWeight(50)
+	routes, _, _ = unstructured.NestedSlice(getSpec(t, c, VirtualServiceGVK, "web-vs"), "http")
+	assert.Equal(t, map[string]interface{}{
+		"name": "primary",
+		"route": []interface{}{
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-stable.default.svc.cluster.local"}, "weight": int64(50)},
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-canary"}, "weight": int64(50)},
+		},
+	}, routes[0])
+
+	routing.StableService = "other"
+	r, err = NewRouter(c, "default", routing)
+	assert.NoError(t, err)
+	assert.Error(t, r.SetWeight(ctx, 10))
+}
+
+func TestSMIRouter(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	r, err := NewRouter(c, "default", newRouting(v1alpha1.SMITrafficRoutingProvider))
+	assert.NoError(t, err)
+	for _, weight := range []int32{0, 20} {
+		assert.NoError(t, r.SetWeight(ctx, weight))
+		assert.Equal(t, map[string]interface{}{
+			"service": "web",
+			"backends": []interface{}{
+				map[string]interface{}{"service": "web-stable", "weight": int64(100 - weight)},
+				map[string]interface{}{"service": "web-canary", "weight": int64(weight)},
+			},
+		}, getSpec(t, c, TrafficSplitGVK, "web"))
+	}
+}
+
+func TestGatewayRouter(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	r, err := NewRouter(c, "default", newRouting(v1alpha1.GatewayAPITrafficRoutingProvider))
+	assert.NoError(t, err)
+	// the HTTPRoute is attached to the gateways by the user
+	assert.Error(t, r.SetWeight(ctx, 0))
+
+	hr := &unstructured.Unstructured{}
+	hr.SetGroupVersionKind(HTTPRouteGVK)
+	hr.SetNamespace("default")
+	hr.SetName("web")
+	assert.NoError(t, unstructured.SetNestedField(hr.Object, map[string]interface{}{
+		"parentRefs": []interface{}{map[string]interface{}{"name": "gateway"}},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": "web-stable", "port": int64(80)}},
+			},
+		},
+	}, "spec"))
+	assert.NoError(t, c.Create(ctx, hr))
+	assert.NoError(t, r.SetWeight(ctx, 10))
+	assert.Equal(t, map[string]interface{}{
+		"parentRefs": []interface{}{map[string]interface{}{"name": "gateway"}},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": "web-stable", "port": int64(80), "weight": int64(90)},
+					map[string]interface{}{"name": "web-canary", "port": int64(80), "weight": int64(10)},
+				},
+			},
+		},
+	}, getSpec(t, c, HTTPRouteGVK, "web"))
+}
+
+func TestIstioRules(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	r, err := NewRouter(c, "default", newRouting(v1alpha1.IstioTrafficRoutingProvider))
+	assert.NoError(t, err)
+	assert.NoError(t, r.SetWeight(ctx, 0))
+	assert.NoError(t, r.SetRules(ctx, []v1alpha1.TrafficRule{{
+		Name:    "beta-users",
+		Headers: []v1alpha1.HeaderMatch{{Name: "X-User-Group", Value: "beta"}},
+		Cookie:  &v1alpha1.CookieMatch{Name: "canary", Value: "always"},
+		Weight:  pointer.Int32Ptr(80),
+	}}))
+	// the weights of the rules are kept when the weights of the other routes are set
+	assert.NoError(t, r.SetWeight(ctx, 0))
+	routes, _, _ := unstructured.NestedSlice(getSpec(t, c, VirtualServiceGVK, "web"), "http")
+	assert.Len(t, routes, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name": "vela-ab-beta-users",
+		"match": []interface{}{map[string]interface{}{"headers": map[string]interface{}{
+			"x-user-group": map[string]interface{}{"exact": "beta"},
+			"cookie":       map[string]interface{}{"regex": `^(.*?;\s*)?canary=always(;.*)?$`},
+		}}},
+		"route": []interface{}{
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-stable"}, "weight": int64(20)},
+			map[string]interface{}{"destination": map[string]interface{}{"host": "web-canary"}, "weight": int64(80)},
+		},
+	}, routes[0])
+
+	// the routes of the rules are removed on promotion
+	assert.NoError(t, r.SetRules(ctx, nil))
+	routes, _, _ = unstructured.NestedSlice(getSpec(t, c, VirtualServiceGVK, "web"), "http")
+	assert.Len(t, routes, 1)
+	assert.Nil(t, routes[0].(map[string]interface{})["name"])
+}
+
+func TestGatewayRules(t *testing.T) {
+	ctx := context.Background()
+	hr := &unstructured.Unstructured{}
+	hr.SetGroupVersionKind(HTTPRouteGVK)
+	hr.SetNamespace("default")
+	hr.SetName("web")
+	assert.NoError(t, unstructured.SetNestedField(hr.Object, map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": "web-stable", "port": int64(80)}},
+			},
+		},
+	}, "spec"))
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, hr)
+	r, err := NewRouter(c, "default", newRouting(v1alpha1.GatewayAPITrafficRoutingProvider))
+	assert.NoError(t, err)
+	assert.NoError(t, r.SetWeight(ctx, 0))
+	rules := []v1alpha1.TrafficRule{{
+		Name:    "mobile",
+		Headers: []v1alpha1.HeaderMatch{{Name: "User-Agent", Value: ".*Mobile.*", Type: v1alpha1.RegexHeaderMatch}},
+	}}
+	// setting the same rules twice doesn't add them again
+	assert.NoError(t, r.SetRules(ctx, rules))
+	assert.NoError(t, r.SetRules(ctx, rules))
+	assert.NoError(t, r.SetWeight(ctx, 0))
+	got, _, _ := unstructured.NestedSlice(getSpec(t, c, HTTPRouteGVK, "web"), "rules")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{"headers": []interface{}{
+				map[string]interface{}{"type": "RegularExpression", "name": "User-Agent", "value": ".*Mobile.*"},
+			}}},
+			"backendRefs": []interface{}{
+				map[string]interface{}{"name": "web-stable", "port": int64(80), "weight": int64(0)},
+				map[string]interface{}{"name": "web-canary", "port": int64(80), "weight": int64(100)},
+			},
+		},
+		map[string]interface{}{
+			"backendRefs": []interface{}{
+				map[string]interface{}{"name": "web-stable", "port": int64(80), "weight": int64(100)},
+				map[string]interface{}{"name": "web-canary", "port": int64(80), "weight": int64(0)},
+			},
+		},
+	}, got)
+
+	assert.NoError(t, r.SetRules(ctx, nil))
+	got, _, _ = unstructured.NestedSlice(getSpec(t, c, HTTPRouteGVK, "web"), "rules")
+	assert.Len(t, got, 1)
+}
+
+func TestSMIRules(t *testing.T) {
+	r, err := NewRouter(fake.NewFakeClientWithScheme(scheme.Scheme), "default", newRouting(v1alpha1.SMITrafficRoutingProvider))
+	assert.NoError(t, err)
+	assert.Error(t, r.SetRules(context.Background(), []v1alpha1.TrafficRule{{Name: "beta"}}))
+	assert.NoError(t, r.SetRules(context.Background(), nil))
+}
+
+func TestNewRouter(t *testing.T) {
+	_, err := NewRouter(nil, "default", newRouting("linkerd"))
+	assert.Error(t, err)
+}
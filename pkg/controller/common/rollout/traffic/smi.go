@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package traffic
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// TrafficSplitGVK is the GVK of the SMI TrafficSplit
+var TrafficSplitGVK = schema.GroupVersionKind{Group: "split.smi-spec.io", Version: "v1alpha2", Kind: "TrafficSplit"}
+
+// smiRouter shifts the traffic with the backends of an SMI TrafficSplit
+type smiRouter struct {
+	route
+}
+
+// SetWeight splits the traffic of the service between the stable and the canary backends,
+// the TrafficSplit is created if it doesn't exist
+func (r *smiRouter) SetWeight(ctx context.Context, canaryWeight int32) error {
+	stableWeight, canaryWeight64 := weights(canaryWeight)
+	spec := map[string]interface{}{
+		"service": r.routing.Service,
+		"backends": []interface{}{
+			map[string]interface{}{"service": r.routing.StableService, "weight": stableWeight},
+			map[string]interface{}{"service": r.routing.CanaryService, "weight": canaryWeight64},
+		},
+	}
+	ts, err := r.get(ctx, TrafficSplitGVK)
+	if err != nil {
+		return err
+	}
+	if ts == nil {
+		return r.create(ctx, TrafficSplitGVK, spec)
+	}
+	// keep the other fields of the spec, e.g. the matches
+	current, _, _ := unstructured.NestedMap(ts.Object, "spec")
+	for k, v := range current {
+		if _, ok := spec[k]; !ok {
+			spec[k] = v
+		}
+	}
+	return r.patchSpec(ctx, ts, spec)
+}
+
+// SetRules is not supported since the TrafficSplit can't match the requests by themselves,
+// it only removes the rules
+func (r *smiRouter) SetRules(_ context.Context, rules []v1alpha1.TrafficRule) error {
+	if len(rules) != 0 {
+		return fmt.Errorf("the %s traffic routing provider doesn't support A/B testing rules", v1alpha1.SMITrafficRoutingProvider)
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ package workloads
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/pkg/errors"
@@ -31,6 +32,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // CloneSetRolloutController is responsible for handle rollout Cloneset type of workloads
@@ -127,15 +129,26 @@ func (c *CloneSetRolloutController) Initialize(ctx context.Context) (bool, error
 		return false, nil
 	}
 
+	// wait for other controllers to release the cloneset
+	if util.IsWorkloadLeasedByOthers(c.cloneSet, c.leaseHolder(), time.Now()) {
+		c.rolloutStatus.RolloutRetry(fmt.Sprintf("the cloneset %s is leased by %s", c.cloneSet.GetName(),
+			util.GetWorkloadLeaseHolder(c.cloneSet, time.Now())))
+		return false, nil
+	}
+
 	if controller := metav1.GetControllerOf(c.cloneSet); controller != nil {
 		if controller.Kind == v1beta1.AppRolloutKind && controller.APIVersion == v1beta1.SchemeGroupVersion.String() {
 			// it's already there
 			return true, nil
 		}
 	}
-	// add the parent controller to the owner of the cloneset
+	// add the parent controller to the owner of the cloneset and lease it
 	// before kicking start the update and start from every pod in the old version
 	clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+	if err := util.AcquireWorkloadLease(c.cloneSet, c.leaseHolder(), time.Now()); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
 	ref := metav1.NewControllerRef(c.parentController, v1beta1.AppRolloutKindVersionKind)
 	c.cloneSet.SetOwnerReferences(append(c.cloneSet.GetOwnerReferences(), *ref))
 	c.cloneSet.Spec.UpdateStrategy.Paused = false
@@ -165,6 +178,11 @@ func (c *CloneSetRolloutController) RolloutOneBatchPods(ctx context.Context) (bo
 	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, 0, int(cloneSetSize), int(c.rolloutStatus.CurrentBatch))
 	// set the Partition as the desired number of pods in old revisions.
 	clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+	// renew the lease along with the patch
+	if err = util.AcquireWorkloadLease(c.cloneSet, c.leaseHolder(), time.Now()); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
 	c.cloneSet.Spec.UpdateStrategy.Partition = &intstr.IntOrString{Type: intstr.Int,
 		IntVal: cloneSetSize - int32(newPodTarget)}
 	// patch the Cloneset
@@ -188,6 +206,10 @@ func (c *CloneSetRolloutController) CheckOneBatchPods(ctx context.Context) (bool
 		c.rolloutStatus.RolloutRetry(err.Error())
 		return false, nil
 	}
+	if err = c.renewLease(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
 	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, 0, int(cloneSetSize), int(c.rolloutStatus.CurrentBatch))
 	// get the number of ready pod from cloneset
 	readyPodCount := int(c.cloneSet.Status.UpdatedReadyReplicas)
@@ -253,6 +275,37 @@ func (c *CloneSetRolloutController) FinalizeOneBatch(ctx context.Context) (bool,
 	return true, nil
 }
 
+// Rollback sets the partition back to the size of the cloneset so that all the pods return to the old revision
+func (c *CloneSetRolloutController) Rollback(ctx context.Context) (bool, error) {
+	cloneSetSize, err := c.size(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	partition := c.cloneSet.Spec.UpdateStrategy.Partition
+	if partition == nil || partition.Type != intstr.Int || partition.IntVal != cloneSetSize {
+		clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+		if err = util.AcquireWorkloadLease(c.cloneSet, c.leaseHolder(), time.Now()); err != nil {
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return false, nil
+		}
+		c.cloneSet.Spec.UpdateStrategy.Partition = &intstr.IntOrString{Type: intstr.Int, IntVal: cloneSetSize}
+		if err = c.client.Patch(ctx, c.cloneSet, clonePatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to roll back the cloneset", err))
+			return false, err
+		}
+	}
+	if c.cloneSet.Status.UpdatedReplicas != 0 {
+		c.rolloutStatus.RolloutRetry(fmt.Sprintf("rolling back with %d pods still in the new revision",
+			c.cloneSet.Status.UpdatedReplicas))
+		return false, nil
+	}
+	klog.InfoS("rolled back the cloneset", "cloneSet", c.cloneSet.GetName())
+	c.rolloutStatus.UpgradedReplicas = 0
+	c.rolloutStatus.UpgradedReadyReplicas = 0
+	return true, nil
+}
+
 // Finalize makes sure the Cloneset is all upgraded
 func (c *CloneSetRolloutController) Finalize(ctx context.Context, succeed bool) bool {
 	if err := c.fetchCloneSet(ctx); err != nil {
@@ -270,7 +323,9 @@ func (c *CloneSetRolloutController) Finalize(ctx context.Context, succeed bool)
 		}
 		newOwnerList = append(newOwnerList, owner)
 	}
-	if !isOwner {
+	// release the lease so that the application controller can take over the cloneset again
+	leaseReleased := util.ReleaseWorkloadLease(c.cloneSet, c.leaseHolder())
+	if !isOwner && !leaseReleased {
 		// nothing to do if we are already not the owner
 		klog.InfoS("the cloneset is already released and not controlled by rollout", "cloneSet", c.cloneSet.Name)
 		return true
@@ -297,6 +352,18 @@ func (c *CloneSetRolloutController) Finalize(ctx context.Context, succeed bool)
 // The functions below are helper functions
 // ---------------------------------------------
 
+// renewLease renews the cloneset lease before it expires when we are waiting for pods to be ready
+func (c *CloneSetRolloutController) renewLease(ctx context.Context) error {
+	if !util.WorkloadLeaseNeedsRenew(c.cloneSet, c.leaseHolder(), time.Now()) {
+		return nil
+	}
+	clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+	if err := util.AcquireWorkloadLease(c.cloneSet, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, c.cloneSet, clonePatch, client.FieldOwner(c.parentController.GetUID()))
+}
+
 // check if the replicas in all the rollout batches add up to the right number
 func (c *CloneSetRolloutController) verifyRolloutBatchReplicaValue(currentReplicas int32) error {
 	// the target size has to be the same as the cloneset size
@@ -134,6 +134,12 @@ func calculateNewBatchTarget(rolloutSpec *v1alpha1.RolloutPlan, originalSize, ta
 	return newPodTarget
 }
 
+// keepSource returns if the strategy keeps the source at its full size next to the whole target
+// until the target is promoted
+func keepSource(strategy v1alpha1.RolloutStrategyType) bool {
+	return strategy == v1alpha1.BlueGreenRolloutStrategyType || strategy == v1alpha1.ABTestingRolloutStrategyType
+}
+
 func getDeployReplicaSize(deploy *apps.Deployment) int32 {
 	// replicas default is 1
 	if deploy.Spec.Replicas != nil {
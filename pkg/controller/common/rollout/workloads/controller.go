@@ -25,8 +25,10 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // WorkloadController is the interface that all type of cloneSet controller implements
@@ -60,6 +62,23 @@ type WorkloadController interface {
 	Finalize(ctx context.Context, succeed bool) bool
 }
 
+// RollbackController is implemented by the workload controllers that can revert the upgraded part of
+// the resources back to the source in the middle of a rollout, e.g. when the canary analysis fails
+type RollbackController interface {
+	// Rollback moves the pods or the traffic of the upgraded part back to the source
+	// it returns if the source is serving all the traffic again or should retry
+	Rollback(ctx context.Context) (bool, error)
+}
+
+// BlueGreenController is implemented by the workload controllers that can keep the source at its full size
+// next to the target, so that all the traffic can be switched to the target at once in a blue-green or
+// an A/B testing rollout
+type BlueGreenController interface {
+	// ScaleDownSource scales the source down to zero once the target is promoted
+	// it returns if the source is scaled down or should retry
+	ScaleDownSource(ctx context.Context) (bool, error)
+}
+
 type workloadController struct {
 	client           client.Client
 	recorder         event.Recorder
@@ -69,6 +88,12 @@ type workloadController struct {
 	rolloutStatus *v1alpha1.RolloutStatus
 }
 
+// leaseHolder is the identity used to lease the workloads to the parent controller so that the application
+// controller won't override the workloads spec while they are being rolled out
+func (c *workloadController) leaseHolder() string {
+	return util.GenWorkloadLeaseHolder(v1beta1.AppRolloutKind, c.parentController)
+}
+
 // cloneSetController is the place to hold fields needed for handle Cloneset type of workloads
 type cloneSetController struct {
 	workloadController
@@ -19,6 +19,7 @@ package workloads
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	apps "k8s.io/api/apps/v1"
@@ -35,6 +36,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // DeploymentRolloutController is responsible for handling rollout deployment type of workloads
@@ -90,6 +92,7 @@ func (c *DeploymentRolloutController) VerifySpec(ctx context.Context) (bool, err
 	// record the size and we will use this value to drive the rest of the batches
 	// we do not handle scale case in this controller
 	c.rolloutStatus.RolloutTargetSize = targetTotalReplicas
+	c.rolloutStatus.RolloutOriginalSize = getDeployReplicaSize(&c.sourceDeploy)
 
 	// make sure that the updateRevision is different from what we have already done
 	targetHash, verifyErr := utils.ComputeSpecHash(c.targetDeploy.Spec)
@@ -207,6 +210,14 @@ func (c *DeploymentRolloutController) CheckOneBatchPods(ctx context.Context) (bo
 		// nolint:nilerr
 		return false, nil
 	}
+	if err = c.renewLease(ctx, &c.sourceDeploy); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	if err = c.renewLease(ctx, &c.targetDeploy); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
 	// get the number of ready pod from target
 	readyTargetPodCount := c.targetDeploy.Status.ReadyReplicas
 	sourcePodCount := c.sourceDeploy.Status.Replicas
@@ -227,8 +238,10 @@ func (c *DeploymentRolloutController) CheckOneBatchPods(ctx context.Context) (bo
 		"max unavailable pod allowed", maxUnavail, "target goal", targetGoal, "source goal", sourceGoal,
 		"rolloutStrategy", rolloutStrategy)
 
+	// the source is kept at its full size until the target is promoted by some strategies
 	if (rolloutStrategy == v1alpha1.IncreaseFirstRolloutStrategyType && sourcePodCount > sourceGoal) ||
-		(rolloutStrategy == v1alpha1.DecreaseFirstRolloutStrategyType &&
+		((rolloutStrategy == v1alpha1.DecreaseFirstRolloutStrategyType ||
+			keepSource(rolloutStrategy)) &&
 			int32(maxUnavail)+readyTargetPodCount < targetGoal) {
 		// we haven't met the end goal of this batch, continue to verify
 		klog.InfoS("the batch is not ready yet", "current batch", c.rolloutStatus.CurrentBatch)
@@ -255,6 +268,16 @@ func (c *DeploymentRolloutController) FinalizeOneBatch(ctx context.Context) (boo
 	}
 	sourceTarget := getDeployReplicaSize(&c.sourceDeploy)
 	targetTarget := getDeployReplicaSize(&c.targetDeploy)
+	if keepSource(c.rolloutSpec.RolloutStrategy) {
+		// the source runs next to the whole target until the target is promoted
+		if targetTarget != c.rolloutStatus.RolloutTargetSize {
+			err = fmt.Errorf("the target deployment size %d doesn't match the total rollout %d",
+				targetTarget, c.rolloutStatus.RolloutTargetSize)
+			klog.ErrorS(err, "the batch is not valid", "current batch", c.rolloutStatus.CurrentBatch)
+			return false, err
+		}
+		return true, nil
+	}
 	if sourceTarget+targetTarget != c.rolloutStatus.RolloutTargetSize {
 		err = fmt.Errorf("deployment targets don't match total rollout, sourceTarget = %d, targetTarget = %d, "+
 			"rolloutTargetSize = %d", sourceTarget, targetTarget, c.rolloutStatus.RolloutTargetSize)
@@ -264,6 +287,60 @@ func (c *DeploymentRolloutController) FinalizeOneBatch(ctx context.Context) (boo
 	return true, nil
 }
 
+// Rollback scales the source Deployment back to its original size and the target Deployment down to zero
+func (c *DeploymentRolloutController) Rollback(ctx context.Context) (bool, error) {
+	err := c.fetchDeployments(ctx)
+	if err != nil {
+		// don't fail the rollback just because of we can't get the resource
+		// nolint:nilerr
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	originalSize := c.rolloutStatus.RolloutOriginalSize
+	if getDeployReplicaSize(&c.sourceDeploy) != originalSize {
+		if err = c.patchDeployment(ctx, originalSize, &c.sourceDeploy); err != nil {
+			return false, err
+		}
+	}
+	// keep the target until the source pods are back so that the capacity doesn't drop
+	if c.sourceDeploy.Status.ReadyReplicas < originalSize {
+		c.rolloutStatus.RolloutRetry(fmt.Sprintf("rolling back with %d source pods ready out of %d",
+			c.sourceDeploy.Status.ReadyReplicas, originalSize))
+		return false, nil
+	}
+	if getDeployReplicaSize(&c.targetDeploy) != 0 {
+		if err = c.patchDeployment(ctx, 0, &c.targetDeploy); err != nil {
+			return false, err
+		}
+	}
+	klog.InfoS("rolled back the deployments", "source deployment", c.sourceDeploy.GetName(),
+		"source size", originalSize, "target deployment", c.targetDeploy.GetName())
+	c.rolloutStatus.UpgradedReplicas = 0
+	c.rolloutStatus.UpgradedReadyReplicas = 0
+	return true, nil
+}
+
+// ScaleDownSource scales the source Deployment down to zero once the target is promoted
+func (c *DeploymentRolloutController) ScaleDownSource(ctx context.Context) (bool, error) {
+	err := c.fetchDeployments(ctx)
+	if err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		// nolint:nilerr
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	if getDeployReplicaSize(&c.sourceDeploy) != 0 {
+		if err = c.patchDeployment(ctx, 0, &c.sourceDeploy); err != nil {
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return false, nil
+		}
+		klog.InfoS("scaled down the source deployment", "source deployment", c.sourceDeploy.GetName())
+		c.recorder.Event(c.parentController, event.Normal("Source Scaled Down",
+			fmt.Sprintf("Scaled down the source deployment %s after the promotion", c.sourceDeploy.GetName())))
+	}
+	return true, nil
+}
+
 // Finalize makes sure the Deployment is all upgraded
 func (c *DeploymentRolloutController) Finalize(ctx context.Context, succeed bool) bool {
 	err := c.fetchDeployments(ctx)
@@ -314,6 +391,10 @@ func (c *DeploymentRolloutController) fetchDeployments(ctx context.Context) erro
 // before kicking start the update and start from every pod in the old version
 func (c *DeploymentRolloutController) claimDeployment(ctx context.Context, deploy *apps.Deployment, initSize *int32) error {
 	deployPatch := client.MergeFrom(deploy.DeepCopyObject())
+	// lease the deployment so that the application controller won't override it during the rollout
+	if err := util.AcquireWorkloadLease(deploy, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
 	if controller := metav1.GetControllerOf(deploy); controller == nil {
 		ref := metav1.NewControllerRef(c.parentController, v1beta1.AppRolloutKindVersionKind)
 		deploy.SetOwnerReferences(append(deploy.GetOwnerReferences(), *ref))
@@ -333,6 +414,10 @@ func (c *DeploymentRolloutController) claimDeployment(ctx context.Context, deplo
 // patch the deployment's target, returns if succeeded
 func (c *DeploymentRolloutController) patchDeployment(ctx context.Context, target int32, deploy *apps.Deployment) error {
 	deployPatch := client.MergeFrom(deploy.DeepCopyObject())
+	// renew the lease along with the patch
+	if err := util.AcquireWorkloadLease(deploy, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
 	deploy.Spec.Replicas = pointer.Int32Ptr(target)
 	// patch the Deployment
 	if err := c.client.Patch(ctx, deploy, deployPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
@@ -357,7 +442,8 @@ func (c *DeploymentRolloutController) releaseDeployment(ctx context.Context, dep
 		}
 		newOwnerList = append(newOwnerList, owner)
 	}
-	if !found {
+	leaseReleased := util.ReleaseWorkloadLease(deploy, c.leaseHolder())
+	if !found && !leaseReleased {
 		klog.InfoS("the deployment is already released", "deploy", deploy.Name)
 		return nil
 	}
@@ -371,6 +457,18 @@ func (c *DeploymentRolloutController) releaseDeployment(ctx context.Context, dep
 	return nil
 }
 
+// renewLease renews the deployment lease before it expires when we are waiting for pods to be ready
+func (c *DeploymentRolloutController) renewLease(ctx context.Context, deploy *apps.Deployment) error {
+	if !util.WorkloadLeaseNeedsRenew(deploy, c.leaseHolder(), time.Now()) {
+		return nil
+	}
+	deployPatch := client.MergeFrom(deploy.DeepCopyObject())
+	if err := util.AcquireWorkloadLease(deploy, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, deploy, deployPatch, client.FieldOwner(c.parentController.GetUID()))
+}
+
 // calculateRolloutTotalSize fetches the Deployment and returns the replicas (not the actual number of pods)
 func (c *DeploymentRolloutController) calculateRolloutTotalSize() (int32, error) {
 	sourceSize := getDeployReplicaSize(&c.sourceDeploy)
@@ -422,7 +520,8 @@ func (c *DeploymentRolloutController) rolloutBatchFirstHalf(ctx context.Context,
 			c.rolloutStatus.UpgradedReplicas = targetSize
 		}
 	}()
-	if rolloutStrategy == v1alpha1.IncreaseFirstRolloutStrategyType {
+	if rolloutStrategy == v1alpha1.IncreaseFirstRolloutStrategyType ||
+		keepSource(rolloutStrategy) {
 		// set the target replica first which should increase its size
 		if targetSize > getDeployReplicaSize(&c.targetDeploy) {
 			klog.InfoS("set target deployment replicas", "deploy", c.targetDeploy.Name, "targetSize", targetSize)
@@ -463,6 +562,10 @@ func (c *DeploymentRolloutController) rolloutBatchFirstHalf(ctx context.Context,
 func (c *DeploymentRolloutController) rolloutBatchSecondHalf(ctx context.Context,
 	rolloutStrategy v1alpha1.RolloutStrategyType, targetSize int32) bool {
 	var err error
+	if keepSource(rolloutStrategy) {
+		// the source keeps serving at its full size until the target is promoted
+		return true
+	}
 	sourceSize := c.calculateCurrentSource(c.rolloutStatus.RolloutTargetSize)
 	if rolloutStrategy == v1alpha1.IncreaseFirstRolloutStrategyType {
 		// calculate the max unavailable given the target size
@@ -0,0 +1,315 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// knativeTrafficTotal is the total traffic percent of a Knative Service, the replicas in the rollout batches
+// are treated as the traffic percent shifted to the new revision
+const knativeTrafficTotal = 100
+
+// KnativeServiceRolloutController is responsible for handling rollout of Knative Service. Knative Service keeps
+// all its revisions, so the rollout shifts the traffic from the serving revision to the latest one batch by batch.
+type KnativeServiceRolloutController struct {
+	workloadController
+	targetNamespacedName types.NamespacedName
+	service              *unstructured.Unstructured
+	sourceRevision       string
+	targetRevision       string
+}
+
+// NewKnativeServiceRolloutController creates a new Knative Service rollout controller
+func NewKnativeServiceRolloutController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus,
+	targetNamespacedName types.NamespacedName) *KnativeServiceRolloutController {
+	return &KnativeServiceRolloutController{
+		workloadController: workloadController{
+			client:           client,
+			recorder:         recorder,
+			parentController: parentController,
+			rolloutSpec:      rolloutSpec,
+			rolloutStatus:    rolloutStatus,
+		},
+		targetNamespacedName: targetNamespacedName,
+	}
+}
+
+// VerifySpec verifies that there is a new revision to shift the traffic to
+func (c *KnativeServiceRolloutController) VerifySpec(ctx context.Context) (bool, error) {
+	var verifyErr error
+
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if err := c.fetchService(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		// do not fail the rollout just because we can't get the resource
+		// nolint:nilerr
+		return false, nil
+	}
+	if len(c.targetRevision) == 0 {
+		c.rolloutStatus.RolloutRetry("the new revision of the knative service is not created yet")
+		return false, nil
+	}
+	if c.targetRevision == c.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, revision = %s", c.targetRevision)
+		return false, verifyErr
+	}
+	if len(c.sourceRevision) == 0 {
+		verifyErr = fmt.Errorf("the knative service %s has no revision serving the traffic except the new revision %s",
+			c.service.GetName(), c.targetRevision)
+		return false, verifyErr
+	}
+	if verifyErr = verifyBatchesWithRollout(c.rolloutSpec, knativeTrafficTotal); verifyErr != nil {
+		return false, verifyErr
+	}
+	c.rolloutStatus.RolloutTargetSize = knativeTrafficTotal
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Rollout Verified",
+		"Rollout spec and the Knative Service are verified"))
+	// record the new revision on success
+	c.rolloutStatus.NewPodTemplateIdentifier = c.targetRevision
+	return true, nil
+}
+
+// Initialize makes sure that all the traffic is on the source revision
+func (c *KnativeServiceRolloutController) Initialize(ctx context.Context) (bool, error) {
+	if err := c.fetchService(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	if err := c.patchTraffic(ctx, 0); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	// mark the rollout initialized
+	c.recorder.Event(c.parentController, event.Normal("Rollout Initialized", "Rollout resource are initialized"))
+	return true, nil
+}
+
+// RolloutOneBatchPods shifts the traffic of the current batch to the target revision
+func (c *KnativeServiceRolloutController) RolloutOneBatchPods(ctx context.Context) (bool, error) {
+	if err := c.fetchService(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		// nolint:nilerr
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	percent := c.calculateCurrentTarget()
+	if err := c.patchTraffic(ctx, percent); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	klog.InfoS("shifted traffic for one batch", "current batch", c.rolloutStatus.CurrentBatch,
+		"target revision", c.targetRevision, "traffic percent", percent)
+	c.recorder.Event(c.parentController, event.Normal("Batch Rollout",
+		fmt.Sprintf("Shifted %d%% traffic to revision %s for batch %d", percent, c.targetRevision,
+			c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.UpgradedReplicas = percent
+	return true, nil
+}
+
+// CheckOneBatchPods checks that the Knative Service is ready and routes the traffic as expected
+func (c *KnativeServiceRolloutController) CheckOneBatchPods(ctx context.Context) (bool, error) {
+	if err := c.fetchService(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		// nolint:nilerr
+		return false, nil
+	}
+	if err := c.renewLease(ctx); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	percent := c.calculateCurrentTarget()
+	ready, diagnosis := util.GetKnativeServiceReadiness(c.service)
+	if !ready || c.routedPercent() != percent {
+		klog.InfoS("the batch is not ready yet", "current batch", c.rolloutStatus.CurrentBatch,
+			"expected traffic percent", percent, "routed traffic percent", c.routedPercent())
+		c.rolloutStatus.RolloutRetry(fmt.Sprintf(
+			"the batch %d is not ready yet with %d%% traffic routed to revision %s %s",
+			c.rolloutStatus.CurrentBatch, c.routedPercent(), c.targetRevision, diagnosis))
+		return false, nil
+	}
+	c.rolloutStatus.UpgradedReadyReplicas = percent
+	klog.InfoS("the traffic of current batch is routed", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Batch Available",
+		fmt.Sprintf("Batch %d is available", c.rolloutStatus.CurrentBatch)))
+	return true, nil
+}
+
+// Rollback routes all the traffic back to the source revision
+func (c *KnativeServiceRolloutController) Rollback(ctx context.Context) (bool, error) {
+	if err := c.fetchService(ctx); err != nil {
+		// nolint:nilerr
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false, nil
+	}
+	if err := c.patchTraffic(ctx, 0); err != nil {
+		return false, err
+	}
+	klog.InfoS("routed the traffic back to the source revision", "source revision", c.sourceRevision)
+	c.rolloutStatus.UpgradedReplicas = 0
+	c.rolloutStatus.UpgradedReadyReplicas = 0
+	return true, nil
+}
+
+// FinalizeOneBatch has nothing to do as the traffic is shifted atomically
+func (c *KnativeServiceRolloutController) FinalizeOneBatch(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// Finalize routes all the traffic to the latest revision if the rollout succeeded,
+// otherwise the traffic is routed back to the source revision
+func (c *KnativeServiceRolloutController) Finalize(ctx context.Context, succeed bool) bool {
+	if err := c.fetchService(ctx); err != nil {
+		// don't fail the rollout just because of we can't get the resource
+		return false
+	}
+	svcPatch := client.MergeFrom(c.service.DeepCopyObject())
+	traffic := []util.KnativeTrafficTarget{{LatestRevision: pointer.BoolPtr(true), Percent: knativeTrafficTotal}}
+	if !succeed && len(c.sourceRevision) != 0 {
+		traffic = []util.KnativeTrafficTarget{{RevisionName: c.sourceRevision, LatestRevision: pointer.BoolPtr(false),
+			Percent: knativeTrafficTotal}}
+	}
+	if err := util.SetKnativeTraffic(c.service, traffic); err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	util.ReleaseWorkloadLease(c.service, c.leaseHolder())
+	if err := c.client.Patch(ctx, c.service, svcPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to the finalize the Knative Service", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return false
+	}
+	// mark the resource finalized
+	c.rolloutStatus.LastAppliedPodTemplateIdentifier = c.rolloutStatus.NewPodTemplateIdentifier
+	c.recorder.Event(c.parentController, event.Normal("Rollout Finalized",
+		fmt.Sprintf("Rollout resource are finalized, succeed := %t", succeed)))
+	return true
+}
+
+/* ----------------------------------
+The functions below are helper functions
+------------------------------------- */
+func (c *KnativeServiceRolloutController) fetchService(ctx context.Context) error {
+	svc, err := util.GetObjectGivenGVKAndName(ctx, c.client, util.KnativeServiceGVK, c.targetNamespacedName.Namespace,
+		c.targetNamespacedName.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(errors.Cause(err)) {
+			c.recorder.Event(c.parentController, event.Warning("Failed to get the Knative Service", err))
+		}
+		return err
+	}
+	c.service = svc
+	// the latest revision is named by the template if it's specified, see util.SetKnativeRevisionName
+	c.targetRevision, _, _ = unstructured.NestedString(svc.Object, "spec", "template", "metadata", "name")
+	if len(c.targetRevision) == 0 {
+		c.targetRevision, _, _ = unstructured.NestedString(svc.Object, "status", "latestCreatedRevisionName")
+	}
+	// the source revision is the one holding the most traffic except the target
+	traffic, err := util.GetKnativeTraffic(svc)
+	if err != nil {
+		return err
+	}
+	c.sourceRevision = ""
+	var sourcePercent int64 = -1
+	for _, t := range traffic {
+		if len(t.RevisionName) != 0 && t.RevisionName != c.targetRevision && t.Percent > sourcePercent {
+			c.sourceRevision, sourcePercent = t.RevisionName, t.Percent
+		}
+	}
+	if len(c.sourceRevision) == 0 {
+		c.sourceRevision, _, _ = unstructured.NestedString(svc.Object, "status", "latestReadyRevisionName")
+		if c.sourceRevision == c.targetRevision {
+			c.sourceRevision = ""
+		}
+	}
+	return nil
+}
+
+// patchTraffic splits the traffic between the source and the target revision, it also leases the service
+// so that the application controller won't override the traffic during the rollout
+func (c *KnativeServiceRolloutController) patchTraffic(ctx context.Context, targetPercent int32) error {
+	svcPatch := client.MergeFrom(c.service.DeepCopyObject())
+	if err := util.AcquireWorkloadLease(c.service, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
+	if err := util.SetKnativeTraffic(c.service, []util.KnativeTrafficTarget{
+		{RevisionName: c.sourceRevision, LatestRevision: pointer.BoolPtr(false), Percent: int64(knativeTrafficTotal - targetPercent)},
+		{RevisionName: c.targetRevision, LatestRevision: pointer.BoolPtr(false), Percent: int64(targetPercent)},
+	}); err != nil {
+		return err
+	}
+	if err := c.client.Patch(ctx, c.service, svcPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning(event.Reason(fmt.Sprintf(
+			"Failed to shift %d%% traffic to revision %s", targetPercent, c.targetRevision)), err))
+		return err
+	}
+	return nil
+}
+
+// renewLease renews the service lease before it expires when we are waiting for the traffic to be routed
+func (c *KnativeServiceRolloutController) renewLease(ctx context.Context) error {
+	if !util.WorkloadLeaseNeedsRenew(c.service, c.leaseHolder(), time.Now()) {
+		return nil
+	}
+	svcPatch := client.MergeFrom(c.service.DeepCopyObject())
+	if err := util.AcquireWorkloadLease(c.service, c.leaseHolder(), time.Now()); err != nil {
+		return err
+	}
+	return c.client.Patch(ctx, c.service, svcPatch, client.FieldOwner(c.parentController.GetUID()))
+}
+
+// routedPercent returns the traffic percent actually routed to the target revision
+func (c *KnativeServiceRolloutController) routedPercent() int32 {
+	traffic, _, _ := unstructured.NestedSlice(c.service.Object, "status", "traffic")
+	var percent int64
+	for _, t := range traffic {
+		if target, ok := t.(map[string]interface{}); ok && target["revisionName"] == c.targetRevision {
+			p, _, _ := unstructured.NestedInt64(target, "percent")
+			percent += p
+		}
+	}
+	return int32(percent)
+}
+
+// the traffic percent of the target revision for the current batch
+func (c *KnativeServiceRolloutController) calculateCurrentTarget() int32 {
+	return int32(calculateNewBatchTarget(c.rolloutSpec, 0, knativeTrafficTotal, int(c.rolloutStatus.CurrentBatch)))
+}
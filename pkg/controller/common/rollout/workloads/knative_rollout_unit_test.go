@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workloads
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestKnativeTrafficOfBatch(t *testing.T) {
+	cases := map[string]struct {
+		currentBatch int32
+		routed       []interface{}
+		wantTarget   int32
+		wantRouted   int32
+	}{
+		"FirstBatch": {
+			currentBatch: 0,
+			routed: []interface{}{
+				map[string]interface{}{"revisionName": "web-v1", "percent": int64(100)},
+				map[string]interface{}{"revisionName": "web-v2", "percent": int64(0)},
+			},
+			wantTarget: 20,
+			wantRouted: 0,
+		},
+		"SecondBatch": {
+			currentBatch: 1,
+			routed: []interface{}{
+				map[string]interface{}{"revisionName": "web-v1", "percent": int64(40)},
+				map[string]interface{}{"revisionName": "web-v2", "percent": int64(60)},
+			},
+			wantTarget: 60,
+			wantRouted: 60,
+		},
+		"LastBatch": {
+			currentBatch: 3,
+			routed: []interface{}{
+				map[string]interface{}{"revisionName": "web-v2", "percent": int64(100)},
+			},
+			wantTarget: 100,
+			wantRouted: 100,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			_ = unstructured.SetNestedSlice(svc.Object, tc.routed, "status", "traffic")
+			controller := KnativeServiceRolloutController{
+				workloadController: workloadController{
+					rolloutSpec: rolloutPercentSpec,
+					rolloutStatus: &v1alpha1.RolloutStatus{
+						CurrentBatch: tc.currentBatch,
+					},
+				},
+				service:        svc,
+				targetRevision: "web-v2",
+			}
+			if got := controller.calculateCurrentTarget(); got != tc.wantTarget {
+				t.Errorf("calculateCurrentTarget(): want %d, got %d", tc.wantTarget, got)
+			}
+			if got := controller.routedPercent(); got != tc.wantRouted {
+				t.Errorf("routedPercent(): want %d, got %d", tc.wantRouted, got)
+			}
+		})
+	}
+}
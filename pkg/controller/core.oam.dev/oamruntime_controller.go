@@ -17,8 +17,16 @@ limitations under the License.
 package core_oam_dev
 
 import (
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/utils/requeue"
+	"github.com/oam-dev/kubevela/pkg/utils/sharding"
+	"github.com/oam-dev/kubevela/pkg/utils/speclimit"
 )
 
 // ApplyOnceOnlyMode enumerates ApplyOnceOnly modes.
@@ -52,6 +60,22 @@ type Args struct {
 	// The default value is 10.
 	AppRevisionLimit int
 
+	// AppRevisionManifestStore is the type of the backend the rendered manifests of application revisions are moved
+	// to if they're larger than AppRevisionManifestThreshold, they're kept in the revisions if it's empty.
+	AppRevisionManifestStore string
+
+	// AppRevisionManifestThreshold is the size in bytes of the rendered manifests above which they're moved out of
+	// the application revisions.
+	AppRevisionManifestThreshold int
+
+	// AppRevisionManifestCompression is the algorithm to compress the rendered manifests of application revisions
+	// by, i.e., gzip or zstd, they're not compressed if it's empty.
+	AppRevisionManifestCompression string
+
+	// ResourceTrackerCompression is the algorithm to compress the resources tracked by the ResourceTrackers by,
+	// i.e., gzip or zstd, they're not compressed if it's empty.
+	ResourceTrackerCompression string
+
 	// DefRevisionLimit is the maximum number of component/trait definition revisions that will be maintained.
 	// The default value is 20.
 	DefRevisionLimit int
@@ -60,10 +84,64 @@ type Args struct {
 	// affected if no spec change is made in the ApplicationConfiguration.
 	ApplyMode ApplyOnceOnlyMode
 
+	// EnableServerSideApply indicates whether to apply resources with server-side apply by default,
+	// it can be overridden by the `app.oam.dev/apply-strategy` annotation of an application.
+	EnableServerSideApply bool
+
+	// EnableAdmissionPreflight indicates whether to check the rendered resources of applications by server-side
+	// dry-run before any of them is written, it can be overridden by the `app.oam.dev/admission-preflight`
+	// annotation of an application.
+	EnableAdmissionPreflight bool
+
 	// CustomRevisionHookURL is a webhook which will let oam-runtime to call with AC+Component info
 	// The webhook server will return a customized component revision for oam-runtime
 	CustomRevisionHookURL string
 
+	// ManifestSigningKeySecret is the secret `namespace/name` holding the key to sign the rendered manifests of
+	// application revisions, the signature is verified before the manifests are applied. Signing is disabled if empty.
+	ManifestSigningKeySecret string
+
+	// HelmWorkloadDiscoveryTimeout is the duration to wait for Helm to create the workloads of Helm-based components
+	// since their HelmReleases are created, the components are pending until then and fail afterwards
+	HelmWorkloadDiscoveryTimeout time.Duration
+
+	// AppSpecLimits are the limits of the size and complexity of applications, they're enforced on admission and
+	// before the rendered resources are applied
+	AppSpecLimits speclimit.Limits
+
+	// DefinitionHealthInterval is the interval to check the health of the installed definitions, the health checks
+	// are disabled if it's zero
+	DefinitionHealthInterval time.Duration
+
+	// MeteringInterval is the interval to collect the usage of applications per namespace and team as UsageReports,
+	// the metering is disabled if it's zero
+	MeteringInterval time.Duration
+	// MeteringHistoryLimit is the maximum number of UsageReports kept, the oldest ones are deleted first
+	MeteringHistoryLimit int
+
+	// ApplyRateLimit limits how fast and how many rendered resources are applied at the same time, so applying
+	// large applications won't flood the API servers
+	ApplyRateLimit apply.RateLimit
+	// ApplyRateLimiter is built from ApplyRateLimit and shared by the controllers applying resources to the
+	// control plane cluster, the rate is not limited if it's nil
+	ApplyRateLimiter flowcontrol.RateLimiter
+
+	// RequireImpersonation requires the applications and ApplicationConfigurations to declare the identities to
+	// impersonate to apply their resources, they're never applied with the permissions of the controller
+	RequireImpersonation bool
+
+	// Sharding is the shard this controller replica runs as, only the applications scheduled to the shard are
+	// reconciled by non-master replicas
+	Sharding sharding.Options
+
+	// ApplicationRequeue are the intervals to reconcile the applications again, the applications executing their
+	// workflows or not healthy are checked again shortly, the healthy ones less and less often
+	ApplicationRequeue requeue.Intervals
+
+	// EventDedupInterval is the interval the same event of an object is recorded at most once in by the application
+	// controllers, the events are not deduplicated if it's zero
+	EventDedupInterval time.Duration
+
 	// DiscoveryMapper used for CRD discovery in controller, a K8s client is contained in it.
 	DiscoveryMapper discoverymapper.DiscoveryMapper
 	// PackageDiscover used for CRD discovery in CUE packages, a K8s client is contained in it.
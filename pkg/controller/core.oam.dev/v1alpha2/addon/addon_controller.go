@@ -0,0 +1,302 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/addon"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+const (
+	addonFinalizer   = "finalizers.addon.oam.dev"
+	reconcileTimeout = time.Minute
+	// pendingInterval is the interval to check the addons waiting for their dependencies or applications
+	pendingInterval = 10 * time.Second
+	// syncInterval is the interval to sync the enabled addons with the catalog to pick up the new versions
+	syncInterval = 10 * time.Minute
+)
+
+// Reconciler enables the addons from the catalogs. The addons depended on are enabled first, then the definitions
+// and auxiliary resources of the addon are rendered as an application owned by the Addon.
+type Reconciler struct {
+	Client   client.Client
+	Recorder event.Recorder
+	// reader reads the Secrets of the catalog credentials from the API server directly, so no informer caching all
+	// Secrets in the cluster is started
+	reader client.Reader
+	// newCatalog creates the catalog of the spec with the credentials, which can be nil
+	newCatalog func(spec v1beta1.AddonCatalog, cred *oci.Credentials) (addon.Catalog, error)
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=addons,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=addons/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Reconcile enables, upgrades or disables the addon
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	a := &v1beta1.Addon{}
+	if err := r.Client.Get(ctx, req.NamespacedName, a); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !a.DeletionTimestamp.IsZero() {
+		return r.disable(ctx, a)
+	}
+	if !meta.FinalizerExists(&a.ObjectMeta, addonFinalizer) {
+		meta.AddFinalizer(&a.ObjectMeta, addonFinalizer)
+		if err := r.Client.Update(ctx, a); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	wasEnabled := a.Status.Phase == v1beta1.AddonEnabled && a.Status.Version == a.Status.TargetVersion
+	requeue, err := r.enable(ctx, a)
+	now := metav1.Now()
+	a.Status.LastSyncTime = &now
+	if err != nil {
+		klog.InfoS("Failed to enable addon", "addon", klog.KObj(a), "err", err)
+		r.Recorder.Event(a, event.Warning(velatypes.ReasonAddonFailed, err))
+		a.Status.Phase, a.Status.Message = v1beta1.AddonFailed, err.Error()
+		requeue = pendingInterval
+	}
+	if err := r.Client.Status().Update(ctx, a); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !wasEnabled && a.Status.Phase == v1beta1.AddonEnabled {
+		klog.InfoS("Addon enabled", "addon", klog.KObj(a), "version", a.Status.Version)
+		r.Recorder.Event(a, event.Normal(velatypes.ReasonAddonEnabled, fmt.Sprintf(velatypes.MessageAddonEnabled, a.Status.Version)))
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// enable resolves the addon in the catalog, waits for the addons it depends on to be enabled and applies the
+// application of the addon. The status is updated in place, it returns when to check the addon again.
+func (r *Reconciler) enable(ctx context.Context, a *v1beta1.Addon) (time.Duration, error) {
+	catalog, err := r.catalog(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	versions, err := catalog.Versions(ctx, a.Name)
+	if err != nil {
+		return 0, err
+	}
+	a.Status.LatestVersion = addon.LatestVersion(versions)
+	// the whole dependency tree is resolved to fail fast on the circular or conflicting dependencies, the addons
+	// depended on are enabled by their own Addons though
+	resolved, err := addon.ResolveDependencies(ctx, catalog, a.Name, a.Spec.Version)
+	if err != nil {
+		return 0, err
+	}
+	target := resolved[len(resolved)-1]
+	a.Status.TargetVersion = target.Version
+	a.Status.Dependencies = nil
+	for _, d := range target.Dependencies {
+		a.Status.Dependencies = append(a.Status.Dependencies, d.Name)
+	}
+	setPending := func(message string) {
+		a.Status.Phase, a.Status.Message = v1beta1.AddonEnabling, message
+		if len(a.Status.Version) != 0 {
+			a.Status.Phase = v1beta1.AddonUpgrading
+		}
+	}
+
+	var waiting []string
+	for _, d := range target.Dependencies {
+		ready, err := r.ensureDependency(ctx, a, d)
+		if err != nil {
+			return 0, err
+		}
+		if ready {
+			continue
+		}
+		if len(d.Version) != 0 {
+			waiting = append(waiting, d.Name+"@"+d.Version)
+		} else {
+			waiting = append(waiting, d.Name)
+		}
+	}
+	if len(waiting) != 0 {
+		setPending(fmt.Sprintf("waiting for addons %s to be enabled", strings.Join(waiting, ", ")))
+		return pendingInterval, nil
+	}
+
+	app, err := r.applyApplication(ctx, a, target.Addon)
+	if err != nil {
+		return 0, err
+	}
+	a.Status.Application = app.Name
+	if app.Status.Phase != common.ApplicationRunning || app.Status.ObservedGeneration != app.Generation {
+		setPending(fmt.Sprintf("waiting for application %s to be running", app.Name))
+		return pendingInterval, nil
+	}
+	a.Status.Phase, a.Status.Message = v1beta1.AddonEnabled, ""
+	a.Status.Version = target.Version
+	return syncInterval, nil
+}
+
+// ensureDependency enables the addon depended on from the same catalog if it's not enabled yet, it returns whether
+// the addon is enabled with a version satisfying the constraint of the dependency
+func (r *Reconciler) ensureDependency(ctx context.Context, a *v1beta1.Addon, d addon.Dependency) (bool, error) {
+	dep := &v1beta1.Addon{}
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: a.Namespace, Name: d.Name}, dep)
+	if apierrors.IsNotFound(err) {
+		dep = &v1beta1.Addon{
+			ObjectMeta: metav1.ObjectMeta{Namespace: a.Namespace, Name: d.Name},
+			Spec:       v1beta1.AddonSpec{Catalog: a.Spec.Catalog, Version: d.Version},
+		}
+		klog.InfoS("Enable addon depended on", "addon", klog.KObj(a), "dependency", d.Name)
+		return false, errors.Wrapf(r.Client.Create(ctx, dep), "cannot enable addon %s depended on", d.Name)
+	}
+	if err != nil {
+		return false, err
+	}
+	if !dep.DeletionTimestamp.IsZero() {
+		return false, errors.Errorf("addon %s depended on is being disabled", d.Name)
+	}
+	return dep.Status.Phase == v1beta1.AddonEnabled && addon.Satisfies(dep.Status.Version, d.Version), nil
+}
+
+// applyApplication creates or updates the application rendered from the addon, it returns the application applied
+func (r *Reconciler) applyApplication(ctx context.Context, a *v1beta1.Addon, content *addon.Addon) (*v1beta1.Application, error) {
+	desired, err := addon.RenderApplication(content, a.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	desired.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(a, v1beta1.AddonGroupVersionKind)})
+	app := &v1beta1.Application{}
+	err = r.Client.Get(ctx, types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, app)
+	if apierrors.IsNotFound(err) {
+		return desired, errors.Wrapf(r.Client.Create(ctx, desired), "cannot create application %s", desired.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if app.Annotations[oam.AnnotationAddonVersion] == content.Version && metav1.IsControlledBy(app, a) {
+		return app, nil
+	}
+	app.Labels, app.Annotations = mergeMap(app.Labels, desired.Labels), mergeMap(app.Annotations, desired.Annotations)
+	app.OwnerReferences = desired.OwnerReferences
+	app.Spec = desired.Spec
+	klog.InfoS("Update addon application", "addon", klog.KObj(a), "application", app.Name, "version", content.Version)
+	return app, errors.Wrapf(r.Client.Update(ctx, app), "cannot update application %s", app.Name)
+}
+
+// disable deletes the application of the addon once no other addon depends on it
+func (r *Reconciler) disable(ctx context.Context, a *v1beta1.Addon) (ctrl.Result, error) {
+	if !meta.FinalizerExists(&a.ObjectMeta, addonFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	addons := &v1beta1.AddonList{}
+	if err := r.Client.List(ctx, addons, client.InNamespace(a.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	var dependents []string
+	for _, other := range addons.Items {
+		for _, d := range other.Status.Dependencies {
+			if d == a.Name {
+				dependents = append(dependents, other.Name)
+			}
+		}
+	}
+	if len(dependents) != 0 {
+		a.Status.Phase = v1beta1.AddonDisabling
+		a.Status.Message = fmt.Sprintf("waiting for addons %s depending on it to be disabled", strings.Join(dependents, ", "))
+		return ctrl.Result{RequeueAfter: pendingInterval}, r.Client.Status().Update(ctx, a)
+	}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: a.Namespace, Name: addon.ApplicationName(a.Name)}}
+	if err := r.Client.Delete(ctx, app); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrapf(err, "cannot delete application %s", app.Name)
+	}
+	klog.InfoS("Addon disabled", "addon", klog.KObj(a))
+	meta.RemoveFinalizer(&a.ObjectMeta, addonFinalizer)
+	return ctrl.Result{}, r.Client.Update(ctx, a)
+}
+
+// catalog creates the catalog of the addon with the credentials in its Secret
+func (r *Reconciler) catalog(ctx context.Context, a *v1beta1.Addon) (addon.Catalog, error) {
+	spec := a.Spec.Catalog
+	var cred *oci.Credentials
+	if spec.SecretRef != nil && len(spec.SecretRef.Name) != 0 {
+		secret := &corev1.Secret{}
+		if err := r.reader.Get(ctx, types.NamespacedName{Namespace: a.Namespace, Name: spec.SecretRef.Name}, secret); err != nil {
+			return nil, errors.Wrapf(err, "cannot get the secret %s of the catalog", spec.SecretRef.Name)
+		}
+		var err error
+		if cred, err = oci.CredentialsFromSecret(secret, catalogHost(spec.URL)); err != nil {
+			return nil, err
+		}
+	}
+	return r.newCatalog(spec, cred)
+}
+
+// catalogHost returns the host of the catalog URL, e.g., ghcr.io of oci://ghcr.io/org/addons
+func catalogHost(u string) string {
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	return strings.SplitN(u, "/", 2)[0]
+}
+
+func mergeMap(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = map[string]string{}
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// SetupWithManager will setup with event recorder
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = event.NewAPIRecorder(mgr.GetEventRecorderFor("Addon"))
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.Addon{}).
+		Owns(&v1beta1.Application{}).
+		Complete(r)
+}
+
+// Setup adds a controller that enables Addons from the catalogs
+func Setup(mgr ctrl.Manager, _ controller.Args, _ logging.Logger) error {
+	r := &Reconciler{Client: mgr.GetClient(), reader: mgr.GetAPIReader(), newCatalog: addon.NewCatalog}
+	return r.SetupWithManager(mgr)
+}
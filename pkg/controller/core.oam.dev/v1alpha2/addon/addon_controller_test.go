@@ -0,0 +1,255 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/addon"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+// fakeCatalog is the catalog of the addons in memory keyed by name and version
+type fakeCatalog map[string]map[string]*addon.Addon
+
+func (c fakeCatalog) add(name, version string, deps ...addon.Dependency) {
+	if c[name] == nil {
+		c[name] = map[string]*addon.Addon{}
+	}
+	def := &unstructured.Unstructured{}
+	def.SetAPIVersion("core.oam.dev/v1beta1")
+	def.SetKind("TraitDefinition")
+	def.SetName(name)
+	c[name][version] = &addon.Addon{
+		Metadata:    addon.Metadata{Name: name, Version: version, Dependencies: deps},
+		Definitions: []*unstructured.Unstructured{def},
+	}
+}
+
+func (c fakeCatalog) List(context.Context) ([]string, error) {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (c fakeCatalog) Versions(_ context.Context, name string) ([]string, error) {
+	if c[name] == nil {
+		return nil, &addon.NotFoundError{Name: name}
+	}
+	var versions []string
+	for v := range c[name] {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (c fakeCatalog) Get(_ context.Context, name, version string) (*addon.Addon, error) {
+	a, ok := c[name][version]
+	if !ok {
+		return nil, &addon.NotFoundError{Name: name, Version: version}
+	}
+	return a, nil
+}
+
+var _ = Describe("Test addon controller", func() {
+	const ns = "vela-system"
+	ctx := context.Background()
+	catalogSpec := v1beta1.AddonCatalog{Type: v1beta1.AddonCatalogHTTP, URL: "https://addons.example.com"}
+	var (
+		c       client.Client
+		r       *Reconciler
+		catalog fakeCatalog
+	)
+
+	BeforeEach(func() {
+		c = fake.NewFakeClientWithScheme(common.Scheme, &v1beta1.Addon{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "ingress"},
+			Spec:       v1beta1.AddonSpec{Catalog: catalogSpec, Version: "^1.0"},
+		})
+		catalog = fakeCatalog{}
+		catalog.add("cert-manager", "1.0.0")
+		catalog.add("cert-manager", "1.1.0")
+		catalog.add("cert-manager", "2.0.0")
+		catalog.add("ingress", "1.0.0", addon.Dependency{Name: "cert-manager", Version: "^1.0"})
+		catalog.add("ingress", "2.0.0", addon.Dependency{Name: "cert-manager", Version: "^2.0"})
+		r = &Reconciler{Client: c, reader: c, Recorder: event.NewNopRecorder(),
+			newCatalog: func(spec v1beta1.AddonCatalog, cred *oci.Credentials) (addon.Catalog, error) {
+				Expect(spec).Should(Equal(catalogSpec))
+				Expect(cred).Should(BeNil())
+				return catalog, nil
+			}}
+	})
+
+	reconcile := func(name string) ctrl.Result {
+		res, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: ns, Name: name}})
+		Expect(err).Should(BeNil())
+		return res
+	}
+	getAddon := func(name string) *v1beta1.Addon {
+		a := &v1beta1.Addon{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, a)).Should(Succeed())
+		return a
+	}
+	getApp := func(name string) *v1beta1.Application {
+		app := &v1beta1.Application{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: ns, Name: addon.ApplicationName(name)}, app)).Should(Succeed())
+		return app
+	}
+	setAppPhase := func(name string, phase commontypes.ApplicationPhase) {
+		app := getApp(name)
+		app.Status.Phase = phase
+		app.Status.ObservedGeneration = app.Generation
+		Expect(c.Status().Update(ctx, app)).Should(Succeed())
+	}
+	enable := func(name string) {
+		reconcile(name)
+		setAppPhase(name, commontypes.ApplicationRunning)
+		Expect(reconcile(name).RequeueAfter).Should(Equal(syncInterval))
+		Expect(getAddon(name).Status.Phase).Should(Equal(v1beta1.AddonEnabled))
+	}
+	enableAll := func() {
+		// the Addon of cert-manager is created by the Addon of ingress
+		reconcile("ingress")
+		enable("cert-manager")
+		enable("ingress")
+	}
+
+	It("enable the addon after the addons it depends on", func() {
+		By("Enable the addon depended on from the same catalog")
+		Expect(reconcile("ingress").RequeueAfter).Should(Equal(pendingInterval))
+		ingress := getAddon("ingress")
+		Expect(ingress.Finalizers).Should(ContainElement(addonFinalizer))
+		Expect(ingress.Status.Phase).Should(Equal(v1beta1.AddonEnabling))
+		Expect(ingress.Status.TargetVersion).Should(Equal("1.0.0"))
+		Expect(ingress.Status.LatestVersion).Should(Equal("2.0.0"))
+		Expect(ingress.Status.Dependencies).Should(Equal([]string{"cert-manager"}))
+		Expect(ingress.Status.Message).Should(ContainSubstring("waiting for addons cert-manager"))
+		Expect(ingress.Status.LastSyncTime).ShouldNot(BeNil())
+		certManager := getAddon("cert-manager")
+		Expect(certManager.Spec).Should(Equal(v1beta1.AddonSpec{Catalog: catalogSpec, Version: "^1.0"}))
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: ns, Name: addon.ApplicationName("ingress")}, &v1beta1.Application{})).ShouldNot(Succeed())
+
+		By("Render the addon depended on as an application")
+		Expect(reconcile("cert-manager").RequeueAfter).Should(Equal(pendingInterval))
+		Expect(getAddon("cert-manager").Status.Phase).Should(Equal(v1beta1.AddonEnabling))
+		app := getApp("cert-manager")
+		Expect(app.Annotations[oam.AnnotationAddonVersion]).Should(Equal("1.1.0"))
+		Expect(app.Labels[oam.LabelAddonName]).Should(Equal("cert-manager"))
+		Expect(metav1.IsControlledBy(app, getAddon("cert-manager"))).Should(BeTrue())
+		Expect(app.Spec.Components).Should(HaveLen(1))
+		Expect(app.Spec.Components[0].Type).Should(Equal(addon.RawComponentType))
+		setAppPhase("cert-manager", commontypes.ApplicationRunning)
+		reconcile("cert-manager")
+		certManager = getAddon("cert-manager")
+		Expect(certManager.Status.Phase).Should(Equal(v1beta1.AddonEnabled))
+		Expect(certManager.Status.Version).Should(Equal("1.1.0"))
+		Expect(certManager.Status.Application).Should(Equal("addon-cert-manager"))
+
+		By("Enable the addon once the addons it depends on are enabled")
+		enable("ingress")
+		Expect(getAddon("ingress").Status.Version).Should(Equal("1.0.0"))
+	})
+
+	It("upgrade the addon once a newer version matching the constraint is published", func() {
+		enableAll()
+		catalog.add("ingress", "1.1.0", addon.Dependency{Name: "cert-manager", Version: "^1.0"})
+		setAppPhase("ingress", commontypes.ApplicationRendering)
+		Expect(reconcile("ingress").RequeueAfter).Should(Equal(pendingInterval))
+		ingress := getAddon("ingress")
+		Expect(ingress.Status.Phase).Should(Equal(v1beta1.AddonUpgrading))
+		Expect(ingress.Status.Version).Should(Equal("1.0.0"))
+		Expect(ingress.Status.TargetVersion).Should(Equal("1.1.0"))
+		Expect(getApp("ingress").Annotations[oam.AnnotationAddonVersion]).Should(Equal("1.1.0"))
+
+		setAppPhase("ingress", commontypes.ApplicationRunning)
+		Expect(reconcile("ingress").RequeueAfter).Should(Equal(syncInterval))
+		Expect(getAddon("ingress").Status.Version).Should(Equal("1.1.0"))
+	})
+
+	It("wait for the addon depended on to be upgraded to the version required", func() {
+		enableAll()
+		ingress := getAddon("ingress")
+		ingress.Spec.Version = "^2.0"
+		Expect(c.Update(ctx, ingress)).Should(Succeed())
+		Expect(reconcile("ingress").RequeueAfter).Should(Equal(pendingInterval))
+		ingress = getAddon("ingress")
+		Expect(ingress.Status.Phase).Should(Equal(v1beta1.AddonUpgrading))
+		Expect(ingress.Status.Message).Should(ContainSubstring("waiting for addons cert-manager@^2.0"))
+		Expect(getApp("ingress").Annotations[oam.AnnotationAddonVersion]).Should(Equal("1.0.0"))
+	})
+
+	It("report the addon failed to be resolved", func() {
+		catalog.add("cert-manager", "1.2.0", addon.Dependency{Name: "ingress"})
+		Expect(reconcile("ingress").RequeueAfter).Should(Equal(pendingInterval))
+		ingress := getAddon("ingress")
+		Expect(ingress.Status.Phase).Should(Equal(v1beta1.AddonFailed))
+		Expect(ingress.Status.Message).Should(ContainSubstring("circular dependency of addons: ingress -> cert-manager -> ingress"))
+
+		delete(catalog, "ingress")
+		reconcile("ingress")
+		Expect(getAddon("ingress").Status.Message).Should(ContainSubstring("addon ingress is not found in the catalog"))
+	})
+
+	It("disable the addon after the addons depending on it", func() {
+		enableAll()
+		deleting := func(name string) {
+			a := getAddon(name)
+			now := metav1.NewTime(time.Now())
+			a.DeletionTimestamp = &now
+			Expect(c.Update(ctx, a)).Should(Succeed())
+		}
+
+		deleting("cert-manager")
+		Expect(reconcile("cert-manager").RequeueAfter).Should(Equal(pendingInterval))
+		certManager := getAddon("cert-manager")
+		Expect(certManager.Status.Phase).Should(Equal(v1beta1.AddonDisabling))
+		Expect(certManager.Status.Message).Should(ContainSubstring("ingress"))
+		getApp("cert-manager")
+
+		deleting("ingress")
+		reconcile("ingress")
+		Expect(getAddon("ingress").Finalizers).ShouldNot(ContainElement(addonFinalizer))
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: ns, Name: addon.ApplicationName("ingress")}, &v1beta1.Application{})).ShouldNot(Succeed())
+		Expect(c.Delete(ctx, getAddon("ingress"))).Should(Succeed())
+
+		reconcile("cert-manager")
+		Expect(getAddon("cert-manager").Finalizers).ShouldNot(ContainElement(addonFinalizer))
+	})
+
+	It("take the host of the catalog as the registry of the credentials", func() {
+		Expect(catalogHost("oci://ghcr.io/org/addons")).Should(Equal("ghcr.io"))
+		Expect(catalogHost("https://addons.example.com/catalog")).Should(Equal("addons.example.com"))
+		Expect(catalogHost("ghcr.io/org/addons")).Should(Equal("ghcr.io"))
+	})
+})
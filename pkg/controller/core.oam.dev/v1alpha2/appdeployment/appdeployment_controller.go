@@ -41,6 +41,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/clustermanager"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/utils/apply"
 )
@@ -48,7 +49,8 @@ import (
 const (
 	appDeploymentFinalizer = "finalizers.appdeployment.oam.dev"
 	reconcileTimeOut       = 60 * time.Second
-	secretKeyConfig        = "config"
+	// cordonedRequeueInterval is the interval to retry placing revisions to the cordoned clusters
+	cordonedRequeueInterval = time.Minute
 )
 
 var (
@@ -121,8 +123,27 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	}
 
 	diff := r.calculateDiff(appDeployment)
+	cordoned, err := clustermanager.CordonedClusters(ctx, r.Client, appDeployment.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// the revisions are placed to the cordoned clusters once they're uncordoned
+	if skipped := skipCordonedClusters(diff, cordoned); len(skipped) != 0 {
+		klog.InfoS("Skip placing revisions to cordoned clusters", "appDeployment", klog.KObj(appDeployment), "clusters", skipped)
+		res.RequeueAfter = cordonedRequeueInterval
+	}
 
-	if !diff.Empty() {
+	if appDeployment.Spec.UpgradePolicy != nil {
+		done, requeueAfter, err := r.upgradeInBatches(ctx, appDeployment, diff)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			flagCordonedClusters(appDeployment.Status.Placement, cordoned)
+			// traffic is shifted after all clusters are upgraded
+			return ctrl.Result{RequeueAfter: requeueAfter}, r.updateStatus(ctx, appDeployment)
+		}
+	} else if !diff.Empty() {
 		if appDeployment.Status.Phase != oamcore.PhaseRolling {
 			appDeployment.Status.Phase = oamcore.PhaseRolling
 			if err := r.updateStatus(ctx, appDeployment); err != nil {
@@ -145,6 +166,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	appDeployment.Status.Placement = makePlacement(
 		append(append(diff.Add, diff.Mod...), diff.Unchanged...),
 	)
+	flagCordonedClusters(appDeployment.Status.Placement, cordoned)
 
 	if appDeployment.Spec.Traffic != nil {
 		if err := r.applyTraffic(ctx, appDeployment); err != nil {
@@ -152,7 +174,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 		}
 	}
 
-	return ctrl.Result{}, r.updateStatus(ctx, appDeployment)
+	return ctrl.Result{RequeueAfter: res.RequeueAfter}, r.updateStatus(ctx, appDeployment)
 }
 
 func (r *Reconciler) handleFinalizer(ctx context.Context, appd *oamcore.AppDeployment) error {
@@ -190,15 +212,11 @@ func (r *Reconciler) getClientForCluster(ctx context.Context, cluster, ns string
 		return nil, err
 	}
 
-	key := client.ObjectKey{
-		Name:      c.Spec.KubeconfigSecretRef.Name,
-		Namespace: ns,
-	}
-	secret := &corev1.Secret{}
-	if err := r.Client.Get(ctx, key, secret); err != nil {
+	kubeConfig, err := clustermanager.GetKubeConfig(ctx, r.Client, c)
+	if err != nil {
 		return nil, err
 	}
-	return clustermanager.GetClient(secret.Data[secretKeyConfig])
+	return clustermanager.GetClient(kubeConfig)
 }
 
 func (r *Reconciler) deleteRevisions(ctx context.Context, appd *oamcore.AppDeployment, revisions []*revision) (err error) {
@@ -310,6 +328,9 @@ func (r *Reconciler) getWorkloadsFromRevision(ctx context.Context, revName, ns s
 	if err := r.Client.Get(ctx, key, &appRev); err != nil {
 		return nil, err
 	}
+	if err := manifeststore.Load(ctx, r.Client, &appRev); err != nil {
+		return nil, err
+	}
 
 	ac, err := convertRawExtention2AppConfig(appRev.Spec.ApplicationConfiguration)
 	if err != nil {
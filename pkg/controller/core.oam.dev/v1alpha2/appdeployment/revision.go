@@ -121,6 +121,31 @@ func makePlacement(revisions []*revision) []oamcore.PlacementStatus {
 	return r
 }
 
+// skipCordonedClusters removes the revisions to be placed to the cordoned clusters from the diff and returns the
+// names of the clusters, the revisions placed to them before are kept as they are
+func skipCordonedClusters(d *revisionsDiff, cordoned map[string]bool) []string {
+	var skipped []string
+	add := d.Add[:0]
+	for _, rev := range d.Add {
+		if cordoned[rev.ClusterName] {
+			skipped = append(skipped, rev.ClusterName)
+			continue
+		}
+		add = append(add, rev)
+	}
+	d.Add = add
+	return skipped
+}
+
+// flagCordonedClusters flags the clusters cordoned after the revisions are placed to them
+func flagCordonedClusters(placement []oamcore.PlacementStatus, cordoned map[string]bool) {
+	for i := range placement {
+		for j := range placement[i].Clusters {
+			placement[i].Clusters[j].Cordoned = cordoned[placement[i].Clusters[j].ClusterName]
+		}
+	}
+}
+
 func makeService(compName, ns, revName string, port int) *corev1.Service {
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
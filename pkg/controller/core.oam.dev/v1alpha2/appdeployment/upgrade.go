@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appdeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble/health"
+)
+
+const (
+	defaultHealthCheckTimeout = 600 * time.Second
+	healthCheckInterval       = 10 * time.Second
+)
+
+// upgradeInBatches upgrades the clusters following the upgrade policy, at most maxSkew clusters are upgraded in a
+// batch and the next batch starts only after the current one is healthy. The upgrade is halted if the clusters of the
+// current batch don't become healthy within the timeout, or turn unhealthy after they were healthy.
+// It returns true if all clusters are upgraded, otherwise the duration after which the upgrade should be checked again.
+func (r *Reconciler) upgradeInBatches(ctx context.Context, appd *oamcore.AppDeployment, diff *revisionsDiff) (bool, time.Duration, error) {
+	policy := appd.Spec.UpgradePolicy
+	us := appd.Status.Upgrade
+	if us != nil && us.Halted {
+		if us.ObservedGeneration == appd.Generation {
+			return false, 0, nil
+		}
+		klog.InfoS("Resume the halted upgrade since the appDeployment is updated", "appDeployment", klog.KObj(appd))
+		us = nil
+	}
+
+	now := metav1.Now()
+	if us != nil && len(us.Clusters) > 0 {
+		healthy, message, err := r.checkClustersHealth(ctx, appd, us.Clusters)
+		if err != nil {
+			return false, 0, err
+		}
+		switch {
+		case !healthy && us.BatchHealthyTime != nil:
+			haltUpgrade(appd, fmt.Sprintf("batch %d turned unhealthy: %s", us.Batch, message))
+			return false, 0, nil
+		case !healthy && now.Sub(us.BatchStartTime.Time) > healthCheckTimeout(policy):
+			haltUpgrade(appd, fmt.Sprintf("batch %d is not healthy in %s: %s", us.Batch, healthCheckTimeout(policy), message))
+			return false, 0, nil
+		case !healthy:
+			us.Message = fmt.Sprintf("waiting for batch %d to be healthy: %s", us.Batch, message)
+			appd.Status.Upgrade = us
+			return false, healthCheckInterval, nil
+		case us.BatchHealthyTime == nil:
+			us.BatchHealthyTime = &now
+		}
+	}
+
+	if diff.Empty() {
+		appd.Status.Upgrade = nil
+		return true, 0, nil
+	}
+
+	if us != nil && us.BatchHealthyTime != nil && policy.PauseSeconds > 0 {
+		resumeTime := us.BatchHealthyTime.Add(time.Duration(policy.PauseSeconds) * time.Second)
+		if remaining := resumeTime.Sub(now.Time); remaining > 0 {
+			us.Message = fmt.Sprintf("batch %d is healthy, pausing until %s", us.Batch, resumeTime.Format(time.RFC3339))
+			appd.Status.Upgrade = us
+			return false, remaining, nil
+		}
+	}
+
+	clusters, batch := nextBatch(diff, policy.MaxSkew)
+	klog.InfoS("Upgrade clusters in batch", "appDeployment", klog.KObj(appd), "clusters", clusters)
+	if err := r.deleteRevisions(ctx, appd, batch.Del); err != nil {
+		return false, 0, err
+	}
+	if err := r.applyRevisions(ctx, appd, batch.Mod); err != nil {
+		return false, 0, err
+	}
+	if err := r.applyRevisions(ctx, appd, batch.Add); err != nil {
+		return false, 0, err
+	}
+
+	batchNum := 1
+	if us != nil {
+		batchNum = us.Batch + 1
+	}
+	appd.Status.Phase = oamcore.PhaseRolling
+	appd.Status.Placement = makePlacement(placeBatch(appd.Status.Placement, batch))
+	appd.Status.Upgrade = &oamcore.UpgradeStatus{
+		Batch:              batchNum,
+		Clusters:           clusters,
+		BatchStartTime:     now,
+		ObservedGeneration: appd.Generation,
+		Message:            fmt.Sprintf("upgrading batch %d", batchNum),
+	}
+	return false, healthCheckInterval, nil
+}
+
+func haltUpgrade(appd *oamcore.AppDeployment, message string) {
+	klog.InfoS("Halt the upgrade", "appDeployment", klog.KObj(appd), "reason", message)
+	appd.Status.Phase = oamcore.PhaseFailed
+	appd.Status.Upgrade.Halted = true
+	appd.Status.Upgrade.Message = "halted: " + message
+}
+
+func healthCheckTimeout(policy *oamcore.UpgradePolicy) time.Duration {
+	if policy.HealthCheckTimeoutSeconds > 0 {
+		return time.Duration(policy.HealthCheckTimeoutSeconds) * time.Second
+	}
+	return defaultHealthCheckTimeout
+}
+
+// nextBatch picks at most maxSkew clusters to upgrade in order, and the changes of them
+func nextBatch(diff *revisionsDiff, maxSkew int) ([]string, *revisionsDiff) {
+	var clusters []string
+	picked := make(map[string]bool)
+	for _, revs := range [][]*revision{diff.Del, diff.Mod, diff.Add} {
+		for _, rev := range revs {
+			if picked[rev.ClusterName] || (maxSkew > 0 && len(clusters) >= maxSkew) {
+				continue
+			}
+			picked[rev.ClusterName] = true
+			clusters = append(clusters, rev.ClusterName)
+		}
+	}
+	filter := func(revs []*revision) []*revision {
+		var r []*revision
+		for _, rev := range revs {
+			if picked[rev.ClusterName] {
+				r = append(r, rev)
+			}
+		}
+		return r
+	}
+	return clusters, &revisionsDiff{Del: filter(diff.Del), Mod: filter(diff.Mod), Add: filter(diff.Add)}
+}
+
+// placeBatch returns the revisions placed after the changes of the batch applied to the current placement
+func placeBatch(placement []oamcore.PlacementStatus, batch *revisionsDiff) []*revision {
+	deleted := make(map[revision]bool)
+	for _, rev := range batch.Del {
+		deleted[revision{RevisionName: rev.RevisionName, ClusterName: rev.ClusterName}] = true
+	}
+	modified := make(map[revision]*revision)
+	for _, rev := range batch.Mod {
+		modified[revision{RevisionName: rev.RevisionName, ClusterName: rev.ClusterName}] = rev
+	}
+
+	var revs []*revision
+	for _, p := range placement {
+		for _, c := range p.Clusters {
+			key := revision{RevisionName: p.RevisionName, ClusterName: c.ClusterName}
+			if deleted[key] {
+				continue
+			}
+			if rev, ok := modified[key]; ok {
+				revs = append(revs, rev)
+				continue
+			}
+			revs = append(revs, newRevision(p.RevisionName, c.ClusterName, c.Replicas))
+		}
+	}
+	return append(revs, batch.Add...)
+}
+
+// checkClustersHealth checks the workloads of all revisions placed to the clusters are healthy
+func (r *Reconciler) checkClustersHealth(ctx context.Context, appd *oamcore.AppDeployment, clusters []string) (bool, string, error) {
+	for _, cluster := range clusters {
+		var kubecli client.Client
+		for _, rev := range appd.Spec.AppRevisions {
+			for _, p := range rev.Placement {
+				clusterName := ""
+				if p.ClusterSelector != nil {
+					clusterName = p.ClusterSelector.Name
+				}
+				if clusterName != cluster {
+					continue
+				}
+				workloads, err := r.getWorkloadsFromRevision(ctx, rev.RevisionName, appd.Namespace)
+				if err != nil {
+					return false, "", err
+				}
+				if kubecli == nil {
+					if kubecli, err = r.getKubeClient(ctx, cluster, appd.Namespace); err != nil {
+						return false, "", err
+					}
+				}
+				for _, wl := range workloads {
+					live := &unstructured.Unstructured{}
+					live.SetGroupVersionKind(wl.Object.GroupVersionKind())
+					if err := kubecli.Get(ctx, client.ObjectKey{Namespace: wl.Object.GetNamespace(), Name: wl.Object.GetName()}, live); err != nil {
+						if apierrors.IsNotFound(err) {
+							return false, fmt.Sprintf("cluster %q: %s %s is not found", cluster, wl.Object.GetKind(), wl.Object.GetName()), nil
+						}
+						return false, "", err
+					}
+					if healthy, message := health.CheckWorkload(live); !healthy {
+						return false, fmt.Sprintf("cluster %q: %s %s is unhealthy: %s", cluster, live.GetKind(), live.GetName(), message), nil
+					}
+				}
+			}
+		}
+	}
+	return true, "", nil
+}
+
+func (r *Reconciler) getKubeClient(ctx context.Context, cluster, ns string) (client.Client, error) {
+	if isHostCluster(cluster) {
+		return r.Client, nil
+	}
+	return r.getClientForCluster(ctx, cluster, ns)
+}
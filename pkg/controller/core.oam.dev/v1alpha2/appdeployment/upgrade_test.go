@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appdeployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestUpgradeInBatches(t *testing.T) {
+	placement := []oamcore.PlacementStatus{{
+		RevisionName: "app-v1",
+		Clusters: []oamcore.ClusterPlacementStatus{
+			{ClusterName: "c1", Replicas: 2},
+			{ClusterName: "c2", Replicas: 2},
+			{ClusterName: "c3", Replicas: 2},
+		},
+	}}
+	diff := &revisionsDiff{
+		Del: []*revision{newRevision("app-v1", "c1", 2), newRevision("app-v1", "c2", 2), newRevision("app-v1", "c3", 2)},
+		Add: []*revision{newRevision("app-v2", "c1", 2), newRevision("app-v2", "c2", 2), newRevision("app-v2", "c3", 2)},
+	}
+
+	clusters, batch := nextBatch(diff, 2)
+	assert.Equal(t, []string{"c1", "c2"}, clusters)
+	assert.Equal(t, 2, len(batch.Del))
+	assert.Equal(t, 2, len(batch.Add))
+
+	placed := placeBatch(placement, batch)
+	assert.Equal(t, []*revision{
+		newRevision("app-v1", "c3", 2),
+		newRevision("app-v2", "c1", 2),
+		newRevision("app-v2", "c2", 2),
+	}, placed)
+
+	// all clusters are upgraded at once without maxSkew
+	clusters, _ = nextBatch(diff, 0)
+	assert.Equal(t, []string{"c1", "c2", "c3"}, clusters)
+}
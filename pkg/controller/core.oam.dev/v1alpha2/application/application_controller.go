@@ -18,6 +18,8 @@ package application
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
@@ -26,24 +28,44 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	velatypes "github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	core "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/notification"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/utils/impersonate"
+	velarecorder "github.com/oam-dev/kubevela/pkg/utils/recorder"
+	"github.com/oam-dev/kubevela/pkg/utils/requeue"
+	"github.com/oam-dev/kubevela/pkg/utils/sharding"
+	"github.com/oam-dev/kubevela/pkg/utils/signature"
+	"github.com/oam-dev/kubevela/pkg/utils/speclimit"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 // RolloutReconcileWaitTime is the time to wait before reconcile again an application still in rollout phase
@@ -64,10 +86,26 @@ type Reconciler struct {
 	Recorder         event.Recorder
 	applicator       apply.Applicator
 	appRevisionLimit int
+	keyStore         *signature.KeyStore
+	gateway          *multicluster.ClusterGateway
+	specLimits       speclimit.Limits
+	notifier         *notification.Notifier
+	impersonation    *impersonate.ClientFactory
+	// admissionPreflight is whether to check the rendered resources by server-side dry-run by default
+	admissionPreflight bool
+	// manifestStore is how the manifests of the revisions are compressed and where they're moved to if they're
+	// too large
+	manifestStore manifeststore.Options
+	// sharding filters the applications not scheduled to the shard of the replica
+	sharding sharding.Options
+	// requeue is how soon the applications are reconciled again, the ones still progressing are checked again
+	// shortly and the stable ones less and less often
+	requeue requeue.Intervals
 }
 
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=environments,verbs=get;list;watch
 
 // Reconcile process app event
 func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -83,12 +121,22 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	// the applications referring to a secret are enqueued regardless of the shards they're scheduled to
+	if !r.sharding.Owns(app) {
+		return ctrl.Result{}, nil
+	}
+	// the phases of the reconciliation are traced as the children of the span
+	ctx, span := tracer.Start(ctx, "reconcile", trace.WithAttributes(
+		attribute.String("application", req.NamespacedName.String()),
+		attribute.Int64("generation", app.Generation)))
+	defer span.End()
 
 	handler := &appHandler{
 		r:      r,
 		app:    app,
 		logger: applog,
 	}
+	defer handler.endPhase(nil)
 
 	if app.ObjectMeta.DeletionTimestamp.IsZero() {
 		if registerFinalizers(app) {
@@ -99,7 +147,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		needUpdate, err := handler.removeResourceTracker(ctx)
 		if err != nil {
 			applog.Error(err, "Failed to remove application resourceTracker")
-			app.Status.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, "error to  remove finalizer")))
+			handler.conditions().failed(common.ApplicationDispatched, reasonCannotFinalize, errors.Wrap(err, "error to  remove finalizer"))
 			return reconcile.Result{}, errors.Wrap(r.UpdateStatus(ctx, app), errUpdateApplicationStatus)
 		}
 		if needUpdate {
@@ -110,51 +158,206 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return reconcile.Result{}, nil
 	}
 
+	// restart the workflow from the first step if it's requested, e.g., by a Trigger on a push event
+	restarted, err := handler.handleWorkflowRestart(ctx)
+	if err != nil {
+		applog.Error(err, "[Handle Workflow Restart]")
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
+
+	if handler.handleWorkflowSuspend() {
+		applog.Info("workflow suspended", "step", app.Status.WorkflowSuspend.CurrentStep)
+		return ctrl.Result{}, r.UpdateStatus(ctx, app)
+	}
+
 	applog.Info("Start Rendering")
 
+	// record the decisions made in this reconciliation if the decision log is enabled
+	handler.decisions = newDecisionLog(app)
+	ctx = decision.WithLog(ctx, handler.decisions)
+	defer handler.dumpDecisionLog(ctx)
+	// persist the render intermediates for `vela debug` if the debug snapshots are enabled
+	handler.snapshot = newDebugSnapshot(app)
+	defer handler.dumpDebugSnapshot(ctx)
+
 	app.Status.Phase = common.ApplicationRendering
+	handler.startPhase(ctx, phaseParse)
+
+	// the limits are enforced on admission, they're checked again in case the webhook is disabled
+	limits, err := speclimit.Load(ctx, r, app.Namespace, r.specLimits)
+	if err == nil {
+		err = limits.ValidateSpec(app).ToAggregate()
+	}
+	if err != nil {
+		applog.Error(err, "[Handle Spec Limits]")
+		handler.conditions().failed(common.ApplicationParsed, reasonSpecLimitExceeded, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedParse, err))
+		return handler.handleErr(err)
+	}
 
 	applog.Info("parse template")
 	// parse template
-	appParser := appfile.NewApplicationParser(r.Client, r.dm, r.pd)
+	defs := &definitionCounter{Client: r.Client}
+	defer defs.observe()
+	appParser := appfile.NewApplicationParser(defs, r.dm, r.pd)
 
 	ctx = oamutil.SetNamespaceInCtx(ctx, app.Namespace)
-	generatedAppfile, err := appParser.GenerateAppFile(ctx, app)
+	outputs, err := workflow.LoadOutputs(ctx, r, app)
+	if err != nil {
+		applog.Error(err, "[Handle Load Workflow Context]")
+		handler.conditions().failed(common.ApplicationParsed, reasonCannotLoadOutputs, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
+	handler.outputs = outputs
+	// the workflow steps get the outputs of the previous steps they import set into their properties
+	resolvedApp, err := handler.resolveStepInputs()
+	if err != nil {
+		applog.Error(err, "[Handle Workflow Step Inputs]")
+		handler.conditions().failed(common.ApplicationParsed, reasonCannotParse, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedParse, err))
+		return handler.handleErr(err)
+	}
+	generatedAppfile, err := appParser.GenerateAppFile(ctx, resolvedApp)
 	if err != nil {
 		applog.Error(err, "[Handle Parse]")
-		app.Status.SetConditions(errorCondition("Parsed", err))
+		handler.conditions().failed(common.ApplicationParsed, reasonCannotParse, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedParse, err))
 		return handler.handleErr(err)
 	}
 
-	app.Status.SetConditions(readyCondition("Parsed"))
+	if restarted {
+		// pick up the new commits of the Git components, the restart may be triggered by a push to the repositories
+		generatedAppfile.ResyncGitSources()
+	}
+
+	handler.conditions().ready(common.ApplicationParsed)
+	// the application is re-rendered once the Secrets its properties are resolved from change
+	app.Status.ValueSources = generatedAppfile.ValueSources()
 	handler.appfile = generatedAppfile
+	handler.parser, handler.parsedApp = appParser, resolvedApp
+
+	// the manifests stored in the revision the application is rolled back to are dispatched instead of rendered
+	if err := handler.loadRollbackSource(ctx); err != nil {
+		applog.Error(err, "[Handle Rollback]")
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRollback, err))
+		return handler.handleErr(err)
+	}
 
 	appRev, err := handler.GenerateAppRevision(ctx)
 	if err != nil {
 		applog.Error(err, "[Handle Calculate Revision]")
-		app.Status.SetConditions(errorCondition("Parsed", err))
+		handler.conditions().failed(common.ApplicationParsed, reasonCannotGenerateRevision, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedParse, err))
 		return handler.handleErr(err)
 	}
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonParsed, velatypes.MessageParsed))
+	handler.recordRollback(appRev)
 	// Record the revision so it can be used to render data in context.appRevision
 	generatedAppfile.RevisionName = appRev.Name
+	// decide whether the conditional workflow steps are executed or skipped once the previous steps finish
+	handler.evaluateStepConditions()
+	// nothing progresses until the suspend-for-approval step the workflow reaches is approved
+	awaitingApproval, err := handler.handleApprovalGate()
+	if err != nil {
+		applog.Error(err, "[Handle Workflow Approval]")
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
+	if len(awaitingApproval) != 0 {
+		applog.Info("workflow waiting for approval", "step", awaitingApproval)
+		app.Status.Phase = common.ApplicationWorkflowSuspended
+		return ctrl.Result{}, r.UpdateStatus(ctx, app)
+	}
+	// start the sub-steps of step groups under their concurrency limits before checking them
+	handler.scheduleStepGroups()
+	timeouts, err := handler.checkAppDependencies(ctx)
+	if err != nil {
+		applog.Error(err, "[Handle application dependencies]")
+		handler.conditions().failed(appDependenciesCondition, reasonDependenciesUnhealthy, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedDependency, err))
+		return handler.handleErr(err)
+	}
+	if len(timeouts) != 0 {
+		// the components keep waiting, the timeout is surfaced to the users without blocking the other components
+		err := errors.Errorf("applications depended on are not healthy within the timeout: %s", strings.Join(timeouts, ", "))
+		handler.conditions().failed(appDependenciesCondition, reasonDependenciesUnhealthy, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedDependency, err))
+	} else if len(app.Status.AppDependencies) != 0 && handler.appDependenciesSatisfied() {
+		handler.conditions().ready(appDependenciesCondition)
+	} else if len(app.Status.AppDependencies) == 0 {
+		handler.conditions().remove(appDependenciesCondition)
+	}
+	handler.aggregateStepGroups()
+	if err := handler.exportStepOutputs(ctx); err != nil {
+		applog.Error(err, "[Handle Workflow Step Outputs]")
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
+	// time out and retry the workflow steps following their policies, requeue to check the running steps in time
+	stepRequeue := handler.enforceWorkflowStepPolicies()
+	// components waiting for their dependencies are neither rendered nor applied
+	handler.scheduleComponents()
+	// the stable Services of the blue-green components switch to the newest revisions once promoted
+	if err := handler.promoteBlueGreen(); err != nil {
+		applog.Error(err, "[Handle Blue-Green Promotion]")
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		return handler.handleErr(err)
+	}
 
 	applog.Info("build template")
+	handler.startPhase(ctx, phaseRender)
+	handler.snapshotComponents()
 	// build template to applicationconfig & component
-	ac, comps, err := generatedAppfile.GenerateApplicationConfiguration()
+	var ac *v1alpha2.ApplicationConfiguration
+	var comps []*v1alpha2.Component
+	if handler.rollbackSource != nil {
+		ac, comps, err = handler.rollbackManifests()
+	} else {
+		ac, comps, err = generatedAppfile.GenerateApplicationConfiguration()
+	}
 	if err != nil {
 		applog.Error(err, "[Handle GenerateApplicationConfiguration]")
-		app.Status.SetConditions(errorCondition("Built", err))
+		handler.conditions().failed(common.ApplicationRendered, reasonCannotRender, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRender, err))
+		return handler.handleErr(err)
+	}
+	handler.conditions().ready(common.ApplicationRendered)
+	if err := limits.ValidateRendered(ac); err != nil {
+		applog.Error(err, "[Handle Spec Limits]")
+		handler.conditions().failed(common.ApplicationPolicyApplied, reasonSpecLimitExceeded, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRender, err))
 		return handler.handleErr(err)
 	}
+	if err := handler.enforceQuota(ctx, comps); err != nil {
+		applog.Error(err, "[Handle Quota]")
+		handler.conditions().failed(common.ApplicationPolicyApplied, reasonQuotaExceeded, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedQuota, err))
+		return handler.handleErr(err)
+	}
+
+	handler.snapshotManifests(ac, comps)
+	handler.recordBlueGreen(ctx, comps)
+
+	// lint the rendered workloads, the warnings are surfaced in the status without blocking the deployment
+	handler.lintWorkloads(ctx, comps, generatedAppfile.RenderWarnings)
+	// the existing workloads referred by components are not managed by the application, warn if any is missing
+	handler.checkReferredWorkloads(ctx, comps)
+	// nothing is written until all the rendered resources pass the admission control of the cluster
+	if err := handler.checkAdmission(ctx, ac, comps); err != nil {
+		applog.Error(err, "[Handle Admission Preflight]")
+		handler.conditions().failed(common.ApplicationPolicyApplied, reasonAdmissionRejected, err)
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedAdmission, err))
+		return handler.handleErr(err)
+	}
+	handler.conditions().ready(common.ApplicationPolicyApplied)
 
+	handler.startPhase(ctx, phaseDispatch)
 	err = handler.handleResourceTracker(ctx, comps, ac)
 	if err != nil {
 		applog.Error(err, "[Handle resourceTracker]")
-		app.Status.SetConditions(errorCondition("Handle resourceTracker", err))
+		handler.conditions().failed(common.ApplicationDispatched, reasonCannotTrackResources, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRender, err))
 		return handler.handleErr(err)
 	}
@@ -162,23 +365,26 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	// pass the App label and annotation to ac except some app specific ones
 	oamutil.PassLabelAndAnnotation(app, ac)
 
-	app.Status.SetConditions(readyCondition("Built"))
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonRendered, velatypes.MessageRendered))
 	applog.Info("apply application revision & component to the cluster")
 	// apply application revision & component to the cluster
 	if err := handler.apply(ctx, appRev, ac, comps); err != nil {
 		applog.Error(err, "[Handle apply]")
-		app.Status.SetConditions(errorCondition("Applied", err))
+		handler.conditions().failed(common.ApplicationDispatched, reasonCannotApply, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedApply, err))
 		return handler.handleErr(err)
 	}
+	// dispatch the components to the managed clusters by the deploy workflow steps
+	deployRunning := handler.dispatchDeploySteps(ctx, ac, comps)
+	// the workloads of the blue-green revisions replaced are deleted after their soak periods
+	retireRequeue := handler.retireBlueGreen(ctx)
 
 	// if inplace is false and rolloutPlan is nil, it means the user will use an outer AppRollout object to rollout the application
 	if handler.app.Spec.RolloutPlan != nil {
 		res, err := handler.handleRollout(ctx)
 		if err != nil {
 			applog.Error(err, "[handle rollout]")
-			app.Status.SetConditions(errorCondition("Rollout", err))
+			handler.conditions().failed(common.ApplicationDispatched, reasonCannotRollout, err)
 			r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedRollout, err))
 			return handler.handleErr(err)
 		}
@@ -191,36 +397,42 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 		// there is no need reconcile immediately, that means the rollout operation have finished
 		r.Recorder.Event(app, event.Normal(velatypes.ReasonRollout, velatypes.MessageRollout))
-		app.Status.SetConditions(readyCondition("Rollout"))
 		applog.Info("rollout finished")
 	}
 
 	// The following logic will be skipped if rollout have not finished
-	app.Status.SetConditions(readyCondition("Applied"))
+	handler.conditions().ready(common.ApplicationDispatched)
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonFailedApply, velatypes.MessageApplied))
 	app.Status.Phase = common.ApplicationHealthChecking
 	applog.Info("check application health status")
+	handler.startPhase(ctx, phaseHealthCheck)
 	// check application health status
 	appCompStatus, healthy, err := handler.statusAggregate(generatedAppfile)
 	if err != nil {
 		applog.Error(err, "[status aggregate]")
-		app.Status.SetConditions(errorCondition("HealthCheck", err))
+		handler.conditions().failed(common.ApplicationHealthy, reasonCannotCheckHealth, err)
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedHealthCheck, err))
 		return handler.handleErr(err)
 	}
+	if pending := handler.pendingComponentStatus(); len(pending) != 0 {
+		appCompStatus = append(appCompStatus, pending...)
+		healthy = false
+	}
 	if !healthy {
-		app.Status.SetConditions(errorCondition("HealthCheck", errors.New("not healthy")))
+		handler.conditions().failed(common.ApplicationHealthy, reasonUnhealthy, errors.New(unhealthyMessage(appCompStatus)))
 
 		app.Status.Services = appCompStatus
-		// unhealthy will check again after 10s
-		return ctrl.Result{RequeueAfter: time.Second * 10}, r.Status().Update(ctx, app)
+		// unhealthy will check again shortly
+		return ctrl.Result{RequeueAfter: r.requeue.After()}, r.UpdateStatus(ctx, app)
 	}
 	app.Status.Services = appCompStatus
-	app.Status.SetConditions(readyCondition("HealthCheck"))
+	handler.conditions().ready(common.ApplicationHealthy)
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonHealthCheck, velatypes.MessageHealthCheck))
 	app.Status.Phase = common.ApplicationRunning
 
+	handler.startPhase(ctx, phaseGC)
 	err = garbageCollection(ctx, handler)
+	handler.endPhase(err)
 	if err != nil {
 		applog.Error(err, "[Garbage collection]")
 		r.Recorder.Event(app, event.Warning(velatypes.ReasonFailedGC, err))
@@ -238,7 +450,29 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 	app.Status.Components = refComps
 	r.Recorder.Event(app, event.Normal(velatypes.ReasonDeployed, velatypes.MessageDeployed))
-	return ctrl.Result{}, r.UpdateStatus(ctx, app)
+	requeue := stepRequeue
+	if handler.gcRequeue != 0 && (requeue == 0 || handler.gcRequeue < requeue) {
+		requeue = handler.gcRequeue
+	}
+	if retireRequeue != 0 && (requeue == 0 || retireRequeue < requeue) {
+		requeue = retireRequeue
+	}
+	if deployRunning && (requeue == 0 || deployRequeueInterval < requeue) {
+		requeue = deployRequeueInterval
+	}
+	// re-render the components of the Git schematic to pick up new commits of the branches and tags
+	if gitRequeue := generatedAppfile.GitSyncInterval(); gitRequeue != 0 && (requeue == 0 || gitRequeue < requeue) {
+		requeue = gitRequeue
+	}
+	// re-render the components resolving properties from Vault to pick up the rotated secrets
+	if vaultRequeue := generatedAppfile.VaultRefreshInterval(); vaultRequeue != 0 && (requeue == 0 || vaultRequeue < requeue) {
+		requeue = vaultRequeue
+	}
+	// check again the running workflow steps shortly, and the healthy applications less often the longer they're stable
+	if stableRequeue := handler.stableRequeue(); stableRequeue != 0 && (requeue == 0 || stableRequeue < requeue) {
+		requeue = stableRequeue
+	}
+	return ctrl.Result{RequeueAfter: requeue}, r.UpdateStatus(ctx, app)
 }
 
 // if any finalizers newly registered, return true
@@ -254,33 +488,100 @@ func registerFinalizers(app *v1beta1.Application) bool {
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// If Application Own these two child objects, AC status change will notify application controller and recursively update AC again, and trigger application event again...
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1beta1.Application{}).
+		For(&v1beta1.Application{}, builder.WithPredicates(r.sharding.Predicate())).
+		// report the pending Helm-based components again once Helm creates their workloads
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: assemble.HelmWorkloadToApplication(mgr.GetClient())}).
+		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: assemble.HelmWorkloadToApplication(mgr.GetClient())}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, secretToApplications(mgr.GetClient())).
 		Complete(r)
 }
 
 // UpdateStatus updates v1beta1.Application's Status with retry.RetryOnConflict
 func (r *Reconciler) UpdateStatus(ctx context.Context, app *v1beta1.Application, opts ...client.UpdateOption) error {
+	oamutil.SetObservedGeneration(app)
 	status := app.DeepCopy().Status
-	return retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
+	var events []common.NotificationEvent
+	var prevPhase common.ApplicationPhase
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
 		if err = r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, app); err != nil {
 			return
 		}
+		prevPhase = app.Status.Phase
+		events = notification.Transitions(&app.Status, &status)
+		// the delivery status of notifications is updated by the notifier only
+		status.Notifications = app.Status.Notifications
 		app.Status = status
 		return r.Status().Update(ctx, app, opts...)
-	})
+	}); err != nil {
+		return err
+	}
+	r.recordTransitions(app, prevPhase, events)
+	if r.notifier != nil {
+		if err := r.notifier.Notify(ctx, app, events...); err != nil {
+			r.Log.Error(err, "[Notify state transitions]", "application", types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, "events", events)
+		}
+	}
+	return nil
+}
+
+// recordTransitions records the events of the phase transitions and the health regressions of the application
+func (r *Reconciler) recordTransitions(app *v1beta1.Application, prevPhase common.ApplicationPhase, events []common.NotificationEvent) {
+	if len(prevPhase) != 0 && prevPhase != app.Status.Phase {
+		r.Recorder.Event(app, event.Normal(velatypes.ReasonPhaseChanged,
+			fmt.Sprintf(velatypes.MessagePhaseChanged, prevPhase, app.Status.Phase)))
+	}
+	for _, e := range events {
+		if e != common.NotificationEventUnhealthy {
+			continue
+		}
+		msg := notification.NewPayload(app, e).Message
+		if len(msg) == 0 {
+			msg = "no component reports the cause"
+		}
+		r.Recorder.Event(app, event.Warning(velatypes.ReasonHealthRegressed,
+			errors.Errorf(velatypes.MessageHealthRegressed, msg)))
+	}
 }
 
 // Setup adds a controller that reconciles AppRollout.
 func Setup(mgr ctrl.Manager, args core.Args, _ logging.Logger) error {
+	applicator := apply.NewAPIApplicator(mgr.GetClient()).WithRateLimiter(args.ApplyRateLimiter)
+	if args.EnableServerSideApply {
+		applicator.WithDefaultStrategy(apply.StrategyServerSide)
+	}
+	keyStore, err := signature.NewKeyStore(mgr.GetClient(), args.ManifestSigningKeySecret)
+	if err != nil {
+		return err
+	}
 	reconciler := Reconciler{
-		Client:           mgr.GetClient(),
-		Log:              ctrl.Log.WithName("Application"),
-		Scheme:           mgr.GetScheme(),
-		Recorder:         event.NewAPIRecorder(mgr.GetEventRecorderFor("Application")),
-		dm:               args.DiscoveryMapper,
-		pd:               args.PackageDiscover,
-		applicator:       apply.NewAPIApplicator(mgr.GetClient()),
-		appRevisionLimit: args.AppRevisionLimit,
+		Client:             mgr.GetClient(),
+		Log:                ctrl.Log.WithName("Application"),
+		Scheme:             mgr.GetScheme(),
+		Recorder:           velarecorder.NewDedupRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor("Application")), args.EventDedupInterval),
+		dm:                 args.DiscoveryMapper,
+		pd:                 args.PackageDiscover,
+		applicator:         applicator,
+		appRevisionLimit:   args.AppRevisionLimit,
+		keyStore:           keyStore,
+		gateway:            multicluster.NewClusterGateway(mgr.GetClient()).WithRateLimit(args.ApplyRateLimit),
+		specLimits:         args.AppSpecLimits,
+		notifier:           notification.NewNotifier(mgr.GetClient(), mgr.GetAPIReader()),
+		impersonation:      impersonate.NewClientFactory(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper()).WithRequired(args.RequireImpersonation),
+		admissionPreflight: args.EnableAdmissionPreflight,
+		manifestStore: manifeststore.Options{
+			Type:        args.AppRevisionManifestStore,
+			Threshold:   args.AppRevisionManifestThreshold,
+			Compression: args.AppRevisionManifestCompression,
+			// the ResourceTrackers are compressed by the application controller too
+			TrackerCompression: args.ResourceTrackerCompression,
+		},
+		sharding: args.Sharding,
+		requeue:  args.ApplicationRequeue,
+	}
+	if err := mgr.Add(reconciler.notifier); err != nil {
+		return err
 	}
 	return reconciler.SetupWithManager(mgr)
 }
@@ -30,7 +30,6 @@ import (
 	terraformtypes "github.com/oam-dev/terraform-controller/api/types"
 	terraformapi "github.com/oam-dev/terraform-controller/api/v1beta1"
 	"github.com/pkg/errors"
-	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -48,34 +47,21 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/appfile/lint"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationrollout"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/debug"
 	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/gc"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
 )
 
-func errorCondition(tpy string, err error) runtimev1alpha1.Condition {
-	return runtimev1alpha1.Condition{
-		Type:               runtimev1alpha1.ConditionType(tpy),
-		Status:             corev1.ConditionFalse,
-		LastTransitionTime: metav1.NewTime(time.Now()),
-		Reason:             runtimev1alpha1.ReasonReconcileError,
-		Message:            err.Error(),
-	}
-}
-
-func readyCondition(tpy string) runtimev1alpha1.Condition {
-	return runtimev1alpha1.Condition{
-		Type:               runtimev1alpha1.ConditionType(tpy),
-		Status:             corev1.ConditionTrue,
-		Reason:             runtimev1alpha1.ReasonAvailable,
-		LastTransitionTime: metav1.NewTime(time.Now()),
-	}
-}
-
 type appHandler struct {
 	r                        *Reconciler
 	app                      *v1beta1.Application
@@ -87,6 +73,25 @@ type appHandler struct {
 	acrossNamespaceResources []v1beta1.TypedReference
 	resourceTracker          *v1beta1.ResourceTracker
 	autodetect               bool
+	pendingWorkloads         []*appfile.Workload
+	decisions                *decision.Log
+	// gcRequeue is the duration after which the resources in their garbage collection grace periods are deleted
+	gcRequeue time.Duration
+	// deployIncomplete is true if any deploy workflow step isn't dispatched to all its clusters in this
+	// reconciliation, the resources dispatched to managed clusters before are kept tracked rather than collected
+	deployIncomplete bool
+	// outputs are exported by the workflow steps and persisted in the workflow context, keyed by output names
+	outputs map[string]string
+	// parser and parsedApp render the variants of the application patched by the override policies, parsedApp is
+	// the application with the inputs of the workflow steps resolved
+	parser    *appfile.Parser
+	parsedApp *v1beta1.Application
+	// snapshot records the render intermediates if the debug snapshots of the application are enabled
+	snapshot *debug.Snapshot
+	// rollbackSource is the revision the application is rolled back to, its stored manifests are dispatched
+	rollbackSource *v1beta1.ApplicationRevision
+	// endCurrentPhase ends the span of the current phase of the reconciliation and observes its duration
+	endCurrentPhase func(error)
 }
 
 // setInplace will mark if the application should upgrade the workload within the same instance(name never changed)
@@ -95,6 +100,7 @@ func (h *appHandler) setInplace(isInplace bool) {
 }
 
 func (h *appHandler) handleErr(err error) (ctrl.Result, error) {
+	h.endPhase(err)
 	nerr := h.r.UpdateStatus(context.Background(), h.app)
 	if err == nil && nerr == nil {
 		return ctrl.Result{}, nil
@@ -103,7 +109,7 @@ func (h *appHandler) handleErr(err error) (ctrl.Result, error) {
 		h.logger.Error(nerr, "[Update] application status")
 	}
 	return ctrl.Result{
-		RequeueAfter: time.Second * 10,
+		RequeueAfter: h.r.requeue.After(),
 	}, nil
 }
 
@@ -215,14 +221,36 @@ func (h *appHandler) createOrUpdateAppRevision(ctx context.Context, appRev *v1be
 	if h.isNewRevision {
 		var revisionNum int64
 		appRev.Name, revisionNum = utils.GetAppNextRevision(h.app)
+		if err := h.signAppRevision(ctx, appRev); err != nil {
+			return err
+		}
 		// only new revision update the status
 		if err := h.UpdateRevisionStatus(ctx, appRev.Name, h.revisionHash, revisionNum); err != nil {
 			return err
 		}
-		return h.r.Create(ctx, appRev)
+		stored, err := h.offloadAppRevision(ctx, appRev)
+		if err != nil {
+			return err
+		}
+		if err := h.r.Create(ctx, stored); err != nil {
+			return err
+		}
+		appRev.ObjectMeta = stored.ObjectMeta
+		return nil
 	}
 
-	return h.r.Update(ctx, appRev)
+	if err := h.signAppRevision(ctx, appRev); err != nil {
+		return err
+	}
+	stored, err := h.offloadAppRevision(ctx, appRev)
+	if err != nil {
+		return err
+	}
+	if err := h.r.Update(ctx, stored); err != nil {
+		return err
+	}
+	appRev.ObjectMeta = stored.ObjectMeta
+	return nil
 }
 
 func (h *appHandler) statusAggregate(appFile *appfile.Appfile) ([]common.ApplicationComponentStatus, bool, error) {
@@ -264,6 +292,13 @@ func (h *appHandler) statusAggregate(appFile *appfile.Appfile) ([]common.Applica
 				status.Healthy = true
 			}
 			status.Message = configuration.Status.Message
+			if len(configuration.Status.Outputs) != 0 {
+				mod, err := appfile.ParseTerraformModule(wl.FullTemplate.Terraform)
+				if err != nil {
+					return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, parse Terraform module error", appFile.Name, wl.Name)
+				}
+				status.Outputs = appfile.TerraformOutputs(mod, configuration.Status.Outputs)
+			}
 		default:
 			pCtx = process.NewContext(h.app.Namespace, wl.Name, appFile.Name, appFile.RevisionName)
 			if err := wl.EvalContext(pCtx); err != nil {
@@ -318,6 +353,29 @@ func (h *appHandler) statusAggregate(appFile *appfile.Appfile) ([]common.Applica
 	return appStatus, healthy, nil
 }
 
+// lintWorkloads checks the rendered workloads with the lint rules configured in the namespace of the application
+// and records the warnings in the status together with the ones found when rendering the components
+func (h *appHandler) lintWorkloads(ctx context.Context, comps []*v1alpha2.Component, renderWarnings []common.LintWarning) {
+	h.app.Status.LintWarnings = nil
+	cfg, err := lint.LoadConfig(ctx, h.r, h.app.Namespace)
+	if err != nil {
+		h.logger.Error(err, "[lint] skip linting the workloads")
+		return
+	}
+	for _, comp := range comps {
+		if comp.Spec.Workload.Raw == nil {
+			continue
+		}
+		workload, err := oamutil.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil {
+			h.logger.Error(err, "[lint] skip linting the workload", "component", comp.Name)
+			continue
+		}
+		h.app.Status.LintWarnings = append(h.app.Status.LintWarnings, lint.Lint(cfg, comp.Name, workload)...)
+	}
+	h.app.Status.LintWarnings = append(h.app.Status.LintWarnings, lint.Filter(cfg, renderWarnings)...)
+}
+
 // createOrUpdateComponent creates a component if not exist and update if exists.
 // it returns the corresponding component revisionName and if a new component revision is created
 func (h *appHandler) createOrUpdateComponent(ctx context.Context, comp *v1alpha2.Component) (string, error) {
@@ -464,17 +522,34 @@ func (h *appHandler) applyHelmModuleResources(ctx context.Context, comp *v1alpha
 	release.SetOwnerReferences(owners)
 	repo.SetOwnerReferences(owners)
 
-	if err := h.r.applicator.Apply(ctx, repo); err != nil {
+	applicator, err := h.applicator()
+	if err != nil {
+		return err
+	}
+	if err := applicator.Apply(ctx, repo); err != nil {
 		return err
 	}
 	klog.InfoS("Apply a HelmRepository", "namespace", repo.GetNamespace(), "name", repo.GetName())
-	if err := h.r.applicator.Apply(ctx, release); err != nil {
+	if err := applicator.Apply(ctx, release); err != nil {
 		return err
 	}
 	klog.InfoS("Apply a HelmRelease", "namespace", release.GetNamespace(), "name", release.GetName())
 	return nil
 }
 
+// applicator returns the applicator applying the rendered resources of the application with the identity declared by
+// its annotations, or the one of the controller if none is declared, see impersonate.ConfigFor
+func (h *appHandler) applicator() (apply.Applicator, error) {
+	c, err := h.r.impersonation.ClientOf(h.app, nil)
+	if err != nil || c == nil {
+		return h.r.applicator, err
+	}
+	if a, ok := h.r.applicator.(*apply.APIApplicator); ok {
+		return a.WithClient(c), nil
+	}
+	return apply.NewAPIApplicator(c), nil
+}
+
 // checkAndSetResourceTracker check if resource's namespace is different with application, if yes set resourceTracker as
 // resource's ownerReference
 func (h *appHandler) checkAndSetResourceTracker(resource *runtime.RawExtension) (bool, error) {
@@ -557,6 +632,14 @@ func (h *appHandler) removeResourceTracker(ctx context.Context) (bool, error) {
 		}
 		return false, err
 	}
+	if err := manifeststore.LoadResourceTracker(rt); err != nil {
+		return false, err
+	}
+	// the resources in the control plane cluster are deleted along with the tracker owning them, while the ones
+	// dispatched to managed clusters are deleted explicitly
+	if err := h.collectDispatchedResources(ctx, rt); err != nil {
+		return false, err
+	}
 	rt = &v1beta1.ResourceTracker{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: trackerName,
@@ -641,7 +724,7 @@ func (h *appHandler) getTraitName(ctx context.Context, componentName string, ct
 	if strings.Contains(traitType, ".") {
 		traitType = strings.Split(traitType, ".")[0]
 	}
-	traitName := oamutil.GenTraitName(componentName, ct, traitType)
+	traitName := oamutil.GenTraitNameWithHashVersion(componentName, ct, traitType, oamutil.GetTraitHashVersion(h.app.GetLabels()))
 	return traitName, nil
 }
 
@@ -664,10 +747,12 @@ type garbageCollectFunc func(ctx context.Context, h *appHandler) error
 
 // 1. collect useless across-namespace resource
 // 2. collect appRevision
+// 3. collect component revisions no longer referred by appRevisions
 func garbageCollection(ctx context.Context, h *appHandler) error {
 	collectFuncs := []garbageCollectFunc{
 		garbageCollectFunc(gcAcrossNamespaceResource),
 		garbageCollectFunc(cleanUpApplicationRevision),
+		garbageCollectFunc(cleanUpComponentRevision),
 	}
 	for _, collectFunc := range collectFuncs {
 		if err := collectFunc(ctx, h); err != nil {
@@ -690,38 +775,63 @@ func gcAcrossNamespaceResource(ctx context.Context, h *appHandler) error {
 		}
 		return err
 	}
-	applied := map[v1beta1.TypedReference]bool{}
-	if len(h.acrossNamespaceResources) == 0 {
-		h.app.Status.ResourceTracker = nil
-		if err := h.r.Delete(ctx, rt); err != nil {
-			return client.IgnoreNotFound(err)
-		}
-		return nil
+	if err := manifeststore.LoadResourceTracker(rt); err != nil {
+		return err
 	}
+	applied := map[v1beta1.TypedReference]bool{}
 	for _, resource := range h.acrossNamespaceResources {
 		applied[resource] = true
 	}
+	var garbage []v1beta1.TypedReference
 	for _, ref := range rt.Status.TrackedResources {
-		if !applied[ref] {
-			resource := new(unstructured.Unstructured)
-			resource.SetAPIVersion(ref.APIVersion)
-			resource.SetKind(ref.Kind)
-			resource.SetNamespace(ref.Namespace)
-			resource.SetName(ref.Name)
-			err := h.r.Delete(ctx, resource)
-			if err != nil {
-				if apierrors.IsNotFound(err) {
-					continue
-				}
-				return err
-			}
+		switch {
+		case applied[ref]:
+		case len(ref.Cluster) != 0 && h.deployIncomplete:
+			// the resources in managed clusters are only collected once all the deploy steps are dispatched,
+			// the ones not dispatched in this reconciliation may be dispatched by the steps not run yet
+			h.acrossNamespaceResources = append(h.acrossNamespaceResources, ref)
+			applied[ref] = true
+		default:
+			garbage = append(garbage, ref)
+		}
+	}
+	// the resources waiting for their grace periods are not tracked any more, unless they're rendered again
+	since := map[v1beta1.TypedReference]time.Time{}
+	for _, p := range rt.Status.PendingDeletions {
+		ref := v1beta1.TypedReference{APIVersion: p.APIVersion, Kind: p.Kind, Namespace: p.Namespace, Name: p.Name, Cluster: p.Cluster}
+		if applied[ref] {
+			continue
 		}
+		if _, ok := since[ref]; !ok {
+			garbage = append(garbage, ref)
+		}
+		since[ref] = p.Since.Time
+	}
+	pending, err := h.collectGarbage(ctx, rt, garbage, since)
+	if err != nil {
+		return err
+	}
+	if len(h.acrossNamespaceResources) == 0 && len(pending) == 0 && !h.hasDeploySteps() {
+		h.app.Status.ResourceTracker = nil
+		if err := h.r.Delete(ctx, rt); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		gc.NotifyTracked(ctx, h.app, nil)
+		return nil
 	}
 	// update resourceTracker status, recode applied across-namespace resources
 	rt.Status.TrackedResources = h.acrossNamespaceResources
+	rt.Status.PendingDeletions = pending
+	if h.app.Status.LatestRevision != nil {
+		rt.Status.AppRevision = h.app.Status.LatestRevision.Name
+	}
+	if err := manifeststore.CompressResourceTracker(rt, h.r.manifestStore.TrackerCompression); err != nil {
+		return err
+	}
 	if err := h.r.Status().Update(ctx, rt); err != nil {
 		return err
 	}
+	gc.NotifyTracked(ctx, h.app, h.acrossNamespaceResources)
 	h.app.Status.ResourceTracker = &runtimev1alpha1.TypedReference{
 		Name:       rt.Name,
 		Kind:       v1beta1.ResourceTrackerGroupKind,
@@ -731,10 +841,11 @@ func gcAcrossNamespaceResource(ctx context.Context, h *appHandler) error {
 }
 
 // handleResourceTracker check the namespace of  all workloads and traits
-// if one resource is across-namespace create resourceTracker and set in appHandler field
+// if one resource is across-namespace or the application has deploy steps, create resourceTracker and set in appHandler field
 func (h *appHandler) handleResourceTracker(ctx context.Context, components []*v1alpha2.Component, ac *v1alpha2.ApplicationConfiguration) error {
 	resourceTracker := new(v1beta1.ResourceTracker)
-	needTracker := false
+	// the resources dispatched to managed clusters by the deploy steps are tracked as well
+	needTracker := h.hasDeploySteps()
 	for _, c := range components {
 		if h.checkAutoDetect(c) {
 			continue
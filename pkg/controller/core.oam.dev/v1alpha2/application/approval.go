@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// handleApprovalGate suspends the workflow at the suspend-for-approval step once all the previous steps finish until
+// it's approved, the approver recorded by the admission webhook is kept in the step status for audit. It returns the
+// name of the step if the workflow is waiting for approval.
+func (h *appHandler) handleApprovalGate() (string, error) {
+	approvals, err := workflow.ParseApprovals(h.app.Spec.Workflow)
+	if err != nil {
+		return "", err
+	}
+	for _, step := range h.app.Spec.Workflow {
+		if step.Type != workflow.TypeSuspendForApproval {
+			if !h.isStepFinished(step.Name) {
+				// the workflow has not reached the following approval steps
+				return "", nil
+			}
+			continue
+		}
+		if h.isStepAwaitingCondition(step.Name) {
+			return "", nil
+		}
+		approval := approvals[step.Name]
+		status := h.workflowStepStatus(step.Name)
+		if status == nil {
+			h.app.Status.Workflow = append(h.app.Status.Workflow, common.WorkflowStepStatus{
+				Name:  step.Name,
+				Type:  step.Type,
+				Phase: common.WorkflowStepPhaseRunning,
+			})
+			status = &h.app.Status.Workflow[len(h.app.Status.Workflow)-1]
+		}
+		if status.Phase == common.WorkflowStepPhaseSucceeded || status.Phase == common.WorkflowStepPhaseSkipped ||
+			workflow.IsStepFailed(status.Phase) {
+			continue
+		}
+		if status.Approval == nil {
+			status.Approval = &common.WorkflowStepApproval{Users: approval.Users, Groups: approval.Groups}
+			status.Message = "waiting for approval"
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonApproval,
+				fmt.Sprintf(velatypes.MessageApproval, step.Name, describeApprovers(approval))))
+		}
+		annotations := h.app.GetAnnotations()
+		approvedBy := annotations[oam.AnnotationApprovedBy]
+		if annotations[oam.AnnotationApproveStep] != step.Name || len(approvedBy) == 0 {
+			return step.Name, nil
+		}
+		now := metav1.Now()
+		status.Phase = common.WorkflowStepPhaseSucceeded
+		status.Message = fmt.Sprintf("approved by %s", approvedBy)
+		status.Approval.ApprovedBy, status.Approval.ApprovedAt = approvedBy, &now
+		h.decisions.Record(decision.StageSchedule, step.Name, "workflow step approved by %s", approvedBy)
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonApproved, fmt.Sprintf(velatypes.MessageApproved, step.Name, approvedBy)))
+	}
+	return "", nil
+}
+
+func describeApprovers(approval *workflow.ApprovalSpec) string {
+	var approvers []string
+	if len(approval.Users) != 0 {
+		approvers = append(approvers, "users "+strings.Join(approval.Users, ", "))
+	}
+	if len(approval.Groups) != 0 {
+		approvers = append(approvers, "groups "+strings.Join(approval.Groups, ", "))
+	}
+	return strings.Join(approvers, " or ")
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	ctrlutil "github.com/oam-dev/kubevela/pkg/controller/utils"
@@ -42,6 +43,9 @@ func NewAppManifests(appRevision *v1beta1.ApplicationRevision) *AppManifests {
 type AppManifests struct {
 	AppRevision     *v1beta1.ApplicationRevision
 	WorkloadOptions []WorkloadOption
+	ValidateOptions []ValidateOption
+	// ReferredWorkloadDiscoverer discovers the existing workloads referred by the components
+	ReferredWorkloadDiscoverer ReferredWorkloadDiscoverer
 
 	appComponents []applicationComponent
 	components    []*v1alpha2.Component
@@ -51,11 +55,17 @@ type AppManifests struct {
 	appLabels      map[string]string
 	appAnnotations map[string]string
 	appOwnerRef    *metav1.OwnerReference
+	// traitHashVersion is the version of hash algorithm to generate trait names
+	traitHashVersion util.TraitHashVersion
 
 	assembledWorkloads map[string]*unstructured.Unstructured
 	assembledTraits    map[string][]*unstructured.Unstructured
 	// key is workload reference, values are the references of scopes the workload belongs to
 	referencedScopes map[runtimev1alpha1.TypedReference][]runtimev1alpha1.TypedReference
+	// key is component name, value is the message telling what the component is waiting for
+	pendingComponents map[string]string
+	// key is component name, value is the existing workload referred by the component which is not emitted
+	referredWorkloads map[string]*unstructured.Unstructured
 
 	finalized bool
 	err       error
@@ -93,6 +103,24 @@ func (am *AppManifests) WithWorkloadOption(wo WorkloadOption) *AppManifests {
 	return am
 }
 
+// ValidateOption will be applied to all assembled resources AFTER all WorkloadOptions have been applied and traits
+// have been assembled, i.e., it sees exactly the resources to be emitted into K8s.
+// It's used to reject an Application before anything is applied to the cluster, e.g., resource quota checks,
+// forbidden field checks, image registry allowlists.
+// Resources passed to the validator are deep copies, so any modification on them takes no effect.
+type ValidateOption interface {
+	Validate(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured) error
+}
+
+// WithValidateOption add a ValidateOption to plug in custom validation applied to all assembled resources
+func (am *AppManifests) WithValidateOption(vo ValidateOption) *AppManifests {
+	if am.ValidateOptions == nil {
+		am.ValidateOptions = make([]ValidateOption, 0)
+	}
+	am.ValidateOptions = append(am.ValidateOptions, vo)
+	return am
+}
+
 // AssembledManifests do assemble and merge all assembled resources(except referenced scopes) into one array
 func (am *AppManifests) AssembledManifests() ([]*unstructured.Unstructured, error) {
 	if !am.finalized {
@@ -129,10 +157,41 @@ func (am *AppManifests) ReferencedScopes() (map[runtimev1alpha1.TypedReference][
 	return r, nil
 }
 
-// GroupAssembledManifests do assemble and return all resources grouped by components
+// PendingComponents do assemble and return the components whose resources are not ready to be assembled yet, e.g.,
+// the workload of a Helm-based component is not created by Helm, or the workload referred by a component is not
+// found. Key is the component name, value is the message telling what the component is waiting for. Resources of
+// pending components are excluded from assembled manifests.
+func (am *AppManifests) PendingComponents() (map[string]string, error) {
+	if !am.finalized {
+		am.assemble()
+	}
+	if am.err != nil {
+		return nil, am.err
+	}
+	r := make(map[string]string, len(am.pendingComponents))
+	for k, v := range am.pendingComponents {
+		r[k] = v
+	}
+	return r, nil
+}
+
+// StagedTraits are the traits of a component grouped by their stages
+type StagedTraits map[common.TraitStage][]*unstructured.Unstructured
+
+// All returns the traits of all stages in the order of the stages
+func (st StagedTraits) All() []*unstructured.Unstructured {
+	r := make([]*unstructured.Unstructured, 0)
+	for _, stage := range common.TraitStages {
+		r = append(r, st[stage]...)
+	}
+	return r
+}
+
+// GroupAssembledManifests do assemble and return all resources grouped by components,
+// traits of a component are further grouped by the stage defined in their TraitDefinitions
 func (am *AppManifests) GroupAssembledManifests() (
 	map[string]*unstructured.Unstructured,
-	map[string][]*unstructured.Unstructured,
+	map[string]StagedTraits,
 	map[runtimev1alpha1.TypedReference][]runtimev1alpha1.TypedReference, error) {
 	if !am.finalized {
 		am.assemble()
@@ -144,11 +203,12 @@ func (am *AppManifests) GroupAssembledManifests() (
 	for k, wl := range am.assembledWorkloads {
 		workloads[k] = wl.DeepCopy()
 	}
-	traits := make(map[string][]*unstructured.Unstructured)
+	traits := make(map[string]StagedTraits)
 	for k, ts := range am.assembledTraits {
-		traits[k] = make([]*unstructured.Unstructured, len(ts))
-		for i, t := range ts {
-			traits[k][i] = t.DeepCopy()
+		traits[k] = make(StagedTraits)
+		for _, t := range ts {
+			stage := am.traitStage(t)
+			traits[k][stage] = append(traits[k][stage], t.DeepCopy())
 		}
 	}
 	scopes := make(map[runtimev1alpha1.TypedReference][]runtimev1alpha1.TypedReference)
@@ -159,6 +219,15 @@ func (am *AppManifests) GroupAssembledManifests() (
 	return workloads, traits, scopes, nil
 }
 
+// traitStage returns the stage defined in the TraitDefinition of the trait
+func (am *AppManifests) traitStage(trait *unstructured.Unstructured) common.TraitStage {
+	traitType := trait.GetLabels()[oam.TraitTypeLabel]
+	if stage := am.AppRevision.Spec.TraitDefinitions[traitType].Spec.Stage; len(stage) != 0 {
+		return stage
+	}
+	return common.DefaultTraitStage
+}
+
 func (am *AppManifests) assemble() {
 	am.complete()
 	klog.InfoS("Assemble manifests for application", "name", am.appName, "revision", am.AppRevision.GetName())
@@ -171,15 +240,35 @@ func (am *AppManifests) assemble() {
 		compName := ctrlutil.ExtractComponentName(compRevisionName)
 		commonLabels := am.generateCommonLabels(compName, compRevisionName)
 		var workloadRef runtimev1alpha1.TypedReference
+		pending := false
 		klog.InfoS("Assemble manifests for component", "name", compName)
 		for _, comp := range am.components {
 			if comp.Name == compName {
-				wl, err := am.assembleWorkload(comp, commonLabels)
+				var wl *unstructured.Unstructured
+				var err error
+				referred := isReferredWorkload(comp)
+				if referred {
+					wl, err = am.assembleReferredWorkload(comp)
+				} else {
+					wl, err = am.assembleWorkload(comp, commonLabels)
+				}
+				if IsHelmWorkloadPending(err) || util.IsReferredWorkloadMissing(err) {
+					// skip the component instead of blocking the others, it's assembled again once Helm creates the
+					// workload or the referred workload comes back
+					klog.InfoS("Component is pending", "name", compName, "reason", err.Error())
+					am.pendingComponents[compName] = errors.Cause(err).Error()
+					pending = true
+					break
+				}
 				if err != nil {
 					am.finalizeAssemble(err)
 					return
 				}
-				am.assembledWorkloads[compName] = wl
+				if referred {
+					am.referredWorkloads[compName] = wl
+				} else {
+					am.assembledWorkloads[compName] = wl
+				}
 				workloadRef = runtimev1alpha1.TypedReference{
 					APIVersion: wl.GetAPIVersion(),
 					Kind:       wl.GetKind(),
@@ -188,6 +277,9 @@ func (am *AppManifests) assemble() {
 				break
 			}
 		}
+		if pending {
+			continue
+		}
 
 		am.assembledTraits[compName] = make([]*unstructured.Unstructured, len(ac.traits))
 		for i, compTrait := range ac.traits {
@@ -208,9 +300,35 @@ func (am *AppManifests) assemble() {
 			am.referencedScopes[workloadRef][i] = scope
 		}
 	}
+	if err := am.validateAssembledManifests(); err != nil {
+		am.finalizeAssemble(err)
+		return
+	}
 	am.finalizeAssemble(nil)
 }
 
+// validateAssembledManifests runs all ValidateOptions against the assembled resources
+func (am *AppManifests) validateAssembledManifests() error {
+	for _, vo := range am.ValidateOptions {
+		workloads := make(map[string]*unstructured.Unstructured, len(am.assembledWorkloads))
+		for k, wl := range am.assembledWorkloads {
+			workloads[k] = wl.DeepCopy()
+		}
+		traits := make(map[string][]*unstructured.Unstructured, len(am.assembledTraits))
+		for k, ts := range am.assembledTraits {
+			traits[k] = make([]*unstructured.Unstructured, len(ts))
+			for i, t := range ts {
+				traits[k][i] = t.DeepCopy()
+			}
+		}
+		if err := vo.Validate(workloads, traits); err != nil {
+			klog.ErrorS(err, "Failed validating assembled manifests", "name", am.appName, "revision", am.AppRevision.GetName())
+			return errors.WithMessage(err, "assembled manifests are rejected by validation")
+		}
+	}
+	return nil
+}
+
 func (am *AppManifests) complete() {
 	// safe to skip error-check
 	appConfig, _ := convertRawExtention2AppConfig(am.AppRevision.Spec.ApplicationConfiguration)
@@ -234,6 +352,7 @@ func (am *AppManifests) complete() {
 	am.appNamespace = appConfig.GetNamespace()
 	am.appLabels = appConfig.GetLabels()
 	am.appAnnotations = appConfig.GetAnnotations()
+	am.traitHashVersion = util.GetTraitHashVersion(am.appLabels)
 	am.appOwnerRef = metav1.GetControllerOf(appConfig)
 
 	am.components = make([]*v1alpha2.Component, len(am.AppRevision.Spec.Components))
@@ -246,6 +365,8 @@ func (am *AppManifests) complete() {
 	am.assembledWorkloads = make(map[string]*unstructured.Unstructured)
 	am.assembledTraits = make(map[string][]*unstructured.Unstructured)
 	am.referencedScopes = make(map[runtimev1alpha1.TypedReference][]runtimev1alpha1.TypedReference)
+	am.pendingComponents = make(map[string]string)
+	am.referredWorkloads = make(map[string]*unstructured.Unstructured)
 }
 
 func (am *AppManifests) finalizeAssemble(err error) {
@@ -322,6 +443,11 @@ func (am *AppManifests) assembleWorkload(comp *v1alpha2.Component, labels map[st
 
 	workloadType := wl.GetLabels()[oam.WorkloadTypeLabel]
 	compDefinition := am.AppRevision.Spec.ComponentDefinitions[workloadType]
+	if isTerraformBasedWorkload(wl, &compDefinition) {
+		if err := assembleTerraformConfiguration(wl, &compDefinition); err != nil {
+			return nil, errors.WithMessagef(err, "cannot assemble Terraform Configuration for component %q", compName)
+		}
+	}
 	for _, wo := range am.WorkloadOptions {
 		if err := wo.ApplyToWorkload(wl, comp.DeepCopy(), compDefinition.DeepCopy()); err != nil {
 			klog.ErrorS(err, "Failed applying a workload option", "workload", klog.KObj(wl), "name", wl.GetName())
@@ -358,7 +484,7 @@ func (am *AppManifests) assembleTrait(compTrait v1alpha2.ComponentTrait, compNam
 	// only set generated name when name is unspecified
 	// it's by design to set arbitrary name in render phase
 	if len(trait.GetName()) == 0 {
-		traitName := util.GenTraitName(compName, &compTrait, traitType)
+		traitName := util.GenTraitNameWithHashVersion(compName, &compTrait, traitType, am.traitHashVersion)
 		trait.SetName(traitName)
 	}
 	am.setTraitLabels(trait, labels)
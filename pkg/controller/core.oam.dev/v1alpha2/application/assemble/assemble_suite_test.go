@@ -25,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/oam"
 
@@ -83,7 +84,7 @@ var _ = Describe("Test Assemble Options", func() {
 
 		By("Verify amount of result grouped resources")
 		Expect(len(workloads)).Should(Equal(1))
-		Expect(len(traits[compName])).Should(Equal(3))
+		Expect(len(traits[compName].All())).Should(Equal(3))
 
 		By("Verify workload metadata (name, namespace, labels, annotations, ownerRef)")
 		wl := workloads[compName]
@@ -107,7 +108,7 @@ var _ = Describe("Test Assemble Options", func() {
 		Expect(ownerRef.Kind).Should(Equal("Application"))
 
 		By("Verify trait metadata (name, namespace, labels, annotations, ownerRef)")
-		trait := traits[compName][0]
+		trait := traits[compName].All()[0]
 		Expect(trait.GetName()).Should(ContainSubstring(compName))
 		Expect(trait.GetNamespace()).Should(Equal(namespace))
 		labels = trait.GetLabels()
@@ -128,7 +129,7 @@ var _ = Describe("Test Assemble Options", func() {
 		Expect(ownerRef.Kind).Should(Equal("Application"))
 
 		By("Verify set workload reference to trait")
-		scaler := traits[compName][2]
+		scaler := traits[compName].All()[2]
 		wlRef, found, err := unstructured.NestedMap(scaler.Object, "spec", "workloadRef")
 		Expect(err).Should(BeNil())
 		Expect(found).Should(BeTrue())
@@ -156,4 +157,25 @@ var _ = Describe("Test Assemble Options", func() {
 		}
 		Expect(wlScope).Should(Equal(wantScopeRef))
 	})
+	It("test group traits by stage", func() {
+		compName := "test-comp"
+		appRev := &v1beta1.ApplicationRevision{}
+		b, err := ioutil.ReadFile("./testdata/apprevision.yaml")
+		Expect(err).Should(BeNil())
+		Expect(yaml.Unmarshal(b, appRev)).Should(BeNil())
+		ingressDef := appRev.Spec.TraitDefinitions["ingress"]
+		ingressDef.Spec.Stage = common.PreWorkloadStage
+		appRev.Spec.TraitDefinitions["ingress"] = ingressDef
+
+		_, traits, _, err := NewAppManifests(appRev).GroupAssembledManifests()
+		Expect(err).Should(BeNil())
+		Expect(len(traits[compName][common.PreDispatchStage])).Should(Equal(0))
+		Expect(len(traits[compName][common.PreWorkloadStage])).Should(Equal(2))
+		Expect(len(traits[compName][common.PostWorkloadStage])).Should(Equal(1))
+		for _, t := range traits[compName][common.PreWorkloadStage] {
+			Expect(t.GetLabels()[oam.TraitTypeLabel]).Should(Equal("ingress"))
+		}
+		all := traits[compName].All()
+		Expect(all[len(all)-1].GetLabels()[oam.TraitTypeLabel]).Should(Equal("manualscaler"))
+	})
 })
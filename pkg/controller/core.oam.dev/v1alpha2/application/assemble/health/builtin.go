@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// builtinCheck checks the health of a live workload of a well-known kind, it's used if its definition has no
+// healthPolicy
+type builtinCheck func(wl *unstructured.Unstructured) (bool, string)
+
+var builtinChecks = map[string]builtinCheck{
+	"Deployment":  checkReplicas("availableReplicas"),
+	"StatefulSet": checkReplicas("readyReplicas"),
+	"CloneSet":    checkReplicas("availableReplicas"),
+}
+
+// CheckWorkload checks the health of a live workload by the built-in rules of well-known kinds,
+// workloads of other kinds are considered healthy
+func CheckWorkload(wl *unstructured.Unstructured) (bool, string) {
+	check, ok := builtinChecks[wl.GetKind()]
+	if !ok {
+		return true, ""
+	}
+	return check(wl)
+}
+
+// checkReplicas checks the latest spec is observed and the replicas counted in the status field are enough
+func checkReplicas(readyField string) builtinCheck {
+	return func(wl *unstructured.Unstructured) (bool, string) {
+		observed, _, _ := unstructured.NestedInt64(wl.Object, "status", "observedGeneration")
+		if observed < wl.GetGeneration() {
+			return false, fmt.Sprintf("waiting for %s %s spec update to be observed", wl.GetKind(), wl.GetName())
+		}
+		replicas, found, _ := unstructured.NestedInt64(wl.Object, "spec", "replicas")
+		if !found {
+			// replicas defaults to 1
+			replicas = 1
+		}
+		ready, _, _ := unstructured.NestedInt64(wl.Object, "status", readyField)
+		if ready < replicas {
+			return false, fmt.Sprintf("%d of %d replicas are ready", ready, replicas)
+		}
+		return true, ""
+	}
+}
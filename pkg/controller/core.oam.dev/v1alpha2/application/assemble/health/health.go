@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// TraitsFieldName is the name of the field in the template context containing the resources of all traits of
+	// the component, grouped by trait types and then the names of the outputs
+	TraitsFieldName = "traits"
+	// ChildrenFieldName is the name of the field in the template context containing the child resources of the
+	// workload selected by the childResourceKinds of the ComponentDefinition, grouped by kinds
+	ChildrenFieldName = "children"
+)
+
+// Checker evaluates the readiness gates of assembled resources, i.e., the `status.healthPolicy` of the
+// ComponentDefinitions and TraitDefinitions recorded in the ApplicationRevision, against the live resources.
+// Resources whose definition has no healthPolicy fall back to the built-in checks of well-known kinds.
+// The `status.customStatus` of the definitions is rendered as the message of the component or trait.
+type Checker struct {
+	c           client.Reader
+	appRevision *v1beta1.ApplicationRevision
+}
+
+// NewChecker creates a Checker for the resources assembled from the ApplicationRevision
+func NewChecker(c client.Reader, appRevision *v1beta1.ApplicationRevision) *Checker {
+	return &Checker{c: c, appRevision: appRevision}
+}
+
+// CheckComponents checks the health of the assembled workloads and traits grouped by components, it returns the
+// per-component health to be populated into the Application status, and whether all components are healthy
+func (hc *Checker) CheckComponents(ctx context.Context, workloads map[string]*unstructured.Unstructured,
+	traits map[string][]*unstructured.Unstructured) ([]common.ApplicationComponentStatus, bool, error) {
+	compNames := make([]string, 0, len(workloads))
+	for compName := range workloads {
+		compNames = append(compNames, compName)
+	}
+	sort.Strings(compNames)
+
+	healthy := true
+	statuses := make([]common.ApplicationComponentStatus, 0, len(compNames))
+	for _, compName := range compNames {
+		status, err := hc.checkComponent(ctx, compName, workloads[compName], traits[compName])
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "cannot check health of component %q", compName)
+		}
+		if !status.Healthy {
+			healthy = false
+		}
+		for _, ts := range status.Traits {
+			if !ts.Healthy {
+				healthy = false
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, healthy, nil
+}
+
+func (hc *Checker) checkComponent(ctx context.Context, compName string, wl *unstructured.Unstructured,
+	traits []*unstructured.Unstructured) (common.ApplicationComponentStatus, error) {
+	status := common.ApplicationComponentStatus{
+		Name:    compName,
+		Healthy: true,
+		WorkloadDefinition: common.WorkloadGVK{
+			APIVersion: wl.GetAPIVersion(),
+			Kind:       wl.GetKind(),
+		},
+	}
+	liveWorkload, err := hc.getLiveResource(ctx, wl)
+	if err != nil {
+		return status, err
+	}
+	if liveWorkload == nil {
+		status.Healthy = false
+		status.Message = fmt.Sprintf("%s %s is not found", wl.GetKind(), wl.GetName())
+		return status, nil
+	}
+
+	// auxiliary workloads are checked with the workload, others are grouped by trait types
+	auxiliaries := make(map[string]interface{})
+	traitTypes := make([]string, 0)
+	traitOutputs := make(map[string]map[string]interface{})
+	for _, t := range traits {
+		live, err := hc.getLiveResource(ctx, t)
+		if err != nil {
+			return status, err
+		}
+		traitType := t.GetLabels()[oam.TraitTypeLabel]
+		outputName := t.GetLabels()[oam.TraitResource]
+		if traitType == definition.AuxiliaryWorkload {
+			if live != nil {
+				auxiliaries[outputName] = live.Object
+			}
+			continue
+		}
+		if _, ok := traitOutputs[traitType]; !ok {
+			traitTypes = append(traitTypes, traitType)
+			traitOutputs[traitType] = make(map[string]interface{})
+		}
+		if live != nil {
+			traitOutputs[traitType][outputName] = live.Object
+		}
+	}
+
+	compDef := hc.appRevision.Spec.ComponentDefinitions[wl.GetLabels()[oam.WorkloadTypeLabel]]
+	children, err := hc.getChildren(ctx, liveWorkload, compDef.Spec.ChildResourceKinds)
+	if err != nil {
+		return status, err
+	}
+	// the health policy and customStatus of both workload and traits can access all resources of the component
+	newTemplateContext := func(outputs map[string]interface{}) map[string]interface{} {
+		templateContext := hc.templateContext(wl)
+		templateContext[definition.OutputFieldName] = liveWorkload.Object
+		if len(outputs) > 0 {
+			templateContext[definition.OutputsFieldName] = outputs
+		}
+		if len(traitOutputs) > 0 {
+			templateContext[TraitsFieldName] = traitOutputs
+		}
+		if len(children) > 0 {
+			templateContext[ChildrenFieldName] = children
+		}
+		return templateContext
+	}
+
+	templateContext := newTemplateContext(auxiliaries)
+	status.Healthy, status.Message, err = evalHealth(compDef.Spec.Status, templateContext, liveWorkload)
+	if err != nil {
+		return status, errors.WithMessagef(err, "evaluate health of %s %s", wl.GetKind(), wl.GetName())
+	}
+	if message, err := evalStatus(compDef.Spec.Status, templateContext, hc.componentProperties(compName, "")); err != nil {
+		return status, errors.WithMessagef(err, "evaluate status message of %s %s", wl.GetKind(), wl.GetName())
+	} else if len(message) != 0 {
+		status.Message = message
+	}
+
+	for _, traitType := range traitTypes {
+		traitStatus := common.ApplicationTraitStatus{Type: traitType, Healthy: true}
+		if def, ok := hc.appRevision.Spec.TraitDefinitions[traitType]; ok && def.Spec.Status != nil {
+			templateContext := newTemplateContext(traitOutputs[traitType])
+			if len(def.Spec.Status.HealthPolicy) != 0 {
+				traitStatus.Healthy, traitStatus.Message, err = evalHealth(def.Spec.Status, templateContext, nil)
+				if err != nil {
+					return status, errors.WithMessagef(err, "evaluate health of trait %q", traitType)
+				}
+			}
+			if message, err := evalStatus(def.Spec.Status, templateContext, hc.componentProperties(compName, traitType)); err != nil {
+				return status, errors.WithMessagef(err, "evaluate status message of trait %q", traitType)
+			} else if len(message) != 0 {
+				traitStatus.Message = message
+			}
+		}
+		status.Traits = append(status.Traits, traitStatus)
+	}
+	return status, nil
+}
+
+// getChildren gets the child resources of the workload selected by the child resource kinds of its definition,
+// they're grouped by kinds
+func (hc *Checker) getChildren(ctx context.Context, wl *unstructured.Unstructured, kinds []common.ChildResourceKind) (map[string][]interface{}, error) {
+	children := make(map[string][]interface{})
+	for _, kind := range kinds {
+		gv, err := schema.ParseGroupVersion(kind.APIVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid apiVersion of child resource kind %s", kind.Kind)
+		}
+		list, err := util.GetObjectsGivenGVKAndLabels(ctx, hc.c, gv.WithKind(kind.Kind), wl.GetNamespace(), kind.Selector)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot list child resources %s", kind.Kind)
+		}
+		for _, item := range list.Items {
+			children[kind.Kind] = append(children[kind.Kind], item.Object)
+		}
+	}
+	return children, nil
+}
+
+// componentProperties returns the properties of the component, or its trait if the trait type is specified,
+// recorded in the ApplicationRevision. They're the parameter of the customStatus template.
+func (hc *Checker) componentProperties(compName, traitType string) interface{} {
+	for _, comp := range hc.appRevision.Spec.Application.Spec.Components {
+		if comp.Name != compName {
+			continue
+		}
+		raw := comp.Properties.Raw
+		if len(traitType) != 0 {
+			raw = nil
+			for _, tr := range comp.Traits {
+				if tr.Type == traitType {
+					raw = tr.Properties.Raw
+					break
+				}
+			}
+		}
+		var properties map[string]interface{}
+		if len(raw) == 0 || json.Unmarshal(raw, &properties) != nil {
+			return nil
+		}
+		return properties
+	}
+	return nil
+}
+
+// getLiveResource gets the live state of the assembled resource, it returns nil if the resource is not found
+func (hc *Checker) getLiveResource(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := hc.c.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}, live); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "cannot get %s %s", obj.GetKind(), obj.GetName())
+	}
+	return live, nil
+}
+
+// templateContext is the base context of the health policy, the same as the one used in rendering
+func (hc *Checker) templateContext(wl *unstructured.Unstructured) map[string]interface{} {
+	labels := wl.GetLabels()
+	return map[string]interface{}{
+		process.ContextName:        labels[oam.LabelAppComponent],
+		process.ContextAppName:     labels[oam.LabelAppName],
+		process.ContextAppRevision: labels[oam.LabelAppRevision],
+	}
+}
+
+// evalHealth evaluates the health policy, or the built-in check of the live workload if there's no health policy
+func evalHealth(status *common.Status, templateContext map[string]interface{}, liveWorkload *unstructured.Unstructured) (bool, string, error) {
+	if status != nil && len(status.HealthPolicy) != 0 {
+		healthy, err := definition.CheckHealth(templateContext, status.HealthPolicy)
+		if err != nil {
+			return false, "", err
+		}
+		if !healthy {
+			return false, "healthPolicy is not satisfied", nil
+		}
+		return true, "", nil
+	}
+	if liveWorkload == nil {
+		return true, "", nil
+	}
+	healthy, message := CheckWorkload(liveWorkload)
+	klog.V(4).InfoS("Checked health by built-in rules", "kind", liveWorkload.GetKind(), "name", liveWorkload.GetName(),
+		"healthy", healthy)
+	return healthy, message, nil
+}
+
+// evalStatus renders the customStatus message, it returns empty if there's no customStatus
+func evalStatus(status *common.Status, templateContext map[string]interface{}, parameter interface{}) (string, error) {
+	if status == nil || len(status.CustomStatus) == 0 {
+		return "", nil
+	}
+	return definition.GetStatusMessage(templateContext, status.CustomStatus, parameter)
+}
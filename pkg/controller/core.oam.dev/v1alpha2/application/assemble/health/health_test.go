@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newResource(apiVersion, kind, name string, labels map[string]string, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+	}}
+	u.SetName(name)
+	u.SetNamespace("default")
+	u.SetLabels(labels)
+	if status != nil {
+		u.Object["status"] = status
+	}
+	return u
+}
+
+func TestCheckComponents(t *testing.T) {
+	appRev := &v1beta1.ApplicationRevision{}
+	appRev.Spec.ComponentDefinitions = map[string]v1beta1.ComponentDefinition{
+		"worker": {Spec: v1beta1.ComponentDefinitionSpec{Status: &common.Status{
+			HealthPolicy: `isHealth: context.output.status.readyReplicas == 2 && context.outputs.svc.metadata.name == "svc"`,
+			CustomStatus: `message: "\(parameter.image) is exposed by \(context.traits.ingress.ingress.metadata.name)"`,
+		}}},
+	}
+	appRev.Spec.Application.Spec.Components = []v1beta1.ApplicationComponent{{
+		Name:       "worker",
+		Type:       "worker",
+		Properties: runtime.RawExtension{Raw: []byte(`{"image":"nginx"}`)},
+	}}
+	appRev.Spec.TraitDefinitions = map[string]v1beta1.TraitDefinition{
+		"ingress": {Spec: v1beta1.TraitDefinitionSpec{Status: &common.Status{
+			HealthPolicy: `isHealth: context.outputs.ingress.metadata.name == "ready"`,
+		}}},
+	}
+
+	worker := newResource("apps/v1", "Deployment", "worker", map[string]string{oam.WorkloadTypeLabel: "worker"},
+		map[string]interface{}{"readyReplicas": int64(2)})
+	svc := newResource("v1", "Service", "svc", map[string]string{oam.TraitTypeLabel: "AuxiliaryWorkload", oam.TraitResource: "svc"}, nil)
+	ingress := newResource("networking.k8s.io/v1beta1", "Ingress", "ingress",
+		map[string]string{oam.TraitTypeLabel: "ingress", oam.TraitResource: "ingress"}, nil)
+	web := newResource("apps/v1", "Deployment", "web", map[string]string{oam.WorkloadTypeLabel: "webservice"},
+		map[string]interface{}{"availableReplicas": int64(0)})
+	missing := newResource("apps/v1", "Deployment", "missing", nil, nil)
+
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, worker, svc, ingress, web)
+	statuses, healthy, err := NewChecker(c, appRev).CheckComponents(context.Background(),
+		map[string]*unstructured.Unstructured{"worker": worker, "web": web, "missing": missing},
+		map[string][]*unstructured.Unstructured{"worker": {svc, ingress}})
+	assert.NoError(t, err)
+	assert.False(t, healthy)
+	assert.Equal(t, 3, len(statuses))
+
+	// components are sorted by name
+	assert.Equal(t, "missing", statuses[0].Name)
+	assert.False(t, statuses[0].Healthy)
+	assert.Equal(t, "Deployment missing is not found", statuses[0].Message)
+
+	// fall back to the built-in check since webservice has no healthPolicy
+	assert.Equal(t, "web", statuses[1].Name)
+	assert.False(t, statuses[1].Healthy)
+	assert.Equal(t, "0 of 1 replicas are ready", statuses[1].Message)
+
+	assert.Equal(t, "worker", statuses[2].Name)
+	assert.True(t, statuses[2].Healthy)
+	assert.Equal(t, "nginx is exposed by ingress", statuses[2].Message)
+	assert.Equal(t, []common.ApplicationTraitStatus{{Type: "ingress", Healthy: false, Message: "healthPolicy is not satisfied"}},
+		statuses[2].Traits)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assemble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	helmapi "github.com/oam-dev/kubevela/pkg/appfile/helm/flux2apis"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// HelmWorkloadPendingError means the workload of a Helm-based component is not created by Helm yet.
+// It doesn't block assembling other components, the component is reported as pending instead.
+type HelmWorkloadPendingError struct {
+	Release      string
+	Namespace    string
+	WorkloadKind string
+	WorkloadName string
+}
+
+func (e *HelmWorkloadPendingError) Error() string {
+	return fmt.Sprintf("waiting for HelmRelease %s to create %s %s", e.Release, e.WorkloadKind, e.WorkloadName)
+}
+
+// IsHelmWorkloadPending checks whether the error is caused by a Helm workload not created yet
+func IsHelmWorkloadPending(err error) bool {
+	var pending *HelmWorkloadPendingError
+	return errors.As(err, &pending)
+}
+
+// HelmWorkloadName returns the name of the HelmRelease of a Helm-based component and the name of the workload Helm
+// creates for it.
+func HelmWorkloadName(comp *v1alpha2.Component) (release, workload string, err error) {
+	rls, err := util.RawExtension2Unstructured(&comp.Spec.Helm.Release)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot get helm release from component")
+	}
+	rlsName := rls.GetName()
+
+	chartName, ok, err := unstructured.NestedString(rls.Object, helmapi.HelmChartNamePath...)
+	if err != nil || !ok {
+		return "", "", errors.New("cannot get helm chart name")
+	}
+
+	// qualifiedFullName is used as the name of target workload.
+	// It strictly follows the convention that Helm generate default full name as below:
+	// > We truncate at 63 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
+	// > If release name contains chart name it will be used as a full name.
+	qualifiedWorkloadName := rlsName
+	if !strings.Contains(rlsName, chartName) {
+		qualifiedWorkloadName = fmt.Sprintf("%s-%s", rlsName, chartName)
+		if len(qualifiedWorkloadName) > 63 {
+			qualifiedWorkloadName = strings.TrimSuffix(qualifiedWorkloadName[:63], "-")
+		}
+	}
+	return rlsName, qualifiedWorkloadName, nil
+}
+
+// HelmWorkloadToApplication maps a workload created by Helm to the Application rendering the HelmRelease, so the
+// caller can watch the workload kinds of Helm-based components and resume the pending components once Helm creates
+// the expected workloads.
+func HelmWorkloadToApplication(c client.Reader) handler.Mapper {
+	return handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		annots := o.Meta.GetAnnotations()
+		rlsName, rlsNamespace := annots["meta.helm.sh/release-name"], annots["meta.helm.sh/release-namespace"]
+		if len(rlsName) == 0 || len(rlsNamespace) == 0 || o.Meta.GetLabels()["app.kubernetes.io/managed-by"] != "Helm" {
+			return nil
+		}
+		rls := &unstructured.Unstructured{}
+		rls.SetGroupVersionKind(helmapi.HelmReleaseGVK)
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: rlsNamespace, Name: rlsName}, rls); err != nil {
+			klog.V(4).InfoS("Cannot get HelmRelease of the workload", "helmRelease", klog.KRef(rlsNamespace, rlsName), "err", err)
+			return nil
+		}
+		appName := rls.GetLabels()[oam.LabelAppName]
+		for _, owner := range rls.GetOwnerReferences() {
+			if owner.Kind == v1beta1.ApplicationKind {
+				appName = owner.Name
+				break
+			}
+		}
+		if len(appName) == 0 {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: rlsNamespace, Name: appName}}}
+	})
+}
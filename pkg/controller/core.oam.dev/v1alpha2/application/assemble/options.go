@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
 	kruisev1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
@@ -36,7 +38,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	helmapi "github.com/oam-dev/kubevela/pkg/appfile/helm/flux2apis"
 	"github.com/oam-dev/kubevela/pkg/oam"
-	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
 )
 
 // WorkloadOptionFn implement interface WorkloadOption
@@ -47,47 +49,49 @@ func (fn WorkloadOptionFn) ApplyToWorkload(wl *unstructured.Unstructured, comp *
 	return fn(wl, comp, compDefinition)
 }
 
+// DefaultHelmWorkloadDiscoveryTimeout is the default duration to wait for Helm to create the workload since the
+// HelmRelease is created
+const DefaultHelmWorkloadDiscoveryTimeout = 10 * time.Minute
+
 // DiscoveryHelmBasedWorkload only works for Helm-based component. It computes a qualifiedFullName for the workload and
 // try to get it from K8s cluster.
-// If not found, block down-streaming process until Helm creates the workload successfully.
+// If not found, the component is pending until Helm creates the workload successfully, see HelmWorkloadPendingError.
 func DiscoveryHelmBasedWorkload(ctx context.Context, c client.Reader) WorkloadOption {
+	return DiscoveryHelmBasedWorkloadWithTimeout(ctx, c, DefaultHelmWorkloadDiscoveryTimeout)
+}
+
+// DiscoveryHelmBasedWorkloadWithTimeout is the same as DiscoveryHelmBasedWorkload, except that the component fails
+// if Helm doesn't create the workload within the timeout since the HelmRelease is created.
+func DiscoveryHelmBasedWorkloadWithTimeout(ctx context.Context, c client.Reader, timeout time.Duration) WorkloadOption {
 	return WorkloadOptionFn(func(assembledWorkload *unstructured.Unstructured, comp *v1alpha2.Component, _ *v1beta1.ComponentDefinition) error {
-		return discoverHelmModuleWorkload(ctx, c, assembledWorkload, comp)
+		return discoverHelmModuleWorkload(ctx, c, assembledWorkload, comp, timeout)
 	})
 }
 
-func discoverHelmModuleWorkload(ctx context.Context, c client.Reader, assembledWorkload *unstructured.Unstructured, comp *v1alpha2.Component) error {
+func discoverHelmModuleWorkload(ctx context.Context, c client.Reader, assembledWorkload *unstructured.Unstructured,
+	comp *v1alpha2.Component, timeout time.Duration) error {
 	if comp == nil || comp.Spec.Helm == nil {
 		return nil
 	}
 
 	ns := assembledWorkload.GetNamespace()
-	rls, err := util.RawExtension2Unstructured(&comp.Spec.Helm.Release)
+	rlsName, qualifiedWorkloadName, err := HelmWorkloadName(comp)
 	if err != nil {
-		return errors.Wrap(err, "cannot get helm release from component")
-	}
-	rlsName := rls.GetName()
-
-	chartName, ok, err := unstructured.NestedString(rls.Object, helmapi.HelmChartNamePath...)
-	if err != nil || !ok {
-		return errors.New("cannot get helm chart name")
-	}
-
-	// qualifiedFullName is used as the name of target workload.
-	// It strictly follows the convention that Helm generate default full name as below:
-	// > We truncate at 63 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
-	// > If release name contains chart name it will be used as a full name.
-	qualifiedWorkloadName := rlsName
-	if !strings.Contains(rlsName, chartName) {
-		qualifiedWorkloadName = fmt.Sprintf("%s-%s", rlsName, chartName)
-		if len(qualifiedWorkloadName) > 63 {
-			qualifiedWorkloadName = strings.TrimSuffix(qualifiedWorkloadName[:63], "-")
-		}
+		return err
 	}
 
 	workloadByHelm := &unstructured.Unstructured{}
+	workloadByHelm.SetGroupVersionKind(assembledWorkload.GroupVersionKind())
 	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: qualifiedWorkloadName}, workloadByHelm); err != nil {
-		return err
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		return CheckHelmWorkloadDiscoveryTimeout(ctx, c, &HelmWorkloadPendingError{
+			Release:      rlsName,
+			Namespace:    ns,
+			WorkloadKind: assembledWorkload.GetKind(),
+			WorkloadName: qualifiedWorkloadName,
+		}, timeout)
 	}
 
 	// check it's created by helm and match the release info
@@ -106,6 +110,29 @@ func discoverHelmModuleWorkload(ctx context.Context, c client.Reader, assembledW
 	return nil
 }
 
+// CheckHelmWorkloadDiscoveryTimeout returns the pending error if the HelmRelease is created within the timeout,
+// otherwise a terminal error telling the workload is never created by Helm. The timeout is disabled if it's zero.
+func CheckHelmWorkloadDiscoveryTimeout(ctx context.Context, c client.Reader, pending *HelmWorkloadPendingError, timeout time.Duration) error {
+	rls := &unstructured.Unstructured{}
+	rls.SetGroupVersionKind(helmapi.HelmReleaseGVK)
+	if err := c.Get(ctx, client.ObjectKey{Namespace: pending.Namespace, Name: pending.Release}, rls); err != nil {
+		if kerrors.IsNotFound(err) {
+			// the HelmRelease itself is not emitted yet
+			return pending
+		}
+		return err
+	}
+	created := rls.GetCreationTimestamp()
+	if timeout > 0 && !created.IsZero() {
+		if waited := time.Since(created.Time); waited > timeout {
+			return errors.Errorf("HelmRelease %s didn't create %s %s in %s, please check the status of the HelmRelease",
+				pending.Release, pending.WorkloadKind, pending.WorkloadName, timeout)
+		}
+	}
+	klog.InfoS(pending.Error(), "helmRelease", klog.KRef(pending.Namespace, pending.Release))
+	return pending
+}
+
 // NameNonInplaceUpgradableWorkload set workload name with component revision name to override component name.
 func NameNonInplaceUpgradableWorkload() WorkloadOption {
 	return WorkloadOptionFn(func(wl *unstructured.Unstructured, comp *v1alpha2.Component, _ *v1beta1.ComponentDefinition) error {
@@ -172,3 +199,131 @@ func PrepareWorkloadForRollout() WorkloadOption {
 			assembledWorkload.GroupVersionKind().String())
 	})
 }
+
+// ValidateOptionFn implement interface ValidateOption
+type ValidateOptionFn func(map[string]*unstructured.Unstructured, map[string][]*unstructured.Unstructured) error
+
+// Validate will validate the assembled workloads and traits using the function
+func (fn ValidateOptionFn) Validate(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured) error {
+	return fn(workloads, traits)
+}
+
+// ForbidFields rejects the application if any assembled workload or trait has a value on one of the given field paths,
+// e.g., "spec.template.spec.hostNetwork".
+func ForbidFields(fieldPaths ...string) ValidateOption {
+	return ValidateOptionFn(func(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured) error {
+		return forEachAssembledResource(workloads, traits, func(compName string, obj *unstructured.Unstructured) error {
+			pv := fieldpath.Pave(obj.UnstructuredContent())
+			for _, path := range fieldPaths {
+				if _, err := pv.GetValue(path); err == nil {
+					return fmt.Errorf("field %q of %s %q in component %q is forbidden", path, obj.GetKind(), obj.GetName(), compName)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// AllowImageRegistries rejects the application if any container image in assembled workloads or traits is not pulled
+// from one of the given registries, e.g., "docker.io/oamdev" or "registry.example.com". The images and registries
+// without registry host are in Docker Hub.
+func AllowImageRegistries(registries ...string) ValidateOption {
+	return ValidateOptionFn(func(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured) error {
+		return forEachAssembledResource(workloads, traits, func(compName string, obj *unstructured.Unstructured) error {
+			for _, image := range findContainerImages(obj.UnstructuredContent()) {
+				if !imageInRegistries(image, registries) {
+					return fmt.Errorf("image %q of %s %q in component %q is not from allowed registries %v", image, obj.GetKind(), obj.GetName(), compName, registries)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func forEachAssembledResource(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured,
+	fn func(compName string, obj *unstructured.Unstructured) error) error {
+	for compName, wl := range workloads {
+		if err := fn(compName, wl); err != nil {
+			return err
+		}
+	}
+	for compName, ts := range traits {
+		for _, t := range ts {
+			if err := fn(compName, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findContainerImages walks through the object and collects images of all containers and initContainers,
+// no matter where the pod template is located
+func findContainerImages(obj interface{}) []string {
+	var images []string
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key == "containers" || key == "initContainers" {
+				if containers, ok := value.([]interface{}); ok {
+					for _, c := range containers {
+						if container, ok := c.(map[string]interface{}); ok {
+							if image, ok := container["image"].(string); ok {
+								images = append(images, image)
+							}
+						}
+					}
+					continue
+				}
+			}
+			images = append(images, findContainerImages(value)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			images = append(images, findContainerImages(item)...)
+		}
+	}
+	return images
+}
+
+// imageInRegistries checks the image against the registries after normalizing both, so the images without registry
+// host are pulled from Docker Hub, e.g., "nginx" is from "docker.io/library" and "oamdev/app" is from "oamdev" or
+// "docker.io/oamdev". The images that cannot be parsed are not from any registry.
+func imageInRegistries(image string, registries []string) bool {
+	ref, err := oci.ParseReference(image)
+	if err != nil {
+		return false
+	}
+	host, repo := normalizeRegistryHost(ref.Registry), ref.Repository
+	for _, registry := range registries {
+		h, path := splitRegistry(registry)
+		if h != host {
+			continue
+		}
+		if len(path) == 0 || repo == path || strings.HasPrefix(repo, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRegistry splits the registry into the host and the path of repositories in it, the registry is in Docker Hub
+// if its first component isn't a host, the same as the images
+func splitRegistry(registry string) (string, string) {
+	registry = strings.Trim(registry, "/")
+	parts := strings.SplitN(registry, "/", 2)
+	if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+		if len(parts) == 1 {
+			return normalizeRegistryHost(parts[0]), ""
+		}
+		return normalizeRegistryHost(parts[0]), parts[1]
+	}
+	return oci.DefaultRegistry, registry
+}
+
+func normalizeRegistryHost(host string) string {
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return oci.DefaultRegistry
+	}
+	return host
+}
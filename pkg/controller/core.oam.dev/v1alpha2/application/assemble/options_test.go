@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
@@ -32,8 +34,11 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/openkruise/kruise-api/apps/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
@@ -144,6 +149,69 @@ var _ = Describe("Test WorkloadOption", func() {
 
 	})
 
+	Context("test ValidateOption", func() {
+		It("test ForbidFields ValidateOption", func() {
+			By("Add ForbidFields ValidateOption with a field the workload doesn't have")
+			ao := NewAppManifests(appRev).WithValidateOption(ForbidFields("spec.template.spec.hostNetwork"))
+			_, _, _, err := ao.GroupAssembledManifests()
+			Expect(err).Should(BeNil())
+
+			By("Add ForbidFields ValidateOption with a field the workload has")
+			ao = NewAppManifests(appRev).WithValidateOption(ForbidFields("spec.selector"))
+			_, _, _, err = ao.GroupAssembledManifests()
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring(`field "spec.selector" of Deployment`))
+		})
+
+		It("test AllowImageRegistries ValidateOption", func() {
+			By("Add AllowImageRegistries ValidateOption accepting the image")
+			ao := NewAppManifests(appRev).WithValidateOption(AllowImageRegistries("crccheck/"))
+			_, _, _, err := ao.GroupAssembledManifests()
+			Expect(err).Should(BeNil())
+
+			By("Add AllowImageRegistries ValidateOption rejecting the image")
+			ao = NewAppManifests(appRev).WithValidateOption(AllowImageRegistries("registry.example.com"))
+			_, err = ao.AssembledManifests()
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring(`image "crccheck/hello-world"`))
+		})
+
+		DescribeTable("test imageInRegistries", func(image string, registries []string, want bool) {
+			Expect(imageInRegistries(image, registries)).Should(Equal(want))
+		},
+			Entry("image with registry host", "registry.example.com/team/app:v1", []string{"registry.example.com"}, true),
+			Entry("image in a path of the registry", "registry.example.com/team/app:v1", []string{"registry.example.com/team/"}, true),
+			Entry("image in another path of the registry", "registry.example.com/other/app:v1", []string{"registry.example.com/team"}, false),
+			Entry("image with a prefix of the path", "registry.example.com/team-b/app:v1", []string{"registry.example.com/team"}, false),
+			Entry("image in a registry with port", "localhost:5000/app@sha256:"+strings.Repeat("a", 64), []string{"localhost:5000"}, true),
+			Entry("image in Docker Hub without registry host", "oamdev/app:v1", []string{"docker.io/oamdev"}, true),
+			Entry("image in Docker Hub with registry host", "docker.io/oamdev/app", []string{"oamdev"}, true),
+			Entry("image in Docker Hub with legacy registry host", "index.docker.io/oamdev/app", []string{"docker.io/oamdev"}, true),
+			Entry("official image in Docker Hub", "nginx:1.20", []string{"docker.io/library"}, true),
+			Entry("official image in Docker Hub isn't in other namespaces", "nginx", []string{"docker.io/oamdev"}, false),
+			Entry("image in Docker Hub isn't in other registries", "oamdev/app", []string{"registry.example.com/oamdev"}, false),
+			Entry("image with the registry as the first path component", "docker.io.example.com/oamdev/app", []string{"docker.io"}, false),
+			Entry("invalid image", "oamdev/app@md5:abc", []string{"docker.io/oamdev"}, false),
+		)
+
+		It("test ValidateOption runs after WorkloadOption", func() {
+			By("Add a ValidateOption checking the workload name set by NameNonInplaceUpgradableWorkload")
+			ao := NewAppManifests(appRev).
+				WithWorkloadOption(NameNonInplaceUpgradableWorkload()).
+				WithValidateOption(ValidateOptionFn(func(workloads map[string]*unstructured.Unstructured, traits map[string][]*unstructured.Unstructured) error {
+					if workloads[compName].GetName() != compRevName {
+						return errors.New("workload option is not applied")
+					}
+					// modification should not take effect
+					workloads[compName].SetName("modified")
+					return nil
+				}))
+			workloads, _, _, err := ao.GroupAssembledManifests()
+			Expect(err).Should(BeNil())
+			Expect(workloads[compName].GetName()).Should(Equal(compRevName))
+		})
+	})
+
 	Describe("test DiscoveryHelmBasedWorkload", func() {
 		ns := "test-ns"
 		releaseName := "test-rls"
@@ -173,6 +241,21 @@ var _ = Describe("Test WorkloadOption", func() {
 			"meta.helm.sh/release-name":      releaseName,
 			"meta.helm.sh/release-namespace": ns,
 		})
+		deployment := &unstructured.Unstructured{}
+		deployment.SetAPIVersion("apps/v1")
+		deployment.SetKind("Deployment")
+		// mockHelm mocks a cluster where the workload is not created by Helm, and the HelmRelease is created at rlsCreated
+		mockHelm := func(rlsCreated time.Time) client.Reader {
+			return &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+				o, _ := obj.(*unstructured.Unstructured)
+				if o.GroupVersionKind() != helmapi.HelmReleaseGVK {
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				}
+				o.SetName(key.Name)
+				o.SetCreationTimestamp(metav1.NewTime(rlsCreated))
+				return nil
+			}}
+		}
 		type SubCase struct {
 			reason         string
 			c              client.Reader
@@ -192,7 +275,10 @@ var _ = Describe("Test WorkloadOption", func() {
 			comp.Spec.Helm = tc.helm
 			assembledWorkload := &unstructured.Unstructured{}
 			assembledWorkload.SetNamespace(ns)
-			err := discoverHelmModuleWorkload(context.Background(), tc.c, assembledWorkload, comp)
+			if tc.workloadInComp != nil {
+				assembledWorkload.SetGroupVersionKind(tc.workloadInComp.GroupVersionKind())
+			}
+			err := discoverHelmModuleWorkload(context.Background(), tc.c, assembledWorkload, comp, time.Minute)
 
 			By("Verify error")
 			diff := cmp.Diff(tc.wantErr, err, test.EquateErrors())
@@ -259,6 +345,25 @@ var _ = Describe("Test WorkloadOption", func() {
 				wantWorkload: wl.DeepCopy(),
 				wantErr:      nil,
 			}),
+			Entry("WorkloadPending", SubCase{
+				reason: "A pending error should occur because Helm doesn't create the workload yet",
+				c:      mockHelm(time.Now()),
+				helm: &common.Helm{
+					Release: runtime.RawExtension{Raw: releaseRaw},
+				},
+				workloadInComp: deployment.DeepCopy(),
+				wantErr: &HelmWorkloadPendingError{Release: releaseName, Namespace: ns,
+					WorkloadKind: "Deployment", WorkloadName: "test-rls-test-chart"},
+			}),
+			Entry("WorkloadDiscoveryTimeout", SubCase{
+				reason: "An error should occur because Helm doesn't create the workload within the timeout",
+				c:      mockHelm(time.Now().Add(-time.Hour)),
+				helm: &common.Helm{
+					Release: runtime.RawExtension{Raw: releaseRaw},
+				},
+				workloadInComp: deployment.DeepCopy(),
+				wantErr:        errors.New("HelmRelease test-rls didn't create Deployment test-rls-test-chart in 1m0s, please check the status of the HelmRelease"),
+			}),
 		)
 	})
 })
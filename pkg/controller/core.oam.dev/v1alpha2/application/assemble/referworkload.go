@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assemble
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// ReferredWorkloadDiscoverer discovers the live object of the existing workload referred by a component, see
+// oam.AnnotationReferWorkload. It returns util.ReferredWorkloadMissingError if the workload is not found.
+type ReferredWorkloadDiscoverer func(rendered *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
+// DiscoveryReferredWorkload discovers the referred workload from K8s cluster, so the workload reference injected
+// into traits follows the actual apiVersion, kind and name of the live object
+func DiscoveryReferredWorkload(ctx context.Context, c client.Reader) ReferredWorkloadDiscoverer {
+	return func(rendered *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		return util.GetReferredWorkload(ctx, c, rendered)
+	}
+}
+
+// WithReferredWorkloadDiscoverer sets the ReferredWorkloadDiscoverer, the rendered workload is used as the referred
+// one if it's not set, e.g., in dry-run
+func (am *AppManifests) WithReferredWorkloadDiscoverer(d ReferredWorkloadDiscoverer) *AppManifests {
+	am.ReferredWorkloadDiscoverer = d
+	return am
+}
+
+// ReferredWorkloads do assemble and return the existing workloads referred by the components, they're excluded
+// from assembled manifests since they're not managed by the application. Key is the component name.
+func (am *AppManifests) ReferredWorkloads() (map[string]*unstructured.Unstructured, error) {
+	if !am.finalized {
+		am.assemble()
+	}
+	if am.err != nil {
+		return nil, am.err
+	}
+	r := make(map[string]*unstructured.Unstructured, len(am.referredWorkloads))
+	for k, wl := range am.referredWorkloads {
+		r[k] = wl.DeepCopy()
+	}
+	return r, nil
+}
+
+func isReferredWorkload(comp *v1alpha2.Component) bool {
+	wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	return err == nil && util.IsReferredWorkload(wl)
+}
+
+// assembleReferredWorkload locates the existing workload referred by the component. None of the generic rules and
+// WorkloadOptions is applied since the workload is not managed by the application.
+func (am *AppManifests) assembleReferredWorkload(comp *v1alpha2.Component) (*unstructured.Unstructured, error) {
+	wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot convert raw workload in component %q", comp.Name)
+	}
+	wl.SetName(util.ReferredWorkloadName(wl))
+	am.setNamespace(wl)
+	if am.ReferredWorkloadDiscoverer == nil {
+		return wl, nil
+	}
+	live, err := am.ReferredWorkloadDiscoverer(wl)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot discover the workload referred by component %q", comp.Name)
+	}
+	klog.InfoS("Successfully discover a referred workload", "workload", klog.KObj(live), "APIVersion", live.GetAPIVersion(), "Kind", live.GetKind())
+	return live, nil
+}
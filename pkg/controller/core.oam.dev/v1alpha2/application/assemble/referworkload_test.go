@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assemble
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+var _ = Describe("Test assemble components referring existing workloads", func() {
+	const compName = "test-comp"
+	var appRev *v1beta1.ApplicationRevision
+
+	BeforeEach(func() {
+		appRev = &v1beta1.ApplicationRevision{}
+		b, err := ioutil.ReadFile("./testdata/apprevision.yaml")
+		Expect(err).Should(BeNil())
+		Expect(yaml.Unmarshal(b, appRev)).Should(Succeed())
+
+		By("Refer the existing Deployment legacy-app in the workload of the component")
+		comp, err := convertRawExtention2Component(appRev.Spec.Components[0].Raw)
+		Expect(err).Should(BeNil())
+		wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		Expect(err).Should(BeNil())
+		util.AddAnnotations(wl, map[string]string{oam.AnnotationReferWorkload: "legacy-app"})
+		rawWorkload, err := json.Marshal(wl.Object)
+		Expect(err).Should(BeNil())
+		comp.Spec.Workload = runtime.RawExtension{Raw: rawWorkload}
+		raw, err := json.Marshal(comp)
+		Expect(err).Should(BeNil())
+		appRev.Spec.Components[0].Raw = runtime.RawExtension{Raw: raw}
+	})
+
+	workloadRefOfScaler := func(traits map[string]StagedTraits) map[string]interface{} {
+		scaler := traits[compName].All()[2]
+		wlRef, _, err := unstructured.NestedMap(scaler.Object, "spec", "workloadRef")
+		Expect(err).Should(BeNil())
+		return wlRef
+	}
+
+	It("inject the rendered workload reference into traits without discovery", func() {
+		am := NewAppManifests(appRev)
+		workloads, traits, _, err := am.GroupAssembledManifests()
+		Expect(err).Should(BeNil())
+		Expect(workloads).Should(BeEmpty())
+		Expect(traits[compName].All()).Should(HaveLen(3))
+		Expect(workloadRefOfScaler(traits)).Should(Equal(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"name":       "legacy-app",
+		}))
+		referred, err := am.ReferredWorkloads()
+		Expect(err).Should(BeNil())
+		Expect(referred[compName].GetName()).Should(Equal("legacy-app"))
+		Expect(referred[compName].GetNamespace()).Should(Equal("default"))
+	})
+
+	It("inject the reference of the live workload into traits", func() {
+		am := NewAppManifests(appRev).WithReferredWorkloadDiscoverer(func(rendered *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			Expect(rendered.GetName()).Should(Equal("legacy-app"))
+			live := &unstructured.Unstructured{}
+			live.SetAPIVersion("apps/v1beta2")
+			live.SetKind("Deployment")
+			live.SetName("legacy-app")
+			live.SetNamespace(rendered.GetNamespace())
+			return live, nil
+		})
+		_, traits, _, err := am.GroupAssembledManifests()
+		Expect(err).Should(BeNil())
+		Expect(workloadRefOfScaler(traits)).Should(Equal(map[string]interface{}{
+			"apiVersion": "apps/v1beta2",
+			"kind":       "Deployment",
+			"name":       "legacy-app",
+		}))
+	})
+
+	It("keep the component pending if the referred workload is missing", func() {
+		am := NewAppManifests(appRev).WithReferredWorkloadDiscoverer(func(rendered *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+			return nil, &util.ReferredWorkloadMissingError{
+				APIVersion: rendered.GetAPIVersion(),
+				Kind:       rendered.GetKind(),
+				Namespace:  rendered.GetNamespace(),
+				Name:       rendered.GetName(),
+			}
+		})
+		_, traits, _, err := am.GroupAssembledManifests()
+		Expect(err).Should(BeNil())
+		Expect(traits).ShouldNot(HaveKey(compName))
+		pending, err := am.PendingComponents()
+		Expect(err).Should(BeNil())
+		Expect(pending[compName]).Should(ContainSubstring("legacy-app"))
+	})
+})
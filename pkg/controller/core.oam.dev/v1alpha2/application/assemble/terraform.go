@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assemble
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+)
+
+// terraformConfigurationGroup is the API group of the Terraform Configuration rendered for Terraform-based components
+const terraformConfigurationGroup = "terraform.core.oam.dev"
+
+// isTerraformBasedWorkload returns true if the workload is the Terraform Configuration of a Terraform-based component
+func isTerraformBasedWorkload(wl *unstructured.Unstructured, compDefinition *v1beta1.ComponentDefinition) bool {
+	if compDefinition == nil || compDefinition.Spec.Schematic == nil || compDefinition.Spec.Schematic.Terraform == nil {
+		return false
+	}
+	return wl.GetObjectKind().GroupVersionKind().Group == terraformConfigurationGroup && wl.GetKind() == "Configuration"
+}
+
+// assembleTerraformConfiguration wires the component properties rendered as variables to the variables declared in
+// the Terraform module, the properties not declared are dropped and the required variables must be set. The
+// connection secret is written to the namespace of the Configuration if its namespace is unspecified.
+func assembleTerraformConfiguration(wl *unstructured.Unstructured, compDefinition *v1beta1.ComponentDefinition) error {
+	mod, err := appfile.ParseTerraformModule(compDefinition.Spec.Schematic.Terraform)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid Terraform configuration of ComponentDefinition %q", compDefinition.Name)
+	}
+	variables, _, err := unstructured.NestedMap(wl.Object, "spec", "variable")
+	if err != nil {
+		return errors.Wrap(err, "cannot get the variables of the Terraform Configuration")
+	}
+	wired := make(map[string]interface{}, len(variables))
+	for name, v := range variables {
+		if _, ok := mod.Variables[name]; ok {
+			wired[name] = v
+		}
+	}
+	var missing []string
+	for name, v := range mod.Variables {
+		if _, ok := wired[name]; !ok && v.Required {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return errors.Errorf("required variables of the Terraform module are not set: %s", strings.Join(missing, ", "))
+	}
+	if len(wired) == 0 {
+		unstructured.RemoveNestedField(wl.Object, "spec", "variable")
+	} else if err := unstructured.SetNestedMap(wl.Object, wired, "spec", "variable"); err != nil {
+		return errors.Wrap(err, "cannot set the variables of the Terraform Configuration")
+	}
+
+	secretName, _, _ := unstructured.NestedString(wl.Object, "spec", appfile.WriteConnectionSecretToRefKey, "name")
+	secretNamespace, _, _ := unstructured.NestedString(wl.Object, "spec", appfile.WriteConnectionSecretToRefKey, "namespace")
+	if len(secretName) != 0 && len(secretNamespace) == 0 {
+		if err := unstructured.SetNestedField(wl.Object, wl.GetNamespace(), "spec", appfile.WriteConnectionSecretToRefKey, "namespace"); err != nil {
+			return errors.Wrap(err, "cannot set the namespace of the connection secret")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assemble
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+var _ = Describe("Test assemble Terraform Configuration", func() {
+	compDefinition := &v1beta1.ComponentDefinition{Spec: v1beta1.ComponentDefinitionSpec{
+		Schematic: &common.Schematic{Terraform: &common.Terraform{Configuration: `
+variable "bucket" {
+  type = string
+}
+
+variable "acl" {
+  default = "private"
+}
+`}},
+	}}
+	newConfiguration := func(variable map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "terraform.core.oam.dev/v1beta1",
+			"kind":       "Configuration",
+			"metadata":   map[string]interface{}{"name": "oss", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"hcl":                        "...",
+				"variable":                   variable,
+				"writeConnectionSecretToRef": map[string]interface{}{"name": "oss-conn"},
+			},
+		}}
+	}
+
+	It("wire the properties to the declared variables", func() {
+		wl := newConfiguration(map[string]interface{}{"bucket": "vela", "unknown": "dropped"})
+		Expect(isTerraformBasedWorkload(wl, compDefinition)).Should(BeTrue())
+		Expect(assembleTerraformConfiguration(wl, compDefinition)).Should(Succeed())
+		variable, _, _ := unstructured.NestedMap(wl.Object, "spec", "variable")
+		Expect(variable).Should(Equal(map[string]interface{}{"bucket": "vela"}))
+		ns, _, _ := unstructured.NestedString(wl.Object, "spec", "writeConnectionSecretToRef", "namespace")
+		Expect(ns).Should(Equal("default"))
+	})
+
+	It("reject the configuration missing required variables", func() {
+		wl := newConfiguration(map[string]interface{}{"acl": "public"})
+		err := assembleTerraformConfiguration(wl, compDefinition)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("bucket"))
+	})
+
+	It("skip the workloads of other components", func() {
+		wl := newConfiguration(nil)
+		Expect(isTerraformBasedWorkload(wl, &v1beta1.ComponentDefinition{})).Should(BeFalse())
+	})
+})
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// promoteBlueGreen decides the revisions selected by the stable Services of the blue-green components before they're
+// rendered. The newest revision of a component is promoted once a blue-green-promote step selecting it is reached and
+// approved, or right away if no step selects it. The revision rendered first is always active.
+func (h *appHandler) promoteBlueGreen() error {
+	approvals, err := workflow.ParseApprovals(h.app.Spec.Workflow)
+	if err != nil {
+		return err
+	}
+	gated, promoted := map[string]bool{}, map[string]bool{}
+	for _, wl := range h.appfile.Workloads {
+		if wl.BlueGreen == nil {
+			continue
+		}
+		for _, spec := range h.appfile.BlueGreenPromoteSteps {
+			gated[wl.Name] = gated[wl.Name] || spec.Selects(wl.Name)
+		}
+	}
+	for _, step := range h.app.Spec.Workflow {
+		spec, ok := h.appfile.BlueGreenPromoteSteps[step.Name]
+		if ok && !h.isStepAwaitingCondition(step.Name) && !h.isStepSkipped(step.Name) && h.approvePromoteStep(step.Name, approvals[step.Name]) {
+			for _, wl := range h.appfile.Workloads {
+				if wl.BlueGreen != nil && spec.Selects(wl.Name) {
+					promoted[wl.Name] = true
+				}
+			}
+		}
+		if !h.isStepFinished(step.Name) {
+			// the following steps wait for this one to finish
+			break
+		}
+	}
+	active := map[string]string{}
+	for _, s := range h.app.Status.BlueGreen {
+		if gated[s.Component] && !promoted[s.Component] {
+			active[s.Component] = s.Active
+		}
+	}
+	h.appfile.BlueGreenActive = active
+	return nil
+}
+
+// approvePromoteStep returns true if the blue-green-promote step reached is approved or declares no approvers, the
+// step keeps running until it's approved by the annotations set with `vela workflow approve`
+func (h *appHandler) approvePromoteStep(stepName string, approval *workflow.ApprovalSpec) bool {
+	status := h.workflowStepStatus(stepName)
+	if status == nil {
+		h.app.Status.Workflow = append(h.app.Status.Workflow, common.WorkflowStepStatus{
+			Name:  stepName,
+			Type:  workflow.TypeBlueGreenPromote,
+			Phase: common.WorkflowStepPhaseRunning,
+		})
+		status = &h.app.Status.Workflow[len(h.app.Status.Workflow)-1]
+	}
+	if status.Phase == common.WorkflowStepPhaseSucceeded {
+		return true
+	}
+	if approval != nil {
+		if status.Approval == nil {
+			status.Approval = &common.WorkflowStepApproval{Users: approval.Users, Groups: approval.Groups}
+			status.Message = "waiting for approval to promote"
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonApproval,
+				fmt.Sprintf(velatypes.MessageApproval, stepName, describeApprovers(approval))))
+		}
+		annotations := h.app.GetAnnotations()
+		approvedBy := annotations[oam.AnnotationApprovedBy]
+		if annotations[oam.AnnotationApproveStep] != stepName || len(approvedBy) == 0 {
+			return false
+		}
+		now := metav1.Now()
+		status.Approval.ApprovedBy, status.Approval.ApprovedAt = approvedBy, &now
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonApproved, fmt.Sprintf(velatypes.MessageApproved, stepName, approvedBy)))
+	}
+	status.Phase = common.WorkflowStepPhaseSucceeded
+	status.Message = "promoted"
+	h.decisions.Record(decision.StageSchedule, stepName, "newest blue-green revisions promoted")
+	return true
+}
+
+// recordBlueGreen records the revisions of the blue-green components rendered. The workload of the revision replaced
+// by a promotion retires after the soak period of the trait, the one retiring before is retired right away.
+func (h *appHandler) recordBlueGreen(ctx context.Context, comps []*v1alpha2.Component) {
+	now := metav1.Now()
+	for _, wl := range h.appfile.Workloads {
+		newest, ok := h.appfile.BlueGreenRevisions[wl.Name]
+		if !ok {
+			continue
+		}
+		active, ok := h.appfile.BlueGreenActive[wl.Name]
+		if !ok {
+			active = newest
+		}
+		status := h.blueGreenStatus(wl.Name)
+		if status == nil {
+			h.app.Status.BlueGreen = append(h.app.Status.BlueGreen, common.BlueGreenStatus{
+				Component:  wl.Name,
+				Active:     active,
+				Preview:    newest,
+				PromotedAt: &now,
+			})
+			continue
+		}
+		status.Preview = newest
+		if status.Active == active {
+			continue
+		}
+		activeName := appfile.BlueGreenWorkloadName(wl.Name, active)
+		if status.Retiring != nil && status.Retiring.Name != activeName {
+			h.retire(ctx, wl.Name, status)
+		}
+		var retiring *runtimev1alpha1.TypedReference
+		for _, comp := range comps {
+			if comp.Name != wl.Name {
+				continue
+			}
+			if obj, err := util.RawExtension2Unstructured(&comp.Spec.Workload); err == nil {
+				retiring = &runtimev1alpha1.TypedReference{
+					APIVersion: obj.GetAPIVersion(),
+					Kind:       obj.GetKind(),
+					Name:       appfile.BlueGreenWorkloadName(wl.Name, status.Active),
+				}
+			}
+		}
+		retireAt := metav1.NewTime(now.Add(wl.BlueGreen.Soak))
+		h.decisions.Record(decision.StageApply, wl.Name, "blue-green revision %s promoted, revision %s retires at %s",
+			active, status.Active, retireAt.Format(time.RFC3339))
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonPromoted,
+			fmt.Sprintf(velatypes.MessagePromoted, active, wl.Name, status.Active, wl.BlueGreen.Soak)))
+		status.Active, status.PromotedAt = active, &now
+		status.Retiring, status.RetireAt = retiring, &retireAt
+	}
+}
+
+// retireBlueGreen deletes the workloads of the retired revisions whose soak periods passed. It returns the duration
+// after which the next one is deleted, zero if none is retiring.
+func (h *appHandler) retireBlueGreen(ctx context.Context) time.Duration {
+	var requeue time.Duration
+	now := time.Now()
+	for i := range h.app.Status.BlueGreen {
+		status := &h.app.Status.BlueGreen[i]
+		if status.Retiring == nil || status.RetireAt == nil {
+			continue
+		}
+		if wait := status.RetireAt.Sub(now); wait > 0 {
+			if requeue == 0 || wait < requeue {
+				requeue = wait
+			}
+			continue
+		}
+		h.retire(ctx, status.Component, status)
+	}
+	return requeue
+}
+
+// retire deletes the workload of the retiring revision of the component, it's kept in the status to be retried if
+// the deletion fails
+func (h *appHandler) retire(ctx context.Context, compName string, status *common.BlueGreenStatus) {
+	ref := status.Retiring
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetNamespace(h.app.Namespace)
+	obj.SetName(ref.Name)
+	if err := h.r.Client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+		h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedRetire,
+			errors.Wrapf(err, "cannot delete %s %s of component %s", ref.Kind, ref.Name, compName)))
+		return
+	}
+	h.decisions.Record(decision.StageGC, compName, "retired %s %s", ref.Kind, ref.Name)
+	h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonRetired, fmt.Sprintf(velatypes.MessageRetired, ref.Name, compName)))
+	status.Retiring, status.RetireAt = nil, nil
+}
+
+func (h *appHandler) blueGreenStatus(compName string) *common.BlueGreenStatus {
+	for i := range h.app.Status.BlueGreen {
+		if h.app.Status.BlueGreen[i].Component == compName {
+			return &h.app.Status.BlueGreen[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"strings"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// the conditions beyond the stages of the reconciliation, they're only set if the application depends on other
+// applications or refers existing workloads
+const (
+	appDependenciesCondition   runtimev1alpha1.ConditionType = "AppDependencies"
+	referredWorkloadsCondition runtimev1alpha1.ConditionType = "ReferredWorkloads"
+)
+
+// the conditions set by the earlier versions of the controller, they're still maintained along with the stages of
+// the reconciliation reporting the same, so the tools checking them keep working
+const (
+	legacyBuiltCondition          runtimev1alpha1.ConditionType = "Built"
+	legacyAppliedCondition        runtimev1alpha1.ConditionType = "Applied"
+	legacyRolloutCondition        runtimev1alpha1.ConditionType = "Rollout"
+	legacyHealthCheckCondition    runtimev1alpha1.ConditionType = "HealthCheck"
+	legacyAdmissionCheckCondition runtimev1alpha1.ConditionType = "AdmissionCheck"
+)
+
+// The reasons of the failed conditions of the application
+const (
+	reasonSpecLimitExceeded      runtimev1alpha1.ConditionReason = "SpecLimitExceeded"
+	reasonCannotLoadOutputs      runtimev1alpha1.ConditionReason = "CannotLoadWorkflowOutputs"
+	reasonCannotParse            runtimev1alpha1.ConditionReason = "CannotParse"
+	reasonCannotGenerateRevision runtimev1alpha1.ConditionReason = "CannotGenerateRevision"
+	reasonDependenciesUnhealthy  runtimev1alpha1.ConditionReason = "DependenciesUnhealthy"
+	reasonCannotRender           runtimev1alpha1.ConditionReason = "CannotRender"
+	reasonQuotaExceeded          runtimev1alpha1.ConditionReason = "QuotaExceeded"
+	reasonAdmissionRejected      runtimev1alpha1.ConditionReason = "AdmissionRejected"
+	reasonCannotTrackResources   runtimev1alpha1.ConditionReason = "CannotTrackResources"
+	reasonCannotApply            runtimev1alpha1.ConditionReason = "CannotApply"
+	reasonCannotRollout          runtimev1alpha1.ConditionReason = "CannotRollout"
+	reasonCannotFinalize         runtimev1alpha1.ConditionReason = "CannotFinalize"
+	reasonCannotCheckHealth      runtimev1alpha1.ConditionReason = "CannotCheckHealth"
+	reasonUnhealthy              runtimev1alpha1.ConditionReason = "Unhealthy"
+	reasonReferredWorkloadAbsent runtimev1alpha1.ConditionReason = "ReferredWorkloadAbsent"
+)
+
+// conditionManager sets the conditions of an application. Each condition is stamped with the generation of the
+// application it's observed in, and keeps its lastTransitionTime until its status changes.
+type conditionManager struct {
+	app *v1beta1.Application
+}
+
+// conditions returns the manager of the conditions of the application reconciled
+func (h *appHandler) conditions() conditionManager {
+	return conditionManager{app: h.app}
+}
+
+// ready marks the condition true, its reason is the type of the condition
+func (m conditionManager) ready(t runtimev1alpha1.ConditionType) {
+	m.set(t, corev1.ConditionTrue, runtimev1alpha1.ConditionReason(t), "")
+}
+
+// failed marks the condition false for the reason, the error is the message
+func (m conditionManager) failed(t runtimev1alpha1.ConditionType, reason runtimev1alpha1.ConditionReason, err error) {
+	m.set(t, corev1.ConditionFalse, reason, err.Error())
+}
+
+// remove removes the condition, e.g., the application no longer depends on other applications
+func (m conditionManager) remove(t runtimev1alpha1.ConditionType) {
+	status := &m.app.Status
+	kept := status.Conditions[:0]
+	for _, c := range status.Conditions {
+		if c.Type != t {
+			kept = append(kept, c)
+		}
+	}
+	status.Conditions = kept
+	delete(status.ConditionGenerations, string(t))
+}
+
+// legacyReady marks the legacy condition true with the reason of the earlier versions of the controller, e.g., the
+// AdmissionCheck condition once the rendered resources pass the preflight
+func (m conditionManager) legacyReady(t runtimev1alpha1.ConditionType) {
+	m.setCondition(t, corev1.ConditionTrue, runtimev1alpha1.ReasonAvailable, "")
+}
+
+func (m conditionManager) set(t runtimev1alpha1.ConditionType, status corev1.ConditionStatus,
+	reason runtimev1alpha1.ConditionReason, message string) {
+	m.setCondition(t, status, reason, message)
+	if m.app.Status.ConditionGenerations == nil {
+		m.app.Status.ConditionGenerations = map[string]int64{}
+	}
+	m.app.Status.ConditionGenerations[string(t)] = m.app.Generation
+	m.setLegacy(t, status, reason, message)
+}
+
+// setLegacy sets the legacy conditions reporting the same stage as the condition, with the reasons of the earlier
+// versions of the controller
+func (m conditionManager) setLegacy(t runtimev1alpha1.ConditionType, status corev1.ConditionStatus,
+	reason runtimev1alpha1.ConditionReason, message string) {
+	var legacy []runtimev1alpha1.ConditionType
+	switch t {
+	case common.ApplicationRendered:
+		legacy = []runtimev1alpha1.ConditionType{legacyBuiltCondition}
+	case common.ApplicationPolicyApplied:
+		// the AdmissionCheck condition is set by the preflight only if it's enabled
+		if reason == reasonAdmissionRejected {
+			legacy = []runtimev1alpha1.ConditionType{legacyAdmissionCheckCondition}
+		} else {
+			legacy = []runtimev1alpha1.ConditionType{legacyBuiltCondition}
+		}
+	case common.ApplicationDispatched:
+		switch {
+		case reason == reasonCannotRollout:
+			legacy = []runtimev1alpha1.ConditionType{legacyRolloutCondition}
+		case status == corev1.ConditionTrue && m.app.Spec.RolloutPlan != nil:
+			legacy = []runtimev1alpha1.ConditionType{legacyAppliedCondition, legacyRolloutCondition}
+		default:
+			legacy = []runtimev1alpha1.ConditionType{legacyAppliedCondition}
+		}
+	case common.ApplicationHealthy:
+		legacy = []runtimev1alpha1.ConditionType{legacyHealthCheckCondition}
+	}
+	legacyReason := runtimev1alpha1.ReasonAvailable
+	if status == corev1.ConditionFalse {
+		legacyReason = runtimev1alpha1.ReasonReconcileError
+	}
+	for _, l := range legacy {
+		m.setCondition(l, status, legacyReason, message)
+	}
+}
+
+// setCondition sets the condition, it keeps the lastTransitionTime of the existing one if the status is unchanged
+func (m conditionManager) setCondition(t runtimev1alpha1.ConditionType, status corev1.ConditionStatus,
+	reason runtimev1alpha1.ConditionReason, message string) {
+	since := metav1.Now()
+	if existing := m.app.Status.GetCondition(t); existing.Status == status && !existing.LastTransitionTime.IsZero() {
+		since = existing.LastTransitionTime
+	}
+	m.app.Status.SetConditions(runtimev1alpha1.Condition{
+		Type:               t,
+		Status:             status,
+		LastTransitionTime: since,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// unhealthyMessage lists the unhealthy components with their messages
+func unhealthyMessage(services []common.ApplicationComponentStatus) string {
+	var unhealthy []string
+	for _, s := range services {
+		if s.Healthy {
+			continue
+		}
+		if len(s.Message) != 0 {
+			unhealthy = append(unhealthy, s.Name+": "+s.Message)
+		} else {
+			unhealthy = append(unhealthy, s.Name)
+		}
+	}
+	return "unhealthy components: " + strings.Join(unhealthy, "; ")
+}
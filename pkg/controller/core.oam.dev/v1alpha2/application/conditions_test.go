@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+var _ = Describe("Test condition manager", func() {
+	It("stamp the conditions with the generation and keep the transition time", func() {
+		since := metav1.NewTime(time.Now().Add(-time.Hour))
+		app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Generation: 3}}
+		app.Status.Conditions = []runtimev1alpha1.Condition{{Type: common.ApplicationHealthy,
+			Status: corev1.ConditionTrue, LastTransitionTime: since, Reason: "Healthy"}}
+		app.Status.ConditionGenerations = map[string]int64{string(common.ApplicationHealthy): 2}
+		h := &appHandler{app: app}
+
+		h.conditions().ready(common.ApplicationHealthy)
+		healthy := app.Status.GetCondition(common.ApplicationHealthy)
+		Expect(app.Status.ConditionGenerations[string(common.ApplicationHealthy)]).Should(BeEquivalentTo(3))
+		Expect(healthy.LastTransitionTime).Should(Equal(since))
+
+		h.conditions().failed(common.ApplicationHealthy, reasonUnhealthy, errors.New("unhealthy components: web"))
+		healthy = app.Status.GetCondition(common.ApplicationHealthy)
+		Expect(healthy.Status).Should(Equal(corev1.ConditionFalse))
+		Expect(healthy.Reason).Should(Equal(reasonUnhealthy))
+		Expect(healthy.Message).Should(Equal("unhealthy components: web"))
+		Expect(healthy.LastTransitionTime.After(since.Time)).Should(BeTrue())
+
+		// the legacy condition of the stage is maintained along with it
+		healthCheck := app.Status.GetCondition(legacyHealthCheckCondition)
+		Expect(healthCheck.Status).Should(Equal(corev1.ConditionFalse))
+		Expect(healthCheck.Reason).Should(Equal(runtimev1alpha1.ReasonReconcileError))
+		Expect(healthCheck.Message).Should(Equal("unhealthy components: web"))
+		Expect(app.Status.ConditionGenerations).ShouldNot(HaveKey(string(legacyHealthCheckCondition)))
+
+		h.conditions().ready(appDependenciesCondition)
+		Expect(app.Status.Conditions).Should(HaveLen(3))
+		h.conditions().remove(appDependenciesCondition)
+		Expect(app.Status.Conditions).Should(HaveLen(2))
+		Expect(app.Status.ConditionGenerations).ShouldNot(HaveKey(string(appDependenciesCondition)))
+	})
+
+	It("maintain the legacy conditions of the stages", func() {
+		app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Generation: 1}}
+		h := &appHandler{app: app}
+		legacy := func(t runtimev1alpha1.ConditionType) runtimev1alpha1.Condition {
+			return app.Status.GetCondition(t)
+		}
+
+		h.conditions().ready(common.ApplicationRendered)
+		Expect(legacy(legacyBuiltCondition).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(legacy(legacyBuiltCondition).Reason).Should(Equal(runtimev1alpha1.ReasonAvailable))
+		h.conditions().failed(common.ApplicationPolicyApplied, reasonQuotaExceeded, errors.New("quota exceeded"))
+		Expect(legacy(legacyBuiltCondition).Status).Should(Equal(corev1.ConditionFalse))
+		h.conditions().failed(common.ApplicationPolicyApplied, reasonAdmissionRejected, errors.New("rejected"))
+		Expect(legacy(legacyAdmissionCheckCondition).Status).Should(Equal(corev1.ConditionFalse))
+		h.conditions().legacyReady(legacyAdmissionCheckCondition)
+		h.conditions().ready(common.ApplicationPolicyApplied)
+		Expect(legacy(legacyBuiltCondition).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(legacy(legacyAdmissionCheckCondition).Status).Should(Equal(corev1.ConditionTrue))
+
+		h.conditions().failed(common.ApplicationDispatched, reasonCannotApply, errors.New("forbidden"))
+		Expect(legacy(legacyAppliedCondition).Status).Should(Equal(corev1.ConditionFalse))
+		h.conditions().failed(common.ApplicationDispatched, reasonCannotRollout, errors.New("rollout failed"))
+		Expect(legacy(legacyRolloutCondition).Status).Should(Equal(corev1.ConditionFalse))
+		app.Spec.RolloutPlan = &v1alpha1.RolloutPlan{}
+		h.conditions().ready(common.ApplicationDispatched)
+		Expect(legacy(legacyAppliedCondition).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(legacy(legacyRolloutCondition).Status).Should(Equal(corev1.ConditionTrue))
+
+		h.conditions().ready(common.ApplicationHealthy)
+		Expect(legacy(legacyHealthCheckCondition).Status).Should(Equal(corev1.ConditionTrue))
+	})
+
+	It("list the unhealthy components", func() {
+		Expect(unhealthyMessage([]common.ApplicationComponentStatus{
+			{Name: "web", Healthy: false, Message: "0/1 replicas ready"},
+			{Name: "db", Healthy: true},
+			{Name: "cache", Healthy: false},
+		})).Should(Equal("unhealthy components: web: 0/1 replicas ready; cache"))
+	})
+})
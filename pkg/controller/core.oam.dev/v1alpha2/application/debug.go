@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/debug"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// newDebugSnapshot returns a debug snapshot if it's enabled by the annotation of the application, otherwise nil
+func newDebugSnapshot(app *v1beta1.Application) *debug.Snapshot {
+	if !debug.Enabled(app) {
+		return nil
+	}
+	return &debug.Snapshot{}
+}
+
+func (h *appHandler) debugContext(name string) debug.Context {
+	ctx := debug.Context{Name: name, AppName: h.app.Name, Namespace: h.app.Namespace}
+	if h.appfile != nil {
+		ctx.AppRevision = h.appfile.RevisionName
+	}
+	return ctx
+}
+
+// snapshotComponents records the CUE context, parameter and template of the components before they're rendered, so
+// they're available even if the templates fail to render
+func (h *appHandler) snapshotComponents() {
+	if h.snapshot == nil || h.appfile == nil {
+		return
+	}
+	h.snapshot.Components = nil
+	for _, wl := range h.appfile.Workloads {
+		comp := &debug.ComponentSnapshot{
+			Name:      wl.Name,
+			Type:      wl.Type,
+			Context:   h.debugContext(wl.Name),
+			Parameter: debug.Redact(wl.Params),
+		}
+		if wl.FullTemplate != nil {
+			comp.Template = wl.FullTemplate.TemplateStr
+		}
+		h.snapshot.Components = append(h.snapshot.Components, comp)
+	}
+}
+
+// snapshotManifests records the workloads and traits rendered from the components
+func (h *appHandler) snapshotManifests(ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) {
+	if h.snapshot == nil {
+		return
+	}
+	for _, comp := range comps {
+		if s := h.snapshot.Component(comp.Name); s != nil && len(comp.Spec.Workload.Raw) != 0 {
+			s.Manifests = appendManifest(s.Manifests, comp.Spec.Workload)
+		}
+	}
+	for _, acc := range ac.Spec.Components {
+		s := h.snapshot.Component(acc.ComponentName)
+		if s == nil {
+			continue
+		}
+		for _, tr := range acc.Traits {
+			s.Manifests = appendManifest(s.Manifests, tr.Trait)
+		}
+	}
+}
+
+func appendManifest(manifests []*unstructured.Unstructured, raw runtime.RawExtension) []*unstructured.Unstructured {
+	obj, err := util.RawExtension2Unstructured(&raw)
+	if err != nil {
+		return manifests
+	}
+	return append(manifests, &unstructured.Unstructured{Object: debug.Redact(obj.Object)})
+}
+
+// snapshotSteps records the workflow steps with their statuses, the properties with the inputs set and the outputs
+func (h *appHandler) snapshotSteps() {
+	resolved := map[string]v1beta1.WorkflowStep{}
+	if h.parsedApp != nil {
+		for _, step := range h.parsedApp.Spec.Workflow {
+			resolved[step.Name] = step
+		}
+	}
+	h.snapshot.Steps = nil
+	for _, step := range h.app.Spec.Workflow {
+		if r, ok := resolved[step.Name]; ok {
+			step.Properties = r.Properties
+		}
+		s := h.stepSnapshot(step.Name, step.Type, step.Properties)
+		for _, in := range step.Inputs {
+			if v, ok := h.outputs[in.From]; ok {
+				if s.Inputs == nil {
+					s.Inputs = map[string]string{}
+				}
+				s.Inputs[in.ParameterKey] = v
+			}
+		}
+		for _, out := range step.Outputs {
+			if v, ok := h.outputs[out.Name]; ok {
+				if s.Outputs == nil {
+					s.Outputs = map[string]string{}
+				}
+				s.Outputs[out.Name] = v
+			}
+		}
+		status := h.workflowStepStatus(step.Name)
+		if status != nil {
+			s.Phase, s.Message = status.Phase, status.Message
+		}
+		for _, sub := range step.SubSteps {
+			subSnapshot := h.stepSnapshot(sub.Name, sub.Type, sub.Properties)
+			if status != nil {
+				for _, subStatus := range status.SubSteps {
+					if subStatus.Name == sub.Name {
+						subSnapshot.Phase, subSnapshot.Message = subStatus.Phase, subStatus.Message
+					}
+				}
+			}
+			s.SubSteps = append(s.SubSteps, subSnapshot)
+		}
+		h.snapshot.Steps = append(h.snapshot.Steps, s)
+	}
+}
+
+func (h *appHandler) stepSnapshot(name, typ string, properties runtime.RawExtension) debug.StepSnapshot {
+	s := debug.StepSnapshot{Name: name, Type: typ, Context: h.debugContext(name)}
+	if len(properties.Raw) != 0 {
+		if props, err := util.RawExtension2Map(&properties); err == nil {
+			s.Properties = debug.Redact(props)
+		}
+	}
+	if h.appfile != nil {
+		if tmpl, ok := h.appfile.WorkflowStepTemplates[typ]; ok {
+			s.Template = tmpl.TemplateStr
+		}
+	}
+	return s
+}
+
+// dumpDebugSnapshot persists the debug snapshot of this reconciliation to the ConfigMaps owned by the application,
+// the ConfigMaps no longer needed are deleted, e.g., the ones of the removed components or all of them once the
+// debug snapshots are disabled. It never fails the reconciliation.
+func (h *appHandler) dumpDebugSnapshot(ctx context.Context) {
+	existing, err := debug.ListConfigMaps(ctx, h.r, h.app)
+	if err != nil {
+		h.logger.Error(err, "cannot list the debug snapshots")
+		return
+	}
+	keep := map[string]bool{}
+	if h.snapshot != nil {
+		h.snapshotSteps()
+		h.snapshot.Errors = map[string]string{}
+		for _, c := range h.app.Status.Conditions {
+			if c.Status == corev1.ConditionFalse {
+				h.snapshot.Errors[string(c.Type)] = c.Message
+			}
+		}
+		cms, err := h.snapshot.ConfigMaps(h.app)
+		if err != nil {
+			h.logger.Error(err, "cannot build the debug snapshots")
+			return
+		}
+		for _, cm := range cms {
+			if err := h.r.applicator.Apply(ctx, cm); err != nil {
+				h.logger.Error(err, "cannot dump the debug snapshot", "configMap", cm.Name)
+			}
+			keep[cm.Name] = true
+		}
+	}
+	for i := range existing {
+		if keep[existing[i].Name] {
+			continue
+		}
+		if err := h.r.Delete(ctx, &existing[i]); err != nil && !apierrors.IsNotFound(err) {
+			h.logger.Error(err, "cannot delete the debug snapshot", "configMap", existing[i].Name)
+		}
+	}
+}
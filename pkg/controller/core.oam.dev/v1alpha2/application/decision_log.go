@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// decisionLogDataKey is the key of the decision log in the dumped ConfigMap
+const decisionLogDataKey = "decisions.json"
+
+// decisionLogName returns the name of the ConfigMap recording the decision log of the application
+func decisionLogName(appName string) string {
+	return appName + "-decision-log"
+}
+
+// newDecisionLog returns a decision log if it's enabled by the annotation of the application, otherwise nil
+func newDecisionLog(app *v1beta1.Application) *decision.Log {
+	if app.GetAnnotations()[oam.AnnotationDecisionLog] != "true" {
+		return nil
+	}
+	return decision.NewLog(app.Namespace+"/"+app.Name, app.Generation)
+}
+
+// dumpDecisionLog dumps the decision log of this reconciliation to the diagnostics endpoint and a ConfigMap
+// owned by the application, it never fails the reconciliation
+func (h *appHandler) dumpDecisionLog(ctx context.Context) {
+	if h.decisions == nil {
+		decision.DefaultStore.Delete(h.app.Namespace + "/" + h.app.Name)
+		return
+	}
+	decision.DefaultStore.Put(h.decisions)
+	data, err := h.decisions.JSON()
+	if err != nil {
+		h.logger.Error(err, "cannot marshal the decision log")
+		return
+	}
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      decisionLogName(h.app.Name),
+			Namespace: h.app.Namespace,
+			Labels:    map[string]string{oam.LabelAppName: h.app.Name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1beta1.SchemeGroupVersion.String(),
+				Kind:       v1beta1.ApplicationKind,
+				Name:       h.app.Name,
+				UID:        h.app.UID,
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Data: map[string]string{decisionLogDataKey: string(data)},
+	}
+	if err := h.r.applicator.Apply(ctx, cm); err != nil {
+		h.logger.Error(err, "cannot dump the decision log", "configMap", cm.Name)
+	}
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// checkAppDependencies checks whether the applications this application depends on are healthy and records them in
+// the status, the workflow steps declaring them are recorded as well. It returns the applications which are not
+// healthy within their timeouts, the components keep waiting for them anyway.
+func (h *appHandler) checkAppDependencies(ctx context.Context) ([]string, error) {
+	last := map[string]common.AppDependencyStatus{}
+	for _, s := range h.app.Status.AppDependencies {
+		last[s.Namespace+"/"+s.Name] = s
+	}
+	now := metav1.Now()
+	var status []common.AppDependencyStatus
+	var timeouts []string
+	for _, dep := range h.appfile.AppDependencies {
+		key := dep.Namespace + "/" + dep.Name
+		s := common.AppDependencyStatus{
+			Name:       dep.Name,
+			Namespace:  dep.Namespace,
+			Components: dep.Components,
+			Phase:      common.AppDependencySatisfied,
+		}
+		// the dependency declared by a skipped step is ignored
+		if h.isStepSkipped(dep.StepName) {
+			continue
+		}
+		// the step waiting for its if condition to be evaluated is not started yet
+		if h.isStepAwaitingCondition(dep.StepName) {
+			s.Phase = common.AppDependencyWaiting
+			s.Message = fmt.Sprintf("workflow step %s is waiting for the previous steps to evaluate its if condition", dep.StepName)
+			s.WaitingSince = &now
+			status = append(status, s)
+			continue
+		}
+		// the sub-step pending under the concurrency limit of its step group is not started yet
+		if len(dep.SubStepName) != 0 && !h.isSubStepStarted(dep.StepName, dep.SubStepName) {
+			s.Phase = common.AppDependencyWaiting
+			s.Message = fmt.Sprintf("sub-step %s of workflow step %s is pending", dep.SubStepName, dep.StepName)
+			s.WaitingSince = &now
+			status = append(status, s)
+			continue
+		}
+		healthy, msg, err := h.isAppHealthy(ctx, dep.Namespace, dep.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !healthy {
+			s.Phase = common.AppDependencyWaiting
+			s.Message = msg
+			s.WaitingSince = &now
+			if l, ok := last[key]; ok && l.WaitingSince != nil {
+				s.WaitingSince = l.WaitingSince
+			}
+			// a retry of the workflow step declaring the dependency waits for another timeout
+			if step := h.workflowStepStatus(dep.StepName); step != nil && step.StartedAt != nil && step.StartedAt.After(s.WaitingSince.Time) {
+				s.WaitingSince = step.StartedAt
+			}
+			if dep.Timeout > 0 && now.Sub(s.WaitingSince.Time) > dep.Timeout {
+				s.Phase = common.AppDependencyTimeout
+				s.Message = fmt.Sprintf("not healthy within %s, %s", dep.Timeout, msg)
+				timeouts = append(timeouts, key)
+			}
+			h.decisions.Record(decision.StageSchedule, key, "application depended on is %s, %s", s.Phase, msg)
+		}
+		status = append(status, s)
+		if len(dep.SubStepName) != 0 {
+			h.setAppDependencySubStepStatus(dep.StepName, dep.SubStepName, s)
+		} else if len(dep.StepName) != 0 {
+			h.setAppDependencyStepStatus(dep.StepName, s)
+		}
+	}
+	h.app.Status.AppDependencies = status
+	return timeouts, nil
+}
+
+// appDependenciesSatisfied returns true if all the applications depended on are healthy
+func (h *appHandler) appDependenciesSatisfied() bool {
+	for _, dep := range h.app.Status.AppDependencies {
+		if dep.Phase != common.AppDependencySatisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// isAppHealthy returns true if the application is running, i.e., all its components are applied and healthy
+func (h *appHandler) isAppHealthy(ctx context.Context, namespace, name string) (bool, string, error) {
+	app := new(v1beta1.Application)
+	if err := h.r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, app); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, fmt.Sprintf("application %s/%s is not found", namespace, name), nil
+		}
+		return false, "", errors.Wrapf(err, "cannot get application %s/%s", namespace, name)
+	}
+	if app.Status.Phase != common.ApplicationRunning {
+		phase := app.Status.Phase
+		if len(phase) == 0 {
+			phase = "not reconciled"
+		}
+		return false, fmt.Sprintf("application %s/%s is %s", namespace, name, phase), nil
+	}
+	return true, "", nil
+}
+
+// setAppDependencyStepStatus records the status of the depends-on-app workflow step
+func (h *appHandler) setAppDependencyStepStatus(stepName string, s common.AppDependencyStatus) {
+	step := common.WorkflowStepStatus{
+		Name:  stepName,
+		Type:  appfile.TypeDependsOnApp,
+		Phase: common.WorkflowStepPhaseSucceeded,
+		ResourceRef: runtimev1alpha1.TypedReference{
+			APIVersion: v1beta1.SchemeGroupVersion.String(),
+			Kind:       v1beta1.ApplicationKind,
+			Name:       s.Name,
+		},
+	}
+	switch s.Phase {
+	case common.AppDependencyWaiting:
+		step.Phase = common.WorkflowStepPhaseRunning
+	case common.AppDependencyTimeout:
+		step.Phase = common.WorkflowStepPhaseFailed
+		step.Message = s.Message
+	}
+	last := h.workflowStepStatus(stepName)
+	if last == nil {
+		h.app.Status.Workflow = append(h.app.Status.Workflow, step)
+		return
+	}
+	// keep the attempts recorded by the retry policy, the step waiting for a retry stays as it is
+	step.Retries, step.StartedAt, step.FailedAt = last.Retries, last.StartedAt, last.FailedAt
+	if last.Phase == common.WorkflowStepPhaseRetrying {
+		step.Phase, step.Message = last.Phase, last.Message
+	}
+	*last = step
+}
+
+// workflowStepStatus returns the status of the workflow step, it's nil if the step is not started
+func (h *appHandler) workflowStepStatus(stepName string) *common.WorkflowStepStatus {
+	if len(stepName) == 0 {
+		return nil
+	}
+	for i := range h.app.Status.Workflow {
+		if h.app.Status.Workflow[i].Name == stepName {
+			return &h.app.Status.Workflow[i]
+		}
+	}
+	return nil
+}
+
+// scheduleComponents decides which components are dispatched in this reconcile following the dependency graph.
+// A component is dispatched once all its dependencies are dispatched and reported healthy by the last health check,
+// and the applications it waits for are healthy as checked by checkAppDependencies, and stays dispatched afterwards, so it won't be garbage collected when its dependencies turn unhealthy temporarily.
+// The workloads waiting for their dependencies are removed from the appfile so they are not rendered or applied.
+func (h *appHandler) scheduleComponents() {
+	dispatched := map[string]bool{}
+	if h.app.Status.DependencyGraph != nil {
+		for _, node := range h.app.Status.DependencyGraph {
+			dispatched[node.Name] = node.Phase == common.ComponentDispatchDispatched
+		}
+	} else {
+		// the application is deployed before the dependency graph is recorded
+		for _, comp := range h.app.Status.Components {
+			dispatched[comp.Name] = true
+		}
+	}
+	healthy := map[string]bool{}
+	for _, svc := range h.app.Status.Services {
+		healthy[svc.Name] = svc.Healthy
+	}
+
+	var graph []common.ComponentDependency
+	var workloads []*appfile.Workload
+	h.pendingWorkloads = nil
+	for _, wl := range h.appfile.Workloads {
+		node := common.ComponentDependency{
+			Name:      wl.Name,
+			DependsOn: wl.DependsOn,
+			Phase:     common.ComponentDispatchDispatched,
+		}
+		if !dispatched[wl.Name] {
+			var waiting, waitingApps, messages []string
+			for _, dep := range wl.DependsOn {
+				if !dispatched[dep] || !healthy[dep] {
+					waiting = append(waiting, dep)
+				}
+			}
+			for _, dep := range h.app.Status.AppDependencies {
+				if dep.Phase != common.AppDependencySatisfied && blocksComponent(dep, wl.Name) {
+					waitingApps = append(waitingApps, dep.Namespace+"/"+dep.Name)
+				}
+			}
+			if len(waiting) != 0 {
+				messages = append(messages, fmt.Sprintf("waiting for dependencies to be healthy: %s", strings.Join(waiting, ", ")))
+			}
+			if len(waitingApps) != 0 {
+				messages = append(messages, fmt.Sprintf("waiting for applications to be healthy: %s", strings.Join(waitingApps, ", ")))
+			}
+			if len(messages) != 0 {
+				node.Phase = common.ComponentDispatchPending
+				node.Message = strings.Join(messages, "; ")
+			}
+		}
+		graph = append(graph, node)
+		if node.Phase == common.ComponentDispatchPending {
+			h.decisions.Record(decision.StageSchedule, wl.Name, "component not dispatched, %s", node.Message)
+			h.pendingWorkloads = append(h.pendingWorkloads, wl)
+			continue
+		}
+		workloads = append(workloads, wl)
+	}
+	h.appfile.Workloads = workloads
+	h.app.Status.DependencyGraph = graph
+}
+
+func blocksComponent(dep common.AppDependencyStatus, compName string) bool {
+	if len(dep.Components) == 0 {
+		return true
+	}
+	for _, name := range dep.Components {
+		if name == compName {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingComponentStatus reports the components waiting for their dependencies as unhealthy
+func (h *appHandler) pendingComponentStatus() []common.ApplicationComponentStatus {
+	var status []common.ApplicationComponentStatus
+	for _, wl := range h.pendingWorkloads {
+		svc := common.ApplicationComponentStatus{
+			Name:    wl.Name,
+			Healthy: false,
+		}
+		if wl.FullTemplate != nil {
+			svc.WorkloadDefinition = wl.FullTemplate.Reference.Definition
+		}
+		for _, node := range h.app.Status.DependencyGraph {
+			if node.Name == wl.Name {
+				svc.Message = node.Message
+			}
+		}
+		status = append(status, svc)
+	}
+	return status
+}
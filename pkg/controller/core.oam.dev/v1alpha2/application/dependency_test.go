@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+)
+
+var _ = Describe("Test schedule components by dependencies", func() {
+	newHandler := func(status common.AppStatus) *appHandler {
+		return &appHandler{
+			app: &v1beta1.Application{Status: status},
+			appfile: &appfile.Appfile{Workloads: []*appfile.Workload{
+				{Name: "db"},
+				{Name: "backend", DependsOn: []string{"db"}},
+				{Name: "frontend", DependsOn: []string{"backend"}},
+			}},
+		}
+	}
+	workloadNames := func(h *appHandler) []string {
+		var names []string
+		for _, wl := range h.appfile.Workloads {
+			names = append(names, wl.Name)
+		}
+		return names
+	}
+
+	It("dispatch the components without dependencies first", func() {
+		h := newHandler(common.AppStatus{})
+		h.scheduleComponents()
+		Expect(workloadNames(h)).Should(Equal([]string{"db"}))
+		Expect(h.app.Status.DependencyGraph).Should(HaveLen(3))
+		Expect(h.app.Status.DependencyGraph[1].Phase).Should(Equal(common.ComponentDispatchPending))
+		Expect(h.app.Status.DependencyGraph[1].Message).Should(ContainSubstring("db"))
+		pending := h.pendingComponentStatus()
+		Expect(pending).Should(HaveLen(2))
+		Expect(pending[0].Healthy).Should(BeFalse())
+	})
+
+	It("dispatch the component after its dependencies are healthy", func() {
+		h := newHandler(common.AppStatus{
+			DependencyGraph: []common.ComponentDependency{
+				{Name: "db", Phase: common.ComponentDispatchDispatched},
+				{Name: "backend", Phase: common.ComponentDispatchPending},
+				{Name: "frontend", Phase: common.ComponentDispatchPending},
+			},
+			Services: []common.ApplicationComponentStatus{{Name: "db", Healthy: true}},
+		})
+		h.scheduleComponents()
+		Expect(workloadNames(h)).Should(Equal([]string{"db", "backend"}))
+		Expect(h.app.Status.DependencyGraph[2].Phase).Should(Equal(common.ComponentDispatchPending))
+	})
+
+	It("keep the dispatched components even if the dependencies turn unhealthy", func() {
+		h := newHandler(common.AppStatus{
+			DependencyGraph: []common.ComponentDependency{
+				{Name: "db", Phase: common.ComponentDispatchDispatched},
+				{Name: "backend", Phase: common.ComponentDispatchDispatched},
+				{Name: "frontend", Phase: common.ComponentDispatchPending},
+			},
+			Services: []common.ApplicationComponentStatus{{Name: "db", Healthy: false}, {Name: "backend", Healthy: true}},
+		})
+		h.scheduleComponents()
+		Expect(workloadNames(h)).Should(Equal([]string{"db", "backend", "frontend"}))
+		Expect(h.pendingComponentStatus()).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("Test schedule components by application dependencies", func() {
+	platform := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "vela-system"},
+		Status:     common.AppStatus{Phase: common.ApplicationHealthChecking},
+	}
+	newHandler := func(status common.AppStatus, platform *v1beta1.Application, timeout time.Duration) *appHandler {
+		return &appHandler{
+			r: &Reconciler{Client: fake.NewFakeClientWithScheme(testScheme, platform)},
+			app: &v1beta1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "default"},
+				Status:     status,
+			},
+			appfile: &appfile.Appfile{
+				Workloads: []*appfile.Workload{{Name: "db"}, {Name: "web"}},
+				AppDependencies: []appfile.AppDependency{{
+					Name:       "platform",
+					Namespace:  "vela-system",
+					Components: []string{"web"},
+					Timeout:    timeout,
+					StepName:   "wait-platform",
+				}},
+			},
+		}
+	}
+
+	It("wait for the application depended on to be healthy", func() {
+		h := newHandler(common.AppStatus{}, platform.DeepCopy(), 0)
+		timeouts, err := h.checkAppDependencies(context.Background())
+		Expect(err).Should(BeNil())
+		Expect(timeouts).Should(BeEmpty())
+		Expect(h.app.Status.AppDependencies).Should(HaveLen(1))
+		Expect(h.app.Status.AppDependencies[0].Phase).Should(Equal(common.AppDependencyWaiting))
+		Expect(h.app.Status.AppDependencies[0].WaitingSince).ShouldNot(BeNil())
+		Expect(h.app.Status.Workflow).Should(HaveLen(1))
+		Expect(h.app.Status.Workflow[0].Phase).Should(Equal(common.WorkflowStepPhaseRunning))
+
+		h.scheduleComponents()
+		Expect(h.appfile.Workloads).Should(HaveLen(1))
+		Expect(h.appfile.Workloads[0].Name).Should(Equal("db"))
+		Expect(h.app.Status.DependencyGraph[1].Message).Should(ContainSubstring("vela-system/platform"))
+	})
+
+	It("report the timeout and keep waiting", func() {
+		since := metav1.NewTime(time.Now().Add(-time.Hour))
+		h := newHandler(common.AppStatus{AppDependencies: []common.AppDependencyStatus{{
+			Name:         "platform",
+			Namespace:    "vela-system",
+			Phase:        common.AppDependencyWaiting,
+			WaitingSince: &since,
+		}}}, platform.DeepCopy(), time.Minute)
+		timeouts, err := h.checkAppDependencies(context.Background())
+		Expect(err).Should(BeNil())
+		Expect(timeouts).Should(Equal([]string{"vela-system/platform"}))
+		Expect(h.app.Status.AppDependencies[0].Phase).Should(Equal(common.AppDependencyTimeout))
+		Expect(h.app.Status.Workflow[0].Phase).Should(Equal(common.WorkflowStepPhaseFailed))
+		h.scheduleComponents()
+		Expect(h.appfile.Workloads).Should(HaveLen(1))
+	})
+
+	It("wait for another timeout once the workflow step is retried", func() {
+		since := metav1.NewTime(time.Now().Add(-time.Hour))
+		retried := metav1.Now()
+		h := newHandler(common.AppStatus{
+			AppDependencies: []common.AppDependencyStatus{{
+				Name:         "platform",
+				Namespace:    "vela-system",
+				Phase:        common.AppDependencyTimeout,
+				WaitingSince: &since,
+			}},
+			Workflow: []common.WorkflowStepStatus{{
+				Name:      "wait-platform",
+				Phase:     common.WorkflowStepPhaseRunning,
+				Retries:   1,
+				StartedAt: &retried,
+			}},
+		}, platform.DeepCopy(), time.Minute)
+		timeouts, err := h.checkAppDependencies(context.Background())
+		Expect(err).Should(BeNil())
+		Expect(timeouts).Should(BeEmpty())
+		Expect(h.app.Status.AppDependencies[0].Phase).Should(Equal(common.AppDependencyWaiting))
+		Expect(h.app.Status.Workflow).Should(HaveLen(1))
+		Expect(h.app.Status.Workflow[0].Phase).Should(Equal(common.WorkflowStepPhaseRunning))
+		Expect(h.app.Status.Workflow[0].Retries).Should(Equal(1))
+	})
+
+	It("dispatch the components once the application depended on is healthy", func() {
+		healthy := platform.DeepCopy()
+		healthy.Status.Phase = common.ApplicationRunning
+		h := newHandler(common.AppStatus{}, healthy, 0)
+		timeouts, err := h.checkAppDependencies(context.Background())
+		Expect(err).Should(BeNil())
+		Expect(timeouts).Should(BeEmpty())
+		Expect(h.appDependenciesSatisfied()).Should(BeTrue())
+		Expect(h.app.Status.Workflow[0].Phase).Should(Equal(common.WorkflowStepPhaseSucceeded))
+		h.scheduleComponents()
+		Expect(h.appfile.Workloads).Should(HaveLen(2))
+	})
+})
@@ -0,0 +1,332 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/clustermanager"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// deployRequeueInterval is the interval to check again the deploy workflow step waiting for schedulable clusters
+const deployRequeueInterval = 30 * time.Second
+
+// dispatchDeploySteps runs the deploy workflow steps once all their previous steps finish, the rendered components
+// are applied to the clusters selected by the topology policies in every reconciliation to keep them in sync. The
+// resources dispatched are tracked by the ResourceTracker of the application. It returns true if any deploy step is
+// still running, e.g., all the selected clusters are cordoned.
+func (h *appHandler) dispatchDeploySteps(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) bool {
+	for i, step := range h.app.Spec.Workflow {
+		spec, ok := h.appfile.DeploySteps[step.Name]
+		if ok && !h.isStepAwaitingCondition(step.Name) && !h.isStepSkipped(step.Name) {
+			h.dispatchDeployStep(ctx, step.Name, spec, ac, comps)
+		}
+		if !h.isStepFinished(step.Name) {
+			// the following steps wait for this one to finish
+			for _, next := range h.app.Spec.Workflow[i:] {
+				if _, ok := h.appfile.DeploySteps[next.Name]; ok {
+					h.deployIncomplete = true
+				}
+			}
+			status := h.workflowStepStatus(step.Name)
+			return ok && status != nil && status.Phase == common.WorkflowStepPhaseRunning
+		}
+	}
+	return false
+}
+
+// hasDeploySteps returns true if the application dispatches resources to managed clusters by deploy steps
+func (h *appHandler) hasDeploySteps() bool {
+	return h.appfile != nil && len(h.appfile.DeploySteps) != 0
+}
+
+// trackDispatched records the manifests dispatched to the managed cluster, they're collected once no longer
+// dispatched by any deploy step or the application is deleted
+func (h *appHandler) trackDispatched(target multicluster.Target) {
+	for _, m := range target.Manifests {
+		h.acrossNamespaceResources = append(h.acrossNamespaceResources, v1beta1.TypedReference{
+			APIVersion: m.GetAPIVersion(),
+			Kind:       m.GetKind(),
+			Namespace:  m.GetNamespace(),
+			Name:       m.GetName(),
+			Cluster:    target.Cluster.Name,
+		})
+	}
+}
+
+func (h *appHandler) dispatchDeployStep(ctx context.Context, stepName string, spec *appfile.DeploySpec,
+	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) {
+	last := h.workflowStepStatus(stepName)
+	if last != nil && last.Phase == common.WorkflowStepPhaseRetrying {
+		h.deployIncomplete = true
+		return
+	}
+	step := common.WorkflowStepStatus{Name: stepName, Type: appfile.TypeDeploy, Phase: common.WorkflowStepPhaseSucceeded}
+	targets, err := h.deployTargets(ctx, spec, ac, comps)
+	switch {
+	case err != nil:
+		step.Phase = common.WorkflowStepPhaseFailed
+		step.Message = err.Error()
+	case len(targets) == 0:
+		step.Phase = common.WorkflowStepPhaseRunning
+		step.Message = "no schedulable cluster is selected, the selected clusters may be cordoned"
+	default:
+		var deployed, failed []string
+		for i, r := range h.r.gateway.Dispatch(ctx, targets, spec.Parallelism) {
+			sub := common.WorkflowSubStepStatus{
+				Name:  r.Cluster,
+				Type:  appfile.TypeDeploy,
+				Phase: common.WorkflowStepPhaseSucceeded,
+				ResourceRef: runtimev1alpha1.TypedReference{
+					APIVersion: v1beta1.SchemeGroupVersion.String(),
+					Kind:       v1beta1.ClusterKind,
+					Name:       r.Cluster,
+				},
+			}
+			if r.Err != nil {
+				sub.Phase = common.WorkflowStepPhaseFailed
+				sub.Message = r.Err.Error()
+				failed = append(failed, r.Cluster)
+			} else {
+				deployed = append(deployed, r.Cluster)
+				h.trackDispatched(targets[i])
+			}
+			step.SubSteps = append(step.SubSteps, sub)
+		}
+		if len(failed) != 0 {
+			step.Phase = common.WorkflowStepPhaseFailed
+			step.Message = fmt.Sprintf("failed to deploy to clusters %s", strings.Join(failed, ", "))
+		}
+		h.decisions.Record(decision.StageApply, stepName, "deployed to clusters [%s], failed in clusters [%s]",
+			strings.Join(deployed, ", "), strings.Join(failed, ", "))
+	}
+
+	if step.Phase != common.WorkflowStepPhaseSucceeded {
+		h.deployIncomplete = true
+	}
+	if step.Phase == common.WorkflowStepPhaseFailed {
+		h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedDispatch, errors.Errorf("workflow step %s: %s", stepName, step.Message)))
+	} else if step.Phase == common.WorkflowStepPhaseSucceeded && (last == nil || last.Phase != step.Phase) {
+		clusters := make([]string, 0, len(step.SubSteps))
+		for _, sub := range step.SubSteps {
+			clusters = append(clusters, sub.Name)
+		}
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonDispatched, fmt.Sprintf(velatypes.MessageDispatched, stepName, strings.Join(clusters, ", "))))
+	}
+	if last == nil {
+		h.app.Status.Workflow = append(h.app.Status.Workflow, step)
+		return
+	}
+	// keep the attempts recorded by the retry policy
+	step.Retries, step.StartedAt, step.FailedAt = last.Retries, last.StartedAt, last.FailedAt
+	*last = step
+}
+
+// deployTargets selects the clusters by the topology policies of the deploy step and renders the manifests deployed
+// to each of them, the cordoned clusters are skipped. A cluster selected by many policies gets the manifests of all
+// of them. The components are patched by the override policies of the step.
+func (h *appHandler) deployTargets(ctx context.Context, spec *appfile.DeploySpec, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) ([]multicluster.Target, error) {
+	ac, comps, err := h.renderOverridden(ctx, spec, ac, comps)
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]*multicluster.Target{}
+	for _, name := range spec.Policies {
+		topology, ok := h.appfile.Topologies[name]
+		if !ok {
+			continue
+		}
+		clusters, err := h.selectClusters(ctx, topology)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "policy %s", name)
+		}
+		namespace := topology.Namespace
+		if len(namespace) == 0 {
+			namespace = h.app.Namespace
+		}
+		for i := range clusters {
+			cluster := clusters[i]
+			manifests, err := renderClusterManifests(h.app.Name, namespace, cluster.Name, topology.Overrides, ac, comps)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "policy %s", name)
+			}
+			if t, ok := targets[cluster.Name]; ok {
+				t.Manifests = append(t.Manifests, manifests...)
+				continue
+			}
+			targets[cluster.Name] = &multicluster.Target{Cluster: &cluster, Manifests: manifests}
+		}
+	}
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]multicluster.Target, 0, len(names))
+	for _, name := range names {
+		result = append(result, *targets[name])
+	}
+	return result, nil
+}
+
+// renderOverridden renders the application patched by the override policies of the deploy step in order, the
+// rendered ones are returned as they are if the step refers to no override policy. Only the components scheduled in
+// this reconciliation are rendered.
+func (h *appHandler) renderOverridden(ctx context.Context, spec *appfile.DeploySpec, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
+	var overrides []*appfile.OverridePolicySpec
+	for _, name := range spec.Policies {
+		if o, ok := h.appfile.Overrides[name]; ok {
+			overrides = append(overrides, o)
+		}
+	}
+	if len(overrides) == 0 {
+		return ac, comps, nil
+	}
+	app, err := appfile.OverrideApplication(h.parsedApp, overrides...)
+	if err != nil {
+		return nil, nil, err
+	}
+	af, err := h.parser.GenerateAppFile(ctx, app)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "cannot parse the overridden application")
+	}
+	af.RevisionName = h.appfile.RevisionName
+	af.BlueGreenActive = h.appfile.BlueGreenActive
+	scheduled := make(map[string]bool, len(h.appfile.Workloads))
+	for _, wl := range h.appfile.Workloads {
+		scheduled[wl.Name] = true
+	}
+	workloads := make([]*appfile.Workload, 0, len(af.Workloads))
+	for _, wl := range af.Workloads {
+		if scheduled[wl.Name] {
+			workloads = append(workloads, wl)
+		}
+	}
+	af.Workloads = workloads
+	ac, comps, err = af.GenerateApplicationConfiguration()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "cannot render the overridden application")
+	}
+	return ac, comps, nil
+}
+
+func (h *appHandler) selectClusters(ctx context.Context, topology *appfile.TopologyPolicySpec) ([]v1beta1.Cluster, error) {
+	if len(topology.ClusterLabelSelector) != 0 {
+		return clustermanager.SelectClusters(ctx, h.r.Client, h.app.Namespace, &v1beta1.ClusterSelector{Labels: topology.ClusterLabelSelector})
+	}
+	var clusters []v1beta1.Cluster
+	for _, name := range topology.Clusters {
+		selected, err := clustermanager.SelectClusters(ctx, h.r.Client, h.app.Namespace, &v1beta1.ClusterSelector{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, selected...)
+	}
+	return clusters, nil
+}
+
+// renderClusterManifests renders the workloads and traits of the components deployed to the cluster in the
+// namespace, the workloads are patched by the overrides selecting the cluster. The existing workloads referred by
+// components are not managed by the application and left out.
+func renderClusterManifests(appName, namespace, cluster string, overrides []appfile.ClusterOverride,
+	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) ([]*unstructured.Unstructured, error) {
+	var manifests []*unstructured.Unstructured
+	for i, comp := range comps {
+		wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot convert the workload of component %s", comp.Name)
+		}
+		if util.IsReferredWorkload(wl) {
+			continue
+		}
+		for _, o := range overrides {
+			if !o.Selects(cluster, comp.Name) {
+				continue
+			}
+			if wl, err = mergePatch(wl, o.Patch.Raw); err != nil {
+				return nil, errors.WithMessagef(err, "cannot patch the workload of component %s for cluster %s", comp.Name, cluster)
+			}
+		}
+		if len(wl.GetName()) == 0 {
+			wl.SetName(comp.Name)
+		}
+		setManifestMeta(wl, appName, comp.Name, namespace)
+		manifests = append(manifests, wl)
+		for _, t := range ac.Spec.Components[i].Traits {
+			trait, err := util.RawExtension2Unstructured(&t.Trait)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "cannot convert the trait of component %s", comp.Name)
+			}
+			if len(trait.GetName()) == 0 {
+				traitType := trait.GetLabels()[oam.TraitTypeLabel]
+				if len(traitType) == 0 {
+					traitType = strings.ToLower(trait.GetKind())
+				}
+				trait.SetName(comp.Name + "-" + traitType)
+			}
+			setManifestMeta(trait, appName, comp.Name, namespace)
+			manifests = append(manifests, trait)
+		}
+	}
+	return manifests, nil
+}
+
+func setManifestMeta(m *unstructured.Unstructured, appName, compName, namespace string) {
+	m.SetNamespace(namespace)
+	util.AddLabels(m, map[string]string{
+		oam.LabelAppName:      appName,
+		oam.LabelAppComponent: compName,
+	})
+}
+
+func mergePatch(obj *unstructured.Unstructured, patch []byte) (*unstructured.Unstructured, error) {
+	original, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, err
+	}
+	patched := &unstructured.Unstructured{}
+	if err := json.Unmarshal(merged, &patched.Object); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+var _ = Describe("Test dispatch components to managed clusters", func() {
+	newComponents := func() (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component) {
+		comp := &v1alpha2.Component{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: v1alpha2.ComponentSpec{Workload: runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","spec":{"replicas":1}}`),
+			}},
+		}
+		ac := &v1alpha2.ApplicationConfiguration{Spec: v1alpha2.ApplicationConfigurationSpec{
+			Components: []v1alpha2.ApplicationConfigurationComponent{{
+				ComponentName: "web",
+				Traits: []v1alpha2.ComponentTrait{{Trait: runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"v1","kind":"Service","metadata":{"labels":{"trait.oam.dev/type":"expose"}}}`),
+				}}},
+			}},
+		}}
+		return ac, []*v1alpha2.Component{comp}
+	}
+
+	It("render the manifests patched by the overrides of the cluster", func() {
+		ac, comps := newComponents()
+		overrides := []appfile.ClusterOverride{{
+			Clusters: []string{"east"},
+			Patch:    runtime.RawExtension{Raw: []byte(`{"spec":{"replicas":3}}`)},
+		}}
+		manifests, err := renderClusterManifests("app", "prod", "east", overrides, ac, comps)
+		Expect(err).Should(BeNil())
+		Expect(manifests).Should(HaveLen(2))
+		wl, trait := manifests[0], manifests[1]
+		Expect(wl.GetName()).Should(Equal("web"))
+		Expect(wl.GetNamespace()).Should(Equal("prod"))
+		Expect(wl.GetLabels()).Should(HaveKeyWithValue(oam.LabelAppName, "app"))
+		Expect(wl.GetLabels()).Should(HaveKeyWithValue(oam.LabelAppComponent, "web"))
+		replicas, _, _ := unstructured.NestedInt64(wl.Object, "spec", "replicas")
+		Expect(replicas).Should(BeEquivalentTo(3))
+		Expect(trait.GetName()).Should(Equal("web-expose"))
+		Expect(trait.GetNamespace()).Should(Equal("prod"))
+
+		By("leave the workloads of other clusters as they are")
+		manifests, err = renderClusterManifests("app", "prod", "west", overrides, ac, comps)
+		Expect(err).Should(BeNil())
+		replicas, _, _ = unstructured.NestedInt64(manifests[0].Object, "spec", "replicas")
+		Expect(replicas).Should(BeEquivalentTo(1))
+	})
+
+	It("keep the deploy step running until any selected cluster is schedulable", func() {
+		cordoned := &v1beta1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "east", Namespace: "default", Labels: map[string]string{"env": "prod"}},
+			Spec:       v1beta1.ClusterSpec{Cordoned: true},
+		}
+		c := fake.NewFakeClientWithScheme(testScheme, cordoned)
+		topology, err := json.Marshal(appfile.TopologyPolicySpec{ClusterLabelSelector: map[string]string{"env": "prod"}})
+		Expect(err).Should(BeNil())
+		h := &appHandler{
+			r: &Reconciler{Client: c, Recorder: event.NewNopRecorder(), gateway: multicluster.NewClusterGateway(c)},
+			app: &v1beta1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: v1beta1.ApplicationSpec{
+					Policies: []v1beta1.AppPolicy{{Name: "prod", Type: appfile.PolicyTypeTopology, Properties: runtime.RawExtension{Raw: topology}}},
+					Workflow: []v1beta1.WorkflowStep{
+						{Name: "wait-db", Type: appfile.TypeDependsOnApp},
+						{Name: "deploy-prod", Type: appfile.TypeDeploy},
+					},
+				},
+			},
+			appfile: &appfile.Appfile{
+				Topologies:  map[string]*appfile.TopologyPolicySpec{"prod": {ClusterLabelSelector: map[string]string{"env": "prod"}}},
+				DeploySteps: map[string]*appfile.DeploySpec{"deploy-prod": {Policies: []string{"prod"}}},
+			},
+		}
+		ac, comps := newComponents()
+
+		By("wait for the previous steps to finish")
+		Expect(h.dispatchDeploySteps(context.Background(), ac, comps)).Should(BeFalse())
+		Expect(h.workflowStepStatus("deploy-prod")).Should(BeNil())
+
+		h.app.Status.Workflow = []common.WorkflowStepStatus{{Name: "wait-db", Phase: common.WorkflowStepPhaseSucceeded}}
+		Expect(h.dispatchDeploySteps(context.Background(), ac, comps)).Should(BeTrue())
+		status := h.workflowStepStatus("deploy-prod")
+		Expect(status).ShouldNot(BeNil())
+		Expect(status.Phase).Should(Equal(common.WorkflowStepPhaseRunning))
+		Expect(status.Message).Should(ContainSubstring("no schedulable cluster"))
+		Expect(h.deployIncomplete).Should(BeTrue())
+	})
+
+	It("track the resources dispatched to managed clusters until no deploy step dispatches them", func() {
+		ctx := context.Background()
+		dispatched := v1beta1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web", Cluster: "east"}
+		rt := &v1beta1.ResourceTracker{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-app"},
+			Status:     v1beta1.ResourceTrackerStatus{TrackedResources: []v1beta1.TypedReference{dispatched}},
+		}
+		c := fake.NewFakeClientWithScheme(testScheme, rt)
+		h := &appHandler{
+			r: &Reconciler{Client: c, Recorder: event.NewNopRecorder(), gateway: multicluster.NewClusterGateway(c)},
+			app: &v1beta1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Status: common.AppStatus{
+					LatestRevision: &common.Revision{Name: "app-v2"},
+				},
+			},
+			appfile: &appfile.Appfile{
+				DeploySteps: map[string]*appfile.DeploySpec{"deploy-prod": {Policies: []string{"prod"}}},
+			},
+		}
+
+		By("keep the resources tracked while the deploy steps are not all dispatched")
+		h.deployIncomplete = true
+		Expect(gcAcrossNamespaceResource(ctx, h)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "default-app"}, rt)).Should(Succeed())
+		Expect(rt.Status.TrackedResources).Should(Equal([]v1beta1.TypedReference{dispatched}))
+		Expect(rt.Status.AppRevision).Should(Equal("app-v2"))
+		Expect(h.app.Status.ResourceTracker).ShouldNot(BeNil())
+
+		By("collect the resources no longer dispatched, the cluster is not registered any more")
+		h.deployIncomplete = false
+		h.acrossNamespaceResources = nil
+		Expect(gcAcrossNamespaceResource(ctx, h)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "default-app"}, rt)).Should(Succeed())
+		Expect(rt.Status.TrackedResources).Should(BeEmpty())
+	})
+})
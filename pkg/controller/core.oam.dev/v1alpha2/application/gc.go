@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/gc"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// collectGarbage deletes the across-namespace resources no longer rendered by the application following their
+// garbage collection policies: the protected ones are orphaned and reported, and the ones labeled with a grace period
+// are kept until it passes. It returns the resources waiting for their grace periods, the time they were found no
+// longer rendered is looked up in since. The resources dispatched to managed clusters are deleted through the
+// clients of the clusters.
+func (h *appHandler) collectGarbage(ctx context.Context, rt *v1beta1.ResourceTracker, garbage []v1beta1.TypedReference,
+	since map[v1beta1.TypedReference]time.Time) ([]common.PendingDeletion, error) {
+	now := time.Now()
+	var pending []common.PendingDeletion
+	for _, ref := range garbage {
+		c, err := h.clientOf(ctx, ref.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			h.decisions.Record(decision.StageGC, ref.Name, "%s left in cluster %s which is not registered any more", ref.Kind, ref.Cluster)
+			continue
+		}
+		resource := trackedResource(ref)
+		start, ok := since[ref]
+		if !ok {
+			start = now
+		}
+		garbage := &gc.Garbage{Application: h.app, Ref: ref, Since: start}
+		d, strategy, err := gc.Decide(ctx, c, garbage)
+		if err != nil {
+			return nil, err
+		}
+		wait := d.Wait
+		switch d.Action {
+		case oamutil.GCGone:
+			continue
+		case oamutil.GCProtect:
+			if err := oamutil.OrphanResource(ctx, c, resource, rt.UID); err != nil {
+				return nil, err
+			}
+			gc.NotifyCollected(ctx, garbage, d.Action)
+			h.decisions.Record(decision.StageGC, ref.Name, "protected %s orphaned instead of deleted by strategy %s", ref.Kind, strategy)
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonProtected, protectedMessage(ref, strategy)))
+			continue
+		case oamutil.GCWait:
+			h.decisions.Record(decision.StageGC, ref.Name, "%s kept by strategy %s, deleted in %s", ref.Kind, strategy, wait.Round(time.Second))
+			pending = append(pending, common.PendingDeletion{
+				APIVersion: ref.APIVersion,
+				Kind:       ref.Kind,
+				Namespace:  ref.Namespace,
+				Name:       ref.Name,
+				Cluster:    ref.Cluster,
+				Since:      metav1.NewTime(start),
+			})
+			if h.gcRequeue == 0 || wait < h.gcRequeue {
+				h.gcRequeue = wait
+			}
+			continue
+		}
+		// a shared resource is only deleted by its last owner
+		released, err := oamutil.ReleaseSharedResource(ctx, c, resource, rt.UID)
+		if err != nil {
+			return nil, err
+		}
+		if released {
+			h.decisions.Record(decision.StageGC, ref.Name, "shared %s released since it's still owned by others", ref.Kind)
+			continue
+		}
+		if err := c.Delete(ctx, resource); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		gc.NotifyCollected(ctx, garbage, oamutil.GCDelete)
+	}
+	return pending, nil
+}
+
+// protectedMessage explains why the garbage is orphaned
+func protectedMessage(ref v1beta1.TypedReference, strategy string) string {
+	if strategy == gc.LabelStrategyName {
+		return fmt.Sprintf("%s %s/%s is protected from garbage collection by label %s, it's orphaned", ref.Kind, ref.Namespace, ref.Name, oam.LabelGCProtect)
+	}
+	return fmt.Sprintf("%s %s/%s is protected from garbage collection by strategy %s, it's orphaned", ref.Kind, ref.Namespace, ref.Name, strategy)
+}
+
+// collectDispatchedResources deletes the resources dispatched to managed clusters once the application is deleted,
+// the protected ones are orphaned and the grace periods are not waited for
+func (h *appHandler) collectDispatchedResources(ctx context.Context, rt *v1beta1.ResourceTracker) error {
+	dispatched := map[v1beta1.TypedReference]bool{}
+	for _, ref := range rt.Status.TrackedResources {
+		if len(ref.Cluster) != 0 {
+			dispatched[ref] = true
+		}
+	}
+	for _, p := range rt.Status.PendingDeletions {
+		if len(p.Cluster) != 0 {
+			dispatched[v1beta1.TypedReference{APIVersion: p.APIVersion, Kind: p.Kind, Namespace: p.Namespace, Name: p.Name, Cluster: p.Cluster}] = true
+		}
+	}
+	for ref := range dispatched {
+		c, err := h.clientOf(ctx, ref.Cluster)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			continue
+		}
+		resource := trackedResource(ref)
+		// the grace periods have passed since the zero time, and the decisions to wait are ignored
+		garbage := &gc.Garbage{Application: h.app, Ref: ref, Finalizing: true}
+		d, _, err := gc.Decide(ctx, c, garbage)
+		if err != nil {
+			return err
+		}
+		switch d.Action {
+		case oamutil.GCGone:
+			continue
+		case oamutil.GCProtect:
+			if err := oamutil.OrphanResource(ctx, c, resource, rt.UID); err != nil {
+				return err
+			}
+			gc.NotifyCollected(ctx, garbage, d.Action)
+			continue
+		}
+		if err := c.Delete(ctx, resource); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete %s %s/%s in cluster %s", ref.Kind, ref.Namespace, ref.Name, ref.Cluster)
+		}
+		gc.NotifyCollected(ctx, garbage, oamutil.GCDelete)
+	}
+	return nil
+}
+
+// clientOf returns the client of the cluster the tracked resources are in, it's the client of the control plane
+// cluster if the cluster is empty, or nil if the managed cluster is not registered any more
+func (h *appHandler) clientOf(ctx context.Context, cluster string) (client.Client, error) {
+	if len(cluster) == 0 {
+		return h.r.Client, nil
+	}
+	managed := &v1beta1.Cluster{}
+	if err := h.r.Get(ctx, client.ObjectKey{Namespace: h.app.Namespace, Name: cluster}, managed); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return h.r.gateway.ClientOf(ctx, managed)
+}
+
+func trackedResource(ref v1beta1.TypedReference) *unstructured.Unstructured {
+	resource := new(unstructured.Unstructured)
+	resource.SetAPIVersion(ref.APIVersion)
+	resource.SetKind(ref.Kind)
+	resource.SetNamespace(ref.Namespace)
+	resource.SetName(ref.Name)
+	return resource
+}
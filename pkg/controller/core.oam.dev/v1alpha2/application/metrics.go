@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// the phases of reconciling an application, each is measured by a span and the duration metric
+const (
+	phaseParse       = "parse"
+	phaseRender      = "render"
+	phaseDispatch    = "dispatch"
+	phaseHealthCheck = "healthCheck"
+	phaseGC          = "gc"
+)
+
+var (
+	phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubevela_application_reconcile_phase_duration_seconds",
+		Help:    "The time each phase of reconciling an application takes, i.e., parse, render, dispatch, healthCheck and gc.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"phase"})
+	definitionFetches = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kubevela_application_reconcile_definition_fetches",
+		Help:    "The number of definitions and definition revisions fetched to reconcile an application.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(phaseDuration, definitionFetches)
+}
+
+// tracer creates the spans of reconciling the applications, they're dropped unless tracing is enabled
+var tracer = otel.Tracer("github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application")
+
+// tracePhase starts the span of a phase of the reconciliation. The returned function ends the span with the error
+// the phase fails with if any, and observes the duration of the phase.
+func tracePhase(ctx context.Context, phase string) func(error) {
+	start := time.Now()
+	_, span := tracer.Start(ctx, phase)
+	return func(err error) {
+		phaseDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// startPhase ends the current phase of the reconciliation and starts the next one
+func (h *appHandler) startPhase(ctx context.Context, phase string) {
+	h.endPhase(nil)
+	h.endCurrentPhase = tracePhase(ctx, phase)
+}
+
+// endPhase ends the current phase of the reconciliation with the error it fails with if any
+func (h *appHandler) endPhase(err error) {
+	if h.endCurrentPhase != nil {
+		h.endCurrentPhase(err)
+		h.endCurrentPhase = nil
+	}
+}
+
+// definitionCounter counts the definitions and definition revisions fetched by the client
+type definitionCounter struct {
+	client.Client
+	fetches int64
+}
+
+// Get gets the object and counts it if it's a definition or a definition revision
+func (c *definitionCounter) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if isDefinition(obj) {
+		atomic.AddInt64(&c.fetches, 1)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+// observe observes the number of the definitions fetched in the reconciliation
+func (c *definitionCounter) observe() {
+	definitionFetches.Observe(float64(atomic.LoadInt64(&c.fetches)))
+}
+
+func isDefinition(obj runtime.Object) bool {
+	switch obj.(type) {
+	case *v1beta1.ComponentDefinition, *v1beta1.WorkloadDefinition, *v1beta1.TraitDefinition,
+		*v1beta1.ScopeDefinition, *v1beta1.PolicyDefinition, *v1beta1.WorkflowStepDefinition,
+		*v1beta1.DefinitionRevision, *v1alpha2.ComponentDefinition, *v1alpha2.WorkloadDefinition,
+		*v1alpha2.TraitDefinition, *v1alpha2.ScopeDefinition:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+var _ = Describe("Test definition counter", func() {
+	It("count the definitions fetched only", func() {
+		def := &v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "vela-system"}}
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}}
+		c := &definitionCounter{Client: fake.NewFakeClientWithScheme(testScheme, def, cm)}
+		ctx := context.Background()
+
+		Expect(c.Get(ctx, client.ObjectKey{Name: "worker", Namespace: "vela-system"}, &v1beta1.ComponentDefinition{})).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "webservice", Namespace: "vela-system"}, &v1beta1.ComponentDefinition{})).ShouldNot(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "config", Namespace: "default"}, &corev1.ConfigMap{})).Should(Succeed())
+		Expect(c.fetches).Should(BeEquivalentTo(2))
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/preflight"
+)
+
+// admissionPreflightEnabled returns whether the rendered resources are checked by server-side dry-run, the
+// oam.AnnotationAdmissionPreflight annotation of the application overrides the default of the controller
+func (h *appHandler) admissionPreflightEnabled() bool {
+	if enabled, err := strconv.ParseBool(h.app.GetAnnotations()[oam.AnnotationAdmissionPreflight]); err == nil {
+		return enabled
+	}
+	return h.r.admissionPreflight
+}
+
+// checkAdmission sends the rendered workloads and traits through server-side dry-run with the identity applying
+// them, all the rejections are aggregated into the PolicyApplied condition instead of failing on the first one.
+// The legacy AdmissionCheck condition is maintained as well, it's removed if the preflight is disabled.
+func (h *appHandler) checkAdmission(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+	if !h.admissionPreflightEnabled() {
+		h.conditions().remove(legacyAdmissionCheckCondition)
+		return nil
+	}
+	objs, err := preflight.Objects(comps, ac)
+	if err != nil {
+		return err
+	}
+	c, err := h.r.impersonation.ClientOf(h.app, h.r.Client)
+	if err != nil {
+		return err
+	}
+	failures, err := preflight.Check(ctx, c, h.r.dm, h.app.Namespace, objs)
+	if err != nil {
+		return err
+	}
+	if err := preflight.Error(failures); err != nil {
+		return err
+	}
+	h.conditions().legacyReady(legacyAdmissionCheckCondition)
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/quota"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// enforceQuota records the usage of the rendered workloads in the status, so it's summed against the quotas of the
+// namespace and the project, and scales the workloads down if they exceed the quotas and the quota policy of the
+// application chooses to. The applications exceeding the quotas are otherwise rejected on admission.
+func (h *appHandler) enforceQuota(ctx context.Context, comps []*v1alpha2.Component) error {
+	workloads, err := quota.WorkloadsOf(comps)
+	if err != nil {
+		return err
+	}
+	usage, err := quota.UsageOf(workloads)
+	if err != nil {
+		return err
+	}
+	action, err := quota.ActionOf(h.app)
+	if err != nil {
+		return err
+	}
+	if action == quota.ActionScaleDown {
+		quotas, err := quota.Load(ctx, h.r, h.app.Namespace)
+		if err != nil {
+			return err
+		}
+		remaining := make([]corev1.ResourceList, 0, len(quotas))
+		for _, q := range quotas {
+			left, err := q.Remaining(ctx, h.r, h.app)
+			if err != nil {
+				return err
+			}
+			remaining = append(remaining, left)
+		}
+		scaled, err := quota.ScaleDown(workloads, usage, remaining)
+		if err != nil {
+			return err
+		}
+		if scaled {
+			for i, wl := range workloads {
+				if len(comps[i].Spec.Workload.Raw) != 0 {
+					comps[i].Spec.Workload = util.Object2RawExtension(wl.Object)
+				}
+			}
+			if usage, err = quota.UsageOf(workloads); err != nil {
+				return err
+			}
+			replicas := usage[quota.ResourceReplicas]
+			msg := fmt.Sprintf(velatypes.MessageScaledDown, replicas.String())
+			h.decisions.Record(decision.StageSchedule, h.app.Name, velatypes.MessageScaledDown, replicas.String())
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonScaledDown, msg))
+		}
+	}
+	h.app.Status.ResourceUsage = usage
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// checkReferredWorkloads validates the existing workloads referred by the components, a missing one is surfaced as
+// a warning condition without blocking the deployment of other components
+func (h *appHandler) checkReferredWorkloads(ctx context.Context, comps []*v1alpha2.Component) {
+	var referred bool
+	for _, comp := range comps {
+		if comp.Spec.Workload.Raw == nil {
+			continue
+		}
+		workload, err := oamutil.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil || !oamutil.IsReferredWorkload(workload) {
+			continue
+		}
+		referred = true
+		if len(workload.GetNamespace()) == 0 {
+			workload.SetNamespace(h.app.Namespace)
+		}
+		if _, err := oamutil.GetReferredWorkload(ctx, h.r, workload); err != nil {
+			h.logger.Error(err, "[check referred workload]", "component", comp.Name)
+			h.conditions().failed(referredWorkloadsCondition, reasonReferredWorkloadAbsent, err)
+			h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedRefer, err))
+			return
+		}
+	}
+	if referred {
+		h.conditions().ready(referredWorkloadsCondition)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"time"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+// stableRequeue returns the interval to reconcile the deployed and healthy application again. The applications
+// still executing their workflows are checked again shortly, the stable ones less and less often the longer they've
+// been healthy. It returns zero if the stable applications aren't requeued.
+func (h *appHandler) stableRequeue() time.Duration {
+	if workflowExecuting(h.app.Status.Workflow) {
+		return h.r.requeue.After()
+	}
+	healthy := h.app.Status.GetCondition(common.ApplicationHealthy)
+	return h.r.requeue.StableAfter(healthy.LastTransitionTime.Time)
+}
+
+// workflowExecuting returns whether any of the workflow steps is still running
+func workflowExecuting(steps []common.WorkflowStepStatus) bool {
+	for _, step := range steps {
+		if step.Phase == common.WorkflowStepPhaseRunning {
+			return true
+		}
+	}
+	return false
+}
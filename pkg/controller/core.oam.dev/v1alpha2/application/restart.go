@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// handleWorkflowRestart restarts the workflow from the first step if it's requested, e.g., by a Trigger on a push
+// event. The status of the steps is cleared and persisted before the annotation requesting the restart is removed, so
+// the restart is never lost, and the Git components are re-synced once the application is parsed. It returns true if
+// the workflow is restarted.
+func (h *appHandler) handleWorkflowRestart(ctx context.Context) (bool, error) {
+	if !workflow.IsRestartRequested(h.app) {
+		return false, nil
+	}
+	reason := h.app.GetAnnotations()[oam.AnnotationRestartWorkflow]
+	h.app.Status.Workflow = nil
+	if err := h.r.UpdateStatus(ctx, h.app); err != nil {
+		return false, errors.WithMessage(err, "cannot clear the status of the workflow steps")
+	}
+	patch := client.MergeFrom(h.app.DeepCopy())
+	annotations := h.app.GetAnnotations()
+	delete(annotations, oam.AnnotationRestartWorkflow)
+	h.app.SetAnnotations(annotations)
+	if err := h.r.Patch(ctx, h.app, patch); err != nil {
+		return false, errors.Wrap(err, "cannot remove the annotation restarting the workflow")
+	}
+	h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonRestarted, fmt.Sprintf(velatypes.MessageRestarted, reason)))
+	return true, nil
+}
@@ -18,9 +18,12 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,9 +33,13 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
 )
 
 // AppRevisionHash is used to compute the hash value of the AppRevision
@@ -44,6 +51,15 @@ type AppRevisionHash struct {
 	ScopeDefinitionHash     map[string]string
 }
 
+// AppRevisionSnapshotHash stores the hashes of the definitions and CUE packages snapshotted in addition to
+// the ones in AppRevisionHash
+type AppRevisionSnapshotHash struct {
+	AppRevisionHash            string
+	PolicyDefinitionHash       map[string]string
+	WorkflowStepDefinitionHash map[string]string
+	CUEPackages                map[string]string
+}
+
 // UpdateRevisionStatus will update the status of Application object mainly for update the revision part
 func (h *appHandler) UpdateRevisionStatus(ctx context.Context, revName, hash string, revision int64) error {
 	h.app.Status.LatestRevision = &common.Revision{
@@ -128,6 +144,35 @@ func (h *appHandler) gatherRevisionSpec() (*v1beta1.ApplicationRevision, string,
 		}
 		// TODO(wonderflow): take scope into the revision
 	}
+	for _, tmpl := range h.appfile.PolicyTemplates {
+		if tmpl.PolicyDefinition != nil {
+			if appRev.Spec.PolicyDefinitions == nil {
+				appRev.Spec.PolicyDefinitions = make(map[string]v1beta1.PolicyDefinition)
+			}
+			d := tmpl.PolicyDefinition.DeepCopy()
+			d.Status = v1beta1.PolicyDefinitionStatus{}
+			appRev.Spec.PolicyDefinitions[d.Name] = *d
+		}
+	}
+	for _, tmpl := range h.appfile.WorkflowStepTemplates {
+		if tmpl.WorkflowStepDefinition != nil {
+			if appRev.Spec.WorkflowStepDefinitions == nil {
+				appRev.Spec.WorkflowStepDefinitions = make(map[string]v1beta1.WorkflowStepDefinition)
+			}
+			d := tmpl.WorkflowStepDefinition.DeepCopy()
+			d.Status = v1beta1.WorkflowStepDefinitionStatus{}
+			appRev.Spec.WorkflowStepDefinitions[d.Name] = *d
+		}
+	}
+	if h.rollbackSource != nil {
+		h.pinRollbackSnapshot(appRev)
+	} else if h.r.pd != nil {
+		packages, err := h.r.pd.SnapshotPackages(h.appfile.Templates()...)
+		if err != nil {
+			return appRev, "", errors.WithMessage(err, "cannot snapshot CUE packages")
+		}
+		appRev.Spec.CUEPackages = packages
+	}
 	appRevisionHash, err := ComputeAppRevisionHash(appRev)
 	if err != nil {
 		h.logger.Error(err, "compute hash of appRevision for application", "application name", h.app.GetName())
@@ -184,9 +229,36 @@ func (h *appHandler) GenerateAppRevision(ctx context.Context) (*v1beta1.Applicat
 	}
 	h.isNewRevision = isNewRev
 	h.revisionHash = appRevisionHash
+	if err := h.setTraitHashVersion(ctx); err != nil {
+		return appRev, err
+	}
 	return appRev, nil
 }
 
+// setTraitHashVersion records the version of the hash algorithm generating trait names in the labels of the
+// application, so all resources rendered from the application share the same version. It's not computed into the
+// revision hash. A new application uses the latest version, while an existing one inherits the version from
+// its latest revision, which is TraitHashV1 if the revision was created before the hash is versioned.
+// It keeps the traits from being renamed after the controller upgrades the hash algorithm.
+func (h *appHandler) setTraitHashVersion(ctx context.Context) error {
+	if _, exist := h.app.GetLabels()[oam.LabelTraitHashVersion]; exist {
+		return nil
+	}
+	version := util.LatestTraitHashVersion
+	if h.app.Status.LatestRevision != nil {
+		latestAppRev := &v1beta1.ApplicationRevision{}
+		if err := h.r.Get(ctx, client.ObjectKey{Name: h.app.Status.LatestRevision.Name,
+			Namespace: h.app.Namespace}, latestAppRev); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+		version = util.GetTraitHashVersion(latestAppRev.GetLabels())
+	}
+	util.AddLabels(h.app, map[string]string{oam.LabelTraitHashVersion: string(version)})
+	return nil
+}
+
 // FinalizeAppRevision will finalize the AppRevision with metadata and rendered result revision for an Application when created/updated
 func (h *appHandler) FinalizeAppRevision(appRev *v1beta1.ApplicationRevision,
 	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) {
@@ -196,6 +268,40 @@ func (h *appHandler) FinalizeAppRevision(appRev *v1beta1.ApplicationRevision,
 
 }
 
+// signAppRevision signs the rendered manifests of the revision if manifest signing is enabled,
+// the ApplicationContext controller verifies the signature before applying the manifests
+func (h *appHandler) signAppRevision(ctx context.Context, appRev *v1beta1.ApplicationRevision) error {
+	if h.r.keyStore == nil {
+		return nil
+	}
+	key, err := h.r.keyStore.KeyFor(ctx, h.app.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := key.Sign(appRev, appRev.Spec.Components, appRev.Spec.ApplicationConfiguration); err != nil {
+		return errors.WithMessagef(err, "fail to sign application revision %s", appRev.Name)
+	}
+	if h.isNewRevision {
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonSigned, fmt.Sprintf(velatypes.MessageSigned, appRev.Name,
+			fmt.Sprintf("digest=%s signer=%s", appRev.Annotations[oam.AnnotationManifestDigest], key.ID))))
+	}
+	return nil
+}
+
+// offloadAppRevision returns a copy of the revision to write with the manifests compressed and moved out of it if
+// they're too large, the manifests of the revision in memory are kept for the rest of the reconciliation
+func (h *appHandler) offloadAppRevision(ctx context.Context, appRev *v1beta1.ApplicationRevision) (*v1beta1.ApplicationRevision, error) {
+	stored := appRev.DeepCopy()
+	if err := manifeststore.Offload(ctx, h.r.Client, stored, h.r.manifestStore); err != nil {
+		return nil, err
+	}
+	if manifeststore.Offloaded(stored) {
+		h.decisions.Record(decision.StageApply, appRev.Name, "manifests of appRevision moved to the %s store since they exceed the threshold",
+			stored.GetAnnotations()[oam.AnnotationManifestStore])
+	}
+	return stored, nil
+}
+
 // ConvertComponents2RawRevisions convert to ComponentMap
 func ConvertComponents2RawRevisions(comps []*v1alpha2.Component) []common.RawComponent {
 	var objs []common.RawComponent
@@ -260,7 +366,10 @@ func ComputeAppRevisionHash(appRevision *v1beta1.ApplicationRevision) (string, e
 		ScopeDefinitionHash:     make(map[string]string),
 	}
 	var err error
-	appRevisionHash.ApplicationSpecHash, err = utils.ComputeSpecHash(&appRevision.Spec.Application.Spec)
+	// the revision history limit doesn't change what's deployed, so it's not hashed
+	appSpec := appRevision.Spec.Application.Spec.DeepCopy()
+	appSpec.RevisionHistoryLimit = nil
+	appRevisionHash.ApplicationSpecHash, err = utils.ComputeSpecHash(appSpec)
 	if err != nil {
 		return "", err
 	}
@@ -293,7 +402,32 @@ func ComputeAppRevisionHash(appRevision *v1beta1.ApplicationRevision) (string, e
 		appRevisionHash.ScopeDefinitionHash[key] = hash
 	}
 	// compute the hash of the entire structure
-	return utils.ComputeSpecHash(&appRevisionHash)
+	hash, err := utils.ComputeSpecHash(&appRevisionHash)
+	if err != nil {
+		return "", err
+	}
+	// the policy and workflow step definitions and the CUE packages are only hashed if they're snapshotted, so the
+	// hash of the revisions without them doesn't change
+	snapshot := AppRevisionSnapshotHash{
+		PolicyDefinitionHash:       make(map[string]string),
+		WorkflowStepDefinitionHash: make(map[string]string),
+		CUEPackages:                appRevision.Spec.CUEPackages,
+	}
+	for key, d := range appRevision.Spec.PolicyDefinitions {
+		if snapshot.PolicyDefinitionHash[key], err = utils.ComputeSpecHash(&d.Spec); err != nil {
+			return "", err
+		}
+	}
+	for key, d := range appRevision.Spec.WorkflowStepDefinitions {
+		if snapshot.WorkflowStepDefinitionHash[key], err = utils.ComputeSpecHash(&d.Spec); err != nil {
+			return "", err
+		}
+	}
+	if len(snapshot.PolicyDefinitionHash) == 0 && len(snapshot.WorkflowStepDefinitionHash) == 0 && len(snapshot.CUEPackages) == 0 {
+		return hash, nil
+	}
+	snapshot.AppRevisionHash = hash
+	return utils.ComputeSpecHash(&snapshot)
 }
 
 // cleanUpApplicationRevision check all appRevisions of the application, remove them if the number of them exceed the limit
@@ -311,8 +445,17 @@ func cleanUpApplicationRevision(ctx context.Context, h *appHandler) error {
 	if err != nil {
 		return err
 	}
-	needKill := len(appRevisionList.Items) - h.r.appRevisionLimit - len(usingRevision)
+	limit := h.revisionHistoryLimit()
+	var inUse int
+	for _, rev := range appRevisionList.Items {
+		if usingRevision[rev.Name] {
+			inUse++
+		}
+	}
+	needKill := len(appRevisionList.Items) - limit - inUse
 	if needKill <= 0 {
+		h.decisions.Record(decision.StageGC, h.app.Name, "keep all the %d appRevisions within the limit %d",
+			len(appRevisionList.Items), limit)
 		return nil
 	}
 	h.logger.Info("application controller cleanup old appRevisions", "needKillNum", needKill)
@@ -325,11 +468,16 @@ func cleanUpApplicationRevision(ctx context.Context, h *appHandler) error {
 		}
 		// we shouldn't delete the revision witch appContext pointing to
 		if usingRevision[rev.Name] {
+			h.decisions.Record(decision.StageGC, rev.Name, "appRevision kept since it's in use")
 			continue
 		}
 		if err := h.r.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
 			return err
 		}
+		if err := manifeststore.Delete(ctx, h.r.Client, &rev); err != nil {
+			return errors.WithMessagef(err, "cannot delete the manifests of appRevision %s", rev.Name)
+		}
+		h.decisions.Record(decision.StageGC, rev.Name, "appRevision deleted since it exceeds the limit %d", limit)
 		needKill--
 	}
 	return nil
@@ -345,6 +493,10 @@ func gatherUsingAppRevision(ctx context.Context, h *appHandler) (map[string]bool
 	if h.app.Status.LatestRevision != nil && len(h.app.Status.LatestRevision.Name) != 0 {
 		usingRevision[h.app.Status.LatestRevision.Name] = true
 	}
+	// the revision rolled back to is kept as long as its manifests are dispatched
+	if source := h.app.GetAnnotations()[oam.AnnotationRollbackRevision]; len(source) != 0 {
+		usingRevision[source] = true
+	}
 	appContextList := new(v1alpha2.ApplicationContextList)
 	err := h.r.List(ctx, appContextList, listOpts...)
 	if err != nil {
@@ -360,9 +512,99 @@ func gatherUsingAppRevision(ctx context.Context, h *appHandler) (map[string]bool
 	for _, revName := range appDeployUsingRevision {
 		usingRevision[revName] = true
 	}
+	rolloutUsingRevision, err := gatherRolloutAppRevision(ctx, h)
+	if err != nil {
+		return usingRevision, err
+	}
+	for _, revName := range rolloutUsingRevision {
+		usingRevision[revName] = true
+	}
 	return usingRevision, nil
 }
 
+// gatherRolloutAppRevision get the appRevisions the live rollouts are rolling out from or to, including the rollout
+// plan of the application
+func gatherRolloutAppRevision(ctx context.Context, h *appHandler) ([]string, error) {
+	var revisions []string
+	addRevision := func(names ...string) {
+		for _, name := range names {
+			if len(name) != 0 {
+				revisions = append(revisions, name)
+			}
+		}
+	}
+	addRevision(h.app.Status.Rollout.LastUpgradedTargetAppRevision, h.app.Status.Rollout.LastSourceAppRevision)
+	rollouts := new(v1beta1.AppRolloutList)
+	if err := h.r.List(ctx, rollouts, client.InNamespace(h.app.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, rollout := range rollouts.Items {
+		addRevision(rollout.Spec.TargetAppRevisionName, rollout.Spec.SourceAppRevisionName)
+	}
+	return revisions, nil
+}
+
+// revisionHistoryLimit returns the number of old revisions of the application and its components to keep
+func (h *appHandler) revisionHistoryLimit() int {
+	if limit := h.app.Spec.RevisionHistoryLimit; limit != nil && *limit >= 0 {
+		return int(*limit)
+	}
+	return h.r.appRevisionLimit
+}
+
+// cleanUpComponentRevision removes the old revisions of the components of the application exceeding the revision
+// history limit. The revisions referred by the remaining appRevisions are kept since the application may be rolled
+// back or out to them, so are the latest ones.
+func cleanUpComponentRevision(ctx context.Context, h *appHandler) error {
+	appRevisionList := new(v1beta1.ApplicationRevisionList)
+	if err := h.r.List(ctx, appRevisionList, client.InNamespace(h.app.Namespace),
+		client.MatchingLabels{oam.LabelAppName: h.app.Name}); err != nil {
+		return err
+	}
+	usingRevision := map[string]bool{}
+	for i := range appRevisionList.Items {
+		appRev := &appRevisionList.Items[i]
+		if err := manifeststore.Load(ctx, h.r.Client, appRev); err != nil {
+			return err
+		}
+		ac, err := util.RawExtension2AppConfig(appRev.Spec.ApplicationConfiguration)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot get the component revisions of appRevision %s", appRev.Name)
+		}
+		for _, acc := range ac.Spec.Components {
+			if len(acc.RevisionName) != 0 {
+				usingRevision[acc.RevisionName] = true
+			}
+		}
+	}
+	limit := h.revisionHistoryLimit()
+	for _, comp := range h.app.Spec.Components {
+		revisionList := new(appsv1.ControllerRevisionList)
+		if err := h.r.List(ctx, revisionList, client.InNamespace(h.app.Namespace),
+			client.MatchingLabels{applicationconfiguration.ControllerRevisionComponentLabel: comp.Name}); err != nil {
+			return err
+		}
+		revisions := revisionList.Items
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+		var kept int
+		for i, rev := range revisions {
+			// the latest revision is kept even if it's not referred by any appRevision yet
+			if i == 0 || usingRevision[rev.Name] {
+				continue
+			}
+			if kept < limit {
+				kept++
+				continue
+			}
+			if err := h.r.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			h.decisions.Record(decision.StageGC, rev.Name, "component revision deleted since it exceeds the limit %d", limit)
+		}
+	}
+	return nil
+}
+
 type historiesByRevision []v1beta1.ApplicationRevision
 
 func (h historiesByRevision) Len() int      { return len(h) }
@@ -20,6 +20,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
@@ -27,9 +29,11 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -39,6 +43,7 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
@@ -408,6 +413,71 @@ var _ = Describe("Test gatherUsingAppRevision func", func() {
 			return nil
 		}, time.Second*60, time.Microsecond).Should(BeNil())
 	})
+
+	It("clean up component revisions exceeding the revision history limit", func() {
+		appName := "app-4"
+		compName := "app-4-comp"
+		app := getApp(appName, namespace, "normal-worker")
+		app.Spec.Components[0].Name = compName
+		app.Spec.RevisionHistoryLimit = pointer.Int32Ptr(1)
+		for i := 1; i <= 5; i++ {
+			rev := &appsv1.ControllerRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      fmt.Sprintf("%s-v%d", compName, i),
+					Labels:    map[string]string{applicationconfiguration.ControllerRevisionComponentLabel: compName},
+				},
+				Data:     runtime.RawExtension{Raw: []byte(`{}`)},
+				Revision: int64(i),
+			}
+			Expect(k8sClient.Create(ctx, rev)).Should(BeNil())
+		}
+		By("appRevision v1 refers to the first component revision")
+		ac := &v1alpha2.ApplicationConfiguration{
+			TypeMeta: metav1.TypeMeta{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: v1alpha2.ApplicationConfigurationKind},
+			Spec: v1alpha2.ApplicationConfigurationSpec{
+				Components: []v1alpha2.ApplicationConfigurationComponent{{RevisionName: compName + "-v1"}},
+			},
+		}
+		appRev := &v1beta1.ApplicationRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      appName + "-v1",
+				Labels:    map[string]string{oam.LabelAppName: appName},
+			},
+			Spec: v1beta1.ApplicationRevisionSpec{
+				Application:              *app.DeepCopy(),
+				ApplicationConfiguration: util.Object2RawExtension(ac),
+			},
+		}
+		Expect(k8sClient.Create(ctx, appRev)).Should(BeNil())
+
+		handler := appHandler{
+			r:      reconciler,
+			app:    app,
+			logger: reconciler.Log.WithValues("application", "cleanUpComponentRevision-func-test"),
+		}
+		Eventually(func() error {
+			if err := cleanUpComponentRevision(ctx, &handler); err != nil {
+				return err
+			}
+			revisions := new(appsv1.ControllerRevisionList)
+			if err := k8sClient.List(ctx, revisions, client.InNamespace(namespace),
+				client.MatchingLabels{applicationconfiguration.ControllerRevisionComponentLabel: compName}); err != nil {
+				return err
+			}
+			var names []string
+			for _, rev := range revisions.Items {
+				names = append(names, rev.Name)
+			}
+			sort.Strings(names)
+			// the latest one, the one referred by appRevision and one more within the limit are kept
+			if want := []string{compName + "-v1", compName + "-v4", compName + "-v5"}; !reflect.DeepEqual(names, want) {
+				return fmt.Errorf("want component revisions %v, actually %v", want, names)
+			}
+			return nil
+		}, time.Second*30, time.Millisecond*300).Should(BeNil())
+	})
 })
 
 func getAppContext(namespace, name string, pointingRev string) *v1alpha2.ApplicationContext {
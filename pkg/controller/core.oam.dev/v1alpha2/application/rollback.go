@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/rollback"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+)
+
+// loadRollbackSource loads the revision the application is rolled back to, see oam.AnnotationRollbackRevision. The
+// annotation is removed once the spec of the application is changed since the rollback, or if the revision is gone,
+// then the application is rendered as usual.
+func (h *appHandler) loadRollbackSource(ctx context.Context) error {
+	name := h.app.GetAnnotations()[oam.AnnotationRollbackRevision]
+	if len(name) == 0 {
+		return nil
+	}
+	rev := &v1beta1.ApplicationRevision{}
+	err := h.r.Get(ctx, client.ObjectKey{Namespace: h.app.Namespace, Name: name}, rev)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "cannot get revision %s the application is rolled back to", name)
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedRollback, fmt.Errorf("revision %s the application is rolled back to is not found, the application is rendered from its spec", name)))
+	case !rollback.SameSpec(h.app, rev):
+		h.decisions.Record(decision.StageParse, h.app.Name, "rollback to %s ends since the spec is changed", name)
+	default:
+		if err := manifeststore.Load(ctx, h.r.Client, rev); err != nil {
+			return err
+		}
+		h.rollbackSource = rev
+		return nil
+	}
+	patch := client.MergeFrom(h.app.DeepCopy())
+	annotations := h.app.GetAnnotations()
+	delete(annotations, oam.AnnotationRollbackRevision)
+	h.app.SetAnnotations(annotations)
+	return errors.Wrap(h.r.Patch(ctx, h.app, patch), "cannot remove the annotation of the rollback")
+}
+
+// pinRollbackSnapshot copies the definitions and CUE packages snapshotted in the revision rolled back to into the
+// revision generated, so the revision recording the rollback is identical to the one rolled back to
+func (h *appHandler) pinRollbackSnapshot(appRev *v1beta1.ApplicationRevision) {
+	source := h.rollbackSource.Spec
+	appRev.Spec.ComponentDefinitions = source.ComponentDefinitions
+	appRev.Spec.WorkloadDefinitions = source.WorkloadDefinitions
+	appRev.Spec.TraitDefinitions = source.TraitDefinitions
+	appRev.Spec.ScopeDefinitions = source.ScopeDefinitions
+	appRev.Spec.PolicyDefinitions = source.PolicyDefinitions
+	appRev.Spec.WorkflowStepDefinitions = source.WorkflowStepDefinitions
+	appRev.Spec.CUEPackages = source.CUEPackages
+}
+
+// rollbackManifests returns the manifests stored in the revision rolled back to, they replace the ones rendered. The
+// components no longer in the revision are collected as garbage as usual.
+func (h *appHandler) rollbackManifests() (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
+	ac, comps, err := rollback.Manifests(h.rollbackSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	h.decisions.Record(decision.StageApply, h.app.Name, "dispatch the %d components stored in revision %s rolled back to", len(comps), h.rollbackSource.Name)
+	return ac, comps, nil
+}
+
+// recordRollback records the event of the rollback once the revision recording it is generated
+func (h *appHandler) recordRollback(appRev *v1beta1.ApplicationRevision) {
+	if h.rollbackSource == nil || !h.isNewRevision {
+		return
+	}
+	h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonRolledBack, fmt.Sprintf(velatypes.MessageRolledBack, h.rollbackSource.Name, appRev.Name)))
+}
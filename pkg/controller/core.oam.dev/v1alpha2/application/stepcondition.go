@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// evaluateStepConditions decides whether the workflow steps with if conditions are executed or skipped once all the
+// previous steps finish, i.e., succeeded, skipped or failed. The decision is recorded in the status of the step and
+// never evaluated again.
+func (h *appHandler) evaluateStepConditions() {
+	for _, step := range h.app.Spec.Workflow {
+		if !h.isStepAwaitingCondition(step.Name) {
+			if !h.isStepFinished(step.Name) {
+				return
+			}
+			continue
+		}
+		// the if condition may refer to the outputs imported as inputs
+		resolved, missing, err := workflow.ResolveInputs(step, h.outputs)
+		if err == nil && len(missing) != 0 {
+			return
+		}
+		status := common.WorkflowStepStatus{Name: step.Name, Type: step.Type, Phase: common.WorkflowStepPhaseRunning}
+		ok := false
+		if err == nil {
+			ok, err = workflow.EvaluateStepCondition(h.app, resolved)
+		}
+		switch {
+		case err != nil:
+			status.Phase = common.WorkflowStepPhaseFailed
+			status.Message = err.Error()
+			h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedWorkflow, err))
+		case !ok:
+			status.Phase = common.WorkflowStepPhaseSkipped
+			status.Message = fmt.Sprintf("skipped since the if condition is false: %s", step.If)
+		}
+		h.decisions.Record(decision.StageSchedule, step.Name, "workflow step %s, if condition: %s", status.Phase, step.If)
+		h.app.Status.Workflow = append(h.app.Status.Workflow, status)
+		if status.Phase == common.WorkflowStepPhaseRunning {
+			// the following steps wait for this one to finish
+			return
+		}
+	}
+}
+
+// isStepAwaitingCondition returns true if the step has an if condition which is not evaluated yet
+func (h *appHandler) isStepAwaitingCondition(stepName string) bool {
+	if len(stepName) == 0 || h.workflowStepStatus(stepName) != nil {
+		return false
+	}
+	for _, step := range h.app.Spec.Workflow {
+		if step.Name == stepName {
+			return len(step.If) != 0
+		}
+	}
+	return false
+}
+
+// isStepSkipped returns true if the step is skipped since its if condition is false
+func (h *appHandler) isStepSkipped(stepName string) bool {
+	status := h.workflowStepStatus(stepName)
+	return status != nil && status.Phase == common.WorkflowStepPhaseSkipped
+}
+
+// isStepFinished returns true if the step succeeded, is skipped, or failed after all its retries
+func (h *appHandler) isStepFinished(stepName string) bool {
+	status := h.workflowStepStatus(stepName)
+	if status == nil {
+		return false
+	}
+	return status.Phase == common.WorkflowStepPhaseSucceeded || status.Phase == common.WorkflowStepPhaseSkipped ||
+		workflow.IsStepFailed(status.Phase)
+}
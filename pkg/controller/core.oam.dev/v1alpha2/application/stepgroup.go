@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// scheduleStepGroups starts the sub-steps of the step-group workflow steps under their concurrency limits, the
+// step groups are recorded as running once the application is reconciled
+func (h *appHandler) scheduleStepGroups() {
+	for _, step := range h.app.Spec.Workflow {
+		if step.Type != workflow.TypeStepGroup || h.isStepAwaitingCondition(step.Name) || h.isStepSkipped(step.Name) {
+			continue
+		}
+		// the step groups waiting for their inputs are left out of the appfile
+		concurrency, ok := h.appfile.WorkflowStepGroups[step.Name]
+		if !ok {
+			continue
+		}
+		status := h.workflowStepStatus(step.Name)
+		if status == nil {
+			h.app.Status.Workflow = append(h.app.Status.Workflow, common.WorkflowStepStatus{
+				Name:  step.Name,
+				Type:  workflow.TypeStepGroup,
+				Phase: common.WorkflowStepPhaseRunning,
+			})
+			status = &h.app.Status.Workflow[len(h.app.Status.Workflow)-1]
+		}
+		workflow.ScheduleSubSteps(step, concurrency, status)
+	}
+}
+
+// aggregateStepGroups records the phases of the step-group workflow steps aggregated from their sub-steps, the
+// step group waiting for a retry stays as it is
+func (h *appHandler) aggregateStepGroups() {
+	for _, step := range h.app.Spec.Workflow {
+		if step.Type != workflow.TypeStepGroup {
+			continue
+		}
+		status := h.workflowStepStatus(step.Name)
+		if status == nil || status.Phase == common.WorkflowStepPhaseRetrying || status.Phase == common.WorkflowStepPhaseSkipped {
+			continue
+		}
+		phase, msg := workflow.StepGroupPhase(status)
+		if phase != status.Phase {
+			h.logger.Info("step group phase changed", "step", step.Name, "from", status.Phase, "to", phase)
+		}
+		status.Phase = phase
+		if phase == common.WorkflowStepPhaseFailed {
+			status.Message = msg
+		}
+	}
+}
+
+// isSubStepStarted returns true if the sub-step of the step group is started, i.e., it's not pending under the
+// concurrency limit of the group
+func (h *appHandler) isSubStepStarted(stepName, subStepName string) bool {
+	group := h.workflowStepStatus(stepName)
+	if group == nil {
+		return false
+	}
+	sub := workflow.SubStepStatus(group, subStepName)
+	return sub != nil && sub.Phase != common.WorkflowStepPhasePending
+}
+
+// setAppDependencySubStepStatus records the status of the depends-on-app sub-step of a step group
+func (h *appHandler) setAppDependencySubStepStatus(stepName, subStepName string, s common.AppDependencyStatus) {
+	group := h.workflowStepStatus(stepName)
+	if group == nil {
+		return
+	}
+	sub := workflow.SubStepStatus(group, subStepName)
+	if sub == nil {
+		return
+	}
+	sub.ResourceRef = runtimev1alpha1.TypedReference{
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Kind:       v1beta1.ApplicationKind,
+		Name:       s.Name,
+	}
+	sub.Message = ""
+	switch s.Phase {
+	case common.AppDependencySatisfied:
+		sub.Phase = common.WorkflowStepPhaseSucceeded
+	case common.AppDependencyWaiting:
+		sub.Phase = common.WorkflowStepPhaseRunning
+	case common.AppDependencyTimeout:
+		sub.Phase = common.WorkflowStepPhaseFailed
+		sub.Message = s.Message
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// resolveStepInputs returns a copy of the application whose workflow steps get the outputs they import set into
+// their properties, it's parsed instead of the application itself. The steps whose inputs are not all exported yet
+// are left out, they're not started until the previous steps export the outputs.
+func (h *appHandler) resolveStepInputs() (*v1beta1.Application, error) {
+	app := h.app.DeepCopy()
+	app.Spec.Workflow = nil
+	for _, step := range h.app.Spec.Workflow {
+		resolved, missing, err := workflow.ResolveInputs(step, h.outputs)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) != 0 {
+			h.decisions.Record(decision.StageSchedule, step.Name, "workflow step waits for outputs %s", strings.Join(missing, ", "))
+			continue
+		}
+		app.Spec.Workflow = append(app.Spec.Workflow, resolved)
+	}
+	return app, nil
+}
+
+// exportStepOutputs exports the outputs of the succeeded workflow steps from the resources they refer to, and
+// persists them in the workflow context. Each output is exported only once.
+func (h *appHandler) exportStepOutputs(ctx context.Context) error {
+	if h.outputs == nil {
+		h.outputs = map[string]string{}
+	}
+	exported := false
+	for _, step := range h.app.Spec.Workflow {
+		if len(step.Outputs) == 0 || h.isStepExported(step) {
+			continue
+		}
+		status := h.workflowStepStatus(step.Name)
+		if status == nil || status.Phase != common.WorkflowStepPhaseSucceeded {
+			continue
+		}
+		if len(status.ResourceRef.Kind) == 0 {
+			return errors.Errorf("workflow step %s refers to no resource to export outputs from", step.Name)
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(status.ResourceRef.APIVersion)
+		obj.SetKind(status.ResourceRef.Kind)
+		key := client.ObjectKey{Namespace: h.stepResourceNamespace(step.Name), Name: status.ResourceRef.Name}
+		if err := h.r.Get(ctx, key, obj); err != nil {
+			return errors.Wrapf(err, "cannot get %s %s referred by workflow step %s", obj.GetKind(), key, step.Name)
+		}
+		outputs, err := workflow.ExtractOutputs(step, obj)
+		if err != nil {
+			return err
+		}
+		for k, v := range outputs {
+			h.outputs[k] = v
+		}
+		h.decisions.Record(decision.StageSchedule, step.Name, "workflow step exports %d outputs", len(outputs))
+		exported = true
+	}
+	if !exported {
+		return nil
+	}
+	cm := workflow.NewContextConfigMap(h.app, h.outputs)
+	return errors.Wrapf(h.r.applicator.Apply(ctx, cm), "cannot persist the workflow context %s", cm.Name)
+}
+
+func (h *appHandler) isStepExported(step v1beta1.WorkflowStep) bool {
+	for _, out := range step.Outputs {
+		if _, ok := h.outputs[out.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stepResourceNamespace returns the namespace of the resource referred by the workflow step, e.g., the application
+// depended on by a depends-on-app step
+func (h *appHandler) stepResourceNamespace(stepName string) string {
+	if h.appfile != nil {
+		for _, dep := range h.appfile.AppDependencies {
+			if dep.StepName == stepName && len(dep.SubStepName) == 0 {
+				return dep.Namespace
+			}
+		}
+	}
+	return h.app.Namespace
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// enforceWorkflowStepPolicies enforces the timeout and retry policies on the started workflow steps. It returns the
+// duration after which the application needs to be reconciled again to check the steps, it's zero if not needed.
+func (h *appHandler) enforceWorkflowStepPolicies() time.Duration {
+	now := metav1.Now()
+	var requeue time.Duration
+	for i := range h.app.Status.Workflow {
+		status := &h.app.Status.Workflow[i]
+		policy, ok := h.appfile.WorkflowStepPolicies[status.Name]
+		if !ok {
+			continue
+		}
+		phase := status.Phase
+		if d := workflow.EnforceStepPolicy(policy, status, now); d > 0 && (requeue == 0 || d < requeue) {
+			requeue = d
+		}
+		if status.Phase == phase {
+			continue
+		}
+		h.logger.Info("workflow step phase changed", "step", status.Name, "from", phase, "to", status.Phase, "message", status.Message)
+		if workflow.IsStepFailed(status.Phase) {
+			h.r.Recorder.Event(h.app, event.Warning(velatypes.ReasonFailedWorkflow,
+				errors.Errorf("workflow step %s %s: %s", status.Name, status.Phase, status.Message)))
+		}
+	}
+	return requeue
+}
@@ -51,7 +51,9 @@ import (
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/utils/requeue"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -136,7 +138,9 @@ var _ = BeforeSuite(func(done Done) {
 		dm:               dm,
 		pd:               pd,
 		Recorder:         event.NewAPIRecorder(recorder),
+		gateway:          multicluster.NewClusterGateway(k8sClient),
 		appRevisionLimit: appRevisionLimit,
+		requeue:          requeue.Intervals{Active: 10 * time.Second},
 	}
 	// setup the controller manager since we need the component handler to run in the background
 	ctlManager, err = ctrl.NewManager(cfg, ctrl.Options{
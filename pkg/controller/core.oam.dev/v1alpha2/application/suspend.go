@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// handleWorkflowSuspend records the suspension of the workflow in the status if it's suspended, and clears the
+// suspension once it's resumed. It returns true if the workflow is suspended, the resources of the application are
+// neither rendered nor applied then, so the deployment stops before its next step and the finished steps are kept.
+func (h *appHandler) handleWorkflowSuspend() bool {
+	if !workflow.IsSuspended(h.app) {
+		if h.app.Status.WorkflowSuspend != nil {
+			h.app.Status.WorkflowSuspend = nil
+			h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonResumed, velatypes.MessageResumed))
+		}
+		return false
+	}
+	reason := h.app.Spec.WorkflowSuspend.Reason
+	status := h.app.Status.WorkflowSuspend
+	if status == nil {
+		status = &common.WorkflowSuspendStatus{
+			CurrentStep: workflow.CurrentStep(h.app),
+			SuspendedAt: metav1.Now(),
+		}
+		h.r.Recorder.Event(h.app, event.Normal(velatypes.ReasonSuspended, fmt.Sprintf(velatypes.MessageSuspended, status.CurrentStep, reason)))
+	}
+	// the current step is kept as it was when suspended since nothing progresses during the suspension
+	status.Reason = reason
+	h.app.Status.WorkflowSuspend = status
+	h.app.Status.Phase = common.ApplicationWorkflowSuspended
+	return true
+}
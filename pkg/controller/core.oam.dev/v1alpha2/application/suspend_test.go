@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+var _ = Describe("Test suspend and resume workflow", func() {
+	newHandler := func() *appHandler {
+		app := &v1beta1.Application{
+			Spec: v1beta1.ApplicationSpec{
+				Workflow: []v1beta1.WorkflowStep{
+					{Name: "wait-db", Type: "depends-on-app"},
+					{Name: "notify", Type: "webhook"},
+				},
+			},
+			Status: common.AppStatus{
+				Workflow: []common.WorkflowStepStatus{
+					{Name: "wait-db", Phase: common.WorkflowStepPhaseSucceeded},
+				},
+			},
+		}
+		return &appHandler{r: &Reconciler{Recorder: event.NewNopRecorder()}, app: app}
+	}
+
+	It("record the suspension before the current step", func() {
+		h := newHandler()
+		h.app.Spec.WorkflowSuspend = &common.WorkflowSuspend{Reason: "manual verification"}
+		Expect(h.handleWorkflowSuspend()).Should(BeTrue())
+		Expect(h.app.Status.Phase).Should(Equal(common.ApplicationWorkflowSuspended))
+		Expect(h.app.Status.WorkflowSuspend).ShouldNot(BeNil())
+		Expect(h.app.Status.WorkflowSuspend.CurrentStep).Should(Equal("notify"))
+		Expect(h.app.Status.WorkflowSuspend.Reason).Should(Equal("manual verification"))
+		suspendedAt := h.app.Status.WorkflowSuspend.SuspendedAt
+
+		By("keep the time suspended while the reason changes")
+		h.app.Spec.WorkflowSuspend.Reason = "waiting for the release window"
+		Expect(h.handleWorkflowSuspend()).Should(BeTrue())
+		Expect(h.app.Status.WorkflowSuspend.SuspendedAt).Should(Equal(suspendedAt))
+		Expect(h.app.Status.WorkflowSuspend.Reason).Should(Equal("waiting for the release window"))
+	})
+
+	It("clear the suspension once resumed", func() {
+		h := newHandler()
+		h.app.Spec.WorkflowSuspend = &common.WorkflowSuspend{}
+		Expect(h.handleWorkflowSuspend()).Should(BeTrue())
+		h.app.Spec.WorkflowSuspend = nil
+		Expect(h.handleWorkflowSuspend()).Should(BeFalse())
+		Expect(h.app.Status.WorkflowSuspend).Should(BeNil())
+	})
+})
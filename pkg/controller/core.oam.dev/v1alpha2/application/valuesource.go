@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// secretToApplications maps a Secret to the Applications in its namespace resolving property values from it, so
+// they're re-rendered with the rotated values once it changes
+func secretToApplications(c client.Reader) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		apps := &v1beta1.ApplicationList{}
+		if err := c.List(context.Background(), apps, client.InNamespace(o.Meta.GetNamespace())); err != nil {
+			klog.V(4).InfoS("Cannot list applications referring to the secret", "secret", klog.KRef(o.Meta.GetNamespace(), o.Meta.GetName()), "err", err)
+			return nil
+		}
+		var reqs []reconcile.Request
+		for i := range apps.Items {
+			if app := &apps.Items[i]; referSecret(app, o.Meta.GetName()) {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: app.Namespace, Name: app.Name}})
+			}
+		}
+		return reqs
+	})}
+}
+
+func referSecret(app *v1beta1.Application, secret string) bool {
+	for _, s := range app.Status.ValueSources {
+		if s.Secret == secret {
+			return true
+		}
+	}
+	return false
+}
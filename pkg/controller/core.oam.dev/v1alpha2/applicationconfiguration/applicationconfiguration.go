@@ -30,24 +30,33 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	oamtype "github.com/oam-dev/kubevela/apis/types"
 	core "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
+	"github.com/oam-dev/kubevela/pkg/migration"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/utils/apply"
+	"github.com/oam-dev/kubevela/pkg/utils/impersonate"
+	"github.com/oam-dev/kubevela/pkg/utils/recorder"
 )
 
 const (
@@ -65,6 +74,7 @@ const (
 	errApplyComponents       = "cannot apply components"
 	errGCComponent           = "cannot garbage collect components"
 	errFinalizeWorkloads     = "failed to finalize workloads"
+	errMigrateAppConfig      = "cannot migrate application configuration to application"
 )
 
 // Reconcile event reasons.
@@ -75,12 +85,17 @@ const (
 	reasonExecutePosthook         = "ExecutePosthook"
 	reasonApplyComponents         = "AppliedComponents"
 	reasonGGComponent             = "GarbageCollectedComponent"
+	reasonDeferGGComponent        = "DeferredGarbageCollection"
+	reasonProtectedComponent      = "ProtectedFromGarbageCollection"
 	reasonCannotExecutePrehooks   = "CannotExecutePrehooks"
 	reasonCannotExecutePosthooks  = "CannotExecutePosthooks"
 	reasonCannotRenderComponents  = "CannotRenderComponents"
 	reasonCannotApplyComponents   = "CannotApplyComponents"
+	reasonCannotApplyResource     = "CannotApplyResource"
 	reasonCannotGGComponents      = "CannotGarbageCollectComponents"
 	reasonCannotFinalizeWorkloads = "CannotFinalizeWorkloads"
+	reasonMigrated                = "MigratedToApplication"
+	reasonCannotMigrate           = "CannotMigrateToApplication"
 )
 
 // Setup adds a controller that reconciles ApplicationConfigurations.
@@ -90,10 +105,28 @@ func Setup(mgr ctrl.Manager, args core.Args, l logging.Logger) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		For(&v1alpha2.ApplicationConfiguration{}).
+		// the ApplicationConfiguration is named after the Application, render the pending Helm-based components again
+		// once Helm creates their workloads
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: assemble.HelmWorkloadToApplication(mgr.GetClient())}).
+		Watches(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: assemble.HelmWorkloadToApplication(mgr.GetClient())}).
 		Complete(NewReconciler(mgr, args.DiscoveryMapper,
 			l.WithValues("controller", name),
-			WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
-			WithApplyOnceOnlyMode(args.ApplyMode)))
+			WithRecorder(recorder.NewDedupRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name)), args.EventDedupInterval)),
+			WithApplyOnceOnlyMode(args.ApplyMode),
+			WithApplyStrategy(applyStrategy(args)),
+			WithApplyRateLimiter(args.ApplyRateLimiter),
+			WithHelmWorkloadDiscoveryTimeout(args.HelmWorkloadDiscoveryTimeout),
+			WithImpersonation(impersonate.NewClientFactory(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper()).
+				WithRequired(args.RequireImpersonation))))
+}
+
+func applyStrategy(args core.Args) apply.Strategy {
+	if args.EnableServerSideApply {
+		return apply.StrategyServerSide
+	}
+	return apply.StrategyThreeWayMerge
 }
 
 // An OAMApplicationReconciler reconciles OAM ApplicationConfigurations by rendering and
@@ -109,6 +142,8 @@ type OAMApplicationReconciler struct {
 	preHooks          map[string]ControllerHooks
 	postHooks         map[string]ControllerHooks
 	applyOnceOnlyMode core.ApplyOnceOnlyMode
+	impersonation     *impersonate.ClientFactory
+	dm                discoverymapper.DiscoveryMapper
 }
 
 // A ReconcilerOption configures a Reconciler.
@@ -128,6 +163,47 @@ func WithApplicator(a WorkloadApplicator) ReconcilerOption {
 	}
 }
 
+// WithApplyStrategy specifies the default strategy to apply existing workloads and traits.
+func WithApplyStrategy(s apply.Strategy) ReconcilerOption {
+	return func(rc *OAMApplicationReconciler) {
+		if w, ok := rc.workloads.(*workloads); ok {
+			if a, ok := w.applicator.(*apply.APIApplicator); ok {
+				a.WithDefaultStrategy(s)
+			}
+		}
+	}
+}
+
+// WithApplyRateLimiter specifies the rate limiter the Reconciler should wait for before applying each workload and
+// trait.
+func WithApplyRateLimiter(l flowcontrol.RateLimiter) ReconcilerOption {
+	return func(rc *OAMApplicationReconciler) {
+		if w, ok := rc.workloads.(*workloads); ok {
+			if a, ok := w.applicator.(*apply.APIApplicator); ok {
+				a.WithRateLimiter(l)
+			}
+		}
+	}
+}
+
+// WithImpersonation specifies the clients impersonating the identities declared by the annotations of the
+// ApplicationConfigurations to apply their workloads and traits, see impersonate.ConfigFor
+func WithImpersonation(f *impersonate.ClientFactory) ReconcilerOption {
+	return func(rc *OAMApplicationReconciler) {
+		rc.impersonation = f
+	}
+}
+
+// WithHelmWorkloadDiscoveryTimeout specifies how long the Reconciler waits for Helm to create the workloads of
+// Helm-based components, the components are pending until then and fail afterwards.
+func WithHelmWorkloadDiscoveryTimeout(timeout time.Duration) ReconcilerOption {
+	return func(rc *OAMApplicationReconciler) {
+		if c, ok := rc.components.(*components); ok {
+			c.helmDiscoveryTimeout = timeout
+		}
+	}
+}
+
 // WithGarbageCollector specifies how the Reconciler should garbage collect
 // workloads and traits when an ApplicationConfiguration is edited to remove
 // them.
@@ -190,6 +266,7 @@ func NewReconciler(m ctrl.Manager, dm discoverymapper.DiscoveryMapper, log loggi
 		preHooks:          make(map[string]ControllerHooks),
 		postHooks:         make(map[string]ControllerHooks),
 		applyOnceOnlyMode: core.ApplyOnceOnlyOff,
+		dm:                dm,
 	}
 
 	for _, ro := range o {
@@ -238,6 +315,11 @@ func (r *OAMApplicationReconciler) Reconcile(req reconcile.Request) (reconcile.R
 		return reconcile.Result{}, errors.Wrap(r.client.Update(ctx, ac), errUpdateAppConfigStatus)
 	}
 
+	// the AppConfig requested to be migrated is replaced by an Application rather than reconciled
+	if migration.Requested(ac) && !isControlledByApp(ac) {
+		return r.migrate(ctx, ac, log)
+	}
+
 	reconResult := r.ACReconcile(ctx, ac, log)
 	// always update ac status and set the error
 	err := errors.Wrap(r.UpdateStatus(ctx, ac), errUpdateAppConfigStatus)
@@ -309,10 +391,19 @@ func (r *OAMApplicationReconciler) ACReconcile(ctx context.Context, ac *v1alpha2
 	r.record.Event(ac, event.Normal(reasonRenderComponents, "Successfully rendered components",
 		"workloads", strconv.Itoa(len(workloads))))
 
-	applyOpts := []apply.ApplyOption{apply.MustBeControllableBy(ac.GetUID()), applyOnceOnly(ac, r.applyOnceOnlyMode, log)}
-	if err := r.workloads.Apply(ctx, ac.Status.Workloads, workloads, applyOpts...); err != nil {
+	applyOpts := []apply.ApplyOption{apply.MustBeControllableBy(ac.GetUID()), applyOnceOnly(ac, r.applyOnceOnlyMode, log),
+		respectWorkloadLease(), respectApplyOncePolicy(), shareResource()}
+	applicator, err := r.workloadApplicatorOf(ac)
+	if err != nil {
+		log.Debug("Cannot impersonate the identity declared", "error", err)
+		r.record.Event(ac, event.Warning(reasonCannotApplyComponents, err))
+		ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errApplyComponents)))
+		return reconcile.Result{}
+	}
+	if err := applicator.Apply(ctx, ac.Status.Workloads, workloads, applyOpts...); err != nil {
 		log.Debug("Cannot apply workload", "error", err)
 		r.record.Event(ac, event.Warning(reasonCannotApplyComponents, err))
+		r.recordResourceFailure(ctx, err)
 		ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errApplyComponents)))
 		return reconcile.Result{}
 	}
@@ -330,7 +421,12 @@ func (r *OAMApplicationReconciler) ACReconcile(ctx context.Context, ac *v1alpha2
 	// when the appconfig that controls them (in the controller reference sense)
 	// is deleted. Here we cover the case in which a component or one of its
 	// traits is removed from an extant appconfig.
-	for _, e := range r.gc.Eligible(ac.GetNamespace(), ac.Status.Workloads, workloads) {
+	// The resources labeled with a grace period are kept until it passes, and the protected ones are orphaned.
+	pending, gcSince := pendingDeletions(ac.GetNamespace(), ac.Status.PendingDeletions, workloads)
+	var deferred []common.PendingDeletion
+	var gcWaitTime time.Duration
+	now := time.Now()
+	for _, e := range append(r.gc.Eligible(ac.GetNamespace(), ac.Status.Workloads, workloads), pending...) {
 		// https://github.com/golang/go/wiki/CommonMistakes#using-reference-to-loop-iterator-variable
 		e := e
 
@@ -344,6 +440,59 @@ func (r *OAMApplicationReconciler) ACReconcile(ctx context.Context, ac *v1alpha2
 			ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errGCComponent)))
 			return reconcile.Result{}
 		}
+		since, ok := gcSince[gcKey(e.GetAPIVersion(), e.GetKind(), e.GetName())]
+		if !ok {
+			since = now
+		}
+		decision, wait, err := util.DecideGarbageCollection(ctx, r.client, &e, since, now)
+		if err != nil {
+			log.Debug("Cannot decide garbage collection of resource", "error", err)
+			record.Event(ac, event.Warning(reasonCannotGGComponents, err))
+			ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errGCComponent)))
+			return reconcile.Result{}
+		}
+		switch decision {
+		case util.GCGone:
+			continue
+		case util.GCProtect:
+			if err := util.OrphanResource(ctx, r.client, &e, ac.GetUID()); err != nil {
+				log.Debug("Cannot orphan protected resource", "error", err)
+				record.Event(ac, event.Warning(reasonCannotGGComponents, err))
+				ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errGCComponent)))
+				return reconcile.Result{}
+			}
+			log.Debug("Orphaned protected resource")
+			record.Event(ac, event.Warning(reasonProtectedComponent, errors.Errorf("%s %s is protected from garbage collection by label %s, it's orphaned",
+				e.GetKind(), e.GetName(), oam.LabelGCProtect)))
+			continue
+		case util.GCWait:
+			deferred = append(deferred, common.PendingDeletion{
+				APIVersion: e.GetAPIVersion(),
+				Kind:       e.GetKind(),
+				Name:       e.GetName(),
+				Since:      metav1.NewTime(since),
+			})
+			if gcWaitTime == 0 || wait < gcWaitTime {
+				gcWaitTime = wait
+			}
+			if !ok {
+				record.Event(ac, event.Normal(reasonDeferGGComponent, "Deferred garbage collection of component",
+					"grace period ends in", wait.Round(time.Second).String()))
+			}
+			continue
+		}
+		// a shared resource is only deleted by its last owner
+		released, err := util.ReleaseSharedResource(ctx, r.client, &e, ac.GetUID())
+		if err != nil {
+			log.Debug("Cannot release shared resource", "error", err)
+			record.Event(ac, event.Warning(reasonCannotGGComponents, err))
+			ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errGCComponent)))
+			return reconcile.Result{}
+		}
+		if released {
+			log.Debug("Released shared resource")
+			continue
+		}
 		if err := r.client.Delete(ctx, &e); resource.IgnoreNotFound(err) != nil {
 			log.Debug("Cannot garbage collect component", "error", err)
 			record.Event(ac, event.Warning(reasonCannotGGComponents, err))
@@ -356,6 +505,7 @@ func (r *OAMApplicationReconciler) ACReconcile(ctx context.Context, ac *v1alpha2
 
 	// patch the final status on the client side, k8s sever can't merge them
 	r.updateStatus(ctx, ac, acPatch, workloads)
+	ac.Status.PendingDeletions = deferred
 
 	ac.Status.Dependency = v1alpha2.DependencyStatus{}
 	var waitTime time.Duration
@@ -363,11 +513,31 @@ func (r *OAMApplicationReconciler) ACReconcile(ctx context.Context, ac *v1alpha2
 		waitTime = dependCheckWait
 		ac.Status.Dependency = *depStatus
 	}
+	if gcWaitTime != 0 && (waitTime == 0 || gcWaitTime < waitTime) {
+		waitTime = gcWaitTime
+	}
 
 	// the defer function will do the final status update
 	return reconcile.Result{RequeueAfter: waitTime}
 }
 
+// recordResourceFailure records the failure to apply a workload or trait on the resource as well, so it shows up in
+// the events of the resource. It's recorded only if the resource is namespaced and exists, the events can't refer to
+// the resources not created yet.
+func (r *OAMApplicationReconciler) recordResourceFailure(ctx context.Context, err error) {
+	var applyErr *resourceApplyError
+	if !errors.As(err, &applyErr) || len(applyErr.resource.GetNamespace()) == 0 {
+		return
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(applyErr.resource.GroupVersionKind())
+	key := types.NamespacedName{Namespace: applyErr.resource.GetNamespace(), Name: applyErr.resource.GetName()}
+	if err := r.client.Get(ctx, key, existing); err != nil {
+		return
+	}
+	r.record.Event(existing, event.Warning(reasonCannotApplyResource, applyErr.err))
+}
+
 // confirmDeleteOnApplyOnceMode will confirm whether the workload can be delete or not in apply once only enabled mode
 // currently only workload replicas with 0 can be delete
 func (r *OAMApplicationReconciler) confirmDeleteOnApplyOnceMode(ctx context.Context, namespace string, u *unstructured.Unstructured) error {
@@ -476,7 +646,8 @@ func patchExtraStatusField(acStatus *v1alpha2.ApplicationConfigurationStatus, ac
 		for _, w := range acPatchStatus.Workloads {
 			// find the workload in the old status
 			if acStatus.Workloads[i].ComponentRevisionName == w.ComponentRevisionName {
-				if len(w.Status) > 0 {
+				// the status set by this controller, e.g., Referred, is not overridden
+				if len(w.Status) > 0 && len(acStatus.Workloads[i].Status) == 0 {
 					acStatus.Workloads[i].Status = w.Status
 				}
 				// find the trait
@@ -528,9 +699,16 @@ type Workload struct {
 	// SkipApply indicates that the workload should not be applied
 	SkipApply bool
 
+	// Referred indicates that the workload is an existing one referred by the component, it's neither applied nor
+	// garbage collected
+	Referred bool
+
 	// HasDep indicates whether this resource has dependencies and unready to be applied.
 	HasDep bool
 
+	// HelmPending is set if the workload of the Helm-based component is not created by Helm yet
+	HelmPending *assemble.HelmWorkloadPendingError
+
 	// Traits associated with this workload.
 	Traits []*Trait
 
@@ -564,6 +742,10 @@ type Trait struct {
 	DataInputs []v1alpha2.DataInput
 }
 
+// WorkloadStatusReferred is the status of a workload referred by a component, it's an existing workload not
+// managed by the ApplicationConfiguration so it's never garbage collected
+const WorkloadStatusReferred = "Referred"
+
 // Status produces the status of this workload and its traits, suitable for use
 // in the status of an ApplicationConfiguration.
 func (w Workload) Status() v1alpha2.WorkloadStatus {
@@ -579,6 +761,9 @@ func (w Workload) Status() v1alpha2.WorkloadStatus {
 		Traits: make([]v1alpha2.WorkloadTrait, len(w.Traits)),
 		Scopes: make([]v1alpha2.WorkloadScope, len(w.Scopes)),
 	}
+	if w.Referred {
+		acw.Status = WorkloadStatusReferred
+	}
 	for i, tr := range w.Traits {
 		if tr.Definition.Name == util.Dummy && tr.Definition.Spec.Reference.Name == util.Dummy {
 			acw.Traits[i].Message = util.DummyTraitMessage
@@ -653,7 +838,7 @@ func eligible(namespace string, ws []v1alpha2.WorkloadStatus, w []Workload) []un
 	eligible := make([]unstructured.Unstructured, 0)
 	for _, s := range ws {
 
-		if !applied[s.Reference] && !IsRevisionWorkload(s, w) {
+		if !applied[s.Reference] && !IsRevisionWorkload(s, w) && s.Status != WorkloadStatusReferred {
 			w := &unstructured.Unstructured{}
 			w.SetAPIVersion(s.Reference.APIVersion)
 			w.SetKind(s.Reference.Kind)
@@ -687,6 +872,56 @@ func (e *GenerationUnchanged) Error() string {
 		"Please ignore this error in other logic.")
 }
 
+// respectWorkloadLease is an ApplyOption that aborts applying a workload if it's leased by another controller,
+// e.g., the rollout controller is upgrading the workload batch by batch.
+// It returns a WorkloadLeasedError which only aborts applying current workload.
+func respectWorkloadLease() apply.ApplyOption {
+	return func(_ context.Context, existing, desired runtime.Object) error {
+		if existing == nil {
+			return nil
+		}
+		d, _ := desired.(metav1.Object)
+		e, _ := existing.(metav1.Object)
+		if d == nil || e == nil || d.GetLabels()[oam.LabelOAMResourceType] != oam.ResourceTypeWorkload {
+			return nil
+		}
+		if holder := util.GetWorkloadLeaseHolder(e, time.Now()); len(holder) != 0 {
+			return &util.WorkloadLeasedError{Holder: holder}
+		}
+		return nil
+	}
+}
+
+// respectApplyOncePolicy is an ApplyOption that aborts applying a resource if it's selected by the apply-once policy
+// of the application and already exists, so other controllers can mutate it without a reconcile war.
+// It returns a GenerationUnchanged error which only aborts applying current resource.
+func respectApplyOncePolicy() apply.ApplyOption {
+	return func(_ context.Context, existing, desired runtime.Object) error {
+		if existing == nil {
+			return nil
+		}
+		d, _ := desired.(metav1.Object)
+		if d == nil || d.GetAnnotations()[oam.AnnotationApplyOnce] != "true" {
+			return nil
+		}
+		return &GenerationUnchanged{}
+	}
+}
+
+// shareResource is an ApplyOption that makes the desired state of a resource shared by multiple applications,
+// it's owned by all of them and only the fields not set by others are applied.
+func shareResource() apply.ApplyOption {
+	return func(_ context.Context, existing, desired runtime.Object) error {
+		d, _ := desired.(*unstructured.Unstructured)
+		if d == nil || !util.IsSharedResource(d) {
+			return nil
+		}
+		e, _ := existing.(*unstructured.Unstructured)
+		util.ShareResource(e, d)
+		return nil
+	}
+}
+
 // applyOnceOnly is an ApplyOption that controls the applying mechanism for workload and trait.
 // More detail refers to the ApplyOnceOnlyMode type annotation
 func applyOnceOnly(ac *v1alpha2.ApplicationConfiguration, mode core.ApplyOnceOnlyMode, log logging.Logger) apply.ApplyOption {
@@ -790,3 +1025,20 @@ func applyOnceOnly(ac *v1alpha2.ApplicationConfiguration, mode core.ApplyOnceOnl
 		return &GenerationUnchanged{}
 	}
 }
+
+// workloadApplicatorOf returns the WorkloadApplicator applying the workloads and traits of the ApplicationConfiguration
+// with the identity declared by its annotations, or the default one if none is declared
+func (r *OAMApplicationReconciler) workloadApplicatorOf(ac *v1alpha2.ApplicationConfiguration) (WorkloadApplicator, error) {
+	w, ok := r.workloads.(*workloads)
+	if !ok || r.impersonation == nil {
+		return r.workloads, nil
+	}
+	c, err := r.impersonation.ClientOf(ac, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return r.workloads, nil
+	}
+	return w.withClient(c), nil
+}
@@ -814,6 +814,32 @@ func TestEligible(t *testing.T) {
 			},
 			want: []unstructured.Unstructured{*workload, *trait},
 		},
+		"ReferredWorkloadNotApplied": {
+			reason: "A referred workload is never eligible for garbage collection while its trait is",
+			args: args{
+				namespace: namespace,
+				ws: []v1alpha2.WorkloadStatus{
+					{
+						Status: WorkloadStatusReferred,
+						Reference: runtimev1alpha1.TypedReference{
+							APIVersion: workload.GetAPIVersion(),
+							Kind:       workload.GetKind(),
+							Name:       workload.GetName(),
+						},
+						Traits: []v1alpha2.WorkloadTrait{
+							{
+								Reference: runtimev1alpha1.TypedReference{
+									APIVersion: trait.GetAPIVersion(),
+									Kind:       trait.GetKind(),
+									Name:       trait.GetName(),
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []unstructured.Unstructured{*trait},
+		},
 		"BothApplied": {
 			reason: "A referenced workload and its trait are not eligible for garbage collection if they were applied",
 			args: args{
@@ -100,13 +100,40 @@ type workloads struct {
 	dm         discoverymapper.DiscoveryMapper
 }
 
+// withClient returns a copy of the workloads applying and reading the objects through the client, e.g., one
+// impersonating the owner of the application
+func (a *workloads) withClient(c client.Client) *workloads {
+	var applicator apply.Applicator = apply.NewAPIApplicator(c)
+	if api, ok := a.applicator.(*apply.APIApplicator); ok {
+		applicator = api.WithClient(c)
+	}
+	return &workloads{applicator: applicator, rawClient: c, dm: a.dm}
+}
+
+// resourceApplyError is the error applying a workload or trait, the failure is recorded on the resource as well
+type resourceApplyError struct {
+	resource *unstructured.Unstructured
+	err      error
+}
+
+func (e *resourceApplyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resourceApplyError) Unwrap() error {
+	return e.err
+}
+
 func (a *workloads) Apply(ctx context.Context, status []v1alpha2.WorkloadStatus, w []Workload,
 	ao ...apply.ApplyOption) error {
 	// they are all in the same namespace
 	var namespace = w[0].Workload.GetNamespace()
 	for _, wl := range w {
 		if !wl.HasDep {
-			if wl.SkipApply {
+			if wl.Referred {
+				klog.InfoS("skip apply a referred workload not managed by the application", "component name", wl.ComponentName,
+					"workload", wl.Workload.GetName())
+			} else if wl.SkipApply {
 				klog.InfoS("skip apply a workload due to rollout", "component name", wl.ComponentName, "component revision",
 					wl.ComponentRevisionName)
 			} else {
@@ -115,10 +142,17 @@ func (a *workloads) Apply(ctx context.Context, status []v1alpha2.WorkloadStatus,
 					return err
 				}
 				if err := a.applicator.Apply(ctx, wl.Workload, ao...); err != nil {
-					if !errors.Is(err, &GenerationUnchanged{}) {
+					var leasedErr *util.WorkloadLeasedError
+					switch {
+					case errors.As(err, &leasedErr):
+						// the workload is being mutated by another controller, e.g., rollout
+						// we leave it to the lease holder and don't block the whole reconciliation
+						klog.InfoS("skip apply a workload leased by others", "component name", wl.ComponentName,
+							"workload", wl.Workload.GetName(), "lease holder", leasedErr.Holder)
+					case !errors.Is(err, &GenerationUnchanged{}):
 						// GenerationUnchanged only aborts applying current workload
 						// but not blocks the whole reconciliation through returning an error
-						return errors.Wrapf(err, errFmtApplyWorkload, wl.Workload.GetName())
+						return &resourceApplyError{resource: wl.Workload, err: errors.Wrapf(err, errFmtApplyWorkload, wl.Workload.GetName())}
 					}
 				}
 			}
@@ -138,7 +172,7 @@ func (a *workloads) Apply(ctx context.Context, status []v1alpha2.WorkloadStatus,
 					if !errors.Is(err, &GenerationUnchanged{}) {
 						// GenerationUnchanged only aborts applying current trait
 						// but not blocks the whole reconciliation through returning an error
-						return errors.Wrapf(err, errFmtApplyTrait, t.GetAPIVersion(), t.GetKind(), t.GetName())
+						return &resourceApplyError{resource: &t, err: errors.Wrapf(err, errFmtApplyTrait, t.GetAPIVersion(), t.GetKind(), t.GetName())}
 					}
 				}
 			}
@@ -152,7 +152,7 @@ func TestApplyWorkloads(t *testing.T) {
 			args: args{
 				w:  []Workload{{Workload: workload, Traits: []*Trait{{Object: *trait}}}},
 				ws: []v1alpha2.WorkloadStatus{}},
-			want: errors.Wrapf(errBoom, errFmtApplyWorkload, workload.GetName()),
+			want: &resourceApplyError{resource: workload, err: errors.Wrapf(errBoom, errFmtApplyWorkload, workload.GetName())},
 		},
 		"ApplyTraitError": {
 			reason: "Errors applying a trait should be reflected as a status condition",
@@ -166,7 +166,7 @@ func TestApplyWorkloads(t *testing.T) {
 			args: args{
 				w:  []Workload{{Workload: workload, Traits: []*Trait{{Object: *trait}}}},
 				ws: []v1alpha2.WorkloadStatus{}},
-			want: errors.Wrapf(errBoom, errFmtApplyTrait, trait.GetAPIVersion(), trait.GetKind(), trait.GetName()),
+			want: &resourceApplyError{resource: trait, err: errors.Wrapf(errBoom, errFmtApplyTrait, trait.GetAPIVersion(), trait.GetKind(), trait.GetName())},
 		},
 		"Success": {
 			reason: "Applied workloads and traits should be returned as a set of UIDs.",
@@ -36,6 +36,7 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 )
 
@@ -243,6 +244,11 @@ func sortedControllerRevision(appConfigs []v1alpha2.ApplicationConfiguration, re
 
 // clean revisions when over limits
 func (c *ComponentHandler) cleanupControllerRevision(curComp *v1alpha2.Component) error {
+	// the revisions of the components of applications are garbage collected by the application controller following
+	// the revision history limit of the applications, the ones the rollouts may use are kept there
+	if owner := metav1.GetControllerOf(curComp); owner != nil && owner.Kind == v1beta1.ApplicationKind {
+		return nil
+	}
 	labels := &metav1.LabelSelector{
 		MatchLabels: map[string]string{
 			ControllerRevisionComponentLabel: curComp.Name,
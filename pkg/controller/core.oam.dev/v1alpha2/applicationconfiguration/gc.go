@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationconfiguration
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func gcKey(apiVersion, kind, name string) string {
+	return apiVersion + "/" + kind + "/" + name
+}
+
+// pendingDeletions returns the resources still waiting for their garbage collection grace periods, except the ones
+// rendered again, and the time they were found no longer rendered keyed by gcKey
+func pendingDeletions(namespace string, pending []common.PendingDeletion, w []Workload) ([]unstructured.Unstructured, map[string]time.Time) {
+	rendered := map[string]bool{}
+	for _, wl := range w {
+		if wl.Workload != nil {
+			rendered[gcKey(wl.Workload.GetAPIVersion(), wl.Workload.GetKind(), wl.Workload.GetName())] = true
+		}
+		for _, tr := range wl.Traits {
+			rendered[gcKey(tr.Object.GetAPIVersion(), tr.Object.GetKind(), tr.Object.GetName())] = true
+		}
+	}
+	var resources []unstructured.Unstructured
+	since := make(map[string]time.Time, len(pending))
+	for _, p := range pending {
+		key := gcKey(p.APIVersion, p.Kind, p.Name)
+		if rendered[key] {
+			continue
+		}
+		res := unstructured.Unstructured{}
+		res.SetAPIVersion(p.APIVersion)
+		res.SetKind(p.Kind)
+		res.SetNamespace(namespace)
+		res.SetName(p.Name)
+		resources = append(resources, res)
+		since[key] = p.Since.Time
+	}
+	return resources, since
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationconfiguration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestPendingDeletions(t *testing.T) {
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	pending := []common.PendingDeletion{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Since: metav1.NewTime(since)},
+		{APIVersion: "v1", Kind: "Service", Name: "web", Since: metav1.NewTime(since)},
+	}
+	rendered := &unstructured.Unstructured{}
+	rendered.SetAPIVersion("apps/v1")
+	rendered.SetKind("Deployment")
+	rendered.SetName("web")
+
+	cases := map[string]struct {
+		w         []Workload
+		wantNames []string
+	}{
+		"NothingRendered": {
+			wantNames: []string{"Deployment/web", "Service/web"},
+		},
+		"RenderedAgain": {
+			w:         []Workload{{Workload: rendered}},
+			wantNames: []string{"Service/web"},
+		},
+		"RenderedAsTrait": {
+			w:         []Workload{{Traits: []*Trait{{Object: *rendered}}}},
+			wantNames: []string{"Service/web"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resources, gotSince := pendingDeletions("default", pending, tc.w)
+			var names []string
+			for _, res := range resources {
+				if res.GetNamespace() != "default" {
+					t.Errorf("pendingDeletions(...): unexpected namespace %q", res.GetNamespace())
+				}
+				names = append(names, res.GetKind()+"/"+res.GetName())
+				if !gotSince[gcKey(res.GetAPIVersion(), res.GetKind(), res.GetName())].Equal(since) {
+					t.Errorf("pendingDeletions(...): unexpected since of %s", res.GetName())
+				}
+			}
+			if diff := cmp.Diff(tc.wantNames, names); diff != "" {
+				t.Errorf("\n%s\npendingDeletions(...): -want, +got:\n%s", name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationconfiguration
+
+import (
+	"context"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/migration"
+)
+
+// migrate replaces the AppConfig by an Application migrated from it, the AppConfig and its Components are deleted
+// with their workloads and traits orphaned, so the Application adopts them without recreating them
+func (r *OAMApplicationReconciler) migrate(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, log logging.Logger) (reconcile.Result, error) {
+	result, err := migration.Migrate(ctx, r.client, r.dm, ac, migration.Options{DeleteLegacy: true})
+	if err != nil {
+		log.Debug("Failed to migrate the application configuration", "error", err)
+		r.record.Event(ac, event.Warning(reasonCannotMigrate, err))
+		ac.SetConditions(v1alpha1.ReconcileError(errors.Wrap(err, errMigrateAppConfig)))
+		return reconcile.Result{}, errors.Wrap(r.UpdateStatus(ctx, ac), errUpdateAppConfigStatus)
+	}
+	msg := "Migrated to application " + result.Application.Name
+	if len(result.Warnings) != 0 {
+		msg += ", " + strings.Join(result.Warnings, "; ")
+	}
+	log.Info(msg)
+	r.record.Event(ac, event.Normal(reasonMigrated, msg))
+	return reconcile.Result{}, nil
+}
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
@@ -43,6 +44,7 @@ import (
 	oamtype "github.com/oam-dev/kubevela/apis/types"
 	helmapi "github.com/oam-dev/kubevela/pkg/appfile/helm/flux2apis"
 	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
@@ -62,6 +64,7 @@ const (
 	errFmtResolveParams    = "cannot resolve parameter values for component %q"
 	errFmtRenderWorkload   = "cannot render workload for component %q"
 	errFmtRenderTrait      = "cannot render trait for component %q"
+	errFmtGetTrait         = "cannot get trait %q"
 	errFmtSetParam         = "cannot set parameter %q"
 	errFmtUnsupportedParam = "unsupported parameter %q"
 	errFmtRequiredParam    = "required parameter %q not specified"
@@ -97,6 +100,9 @@ type components struct {
 	params   ParameterResolver
 	workload ResourceRenderer
 	trait    ResourceRenderer
+
+	// helmDiscoveryTimeout is the duration to wait for Helm to create the workloads of Helm-based components
+	helmDiscoveryTimeout time.Duration
 }
 
 func (r *components) Render(ctx context.Context, ac *v1alpha2.ApplicationConfiguration) ([]Workload, *v1alpha2.DependencyStatus, error) {
@@ -148,6 +154,9 @@ func (r *components) Render(ctx context.Context, ac *v1alpha2.ApplicationConfigu
 			return nil, nil, err
 		}
 		ds.Unsatisfied = append(ds.Unsatisfied, unsatisfied...)
+		if p := workloads[i].HelmPending; p != nil {
+			ds.Unsatisfied = append(ds.Unsatisfied, makeHelmPendingDependency(workloads[i].Workload, p))
+		}
 		res = append(res, *workloads[i])
 	}
 
@@ -171,6 +180,7 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 	if err != nil {
 		return nil, errors.Wrapf(err, errFmtResolveParams, acc.ComponentName)
 	}
+	var helmPending *assemble.HelmWorkloadPendingError
 
 	w, err := r.workload.Render(c.Spec.Workload.Raw, p...)
 	if err != nil {
@@ -203,6 +213,7 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 	if len(w.GetNamespace()) == 0 {
 		w.SetNamespace(ac.GetNamespace())
 	}
+	referred := util.IsReferredWorkload(w)
 	traits := make([]*Trait, 0, len(acc.Traits))
 	traitDefs := make([]v1alpha2.TraitDefinition, 0, len(acc.Traits))
 	compInfoLabels[oam.LabelOAMResourceType] = oam.ResourceTypeTrait
@@ -232,19 +243,42 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 		}
 	} else {
 		// we have completely different approaches on workload name for application generated appConfig
-		if c.Spec.Helm != nil {
+		if referred {
+			// the existing workload is not managed by the application, it's only located to inject its reference
+			// into the traits, the rendered reference is used as a fallback if it's missing
+			w.SetName(util.ReferredWorkloadName(w))
+			live, err := util.GetReferredWorkload(ctx, r.client, w)
+			switch {
+			case err == nil:
+				w = live
+			case util.IsReferredWorkloadMissing(err):
+				klog.InfoS("Referred workload is missing, fall back to the rendered reference", "component name", acc.ComponentName,
+					"workload", klog.KObj(w), "kind", w.GetKind())
+			default:
+				return nil, err
+			}
+		} else if c.Spec.Helm != nil {
 			// for helm workload, make sure the workload is already generated by Helm successfully
 			existingWorkloadByHelm, err := discoverHelmModuleWorkload(ctx, r.client, c, ac.GetNamespace())
-			if err != nil {
+			switch {
+			case kerrors.IsNotFound(err):
+				// the workload is not created by Helm yet, the component is pending instead of blocking the others
+				if helmPending, err = r.pendHelmModuleWorkload(ctx, c, w.GetKind(), ac.GetNamespace()); err != nil {
+					return nil, err
+				}
+				klog.InfoS("Component is pending", "component name", acc.ComponentName, "reason", helmPending.Error())
+				w.SetName(helmPending.WorkloadName)
+			case err != nil:
 				klog.ErrorS(err, "Could not get the workload created by Helm module",
 					"component name", acc.ComponentName, "component revision", acc.RevisionName)
 				return nil, errors.Wrap(err, "cannot get the workload created by a Helm module")
+			default:
+				klog.InfoS("Successfully discovered the workload created by Helm",
+					"component name", acc.ComponentName, "component revision", acc.RevisionName,
+					"workload name", existingWorkloadByHelm.GetName())
+				// use the name already generated instead of setting a new one
+				w.SetName(existingWorkloadByHelm.GetName())
 			}
-			klog.InfoS("Successfully discovered the workload created by Helm",
-				"component name", acc.ComponentName, "component revision", acc.RevisionName,
-				"workload name", existingWorkloadByHelm.GetName())
-			// use the name already generated instead of setting a new one
-			w.SetName(existingWorkloadByHelm.GetName())
 		} else {
 			// for non-helm workload, we generate a workload name based on component name and revision
 			revision, err := utils.ExtractRevision(acc.RevisionName)
@@ -253,6 +287,21 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 			}
 			// Pass inpalce upgrade into it
 			SetAppWorkloadInstanceName(acc.ComponentName, w, revision, inplaceUpgrade)
+			if util.IsKnativeService(w.GroupVersionKind()) {
+				// map the component revision to the knative revision
+				if err := util.SetKnativeRevisionName(w, acc.RevisionName); err != nil {
+					return nil, err
+				}
+				if isComponentRolling && needRolloutTemplate {
+					existing, err := util.GetObjectGivenGVKAndName(ctx, r.client, w.GroupVersionKind(), w.GetNamespace(), w.GetName())
+					if err != nil && !kerrors.IsNotFound(errors.Cause(err)) {
+						return nil, errors.Wrapf(err, "cannot get knative service %q", w.GetName())
+					}
+					if err := pinKnativeTrafficForRollout(w, existing); err != nil {
+						return nil, err
+					}
+				}
+			}
 			if isComponentRolling && needRolloutTemplate {
 				// we have a special logic to emit the workload as a template so that the rollout
 				// controller can take over.
@@ -303,9 +352,50 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 	addDataOutputsToDAG(dag, acc.DataOutputs, w)
 	// To avoid conflict with rollout controller, we will not render the workload until the rollout phase is over
 	// indicated by the AnnotationAppRollout annotation disappear
+	if helmPending != nil {
+		// neither the workload nor the traits are applied until Helm creates the workload
+		for _, t := range traits {
+			t.HasDep = true
+		}
+	}
 	return &Workload{ComponentName: acc.ComponentName, ComponentRevisionName: componentRevisionName,
-		SkipApply: isComponentRolling && !needRolloutTemplate,
-		Workload:  w, Traits: traits, RevisionEnabled: isRevisionEnabled(traitDefs), Scopes: scopes}, nil
+		SkipApply: isComponentRolling && !needRolloutTemplate, Referred: referred, HasDep: helmPending != nil,
+		HelmPending: helmPending, Workload: w, Traits: traits, RevisionEnabled: isRevisionEnabled(traitDefs), Scopes: scopes}, nil
+}
+
+// pendHelmModuleWorkload returns the pending status of the Helm-based component whose workload is not created by
+// Helm yet, or an error if Helm doesn't create it within the timeout
+func (r *components) pendHelmModuleWorkload(ctx context.Context, comp *v1alpha2.Component, kind, ns string) (*assemble.HelmWorkloadPendingError, error) {
+	rlsName, wlName, err := assemble.HelmWorkloadName(comp)
+	if err != nil {
+		return nil, err
+	}
+	pending := &assemble.HelmWorkloadPendingError{Release: rlsName, Namespace: ns, WorkloadKind: kind, WorkloadName: wlName}
+	if err := assemble.CheckHelmWorkloadDiscoveryTimeout(ctx, r.client, pending, r.helmDiscoveryTimeout); !assemble.IsHelmWorkloadPending(err) {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// makeHelmPendingDependency tells the workload of a Helm-based component is waiting for its HelmRelease
+func makeHelmPendingDependency(w *unstructured.Unstructured, p *assemble.HelmWorkloadPendingError) v1alpha2.UnstaifiedDependency {
+	return v1alpha2.UnstaifiedDependency{
+		Reason: p.Error(),
+		From: v1alpha2.DependencyFromObject{
+			TypedReference: runtimev1alpha1.TypedReference{
+				APIVersion: helmapi.HelmReleaseGVK.GroupVersion().String(),
+				Kind:       helmapi.HelmReleaseGVK.Kind,
+				Name:       p.Release,
+			},
+		},
+		To: v1alpha2.DependencyToObject{
+			TypedReference: runtimev1alpha1.TypedReference{
+				APIVersion: w.GetAPIVersion(),
+				Kind:       w.GetKind(),
+				Name:       w.GetName(),
+			},
+		},
+	}
 }
 
 func (r *components) renderTrait(ctx context.Context, ct v1alpha2.ComponentTrait, ac *v1alpha2.ApplicationConfiguration,
@@ -322,7 +412,12 @@ func (r *components) renderTrait(ctx context.Context, ct v1alpha2.ComponentTrait
 		traitDef = util.GetDummyTraitDefinition(t)
 	}
 
-	traitName := getTraitName(ac, componentName, &ct, t, traitDef)
+	traitName := getExistingTraitName(ac, componentName, t)
+	if len(traitName) == 0 {
+		if traitName, err = r.genTraitName(ctx, ac, componentName, &ct, t, traitDef, ref); err != nil {
+			return nil, nil, errors.Wrapf(err, errFmtRenderTrait, componentName)
+		}
+	}
 	setTraitProperties(t, traitName, ac.GetNamespace(), ref)
 
 	addDataOutputsToDAG(dag, ct.DataOutputs, t)
@@ -907,20 +1002,24 @@ func checkConditions(conds []v1alpha2.ConditionRequirement, paved *fieldpath.Pav
 // GetTraitName return trait name
 func getTraitName(ac *v1alpha2.ApplicationConfiguration, componentName string,
 	ct *v1alpha2.ComponentTrait, t *unstructured.Unstructured, traitDef *v1alpha2.TraitDefinition) string {
-	var traitName, apiVersion, kind string
+	if traitName := getExistingTraitName(ac, componentName, t); len(traitName) > 0 {
+		return traitName
+	}
+	return util.GenTraitNameWithCollisionCount(componentName, ct.DeepCopy(), getTraitType(traitDef),
+		util.GetTraitHashVersion(ac.GetLabels()), ac.Status.TraitHashCollisionCount)
+}
+
+// getExistingTraitName returns the name set in the trait template or recorded in the status of the
+// applicationConfiguration, an empty string is returned if the trait name has to be generated.
+func getExistingTraitName(ac *v1alpha2.ApplicationConfiguration, componentName string, t *unstructured.Unstructured) string {
+	var traitName string
 	// we forbid the trait name in the template if the applicationConfiguration is generated by application
 	if len(t.GetName()) > 0 && !isControlledByApp(ac) {
 		return t.GetName()
 	}
 
-	apiVersion = t.GetAPIVersion()
-	kind = t.GetKind()
-
-	traitType := traitDef.Name
-	if strings.Contains(traitType, ".") {
-		traitType = strings.Split(traitType, ".")[0]
-	}
-
+	apiVersion := t.GetAPIVersion()
+	kind := t.GetKind()
 	for _, w := range ac.Status.Workloads {
 		if w.ComponentName != componentName {
 			continue
@@ -931,12 +1030,56 @@ func getTraitName(ac *v1alpha2.ApplicationConfiguration, componentName string,
 			}
 		}
 	}
+	return traitName
+}
 
-	if len(traitName) == 0 {
-		traitName = util.GenTraitName(componentName, ct.DeepCopy(), traitType)
+func getTraitType(traitDef *v1alpha2.TraitDefinition) string {
+	traitType := traitDef.Name
+	if strings.Contains(traitType, ".") {
+		traitType = strings.Split(traitType, ".")[0]
 	}
+	return traitType
+}
 
-	return traitName
+// genTraitName generates the name of a newly created trait. Like the names of the ReplicaSets of a Deployment,
+// the collision count in the status of the applicationConfiguration is increased and the name is regenerated
+// if the name is already taken by a resource controlled by others, the status is persisted by the caller.
+func (r *components) genTraitName(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, componentName string,
+	ct *v1alpha2.ComponentTrait, t *unstructured.Unstructured, traitDef *v1alpha2.TraitDefinition, ref *metav1.OwnerReference) (string, error) {
+	owner := metav1.GetControllerOf(t)
+	if owner != nil && owner.Kind == v1beta1.ResourceTrackerKind {
+		// the cross-namespace traits are named by the application controller with the same algorithm
+		return getTraitName(ac, componentName, ct, t, traitDef), nil
+	}
+	if owner == nil {
+		owner = ref
+	}
+	namespace := t.GetNamespace()
+	if len(namespace) == 0 {
+		namespace = ac.GetNamespace()
+	}
+	for {
+		traitName := getTraitName(ac, componentName, ct, t, traitDef)
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(t.GroupVersionKind())
+		err := r.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: traitName}, existing)
+		if kerrors.IsNotFound(err) {
+			return traitName, nil
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, errFmtGetTrait, traitName)
+		}
+		controller := metav1.GetControllerOf(existing)
+		if controller == nil || controller.UID == owner.UID {
+			return traitName, nil
+		}
+		var collisionCount int32
+		if ac.Status.TraitHashCollisionCount != nil {
+			collisionCount = *ac.Status.TraitHashCollisionCount
+		}
+		collisionCount++
+		ac.Status.TraitHashCollisionCount = &collisionCount
+	}
 }
 
 // getExistingWorkload tries to retrieve the currently running workload
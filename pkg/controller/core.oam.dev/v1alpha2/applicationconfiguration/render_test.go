@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
@@ -31,9 +32,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -869,8 +872,8 @@ func TestRender(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			r := &components{tc.fields.client, mock.NewMockDiscoveryMapper(), tc.fields.params,
-				tc.fields.workload, tc.fields.trait}
+			r := &components{client: tc.fields.client, dm: mock.NewMockDiscoveryMapper(), params: tc.fields.params,
+				workload: tc.fields.workload, trait: tc.fields.trait}
 			needTemplating := tc.args.ac.Status.RollingStatus != oamtype.RollingTemplated
 			_, isRolling := tc.args.ac.GetAnnotations()[oam.AnnotationAppRollout]
 			got, _, err := r.Render(context.Background(), tc.args.ac)
@@ -1187,8 +1190,8 @@ func TestRenderComponent(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			r := &components{tc.fields.client, mock.NewMockDiscoveryMapper(), mockParams,
-				tc.fields.workload, tc.fields.trait}
+			r := &components{client: tc.fields.client, dm: mock.NewMockDiscoveryMapper(), params: mockParams,
+				workload: tc.fields.workload, trait: tc.fields.trait}
 			got, err := r.renderComponent(ctx, tc.args.ac.Spec.Components[0], tc.args.ac, tc.args.isControlledByApp,
 				tc.args.isCompChanged, tc.args.isRollingTemplate, tc.args.dag)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
@@ -1563,8 +1566,8 @@ func TestRenderTraitWithoutMetadataName(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			r := &components{tc.fields.client, mock.NewMockDiscoveryMapper(), tc.fields.params,
-				tc.fields.workload, tc.fields.trait}
+			r := &components{client: tc.fields.client, dm: mock.NewMockDiscoveryMapper(), params: tc.fields.params,
+				workload: tc.fields.workload, trait: tc.fields.trait}
 			got, _, _ := r.Render(context.Background(), tc.args.ac)
 			if len(got) == 0 || len(got[0].Traits) == 0 || got[0].Traits[0].Object.GetName() != util.GenTraitName(componentName, ac.Spec.Components[0].Traits[0].DeepCopy(), "") {
 				t.Errorf("\n%s\nr.Render(...): -want error, +got error:\n%s\n", tc.reason, "Trait name is NOT "+
@@ -2011,6 +2014,62 @@ func TestRenderTraitNameWithShortNameTraitDefinition(t *testing.T) {
 	assert.Contains(t, traitName, "component5-scale")
 }
 
+func TestGenTraitNameOnHashCollision(t *testing.T) {
+	componentName := "component6"
+	ac := &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "coolappconfig6",
+			UID:       types.UID("definitely-a-uuid"),
+			Labels:    map[string]string{oam.LabelTraitHashVersion: string(util.TraitHashV2)},
+		},
+	}
+	ref := metav1.NewControllerRef(ac, v1alpha2.ApplicationConfigurationGroupVersionKind)
+	ct := &v1alpha2.ComponentTrait{Trait: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"core.oam.dev/v1alpha2","kind":"ManualScalerTrait","spec":{"replicaCount":3}}`),
+	}}
+	traitDef := &v1alpha2.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "scaler"}}
+	trait := &unstructured.Unstructured{}
+	trait.SetAPIVersion("core.oam.dev/v1alpha2")
+	trait.SetKind("ManualScalerTrait")
+
+	taken := util.GenTraitNameWithHashVersion(componentName, ct, "scaler", util.TraitHashV2)
+	getFn := func(owner types.UID) test.MockGetFn {
+		return func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+			if key.Name != taken {
+				return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+			}
+			u := obj.(*unstructured.Unstructured)
+			u.SetName(key.Name)
+			u.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Other", Name: "other", UID: owner, Controller: pointer.BoolPtr(true)}})
+			return nil
+		}
+	}
+
+	// the existing trait is controlled by the applicationConfiguration itself
+	r := &components{client: &test.MockClient{MockGet: getFn(ac.GetUID())}}
+	name, err := r.genTraitName(context.Background(), ac, componentName, ct, trait, traitDef, ref)
+	assert.NoError(t, err)
+	assert.Equal(t, taken, name)
+	assert.Nil(t, ac.Status.TraitHashCollisionCount)
+
+	// the name is taken by a resource controlled by others
+	r = &components{client: &test.MockClient{MockGet: getFn(types.UID("another-uuid"))}}
+	name, err = r.genTraitName(context.Background(), ac, componentName, ct, trait, traitDef, ref)
+	assert.NoError(t, err)
+	collisionCount := int32(1)
+	assert.Equal(t, &collisionCount, ac.Status.TraitHashCollisionCount)
+	assert.Equal(t, util.GenTraitNameWithCollisionCount(componentName, ct, "scaler", util.TraitHashV2, &collisionCount), name)
+	assert.NotEqual(t, taken, name)
+
+	// the collision count is kept for the following traits
+	assert.Equal(t, name, getTraitName(ac, componentName, ct, trait, traitDef))
+
+	r = &components{client: &test.MockClient{MockGet: test.NewMockGetFn(errors.New("boom"))}}
+	_, err = r.genTraitName(context.Background(), ac, componentName, ct, trait, traitDef, ref)
+	assert.Error(t, err)
+}
+
 func TestMatchValue(t *testing.T) {
 	obj := &unstructured.Unstructured{}
 	obj.SetAPIVersion("v1")
@@ -2370,3 +2429,49 @@ func TestDiscoverHelmModuleWorkload(t *testing.T) {
 		})
 	}
 }
+
+func TestPendHelmModuleWorkload(t *testing.T) {
+	release := &unstructured.Unstructured{}
+	release.SetGroupVersionKind(helmapi.HelmReleaseGVK)
+	release.SetName("test-rls")
+	unstructured.SetNestedField(release.Object, "test-chart", "spec", "chart", "spec", "chart")
+	releaseRaw, _ := release.MarshalJSON()
+	comp := &v1alpha2.Component{}
+	comp.Spec.Helm = &common.Helm{Release: runtime.RawExtension{Raw: releaseRaw}}
+
+	createdAt := func(ago time.Duration) test.ObjectFn {
+		return func(obj runtime.Object) error {
+			obj.(*unstructured.Unstructured).SetCreationTimestamp(metav1.NewTime(time.Now().Add(-ago)))
+			return nil
+		}
+	}
+	tests := map[string]struct {
+		c           client.Reader
+		wantPending bool
+		wantErr     bool
+	}{
+		"ReleaseNotCreated": {
+			c:           &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "test-rls"))},
+			wantPending: true,
+		},
+		"WithinTimeout": {
+			c:           &test.MockClient{MockGet: test.NewMockGetFn(nil, createdAt(time.Minute))},
+			wantPending: true,
+		},
+		"TimedOut": {
+			c:       &test.MockClient{MockGet: test.NewMockGetFn(nil, createdAt(time.Hour))},
+			wantErr: true,
+		},
+	}
+	for caseName, tc := range tests {
+		t.Run(caseName, func(t *testing.T) {
+			r := &components{client: tc.c, helmDiscoveryTimeout: 10 * time.Minute}
+			pending, err := r.pendHelmModuleWorkload(context.Background(), comp, "Deployment", "test-ns")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantPending, pending != nil)
+			if pending != nil {
+				assert.Equal(t, "waiting for HelmRelease test-rls to create Deployment test-rls-test-chart", pending.Error())
+			}
+		})
+	}
+}
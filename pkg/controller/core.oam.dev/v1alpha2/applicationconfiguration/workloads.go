@@ -26,9 +26,11 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 const (
@@ -61,6 +63,13 @@ func SetAppWorkloadInstanceName(componentName string, w *unstructured.Unstructur
 			return
 		}
 	}
+	// Knative Service keeps the history of revisions by itself, one Knative Revision per component revision
+	if util.IsKnativeService(w.GroupVersionKind()) {
+		klog.InfoS("we reuse the component name for knative service which manages revisions by itself",
+			"GVK", w.GroupVersionKind(), "instance name", componentName)
+		w.SetName(componentName)
+		return
+	}
 	// we assume that the rest of the resources do not support in-place upgrade
 	instanceName := utils.ConstructRevisionName(componentName, int64(revision))
 	klog.InfoS("we encountered an unknown resources, assume that it does not support in-place upgrade",
@@ -98,6 +107,12 @@ func prepWorkloadInstanceForRollout(workload *unstructured.Unstructured) error {
 				"kind", workload.GetKind(), "instance name", workload.GetName())
 			return nil
 		}
+	} else if util.IsKnativeService(workload.GroupVersionKind()) {
+		// a knative service can't be paused, the new revision is created but receives no traffic
+		// as the traffic has been pinned to the serving revision, see pinKnativeTrafficForRollout
+		klog.InfoS("we render a knative service with its traffic managed by rollout",
+			"kind", workload.GetKind(), "instance name", workload.GetName())
+		return nil
 	} else if workload.GroupVersionKind().Group == appsv1.GroupName &&
 		workload.GetKind() == reflect.TypeOf(appsv1.Deployment{}).Name() {
 		err := pv.SetBool(deploymentDisablePath, true)
@@ -113,3 +128,18 @@ func prepWorkloadInstanceForRollout(workload *unstructured.Unstructured) error {
 	return fmt.Errorf("we do not know how to prepare `%s` as it has an unknown type %s", workload.GetName(),
 		workload.GroupVersionKind().String())
 }
+
+// pinKnativeTrafficForRollout keeps all the traffic on the revision serving now so that the new revision
+// won't take over the traffic immediately, the rollout controller shifts the traffic batch by batch
+func pinKnativeTrafficForRollout(workload, existing *unstructured.Unstructured) error {
+	if existing == nil {
+		return nil
+	}
+	serving, _, _ := unstructured.NestedString(existing.Object, "status", "latestReadyRevisionName")
+	if len(serving) == 0 {
+		return nil
+	}
+	return util.SetKnativeTraffic(workload, []util.KnativeTrafficTarget{{
+		RevisionName: serving, LatestRevision: pointer.BoolPtr(false), Percent: 100,
+	}})
+}
@@ -26,8 +26,10 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktype "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -36,8 +38,13 @@ import (
 	"github.com/oam-dev/kubevela/apis/types"
 	core "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	ac "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/impersonate"
+	"github.com/oam-dev/kubevela/pkg/utils/recorder"
+	"github.com/oam-dev/kubevela/pkg/utils/sharding"
+	"github.com/oam-dev/kubevela/pkg/utils/signature"
 )
 
 // Reconcile error strings.
@@ -45,6 +52,7 @@ const (
 	errGetAppContex           = "cannot get application context"
 	errGetAppRevision         = "cannot get the application revision the context refers to"
 	errUpdateAppContextStatus = "cannot update application context status"
+	errVerifyAppRevision      = "cannot verify the manifests of the application revision"
 )
 
 const reconcileTimeout = 1 * time.Minute
@@ -57,6 +65,14 @@ type Reconciler struct {
 	record    event.Recorder
 	mgr       ctrl.Manager
 	applyMode core.ApplyOnceOnlyMode
+	keyStore  *signature.KeyStore
+	// impersonation builds the clients impersonating the identities declared by the applications
+	impersonation *impersonate.ClientFactory
+	// sharding filters the contexts of the applications not scheduled to the shard of the replica, the contexts
+	// inherit the labels of the applications
+	sharding sharding.Options
+	// eventDedupInterval is the interval the same events on an object are recorded once within
+	eventDedupInterval time.Duration
 }
 
 // Reconcile reconcile an application context
@@ -73,6 +89,10 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 		}
 		return reconcile.Result{}, errors.Wrap(err, errGetAppContex)
 	}
+	// the contexts using a component are enqueued regardless of the shards they're scheduled to
+	if !r.sharding.Owns(appContext) {
+		return reconcile.Result{}, nil
+	}
 
 	ctx = util.SetNamespaceInCtx(ctx, appContext.Namespace)
 	dm, err := discoverymapper.New(r.mgr.GetConfig())
@@ -89,6 +109,17 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 		}
 		return reconcile.Result{}, errors.Wrap(err, errGetAppRevision)
 	}
+	if err := manifeststore.LoadV1alpha2(ctx, r.client, appRevision); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, errGetAppRevision)
+	}
+
+	// make sure the manifests are not tampered between render and apply
+	if r.keyStore != nil {
+		if err := r.verifyAppRevision(ctx, appContext, appRevision); err != nil {
+			r.record.Event(appContext, event.Warning(types.ReasonFailedVerify, err))
+			return reconcile.Result{}, errors.Wrap(err, errVerifyAppRevision)
+		}
+	}
 
 	// copy the status from appContext to appConfig
 	appConfig, err := util.RawExtension2AppConfig(appRevision.Spec.ApplicationConfiguration)
@@ -104,7 +135,8 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 	// makes sure that the appConfig's owner is the same as the appContext
 	appConfig.SetOwnerReferences(appContext.GetOwnerReferences())
 	// call into the old ac Reconciler and copy the status back
-	acReconciler := ac.NewReconciler(r.mgr, dm, r.log, ac.WithRecorder(r.record), ac.WithApplyOnceOnlyMode(r.applyMode))
+	acReconciler := ac.NewReconciler(r.mgr, dm, r.log, ac.WithRecorder(r.record), ac.WithApplyOnceOnlyMode(r.applyMode),
+		ac.WithImpersonation(r.impersonation))
 	reconResult := acReconciler.ACReconcile(ctx, appConfig, r.log)
 	appContextPatch := client.MergeFrom(appContext.DeepCopy())
 	appContext.Status = appConfig.Status
@@ -121,12 +153,40 @@ func (r *Reconciler) Reconcile(request reconcile.Request) (reconcile.Result, err
 	return reconResult, err
 }
 
+// verifyAppRevision verifies the signature of the manifests rendered in the application revision and records
+// the digest and signature as the audit record in the annotations of the revision, and in an event
+func (r *Reconciler) verifyAppRevision(ctx context.Context, appContext *v1alpha2.ApplicationContext,
+	appRevision *v1alpha2.ApplicationRevision) error {
+	key, err := r.keyStore.KeyFor(ctx, appRevision.Namespace)
+	if err != nil {
+		return err
+	}
+	record, err := key.Verify(appRevision, appRevision.Spec.Components, appRevision.Spec.ApplicationConfiguration)
+	if err != nil {
+		return err
+	}
+	record.VerifiedAt = metav1.Now()
+	// patch the metadata only, the manifests may be loaded from the manifest store into the revision in memory
+	stored := &v1alpha2.ApplicationRevision{ObjectMeta: *appRevision.ObjectMeta.DeepCopy()}
+	patch := client.MergeFrom(stored.DeepCopy())
+	recorded, err := signature.RecordVerification(stored, record)
+	if err != nil || !recorded {
+		return err
+	}
+	if err := r.client.Patch(ctx, stored, patch); err != nil {
+		return errors.Wrapf(err, "fail to record the verification of revision %s", appRevision.Name)
+	}
+	r.record.Event(appContext, event.Normal(types.ReasonVerified,
+		fmt.Sprintf(types.MessageVerified, appRevision.Name, record)))
+	return nil
+}
+
 // SetupWithManager setup the controller with manager
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, compHandler *ac.ComponentHandler) error {
-	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("AppRollout")).
-		WithAnnotations("controller", "AppRollout")
+	r.record = recorder.NewDedupRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor("AppRollout")),
+		r.eventDedupInterval).WithAnnotations("controller", "AppRollout")
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha2.ApplicationContext{}).
+		For(&v1alpha2.ApplicationContext{}, builder.WithPredicates(r.sharding.Predicate())).
 		Watches(&source.Kind{Type: &v1alpha2.Component{}}, compHandler).
 		Complete(r)
 }
@@ -135,12 +195,21 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, compHandler *ac.Componen
 func Setup(mgr ctrl.Manager, args core.Args, l logging.Logger) error {
 	name := "oam/" + strings.ToLower(v1alpha2.ApplicationContextGroupKind)
 	record := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+	keyStore, err := signature.NewKeyStore(mgr.GetClient(), args.ManifestSigningKeySecret)
+	if err != nil {
+		return err
+	}
 	reconciler := Reconciler{
 		client:    mgr.GetClient(),
 		mgr:       mgr,
 		log:       l.WithValues("controller", name),
 		record:    record,
 		applyMode: args.ApplyMode,
+		keyStore:  keyStore,
+		sharding:  args.Sharding,
+
+		eventDedupInterval: args.EventDedupInterval,
+		impersonation:      impersonate.NewClientFactory(mgr.GetConfig(), mgr.GetScheme(), mgr.GetRESTMapper()).WithRequired(args.RequireImpersonation),
 	}
 	compHandler := &ac.ComponentHandler{
 		Client:                mgr.GetClient(),
@@ -36,6 +36,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/controller/common"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 	appUtil "github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/applicationrollout"
@@ -54,6 +55,9 @@ func (r *Reconciler) getTargetApps(ctx context.Context, componentList []string,
 			klog.KRef(namespaceName, targetAppRevisionName))
 		return nil, nil, err
 	}
+	if err := manifeststore.LoadV1alpha2(ctx, r.Client, &appRevision); err != nil {
+		return nil, nil, err
+	}
 	if err := r.Get(ctx, ktypes.NamespacedName{Namespace: namespaceName, Name: targetAppRevisionName},
 		&appContext); err != nil {
 		if apierrors.IsNotFound(err) && rollingState == v1alpha1.LocatingTargetAppState {
@@ -103,6 +107,9 @@ func (r *Reconciler) getSourceAppContexts(ctx context.Context, componentList []s
 			klog.KRef(namespaceName, sourceAppRevisionName))
 		return nil, nil, err
 	}
+	if err := manifeststore.LoadV1alpha2(ctx, r.Client, &appRevision); err != nil {
+		return nil, nil, err
+	}
 	// the source app has to exist or there is nothing for us to upgrade from
 	if err := r.Get(ctx, ktypes.NamespacedName{Namespace: namespaceName, Name: sourceAppRevisionName},
 		&appContext); err != nil {
@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/clustermanager"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+const (
+	reconcileTimeout = 30 * time.Second
+	probeTimeout     = 10 * time.Second
+	// probeInterval is the interval to probe the clusters
+	probeInterval = 30 * time.Second
+	// failureThreshold is the number of the consecutive unhealthy probes to cordon a cluster automatically
+	failureThreshold = 3
+)
+
+// ProbeResult is what's observed from a managed cluster
+type ProbeResult struct {
+	Version string
+	Nodes   []corev1.Node
+}
+
+// Prober probes the managed cluster with the kubeconfig
+type Prober func(ctx context.Context, kubeConfig []byte) (*ProbeResult, error)
+
+// Reconciler probes the registered managed clusters, maintains their health in the status and cordons the
+// clusters unhealthy for several consecutive probes, so new placements skip them
+type Reconciler struct {
+	Client   client.Client
+	Recorder event.Recorder
+	probe    Prober
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=clusters/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=appdeployments/status,verbs=get;update;patch
+
+// Reconcile probes the cluster and updates its status
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	cluster := &v1beta1.Cluster{}
+	if err := r.Client.Get(ctx, req.NamespacedName, cluster); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	wasCordoned := cluster.Status.Cordoned
+
+	result, err := r.probeCluster(ctx, cluster)
+	updateHealth(&cluster.Status, result, err)
+	updateCordon(cluster)
+	now := metav1.Now()
+	cluster.Status.LastProbeTime = &now
+	if cluster.Status.Health == v1beta1.ClusterUnhealthy {
+		klog.InfoS("Cluster is unhealthy", "cluster", klog.KObj(cluster), "failures", cluster.Status.ConsecutiveFailures, "message", cluster.Status.Message)
+		r.Recorder.Event(cluster, event.Warning(velatypes.ReasonClusterUnhealthy, errors.New(cluster.Status.Message)))
+	}
+
+	if err := r.Client.Status().Update(ctx, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster.Status.Cordoned != wasCordoned {
+		if cluster.Status.Cordoned {
+			klog.InfoS("Cordon cluster", "cluster", klog.KObj(cluster), "reason", cluster.Status.CordonReason)
+			r.Recorder.Event(cluster, event.Warning(velatypes.ReasonClusterCordoned,
+				errors.Errorf(velatypes.MessageClusterCordoned, cluster.Status.CordonReason)))
+		} else {
+			klog.InfoS("Uncordon cluster", "cluster", klog.KObj(cluster))
+			r.Recorder.Event(cluster, event.Normal(velatypes.ReasonClusterUncordoned, velatypes.MessageClusterUncordoned))
+		}
+		if err := r.flagPlacements(ctx, cluster); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: probeInterval}, nil
+}
+
+func (r *Reconciler) probeCluster(ctx context.Context, cluster *v1beta1.Cluster) (*ProbeResult, error) {
+	kubeConfig, err := clustermanager.GetKubeConfig(ctx, r.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	return r.probe(ctx, kubeConfig)
+}
+
+// flagPlacements flags the cluster in the placement status of the AppDeployments once it's cordoned or uncordoned
+func (r *Reconciler) flagPlacements(ctx context.Context, cluster *v1beta1.Cluster) error {
+	appds := &v1beta1.AppDeploymentList{}
+	if err := r.Client.List(ctx, appds, client.InNamespace(cluster.Namespace)); err != nil {
+		return err
+	}
+	for i := range appds.Items {
+		appd := &appds.Items[i]
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if err := r.Client.Get(ctx, client.ObjectKey{Namespace: appd.Namespace, Name: appd.Name}, appd); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			changed := false
+			for j := range appd.Status.Placement {
+				for k := range appd.Status.Placement[j].Clusters {
+					c := &appd.Status.Placement[j].Clusters[k]
+					if c.ClusterName == cluster.Name && c.Cordoned != cluster.Status.Cordoned {
+						c.Cordoned = cluster.Status.Cordoned
+						changed = true
+					}
+				}
+			}
+			if !changed {
+				return nil
+			}
+			return r.Client.Status().Update(ctx, appd)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateHealth updates the health in the status with the probe result, the cluster is unhealthy if its API server
+// is unreachable or none of its nodes is ready
+func updateHealth(status *v1beta1.ClusterStatus, result *ProbeResult, err error) {
+	if err != nil {
+		status.Health = v1beta1.ClusterUnhealthy
+		status.Message = err.Error()
+		status.ConsecutiveFailures++
+		return
+	}
+	status.Version = result.Version
+	status.Nodes = len(result.Nodes)
+	status.NotReadyNodes = 0
+	status.PressureNodes = nil
+	for _, node := range result.Nodes {
+		ready := false
+		pressure := false
+		for _, cond := range node.Status.Conditions {
+			switch cond.Type {
+			case corev1.NodeReady:
+				ready = cond.Status == corev1.ConditionTrue
+			case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+				pressure = pressure || cond.Status == corev1.ConditionTrue
+			}
+		}
+		if !ready {
+			status.NotReadyNodes++
+		}
+		if pressure {
+			status.PressureNodes = append(status.PressureNodes, node.Name)
+		}
+	}
+	sort.Strings(status.PressureNodes)
+
+	var messages []string
+	if status.NotReadyNodes != 0 {
+		messages = append(messages, fmt.Sprintf("%d of %d nodes not ready", status.NotReadyNodes, status.Nodes))
+	}
+	if len(status.PressureNodes) != 0 {
+		messages = append(messages, fmt.Sprintf("nodes under resource pressure: %s", strings.Join(status.PressureNodes, ", ")))
+	}
+	status.Message = strings.Join(messages, "; ")
+	if status.Nodes != 0 && status.NotReadyNodes == status.Nodes {
+		status.Health = v1beta1.ClusterUnhealthy
+		status.ConsecutiveFailures++
+		return
+	}
+	status.Health = v1beta1.ClusterHealthy
+	status.ConsecutiveFailures = 0
+}
+
+// updateCordon cordons the cluster if it's cordoned manually or it's unhealthy for failureThreshold consecutive
+// probes, the cluster cordoned automatically is uncordoned once it's healthy again
+func updateCordon(cluster *v1beta1.Cluster) {
+	status := &cluster.Status
+	switch {
+	case cluster.Spec.Cordoned:
+		status.Cordoned, status.CordonReason = true, "cordoned manually"
+	case status.ConsecutiveFailures >= failureThreshold:
+		status.Cordoned = true
+		status.CordonReason = fmt.Sprintf("unhealthy for %d consecutive probes: %s", status.ConsecutiveFailures, status.Message)
+	case status.Health == v1beta1.ClusterHealthy:
+		status.Cordoned, status.CordonReason = false, ""
+	}
+}
+
+// ProbeKubeConfig probes the API server version and the nodes of the cluster with the kubeconfig
+func ProbeKubeConfig(ctx context.Context, kubeConfig []byte) (*ProbeResult, error) {
+	restConfig, err := clustermanager.GetRestConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = probeTimeout
+	dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	version, err := dc.ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "API server unreachable")
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: common.Scheme})
+	if err != nil {
+		return nil, err
+	}
+	nodes := &corev1.NodeList{}
+	if err := c.List(ctx, nodes); err != nil {
+		return nil, errors.Wrap(err, "cannot list nodes")
+	}
+	return &ProbeResult{Version: version.GitVersion, Nodes: nodes.Items}, nil
+}
+
+// SetupWithManager will setup with event recorder
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = event.NewAPIRecorder(mgr.GetEventRecorderFor("Cluster"))
+	// the clusters are probed periodically, the status updates by the controller itself are not watched
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.Cluster{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
+
+// Setup adds a controller that probes the health of Clusters
+func Setup(mgr ctrl.Manager, _ controller.Args, _ logging.Logger) error {
+	r := &Reconciler{Client: mgr.GetClient(), probe: ProbeKubeConfig}
+	return r.SetupWithManager(mgr)
+}
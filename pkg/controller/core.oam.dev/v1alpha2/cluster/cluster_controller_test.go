@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/clustermanager"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func node(name string, ready bool, pressure corev1.NodeConditionType) corev1.Node {
+	n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: status})
+	if len(pressure) != 0 {
+		n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{Type: pressure, Status: corev1.ConditionTrue})
+	}
+	return n
+}
+
+var _ = Describe("Test cluster controller", func() {
+	const ns = "default"
+	ctx := context.Background()
+	var (
+		c        client.Client
+		r        *Reconciler
+		result   *ProbeResult
+		probeErr error
+	)
+	req := ctrl.Request{}
+	req.Namespace, req.Name = ns, "prod"
+
+	BeforeEach(func() {
+		c = fake.NewFakeClientWithScheme(common.Scheme,
+			&v1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "prod", Labels: map[string]string{"env": "prod"}},
+				Spec:       v1beta1.ClusterSpec{KubeconfigSecretRef: v1beta1.LocalSecretReference{Name: "prod-kubeconfig"}},
+			},
+			&v1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "staging", Labels: map[string]string{"env": "staging"}},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "prod-kubeconfig"},
+				Data:       map[string][]byte{clustermanager.KubeConfigKey: []byte("kubeconfig")},
+			},
+			&v1beta1.AppDeployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "app"},
+				Status: v1beta1.AppDeploymentStatus{Placement: []v1beta1.PlacementStatus{{
+					RevisionName: "app-v1",
+					Clusters:     []v1beta1.ClusterPlacementStatus{{ClusterName: "prod", Replicas: 1}, {ClusterName: "staging", Replicas: 1}},
+				}}},
+			})
+		result = &ProbeResult{Version: "v1.20.2", Nodes: []corev1.Node{node("n1", true, ""), node("n2", true, corev1.NodeDiskPressure)}}
+		probeErr = nil
+		r = &Reconciler{Client: c, Recorder: event.NewNopRecorder(), probe: func(_ context.Context, kubeConfig []byte) (*ProbeResult, error) {
+			Expect(string(kubeConfig)).Should(Equal("kubeconfig"))
+			return result, probeErr
+		}}
+	})
+
+	getCluster := func() *v1beta1.Cluster {
+		cluster := &v1beta1.Cluster{}
+		Expect(c.Get(ctx, req.NamespacedName, cluster)).Should(Succeed())
+		return cluster
+	}
+
+	It("maintain the health of the cluster in its status", func() {
+		res, err := r.Reconcile(req)
+		Expect(err).Should(BeNil())
+		Expect(res.RequeueAfter).Should(Equal(probeInterval))
+		status := getCluster().Status
+		Expect(status.Health).Should(Equal(v1beta1.ClusterHealthy))
+		Expect(status.Version).Should(Equal("v1.20.2"))
+		Expect(status.Nodes).Should(Equal(2))
+		Expect(status.PressureNodes).Should(Equal([]string{"n2"}))
+		Expect(status.Message).Should(ContainSubstring("n2"))
+		Expect(status.LastProbeTime).ShouldNot(BeNil())
+		Expect(status.Cordoned).Should(BeFalse())
+
+		By("none of the nodes is ready")
+		result.Nodes = []corev1.Node{node("n1", false, ""), node("n2", false, "")}
+		_, err = r.Reconcile(req)
+		Expect(err).Should(BeNil())
+		status = getCluster().Status
+		Expect(status.Health).Should(Equal(v1beta1.ClusterUnhealthy))
+		Expect(status.ConsecutiveFailures).Should(Equal(1))
+		Expect(status.Cordoned).Should(BeFalse())
+	})
+
+	It("cordon the cluster unhealthy for consecutive probes and flag the placements", func() {
+		probeErr = errors.New("connection refused")
+		for i := 0; i < failureThreshold; i++ {
+			_, err := r.Reconcile(req)
+			Expect(err).Should(BeNil())
+		}
+		cluster := getCluster()
+		Expect(cluster.Status.Cordoned).Should(BeTrue())
+		Expect(cluster.Status.CordonReason).Should(ContainSubstring("connection refused"))
+		appd := &v1beta1.AppDeployment{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "app"}, appd)).Should(Succeed())
+		Expect(appd.Status.Placement[0].Clusters[0].Cordoned).Should(BeTrue())
+		Expect(appd.Status.Placement[0].Clusters[1].Cordoned).Should(BeFalse())
+
+		By("new placements skip the cordoned cluster")
+		selected, err := clustermanager.SelectClusters(ctx, c, ns, &v1beta1.ClusterSelector{Name: "prod"})
+		Expect(err).Should(BeNil())
+		Expect(selected).Should(BeEmpty())
+		cordoned, err := clustermanager.CordonedClusters(ctx, c, ns)
+		Expect(err).Should(BeNil())
+		Expect(cordoned).Should(Equal(map[string]bool{"prod": true}))
+
+		By("uncordon the cluster healthy again")
+		probeErr = nil
+		_, err = r.Reconcile(req)
+		Expect(err).Should(BeNil())
+		Expect(getCluster().Status.Cordoned).Should(BeFalse())
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "app"}, appd)).Should(Succeed())
+		Expect(appd.Status.Placement[0].Clusters[0].Cordoned).Should(BeFalse())
+		selected, err = clustermanager.SelectClusters(ctx, c, ns, &v1beta1.ClusterSelector{Labels: map[string]string{"env": "prod"}})
+		Expect(err).Should(BeNil())
+		Expect(selected).Should(HaveLen(1))
+	})
+
+	It("keep the cluster cordoned manually", func() {
+		cluster := getCluster()
+		cluster.Spec.Cordoned = true
+		Expect(c.Update(ctx, cluster)).Should(Succeed())
+		_, err := r.Reconcile(req)
+		Expect(err).Should(BeNil())
+		cluster = getCluster()
+		Expect(cluster.Status.Health).Should(Equal(v1beta1.ClusterHealthy))
+		Expect(cluster.Status.Cordoned).Should(BeTrue())
+		Expect(cluster.Status.CordonReason).Should(Equal("cordoned manually"))
+	})
+})
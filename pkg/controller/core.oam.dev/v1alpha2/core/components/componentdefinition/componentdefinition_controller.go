@@ -101,6 +101,15 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		klog.InfoS("Successfully update DefinitionRevision", "name", defRev.Name)
 
+		// the spec may be reverted to an existing revision, the status still needs to observe the new generation
+		if !util.IsUpToDate(&componentDefinition) {
+			if err := r.UpdateStatus(ctx, &componentDefinition); err != nil {
+				klog.ErrorS(err, "cannot update ComponentDefinition Status")
+				return ctrl.Result{}, util.PatchCondition(ctx, r, &componentDefinition,
+					cpv1alpha1.ReconcileError(fmt.Errorf(util.ErrUpdateComponentDefinition, componentDefinition.Name, err)))
+			}
+		}
+
 		if err := coredef.CleanUpDefinitionRevision(ctx, r.Client, &componentDefinition, r.defRevLimit); err != nil {
 			klog.Error("[Garbage collection]")
 			r.record.Event(&componentDefinition, event.Warning("failed to garbage collect DefinitionRevision of type ComponentDefinition", err))
@@ -185,6 +194,7 @@ func (r *Reconciler) createOrUpdateComponentDefRevision(ctx context.Context, nam
 
 // UpdateStatus updates v1beta1.ComponentDefinition's Status with retry.RetryOnConflict
 func (r *Reconciler) UpdateStatus(ctx context.Context, def *v1beta1.ComponentDefinition, opts ...client.UpdateOption) error {
+	util.SetObservedGeneration(def)
 	status := def.DeepCopy().Status
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
 		if err = r.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: def.Name}, def); err != nil {
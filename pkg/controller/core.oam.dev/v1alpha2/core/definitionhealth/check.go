@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definitionhealth
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// check returns the reason why the definition can't be used by applications, or nil if it's healthy
+func (r *Reconciler) check(ctx context.Context, def util.ConditionedObject) error {
+	switch d := def.(type) {
+	case *v1beta1.ComponentDefinition:
+		return r.checkComponentDefinition(ctx, d)
+	case *v1beta1.TraitDefinition:
+		if err := r.checkReference(d.Spec.Reference); err != nil {
+			return err
+		}
+		capability := utils.NewCapabilityTraitDef(d)
+		if capability.DefCategoryType == util.KubeDef {
+			return schemaError(utils.GetKubeSchematicOpenAPISchema(capability.Kube.Parameters))
+		}
+		if d.Spec.Schematic == nil || d.Spec.Schematic.CUE == nil {
+			return nil
+		}
+		return schemaError(capability.GetOpenAPISchema(r.pd, d.Name))
+	case *v1beta1.WorkloadDefinition:
+		if err := r.checkReference(d.Spec.Reference); err != nil {
+			return err
+		}
+		return r.checkCUETemplate(d.Spec.Schematic)
+	case *v1beta1.PolicyDefinition:
+		if err := r.checkReference(d.Spec.Reference); err != nil {
+			return err
+		}
+		return r.checkCUETemplate(d.Spec.Schematic)
+	case *v1beta1.WorkflowStepDefinition:
+		if err := r.checkReference(d.Spec.Reference); err != nil {
+			return err
+		}
+		return r.checkCUETemplate(d.Spec.Schematic)
+	}
+	return nil
+}
+
+func (r *Reconciler) checkComponentDefinition(ctx context.Context, def *v1beta1.ComponentDefinition) error {
+	switch workload := def.Spec.Workload; {
+	case workload.Definition != (common.WorkloadGVK{}):
+		gv, err := schema.ParseGroupVersion(workload.Definition.APIVersion)
+		if err != nil {
+			return errors.Wrap(err, "invalid apiVersion of the workload")
+		}
+		if _, err := r.dm.RESTMapping(gv.WithKind(workload.Definition.Kind).GroupKind(), gv.Version); err != nil {
+			return errors.Wrapf(err, "the CRD of workload %s (%s) is not installed", workload.Definition.Kind, workload.Definition.APIVersion)
+		}
+	case len(workload.Type) != 0 && workload.Type != types.AutoDetectWorkloadDefinition:
+		workloadDef := &v1beta1.WorkloadDefinition{}
+		if err := util.GetDefinition(util.SetNamespaceInCtx(ctx, def.Namespace), r.Client, workloadDef, workload.Type); err != nil {
+			return errors.Wrapf(err, "cannot get WorkloadDefinition %s of the workload", workload.Type)
+		}
+		if err := r.checkReference(workloadDef.Spec.Reference); err != nil {
+			return errors.WithMessagef(err, "WorkloadDefinition %s", workload.Type)
+		}
+	}
+
+	capability := utils.NewCapabilityComponentDef(def)
+	switch capability.WorkloadType {
+	case util.HELMDef:
+		// the chart is pulled from the remote repository, it's left to the rendering of applications
+		return nil
+	case util.KubeDef:
+		return schemaError(utils.GetKubeSchematicOpenAPISchema(capability.Kube.Parameters))
+	case util.TerraformDef:
+		return schemaError(utils.GetTerraformConfigurationOpenAPISchema(capability.Terraform))
+	case util.KustomizeDef:
+		// the kustomize base is pulled from the remote source, it's left to the rendering of applications
+		return nil
+	case util.GitDef:
+		// the manifests or CUE template are loaded from the Git repository, it's left to the rendering of applications
+		return nil
+	}
+	if def.Spec.Schematic == nil || def.Spec.Schematic.CUE == nil {
+		return nil
+	}
+	return schemaError(capability.GetOpenAPISchema(r.pd, def.Name))
+}
+
+// checkReference checks whether the CRD referred by the definition is installed
+func (r *Reconciler) checkReference(ref common.DefinitionReference) error {
+	if _, err := util.GetGVKFromDefinition(r.dm, ref); err != nil {
+		return errors.Wrapf(err, "the CRD %s is not installed", ref.Name)
+	}
+	return nil
+}
+
+// checkCUETemplate checks whether the CUE template of the definition compiles, the parameter schema is not
+// required since policies, workflow steps and workloads may have no parameter
+func (r *Reconciler) checkCUETemplate(schematic *common.Schematic) error {
+	if schematic == nil || schematic.CUE == nil {
+		return nil
+	}
+	return errors.Wrap(utils.CompileCUETemplate(r.pd, schematic.CUE.Template), "invalid CUE template")
+}
+
+func schemaError(_ []byte, err error) error {
+	return errors.Wrap(err, "cannot generate the parameter schema")
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definitionhealth
+
+import (
+	"context"
+	"time"
+
+	cpv1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const checkTimeout = time.Minute
+
+// Reconciler checks the installed component, trait, workload, policy and workflow step definitions periodically and
+// maintains the Ready condition of them, so the broken capabilities are reported before any application hits them.
+// A definition is ready if its template compiles, its parameter schema can be generated and the CRDs it refers to
+// are installed.
+type Reconciler struct {
+	Client   client.Client
+	Recorder event.Recorder
+	dm       discoverymapper.DiscoveryMapper
+	pd       *definition.PackageDiscover
+	interval time.Duration
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=componentdefinitions;traitdefinitions;workloaddefinitions;policydefinitions;workflowstepdefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=componentdefinitions/status;traitdefinitions/status;workloaddefinitions/status;policydefinitions/status;workflowstepdefinitions/status,verbs=get;update;patch
+
+// Start checks the definitions every interval until stop is closed
+func (r *Reconciler) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+		if err := r.CheckAll(ctx); err != nil {
+			klog.ErrorS(err, "Failed to check the health of definitions")
+		}
+	}, r.interval, stop)
+	return nil
+}
+
+type definitionEntry struct {
+	kind string
+	def  util.ConditionedObject
+}
+
+// CheckAll checks the definitions in all namespaces and updates their Ready condition
+func (r *Reconciler) CheckAll(ctx context.Context) error {
+	var defs []definitionEntry
+	compDefs := &v1beta1.ComponentDefinitionList{}
+	if err := r.Client.List(ctx, compDefs); err != nil {
+		return err
+	}
+	for i := range compDefs.Items {
+		defs = append(defs, definitionEntry{kind: v1beta1.ComponentDefinitionKind, def: &compDefs.Items[i]})
+	}
+	traitDefs := &v1beta1.TraitDefinitionList{}
+	if err := r.Client.List(ctx, traitDefs); err != nil {
+		return err
+	}
+	for i := range traitDefs.Items {
+		defs = append(defs, definitionEntry{kind: v1beta1.TraitDefinitionKind, def: &traitDefs.Items[i]})
+	}
+	workloadDefs := &v1beta1.WorkloadDefinitionList{}
+	if err := r.Client.List(ctx, workloadDefs); err != nil {
+		return err
+	}
+	for i := range workloadDefs.Items {
+		defs = append(defs, definitionEntry{kind: v1beta1.WorkloadDefinitionKind, def: &workloadDefs.Items[i]})
+	}
+	policyDefs := &v1beta1.PolicyDefinitionList{}
+	if err := r.Client.List(ctx, policyDefs); err != nil {
+		return err
+	}
+	for i := range policyDefs.Items {
+		defs = append(defs, definitionEntry{kind: v1beta1.PolicyDefinitionKind, def: &policyDefs.Items[i]})
+	}
+	stepDefs := &v1beta1.WorkflowStepDefinitionList{}
+	if err := r.Client.List(ctx, stepDefs); err != nil {
+		return err
+	}
+	for i := range stepDefs.Items {
+		defs = append(defs, definitionEntry{kind: v1beta1.WorkflowStepDefinitionKind, def: &stepDefs.Items[i]})
+	}
+
+	var errs []error
+	for _, e := range defs {
+		if e.def.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err := r.updateReady(ctx, e.def, r.check(ctx, e.def)); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "cannot update the Ready condition of %s %s/%s",
+				e.kind, e.def.GetNamespace(), e.def.GetName()))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// updateReady sets the Ready condition of the definition by the result of the check, the status is patched only if
+// the condition changes
+func (r *Reconciler) updateReady(ctx context.Context, def util.ConditionedObject, checkErr error) error {
+	cond := cpv1alpha1.Available()
+	if checkErr != nil {
+		cond = cpv1alpha1.Unavailable().WithMessage(checkErr.Error())
+	}
+	last := def.GetCondition(cpv1alpha1.TypeReady)
+	if last.Equal(cond) {
+		return nil
+	}
+	if checkErr != nil {
+		klog.InfoS("Definition is unhealthy", "definition", klog.KObj(def), "err", checkErr)
+		r.Recorder.Event(def, event.Warning(velatypes.ReasonDefinitionUnhealthy, checkErr))
+	} else if last.Status == cpv1alpha1.Unavailable().Status {
+		klog.InfoS("Definition is healthy again", "definition", klog.KObj(def))
+		r.Recorder.Event(def, event.Normal(velatypes.ReasonDefinitionHealthy, velatypes.MessageDefinitionHealthy))
+	}
+	return util.PatchCondition(ctx, r.Client, def, cond)
+}
+
+// Setup adds a controller that checks the health of definitions periodically
+func Setup(mgr ctrl.Manager, args controller.Args, _ logging.Logger) error {
+	if args.DefinitionHealthInterval <= 0 {
+		return nil
+	}
+	r := &Reconciler{
+		Client:   mgr.GetClient(),
+		Recorder: event.NewAPIRecorder(mgr.GetEventRecorderFor("DefinitionHealth")),
+		dm:       args.DiscoveryMapper,
+		pd:       args.PackageDiscover,
+		interval: args.DefinitionHealthInterval,
+	}
+	return mgr.Add(r)
+}
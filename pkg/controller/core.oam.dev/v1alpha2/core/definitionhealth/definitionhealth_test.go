@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definitionhealth
+
+import (
+	"context"
+	"errors"
+
+	cpv1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/mock"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+const workerTemplate = `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: containers: [{image: parameter.image}]
+}
+parameter: {
+	image: string
+}
+`
+
+var _ = Describe("Test definition health checks", func() {
+	const ns = "vela-system"
+	ctx := context.Background()
+	var (
+		c client.Client
+		r *Reconciler
+	)
+
+	BeforeEach(func() {
+		c = fake.NewFakeClientWithScheme(utilcommon.Scheme,
+			&v1beta1.ComponentDefinition{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "worker"},
+				Spec: v1beta1.ComponentDefinitionSpec{
+					Workload:  common.WorkloadTypeDescriptor{Definition: common.WorkloadGVK{APIVersion: "apps/v1", Kind: "Deployment"}},
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: workerTemplate}},
+				},
+			},
+			&v1beta1.TraitDefinition{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "scaler"},
+				Spec: v1beta1.TraitDefinitionSpec{
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: `patch: spec: replicas: 1`}},
+				},
+			},
+			&v1beta1.TraitDefinition{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "foo"},
+				Spec:       v1beta1.TraitDefinitionSpec{Reference: common.DefinitionReference{Name: "foos.example.com"}},
+			},
+			&v1beta1.PolicyDefinition{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "broken"},
+				Spec: v1beta1.PolicyDefinitionSpec{
+					Schematic: &common.Schematic{CUE: &common.CUE{Template: `output: {`}},
+				},
+			},
+		)
+		dm := mock.NewMockDiscoveryMapper()
+		dm.MockKindsFor = func(input schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+			return nil, errors.New("no matches for " + input.String())
+		}
+		r = &Reconciler{Client: c, Recorder: event.NewNopRecorder(), dm: dm}
+	})
+
+	ready := func(def oam.Conditioned) cpv1alpha1.Condition {
+		return def.GetCondition(cpv1alpha1.TypeReady)
+	}
+
+	It("maintain the Ready condition of the definitions", func() {
+		Expect(r.CheckAll(ctx)).Should(Succeed())
+
+		compDef := &v1beta1.ComponentDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "worker"}, compDef)).Should(Succeed())
+		Expect(ready(compDef).Status).Should(Equal(corev1.ConditionTrue))
+
+		By("the trait has no parameter in the template")
+		scaler := &v1beta1.TraitDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "scaler"}, scaler)).Should(Succeed())
+		Expect(ready(scaler).Status).Should(Equal(corev1.ConditionFalse))
+		Expect(ready(scaler).Message).Should(ContainSubstring("parameter"))
+
+		By("the CRD referred by the trait is not installed")
+		foo := &v1beta1.TraitDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "foo"}, foo)).Should(Succeed())
+		Expect(ready(foo).Status).Should(Equal(corev1.ConditionFalse))
+		Expect(ready(foo).Message).Should(ContainSubstring("foos.example.com"))
+
+		By("the template of the policy doesn't compile")
+		policyDef := &v1beta1.PolicyDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "broken"}, policyDef)).Should(Succeed())
+		Expect(ready(policyDef).Status).Should(Equal(corev1.ConditionFalse))
+		Expect(ready(policyDef).Message).Should(ContainSubstring("invalid CUE template"))
+
+		By("the trait is ready once it's fixed")
+		scaler.Spec.Schematic.CUE.Template = "patch: spec: replicas: parameter.replicas\nparameter: replicas: *1 | int\n"
+		Expect(c.Update(ctx, scaler)).Should(Succeed())
+		Expect(r.CheckAll(ctx)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "scaler"}, scaler)).Should(Succeed())
+		Expect(ready(scaler).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(ready(scaler).Message).Should(BeEmpty())
+	})
+
+	It("keep the condition untouched if the health doesn't change", func() {
+		Expect(r.CheckAll(ctx)).Should(Succeed())
+		foo := &v1beta1.TraitDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "foo"}, foo)).Should(Succeed())
+		transition := ready(foo).LastTransitionTime
+		resourceVersion := foo.ResourceVersion
+
+		Expect(r.CheckAll(ctx)).Should(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "foo"}, foo)).Should(Succeed())
+		Expect(ready(foo).LastTransitionTime).Should(Equal(transition))
+		Expect(foo.ResourceVersion).Should(Equal(resourceVersion))
+	})
+})
@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aryann/difflib"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// diffWithLastDefRevision computes the changes of the definition in the new revision from the last revision, nil
+// is returned if there's no last revision or it's garbage collected
+func diffWithLastDefRevision(ctx context.Context, cli client.Reader, newDefRev *v1beta1.DefinitionRevision,
+	lastRevision *common.Revision) (*v1beta1.DefinitionRevisionDiff, error) {
+	if lastRevision == nil {
+		return nil, nil
+	}
+	def, _ := definitionOf(newDefRev)
+	lastDefRev := &v1beta1.DefinitionRevision{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: def.GetNamespace(), Name: lastRevision.Name}, lastDefRev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "get the definitionRevision %s", lastRevision.Name)
+	}
+	changes, err := DiffDefinitionRevision(lastDefRev, newDefRev)
+	if err != nil {
+		return nil, err
+	}
+	return &v1beta1.DefinitionRevisionDiff{PreviousRevision: lastDefRev.Name, Changes: changes}, nil
+}
+
+// DiffDefinitionRevision returns the changes of the spec and the version label of the definition from the old
+// revision to the new one
+func DiffDefinitionRevision(old, new *v1beta1.DefinitionRevision) ([]v1beta1.DefinitionFieldChange, error) {
+	oldDef, oldSpec := definitionOf(old)
+	newDef, newSpec := definitionOf(new)
+	var changes []v1beta1.DefinitionFieldChange
+	oldVersion, newVersion := oldDef.GetLabels()[oam.LabelDefinitionVersion], newDef.GetLabels()[oam.LabelDefinitionVersion]
+	if oldVersion != newVersion {
+		c, err := fieldChange("metadata.labels."+oam.LabelDefinitionVersion, oldVersion, newVersion)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	oldObj, err := toJSONValue(oldSpec)
+	if err != nil {
+		return nil, err
+	}
+	newObj, err := toJSONValue(newSpec)
+	if err != nil {
+		return nil, err
+	}
+	specChanges, err := diffJSONValue("spec", oldObj, newObj)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, specChanges...), nil
+}
+
+// definitionOf returns the definition snapshotted in the DefinitionRevision and its spec
+func definitionOf(defRev *v1beta1.DefinitionRevision) (metav1.Object, interface{}) {
+	switch defRev.Spec.DefinitionType {
+	case common.TraitType:
+		return &defRev.Spec.TraitDefinition, defRev.Spec.TraitDefinition.Spec
+	case common.PolicyType:
+		return &defRev.Spec.PolicyDefinition, defRev.Spec.PolicyDefinition.Spec
+	case common.WorkflowStepType:
+		return &defRev.Spec.WorkflowStepDefinition, defRev.Spec.WorkflowStepDefinition.Spec
+	default:
+		return &defRev.Spec.ComponentDefinition, defRev.Spec.ComponentDefinition.Spec
+	}
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// diffJSONValue compares two decoded JSON values, objects are compared field by field recursively while the other
+// values including arrays are compared as a whole
+func diffJSONValue(path string, old, new interface{}) ([]v1beta1.DefinitionFieldChange, error) {
+	oldObj, oldIsObj := old.(map[string]interface{})
+	newObj, newIsObj := new.(map[string]interface{})
+	if !oldIsObj || !newIsObj {
+		if reflect.DeepEqual(old, new) {
+			return nil, nil
+		}
+		c, err := fieldChange(path, old, new)
+		if err != nil {
+			return nil, err
+		}
+		return []v1beta1.DefinitionFieldChange{c}, nil
+	}
+	keys := map[string]bool{}
+	for k := range oldObj {
+		keys[k] = true
+	}
+	for k := range newObj {
+		keys[k] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	var changes []v1beta1.DefinitionFieldChange
+	for _, k := range sortedKeys {
+		c, err := diffJSONValue(path+"."+k, oldObj[k], newObj[k])
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+// fieldChange records the change of a field from old to new, nil or empty string means the field is absent
+func fieldChange(path string, old, new interface{}) (v1beta1.DefinitionFieldChange, error) {
+	c := v1beta1.DefinitionFieldChange{Path: path, Type: v1beta1.DefinitionFieldModified}
+	switch {
+	case isAbsent(old):
+		c.Type = v1beta1.DefinitionFieldAdded
+	case isAbsent(new):
+		c.Type = v1beta1.DefinitionFieldRemoved
+	}
+	oldStr, oldIsStr := old.(string)
+	newStr, newIsStr := new.(string)
+	if c.Type == v1beta1.DefinitionFieldModified && oldIsStr && newIsStr &&
+		(strings.Contains(oldStr, "\n") || strings.Contains(newStr, "\n")) {
+		c.LineDiff = lineDiff(oldStr, newStr)
+		return c, nil
+	}
+	if !isAbsent(old) {
+		b, err := json.Marshal(old)
+		if err != nil {
+			return c, err
+		}
+		c.Old = string(b)
+	}
+	if !isAbsent(new) {
+		b, err := json.Marshal(new)
+		if err != nil {
+			return c, err
+		}
+		c.New = string(b)
+	}
+	return c, nil
+}
+
+func isAbsent(v interface{}) bool {
+	if s, ok := v.(string); ok {
+		return len(s) == 0
+	}
+	return v == nil
+}
+
+// lineDiff returns the lines removed and added from old to new, prefixed with "-" and "+" respectively
+func lineDiff(old, new string) string {
+	const sep = "\n"
+	var lines []string
+	for _, d := range difflib.Diff(strings.Split(old, sep), strings.Split(new, sep)) {
+		switch d.Delta {
+		case difflib.LeftOnly:
+			lines = append(lines, "-"+d.Payload)
+		case difflib.RightOnly:
+			lines = append(lines, "+"+d.Payload)
+		default:
+		}
+	}
+	return strings.Join(lines, sep)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestDiffDefinitionRevision(t *testing.T) {
+	compRev := func(version, template, podSpecPath string, status *common.Status) *v1beta1.DefinitionRevision {
+		return &v1beta1.DefinitionRevision{
+			Spec: v1beta1.DefinitionRevisionSpec{
+				DefinitionType: common.ComponentType,
+				ComponentDefinition: v1beta1.ComponentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "worker", Labels: map[string]string{oam.LabelDefinitionVersion: version}},
+					Spec: v1beta1.ComponentDefinitionSpec{
+						Workload:    common.WorkloadTypeDescriptor{Type: "deployments.apps"},
+						Schematic:   &common.Schematic{CUE: &common.CUE{Template: template}},
+						Status:      status,
+						PodSpecPath: podSpecPath,
+					},
+				},
+			},
+		}
+	}
+	oldRev := compRev("1.0.0", "output: {\n\tkind: \"Deployment\"\n\tspec: replicas: 1\n}\n", "", &common.Status{HealthPolicy: "isHealth: true"})
+	newRev := compRev("1.1.0", "output: {\n\tkind: \"Deployment\"\n\tspec: replicas: 2\n}\n", "spec.template.spec", nil)
+
+	changes, err := DiffDefinitionRevision(oldRev, newRev)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1beta1.DefinitionFieldChange{{
+		Path: "metadata.labels." + oam.LabelDefinitionVersion,
+		Type: v1beta1.DefinitionFieldModified,
+		Old:  `"1.0.0"`,
+		New:  `"1.1.0"`,
+	}, {
+		Path: "spec.podSpecPath",
+		Type: v1beta1.DefinitionFieldAdded,
+		New:  `"spec.template.spec"`,
+	}, {
+		Path:     "spec.schematic.cue.template",
+		Type:     v1beta1.DefinitionFieldModified,
+		LineDiff: "-\tspec: replicas: 1\n+\tspec: replicas: 2",
+	}, {
+		Path: "spec.status",
+		Type: v1beta1.DefinitionFieldRemoved,
+		Old:  `{"healthPolicy":"isHealth: true"}`,
+	}}, changes)
+
+	changes, err = DiffDefinitionRevision(oldRev, oldRev.DeepCopy())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -22,9 +22,11 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,6 +35,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // GenerateDefinitionRevision will generate a definition revision the generated revision
@@ -50,6 +53,9 @@ func GenerateDefinitionRevision(ctx context.Context, cli client.Client, def runt
 		defRevName, revNum := getDefNextRevision(defRev, lastRevision)
 		defRev.Name = defRevName
 		defRev.Spec.Revision = revNum
+		if defRev.Spec.Diff, err = diffWithLastDefRevision(ctx, cli, defRev, lastRevision); err != nil {
+			return defRev, false, err
+		}
 	}
 	return defRev, isNewRev, nil
 }
@@ -81,6 +87,9 @@ func gatherRevisionInfo(def runtime.Object) (*v1beta1.DefinitionRevision, *commo
 	default:
 		return nil, nil, fmt.Errorf("unsupported type %v", definition)
 	}
+	if err := validateDefinitionVersion(def); err != nil {
+		return nil, nil, err
+	}
 	defHash, err := computeDefinitionRevisionHash(defRev)
 	if err != nil {
 		return nil, nil, err
@@ -89,6 +98,22 @@ func gatherRevisionInfo(def runtime.Object) (*v1beta1.DefinitionRevision, *commo
 	return defRev, LastRevision, nil
 }
 
+// validateDefinitionVersion checks the semantic version of the definition if it's labeled with one
+func validateDefinitionVersion(def runtime.Object) error {
+	accessor, err := meta.Accessor(def)
+	if err != nil {
+		return err
+	}
+	version, ok := accessor.GetLabels()[oam.LabelDefinitionVersion]
+	if !ok {
+		return nil
+	}
+	if _, err := semver.NewVersion(version); err != nil {
+		return errors.Wrapf(err, "invalid semantic version %q in label %s", version, oam.LabelDefinitionVersion)
+	}
+	return nil
+}
+
 func computeDefinitionRevisionHash(defRev *v1beta1.DefinitionRevision) (string, error) {
 	var defHash string
 	var err error
@@ -232,6 +257,10 @@ func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runti
 		return nil
 	}
 	klog.InfoS("cleanup old definitionRevision", "needKillNum", needKill)
+	pinned, err := pinnedDefinitionRevisions(ctx, cli)
+	if err != nil {
+		return err
+	}
 
 	sortedRevision := defRevList.Items
 	sort.Sort(historiesByRevision(sortedRevision))
@@ -240,7 +269,7 @@ func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runti
 		if needKill <= 0 {
 			break
 		}
-		if rev.Name == usingRevision {
+		if rev.Name == usingRevision || pinned[rev.Name] {
 			continue
 		}
 		if err := cli.Delete(ctx, rev.DeepCopy()); err != nil && !apierrors.IsNotFound(err) {
@@ -251,6 +280,59 @@ func CleanUpDefinitionRevision(ctx context.Context, cli client.Client, def runti
 	return nil
 }
 
+// pinnedDefinitionRevisions returns the DefinitionRevisions pinned by the types of the components and traits, e.g.,
+// `worker@v2` pins DefinitionRevision worker-v2, in the applications and the appRevisions of the live rollouts
+func pinnedDefinitionRevisions(ctx context.Context, cli client.Reader) (map[string]bool, error) {
+	pinned := map[string]bool{}
+	pin := func(app *v1beta1.Application) {
+		appCtx := util.SetNamespaceInCtx(ctx, app.Namespace)
+		for _, comp := range app.Spec.Components {
+			pinDefinitionRevision(appCtx, cli, pinned, comp.Type, common.ComponentType)
+			for _, trait := range comp.Traits {
+				pinDefinitionRevision(appCtx, cli, pinned, trait.Type, common.TraitType)
+			}
+		}
+	}
+	apps := new(v1beta1.ApplicationList)
+	if err := cli.List(ctx, apps); err != nil {
+		return nil, err
+	}
+	for i := range apps.Items {
+		pin(&apps.Items[i])
+	}
+	rollouts := new(v1beta1.AppRolloutList)
+	if err := cli.List(ctx, rollouts); err != nil {
+		return nil, err
+	}
+	for _, rollout := range rollouts.Items {
+		for _, revName := range []string{rollout.Spec.TargetAppRevisionName, rollout.Spec.SourceAppRevisionName} {
+			if len(revName) == 0 {
+				continue
+			}
+			appRev := new(v1beta1.ApplicationRevision)
+			if err := cli.Get(ctx, client.ObjectKey{Namespace: rollout.Namespace, Name: revName}, appRev); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			pin(&appRev.Spec.Application)
+		}
+	}
+	return pinned, nil
+}
+
+// pinDefinitionRevision pins the revision of the type, a semantic version constraint, e.g., worker@^1.2, pins the
+// revision it's resolved to currently
+func pinDefinitionRevision(ctx context.Context, cli client.Reader, pinned map[string]bool, typ string, defType common.DefinitionType) {
+	if !strings.Contains(typ, "@") {
+		return
+	}
+	if name, err := util.ResolveDefinitionRevName(ctx, cli, typ, defType); err == nil {
+		pinned[name] = true
+	}
+}
+
 type historiesByRevision []v1beta1.DefinitionRevision
 
 func (h historiesByRevision) Len() int      { return len(h) }
@@ -26,6 +26,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 var (
@@ -71,3 +73,29 @@ func CheckPodSpecWorkloadHealth(ctx context.Context, c client.Client, ref runtim
 	updateChildResourcesCondition(ctx, c, namespace, r, ref, childRefs)
 	return r
 }
+
+// CheckKnativeServiceHealth checks health condition of Knative Service by its Ready condition
+func CheckKnativeServiceHealth(ctx context.Context, c client.Client, ref runtimev1alpha1.TypedReference, namespace string) *WorkloadHealthCondition {
+	if !util.IsKnativeService(ref.GroupVersionKind()) {
+		return nil
+	}
+	r := &WorkloadHealthCondition{
+		HealthStatus:   StatusUnhealthy,
+		TargetWorkload: ref,
+	}
+	svc := unstructured.Unstructured{}
+	svc.SetGroupVersionKind(ref.GroupVersionKind())
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &svc); err != nil {
+		r.Diagnosis = errors.Wrap(err, errHealthCheck).Error()
+		return r
+	}
+	r.ComponentName = getComponentNameFromLabel(&svc)
+	r.TargetWorkload.UID = svc.GetUID()
+
+	ready, diagnosis := util.GetKnativeServiceReadiness(&svc)
+	r.Diagnosis = diagnosis
+	if ready {
+		r.HealthStatus = StatusHealthy
+	}
+	return r
+}
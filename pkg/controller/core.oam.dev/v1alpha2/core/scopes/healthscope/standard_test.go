@@ -149,3 +149,93 @@ func TestCheckPodSpecWorkloadHealth(t *testing.T) {
 		}(t)
 	}
 }
+
+func TestCheckKnativeServiceHealth(t *testing.T) {
+	mockClient := test.NewMockClient()
+	ksvcRef := runtimev1alpha1.TypedReference{Name: "ksvc"}
+	ksvcRef.SetGroupVersionKind(util.KnativeServiceGVK)
+
+	ksvc := func(generation, observedGeneration int64, ready string) unstructured.Unstructured {
+		o := unstructured.Unstructured{}
+		o.SetGroupVersionKind(util.KnativeServiceGVK)
+		o.SetGeneration(generation)
+		unstructured.SetNestedField(o.Object, observedGeneration, "status", "observedGeneration")
+		unstructured.SetNestedSlice(o.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": ready, "reason": "RevisionMissing"},
+		}, "status", "conditions")
+		return o
+	}
+
+	tests := []struct {
+		caseName  string
+		mockGetFn test.MockGetFn
+		wlRef     runtimev1alpha1.TypedReference
+		expect    *WorkloadHealthCondition
+	}{
+		{
+			caseName: "not matched checker",
+			wlRef:    runtimev1alpha1.TypedReference{},
+			expect:   nil,
+		},
+		{
+			caseName: "healthy knative service",
+			wlRef:    ksvcRef,
+			mockGetFn: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+				*obj.(*unstructured.Unstructured) = ksvc(2, 2, "True")
+				return nil
+			},
+			expect: &WorkloadHealthCondition{
+				HealthStatus: StatusHealthy,
+			},
+		},
+		{
+			caseName: "unhealthy for knative service not ready",
+			wlRef:    ksvcRef,
+			mockGetFn: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+				*obj.(*unstructured.Unstructured) = ksvc(2, 2, "False")
+				return nil
+			},
+			expect: &WorkloadHealthCondition{
+				HealthStatus: StatusUnhealthy,
+				Diagnosis:    "RevisionMissing",
+			},
+		},
+		{
+			caseName: "unhealthy for generation not observed",
+			wlRef:    ksvcRef,
+			mockGetFn: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+				*obj.(*unstructured.Unstructured) = ksvc(3, 2, "True")
+				return nil
+			},
+			expect: &WorkloadHealthCondition{
+				HealthStatus: StatusUnhealthy,
+				Diagnosis:    "generation 3 is not observed yet",
+			},
+		},
+		{
+			caseName: "unhealthy for knative service not found",
+			wlRef:    ksvcRef,
+			mockGetFn: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+				return errMockErr
+			},
+			expect: &WorkloadHealthCondition{
+				HealthStatus: StatusUnhealthy,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		func(t *testing.T) {
+			mockClient.MockGet = tc.mockGetFn
+			result := CheckKnativeServiceHealth(ctx, mockClient, tc.wlRef, namespace)
+			if tc.expect == nil {
+				assert.Nil(t, result, tc.caseName)
+				return
+			}
+			assert.Equal(t, tc.expect.HealthStatus, result.HealthStatus, tc.caseName)
+			if len(tc.expect.Diagnosis) != 0 {
+				assert.Equal(t, tc.expect.Diagnosis, result.Diagnosis, tc.caseName)
+			}
+		}(t)
+	}
+}
@@ -107,7 +107,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	workload, err := util.FetchWorkload(ctx, r, mLog, &manualScalar)
 	if err != nil {
 		r.record.Event(eventObj, event.Warning(util.ErrLocateWorkload, err))
-		return util.ReconcileWaitResult, util.PatchCondition(
+		return util.ReconcileWaitResult(), util.PatchCondition(
 			ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.Wrap(err, util.ErrLocateWorkload)))
 	}
 
@@ -116,7 +116,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if err != nil {
 		mLog.Error(err, "Error while fetching the workload child resources", "workload", workload.UnstructuredContent())
 		r.record.Event(eventObj, event.Warning(util.ErrFetchChildResources, err))
-		return util.ReconcileWaitResult, util.PatchCondition(ctx, r, &manualScalar,
+		return util.ReconcileWaitResult(), util.PatchCondition(ctx, r, &manualScalar,
 			cpv1alpha1.ReconcileError(errors.New(util.ErrFetchChildResources)))
 	}
 	// include the workload itself if there is no child resources
@@ -126,7 +126,7 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	// Scale the child resources that we know how to scale
 	result, err := r.scaleResources(ctx, mLog, manualScalar, resources)
 	// the scaleResources function will patch error message and should return here to prevent the condition override by the following patch.
-	if result == util.ReconcileWaitResult {
+	if result.RequeueAfter != 0 {
 		return result, err
 	}
 	if err != nil {
@@ -158,12 +158,12 @@ func (r *Reconciler) scaleResources(ctx context.Context, mLog logr.Logger,
 	// prepare for openApi schema check
 	schemaDoc, err := r.DiscoveryClient.OpenAPISchema()
 	if err != nil {
-		return util.ReconcileWaitResult,
+		return util.ReconcileWaitResult(),
 			util.PatchCondition(ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.Wrap(err, errQueryOpenAPI)))
 	}
 	document, err := openapi.NewOpenAPIData(schemaDoc)
 	if err != nil {
-		return util.ReconcileWaitResult,
+		return util.ReconcileWaitResult(),
 			util.PatchCondition(ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.Wrap(err, errQueryOpenAPI)))
 	}
 	for _, res := range resources {
@@ -176,13 +176,13 @@ func (r *Reconciler) scaleResources(ctx context.Context, mLog logr.Logger,
 			err := unstructured.SetNestedField(res.Object, int64(manualScalar.Spec.ReplicaCount), "spec", "replicas")
 			if err != nil {
 				mLog.Error(err, "Failed to patch a resource for scaling")
-				return util.ReconcileWaitResult,
+				return util.ReconcileWaitResult(),
 					util.PatchCondition(ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.Wrap(err, errPatchTobeScaledResource)))
 			}
 			// merge patch to scale the resource
 			if err := r.Patch(ctx, res, resPatch, client.FieldOwner(manualScalar.GetUID())); err != nil {
 				mLog.Error(err, "Failed to scale a resource")
-				return util.ReconcileWaitResult,
+				return util.ReconcileWaitResult(),
 					util.PatchCondition(ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.Wrap(err, errScaleResource)))
 			}
 			mLog.Info("Successfully scaled a resource", "resource GVK", res.GroupVersionKind().String(),
@@ -191,7 +191,7 @@ func (r *Reconciler) scaleResources(ctx context.Context, mLog logr.Logger,
 	}
 	if !found {
 		mLog.Info("Cannot locate any resource", "total resources", len(resources))
-		return util.ReconcileWaitResult,
+		return util.ReconcileWaitResult(),
 			util.PatchCondition(ctx, r, &manualScalar, cpv1alpha1.ReconcileError(errors.New(errScaleResource)))
 	}
 	return ctrl.Result{}, nil
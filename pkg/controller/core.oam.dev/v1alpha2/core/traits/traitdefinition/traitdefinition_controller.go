@@ -102,6 +102,15 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		klog.InfoS("Successfully update DefinitionRevision", "name", defRev.Name)
 
+		// the spec may be reverted to an existing revision, the status still needs to observe the new generation
+		if !util.IsUpToDate(&traitdefinition) {
+			if err := r.UpdateStatus(ctx, &traitdefinition); err != nil {
+				klog.ErrorS(err, "cannot update TraitDefinition Status")
+				return ctrl.Result{}, util.PatchCondition(ctx, r, &traitdefinition,
+					cpv1alpha1.ReconcileError(fmt.Errorf(util.ErrUpdateTraitDefinition, traitdefinition.Name, err)))
+			}
+		}
+
 		if err := coredef.CleanUpDefinitionRevision(ctx, r.Client, &traitdefinition, r.defRevLimit); err != nil {
 			klog.Error("[Garbage collection]")
 			r.record.Event(&traitdefinition, event.Warning("failed to garbage collect DefinitionRevision of type TraitDefinition", err))
@@ -187,6 +196,7 @@ func (r *Reconciler) createOrUpdateTraitDefRevision(ctx context.Context, namespa
 
 // UpdateStatus updates v1beta1.TraitDefinition's Status with retry.RetryOnConflict
 func (r *Reconciler) UpdateStatus(ctx context.Context, def *v1beta1.TraitDefinition, opts ...client.UpdateOption) error {
+	util.SetObservedGeneration(def)
 	status := def.DeepCopy().Status
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
 		if err = r.Get(ctx, client.ObjectKey{Namespace: def.Namespace, Name: def.Name}, def); err != nil {
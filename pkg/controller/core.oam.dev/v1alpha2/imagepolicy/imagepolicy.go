@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/imagepolicy"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+	"github.com/oam-dev/kubevela/pkg/trigger"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+const scanTimeout = 5 * time.Minute
+
+// Reconciler scans the registries for the components with image policies, the image property of a component is
+// updated to the tag selected by its policy and the workflow of the application is restarted to deploy it. The tags
+// selected are recorded in the status of the application.
+type Reconciler struct {
+	Client client.Client
+	// reader reads the Secrets of the registry credentials from the API server directly, so no informer caching all
+	// Secrets in the cluster is started
+	reader client.Reader
+	// newRegistryClient creates the client listing the tags of the registry with the credentials, which can be nil
+	newRegistryClient func(cred *oci.Credentials) *oci.Client
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications/status,verbs=get;update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Start checks the image policies every imagepolicy.MinInterval until stop is closed, each policy is scanned when its
+// interval has elapsed since its last scan
+func (r *Reconciler) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+		defer cancel()
+		if err := r.Scan(ctx); err != nil {
+			klog.ErrorS(err, "Failed to scan the image policies")
+		}
+	}, imagepolicy.MinInterval, stop)
+	return nil
+}
+
+// Scan scans the image policies of the applications in all namespaces whose intervals have elapsed
+func (r *Reconciler) Scan(ctx context.Context) error {
+	apps := &v1beta1.ApplicationList{}
+	if err := r.Client.List(ctx, apps); err != nil {
+		return errors.Wrap(err, "cannot list applications")
+	}
+	var errs []error
+	for i := range apps.Items {
+		if err := r.scanApplication(ctx, &apps.Items[i], time.Now()); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "application %s/%s", apps.Items[i].Namespace, apps.Items[i].Name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// scanApplication selects the images of the components whose policies are due, updates the components and records
+// the result in the status. The errors of the scans are recorded in the status rather than returned.
+func (r *Reconciler) scanApplication(ctx context.Context, app *v1beta1.Application, now time.Time) error {
+	scanned := map[string]*common.ImagePolicyStatus{}
+	images := map[string]string{}
+	for _, comp := range app.Spec.Components {
+		if comp.ImagePolicy == nil {
+			continue
+		}
+		status := findStatus(app.Status.ImagePolicies, comp.Name)
+		if status != nil && now.Before(status.LastScanTime.Add(imagepolicy.Interval(comp.ImagePolicy))) {
+			continue
+		}
+		s := &common.ImagePolicyStatus{Component: comp.Name, LastScanTime: metav1.NewTime(now)}
+		if status != nil {
+			s.Image, s.Previous, s.UpdatedAt = status.Image, status.Previous, status.UpdatedAt
+		}
+		image, err := r.latestImage(ctx, app.Namespace, comp.ImagePolicy)
+		if err != nil {
+			s.Message = err.Error()
+			klog.InfoS("Failed to scan the image policy", "application", klog.KObj(app), "component", comp.Name, "err", err)
+		} else {
+			images[comp.Name] = image
+		}
+		scanned[comp.Name] = s
+	}
+	if len(scanned) == 0 && !hasStaleStatus(app) {
+		return nil
+	}
+	if len(images) != 0 {
+		if err := r.updateImages(ctx, app, images, scanned, now); err != nil {
+			return err
+		}
+	}
+	return r.recordStatus(ctx, app, scanned)
+}
+
+// latestImage lists the tags of the repository and returns the image of the tag selected by the policy
+func (r *Reconciler) latestImage(ctx context.Context, ns string, p *common.ImagePolicy) (string, error) {
+	if err := imagepolicy.Validate(p); err != nil {
+		return "", err
+	}
+	ref, err := oci.ParseReference(p.Image)
+	if err != nil {
+		return "", err
+	}
+	var cred *oci.Credentials
+	if p.SecretRef != nil && len(p.SecretRef.Name) != 0 {
+		secret := &corev1.Secret{}
+		if err := r.reader.Get(ctx, types.NamespacedName{Namespace: ns, Name: p.SecretRef.Name}, secret); err != nil {
+			return "", errors.Wrapf(err, "cannot get the secret %s of the registry", p.SecretRef.Name)
+		}
+		if cred, err = oci.CredentialsFromSecret(secret, ref.Registry); err != nil {
+			return "", err
+		}
+	}
+	c := r.newRegistryClient(cred)
+	tags, err := c.ListTags(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	tag, err := imagepolicy.Select(ctx, p, tags, func(ctx context.Context, tag string) (time.Time, error) {
+		tagged := ref
+		tagged.Tag = tag
+		return c.ImageCreated(ctx, tagged)
+	})
+	if err != nil {
+		return "", err
+	}
+	// the repository is kept as it's written in the policy, e.g., without the default registry
+	return p.Image + ":" + tag, nil
+}
+
+// updateImages sets the images selected to the components and requests the workflow to restart if any is changed
+func (r *Reconciler) updateImages(ctx context.Context, app *v1beta1.Application, images map[string]string, scanned map[string]*common.ImagePolicyStatus, now time.Time) error {
+	var changes []string
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		changes = nil
+		latest := &v1beta1.Application{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, latest); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(latest.DeepCopy())
+		for i := range latest.Spec.Components {
+			comp := &latest.Spec.Components[i]
+			image, ok := images[comp.Name]
+			if !ok || comp.ImagePolicy == nil {
+				continue
+			}
+			previous, changed, err := trigger.SetImage(comp, image)
+			if err != nil {
+				return err
+			}
+			s := scanned[comp.Name]
+			s.Image = image
+			if changed {
+				updatedAt := metav1.NewTime(now)
+				s.Previous, s.UpdatedAt = previous, &updatedAt
+				changes = append(changes, fmt.Sprintf("%s to %s", comp.Name, image))
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		workflow.RequestRestart(latest, "image policy updated "+strings.Join(changes, ", "))
+		return r.Client.Patch(ctx, latest, patch)
+	})
+	if err != nil {
+		return errors.WithMessage(err, "cannot update the images of the components")
+	}
+	if len(changes) != 0 {
+		klog.InfoS("Update the images of the components by image policies", "application", klog.KObj(app), "changes", changes)
+	}
+	return nil
+}
+
+// recordStatus replaces the status of the policies scanned and drops the status of the components without policies
+func (r *Reconciler) recordStatus(ctx context.Context, app *v1beta1.Application, scanned map[string]*common.ImagePolicyStatus) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &v1beta1.Application{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, latest); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		var statuses []common.ImagePolicyStatus
+		for _, comp := range latest.Spec.Components {
+			if comp.ImagePolicy == nil {
+				continue
+			}
+			if s, ok := scanned[comp.Name]; ok {
+				statuses = append(statuses, *s)
+			} else if s := findStatus(latest.Status.ImagePolicies, comp.Name); s != nil {
+				statuses = append(statuses, *s)
+			}
+		}
+		latest.Status.ImagePolicies = statuses
+		return r.Client.Status().Update(ctx, latest)
+	})
+}
+
+// hasStaleStatus returns whether the status records the policy of a component which no longer has one
+func hasStaleStatus(app *v1beta1.Application) bool {
+	for _, s := range app.Status.ImagePolicies {
+		found := false
+		for _, comp := range app.Spec.Components {
+			if comp.Name == s.Component && comp.ImagePolicy != nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+	return false
+}
+
+func findStatus(statuses []common.ImagePolicyStatus, component string) *common.ImagePolicyStatus {
+	for i := range statuses {
+		if statuses[i].Component == component {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// Setup adds a controller that scans the image policies of the components periodically
+func Setup(mgr ctrl.Manager, _ controller.Args, _ logging.Logger) error {
+	r := &Reconciler{
+		Client: mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+		newRegistryClient: func(cred *oci.Credentials) *oci.Client {
+			c := &oci.Client{HTTPClient: remote.HTTPClient()}
+			if cred != nil {
+				c.Username, c.Password = cred.Username, cred.Password
+			}
+			return c
+		},
+	}
+	return mgr.Add(r)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepolicy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+func TestScanApplication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user+":"+pass != "user:pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/v2/org/app/tags/list" {
+			fmt.Fprint(w, `{"tags":["1.0.0","1.1.0","2.0.0","latest"]}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/org/app"
+
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+		Spec: v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{{
+			Name:       "web",
+			Type:       "webservice",
+			Properties: runtime.RawExtension{Raw: []byte(`{"image":"` + repo + `:1.0.0","port":80}`)},
+			ImagePolicy: &common.ImagePolicy{
+				Image:     repo,
+				Policy:    common.ImagePolicySemver,
+				Range:     "<2.0.0",
+				SecretRef: &corev1.LocalObjectReference{Name: "registry"},
+			},
+		}, {
+			Name:       "worker",
+			Type:       "worker",
+			Properties: runtime.RawExtension{Raw: []byte(`{"image":"busybox"}`)},
+		}}},
+		Status: common.AppStatus{ImagePolicies: []common.ImagePolicyStatus{{Component: "removed"}}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "registry"},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	c := fake.NewFakeClientWithScheme(scheme, app, secret)
+	r := &Reconciler{Client: c, reader: c, newRegistryClient: func(cred *oci.Credentials) *oci.Client {
+		cl := &oci.Client{PlainHTTP: true}
+		if cred != nil {
+			cl.Username, cl.Password = cred.Username, cred.Password
+		}
+		return cl
+	}}
+
+	now := time.Now()
+	require.NoError(t, r.scanApplication(context.Background(), app, now))
+	updated := &v1beta1.Application{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "app"}, updated))
+	assert.JSONEq(t, `{"image":"`+repo+`:1.1.0","port":80}`, string(updated.Spec.Components[0].Properties.Raw))
+	assert.JSONEq(t, `{"image":"busybox"}`, string(updated.Spec.Components[1].Properties.Raw))
+	assert.Contains(t, updated.GetAnnotations()[oam.AnnotationRestartWorkflow], "web to "+repo+":1.1.0")
+	require.Len(t, updated.Status.ImagePolicies, 1)
+	status := updated.Status.ImagePolicies[0]
+	assert.Equal(t, "web", status.Component)
+	assert.Equal(t, repo+":1.1.0", status.Image)
+	assert.Equal(t, repo+":1.0.0", status.Previous)
+	assert.NotNil(t, status.UpdatedAt)
+	assert.Empty(t, status.Message)
+
+	// the policy isn't scanned again before its interval elapses
+	server.Close()
+	require.NoError(t, r.scanApplication(context.Background(), updated, now.Add(time.Minute)))
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "app"}, updated))
+	assert.Empty(t, updated.Status.ImagePolicies[0].Message)
+
+	// the error of the scan is recorded in the status and the image is kept
+	require.NoError(t, r.scanApplication(context.Background(), updated, now.Add(10*time.Minute)))
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "app"}, updated))
+	assert.NotEmpty(t, updated.Status.ImagePolicies[0].Message)
+	assert.Equal(t, repo+":1.1.0", updated.Status.ImagePolicies[0].Image)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metering
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/metering"
+)
+
+const (
+	collectTimeout = 5 * time.Minute
+	// reportNamePrefix is the prefix of the names of the UsageReports, the collection time follows
+	reportNamePrefix = "usage-"
+)
+
+// Reconciler collects the usage of applications per namespace and team periodically, the usage is stored as
+// UsageReports and exported as metrics, the oldest reports beyond the history limit are deleted.
+type Reconciler struct {
+	Client client.Client
+	// reader reads the applications and pods from the API server directly, so no informer caching all pods in the
+	// cluster is started for the collection
+	reader       client.Reader
+	interval     time.Duration
+	historyLimit int
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=usagereports,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications;resourcetrackers,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces;pods,verbs=get;list;watch
+
+// Start collects the usage every interval until stop is closed
+func (r *Reconciler) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		defer cancel()
+		if err := r.Report(ctx); err != nil {
+			klog.ErrorS(err, "Failed to report the usage of applications")
+		}
+	}, r.interval, stop)
+	return nil
+}
+
+// Report collects the usage of applications, exports it as metrics and stores it as a UsageReport
+func (r *Reconciler) Report(ctx context.Context) error {
+	spec, err := metering.Collect(ctx, r.reader)
+	if err != nil {
+		return err
+	}
+	metering.Export(spec)
+	report := &v1beta1.UsageReport{
+		ObjectMeta: metav1.ObjectMeta{Name: reportNamePrefix + spec.CollectedAt.UTC().Format("20060102-150405")},
+		Spec:       *spec,
+	}
+	if err := r.Client.Create(ctx, report); err != nil {
+		return errors.Wrapf(err, "cannot create usage report %s", report.Name)
+	}
+	klog.InfoS("Successfully report the usage of applications", "report", report.Name, "namespaces", len(spec.Namespaces))
+	return r.prune(ctx)
+}
+
+// prune deletes the oldest UsageReports beyond the history limit
+func (r *Reconciler) prune(ctx context.Context) error {
+	if r.historyLimit <= 0 {
+		return nil
+	}
+	reports := &v1beta1.UsageReportList{}
+	if err := r.Client.List(ctx, reports); err != nil {
+		return errors.Wrap(err, "cannot list usage reports")
+	}
+	if len(reports.Items) <= r.historyLimit {
+		return nil
+	}
+	sort.Slice(reports.Items, func(i, j int) bool {
+		return reports.Items[i].Spec.CollectedAt.Before(&reports.Items[j].Spec.CollectedAt)
+	})
+	for i := range reports.Items[:len(reports.Items)-r.historyLimit] {
+		if err := r.Client.Delete(ctx, &reports.Items[i]); client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "cannot delete usage report %s", reports.Items[i].Name)
+		}
+	}
+	return nil
+}
+
+// Setup adds a controller that reports the usage of applications periodically
+func Setup(mgr ctrl.Manager, args controller.Args, _ logging.Logger) error {
+	if args.MeteringInterval <= 0 {
+		return nil
+	}
+	r := &Reconciler{
+		Client:       mgr.GetClient(),
+		reader:       mgr.GetAPIReader(),
+		interval:     args.MeteringInterval,
+		historyLimit: args.MeteringHistoryLimit,
+	}
+	return mgr.Add(r)
+}
@@ -21,24 +21,42 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/addon"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/appdeployment"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationcontext"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationrollout"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/cluster"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/components/componentdefinition"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/definitionhealth"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/scopes/healthscope"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/traits/manualscalertrait"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/traits/traitdefinition"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/workloads/containerizedworkload"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/imagepolicy"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/metering"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/shardscheduler"
 )
 
 // Setup workload controllers.
 func Setup(mgr ctrl.Manager, args controller.Args, l logging.Logger) error {
+	if !args.Sharding.IsMaster() {
+		// non-master shards only reconcile the applications scheduled to them
+		for _, setup := range []func(ctrl.Manager, controller.Args, logging.Logger) error{
+			application.Setup, applicationcontext.Setup,
+		} {
+			if err := setup(mgr, args, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	for _, setup := range []func(ctrl.Manager, controller.Args, logging.Logger) error{
 		containerizedworkload.Setup, manualscalertrait.Setup, healthscope.Setup,
 		application.Setup, applicationrollout.Setup, applicationcontext.Setup, appdeployment.Setup,
-		traitdefinition.Setup, componentdefinition.Setup,
+		cluster.Setup, traitdefinition.Setup, componentdefinition.Setup, definitionhealth.Setup,
+		metering.Setup, imagepolicy.Setup, addon.Setup, shardscheduler.Setup,
 	} {
 		if err := setup(mgr, args, l); err != nil {
 			return err
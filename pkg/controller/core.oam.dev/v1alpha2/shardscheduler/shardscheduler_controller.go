@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardscheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/sharding"
+)
+
+const reconcileTimeout = 30 * time.Second
+
+// Reconciler schedules the applications to the shards of the controller. The applications not scheduled yet or
+// scheduled to a shard no longer schedulable are labeled with the shard picked by the hash of their namespaces and
+// names, the ones pinned to a schedulable shard by the label are kept there.
+type Reconciler struct {
+	Client  client.Client
+	Options sharding.Options
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;patch
+
+// Reconcile schedules the application
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	app := &v1beta1.Application{}
+	if err := r.Client.Get(ctx, req.NamespacedName, app); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !app.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	previous := app.GetLabels()[oam.LabelShardID]
+	patch := client.MergeFrom(app.DeepCopy())
+	if !r.Options.Schedule(app) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Client.Patch(ctx, app, patch); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	klog.InfoS("Scheduled application to shard", "application", klog.KObj(app),
+		"shard", app.GetLabels()[oam.LabelShardID], "previous", previous)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the scheduler with the manager
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("shard-scheduler").
+		For(&v1beta1.Application{}).
+		Complete(r)
+}
+
+// Setup adds the scheduler of the shards to the master shard if sharding is enabled
+func Setup(mgr ctrl.Manager, args controller.Args, _ logging.Logger) error {
+	if !args.Sharding.Enabled || !args.Sharding.IsMaster() {
+		return nil
+	}
+	r := &Reconciler{Client: mgr.GetClient(), Options: args.Sharding}
+	return r.SetupWithManager(mgr)
+}
@@ -20,6 +20,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/standard.oam.dev/v1alpha1/autoscaler"
 	"github.com/oam-dev/kubevela/pkg/controller/standard.oam.dev/v1alpha1/podspecworkload"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 )
@@ -30,7 +31,7 @@ func Setup(mgr ctrl.Manager, disableCaps string) error {
 	switch disableCaps {
 	case common.DisableNoneCaps:
 		functions = []func(ctrl.Manager) error{
-			podspecworkload.Setup,
+			podspecworkload.Setup, autoscaler.Setup,
 		}
 	case common.DisableAllCaps:
 	default:
@@ -38,6 +39,9 @@ func Setup(mgr ctrl.Manager, disableCaps string) error {
 		if !disableCapsSet.Contains(common.PodspecWorkloadControllerName) {
 			functions = append(functions, podspecworkload.Setup)
 		}
+		if !disableCapsSet.Contains(common.AutoscaleControllerName) {
+			functions = append(functions, autoscaler.Setup)
+		}
 	}
 
 	for _, setup := range functions {
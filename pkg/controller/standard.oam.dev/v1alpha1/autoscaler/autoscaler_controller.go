@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cpv1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// Reconcile error strings.
+const (
+	errInvalidReplicas = "the min replicas is greater than the max replicas"
+	errRenderScaler    = "cannot render the scaler"
+	errApplyScaler     = "cannot apply the scaler"
+	errDeleteScaler    = "cannot delete the scaler of the previous backend"
+)
+
+// Reconciler reconciles an Autoscaler object
+type Reconciler struct {
+	client.Client
+	log    logr.Logger
+	record event.Recorder
+	Scheme *runtime.Scheme
+}
+
+// Reconcile is the main logic for autoscaler controller
+// +kubebuilder:rbac:groups=standard.oam.dev,resources=autoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=standard.oam.dev,resources=autoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=keda.sh,resources=scaledobjects,verbs=get;list;watch;create;update;patch;delete
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.log.WithValues("autoscaler", req.NamespacedName)
+	log.Info("Reconcile autoscaler trait")
+
+	var scaler v1alpha1.Autoscaler
+	if err := r.Get(ctx, req.NamespacedName, &scaler); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Autoscaler trait is deleted")
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// find the resource object to record the event to, default is the parent appConfig.
+	eventObj, err := util.LocateParentAppConfig(ctx, r.Client, &scaler)
+	if eventObj == nil {
+		// fallback to the trait itself
+		log.Error(err, "Failed to find the parent resource", "autoscaler", scaler.Name)
+		eventObj = &scaler
+	}
+	if scaler.Spec.MinReplicas != nil && *scaler.Spec.MinReplicas > scaler.Spec.MaxReplicas {
+		err := fmt.Errorf("min replicas %d, max replicas %d", *scaler.Spec.MinReplicas, scaler.Spec.MaxReplicas)
+		r.record.Event(eventObj, event.Warning(errInvalidReplicas, err))
+		return ctrl.Result{}, util.PatchCondition(ctx, r, &scaler,
+			cpv1alpha1.ReconcileError(errors.Wrap(err, errInvalidReplicas)))
+	}
+	workload, err := util.FetchWorkload(ctx, r, log, &scaler)
+	if err != nil {
+		r.record.Event(eventObj, event.Warning(util.ErrLocateWorkload, err))
+		return util.ReconcileWaitResult(), util.PatchCondition(ctx, r, &scaler,
+			cpv1alpha1.ReconcileError(errors.Wrap(err, util.ErrLocateWorkload)))
+	}
+
+	spec, holder := effectiveSpec(&scaler, workload, time.Now())
+	backend := backendOf(spec)
+	obj, err := r.renderScaler(&scaler, spec, backend, workload)
+	if err != nil {
+		log.Error(err, "Failed to render the scaler", "backend", backend)
+		r.record.Event(eventObj, event.Warning(errRenderScaler, err))
+		return util.ReconcileWaitResult(), util.PatchCondition(ctx, r, &scaler,
+			cpv1alpha1.ReconcileError(errors.Wrap(err, errRenderScaler)))
+	}
+	// server side apply
+	applyOpts := []client.PatchOption{client.ForceOwnership, client.FieldOwner(scaler.GetUID())}
+	if err := r.Patch(ctx, obj, client.Apply, applyOpts...); err != nil {
+		log.Error(err, "Failed to apply the scaler", "backend", backend)
+		r.record.Event(eventObj, event.Warning(errApplyScaler, err))
+		return util.ReconcileWaitResult(), util.PatchCondition(ctx, r, &scaler,
+			cpv1alpha1.ReconcileError(errors.Wrap(err, errApplyScaler)))
+	}
+	// the scaler of the other backend is removed when the triggers are added or removed
+	if err := r.deleteStaleScaler(ctx, &scaler, backend); err != nil {
+		log.Error(err, "Failed to delete the scaler of the previous backend", "backend", backend)
+		r.record.Event(eventObj, event.Warning(errDeleteScaler, err))
+		return util.ReconcileWaitResult(), util.PatchCondition(ctx, r, &scaler,
+			cpv1alpha1.ReconcileError(errors.Wrap(err, errDeleteScaler)))
+	}
+
+	if holder != scaler.Status.SuspendedBy {
+		if len(holder) != 0 {
+			r.record.Event(eventObj, event.Normal("Autoscaler suspended",
+				fmt.Sprintf("Trait `%s` pinned the workload `%s` to %d replicas while it's leased by %s",
+					scaler.Name, workload.GetName(), spec.MaxReplicas, holder)))
+		} else {
+			r.record.Event(eventObj, event.Normal("Autoscaler resumed",
+				fmt.Sprintf("Trait `%s` resumed scaling the workload `%s` with %s", scaler.Name,
+					workload.GetName(), backend)))
+		}
+	}
+	if backend != scaler.Status.Backend {
+		r.record.Event(eventObj, event.Normal("Autoscaler applied",
+			fmt.Sprintf("Trait `%s` successfully applied a %s scaling the workload `%s`", scaler.Name,
+				obj.GetObjectKind().GroupVersionKind().Kind, workload.GetName())))
+	}
+	scaler.Status.Backend = backend
+	scaler.Status.SuspendedBy = holder
+	scaler.Status.ScalerRef = &cpv1alpha1.TypedReference{
+		APIVersion: obj.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+		Kind:       obj.GetObjectKind().GroupVersionKind().Kind,
+		Name:       scaler.GetName(),
+	}
+	if err := r.UpdateStatus(ctx, &scaler); err != nil {
+		return util.ReconcileWaitResult(), err
+	}
+	result := ctrl.Result{}
+	if len(holder) != 0 {
+		// check again until the lease is released or expired
+		result = util.ReconcileWaitResult()
+	}
+	return result, util.PatchCondition(ctx, r, &scaler, cpv1alpha1.ReconcileSuccess())
+}
+
+// renderScaler renders the HorizontalPodAutoscaler or the KEDA ScaledObject of the backend
+func (r *Reconciler) renderScaler(scaler *v1alpha1.Autoscaler, spec v1alpha1.AutoscalerSpec,
+	backend v1alpha1.AutoscalerBackend, workload *unstructured.Unstructured) (oam.Object, error) {
+	var obj oam.Object
+	if backend == v1alpha1.KEDAAutoscalerBackend {
+		obj = renderScaledObject(scaler, spec, workload)
+	} else {
+		obj = renderHPA(scaler, spec, workload)
+	}
+	// set the controller reference so that the scaler is deleted along with the trait
+	if err := ctrl.SetControllerReference(scaler, obj, r.Scheme); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// deleteStaleScaler deletes the scaler of the trait applied by the other backend
+func (r *Reconciler) deleteStaleScaler(ctx context.Context, scaler *v1alpha1.Autoscaler,
+	backend v1alpha1.AutoscalerBackend) error {
+	stale := &unstructured.Unstructured{}
+	if backend == v1alpha1.KEDAAutoscalerBackend {
+		stale.SetAPIVersion(hpaAPIVersion)
+		stale.SetKind(hpaKind)
+	} else {
+		stale.SetAPIVersion(scaledObjectAPIVersion)
+		stale.SetKind(scaledObjectKind)
+	}
+	key := types.NamespacedName{Namespace: scaler.GetNamespace(), Name: scaler.GetName()}
+	if err := r.Get(ctx, key, stale); err != nil {
+		// KEDA may not be installed if the workload has never been scaled by it
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(stale, scaler) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, stale))
+}
+
+// SetupWithManager will setup controller for autoscaler
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("Autoscaler")).
+		WithAnnotations("controller", "Autoscaler")
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Autoscaler{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		Complete(r)
+}
+
+// UpdateStatus updates *v1alpha1.Autoscaler's Status with retry.RetryOnConflict
+func (r *Reconciler) UpdateStatus(ctx context.Context, scaler *v1alpha1.Autoscaler, opts ...client.UpdateOption) error {
+	status := scaler.DeepCopy().Status
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() (err error) {
+		if err = r.Get(ctx, types.NamespacedName{Namespace: scaler.Namespace, Name: scaler.Name}, scaler); err != nil {
+			return
+		}
+		scaler.Status = status
+		return r.Status().Update(ctx, scaler, opts...)
+	})
+}
+
+// Setup adds a controller that reconciles Autoscaler.
+func Setup(mgr ctrl.Manager) error {
+	reconciler := Reconciler{
+		Client: mgr.GetClient(),
+		log:    ctrl.Log.WithName("Autoscaler"),
+		Scheme: mgr.GetScheme(),
+	}
+	return reconciler.SetupWithManager(mgr)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+var (
+	hpaKind       = reflect.TypeOf(autoscalingv2beta2.HorizontalPodAutoscaler{}).Name()
+	hpaAPIVersion = autoscalingv2beta2.SchemeGroupVersion.String()
+)
+
+const (
+	// scaledObjectKind and scaledObjectAPIVersion identify the KEDA ScaledObject, KEDA is not a dependency
+	// so the object is handled as unstructured
+	scaledObjectKind       = "ScaledObject"
+	scaledObjectAPIVersion = "keda.sh/v1alpha1"
+)
+
+// backendOf returns the implementation the workload is scaled with, KEDA is only needed for the event sources
+func backendOf(spec v1alpha1.AutoscalerSpec) v1alpha1.AutoscalerBackend {
+	if len(spec.Triggers) != 0 {
+		return v1alpha1.KEDAAutoscalerBackend
+	}
+	return v1alpha1.HPAAutoscalerBackend
+}
+
+// effectiveSpec returns the spec the workload is scaled with and the holder of the workload lease. While the
+// workload is leased by another controller, e.g. a rollout, the replicas are pinned to the current ones so the
+// autoscaler and the holder don't fight over them
+func effectiveSpec(scaler *v1alpha1.Autoscaler, workload *unstructured.Unstructured, now time.Time) (
+	v1alpha1.AutoscalerSpec, string) {
+	spec := *scaler.Spec.DeepCopy()
+	holder := util.GetWorkloadLeaseHolder(workload, now)
+	if len(holder) == 0 {
+		return spec, ""
+	}
+	replicas, found, err := unstructured.NestedInt64(workload.Object, "spec", "replicas")
+	if err != nil || !found {
+		replicas = 1
+	}
+	// a HorizontalPodAutoscaler can't keep a workload at zero, the lowest replicas it's pinned to is one
+	if replicas < 1 {
+		replicas = 1
+	}
+	pinned := int32(replicas)
+	spec.MinReplicas = &pinned
+	spec.MaxReplicas = pinned
+	return spec, holder
+}
+
+// renderHPA renders the HorizontalPodAutoscaler scaling the workload on its CPU and memory utilization
+func renderHPA(scaler *v1alpha1.Autoscaler, spec v1alpha1.AutoscalerSpec,
+	workload *unstructured.Unstructured) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       hpaKind,
+			APIVersion: hpaAPIVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      scaler.GetName(),
+			Namespace: scaler.GetNamespace(),
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: workload.GetAPIVersion(),
+				Kind:       workload.GetKind(),
+				Name:       workload.GetName(),
+			},
+			MinReplicas: spec.MinReplicas,
+			MaxReplicas: spec.MaxReplicas,
+		},
+	}
+	// the HorizontalPodAutoscaler targets 80% of the CPU if there is no metric
+	addMetric := func(name corev1.ResourceName, utilization *int32) {
+		if utilization == nil {
+			return
+		}
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2beta2.MetricSpec{
+			Type: autoscalingv2beta2.ResourceMetricSourceType,
+			Resource: &autoscalingv2beta2.ResourceMetricSource{
+				Name: name,
+				Target: autoscalingv2beta2.MetricTarget{
+					Type:               autoscalingv2beta2.UtilizationMetricType,
+					AverageUtilization: utilization,
+				},
+			},
+		})
+	}
+	addMetric(corev1.ResourceCPU, spec.CPUUtilization)
+	addMetric(corev1.ResourceMemory, spec.MemoryUtilization)
+	util.PassLabelAndAnnotation(scaler, hpa)
+	return hpa
+}
+
+// renderScaledObject renders the KEDA ScaledObject scaling the workload on its event sources, the CPU and memory
+// utilization are added as the cpu and memory scalers of KEDA
+func renderScaledObject(scaler *v1alpha1.Autoscaler, spec v1alpha1.AutoscalerSpec,
+	workload *unstructured.Unstructured) *unstructured.Unstructured {
+	triggers := make([]interface{}, 0, len(spec.Triggers)+2)
+	addResourceTrigger := func(name string, utilization *int32) {
+		if utilization == nil {
+			return
+		}
+		triggers = append(triggers, map[string]interface{}{
+			"type": name,
+			"metadata": map[string]interface{}{
+				"type":  "Utilization",
+				"value": strconv.Itoa(int(*utilization)),
+			},
+		})
+	}
+	addResourceTrigger("cpu", spec.CPUUtilization)
+	addResourceTrigger("memory", spec.MemoryUtilization)
+	for _, t := range spec.Triggers {
+		metadata := make(map[string]interface{}, len(t.Metadata))
+		for k, v := range t.Metadata {
+			metadata[k] = v
+		}
+		trigger := map[string]interface{}{
+			"type":     t.Type,
+			"metadata": metadata,
+		}
+		if len(t.Name) != 0 {
+			trigger["name"] = t.Name
+		}
+		if len(t.AuthenticationRef) != 0 {
+			trigger["authenticationRef"] = map[string]interface{}{"name": t.AuthenticationRef}
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	scaledObjectSpec := map[string]interface{}{
+		"scaleTargetRef": map[string]interface{}{
+			"apiVersion": workload.GetAPIVersion(),
+			"kind":       workload.GetKind(),
+			"name":       workload.GetName(),
+		},
+		"maxReplicaCount": int64(spec.MaxReplicas),
+		"triggers":        triggers,
+	}
+	if spec.MinReplicas != nil {
+		scaledObjectSpec["minReplicaCount"] = int64(*spec.MinReplicas)
+	}
+	if spec.PollingInterval != nil {
+		scaledObjectSpec["pollingInterval"] = int64(*spec.PollingInterval)
+	}
+	if spec.CooldownPeriod != nil {
+		scaledObjectSpec["cooldownPeriod"] = int64(*spec.CooldownPeriod)
+	}
+	so := &unstructured.Unstructured{Object: map[string]interface{}{"spec": scaledObjectSpec}}
+	so.SetAPIVersion(scaledObjectAPIVersion)
+	so.SetKind(scaledObjectKind)
+	so.SetName(scaler.GetName())
+	so.SetNamespace(scaler.GetNamespace())
+	util.PassLabelAndAnnotation(scaler, so)
+	return so
+}
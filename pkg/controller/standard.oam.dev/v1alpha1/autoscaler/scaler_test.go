@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func newWorkload(replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": replicas},
+	}}
+}
+
+func newAutoscaler(spec v1alpha1.AutoscalerSpec) *v1alpha1.Autoscaler {
+	return &v1alpha1.Autoscaler{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "Autoscaler"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web-scaler", Namespace: "default", UID: "scaler-uid"},
+		Spec:       spec,
+	}
+}
+
+func TestEffectiveSpec(t *testing.T) {
+	now := time.Now()
+	scaler := newAutoscaler(v1alpha1.AutoscalerSpec{MinReplicas: pointer.Int32Ptr(2), MaxReplicas: 10})
+
+	spec, holder := effectiveSpec(scaler, newWorkload(4), now)
+	assert.Empty(t, holder)
+	assert.Equal(t, scaler.Spec, spec)
+
+	leased := newWorkload(4)
+	assert.NoError(t, util.AcquireWorkloadLease(leased, "AppRollout/default/web", now))
+	spec, holder = effectiveSpec(scaler, leased, now)
+	assert.Equal(t, "AppRollout/default/web", holder)
+	assert.Equal(t, int32(4), *spec.MinReplicas)
+	assert.Equal(t, int32(4), spec.MaxReplicas)
+	// the spec of the trait is kept
+	assert.Equal(t, int32(10), scaler.Spec.MaxReplicas)
+
+	// an expired lease doesn't suspend the autoscaler
+	_, holder = effectiveSpec(scaler, leased, now.Add(2*util.WorkloadLeaseDuration))
+	assert.Empty(t, holder)
+}
+
+func TestRenderHPA(t *testing.T) {
+	scaler := newAutoscaler(v1alpha1.AutoscalerSpec{
+		MinReplicas:       pointer.Int32Ptr(2),
+		MaxReplicas:       10,
+		CPUUtilization:    pointer.Int32Ptr(60),
+		MemoryUtilization: pointer.Int32Ptr(70),
+	})
+	assert.Equal(t, v1alpha1.HPAAutoscalerBackend, backendOf(scaler.Spec))
+	hpa := renderHPA(scaler, scaler.Spec, newWorkload(1))
+	assert.Equal(t, "web-scaler", hpa.Name)
+	assert.Equal(t, autoscalingv2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+		hpa.Spec.ScaleTargetRef)
+	assert.Equal(t, int32(2), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+	assert.Len(t, hpa.Spec.Metrics, 2)
+	assert.Equal(t, corev1.ResourceCPU, hpa.Spec.Metrics[0].Resource.Name)
+	assert.Equal(t, int32(60), *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization)
+	assert.Equal(t, corev1.ResourceMemory, hpa.Spec.Metrics[1].Resource.Name)
+}
+
+func TestRenderScaledObject(t *testing.T) {
+	scaler := newAutoscaler(v1alpha1.AutoscalerSpec{
+		MaxReplicas:     20,
+		CPUUtilization:  pointer.Int32Ptr(50),
+		PollingInterval: pointer.Int32Ptr(15),
+		Triggers: []v1alpha1.ScaleTrigger{{
+			Name:              "orders",
+			Type:              "kafka",
+			Metadata:          map[string]string{"topic": "orders", "lagThreshold": "50"},
+			AuthenticationRef: "kafka-auth",
+		}},
+	})
+	assert.Equal(t, v1alpha1.KEDAAutoscalerBackend, backendOf(scaler.Spec))
+	so := renderScaledObject(scaler, scaler.Spec, newWorkload(1))
+	assert.Equal(t, "keda.sh/v1alpha1", so.GetAPIVersion())
+	assert.Equal(t, "ScaledObject", so.GetKind())
+	assert.Equal(t, map[string]interface{}{
+		"scaleTargetRef":  map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+		"maxReplicaCount": int64(20),
+		"pollingInterval": int64(15),
+		"triggers": []interface{}{
+			map[string]interface{}{
+				"type":     "cpu",
+				"metadata": map[string]interface{}{"type": "Utilization", "value": "50"},
+			},
+			map[string]interface{}{
+				"name":              "orders",
+				"type":              "kafka",
+				"metadata":          map[string]interface{}{"topic": "orders", "lagThreshold": "50"},
+				"authenticationRef": map[string]interface{}{"name": "kafka-auth"},
+			},
+		},
+	}, so.Object["spec"])
+}
+
+func TestDeleteStaleScaler(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(s))
+	assert.NoError(t, v1alpha1.AddToScheme(s))
+	scaler := newAutoscaler(v1alpha1.AutoscalerSpec{MaxReplicas: 5})
+	owned := renderHPA(scaler, scaler.Spec, newWorkload(1))
+	assert.NoError(t, ctrl.SetControllerReference(scaler, owned, s))
+	c := fake.NewFakeClientWithScheme(s, owned)
+	r := &Reconciler{Client: c, Scheme: s, record: event.NewNopRecorder()}
+
+	// the HorizontalPodAutoscaler is kept while the workload is scaled by it
+	assert.NoError(t, r.deleteStaleScaler(context.Background(), scaler, v1alpha1.HPAAutoscalerBackend))
+	key := types.NamespacedName{Namespace: "default", Name: "web-scaler"}
+	assert.NoError(t, c.Get(context.Background(), key, &autoscalingv2beta2.HorizontalPodAutoscaler{}))
+
+	// it's removed once the workload is scaled by KEDA
+	assert.NoError(t, r.deleteStaleScaler(context.Background(), scaler, v1alpha1.KEDAAutoscalerBackend))
+	err := c.Get(context.Background(), key, &autoscalingv2beta2.HorizontalPodAutoscaler{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"cuelang.org/go/cue"
@@ -37,6 +38,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/appfile/gitsource"
 	"github.com/oam-dev/kubevela/pkg/appfile/helm"
 	mycue "github.com/oam-dev/kubevela/pkg/cue"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
@@ -61,8 +63,15 @@ type CapabilityComponentDefinition struct {
 	WorkloadType    util.WorkloadType `json:"workloadType"`
 	WorkloadDefName string            `json:"workloadDefName"`
 
-	Helm *commontypes.Helm `json:"helm"`
-	Kube *commontypes.Kube `json:"kube"`
+	Helm      *commontypes.Helm      `json:"helm"`
+	Kube      *commontypes.Kube      `json:"kube"`
+	Terraform *commontypes.Terraform `json:"terraform"`
+	Kustomize *commontypes.Kustomize `json:"kustomize"`
+	Git       *commontypes.Git       `json:"git"`
+	// HelmCredentials are the credentials of the OCI registry storing the chart of the Helm schematic
+	HelmCredentials *helm.Credentials `json:"-"`
+	// GitCredentials are the credentials of the repository of the Git schematic
+	GitCredentials *gitsource.Credentials `json:"-"`
 	CapabilityBaseDefinition
 }
 
@@ -82,6 +91,18 @@ func NewCapabilityComponentDef(componentDefinition *v1beta1.ComponentDefinition)
 		def.WorkloadType = util.KubeDef
 		def.Kube = componentDefinition.Spec.Schematic.KUBE
 	}
+	if componentDefinition.Spec.Schematic != nil && componentDefinition.Spec.Schematic.Terraform != nil {
+		def.WorkloadType = util.TerraformDef
+		def.Terraform = componentDefinition.Spec.Schematic.Terraform
+	}
+	if componentDefinition.Spec.Schematic != nil && componentDefinition.Spec.Schematic.Kustomize != nil {
+		def.WorkloadType = util.KustomizeDef
+		def.Kustomize = componentDefinition.Spec.Schematic.Kustomize
+	}
+	if componentDefinition.Spec.Schematic != nil && componentDefinition.Spec.Schematic.Git != nil {
+		def.WorkloadType = util.GitDef
+		def.Git = componentDefinition.Spec.Schematic.Git
+	}
 	def.ComponentDefinition = *componentDefinition.DeepCopy()
 	return def
 }
@@ -115,8 +136,9 @@ func GetKubeSchematicOpenAPISchema(params []commontypes.KubeParameter) ([]byte,
 			required = append(required, p.Name)
 		}
 
+		tmp.Title = p.Name
 		if p.Description != nil {
-			tmp.Description = fmt.Sprintf("%s %s", tmp.Description, *p.Description)
+			tmp.Description = strings.TrimSpace(*p.Description)
 		} else {
 			// save FieldPaths into description
 			tmp.Description = fmt.Sprintf("The value will be applied to fields: [%s].", strings.Join(p.FieldPaths, ","))
@@ -125,6 +147,7 @@ func GetKubeSchematicOpenAPISchema(params []commontypes.KubeParameter) ([]byte,
 	}
 	s := openapi3.NewObjectSchema().WithProperties(properties)
 	if len(required) > 0 {
+		sort.Strings(required)
 		s.Required = required
 	}
 	b, err := s.MarshalJSON()
@@ -134,23 +157,178 @@ func GetKubeSchematicOpenAPISchema(params []commontypes.KubeParameter) ([]byte,
 	return b, nil
 }
 
-// StoreOpenAPISchema stores OpenAPI v3 schema in ConfigMap from WorkloadDefinition
-func (def *CapabilityComponentDefinition) StoreOpenAPISchema(ctx context.Context, k8sClient client.Client,
-	pd *definition.PackageDiscover, namespace, name, revName string) (string, error) {
-	var jsonSchema []byte
-	var err error
+// GetTerraformConfigurationOpenAPISchema gets OpenAPI v3 schema based on the variables of the Terraform configuration,
+// besides which writeConnectionSecretToRef is accepted to specify the secret the outputs are written to
+func GetTerraformConfigurationOpenAPISchema(tf *commontypes.Terraform) ([]byte, error) {
+	mod, err := appfile.ParseTerraformModule(tf)
+	if err != nil {
+		return nil, err
+	}
+	required := []string{}
+	properties := map[string]*openapi3.Schema{}
+	for name, v := range mod.Variables {
+		tmp := terraformVariableSchema(v.Type)
+		tmp.Description = v.Description
+		if v.Default != nil {
+			tmp.Default = v.Default
+		}
+		if v.Required {
+			required = append(required, name)
+		}
+		properties[name] = tmp
+	}
+	secretRef := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"name":      openapi3.NewStringSchema(),
+		"namespace": openapi3.NewStringSchema(),
+	})
+	secretRef.Required = []string{"name"}
+	secretRef.Description = "The secret which the cloud resource connection will be written to"
+	properties[appfile.WriteConnectionSecretToRefKey] = secretRef
+	s := openapi3.NewObjectSchema().WithProperties(properties)
+	if len(required) > 0 {
+		sort.Strings(required)
+		s.Required = required
+	}
+	b, err := s.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal generated schema into json")
+	}
+	return b, nil
+}
+
+// terraformVariableSchema converts the type constraint of a Terraform variable, e.g., list(string), to the schema,
+// a variable without type constraint accepts any value
+func terraformVariableSchema(typ string) *openapi3.Schema {
+	switch {
+	case typ == "string":
+		return openapi3.NewStringSchema()
+	case typ == "number":
+		return openapi3.NewFloat64Schema()
+	case typ == "bool":
+		return openapi3.NewBoolSchema()
+	case strings.HasPrefix(typ, "list"), strings.HasPrefix(typ, "set"), strings.HasPrefix(typ, "tuple"):
+		return openapi3.NewArraySchema()
+	case strings.HasPrefix(typ, "map"), strings.HasPrefix(typ, "object"):
+		return openapi3.NewObjectSchema()
+	default:
+		return &openapi3.Schema{}
+	}
+}
+
+// GetKustomizeOpenAPISchema gets OpenAPI v3 schema of the properties of the Kustomize schematic, i.e., the patches
+// and images applied to the kustomize base
+func GetKustomizeOpenAPISchema() ([]byte, error) {
+	target := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"group":     openapi3.NewStringSchema(),
+		"version":   openapi3.NewStringSchema(),
+		"kind":      openapi3.NewStringSchema(),
+		"name":      openapi3.NewStringSchema(),
+		"namespace": openapi3.NewStringSchema(),
+	})
+	target.Required = []string{"kind", "name", "version"}
+	operation := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"op":    openapi3.NewStringSchema().WithEnum("add", "remove", "replace", "move", "copy", "test"),
+		"path":  openapi3.NewStringSchema(),
+		"from":  openapi3.NewStringSchema(),
+		"value": {},
+	})
+	operation.Required = []string{"op", "path"}
+	json6902 := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"target": target,
+		"patch":  openapi3.NewArraySchema().WithItems(operation),
+	})
+	json6902.Required = []string{"patch", "target"}
+	image := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"name":    openapi3.NewStringSchema(),
+		"newName": openapi3.NewStringSchema(),
+		"newTag":  openapi3.NewStringSchema(),
+		"digest":  openapi3.NewStringSchema(),
+	})
+	image.Required = []string{"name"}
+
+	strategicMerge := openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema())
+	strategicMerge.Description = "The strategic merge patches applied to the resources of the kustomize base"
+	patchesJSON6902 := openapi3.NewArraySchema().WithItems(json6902)
+	patchesJSON6902.Description = "The JSON patches applied to the resources of the kustomize base"
+	images := openapi3.NewArraySchema().WithItems(image)
+	images.Description = "The images overridden in the resources of the kustomize base"
+	s := openapi3.NewObjectSchema().WithProperties(map[string]*openapi3.Schema{
+		"patchesStrategicMerge": strategicMerge,
+		"patchesJson6902":       patchesJSON6902,
+		"images":                images,
+	})
+	b, err := s.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal generated schema into json")
+	}
+	return b, nil
+}
+
+// GetGitOpenAPISchema gets OpenAPI v3 schema of the parameter of the CUE template loaded by the Git schematic, it's an
+// empty object if the Git schematic loads raw manifests which take no properties
+func GetGitOpenAPISchema(ctx context.Context, g *commontypes.Git, cred *gitsource.Credentials, pd *definition.PackageDiscover, name string) ([]byte, error) {
+	content, err := gitsource.DefaultLoader.Load(ctx, g, cred)
+	if err != nil {
+		return nil, err
+	}
+	if len(content.Template) == 0 {
+		b, err := openapi3.NewObjectSchema().MarshalJSON()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot marshal generated schema into json")
+		}
+		return b, nil
+	}
+	return getOpenAPISchema(types.Capability{Name: name, CueTemplate: content.Template}, pd)
+}
+
+// GenerateOpenAPISchema generates OpenAPI v3 schema of the parameters of the ComponentDefinition for all the
+// schematic types, i.e., from the values schema of the Helm chart, the parameters of the Kube schematic, the variables
+// of the Terraform configuration, the properties of the Kustomize schematic, the parameter of the CUE template loaded
+// by the Git schematic or the parameter of the CUE template
+func (def *CapabilityComponentDefinition) GenerateOpenAPISchema(ctx context.Context, pd *definition.PackageDiscover, name string) ([]byte, error) {
 	switch def.WorkloadType {
 	case util.HELMDef:
-		jsonSchema, err = helm.GetChartValuesJSONSchema(ctx, def.Helm)
+		return helm.GetChartValuesJSONSchema(ctx, def.Helm, def.HelmCredentials)
 	case util.KubeDef:
-		jsonSchema, err = GetKubeSchematicOpenAPISchema(def.Kube.Parameters)
+		return GetKubeSchematicOpenAPISchema(def.Kube.Parameters)
+	case util.TerraformDef:
+		return GetTerraformConfigurationOpenAPISchema(def.Terraform)
+	case util.KustomizeDef:
+		return GetKustomizeOpenAPISchema()
+	case util.GitDef:
+		return GetGitOpenAPISchema(ctx, def.Git, def.GitCredentials, pd, name)
 	default:
-		jsonSchema, err = def.GetOpenAPISchema(pd, name)
+		return def.GetOpenAPISchema(pd, name)
+	}
+}
+
+// StoreOpenAPISchema stores OpenAPI v3 schema in ConfigMap from WorkloadDefinition
+func (def *CapabilityComponentDefinition) StoreOpenAPISchema(ctx context.Context, k8sClient client.Client,
+	pd *definition.PackageDiscover, namespace, name, revName string) (string, error) {
+	if def.WorkloadType == util.HELMDef && def.HelmCredentials == nil && helm.IsOCIRepository(def.Helm) {
+		// the credentials of the OCI registry are loaded from the namespace of the definition
+		cred, err := helm.LoadRepositoryCredentials(ctx, k8sClient, def.ComponentDefinition.Namespace, def.Helm)
+		if err != nil {
+			return "", err
+		}
+		def.HelmCredentials = cred
 	}
+	if def.WorkloadType == util.GitDef && def.GitCredentials == nil && def.Git != nil {
+		cred, err := gitsource.LoadCredentials(ctx, k8sClient, def.ComponentDefinition.Namespace, def.Git)
+		if err != nil {
+			return "", err
+		}
+		def.GitCredentials = cred
+	}
+	jsonSchema, err := def.GenerateOpenAPISchema(ctx, pd, name)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
 	}
 	componentDefinition := def.ComponentDefinition
+	localized, err := LocalizeOpenAPISchemas(jsonSchema, componentDefinition.Annotations)
+	if err != nil {
+		return "", fmt.Errorf("failed to localize OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
+	}
 	ownerReference := []metav1.OwnerReference{{
 		APIVersion:         componentDefinition.APIVersion,
 		Kind:               componentDefinition.Kind,
@@ -159,12 +337,12 @@ func (def *CapabilityComponentDefinition) StoreOpenAPISchema(ctx context.Context
 		Controller:         pointer.BoolPtr(true),
 		BlockOwnerDeletion: pointer.BoolPtr(true),
 	}}
-	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, componentDefinition.Name, jsonSchema, ownerReference)
+	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, componentDefinition.Name, jsonSchema, localized, ownerReference)
 	if err != nil {
 		return cmName, err
 	}
 
-	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, revName, jsonSchema, ownerReference)
+	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, revName, jsonSchema, localized, ownerReference)
 	if err != nil {
 		return cmName, err
 	}
@@ -204,21 +382,29 @@ func (def *CapabilityTraitDefinition) GetOpenAPISchema(pd *definition.PackageDis
 	return getOpenAPISchema(capability, pd)
 }
 
-// StoreOpenAPISchema stores OpenAPI v3 schema from TraitDefinition in ConfigMap
-func (def *CapabilityTraitDefinition) StoreOpenAPISchema(ctx context.Context, k8sClient client.Client, pd *definition.PackageDiscover, namespace, name string, revName string) (string, error) {
-	var jsonSchema []byte
-	var err error
+// GenerateOpenAPISchema generates OpenAPI v3 schema of the parameters of the TraitDefinition for all the schematic
+// types, i.e., from the parameters of the Kube schematic or the parameter of the CUE template
+func (def *CapabilityTraitDefinition) GenerateOpenAPISchema(pd *definition.PackageDiscover, name string) ([]byte, error) {
 	switch def.DefCategoryType {
 	case util.KubeDef: // Kube template
-		jsonSchema, err = GetKubeSchematicOpenAPISchema(def.Kube.Parameters)
+		return GetKubeSchematicOpenAPISchema(def.Kube.Parameters)
 	default: // CUE  template
-		jsonSchema, err = def.GetOpenAPISchema(pd, name)
+		return def.GetOpenAPISchema(pd, name)
 	}
+}
+
+// StoreOpenAPISchema stores OpenAPI v3 schema from TraitDefinition in ConfigMap
+func (def *CapabilityTraitDefinition) StoreOpenAPISchema(ctx context.Context, k8sClient client.Client, pd *definition.PackageDiscover, namespace, name string, revName string) (string, error) {
+	jsonSchema, err := def.GenerateOpenAPISchema(pd, name)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
 	}
 
 	traitDefinition := def.TraitDefinition
+	localized, err := LocalizeOpenAPISchemas(jsonSchema, traitDefinition.Annotations)
+	if err != nil {
+		return "", fmt.Errorf("failed to localize OpenAPI v3 JSON schema for capability %s: %w", def.Name, err)
+	}
 	ownerReference := []metav1.OwnerReference{{
 		APIVersion:         traitDefinition.APIVersion,
 		Kind:               traitDefinition.Kind,
@@ -227,13 +413,13 @@ func (def *CapabilityTraitDefinition) StoreOpenAPISchema(ctx context.Context, k8
 		Controller:         pointer.BoolPtr(true),
 		BlockOwnerDeletion: pointer.BoolPtr(true),
 	}}
-	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, traitDefinition.Name, jsonSchema, ownerReference)
+	cmName, err := def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, traitDefinition.Name, jsonSchema, localized, ownerReference)
 	if err != nil {
 		return cmName, err
 	}
 	def.TraitDefinition.Status.ConfigMapRef = cmName
 
-	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, revName, jsonSchema, ownerReference)
+	_, err = def.CreateOrUpdateConfigMap(ctx, k8sClient, namespace, revName, jsonSchema, localized, ownerReference)
 	if err != nil {
 		return cmName, err
 	}
@@ -244,14 +430,18 @@ func (def *CapabilityTraitDefinition) StoreOpenAPISchema(ctx context.Context, k8
 type CapabilityBaseDefinition struct {
 }
 
-// CreateOrUpdateConfigMap creates ConfigMap to store OpenAPI v3 schema or or updates data in ConfigMap
+// CreateOrUpdateConfigMap creates ConfigMap to store OpenAPI v3 schema or or updates data in ConfigMap, the schemas
+// localized are stored along with it keyed by the locales
 func (def *CapabilityBaseDefinition) CreateOrUpdateConfigMap(ctx context.Context, k8sClient client.Client, namespace,
-	definitionName string, jsonSchema []byte, ownerReferences []metav1.OwnerReference) (string, error) {
+	definitionName string, jsonSchema []byte, localized map[string][]byte, ownerReferences []metav1.OwnerReference) (string, error) {
 	cmName := fmt.Sprintf("%s%s", types.CapabilityConfigMapNamePrefix, definitionName)
 	var cm v1.ConfigMap
 	var data = map[string]string{
 		types.OpenapiV3JSONSchema: string(jsonSchema),
 	}
+	for locale, schema := range localized {
+		data[types.LocalizedOpenapiV3JSONSchema(locale)] = string(schema)
+	}
 	// No need to check the existence of namespace, if it doesn't exist, API server will return the error message
 	// before it's to be reconciled by ComponentDefinition/TraitDefinition controller.
 	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cmName}, &cm)
@@ -311,27 +501,33 @@ func generateOpenAPISchemaFromCapabilityParameter(capability types.Capability, p
 	if err != nil {
 		return nil, err
 	}
+	cueInst, err := buildCUEInstance(template, pd)
+	if err != nil {
+		return nil, err
+	}
+	return common.GenOpenAPI(cueInst)
+}
 
+// buildCUEInstance builds the template along with the base template, the CUE packages of the CRDs in the cluster
+// are imported if pd is not nil
+func buildCUEInstance(template string, pd *definition.PackageDiscover) (*cue.Instance, error) {
 	template += mycue.BaseTemplate
 	if pd == nil {
 		var r cue.Runtime
-		cueInst, err := r.Compile("-", template)
-		if err != nil {
-			return nil, err
-		}
-		return common.GenOpenAPI(cueInst)
+		return r.Compile("-", template)
 	}
 	bi := build.NewContext().NewInstance("", nil)
-	err = bi.AddFile("-", template)
-	if err != nil {
+	if err := bi.AddFile("-", template); err != nil {
 		return nil, err
 	}
+	return pd.ImportPackagesAndBuildInstance(bi)
+}
 
-	cueInst, err := pd.ImportPackagesAndBuildInstance(bi)
-	if err != nil {
-		return nil, err
-	}
-	return common.GenOpenAPI(cueInst)
+// CompileCUETemplate checks whether the CUE template of a definition compiles, the CUE packages of the CRDs in
+// the cluster are imported if pd is not nil
+func CompileCUETemplate(pd *definition.PackageDiscover, template string) error {
+	_, err := buildCUEInstance(template, pd)
+	return err
 }
 
 // GenerateOpenAPISchemaFromDefinition returns the parameter of a definition
@@ -343,6 +539,12 @@ func GenerateOpenAPISchemaFromDefinition(definitionName, cueTemplate string) ([]
 	return generateOpenAPISchemaFromCapabilityParameter(capability, nil)
 }
 
+// GenerateParameterSchema returns the OpenAPI schema of the parameter of the CUE template of a definition, the CUE
+// packages of the CRDs in the cluster are imported if pd is not nil
+func GenerateParameterSchema(pd *definition.PackageDiscover, definitionName, cueTemplate string) ([]byte, error) {
+	return getOpenAPISchema(types.Capability{Name: definitionName, CueTemplate: cueTemplate}, pd)
+}
+
 // prepareParameterCue cuts `parameter` section form definition .cue file
 func prepareParameterCue(capabilityName, capabilityTemplate string) (string, error) {
 	var template string
@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -186,3 +187,60 @@ func TestGenerateOpenAPISchemaFromCapabilityParameter(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTerraformConfigurationOpenAPISchema(t *testing.T) {
+	hcl := `
+variable "bucket" {
+  type        = string
+  description = "OSS bucket name"
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+`
+	b, err := GetTerraformConfigurationOpenAPISchema(&common.Terraform{Configuration: hcl})
+	assert.NilError(t, err)
+	schema := &openapi3.Schema{}
+	assert.NilError(t, json.Unmarshal(b, schema))
+	assert.DeepEqual(t, []string{"bucket"}, schema.Required)
+	assert.Equal(t, "string", schema.Properties["bucket"].Value.Type)
+	assert.Equal(t, "OSS bucket name", schema.Properties["bucket"].Value.Description)
+	assert.Equal(t, "object", schema.Properties["tags"].Value.Type)
+	assert.Equal(t, "object", schema.Properties[appfile.WriteConnectionSecretToRefKey].Value.Type)
+
+	_, err = GetTerraformConfigurationOpenAPISchema(&common.Terraform{})
+	assert.Assert(t, err != nil)
+}
+
+func TestGetKubeSchematicOpenAPISchema(t *testing.T) {
+	required := true
+	description := "Number of replicas"
+	b, err := GetKubeSchematicOpenAPISchema([]common.KubeParameter{
+		{Name: "replicas", ValueType: common.NumberType, FieldPaths: []string{"spec.replicas"}, Required: &required, Description: &description},
+		{Name: "image", ValueType: common.StringType, FieldPaths: []string{"spec.template.spec.containers[0].image"}, Required: &required},
+		{Name: "debug", ValueType: common.BooleanType, FieldPaths: []string{"spec.debug"}},
+	})
+	assert.NilError(t, err)
+	schema := &openapi3.Schema{}
+	assert.NilError(t, json.Unmarshal(b, schema))
+	assert.DeepEqual(t, []string{"image", "replicas"}, schema.Required)
+	assert.Equal(t, "number", schema.Properties["replicas"].Value.Type)
+	assert.Equal(t, "replicas", schema.Properties["replicas"].Value.Title)
+	assert.Equal(t, "Number of replicas", schema.Properties["replicas"].Value.Description)
+	assert.Equal(t, "The value will be applied to fields: [spec.template.spec.containers[0].image].", schema.Properties["image"].Value.Description)
+	assert.Equal(t, "boolean", schema.Properties["debug"].Value.Type)
+}
+
+func TestGetKustomizeOpenAPISchema(t *testing.T) {
+	b, err := GetKustomizeOpenAPISchema()
+	assert.NilError(t, err)
+	schema := &openapi3.Schema{}
+	assert.NilError(t, json.Unmarshal(b, schema))
+	assert.Equal(t, "array", schema.Properties["patchesStrategicMerge"].Value.Type)
+	patch := schema.Properties["patchesJson6902"].Value.Items.Value
+	assert.DeepEqual(t, []string{"patch", "target"}, patch.Required)
+	assert.DeepEqual(t, []string{"kind", "name", "version"}, patch.Properties["target"].Value.Required)
+	assert.DeepEqual(t, []string{"name"}, schema.Properties["images"].Value.Items.Value.Required)
+}
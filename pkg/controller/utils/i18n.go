@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+// localeRegexp matches the BCP 47 language tags used as locales, e.g., zh, en or zh-CN
+var localeRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// LocalizedText is the localized title and description of a parameter
+type LocalizedText struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ParseLocalizedTexts parses the localized texts of the parameters from the annotations of a definition, they're
+// keyed by the locale and then by the path of the parameter
+func ParseLocalizedTexts(annotations map[string]string) (map[string]map[string]LocalizedText, error) {
+	texts := map[string]map[string]LocalizedText{}
+	for k, v := range annotations {
+		if !strings.HasPrefix(k, types.AnnI18nPrefix) {
+			continue
+		}
+		locale := strings.TrimPrefix(k, types.AnnI18nPrefix)
+		if !localeRegexp.MatchString(locale) {
+			return nil, errors.Errorf("invalid locale %q in annotation %s", locale, k)
+		}
+		t := map[string]LocalizedText{}
+		if err := yaml.UnmarshalStrict([]byte(v), &t); err != nil {
+			return nil, errors.Wrapf(err, "invalid annotation %s", k)
+		}
+		texts[locale] = t
+	}
+	return texts, nil
+}
+
+// LocalizeOpenAPISchemas localizes the OpenAPI v3 JSON schema of the parameters of a definition with the texts in
+// the annotations of it, the schemas are keyed by the locale. The properties are addressed by their paths joined
+// by dots, the items of arrays are transparent in the paths, e.g., ports.port is the port of an item of the ports
+// array. The titles and descriptions not localized, and the paths not in the schema, are left as is.
+func LocalizeOpenAPISchemas(jsonSchema []byte, annotations map[string]string) (map[string][]byte, error) {
+	texts, err := ParseLocalizedTexts(annotations)
+	if err != nil || len(texts) == 0 {
+		return nil, err
+	}
+	schemas := make(map[string][]byte, len(texts))
+	for locale, t := range texts {
+		// the schema is decoded for each locale as it's localized in place
+		schema := map[string]interface{}{}
+		if err := json.Unmarshal(jsonSchema, &schema); err != nil {
+			return nil, errors.Wrap(err, "invalid OpenAPI v3 JSON schema")
+		}
+		localizeProperties(schema, "", t)
+		b, err := json.Marshal(schema)
+		if err != nil {
+			return nil, err
+		}
+		schemas[locale] = b
+	}
+	return schemas, nil
+}
+
+func localizeSchema(schema map[string]interface{}, path string, texts map[string]LocalizedText) {
+	if t, ok := texts[path]; ok {
+		if len(t.Title) != 0 {
+			schema["title"] = t.Title
+		}
+		if len(t.Description) != 0 {
+			schema["description"] = t.Description
+		}
+	}
+	localizeProperties(schema, path, texts)
+}
+
+func localizeProperties(schema map[string]interface{}, path string, texts map[string]LocalizedText) {
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, prop := range props {
+			p, ok := prop.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if len(path) == 0 {
+				localizeSchema(p, name, texts)
+			} else {
+				localizeSchema(p, path+"."+name, texts)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		localizeProperties(items, path, texts)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+func TestLocalizeOpenAPISchemas(t *testing.T) {
+	schema := []byte(`{
+  "type": "object",
+  "properties": {
+    "image": {"type": "string", "title": "image", "description": "Which image would you like to use"},
+    "ports": {
+      "type": "array",
+      "title": "ports",
+      "items": {"type": "object", "properties": {"port": {"type": "integer", "title": "port"}}}
+    }
+  }
+}`)
+	annotations := map[string]string{
+		types.AnnDescription: "Describes long-running services",
+		types.AnnI18nPrefix + "zh": `
+image:
+  title: 镜像
+  description: 容器镜像
+ports.port:
+  description: 端口
+unknown:
+  title: 未知
+`,
+	}
+	schemas, err := LocalizeOpenAPISchemas(schema, annotations)
+	assert.NilError(t, err)
+	assert.Equal(t, len(schemas), 1)
+	var zh map[string]interface{}
+	assert.NilError(t, json.Unmarshal(schemas["zh"], &zh))
+	props := zh["properties"].(map[string]interface{})
+	image := props["image"].(map[string]interface{})
+	assert.Equal(t, image["title"], "镜像")
+	assert.Equal(t, image["description"], "容器镜像")
+	ports := props["ports"].(map[string]interface{})
+	assert.Equal(t, ports["title"], "ports")
+	port := ports["items"].(map[string]interface{})["properties"].(map[string]interface{})["port"].(map[string]interface{})
+	assert.Equal(t, port["title"], "port")
+	assert.Equal(t, port["description"], "端口")
+
+	schemas, err = LocalizeOpenAPISchemas(schema, map[string]string{types.AnnDescription: "no texts"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(schemas), 0)
+
+	_, err = LocalizeOpenAPISchemas(schema, map[string]string{types.AnnI18nPrefix + "zh_CN": "image: {title: 镜像}"})
+	assert.ErrorContains(t, err, "invalid locale")
+	_, err = LocalizeOpenAPISchemas(schema, map[string]string{types.AnnI18nPrefix + "zh": "image: {name: 镜像}"})
+	assert.ErrorContains(t, err, "invalid annotation")
+}
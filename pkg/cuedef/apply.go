@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cuedef
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyResult is the result of applying a definition
+type ApplyResult string
+
+// Results of applying a definition
+const (
+	ApplyResultCreated   ApplyResult = "created"
+	ApplyResultUpdated   ApplyResult = "updated"
+	ApplyResultUnchanged ApplyResult = "unchanged"
+)
+
+// Apply creates the definition in the namespace or updates the existing one. With dryRun, the request is sent to the
+// API server in the dry-run mode, so the definition is validated by the admission webhooks without being persisted.
+func Apply(ctx context.Context, c client.Client, def *unstructured.Unstructured, namespace string, dryRun bool) (ApplyResult, error) {
+	obj := def.DeepCopy()
+	obj.SetNamespace(namespace)
+	var opts []client.CreateOption
+	var updateOpts []client.UpdateOption
+	if dryRun {
+		opts = append(opts, client.DryRunAll)
+		updateOpts = append(updateOpts, client.DryRunAll)
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: obj.GetName()}, live)
+	switch {
+	case apierrors.IsNotFound(err):
+		if err := c.Create(ctx, obj, opts...); err != nil {
+			return "", errors.Wrapf(err, "cannot create %s %s", obj.GetKind(), obj.GetName())
+		}
+		return ApplyResultCreated, nil
+	case err != nil:
+		return "", errors.Wrapf(err, "cannot get %s %s", obj.GetKind(), obj.GetName())
+	}
+
+	// the labels and annotations added by others are kept
+	obj.SetLabels(merge(live.GetLabels(), obj.GetLabels()))
+	obj.SetAnnotations(merge(live.GetAnnotations(), obj.GetAnnotations()))
+	if equality.Semantic.DeepEqual(live.Object["spec"], obj.Object["spec"]) &&
+		equality.Semantic.DeepEqual(live.GetLabels(), obj.GetLabels()) &&
+		equality.Semantic.DeepEqual(live.GetAnnotations(), obj.GetAnnotations()) {
+		return ApplyResultUnchanged, nil
+	}
+	obj.SetResourceVersion(live.GetResourceVersion())
+	if status, ok := live.Object["status"]; ok {
+		obj.Object["status"] = status
+	}
+	if err := c.Update(ctx, obj, updateOpts...); err != nil {
+		return "", errors.Wrapf(err, "cannot update %s %s", obj.GetKind(), obj.GetName())
+	}
+	return ApplyResultUpdated, nil
+}
+
+func merge(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
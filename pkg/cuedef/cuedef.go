@@ -0,0 +1,358 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cuedef converts definitions between the CRD YAML and a single CUE file, which is easier to author as the
+// template is written in CUE directly rather than embedded in a YAML string. A def file has the metadata of the
+// definition in a field named after it, and the CUE template in the template field:
+//
+//	import "strings"
+//
+//	webservice: {
+//		type:        "component"
+//		description: "Describes long-running services"
+//		labels: {}
+//		annotations: {}
+//		attributes: workload: definition: {
+//			apiVersion: "apps/v1"
+//			kind:       "Deployment"
+//		}
+//	}
+//	template: {
+//		output: {...}
+//		parameter: {...}
+//	}
+//
+// The attributes are the spec of the definition except the schematic, and the imports belong to the template.
+package cuedef
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+const (
+	// TemplateFieldName is the field of the CUE template in a def file
+	TemplateFieldName = "template"
+	// FileExtension is the extension of def files
+	FileExtension = ".cue"
+)
+
+// Types of definitions in def files
+const (
+	TypeComponent    = "component"
+	TypeTrait        = "trait"
+	TypePolicy       = "policy"
+	TypeWorkflowStep = "workflow-step"
+)
+
+var kindOfType = map[string]string{
+	TypeComponent:    v1beta1.ComponentDefinitionKind,
+	TypeTrait:        v1beta1.TraitDefinitionKind,
+	TypePolicy:       v1beta1.PolicyDefinitionKind,
+	TypeWorkflowStep: v1beta1.WorkflowStepDefinitionKind,
+}
+
+// ignoredAnnotations are not kept in def files as they're maintained by the clients
+var ignoredAnnotations = map[string]bool{
+	types.AnnDescription: true,
+	"kubectl.kubernetes.io/last-applied-configuration": true,
+}
+
+// header is the metadata of the definition in a def file
+type header struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Types returns the types of definitions supported in def files
+func Types() []string {
+	list := make([]string, 0, len(kindOfType))
+	for t := range kindOfType {
+		list = append(list, t)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// KindOf returns the kind of the definitions of the type in def files
+func KindOf(defType string) (string, error) {
+	kind, ok := kindOfType[defType]
+	if !ok {
+		return "", errors.Errorf("unsupported definition type %q, must be one of %s", defType, strings.Join(Types(), ", "))
+	}
+	return kind, nil
+}
+
+// TypeOf returns the type in def files of the definitions of the kind
+func TypeOf(kind string) (string, error) {
+	for t, k := range kindOfType {
+		if k == kind {
+			return t, nil
+		}
+	}
+	return "", errors.Errorf("%s is not a definition", kind)
+}
+
+// FromCUE converts a def file to the definition
+func FromCUE(src string) (*unstructured.Unstructured, error) {
+	f, err := parser.ParseFile("-", src, parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid def file")
+	}
+	var name, imports string
+	var meta ast.Expr
+	var template *ast.StructLit
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.ImportDecl:
+			imports += src[d.Pos().Offset():d.End().Offset()] + "\n"
+		case *ast.CommentGroup, *ast.Attribute:
+		case *ast.Field:
+			label, _, err := ast.LabelName(d.Label)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid def file")
+			}
+			if label == TemplateFieldName {
+				st, ok := d.Value.(*ast.StructLit)
+				if !ok {
+					return nil, errors.Errorf("the %s field of def file must be a struct", TemplateFieldName)
+				}
+				template = st
+				continue
+			}
+			if len(name) != 0 {
+				return nil, errors.Errorf("def file can only have one definition, found %s and %s", name, label)
+			}
+			name, meta = label, d.Value
+		default:
+			return nil, errors.Errorf("unexpected declaration at %s of def file, only imports, the definition and the %s are allowed",
+				decl.Pos(), TemplateFieldName)
+		}
+	}
+	if len(name) == 0 {
+		return nil, errors.New("def file has no definition")
+	}
+	if template == nil {
+		return nil, errors.Errorf("definition %s has no %s", name, TemplateFieldName)
+	}
+
+	h, err := decodeHeader(name, meta)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := KindOf(h.Type)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "definition %s", name)
+	}
+	fields, err := templateFields(src, template)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of definition %s", name)
+	}
+	body, err := formatTemplate(imports + fields)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of definition %s", name)
+	}
+
+	def := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	def.SetAPIVersion(v1beta1.SchemeGroupVersion.String())
+	def.SetKind(kind)
+	def.SetName(name)
+	if len(h.Labels) != 0 {
+		def.SetLabels(h.Labels)
+	}
+	annotations := h.Annotations
+	if len(h.Description) != 0 {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[types.AnnDescription] = h.Description
+	}
+	if len(annotations) != 0 {
+		def.SetAnnotations(annotations)
+	}
+	spec := h.Attributes
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	spec["schematic"] = map[string]interface{}{"cue": map[string]interface{}{"template": body}}
+	def.Object["spec"] = spec
+	// decode the definition again so the numbers are int64 rather than float64 as the ones got from the cluster
+	data, err := json.Marshal(def.Object)
+	if err != nil {
+		return nil, err
+	}
+	normalized := &unstructured.Unstructured{}
+	if err := normalized.UnmarshalJSON(data); err != nil {
+		return nil, errors.Wrapf(err, "invalid definition %s", name)
+	}
+	return normalized, nil
+}
+
+func decodeHeader(name string, meta ast.Expr) (*header, error) {
+	b, err := format.Node(meta)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid definition %s", name)
+	}
+	var r cue.Runtime
+	inst, err := r.Compile(name, "header: "+string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid definition %s", name)
+	}
+	data, err := inst.Lookup("header").MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrapf(err, "definition %s must be concrete", name)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	h := &header{}
+	if err := dec.Decode(h); err != nil {
+		return nil, errors.Wrapf(err, "invalid definition %s", name)
+	}
+	return h, nil
+}
+
+// ToCUE converts the definition to a def file, only the definitions with CUE templates are supported
+func ToCUE(def *unstructured.Unstructured) (string, error) {
+	defType, err := TypeOf(def.GetKind())
+	if err != nil {
+		return "", err
+	}
+	template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+	if len(template) == 0 {
+		return "", errors.Errorf("%s %s has no CUE template", def.GetKind(), def.GetName())
+	}
+	h := header{Type: defType, Labels: def.GetLabels(), Annotations: map[string]string{}}
+	for k, v := range def.GetAnnotations() {
+		if !ignoredAnnotations[k] {
+			h.Annotations[k] = v
+		}
+	}
+	h.Description = def.GetAnnotations()[types.AnnDescription]
+	spec, _, _ := unstructured.NestedMap(def.Object, "spec")
+	delete(spec, "schematic")
+	if len(spec) != 0 {
+		h.Attributes = spec
+	}
+	meta, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := parser.ParseFile("-", template, parser.ParseComments, parser.ImportsOnly)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid template of %s %s", def.GetKind(), def.GetName())
+	}
+	var imports, body string
+	body = template
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.ImportDecl); ok {
+			imports = template[:d.End().Offset()]
+			body = template[d.End().Offset():]
+		}
+	}
+	src := fmt.Sprintf("%s\n\n%s: %s\n%s: {\n%s\n}\n", imports, strconv.Quote(def.GetName()), meta, TemplateFieldName, body)
+	formatted, err := formatSource(src)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid template of %s %s", def.GetKind(), def.GetName())
+	}
+	return formatted, nil
+}
+
+// templateFields returns the source of the fields of the template, the braces are omitted in the shorthand such as
+// template: parameter: {}, the fields are formatted from the node then
+func templateFields(src string, template *ast.StructLit) (string, error) {
+	if !template.Lbrace.IsValid() || !template.Rbrace.IsValid() {
+		b, err := format.Node(&ast.File{Decls: template.Elts}, format.Simplify())
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	start, end := template.Lbrace.Offset()+1, template.Rbrace.Offset()
+	if start > end || end > len(src) {
+		return "", errors.Errorf("unexpected braces of the template at %s", template.Pos())
+	}
+	return src[start:end], nil
+}
+
+func formatTemplate(src string) (string, error) {
+	formatted, err := formatSource(src)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(formatted) + "\n", nil
+}
+
+func formatSource(src string) (string, error) {
+	f, err := parser.ParseFile("-", src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	b, err := format.Node(f, format.Simplify())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// LoadFile loads a definition from a def file ending with FileExtension, or a YAML file with only the definition
+func LoadFile(path string) (*unstructured.Unstructured, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) == FileExtension {
+		def, err := FromCUE(string(data))
+		return def, errors.WithMessagef(err, "cannot load %s", path)
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load %s", path)
+	}
+	def := &unstructured.Unstructured{}
+	if err := def.UnmarshalJSON(data); err != nil {
+		return nil, errors.Wrapf(err, "cannot load %s", path)
+	}
+	if _, err := TypeOf(def.GetKind()); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load %s", path)
+	}
+	return def, nil
+}
+
+// ToYAML converts the definition to YAML
+func ToYAML(def *unstructured.Unstructured) ([]byte, error) {
+	return yaml.Marshal(def.Object)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cuedef
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestConvert(t *testing.T) {
+	for _, defType := range Types() {
+		src, err := Init("my-"+defType, defType, "my "+defType)
+		require.NoError(t, err)
+		def, err := FromCUE(src)
+		require.NoError(t, err, src)
+		kind, _ := KindOf(defType)
+		assert.Equal(t, kind, def.GetKind())
+		assert.Equal(t, "my-"+defType, def.GetName())
+		assert.Equal(t, "my "+defType, def.GetAnnotations()[types.AnnDescription])
+		template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+		assert.Contains(t, template, "parameter:")
+		require.NoError(t, Vet(def, nil), template)
+
+		converted, err := ToCUE(def)
+		require.NoError(t, err)
+		again, err := FromCUE(converted)
+		require.NoError(t, err, converted)
+		assert.Equal(t, def, again)
+	}
+
+	def, err := FromCUE(`import "strings"
+
+"upper": {
+	type: "trait"
+	labels: "custom.oam.dev/team": "web"
+	attributes: podDisruptive: true
+}
+template: {
+	// the name in upper case
+	outputs: cm: {
+		apiVersion: "v1"
+		kind:       "ConfigMap"
+		data: name: strings.ToUpper(context.name)
+	}
+	parameter: {}
+}
+`)
+	require.NoError(t, err)
+	assert.Equal(t, v1beta1.TraitDefinitionKind, def.GetKind())
+	assert.Equal(t, map[string]string{"custom.oam.dev/team": "web"}, def.GetLabels())
+	disruptive, _, _ := unstructured.NestedBool(def.Object, "spec", "podDisruptive")
+	assert.True(t, disruptive)
+	template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+	assert.Contains(t, template, `import "strings"`)
+	assert.Contains(t, template, "// the name in upper case")
+	src, err := ToCUE(def)
+	require.NoError(t, err)
+	assert.Regexp(t, `^import "strings"`, src)
+	assert.Contains(t, src, `podDisruptive: true`)
+}
+
+func TestFromCUEErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"no definition": `template: {}`,
+		"no template":   `a: type: "trait"`,
+		"two definitions": `a: type: "trait"
+b: type: "trait"
+template: {}`,
+		"unknown type":  `a: type: "workload"` + "\ntemplate: {}",
+		"unknown field": `a: {type: "trait", spec: {}}` + "\ntemplate: {}",
+		"not concrete":  `a: type: string` + "\ntemplate: {}",
+	} {
+		_, err := FromCUE(src)
+		assert.Error(t, err, name)
+	}
+}
+
+func loadComponent(t *testing.T) *unstructured.Unstructured {
+	src, err := Init("worker", TypeComponent, "")
+	require.NoError(t, err)
+	def, err := FromCUE(src)
+	require.NoError(t, err)
+	return def
+}
+
+func TestVet(t *testing.T) {
+	def := loadComponent(t)
+	unstructured.RemoveNestedField(def.Object, "spec", "workload")
+	assert.Error(t, Vet(def, nil))
+
+	def = loadComponent(t)
+	require.NoError(t, unstructured.SetNestedField(def.Object, "parameter: {}", "spec", "schematic", "cue", "template"))
+	assert.Error(t, Vet(def, nil))
+}
+
+func TestValidateParameters(t *testing.T) {
+	def := loadComponent(t)
+	assert.NoError(t, ValidateParameters(def, map[string]interface{}{"image": "nginx"}, nil))
+	assert.Error(t, ValidateParameters(def, nil, nil))
+	assert.Error(t, ValidateParameters(def, map[string]interface{}{"image": 1}, nil))
+	err := ValidateParameters(def, map[string]interface{}{"image": "nginx", "port": 80}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "port")
+}
+
+func TestRender(t *testing.T) {
+	pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+	require.NoError(t, err)
+	comp := loadComponent(t)
+	objs, err := Render(comp, RenderOptions{Name: "web", Parameter: map[string]interface{}{"image": "nginx"}}, pd)
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	assert.Equal(t, "Deployment", objs[0].GetKind())
+	image, _, _ := unstructured.NestedSlice(objs[0].Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "nginx", image[0].(map[string]interface{})["image"])
+	_, err = Render(comp, RenderOptions{}, pd)
+	assert.Error(t, err)
+
+	src, err := Init("scaler", TypeTrait, "")
+	require.NoError(t, err)
+	trait, err := FromCUE(src)
+	require.NoError(t, err)
+	_, err = Render(trait, RenderOptions{Parameter: map[string]interface{}{"replicas": 3}}, pd)
+	assert.Error(t, err, "a workload is required to patch")
+	objs, err = Render(trait, RenderOptions{Parameter: map[string]interface{}{"replicas": 3}, Workload: objs[0]}, pd)
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(objs[0].Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(common.Scheme)
+	def := loadComponent(t)
+	result, err := Apply(ctx, c, def, types.DefaultKubeVelaNS, false)
+	require.NoError(t, err)
+	assert.Equal(t, ApplyResultCreated, result)
+	result, err = Apply(ctx, c, def, types.DefaultKubeVelaNS, false)
+	require.NoError(t, err)
+	assert.Equal(t, ApplyResultUnchanged, result)
+
+	require.NoError(t, unstructured.SetNestedField(def.Object, "StatefulSet", "spec", "workload", "definition", "kind"))
+	result, err = Apply(ctx, c, def, types.DefaultKubeVelaNS, false)
+	require.NoError(t, err)
+	assert.Equal(t, ApplyResultUpdated, result)
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(def.GroupVersionKind())
+	require.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: types.DefaultKubeVelaNS, Name: def.GetName()}, live))
+	kind, _, _ := unstructured.NestedString(live.Object, "spec", "workload", "definition", "kind")
+	assert.Equal(t, "StatefulSet", kind)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cuedef
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// scaffolds are the attributes and templates of the def files initialized for each type
+var scaffolds = map[string]struct{ attributes, template string }{
+	TypeComponent: {
+		attributes: `workload: definition: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+}`,
+		template: `output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: {
+		selector: matchLabels: "app.oam.dev/component": context.name
+		template: {
+			metadata: labels: "app.oam.dev/component": context.name
+			spec: containers: [{
+				name:  context.name
+				image: parameter.image
+			}]
+		}
+	}
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	image: string
+}`,
+	},
+	TypeTrait: {
+		attributes: `appliesToWorkloads: ["deployments.apps"]`,
+		template: `patch: spec: replicas: parameter.replicas
+parameter: {
+	// +usage=Specify the number of workload
+	replicas: *1 | int
+}`,
+	},
+	TypePolicy: {
+		template: `parameter: {}`,
+	},
+	TypeWorkflowStep: {
+		template: `parameter: {}`,
+	},
+}
+
+// Init generates a def file of the type with a sample template to start with
+func Init(name, defType, description string) (string, error) {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return "", errors.Errorf("invalid definition name %q: %v", name, errs)
+	}
+	if _, err := KindOf(defType); err != nil {
+		return "", err
+	}
+	s := scaffolds[defType]
+	meta := fmt.Sprintf("type: %q\ndescription: %s\n", defType, strconv.Quote(description))
+	if len(s.attributes) != 0 {
+		meta += "attributes: {\n" + s.attributes + "\n}\n"
+	}
+	src := fmt.Sprintf("%s: {\n%s}\n%s: {\n%s\n}\n", strconv.Quote(name), meta, TemplateFieldName, s.template)
+	return formatSource(src)
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cuedef
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	mycue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+// DefaultRenderNamespace is the namespace the examples are rendered in if not specified
+const DefaultRenderNamespace = "default"
+
+// validationContextFile declares the context as an open struct, so the template can be evaluated without an
+// application
+const validationContextFile = "context: {}"
+
+var (
+	parameterRegexp = regexp.MustCompile(`(?m)^\s*parameter:`)
+	patchRegexp     = regexp.MustCompile(`(?m)^\s*patch:`)
+)
+
+var definitionTypes = map[string]common.DefinitionType{
+	TypeComponent:    common.ComponentType,
+	TypeTrait:        common.TraitType,
+	TypePolicy:       common.PolicyType,
+	TypeWorkflowStep: common.WorkflowStepType,
+}
+
+func templateOf(def *unstructured.Unstructured) (string, error) {
+	template, _, _ := unstructured.NestedString(def.Object, "spec", "schematic", "cue", "template")
+	if len(template) == 0 {
+		return "", errors.Errorf("%s %s has no CUE template", def.GetKind(), def.GetName())
+	}
+	return template, nil
+}
+
+// Vet checks the definition before it's applied: the name, the blocks of the template required by the type, the
+// workload of a component, and whether the schema of the parameter can be generated. The packages discovered from
+// the cluster are imported if pd is not nil.
+func Vet(def *unstructured.Unstructured, pd *definition.PackageDiscover) error {
+	defType, err := TypeOf(def.GetKind())
+	if err != nil {
+		return err
+	}
+	if errs := validation.IsDNS1123Subdomain(def.GetName()); len(errs) != 0 {
+		return errors.Errorf("invalid name %q of %s: %s", def.GetName(), def.GetKind(), strings.Join(errs, ", "))
+	}
+	template, err := templateOf(def)
+	if err != nil {
+		return err
+	}
+	if err := definition.ValidateTemplate(pd, definitionTypes[defType], template); err != nil {
+		return errors.WithMessagef(err, "%s %s", def.GetKind(), def.GetName())
+	}
+	if defType == TypeComponent {
+		workloadType, _, _ := unstructured.NestedString(def.Object, "spec", "workload", "type")
+		workload, _, _ := unstructured.NestedMap(def.Object, "spec", "workload", "definition")
+		if len(workloadType) == 0 && len(workload) == 0 {
+			return errors.Errorf("%s %s must specify its workload by either the type or the definition", def.GetKind(), def.GetName())
+		}
+	}
+	if parameterRegexp.MatchString(template) {
+		if _, err := utils.GenerateParameterSchema(pd, def.GetName(), template); err != nil {
+			return errors.WithMessagef(err, "cannot generate the schema of the parameter of %s %s", def.GetKind(), def.GetName())
+		}
+	}
+	return nil
+}
+
+// buildTemplate builds the template with the parameter file, the template is built alone if it's empty
+func buildTemplate(pd *definition.PackageDiscover, template, parameterFile string) (*cue.Instance, error) {
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", template); err != nil {
+		return nil, errors.WithMessage(err, "invalid cue template")
+	}
+	if err := bi.AddFile("context", validationContextFile); err != nil {
+		return nil, err
+	}
+	if len(parameterFile) != 0 {
+		if err := bi.AddFile("parameter", parameterFile); err != nil {
+			return nil, err
+		}
+	}
+	if pd != nil {
+		return pd.ImportPackagesAndBuildInstance(bi)
+	}
+	var r cue.Runtime
+	return r.Build(bi)
+}
+
+// ValidateParameters checks the parameters against the parameter block of the template of the definition, they're
+// invalid if any of them conflicts with the template or is unknown, or any parameter required is missing
+func ValidateParameters(def *unstructured.Unstructured, params map[string]interface{}, pd *definition.PackageDiscover) error {
+	template, err := templateOf(def)
+	if err != nil {
+		return err
+	}
+	inst, err := buildTemplate(pd, template, "")
+	if err != nil {
+		return errors.WithMessagef(err, "cannot compile the template of %s %s", def.GetKind(), def.GetName())
+	}
+	schema := inst.Lookup(mycue.ParameterTag)
+	if !schema.Exists() {
+		if len(params) != 0 {
+			return errors.Errorf("%s %s has no parameter", def.GetKind(), def.GetName())
+		}
+		return nil
+	}
+	// the parameters are unknown unless the parameter block declares them or accepts any fields
+	if schema.Template() == nil {
+		known := map[string]bool{}
+		iter, err := schema.Fields(cue.Optional(true))
+		if err != nil {
+			return errors.WithMessagef(err, "invalid parameter block of %s %s", def.GetKind(), def.GetName())
+		}
+		for iter.Next() {
+			known[iter.Label()] = true
+		}
+		var unknown []string
+		for k := range params {
+			if !known[k] {
+				unknown = append(unknown, k)
+			}
+		}
+		if len(unknown) != 0 {
+			sort.Strings(unknown)
+			return errors.Errorf("unknown parameters of %s %s: %s", def.GetKind(), def.GetName(), strings.Join(unknown, ", "))
+		}
+	}
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	inst, err = buildTemplate(pd, template, mycue.ParameterTag+": "+string(data))
+	if err != nil {
+		return errors.WithMessagef(err, "invalid parameters of %s %s", def.GetKind(), def.GetName())
+	}
+	if err := inst.Lookup(mycue.ParameterTag).Validate(cue.Concrete(true)); err != nil {
+		return errors.WithMessagef(err, "invalid parameters of %s %s", def.GetKind(), def.GetName())
+	}
+	return nil
+}
+
+// RenderOptions are the component the definition is rendered for
+type RenderOptions struct {
+	// Name is the name of the component, i.e., context.name, default is the name of the definition
+	Name string
+	// Namespace is the namespace of the component, i.e., context.namespace, default is DefaultRenderNamespace
+	Namespace string
+	Parameter map[string]interface{}
+	// Workload is the workload a trait is applied to, it's required if the trait patches the workload
+	Workload *unstructured.Unstructured
+}
+
+// Render renders an example of the resources a component or trait definition outputs with the parameters, after
+// the parameters are validated. The workload of a component, or the workload patched by a trait, comes first and the
+// resources in the outputs follow. pd must not be nil, definition.NewPackageDiscoverFromSnapshot creates one without
+// a cluster.
+func Render(def *unstructured.Unstructured, opts RenderOptions, pd *definition.PackageDiscover) ([]*unstructured.Unstructured, error) {
+	defType, err := TypeOf(def.GetKind())
+	if err != nil {
+		return nil, err
+	}
+	template, err := templateOf(def)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateParameters(def, opts.Parameter, pd); err != nil {
+		return nil, err
+	}
+	name, namespace := opts.Name, opts.Namespace
+	if len(name) == 0 {
+		name = def.GetName()
+	}
+	if len(namespace) == 0 {
+		namespace = DefaultRenderNamespace
+	}
+	ctx := process.NewContext(namespace, name, name, name+"-v1")
+
+	switch defType {
+	case TypeComponent:
+		if err := definition.NewWorkloadAbstractEngine(def.GetName(), pd).Complete(ctx, template, opts.Parameter); err != nil {
+			return nil, err
+		}
+	case TypeTrait:
+		if opts.Workload != nil {
+			data, err := json.Marshal(opts.Workload.Object)
+			if err != nil {
+				return nil, err
+			}
+			var r cue.Runtime
+			inst, err := r.Compile("workload", string(data))
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid workload")
+			}
+			base, err := model.NewBase(inst.Value())
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid workload")
+			}
+			if err := ctx.SetBase(base); err != nil {
+				return nil, err
+			}
+		} else if patchRegexp.MatchString(template) {
+			return nil, errors.Errorf("a workload is required to render %s %s as it patches the workload", def.GetKind(), def.GetName())
+		}
+		if err := definition.NewTraitAbstractEngine(def.GetName(), pd).Complete(ctx, template, opts.Parameter); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("only component and trait definitions can be rendered, %s %s is a %s", def.GetKind(), def.GetName(), defType)
+	}
+
+	var objs []*unstructured.Unstructured
+	base, auxiliaries := ctx.Output()
+	if base != nil {
+		obj, err := base.Unstructured()
+		if err != nil {
+			return nil, errors.WithMessage(err, "the output is not concrete")
+		}
+		objs = append(objs, obj)
+	}
+	for _, aux := range auxiliaries {
+		obj, err := aux.Ins.Unstructured()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "the output %s is not concrete", aux.Name)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debug persists the render intermediates of the applications annotated by oam.AnnotationDebug to ConfigMaps,
+// i.e., the CUE context, parameter and template of the components and the workflow steps, the inputs and outputs of
+// the steps and the manifests rendered, so `vela debug` can inspect them and re-evaluate the templates.
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// keys of the data of the debug snapshot ConfigMaps
+const (
+	// KeyType is the type of the component
+	KeyType = "type"
+	// KeyContext is the base CUE context of the component template in json
+	KeyContext = "context.json"
+	// KeyParameter is the parameter of the component template in json
+	KeyParameter = "parameter.json"
+	// KeyTemplate is the CUE template of the component definition
+	KeyTemplate = "template.cue"
+	// KeyManifests is the workload and traits rendered from the component in yaml
+	KeyManifests = "manifests.yaml"
+	// KeySteps is the snapshots of the workflow steps in json
+	KeySteps = "steps.json"
+	// KeyErrors is the errors of the reconciliation keyed by the condition types in json
+	KeyErrors = "errors.json"
+)
+
+// values of oam.LabelDebugSnapshot
+const (
+	snapshotWorkflow  = "workflow"
+	snapshotComponent = "component"
+)
+
+// Enabled returns true if the debug snapshots of the application are enabled by oam.AnnotationDebug
+func Enabled(app *v1beta1.Application) bool {
+	return app.GetAnnotations()[oam.AnnotationDebug] == "true"
+}
+
+// Context is the base context of a template, the secrets required by the component are never persisted
+type Context struct {
+	Name        string `json:"name"`
+	AppName     string `json:"appName"`
+	AppRevision string `json:"appRevision,omitempty"`
+	Namespace   string `json:"namespace"`
+}
+
+// ProcessContext returns the process context to evaluate a template in
+func (c Context) ProcessContext() process.Context {
+	return process.NewContext(c.Namespace, c.Name, c.AppName, c.AppRevision)
+}
+
+// ComponentSnapshot is the render intermediates of a component
+type ComponentSnapshot struct {
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type,omitempty"`
+	Context   Context                `json:"context"`
+	Parameter map[string]interface{} `json:"parameter,omitempty"`
+	// Template is the CUE template of the component definition, it's empty for the other schematics
+	Template string `json:"template,omitempty"`
+	// Manifests are the workload and traits rendered, it's empty if the component fails to render
+	Manifests []*unstructured.Unstructured `json:"manifests,omitempty"`
+}
+
+// StepSnapshot is the render intermediates of a workflow step
+type StepSnapshot struct {
+	Name    string                   `json:"name"`
+	Type    string                   `json:"type"`
+	Phase   common.WorkflowStepPhase `json:"phase,omitempty"`
+	Message string                   `json:"message,omitempty"`
+	Context Context                  `json:"context"`
+	// Properties are the properties of the step with the inputs set
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// Inputs are the values imported into the properties keyed by the parameter keys
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// Outputs are the values exported by the step keyed by the output names
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Template is the CUE template of the WorkflowStepDefinition, it's empty for the built-in steps
+	Template string         `json:"template,omitempty"`
+	SubSteps []StepSnapshot `json:"subSteps,omitempty"`
+}
+
+// Snapshot is the render intermediates of an application
+type Snapshot struct {
+	Components []*ComponentSnapshot `json:"components,omitempty"`
+	Steps      []StepSnapshot       `json:"steps,omitempty"`
+	// Errors are the messages of the failed conditions keyed by the condition types
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Component returns the snapshot of the component, nil if it's not found
+func (s *Snapshot) Component(name string) *ComponentSnapshot {
+	for _, c := range s.Components {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Step returns the snapshot of the workflow step, the sub-steps are named as STEP/SUB_STEP. It's nil if not found.
+func (s *Snapshot) Step(name string) *StepSnapshot {
+	parent, sub := name, ""
+	if i := strings.Index(name, "/"); i >= 0 {
+		parent, sub = name[:i], name[i+1:]
+	}
+	for i := range s.Steps {
+		if s.Steps[i].Name != parent {
+			continue
+		}
+		if len(sub) == 0 {
+			return &s.Steps[i]
+		}
+		for j := range s.Steps[i].SubSteps {
+			if s.Steps[i].SubSteps[j].Name == sub {
+				return &s.Steps[i].SubSteps[j]
+			}
+		}
+	}
+	return nil
+}
+
+// WorkflowConfigMapName returns the name of the ConfigMap of the workflow snapshot of the application
+func WorkflowConfigMapName(appName string) string {
+	return appName + "-debug-workflow"
+}
+
+// ComponentConfigMapName returns the name of the ConfigMap of the snapshot of the component
+func ComponentConfigMapName(appName, compName string) string {
+	return appName + "-debug-component-" + compName
+}
+
+// ConfigMaps returns the ConfigMaps the snapshot is persisted to, they're owned by the application. The secrets in
+// the parameters and the manifests are redacted.
+func (s *Snapshot) ConfigMaps(app *v1beta1.Application) ([]*corev1.ConfigMap, error) {
+	steps, err := json.Marshal(s.Steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal the snapshots of workflow steps")
+	}
+	errs, err := json.Marshal(s.Errors)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal the errors")
+	}
+	cms := []*corev1.ConfigMap{newConfigMap(app, WorkflowConfigMapName(app.Name), snapshotWorkflow, "", map[string]string{
+		KeySteps:  string(steps),
+		KeyErrors: string(errs),
+	})}
+	for _, c := range s.Components {
+		ctx, err := json.Marshal(c.Context)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshal the context of component %s", c.Name)
+		}
+		parameter, err := json.Marshal(c.Parameter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshal the parameter of component %s", c.Name)
+		}
+		var manifests []string
+		for _, m := range c.Manifests {
+			b, err := yaml.Marshal(m.Object)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot marshal the manifests of component %s", c.Name)
+			}
+			manifests = append(manifests, string(b))
+		}
+		cms = append(cms, newConfigMap(app, ComponentConfigMapName(app.Name, c.Name), snapshotComponent, c.Name, map[string]string{
+			KeyType:      c.Type,
+			KeyContext:   string(ctx),
+			KeyParameter: string(parameter),
+			KeyTemplate:  c.Template,
+			KeyManifests: strings.Join(manifests, "---\n"),
+		}))
+	}
+	return cms, nil
+}
+
+func newConfigMap(app *v1beta1.Application, name, snapshot, component string, data map[string]string) *corev1.ConfigMap {
+	labels := map[string]string{oam.LabelAppName: app.Name, oam.LabelDebugSnapshot: snapshot}
+	if len(component) != 0 {
+		labels[oam.LabelAppComponent] = component
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: app.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1beta1.SchemeGroupVersion.String(),
+				Kind:       v1beta1.ApplicationKind,
+				Name:       app.Name,
+				UID:        app.UID,
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Data: data,
+	}
+}
+
+// ListConfigMaps lists the debug snapshot ConfigMaps of the application
+func ListConfigMaps(ctx context.Context, c client.Reader, app *v1beta1.Application) ([]corev1.ConfigMap, error) {
+	// client.HasLabels replaces the selector of client.MatchingLabels instead of narrowing it down, so both are
+	// combined into one selector
+	snapshot, err := labels.NewRequirement(oam.LabelDebugSnapshot, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(labels.Set{oam.LabelAppName: app.Name}).Add(*snapshot)
+	cms := &corev1.ConfigMapList{}
+	if err := c.List(ctx, cms, client.InNamespace(app.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, errors.Wrapf(err, "cannot list the debug snapshots of application %s", app.Name)
+	}
+	return cms.Items, nil
+}
+
+// Load loads the snapshot of the application from its ConfigMaps, it returns NotFound if the snapshot is not
+// persisted, e.g., the application is not annotated by oam.AnnotationDebug
+func Load(ctx context.Context, c client.Reader, app *v1beta1.Application) (*Snapshot, error) {
+	cms, err := ListConfigMaps(ctx, c, app)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	found := false
+	for _, cm := range cms {
+		switch cm.Labels[oam.LabelDebugSnapshot] {
+		case snapshotWorkflow:
+			found = true
+			if err := json.Unmarshal([]byte(cm.Data[KeySteps]), &s.Steps); err != nil {
+				return nil, errors.Wrapf(err, "invalid workflow snapshot %s", cm.Name)
+			}
+			if err := json.Unmarshal([]byte(cm.Data[KeyErrors]), &s.Errors); err != nil {
+				return nil, errors.Wrapf(err, "invalid workflow snapshot %s", cm.Name)
+			}
+		case snapshotComponent:
+			comp, err := loadComponent(cm)
+			if err != nil {
+				return nil, err
+			}
+			s.Components = append(s.Components, comp)
+		}
+	}
+	if !found {
+		return nil, &NotFoundError{App: app.Name}
+	}
+	sort.Slice(s.Components, func(i, j int) bool { return s.Components[i].Name < s.Components[j].Name })
+	return s, nil
+}
+
+func loadComponent(cm corev1.ConfigMap) (*ComponentSnapshot, error) {
+	comp := &ComponentSnapshot{Name: cm.Labels[oam.LabelAppComponent], Type: cm.Data[KeyType], Template: cm.Data[KeyTemplate]}
+	if err := json.Unmarshal([]byte(cm.Data[KeyContext]), &comp.Context); err != nil {
+		return nil, errors.Wrapf(err, "invalid context in component snapshot %s", cm.Name)
+	}
+	if err := json.Unmarshal([]byte(cm.Data[KeyParameter]), &comp.Parameter); err != nil {
+		return nil, errors.Wrapf(err, "invalid parameter in component snapshot %s", cm.Name)
+	}
+	for _, doc := range strings.Split(cm.Data[KeyManifests], "---\n") {
+		if len(strings.TrimSpace(doc)) == 0 {
+			continue
+		}
+		m := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &m.Object); err != nil {
+			return nil, errors.Wrapf(err, "invalid manifests in component snapshot %s", cm.Name)
+		}
+		comp.Manifests = append(comp.Manifests, m)
+	}
+	return comp, nil
+}
+
+// NotFoundError is returned if the snapshot of the application is not persisted
+type NotFoundError struct {
+	App string
+}
+
+func (e *NotFoundError) Error() string {
+	return "no debug snapshot of application " + e.App + " is found, annotate it with " + oam.AnnotationDebug + "=true and wait for it to reconcile"
+}
+
+// IsNotFound returns true if the error is NotFoundError
+func IsNotFound(err error) bool {
+	var nf *NotFoundError
+	return errors.As(err, &nf)
+}
+
+// Eval re-evaluates the template with the context and the parameter, it fails if the template is empty, e.g., the
+// component is not defined by CUE or the step is a built-in one. The evaluated values have the secrets redacted.
+func Eval(pd *definition.PackageDiscover, typ, template string, ctx Context, parameter map[string]interface{}) (*definition.TemplateDebugInfo, error) {
+	if len(template) == 0 {
+		return nil, errors.Errorf("%s has no CUE template to evaluate", typ)
+	}
+	return definition.DebugWorkloadTemplate(ctx.ProcessContext(), pd, typ, template, parameter)
+}
+
+// Redact returns a copy of the value with the secrets redacted, see definition.RedactSensitiveFields
+func Redact(v map[string]interface{}) map[string]interface{} {
+	b, err := json.Marshal(v)
+	if err != nil || v == nil {
+		return nil
+	}
+	cp := map[string]interface{}{}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil
+	}
+	definition.RedactSensitiveFields(cp)
+	return cp
+}
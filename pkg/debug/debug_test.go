@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestConfigMapsAndLoad(t *testing.T) {
+	ctx := context.Background()
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{
+		Name:        "app",
+		Namespace:   "default",
+		UID:         "uid",
+		Annotations: map[string]string{oam.AnnotationDebug: "true"},
+	}}
+	assert.True(t, Enabled(app))
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme)
+
+	_, err := Load(ctx, c, app)
+	assert.True(t, IsNotFound(err))
+
+	snapshot := &Snapshot{
+		Components: []*ComponentSnapshot{{
+			Name:      "frontend",
+			Type:      "webservice",
+			Context:   Context{Name: "frontend", AppName: "app", AppRevision: "app-v1", Namespace: "default"},
+			Parameter: map[string]interface{}{"image": "nginx"},
+			Template:  "output: {}",
+			Manifests: []*unstructured.Unstructured{
+				{Object: map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": map[string]interface{}{"name": "frontend"}}},
+				{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Service", "metadata": map[string]interface{}{"name": "frontend"}}},
+			},
+		}},
+		Steps: []StepSnapshot{{
+			Name:    "deploy",
+			Type:    "step-group",
+			Phase:   common.WorkflowStepPhaseFailed,
+			Outputs: map[string]string{"endpoint": "http://frontend"},
+			SubSteps: []StepSnapshot{{
+				Name:       "deploy-prod",
+				Type:       "deploy2env",
+				Properties: map[string]interface{}{"env": "prod"},
+				Inputs:     map[string]string{"endpoint": "http://frontend"},
+			}},
+		}},
+		Errors: map[string]string{"Rendered": "cannot render"},
+	}
+	cms, err := snapshot.ConfigMaps(app)
+	assert.NoError(t, err)
+	assert.Len(t, cms, 2)
+	assert.Equal(t, "app-debug-workflow", cms[0].Name)
+	assert.Equal(t, "app-debug-component-frontend", cms[1].Name)
+	assert.Equal(t, "frontend", cms[1].Labels[oam.LabelAppComponent])
+	assert.Equal(t, app.UID, cms[1].OwnerReferences[0].UID)
+	for _, cm := range cms {
+		assert.NoError(t, c.Create(ctx, cm))
+	}
+
+	loaded, err := Load(ctx, c, app)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Steps, loaded.Steps)
+	assert.Equal(t, snapshot.Errors, loaded.Errors)
+	comp := loaded.Component("frontend")
+	assert.NotNil(t, comp)
+	assert.Equal(t, "webservice", comp.Type)
+	assert.Equal(t, snapshot.Components[0].Context, comp.Context)
+	assert.Equal(t, snapshot.Components[0].Parameter, comp.Parameter)
+	assert.Equal(t, "output: {}", comp.Template)
+	assert.Len(t, comp.Manifests, 2)
+	assert.Equal(t, "Service", comp.Manifests[1].GetKind())
+	assert.Nil(t, loaded.Component("backend"))
+
+	listed, err := ListConfigMaps(ctx, c, &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}})
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func TestSnapshotStep(t *testing.T) {
+	s := &Snapshot{Steps: []StepSnapshot{
+		{Name: "apply", Type: "apply-component"},
+		{Name: "deploy", Type: "step-group", SubSteps: []StepSnapshot{{Name: "deploy-prod", Type: "deploy2env"}}},
+	}}
+	assert.Equal(t, "apply-component", s.Step("apply").Type)
+	assert.Equal(t, "step-group", s.Step("deploy").Type)
+	assert.Equal(t, "deploy2env", s.Step("deploy/deploy-prod").Type)
+	assert.Nil(t, s.Step("deploy/deploy-staging"))
+	assert.Nil(t, s.Step("notify"))
+}
+
+func TestRedact(t *testing.T) {
+	v := map[string]interface{}{
+		"image":    "nginx",
+		"password": "123456",
+		"env":      []interface{}{map[string]interface{}{"name": "DB", "dbPassword": "123456"}},
+	}
+	redacted := Redact(v)
+	assert.Equal(t, map[string]interface{}{
+		"image":    "nginx",
+		"password": definition.RedactedValue,
+		"env":      []interface{}{map[string]interface{}{"name": "DB", "dbPassword": definition.RedactedValue}},
+	}, redacted)
+	// the value redacted is a copy
+	assert.Equal(t, "123456", v["password"])
+	assert.Nil(t, Redact(nil))
+}
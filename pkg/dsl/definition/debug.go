@@ -0,0 +1,213 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"regexp"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/format"
+
+	mycue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+// RedactedValue replaces the secret values in TemplateDebugInfo
+const RedactedValue = "<redacted>"
+
+// sensitiveKey matches the fields holding secrets by their names, e.g., password, dbPassword or api_key
+var sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|token|secret|credentials?|private[-_]?key|api[-_]?key)$`)
+
+// builtinContextKeys are the fields of the context which are not secrets, the others are the data of the
+// secrets required by the component
+var builtinContextKeys = map[string]bool{
+	process.ContextName:           true,
+	process.ContextAppName:        true,
+	process.ContextAppRevision:    true,
+	process.ContextAppRevisionNum: true,
+	process.ContextNamespace:      true,
+	process.OutputFieldName:       true,
+	process.OutputsFieldName:      true,
+	process.ConfigFieldName:       true,
+	process.OutputSecretName:      true,
+}
+
+// TemplateDebugInfo is the evaluated values of a definition template with the parameters and the context, it helps
+// definition authors to find out why an expression is evaluated unexpectedly. The values not concrete are shown in
+// CUE syntax and the secrets are replaced with RedactedValue.
+type TemplateDebugInfo struct {
+	Context   map[string]interface{} `json:"context"`
+	Parameter map[string]interface{} `json:"parameter"`
+	Output    interface{}            `json:"output,omitempty"`
+	Outputs   map[string]interface{} `json:"outputs,omitempty"`
+	// Values are the other top-level fields of the template, including the hidden ones holding intermediate values
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Error is the error of evaluating the template, the values are evaluated as far as possible anyway
+	Error string `json:"error,omitempty"`
+}
+
+// DebugWorkloadTemplate evaluates the template of the workload definition with the parameters and the context, and
+// returns all the evaluated values. It only fails if the template cannot be compiled, an invalid template is
+// reported in TemplateDebugInfo.Error.
+func DebugWorkloadTemplate(ctx process.Context, pd *PackageDiscover, name, abstractTemplate string, params interface{}) (*TemplateDebugInfo, error) {
+	wd := &workloadDef{def: def{name: name, pd: pd}}
+	inst, err := wd.buildInstance(ctx, abstractTemplate, params)
+	if err != nil {
+		return nil, err
+	}
+	info := &TemplateDebugInfo{
+		Context:   map[string]interface{}{},
+		Parameter: map[string]interface{}{},
+		Outputs:   map[string]interface{}{},
+		Values:    map[string]interface{}{},
+	}
+	if err := inst.Value().Validate(); err != nil {
+		info.Error = err.Error()
+	}
+	iter, err := inst.Value().Fields(cue.Hidden(true))
+	if err != nil {
+		return nil, err
+	}
+	for iter.Next() {
+		v := debugValue(iter.Value())
+		switch label := iter.Label(); label {
+		case "context":
+			info.Context, _ = v.(map[string]interface{})
+		case mycue.ParameterTag:
+			info.Parameter, _ = v.(map[string]interface{})
+		case OutputFieldName:
+			info.Output = v
+		case OutputsFieldName:
+			info.Outputs, _ = v.(map[string]interface{})
+		default:
+			info.Values[label] = v
+		}
+	}
+	info.redactSecrets()
+	return info, nil
+}
+
+// debugValue converts the cue value into go values as far as possible, the values not concrete or invalid are
+// kept in CUE syntax
+func debugValue(v cue.Value) interface{} {
+	if err := v.Err(); err != nil {
+		return "_|_ // " + err.Error()
+	}
+	switch v.IncompleteKind() {
+	case cue.StructKind:
+		iter, err := v.Fields(cue.Hidden(true))
+		if err != nil {
+			break
+		}
+		m := map[string]interface{}{}
+		for iter.Next() {
+			m[iter.Label()] = debugValue(iter.Value())
+		}
+		return m
+	case cue.ListKind:
+		iter, err := v.List()
+		if err != nil {
+			break
+		}
+		l := []interface{}{}
+		for iter.Next() {
+			l = append(l, debugValue(iter.Value()))
+		}
+		return l
+	default:
+		if v.IsConcrete() {
+			var x interface{}
+			if err := v.Decode(&x); err == nil {
+				return x
+			}
+		}
+	}
+	b, err := format.Node(v.Syntax())
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// redactSecrets replaces the data of the secrets required by the component, the data of the rendered Secrets and
+// the fields named as secrets with RedactedValue
+func (info *TemplateDebugInfo) redactSecrets() {
+	for k := range info.Context {
+		if !builtinContextKeys[k] {
+			info.Context[k] = RedactedValue
+			// the required secrets are also available at the top level of the template
+			if _, ok := info.Values[k]; ok {
+				info.Values[k] = RedactedValue
+			}
+		}
+	}
+	for _, m := range []map[string]interface{}{info.Context, info.Parameter, info.Outputs, info.Values} {
+		RedactSensitiveFields(m)
+	}
+	info.Output = RedactSensitiveFields(info.Output)
+}
+
+// RedactSensitiveFields replaces the scalar fields named as secrets and the data of the Secrets in the value with
+// RedactedValue in place, the structure of the value is kept. The value is returned for convenience.
+func RedactSensitiveFields(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		isSecret := x["kind"] == "Secret"
+		for k, field := range x {
+			switch {
+			case isSecret && (k == "data" || k == "stringData"):
+				x[k] = redactLeaves(field)
+			case sensitiveKey.MatchString(k) && isLeaf(field):
+				x[k] = RedactedValue
+			default:
+				x[k] = RedactSensitiveFields(field)
+			}
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = RedactSensitiveFields(x[i])
+		}
+	}
+	return v
+}
+
+// redactLeaves replaces all the scalar values in the value with RedactedValue
+func redactLeaves(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k := range x {
+			x[k] = redactLeaves(x[k])
+		}
+		return x
+	case []interface{}:
+		for i := range x {
+			x[i] = redactLeaves(x[i])
+		}
+		return x
+	default:
+		return RedactedValue
+	}
+}
+
+func isLeaf(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestDebugWorkloadTemplate(t *testing.T) {
+	template := `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: context.name
+	spec: replicas: _replicas
+}
+outputs: secret: {
+	apiVersion: "v1"
+	kind:       "Secret"
+	metadata: name: context.name
+	stringData: password: parameter.dbPassword
+}
+_replicas: parameter.replicas * 2
+image: parameter.image
+parameter: {
+	replicas:   *1 | int
+	image:      string
+	dbPassword: string
+}
+`
+	ctx := process.NewContext("default", "test", "myapp", "myapp-v1")
+	ctx.InsertSecrets("", []process.RequiredSecrets{{Name: "db-conn", ContextName: "dbConn", Data: map[string]interface{}{"url": "mysql://"}}})
+	info, err := DebugWorkloadTemplate(ctx, &PackageDiscover{}, "test", template, map[string]interface{}{"replicas": 2, "dbPassword": "p@ss"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "test", info.Context[process.ContextName])
+	assert.Equal(t, "myapp-v1", info.Context[process.ContextAppRevision])
+	assert.Equal(t, RedactedValue, info.Context["dbConn"])
+	assert.Equal(t, RedactedValue, info.Values["dbConn"])
+	assert.Equal(t, RedactedValue, info.Parameter["dbPassword"])
+	assert.Contains(t, info.Parameter, "replicas")
+	// the intermediate values are shown, and the values not concrete are kept in CUE syntax
+	assert.Contains(t, info.Values, "_replicas")
+	assert.Contains(t, info.Values["image"], "string")
+	output, ok := info.Output.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "Deployment", output["kind"])
+	secret, ok := info.Outputs["secret"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "Secret", secret["kind"])
+	assert.Equal(t, map[string]interface{}{"password": RedactedValue}, secret["stringData"])
+
+	_, err = DebugWorkloadTemplate(ctx, &PackageDiscover{}, "test", "output: {", nil)
+	assert.Error(t, err)
+}
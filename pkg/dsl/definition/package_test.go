@@ -427,6 +427,24 @@ output: v1.#Bucket
 	base, err = model.NewBase(inst.Value())
 	assert.NilError(t, err)
 	assert.Equal(t, base.String(), exceptObj)
+
+	// render the same object from the snapshot of the packages
+	tmpl := `
+import "test.io/apps/v1"
+output: v1.#Bucket
+`
+	snapshot, err := mypd.SnapshotPackages(tmpl)
+	assert.NilError(t, err)
+	assert.Equal(t, len(snapshot), 2)
+	snapshotPD, err := NewPackageDiscoverFromSnapshot(snapshot)
+	assert.NilError(t, err)
+	bi = build.NewContext().NewInstance("", nil)
+	bi.AddFile("-", tmpl)
+	inst, err = snapshotPD.ImportPackagesAndBuildInstance(bi)
+	assert.NilError(t, err)
+	base, err = model.NewBase(inst.Value())
+	assert.NilError(t, err)
+	assert.Equal(t, base.String(), exceptObj)
 }
 
 func TestProcessFile(t *testing.T) {
@@ -0,0 +1,244 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+)
+
+// SnapshotPackages returns the CUE sources of the built-in packages imported by the templates, keyed by import paths.
+// The packages are pruned to the definitions referenced by the templates, e.g., the package of the kube OpenAPI
+// schema only contains the definitions of the kinds used and the definitions they refer to.
+// The snapshot can be loaded by NewPackageDiscoverFromSnapshot, so the templates are rendered with exactly the
+// same packages even if the OpenAPI schema of the cluster changes later.
+func (pd *PackageDiscover) SnapshotPackages(templates ...string) (map[string]string, error) {
+	refs := make(map[string]map[string]bool)
+	for _, tmpl := range templates {
+		if len(tmpl) == 0 {
+			continue
+		}
+		f, err := parser.ParseFile("-", tmpl)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse the template")
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid import path %s", spec.Path.Value)
+			}
+			name := filepath.Base(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if refs[path] == nil {
+				refs[path] = make(map[string]bool)
+			}
+			collectSelectors([]*ast.File{f}, name, nil, refs[path])
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	pd.mutex.RLock()
+	defer pd.mutex.RUnlock()
+	snapshot := make(map[string]string)
+	deps := make(map[string]*build.Instance)
+	depRefs := make(map[string]map[string]bool)
+	for _, inst := range pd.velaBuiltinPackages {
+		names, ok := refs[inst.ImportPath]
+		if !ok {
+			continue
+		}
+		names = referencedDecls(inst.Files, names)
+		src, err := formatFiles(inst.Files, names)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot snapshot package %q", inst.ImportPath)
+		}
+		snapshot[inst.ImportPath] = src
+		for _, dep := range inst.Imports {
+			deps[dep.ImportPath] = dep
+			if depRefs[dep.ImportPath] == nil {
+				depRefs[dep.ImportPath] = make(map[string]bool)
+			}
+			collectSelectors(inst.Files, dep.PkgName, names, depRefs[dep.ImportPath])
+		}
+	}
+	for path, dep := range deps {
+		src, err := formatFiles(dep.Files, referencedDecls(dep.Files, depRefs[path]))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot snapshot package %q", path)
+		}
+		snapshot[path] = src
+	}
+	return snapshot, nil
+}
+
+// NewPackageDiscoverFromSnapshot creates a PackageDiscover serving only the packages in the snapshot, which is
+// taken by SnapshotPackages. It never loads packages from the cluster.
+func NewPackageDiscoverFromSnapshot(snapshot map[string]string) (*PackageDiscover, error) {
+	pd := &PackageDiscover{pkgKinds: make(map[string][]VersionKind)}
+	packages := make(map[string]*pkgInstance, len(snapshot))
+	for path, src := range snapshot {
+		pkg := newPackage(path)
+		if err := pkg.AddFile(path, src); err != nil {
+			return nil, errors.WithMessagef(err, "invalid snapshot of package %q", path)
+		}
+		packages[path] = pkg
+	}
+	for _, pkg := range packages {
+		for _, f := range pkg.Files {
+			for _, spec := range f.Imports {
+				path, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				if dep, ok := packages[path]; ok {
+					pkg.Imports = append(pkg.Imports, dep.Instance)
+				}
+			}
+		}
+	}
+	for _, pkg := range packages {
+		pd.mount(pkg, nil)
+	}
+	return pd, nil
+}
+
+// collectSelectors collects the names selected from the package in the top-level fields of the files, e.g., `X` of
+// `kube.X`. Only the fields in the scope are walked if it's not nil.
+func collectSelectors(files []*ast.File, pkgName string, scope map[string]bool, names map[string]bool) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if field, ok := decl.(*ast.Field); ok && scope != nil && !scope[labelName(field.Label)] {
+				continue
+			}
+			ast.Walk(decl, func(node ast.Node) bool {
+				if sel, ok := node.(*ast.SelectorExpr); ok {
+					if x, ok := sel.X.(*ast.Ident); ok && x.Name == pkgName {
+						if name := labelName(sel.Sel); name != "" {
+							names[name] = true
+						}
+					}
+				}
+				return true
+			}, nil)
+		}
+	}
+}
+
+// referencedDecls returns the names of the top-level fields of the files transitively referenced by the names
+func referencedDecls(files []*ast.File, names map[string]bool) map[string]bool {
+	decls := make(map[string]*ast.Field)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if field, ok := decl.(*ast.Field); ok {
+				if name := labelName(field.Label); name != "" {
+					decls[name] = field
+				}
+			}
+		}
+	}
+	referenced := make(map[string]bool)
+	queue := make([]string, 0, len(names))
+	for name := range names {
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		field, ok := decls[name]
+		if !ok || referenced[name] {
+			continue
+		}
+		referenced[name] = true
+		ast.Walk(field.Value, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok {
+				if _, ok := decls[ident.Name]; ok && !referenced[ident.Name] {
+					queue = append(queue, ident.Name)
+				}
+			}
+			return true
+		}, nil)
+	}
+	return referenced
+}
+
+// formatFiles formats the imports and the top-level declarations of the files into a single source, the fields are
+// filtered by the names if they're not nil
+func formatFiles(files []*ast.File, names map[string]bool) (string, error) {
+	imports := make(map[string]bool)
+	var importSrcs, declSrcs []string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.ImportDecl:
+				for _, spec := range d.Specs {
+					// import specs can't be formatted alone, each is formatted as an import declaration instead
+					b, err := format.Node(&ast.ImportDecl{Specs: []*ast.ImportSpec{spec}})
+					if err != nil {
+						return "", err
+					}
+					if src := strings.TrimSpace(string(b)); !imports[src] {
+						imports[src] = true
+						importSrcs = append(importSrcs, src)
+					}
+				}
+				continue
+			case *ast.Field:
+				if names != nil && !names[labelName(d.Label)] {
+					continue
+				}
+			}
+			b, err := format.Node(decl)
+			if err != nil {
+				return "", err
+			}
+			// the package clause is omitted, the package name is derived from the import path on loading
+			if strings.HasPrefix(string(b), "package ") {
+				continue
+			}
+			declSrcs = append(declSrcs, string(b))
+		}
+	}
+	if names != nil {
+		// keep the pruned package stable regardless of the order of the declarations
+		sort.Strings(declSrcs)
+	}
+	return strings.Join(append(importSrcs, declSrcs...), "\n\n"), nil
+}
+
+func labelName(label ast.Label) string {
+	switch l := label.(type) {
+	case *ast.Ident:
+		return l.Name
+	case *ast.BasicLit:
+		if name, err := strconv.Unquote(l.Value); err == nil {
+			return name
+		}
+	}
+	return ""
+}
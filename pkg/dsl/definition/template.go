@@ -80,31 +80,36 @@ func NewWorkloadAbstractEngine(name string, pd *PackageDiscover) AbstractEngine
 	}
 }
 
-// Complete do workload definition's rendering
-func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string, params interface{}) error {
+// buildInstance builds the cue instance of the workload definition template merged with the parameters and context
+func (wd *workloadDef) buildInstance(ctx process.Context, abstractTemplate string, params interface{}) (*cue.Instance, error) {
 	bi := build.NewContext().NewInstance("", nil)
 	if err := bi.AddFile("-", abstractTemplate); err != nil {
-		return errors.WithMessagef(err, "invalid cue template of workload %s", wd.name)
+		return nil, errors.WithMessagef(err, "invalid cue template of workload %s", wd.name)
 	}
 	var paramFile = "parameter: {}"
 	if params != nil {
 		bt, err := json.Marshal(params)
 		if err != nil {
-			return errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
+			return nil, errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
 		}
 		if string(bt) != "null" {
 			paramFile = fmt.Sprintf("%s: %s", mycue.ParameterTag, string(bt))
 		}
 	}
 	if err := bi.AddFile("parameter", paramFile); err != nil {
-		return errors.WithMessagef(err, "invalid parameter of workload %s", wd.name)
+		return nil, errors.WithMessagef(err, "invalid parameter of workload %s", wd.name)
 	}
 
 	if err := bi.AddFile("-", ctx.ExtendedContextFile()); err != nil {
-		return err
+		return nil, err
 	}
 
-	inst, err := wd.pd.ImportPackagesAndBuildInstance(bi)
+	return wd.pd.ImportPackagesAndBuildInstance(bi)
+}
+
+// Complete do workload definition's rendering
+func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string, params interface{}) error {
+	inst, err := wd.buildInstance(ctx, abstractTemplate, params)
 	if err != nil {
 		return err
 	}
@@ -200,10 +205,12 @@ func (wd *workloadDef) HealthCheck(ctx process.Context, cli client.Client, ns st
 	if err != nil {
 		return false, errors.WithMessage(err, "get template context")
 	}
-	return checkHealth(templateContext, healthPolicyTemplate)
+	return CheckHealth(templateContext, healthPolicyTemplate)
 }
 
-func checkHealth(templateContext map[string]interface{}, healthPolicyTemplate string) (bool, error) {
+// CheckHealth evaluates the health policy of a definition against the template context, i.e., the live resources
+// rendered by the definition
+func CheckHealth(templateContext map[string]interface{}, healthPolicyTemplate string) (bool, error) {
 	bt, err := json.Marshal(templateContext)
 	if err != nil {
 		return false, errors.WithMessage(err, "json marshal template context")
@@ -231,10 +238,12 @@ func (wd *workloadDef) Status(ctx process.Context, cli client.Client, ns string,
 	if err != nil {
 		return "", errors.WithMessage(err, "get template context")
 	}
-	return getStatusMessage(templateContext, customStatusTemplate, parameter)
+	return GetStatusMessage(templateContext, customStatusTemplate, parameter)
 }
 
-func getStatusMessage(templateContext map[string]interface{}, customStatusTemplate string, parameter interface{}) (string, error) {
+// GetStatusMessage renders the message of the customStatus template of a definition against the template context,
+// i.e., the live resources rendered by the definition, and the parameter of the definition
+func GetStatusMessage(templateContext map[string]interface{}, customStatusTemplate string, parameter interface{}) (string, error) {
 	var ctxBuff string
 	var paramBuff = "parameter: {}\n"
 
@@ -426,7 +435,7 @@ func (td *traitDef) Status(ctx process.Context, cli client.Client, ns string, cu
 	if err != nil {
 		return "", errors.WithMessage(err, "get template context")
 	}
-	return getStatusMessage(templateContext, customStatusTemplate, parameter)
+	return GetStatusMessage(templateContext, customStatusTemplate, parameter)
 }
 
 // HealthCheck address health check for trait
@@ -438,7 +447,7 @@ func (td *traitDef) HealthCheck(ctx process.Context, cli client.Client, ns strin
 	if err != nil {
 		return false, errors.WithMessage(err, "get template context")
 	}
-	return checkHealth(templateContext, healthPolicyTemplate)
+	return CheckHealth(templateContext, healthPolicyTemplate)
 }
 
 func getResourceFromObj(obj *unstructured.Unstructured, client client.Reader, namespace string, labels map[string]string, outputsResource string) (map[string]interface{}, error) {
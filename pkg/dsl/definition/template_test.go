@@ -994,7 +994,7 @@ func TestCheckHealth(t *testing.T) {
 		},
 	}
 	for message, ca := range cases {
-		healthy, err := checkHealth(ca.tpContext, ca.healthTemp)
+		healthy, err := CheckHealth(ca.tpContext, ca.healthTemp)
 		assert.NoError(t, err, message)
 		assert.Equal(t, ca.exp, healthy, message)
 	}
@@ -1090,7 +1090,7 @@ if len(context.outputs.ingress.status.loadBalancer.ingress) == 0 {
 		},
 	}
 	for message, ca := range cases {
-		gotMessage, err := getStatusMessage(ca.tpContext, ca.statusTemp, ca.parameter)
+		gotMessage, err := GetStatusMessage(ca.tpContext, ca.statusTemp, ca.parameter)
 		assert.NoError(t, err, message)
 		assert.Equal(t, ca.expMessage, gotMessage, message)
 	}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	mycue "github.com/oam-dev/kubevela/pkg/cue"
+)
+
+// validationContextFile declares the context as an open struct, so the references to the context fields in the
+// template are incomplete rather than undefined when validating the template without an application
+const validationContextFile = "context: {}"
+
+// ValidateTemplate compiles the CUE template of a definition of the type and checks the blocks required to render it,
+// so a broken template is rejected on admission instead of failing the applications at render time.
+// A component template requires the output and parameter blocks, a trait template requires the parameter block and
+// the outputs or patch block. A workflow step template is only compiled. The packages discovered from the cluster are
+// imported if pd is not nil.
+func ValidateTemplate(pd *PackageDiscover, defType common.DefinitionType, template string) error {
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", template); err != nil {
+		return errors.WithMessage(err, "invalid cue template")
+	}
+	if err := bi.AddFile("context", validationContextFile); err != nil {
+		return err
+	}
+	var inst *cue.Instance
+	var err error
+	if pd != nil {
+		inst, err = pd.ImportPackagesAndBuildInstance(bi)
+	} else {
+		var r cue.Runtime
+		inst, err = r.Build(bi)
+	}
+	if err != nil {
+		return errors.WithMessage(err, "cannot compile cue template")
+	}
+	if err := inst.Value().Validate(); err != nil {
+		return errors.WithMessage(err, "invalid cue template")
+	}
+
+	switch defType {
+	case common.ComponentType:
+		for _, block := range []string{OutputFieldName, mycue.ParameterTag} {
+			if !inst.Lookup(block).Exists() {
+				return errors.Errorf("the %s block is missing in the cue template of the component", block)
+			}
+		}
+	case common.TraitType:
+		if !inst.Lookup(mycue.ParameterTag).Exists() {
+			return errors.Errorf("the %s block is missing in the cue template of the trait", mycue.ParameterTag)
+		}
+		if !inst.Lookup(OutputsFieldName).Exists() && !inst.Lookup(PatchFieldName).Exists() {
+			return errors.Errorf("neither the %s nor the %s block is in the cue template of the trait", OutputsFieldName, PatchFieldName)
+		}
+	}
+	return nil
+}
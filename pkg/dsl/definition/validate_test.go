@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		defType  common.DefinitionType
+		template string
+		errMsg   string
+	}{
+		"valid component": {
+			defType: common.ComponentType,
+			template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: context.name
+	spec: replicas: parameter.replicas
+}
+parameter: replicas: *1 | int
+`,
+		},
+		"syntax error": {
+			defType:  common.ComponentType,
+			template: `output: { apiVersion: "apps/v1"`,
+			errMsg:   "invalid cue template",
+		},
+		"conflicting values": {
+			defType: common.ComponentType,
+			template: `
+output: spec: replicas: 1
+output: spec: replicas: 2
+parameter: {}
+`,
+			errMsg: "conflicting values",
+		},
+		"component without output": {
+			defType:  common.ComponentType,
+			template: `parameter: image: string`,
+			errMsg:   "the output block is missing",
+		},
+		"component without parameter": {
+			defType:  common.ComponentType,
+			template: `output: kind: "Deployment"`,
+			errMsg:   "the parameter block is missing",
+		},
+		"valid patch trait": {
+			defType: common.TraitType,
+			template: `
+patch: spec: template: metadata: labels: parameter
+parameter: [string]: string
+`,
+		},
+		"trait without outputs or patch": {
+			defType:  common.TraitType,
+			template: `parameter: port: int`,
+			errMsg:   "neither the outputs nor the patch block",
+		},
+		"workflow step is only compiled": {
+			defType:  common.WorkflowStepType,
+			template: `wait: true`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateTemplate(nil, tc.defType, tc.template)
+			if tc.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gc exposes the garbage collection of the resources tracked by the ResourceTrackers of applications, the
+// resources no longer rendered by an application are decided by the registered strategies, and the registered
+// trackers are notified of the resources tracked and collected. They're registered in the init functions of the
+// packages imported by a custom build of the controller, so no fork of the application controller is needed.
+package gc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// Garbage is a resource tracked by the ResourceTracker of an application but no longer rendered by it
+type Garbage struct {
+	Application *v1beta1.Application
+	Ref         v1beta1.TypedReference
+	// Since is the time the resource was found no longer rendered
+	Since time.Time
+	// Finalizing means the application is being deleted, the resource is deleted even if a strategy decides to wait
+	Finalizing bool
+}
+
+// Object returns the object of the garbage with its identity only
+func (g *Garbage) Object() *unstructured.Unstructured {
+	obj := new(unstructured.Unstructured)
+	obj.SetAPIVersion(g.Ref.APIVersion)
+	obj.SetKind(g.Ref.Kind)
+	obj.SetNamespace(g.Ref.Namespace)
+	obj.SetName(g.Ref.Name)
+	return obj
+}
+
+// Decision is what a strategy decides to do with a garbage, the zero Decision abstains
+type Decision struct {
+	Action util.GCDecision
+	// Wait is the duration to wait before the garbage is decided again, it's required by util.GCWait
+	Wait time.Duration
+}
+
+func (d Decision) validate() error {
+	switch d.Action {
+	case util.GCDelete, util.GCProtect, util.GCGone:
+		return nil
+	case util.GCWait:
+		if d.Wait <= 0 {
+			return errors.New("a positive duration to wait is required")
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown action %q", d.Action)
+	}
+}
+
+// Abstain defers the decision to the next strategy
+var Abstain = Decision{}
+
+// Strategy decides what to do with a garbage, e.g., defer the deletions to a maintenance window. The client is the
+// one of the cluster the garbage is in.
+type Strategy interface {
+	Decide(ctx context.Context, c client.Client, g *Garbage) (Decision, error)
+}
+
+// StrategyFunc is a function implementing Strategy
+type StrategyFunc func(ctx context.Context, c client.Client, g *Garbage) (Decision, error)
+
+// Decide calls the function
+func (f StrategyFunc) Decide(ctx context.Context, c client.Client, g *Garbage) (Decision, error) {
+	return f(ctx, c, g)
+}
+
+// LabelStrategyName is the name of the LabelStrategy
+const LabelStrategyName = "label"
+
+// LabelStrategy is the built-in strategy following the garbage collection policy declared by the labels of the
+// resource, see util.GetGCPolicy. It always decides, so it's the last one consulted.
+var LabelStrategy = StrategyFunc(func(ctx context.Context, c client.Client, g *Garbage) (Decision, error) {
+	action, wait, err := util.DecideGarbageCollection(ctx, c, g.Object(), g.Since, time.Now())
+	return Decision{Action: action, Wait: wait}, err
+})
+
+// Tracker is notified of the resources tracked by the ResourceTracker of an application and the garbage collected,
+// e.g., to mirror them to an external CMDB. The errors returned are logged only, they don't fail the reconciliation.
+type Tracker interface {
+	// Tracked is called with all the resources tracked after the ResourceTracker is updated, refs is empty if the
+	// ResourceTracker is deleted
+	Tracked(ctx context.Context, app *v1beta1.Application, refs []v1beta1.TypedReference) error
+	// Collected is called after the garbage is deleted, util.GCDelete, or orphaned, util.GCProtect
+	Collected(ctx context.Context, g *Garbage, action util.GCDecision) error
+}
+
+type registration struct {
+	name     string
+	strategy Strategy
+	tracker  Tracker
+}
+
+var (
+	mu         sync.RWMutex
+	strategies []registration
+	trackers   []registration
+)
+
+// RegisterStrategy registers a strategy consulted before the LabelStrategy, the strategies are consulted in the
+// order they're registered until one of them decides. It panics if the name is registered twice.
+func RegisterStrategy(name string, s Strategy) {
+	mu.Lock()
+	defer mu.Unlock()
+	if s == nil {
+		panic("gc: strategy " + name + " is nil")
+	}
+	for _, r := range strategies {
+		if r.name == name {
+			panic(fmt.Sprintf("gc: strategy %s is registered twice", name))
+		}
+	}
+	strategies = append(strategies, registration{name: name, strategy: s})
+}
+
+// RegisterTracker registers a tracker notified of the resources tracked and collected. It panics if the name is
+// registered twice.
+func RegisterTracker(name string, t Tracker) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t == nil {
+		panic("gc: tracker " + name + " is nil")
+	}
+	for _, r := range trackers {
+		if r.name == name {
+			panic(fmt.Sprintf("gc: tracker %s is registered twice", name))
+		}
+	}
+	trackers = append(trackers, registration{name: name, tracker: t})
+}
+
+// Decide consults the registered strategies and then the LabelStrategy, it returns the first decision made and the
+// name of the strategy making it
+func Decide(ctx context.Context, c client.Client, g *Garbage) (Decision, string, error) {
+	mu.RLock()
+	registered := append([]registration{}, strategies...)
+	mu.RUnlock()
+	for _, r := range registered {
+		d, err := r.strategy.Decide(ctx, c, g)
+		if err != nil {
+			return Abstain, r.name, errors.Wrapf(err, "strategy %s cannot decide %s %s/%s", r.name, g.Ref.Kind, g.Ref.Namespace, g.Ref.Name)
+		}
+		if d == Abstain {
+			continue
+		}
+		if err := d.validate(); err != nil {
+			return Abstain, r.name, errors.WithMessagef(err, "invalid decision of strategy %s on %s %s/%s", r.name, g.Ref.Kind, g.Ref.Namespace, g.Ref.Name)
+		}
+		return d, r.name, nil
+	}
+	d, err := LabelStrategy(ctx, c, g)
+	return d, LabelStrategyName, err
+}
+
+// NotifyTracked notifies the registered trackers of the resources tracked by the application
+func NotifyTracked(ctx context.Context, app *v1beta1.Application, refs []v1beta1.TypedReference) {
+	mu.RLock()
+	registered := append([]registration{}, trackers...)
+	mu.RUnlock()
+	for _, r := range registered {
+		if err := r.tracker.Tracked(ctx, app, refs); err != nil {
+			klog.ErrorS(err, "Tracker failed to handle the tracked resources", "tracker", r.name, "application", klog.KObj(app))
+		}
+	}
+}
+
+// NotifyCollected notifies the registered trackers of the garbage collected
+func NotifyCollected(ctx context.Context, g *Garbage, action util.GCDecision) {
+	mu.RLock()
+	registered := append([]registration{}, trackers...)
+	mu.RUnlock()
+	for _, r := range registered {
+		if err := r.tracker.Collected(ctx, g, action); err != nil {
+			klog.ErrorS(err, "Tracker failed to handle the collected garbage", "tracker", r.name, "application", klog.KObj(g.Application),
+				"kind", g.Ref.Kind, "resource", klog.KRef(g.Ref.Namespace, g.Ref.Name))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+type recordingTracker struct {
+	tracked   []v1beta1.TypedReference
+	collected map[string]util.GCDecision
+}
+
+func (t *recordingTracker) Tracked(_ context.Context, _ *v1beta1.Application, refs []v1beta1.TypedReference) error {
+	t.tracked = refs
+	return nil
+}
+
+func (t *recordingTracker) Collected(_ context.Context, g *Garbage, action util.GCDecision) error {
+	t.collected[g.Ref.Name] = action
+	return errors.New("errors of trackers are logged only")
+}
+
+func TestDecide(t *testing.T) {
+	defer func() { strategies = nil }()
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      "config",
+		Namespace: "default",
+		Labels:    map[string]string{oam.LabelGCGracePeriod: "10m"},
+	}}
+	c := fake.NewFakeClientWithScheme(common.Scheme, cm)
+	g := &Garbage{
+		Application: &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+		Ref:         v1beta1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "config"},
+		Since:       time.Now(),
+	}
+
+	d, name, err := Decide(ctx, c, g)
+	assert.NoError(t, err)
+	assert.Equal(t, LabelStrategyName, name)
+	assert.Equal(t, util.GCWait, d.Action)
+
+	// the maintenance window strategy abstains from the finalizing garbage, so it's decided by the labels
+	RegisterStrategy("maintenance-window", StrategyFunc(func(_ context.Context, _ client.Client, g *Garbage) (Decision, error) {
+		if g.Finalizing {
+			return Abstain, nil
+		}
+		return Decision{Action: util.GCWait, Wait: time.Hour}, nil
+	}))
+	assert.Panics(t, func() { RegisterStrategy("maintenance-window", LabelStrategy) })
+	d, name, err = Decide(ctx, c, g)
+	assert.NoError(t, err)
+	assert.Equal(t, "maintenance-window", name)
+	assert.Equal(t, Decision{Action: util.GCWait, Wait: time.Hour}, d)
+
+	g.Finalizing = true
+	g.Since = time.Time{}
+	d, name, err = Decide(ctx, c, g)
+	assert.NoError(t, err)
+	assert.Equal(t, LabelStrategyName, name)
+	assert.Equal(t, util.GCDelete, d.Action)
+
+	strategies = nil
+	RegisterStrategy("invalid", StrategyFunc(func(context.Context, client.Client, *Garbage) (Decision, error) {
+		return Decision{Action: util.GCWait}, nil
+	}))
+	_, _, err = Decide(ctx, c, g)
+	assert.Error(t, err)
+}
+
+func TestNotify(t *testing.T) {
+	defer func() { trackers = nil }()
+	tracker := &recordingTracker{collected: map[string]util.GCDecision{}}
+	RegisterTracker("cmdb", tracker)
+	assert.Panics(t, func() { RegisterTracker("cmdb", tracker) })
+
+	ctx := context.Background()
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	refs := []v1beta1.TypedReference{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "other", Name: "config"}}
+	NotifyTracked(ctx, app, refs)
+	assert.Equal(t, refs, tracker.tracked)
+
+	NotifyCollected(ctx, &Garbage{Application: app, Ref: refs[0]}, util.GCDelete)
+	assert.Equal(t, map[string]util.GCDecision{"config": util.GCDelete}, tracker.collected)
+}
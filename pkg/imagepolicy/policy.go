@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagepolicy selects the image tags of the components from the tags in the registry following their image
+// policies, e.g., the highest semantic version in a range or the image created most recently
+package imagepolicy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+var (
+	// DefaultInterval is how often the tags are listed if the image policy doesn't set the interval
+	DefaultInterval = 5 * time.Minute
+	// MinInterval is the minimum interval of the image policies, it's also how often the scanner checks the policies
+	MinInterval = 30 * time.Second
+	// MaxTimestampTags is the maximum number of tags the timestamp policy selects from, the creation time of each
+	// tag is pulled from the registry, so the pattern is required to narrow the tags down
+	MaxTimestampTags = 50
+)
+
+// CreatedFunc returns the creation time of the image of the tag
+type CreatedFunc func(ctx context.Context, tag string) (time.Time, error)
+
+// Validate validates the image policy
+func Validate(p *common.ImagePolicy) error {
+	ref, err := oci.ParseReference(p.Image)
+	if err != nil {
+		return err
+	}
+	if ref.Pinned() || strings.HasSuffix(p.Image, ":"+ref.Tag) {
+		return fmt.Errorf("image %q must be a repository without tag or digest", p.Image)
+	}
+	switch p.Policy {
+	case common.ImagePolicySemver:
+		if len(p.Range) != 0 {
+			if _, err := semver.NewConstraint(p.Range); err != nil {
+				return errors.Wrapf(err, "invalid semver range %q", p.Range)
+			}
+		}
+	case common.ImagePolicyTimestamp:
+		if len(p.Range) != 0 {
+			return errors.New("range applies to the semver policy only")
+		}
+	default:
+		return fmt.Errorf("unsupported image policy %q, the supported ones are %s and %s", p.Policy, common.ImagePolicySemver, common.ImagePolicyTimestamp)
+	}
+	if len(p.Pattern) != 0 {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", p.Pattern)
+		}
+	}
+	if p.Interval != nil && p.Interval.Duration < MinInterval {
+		return fmt.Errorf("interval %s is less than the minimum %s", p.Interval.Duration, MinInterval)
+	}
+	return nil
+}
+
+// Interval returns how often the tags are listed for the image policy
+func Interval(p *common.ImagePolicy) time.Duration {
+	if p.Interval == nil || p.Interval.Duration == 0 {
+		return DefaultInterval
+	}
+	return p.Interval.Duration
+}
+
+// Select selects the tag from the tags following the image policy, it returns an error if no tag is selected
+func Select(ctx context.Context, p *common.ImagePolicy, tags []string, created CreatedFunc) (string, error) {
+	tags, err := filter(p, tags)
+	if err != nil {
+		return "", err
+	}
+	var tag string
+	switch p.Policy {
+	case common.ImagePolicySemver:
+		tag, err = selectSemver(p, tags)
+	case common.ImagePolicyTimestamp:
+		tag, err = selectTimestamp(ctx, tags, created)
+	default:
+		err = fmt.Errorf("unsupported image policy %q", p.Policy)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(tag) == 0 {
+		return "", fmt.Errorf("no tag of %s matches the %s policy", p.Image, p.Policy)
+	}
+	return tag, nil
+}
+
+func filter(p *common.ImagePolicy, tags []string) ([]string, error) {
+	if len(p.Pattern) == 0 {
+		return tags, nil
+	}
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern %q", p.Pattern)
+	}
+	var matched []string
+	for _, tag := range tags {
+		if re.MatchString(tag) {
+			matched = append(matched, tag)
+		}
+	}
+	return matched, nil
+}
+
+// selectSemver selects the highest version in the range, the tags not being semantic versions are skipped and the
+// pre-releases are selected only if the range includes a pre-release, e.g., ">=1.0.0-0"
+func selectSemver(p *common.ImagePolicy, tags []string) (string, error) {
+	var constraint *semver.Constraints
+	if len(p.Range) != 0 {
+		c, err := semver.NewConstraint(p.Range)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid semver range %q", p.Range)
+		}
+		constraint = c
+	}
+	var latest *semver.Version
+	var selected string
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if constraint != nil && !constraint.Check(v) {
+			continue
+		}
+		if constraint == nil && len(v.Prerelease()) != 0 {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, selected = v, tag
+		}
+	}
+	return selected, nil
+}
+
+// selectTimestamp selects the tag of the image created most recently
+func selectTimestamp(ctx context.Context, tags []string, created CreatedFunc) (string, error) {
+	if len(tags) > MaxTimestampTags {
+		return "", fmt.Errorf("%d tags match the timestamp policy, more than the maximum %d, narrow them down with the pattern", len(tags), MaxTimestampTags)
+	}
+	var latest time.Time
+	var selected string
+	for _, tag := range tags {
+		t, err := created(ctx, tag)
+		if err != nil {
+			return "", errors.WithMessagef(err, "cannot get the creation time of tag %s", tag)
+		}
+		if len(selected) == 0 || t.After(latest) {
+			latest, selected = t, tag
+		}
+	}
+	return selected, nil
+}
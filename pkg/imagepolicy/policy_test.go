@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagepolicy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		policy  common.ImagePolicy
+		wantErr bool
+	}{
+		"semver": {
+			policy: common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicySemver, Range: ">=1.0.0 <2.0.0"},
+		},
+		"timestamp with pattern": {
+			policy: common.ImagePolicy{Image: "localhost:5000/app", Policy: common.ImagePolicyTimestamp, Pattern: "^main-"},
+		},
+		"image with tag": {
+			policy:  common.ImagePolicy{Image: "myrepo/app:1.0.0", Policy: common.ImagePolicySemver},
+			wantErr: true,
+		},
+		"invalid range": {
+			policy:  common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicySemver, Range: ">=1.2.3.4.5"},
+			wantErr: true,
+		},
+		"range of timestamp": {
+			policy:  common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicyTimestamp, Range: "~1.2"},
+			wantErr: true,
+		},
+		"unknown policy": {
+			policy:  common.ImagePolicy{Image: "myrepo/app", Policy: "alphabetical"},
+			wantErr: true,
+		},
+		"invalid pattern": {
+			policy:  common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicySemver, Pattern: "("},
+			wantErr: true,
+		},
+		"interval too short": {
+			policy:  common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicySemver, Interval: &metav1.Duration{Duration: time.Second}},
+			wantErr: true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(&c.policy)
+			assert.Equal(t, c.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestSelectSemver(t *testing.T) {
+	tags := []string{"latest", "1.0.0", "v1.2.0", "1.10.1", "2.0.0-rc.1", "1.9.0"}
+	p := &common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicySemver}
+	tag, err := Select(context.Background(), p, tags, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.10.1", tag)
+
+	p.Range = "~1.2"
+	tag, err = Select(context.Background(), p, tags, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.2.0", tag)
+
+	p.Range = ">=2.0.0-0"
+	tag, err = Select(context.Background(), p, tags, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0-rc.1", tag)
+
+	p.Range = ">=3.0.0"
+	_, err = Select(context.Background(), p, tags, nil)
+	assert.Error(t, err)
+}
+
+func TestSelectTimestamp(t *testing.T) {
+	now := time.Now()
+	created := map[string]time.Time{
+		"main-a1b2c3d": now.Add(-time.Hour),
+		"main-e4f5a6b": now,
+		"main-c7d8e9f": now.Add(-2 * time.Hour),
+		"dev-0a1b2c3":  now.Add(time.Hour),
+	}
+	var tags []string
+	for tag := range created {
+		tags = append(tags, tag)
+	}
+	createdFunc := func(_ context.Context, tag string) (time.Time, error) {
+		return created[tag], nil
+	}
+	p := &common.ImagePolicy{Image: "myrepo/app", Policy: common.ImagePolicyTimestamp, Pattern: "^main-"}
+	tag, err := Select(context.Background(), p, tags, createdFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "main-e4f5a6b", tag)
+
+	p.Pattern = ""
+	tag, err = Select(context.Background(), p, tags, createdFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, "dev-0a1b2c3", tag)
+
+	defer func(max int) { MaxTimestampTags = max }(MaxTimestampTags)
+	MaxTimestampTags = 2
+	_, err = Select(context.Background(), p, tags, createdFunc)
+	assert.Error(t, err)
+}
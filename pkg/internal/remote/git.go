@@ -0,0 +1,269 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	gitclient "gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// maxAdvertisementSize is the maximum size in bytes of the refs advertised by a Git server, go-git requests them
+// without the context carrying the transfer limit of the clone
+const maxAdvertisementSize int64 = 8 << 20
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+type transferLimitKey struct{}
+
+func init() {
+	// the Git repositories are cloned over HTTP(S) with the restrictions of Get, the other protocols are rejected by
+	// ReadGit before cloning
+	gitHTTP := githttp.NewClient(&http.Client{
+		Timeout:       client.Timeout,
+		Transport:     &limitedTransport{base: client.Transport},
+		CheckRedirect: client.CheckRedirect,
+	})
+	gitclient.InstallProtocol("http", gitHTTP)
+	gitclient.InstallProtocol("https", gitHTTP)
+}
+
+// GitAuth is the basic auth to clone a Git repository, the password can be a personal access token as well
+type GitAuth struct {
+	Username string
+	Password string
+}
+
+// GitOptions are the options to read the files of a Git repository
+type GitOptions struct {
+	// Repo is the http or https URL of the repository
+	Repo string
+	// Ref is the branch, tag or commit SHA of the repository
+	Ref string
+	// Auth is the credentials to clone the repository, it's cloned anonymously if it's nil
+	Auth *GitAuth
+	// Dir is the directory in the repository to read, the files of the sub-directories are read as well
+	Dir string
+	// Accept tells whether to read the file at the path in the repository, all the files are read if it's nil
+	Accept func(name string) bool
+	// MaxTransferSize is the maximum size in bytes of the data transferred to clone the repository
+	MaxTransferSize int64
+	// MaxSize is the maximum total size in bytes of the files read
+	MaxSize int64
+}
+
+// ReadGit clones the Git repository at the ref into memory and reads the files in the directory, the files are keyed
+// by their paths in the repository. The branch or tag is cloned with a depth of one, while a commit which is not the
+// head of any branch or tag requires the whole history. The repository is cloned over HTTP(S) with the restrictions
+// of Get, and the data transferred is limited.
+func ReadGit(ctx context.Context, o GitOptions) (map[string][]byte, error) {
+	u, err := url.Parse(o.Repo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid git repo %q", o.Repo)
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, transferLimitKey{}, o.MaxTransferSize)
+
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+	origin, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{o.Repo}})
+	if err != nil {
+		return nil, err
+	}
+	opts := &git.FetchOptions{Tags: git.NoTags}
+	if o.Auth != nil {
+		opts.Auth = &githttp.BasicAuth{Username: o.Auth.Username, Password: o.Auth.Password}
+	}
+
+	refs, err := origin.List(&git.ListOptions{Auth: opts.Auth})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list refs")
+	}
+	ref := resolveRef(refs, o.Ref)
+	if ref != "" {
+		opts.RefSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))}
+		opts.Depth = 1
+	} else {
+		if !commitPattern.MatchString(o.Ref) {
+			return nil, fmt.Errorf("ref %q is not found", o.Ref)
+		}
+		opts.RefSpecs = []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+	}
+	if err := origin.FetchContext(ctx, opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, errors.Wrapf(err, "cannot fetch ref %q", o.Ref)
+	}
+
+	commit, err := resolveCommit(repo, ref, o.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return readTree(commit, o)
+}
+
+// resolveRef returns the name of the branch or tag named by the ref, or the branch or tag whose head is the commit,
+// an empty name is returned if none matches
+func resolveRef(refs []*plumbing.Reference, ref string) plumbing.ReferenceName {
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)} {
+		for _, r := range refs {
+			if r.Name() == name {
+				return name
+			}
+		}
+	}
+	if !commitPattern.MatchString(ref) {
+		return ""
+	}
+	for _, r := range refs {
+		if (r.Name().IsBranch() || r.Name().IsTag()) && strings.HasPrefix(r.Hash().String(), ref) {
+			return r.Name()
+		}
+	}
+	return ""
+}
+
+// resolveCommit returns the commit the fetched ref points to, or the commit of the SHA if no ref is fetched
+func resolveCommit(repo *git.Repository, ref plumbing.ReferenceName, sha string) (*object.Commit, error) {
+	if ref == "" {
+		var found *object.Commit
+		commits, err := repo.CommitObjects()
+		if err != nil {
+			return nil, err
+		}
+		err = commits.ForEach(func(c *object.Commit) error {
+			if strings.HasPrefix(c.Hash.String(), sha) {
+				found = c
+				return io.EOF
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if found == nil {
+			return nil, fmt.Errorf("commit %q is not found", sha)
+		}
+		return found, nil
+	}
+	r, err := repo.Reference(ref, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot resolve ref %q", ref)
+	}
+	// an annotated tag points to the tag object instead of the commit
+	if tag, err := repo.TagObject(r.Hash()); err == nil {
+		return tag.Commit()
+	}
+	return repo.CommitObject(r.Hash())
+}
+
+// readTree reads the regular files accepted in the directory of the commit
+func readTree(commit *object.Commit, o GitOptions) (map[string][]byte, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	dir := strings.Trim(path.Clean("/"+o.Dir), "/")
+	if dir != "" {
+		if tree, err = tree.Tree(dir); err != nil {
+			return nil, errors.Wrapf(err, "cannot read directory %q", dir)
+		}
+	}
+	files := map[string][]byte{}
+	var total int64
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode != filemode.Regular && f.Mode != filemode.Executable {
+			return nil
+		}
+		name := path.Join(dir, f.Name)
+		if o.Accept != nil && !o.Accept(name) {
+			return nil
+		}
+		total += f.Size
+		if total > o.MaxSize {
+			return fmt.Errorf("files exceed the size limit of %d bytes", o.MaxSize)
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return errors.Wrapf(err, "cannot read %s", name)
+		}
+		files[name] = []byte(contents)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// limitedTransport fails the responses exceeding the transfer limit carried by the context of the requests
+type limitedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	limit, ok := req.Context().Value(transferLimitKey{}).(int64)
+	if !ok || limit <= 0 {
+		limit = maxAdvertisementSize
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, limit: limit}
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// tell the content exceeding the limit from the end of the content
+		var one [1]byte
+		if n, _ := b.ReadCloser.Read(one[:]); n > 0 {
+			return 0, fmt.Errorf("transfer exceeds the size limit of %d bytes", b.limit)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitServer serves a repository with two commits over the smart HTTP protocol of git http-backend, it returns
+// the URL of the repository and the SHA of the first commit
+func newGitServer(t *testing.T) (string, string) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	root, err := ioutil.TempDir("", "git-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(root) })
+	work := filepath.Join(root, "work")
+	git := func(args ...string) string {
+		cmd := exec.Command(gitPath, args...)
+		cmd.Dir = work
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name, content string) {
+		p := filepath.Join(work, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(work, 0750); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	write("deploy/app.yaml", "v1")
+	write("deploy/nested/db.yaml", "db")
+	write("README.md", "readme")
+	git("add", "-A")
+	git("commit", "-q", "-m", "first")
+	first := git("rev-parse", "HEAD")
+	git("tag", "-a", "v1.0.0", "-m", "v1.0.0")
+	write("deploy/app.yaml", "v2")
+	git("commit", "-q", "-am", "second")
+	git("branch", "-M", "main")
+	git("clone", "-q", "--bare", work, filepath.Join(root, "repo.git"))
+
+	srv := httptest.NewServer(&cgi.Handler{
+		Path: filepath.Join(strings.TrimSpace(git("--exec-path")), "git-http-backend"),
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(srv.Close)
+	return srv.URL + "/repo.git", first
+}
+
+func TestReadGit(t *testing.T) {
+	repo, first := newGitServer(t)
+	ctx := context.Background()
+	opts := GitOptions{Repo: repo, Dir: "deploy", MaxTransferSize: 1 << 20, MaxSize: 1 << 20}
+
+	// the local server is in the loopback network, which is blocked
+	opts.Ref = "main"
+	if _, err := ReadGit(ctx, opts); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected the loopback address rejected, got %v", err)
+	}
+	if _, err := ReadGit(ctx, GitOptions{Repo: "file:///etc", Ref: "main"}); err == nil || !strings.Contains(err.Error(), "unsupported scheme") {
+		t.Errorf("expected the file scheme rejected, got %v", err)
+	}
+
+	allowBlockedNetworks = true
+	defer func() { allowBlockedNetworks = false }()
+	for ref, want := range map[string]string{"main": "v2", "v1.0.0": "v1", first: "v1", first[:7]: "v1"} {
+		opts.Ref = ref
+		files, err := ReadGit(ctx, opts)
+		if err != nil {
+			t.Fatalf("ref %s: %v", ref, err)
+		}
+		if len(files) != 2 || string(files["deploy/app.yaml"]) != want || string(files["deploy/nested/db.yaml"]) != "db" {
+			t.Errorf("ref %s: unexpected files %v", ref, files)
+		}
+	}
+
+	opts.Ref = "main"
+	opts.Accept = func(name string) bool { return filepath.Ext(name) == ".yaml" && filepath.Dir(name) == "deploy" }
+	if files, err := ReadGit(ctx, opts); err != nil || len(files) != 1 {
+		t.Errorf("expected only the accepted file read, got %v, %v", files, err)
+	}
+	opts.Accept = nil
+
+	opts.Ref = "missing"
+	if _, err := ReadGit(ctx, opts); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the missing ref rejected, got %v", err)
+	}
+	opts.Ref, opts.Dir = "main", "missing"
+	if _, err := ReadGit(ctx, opts); err == nil || !strings.Contains(err.Error(), "cannot read directory") {
+		t.Errorf("expected the missing directory rejected, got %v", err)
+	}
+	opts.Dir, opts.MaxSize = "deploy", 3
+	if _, err := ReadGit(ctx, opts); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("expected the size limit of the files exceeded, got %v", err)
+	}
+	opts.MaxSize, opts.MaxTransferSize = 1<<20, 16
+	if _, err := ReadGit(ctx, opts); err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("expected the transfer limit exceeded, got %v", err)
+	}
+}
+
+func TestLimitedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: &limitedTransport{base: http.DefaultTransport}}
+	for limit, exceeded := range map[int64]bool{4: true, 10: false} {
+		req, _ := http.NewRequestWithContext(context.WithValue(context.Background(), transferLimitKey{}, limit), http.MethodGet, srv.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if (err != nil) != exceeded {
+			t.Errorf("limit %d: expected exceeded %v, got %v", limit, exceeded, err)
+		}
+	}
+}
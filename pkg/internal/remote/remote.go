@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package remote downloads the remote sources of the schematics, addons and image policies, and caches them across
+// renderings.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Timeout is the timeout of downloading a remote source, including the redirects
+const Timeout = time.Minute
+
+// maxRedirects is the maximum number of redirects followed
+const maxRedirects = 10
+
+// blockedNetworks are the addresses the remote sources cannot be downloaded from, otherwise the applications could
+// make the controller request the services of the cluster or the metadata endpoints of the cloud providers
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, e.g., the metadata endpoints
+	"172.16.0.0/12",  // private
+	"192.168.0.0/16", // private
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+// allowBlockedNetworks allows the blocked addresses, it's only set by the tests against local servers
+var allowBlockedNetworks = false
+
+var client = &http.Client{
+	Timeout: Timeout,
+	// the remote sources are downloaded without proxies, which would bypass the checks of the addresses dialed
+	Transport: &http.Transport{
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, Control: checkDial}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return checkURL(req.URL)
+	},
+}
+
+// HTTPClient returns the client downloading the remote sources with the restrictions of Get, the OCI clients pulling
+// from the registries given by users and the notifiers of the endpoints given by users must use it
+func HTTPClient() *http.Client {
+	return client
+}
+
+// Get downloads the content at the URL over HTTP. At most one byte more than the limit is read, so the callers can tell the
+// content exceeding the limit without reading all of it. Only http and https URLs are allowed, and the addresses of
+// the private, loopback and link-local networks are rejected when they're dialed, so are the redirects to them.
+func Get(ctx context.Context, u string, limit int64) ([]byte, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURL(parsed); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+}
+
+// checkURL rejects the URLs of other schemes than http and https
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q of %s, only http and https are allowed", u.Scheme, u.Redacted())
+	}
+	if len(u.Hostname()) == 0 {
+		return fmt.Errorf("no host in %s", u.Redacted())
+	}
+	return nil
+}
+
+// checkDial rejects dialing the blocked addresses. It checks the resolved addresses, so the host names resolving to
+// them are rejected as well.
+func checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %s", address)
+	}
+	if allowBlockedNetworks {
+		return nil
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return fmt.Errorf("address %s is not allowed, it's in the blocked network %s", ip, n)
+		}
+	}
+	return nil
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+type cacheEntry struct {
+	value     interface{}
+	fetchedAt time.Time
+	ttl       time.Duration
+}
+
+// expired tells whether the entry is expired, an entry without ttl never expires
+func (e cacheEntry) expired() bool {
+	return e.ttl != 0 && time.Since(e.fetchedAt) >= e.ttl
+}
+
+// Cache caches the contents fetched, it's safe for concurrent use
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCache creates an empty Cache
+func NewCache() *Cache {
+	return &Cache{entries: map[string]cacheEntry{}}
+}
+
+// Get returns the value cached for the key, it's not found if it's expired
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.expired() {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set caches the value for the key, it expires after the ttl, or never if the ttl is zero, e.g., the content is
+// pinned by a checksum or a commit. Entries are evicted to keep at most maxEntries in the cache.
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration, maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxEntries {
+		c.evict(maxEntries)
+	}
+	c.entries[key] = cacheEntry{value: value, fetchedAt: time.Now(), ttl: ttl}
+}
+
+// Delete removes the value cached for the key
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// evict removes the expired entries, or the oldest one if none is expired
+func (c *Cache) evict(maxEntries int) {
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range c.entries {
+		if e.expired() {
+			delete(c.entries, k)
+			continue
+		}
+		if oldestKey == "" || e.fetchedAt.Before(oldest) {
+			oldestKey, oldest = k, e.fetchedAt
+		}
+	}
+	if len(c.entries) >= maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
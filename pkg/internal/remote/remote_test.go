@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	// the local server is in the loopback network, which is blocked
+	if _, err := Get(context.Background(), srv.URL+"/manifest", 4); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected the loopback address rejected, got %v", err)
+	}
+	if _, err := Get(context.Background(), "file:///etc/passwd", 4); err == nil || !strings.Contains(err.Error(), "unsupported scheme") {
+		t.Errorf("expected the file scheme rejected, got %v", err)
+	}
+
+	allowBlockedNetworks = true
+	defer func() { allowBlockedNetworks = false }()
+	data, err := Get(context.Background(), srv.URL+"/manifest", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "01234" {
+		t.Errorf("expected one byte more than the limit, got %q", data)
+	}
+	if _, err := Get(context.Background(), srv.URL+"/missing", 4); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the unexpected status, got %v", err)
+	}
+}
+
+func TestCheckDial(t *testing.T) {
+	for address, blocked := range map[string]bool{
+		"127.0.0.1:80":          true,
+		"10.0.0.1:443":          true,
+		"169.254.169.254:80":    true,
+		"[::1]:80":              true,
+		"[::ffff:10.0.0.1]:80":  true,
+		"[fd00::1]:443":         true,
+		"140.82.112.3:443":      false,
+		"[2606:4700::6810]:443": false,
+	} {
+		if err := checkDial("tcp", address, nil); (err != nil) != blocked {
+			t.Errorf("address %s: expected blocked %v, got %v", address, blocked, err)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+	c.Set("pinned", "a", 0, 2)
+	c.Set("expired", "b", time.Nanosecond, 2)
+	time.Sleep(time.Millisecond)
+	if v, ok := c.Get("pinned"); !ok || v != "a" {
+		t.Errorf("expected the pinned entry cached, got %v", v)
+	}
+	if _, ok := c.Get("expired"); ok {
+		t.Error("expected the expired entry not found")
+	}
+
+	// the expired entry is evicted first
+	c.Set("fresh", "c", time.Hour, 2)
+	if _, ok := c.Get("pinned"); !ok {
+		t.Error("expected the pinned entry kept")
+	}
+	// the oldest entry is evicted if none is expired
+	c.Set("newest", "d", time.Hour, 2)
+	if _, ok := c.Get("pinned"); ok {
+		t.Error("expected the oldest entry evicted")
+	}
+	if _, ok := c.Get("fresh"); !ok {
+		t.Error("expected the fresh entry kept")
+	}
+
+	c.Delete("fresh")
+	if _, ok := c.Get("fresh"); ok {
+		t.Error("expected the deleted entry not found")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifeststore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip compresses the manifests by gzip
+	CompressionGzip = "gzip"
+	// CompressionZstd compresses the manifests by zstd, it's faster and compresses better than gzip
+	CompressionZstd = "zstd"
+
+	// DefaultMaxDecompressedSize is the default maximum size in bytes of the decompressed payloads, it's far above
+	// the size limit of etcd the payloads are compressed to stay under
+	DefaultMaxDecompressedSize = 64 * 1024 * 1024
+)
+
+// maxDecompressedSize is the maximum size in bytes of the decompressed payloads, the payloads decompressed larger
+// are rejected rather than exhausting the memory, e.g., the ones crafted to decompress to gigabytes
+var maxDecompressedSize int64 = DefaultMaxDecompressedSize
+
+// SetMaxDecompressedSize sets the maximum size in bytes of the decompressed payloads, DefaultMaxDecompressedSize is
+// used if it's not positive
+func SetMaxDecompressedSize(size int64) {
+	if size <= 0 {
+		size = DefaultMaxDecompressedSize
+	}
+	maxDecompressedSize = size
+}
+
+// validCompression checks whether the compression algorithm is supported, it's not compressed if empty
+func validCompression(algorithm string) error {
+	switch algorithm {
+	case "", CompressionGzip, CompressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("unknown compression algorithm %q, available ones: %s, %s", algorithm, CompressionGzip, CompressionZstd)
+	}
+}
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close() //nolint:errcheck
+		return enc.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm %q", algorithm)
+	}
+}
+
+func decompress(algorithm string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch algorithm {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close() //nolint:errcheck
+		r = gr
+	case CompressionZstd:
+		dec, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		r = dec
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm %q", algorithm)
+	}
+	limit := maxDecompressedSize
+	out, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("the decompressed payload exceeds the maximum size of %d bytes", limit)
+	}
+	return out, nil
+}
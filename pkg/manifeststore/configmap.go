@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifeststore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// TypeConfigMap is the type of the backend storing the manifests in ConfigMaps
+	TypeConfigMap = "configmap"
+	// ShardSize is the maximum size in bytes of the manifests stored in a ConfigMap, the ConfigMaps are under the
+	// 1MiB limit with their metadata
+	ShardSize = 768 * 1024
+	// shardDataKey is the key of the manifests in the ConfigMaps
+	shardDataKey = "manifests"
+)
+
+// ConfigMapBackend stores the manifests of a revision in ConfigMaps in its namespace, they're split into shards
+// if they're larger than ShardSize. The ConfigMaps are owned by the owners of the revision, i.e., the application,
+// so they're garbage collected with it even if the revision is deleted without them.
+type ConfigMapBackend struct{}
+
+// ShardName returns the name of the i-th ConfigMap storing the manifests of the revision
+func ShardName(revName string, i int) string {
+	return fmt.Sprintf("%s-manifests-%d", revName, i)
+}
+
+// Save implements Backend
+func (b ConfigMapBackend) Save(ctx context.Context, c client.Client, rev metav1.Object, data []byte) error {
+	var shards int
+	for offset := 0; offset < len(data) || shards == 0; offset += ShardSize {
+		end := offset + ShardSize
+		if end > len(data) {
+			end = len(data)
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ShardName(rev.GetName(), shards),
+				Namespace:       rev.GetNamespace(),
+				Labels:          map[string]string{oam.LabelManifestRevision: rev.GetName()},
+				OwnerReferences: rev.GetOwnerReferences(),
+			},
+			BinaryData: map[string][]byte{shardDataKey: data[offset:end]},
+		}
+		if err := b.save(ctx, c, cm); err != nil {
+			return errors.Wrapf(err, "cannot save ConfigMap %s", cm.Name)
+		}
+		shards++
+	}
+	util.AddAnnotations(rev, map[string]string{oam.AnnotationManifestShards: strconv.Itoa(shards)})
+	// the revision is updated in place if its spec is unchanged, the shards of the last save beyond the ones
+	// just saved are stale
+	return b.deleteShards(ctx, c, rev, shards)
+}
+
+func (b ConfigMapBackend) save(ctx context.Context, c client.Client, cm *corev1.ConfigMap) error {
+	err := c.Create(ctx, cm)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cm.Namespace, Name: cm.Name}, existing); err != nil {
+		return err
+	}
+	existing.Labels, existing.OwnerReferences = cm.Labels, cm.OwnerReferences
+	existing.Data, existing.BinaryData = nil, cm.BinaryData
+	return c.Update(ctx, existing)
+}
+
+// Load implements Backend
+func (b ConfigMapBackend) Load(ctx context.Context, c client.Reader, rev metav1.Object) ([]byte, error) {
+	shards, err := strconv.Atoi(rev.GetAnnotations()[oam.AnnotationManifestShards])
+	if err != nil || shards <= 0 {
+		return nil, fmt.Errorf("invalid number of manifest shards %q", rev.GetAnnotations()[oam.AnnotationManifestShards])
+	}
+	var data []byte
+	for i := 0; i < shards; i++ {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: rev.GetNamespace(), Name: ShardName(rev.GetName(), i)}, cm); err != nil {
+			return nil, errors.Wrapf(err, "cannot get ConfigMap %s", ShardName(rev.GetName(), i))
+		}
+		data = append(data, cm.BinaryData[shardDataKey]...)
+	}
+	return data, nil
+}
+
+// Delete implements Backend
+func (b ConfigMapBackend) Delete(ctx context.Context, c client.Client, rev metav1.Object) error {
+	return b.deleteShards(ctx, c, rev, 0)
+}
+
+// deleteShards deletes the ConfigMaps of the revision from the from-th shard
+func (b ConfigMapBackend) deleteShards(ctx context.Context, c client.Client, rev metav1.Object, from int) error {
+	cms := &corev1.ConfigMapList{}
+	if err := c.List(ctx, cms, client.InNamespace(rev.GetNamespace()),
+		client.MatchingLabels{oam.LabelManifestRevision: rev.GetName()}); err != nil {
+		return err
+	}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		index, err := strconv.Atoi(strings.TrimPrefix(cm.Name, rev.GetName()+"-manifests-"))
+		if err == nil && index < from {
+			continue
+		}
+		if err := c.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete ConfigMap %s", cm.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifeststore packs the rendered manifests of ApplicationRevisions, i.e., the Components and the
+// ApplicationConfiguration, by compressing them and moving them out of the revisions, so the revisions of large
+// applications stay under the size limit of etcd. The manifests are unpacked lazily by the readers needing them, the
+// definitions and the application snapshotted are always kept in the revisions as they are.
+package manifeststore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DefaultThreshold is the default size in bytes of the rendered manifests above which they're moved out of the
+// revision, it leaves room for the definitions in the revision under the 1MiB limit
+const DefaultThreshold = 512 * 1024
+
+// Backend stores the rendered manifests moved out of application revisions, the client is the one of the cluster
+// the revisions are in, the backends storing the manifests out of the cluster may ignore it
+type Backend interface {
+	// Save stores the manifests of the revision, it may record where they're stored in the annotations of the
+	// revision, the revision is written after the manifests are saved
+	Save(ctx context.Context, c client.Client, rev metav1.Object, data []byte) error
+	// Load returns the manifests of the revision
+	Load(ctx context.Context, c client.Reader, rev metav1.Object) ([]byte, error)
+	// Delete deletes the manifests of the revision, it's not an error if they don't exist
+	Delete(ctx context.Context, c client.Client, rev metav1.Object) error
+}
+
+var backends = map[string]Backend{
+	TypeConfigMap: ConfigMapBackend{},
+}
+
+// RegisterBackend registers a backend by its type, e.g., an external object store. The type is recorded in the
+// revisions whose manifests are saved to the backend, so the backend must be registered in all the components
+// reading the revisions.
+func RegisterBackend(typ string, backend Backend) {
+	backends[typ] = backend
+}
+
+// Types returns the types of the registered backends
+func Types() []string {
+	types := make([]string, 0, len(backends))
+	for typ := range backends {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Options are the options to pack the manifests of revisions
+type Options struct {
+	// Type is the type of the backend, the manifests are kept in the revisions if it's empty
+	Type string
+	// Threshold is the size in bytes of the manifests, after compressed if they are, above which they're moved out
+	// of the revision
+	Threshold int
+	// Compression is the algorithm to compress the manifests by, i.e., gzip or zstd, they're not compressed if
+	// it's empty
+	Compression string
+	// TrackerCompression is the algorithm to compress the resources tracked by the ResourceTrackers by, they're
+	// not compressed if it's empty
+	TrackerCompression string
+}
+
+// Validate checks whether the backend is registered and the compression algorithms are supported
+func (o Options) Validate() error {
+	if err := validCompression(o.Compression); err != nil {
+		return err
+	}
+	if err := validCompression(o.TrackerCompression); err != nil {
+		return err
+	}
+	if len(o.Type) == 0 {
+		return nil
+	}
+	if _, ok := backends[o.Type]; !ok {
+		return fmt.Errorf("unknown manifest store %q, available ones: %s", o.Type, strings.Join(Types(), ", "))
+	}
+	return nil
+}
+
+// manifests is the payload stored by the backends
+type manifests struct {
+	Components               []common.RawComponent `json:"components,omitempty"`
+	ApplicationConfiguration runtime.RawExtension  `json:"applicationConfiguration"`
+}
+
+// Offload packs the manifests of the revision, i.e., compresses them into CompressedManifests if compression is
+// enabled, and moves them to the backend if they're still larger than the threshold. The ApplicationConfiguration
+// is replaced by a stub without components once they're packed, and the compression, the backend and the checksum
+// of the manifests are recorded in the annotations. It must be called after the revision is signed and before it's
+// written.
+func Offload(ctx context.Context, c client.Client, rev *v1beta1.ApplicationRevision, opts Options) error {
+	if len(opts.Type) == 0 && len(opts.Compression) == 0 {
+		return nil
+	}
+	var backend Backend
+	if len(opts.Type) != 0 {
+		var ok bool
+		if backend, ok = backends[opts.Type]; !ok {
+			return fmt.Errorf("unknown manifest store %q", opts.Type)
+		}
+	}
+	data, err := json.Marshal(manifests{Components: rev.Spec.Components, ApplicationConfiguration: rev.Spec.ApplicationConfiguration})
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal the manifests of revision %s", rev.Name)
+	}
+	payload := data
+	if len(opts.Compression) != 0 {
+		if payload, err = compress(opts.Compression, data); err != nil {
+			return errors.Wrapf(err, "cannot compress the manifests of revision %s", rev.Name)
+		}
+	}
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	offload := backend != nil && len(payload) > threshold
+	if !offload && len(opts.Compression) == 0 {
+		return nil
+	}
+	stub, err := stubAppConfig(rev.Spec.ApplicationConfiguration)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid application configuration of revision %s", rev.Name)
+	}
+	util.AddAnnotations(rev, map[string]string{oam.AnnotationManifestChecksum: checksum(data)})
+	if len(opts.Compression) != 0 {
+		util.AddAnnotations(rev, map[string]string{oam.AnnotationManifestCompression: opts.Compression})
+	}
+	if offload {
+		util.AddAnnotations(rev, map[string]string{oam.AnnotationManifestStore: opts.Type})
+		if err := backend.Save(ctx, c, rev, payload); err != nil {
+			return errors.WithMessagef(err, "cannot save the manifests of revision %s", rev.Name)
+		}
+	} else {
+		rev.Spec.CompressedManifests = payload
+	}
+	rev.Spec.Components = nil
+	rev.Spec.ApplicationConfiguration = stub
+	return nil
+}
+
+// Offloaded returns whether the manifests of the revision are moved out of it
+func Offloaded(rev metav1.Object) bool {
+	return len(rev.GetAnnotations()[oam.AnnotationManifestStore]) != 0
+}
+
+// Packed returns whether the manifests of the revision are compressed or moved out of it, the revisions created
+// before the manifests are packed are read as they are
+func Packed(rev metav1.Object) bool {
+	return Offloaded(rev) || len(rev.GetAnnotations()[oam.AnnotationManifestCompression]) != 0
+}
+
+// Load unpacks the manifests of the revision back into it, it does nothing if they're not packed
+func Load(ctx context.Context, c client.Reader, rev *v1beta1.ApplicationRevision) error {
+	m, err := load(ctx, c, rev, rev.Spec.CompressedManifests)
+	if err != nil || m == nil {
+		return err
+	}
+	rev.Spec.Components, rev.Spec.ApplicationConfiguration = m.Components, m.ApplicationConfiguration
+	rev.Spec.CompressedManifests = nil
+	return nil
+}
+
+// LoadV1alpha2 is Load for the v1alpha2 revisions
+func LoadV1alpha2(ctx context.Context, c client.Reader, rev *v1alpha2.ApplicationRevision) error {
+	m, err := load(ctx, c, rev, rev.Spec.CompressedManifests)
+	if err != nil || m == nil {
+		return err
+	}
+	rev.Spec.Components, rev.Spec.ApplicationConfiguration = m.Components, m.ApplicationConfiguration
+	rev.Spec.CompressedManifests = nil
+	return nil
+}
+
+func load(ctx context.Context, c client.Reader, rev metav1.Object, compressed []byte) (*manifests, error) {
+	if !Packed(rev) {
+		return nil, nil
+	}
+	// the manifests compressed in the revision are unpacked already if they're gone
+	if !Offloaded(rev) && len(compressed) == 0 {
+		return nil, nil
+	}
+	data := compressed
+	if typ := rev.GetAnnotations()[oam.AnnotationManifestStore]; len(typ) != 0 {
+		backend, ok := backends[typ]
+		if !ok {
+			return nil, fmt.Errorf("the manifests of revision %s are stored in unknown manifest store %q", rev.GetName(), typ)
+		}
+		var err error
+		if data, err = backend.Load(ctx, c, rev); err != nil {
+			return nil, errors.WithMessagef(err, "cannot load the manifests of revision %s", rev.GetName())
+		}
+	}
+	if algorithm := rev.GetAnnotations()[oam.AnnotationManifestCompression]; len(algorithm) != 0 {
+		var err error
+		if data, err = decompress(algorithm, data); err != nil {
+			return nil, errors.Wrapf(err, "cannot decompress the manifests of revision %s", rev.GetName())
+		}
+	}
+	if sum := rev.GetAnnotations()[oam.AnnotationManifestChecksum]; len(sum) != 0 && sum != checksum(data) {
+		return nil, fmt.Errorf("the checksum of the manifests of revision %s mismatches, they're modified or partially saved", rev.GetName())
+	}
+	m := &manifests{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal the manifests of revision %s", rev.GetName())
+	}
+	return m, nil
+}
+
+// Delete deletes the manifests moved out of the revision, it's called once the revision is deleted. The backends
+// may leave the manifests to be garbage collected with the application as well.
+func Delete(ctx context.Context, c client.Client, rev metav1.Object) error {
+	if !Offloaded(rev) {
+		return nil
+	}
+	backend, ok := backends[rev.GetAnnotations()[oam.AnnotationManifestStore]]
+	if !ok {
+		return nil
+	}
+	return backend.Delete(ctx, c, rev)
+}
+
+// stubAppConfig keeps the type and name of the ApplicationConfiguration only, the readers not loading the manifests
+// find no components rather than failing to decode it
+func stubAppConfig(raw runtime.RawExtension) (runtime.RawExtension, error) {
+	ac, err := util.RawExtension2AppConfig(raw)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return util.Object2RawExtension(&v1alpha2.ApplicationConfiguration{
+		TypeMeta:   ac.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{Name: ac.Name, Namespace: ac.Namespace},
+	}), nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
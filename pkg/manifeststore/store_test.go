@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifeststore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	velacommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newRevision(size int) *v1beta1.ApplicationRevision {
+	comp := &v1alpha2.Component{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: v1alpha2.ComponentKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: v1alpha2.ComponentSpec{Workload: util.Object2RawExtension(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"data":       map[string]interface{}{"blob": strings.Repeat("x", size)},
+		})},
+	}
+	ac := &v1alpha2.ApplicationConfiguration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: v1alpha2.ApplicationConfigurationKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1alpha2.ApplicationConfigurationSpec{
+			Components: []v1alpha2.ApplicationConfigurationComponent{{RevisionName: "web-v1"}},
+		},
+	}
+	return &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v1", Namespace: "default"},
+		Spec: v1beta1.ApplicationRevisionSpec{
+			Components:               []common.RawComponent{{Raw: util.Object2RawExtension(comp)}},
+			ApplicationConfiguration: util.Object2RawExtension(ac),
+		},
+	}
+}
+
+func listShards(t *testing.T, c client.Client) []string {
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, c.List(context.Background(), cms, client.MatchingLabels{oam.LabelManifestRevision: "app-v1"}))
+	var names []string
+	for _, cm := range cms.Items {
+		names = append(names, cm.Name)
+	}
+	return names
+}
+
+func TestOffloadAndLoad(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewFakeClientWithScheme(velacommon.Scheme)
+	opts := Options{Type: TypeConfigMap}
+
+	small := newRevision(16)
+	require.NoError(t, Offload(ctx, c, small, opts))
+	assert.False(t, Offloaded(small))
+	assert.Len(t, small.Spec.Components, 1)
+
+	rev := newRevision(2 * ShardSize)
+	original := rev.DeepCopy()
+	require.NoError(t, Offload(ctx, c, rev, opts))
+	assert.True(t, Offloaded(rev))
+	assert.Equal(t, "3", rev.Annotations[oam.AnnotationManifestShards])
+	assert.Empty(t, rev.Spec.Components)
+	stub, err := util.RawExtension2AppConfig(rev.Spec.ApplicationConfiguration)
+	require.NoError(t, err)
+	assert.Equal(t, "app", stub.Name)
+	assert.Empty(t, stub.Spec.Components)
+	assert.ElementsMatch(t, []string{"app-v1-manifests-0", "app-v1-manifests-1", "app-v1-manifests-2"}, listShards(t, c))
+
+	loaded := rev.DeepCopy()
+	require.NoError(t, Load(ctx, c, loaded))
+	assert.Equal(t, original.Spec.Components, loaded.Spec.Components)
+	assert.Equal(t, original.Spec.ApplicationConfiguration, loaded.Spec.ApplicationConfiguration)
+
+	// the revision updated in place with smaller manifests leaves no stale shards
+	updated := newRevision(ShardSize / 2)
+	require.NoError(t, Offload(ctx, c, updated, Options{Type: TypeConfigMap, Threshold: 1024}))
+	assert.Equal(t, "1", updated.Annotations[oam.AnnotationManifestShards])
+	assert.Equal(t, []string{"app-v1-manifests-0"}, listShards(t, c))
+
+	// the manifests partially saved or modified are not loaded
+	assert.Error(t, Load(ctx, c, rev))
+
+	require.NoError(t, Delete(ctx, c, updated))
+	assert.Empty(t, listShards(t, c))
+	assert.Error(t, Load(ctx, c, updated))
+}
+
+func TestCompress(t *testing.T) {
+	ctx := context.Background()
+	for _, algorithm := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(algorithm, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(velacommon.Scheme)
+
+			// the revisions created before the manifests are packed are read as they are
+			legacy := newRevision(1024)
+			require.NoError(t, Load(ctx, c, legacy))
+			assert.Len(t, legacy.Spec.Components, 1)
+
+			rev := newRevision(1024)
+			original := rev.DeepCopy()
+			require.NoError(t, Offload(ctx, c, rev, Options{Type: TypeConfigMap, Compression: algorithm}))
+			assert.False(t, Offloaded(rev))
+			assert.True(t, Packed(rev))
+			assert.Equal(t, algorithm, rev.Annotations[oam.AnnotationManifestCompression])
+			assert.Empty(t, rev.Spec.Components)
+			assert.NotEmpty(t, rev.Spec.CompressedManifests)
+			assert.Less(t, len(rev.Spec.CompressedManifests), 1024)
+			assert.Empty(t, listShards(t, c))
+
+			loaded := rev.DeepCopy()
+			require.NoError(t, Load(ctx, c, loaded))
+			assert.Equal(t, original.Spec.Components, loaded.Spec.Components)
+			assert.Equal(t, original.Spec.ApplicationConfiguration, loaded.Spec.ApplicationConfiguration)
+			assert.Empty(t, loaded.Spec.CompressedManifests)
+			// it's a no-op to load the manifests unpacked
+			require.NoError(t, Load(ctx, c, loaded))
+			assert.Equal(t, original.Spec.Components, loaded.Spec.Components)
+
+			// the manifests still larger than the threshold after compressed are moved out of the revision
+			large := newRevision(2048)
+			require.NoError(t, Offload(ctx, c, large, Options{Type: TypeConfigMap, Threshold: 16, Compression: algorithm}))
+			assert.True(t, Offloaded(large))
+			assert.Empty(t, large.Spec.CompressedManifests)
+			assert.Equal(t, []string{"app-v1-manifests-0"}, listShards(t, c))
+			require.NoError(t, Load(ctx, c, large))
+			assert.Len(t, large.Spec.Components, 1)
+
+			// the payloads decompressed larger than the maximum are rejected
+			bomb := newRevision(4096)
+			require.NoError(t, Offload(ctx, c, bomb, Options{Compression: algorithm}))
+			SetMaxDecompressedSize(1024)
+			defer SetMaxDecompressedSize(0)
+			assert.Error(t, Load(ctx, c, bomb))
+		})
+	}
+}
+
+func TestCompressResourceTracker(t *testing.T) {
+	for _, algorithm := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(algorithm, func(t *testing.T) {
+			rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "default-app"}}
+			rt.Status.TrackedResources = []v1beta1.TypedReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web", Cluster: "east"},
+			}
+			rt.Status.PendingDeletions = []common.PendingDeletion{
+				{APIVersion: "v1", Kind: "Service", Namespace: "prod", Name: "web", Since: metav1.Unix(1600000000, 0)},
+			}
+			original := rt.DeepCopy()
+			// the ResourceTrackers written before compression is enabled are read as they are
+			require.NoError(t, LoadResourceTracker(rt))
+			assert.Equal(t, original, rt)
+
+			require.NoError(t, CompressResourceTracker(rt, algorithm))
+			assert.Equal(t, algorithm, rt.Status.Compression)
+			assert.NotEmpty(t, rt.Status.CompressedResources)
+			assert.Empty(t, rt.Status.TrackedResources)
+			assert.Empty(t, rt.Status.PendingDeletions)
+
+			require.NoError(t, LoadResourceTracker(rt))
+			assert.Equal(t, original, rt)
+
+			// the ResourceTrackers compressed before are decompressed if compression is disabled
+			require.NoError(t, CompressResourceTracker(rt, algorithm))
+			require.NoError(t, CompressResourceTracker(rt, ""))
+			assert.Equal(t, original, rt)
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{Type: TypeConfigMap}.Validate())
+	assert.NoError(t, Options{Compression: CompressionZstd}.Validate())
+	assert.EqualError(t, Options{Type: "s3"}.Validate(), `unknown manifest store "s3", available ones: configmap`)
+	assert.EqualError(t, Options{Compression: "lz4"}.Validate(), `unknown compression algorithm "lz4", available ones: gzip, zstd`)
+	assert.Error(t, Options{TrackerCompression: "lz4"}.Validate())
+}
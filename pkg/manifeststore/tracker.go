@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifeststore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// trackedResources is the payload of the compressed ResourceTrackers
+type trackedResources struct {
+	TrackedResources []v1beta1.TypedReference `json:"trackedResources,omitempty"`
+	PendingDeletions []common.PendingDeletion `json:"pendingDeletions,omitempty"`
+}
+
+// CompressResourceTracker compresses the tracked resources and the pending deletions of the ResourceTracker into
+// CompressedResources by the algorithm, it's called right before the status is written. The ResourceTracker is
+// decompressed again if the algorithm is empty.
+func CompressResourceTracker(rt *v1beta1.ResourceTracker, algorithm string) error {
+	if err := LoadResourceTracker(rt); err != nil {
+		return err
+	}
+	if len(algorithm) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(trackedResources{TrackedResources: rt.Status.TrackedResources, PendingDeletions: rt.Status.PendingDeletions})
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal the resources tracked by %s", rt.Name)
+	}
+	compressed, err := compress(algorithm, data)
+	if err != nil {
+		return errors.Wrapf(err, "cannot compress the resources tracked by %s", rt.Name)
+	}
+	rt.Status.Compression, rt.Status.CompressedResources = algorithm, compressed
+	rt.Status.TrackedResources, rt.Status.PendingDeletions = nil, nil
+	return nil
+}
+
+// LoadResourceTracker decompresses the tracked resources and the pending deletions of the ResourceTracker back into
+// it, it does nothing if they're not compressed, e.g., the ResourceTrackers written before compression is enabled
+func LoadResourceTracker(rt *v1beta1.ResourceTracker) error {
+	if len(rt.Status.Compression) == 0 {
+		return nil
+	}
+	data, err := decompress(rt.Status.Compression, rt.Status.CompressedResources)
+	if err != nil {
+		return errors.Wrapf(err, "cannot decompress the resources tracked by %s", rt.Name)
+	}
+	tracked := &trackedResources{}
+	if err := json.Unmarshal(data, tracked); err != nil {
+		return errors.Wrapf(err, "cannot unmarshal the resources tracked by %s", rt.Name)
+	}
+	rt.Status.TrackedResources, rt.Status.PendingDeletions = tracked.TrackedResources, tracked.PendingDeletions
+	rt.Status.Compression, rt.Status.CompressedResources = "", nil
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metering aggregates the usage of applications per namespace and team for chargeback or showback
+package metering
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// Collect aggregates the usage of the applications in all namespaces, namespaces without applications are omitted.
+// The team of a namespace is read from its oam.LabelMeteringTeam label. The resource requests are summed from the
+// running pods labeled with a component in the control plane cluster.
+func Collect(ctx context.Context, c client.Reader) (*v1beta1.UsageReportSpec, error) {
+	apps := &v1beta1.ApplicationList{}
+	if err := c.List(ctx, apps); err != nil {
+		return nil, errors.Wrap(err, "cannot list applications")
+	}
+	usages := map[string]*v1beta1.NamespaceUsage{}
+	for i := range apps.Items {
+		app := &apps.Items[i]
+		u, ok := usages[app.Namespace]
+		if !ok {
+			u = &v1beta1.NamespaceUsage{Namespace: app.Namespace}
+			usages[app.Namespace] = u
+		}
+		u.Applications++
+		u.Components += len(app.Spec.Components)
+		dispatched, err := dispatchedObjectsOf(ctx, c, app)
+		if err != nil {
+			return nil, err
+		}
+		u.DispatchedObjects += dispatched
+	}
+	if len(usages) == 0 {
+		return &v1beta1.UsageReportSpec{CollectedAt: metav1.Now()}, nil
+	}
+
+	// the pods are listed page by page since there can be a lot of them on big clusters
+	podQuery := util.ObjectQuery{
+		LabelSelector: oam.LabelAppComponent,
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+		).String(),
+	}
+	err := util.EachObject(ctx, c, corev1.SchemeGroupVersion.WithKind("Pod"), podQuery, func(obj *unstructured.Unstructured) error {
+		u, ok := usages[obj.GetNamespace()]
+		if !ok {
+			return nil
+		}
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+			return errors.Wrapf(err, "cannot convert pod %s/%s", obj.GetNamespace(), obj.GetName())
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			return nil
+		}
+		for _, ctr := range pod.Spec.Containers {
+			u.Requests = addResources(u.Requests, ctr.Resources.Requests)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot list the pods of components")
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces); err != nil {
+		return nil, errors.Wrap(err, "cannot list namespaces")
+	}
+	for _, ns := range namespaces.Items {
+		if u, ok := usages[ns.Name]; ok {
+			u.Team = ns.Labels[oam.LabelMeteringTeam]
+		}
+	}
+
+	spec := &v1beta1.UsageReportSpec{CollectedAt: metav1.Now()}
+	teams := map[string]*v1beta1.TeamUsage{}
+	for _, u := range usages {
+		spec.Namespaces = append(spec.Namespaces, *u)
+		if len(u.Team) == 0 {
+			continue
+		}
+		t, ok := teams[u.Team]
+		if !ok {
+			t = &v1beta1.TeamUsage{Team: u.Team}
+			teams[u.Team] = t
+		}
+		t.Applications += u.Applications
+		t.Components += u.Components
+		t.DispatchedObjects += u.DispatchedObjects
+		t.Requests = addResources(t.Requests, u.Requests)
+	}
+	for _, t := range teams {
+		spec.Teams = append(spec.Teams, *t)
+	}
+	sort.Slice(spec.Namespaces, func(i, j int) bool { return spec.Namespaces[i].Namespace < spec.Namespaces[j].Namespace })
+	sort.Slice(spec.Teams, func(i, j int) bool { return spec.Teams[i].Team < spec.Teams[j].Team })
+	return spec, nil
+}
+
+// dispatchedObjectsOf counts the workloads and traits of the application in its status, plus the resources
+// dispatched to managed clusters tracked in its ResourceTracker
+func dispatchedObjectsOf(ctx context.Context, c client.Reader, app *v1beta1.Application) (int, error) {
+	n := 0
+	for _, svc := range app.Status.Services {
+		n += 1 + len(svc.Traits)
+	}
+	if app.Status.ResourceTracker == nil || len(app.Status.ResourceTracker.Name) == 0 {
+		return n, nil
+	}
+	rt := &v1beta1.ResourceTracker{}
+	if err := c.Get(ctx, client.ObjectKey{Name: app.Status.ResourceTracker.Name}, rt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return n, nil
+		}
+		return 0, errors.Wrapf(err, "cannot get the resource tracker of application %s/%s", app.Namespace, app.Name)
+	}
+	if err := manifeststore.LoadResourceTracker(rt); err != nil {
+		return 0, err
+	}
+	for _, ref := range rt.Status.TrackedResources {
+		if len(ref.Cluster) != 0 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// addResources adds the quantities of b to a, a is allocated if it's nil and b is not empty
+func addResources(a, b corev1.ResourceList) corev1.ResourceList {
+	if len(b) == 0 {
+		return a
+	}
+	if a == nil {
+		a = corev1.ResourceList{}
+	}
+	for name, q := range b {
+		sum := a[name]
+		sum.Add(q)
+		a[name] = sum
+	}
+	return a
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metering
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func componentPod(ns, name, phase, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: map[string]string{oam.LabelAppComponent: "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "main",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodPhase(phase)},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev", Labels: map[string]string{oam.LabelMeteringTeam: "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-prod", Labels: map[string]string{oam.LabelMeteringTeam: "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}},
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a-dev", Name: "app1"},
+			Spec: v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{
+				{Name: "web", Type: "webservice"}, {Name: "db", Type: "worker"},
+			}},
+			Status: common.AppStatus{
+				Services: []common.ApplicationComponentStatus{
+					{Name: "web", Traits: []common.ApplicationTraitStatus{{Type: "scaler"}, {Type: "ingress"}}},
+					{Name: "db"},
+				},
+				ResourceTracker: &runtimev1alpha1.TypedReference{Name: "team-a-dev-app1"},
+			},
+		},
+		&v1beta1.ResourceTracker{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev-app1"},
+			Status: v1beta1.ResourceTrackerStatus{TrackedResources: []v1beta1.TypedReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Cluster: "cluster-1"},
+			}},
+		},
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a-prod", Name: "app1"},
+			Spec:       v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{{Name: "web", Type: "webservice"}}},
+			Status: common.AppStatus{
+				Services: []common.ApplicationComponentStatus{{Name: "web"}},
+				// the resource tracker is gone, only the status is counted
+				ResourceTracker: &runtimev1alpha1.TypedReference{Name: "team-a-prod-app1"},
+			},
+		},
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "sandbox", Name: "app2"},
+			Spec:       v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{{Name: "web", Type: "webservice"}}},
+		},
+		componentPod("team-a-dev", "web-1", "Running", "500m", "128Mi"),
+		componentPod("team-a-dev", "web-2", "Running", "250m", "128Mi"),
+		componentPod("team-a-dev", "job-1", "Succeeded", "1", "1Gi"),
+		componentPod("team-a-prod", "web-1", "Pending", "1", "512Mi"),
+		// pods in namespaces without applications are not counted
+		componentPod("default", "web-1", "Running", "1", "512Mi"),
+	)
+
+	spec, err := Collect(context.Background(), c)
+	require.NoError(t, err)
+	assert.False(t, spec.CollectedAt.IsZero())
+	require.Len(t, spec.Namespaces, 3)
+
+	sandbox, dev, prod := spec.Namespaces[0], spec.Namespaces[1], spec.Namespaces[2]
+	assert.Equal(t, "sandbox", sandbox.Namespace)
+	assert.Equal(t, "", sandbox.Team)
+	assert.Equal(t, 1, sandbox.Applications)
+	assert.Equal(t, 0, sandbox.DispatchedObjects)
+	assert.Empty(t, sandbox.Requests)
+
+	assert.Equal(t, "team-a-dev", dev.Namespace)
+	assert.Equal(t, "a", dev.Team)
+	assert.Equal(t, 1, dev.Applications)
+	assert.Equal(t, 2, dev.Components)
+	assert.Equal(t, 5, dev.DispatchedObjects)
+	assert.Equal(t, "750m", dev.Requests.Cpu().String())
+	assert.Equal(t, "256Mi", dev.Requests.Memory().String())
+
+	assert.Equal(t, "team-a-prod", prod.Namespace)
+	assert.Equal(t, 1, prod.DispatchedObjects)
+
+	require.Len(t, spec.Teams, 1)
+	team := spec.Teams[0]
+	assert.Equal(t, "a", team.Team)
+	assert.Equal(t, 2, team.Applications)
+	assert.Equal(t, 3, team.Components)
+	assert.Equal(t, 6, team.DispatchedObjects)
+	assert.Equal(t, "1750m", team.Requests.Cpu().String())
+	assert.Equal(t, "768Mi", team.Requests.Memory().String())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteCSV(buf, spec))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "collectedAt,namespace,team,applications,components,dispatchedObjects,requests.cpu,requests.memory", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",sandbox,,1,1,0,0,0"))
+	assert.True(t, strings.HasSuffix(lines[2], ",team-a-dev,a,1,2,5,750m,256Mi"))
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metering
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// WriteCSV writes the usage of each namespace in the report as CSV, a requests.<resource> column is added for each
+// resource requested in any namespace
+func WriteCSV(w io.Writer, spec *v1beta1.UsageReportSpec) error {
+	resourceSet := map[corev1.ResourceName]bool{}
+	for _, u := range spec.Namespaces {
+		for name := range u.Requests {
+			resourceSet[name] = true
+		}
+	}
+	resources := make([]string, 0, len(resourceSet))
+	for name := range resourceSet {
+		resources = append(resources, string(name))
+	}
+	sort.Strings(resources)
+
+	cw := csv.NewWriter(w)
+	header := []string{"collectedAt", "namespace", "team", "applications", "components", "dispatchedObjects"}
+	for _, name := range resources {
+		header = append(header, "requests."+name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	collectedAt := spec.CollectedAt.UTC().Format("2006-01-02T15:04:05Z")
+	for _, u := range spec.Namespaces {
+		row := []string{collectedAt, u.Namespace, u.Team, strconv.Itoa(u.Applications), strconv.Itoa(u.Components),
+			strconv.Itoa(u.DispatchedObjects)}
+		for _, name := range resources {
+			q, ok := u.Requests[corev1.ResourceName(name)]
+			if !ok {
+				row = append(row, "0")
+				continue
+			}
+			row = append(row, q.String())
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metering
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+var (
+	applicationsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_usage_applications",
+		Help: "The number of applications in the namespace.",
+	}, []string{"namespace", "team"})
+	componentsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_usage_components",
+		Help: "The number of components of the applications in the namespace.",
+	}, []string{"namespace", "team"})
+	dispatchedObjectsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_usage_dispatched_objects",
+		Help: "The number of workloads and traits dispatched by the applications in the namespace.",
+	}, []string{"namespace", "team"})
+	requestsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubevela_usage_resource_requests",
+		Help: "The resources requested by the pods of the components in the namespace, cpu in cores and the others in units.",
+	}, []string{"namespace", "team", "resource"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(applicationsGauge, componentsGauge, dispatchedObjectsGauge, requestsGauge)
+}
+
+// Export sets the usage metrics of the namespaces by the report, the namespaces not in the report are removed
+func Export(spec *v1beta1.UsageReportSpec) {
+	applicationsGauge.Reset()
+	componentsGauge.Reset()
+	dispatchedObjectsGauge.Reset()
+	requestsGauge.Reset()
+	for _, u := range spec.Namespaces {
+		applicationsGauge.WithLabelValues(u.Namespace, u.Team).Set(float64(u.Applications))
+		componentsGauge.WithLabelValues(u.Namespace, u.Team).Set(float64(u.Components))
+		dispatchedObjectsGauge.WithLabelValues(u.Namespace, u.Team).Set(float64(u.DispatchedObjects))
+		for name, q := range u.Requests {
+			requestsGauge.WithLabelValues(u.Namespace, u.Team, string(name)).Set(float64(q.MilliValue()) / 1000)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration migrates the legacy v1alpha2 ApplicationConfigurations and their Components to v1beta1
+// Applications. The workloads and traits are kept as they are by the raw component and the raw-trait trait, so the
+// running resources are adopted by the Applications without being recreated.
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	// RawComponentType is the type of the components converted from the legacy Components, it outputs the workload
+	// in the properties as it is
+	RawComponentType = "raw"
+	// RawTraitType is the type of the traits converted from the legacy traits, it outputs the trait in the
+	// properties as it is
+	RawTraitType = "raw-trait"
+)
+
+// Source is a legacy ApplicationConfiguration with the definitions it refers to
+type Source struct {
+	AppConfig *v1alpha2.ApplicationConfiguration
+	// Components are the Components keyed by the componentName or the revisionName the AppConfig refers them by,
+	// the ones referred by revisionName are unpacked from the ControllerRevisions
+	Components map[string]*v1alpha2.Component
+	// WorkloadRefPaths are the workloadRefPath of the TraitDefinitions keyed by the group kinds of the traits, the
+	// workload references of the traits are set explicitly as the raw-trait doesn't set them
+	WorkloadRefPaths map[schema.GroupKind]string
+	// ScopeTypes are the names of the ScopeDefinitions keyed by the group kinds of the scopes
+	ScopeTypes map[schema.GroupKind]string
+}
+
+// Convert converts the legacy ApplicationConfiguration to an Application. The workloads are named after the ones
+// running, i.e., the ones in the status of the AppConfig, so they're adopted rather than recreated. The features
+// without an equivalent in Applications are returned as warnings, e.g., the values passed by the data inputs, the
+// dependencies are kept by dependsOn though.
+func Convert(src Source) (*v1beta1.Application, []string, error) {
+	ac := src.AppConfig
+	app := &v1beta1.Application{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: v1beta1.ApplicationKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ac.Name,
+			Namespace:   ac.Namespace,
+			Labels:      copyMap(ac.Labels),
+			Annotations: copyMap(ac.Annotations),
+		},
+	}
+	delete(app.Annotations, oam.AnnotationMigrate)
+	delete(app.Annotations, oam.AnnotationLastAppliedConfig)
+	delete(app.Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	util.AddAnnotations(app, map[string]string{oam.AnnotationMigratedFrom: ac.Name})
+
+	// the components emitting the data outputs, the ones taking them as inputs depend on them
+	outputs := map[string]string{}
+	for _, acc := range ac.Spec.Components {
+		for _, out := range acc.DataOutputs {
+			outputs[out.Name] = componentNameOf(acc)
+		}
+		for _, ct := range acc.Traits {
+			for _, out := range ct.DataOutputs {
+				outputs[out.Name] = componentNameOf(acc)
+			}
+		}
+	}
+	running := map[string]string{}
+	for _, w := range ac.Status.Workloads {
+		if len(w.ComponentName) != 0 && len(w.Reference.Name) != 0 {
+			running[w.ComponentName] = w.Reference.Name
+		}
+	}
+
+	var warnings, revisions []string
+	for _, acc := range ac.Spec.Components {
+		name := componentNameOf(acc)
+		key := acc.ComponentName
+		if len(key) == 0 {
+			key = acc.RevisionName
+		}
+		comp, ok := src.Components[key]
+		if !ok {
+			return nil, nil, errors.Errorf("component %s is not found", key)
+		}
+		if comp.Spec.Helm != nil {
+			return nil, nil, errors.Errorf("component %s is a Helm component, which cannot be migrated", name)
+		}
+		workload, err := renderWorkload(comp, acc.ParameterValues)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot render the workload of component %s", name)
+		}
+		switch {
+		case len(running[name]) != 0:
+			workload.SetName(running[name])
+		case len(workload.GetName()) == 0:
+			workload.SetName(name)
+		}
+		switch {
+		case len(acc.RevisionName) != 0:
+			revisions = append(revisions, name+"="+acc.RevisionName)
+		case comp.Status.LatestRevision != nil:
+			revisions = append(revisions, name+"="+comp.Status.LatestRevision.Name)
+		}
+
+		appComp := v1beta1.ApplicationComponent{
+			Name:       name,
+			Type:       RawComponentType,
+			Properties: util.Object2RawExtension(workload.Object),
+		}
+		for i := range acc.Traits {
+			ct := acc.Traits[i]
+			trait, err := util.RawExtension2Unstructured(&ct.Trait)
+			if err != nil {
+				return nil, nil, errors.WithMessagef(err, "invalid trait of component %s", name)
+			}
+			if path := src.WorkloadRefPaths[trait.GroupVersionKind().GroupKind()]; len(path) != 0 {
+				ref := map[string]interface{}{
+					"apiVersion": workload.GetAPIVersion(),
+					"kind":       workload.GetKind(),
+					"name":       workload.GetName(),
+				}
+				if err := fieldpath.Pave(trait.Object).SetValue(path, ref); err != nil {
+					return nil, nil, errors.Wrapf(err, "cannot set the workload reference of trait %s of component %s", trait.GetKind(), name)
+				}
+			}
+			if len(ct.DataInputs) != 0 {
+				warnings = append(warnings, fmt.Sprintf("the data inputs of trait %s of component %s are not migrated", trait.GetKind(), name))
+			}
+			appComp.Traits = append(appComp.Traits, v1beta1.ApplicationTrait{
+				Type:       RawTraitType,
+				Properties: util.Object2RawExtension(trait.Object),
+			})
+		}
+		for _, cs := range acc.Scopes {
+			gk := schema.FromAPIVersionAndKind(cs.ScopeReference.APIVersion, cs.ScopeReference.Kind).GroupKind()
+			scopeType, ok := src.ScopeTypes[gk]
+			if !ok {
+				return nil, nil, errors.Errorf("the definition of scope %s of component %s is not found", gk, name)
+			}
+			if appComp.Scopes == nil {
+				appComp.Scopes = map[string]string{}
+			}
+			appComp.Scopes[scopeType] = cs.ScopeReference.Name
+		}
+		for _, in := range acc.DataInputs {
+			dep := outputs[in.ValueFrom.DataOutputName]
+			if len(dep) != 0 && dep != name && !contains(appComp.DependsOn, dep) {
+				appComp.DependsOn = append(appComp.DependsOn, dep)
+			}
+			if len(in.ToFieldPaths) != 0 {
+				warnings = append(warnings, fmt.Sprintf("the value of data input %s of component %s is not passed, only the dependency is migrated",
+					in.ValueFrom.DataOutputName, name))
+			}
+		}
+		app.Spec.Components = append(app.Spec.Components, appComp)
+	}
+	if len(revisions) != 0 {
+		sort.Strings(revisions)
+		util.AddAnnotations(app, map[string]string{oam.AnnotationMigratedRevisions: strings.Join(revisions, ",")})
+	}
+	return app, warnings, nil
+}
+
+// componentNameOf returns the name of the component the AppConfig refers to, by name or by revision
+func componentNameOf(acc v1alpha2.ApplicationConfigurationComponent) string {
+	if len(acc.ComponentName) != 0 {
+		return acc.ComponentName
+	}
+	return utils.ExtractComponentName(acc.RevisionName)
+}
+
+// renderWorkload sets the parameter values to the field paths of the parameters of the component, the same as the
+// ApplicationConfiguration controller does
+func renderWorkload(comp *v1alpha2.Component, values []v1alpha2.ComponentParameterValue) (*unstructured.Unstructured, error) {
+	workload, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]v1alpha2.ComponentParameter{}
+	for _, p := range comp.Spec.Parameters {
+		params[p.Name] = p
+	}
+	set := map[string]bool{}
+	paved := fieldpath.Pave(workload.Object)
+	for _, v := range values {
+		p, ok := params[v.Name]
+		if !ok {
+			return nil, errors.Errorf("unsupported parameter %q", v.Name)
+		}
+		set[v.Name] = true
+		for _, path := range p.FieldPaths {
+			switch v.Value.Type {
+			case intstr.String:
+				err = paved.SetString(path, v.Value.StrVal)
+			case intstr.Int:
+				err = paved.SetNumber(path, float64(v.Value.IntVal))
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot set parameter %q", v.Name)
+			}
+		}
+	}
+	for _, p := range comp.Spec.Parameters {
+		if p.Required != nil && *p.Required && !set[p.Name] {
+			return nil, errors.Errorf("required parameter %q is not specified", p.Name)
+		}
+	}
+	return &unstructured.Unstructured{Object: paved.UnstructuredContent()}, nil
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func raw(s string) runtime.RawExtension {
+	return runtime.RawExtension{Raw: []byte(s)}
+}
+
+func properties(t *testing.T, ext runtime.RawExtension) map[string]interface{} {
+	m := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(ext.Raw, &m))
+	return m
+}
+
+func TestConvert(t *testing.T) {
+	ac := &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "default",
+			Labels:    map[string]string{"team": "a"},
+			Annotations: map[string]string{
+				oam.AnnotationMigrate: "true",
+				"note":                "kept",
+			},
+		},
+		Spec: v1alpha2.ApplicationConfigurationSpec{
+			Components: []v1alpha2.ApplicationConfigurationComponent{
+				{
+					ComponentName: "db",
+					DataOutputs:   []v1alpha2.DataOutput{{Name: "db-conn", FieldPath: "status.conn"}},
+				},
+				{
+					RevisionName:    "web-v2",
+					ParameterValues: []v1alpha2.ComponentParameterValue{{Name: "image", Value: intstr.FromString("nginx:1.20")}},
+					DataInputs: []v1alpha2.DataInput{{
+						ValueFrom:    v1alpha2.DataInputValueFrom{DataOutputName: "db-conn"},
+						ToFieldPaths: []string{"spec.conn"},
+					}},
+					Traits: []v1alpha2.ComponentTrait{{
+						Trait: raw(`{"apiVersion":"core.oam.dev/v1alpha2","kind":"ManualScalerTrait","spec":{"replicaCount":3}}`),
+					}},
+					Scopes: []v1alpha2.ComponentScope{{
+						ScopeReference: runtimev1alpha1.TypedReference{APIVersion: "core.oam.dev/v1alpha2", Kind: "HealthScope", Name: "health"},
+					}},
+				},
+			},
+		},
+		Status: v1alpha2.ApplicationConfigurationStatus{
+			Workloads: []v1alpha2.WorkloadStatus{{
+				ComponentName: "db",
+				Reference:     runtimev1alpha1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "db-running"},
+			}},
+		},
+	}
+	src := Source{
+		AppConfig: ac,
+		Components: map[string]*v1alpha2.Component{
+			"db": {
+				Spec: v1alpha2.ComponentSpec{Workload: raw(`{"apiVersion":"apps/v1","kind":"Deployment"}`)},
+				Status: v1alpha2.ComponentStatus{
+					LatestRevision: &common.Revision{Name: "db-v1", Revision: 1},
+				},
+			},
+			"web-v2": {
+				Spec: v1alpha2.ComponentSpec{
+					Workload:   raw(`{"apiVersion":"apps/v1","kind":"Deployment","spec":{"image":"nginx"}}`),
+					Parameters: []v1alpha2.ComponentParameter{{Name: "image", FieldPaths: []string{"spec.image"}}},
+				},
+			},
+		},
+		WorkloadRefPaths: map[schema.GroupKind]string{
+			{Group: "core.oam.dev", Kind: "ManualScalerTrait"}: "spec.workloadRef",
+		},
+		ScopeTypes: map[schema.GroupKind]string{
+			{Group: "core.oam.dev", Kind: "HealthScope"}: "healthscopes.core.oam.dev",
+		},
+	}
+
+	app, warnings, err := Convert(src)
+	require.NoError(t, err)
+	assert.Equal(t, "myapp", app.Name)
+	assert.Equal(t, "default", app.Namespace)
+	assert.Equal(t, map[string]string{"team": "a"}, app.Labels)
+	assert.Equal(t, map[string]string{
+		"note":                          "kept",
+		oam.AnnotationMigratedFrom:      "myapp",
+		oam.AnnotationMigratedRevisions: "db=db-v1,web=web-v2",
+	}, app.Annotations)
+	assert.Equal(t, "true", ac.Annotations[oam.AnnotationMigrate], "the AppConfig must not be mutated")
+	assert.Len(t, warnings, 1)
+
+	require.Len(t, app.Spec.Components, 2)
+	db := app.Spec.Components[0]
+	assert.Equal(t, "db", db.Name)
+	assert.Equal(t, RawComponentType, db.Type)
+	assert.Equal(t, "db-running", properties(t, db.Properties)["metadata"].(map[string]interface{})["name"])
+
+	web := app.Spec.Components[1]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, []string{"db"}, web.DependsOn)
+	assert.Equal(t, map[string]string{"healthscopes.core.oam.dev": "health"}, web.Scopes)
+	workload := properties(t, web.Properties)
+	assert.Equal(t, "web", workload["metadata"].(map[string]interface{})["name"])
+	assert.Equal(t, "nginx:1.20", workload["spec"].(map[string]interface{})["image"])
+	require.Len(t, web.Traits, 1)
+	assert.Equal(t, RawTraitType, web.Traits[0].Type)
+	assert.Equal(t, map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+		properties(t, web.Traits[0].Properties)["spec"].(map[string]interface{})["workloadRef"])
+}
+
+func TestConvertErrors(t *testing.T) {
+	required := true
+	ac := &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp"},
+		Spec: v1alpha2.ApplicationConfigurationSpec{
+			Components: []v1alpha2.ApplicationConfigurationComponent{{ComponentName: "web"}},
+		},
+	}
+	testCases := map[string]struct {
+		components map[string]*v1alpha2.Component
+		err        string
+	}{
+		"component not found": {
+			components: map[string]*v1alpha2.Component{},
+			err:        "component web is not found",
+		},
+		"helm component": {
+			components: map[string]*v1alpha2.Component{"web": {Spec: v1alpha2.ComponentSpec{
+				Workload: raw(`{"apiVersion":"apps/v1","kind":"Deployment"}`),
+				Helm:     &common.Helm{},
+			}}},
+			err: "component web is a Helm component, which cannot be migrated",
+		},
+		"required parameter": {
+			components: map[string]*v1alpha2.Component{"web": {Spec: v1alpha2.ComponentSpec{
+				Workload:   raw(`{"apiVersion":"apps/v1","kind":"Deployment"}`),
+				Parameters: []v1alpha2.ComponentParameter{{Name: "image", FieldPaths: []string{"spec.image"}, Required: &required}},
+			}}},
+			err: `cannot render the workload of component web: required parameter "image" is not specified`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Convert(Source{AppConfig: ac, Components: tc.components})
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// Options are the options of migrating an ApplicationConfiguration
+type Options struct {
+	// DryRun converts the ApplicationConfiguration without creating the Application
+	DryRun bool
+	// DeleteLegacy deletes the ApplicationConfiguration and the Components not referred by other ones after the
+	// Application is created, the workloads, traits and ComponentRevisions are orphaned rather than deleted, so
+	// they're adopted by the Application
+	DeleteLegacy bool
+}
+
+// Result is the result of migrating an ApplicationConfiguration
+type Result struct {
+	Application *v1beta1.Application
+	Warnings    []string
+	// Created is false if the Application has been migrated before or it's a dry run
+	Created bool
+}
+
+// Requested returns whether the ApplicationConfiguration is requested to be migrated by oam.AnnotationMigrate
+func Requested(ac *v1alpha2.ApplicationConfiguration) bool {
+	return ac.GetAnnotations()[oam.AnnotationMigrate] == "true"
+}
+
+// Load loads the Components and the definitions the ApplicationConfiguration refers to
+func Load(ctx context.Context, c client.Reader, dm discoverymapper.DiscoveryMapper, ac *v1alpha2.ApplicationConfiguration) (Source, error) {
+	src := Source{
+		AppConfig:        ac,
+		Components:       map[string]*v1alpha2.Component{},
+		WorkloadRefPaths: map[schema.GroupKind]string{},
+		ScopeTypes:       map[schema.GroupKind]string{},
+	}
+	ctx = util.SetNamespaceInCtx(ctx, ac.Namespace)
+	for _, acc := range ac.Spec.Components {
+		comp, _, err := util.GetComponent(ctx, c, acc, ac.Namespace)
+		if err != nil {
+			return src, err
+		}
+		key := acc.ComponentName
+		if len(key) == 0 {
+			key = acc.RevisionName
+		}
+		src.Components[key] = comp
+		for i := range acc.Traits {
+			trait, err := util.RawExtension2Unstructured(&acc.Traits[i].Trait)
+			if err != nil {
+				return src, errors.WithMessagef(err, "invalid trait of component %s", key)
+			}
+			td, err := util.FetchTraitDefinition(ctx, c, dm, trait)
+			if err != nil {
+				if kerrors.IsNotFound(err) {
+					continue
+				}
+				return src, errors.WithMessagef(err, "cannot get the definition of trait %s of component %s", trait.GetKind(), key)
+			}
+			src.WorkloadRefPaths[trait.GroupVersionKind().GroupKind()] = td.Spec.WorkloadRefPath
+		}
+		for _, cs := range acc.Scopes {
+			scope := &unstructured.Unstructured{}
+			scope.SetAPIVersion(cs.ScopeReference.APIVersion)
+			scope.SetKind(cs.ScopeReference.Kind)
+			sd, err := util.FetchScopeDefinition(ctx, c, dm, scope)
+			if err != nil {
+				return src, errors.WithMessagef(err, "cannot get the definition of scope %s of component %s", scope.GetKind(), key)
+			}
+			src.ScopeTypes[scope.GroupVersionKind().GroupKind()] = sd.Name
+		}
+	}
+	return src, nil
+}
+
+// Migrate converts the ApplicationConfiguration to an Application and creates it. It's idempotent, the Application
+// migrated before is kept as it is, while an existing Application not migrated from the ApplicationConfiguration
+// fails the migration.
+func Migrate(ctx context.Context, c client.Client, dm discoverymapper.DiscoveryMapper, ac *v1alpha2.ApplicationConfiguration, opts Options) (*Result, error) {
+	src, err := Load(ctx, c, dm, ac)
+	if err != nil {
+		return nil, err
+	}
+	app, warnings, err := Convert(src)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Application: app, Warnings: warnings}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	existing := &v1beta1.Application{}
+	err = c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Name}, existing)
+	switch {
+	case err == nil:
+		if existing.GetAnnotations()[oam.AnnotationMigratedFrom] != ac.Name {
+			return nil, errors.Errorf("application %s already exists and is not migrated from the application configuration", app.Name)
+		}
+		result.Application = existing
+	case kerrors.IsNotFound(err):
+		if err := c.Create(ctx, app); err != nil {
+			return nil, errors.Wrapf(err, "cannot create application %s", app.Name)
+		}
+		result.Created = true
+	default:
+		return nil, err
+	}
+
+	if opts.DeleteLegacy {
+		if err := deleteLegacy(ctx, c, ac); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// deleteLegacy deletes the ApplicationConfiguration and the Components not referred by other ones with the orphan
+// propagation, so the resources owned by them are kept
+func deleteLegacy(ctx context.Context, c client.Client, ac *v1alpha2.ApplicationConfiguration) error {
+	orphan := client.PropagationPolicy(metav1.DeletePropagationOrphan)
+	if err := c.Delete(ctx, ac, orphan); client.IgnoreNotFound(err) != nil {
+		return errors.Wrapf(err, "cannot delete application configuration %s", ac.Name)
+	}
+	acs := &v1alpha2.ApplicationConfigurationList{}
+	if err := c.List(ctx, acs, client.InNamespace(ac.Namespace)); err != nil {
+		return err
+	}
+	referred := map[string]bool{}
+	for _, other := range acs.Items {
+		if other.Name == ac.Name {
+			continue
+		}
+		for _, acc := range other.Spec.Components {
+			referred[componentNameOf(acc)] = true
+		}
+	}
+	for _, acc := range ac.Spec.Components {
+		name := componentNameOf(acc)
+		if referred[name] || len(name) == 0 {
+			continue
+		}
+		comp := &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Namespace: ac.Namespace, Name: name}}
+		if err := c.Delete(ctx, comp, orphan); client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "cannot delete component %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package multicluster dispatches the resources of applications to the managed clusters through cluster clients
+// built from the kubeconfig of the Cluster objects
+package multicluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/clustermanager"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+)
+
+// ClusterGateway serves the clients of the managed clusters, a client is built once for a cluster and rebuilt only
+// after the kubeconfig of the cluster changes, so the connections are reused across reconciliations
+type ClusterGateway struct {
+	c         client.Reader
+	newClient func(kubeConfig []byte) (client.Client, error)
+
+	rateLimit apply.RateLimit
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]*clusterClient
+}
+
+// clusterClient is the cached client of a cluster, the resources applied to the cluster share the rate limiter
+type clusterClient struct {
+	kubeConfigHash string
+	client         client.Client
+	limiter        flowcontrol.RateLimiter
+}
+
+// Target is a managed cluster and the manifests deployed to it
+type Target struct {
+	Cluster   *v1beta1.Cluster
+	Manifests []*unstructured.Unstructured
+}
+
+// Result is the result of the deployment to a managed cluster, Err is nil if all the manifests are applied
+type Result struct {
+	Cluster string
+	Err     error
+}
+
+// NewClusterGateway creates a ClusterGateway reading the kubeconfig secrets of the clusters by the client
+func NewClusterGateway(c client.Reader) *ClusterGateway {
+	return &ClusterGateway{
+		c:         c,
+		newClient: clustermanager.GetClient,
+		clients:   map[types.NamespacedName]*clusterClient{},
+	}
+}
+
+// WithRateLimit sets how fast and how many resources are applied to a managed cluster at the same time
+func (g *ClusterGateway) WithRateLimit(rl apply.RateLimit) *ClusterGateway {
+	g.rateLimit = rl
+	return g
+}
+
+// ClientOf returns the client of the managed cluster
+func (g *ClusterGateway) ClientOf(ctx context.Context, cluster *v1beta1.Cluster) (client.Client, error) {
+	cc, err := g.clusterClientOf(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return cc.client, nil
+}
+
+// RestConfigOf returns the rest config of the managed cluster, e.g., to stream from or attach to the pods in it
+func (g *ClusterGateway) RestConfigOf(ctx context.Context, cluster *v1beta1.Cluster) (*rest.Config, error) {
+	kubeConfig, err := g.kubeConfigOf(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := clustermanager.GetRestConfig(kubeConfig)
+	return cfg, errors.WithMessagef(err, "cannot build the rest config of cluster %s", cluster.Name)
+}
+
+func (g *ClusterGateway) kubeConfigOf(ctx context.Context, cluster *v1beta1.Cluster) ([]byte, error) {
+	kubeConfig, err := clustermanager.GetKubeConfig(ctx, g.c, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if len(kubeConfig) == 0 {
+		return nil, errors.Errorf("kubeconfig of cluster %s is empty", cluster.Name)
+	}
+	return kubeConfig, nil
+}
+
+func (g *ClusterGateway) clusterClientOf(ctx context.Context, cluster *v1beta1.Cluster) (*clusterClient, error) {
+	kubeConfig, err := g.kubeConfigOf(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(kubeConfig)
+	hash := hex.EncodeToString(sum[:])
+	key := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cached, ok := g.clients[key]; ok && cached.kubeConfigHash == hash {
+		return cached, nil
+	}
+	c, err := g.newClient(kubeConfig)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot build the client of cluster %s", cluster.Name)
+	}
+	cc := &clusterClient{kubeConfigHash: hash, client: c, limiter: g.rateLimit.NewLimiter()}
+	g.clients[key] = cc
+	return cc, nil
+}
+
+// Dispatch applies the manifests of the targets to their clusters, at most parallelism clusters are deployed to at
+// the same time, all of them are if parallelism is zero. The results are in the order of the targets.
+func (g *ClusterGateway) Dispatch(ctx context.Context, targets []Target, parallelism int) []Result {
+	if parallelism <= 0 || parallelism > len(targets) {
+		parallelism = len(targets)
+	}
+	results := make([]Result, len(targets))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = Result{
+				Cluster: targets[i].Cluster.Name,
+				Err:     g.deploy(ctx, targets[i]),
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+func (g *ClusterGateway) deploy(ctx context.Context, target Target) error {
+	cc, err := g.clusterClientOf(ctx, target.Cluster)
+	if err != nil {
+		return err
+	}
+	applicator := apply.NewAPIApplicator(cc.client).WithRateLimiter(cc.limiter)
+	return apply.NewDispatcher(applicator, g.rateLimit.BatchSize).Dispatch(ctx, target.Manifests)
+}
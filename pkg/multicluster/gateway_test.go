@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/clustermanager"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newCluster(name string) (*v1beta1.Cluster, *corev1.Secret) {
+	cluster := &v1beta1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1beta1.ClusterSpec{
+			KubeconfigSecretRef: v1beta1.LocalSecretReference{Name: name + "-kubeconfig"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-kubeconfig", Namespace: "default"},
+		Data:       map[string][]byte{clustermanager.KubeConfigKey: []byte(name)},
+	}
+	return cluster, secret
+}
+
+func newConfigMap(name string) *unstructured.Unstructured {
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetName(name)
+	cm.SetNamespace("default")
+	return cm
+}
+
+func TestClusterGateway(t *testing.T) {
+	ctx := context.Background()
+	east, eastSecret := newCluster("east")
+	west, westSecret := newCluster("west")
+	broken, _ := newCluster("broken")
+	hub := fake.NewFakeClientWithScheme(common.Scheme, eastSecret, westSecret)
+
+	remotes := map[string]client.Client{
+		"east": fake.NewFakeClientWithScheme(common.Scheme),
+		"west": fake.NewFakeClientWithScheme(common.Scheme),
+	}
+	built := map[string]int{}
+	g := NewClusterGateway(hub)
+	g.newClient = func(kubeConfig []byte) (client.Client, error) {
+		built[string(kubeConfig)]++
+		return remotes[string(kubeConfig)], nil
+	}
+
+	results := g.Dispatch(ctx, []Target{
+		{Cluster: east, Manifests: []*unstructured.Unstructured{newConfigMap("east-cm")}},
+		{Cluster: broken, Manifests: []*unstructured.Unstructured{newConfigMap("broken-cm")}},
+		{Cluster: west, Manifests: []*unstructured.Unstructured{newConfigMap("west-cm")}},
+	}, 1)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "east", results[0].Cluster)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "broken", results[1].Cluster)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, "west", results[2].Cluster)
+	assert.NoError(t, results[2].Err)
+	for name, remote := range remotes {
+		cm := &corev1.ConfigMap{}
+		assert.NoError(t, remote.Get(ctx, client.ObjectKey{Namespace: "default", Name: name + "-cm"}, cm))
+	}
+
+	// the client of the cluster is reused until its kubeconfig changes
+	_, err := g.ClientOf(ctx, east)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, built["east"])
+	eastSecret.Data[clustermanager.KubeConfigKey] = []byte("west")
+	assert.NoError(t, hub.Update(ctx, eastSecret))
+	c, err := g.ClientOf(ctx, east)
+	assert.NoError(t, err)
+	assert.Equal(t, remotes["west"], c)
+	assert.Equal(t, 2, built["west"])
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notification notifies the registered endpoints of the state transitions of applications, the endpoints are
+// declared per namespace by annotations of the namespace or per application by the notification policy
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// PolicyTypeNotification is the type of the policy declaring the endpoints notified of the state transitions of the
+// application
+const PolicyTypeNotification = "notification"
+
+// defaultSecretKey is the key of the signing secret used if it's not specified
+const defaultSecretKey = "token"
+
+// Spec is the properties of the notification policy
+type Spec struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Endpoint is an endpoint notified of the state transitions of applications
+type Endpoint struct {
+	// Name identifies the endpoint in the delivery status
+	Name string `json:"name"`
+	// URL is the http(s) URL the notifications are POSTed to
+	URL string `json:"url"`
+	// Events are the state transitions notified, all of them are notified if it's empty
+	Events []common.NotificationEvent `json:"events,omitempty"`
+	// Secret refers to the secret key signing the payload, the payload is not signed if it's not set
+	Secret *SecretKeyRef `json:"secret,omitempty"`
+}
+
+// SecretKeyRef refers to a key of a Secret in the namespace of the application
+type SecretKeyRef struct {
+	Name string `json:"name"`
+	// Key defaults to "token"
+	Key string `json:"key,omitempty"`
+}
+
+// Payload is the body POSTed to the endpoints
+type Payload struct {
+	Event       common.NotificationEvent `json:"event"`
+	Application string                   `json:"application"`
+	Namespace   string                   `json:"namespace"`
+	Generation  int64                    `json:"generation"`
+	Revision    string                   `json:"revision,omitempty"`
+	Phase       common.ApplicationPhase  `json:"phase"`
+	// Message explains the transition, e.g., the unhealthy components
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// subscribes checks whether the endpoint is notified of the event
+func (e *Endpoint) subscribes(event common.NotificationEvent) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Endpoint) validate() error {
+	if len(e.Name) == 0 {
+		return errors.New("the name of the endpoint is required")
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.Errorf("the url of endpoint %s must be an absolute http(s) url, got %q", e.Name, e.URL)
+	}
+	return nil
+}
+
+// ParsePolicies parses the endpoints declared by the notification policies of the application
+func ParsePolicies(app *v1beta1.Application) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeNotification {
+			continue
+		}
+		spec := &Spec{}
+		if p.Properties.Raw != nil {
+			if err := json.Unmarshal(p.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		for i := range spec.Endpoints {
+			if err := spec.Endpoints[i].validate(); err != nil {
+				return nil, errors.WithMessagef(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		endpoints = append(endpoints, spec.Endpoints...)
+	}
+	return endpoints, nil
+}
+
+// Endpoints returns the endpoints notified of the state transitions of the application, i.e., the ones declared by
+// the annotation of its namespace overridden by the ones of the same names declared by its notification policies
+func Endpoints(ctx context.Context, c client.Reader, app *v1beta1.Application) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: app.Namespace}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "cannot get namespace %s", app.Namespace)
+		}
+	} else if v, ok := ns.GetAnnotations()[oam.AnnotationNotificationEndpoints]; ok {
+		if err := json.Unmarshal([]byte(v), &endpoints); err != nil {
+			return nil, errors.Wrapf(err, "invalid annotation %s of namespace %s", oam.AnnotationNotificationEndpoints, app.Namespace)
+		}
+		for i := range endpoints {
+			if err := endpoints[i].validate(); err != nil {
+				return nil, errors.WithMessagef(err, "invalid annotation %s of namespace %s", oam.AnnotationNotificationEndpoints, app.Namespace)
+			}
+		}
+	}
+	overrides, err := ParsePolicies(app)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		replaced := false
+		for i := range endpoints {
+			if endpoints[i].Name == o.Name {
+				endpoints[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			endpoints = append(endpoints, o)
+		}
+	}
+	return endpoints, nil
+}
+
+// Transitions returns the events notified when the status of an application changes from prev to cur
+func Transitions(prev, cur *common.AppStatus) []common.NotificationEvent {
+	var events []common.NotificationEvent
+	if cur.Phase == common.ApplicationRunning && prev.Phase != common.ApplicationRunning {
+		events = append(events, common.NotificationEventRunning)
+	}
+	if prev.Phase == common.ApplicationRunning && cur.Phase != common.ApplicationRunning &&
+		cur.GetCondition(common.ApplicationHealthy).Status == corev1.ConditionFalse {
+		events = append(events, common.NotificationEventUnhealthy)
+	}
+	if cur.Rollout.GetCondition(v1alpha1.BatchPaused).Status == corev1.ConditionTrue &&
+		prev.Rollout.GetCondition(v1alpha1.BatchPaused).Status != corev1.ConditionTrue {
+		events = append(events, common.NotificationEventRolloutPaused)
+	}
+	return events
+}
+
+// NewPayload returns the payload notifying the event of the application
+func NewPayload(app *v1beta1.Application, event common.NotificationEvent) *Payload {
+	p := &Payload{
+		Event:       event,
+		Application: app.Name,
+		Namespace:   app.Namespace,
+		Generation:  app.Generation,
+		Phase:       app.Status.Phase,
+		Time:        time.Now().UTC(),
+	}
+	if app.Status.LatestRevision != nil {
+		p.Revision = app.Status.LatestRevision.Name
+	}
+	switch event {
+	case common.NotificationEventUnhealthy:
+		var unhealthy []string
+		for _, svc := range app.Status.Services {
+			if svc.Healthy {
+				continue
+			}
+			if len(svc.Message) != 0 {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s: %s", svc.Name, svc.Message))
+			} else {
+				unhealthy = append(unhealthy, svc.Name)
+			}
+		}
+		if len(unhealthy) != 0 {
+			p.Message = "unhealthy components: " + strings.Join(unhealthy, "; ")
+		}
+	case common.NotificationEventRolloutPaused:
+		p.Message = app.Status.Rollout.GetCondition(v1alpha1.BatchPaused).Message
+	}
+	return p
+}
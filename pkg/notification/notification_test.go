@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newApp(endpoints ...Endpoint) *v1beta1.Application {
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "tenant", Generation: 3}}
+	if len(endpoints) != 0 {
+		raw, _ := json.Marshal(Spec{Endpoints: endpoints})
+		app.Spec.Policies = []v1beta1.AppPolicy{{
+			Name:       "notify",
+			Type:       PolicyTypeNotification,
+			Properties: runtime.RawExtension{Raw: raw},
+		}}
+	}
+	return app
+}
+
+func TestEndpoints(t *testing.T) {
+	nsEndpoints, _ := json.Marshal([]Endpoint{
+		{Name: "ops", URL: "https://ops.example.com/hook"},
+		{Name: "chat", URL: "https://chat.example.com/hook"},
+	})
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "tenant",
+		Annotations: map[string]string{oam.AnnotationNotificationEndpoints: string(nsEndpoints)},
+	}}
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme, ns)
+
+	endpoints, err := Endpoints(context.Background(), c, newApp(
+		Endpoint{Name: "chat", URL: "https://chat.example.com/team", Events: []common.NotificationEvent{common.NotificationEventUnhealthy}},
+		Endpoint{Name: "ci", URL: "http://ci.example.com/hook"},
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []Endpoint{
+		{Name: "ops", URL: "https://ops.example.com/hook"},
+		{Name: "chat", URL: "https://chat.example.com/team", Events: []common.NotificationEvent{common.NotificationEventUnhealthy}},
+		{Name: "ci", URL: "http://ci.example.com/hook"},
+	}, endpoints)
+
+	app := newApp(Endpoint{Name: "ci", URL: "http://ci.example.com/hook"})
+	app.Namespace = "not-found"
+	endpoints, err = Endpoints(context.Background(), c, app)
+	assert.NoError(t, err)
+	assert.Equal(t, []Endpoint{{Name: "ci", URL: "http://ci.example.com/hook"}}, endpoints)
+
+	_, err = Endpoints(context.Background(), c, newApp(Endpoint{Name: "bad", URL: "ci.example.com"}))
+	assert.Error(t, err)
+}
+
+func TestTransitions(t *testing.T) {
+	unhealthy := common.AppStatus{Phase: common.ApplicationHealthChecking}
+	unhealthy.SetConditions(runtimev1alpha1.Condition{Type: common.ApplicationHealthy, Status: corev1.ConditionFalse})
+	paused := common.AppStatus{Phase: common.ApplicationRollingOut}
+	paused.Rollout.SetConditions(runtimev1alpha1.Condition{Type: v1alpha1.BatchPaused, Status: corev1.ConditionTrue})
+
+	testCases := map[string]struct {
+		prev, cur common.AppStatus
+		events    []common.NotificationEvent
+	}{
+		"rendered to running": {
+			prev:   common.AppStatus{Phase: common.ApplicationRendering},
+			cur:    common.AppStatus{Phase: common.ApplicationRunning},
+			events: []common.NotificationEvent{common.NotificationEventRunning},
+		},
+		"still running": {
+			prev: common.AppStatus{Phase: common.ApplicationRunning},
+			cur:  common.AppStatus{Phase: common.ApplicationRunning},
+		},
+		"running to unhealthy": {
+			prev:   common.AppStatus{Phase: common.ApplicationRunning},
+			cur:    unhealthy,
+			events: []common.NotificationEvent{common.NotificationEventUnhealthy},
+		},
+		"still unhealthy": {
+			prev: unhealthy,
+			cur:  unhealthy,
+		},
+		"rollout paused": {
+			prev:   common.AppStatus{Phase: common.ApplicationRollingOut},
+			cur:    paused,
+			events: []common.NotificationEvent{common.NotificationEventRolloutPaused},
+		},
+		"rollout still paused": {
+			prev: paused,
+			cur:  paused,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.events, Transitions(&tc.prev, &tc.cur))
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var headers []http.Header
+	var bodies [][]byte
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		headers = append(headers, r.Header.Clone())
+		bodies = append(bodies, body)
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hook-secret", Namespace: "tenant"},
+		Data:       map[string][]byte{"token": []byte("s3cret")},
+	}
+	app := newApp(
+		Endpoint{Name: "ops", URL: server.URL, Secret: &SecretKeyRef{Name: "hook-secret"}},
+		Endpoint{Name: "ci", URL: server.URL, Events: []common.NotificationEvent{common.NotificationEventRolloutPaused}},
+	)
+	app.Status.Phase = common.ApplicationRunning
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme, ns, secret, app.DeepCopy())
+	n := NewNotifier(c, c)
+	// the test server listens on the loopback address
+	n.httpClient = server.Client()
+	n.maxAttempts = 2
+
+	ctx := context.Background()
+	assert.NoError(t, n.Notify(ctx, app))
+	assert.Equal(t, 0, n.queue.Len())
+	assert.NoError(t, n.Notify(ctx, app, common.NotificationEventRunning))
+	// the ci endpoint doesn't subscribe the event
+	assert.Equal(t, 1, n.queue.Len())
+
+	recorded := func() common.NotificationStatus {
+		got := &v1beta1.Application{}
+		assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "app"}, got))
+		assert.Len(t, got.Status.Notifications, 1)
+		return got.Status.Notifications[0]
+	}
+
+	// the failed delivery is retried
+	assert.True(t, n.processNext())
+	assert.Len(t, headers, 1)
+	assert.Equal(t, "Running", headers[0].Get(HeaderEvent))
+	assert.Equal(t, "sha256="+Sign([]byte("s3cret"), bodies[0]), headers[0].Get(HeaderSignature))
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal(bodies[0], payload))
+	assert.Equal(t, common.NotificationEventRunning, payload.Event)
+	assert.Equal(t, "app", payload.Application)
+	assert.Equal(t, int64(3), payload.Generation)
+	s := recorded()
+	assert.Equal(t, "ops", s.Endpoint)
+	assert.Equal(t, common.NotificationRetrying, s.Phase)
+	assert.Equal(t, 1, s.Attempts)
+	assert.Contains(t, s.Message, "503")
+
+	// the delivery fails after the maximum attempts
+	assert.True(t, n.processNext())
+	s = recorded()
+	assert.Equal(t, common.NotificationFailed, s.Phase)
+	assert.Equal(t, 2, s.Attempts)
+	assert.Equal(t, 0, n.queue.Len())
+
+	// the delivery succeeds
+	failing = false
+	assert.NoError(t, n.Notify(ctx, app, common.NotificationEventRunning))
+	assert.True(t, n.processNext())
+	s = recorded()
+	assert.Equal(t, common.NotificationDelivered, s.Phase)
+	assert.Equal(t, 1, s.Attempts)
+	assert.Empty(t, s.Message)
+}
+
+func TestNotifyBlockedAddress(t *testing.T) {
+	var requested bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant"}}
+	app := newApp(Endpoint{Name: "ops", URL: server.URL})
+	app.Status.Phase = common.ApplicationRunning
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme, ns, app.DeepCopy())
+	n := NewNotifier(c, c)
+	n.maxAttempts = 1
+
+	ctx := context.Background()
+	assert.NoError(t, n.Notify(ctx, app, common.NotificationEventRunning))
+	assert.True(t, n.processNext())
+	assert.False(t, requested)
+	got := &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "app"}, got))
+	assert.Len(t, got.Status.Notifications, 1)
+	assert.Equal(t, common.NotificationFailed, got.Status.Notifications[0].Phase)
+	assert.Contains(t, got.Status.Notifications[0].Message, "is not allowed")
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/internal/remote"
+)
+
+const (
+	// HeaderEvent is the header carrying the event notified
+	HeaderEvent = "X-Vela-Event"
+	// HeaderSignature is the header carrying the hex encoded HMAC-SHA256 signature of the payload, prefixed by "sha256="
+	HeaderSignature = "X-Vela-Signature"
+
+	// DefaultMaxAttempts is the default maximum number of the attempts to deliver a notification
+	DefaultMaxAttempts = 5
+
+	deliveryTimeout = 10 * time.Second
+	// maxResponseSize is the maximum size of the response read from an endpoint, the rest is discarded along with
+	// the connection
+	maxResponseSize = 1 << 20
+	workers         = 2
+)
+
+// delivery is a notification to deliver to an endpoint
+type delivery struct {
+	app      types.NamespacedName
+	endpoint Endpoint
+	payload  *Payload
+	attempts int
+}
+
+// Notifier delivers the notifications of the state transitions of applications asynchronously, so the reconciliation
+// is never blocked by a slow endpoint. The failed deliveries are retried with exponential backoff and the delivery
+// status is recorded in the status of the application.
+type Notifier struct {
+	client client.Client
+	// reader reads the namespaces and signing secrets from the API server directly, so no informer caching all the
+	// secrets in the cluster is started
+	reader      client.Reader
+	httpClient  *http.Client
+	queue       workqueue.RateLimitingInterface
+	maxAttempts int
+}
+
+// NewNotifier creates a Notifier, it must be started to deliver the notifications. The endpoints are given by the
+// applications, so they're notified by the client rejecting the private, loopback and link-local addresses.
+func NewNotifier(c client.Client, reader client.Reader) *Notifier {
+	return &Notifier{
+		client:      c,
+		reader:      reader,
+		httpClient:  remote.HTTPClient(),
+		queue:       workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute)),
+		maxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces;secrets,verbs=get
+
+// Start delivers the notifications until stop is closed
+func (n *Notifier) Start(stop <-chan struct{}) error {
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() {
+			for n.processNext() {
+			}
+		}, time.Second, stop)
+	}
+	<-stop
+	n.queue.ShutDown()
+	return nil
+}
+
+// Notify queues the notifications of the events of the application to the endpoints subscribing them
+func (n *Notifier) Notify(ctx context.Context, app *v1beta1.Application, events ...common.NotificationEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	endpoints, err := Endpoints(ctx, n.reader, app)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		payload := NewPayload(app, event)
+		for _, e := range endpoints {
+			if !e.subscribes(event) {
+				continue
+			}
+			n.queue.Add(&delivery{
+				app:      types.NamespacedName{Namespace: app.Namespace, Name: app.Name},
+				endpoint: e,
+				payload:  payload,
+			})
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) processNext() bool {
+	item, shutdown := n.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer n.queue.Done(item)
+	d := item.(*delivery)
+	d.attempts++
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*deliveryTimeout)
+	defer cancel()
+	phase := common.NotificationDelivered
+	err := n.deliver(ctx, d)
+	switch {
+	case err == nil:
+		n.queue.Forget(d)
+	case d.attempts < n.maxAttempts:
+		phase = common.NotificationRetrying
+		n.queue.AddRateLimited(d)
+	default:
+		phase = common.NotificationFailed
+		n.queue.Forget(d)
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to deliver notification", "application", d.app, "endpoint", d.endpoint.Name,
+			"event", d.payload.Event, "attempts", d.attempts)
+	}
+	if err := n.record(ctx, d, phase, err); err != nil {
+		klog.ErrorS(err, "Failed to record the delivery of notification", "application", d.app, "endpoint", d.endpoint.Name)
+	}
+	return true
+}
+
+// deliver POSTs the payload to the endpoint, signed by the secret of the endpoint if any
+func (n *Notifier) deliver(ctx context.Context, d *delivery) error {
+	body, err := json.Marshal(d.payload)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal the payload")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "cannot create the request to endpoint %s", d.endpoint.Name)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(d.payload.Event))
+	if d.endpoint.Secret != nil {
+		key, err := n.signingKey(ctx, d.app.Namespace, d.endpoint.Secret)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(HeaderSignature, "sha256="+Sign(key, body))
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	resp, err := n.httpClient.Do(req.WithContext(reqCtx))
+	if err != nil {
+		return errors.Wrapf(err, "cannot notify endpoint %s", d.endpoint.Name)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("endpoint %s responded with status %s", d.endpoint.Name, resp.Status)
+	}
+	return nil
+}
+
+func (n *Notifier) signingKey(ctx context.Context, namespace string, ref *SecretKeyRef) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := n.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "cannot get signing secret %s", ref.Name)
+	}
+	k := ref.Key
+	if len(k) == 0 {
+		k = defaultSecretKey
+	}
+	key, ok := secret.Data[k]
+	if !ok || len(key) == 0 {
+		return nil, errors.Errorf("signing secret %s has no key %s", ref.Name, k)
+	}
+	return key, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body signed by the key, the endpoints verify the HeaderSignature
+// against it
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// record upserts the delivery status of the endpoint in the status of the application
+func (n *Notifier) record(ctx context.Context, d *delivery, phase common.NotificationDeliveryPhase, deliverErr error) error {
+	s := common.NotificationStatus{
+		Endpoint:        d.endpoint.Name,
+		Event:           d.payload.Event,
+		Phase:           phase,
+		Attempts:        d.attempts,
+		LastAttemptTime: metav1.Now(),
+	}
+	if deliverErr != nil {
+		s.Message = deliverErr.Error()
+	}
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		app := &v1beta1.Application{}
+		if err := n.client.Get(ctx, d.app, app); err != nil {
+			return err
+		}
+		found := false
+		for i := range app.Status.Notifications {
+			if app.Status.Notifications[i].Endpoint == s.Endpoint {
+				app.Status.Notifications[i] = s
+				found = true
+			}
+		}
+		if !found {
+			app.Status.Notifications = append(app.Status.Notifications, s)
+		}
+		return n.client.Status().Update(ctx, app)
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discoverymapper
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// Invalidate drops the cached mapper, it will be re-created by discovery on the next use
+func (d *DefaultDiscoveryMapper) Invalidate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.mapper = nil
+}
+
+// CRDWatcher watches CustomResourceDefinitions and invalidates the cached mapper of the DiscoveryMapper as soon as
+// CRDs are added, changed or removed, so kinds of newly installed CRDs are mapped without waiting for a refresh.
+// It implements manager.Runnable and runs on all replicas regardless of leader election.
+type CRDWatcher struct {
+	mapper interface{ Invalidate() }
+	client dynamic.Interface
+}
+
+// NewCRDWatcher creates a CRDWatcher for the DiscoveryMapper, the DiscoveryMapper must support invalidation
+func NewCRDWatcher(c *rest.Config, dm DiscoveryMapper) (*CRDWatcher, error) {
+	mapper, ok := dm.(interface{ Invalidate() })
+	if !ok {
+		return nil, errors.Errorf("discovery mapper %T doesn't support invalidation", dm)
+	}
+	client, err := dynamic.NewForConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return &CRDWatcher{mapper: mapper, client: client}, nil
+}
+
+// Start watches CRDs until the stop channel is closed
+func (w *CRDWatcher) Start(stop <-chan struct{}) error {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(w.client, 0)
+	informer := factory.ForResource(crdGVR).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// CRDs listed on start are mapped by the first discovery anyway
+			if informer.HasSynced() {
+				w.invalidate("added", obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// the kinds are served after the CRD is established, or its versions and names are changed
+			if crdChanged(oldObj, newObj) {
+				w.invalidate("changed", newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.invalidate("removed", obj)
+		},
+	})
+	factory.Start(stop)
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		return errors.New("cannot sync the cache of CRDs")
+	}
+	<-stop
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the mapper is used by all replicas
+func (w *CRDWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *CRDWatcher) invalidate(event string, obj interface{}) {
+	name := ""
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		name = u.GetName()
+	}
+	klog.V(4).InfoS("Invalidate discovery mapper", "event", event, "crd", name)
+	w.mapper.Invalidate()
+}
+
+func crdChanged(oldObj, newObj interface{}) bool {
+	o, ok := oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	n, ok := newObj.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	return !reflect.DeepEqual(o.Object["spec"], n.Object["spec"]) || !reflect.DeepEqual(o.Object["status"], n.Object["status"])
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discoverymapper
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Watch CRDs to invalidate the mapper", func() {
+
+	It("map the kind of a new CRD without refreshing explicitly", func() {
+		dism, err := New(cfg)
+		Expect(err).Should(BeNil())
+		_, err = dism.GetMapper()
+		Expect(err).Should(BeNil())
+
+		watcher, err := NewCRDWatcher(cfg, dism)
+		Expect(err).Should(BeNil())
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			defer GinkgoRecover()
+			Expect(watcher.Start(stop)).Should(BeNil())
+		}()
+
+		By("Wait for the watcher to sync")
+		time.Sleep(time.Second)
+
+		crd := crdv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "bars.example.com",
+			},
+			Spec: crdv1.CustomResourceDefinitionSpec{
+				Group: "example.com",
+				Names: crdv1.CustomResourceDefinitionNames{
+					Kind:   "Bar",
+					Plural: "bars",
+				},
+				Versions: []crdv1.CustomResourceDefinitionVersion{{
+					Name:    "v1",
+					Served:  true,
+					Storage: true,
+					Schema: &crdv1.CustomResourceValidation{
+						OpenAPIV3Schema: &crdv1.JSONSchemaProps{
+							Type: "object",
+						}},
+				}},
+				Scope: crdv1.NamespaceScoped,
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), &crd)).Should(BeNil())
+
+		By("The cached mapper is invalidated and re-created with the new kind")
+		Eventually(func() error {
+			mapper, err := dism.GetMapper()
+			if err != nil {
+				return err
+			}
+			_, err = mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: "Bar"}, "v1")
+			return err
+		}, time.Second*10, time.Millisecond*300).Should(BeNil())
+	})
+})
@@ -38,6 +38,8 @@ const (
 	WorkloadTypeLabel = "workload.oam.dev/type"
 	// TraitTypeLabel indicates the type of the traitDefinition
 	TraitTypeLabel = "trait.oam.dev/type"
+	// LabelTraitHashVersion records the version of the algorithm computing the hash in generated trait names
+	LabelTraitHashVersion = "trait.oam.dev/hash-version"
 	// TraitResource indicates which resource it is when a trait is composed by multiple resources in KubeVela
 	TraitResource = "trait.oam.dev/resource"
 
@@ -49,6 +51,37 @@ const (
 	LabelPolicyDefinitionName = "policydefinition.oam.dev/name"
 	// LabelWorkflowStepDefinitionName records the name of WorkflowStepDefinition
 	LabelWorkflowStepDefinitionName = "workflowstepdefinition.oam.dev/name"
+	// LabelDefinitionPruneProtection protects a definition or definition revision from being pruned if it's "true"
+	LabelDefinitionPruneProtection = "definition.oam.dev/prune-protection"
+	// LabelDefinitionVersion records the semantic version of a definition, e.g., 1.2.0, it's inherited by the
+	// DefinitionRevisions so applications can refer to a revision by a version constraint, e.g., webservice@^1.2
+	LabelDefinitionVersion = "definition.oam.dev/version"
+	// LabelDefinitionBundle records the name of the bundle a definition is installed from
+	LabelDefinitionBundle = "definition.oam.dev/bundle"
+	// LabelAddonName records the name of the addon an application is rendered from
+	LabelAddonName = "addons.oam.dev/name"
+	// LabelGCGracePeriod is the duration to wait before deleting a resource no longer rendered by the application,
+	// e.g., 10m, the resource is deleted right away if it's not set
+	LabelGCGracePeriod = "gc.oam.dev/grace-period"
+	// LabelGCProtect protects a resource no longer rendered by the application from being deleted if it's "true",
+	// the resource is orphaned and reported instead
+	LabelGCProtect = "gc.oam.dev/protect"
+	// LabelBlueGreenRevision records the revision of the workload of a component switched by the blue-green trait,
+	// it's injected at the selector paths of the workload so the Services select the pods of one revision only
+	LabelBlueGreenRevision = "app.oam.dev/blue-green-revision"
+	// LabelMeteringTeam records the team owning a namespace, the usage of the namespaces is aggregated by it
+	LabelMeteringTeam = "metering.oam.dev/team"
+	// LabelProject records the project a namespace belongs to, the quota of the project is shared by the applications
+	// in all its namespaces. It also labels the ConfigMap declaring the quota of the project in the system namespace.
+	LabelProject = "app.oam.dev/project"
+	// LabelDebugSnapshot records what a debug snapshot ConfigMap of an application is about, i.e., "workflow" or
+	// "component", the component is recorded by LabelAppComponent
+	LabelDebugSnapshot = "app.oam.dev/debug-snapshot"
+	// LabelShardID records the shard of the controller an application is scheduled to, it's copied to the
+	// resources of the application reconciled by the shard, e.g., the ApplicationContexts
+	LabelShardID = "controller.core.oam.dev/shard-id"
+	// LabelManifestRevision records the application revision whose rendered manifests are stored in a ConfigMap
+	LabelManifestRevision = "app.oam.dev/manifest-revision"
 )
 
 const (
@@ -89,4 +122,194 @@ const (
 
 	// AnnotationWorkflowContext is used to pass in the workflow context marshalled in json format.
 	AnnotationWorkflowContext = "app.oam.dev/workflow-context"
+
+	// AnnotationApplyStrategy indicates the strategy to apply the resources of an application,
+	// it can be "server-side" or "three-way-merge" and overrides the default strategy of the controller
+	AnnotationApplyStrategy = "app.oam.dev/apply-strategy"
+
+	// AnnotationWorkloadLeaseHolder records which controller is currently mutating the workload spec,
+	// e.g., the rollout controller, other controllers should not override the workload until the lease is released
+	// or expired
+	AnnotationWorkloadLeaseHolder = "app.oam.dev/workload-lease-holder"
+
+	// AnnotationWorkloadLeaseRenewTime records the last time the workload lease holder acquired or renewed the lease
+	AnnotationWorkloadLeaseRenewTime = "app.oam.dev/workload-lease-renew-time"
+
+	// AnnotationApplyOnce indicates the resource is applied on creation but never reconciled afterwards
+	AnnotationApplyOnce = "app.oam.dev/apply-once"
+
+	// AnnotationSharedResource indicates the resource can be shared by multiple applications, it's owned by all of them
+	// and only deleted when the last owner is removed
+	AnnotationSharedResource = "app.oam.dev/shared-resource"
+
+	// AnnotationReferWorkload indicates the workload of the component refers to an existing workload not managed by
+	// the application, the value is the name of the existing workload. The workload is neither applied nor garbage
+	// collected, only the traits of the component are.
+	AnnotationReferWorkload = "app.oam.dev/refer-workload"
+
+	// AnnotationDefinitionBundleVersion records the version of the bundle a definition is installed from
+	AnnotationDefinitionBundleVersion = "definition.oam.dev/bundle-version"
+
+	// AnnotationDefinitionBundleRef records the reference pinned by digest of the OCI artifact of the bundle a
+	// definition is installed from, it's not set if the bundle is installed from a local directory
+	AnnotationDefinitionBundleRef = "definition.oam.dev/bundle-ref"
+
+	// AnnotationAddonVersion records the version of the addon an application is rendered from
+	AnnotationAddonVersion = "addons.oam.dev/version"
+
+	// AnnotationManifestDigest records the digest of the rendered manifests of an application revision
+	AnnotationManifestDigest = "app.oam.dev/manifest-digest"
+
+	// AnnotationManifestStore records the type of the backend the rendered manifests of an application revision are
+	// moved to, the manifests are stored in the revision itself if it's absent
+	AnnotationManifestStore = "app.oam.dev/manifest-store"
+
+	// AnnotationManifestChecksum records the checksum of the rendered manifests moved out of an application revision,
+	// it's checked when they're loaded back
+	AnnotationManifestChecksum = "app.oam.dev/manifest-checksum"
+
+	// AnnotationManifestCompression records the algorithm the rendered manifests of an application revision are
+	// compressed by, they're plain JSON if it's absent
+	AnnotationManifestCompression = "app.oam.dev/manifest-compression"
+
+	// AnnotationManifestShards records the number of ConfigMaps the rendered manifests of an application revision are
+	// split into
+	AnnotationManifestShards = "app.oam.dev/manifest-shards"
+
+	// AnnotationManifestSignature records the signature of the manifest digest of an application revision
+	AnnotationManifestSignature = "app.oam.dev/manifest-signature"
+
+	// AnnotationManifestSigner records the id of the key signing the manifest digest of an application revision
+	AnnotationManifestSigner = "app.oam.dev/manifest-signer"
+
+	// AnnotationManifestVerification records the audit record of the verification of the manifest signature of an
+	// application revision before its manifests are applied, in JSON
+	AnnotationManifestVerification = "app.oam.dev/manifest-verification"
+
+	// AnnotationDecisionLog enables the decision log of an application if it's "true", the decisions made by the
+	// controller during the last reconciliation are dumped to a ConfigMap and the diagnostics endpoint
+	AnnotationDecisionLog = "app.oam.dev/decision-log"
+
+	// AnnotationAdmissionPreflight overrides whether the rendered resources of an application are checked by
+	// server-side dry-run before any of them is written, it can be "true" or "false"
+	AnnotationAdmissionPreflight = "app.oam.dev/admission-preflight"
+
+	// AnnotationMigrate requests the ApplicationConfiguration annotated by "true" to be migrated to an Application
+	// with its components, the admission webhook rejects the ones that cannot be migrated
+	AnnotationMigrate = "app.oam.dev/migrate"
+
+	// AnnotationMigratedFrom records the name of the ApplicationConfiguration an Application is migrated from
+	AnnotationMigratedFrom = "app.oam.dev/migrated-from"
+
+	// AnnotationMigratedRevisions records the comma separated revisions of the components, e.g., "web=web-v3", the
+	// ApplicationConfiguration is bound to when an Application is migrated from it
+	AnnotationMigratedRevisions = "app.oam.dev/migrated-revisions"
+
+	// AnnotationDebug enables the debug snapshots of an application if it's "true", the render intermediates of the
+	// components and the workflow steps are persisted to ConfigMaps owned by the application for `vela debug`
+	AnnotationDebug = "app.oam.dev/debug"
+
+	// AnnotationSupportedOS declares the comma separated operating systems supported by a definition, e.g., "linux",
+	// the images of a component definition are built for them. All the operating systems are supported if it's not set.
+	AnnotationSupportedOS = "definition.oam.dev/supported-os"
+
+	// AnnotationSelectorPaths declares the comma separated paths of the label maps selecting the pods of the workload
+	// rendered by a definition, e.g., "spec.selector.matchLabels,spec.template.metadata.labels". They're derived
+	// from the pod spec path of the known workload kinds if it's not set.
+	AnnotationSelectorPaths = "definition.oam.dev/selector-paths"
+
+	// AnnotationTraitHandler declares the built-in handler rendering the trait of a definition instead of its
+	// template, e.g., blue-green. The template still declares the parameter of the trait.
+	AnnotationTraitHandler = "definition.oam.dev/trait-handler"
+
+	// AnnotationBoundSecrets records the comma separated names of the Secrets bound to the workload by the
+	// service-binding trait
+	AnnotationBoundSecrets = "app.oam.dev/bound-secrets"
+
+	// AnnotationApproveStep approves the suspend-for-approval workflow step named by the value
+	AnnotationApproveStep = "app.oam.dev/approve-step"
+
+	// AnnotationApprovedBy records the user who set AnnotationApproveStep, it's set by the admission webhook from
+	// the authenticated identity of the request and cannot be set by the users themselves
+	AnnotationApprovedBy = "app.oam.dev/approved-by"
+
+	// AnnotationCertNotAfter records the expiry of the webhook serving certificate in its secret, in RFC3339
+	AnnotationCertNotAfter = "app.oam.dev/cert-not-after"
+
+	// AnnotationCertRotatedAt records when the webhook serving certificate in its secret is rotated, in RFC3339
+	AnnotationCertRotatedAt = "app.oam.dev/cert-rotated-at"
+
+	// AnnotationMaxComponents overrides the maximum number of components of the applications in the namespace
+	// annotated, zero means unlimited
+	AnnotationMaxComponents = "app.oam.dev/max-components"
+
+	// AnnotationMaxTraitsPerComponent overrides the maximum number of traits of a component of the applications in
+	// the namespace annotated, zero means unlimited
+	AnnotationMaxTraitsPerComponent = "app.oam.dev/max-traits-per-component"
+
+	// AnnotationMaxRenderedObjects overrides the maximum number of workloads and traits rendered from an application
+	// in the namespace annotated, zero means unlimited
+	AnnotationMaxRenderedObjects = "app.oam.dev/max-rendered-objects"
+
+	// AnnotationMaxPropertiesSize overrides the maximum size in bytes of the properties of a component, trait,
+	// policy or workflow step of the applications in the namespace annotated, zero means unlimited
+	AnnotationMaxPropertiesSize = "app.oam.dev/max-properties-size"
+
+	// AnnotationNotificationEndpoints declares the endpoints notified of the state transitions of the applications in
+	// the namespace annotated, in the json format of a list of notification endpoints. The endpoints declared by the
+	// notification policy of an application override the ones of the same names.
+	AnnotationNotificationEndpoints = "app.oam.dev/notification-endpoints"
+
+	// AnnotationServiceAccountName declares the ServiceAccount in the namespace of the application impersonated to
+	// apply the rendered resources, so they're applied with the permissions of the application owner rather than the
+	// controller. It's exclusive with AnnotationImpersonateUser.
+	AnnotationServiceAccountName = "app.oam.dev/service-account-name"
+
+	// AnnotationImpersonateUser declares the user impersonated to apply the rendered resources of the application
+	AnnotationImpersonateUser = "app.oam.dev/impersonate-user"
+
+	// AnnotationImpersonateGroups declares the comma separated groups impersonated along with the user or the
+	// ServiceAccount to apply the rendered resources of the application
+	AnnotationImpersonateGroups = "app.oam.dev/impersonate-groups"
+
+	// AnnotationQuotaCPU declares the maximum sum of the CPU requests of the workloads rendered from the applications
+	// in the namespace annotated, e.g., 8 or 500m
+	AnnotationQuotaCPU = "app.oam.dev/quota-cpu"
+
+	// AnnotationQuotaMemory declares the maximum sum of the memory requests of the workloads rendered from the
+	// applications in the namespace annotated, e.g., 16Gi
+	AnnotationQuotaMemory = "app.oam.dev/quota-memory"
+
+	// AnnotationQuotaReplicas declares the maximum sum of the replicas of the workloads rendered from the applications
+	// in the namespace annotated
+	AnnotationQuotaReplicas = "app.oam.dev/quota-replicas"
+
+	// AnnotationTrigger subscribes the application to the push events received by the Trigger named by the value in
+	// the namespace of the application
+	AnnotationTrigger = "app.oam.dev/trigger"
+
+	// AnnotationTriggerImages declares the comma separated images of the components updated by the image Trigger in
+	// the format of <component>=<repository>, e.g., "web=ghcr.io/org/web", the image property of the component is set
+	// to the image pushed to the repository
+	AnnotationTriggerImages = "app.oam.dev/trigger-images"
+
+	// AnnotationTriggerGit declares the Git repository whose pushes redeploy the application by the git Trigger in the
+	// format of <repo>[@<branch or tag>], e.g., "https://github.com/org/app@main", all the branches and tags are
+	// matched if it's not specified
+	AnnotationTriggerGit = "app.oam.dev/trigger-git"
+
+	// AnnotationRestartWorkflow requests the workflow of the application to restart from the first step, the value is
+	// the reason. The status of the steps is cleared, the Git components are re-synced and the annotation is removed
+	// once the workflow is restarted.
+	AnnotationRestartWorkflow = "app.oam.dev/restart-workflow"
+
+	// AnnotationRollbackRevision is the ApplicationRevision the application is rolled back to. The manifests stored in
+	// the revision are dispatched rather than rendered as long as the spec of the application equals the one stored,
+	// the annotation is removed once the spec is changed. It's copied to the revisions created during the rollback
+	// to link them to the revision they're rolled back to.
+	AnnotationRollbackRevision = "app.oam.dev/rollback-revision"
+
+	// AnnotationABTestingRules records the number of rules prepended to a Gateway API HTTPRoute by the A/B testing
+	// rollout, they're replaced when the rules change and removed once the target is promoted
+	AnnotationABTestingRules = "app.oam.dev/ab-testing-rules"
 )
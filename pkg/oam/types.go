@@ -43,6 +43,13 @@ type Conditioned interface {
 	GetCondition(runtimev1alpha1.ConditionType) runtimev1alpha1.Condition
 }
 
+// An ObservedGenerationRecorder records in its status the generation of the spec the status reflects, so the
+// consumers, e.g., GitOps tools, can tell whether the status is up to date with the latest spec.
+type ObservedGenerationRecorder interface {
+	GetObservedGeneration() int64
+	SetObservedGeneration(generation int64)
+}
+
 // A WorkloadReferencer may reference an OAM workload.
 type WorkloadReferencer interface {
 	GetWorkloadReference() runtimev1alpha1.TypedReference
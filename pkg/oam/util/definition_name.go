@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// crdListGVK is the GVK to list CRDs as unstructured, so the scheme of the client doesn't need the apiextensions types
+var crdListGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinitionList"}
+
+var (
+	definitionNamesMu sync.RWMutex
+	// definitionNames is the local fallback table of the definition names of well-known kinds, it's used if the
+	// kind is neither discoverable nor defined by a CRD, e.g., the kind of an aggregated API during bootstrap
+	definitionNames = map[schema.GroupKind]string{
+		{Group: "apps", Kind: "Deployment"}:  "deployments.apps",
+		{Group: "apps", Kind: "StatefulSet"}: "statefulsets.apps",
+		{Group: "apps", Kind: "DaemonSet"}:   "daemonsets.apps",
+		{Group: "batch", Kind: "Job"}:        "jobs.batch",
+		{Group: "batch", Kind: "CronJob"}:    "cronjobs.batch",
+		{Group: "", Kind: "Service"}:         "services.",
+		{Group: "", Kind: "ConfigMap"}:       "configmaps.",
+		{Group: "", Kind: "Secret"}:          "secrets.",
+	}
+)
+
+// RegisterDefinitionName adds the definition name of the kind to the local fallback table
+func RegisterDefinitionName(gk schema.GroupKind, definitionName string) {
+	definitionNamesMu.Lock()
+	defer definitionNamesMu.Unlock()
+	definitionNames[gk] = definitionName
+}
+
+func lookupDefinitionName(gk schema.GroupKind) (string, bool) {
+	definitionNamesMu.RLock()
+	defer definitionNamesMu.RUnlock()
+	name, ok := definitionNames[gk]
+	return name, ok
+}
+
+// DefinitionNameOption configures how GetDefinitionName resolves the definition name if the kind is not discoverable
+type DefinitionNameOption func(*definitionNameOptions)
+
+type definitionNameOptions struct {
+	ctx    context.Context
+	client client.Reader
+}
+
+// WithCRDLookup resolves the definition name from the CRDs in the cluster if the kind is not discoverable yet,
+// e.g., the CRD is just created and its API is not served
+func WithCRDLookup(ctx context.Context, c client.Reader) DefinitionNameOption {
+	return func(o *definitionNameOptions) {
+		o.ctx = ctx
+		o.client = c
+	}
+}
+
+// ResolveCRDName finds the CRD of the group whose kind, plural, singular or shortNames matches the name case-insensitively,
+// or whose categories contain the name if there's only one such CRD in the group. It returns the name of the CRD,
+// i.e., `<plural>.<group>`, or empty if not found.
+func ResolveCRDName(ctx context.Context, c client.Reader, group, name string) (string, error) {
+	crds := &unstructured.UnstructuredList{}
+	crds.SetGroupVersionKind(crdListGVK)
+	if err := c.List(ctx, crds); err != nil {
+		return "", errors.Wrap(err, "cannot list CustomResourceDefinitions")
+	}
+	var byCategory []string
+	for _, crd := range crds.Items {
+		if g, _, _ := unstructured.NestedString(crd.Object, "spec", "group"); g != group {
+			continue
+		}
+		for _, field := range []string{"kind", "plural", "singular"} {
+			if n, _, _ := unstructured.NestedString(crd.Object, "spec", "names", field); n != "" && strings.EqualFold(n, name) {
+				return crd.GetName(), nil
+			}
+		}
+		shortNames, _, _ := unstructured.NestedStringSlice(crd.Object, "spec", "names", "shortNames")
+		for _, n := range shortNames {
+			if strings.EqualFold(n, name) {
+				return crd.GetName(), nil
+			}
+		}
+		categories, _, _ := unstructured.NestedStringSlice(crd.Object, "spec", "names", "categories")
+		for _, n := range categories {
+			if strings.EqualFold(n, name) {
+				byCategory = append(byCategory, crd.GetName())
+				break
+			}
+		}
+	}
+	if len(byCategory) == 1 {
+		return byCategory[0], nil
+	}
+	return "", nil
+}
+
+// resolveUndiscoverableDefinitionName resolves the definition name of a kind which is not discoverable, from the CRDs
+// if WithCRDLookup is set, and then the local fallback table
+func resolveUndiscoverableDefinitionName(gk schema.GroupKind, opts ...DefinitionNameOption) (string, bool, error) {
+	o := &definitionNameOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.client != nil {
+		name, err := ResolveCRDName(o.ctx, o.client, gk.Group, gk.Kind)
+		if err != nil {
+			return "", false, err
+		}
+		if name != "" {
+			return name, true, nil
+		}
+	}
+	name, ok := lookupDefinitionName(gk)
+	return name, ok, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ResolveDefinitionRevName converts the definition type defined in Application to the DefinitionRevision name like
+// ConvertDefinitionRevName, besides the revision number, e.g., webservice@v3, it resolves the semantic version
+// constraint, e.g., webservice@^1.2 or webservice@1.2.0, to the revision with the highest version matching it.
+// The version of a revision is set by the oam.LabelDefinitionVersion label of the definition. The revisions are
+// searched in the same namespaces as GetDefinition, defType filters the type of the definition if it's not empty.
+// ErrBadRevisionName is returned if no revision is specified.
+func ResolveDefinitionRevName(ctx context.Context, cli client.Reader, definitionName string, defType common.DefinitionType) (string, error) {
+	revName, err := ConvertDefinitionRevName(definitionName)
+	if err == nil || !strings.Contains(definitionName, "@") {
+		return revName, err
+	}
+	i := strings.LastIndex(definitionName, "@")
+	defName, version := definitionName[:i], definitionName[i+1:]
+	if len(defName) == 0 || len(version) == 0 {
+		return "", errors.Errorf("invalid definition name %s", definitionName)
+	}
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid version %q of definition %s", version, defName)
+	}
+	revs, err := listDefinitionRevisions(ctx, cli, defName, defType)
+	if err != nil {
+		return "", err
+	}
+	var (
+		matched    *v1beta1.DefinitionRevision
+		matchedVer *semver.Version
+	)
+	for i := range revs {
+		v, err := semver.NewVersion(revs[i].Labels[oam.LabelDefinitionVersion])
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		// the latest revision wins if multiple revisions are labeled with the same version
+		if matched == nil || v.GreaterThan(matchedVer) || (v.Equal(matchedVer) && revs[i].Spec.Revision > matched.Spec.Revision) {
+			matched, matchedVer = &revs[i], v
+		}
+	}
+	if matched == nil {
+		return "", errors.Errorf("no revision of definition %s matches version %s", defName, version)
+	}
+	return matched.Name, nil
+}
+
+// listDefinitionRevisions lists the DefinitionRevisions of the definition in the first namespace having any of them,
+// the namespaces are searched in the same order as GetDefinition
+func listDefinitionRevisions(ctx context.Context, cli client.Reader, defName string, defType common.DefinitionType) ([]v1beta1.DefinitionRevision, error) {
+	var namespaces []string
+	if dns := os.Getenv(DefinitionNamespaceEnv); dns != "" {
+		namespaces = append(namespaces, dns)
+	}
+	namespaces = append(namespaces, GetDefinitionNamespaceWithCtx(ctx), oam.SystemDefinitonNamespace)
+	for _, ns := range namespaces {
+		list := &v1beta1.DefinitionRevisionList{}
+		if err := cli.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return nil, errors.Wrapf(err, "cannot list definition revisions in namespace %s", ns)
+		}
+		var revs []v1beta1.DefinitionRevision
+		for i := range list.Items {
+			rev := list.Items[i]
+			if DefinitionNameOfRevision(&rev) == defName && (len(defType) == 0 || rev.Spec.DefinitionType == defType) {
+				revs = append(revs, rev)
+			}
+		}
+		if len(revs) != 0 {
+			sort.Slice(revs, func(i, j int) bool { return revs[i].Spec.Revision < revs[j].Spec.Revision })
+			return revs, nil
+		}
+	}
+	return nil, nil
+}
+
+// DefinitionNameOfRevision returns the name of the definition the DefinitionRevision is a snapshot of
+func DefinitionNameOfRevision(rev *v1beta1.DefinitionRevision) string {
+	switch rev.Spec.DefinitionType {
+	case common.ComponentType:
+		return rev.Spec.ComponentDefinition.Name
+	case common.TraitType:
+		return rev.Spec.TraitDefinition.Name
+	case common.PolicyType:
+		return rev.Spec.PolicyDefinition.Name
+	case common.WorkflowStepType:
+		return rev.Spec.WorkflowStepDefinition.Name
+	}
+	return ""
+}
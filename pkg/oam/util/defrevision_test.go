@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestResolveDefinitionRevName(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	compRev := func(ns string, revision int64, version string) *v1beta1.DefinitionRevision {
+		rev := &v1beta1.DefinitionRevision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: fmt.Sprintf("webservice-v%d", revision)},
+			Spec: v1beta1.DefinitionRevisionSpec{
+				Revision:            revision,
+				DefinitionType:      common.ComponentType,
+				ComponentDefinition: v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "webservice"}},
+			},
+		}
+		if len(version) != 0 {
+			rev.Labels = map[string]string{oam.LabelDefinitionVersion: version}
+		}
+		return rev
+	}
+	cli := fake.NewFakeClientWithScheme(scheme,
+		compRev(oam.SystemDefinitonNamespace, 1, "1.1.0"),
+		compRev(oam.SystemDefinitonNamespace, 2, "1.2.0"),
+		compRev(oam.SystemDefinitonNamespace, 3, "1.2.3"),
+		// the version is not bumped, the latest revision of the version wins
+		compRev(oam.SystemDefinitonNamespace, 4, "1.2.3"),
+		compRev(oam.SystemDefinitonNamespace, 5, "2.0.0"),
+		compRev(oam.SystemDefinitonNamespace, 6, ""),
+		&v1beta1.DefinitionRevision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: oam.SystemDefinitonNamespace, Name: "trait-webservice-v1",
+				Labels: map[string]string{oam.LabelDefinitionVersion: "9.0.0"}},
+			Spec: v1beta1.DefinitionRevisionSpec{
+				Revision:        1,
+				DefinitionType:  common.TraitType,
+				TraitDefinition: v1beta1.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "webservice"}},
+			},
+		},
+	)
+	ctx := util.SetNamespaceInCtx(context.Background(), "default")
+
+	testcases := map[string]struct {
+		defName     string
+		wantRevName string
+		hasError    bool
+	}{
+		"revision number":        {defName: "webservice@v2", wantRevName: "webservice-v2"},
+		"exact version":          {defName: "webservice@1.2.0", wantRevName: "webservice-v2"},
+		"version with v prefix":  {defName: "webservice@v1.1.0", wantRevName: "webservice-v1"},
+		"caret constraint":       {defName: "webservice@^1.2", wantRevName: "webservice-v4"},
+		"tilde constraint":       {defName: "webservice@~1.1", wantRevName: "webservice-v1"},
+		"range constraint":       {defName: "webservice@>=1.0.0 <2.0.0", wantRevName: "webservice-v4"},
+		"highest major version":  {defName: "webservice@>=1", wantRevName: "webservice-v5"},
+		"no matched version":     {defName: "webservice@^3", hasError: true},
+		"invalid constraint":     {defName: "webservice@latest", hasError: true},
+		"no revision specified":  {defName: "webservice", hasError: true},
+		"no definition name":     {defName: "@^1.2", hasError: true},
+		"definition not existed": {defName: "worker@^1.2", hasError: true},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			revName, err := util.ResolveDefinitionRevName(ctx, cli, tc.defName, common.ComponentType)
+			assert.Equal(t, tc.hasError, err != nil, err)
+			assert.Equal(t, tc.wantRevName, revName)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// GCDecision is what garbage collection does with a resource no longer rendered by the application
+type GCDecision string
+
+const (
+	// GCDelete means the resource is deleted now
+	GCDelete GCDecision = "Delete"
+	// GCWait means the resource is in its grace period and deleted later
+	GCWait GCDecision = "Wait"
+	// GCProtect means the resource is protected, it's orphaned instead of deleted
+	GCProtect GCDecision = "Protect"
+	// GCGone means the resource doesn't exist any more
+	GCGone GCDecision = "Gone"
+)
+
+// GCPolicy is the garbage collection policy declared by the labels of a resource
+type GCPolicy struct {
+	// Protected resources are never deleted by garbage collection
+	Protected bool
+	// GracePeriod is the duration to wait before deleting the resource
+	GracePeriod time.Duration
+}
+
+// GetGCPolicy returns the garbage collection policy declared by the labels of the object, see oam.LabelGCProtect and
+// oam.LabelGCGracePeriod
+func GetGCPolicy(obj metav1.Object) (GCPolicy, error) {
+	labels := obj.GetLabels()
+	policy := GCPolicy{Protected: labels[oam.LabelGCProtect] == "true"}
+	if v, ok := labels[oam.LabelGCGracePeriod]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, errors.Wrapf(err, "invalid label %s of %s %s", oam.LabelGCGracePeriod, obj.GetNamespace(), obj.GetName())
+		}
+		policy.GracePeriod = d
+	}
+	return policy, nil
+}
+
+// DecideGarbageCollection decides what garbage collection does with the resource following the policy declared by
+// the labels of its live object. The resource is found to be garbage since the given time, it's kept until the grace
+// period passes, and the remaining duration to wait is returned then.
+func DecideGarbageCollection(ctx context.Context, c client.Reader, obj *unstructured.Unstructured, since, now time.Time) (GCDecision, time.Duration, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return GCGone, 0, nil
+		}
+		return "", 0, err
+	}
+	policy, err := GetGCPolicy(existing)
+	if err != nil {
+		return "", 0, err
+	}
+	if policy.Protected {
+		return GCProtect, 0, nil
+	}
+	if wait := policy.GracePeriod - now.Sub(since); wait > 0 {
+		return GCWait, wait, nil
+	}
+	return GCDelete, 0, nil
+}
+
+// OrphanResource removes the owner from the owner references of the resource, so the protected resource is not
+// deleted along with the owner
+func OrphanResource(ctx context.Context, c client.Client, obj *unstructured.Unstructured, owner types.UID) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	var owners []metav1.OwnerReference
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.UID != owner {
+			owners = append(owners, ref)
+		}
+	}
+	if len(owners) == len(existing.GetOwnerReferences()) {
+		return nil
+	}
+	existing.SetOwnerReferences(owners)
+	return errors.Wrapf(c.Update(ctx, existing), "cannot orphan protected resource %s %s", existing.GetKind(), existing.GetName())
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestGetGCPolicy(t *testing.T) {
+	cm := sharedConfigMap(nil)
+	policy, err := util.GetGCPolicy(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, util.GCPolicy{}, policy)
+
+	cm.SetLabels(map[string]string{oam.LabelGCProtect: "true", oam.LabelGCGracePeriod: "10m"})
+	policy, err = util.GetGCPolicy(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, util.GCPolicy{Protected: true, GracePeriod: 10 * time.Minute}, policy)
+
+	cm.SetLabels(map[string]string{oam.LabelGCGracePeriod: "ten-minutes"})
+	_, err = util.GetGCPolicy(cm)
+	assert.Error(t, err)
+}
+
+func TestDecideGarbageCollection(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	testCases := map[string]struct {
+		labels   map[string]string
+		missing  bool
+		since    time.Time
+		decision util.GCDecision
+		wait     time.Duration
+	}{
+		"resource gone": {
+			missing:  true,
+			since:    now,
+			decision: util.GCGone,
+		},
+		"no policy": {
+			since:    now,
+			decision: util.GCDelete,
+		},
+		"protected": {
+			labels:   map[string]string{oam.LabelGCProtect: "true", oam.LabelGCGracePeriod: "1m"},
+			since:    now.Add(-time.Hour),
+			decision: util.GCProtect,
+		},
+		"in grace period": {
+			labels:   map[string]string{oam.LabelGCGracePeriod: "10m"},
+			since:    now.Add(-4 * time.Minute),
+			decision: util.GCWait,
+			wait:     6 * time.Minute,
+		},
+		"grace period passed": {
+			labels:   map[string]string{oam.LabelGCGracePeriod: "10m"},
+			since:    now.Add(-10 * time.Minute),
+			decision: util.GCDelete,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cm := sharedConfigMap(nil)
+			cm.SetLabels(tc.labels)
+			c := fake.NewFakeClientWithScheme(scheme.Scheme)
+			if !tc.missing {
+				c = fake.NewFakeClientWithScheme(scheme.Scheme, cm.DeepCopy())
+			}
+			decision, wait, err := util.DecideGarbageCollection(ctx, c, cm, tc.since, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.decision, decision)
+			assert.Equal(t, tc.wait, wait)
+		})
+	}
+}
+
+func TestOrphanResource(t *testing.T) {
+	ctx := context.Background()
+	cm := sharedConfigMap(nil, "app1", "app2")
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, cm)
+
+	assert.NoError(t, util.OrphanResource(ctx, c, cm, "uid-app1"))
+	got := &unstructured.Unstructured{}
+	got.SetGroupVersionKind(cm.GroupVersionKind())
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "shared"}, got))
+	assert.Equal(t, 1, len(got.GetOwnerReferences()))
+	assert.Equal(t, "app2", got.GetOwnerReferences()[0].Name)
+
+	// orphaning a missing resource is a no-op
+	c = fake.NewFakeClientWithScheme(scheme.Scheme)
+	assert.NoError(t, util.OrphanResource(ctx, c, cm, "uid-app1"))
+}
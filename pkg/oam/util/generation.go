@@ -0,0 +1,51 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ObservedGenerationObject is an object recording the generation its status reflects
+type ObservedGenerationObject interface {
+	oam.Object
+
+	oam.ObservedGenerationRecorder
+}
+
+// SetObservedGeneration records the current generation of the object in its status, controllers call it whenever
+// they update the status after reconciling the spec
+func SetObservedGeneration(o ObservedGenerationObject) {
+	o.SetObservedGeneration(o.GetGeneration())
+}
+
+// IsUpToDate returns whether the status of the object reflects its latest spec. The observed generation is read from
+// "status.observedGeneration" of an unstructured object. Objects not recording the observed generation are never up
+// to date.
+func IsUpToDate(o oam.Object) bool {
+	switch obj := o.(type) {
+	case ObservedGenerationObject:
+		return obj.GetObservedGeneration() >= obj.GetGeneration()
+	case *unstructured.Unstructured:
+		observed, found, err := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+		return err == nil && found && observed >= obj.GetGeneration()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestObservedGeneration(t *testing.T) {
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Generation: 2}}
+	assert.False(t, util.IsUpToDate(app))
+	util.SetObservedGeneration(app)
+	assert.Equal(t, int64(2), app.Status.ObservedGeneration)
+	assert.True(t, util.IsUpToDate(app))
+	app.Generation = 3
+	assert.False(t, util.IsUpToDate(app))
+
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetGeneration(3)
+	assert.False(t, util.IsUpToDate(u))
+	assert.NoError(t, unstructured.SetNestedField(u.Object, int64(3), "status", "observedGeneration"))
+	assert.True(t, util.IsUpToDate(u))
+
+	assert.False(t, util.IsUpToDate(&corev1.ConfigMap{}))
+}
@@ -18,6 +18,7 @@ package util
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"hash"
@@ -50,6 +51,8 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/utils/decision"
+	"github.com/oam-dev/kubevela/pkg/utils/requeue"
 )
 
 var (
@@ -57,10 +60,16 @@ var (
 	KindDeployment = reflect.TypeOf(appsv1.Deployment{}).Name()
 	// KindService is the k8s Service kind.
 	KindService = reflect.TypeOf(corev1.Service{}).Name()
-	// ReconcileWaitResult is the time to wait between reconciliation.
-	ReconcileWaitResult = reconcile.Result{RequeueAfter: 30 * time.Second}
+	// ReconcileWaitIntervals are the intervals to wait between the reconciliations of the workloads and traits,
+	// they're set by the controller flags.
+	ReconcileWaitIntervals = requeue.Intervals{Active: 30 * time.Second}
 )
 
+// ReconcileWaitResult returns the result to wait between reconciliation, the interval is jittered.
+func ReconcileWaitResult() reconcile.Result {
+	return ReconcileWaitIntervals.Result()
+}
+
 const (
 	// TraitPrefixKey is prefix of trait name
 	TraitPrefixKey = "trait"
@@ -131,6 +140,15 @@ const (
 	// HELMDef describe a workload refer to HELM
 	HELMDef WorkloadType = "HelmDef"
 
+	// TerraformDef describe a workload refer to Terraform
+	TerraformDef WorkloadType = "TerraformDef"
+
+	// KustomizeDef describe a workload refer to a kustomize base
+	KustomizeDef WorkloadType = "KustomizeDef"
+
+	// GitDef describe a workload refer to a directory of a Git repository
+	GitDef WorkloadType = "GitDef"
+
 	// ReferWorkload describe an existing workload
 	ReferWorkload WorkloadType = "ReferWorkload"
 )
@@ -245,7 +263,7 @@ func FetchScopeDefinition(ctx context.Context, r client.Reader, dm discoverymapp
 	scope *unstructured.Unstructured) (*v1alpha2.ScopeDefinition, error) {
 	// The name of the scopeDefinition CR is the CRD name of the scope
 	// TODO(wonderflow): we haven't support scope definition label type yet.
-	spName, err := GetDefinitionName(dm, scope, "")
+	spName, err := GetDefinitionName(dm, scope, "", WithCRDLookup(ctx, r))
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +278,7 @@ func FetchScopeDefinition(ctx context.Context, r client.Reader, dm discoverymapp
 func FetchTraitDefinition(ctx context.Context, r client.Reader, dm discoverymapper.DiscoveryMapper,
 	trait *unstructured.Unstructured) (*v1alpha2.TraitDefinition, error) {
 	// The name of the traitDefinition CR is the CRD name of the trait
-	trName, err := GetDefinitionName(dm, trait, oam.TraitTypeLabel)
+	trName, err := GetDefinitionName(dm, trait, oam.TraitTypeLabel, WithCRDLookup(ctx, r))
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +293,7 @@ func FetchTraitDefinition(ctx context.Context, r client.Reader, dm discoverymapp
 func FetchWorkloadDefinition(ctx context.Context, r client.Reader, dm discoverymapper.DiscoveryMapper,
 	workload *unstructured.Unstructured) (*v1alpha2.WorkloadDefinition, error) {
 	// The name of the workloadDefinition CR is the CRD name of the component
-	wldName, err := GetDefinitionName(dm, workload, oam.WorkloadTypeLabel)
+	wldName, err := GetDefinitionName(dm, workload, oam.WorkloadTypeLabel, WithCRDLookup(ctx, r))
 	if err != nil {
 		return nil, err
 	}
@@ -343,13 +361,16 @@ func GetDefinition(ctx context.Context, cli client.Reader, definition runtime.Ob
 // GetCapabilityDefinition can get different versions of ComponentDefinition/TraitDefinition
 func GetCapabilityDefinition(ctx context.Context, cli client.Reader, definition runtime.Object,
 	definitionName string) error {
-	isLatestRevision, defRev, err := fetchDefinitionRev(ctx, cli, definitionName)
+	isLatestRevision, defRev, err := fetchDefinitionRev(ctx, cli, definitionName, definitionTypeOf(definition))
 	if err != nil {
 		return err
 	}
 	if isLatestRevision {
+		decision.Record(ctx, decision.StageDefinition, definitionName, "use the latest definition")
 		return GetDefinition(ctx, cli, definition, definitionName)
 	}
+	decision.Record(ctx, decision.StageDefinition, definitionName, "use the definition revision %s(revision %d)",
+		defRev.Name, defRev.Spec.Revision)
 	switch def := definition.(type) {
 	case *v1beta1.ComponentDefinition:
 		*def = defRev.Spec.ComponentDefinition
@@ -364,8 +385,8 @@ func GetCapabilityDefinition(ctx context.Context, cli client.Reader, definition
 	return nil
 }
 
-func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName string) (bool, *v1beta1.DefinitionRevision, error) {
-	defRevName, err := ConvertDefinitionRevName(definitionName)
+func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName string, defType common.DefinitionType) (bool, *v1beta1.DefinitionRevision, error) {
+	defRevName, err := ResolveDefinitionRevName(ctx, cli, definitionName, defType)
 	if err != nil {
 		if errors.As(err, &ErrBadRevisionName) {
 			return true, nil, nil
@@ -379,8 +400,25 @@ func fetchDefinitionRev(ctx context.Context, cli client.Reader, definitionName s
 	return false, defRev, err
 }
 
+// definitionTypeOf returns the type of the definition, it's empty if the definition has no revisions
+func definitionTypeOf(definition runtime.Object) common.DefinitionType {
+	switch definition.(type) {
+	case *v1beta1.ComponentDefinition:
+		return common.ComponentType
+	case *v1beta1.TraitDefinition:
+		return common.TraitType
+	case *v1beta1.PolicyDefinition:
+		return common.PolicyType
+	case *v1beta1.WorkflowStepDefinition:
+		return common.WorkflowStepType
+	default:
+		return ""
+	}
+}
+
 // ConvertDefinitionRevName can help convert definition type defined in Application to DefinitionRevision Name
-// e.g., worker@v2 will be convert to worker-v2
+// e.g., worker@v2 will be convert to worker-v2. The semantic version constraints, e.g., worker@^1.2, are resolved
+// by ResolveDefinitionRevName.
 func ConvertDefinitionRevName(definitionName string) (string, error) {
 	revNum, err := ExtractRevisionNum(definitionName, "@")
 	if err != nil {
@@ -405,10 +443,14 @@ func FetchWorkloadChildResources(ctx context.Context, mLog logr.Logger, r client
 	workloadDefinition, err := FetchWorkloadDefinition(ctx, r, dm, workload)
 	if err != nil {
 		// No definition will won't block app from running
-		if apierrors.IsNotFound(err) {
-			return nil, nil
+		if !apierrors.IsNotFound(err) {
+			return nil, err
 		}
-		return nil, err
+		workloadDefinition = &v1alpha2.WorkloadDefinition{}
+	}
+	// Knative Service generates Revisions through Configuration, discover them unless the definition overrides
+	if len(workloadDefinition.Spec.ChildResourceKinds) == 0 && IsKnativeService(workload.GroupVersionKind()) {
+		return fetchKnativeServiceChildResources(ctx, mLog, r, workload)
 	}
 	return fetchChildResources(ctx, mLog, r, workload, workloadDefinition.Spec.ChildResourceKinds)
 }
@@ -449,6 +491,9 @@ func PatchCondition(ctx context.Context, r client.StatusClient, workload Conditi
 	condition ...cpv1alpha1.Condition) error {
 	workloadPatch := client.MergeFrom(workload.DeepCopyObject())
 	workload.SetConditions(condition...)
+	if o, ok := workload.(ObservedGenerationObject); ok {
+		SetObservedGeneration(o)
+	}
 	return errors.Wrap(
 		r.Status().Patch(ctx, workload, workloadPatch, client.FieldOwner(workload.GetUID())),
 		ErrUpdateStatus)
@@ -499,7 +544,10 @@ func RemoveAnnotations(o labelAnnotationObject, removeKeys []string) {
 // the format of the definition of a resource is <kind plurals>.<group>
 // Now the definition name of a resource could also be defined as `definition.oam.dev/name` in `metadata.annotations`
 // typeLabel specified which Definition it is, if specified, will directly get definition from label.
-func GetDefinitionName(dm discoverymapper.DiscoveryMapper, u *unstructured.Unstructured, typeLabel string) (string, error) {
+// If the kind is not discoverable yet, the name is resolved by the options, e.g., from the CRDs, and then from the local
+// fallback table of well-known kinds, see RegisterDefinitionName.
+func GetDefinitionName(dm discoverymapper.DiscoveryMapper, u *unstructured.Unstructured, typeLabel string,
+	opts ...DefinitionNameOption) (string, error) {
 	if typeLabel != "" {
 		if labels := u.GetLabels(); labels != nil {
 			if definitionName, ok := labels[typeLabel]; ok {
@@ -511,9 +559,20 @@ func GetDefinitionName(dm discoverymapper.DiscoveryMapper, u *unstructured.Unstr
 	if err != nil {
 		return "", err
 	}
-	mapping, err := dm.RESTMapping(schema.GroupKind{Group: groupVersion.Group, Kind: u.GetKind()}, groupVersion.Version)
+	gk := schema.GroupKind{Group: groupVersion.Group, Kind: u.GetKind()}
+	mapping, err := dm.RESTMapping(gk, groupVersion.Version)
 	if err != nil {
-		return "", err
+		if !meta.IsNoMatchError(err) {
+			return "", err
+		}
+		name, ok, lookupErr := resolveUndiscoverableDefinitionName(gk, opts...)
+		if lookupErr != nil {
+			return "", lookupErr
+		}
+		if !ok {
+			return "", err
+		}
+		return name, nil
 	}
 	return mapping.Resource.Resource + "." + groupVersion.Group, nil
 }
@@ -557,20 +616,15 @@ func ConvertWorkloadGVK2Definition(dm discoverymapper.DiscoveryMapper, def commo
 	return reference, nil
 }
 
-// GetObjectsGivenGVKAndLabels fetches the kubernetes object given its gvk and labels by list API
+// GetObjectsGivenGVKAndLabels fetches the kubernetes object given its gvk and labels by list API, the objects are
+// listed page by page, see ListObjects for the richer queries
 func GetObjectsGivenGVKAndLabels(ctx context.Context, cli client.Reader,
 	gvk schema.GroupVersionKind, namespace string, labels map[string]string) (*unstructured.UnstructuredList, error) {
-	unstructuredObjList := &unstructured.UnstructuredList{}
-	apiVersion := metav1.GroupVersion{
-		Group:   gvk.Group,
-		Version: gvk.Version,
-	}.String()
-	unstructuredObjList.SetAPIVersion(apiVersion)
-	unstructuredObjList.SetKind(gvk.Kind)
-	if err := cli.List(ctx, unstructuredObjList, client.MatchingLabels(labels), client.InNamespace(namespace)); err != nil {
+	list, err := ListObjects(ctx, cli, gvk, ObjectQuery{Namespaces: []string{namespace}, Labels: labels})
+	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to get obj with labels %+v and gvk %+v ", labels, gvk))
 	}
-	return unstructuredObjList, nil
+	return list, nil
 }
 
 // GetObjectGivenGVKAndName fetches the kubernetes object given its gvk and name
@@ -667,24 +721,98 @@ func RawExtension2Map(raw *runtime.RawExtension) (map[string]interface{}, error)
 
 // GenTraitName generate trait name
 func GenTraitName(componentName string, ct *v1alpha2.ComponentTrait, traitType string) string {
+	return GenTraitNameWithHashVersion(componentName, ct, traitType, TraitHashV1)
+}
+
+// GenTraitNameWithHashVersion generate trait name with the hash computed by the given version of algorithm
+func GenTraitNameWithHashVersion(componentName string, ct *v1alpha2.ComponentTrait, traitType string, version TraitHashVersion) string {
+	return GenTraitNameWithCollisionCount(componentName, ct, traitType, version, nil)
+}
+
+// GenTraitNameWithCollisionCount generate trait name with the hash computed by the given version of algorithm and
+// collisionCount, the collisionCount is ignored if it's nil.
+func GenTraitNameWithCollisionCount(componentName string, ct *v1alpha2.ComponentTrait, traitType string,
+	version TraitHashVersion, collisionCount *int32) string {
 	var traitMiddleName = TraitPrefixKey
 	if traitType != "" && traitType != Dummy {
 		traitMiddleName = strings.ToLower(traitType)
 	}
-	return fmt.Sprintf("%s-%s-%s", componentName, traitMiddleName, ComputeHash(ct))
+	return fmt.Sprintf("%s-%s-%s", componentName, traitMiddleName, ComputeTraitHash(ct, version, collisionCount))
+}
 
+// TraitHashVersion is the version of the algorithm to compute the hash of a ComponentTrait.
+// The version is persisted in the label oam.LabelTraitHashVersion, so changing the algorithm
+// won't rename the traits generated by the former versions.
+type TraitHashVersion string
+
+const (
+	// TraitHashV1 hashes the whole ComponentTrait struct with spew, any change of the struct layout changes the hash
+	TraitHashV1 TraitHashVersion = "v1"
+	// TraitHashV2 hashes the normalized JSON of the ComponentTrait, which is stable across struct layout changes
+	// as long as new fields are omitted when empty
+	TraitHashV2 TraitHashVersion = "v2"
+
+	// LatestTraitHashVersion is the hash version used by newly created applications
+	LatestTraitHashVersion = TraitHashV2
+)
+
+// GetTraitHashVersion returns the trait hash version recorded in the labels, TraitHashV1 is returned if the version
+// is not recorded or unknown, as it's the version used before the hash is versioned.
+func GetTraitHashVersion(labels map[string]string) TraitHashVersion {
+	switch v := TraitHashVersion(labels[oam.LabelTraitHashVersion]); v {
+	case TraitHashV1, TraitHashV2:
+		return v
+	default:
+		return TraitHashV1
+	}
 }
 
-// ComputeHash returns a hash value calculated from pod template and
-// a collisionCount to avoid hash collision. The hash will be safe encoded to
-// avoid bad words.
+// ComputeHash returns a hash value calculated from the ComponentTrait with TraitHashV1.
+// The hash will be safe encoded to avoid bad words.
 func ComputeHash(trait *v1alpha2.ComponentTrait) string {
+	return ComputeTraitHash(trait, TraitHashV1, nil)
+}
+
+// ComputeTraitHash returns a hash value calculated from the ComponentTrait with the given version of algorithm and
+// a collisionCount to avoid hash collision, the collisionCount is ignored if it's nil.
+// The hash will be safe encoded to avoid bad words.
+func ComputeTraitHash(trait *v1alpha2.ComponentTrait, version TraitHashVersion, collisionCount *int32) string {
 	componentTraitHasher := fnv.New32a()
-	DeepHashObject(componentTraitHasher, *trait)
+	switch version {
+	case TraitHashV2:
+		_, _ = componentTraitHasher.Write(normalizedTraitJSON(trait))
+	default:
+		DeepHashObject(componentTraitHasher, *trait)
+	}
+
+	// Add collisionCount in the hash if it exists.
+	if collisionCount != nil {
+		collisionCountBytes := make([]byte, 8)
+		binary.LittleEndian.PutUint32(collisionCountBytes, uint32(*collisionCount))
+		_, _ = componentTraitHasher.Write(collisionCountBytes)
+	}
 
 	return rand.SafeEncodeString(fmt.Sprint(componentTraitHasher.Sum32()))
 }
 
+// normalizedTraitJSON marshals the ComponentTrait into JSON with sorted keys and no insignificant whitespace,
+// so the format of the raw trait doesn't affect the hash
+func normalizedTraitJSON(trait *v1alpha2.ComponentTrait) []byte {
+	data, err := json.Marshal(trait)
+	if err != nil {
+		// the raw trait is not a valid JSON, hash it as is
+		return trait.Trait.Raw
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return data
+	}
+	if data, err = json.Marshal(normalized); err != nil {
+		return trait.Trait.Raw
+	}
+	return data
+}
+
 // DeepHashObject writes specified object to hash using the spew library
 // which follows pointers and prints actual values of the nested objects
 // ensuring the hash does not change when a pointer changes.
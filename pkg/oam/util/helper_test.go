@@ -23,6 +23,7 @@ import (
 	"hash/adler32"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
@@ -32,6 +33,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -794,6 +796,70 @@ func TestUnstructured(t *testing.T) {
 	}
 }
 
+func TestGetDefinitionNameOfUndiscoverableKind(t *testing.T) {
+	crd := func(name, group, kind string, shortNames, categories []interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"group": group,
+				"names": map[string]interface{}{
+					"kind":       kind,
+					"plural":     strings.ToLower(kind) + "s",
+					"shortNames": shortNames,
+					"categories": categories,
+				},
+			},
+		}}
+	}
+	crds := []unstructured.Unstructured{
+		crd("foos.example.com", "example.com", "Foo", []interface{}{"fo"}, []interface{}{"all", "example"}),
+		crd("bars.example.com", "example.com", "Bar", nil, []interface{}{"all"}),
+		crd("foos.another.com", "another.com", "Foo", nil, nil),
+	}
+	c := &test.MockClient{MockList: func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+		list.(*unstructured.UnstructuredList).Items = crds
+		return nil
+	}}
+	mapper := mock.NewMockDiscoveryMapper()
+	mapper.MockRESTMapping = func(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+		return nil, &meta.NoKindMatchError{GroupKind: gk, SearchedVersions: versions}
+	}
+	ctx := context.Background()
+	newObj := func(apiVersion, kind string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		return u
+	}
+
+	name, err := util.GetDefinitionName(mapper, newObj("example.com/v1", "Foo"), "", util.WithCRDLookup(ctx, c))
+	assert.NoError(t, err)
+	assert.Equal(t, "foos.example.com", name)
+
+	name, err = util.ResolveCRDName(ctx, c, "example.com", "fo")
+	assert.NoError(t, err)
+	assert.Equal(t, "foos.example.com", name)
+	name, err = util.ResolveCRDName(ctx, c, "example.com", "example")
+	assert.NoError(t, err)
+	assert.Equal(t, "foos.example.com", name)
+	// the category is ambiguous
+	name, err = util.ResolveCRDName(ctx, c, "example.com", "all")
+	assert.NoError(t, err)
+	assert.Equal(t, "", name)
+
+	// well-known kinds fall back to the local table
+	name, err = util.GetDefinitionName(mapper, newObj("apps/v1", "Deployment"), "", util.WithCRDLookup(ctx, c))
+	assert.NoError(t, err)
+	assert.Equal(t, "deployments.apps", name)
+	util.RegisterDefinitionName(schema.GroupKind{Group: "metrics.example.com", Kind: "Metric"}, "metrics.metrics.example.com")
+	name, err = util.GetDefinitionName(mapper, newObj("metrics.example.com/v1", "Metric"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "metrics.metrics.example.com", name)
+
+	_, err = util.GetDefinitionName(mapper, newObj("example.com/v1", "Baz"), "", util.WithCRDLookup(ctx, c))
+	assert.True(t, meta.IsNoMatchError(err))
+}
+
 func TestGetGVKFromDef(t *testing.T) {
 	mapper := mock.NewMockDiscoveryMapper()
 	mapper.MockKindsFor = mock.NewMockKindsFor("Abc", "v1", "v2")
@@ -967,6 +1033,46 @@ func TestComputeHash(t *testing.T) {
 	}
 }
 
+func TestComputeTraitHash(t *testing.T) {
+	compact := &v1alpha2.ComponentTrait{Trait: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"core.oam.dev/v1alpha2","kind":"ManualScalerTrait","spec":{"replicaCount":3}}`),
+	}}
+	formatted := &v1alpha2.ComponentTrait{Trait: runtime.RawExtension{
+		Raw: []byte(`{"spec": {"replicaCount": 3}, "kind": "ManualScalerTrait", "apiVersion": "core.oam.dev/v1alpha2"}`),
+	}}
+	another := &v1alpha2.ComponentTrait{Trait: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"core.oam.dev/v1alpha2","kind":"ManualScalerTrait","spec":{"replicaCount":5}}`),
+	}}
+
+	// v1 is kept the same as ComputeHash
+	assert.Equal(t, util.ComputeHash(compact), util.ComputeTraitHash(compact, util.TraitHashV1, nil))
+	// v2 doesn't care about the format of the raw trait
+	assert.Equal(t, util.ComputeTraitHash(compact, util.TraitHashV2, nil), util.ComputeTraitHash(formatted, util.TraitHashV2, nil))
+	assert.NotEqual(t, util.ComputeTraitHash(compact, util.TraitHashV2, nil), util.ComputeTraitHash(another, util.TraitHashV2, nil))
+	assert.NotEqual(t, util.ComputeTraitHash(compact, util.TraitHashV1, nil), util.ComputeTraitHash(compact, util.TraitHashV2, nil))
+
+	// collisionCount changes the hash
+	var collisionCount int32 = 1
+	assert.NotEqual(t, util.ComputeTraitHash(compact, util.TraitHashV2, nil), util.ComputeTraitHash(compact, util.TraitHashV2, &collisionCount))
+	assert.Equal(t, util.ComputeTraitHash(compact, util.TraitHashV2, &collisionCount), util.ComputeTraitHash(formatted, util.TraitHashV2, &collisionCount))
+
+	assert.Equal(t, "comp-scaler-"+util.ComputeTraitHash(compact, util.TraitHashV2, nil),
+		util.GenTraitNameWithHashVersion("comp", compact, "scaler", util.TraitHashV2))
+	assert.Equal(t, util.GenTraitName("comp", compact, "scaler"),
+		util.GenTraitNameWithHashVersion("comp", compact, "scaler", util.TraitHashV1))
+	assert.Equal(t, util.GenTraitNameWithHashVersion("comp", compact, "scaler", util.TraitHashV2),
+		util.GenTraitNameWithCollisionCount("comp", compact, "scaler", util.TraitHashV2, nil))
+	assert.Equal(t, "comp-scaler-"+util.ComputeTraitHash(compact, util.TraitHashV2, &collisionCount),
+		util.GenTraitNameWithCollisionCount("comp", compact, "scaler", util.TraitHashV2, &collisionCount))
+}
+
+func TestGetTraitHashVersion(t *testing.T) {
+	assert.Equal(t, util.TraitHashV1, util.GetTraitHashVersion(nil))
+	assert.Equal(t, util.TraitHashV1, util.GetTraitHashVersion(map[string]string{oam.LabelTraitHashVersion: "v1"}))
+	assert.Equal(t, util.TraitHashV2, util.GetTraitHashVersion(map[string]string{oam.LabelTraitHashVersion: "v2"}))
+	assert.Equal(t, util.TraitHashV1, util.GetTraitHashVersion(map[string]string{oam.LabelTraitHashVersion: "unknown"}))
+}
+
 func TestDeepHashObject(t *testing.T) {
 	successCases := []func() interface{}{
 		func() interface{} { return 8675309 },
@@ -0,0 +1,164 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+)
+
+const (
+	// KnativeServingGroup is the API group of Knative Serving
+	KnativeServingGroup = "serving.knative.dev"
+	// KnativeServiceKind is the kind of Knative Service
+	KnativeServiceKind = "Service"
+	// LabelKnativeService is the label Knative sets on the resources generated by a Knative Service
+	LabelKnativeService = "serving.knative.dev/service"
+
+	knativeServingAPIVersion = "serving.knative.dev/v1"
+	knativeConfigurationKind = "Configuration"
+	knativeRouteKind         = "Route"
+	knativeRevisionKind      = "Revision"
+
+	knativeConditionReady = "Ready"
+)
+
+// KnativeServiceGVK is the GVK of Knative Service
+var KnativeServiceGVK = schema.GroupVersionKind{Group: KnativeServingGroup, Version: "v1", Kind: KnativeServiceKind}
+
+// KnativeTrafficTarget is a traffic target of a Knative Service
+type KnativeTrafficTarget struct {
+	RevisionName   string `json:"revisionName,omitempty"`
+	LatestRevision *bool  `json:"latestRevision,omitempty"`
+	Percent        int64  `json:"percent"`
+	Tag            string `json:"tag,omitempty"`
+}
+
+// IsKnativeService checks if the GVK is a Knative Service regardless of the version
+func IsKnativeService(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == KnativeServingGroup && gvk.Kind == KnativeServiceKind
+}
+
+// SetKnativeRevisionName names the Revision created by the Knative Service after the component revision,
+// so a component revision maps to exactly one Knative Revision. Knative requires the name to be prefixed
+// with the Service name, the name is left unset if the requirement can't be met or it's specified by user.
+func SetKnativeRevisionName(svc *unstructured.Unstructured, revisionName string) error {
+	if name, _, _ := unstructured.NestedString(svc.Object, "spec", "template", "metadata", "name"); len(name) != 0 {
+		return nil
+	}
+	if !strings.HasPrefix(revisionName, svc.GetName()+"-") {
+		return nil
+	}
+	return unstructured.SetNestedField(svc.Object, revisionName, "spec", "template", "metadata", "name")
+}
+
+// GetKnativeTraffic returns the traffic targets in the spec of a Knative Service
+func GetKnativeTraffic(svc *unstructured.Unstructured) ([]KnativeTrafficTarget, error) {
+	raw, found, err := unstructured.NestedSlice(svc.Object, "spec", "traffic")
+	if err != nil || !found {
+		return nil, err
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var targets []KnativeTrafficTarget
+	if err := json.Unmarshal(b, &targets); err != nil {
+		return nil, errors.Wrapf(err, "invalid traffic of knative service %q", svc.GetName())
+	}
+	return targets, nil
+}
+
+// SetKnativeTraffic overrides the traffic targets in the spec of a Knative Service
+func SetKnativeTraffic(svc *unstructured.Unstructured, targets []KnativeTrafficTarget) error {
+	raw := make([]interface{}, len(targets))
+	for i, t := range targets {
+		target := map[string]interface{}{"percent": t.Percent}
+		if len(t.RevisionName) != 0 {
+			target["revisionName"] = t.RevisionName
+		}
+		if t.LatestRevision != nil {
+			target["latestRevision"] = *t.LatestRevision
+		}
+		if len(t.Tag) != 0 {
+			target["tag"] = t.Tag
+		}
+		raw[i] = target
+	}
+	return unstructured.SetNestedSlice(svc.Object, raw, "spec", "traffic")
+}
+
+// GetKnativeServiceReadiness derives the health of a Knative Service from its Ready condition,
+// the status is considered stale until the observed generation catches up with the spec
+func GetKnativeServiceReadiness(svc *unstructured.Unstructured) (bool, string) {
+	observed, _, _ := unstructured.NestedInt64(svc.Object, "status", "observedGeneration")
+	if observed < svc.GetGeneration() {
+		return false, fmt.Sprintf("generation %d is not observed yet", svc.GetGeneration())
+	}
+	conditions, _, _ := unstructured.NestedSlice(svc.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != knativeConditionReady {
+			continue
+		}
+		if cond["status"] == "True" {
+			return true, ""
+		}
+		reason, _ := cond["reason"].(string)
+		message, _ := cond["message"].(string)
+		return false, strings.TrimSpace(fmt.Sprintf("%s %s", reason, message))
+	}
+	return false, "condition Ready is not reported yet"
+}
+
+// fetchKnativeServiceChildResources discovers the Configurations and Routes owned by the Knative Service
+// and the Revisions owned by its Configurations
+func fetchKnativeServiceChildResources(ctx context.Context, mLog logr.Logger, r client.Reader,
+	svc *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	selector := map[string]string{LabelKnativeService: svc.GetName()}
+	owned, err := fetchChildResources(ctx, mLog, r, svc, []common.ChildResourceKind{
+		{APIVersion: knativeServingAPIVersion, Kind: knativeConfigurationKind, Selector: selector},
+		{APIVersion: knativeServingAPIVersion, Kind: knativeRouteKind, Selector: selector},
+	})
+	if err != nil {
+		return nil, err
+	}
+	childResources := owned
+	for _, cr := range owned {
+		if cr.GetKind() != knativeConfigurationKind {
+			continue
+		}
+		revisions, err := fetchChildResources(ctx, mLog, r, cr, []common.ChildResourceKind{
+			{APIVersion: knativeServingAPIVersion, Kind: knativeRevisionKind, Selector: selector},
+		})
+		if err != nil {
+			return nil, err
+		}
+		childResources = append(childResources, revisions...)
+	}
+	return childResources, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestKnativeService(t *testing.T) {
+	assert.True(t, util.IsKnativeService(schema.GroupVersionKind{Group: "serving.knative.dev", Version: "v1alpha1", Kind: "Service"}))
+	assert.False(t, util.IsKnativeService(schema.GroupVersionKind{Version: "v1", Kind: "Service"}))
+
+	svc := &unstructured.Unstructured{}
+	svc.SetGroupVersionKind(util.KnativeServiceGVK)
+	svc.SetName("web")
+
+	// revision name must be prefixed with the service name
+	assert.NoError(t, util.SetKnativeRevisionName(svc, "api-v1"))
+	_, found, _ := unstructured.NestedString(svc.Object, "spec", "template", "metadata", "name")
+	assert.False(t, found)
+	assert.NoError(t, util.SetKnativeRevisionName(svc, "web-v2"))
+	name, _, _ := unstructured.NestedString(svc.Object, "spec", "template", "metadata", "name")
+	assert.Equal(t, "web-v2", name)
+	// don't override the name specified
+	assert.NoError(t, util.SetKnativeRevisionName(svc, "web-v3"))
+	name, _, _ = unstructured.NestedString(svc.Object, "spec", "template", "metadata", "name")
+	assert.Equal(t, "web-v2", name)
+
+	traffic := []util.KnativeTrafficTarget{
+		{RevisionName: "web-v1", LatestRevision: pointer.BoolPtr(false), Percent: 80},
+		{RevisionName: "web-v2", LatestRevision: pointer.BoolPtr(false), Percent: 20, Tag: "canary"},
+	}
+	assert.NoError(t, util.SetKnativeTraffic(svc, traffic))
+	got, err := util.GetKnativeTraffic(svc)
+	assert.NoError(t, err)
+	assert.Equal(t, traffic, got)
+}
+
+func TestGetKnativeServiceReadiness(t *testing.T) {
+	svc := &unstructured.Unstructured{}
+	svc.SetGroupVersionKind(util.KnativeServiceGVK)
+	svc.SetGeneration(2)
+
+	ready, diagnosis := util.GetKnativeServiceReadiness(svc)
+	assert.False(t, ready)
+	assert.Equal(t, "generation 2 is not observed yet", diagnosis)
+
+	unstructured.SetNestedField(svc.Object, int64(2), "status", "observedGeneration")
+	ready, diagnosis = util.GetKnativeServiceReadiness(svc)
+	assert.False(t, ready)
+	assert.Equal(t, "condition Ready is not reported yet", diagnosis)
+
+	unstructured.SetNestedSlice(svc.Object, []interface{}{map[string]interface{}{
+		"type": "Ready", "status": "False", "reason": "RevisionFailed", "message": "image pull failed",
+	}}, "status", "conditions")
+	ready, diagnosis = util.GetKnativeServiceReadiness(svc)
+	assert.False(t, ready)
+	assert.Equal(t, "RevisionFailed image pull failed", diagnosis)
+
+	unstructured.SetNestedSlice(svc.Object, []interface{}{map[string]interface{}{
+		"type": "Ready", "status": "True",
+	}}, "status", "conditions")
+	ready, _ = util.GetKnativeServiceReadiness(svc)
+	assert.True(t, ready)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// WorkloadLeaseDuration is how long a workload lease stays valid after it's acquired or renewed.
+// A holder that crashed without releasing the lease will not block others forever.
+const WorkloadLeaseDuration = 5 * time.Minute
+
+// WorkloadLeasedError indicates the workload is leased by another holder
+type WorkloadLeasedError struct {
+	Holder string
+}
+
+func (e *WorkloadLeasedError) Error() string {
+	return fmt.Sprintf("the workload is leased by %q", e.Holder)
+}
+
+// GenWorkloadLeaseHolder generates the lease holder identity of a controller object, e.g., AppRollout/default/myapp
+func GenWorkloadLeaseHolder(kind string, owner metav1.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, owner.GetNamespace(), owner.GetName())
+}
+
+// GetWorkloadLeaseHolder returns the holder of the workload lease if it's not expired at the given time
+func GetWorkloadLeaseHolder(workload metav1.Object, now time.Time) string {
+	annots := workload.GetAnnotations()
+	holder := annots[oam.AnnotationWorkloadLeaseHolder]
+	if len(holder) == 0 {
+		return ""
+	}
+	renewTime, err := time.Parse(time.RFC3339, annots[oam.AnnotationWorkloadLeaseRenewTime])
+	if err != nil || now.After(renewTime.Add(WorkloadLeaseDuration)) {
+		// a malformed or expired lease is treated as released
+		return ""
+	}
+	return holder
+}
+
+// IsWorkloadLeasedByOthers checks whether the workload is leased by any holder other than the given one
+func IsWorkloadLeasedByOthers(workload metav1.Object, holder string, now time.Time) bool {
+	h := GetWorkloadLeaseHolder(workload, now)
+	return len(h) != 0 && h != holder
+}
+
+// AcquireWorkloadLease marks the workload as leased by the holder, it renews the lease if the holder has it already.
+// It only modifies the object in memory, the caller is responsible to persist it.
+func AcquireWorkloadLease(workload metav1.Object, holder string, now time.Time) error {
+	if h := GetWorkloadLeaseHolder(workload, now); len(h) != 0 && h != holder {
+		return &WorkloadLeasedError{Holder: h}
+	}
+	AddAnnotations(workload, map[string]string{
+		oam.AnnotationWorkloadLeaseHolder:    holder,
+		oam.AnnotationWorkloadLeaseRenewTime: now.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// ReleaseWorkloadLease removes the workload lease if it's held by the holder, it returns whether the object is modified.
+// It only modifies the object in memory, the caller is responsible to persist it.
+func ReleaseWorkloadLease(workload metav1.Object, holder string) bool {
+	if workload.GetAnnotations()[oam.AnnotationWorkloadLeaseHolder] != holder {
+		return false
+	}
+	RemoveAnnotations(workload, []string{oam.AnnotationWorkloadLeaseHolder, oam.AnnotationWorkloadLeaseRenewTime})
+	return true
+}
+
+// WorkloadLeaseNeedsRenew checks whether the lease held by the holder has passed half of its duration,
+// so that a long-running holder can renew it before it expires
+func WorkloadLeaseNeedsRenew(workload metav1.Object, holder string, now time.Time) bool {
+	annots := workload.GetAnnotations()
+	if annots[oam.AnnotationWorkloadLeaseHolder] != holder {
+		return false
+	}
+	renewTime, err := time.Parse(time.RFC3339, annots[oam.AnnotationWorkloadLeaseRenewTime])
+	if err != nil {
+		return true
+	}
+	return now.After(renewTime.Add(WorkloadLeaseDuration / 2))
+}
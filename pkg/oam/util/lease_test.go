@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func TestWorkloadLease(t *testing.T) {
+	now := time.Now()
+	rollout := "AppRollout/default/myapp"
+	another := "AppRollout/default/another"
+	deploy := &appsv1.Deployment{}
+
+	// no lease at all
+	assert.Equal(t, "", util.GetWorkloadLeaseHolder(deploy, now))
+	assert.False(t, util.IsWorkloadLeasedByOthers(deploy, rollout, now))
+
+	// acquire the lease
+	assert.NoError(t, util.AcquireWorkloadLease(deploy, rollout, now))
+	assert.Equal(t, rollout, util.GetWorkloadLeaseHolder(deploy, now))
+	assert.False(t, util.IsWorkloadLeasedByOthers(deploy, rollout, now))
+	assert.True(t, util.IsWorkloadLeasedByOthers(deploy, another, now))
+	assert.False(t, util.WorkloadLeaseNeedsRenew(deploy, rollout, now))
+
+	// others cannot acquire it
+	err := util.AcquireWorkloadLease(deploy, another, now)
+	assert.Equal(t, &util.WorkloadLeasedError{Holder: rollout}, err)
+
+	// the holder should renew it after half of the duration
+	assert.True(t, util.WorkloadLeaseNeedsRenew(deploy, rollout, now.Add(util.WorkloadLeaseDuration/2+time.Second)))
+	assert.False(t, util.WorkloadLeaseNeedsRenew(deploy, another, now.Add(util.WorkloadLeaseDuration/2+time.Second)))
+
+	// an expired lease can be acquired by others
+	expired := now.Add(util.WorkloadLeaseDuration + time.Second)
+	assert.Equal(t, "", util.GetWorkloadLeaseHolder(deploy, expired))
+	assert.NoError(t, util.AcquireWorkloadLease(deploy, another, expired))
+	assert.Equal(t, another, util.GetWorkloadLeaseHolder(deploy, expired))
+
+	// only the holder can release it
+	assert.False(t, util.ReleaseWorkloadLease(deploy, rollout))
+	assert.True(t, util.ReleaseWorkloadLease(deploy, another))
+	assert.Equal(t, "", util.GetWorkloadLeaseHolder(deploy, expired))
+	_, exist := deploy.GetAnnotations()[oam.AnnotationWorkloadLeaseRenewTime]
+	assert.False(t, exist)
+
+	// malformed renew time is treated as released
+	deploy.SetAnnotations(map[string]string{
+		oam.AnnotationWorkloadLeaseHolder:    rollout,
+		oam.AnnotationWorkloadLeaseRenewTime: "boom",
+	})
+	assert.Equal(t, "", util.GetWorkloadLeaseHolder(deploy, now))
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultListPageSize is the number of objects listed per request by ListObjects and EachObject
+const DefaultListPageSize int64 = 500
+
+// ObjectQuery selects the objects of a kind to list. The objects are listed page by page with limit/continue, so
+// enumerating a large number of objects, e.g., all the pods of all components, won't time out. Note that the pages
+// and the field selectors are served only by the API server, the cached clients return all objects in one page and
+// support the field selectors of indexed fields only.
+type ObjectQuery struct {
+	// Namespaces are the namespaces to list the objects in, the objects in all namespaces are listed if it's empty
+	Namespaces []string
+	// Labels selects the objects with all the labels
+	Labels map[string]string
+	// LabelSelector selects the objects by label expressions, e.g., "tier in (web,api),!canary", it's ANDed with Labels
+	LabelSelector string
+	// FieldSelector selects the objects by fields, e.g., "status.phase=Running"
+	FieldSelector string
+	// PageSize is the number of objects listed per request, DefaultListPageSize is used if it's not positive
+	PageSize int64
+	// Limit is the maximum number of objects returned, it's unlimited if it's not positive
+	Limit int
+}
+
+// listOptions builds the options of the list requests in the namespace except the pagination
+func (q ObjectQuery) listOptions(namespace string) (*client.ListOptions, error) {
+	opts := &client.ListOptions{Namespace: namespace}
+	selector := labels.SelectorFromSet(q.Labels)
+	if len(q.LabelSelector) != 0 {
+		parsed, err := labels.Parse(q.LabelSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid label selector %q", q.LabelSelector)
+		}
+		requirements, _ := parsed.Requirements()
+		selector = selector.Add(requirements...)
+	}
+	if !selector.Empty() {
+		opts.LabelSelector = selector
+	}
+	if len(q.FieldSelector) != 0 {
+		parsed, err := fields.ParseSelector(q.FieldSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid field selector %q", q.FieldSelector)
+		}
+		opts.FieldSelector = parsed
+	}
+	opts.Limit = q.PageSize
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultListPageSize
+	}
+	return opts, nil
+}
+
+// EachObject lists the objects of the kind selected by the query page by page and calls fn with each of them, the
+// objects are not held across pages. Listing stops at the first error returned by fn.
+func EachObject(ctx context.Context, cli client.Reader, gvk schema.GroupVersionKind, q ObjectQuery,
+	fn func(obj *unstructured.Unstructured) error) error {
+	namespaces := q.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	count := 0
+	for _, ns := range namespaces {
+		opts, err := q.listOptions(ns)
+		if err != nil {
+			return err
+		}
+		for {
+			if q.Limit > 0 && count >= q.Limit {
+				return nil
+			}
+			page := &unstructured.UnstructuredList{}
+			page.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			if err := cli.List(ctx, page, opts); err != nil {
+				return errors.Wrapf(err, "failed to list %s in namespace %q", gvk.String(), ns)
+			}
+			for i := range page.Items {
+				if q.Limit > 0 && count >= q.Limit {
+					return nil
+				}
+				if err := fn(&page.Items[i]); err != nil {
+					return err
+				}
+				count++
+			}
+			if len(page.GetContinue()) == 0 {
+				break
+			}
+			opts.Continue = page.GetContinue()
+		}
+	}
+	return nil
+}
+
+// ListObjects lists the objects of the kind selected by the query, see ObjectQuery
+func ListObjects(ctx context.Context, cli client.Reader, gvk schema.GroupVersionKind, q ObjectQuery) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err := EachObject(ctx, cli, gvk, q, func(obj *unstructured.Unstructured) error {
+		list.Items = append(list.Items, *obj)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// pagedReader serves the list requests page by page like the API server
+type pagedReader struct {
+	client.Reader
+	objects   []unstructured.Unstructured
+	requests  []client.ListOptions
+	pageLimit int64
+}
+
+func (r *pagedReader) List(_ context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	r.requests = append(r.requests, listOpts)
+	var matched []unstructured.Unstructured
+	for _, o := range r.objects {
+		if listOpts.Namespace != "" && o.GetNamespace() != listOpts.Namespace {
+			continue
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(o.GetLabels())) {
+			continue
+		}
+		matched = append(matched, o)
+	}
+	start := 0
+	if listOpts.Continue != "" {
+		start, _ = strconv.Atoi(listOpts.Continue)
+	}
+	end := start + int(listOpts.Limit)
+	ul := list.(*unstructured.UnstructuredList)
+	if end < len(matched) {
+		ul.SetContinue(strconv.Itoa(end))
+	} else {
+		end = len(matched)
+	}
+	ul.Items = matched[start:end]
+	return nil
+}
+
+func TestListObjects(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	var pods []unstructured.Unstructured
+	for _, ns := range []string{"ns-1", "ns-2"} {
+		for i := 0; i < 5; i++ {
+			pod := unstructured.Unstructured{}
+			pod.SetGroupVersionKind(podGVK)
+			pod.SetNamespace(ns)
+			pod.SetName(fmt.Sprintf("pod-%d", i))
+			pod.SetLabels(map[string]string{"app.oam.dev/component": "web", "tier": []string{"web", "api"}[i%2]})
+			pods = append(pods, pod)
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("list all the pages of multiple namespaces", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		list, err := util.ListObjects(ctx, r, podGVK, util.ObjectQuery{Namespaces: []string{"ns-1", "ns-2"}, PageSize: 2})
+		assert.NoError(t, err)
+		assert.Len(t, list.Items, 10)
+		assert.Equal(t, "PodList", list.GetKind())
+		// 3 pages of 2 objects per namespace
+		assert.Len(t, r.requests, 6)
+		assert.Equal(t, "ns-1", r.requests[2].Namespace)
+		assert.Equal(t, "4", r.requests[2].Continue)
+		assert.Equal(t, "ns-2", r.requests[3].Namespace)
+		assert.Empty(t, r.requests[3].Continue)
+	})
+
+	t.Run("list all the namespaces with the default page size", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		list, err := util.ListObjects(ctx, r, podGVK, util.ObjectQuery{})
+		assert.NoError(t, err)
+		assert.Len(t, list.Items, 10)
+		assert.Len(t, r.requests, 1)
+		assert.Equal(t, "", r.requests[0].Namespace)
+		assert.Equal(t, util.DefaultListPageSize, r.requests[0].Limit)
+	})
+
+	t.Run("select by labels and label expressions", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		list, err := util.ListObjects(ctx, r, podGVK, util.ObjectQuery{
+			Labels:        map[string]string{"app.oam.dev/component": "web"},
+			LabelSelector: "tier in (api),!canary",
+			FieldSelector: "status.phase=Running",
+		})
+		assert.NoError(t, err)
+		assert.Len(t, list.Items, 4)
+		assert.Equal(t, "status.phase=Running", r.requests[0].FieldSelector.String())
+	})
+
+	t.Run("stop at the limit", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		list, err := util.ListObjects(ctx, r, podGVK, util.ObjectQuery{PageSize: 3, Limit: 4})
+		assert.NoError(t, err)
+		assert.Len(t, list.Items, 4)
+		assert.Len(t, r.requests, 2)
+	})
+
+	t.Run("stop at the error of the callback", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		count := 0
+		err := util.EachObject(ctx, r, podGVK, util.ObjectQuery{PageSize: 2}, func(obj *unstructured.Unstructured) error {
+			count++
+			if count == 3 {
+				return fmt.Errorf("stop at %s", obj.GetName())
+			}
+			return nil
+		})
+		assert.EqualError(t, err, "stop at pod-2")
+		assert.Len(t, r.requests, 2)
+	})
+
+	t.Run("reject invalid selectors", func(t *testing.T) {
+		r := &pagedReader{objects: pods}
+		_, err := util.ListObjects(ctx, r, podGVK, util.ObjectQuery{LabelSelector: "tier in web"})
+		assert.Error(t, err)
+		_, err = util.ListObjects(ctx, r, podGVK, util.ObjectQuery{FieldSelector: "status.phase"})
+		assert.Error(t, err)
+		assert.Empty(t, r.requests)
+	})
+}
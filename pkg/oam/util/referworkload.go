@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ReferredWorkloadMissingError means the existing workload referred by a component is not found
+type ReferredWorkloadMissingError struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+func (e *ReferredWorkloadMissingError) Error() string {
+	return fmt.Sprintf("referred workload %s %s/%s (%s) is not found", e.Kind, e.Namespace, e.Name, e.APIVersion)
+}
+
+// IsReferredWorkloadMissing checks whether the error is caused by a referred workload not found
+func IsReferredWorkloadMissing(err error) bool {
+	var missing *ReferredWorkloadMissingError
+	return errors.As(err, &missing)
+}
+
+// IsReferredWorkload returns true if the workload refers to an existing workload not managed by the application
+func IsReferredWorkload(obj metav1.Object) bool {
+	_, ok := obj.GetAnnotations()[oam.AnnotationReferWorkload]
+	return ok
+}
+
+// ReferredWorkloadName returns the name of the existing workload referred by the workload, it falls back to the
+// name of the workload if the annotation has no value
+func ReferredWorkloadName(obj metav1.Object) string {
+	if name := obj.GetAnnotations()[oam.AnnotationReferWorkload]; len(name) != 0 {
+		return name
+	}
+	return obj.GetName()
+}
+
+// GetReferredWorkload gets the live object of the existing workload referred by the rendered workload, the
+// apiVersion and kind of the rendered workload and the namespace it's rendered to are used to locate it.
+// It returns ReferredWorkloadMissingError if the workload is not found.
+func GetReferredWorkload(ctx context.Context, c client.Reader, wl *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	name := ReferredWorkloadName(wl)
+	if len(name) == 0 {
+		return nil, errors.Errorf("the name of the workload referred by %s is not set in annotation %s", wl.GetKind(), oam.AnnotationReferWorkload)
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(wl.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKey{Namespace: wl.GetNamespace(), Name: name}, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &ReferredWorkloadMissingError{
+				APIVersion: wl.GetAPIVersion(),
+				Kind:       wl.GetKind(),
+				Namespace:  wl.GetNamespace(),
+				Name:       name,
+			}
+		}
+		return nil, errors.Wrapf(err, "cannot get referred workload %s %s/%s", wl.GetKind(), wl.GetNamespace(), name)
+	}
+	return live, nil
+}
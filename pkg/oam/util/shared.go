@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// IsSharedResource returns true if the resource is shared by multiple applications
+func IsSharedResource(obj metav1.Object) bool {
+	return obj.GetAnnotations()[oam.AnnotationSharedResource] == "true"
+}
+
+// ShareResource makes the desired state of a shared resource: none of its owners controls it, the owners of the
+// existing resource are kept and the fields already set by them are not overridden, so the applications sharing
+// the resource only apply the non-conflicting fields, i.e., the first writer of a field wins
+func ShareResource(existing, desired *unstructured.Unstructured) {
+	owners := desired.GetOwnerReferences()
+	for i := range owners {
+		owners[i].Controller = pointer.BoolPtr(false)
+	}
+	if existing == nil {
+		desired.SetOwnerReferences(owners)
+		return
+	}
+	for _, ref := range existing.GetOwnerReferences() {
+		found := false
+		for _, owner := range owners {
+			if owner.UID == ref.UID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			owners = append(owners, ref)
+		}
+	}
+	for key, value := range existing.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			labels := desired.GetLabels()
+			for k, v := range existing.GetLabels() {
+				if labels == nil {
+					labels = map[string]string{}
+				}
+				if _, ok := labels[k]; !ok {
+					labels[k] = v
+				}
+			}
+			desired.SetLabels(labels)
+			annotations := desired.GetAnnotations()
+			for k, v := range existing.GetAnnotations() {
+				if k == oam.AnnotationLastAppliedConfig {
+					continue
+				}
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				if _, ok := annotations[k]; !ok {
+					annotations[k] = v
+				}
+			}
+			desired.SetAnnotations(annotations)
+		default:
+			desired.Object[key] = keepExistingFields(value, desired.Object[key])
+		}
+	}
+	desired.SetOwnerReferences(owners)
+}
+
+// keepExistingFields merges the desired value into the existing one, the existing value takes precedence on conflicts
+func keepExistingFields(existing, desired interface{}) interface{} {
+	e, ok := existing.(map[string]interface{})
+	if !ok {
+		return existing
+	}
+	d, ok := desired.(map[string]interface{})
+	if !ok {
+		return existing
+	}
+	merged := make(map[string]interface{}, len(e)+len(d))
+	for k, v := range d {
+		merged[k] = v
+	}
+	for k, v := range e {
+		merged[k] = keepExistingFields(v, d[k])
+	}
+	return merged
+}
+
+// ReleaseSharedResource removes the owner from a shared resource instead of deleting it if it's still owned by
+// others. It returns true if the resource is released, false if the resource is not shared or the owner is the last
+// one, so the caller should delete it.
+func ReleaseSharedResource(ctx context.Context, c client.Client, obj *unstructured.Unstructured, owner types.UID) (bool, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if !IsSharedResource(existing) {
+		return false, nil
+	}
+	var owners []metav1.OwnerReference
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.UID != owner {
+			owners = append(owners, ref)
+		}
+	}
+	if len(owners) == 0 {
+		return false, nil
+	}
+	existing.SetOwnerReferences(owners)
+	if err := c.Update(ctx, existing); err != nil {
+		return false, errors.Wrapf(err, "cannot release shared resource %s %s", existing.GetKind(), existing.GetName())
+	}
+	return true, nil
+}
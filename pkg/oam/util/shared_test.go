@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func sharedConfigMap(data map[string]interface{}, owners ...string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"data":       data,
+	}}
+	u.SetName("shared")
+	u.SetNamespace("default")
+	u.SetAnnotations(map[string]string{oam.AnnotationSharedResource: "true"})
+	var refs []metav1.OwnerReference
+	for _, owner := range owners {
+		refs = append(refs, metav1.OwnerReference{
+			APIVersion: "core.oam.dev/v1alpha2",
+			Kind:       "ApplicationContext",
+			Name:       owner,
+			UID:        types.UID("uid-" + owner),
+			Controller: pointer.BoolPtr(true),
+		})
+	}
+	u.SetOwnerReferences(refs)
+	return u
+}
+
+func TestShareResource(t *testing.T) {
+	desired := sharedConfigMap(map[string]interface{}{"a": "2", "b": "2"}, "app2")
+	util.ShareResource(nil, desired)
+	assert.False(t, *desired.GetOwnerReferences()[0].Controller)
+
+	existing := sharedConfigMap(map[string]interface{}{"a": "1", "c": "1"}, "app1")
+	existing.SetLabels(map[string]string{"owner": "app1"})
+	desired = sharedConfigMap(map[string]interface{}{"a": "2", "b": "2"}, "app2")
+	util.ShareResource(existing, desired)
+	data, _, _ := unstructured.NestedStringMap(desired.Object, "data")
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": "1"}, data)
+	assert.Equal(t, "app1", desired.GetLabels()["owner"])
+	var owners []string
+	for _, ref := range desired.GetOwnerReferences() {
+		owners = append(owners, ref.Name)
+	}
+	assert.Equal(t, []string{"app2", "app1"}, owners)
+}
+
+func TestReleaseSharedResource(t *testing.T) {
+	ctx := context.Background()
+	cm := sharedConfigMap(map[string]interface{}{"a": "1"}, "app1", "app2")
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, cm)
+
+	// released by app1 since it's still owned by app2
+	released, err := util.ReleaseSharedResource(ctx, c, cm, "uid-app1")
+	assert.NoError(t, err)
+	assert.True(t, released)
+	got := &unstructured.Unstructured{}
+	got.SetGroupVersionKind(cm.GroupVersionKind())
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "shared"}, got))
+	assert.Equal(t, 1, len(got.GetOwnerReferences()))
+	assert.Equal(t, "app2", got.GetOwnerReferences()[0].Name)
+
+	// app2 is the last owner, it should delete the resource
+	released, err = util.ReleaseSharedResource(ctx, c, cm, "uid-app2")
+	assert.NoError(t, err)
+	assert.False(t, released)
+}
@@ -57,7 +57,7 @@ func NewLiveDiffCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comma
 
 	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file name")
 	cmd.Flags().StringVarP(&o.DefinitionFile, "definition", "d", "", "specify a file or directory containing capability definitions, they will only be used in dry-run rather than applied to K8s cluster")
-	cmd.Flags().StringVarP(&o.Revision, "revision", "r", "", "specify an application revision name, by default, it will compare with the latest revision")
+	cmd.Flags().StringVarP(&o.Revision, "revision", "r", "", "specify an application revision name or number, e.g., app-v3, v3 or 3, by default, it will compare with the latest revision")
 	cmd.Flags().IntVarP(&o.Context, "context", "c", -1, "output number lines of context around changes, by default show all unchanged lines")
 	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "specify namespace of the application to be compared, by default is default namespace")
 	cmd.SetOut(ioStreams.Out)
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preflight checks the rendered resources of an application against the admission control of the cluster,
+// i.e., the validating webhooks, policy engines like OPA Gatekeeper and the ResourceQuotas, by sending them through
+// server-side dry-run before any of them is written, so all the admission problems are found at once.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/apply"
+)
+
+// Object is a rendered resource of a component
+type Object struct {
+	Component string
+	*unstructured.Unstructured
+}
+
+// Failure is a resource rejected on admission
+type Failure struct {
+	Component  string `json:"component"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name,omitempty"`
+	Message    string `json:"message"`
+}
+
+func (f Failure) String() string {
+	name := f.Name
+	if len(name) == 0 {
+		name = "<generated>"
+	}
+	return fmt.Sprintf("%s %s/%s of component %s: %s", f.APIVersion, f.Kind, name, f.Component, f.Message)
+}
+
+// Objects returns the rendered workloads and traits to check, the workloads referring to existing ones and the
+// components not rendered yet, e.g., the Helm components, are skipped as they're not applied by the application
+func Objects(comps []*v1alpha2.Component, ac *v1alpha2.ApplicationConfiguration) ([]Object, error) {
+	var objs []Object
+	for _, comp := range comps {
+		if len(comp.Spec.Workload.Raw) == 0 {
+			continue
+		}
+		wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot convert the workload of component %s", comp.Name)
+		}
+		if util.IsReferredWorkload(wl) {
+			continue
+		}
+		if len(wl.GetName()) == 0 {
+			wl.SetName(comp.Name)
+		}
+		objs = append(objs, Object{Component: comp.Name, Unstructured: wl})
+	}
+	if ac == nil {
+		return objs, nil
+	}
+	for _, acc := range ac.Spec.Components {
+		for i := range acc.Traits {
+			if len(acc.Traits[i].Trait.Raw) == 0 {
+				continue
+			}
+			trait, err := util.RawExtension2Unstructured(&acc.Traits[i].Trait)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "cannot convert the trait of component %s", acc.ComponentName)
+			}
+			objs = append(objs, Object{Component: acc.ComponentName, Unstructured: trait})
+		}
+	}
+	return objs, nil
+}
+
+// Check sends the objects through server-side dry-run in the namespace, the namespaced objects without a namespace
+// are checked in it. The existing objects are checked by server-side apply as the changes to them, the objects
+// without a name, i.e., the traits named by the controller, are checked as new ones with generated names. The
+// failures are sorted by component, an error is returned only if the check itself fails, e.g., the API server is
+// unreachable.
+func Check(ctx context.Context, c client.Client, dm discoverymapper.DiscoveryMapper, namespace string, objs []Object) ([]Failure, error) {
+	var failures []Failure
+	for _, o := range objs {
+		obj := o.DeepCopy()
+		obj.SetResourceVersion("")
+		obj.SetManagedFields(nil)
+		fail := func(msg string) {
+			failures = append(failures, Failure{
+				Component:  o.Component,
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Name:       obj.GetName(),
+				Message:    msg,
+			})
+		}
+		namespaced, err := discoverymapper.IsNamespacedScope(dm, obj.GroupVersionKind().GroupKind())
+		if err != nil {
+			if meta.IsNoMatchError(errors.Cause(err)) {
+				fail(err.Error())
+				continue
+			}
+			return nil, err
+		}
+		switch {
+		case !namespaced:
+			obj.SetNamespace("")
+		case len(obj.GetNamespace()) == 0:
+			obj.SetNamespace(namespace)
+		}
+		if len(obj.GetName()) == 0 {
+			obj.SetGenerateName(o.Component + "-")
+			err = c.Create(ctx, obj, client.DryRunAll)
+		} else {
+			err = c.Patch(ctx, obj, client.Apply, client.DryRunAll, client.FieldOwner(apply.FieldManager), client.ForceOwnership)
+		}
+		if err == nil {
+			continue
+		}
+		if !isRejected(err) {
+			return nil, errors.Wrapf(err, "cannot dry-run %s %s of component %s", obj.GetKind(), obj.GetName(), o.Component)
+		}
+		fail(messageOf(err))
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Component < failures[j].Component
+	})
+	return failures, nil
+}
+
+// isRejected returns whether the error is caused by the object, i.e., it would fail the same way if it's written
+func isRejected(err error) bool {
+	return apierrors.IsForbidden(err) || apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) ||
+		apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) || apierrors.IsNotFound(err) ||
+		apierrors.IsMethodNotSupported(err) || apierrors.IsUnsupportedMediaType(err) ||
+		apierrors.IsRequestEntityTooLargeError(err)
+}
+
+func messageOf(err error) string {
+	if status, ok := err.(apierrors.APIStatus); ok && len(status.Status().Message) != 0 {
+		return status.Status().Message
+	}
+	return err.Error()
+}
+
+// Error aggregates the failures into an error, nil is returned if there's no failure
+func Error(failures []Failure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(failures))
+	for _, f := range failures {
+		msgs = append(msgs, f.String())
+	}
+	return errors.Errorf("%d resources are rejected on admission: %s", len(failures), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/mock"
+)
+
+// dryRunClient records the objects dry-run and rejects the ones named in denied
+type dryRunClient struct {
+	client.Client
+	denied  map[string]error
+	checked []string
+}
+
+func (c *dryRunClient) check(obj runtime.Object, opts []string) error {
+	o := obj.(metav1.Object)
+	if len(opts) != 1 || opts[0] != metav1.DryRunAll {
+		return errors.New("not a dry run")
+	}
+	name := o.GetName()
+	if len(name) == 0 {
+		name = o.GetGenerateName()
+	}
+	c.checked = append(c.checked, o.GetNamespace()+"/"+name)
+	return c.denied[name]
+}
+
+func (c *dryRunClient) Create(_ context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	o := &client.CreateOptions{}
+	o.ApplyOptions(opts)
+	return c.check(obj, o.DryRun)
+}
+
+func (c *dryRunClient) Patch(_ context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch != client.Apply {
+		return errors.New("not a server-side apply")
+	}
+	o := &client.PatchOptions{}
+	o.ApplyOptions(opts)
+	return c.check(obj, o.DryRun)
+}
+
+func newMapper() *mock.DiscoveryMapper {
+	dm := mock.NewMockDiscoveryMapper()
+	dm.MockRESTMapping = func(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+		switch gk.Kind {
+		case "Namespace":
+			return &meta.RESTMapping{Scope: meta.RESTScopeRoot}, nil
+		case "Unknown":
+			return nil, &meta.NoKindMatchError{GroupKind: gk}
+		}
+		return &meta.RESTMapping{Scope: meta.RESTScopeNamespace}, nil
+	}
+	return dm
+}
+
+func raw(s string) runtime.RawExtension {
+	return runtime.RawExtension{Raw: []byte(s)}
+}
+
+func TestCheck(t *testing.T) {
+	comps := []*v1alpha2.Component{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: v1alpha2.ComponentSpec{
+			Workload: raw(`{"apiVersion":"apps/v1","kind":"Deployment"}`)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns"}, Spec: v1alpha2.ComponentSpec{
+			Workload: raw(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"team","namespace":"default"}}`)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}, Spec: v1alpha2.ComponentSpec{
+			Workload: raw(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"annotations":{"` + oam.AnnotationReferWorkload + `":"old"}}}`)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "chart"}},
+	}
+	ac := &v1alpha2.ApplicationConfiguration{Spec: v1alpha2.ApplicationConfigurationSpec{
+		Components: []v1alpha2.ApplicationConfigurationComponent{{
+			ComponentName: "web",
+			Traits: []v1alpha2.ComponentTrait{
+				{Trait: raw(`{"apiVersion":"v1","kind":"Service","metadata":{"name":"web-svc","namespace":"other"}}`)},
+				{Trait: raw(`{"apiVersion":"example.com/v1","kind":"Unknown"}`)},
+				{Trait: raw(`{"apiVersion":"autoscaling/v1","kind":"HorizontalPodAutoscaler"}`)},
+			},
+		}},
+	}}
+	objs, err := Objects(comps, ac)
+	require.NoError(t, err)
+	require.Len(t, objs, 5)
+
+	c := &dryRunClient{denied: map[string]error{
+		"web": apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web",
+			errors.New(`admission webhook "validate.gatekeeper.sh" denied the request: image must be pinned`)),
+		"web-": apierrors.NewForbidden(schema.GroupResource{Resource: "horizontalpodautoscalers"}, "",
+			errors.New("exceeded quota: compute")),
+	}}
+	failures, err := Check(context.Background(), c, newMapper(), "prod", objs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod/web", "/team", "other/web-svc", "prod/web-"}, c.checked)
+	require.Len(t, failures, 3)
+	assert.Equal(t, "web", failures[0].Name)
+	assert.Contains(t, failures[0].Message, "image must be pinned")
+	assert.Equal(t, "Unknown", failures[1].Kind)
+	assert.Equal(t, "HorizontalPodAutoscaler", failures[2].Kind)
+	assert.Contains(t, failures[2].String(), "<generated>")
+
+	err = Error(failures)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3 resources are rejected on admission")
+	assert.NoError(t, Error(nil))
+
+	c.denied = map[string]error{"web-svc": errors.New("connection refused")}
+	_, err = Check(context.Background(), c, newMapper(), "prod", objs)
+	assert.Error(t, err)
+}
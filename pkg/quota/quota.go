@@ -0,0 +1,386 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota enforces the quotas of the CPU and memory requests and the replicas of the workloads rendered from
+// applications. The quotas are declared per namespace by the annotations of the namespace, or per project by a
+// ConfigMap in the system namespace shared by all the namespaces of the project, see oam.LabelProject.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// PolicyTypeQuota is the type of the policy choosing what to do if the application exceeds the quotas
+const PolicyTypeQuota = "quota"
+
+// ResourceReplicas is the name of the replicas of the workloads in the quotas and the usage
+const ResourceReplicas corev1.ResourceName = "replicas"
+
+// keys of the data of the ConfigMap declaring the quota of a project
+const (
+	keyCPU      = "cpu"
+	keyMemory   = "memory"
+	keyReplicas = "replicas"
+)
+
+// Action is what to do if the application exceeds the quotas
+type Action string
+
+const (
+	// ActionReject rejects the application on admission
+	ActionReject Action = "reject"
+	// ActionScaleDown admits the application and scales down the replicas of its workloads to fit in the quotas
+	ActionScaleDown Action = "scale-down"
+)
+
+// PolicySpec is the properties of the quota policy
+type PolicySpec struct {
+	// Action is what to do if the application exceeds the quotas, it defaults to reject
+	Action Action `json:"action,omitempty"`
+}
+
+// ActionOf returns the action of the quota policy of the application, it defaults to reject
+func ActionOf(app *v1beta1.Application) (Action, error) {
+	for _, p := range app.Spec.Policies {
+		if p.Type != PolicyTypeQuota {
+			continue
+		}
+		spec := PolicySpec{}
+		if p.Properties.Raw != nil {
+			if err := json.Unmarshal(p.Properties.Raw, &spec); err != nil {
+				return ActionReject, errors.Wrapf(err, "invalid properties of policy %s", p.Name)
+			}
+		}
+		switch spec.Action {
+		case "", ActionReject:
+			return ActionReject, nil
+		case ActionScaleDown:
+			return ActionScaleDown, nil
+		default:
+			return ActionReject, errors.Errorf("invalid properties of policy %s: unknown action %q", p.Name, spec.Action)
+		}
+	}
+	return ActionReject, nil
+}
+
+// Quota is the maximum usage of the applications in a set of namespaces
+type Quota struct {
+	// Scope describes the quota in messages, e.g., namespace default or project shop
+	Scope string
+	// Namespaces are the namespaces whose applications share the quota
+	Namespaces []string
+	// Hard is the maximum usage, the resources not listed are unlimited
+	Hard corev1.ResourceList
+}
+
+// Load returns the quotas of the applications in the namespace, i.e., the one of the namespace and the one of its
+// project, the ConfigMap declaring the quota of a project is looked up in the system namespace
+func Load(ctx context.Context, c client.Reader, namespace string) ([]Quota, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "cannot get namespace %s", namespace)
+	}
+	var quotas []Quota
+	hard, err := parseHard(map[string]corev1.ResourceName{
+		oam.AnnotationQuotaCPU:      corev1.ResourceRequestsCPU,
+		oam.AnnotationQuotaMemory:   corev1.ResourceRequestsMemory,
+		oam.AnnotationQuotaReplicas: ResourceReplicas,
+	}, ns.GetAnnotations())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid quota of namespace %s", namespace)
+	}
+	if len(hard) != 0 {
+		quotas = append(quotas, Quota{Scope: "namespace " + namespace, Namespaces: []string{namespace}, Hard: hard})
+	}
+	project := ns.GetLabels()[oam.LabelProject]
+	if len(project) == 0 {
+		return quotas, nil
+	}
+	cms := &corev1.ConfigMapList{}
+	if err := c.List(ctx, cms, client.InNamespace(oam.SystemDefinitonNamespace), client.MatchingLabels{oam.LabelProject: project}); err != nil {
+		return nil, errors.Wrapf(err, "cannot list the quota of project %s", project)
+	}
+	if len(cms.Items) == 0 {
+		return quotas, nil
+	}
+	if len(cms.Items) > 1 {
+		return nil, errors.Errorf("project %s has %d quotas in namespace %s, expect one", project, len(cms.Items), oam.SystemDefinitonNamespace)
+	}
+	hard, err = parseHard(map[string]corev1.ResourceName{
+		keyCPU:      corev1.ResourceRequestsCPU,
+		keyMemory:   corev1.ResourceRequestsMemory,
+		keyReplicas: ResourceReplicas,
+	}, cms.Items[0].Data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid quota of project %s", project)
+	}
+	if len(hard) == 0 {
+		return quotas, nil
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := c.List(ctx, namespaces, client.MatchingLabels{oam.LabelProject: project}); err != nil {
+		return nil, errors.Wrapf(err, "cannot list the namespaces of project %s", project)
+	}
+	q := Quota{Scope: "project " + project, Hard: hard}
+	for _, item := range namespaces.Items {
+		q.Namespaces = append(q.Namespaces, item.Name)
+	}
+	return append(quotas, q), nil
+}
+
+func parseHard(keys map[string]corev1.ResourceName, values map[string]string) (corev1.ResourceList, error) {
+	hard := corev1.ResourceList{}
+	for key, name := range keys {
+		v, ok := values[key]
+		if !ok {
+			continue
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Sign() < 0 {
+			return nil, errors.Errorf("%s must be a non-negative quantity, got %q", key, v)
+		}
+		hard[name] = q
+	}
+	return hard, nil
+}
+
+// Remaining returns the usage left to the application by the quota, i.e., the hard limits minus the usage of the
+// other applications sharing the quota, as recorded in their status
+func (q Quota) Remaining(ctx context.Context, c client.Reader, app *v1beta1.Application) (corev1.ResourceList, error) {
+	remaining := q.Hard.DeepCopy()
+	for _, ns := range q.Namespaces {
+		apps := &v1beta1.ApplicationList{}
+		if err := c.List(ctx, apps, client.InNamespace(ns)); err != nil {
+			return nil, errors.Wrapf(err, "cannot list the applications in namespace %s", ns)
+		}
+		for _, other := range apps.Items {
+			if other.Namespace == app.Namespace && other.Name == app.Name {
+				continue
+			}
+			for name, used := range other.Status.ResourceUsage {
+				if left, ok := remaining[name]; ok {
+					left.Sub(used)
+					remaining[name] = left
+				}
+			}
+		}
+	}
+	return remaining, nil
+}
+
+// ExceededError is returned if the usage of the application exceeds the quota
+type ExceededError struct {
+	Scope     string
+	Exceeded  []corev1.ResourceName
+	Usage     corev1.ResourceList
+	Remaining corev1.ResourceList
+}
+
+func (e *ExceededError) Error() string {
+	var msgs []string
+	for _, name := range e.Exceeded {
+		remaining := e.Remaining[name]
+		if remaining.Sign() < 0 {
+			remaining = resource.Quantity{}
+		}
+		used := e.Usage[name]
+		msgs = append(msgs, fmt.Sprintf("%s %s exceeds %s left", name, used.String(), remaining.String()))
+	}
+	return fmt.Sprintf("quota of %s exceeded: %s", e.Scope, strings.Join(msgs, ", "))
+}
+
+// Check checks the usage of the application against the quotas, it returns ExceededError for the first quota
+// exceeded
+func Check(ctx context.Context, c client.Reader, app *v1beta1.Application, quotas []Quota, usage corev1.ResourceList) error {
+	for _, q := range quotas {
+		remaining, err := q.Remaining(ctx, c, app)
+		if err != nil {
+			return err
+		}
+		if exceeded := Exceeded(usage, remaining); len(exceeded) != 0 {
+			return &ExceededError{Scope: q.Scope, Exceeded: exceeded, Usage: usage, Remaining: remaining}
+		}
+	}
+	return nil
+}
+
+// Exceeded returns the names of the resources whose usage exceeds the remaining, sorted
+func Exceeded(usage, remaining corev1.ResourceList) []corev1.ResourceName {
+	var exceeded []corev1.ResourceName
+	for name, left := range remaining {
+		if used, ok := usage[name]; ok && used.Cmp(left) > 0 {
+			exceeded = append(exceeded, name)
+		}
+	}
+	sort.Slice(exceeded, func(i, j int) bool { return exceeded[i] < exceeded[j] })
+	return exceeded
+}
+
+// UsageOf returns the sum of the CPU and memory requests and the replicas of the workloads. Only the workloads of
+// the known kinds are counted, see util.PodSpecPath, and the existing workloads referred by components are skipped
+// since they're not managed by the application.
+func UsageOf(workloads []*unstructured.Unstructured) (corev1.ResourceList, error) {
+	usage := corev1.ResourceList{
+		corev1.ResourceRequestsCPU:    resource.Quantity{},
+		corev1.ResourceRequestsMemory: resource.Quantity{},
+		ResourceReplicas:              resource.Quantity{},
+	}
+	for _, wl := range workloads {
+		replicas, requests, err := usageOfWorkload(wl)
+		if err != nil {
+			return nil, err
+		}
+		for name, q := range requests {
+			total := usage[name]
+			total.Add(*resource.NewMilliQuantity(q.MilliValue()*replicas, q.Format))
+			usage[name] = total
+		}
+		total := usage[ResourceReplicas]
+		total.Add(*resource.NewQuantity(replicas, resource.DecimalSI))
+		usage[ResourceReplicas] = total
+	}
+	return usage, nil
+}
+
+// WorkloadsOf returns the workloads of the rendered components in the same order, the workloads not rendered yet,
+// e.g., the ones of Helm components, are empty
+func WorkloadsOf(comps []*v1alpha2.Component) ([]*unstructured.Unstructured, error) {
+	workloads := make([]*unstructured.Unstructured, 0, len(comps))
+	for _, comp := range comps {
+		if len(comp.Spec.Workload.Raw) == 0 {
+			workloads = append(workloads, &unstructured.Unstructured{Object: map[string]interface{}{}})
+			continue
+		}
+		wl, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot convert the workload of component %s", comp.Name)
+		}
+		workloads = append(workloads, wl)
+	}
+	return workloads, nil
+}
+
+// usageOfWorkload returns the replicas of the workload and the requests of one replica
+func usageOfWorkload(wl *unstructured.Unstructured) (int64, corev1.ResourceList, error) {
+	path := util.PodSpecPath(wl.GetKind())
+	if path == nil || util.IsReferredWorkload(wl) {
+		return 0, nil, nil
+	}
+	replicas := int64(1)
+	if scalable(wl.GetKind()) {
+		n, found, err := unstructured.NestedInt64(wl.Object, "spec", "replicas")
+		if err != nil {
+			return 0, nil, errors.Wrapf(err, "invalid replicas of workload %s", wl.GetName())
+		}
+		if found {
+			replicas = n
+		}
+	}
+	podSpec, _, err := unstructured.NestedMap(wl.Object, path...)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "invalid pod spec of workload %s", wl.GetName())
+	}
+	spec := corev1.PodSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podSpec, &spec); err != nil {
+		return 0, nil, errors.Wrapf(err, "invalid pod spec of workload %s", wl.GetName())
+	}
+	requests := corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		for name, rName := range map[corev1.ResourceName]corev1.ResourceName{
+			corev1.ResourceCPU:    corev1.ResourceRequestsCPU,
+			corev1.ResourceMemory: corev1.ResourceRequestsMemory,
+		} {
+			if q, ok := container.Resources.Requests[name]; ok {
+				total := requests[rName]
+				total.Add(q)
+				requests[rName] = total
+			}
+		}
+	}
+	return replicas, requests, nil
+}
+
+// scalable checks whether the replicas of the workload kind can be scaled by spec.replicas
+func scalable(kind string) bool {
+	switch kind {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		return true
+	default:
+		return false
+	}
+}
+
+// ScaleDown scales down the replicas of the scalable workloads by the same ratio so the usage fits in the remaining
+// of all the quotas, the replicas are rounded down. It returns whether any workload is scaled down.
+func ScaleDown(workloads []*unstructured.Unstructured, usage corev1.ResourceList, remaining []corev1.ResourceList) (bool, error) {
+	ratio := 1.0
+	for _, left := range remaining {
+		for _, name := range Exceeded(usage, left) {
+			used, l := usage[name], left[name]
+			r := 0.0
+			if l.Sign() > 0 {
+				r = float64(l.MilliValue()) / float64(used.MilliValue())
+			}
+			ratio = math.Min(ratio, r)
+		}
+	}
+	if ratio >= 1 {
+		return false, nil
+	}
+	scaled := false
+	for _, wl := range workloads {
+		if !scalable(wl.GetKind()) || util.IsReferredWorkload(wl) {
+			continue
+		}
+		replicas, found, err := unstructured.NestedInt64(wl.Object, "spec", "replicas")
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid replicas of workload %s", wl.GetName())
+		}
+		if !found {
+			replicas = 1
+		}
+		target := int64(math.Floor(float64(replicas) * ratio))
+		if target >= replicas {
+			continue
+		}
+		if err := unstructured.SetNestedField(wl.Object, target, "spec", "replicas"); err != nil {
+			return false, errors.Wrapf(err, "cannot scale down workload %s", wl.GetName())
+		}
+		scaled = true
+	}
+	return scaled, nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func deployment(name string, replicas int64, cpu, memory string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "main",
+							"image": "nginx",
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"cpu": cpu, "memory": memory},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func usage(cpu, memory string, replicas int64) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceRequestsCPU:    resource.MustParse(cpu),
+		corev1.ResourceRequestsMemory: resource.MustParse(memory),
+		ResourceReplicas:              *resource.NewQuantity(replicas, resource.DecimalSI),
+	}
+}
+
+func assertUsage(t *testing.T, want, got corev1.ResourceList) {
+	t.Helper()
+	assert.Len(t, got, len(want))
+	for name, q := range want {
+		g := got[name]
+		assert.Equal(t, 0, q.Cmp(g), "%s: want %s, got %s", name, q.String(), g.String())
+	}
+}
+
+func TestUsageOf(t *testing.T) {
+	referred := deployment("legacy", 5, "1", "1Gi")
+	referred.SetAnnotations(map[string]string{oam.AnnotationReferWorkload: "legacy"})
+	job := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{
+					"name":      "main",
+					"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "100m"}},
+				}},
+			}},
+		},
+	}}
+	custom := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Custom"}}
+	got, err := UsageOf([]*unstructured.Unstructured{deployment("web", 3, "500m", "256Mi"), referred, job, custom,
+		{Object: map[string]interface{}{}}})
+	assert.NoError(t, err)
+	assertUsage(t, usage("1600m", "768Mi", 4), got)
+}
+
+func TestScaleDown(t *testing.T) {
+	workloads := []*unstructured.Unstructured{deployment("web", 4, "500m", "256Mi"), deployment("api", 2, "1", "1Gi")}
+	u, err := UsageOf(workloads)
+	assert.NoError(t, err)
+	assertUsage(t, usage("4", "3Gi", 6), u)
+
+	scaled, err := ScaleDown(workloads, u, []corev1.ResourceList{{corev1.ResourceRequestsCPU: resource.MustParse("10")}})
+	assert.NoError(t, err)
+	assert.False(t, scaled)
+
+	scaled, err = ScaleDown(workloads, u, []corev1.ResourceList{
+		{corev1.ResourceRequestsCPU: resource.MustParse("3")},
+		{ResourceReplicas: resource.MustParse("3")},
+	})
+	assert.NoError(t, err)
+	assert.True(t, scaled)
+	u, err = UsageOf(workloads)
+	assert.NoError(t, err)
+	assertUsage(t, usage("2", "1536Mi", 3), u)
+}
+
+func TestActionOf(t *testing.T) {
+	app := &v1beta1.Application{}
+	action, err := ActionOf(app)
+	assert.NoError(t, err)
+	assert.Equal(t, ActionReject, action)
+
+	app.Spec.Policies = []v1beta1.AppPolicy{{Name: "quota", Type: PolicyTypeQuota,
+		Properties: runtime.RawExtension{Raw: []byte(`{"action":"scale-down"}`)}}}
+	action, err = ActionOf(app)
+	assert.NoError(t, err)
+	assert.Equal(t, ActionScaleDown, action)
+
+	app.Spec.Policies[0].Properties.Raw = []byte(`{"action":"evict"}`)
+	_, err = ActionOf(app)
+	assert.Error(t, err)
+}
+
+func TestLoadAndCheck(t *testing.T) {
+	ctx := context.Background()
+	project := map[string]string{oam.LabelProject: "shop"}
+	objs := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cart", Labels: project, Annotations: map[string]string{
+			oam.AnnotationQuotaCPU: "2",
+		}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payment", Labels: project}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop-quota", Namespace: oam.SystemDefinitonNamespace, Labels: project},
+			Data:       map[string]string{"memory": "4Gi", "replicas": "10"},
+		},
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "payment"},
+			Status:     common.AppStatus{ResourceUsage: usage("4", "3Gi", 6)},
+		},
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "cart"},
+			Status:     common.AppStatus{ResourceUsage: usage("1", "1Gi", 2)},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme, objs...)
+
+	quotas, err := Load(ctx, c, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, quotas)
+
+	quotas, err = Load(ctx, c, "cart")
+	assert.NoError(t, err)
+	assert.Len(t, quotas, 2)
+	assert.Equal(t, "namespace cart", quotas[0].Scope)
+	assert.Equal(t, "project shop", quotas[1].Scope)
+	assert.ElementsMatch(t, []string{"cart", "payment"}, quotas[1].Namespaces)
+
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "cart"}}
+	assert.NoError(t, Check(ctx, c, app, quotas, usage("2", "1Gi", 4)))
+
+	err = Check(ctx, c, app, quotas, usage("2", "2Gi", 4))
+	exceeded, ok := err.(*ExceededError)
+	assert.True(t, ok)
+	assert.Equal(t, "project shop", exceeded.Scope)
+	assert.Equal(t, []corev1.ResourceName{corev1.ResourceRequestsMemory}, exceeded.Exceeded)
+
+	err = Check(ctx, c, app, quotas, usage("3", "1Gi", 1))
+	exceeded, ok = err.(*ExceededError)
+	assert.True(t, ok)
+	assert.Equal(t, "namespace cart", exceeded.Scope)
+}
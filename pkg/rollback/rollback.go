@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rollback rolls applications back to the revisions stored as ApplicationRevisions, the manifests rendered
+// for the revision are dispatched again rather than rendered from the current definitions
+package rollback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// Rollback rolls the application back to the revision of the number, e.g., 3 for the revision app-v3. The spec of
+// the application is restored from the revision except the rollout plan and the revision history limit, which are
+// not stored in the revisions, and the workflow is restarted. The application controller dispatches the manifests
+// stored in the revision and records them as a new revision, see oam.AnnotationRollbackRevision.
+func Rollback(ctx context.Context, c client.Client, app *v1beta1.Application, revision int64) (*v1beta1.ApplicationRevision, error) {
+	name := utils.ConstructRevisionName(app.Name, revision)
+	if latest := app.Status.LatestRevision; latest != nil && latest.Name == name {
+		return nil, fmt.Errorf("application %s is already at revision %s", app.Name, name)
+	}
+	rev := &v1beta1.ApplicationRevision{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: name}, rev); err != nil {
+		return nil, errors.Wrapf(err, "cannot get revision %s of application %s", name, app.Name)
+	}
+	if rev.GetLabels()[oam.LabelAppName] != app.Name {
+		return nil, fmt.Errorf("revision %s doesn't belong to application %s", name, app.Name)
+	}
+	patch := client.MergeFrom(app.DeepCopy())
+	spec := rev.Spec.Application.Spec.DeepCopy()
+	spec.RolloutPlan, spec.RevisionHistoryLimit = app.Spec.RolloutPlan, app.Spec.RevisionHistoryLimit
+	app.Spec = *spec
+	util.AddAnnotations(app, map[string]string{oam.AnnotationRollbackRevision: name})
+	workflow.RequestRestart(app, "rolled back to revision "+name)
+	if err := c.Patch(ctx, app, patch); err != nil {
+		return nil, errors.Wrapf(err, "cannot roll back application %s", app.Name)
+	}
+	return rev, nil
+}
+
+// SameSpec returns whether the spec of the application equals the one stored in the revision, the rollout plan and
+// the revision history limit are not compared
+func SameSpec(app *v1beta1.Application, rev *v1beta1.ApplicationRevision) bool {
+	spec := app.Spec.DeepCopy()
+	spec.RolloutPlan, spec.RevisionHistoryLimit = nil, nil
+	stored := rev.Spec.Application.Spec.DeepCopy()
+	stored.RolloutPlan, stored.RevisionHistoryLimit = nil, nil
+	return apiequality.Semantic.DeepEqual(spec, stored)
+}
+
+// Manifests decodes the ApplicationConfiguration and Components rendered for the revision. The configuration
+// refers to the components by name rather than by the component revisions created for the revision, so they're
+// created again from the components if they're already cleaned up.
+func Manifests(rev *v1beta1.ApplicationRevision) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
+	if len(rev.Spec.ApplicationConfiguration.Raw) == 0 && rev.Spec.ApplicationConfiguration.Object == nil {
+		return nil, nil, fmt.Errorf("revision %s has no manifests stored", rev.Name)
+	}
+	ac, err := util.RawExtension2AppConfig(rev.Spec.ApplicationConfiguration)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot decode the application configuration of revision %s", rev.Name)
+	}
+	ac.ResourceVersion, ac.UID = "", ""
+	for i := range ac.Spec.Components {
+		acc := &ac.Spec.Components[i]
+		if len(acc.RevisionName) != 0 {
+			acc.ComponentName, acc.RevisionName = utils.ExtractComponentName(acc.RevisionName), ""
+		}
+	}
+	comps := make([]*v1alpha2.Component, 0, len(rev.Spec.Components))
+	for _, raw := range rev.Spec.Components {
+		b, err := raw.Raw.MarshalJSON()
+		if err != nil {
+			return nil, nil, err
+		}
+		comp := &v1alpha2.Component{}
+		if err := json.Unmarshal(b, comp); err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot decode the components of revision %s", rev.Name)
+		}
+		comp.ResourceVersion, comp.UID = "", ""
+		comps = append(comps, comp)
+	}
+	return ac, comps, nil
+}
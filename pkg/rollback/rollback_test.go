@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollback
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+func newComponent(name, image string) v1beta1.ApplicationComponent {
+	return v1beta1.ApplicationComponent{
+		Name:       name,
+		Type:       "webservice",
+		Properties: runtime.RawExtension{Raw: []byte(`{"image":"` + image + `"}`)},
+	}
+}
+
+func TestRollback(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+		Spec: v1beta1.ApplicationSpec{
+			Components:           []v1beta1.ApplicationComponent{newComponent("web", "nginx:1.21"), newComponent("cache", "redis")},
+			RevisionHistoryLimit: pointer.Int32Ptr(5),
+		},
+		Status: common.AppStatus{LatestRevision: &common.Revision{Name: "app-v2", Revision: 2}},
+	}
+	source := &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-v1", Labels: map[string]string{oam.LabelAppName: "app"}},
+		Spec: v1beta1.ApplicationRevisionSpec{Application: v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+			Components: []v1beta1.ApplicationComponent{newComponent("web", "nginx:1.20")},
+		}}},
+	}
+	other := &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-v3", Labels: map[string]string{oam.LabelAppName: "app-v3-owner"}},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	c := fake.NewFakeClientWithScheme(scheme, app, source, other)
+
+	_, err := Rollback(context.Background(), c, app.DeepCopy(), 2)
+	assert.Error(t, err, "rolling back to the current revision")
+	_, err = Rollback(context.Background(), c, app.DeepCopy(), 3)
+	assert.Error(t, err, "rolling back to the revision of another application")
+	_, err = Rollback(context.Background(), c, app.DeepCopy(), 4)
+	assert.Error(t, err, "rolling back to a missing revision")
+
+	rev, err := Rollback(context.Background(), c, app.DeepCopy(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "app-v1", rev.Name)
+	updated := &v1beta1.Application{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "app"}, updated))
+	require.Len(t, updated.Spec.Components, 1)
+	assert.JSONEq(t, `{"image":"nginx:1.20"}`, string(updated.Spec.Components[0].Properties.Raw))
+	assert.Equal(t, pointer.Int32Ptr(5), updated.Spec.RevisionHistoryLimit)
+	assert.Equal(t, "app-v1", updated.GetAnnotations()[oam.AnnotationRollbackRevision])
+	assert.Contains(t, updated.GetAnnotations()[oam.AnnotationRestartWorkflow], "app-v1")
+	assert.True(t, SameSpec(updated, source))
+
+	updated.Spec.Components[0] = newComponent("web", "nginx:1.22")
+	assert.False(t, SameSpec(updated, source))
+}
+
+func TestManifests(t *testing.T) {
+	comp := &v1alpha2.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", ResourceVersion: "12"},
+		Spec:       v1alpha2.ComponentSpec{Workload: runtime.RawExtension{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment"}`)}},
+	}
+	ac := &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: v1alpha2.ApplicationConfigurationSpec{Components: []v1alpha2.ApplicationConfigurationComponent{
+			{RevisionName: "web-v2"},
+		}},
+	}
+	rev := &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v1"},
+		Spec: v1beta1.ApplicationRevisionSpec{
+			Components:               []common.RawComponent{{Raw: util.Object2RawExtension(comp)}},
+			ApplicationConfiguration: util.Object2RawExtension(ac),
+		},
+	}
+	gotAC, gotComps, err := Manifests(rev)
+	require.NoError(t, err)
+	assert.Equal(t, "web", gotAC.Spec.Components[0].ComponentName)
+	assert.Empty(t, gotAC.Spec.Components[0].RevisionName)
+	require.Len(t, gotComps, 1)
+	assert.Equal(t, "web", gotComps[0].Name)
+	assert.Empty(t, gotComps[0].ResourceVersion)
+
+	_, _, err = Manifests(&v1beta1.ApplicationRevision{ObjectMeta: metav1.ObjectMeta{Name: "app-v2"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// ImageProperty is the property of the components set to the image pushed
+const ImageProperty = "image"
+
+// ApplyImageEvent sets the image property of the components of the application subscribing the repository pushed,
+// see oam.AnnotationTriggerImages, and requests the workflow to restart. It returns false if no component is updated.
+func ApplyImageEvent(app *v1beta1.Application, e *ImageEvent) (bool, error) {
+	subs, err := parseImageSubscriptions(app.GetAnnotations()[oam.AnnotationTriggerImages])
+	if err != nil {
+		return false, err
+	}
+	updated := false
+	for i := range app.Spec.Components {
+		comp := &app.Spec.Components[i]
+		repo, ok := subs[comp.Name]
+		if !ok || !sameRepository(repo, e.Repository) {
+			continue
+		}
+		// the repository is kept as it's written in the annotation, e.g., without the default registry
+		image := repo + ":" + e.Tag
+		if len(e.Tag) == 0 {
+			image = repo + "@" + e.Digest
+		}
+		_, changed, err := SetImage(comp, image)
+		if err != nil {
+			return false, err
+		}
+		updated = updated || changed
+	}
+	if updated {
+		workflow.RequestRestart(app, fmt.Sprintf("image %s pushed", e))
+	}
+	return updated, nil
+}
+
+// SetImage sets the image property of the component, it returns the image before and false if it's unchanged
+func SetImage(comp *v1beta1.ApplicationComponent, image string) (string, bool, error) {
+	props := map[string]interface{}{}
+	if len(comp.Properties.Raw) != 0 {
+		if err := json.Unmarshal(comp.Properties.Raw, &props); err != nil {
+			return "", false, errors.Wrapf(err, "invalid properties of component %s", comp.Name)
+		}
+	}
+	previous, _ := props[ImageProperty].(string)
+	if previous == image {
+		return previous, false, nil
+	}
+	props[ImageProperty] = image
+	raw, err := json.Marshal(props)
+	if err != nil {
+		return "", false, err
+	}
+	comp.Properties = runtime.RawExtension{Raw: raw}
+	return previous, true, nil
+}
+
+// ApplyGitEvent requests the workflow of the application subscribing the repository and ref pushed to restart, see
+// oam.AnnotationTriggerGit, the Git components are re-synced on restart. It returns false if the application doesn't
+// subscribe the push.
+func ApplyGitEvent(app *v1beta1.Application, e *GitEvent) bool {
+	repo, ref := parseGitSubscription(app.GetAnnotations()[oam.AnnotationTriggerGit])
+	if len(repo) == 0 || !sameGitRepo(repo, e.Repo) || (len(ref) != 0 && ref != e.Ref) {
+		return false
+	}
+	commit := e.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	workflow.RequestRestart(app, fmt.Sprintf("commit %s pushed to %s", commit, e))
+	return true
+}
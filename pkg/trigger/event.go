@@ -0,0 +1,290 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trigger redeploys the applications on the push events of image registries and Git repositories received
+// by the Triggers, so the applications are deployed on push without a CI pipeline patching them.
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+)
+
+const (
+	// HeaderToken is the header carrying the token of the Trigger sent by the generic senders
+	HeaderToken = "X-Vela-Trigger-Token"
+	// HeaderGitHubEvent is the header carrying the type of the GitHub events
+	HeaderGitHubEvent = "X-GitHub-Event"
+	// HeaderGitHubSignature is the header carrying the HMAC-SHA256 signature of the GitHub payloads
+	HeaderGitHubSignature = "X-Hub-Signature-256"
+	// HeaderGitLabEvent is the header carrying the type of the GitLab events
+	HeaderGitLabEvent = "X-Gitlab-Event"
+	// HeaderGitLabToken is the header carrying the secret token of the GitLab webhooks
+	HeaderGitLabToken = "X-Gitlab-Token"
+
+	// SecretTokenKey is the key of the token in the Secret referred by a Trigger
+	SecretTokenKey = "token"
+
+	refHeadsPrefix = "refs/heads/"
+	refTagsPrefix  = "refs/tags/"
+)
+
+// ErrIgnored is returned for the events not redeploying anything, e.g., the ping events or the deletion of branches
+var ErrIgnored = errors.New("event ignored")
+
+// ImageEvent is an image pushed to a registry
+type ImageEvent struct {
+	// Repository is the repository the image is pushed to, e.g., ghcr.io/org/web
+	Repository string
+	// Tag is the tag pushed, it can be empty if the image is pushed by digest
+	Tag string
+	// Digest is the digest of the image pushed, it's empty if the sender doesn't report it
+	Digest string
+}
+
+// String returns the image pushed
+func (e ImageEvent) String() string {
+	if len(e.Tag) != 0 {
+		return e.Repository + ":" + e.Tag
+	}
+	return e.Repository + "@" + e.Digest
+}
+
+// GitEvent is a push to a branch or tag of a Git repository
+type GitEvent struct {
+	// Repo is the URL of the repository, e.g., https://github.com/org/app
+	Repo string
+	// Ref is the branch or tag pushed
+	Ref string
+	// Commit is the SHA of the commit the ref is at after the push
+	Commit string
+}
+
+// String returns the ref of the repository pushed
+func (e GitEvent) String() string {
+	return e.Repo + "@" + e.Ref
+}
+
+// Verify verifies the webhook is sent by a sender knowing the token of the Trigger, i.e., the HMAC signature of
+// GitHub, the secret token of GitLab, the Authorization header or the token header or query parameter of the others.
+// The query parameter is for the senders unable to set headers, e.g., Docker Hub.
+func Verify(r *http.Request, body, token []byte) error {
+	if len(token) == 0 {
+		return errors.New("the token of the trigger is empty")
+	}
+	if sig := r.Header.Get(HeaderGitHubSignature); len(sig) != 0 {
+		mac := hmac.New(sha256.New, token)
+		_, _ = mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(sig), []byte(expected)) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	candidates := []string{
+		r.Header.Get(HeaderGitLabToken),
+		r.Header.Get(HeaderToken),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.URL.Query().Get(SecretTokenKey),
+	}
+	for _, c := range candidates {
+		if len(c) != 0 && subtle.ConstantTimeCompare([]byte(c), token) == 1 {
+			return nil
+		}
+	}
+	return errors.New("token mismatch")
+}
+
+// imagePayload is the union of the image push payloads of Docker Hub, Harbor and the generic senders, i.e.,
+// {"repository": "ghcr.io/org/web", "tag": "v1.0.0", "digest": "sha256:..."}
+type imagePayload struct {
+	// PushData is set by Docker Hub
+	PushData *struct {
+		Tag string `json:"tag"`
+	} `json:"push_data,omitempty"`
+	// EventData is set by Harbor
+	EventData *struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data,omitempty"`
+	// Repository is an object of Docker Hub, or the repository of the generic senders
+	Repository json.RawMessage `json:"repository,omitempty"`
+	Tag        string          `json:"tag,omitempty"`
+	Digest     string          `json:"digest,omitempty"`
+}
+
+// ParseImageEvent parses the image push event of Docker Hub, Harbor or the generic senders
+func ParseImageEvent(body []byte) (*ImageEvent, error) {
+	p := &imagePayload{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	switch {
+	case p.EventData != nil:
+		if len(p.EventData.Resources) == 0 {
+			return nil, ErrIgnored
+		}
+		res := p.EventData.Resources[0]
+		ref, err := oci.ParseReference(res.ResourceURL)
+		if err != nil {
+			return nil, err
+		}
+		return &ImageEvent{Repository: ref.Registry + "/" + ref.Repository, Tag: res.Tag, Digest: res.Digest}, nil
+	case p.PushData != nil:
+		repo := struct {
+			RepoName string `json:"repo_name"`
+		}{}
+		if err := json.Unmarshal(p.Repository, &repo); err != nil {
+			return nil, errors.Wrap(err, "invalid repository of Docker Hub payload")
+		}
+		return &ImageEvent{Repository: repo.RepoName, Tag: p.PushData.Tag}, nil
+	default:
+		var repo string
+		if err := json.Unmarshal(p.Repository, &repo); err != nil || len(repo) == 0 {
+			return nil, errors.New("the repository is not set")
+		}
+		if len(p.Tag) == 0 && len(p.Digest) == 0 {
+			return nil, errors.New("neither tag nor digest is set")
+		}
+		return &ImageEvent{Repository: repo, Tag: p.Tag, Digest: p.Digest}, nil
+	}
+}
+
+// gitPayload is the union of the push payloads of GitHub and GitLab
+type gitPayload struct {
+	Ref string `json:"ref"`
+	// After is set by GitHub, it's all zeros if the ref is deleted
+	After string `json:"after"`
+	// CheckoutSHA is set by GitLab, it's empty if the ref is deleted
+	CheckoutSHA *string `json:"checkout_sha"`
+	Deleted     bool    `json:"deleted"`
+	Repository  *struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+	Project *struct {
+		WebURL string `json:"web_url"`
+	} `json:"project"`
+}
+
+// ParseGitEvent parses the push event of GitHub or GitLab, the other events are ignored
+func ParseGitEvent(header http.Header, body []byte) (*GitEvent, error) {
+	github, gitlab := header.Get(HeaderGitHubEvent), header.Get(HeaderGitLabEvent)
+	switch {
+	case len(github) != 0 && github != "push":
+		return nil, ErrIgnored
+	case len(gitlab) != 0 && gitlab != "Push Hook" && gitlab != "Tag Push Hook":
+		return nil, ErrIgnored
+	}
+	p := &gitPayload{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	e := &GitEvent{Ref: strings.TrimPrefix(strings.TrimPrefix(p.Ref, refHeadsPrefix), refTagsPrefix)}
+	switch {
+	case p.Project != nil:
+		e.Repo = p.Project.WebURL
+		if p.CheckoutSHA != nil {
+			e.Commit = *p.CheckoutSHA
+		}
+	case p.Repository != nil:
+		e.Repo, e.Commit = p.Repository.HTMLURL, p.After
+	}
+	if len(e.Repo) == 0 || len(e.Ref) == 0 {
+		return nil, errors.New("neither the repository nor the ref is set")
+	}
+	if p.Deleted || len(strings.Trim(e.Commit, "0")) == 0 {
+		return nil, ErrIgnored
+	}
+	return e, nil
+}
+
+// MatchTag returns whether the tag matches the pattern, all the tags match an empty pattern
+func MatchTag(pattern, tag string) (bool, error) {
+	if len(pattern) == 0 {
+		return true, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid tag pattern %q", pattern)
+	}
+	return re.MatchString(tag), nil
+}
+
+// sameRepository returns whether the image repositories are the same, e.g., nginx and docker.io/library/nginx
+func sameRepository(a, b string) bool {
+	ra, err := oci.ParseReference(a)
+	if err != nil {
+		return false
+	}
+	rb, err := oci.ParseReference(b)
+	if err != nil {
+		return false
+	}
+	return ra.Registry == rb.Registry && ra.Repository == rb.Repository
+}
+
+// sameGitRepo returns whether the Git repository URLs are the same, ignoring the scheme, the case and the .git suffix
+func sameGitRepo(a, b string) bool {
+	normalize := func(s string) string {
+		u, err := url.Parse(strings.ToLower(s))
+		if err != nil {
+			return s
+		}
+		return u.Host + strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// parseGitSubscription parses the value of oam.AnnotationTriggerGit, i.e., <repo>[@<branch or tag>]
+func parseGitSubscription(s string) (repo, ref string) {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "@"); i > strings.LastIndex(s, "/") {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// parseImageSubscriptions parses the value of oam.AnnotationTriggerImages, i.e., <component>=<repository>,...
+func parseImageSubscriptions(s string) (map[string]string, error) {
+	subs := map[string]string{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 || len(kv[1]) == 0 {
+			return nil, fmt.Errorf("invalid image subscription %q, it must be <component>=<repository>", item)
+		}
+		subs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return subs, nil
+}
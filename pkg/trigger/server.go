@@ -0,0 +1,285 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// PathPrefix is the path prefix of the webhooks of the Triggers, i.e., /triggers/<namespace>/<name>
+const PathPrefix = "/triggers/"
+
+var (
+	// MaxPayloadSize is the maximum size in bytes of the webhook payloads
+	MaxPayloadSize int64 = 1 << 20
+	// HandleTimeout is the timeout of redeploying the applications on a webhook
+	HandleTimeout = 30 * time.Second
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// httpError is an error responded with the status code
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func newHTTPError(code int, err error) error {
+	return &httpError{code: code, err: err}
+}
+
+// Response is the response of a webhook
+type Response struct {
+	// Applications are the applications redeployed
+	Applications []string `json:"applications,omitempty"`
+	// Message describes why nothing is redeployed or the error
+	Message string `json:"message,omitempty"`
+}
+
+// Server receives the webhooks of the Triggers at /triggers/<namespace>/<name>. It's served by every replica of the
+// controller since the applications are redeployed through the API server.
+type Server struct {
+	addr   string
+	client client.Client
+	// reader reads the Triggers and Secrets from the API server directly, so no informer caching all the secrets in
+	// the cluster is started
+	reader client.Reader
+}
+
+// NewServer creates a Server listening on the address, it must be started to receive the webhooks
+func NewServer(addr string, c client.Client, reader client.Reader) *Server {
+	return &Server{addr: addr, client: c, reader: reader}
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=triggers,verbs=get
+// +kubebuilder:rbac:groups=core.oam.dev,resources=triggers/status,verbs=update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// Start serves the webhooks until stop is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	srv := &http.Server{
+		Addr:         s.addr,
+		Handler:      s,
+		ReadTimeout:  HandleTimeout,
+		WriteTimeout: 2 * HandleTimeout,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	klog.InfoS("Serving the webhooks of triggers", "address", s.addr)
+	select {
+	case err := <-errCh:
+		return err
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the webhooks are received by all replicas
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// ServeHTTP handles the webhook of a Trigger
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeResponse(w, http.StatusMethodNotAllowed, &Response{Message: "only POST is allowed"})
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/"), "/")
+	if !strings.HasPrefix(r.URL.Path, PathPrefix) || len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		writeResponse(w, http.StatusNotFound, &Response{Message: "the path must be " + PathPrefix + "<namespace>/<name>"})
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxPayloadSize+1))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, &Response{Message: err.Error()})
+		return
+	}
+	if int64(len(body)) > MaxPayloadSize {
+		writeResponse(w, http.StatusRequestEntityTooLarge, &Response{Message: fmt.Sprintf("the payload exceeds %d bytes", MaxPayloadSize)})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), HandleTimeout)
+	defer cancel()
+	key := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+	resp, err := s.Handle(ctx, key, r, body)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var herr *httpError
+		if errors.As(err, &herr) {
+			code = herr.code
+		}
+		klog.ErrorS(err, "Failed to handle the webhook of trigger", "trigger", key)
+		resp.Message = err.Error()
+		writeResponse(w, code, resp)
+		return
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+// Handle verifies the webhook of the Trigger and redeploys the applications subscribing it with the push event, the
+// event is recorded in the status of the Trigger
+func (s *Server) Handle(ctx context.Context, key types.NamespacedName, r *http.Request, body []byte) (*Response, error) {
+	resp := &Response{}
+	trigger := &v1beta1.Trigger{}
+	if err := s.reader.Get(ctx, key, trigger); err != nil {
+		if apierrors.IsNotFound(err) {
+			return resp, newHTTPError(http.StatusNotFound, fmt.Errorf("trigger %s not found", key))
+		}
+		return resp, err
+	}
+	secret := &corev1.Secret{}
+	if err := s.reader.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: trigger.Spec.SecretRef.Name}, secret); err != nil {
+		return resp, errors.WithMessagef(err, "cannot get the secret of trigger %s", key)
+	}
+	if err := Verify(r, body, secret.Data[SecretTokenKey]); err != nil {
+		return resp, newHTTPError(http.StatusUnauthorized, errors.WithMessage(err, "cannot verify the webhook"))
+	}
+
+	var event fmt.Stringer
+	var apply func(app *v1beta1.Application) (bool, error)
+	switch trigger.Spec.Type {
+	case v1beta1.TriggerTypeImage:
+		e, err := ParseImageEvent(body)
+		if err != nil {
+			return ignoreOrReject(resp, err)
+		}
+		matched, err := MatchTag(trigger.Spec.TagPattern, e.Tag)
+		if err != nil {
+			return resp, err
+		}
+		if !matched {
+			resp.Message = fmt.Sprintf("tag %q doesn't match the pattern", e.Tag)
+			return resp, nil
+		}
+		event = e
+		apply = func(app *v1beta1.Application) (bool, error) {
+			return ApplyImageEvent(app, e)
+		}
+	case v1beta1.TriggerTypeGit:
+		e, err := ParseGitEvent(r.Header, body)
+		if err != nil {
+			return ignoreOrReject(resp, err)
+		}
+		event = e
+		apply = func(app *v1beta1.Application) (bool, error) {
+			return ApplyGitEvent(app, e), nil
+		}
+	default:
+		return resp, fmt.Errorf("unknown type %q of trigger %s", trigger.Spec.Type, key)
+	}
+
+	apps, err := s.redeploy(ctx, trigger, apply)
+	resp.Applications = apps
+	if len(apps) == 0 && err == nil {
+		resp.Message = "no application subscribes " + event.String()
+	}
+	s.recordStatus(ctx, trigger, event.String(), apps, err)
+	return resp, err
+}
+
+// redeploy applies the push event to the applications subscribing the Trigger, it returns the applications updated
+func (s *Server) redeploy(ctx context.Context, trigger *v1beta1.Trigger, apply func(*v1beta1.Application) (bool, error)) ([]string, error) {
+	apps := &v1beta1.ApplicationList{}
+	if err := s.client.List(ctx, apps, client.InNamespace(trigger.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "cannot list applications")
+	}
+	var updated []string
+	var errs []error
+	for i := range apps.Items {
+		if apps.Items[i].GetAnnotations()[oam.AnnotationTrigger] != trigger.Name {
+			continue
+		}
+		name := apps.Items[i].Name
+		changed := false
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			app := &v1beta1.Application{}
+			if err := s.client.Get(ctx, types.NamespacedName{Namespace: trigger.Namespace, Name: name}, app); err != nil {
+				return err
+			}
+			patch := client.MergeFrom(app.DeepCopy())
+			var err error
+			if changed, err = apply(app); err != nil || !changed {
+				return err
+			}
+			return s.client.Patch(ctx, app, patch)
+		})
+		if err != nil {
+			errs = append(errs, errors.WithMessagef(err, "cannot redeploy application %s", name))
+			continue
+		}
+		if changed {
+			updated = append(updated, name)
+			klog.InfoS("Redeploy application on trigger", "application", klog.KRef(trigger.Namespace, name), "trigger", klog.KObj(trigger))
+		}
+	}
+	return updated, utilerrors.NewAggregate(errs)
+}
+
+func (s *Server) recordStatus(ctx context.Context, trigger *v1beta1.Trigger, event string, apps []string, err error) {
+	now := metav1.Now()
+	trigger.Status = v1beta1.TriggerStatus{LastEvent: event, LastTriggeredAt: &now, Applications: apps}
+	if err != nil {
+		trigger.Status.Message = err.Error()
+	}
+	if err := s.client.Status().Update(ctx, trigger); err != nil {
+		klog.ErrorS(err, "Failed to update the status of trigger", "trigger", klog.KObj(trigger))
+	}
+}
+
+// ignoreOrReject responds OK to the events ignored, e.g., the ping events, and rejects the invalid payloads
+func ignoreOrReject(resp *Response, err error) (*Response, error) {
+	if errors.Is(err, ErrIgnored) {
+		resp.Message = err.Error()
+		return resp, nil
+	}
+	return resp, newHTTPError(http.StatusBadRequest, err)
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const token = "s3cr3t"
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+
+	github := httptest.NewRequest(http.MethodPost, "/triggers/default/t", nil)
+	github.Header.Set(HeaderGitHubSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	assert.NoError(t, Verify(github, body, []byte(token)))
+	assert.Error(t, Verify(github, []byte(`{"ref":"refs/heads/evil"}`), []byte(token)))
+
+	gitlab := httptest.NewRequest(http.MethodPost, "/triggers/default/t", nil)
+	gitlab.Header.Set(HeaderGitLabToken, token)
+	assert.NoError(t, Verify(gitlab, body, []byte(token)))
+
+	query := httptest.NewRequest(http.MethodPost, "/triggers/default/t?token="+token, nil)
+	assert.NoError(t, Verify(query, body, []byte(token)))
+
+	wrong := httptest.NewRequest(http.MethodPost, "/triggers/default/t", nil)
+	wrong.Header.Set(HeaderToken, "wrong")
+	assert.Error(t, Verify(wrong, body, []byte(token)))
+	assert.Error(t, Verify(wrong, body, nil))
+}
+
+func TestParseImageEvent(t *testing.T) {
+	testCases := map[string]struct {
+		payload string
+		want    *ImageEvent
+		wantErr bool
+	}{
+		"docker hub": {
+			payload: `{"push_data":{"tag":"v1.0.0"},"repository":{"repo_name":"org/web"}}`,
+			want:    &ImageEvent{Repository: "org/web", Tag: "v1.0.0"},
+		},
+		"harbor": {
+			payload: `{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"digest":"sha256:abc","tag":"v1.0.0","resource_url":"harbor.example.com/lib/web:v1.0.0"}]}}`,
+			want:    &ImageEvent{Repository: "harbor.example.com/lib/web", Tag: "v1.0.0", Digest: "sha256:abc"},
+		},
+		"generic": {
+			payload: `{"repository":"ghcr.io/org/web","tag":"v1.0.0"}`,
+			want:    &ImageEvent{Repository: "ghcr.io/org/web", Tag: "v1.0.0"},
+		},
+		"no tag": {
+			payload: `{"repository":"ghcr.io/org/web"}`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseImageEvent([]byte(tc.payload))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseGitEvent(t *testing.T) {
+	header := http.Header{}
+	header.Set(HeaderGitHubEvent, "push")
+	e, err := ParseGitEvent(header, []byte(`{"ref":"refs/heads/main","after":"0123abcd","repository":{"html_url":"https://github.com/org/app"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &GitEvent{Repo: "https://github.com/org/app", Ref: "main", Commit: "0123abcd"}, e)
+
+	_, err = ParseGitEvent(header, []byte(`{"ref":"refs/heads/main","after":"0000000000","deleted":true,"repository":{"html_url":"https://github.com/org/app"}}`))
+	assert.Equal(t, ErrIgnored, err)
+
+	header.Set(HeaderGitHubEvent, "ping")
+	_, err = ParseGitEvent(header, []byte(`{"zen":"hello"}`))
+	assert.Equal(t, ErrIgnored, err)
+
+	header = http.Header{}
+	header.Set(HeaderGitLabEvent, "Tag Push Hook")
+	e, err = ParseGitEvent(header, []byte(`{"ref":"refs/tags/v1.0.0","checkout_sha":"4567cdef","project":{"web_url":"https://gitlab.com/org/app"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &GitEvent{Repo: "https://gitlab.com/org/app", Ref: "v1.0.0", Commit: "4567cdef"}, e)
+}
+
+func newApp(name string, annotations map[string]string, props string) *v1beta1.Application {
+	return &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+		Spec: v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{
+			{Name: "web", Type: "webservice", Properties: runtime.RawExtension{Raw: []byte(props)}},
+			{Name: "worker", Type: "worker", Properties: runtime.RawExtension{Raw: []byte(`{"image":"org/worker:v1"}`)}},
+		}},
+	}
+}
+
+func imageOf(t *testing.T, comp v1beta1.ApplicationComponent) string {
+	props := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(comp.Properties.Raw, &props))
+	return props[ImageProperty].(string)
+}
+
+func TestApplyImageEvent(t *testing.T) {
+	app := newApp("app", map[string]string{oam.AnnotationTriggerImages: "web=org/web"}, `{"image":"org/web:v1","port":80}`)
+	updated, err := ApplyImageEvent(app, &ImageEvent{Repository: "docker.io/org/web", Tag: "v2"})
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "org/web:v2", imageOf(t, app.Spec.Components[0]))
+	assert.Equal(t, "org/worker:v1", imageOf(t, app.Spec.Components[1]))
+	assert.Equal(t, "image docker.io/org/web:v2 pushed", app.Annotations[oam.AnnotationRestartWorkflow])
+
+	updated, err = ApplyImageEvent(app, &ImageEvent{Repository: "docker.io/org/web", Tag: "v2"})
+	require.NoError(t, err)
+	assert.False(t, updated, "the image is up to date")
+	updated, err = ApplyImageEvent(app, &ImageEvent{Repository: "ghcr.io/org/web", Tag: "v3"})
+	require.NoError(t, err)
+	assert.False(t, updated, "the repository is not subscribed")
+}
+
+func TestApplyGitEvent(t *testing.T) {
+	e := &GitEvent{Repo: "https://github.com/Org/App", Ref: "main", Commit: "0123abcdef"}
+	assert.True(t, ApplyGitEvent(newApp("a", map[string]string{oam.AnnotationTriggerGit: "https://github.com/org/app.git"}, `{}`), e))
+	assert.True(t, ApplyGitEvent(newApp("b", map[string]string{oam.AnnotationTriggerGit: "https://github.com/org/app@main"}, `{}`), e))
+	assert.False(t, ApplyGitEvent(newApp("c", map[string]string{oam.AnnotationTriggerGit: "https://github.com/org/app@release"}, `{}`), e))
+	assert.False(t, ApplyGitEvent(newApp("d", nil, `{}`), e))
+}
+
+func TestServer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	trigger := &v1beta1.Trigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "images", Namespace: "default"},
+		Spec: v1beta1.TriggerSpec{
+			Type:       v1beta1.TriggerTypeImage,
+			SecretRef:  corev1.LocalObjectReference{Name: "trigger-token"},
+			TagPattern: `^v\d+$`,
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "trigger-token", Namespace: "default"},
+		Data:       map[string][]byte{SecretTokenKey: []byte(token)},
+	}
+	subscribed := newApp("subscribed", map[string]string{
+		oam.AnnotationTrigger:       "images",
+		oam.AnnotationTriggerImages: "web=ghcr.io/org/web",
+	}, `{"image":"ghcr.io/org/web:v1"}`)
+	other := newApp("other", map[string]string{oam.AnnotationTriggerImages: "web=ghcr.io/org/web"}, `{"image":"ghcr.io/org/web:v1"}`)
+	c := fake.NewFakeClientWithScheme(scheme, trigger, secret, subscribed, other)
+	s := NewServer(":0", c, c)
+
+	post := func(path, tok, payload string) (*httptest.ResponseRecorder, *Response) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(payload))
+		r.Header.Set(HeaderToken, tok)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		resp := &Response{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		return w, resp
+	}
+
+	w, _ := post("/triggers/default/images", "wrong", `{"repository":"ghcr.io/org/web","tag":"v2"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w, _ = post("/triggers/default/missing", token, `{"repository":"ghcr.io/org/web","tag":"v2"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, resp := post("/triggers/default/images", token, `{"repository":"ghcr.io/org/web","tag":"latest"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, resp.Applications, "the tag doesn't match the pattern")
+
+	w, resp = post("/triggers/default/images", token, `{"repository":"ghcr.io/org/web","tag":"v2"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"subscribed"}, resp.Applications)
+
+	ctx := context.Background()
+	got := &v1beta1.Application{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "subscribed"}, got))
+	assert.Equal(t, "ghcr.io/org/web:v2", imageOf(t, got.Spec.Components[0]))
+	assert.Contains(t, got.Annotations, oam.AnnotationRestartWorkflow)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "other"}, got))
+	assert.Equal(t, "ghcr.io/org/web:v1", imageOf(t, got.Spec.Components[0]))
+
+	gotTrigger := &v1beta1.Trigger{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "images"}, gotTrigger))
+	assert.Equal(t, "ghcr.io/org/web:v2", gotTrigger.Status.LastEvent)
+	assert.Equal(t, []string{"subscribed"}, gotTrigger.Status.Applications)
+}
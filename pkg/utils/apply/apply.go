@@ -28,15 +28,32 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// FieldManager is the stable field manager name used by KubeVela in server-side apply
+const FieldManager = "kubevela"
+
+// Strategy is the strategy to apply the new state of an existing object
+type Strategy string
+
+const (
+	// StrategyThreeWayMerge computes a three-way merge patch in client side, it's the default strategy
+	StrategyThreeWayMerge Strategy = "three-way-merge"
+	// StrategyServerSide uses server-side apply with FieldManager, so KubeVela only owns the fields it rendered
+	// and won't conflict with other controllers mutating the same object
+	StrategyServerSide Strategy = "server-side"
+)
+
 // Applicator applies new state to an object or create it if not exist.
 // It uses the same mechanism as `kubectl apply`, that is, for each resource being applied,
 // computing a three-way diff merge in client side based on its current state, modified stated,
 // and last-applied-state which is tracked through an specific annotation.
 // If the resource doesn't exist before, Apply will create it.
+// Server-side apply will be used instead if it's the default strategy of the applicator, or the object
+// is annotated with `app.oam.dev/apply-strategy: server-side`.
 type Applicator interface {
 	Apply(context.Context, runtime.Object, ...ApplyOption) error
 }
@@ -51,12 +68,27 @@ type ApplyOption func(ctx context.Context, existing, desired runtime.Object) err
 // object or creates the object if not exist.
 func NewAPIApplicator(c client.Client) *APIApplicator {
 	return &APIApplicator{
-		creator: creatorFn(createOrGetExisting),
-		patcher: patcherFn(threeWayMergePatch),
-		c:       c,
+		creator:  creatorFn(createOrGetExisting),
+		patcher:  patcherFn(threeWayMergePatch),
+		c:        c,
+		strategy: StrategyThreeWayMerge,
 	}
 }
 
+// WithDefaultStrategy sets the strategy used for objects without the apply strategy annotation
+func (a *APIApplicator) WithDefaultStrategy(s Strategy) *APIApplicator {
+	a.strategy = s
+	return a
+}
+
+// WithClient returns a copy of the applicator applying through the client, e.g., one impersonating the owner of the
+// objects, the strategy and the rate limiter are shared
+func (a *APIApplicator) WithClient(c client.Client) *APIApplicator {
+	cp := *a
+	cp.c = c
+	return &cp
+}
+
 type creator interface {
 	createOrGetExisting(context.Context, client.Client, runtime.Object, ...ApplyOption) (runtime.Object, error)
 }
@@ -81,7 +113,9 @@ func (fn patcherFn) patch(c, m runtime.Object) (client.Patch, error) {
 type APIApplicator struct {
 	creator
 	patcher
-	c client.Client
+	c        client.Client
+	strategy Strategy
+	limiter  flowcontrol.RateLimiter
 }
 
 // loggingApply will record a log with desired object applied
@@ -96,6 +130,12 @@ func loggingApply(msg string, desired runtime.Object) {
 
 // Apply applies new state to an object or create it if not exist
 func (a *APIApplicator) Apply(ctx context.Context, desired runtime.Object, ao ...ApplyOption) error {
+	if err := a.wait(ctx); err != nil {
+		return err
+	}
+	if a.strategyOf(desired) == StrategyServerSide {
+		return a.serverSideApply(ctx, desired, ao...)
+	}
 	existing, err := a.createOrGetExisting(ctx, a.c, desired, ao...)
 	if err != nil {
 		return err
@@ -116,6 +156,53 @@ func (a *APIApplicator) Apply(ctx context.Context, desired runtime.Object, ao ..
 	return errors.Wrapf(a.c.Patch(ctx, desired, patch), "cannot patch object")
 }
 
+// strategyOf returns the apply strategy of the object, the annotation of the object takes precedence
+func (a *APIApplicator) strategyOf(desired runtime.Object) Strategy {
+	if m, ok := desired.(metav1.Object); ok {
+		switch s := Strategy(m.GetAnnotations()[oam.AnnotationApplyStrategy]); s {
+		case StrategyServerSide, StrategyThreeWayMerge:
+			return s
+		}
+	}
+	return a.strategy
+}
+
+// serverSideApply applies the desired state with server-side apply, the object will be created if it does not exist.
+// Ownership of conflicting fields is forced to FieldManager as the rendered state is the source of truth.
+func (a *APIApplicator) serverSideApply(ctx context.Context, desired runtime.Object, ao ...ApplyOption) error {
+	m, ok := desired.(oam.Object)
+	if !ok {
+		return errors.New("cannot access object metadata")
+	}
+	// server-side apply requires the name, so create the object with only generateName directly
+	if m.GetName() == "" && m.GetGenerateName() != "" {
+		if err := executeApplyOptions(ctx, nil, desired, ao); err != nil {
+			return err
+		}
+		loggingApply("creating object", desired)
+		return errors.Wrap(a.c.Create(ctx, desired, client.FieldOwner(FieldManager)), "cannot create object")
+	}
+
+	var existing runtime.Object
+	u := &unstructured.Unstructured{}
+	u.GetObjectKind().SetGroupVersionKind(desired.GetObjectKind().GroupVersionKind())
+	err := a.c.Get(ctx, types.NamespacedName{Name: m.GetName(), Namespace: m.GetNamespace()}, u)
+	switch {
+	case err == nil:
+		existing = u
+	case !kerrors.IsNotFound(err):
+		return errors.Wrap(err, "cannot get object")
+	}
+	if err := executeApplyOptions(ctx, existing, desired, ao); err != nil {
+		return err
+	}
+	// managedFields must be empty in an apply request
+	m.SetManagedFields(nil)
+	loggingApply("server-side applying object", desired)
+	return errors.Wrap(a.c.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership),
+		"cannot server-side apply object")
+}
+
 // createOrGetExisting will create the object if it does not exist
 // or get and return the existing object
 func createOrGetExisting(ctx context.Context, c client.Client, desired runtime.Object, ao ...ApplyOption) (runtime.Object, error) {
@@ -33,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 var ctx = context.Background()
@@ -151,6 +152,105 @@ func TestAPIApplicator(t *testing.T) {
 	}
 }
 
+func TestServerSideApply(t *testing.T) {
+	desired := &unstructured.Unstructured{}
+	desired.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	desired.SetName("desired")
+	desired.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl"}})
+	annotated := desired.DeepCopy()
+	annotated.SetAnnotations(map[string]string{oam.AnnotationApplyStrategy: string(StrategyServerSide)})
+
+	cases := map[string]struct {
+		reason   string
+		strategy Strategy
+		desired  runtime.Object
+		getErr   error
+		ao       []ApplyOption
+		wantSSA  bool
+		want     error
+	}{
+		"DefaultStrategy": {
+			reason:  "Three-way merge patch should be used by default",
+			desired: desired.DeepCopy(),
+		},
+		"ServerSideByDefault": {
+			reason:   "Server-side apply should be used if it's the default strategy",
+			strategy: StrategyServerSide,
+			desired:  desired.DeepCopy(),
+			wantSSA:  true,
+		},
+		"ServerSideByAnnotation": {
+			reason:   "Server-side apply should be used if the object is annotated",
+			strategy: StrategyThreeWayMerge,
+			desired:  annotated.DeepCopy(),
+			wantSSA:  true,
+		},
+		"ServerSideNotFound": {
+			reason:   "Server-side apply should create the object if it does not exist",
+			strategy: StrategyServerSide,
+			desired:  desired.DeepCopy(),
+			getErr:   kerrors.NewNotFound(schema.GroupResource{}, "desired"),
+			wantSSA:  true,
+		},
+		"ServerSideGetError": {
+			reason:   "An error should be returned if cannot get the existing object",
+			strategy: StrategyServerSide,
+			desired:  desired.DeepCopy(),
+			getErr:   errFake,
+			want:     errors.Wrap(errFake, "cannot get object"),
+		},
+		"ServerSideApplyOptionError": {
+			reason:   "An error should be returned if cannot apply ApplyOption",
+			strategy: StrategyServerSide,
+			desired:  desired.DeepCopy(),
+			ao: []ApplyOption{func(_ context.Context, _, _ runtime.Object) error {
+				return errFake
+			}},
+			want: errors.Wrap(errFake, "cannot apply ApplyOption"),
+		},
+	}
+
+	for caseName, tc := range cases {
+		t.Run(caseName, func(t *testing.T) {
+			var ssa bool
+			a := &APIApplicator{
+				creator: creatorFn(func(_ context.Context, _ client.Client, _ runtime.Object, _ ...ApplyOption) (runtime.Object, error) {
+					return &unstructured.Unstructured{}, nil
+				}),
+				patcher: patcherFn(func(c, m runtime.Object) (client.Patch, error) {
+					return client.MergeFrom(c), nil
+				}),
+				c: &test.MockClient{
+					MockGet: test.NewMockGetFn(tc.getErr),
+					MockPatch: func(_ context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if patch.Type() != types.ApplyPatchType {
+							return nil
+						}
+						ssa = true
+						po := &client.PatchOptions{}
+						po.ApplyOptions(opts)
+						if po.FieldManager != FieldManager || po.Force == nil || !*po.Force {
+							t.Errorf("unexpected patch options %v", po)
+						}
+						if len(obj.(metav1.Object).GetManagedFields()) != 0 {
+							t.Errorf("managedFields should be cleared")
+						}
+						return nil
+					},
+				},
+				strategy: tc.strategy,
+			}
+			result := a.Apply(ctx, tc.desired, tc.ao...)
+			if diff := cmp.Diff(tc.want, result, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want , +got \n%s\n", tc.reason, diff)
+			}
+			if tc.want == nil && ssa != tc.wantSSA {
+				t.Errorf("\n%s\nserver-side apply: want %t, got %t", tc.reason, tc.wantSSA, ssa)
+			}
+		})
+	}
+}
+
 func TestCreator(t *testing.T) {
 	desired := &unstructured.Unstructured{}
 	desired.SetName("desired")
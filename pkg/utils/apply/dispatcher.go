@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// RateLimit configures how fast and how many resources are applied at the same time
+type RateLimit struct {
+	// QPS is the maximum number of resources applied per second, zero means unlimited
+	QPS float64
+	// Burst is the maximum number of resources applied in a burst above QPS
+	Burst int
+	// BatchSize is the number of resources of the same apply order applied concurrently, they're applied one by
+	// one if it's less than 2
+	BatchSize int
+}
+
+// NewLimiter creates a token bucket rate limiter following the QPS and Burst, nil is returned if QPS is not set
+func (r RateLimit) NewLimiter() flowcontrol.RateLimiter {
+	if r.QPS <= 0 {
+		return nil
+	}
+	burst := r.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(r.QPS), burst)
+}
+
+// WithRateLimiter makes the applicator wait for the rate limiter before applying each object, the limiter can be
+// shared by applicators to limit the overall rate to the same API server
+func (a *APIApplicator) WithRateLimiter(l flowcontrol.RateLimiter) *APIApplicator {
+	a.limiter = l
+	return a
+}
+
+func (a *APIApplicator) wait(ctx context.Context) error {
+	if a.limiter == nil {
+		return nil
+	}
+	return errors.Wrap(a.limiter.Wait(ctx), "cannot wait for the apply rate limiter")
+}
+
+// Apply orders of the resources, the resources others depend on are applied first, i.e.,
+// namespaces -> CRDs -> RBAC -> configurations and storage -> workloads and the others.
+const (
+	orderNamespace = iota
+	orderCRD
+	orderRBAC
+	orderConfig
+	orderOthers
+)
+
+var applyOrders = map[string]int{
+	"Namespace": orderNamespace,
+	"CustomResourceDefinition.apiextensions.k8s.io": orderCRD,
+	"ServiceAccount":                               orderRBAC,
+	"ClusterRole.rbac.authorization.k8s.io":        orderRBAC,
+	"Role.rbac.authorization.k8s.io":               orderRBAC,
+	"ClusterRoleBinding.rbac.authorization.k8s.io": orderRBAC,
+	"RoleBinding.rbac.authorization.k8s.io":        orderRBAC,
+	"PodSecurityPolicy.policy":                     orderRBAC,
+	"ConfigMap":                                    orderConfig,
+	"Secret":                                       orderConfig,
+	"StorageClass.storage.k8s.io":                  orderConfig,
+	"PersistentVolume":                             orderConfig,
+	"PersistentVolumeClaim":                        orderConfig,
+	"PriorityClass.scheduling.k8s.io":              orderConfig,
+	"LimitRange":                                   orderConfig,
+	"ResourceQuota":                                orderConfig,
+	"NetworkPolicy.networking.k8s.io":              orderConfig,
+	"MutatingWebhookConfiguration.admissionregistration.k8s.io":   orderConfig,
+	"ValidatingWebhookConfiguration.admissionregistration.k8s.io": orderConfig,
+}
+
+// applyOrder returns the apply order of the object by its group and kind
+func applyOrder(u *unstructured.Unstructured) int {
+	if order, ok := applyOrders[u.GroupVersionKind().GroupKind().String()]; ok {
+		return order
+	}
+	return orderOthers
+}
+
+// Dispatcher applies a set of resources in the apply order of their kinds, the resources of the same order are
+// applied concurrently in batches, so large applications are applied faster while the rate limiter of the
+// applicator keeps the API server from being flooded
+type Dispatcher struct {
+	applicator Applicator
+	batchSize  int
+}
+
+// NewDispatcher creates a Dispatcher applying at most batchSize resources at the same time by the applicator
+func NewDispatcher(a Applicator, batchSize int) *Dispatcher {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &Dispatcher{applicator: a, batchSize: batchSize}
+}
+
+// Dispatch applies the manifests, resources of an apply order are applied only after all the resources of the
+// previous orders are applied. The manifests are not modified, the first error in the order of the manifests is
+// returned.
+func (d *Dispatcher) Dispatch(ctx context.Context, manifests []*unstructured.Unstructured, ao ...ApplyOption) error {
+	ordered := make([]*unstructured.Unstructured, len(manifests))
+	copy(ordered, manifests)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return applyOrder(ordered[i]) < applyOrder(ordered[j])
+	})
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		for end < len(ordered) && end-start < d.batchSize && applyOrder(ordered[end]) == applyOrder(ordered[start]) {
+			end++
+		}
+		if err := d.applyBatch(ctx, ordered[start:end], ao); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+func (d *Dispatcher) applyBatch(ctx context.Context, batch []*unstructured.Unstructured, ao []ApplyOption) error {
+	apply := func(manifest *unstructured.Unstructured) error {
+		return errors.WithMessagef(d.applicator.Apply(ctx, manifest.DeepCopy(), ao...),
+			"cannot apply %s %s/%s", manifest.GetKind(), manifest.GetNamespace(), manifest.GetName())
+	}
+	if len(batch) == 1 {
+		return apply(batch[0])
+	}
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = apply(batch[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
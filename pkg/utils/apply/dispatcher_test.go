@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type recordingApplicator struct {
+	mu      sync.Mutex
+	applied []string
+	errs    map[string]error
+}
+
+func (r *recordingApplicator) Apply(_ context.Context, o runtime.Object, _ ...ApplyOption) error {
+	u := o.(*unstructured.Unstructured)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = append(r.applied, u.GetName())
+	return r.errs[u.GetName()]
+}
+
+func newManifest(apiVersion, kind, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func TestDispatcher(t *testing.T) {
+	manifests := []*unstructured.Unstructured{
+		newManifest("apps/v1", "Deployment", "deploy"),
+		newManifest("v1", "Service", "svc"),
+		newManifest("rbac.authorization.k8s.io/v1", "RoleBinding", "rolebinding"),
+		newManifest("v1", "ConfigMap", "cm"),
+		newManifest("apiextensions.k8s.io/v1", "CustomResourceDefinition", "crd"),
+		newManifest("v1", "ServiceAccount", "sa"),
+		newManifest("v1", "Namespace", "ns"),
+	}
+
+	cases := map[string]struct {
+		reason    string
+		batchSize int
+		errs      map[string]error
+		want      []string
+		wantErr   string
+	}{
+		"ApplyInOrder": {
+			reason:    "Resources should be applied in the apply order of their kinds and keep their order in a kind",
+			batchSize: 1,
+			want:      []string{"ns", "crd", "rolebinding", "sa", "cm", "deploy", "svc"},
+		},
+		"StopAtFailedOrder": {
+			reason:    "Resources of the later orders should not be applied if a resource failed to be applied",
+			batchSize: 10,
+			errs:      map[string]error{"sa": errFake},
+			want:      []string{"ns", "crd", "rolebinding", "sa"},
+			wantErr:   "cannot apply ServiceAccount /sa: fake error",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &recordingApplicator{errs: tc.errs}
+			err := NewDispatcher(a, tc.batchSize).Dispatch(context.Background(), manifests)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("\n%s\nDispatch(...): unexpected error %v", tc.reason, err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Fatalf("\n%s\nDispatch(...): want error %q, got %v", tc.reason, tc.wantErr, err)
+			}
+			// resources of the same order are applied concurrently in a batch, so only compare them as sets
+			got := a.applied
+			if tc.batchSize > 1 {
+				got = sortedByOrder(manifests, got)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDispatch(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// sortedByOrder sorts the names of the applied resources by their apply orders then the order of the manifests
+func sortedByOrder(manifests []*unstructured.Unstructured, applied []string) []string {
+	var sorted []string
+	for order := orderNamespace; order <= orderOthers; order++ {
+		for _, m := range manifests {
+			if applyOrder(m) != order {
+				continue
+			}
+			for _, name := range applied {
+				if name == m.GetName() {
+					sorted = append(sorted, name)
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+func TestRateLimitNewLimiter(t *testing.T) {
+	if l := (RateLimit{}).NewLimiter(); l != nil {
+		t.Errorf("NewLimiter(): want nil limiter if QPS is not set, got %v", l)
+	}
+	l := RateLimit{QPS: 10, Burst: 20}.NewLimiter()
+	if l == nil || l.QPS() != 10 {
+		t.Errorf("NewLimiter(): want a limiter of 10 QPS, got %v", l)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+)
+
+// stages of the reconciliation recording decisions
+const (
+	StageParse      = "parse"
+	StageDefinition = "definition"
+	StageSchedule   = "schedule"
+	StageApply      = "apply"
+	StageGC         = "gc"
+)
+
+// DefaultStore keeps the latest decision log of every application in memory, it's served by the diagnostics endpoint
+var DefaultStore = NewStore()
+
+// Entry is a single decision made during the reconciliation of an application
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Stage    string    `json:"stage"`
+	Subject  string    `json:"subject"`
+	Decision string    `json:"decision"`
+}
+
+// Log is the structured decision trace of one reconciliation of an application
+type Log struct {
+	mu         sync.Mutex
+	Key        string  `json:"application"`
+	Generation int64   `json:"generation"`
+	Entries    []Entry `json:"entries"`
+}
+
+// NewLog creates a decision log for the application identified by key, i.e., namespace/name
+func NewLog(key string, generation int64) *Log {
+	return &Log{Key: key, Generation: generation}
+}
+
+// Record appends a decision to the log, it's a no-op on a nil log so callers don't need to check whether
+// the decision log is enabled
+func (l *Log) Record(stage, subject, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	decision := fmt.Sprintf(format, args...)
+	klog.V(common.LogDebug).InfoS("reconcile decision", "application", l.Key, "stage", stage,
+		"subject", subject, "decision", decision)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Entries = append(l.Entries, Entry{Time: time.Now(), Stage: stage, Subject: subject, Decision: decision})
+}
+
+// JSON returns the log marshalled in json format
+func (l *Log) JSON() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return json.MarshalIndent(l, "", "  ")
+}
+
+type logKey struct{}
+
+// WithLog returns a copy of ctx carrying the decision log
+func WithLog(ctx context.Context, l *Log) context.Context {
+	return context.WithValue(ctx, logKey{}, l)
+}
+
+// FromContext returns the decision log carried by ctx, or nil if the decision log is not enabled
+func FromContext(ctx context.Context) *Log {
+	l, _ := ctx.Value(logKey{}).(*Log)
+	return l
+}
+
+// Record appends a decision to the log carried by ctx if any
+func Record(ctx context.Context, stage, subject, format string, args ...interface{}) {
+	FromContext(ctx).Record(stage, subject, format, args...)
+}
+
+// Store keeps the latest decision log of applications
+type Store struct {
+	mu   sync.RWMutex
+	logs map[string]*Log
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{logs: map[string]*Log{}}
+}
+
+// Put replaces the decision log of the application
+func (s *Store) Put(l *Log) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs[l.Key] = l
+}
+
+// Get returns the latest decision log of the application identified by key
+func (s *Store) Get(key string) (*Log, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.logs[key]
+	return l, ok
+}
+
+// Delete drops the decision log of the application, e.g., the decision log is disabled
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, key)
+}
+
+// ServeHTTP serves the decision log of the application given by the query "app=namespace/name",
+// it lists the applications having a decision log if the query is absent
+func (s *Store) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	key := req.URL.Query().Get("app")
+	if len(key) == 0 {
+		s.mu.RLock()
+		keys := make([]string, 0, len(s.logs))
+		for k := range s.logs {
+			keys = append(keys, k)
+		}
+		s.mu.RUnlock()
+		sort.Strings(keys)
+		_ = json.NewEncoder(w).Encode(keys)
+		return
+	}
+	l, ok := s.Get(key)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no decision log of application %s", key), http.StatusNotFound)
+		return
+	}
+	b, err := l.JSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decision
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	// recording without a log is a no-op
+	ctx := context.Background()
+	Record(ctx, StageParse, "web", "skipped")
+	assert.Nil(t, FromContext(ctx))
+
+	l := NewLog("default/app", 2)
+	ctx = WithLog(ctx, l)
+	Record(ctx, StageParse, "web", "skipped since the condition %q is false", "context.env == \"prod\"")
+	Record(ctx, StageGC, "app-v1", "kept")
+	assert.Equal(t, 2, len(l.Entries))
+	assert.Equal(t, StageParse, l.Entries[0].Stage)
+	assert.Equal(t, "web", l.Entries[0].Subject)
+	assert.Equal(t, `skipped since the condition "context.env == \"prod\"" is false`, l.Entries[0].Decision)
+
+	b, err := l.JSON()
+	assert.NoError(t, err)
+	got := &Log{}
+	assert.NoError(t, json.Unmarshal(b, got))
+	assert.Equal(t, "default/app", got.Key)
+	assert.Equal(t, int64(2), got.Generation)
+	assert.Equal(t, 2, len(got.Entries))
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	l := NewLog("default/app", 1)
+	l.Record(StageApply, "web", "applied")
+	s.Put(l)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/decisions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `["default/app"]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/decisions?app=default/app", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	got := &Log{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), got))
+	assert.Equal(t, "applied", got.Entries[0].Decision)
+
+	s.Delete("default/app")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/decisions?app=default/app", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package impersonate builds the clients impersonating the identities declared by applications, so the rendered
+// resources are applied with the permissions of the application owners rather than the controller, see
+// oam.AnnotationServiceAccountName and oam.AnnotationImpersonateUser
+package impersonate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const serviceAccountUsernamePrefix = "system:serviceaccount:"
+
+// ServiceAccountUsername returns the username of the ServiceAccount
+func ServiceAccountUsername(namespace, name string) string {
+	return fmt.Sprintf("%s%s:%s", serviceAccountUsernamePrefix, namespace, name)
+}
+
+// serviceAccountGroups returns the groups the ServiceAccounts in the namespace belong to
+func serviceAccountGroups(namespace string) []string {
+	return []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace}
+}
+
+// ConfigFor returns the identity declared by the annotations of the object to impersonate, or nil if none is
+// declared. The ServiceAccount is in the namespace of the object and it's impersonated along with the groups of the
+// ServiceAccounts.
+func ConfigFor(obj metav1.Object) (*rest.ImpersonationConfig, error) {
+	annotations := obj.GetAnnotations()
+	sa, user := annotations[oam.AnnotationServiceAccountName], annotations[oam.AnnotationImpersonateUser]
+	var groups []string
+	for _, g := range strings.Split(annotations[oam.AnnotationImpersonateGroups], ",") {
+		if g = strings.TrimSpace(g); len(g) != 0 {
+			groups = append(groups, g)
+		}
+	}
+	switch {
+	case len(sa) != 0 && len(user) != 0:
+		return nil, errors.Errorf("annotations %s and %s are exclusive", oam.AnnotationServiceAccountName, oam.AnnotationImpersonateUser)
+	case len(sa) != 0:
+		user = ServiceAccountUsername(obj.GetNamespace(), sa)
+		groups = append(groups, serviceAccountGroups(obj.GetNamespace())...)
+	case len(user) == 0 && len(groups) != 0:
+		return nil, errors.Errorf("annotation %s requires a user or a ServiceAccount to impersonate", oam.AnnotationImpersonateGroups)
+	case len(user) == 0:
+		return nil, nil
+	}
+	sort.Strings(groups)
+	return &rest.ImpersonationConfig{UserName: user, Groups: groups}, nil
+}
+
+// ClientFactory builds the clients impersonating identities, a client is built once for an identity and reused
+type ClientFactory struct {
+	config    *rest.Config
+	options   client.Options
+	newClient func(*rest.Config, client.Options) (client.Client, error)
+	// required means the objects must declare identities to impersonate, they're never applied by the controller
+	required bool
+
+	mu      sync.Mutex
+	clients map[string]client.Client
+}
+
+// NewClientFactory creates a ClientFactory building the clients from the config of the controller
+func NewClientFactory(cfg *rest.Config, scheme *runtime.Scheme, mapper meta.RESTMapper) *ClientFactory {
+	return &ClientFactory{
+		config:    cfg,
+		options:   client.Options{Scheme: scheme, Mapper: mapper},
+		newClient: client.New,
+		clients:   map[string]client.Client{},
+	}
+}
+
+// WithRequired requires the objects to declare identities to impersonate, ClientOf fails for the ones declaring none
+// instead of falling back to the default client
+func (f *ClientFactory) WithRequired(required bool) *ClientFactory {
+	f.required = required
+	return f
+}
+
+// ClientFor returns the client impersonating the identity, the objects are read from the API server directly
+func (f *ClientFactory) ClientFor(identity rest.ImpersonationConfig) (client.Client, error) {
+	key := identity.UserName + "\x00" + strings.Join(identity.Groups, ",")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.clients[key]; ok {
+		return c, nil
+	}
+	cfg := rest.CopyConfig(f.config)
+	cfg.Impersonate = identity
+	c, err := f.newClient(cfg, f.options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build the client impersonating %s", identity.UserName)
+	}
+	f.clients[key] = c
+	return c, nil
+}
+
+// ClientOf returns the client impersonating the identity declared by the annotations of the object, or the default
+// client if none is declared. It's safe to call on a nil ClientFactory, the default client is always returned then.
+func (f *ClientFactory) ClientOf(obj metav1.Object, defaultClient client.Client) (client.Client, error) {
+	if f == nil {
+		return defaultClient, nil
+	}
+	identity, err := ConfigFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if identity == nil {
+		if f.required {
+			return nil, errors.Errorf("one of annotations %s and %s is required to declare the identity to impersonate",
+				oam.AnnotationServiceAccountName, oam.AnnotationImpersonateUser)
+		}
+		return defaultClient, nil
+	}
+	return f.ClientFor(*identity)
+}
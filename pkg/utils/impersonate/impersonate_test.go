@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impersonate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestConfigFor(t *testing.T) {
+	testCases := map[string]struct {
+		annotations map[string]string
+		want        *rest.ImpersonationConfig
+		wantErr     bool
+	}{
+		"nothing declared": {},
+		"service account": {
+			annotations: map[string]string{oam.AnnotationServiceAccountName: "deployer"},
+			want: &rest.ImpersonationConfig{
+				UserName: "system:serviceaccount:tenant:deployer",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:tenant"},
+			},
+		},
+		"user with groups": {
+			annotations: map[string]string{
+				oam.AnnotationImpersonateUser:   "alice",
+				oam.AnnotationImpersonateGroups: "ops, dev,,",
+			},
+			want: &rest.ImpersonationConfig{UserName: "alice", Groups: []string{"dev", "ops"}},
+		},
+		"service account and user": {
+			annotations: map[string]string{
+				oam.AnnotationServiceAccountName: "deployer",
+				oam.AnnotationImpersonateUser:    "alice",
+			},
+			wantErr: true,
+		},
+		"groups only": {
+			annotations: map[string]string{oam.AnnotationImpersonateGroups: "ops"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			obj := &metav1.ObjectMeta{Namespace: "tenant", Annotations: tc.annotations}
+			got, err := ConfigFor(obj)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestClientOf(t *testing.T) {
+	defaultClient := fake.NewFakeClientWithScheme(utilcommon.Scheme)
+	var built []rest.ImpersonationConfig
+	f := &ClientFactory{
+		config: &rest.Config{Host: "https://localhost:6443"},
+		newClient: func(cfg *rest.Config, _ client.Options) (client.Client, error) {
+			built = append(built, cfg.Impersonate)
+			return fake.NewFakeClientWithScheme(utilcommon.Scheme), nil
+		},
+		clients: map[string]client.Client{},
+	}
+
+	c, err := f.ClientOf(&metav1.ObjectMeta{Namespace: "tenant"}, defaultClient)
+	assert.NoError(t, err)
+	assert.Same(t, defaultClient, c)
+
+	obj := &metav1.ObjectMeta{Namespace: "tenant", Annotations: map[string]string{oam.AnnotationServiceAccountName: "deployer"}}
+	c1, err := f.ClientOf(obj, defaultClient)
+	assert.NoError(t, err)
+	assert.NotSame(t, defaultClient, c1)
+	c2, err := f.ClientOf(obj, defaultClient)
+	assert.NoError(t, err)
+	assert.Same(t, c1, c2)
+	assert.Len(t, built, 1)
+	assert.Equal(t, "system:serviceaccount:tenant:deployer", built[0].UserName)
+
+	_, err = f.WithRequired(true).ClientOf(&metav1.ObjectMeta{Namespace: "tenant"}, defaultClient)
+	assert.Error(t, err)
+	c, err = f.ClientOf(obj, defaultClient)
+	assert.NoError(t, err)
+	assert.Same(t, c1, c)
+
+	var nilFactory *ClientFactory
+	c, err = nilFactory.ClientOf(obj, defaultClient)
+	assert.NoError(t, err)
+	assert.Same(t, defaultClient, c)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impersonate
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// Validate validates the identity declared by the object to apply its resources, the user creating or changing it
+// must be allowed to impersonate the identity through SubjectAccessReviews, otherwise one could escalate the
+// privileges through the object. The identity cannot be removed once declared, since the resources would be applied
+// by the controller then, and it must be declared if required. The old object is nil on creation.
+func Validate(ctx context.Context, c client.Client, newObj, oldObj metav1.Object, user authenticationv1.UserInfo, required bool) field.ErrorList {
+	path := field.NewPath("metadata", "annotations")
+	identity, err := ConfigFor(newObj)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, newObj.GetAnnotations(), err.Error())}
+	}
+	var oldIdentity *rest.ImpersonationConfig
+	if oldObj != nil {
+		oldIdentity, _ = ConfigFor(oldObj)
+	}
+	if identity == nil {
+		switch {
+		case oldIdentity != nil:
+			return field.ErrorList{field.Forbidden(path, fmt.Sprintf("the identity %s to impersonate cannot be removed", oldIdentity.UserName))}
+		case required:
+			return field.ErrorList{field.Required(path, fmt.Sprintf("one of annotations %s and %s is required to declare the identity to impersonate",
+				oam.AnnotationServiceAccountName, oam.AnnotationImpersonateUser))}
+		}
+		return nil
+	}
+	if oldIdentity != nil && equality.Semantic.DeepEqual(identity, oldIdentity) {
+		return nil
+	}
+	key := oam.AnnotationImpersonateUser
+	attrs := authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: "users", Name: identity.UserName}
+	// the groups of the ServiceAccounts come with the ServiceAccount, they're not reviewed since groups are cluster
+	// scoped and a namespaced Role couldn't allow impersonating them
+	implicit := map[string]bool{}
+	if sa := newObj.GetAnnotations()[oam.AnnotationServiceAccountName]; len(sa) != 0 {
+		key = oam.AnnotationServiceAccountName
+		attrs = authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: "serviceaccounts", Namespace: newObj.GetNamespace(), Name: sa}
+		for _, g := range serviceAccountGroups(newObj.GetNamespace()) {
+			implicit[g] = true
+		}
+	}
+	var allErrs field.ErrorList
+	if ok, err := allowed(ctx, c, user, attrs); err != nil {
+		return field.ErrorList{field.InternalError(path.Key(key), err)}
+	} else if !ok {
+		allErrs = append(allErrs, field.Forbidden(path.Key(key), fmt.Sprintf("user %s is not allowed to impersonate %s", user.Username, identity.UserName)))
+	}
+	for _, g := range identity.Groups {
+		if implicit[g] {
+			continue
+		}
+		ok, err := allowed(ctx, c, user, authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: "groups", Name: g})
+		if err != nil {
+			return field.ErrorList{field.InternalError(path.Key(oam.AnnotationImpersonateGroups), err)}
+		}
+		if !ok {
+			allErrs = append(allErrs, field.Forbidden(path.Key(oam.AnnotationImpersonateGroups), fmt.Sprintf("user %s is not allowed to impersonate group %s", user.Username, g)))
+		}
+	}
+	return allErrs
+}
+
+// allowed reviews whether the user is allowed to access the resource through a SubjectAccessReview
+func allowed(ctx context.Context, c client.Client, user authenticationv1.UserInfo, attrs authorizationv1.ResourceAttributes) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attrs,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package impersonate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	user := authenticationv1.UserInfo{Username: "alice"}
+	// alice is granted the namespaced Role of the docs only, i.e., she may impersonate the ServiceAccount deployer
+	// of her namespace but no group
+	c := &test.MockClient{MockCreate: func(_ context.Context, obj runtime.Object, _ ...client.CreateOption) error {
+		review := obj.(*authorizationv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Verb == "impersonate" && attrs.Resource == "serviceaccounts" &&
+			attrs.Namespace == "tenant" && attrs.Name == "deployer"
+		return nil
+	}}
+	objWith := func(annotations map[string]string) *metav1.ObjectMeta {
+		return &metav1.ObjectMeta{Namespace: "tenant", Annotations: annotations}
+	}
+	none := objWith(nil)
+	deployer := objWith(map[string]string{oam.AnnotationServiceAccountName: "deployer"})
+	admin := objWith(map[string]string{oam.AnnotationServiceAccountName: "admin"})
+
+	assert.Empty(t, Validate(ctx, c, none, nil, user, false))
+	assert.NotEmpty(t, Validate(ctx, c, none, nil, user, true))
+	assert.Empty(t, Validate(ctx, c, deployer, nil, user, true))
+	assert.NotEmpty(t, Validate(ctx, c, admin, nil, user, false))
+	// the identity already declared is not reviewed again
+	assert.Empty(t, Validate(ctx, c, admin, admin, user, false))
+	// the identity cannot be removed to fall back to the permissions of the controller
+	assert.NotEmpty(t, Validate(ctx, c, none, deployer, user, false))
+	assert.NotEmpty(t, Validate(ctx, c, objWith(map[string]string{oam.AnnotationImpersonateGroups: "ops"}), nil, user, false))
+	// the groups declared along with the ServiceAccount are still reviewed
+	assert.NotEmpty(t, Validate(ctx, c, objWith(map[string]string{oam.AnnotationServiceAccountName: "deployer",
+		oam.AnnotationImpersonateGroups: "ops"}), nil, user, false))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Credentials are the credentials of an OCI registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsFromSecret returns the credentials of the registry in the Secret, which holds either the username and
+// password keys or the docker config of type kubernetes.io/dockerconfigjson
+func CredentialsFromSecret(secret *corev1.Secret, registry string) (*Credentials, error) {
+	if username, ok := secret.Data["username"]; ok {
+		return &Credentials{Username: string(username), Password: string(secret.Data["password"])}, nil
+	}
+	dockerConfig, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has neither username nor %s", secret.Name, corev1.DockerConfigJsonKey)
+	}
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode %s of secret %s", corev1.DockerConfigJsonKey, secret.Name)
+	}
+	for server, auth := range config.Auths {
+		// the server can be a host or an URL, e.g., https://index.docker.io/v1/
+		host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+		host = strings.SplitN(host, "/", 2)[0]
+		if host != registry && !(registry == DefaultRegistry && host == "index.docker.io") {
+			continue
+		}
+		if len(auth.Username) != 0 {
+			return &Credentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid auth of registry %s in secret %s", server, secret.Name)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid auth of registry %s in secret %s", server, secret.Name)
+		}
+		return &Credentials{Username: parts[0], Password: parts[1]}, nil
+	}
+	return nil, fmt.Errorf("secret %s has no credentials of registry %s", secret.Name, registry)
+}
@@ -0,0 +1,603 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oci pulls and pushes artifacts of OCI registries through the distribution API, e.g., the kustomize bases
+// and Helm charts stored in Harbor, GHCR or ECR.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRegistry is the registry of the references without registry host, e.g., nginx:1.20
+	DefaultRegistry = "docker.io"
+	// SchemePrefix is the optional prefix of the references, e.g., oci://ghcr.io/org/chart:1.0.0
+	SchemePrefix = "oci://"
+
+	dockerHubHost  = "registry-1.docker.io"
+	defaultTag     = "latest"
+	digestPrefix   = "sha256:"
+	manifestAccept = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+	// ManifestMediaType is the media type of the manifests pushed
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+var (
+	// DefaultMaxSize is the maximum size in bytes of a layer pulled if the Client doesn't set one
+	DefaultMaxSize int64 = 32 << 20
+	// MaxTagPages is the maximum number of pages of tags listed from a repository
+	MaxTagPages = 10
+)
+
+// Reference is a parsed reference of an artifact in an OCI registry
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses the reference, e.g., ghcr.io/org/manifests:v1.0.0 or ghcr.io/org/manifests@sha256:...,
+// the references without registry host are in Docker Hub and the tag defaults to latest
+func ParseReference(ref string) (Reference, error) {
+	r := Reference{}
+	s := strings.TrimPrefix(ref, SchemePrefix)
+	if i := strings.Index(s, "@"); i >= 0 {
+		s, r.Digest = s[:i], s[i+1:]
+		if !strings.HasPrefix(r.Digest, digestPrefix) || len(r.Digest) != len(digestPrefix)+sha256.Size*2 {
+			return r, fmt.Errorf("invalid digest of reference %q, only sha256 is supported", ref)
+		}
+	}
+	// the tag is after the last colon unless it belongs to the registry host, e.g., localhost:5000/repo
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		s, r.Tag = s[:i], s[i+1:]
+	}
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		r.Registry, r.Repository = parts[0], parts[1]
+	} else {
+		r.Registry, r.Repository = DefaultRegistry, s
+		if !strings.Contains(s, "/") {
+			r.Repository = "library/" + s
+		}
+	}
+	if len(r.Repository) == 0 {
+		return r, fmt.Errorf("invalid reference %q, the repository is empty", ref)
+	}
+	if len(r.Tag) == 0 && len(r.Digest) == 0 {
+		r.Tag = defaultTag
+	}
+	return r, nil
+}
+
+// String returns the reference in the canonical format
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if len(r.Tag) != 0 {
+		s += ":" + r.Tag
+	}
+	if len(r.Digest) != 0 {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Pinned returns whether the reference is pinned by digest, so the content it refers to is immutable
+func (r Reference) Pinned() bool {
+	return len(r.Digest) != 0
+}
+
+// Descriptor describes a layer of an artifact
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Manifest is the image manifest of an artifact
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion,omitempty"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Blob is the content of a config or layer pushed
+type Blob struct {
+	MediaType string
+	Data      []byte
+}
+
+// Client pulls and pushes artifacts of OCI registries, the anonymous access is used if the credentials are not set
+type Client struct {
+	// HTTPClient is the client sending the requests, http.DefaultClient is used if it's nil
+	HTTPClient *http.Client
+	// Username and Password are the credentials of the registry, they're used for the basic authentication or to
+	// request the bearer token from the token service of the registry
+	Username string
+	Password string
+	// PlainHTTP accesses the registry through HTTP rather than HTTPS, e.g., a registry inside the cluster
+	PlainHTTP bool
+	// MaxSize is the maximum size in bytes of a layer pulled, DefaultMaxSize is used if it's zero
+	MaxSize int64
+}
+
+// PullManifest pulls the image manifest of the artifact
+func (c *Client) PullManifest(ctx context.Context, ref Reference) (*Manifest, error) {
+	m, _, err := c.pullManifest(ctx, ref)
+	return m, err
+}
+
+// Resolve returns the reference pinned by the digest of the manifest it refers to, the reference is returned as it
+// is if it's already pinned
+func (c *Client) Resolve(ctx context.Context, ref Reference) (Reference, error) {
+	if ref.Pinned() {
+		return ref, nil
+	}
+	_, digest, err := c.pullManifest(ctx, ref)
+	if err != nil {
+		return ref, err
+	}
+	ref.Digest = digest
+	return ref, nil
+}
+
+func (c *Client) pullManifest(ctx context.Context, ref Reference) (*Manifest, string, error) {
+	version := ref.Digest
+	if len(version) == 0 {
+		version = ref.Tag
+	}
+	data, err := c.get(ctx, ref, "manifests/"+version, manifestAccept, 4<<20)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "cannot pull manifest of %s", ref)
+	}
+	if len(ref.Digest) != 0 {
+		if err := verify(data, ref.Digest); err != nil {
+			return nil, "", errors.WithMessagef(err, "manifest of %s", ref)
+		}
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, "", errors.Wrapf(err, "cannot decode manifest of %s", ref)
+	}
+	return m, digestOf(data), nil
+}
+
+// PullLayer pulls the content of the first layer of the artifact whose media type is one of the given ones, or the
+// first layer if no media type is given. The content is verified against the digest of the layer.
+func (c *Client) PullLayer(ctx context.Context, ref Reference, mediaTypes ...string) ([]byte, error) {
+	m, err := c.PullManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := selectLayer(m, mediaTypes)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "artifact %s", ref)
+	}
+	if layer.Size > c.maxSize() {
+		return nil, fmt.Errorf("layer %s of %s exceeds the size limit of %d bytes", layer.Digest, ref, c.maxSize())
+	}
+	data, err := c.get(ctx, ref, "blobs/"+layer.Digest, "", c.maxSize())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot pull layer %s of %s", layer.Digest, ref)
+	}
+	if err := verify(data, layer.Digest); err != nil {
+		return nil, errors.WithMessagef(err, "layer of %s", ref)
+	}
+	return data, nil
+}
+
+// PushArtifact pushes the artifact made of the config and the layers, and tags it with the tag of the reference. The
+// blobs already in the repository are not uploaded again. It returns the digest of the manifest pushed.
+func (c *Client) PushArtifact(ctx context.Context, ref Reference, config Blob, layers ...Blob) (string, error) {
+	if len(ref.Tag) == 0 {
+		return "", fmt.Errorf("reference %s has no tag to push", ref)
+	}
+	m := Manifest{SchemaVersion: 2, MediaType: ManifestMediaType}
+	var err error
+	if m.Config, err = c.pushBlob(ctx, ref, config); err != nil {
+		return "", errors.WithMessagef(err, "cannot push config of %s", ref)
+	}
+	for _, l := range layers {
+		d, err := c.pushBlob(ctx, ref, l)
+		if err != nil {
+			return "", errors.WithMessagef(err, "cannot push layer of %s", ref)
+		}
+		m.Layers = append(m.Layers, d)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": []string{ManifestMediaType}}
+	resp, err := c.do(ctx, ref, http.MethodPut, c.endpoint(ref, "manifests/"+ref.Tag), header, data)
+	if err != nil {
+		return "", errors.WithMessagef(err, "cannot push manifest of %s", ref)
+	}
+	//nolint:errcheck
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot push manifest of %s, unexpected status %s", ref, resp.Status)
+	}
+	return digestOf(data), nil
+}
+
+// pushBlob uploads the blob in a single request unless it already exists in the repository
+func (c *Client) pushBlob(ctx context.Context, ref Reference, b Blob) (Descriptor, error) {
+	d := Descriptor{MediaType: b.MediaType, Digest: digestOf(b.Data), Size: int64(len(b.Data))}
+	resp, err := c.do(ctx, ref, http.MethodHead, c.endpoint(ref, "blobs/"+d.Digest), nil, nil)
+	if err != nil {
+		return d, err
+	}
+	//nolint:errcheck
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return d, nil
+	}
+	uploads := c.endpoint(ref, "blobs/uploads/")
+	if resp, err = c.do(ctx, ref, http.MethodPost, uploads, nil, nil); err != nil {
+		return d, err
+	}
+	//nolint:errcheck
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return d, fmt.Errorf("cannot start the upload of blob %s, unexpected status %s", d.Digest, resp.Status)
+	}
+	// the location can be relative to the endpoint
+	base, err := url.Parse(uploads)
+	if err != nil {
+		return d, err
+	}
+	location, err := base.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return d, errors.Wrapf(err, "invalid upload location %q", resp.Header.Get("Location"))
+	}
+	q := location.Query()
+	q.Set("digest", d.Digest)
+	location.RawQuery = q.Encode()
+	header := http.Header{"Content-Type": []string{"application/octet-stream"}}
+	if resp, err = c.do(ctx, ref, http.MethodPut, location.String(), header, b.Data); err != nil {
+		return d, err
+	}
+	//nolint:errcheck
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return d, fmt.Errorf("cannot upload blob %s, unexpected status %s", d.Digest, resp.Status)
+	}
+	return d, nil
+}
+
+// ListTags lists the tags of the repository of the reference, following the pagination of the registry up to
+// MaxTagPages pages
+func (c *Client) ListTags(ctx context.Context, ref Reference) ([]string, error) {
+	var tags []string
+	u := c.endpoint(ref, "tags/list?n=1000")
+	for page := 0; len(u) != 0; page++ {
+		if page == MaxTagPages {
+			return nil, fmt.Errorf("repository %s has more than %d pages of tags", ref.Repository, MaxTagPages)
+		}
+		data, header, err := c.fetch(ctx, ref, u, "application/json", 4<<20)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot list tags of %s/%s", ref.Registry, ref.Repository)
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode tags of %s/%s", ref.Registry, ref.Repository)
+		}
+		tags = append(tags, list.Tags...)
+		if u, err = nextPage(u, header.Get("Link")); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// nextPage returns the URL of the next page in the Link header, e.g., </v2/org/app/tags/list?last=v1&n=1000>; rel="next",
+// or an empty string if it's the last page
+func nextPage(current, link string) (string, error) {
+	if !strings.Contains(link, `rel="next"`) {
+		return "", nil
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid Link header %q", link)
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := base.Parse(link[start+1 : end])
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid Link header %q", link)
+	}
+	return next.String(), nil
+}
+
+// ImageCreated returns the creation time in the config of the image
+func (c *Client) ImageCreated(ctx context.Context, ref Reference) (time.Time, error) {
+	m, err := c.PullManifest(ctx, ref)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, err := c.get(ctx, ref, "blobs/"+m.Config.Digest, "", 4<<20)
+	if err != nil {
+		return time.Time{}, errors.WithMessagef(err, "cannot pull config of %s", ref)
+	}
+	if err := verify(data, m.Config.Digest); err != nil {
+		return time.Time{}, errors.WithMessagef(err, "config of %s", ref)
+	}
+	var config struct {
+		Created *time.Time `json:"created"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return time.Time{}, errors.Wrapf(err, "cannot decode config of %s", ref)
+	}
+	if config.Created == nil {
+		return time.Time{}, fmt.Errorf("config of %s has no creation time", ref)
+	}
+	return *config.Created, nil
+}
+
+func selectLayer(m *Manifest, mediaTypes []string) (Descriptor, error) {
+	for _, l := range m.Layers {
+		if len(mediaTypes) == 0 {
+			return l, nil
+		}
+		for _, t := range mediaTypes {
+			if l.MediaType == t {
+				return l, nil
+			}
+		}
+	}
+	if len(mediaTypes) == 0 {
+		return Descriptor{}, errors.New("no layer found")
+	}
+	return Descriptor{}, fmt.Errorf("no layer of media type %s found", strings.Join(mediaTypes, ", "))
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+func verify(data []byte, digest string) error {
+	if !strings.HasPrefix(digest, digestPrefix) {
+		return fmt.Errorf("unsupported digest %q, only sha256 is supported", digest)
+	}
+	if actual := digestOf(data); actual != digest {
+		return fmt.Errorf("digest mismatch, expected %s but got %s", digest, actual)
+	}
+	return nil
+}
+
+func (c *Client) maxSize() int64 {
+	if c.MaxSize > 0 {
+		return c.MaxSize
+	}
+	return DefaultMaxSize
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) endpoint(ref Reference, p string) string {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	host := ref.Registry
+	if host == DefaultRegistry {
+		host = dockerHubHost
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, ref.Repository, p)
+}
+
+// get sends a GET request to the registry API, it authenticates and retries once if the registry challenges
+func (c *Client) get(ctx context.Context, ref Reference, p, accept string, limit int64) ([]byte, error) {
+	data, _, err := c.fetch(ctx, ref, c.endpoint(ref, p), accept, limit)
+	return data, err
+}
+
+// fetch sends a GET request to the URL and returns the response body together with the headers
+func (c *Client) fetch(ctx context.Context, ref Reference, u, accept string, limit int64) ([]byte, http.Header, error) {
+	var header http.Header
+	if len(accept) != 0 {
+		header = http.Header{"Accept": []string{accept}}
+	}
+	resp, err := c.do(ctx, ref, http.MethodGet, u, header, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// read one more byte to detect the content exceeding the limit
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, nil, fmt.Errorf("content exceeds the size limit of %d bytes", limit)
+	}
+	return data, resp.Header, nil
+}
+
+// do sends the request to the registry, it authenticates and retries once if the registry challenges
+func (c *Client) do(ctx context.Context, ref Reference, method, u string, header http.Header, body []byte) (*http.Response, error) {
+	resp, err := c.send(ctx, method, u, header, body, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	//nolint:errcheck
+	resp.Body.Close()
+	authorization, err := c.authorize(ctx, challenge, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, method, u, header, body, authorization)
+}
+
+func (c *Client) send(ctx context.Context, method, u string, header http.Header, body []byte, authorization string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if len(authorization) != 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient().Do(req)
+}
+
+// authorize answers the challenge of the registry with the value of the Authorization header, the bearer token is
+// requested from the token service with the credentials, or anonymously if they're not set
+func (c *Client) authorize(ctx context.Context, challenge string, ref Reference) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if len(c.Username) == 0 {
+			return "", errors.New("the registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)), nil
+	case "bearer":
+		token, err := c.requestToken(ctx, params, ref)
+		if err != nil {
+			return "", errors.WithMessage(err, "cannot request the bearer token")
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+}
+
+func (c *Client) requestToken(ctx context.Context, params map[string]string, ref Reference) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(realm.Host) == 0 {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+	q := realm.Query()
+	if service := params["service"]; len(service) != 0 {
+		q.Set("service", service)
+	}
+	scope := params["scope"]
+	if len(scope) == 0 {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(c.Username) != 0 {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", errors.Wrap(err, "cannot decode the token response")
+	}
+	if len(body.Token) != 0 {
+		return body.Token, nil
+	}
+	if len(body.AccessToken) != 0 {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("no token in the response")
+}
+
+// parseChallenge parses the WWW-Authenticate header, e.g., Bearer realm="https://ghcr.io/token",service="ghcr.io"
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	challenge = strings.TrimSpace(challenge)
+	i := strings.Index(challenge, " ")
+	if i < 0 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:i], challenge[i+1:]
+	for len(rest) != 0 {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
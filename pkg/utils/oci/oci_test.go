@@ -0,0 +1,276 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	cases := map[string]struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		"tag": {
+			ref:  "ghcr.io/org/manifests:v1.0.0",
+			want: Reference{Registry: "ghcr.io", Repository: "org/manifests", Tag: "v1.0.0"},
+		},
+		"digest with scheme": {
+			ref:  "oci://ghcr.io/org/manifests@" + digest,
+			want: Reference{Registry: "ghcr.io", Repository: "org/manifests", Digest: digest},
+		},
+		"registry with port": {
+			ref:  "localhost:5000/charts/nginx",
+			want: Reference{Registry: "localhost:5000", Repository: "charts/nginx", Tag: "latest"},
+		},
+		"docker hub": {
+			ref:  "nginx:1.20",
+			want: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.20"},
+		},
+		"invalid digest": {
+			ref:     "ghcr.io/org/manifests@md5:abc",
+			wantErr: true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseReference(c.ref)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/app:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:org/app:pull",
+	}, params)
+}
+
+func TestPullLayer(t *testing.T) {
+	layer := []byte("layer content")
+	sum := sha256.Sum256(layer)
+	layerDigest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest, err := json.Marshal(Manifest{Layers: []Descriptor{
+		{MediaType: "application/vnd.oci.image.config.v1+json", Digest: "sha256:" + strings.Repeat("0", 64), Size: 2},
+		{MediaType: "application/vnd.cncf.helm.chart.content.v1.tar+gzip", Digest: layerDigest, Size: int64(len(layer))},
+	}})
+	require.NoError(t, err)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			assert.Equal(t, "repository:org/chart:pull", r.URL.Query().Get("scope"))
+			user, pass, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user:pass", user+":"+pass)
+			fmt.Fprint(w, `{"token":"t0k3n"}`)
+		case r.Header.Get("Authorization") != "Bearer t0k3n":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/org/chart/manifests/1.0.0":
+			_, _ = w.Write(manifest)
+		case r.URL.Path == "/v2/org/chart/blobs/"+layerDigest:
+			_, _ = w.Write(layer)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/org/chart:1.0.0")
+	require.NoError(t, err)
+	c := &Client{Username: "user", Password: "pass", PlainHTTP: true}
+	data, err := c.PullLayer(context.Background(), ref, "application/vnd.cncf.helm.chart.content.v1.tar+gzip")
+	require.NoError(t, err)
+	assert.Equal(t, layer, data)
+
+	_, err = c.PullLayer(context.Background(), ref, "application/x-unknown")
+	assert.Error(t, err)
+
+	c.MaxSize = 4
+	_, err = c.PullLayer(context.Background(), ref, "application/vnd.cncf.helm.chart.content.v1.tar+gzip")
+	assert.Error(t, err)
+}
+
+func TestCredentialsFromSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds"},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	}
+	cred, err := CredentialsFromSecret(secret, "ghcr.io")
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "user", Password: "pass"}, cred)
+
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret"))
+	secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://harbor.example.com":{"auth":"` + auth + `"},"ghcr.io":{"username":"gh","password":"pat"}}}`)}
+	cred, err = CredentialsFromSecret(secret, "harbor.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "robot", Password: "s3cret"}, cred)
+	cred, err = CredentialsFromSecret(secret, "ghcr.io")
+	assert.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "gh", Password: "pat"}, cred)
+
+	_, err = CredentialsFromSecret(secret, "quay.io")
+	assert.Error(t, err)
+	_, err = CredentialsFromSecret(&corev1.Secret{}, "ghcr.io")
+	assert.Error(t, err)
+}
+
+func TestListTags(t *testing.T) {
+	config := []byte(`{"created":"2021-09-01T10:00:00Z"}`)
+	sum := sha256.Sum256(config)
+	configDigest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest, err := json.Marshal(Manifest{Config: Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: configDigest, Size: int64(len(config))}})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/org/app/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/org/app/tags/list?last=1.1.0&n=1000>; rel="next"`)
+			fmt.Fprint(w, `{"name":"org/app","tags":["1.0.0","1.1.0"]}`)
+		case r.URL.Path == "/v2/org/app/tags/list" && r.URL.Query().Get("last") == "1.1.0":
+			fmt.Fprint(w, `{"name":"org/app","tags":["2.0.0"]}`)
+		case r.URL.Path == "/v2/org/app/manifests/2.0.0":
+			_, _ = w.Write(manifest)
+		case r.URL.Path == "/v2/org/app/blobs/"+configDigest:
+			_, _ = w.Write(config)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/org/app")
+	require.NoError(t, err)
+	c := &Client{PlainHTTP: true}
+	tags, err := c.ListTags(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "2.0.0"}, tags)
+
+	ref.Tag = "2.0.0"
+	created, err := c.ImageCreated(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-09-01T10:00:00Z", created.UTC().Format(time.RFC3339))
+
+	defer func(max int) { MaxTagPages = max }(MaxTagPages)
+	MaxTagPages = 1
+	_, err = c.ListTags(context.Background(), ref)
+	assert.Error(t, err)
+}
+
+func TestPushArtifact(t *testing.T) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/org/bundle/blobs/"):
+			if _, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/org/bundle/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/org/bundle/blobs/uploads/":
+			w.Header().Set("Location", "/v2/org/bundle/blobs/uploads/session?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/org/bundle/blobs/uploads/session":
+			assert.Equal(t, "abc", r.URL.Query().Get("state"))
+			data, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, verify(data, r.URL.Query().Get("digest")))
+			blobs[r.URL.Query().Get("digest")] = data
+			uploads++
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/org/bundle/manifests/"):
+			assert.Equal(t, ManifestMediaType, r.Header.Get("Content-Type"))
+			data, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			manifests[strings.TrimPrefix(r.URL.Path, "/v2/org/bundle/manifests/")] = data
+			manifests[digestOf(data)] = data
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/org/bundle/manifests/"):
+			data, ok := manifests[strings.TrimPrefix(r.URL.Path, "/v2/org/bundle/manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/org/bundle/blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/org/bundle/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/org/bundle:1.0.0")
+	require.NoError(t, err)
+	c := &Client{PlainHTTP: true}
+	config := Blob{MediaType: "application/vnd.test.config.v1+json", Data: []byte(`{}`)}
+	layer := Blob{MediaType: "application/vnd.test.content.v1.tar+gzip", Data: []byte("content")}
+	digest, err := c.PushArtifact(context.Background(), ref, config, layer)
+	require.NoError(t, err)
+	assert.Equal(t, 2, uploads)
+
+	pull := func(ref Reference) []byte {
+		data, err := c.PullLayer(context.Background(), ref, layer.MediaType)
+		require.NoError(t, err)
+		return data
+	}
+	assert.Equal(t, layer.Data, pull(ref))
+	pinned, err := c.Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, pinned.Digest)
+	assert.Equal(t, layer.Data, pull(Reference{Registry: ref.Registry, Repository: ref.Repository, Digest: digest}))
+
+	next := ref
+	next.Tag = "1.0.1"
+	_, err = c.PushArtifact(context.Background(), next, config, layer)
+	require.NoError(t, err)
+	assert.Equal(t, 2, uploads, "existing blobs should not be uploaded again")
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recorder records the Kubernetes events of the controllers with deduplication and rate limiting, so an
+// object failing in every reconciliation doesn't flood the API servers and the event list with the same events.
+package recorder
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DefaultDedupInterval is the default interval the same event of an object is recorded at most once in
+	DefaultDedupInterval = 10 * time.Minute
+
+	// objectEventBurst and objectEventQPS limit the rate of the distinct events of an object
+	objectEventBurst = 20
+	objectEventQPS   = 0.1
+)
+
+// A DedupRecorder records the events by the wrapped recorder. The same event of an object, i.e., of the same type,
+// reason and message, is recorded at most once per interval, and the distinct events of an object are rate limited.
+type DedupRecorder struct {
+	event.Recorder
+	*dedup
+}
+
+type eventKey struct {
+	object  string
+	typ     event.Type
+	reason  event.Reason
+	message string
+}
+
+// bucket is the token bucket limiting the rate of the events of an object
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type dedup struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	recorded  map[eventKey]time.Time
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewDedupRecorder returns a DedupRecorder wrapping the recorder, the events are passed through if the interval is
+// not positive
+func NewDedupRecorder(r event.Recorder, interval time.Duration) *DedupRecorder {
+	return &DedupRecorder{Recorder: r, dedup: &dedup{
+		interval: interval,
+		now:      time.Now,
+		recorded: map[eventKey]time.Time{},
+		buckets:  map[string]*bucket{},
+	}}
+}
+
+// Event records the event of the object unless it's recorded within the interval or the object records too many
+func (r *DedupRecorder) Event(obj runtime.Object, e event.Event) {
+	if r.allow(obj, e) {
+		r.Recorder.Event(obj, e)
+	}
+}
+
+// WithAnnotations returns a DedupRecorder recording the events with the annotations, it shares the deduplication
+// and the rate limits with the recorder
+func (r *DedupRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return &DedupRecorder{Recorder: r.Recorder.WithAnnotations(keysAndValues...), dedup: r.dedup}
+}
+
+func (d *dedup) allow(obj runtime.Object, e event.Event) bool {
+	if d.interval <= 0 {
+		return true
+	}
+	object := objectKey(obj)
+	key := eventKey{object: object, typ: e.Type, reason: e.Reason, message: e.Message}
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep(now)
+	if last, ok := d.recorded[key]; ok && now.Sub(last) < d.interval {
+		return false
+	}
+	b, ok := d.buckets[object]
+	if !ok {
+		b = &bucket{tokens: objectEventBurst, last: now}
+		d.buckets[object] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * objectEventQPS
+	if b.tokens > objectEventBurst {
+		b.tokens = objectEventBurst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	d.recorded[key] = now
+	return true
+}
+
+// sweep forgets the events recorded before the interval and the objects whose buckets are full again
+func (d *dedup) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.interval {
+		return
+	}
+	d.lastSweep = now
+	for key, last := range d.recorded {
+		if now.Sub(last) >= d.interval {
+			delete(d.recorded, key)
+		}
+	}
+	refill := time.Duration(objectEventBurst / objectEventQPS * float64(time.Second))
+	for object, b := range d.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(d.buckets, object)
+		}
+	}
+}
+
+func objectKey(obj runtime.Object) string {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	if uid := m.GetUID(); len(uid) != 0 {
+		return string(uid)
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetObjectKind().GroupVersionKind().Kind, m.GetNamespace(), m.GetName())
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recorder
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakeRecorder struct {
+	events *[]event.Event
+}
+
+func (r fakeRecorder) Event(_ runtime.Object, e event.Event) { *r.events = append(*r.events, e) }
+
+func (r fakeRecorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func newRecorder(interval time.Duration) (*DedupRecorder, *[]event.Event, *time.Time) {
+	var events []event.Event
+	now := time.Now()
+	r := NewDedupRecorder(fakeRecorder{events: &events}, interval)
+	r.now = func() time.Time { return now }
+	return r, &events, &now
+}
+
+func TestDedup(t *testing.T) {
+	r, events, now := newRecorder(time.Minute)
+	app := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "uid-1"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", UID: "uid-2"}}
+
+	r.Event(app, event.Warning("FailedRender", errors.New("boom")))
+	r.Event(app, event.Warning("FailedRender", errors.New("boom")))
+	r.WithAnnotations("k", "v").Event(app, event.Warning("FailedRender", errors.New("boom")))
+	assert.Len(t, *events, 1, "the same event is recorded once")
+
+	r.Event(app, event.Warning("FailedRender", errors.New("another")))
+	r.Event(other, event.Warning("FailedRender", errors.New("boom")))
+	assert.Len(t, *events, 3, "the distinct events are recorded")
+
+	*now = now.Add(time.Minute)
+	r.Event(app, event.Warning("FailedRender", errors.New("boom")))
+	assert.Len(t, *events, 4, "the same event is recorded again after the interval")
+}
+
+func TestRateLimit(t *testing.T) {
+	r, events, now := newRecorder(time.Minute)
+	app := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "uid-1"}}
+	for i := 0; i < objectEventBurst+10; i++ {
+		r.Event(app, event.Normal("Rendered", fmt.Sprintf("event %d", i)))
+	}
+	assert.Len(t, *events, objectEventBurst)
+
+	*now = now.Add(20 * time.Second)
+	r.Event(app, event.Normal("Rendered", "event after refill"))
+	r.Event(app, event.Normal("Rendered", "event after refill again"))
+	assert.Len(t, *events, objectEventBurst+2)
+	r.Event(app, event.Normal("Rendered", "event limited"))
+	assert.Len(t, *events, objectEventBurst+2)
+}
+
+func TestPassThrough(t *testing.T) {
+	r, events, _ := newRecorder(0)
+	app := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	for i := 0; i < objectEventBurst+10; i++ {
+		r.Event(app, event.Normal("Rendered", "same"))
+	}
+	assert.Len(t, *events, objectEventBurst+10)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package requeue computes the intervals the controllers requeue the objects they reconcile after. The objects
+// still progressing are checked again shortly, the ones staying stable are checked less and less often, and the
+// intervals are jittered so the objects reconciled together don't hit the API servers at the same time again.
+package requeue
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultJitter is the default maximum fraction of the intervals added at random
+const DefaultJitter = 0.1
+
+// Intervals are the requeue intervals of a controller
+type Intervals struct {
+	// Active is the interval to check again the objects still progressing, e.g., the applications executing their
+	// workflows or not healthy yet
+	Active time.Duration
+	// Stable is the maximum interval to check again the objects staying stable, e.g., the healthy applications. The
+	// interval grows from Active to Stable the longer the object stays stable, the stable objects aren't requeued
+	// if it's zero.
+	Stable time.Duration
+	// Jitter is the maximum fraction of the intervals added at random, the intervals aren't jittered if it's zero
+	Jitter float64
+}
+
+// Validate validates the intervals
+func (i Intervals) Validate() error {
+	if i.Active <= 0 {
+		return errors.Errorf("the requeue interval must be positive, got %s", i.Active)
+	}
+	if i.Stable != 0 && i.Stable < i.Active {
+		return errors.Errorf("the stable requeue interval %s must not be less than the requeue interval %s", i.Stable, i.Active)
+	}
+	if i.Jitter < 0 || i.Jitter > 1 {
+		return errors.Errorf("the requeue jitter must be between 0 and 1, got %v", i.Jitter)
+	}
+	return nil
+}
+
+// Jittered adds a random duration up to Jitter of the duration to it
+func (i Intervals) Jittered(d time.Duration) time.Duration {
+	if i.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	return wait.Jitter(d, i.Jitter)
+}
+
+// After returns the jittered interval to check again an object still progressing
+func (i Intervals) After() time.Duration {
+	return i.Jittered(i.Active)
+}
+
+// Result returns the result requeueing an object still progressing
+func (i Intervals) Result() reconcile.Result {
+	return reconcile.Result{RequeueAfter: i.After()}
+}
+
+// StableAfter returns the jittered interval to check again an object stable since the time. The interval is as long
+// as the object has been stable, bounded by Active and Stable, so it doubles every time the object is found still
+// stable. It returns zero if the stable objects aren't requeued.
+func (i Intervals) StableAfter(since time.Time) time.Duration {
+	if i.Stable <= 0 {
+		return 0
+	}
+	d := time.Since(since)
+	if d < i.Active {
+		d = i.Active
+	}
+	if d > i.Stable {
+		d = i.Stable
+	}
+	return i.Jittered(d)
+}
+
+// Earliest returns the shortest of the non-zero durations, it returns zero if all are zero
+func Earliest(durations ...time.Duration) time.Duration {
+	var earliest time.Duration
+	for _, d := range durations {
+		if d > 0 && (earliest == 0 || d < earliest) {
+			earliest = d
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package requeue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Intervals{Active: 10 * time.Second}.Validate())
+	assert.NoError(t, Intervals{Active: 10 * time.Second, Stable: 5 * time.Minute, Jitter: DefaultJitter}.Validate())
+	assert.Error(t, Intervals{}.Validate())
+	assert.Error(t, Intervals{Active: time.Minute, Stable: time.Second}.Validate())
+	assert.Error(t, Intervals{Active: time.Minute, Jitter: 2}.Validate())
+}
+
+func TestJittered(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Intervals{}.Jittered(30*time.Second))
+	i := Intervals{Jitter: 0.5}
+	for n := 0; n < 100; n++ {
+		d := i.Jittered(30 * time.Second)
+		assert.True(t, d >= 30*time.Second && d <= 45*time.Second, d.String())
+	}
+	assert.Equal(t, time.Duration(0), i.Jittered(0))
+}
+
+func TestStableAfter(t *testing.T) {
+	i := Intervals{Active: 10 * time.Second, Stable: 5 * time.Minute}
+	now := time.Now()
+	assert.Equal(t, 10*time.Second, i.StableAfter(now))
+	d := i.StableAfter(now.Add(-time.Minute))
+	assert.True(t, d >= time.Minute && d < time.Minute+time.Second, d.String())
+	assert.Equal(t, 5*time.Minute, i.StableAfter(now.Add(-time.Hour)))
+	assert.Equal(t, time.Duration(0), Intervals{Active: 10 * time.Second}.StableAfter(now))
+}
+
+func TestEarliest(t *testing.T) {
+	assert.Equal(t, time.Duration(0), Earliest())
+	assert.Equal(t, time.Duration(0), Earliest(0, 0))
+	assert.Equal(t, 5*time.Second, Earliest(0, 10*time.Second, 5*time.Second))
+}
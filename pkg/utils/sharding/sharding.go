@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits the applications among the replicas of the controller, so the reconcile throughput
+// scales horizontally. Each replica is a shard reconciling the applications labeled with its shard id, the master
+// shard schedules the applications to the shards by the hash of their namespaces and names.
+package sharding
+
+import (
+	"hash/fnv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// MasterShardID is the id of the master shard, it runs the scheduler, the webhooks and the controllers not sharded,
+// e.g., the ones of the definitions
+const MasterShardID = "master"
+
+// Options are the options of the sharding of a controller replica
+type Options struct {
+	// Enabled indicates whether the controller is sharded, a replica reconciles all the applications otherwise
+	Enabled bool
+	// ShardID is the id of the shard of the replica
+	ShardID string
+	// SchedulableShards are the shards the applications are scheduled to, they're scheduled to the master shard if
+	// it's empty
+	SchedulableShards []string
+}
+
+// ParseShards parses the shards separated by commas
+func ParseShards(s string) []string {
+	var shards []string
+	for _, shard := range strings.Split(s, ",") {
+		if shard = strings.TrimSpace(shard); len(shard) != 0 {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// IsMaster returns whether the replica is the master shard, every replica is the master if sharding is disabled
+func (o Options) IsMaster() bool {
+	return !o.Enabled || o.ShardID == MasterShardID
+}
+
+// Owns returns whether the object is reconciled by the shard of the replica
+func (o Options) Owns(obj metav1.Object) bool {
+	return !o.Enabled || obj.GetLabels()[oam.LabelShardID] == o.ShardID
+}
+
+// Predicate filters the events of the objects not reconciled by the shard of the replica
+func (o Options) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return o.Owns(meta)
+	})
+}
+
+// Schedulable returns whether the shard is one the applications are scheduled to
+func (o Options) Schedulable(shard string) bool {
+	if len(o.SchedulableShards) == 0 {
+		return shard == MasterShardID
+	}
+	for _, s := range o.SchedulableShards {
+		if s == shard {
+			return true
+		}
+	}
+	return false
+}
+
+// ShardOf returns the shard the object is scheduled to by the hash of its namespace and name, the same object is
+// always scheduled to the same shard as long as the schedulable shards are unchanged
+func (o Options) ShardOf(obj metav1.Object) string {
+	if len(o.SchedulableShards) == 0 {
+		return MasterShardID
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(obj.GetNamespace() + "/" + obj.GetName()))
+	return o.SchedulableShards[h.Sum32()%uint32(len(o.SchedulableShards))]
+}
+
+// Schedule labels the object with the shard it's scheduled to if it's not scheduled to a schedulable shard yet,
+// the objects pinned to a schedulable shard by the label are kept there. It returns whether the label is changed.
+func (o Options) Schedule(obj metav1.Object) bool {
+	if o.Schedulable(obj.GetLabels()[oam.LabelShardID]) {
+		return false
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[oam.LabelShardID] = o.ShardOf(obj)
+	obj.SetLabels(labels)
+	return true
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newApp(name string, labels map[string]string) *v1beta1.Application {
+	return &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+}
+
+func TestParseShards(t *testing.T) {
+	assert.Equal(t, []string{"shard-0", "shard-1"}, ParseShards(" shard-0, ,shard-1"))
+	assert.Nil(t, ParseShards(""))
+}
+
+func TestOwns(t *testing.T) {
+	app := newApp("app", map[string]string{oam.LabelShardID: "shard-0"})
+	assert.True(t, Options{}.Owns(app))
+	assert.True(t, Options{Enabled: true, ShardID: "shard-0"}.Owns(app))
+	assert.False(t, Options{Enabled: true, ShardID: "shard-1"}.Owns(app))
+	assert.False(t, Options{Enabled: true, ShardID: MasterShardID}.Owns(newApp("app", nil)))
+	assert.True(t, Options{}.IsMaster())
+	assert.False(t, Options{Enabled: true, ShardID: "shard-0"}.IsMaster())
+}
+
+func TestSchedule(t *testing.T) {
+	opts := Options{Enabled: true, ShardID: MasterShardID, SchedulableShards: []string{"shard-0", "shard-1", "shard-2"}}
+
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		app := newApp(fmt.Sprintf("app-%d", i), nil)
+		assert.True(t, opts.Schedule(app))
+		shard := app.Labels[oam.LabelShardID]
+		assert.Equal(t, opts.ShardOf(app), shard)
+		counts[shard]++
+		// the scheduled apps are stable
+		assert.False(t, opts.Schedule(app))
+	}
+	assert.Len(t, counts, 3)
+	for shard, count := range counts {
+		assert.Greater(t, count, 50, "shard %s is unbalanced", shard)
+	}
+
+	pinned := newApp("pinned", map[string]string{oam.LabelShardID: "shard-2", "team": "a"})
+	assert.False(t, opts.Schedule(pinned))
+	assert.Equal(t, "shard-2", pinned.Labels[oam.LabelShardID])
+
+	// the apps of the shards removed are rescheduled
+	removed := newApp("removed", map[string]string{oam.LabelShardID: "shard-9"})
+	assert.True(t, opts.Schedule(removed))
+	assert.Contains(t, opts.SchedulableShards, removed.Labels[oam.LabelShardID])
+
+	// the apps are scheduled to the master shard without schedulable shards
+	app := newApp("app", nil)
+	assert.True(t, Options{Enabled: true, ShardID: MasterShardID}.Schedule(app))
+	assert.Equal(t, MasterShardID, app.Labels[oam.LabelShardID])
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	// TenantKeySecretPrefix prefixes the namespace of a tenant to name the secret holding its signing key, which
+	// overrides the controller key for the applications in the namespace. The secret is only looked up in the
+	// namespace of the controller key, so a tenant cannot sign the revisions by a key of its own.
+	TenantKeySecretPrefix = "vela-manifest-signing-key-"
+	// KeySecretDataKey is the key of the PEM encoded ed25519 private key in the data of a signing key secret
+	KeySecretDataKey = "ed25519.key"
+
+	digestPrefix = "sha256:"
+)
+
+// Record is the audit record of a verified revision, the digest and signature can be verified
+// independently with the public key of the signer
+type Record struct {
+	Digest    string `json:"digest"`
+	Signature string `json:"signature"`
+	Signer    string `json:"signer"`
+	// VerifiedAt is the time the revision is verified before its manifests are applied
+	VerifiedAt metav1.Time `json:"verifiedAt,omitempty"`
+}
+
+// String formats the record as an event message
+func (r *Record) String() string {
+	return fmt.Sprintf("digest=%s signer=%s signature=%s", r.Digest, r.Signer, r.Signature)
+}
+
+// Key is an ed25519 key signing the rendered manifests of application revisions
+type Key struct {
+	// ID identifies the key by the fingerprint of its public key
+	ID      string
+	private ed25519.PrivateKey
+}
+
+// ParseKey parses a PEM encoded PKCS #8 ed25519 private key
+func ParseKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in the signing key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signing key")
+	}
+	private, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("signing key must be an ed25519 key, got %T", parsed)
+	}
+	return NewKey(private), nil
+}
+
+// NewKey creates a Key from an ed25519 private key
+func NewKey(private ed25519.PrivateKey) *Key {
+	fingerprint := sha256.Sum256(private.Public().(ed25519.PublicKey))
+	return &Key{ID: hex.EncodeToString(fingerprint[:8]), private: private}
+}
+
+// Sign records the digest of the rendered manifests and its signature in the annotations of the revision
+func (k *Key) Sign(rev metav1.Object, components []common.RawComponent, ac runtime.RawExtension) error {
+	digest, err := Digest(components, ac)
+	if err != nil {
+		return err
+	}
+	annotations := rev.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationManifestDigest] = digest
+	annotations[oam.AnnotationManifestSignature] = base64.StdEncoding.EncodeToString(ed25519.Sign(k.private, []byte(digest)))
+	annotations[oam.AnnotationManifestSigner] = k.ID
+	rev.SetAnnotations(annotations)
+	return nil
+}
+
+// Verify recomputes the digest of the rendered manifests and checks it against the signature in the annotations
+// of the revision, an error is returned if the revision is not signed by this key or the manifests are tampered
+func (k *Key) Verify(rev metav1.Object, components []common.RawComponent, ac runtime.RawExtension) (*Record, error) {
+	annotations := rev.GetAnnotations()
+	record := &Record{
+		Digest:    annotations[oam.AnnotationManifestDigest],
+		Signature: annotations[oam.AnnotationManifestSignature],
+		Signer:    annotations[oam.AnnotationManifestSigner],
+	}
+	if len(record.Digest) == 0 || len(record.Signature) == 0 {
+		return nil, errors.Errorf("revision %s is not signed", rev.GetName())
+	}
+	if record.Signer != k.ID {
+		return nil, errors.Errorf("revision %s is signed by unknown key %q, want %q", rev.GetName(), record.Signer, k.ID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(record.Signature)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid signature of revision %s", rev.GetName())
+	}
+	if !ed25519.Verify(k.private.Public().(ed25519.PublicKey), []byte(record.Digest), sig) {
+		return nil, errors.Errorf("signature of revision %s doesn't match its digest", rev.GetName())
+	}
+	digest, err := Digest(components, ac)
+	if err != nil {
+		return nil, err
+	}
+	if digest != record.Digest {
+		return nil, errors.Errorf("manifests of revision %s are tampered, digest %s doesn't match the signed %s",
+			rev.GetName(), digest, record.Digest)
+	}
+	return record, nil
+}
+
+// RecordVerification records the audit record in the annotations of the revision, so it's kept along with the
+// revision rather than only in an event. It returns false if the digest is already recorded as verified, the
+// time of the first verification is kept then.
+func RecordVerification(rev metav1.Object, record *Record) (bool, error) {
+	annotations := rev.GetAnnotations()
+	existing := &Record{}
+	if v, ok := annotations[oam.AnnotationManifestVerification]; ok && json.Unmarshal([]byte(v), existing) == nil &&
+		existing.Digest == record.Digest && existing.Signer == record.Signer {
+		return false, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationManifestVerification] = string(data)
+	rev.SetAnnotations(annotations)
+	return true, nil
+}
+
+// Digest computes the digest of the rendered manifests of a revision. The manifests are normalized into
+// canonical JSON so the digest doesn't depend on the API version the revision is read with.
+func Digest(components []common.RawComponent, ac runtime.RawExtension) (string, error) {
+	h := sha256.New()
+	for i := range components {
+		b, err := canonicalJSON(components[i].Raw)
+		if err != nil {
+			return "", errors.WithMessagef(err, "fail to normalize component %d", i)
+		}
+		_, _ = h.Write(b)
+		_, _ = h.Write([]byte{'\n'})
+	}
+	b, err := canonicalJSON(ac)
+	if err != nil {
+		return "", errors.WithMessage(err, "fail to normalize application configuration")
+	}
+	_, _ = h.Write(b)
+	return digestPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func canonicalJSON(raw runtime.RawExtension) ([]byte, error) {
+	data := raw.Raw
+	if len(data) == 0 && raw.Object != nil {
+		var err error
+		if data, err = json.Marshal(raw.Object); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return []byte("null"), nil
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	// json.Marshal sorts the keys of maps
+	return json.Marshal(dropNulls(obj))
+}
+
+// dropNulls removes the null fields, e.g., `creationTimestamp: null`, which may be pruned when the
+// manifests are stored by the apiserver
+func dropNulls(obj interface{}) interface{} {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if v == nil {
+				delete(o, k)
+				continue
+			}
+			o[k] = dropNulls(v)
+		}
+	case []interface{}:
+		for i, v := range o {
+			o[i] = dropNulls(v)
+		}
+	}
+	return obj
+}
+
+// KeyStore resolves the key signing the revisions of a namespace
+type KeyStore struct {
+	client        client.Reader
+	controllerKey types.NamespacedName
+}
+
+// NewKeyStore creates a KeyStore with the controller key stored in the secret `namespace/name`,
+// signing is disabled if the secret is not specified.
+func NewKeyStore(c client.Reader, secret string) (*KeyStore, error) {
+	if len(secret) == 0 {
+		return nil, nil
+	}
+	parts := strings.Split(secret, "/")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, errors.Errorf("signing key secret %q must be in the format of namespace/name", secret)
+	}
+	return &KeyStore{client: c, controllerKey: types.NamespacedName{Namespace: parts[0], Name: parts[1]}}, nil
+}
+
+// KeyFor returns the tenant key of the namespace if the operator configures one, otherwise the controller key. Only
+// the secrets in the namespace of the controller key are trusted, never the ones in the namespace of the application.
+func (s *KeyStore) KeyFor(ctx context.Context, namespace string) (*Key, error) {
+	key, err := s.loadKey(ctx, types.NamespacedName{Namespace: s.controllerKey.Namespace, Name: TenantKeySecretPrefix + namespace})
+	if err == nil || !apierrors.IsNotFound(errors.Cause(err)) {
+		return key, err
+	}
+	return s.loadKey(ctx, s.controllerKey)
+}
+
+func (s *KeyStore) loadKey(ctx context.Context, name types.NamespacedName) (*Key, error) {
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, name, secret); err != nil {
+		return nil, errors.Wrapf(err, "fail to get signing key secret %s", name)
+	}
+	data, ok := secret.Data[KeySecretDataKey]
+	if !ok {
+		return nil, errors.Errorf("signing key secret %s has no %s", name, KeySecretDataKey)
+	}
+	key, err := ParseKey(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "signing key secret %s", name)
+	}
+	return key, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func generateKey(t *testing.T) (*Key, []byte) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	assert.NoError(t, err)
+	return NewKey(private), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, _ := generateKey(t)
+	comps := []common.RawComponent{{Raw: runtime.RawExtension{
+		Raw: []byte(`{"kind":"Component","metadata":{"name":"web","creationTimestamp":null}}`)}}}
+	ac := runtime.RawExtension{Raw: []byte(`{"kind":"ApplicationConfiguration","spec":{"components":[]}}`)}
+	rev := &metav1.ObjectMeta{Name: "app-v1", Annotations: map[string]string{"foo": "bar"}}
+
+	assert.NoError(t, key.Sign(rev, comps, ac))
+	assert.Equal(t, key.ID, rev.Annotations[oam.AnnotationManifestSigner])
+	assert.Equal(t, "bar", rev.Annotations["foo"])
+
+	// the digest doesn't depend on the key order, whitespaces and null fields
+	sameComps := []common.RawComponent{{Raw: runtime.RawExtension{
+		Raw: []byte(`{"metadata": {"name": "web"}, "kind": "Component"}`)}}}
+	record, err := key.Verify(rev, sameComps, ac)
+	assert.NoError(t, err)
+	assert.Equal(t, rev.Annotations[oam.AnnotationManifestDigest], record.Digest)
+	assert.Equal(t, key.ID, record.Signer)
+
+	// tampered manifests
+	tampered := runtime.RawExtension{Raw: []byte(`{"kind":"ApplicationConfiguration","spec":{"components":[{}]}}`)}
+	_, err = key.Verify(rev, comps, tampered)
+	assert.Error(t, err)
+
+	// tampered digest
+	forged := rev.DeepCopy()
+	forged.Annotations[oam.AnnotationManifestDigest], _ = Digest(comps, tampered)
+	_, err = key.Verify(forged, comps, tampered)
+	assert.Error(t, err)
+
+	// signed by another key
+	other, _ := generateKey(t)
+	_, err = other.Verify(rev, comps, ac)
+	assert.Error(t, err)
+
+	// not signed
+	_, err = key.Verify(&metav1.ObjectMeta{Name: "app-v2"}, comps, ac)
+	assert.Error(t, err)
+}
+
+func TestKeyStore(t *testing.T) {
+	store, err := NewKeyStore(nil, "")
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+	_, err = NewKeyStore(nil, "vela-system")
+	assert.Error(t, err)
+
+	controllerKey, controllerPEM := generateKey(t)
+	tenantKey, tenantPEM := generateKey(t)
+	c := fake.NewFakeClientWithScheme(scheme.Scheme,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "signing-key", Namespace: "vela-system"},
+			Data: map[string][]byte{KeySecretDataKey: controllerPEM}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: TenantKeySecretPrefix + "tenant", Namespace: "vela-system"},
+			Data: map[string][]byte{KeySecretDataKey: tenantPEM}},
+		// the secrets in the namespaces of the applications are not trusted
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: TenantKeySecretPrefix + "mallory", Namespace: "mallory"},
+			Data: map[string][]byte{KeySecretDataKey: tenantPEM}},
+	)
+	store, err = NewKeyStore(c, "vela-system/signing-key")
+	assert.NoError(t, err)
+
+	key, err := store.KeyFor(context.Background(), "default")
+	assert.NoError(t, err)
+	assert.Equal(t, controllerKey.ID, key.ID)
+
+	key, err = store.KeyFor(context.Background(), "tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, tenantKey.ID, key.ID)
+
+	key, err = store.KeyFor(context.Background(), "mallory")
+	assert.NoError(t, err)
+	assert.Equal(t, controllerKey.ID, key.ID)
+}
+
+func TestRecordVerification(t *testing.T) {
+	rev := &metav1.ObjectMeta{Name: "app-v1"}
+	first := &Record{Digest: "sha256:1", Signature: "sig", Signer: "key", VerifiedAt: metav1.Unix(1, 0)}
+	recorded, err := RecordVerification(rev, first)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+	assert.JSONEq(t, `{"digest":"sha256:1","signature":"sig","signer":"key","verifiedAt":"1970-01-01T00:00:01Z"}`,
+		rev.Annotations[oam.AnnotationManifestVerification])
+
+	// the first verification of the digest is kept
+	recorded, err = RecordVerification(rev, &Record{Digest: "sha256:1", Signature: "sig", Signer: "key", VerifiedAt: metav1.Unix(2, 0)})
+	assert.NoError(t, err)
+	assert.False(t, recorded)
+
+	recorded, err = RecordVerification(rev, &Record{Digest: "sha256:2", Signature: "sig", Signer: "key", VerifiedAt: metav1.Unix(3, 0)})
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package speclimit guards the control plane against pathological application specs by limiting their size and
+// complexity, the limits are configured by flags and overridden per namespace by annotations of the namespace
+package speclimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// DefaultMaxPropertiesSize is the default maximum size of the properties, an application close to it would fail
+// to be stored in etcd together with its revision
+const DefaultMaxPropertiesSize = 1024 * 1024
+
+// Limits are the limits of the size and complexity of an application, zero means unlimited
+type Limits struct {
+	// MaxComponents is the maximum number of components
+	MaxComponents int
+	// MaxTraitsPerComponent is the maximum number of traits of a component
+	MaxTraitsPerComponent int
+	// MaxRenderedObjects is the maximum number of workloads and traits rendered
+	MaxRenderedObjects int
+	// MaxPropertiesSize is the maximum size in bytes of the properties of a component, trait, policy or workflow step
+	MaxPropertiesSize int
+}
+
+// Load returns the limits of the applications in the namespace, i.e., the defaults overridden by the annotations of
+// the namespace
+func Load(ctx context.Context, c client.Reader, namespace string, defaults Limits) (Limits, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return defaults, nil
+		}
+		return defaults, errors.Wrapf(err, "cannot get namespace %s", namespace)
+	}
+	limits, err := defaults.Override(ns.GetAnnotations())
+	if err != nil {
+		return defaults, errors.WithMessagef(err, "invalid limits of namespace %s", namespace)
+	}
+	return limits, nil
+}
+
+// Override returns the limits overridden by the annotations, see oam.AnnotationMaxComponents and the others
+func (l Limits) Override(annotations map[string]string) (Limits, error) {
+	for anno, limit := range map[string]*int{
+		oam.AnnotationMaxComponents:         &l.MaxComponents,
+		oam.AnnotationMaxTraitsPerComponent: &l.MaxTraitsPerComponent,
+		oam.AnnotationMaxRenderedObjects:    &l.MaxRenderedObjects,
+		oam.AnnotationMaxPropertiesSize:     &l.MaxPropertiesSize,
+	} {
+		v, ok := annotations[anno]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return l, errors.Errorf("annotation %s must be a non-negative integer, got %q", anno, v)
+		}
+		*limit = n
+	}
+	return l, nil
+}
+
+// ValidateSpec validates the numbers of components and traits and the sizes of properties of the application
+func (l Limits) ValidateSpec(app *v1beta1.Application) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	if l.MaxComponents > 0 && len(app.Spec.Components) > l.MaxComponents {
+		errs = append(errs, field.TooMany(spec.Child("components"), len(app.Spec.Components), l.MaxComponents))
+	}
+	for i, comp := range app.Spec.Components {
+		path := spec.Child("components").Index(i)
+		if l.MaxTraitsPerComponent > 0 && len(comp.Traits) > l.MaxTraitsPerComponent {
+			errs = append(errs, field.TooMany(path.Child("traits"), len(comp.Traits), l.MaxTraitsPerComponent))
+		}
+		errs = append(errs, l.validateProperties(path.Child("properties"), comp.Properties)...)
+		for j, trait := range comp.Traits {
+			errs = append(errs, l.validateProperties(path.Child("traits").Index(j).Child("properties"), trait.Properties)...)
+		}
+	}
+	for i, policy := range app.Spec.Policies {
+		errs = append(errs, l.validateProperties(spec.Child("policies").Index(i).Child("properties"), policy.Properties)...)
+	}
+	for i, step := range app.Spec.Workflow {
+		path := spec.Child("workflow").Index(i)
+		errs = append(errs, l.validateProperties(path.Child("properties"), step.Properties)...)
+		for j, sub := range step.SubSteps {
+			errs = append(errs, l.validateProperties(path.Child("subSteps").Index(j).Child("properties"), sub.Properties)...)
+		}
+	}
+	return errs
+}
+
+func (l Limits) validateProperties(path *field.Path, properties runtime.RawExtension) field.ErrorList {
+	if l.MaxPropertiesSize <= 0 || len(properties.Raw) <= l.MaxPropertiesSize {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(path, fmt.Sprintf("<%d bytes>", len(properties.Raw)),
+		fmt.Sprintf("must have at most %d bytes", l.MaxPropertiesSize))}
+}
+
+// ValidateRendered validates the number of workloads and traits rendered from the application
+func (l Limits) ValidateRendered(ac *v1alpha2.ApplicationConfiguration) error {
+	if l.MaxRenderedObjects <= 0 {
+		return nil
+	}
+	count := 0
+	for _, comp := range ac.Spec.Components {
+		count += 1 + len(comp.Traits)
+	}
+	if count > l.MaxRenderedObjects {
+		return errors.Errorf("%d workloads and traits are rendered, must have at most %d", count, l.MaxRenderedObjects)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package speclimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	utilcommon "github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestLoad(t *testing.T) {
+	defaults := Limits{MaxComponents: 10, MaxPropertiesSize: DefaultMaxPropertiesSize}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "tenant",
+		Annotations: map[string]string{
+			oam.AnnotationMaxComponents:      "50",
+			oam.AnnotationMaxRenderedObjects: "200",
+		},
+	}}
+	invalid := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "invalid",
+		Annotations: map[string]string{oam.AnnotationMaxTraitsPerComponent: "-1"},
+	}}
+	c := fake.NewFakeClientWithScheme(utilcommon.Scheme, ns, invalid)
+
+	limits, err := Load(context.Background(), c, "tenant", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{MaxComponents: 50, MaxRenderedObjects: 200, MaxPropertiesSize: DefaultMaxPropertiesSize}, limits)
+
+	limits, err = Load(context.Background(), c, "not-found", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, limits)
+
+	_, err = Load(context.Background(), c, "invalid", defaults)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), oam.AnnotationMaxTraitsPerComponent)
+}
+
+func TestValidateSpec(t *testing.T) {
+	props := func(size int) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(`"` + strings.Repeat("a", size-2) + `"`)}
+	}
+	app := &v1beta1.Application{Spec: v1beta1.ApplicationSpec{
+		Components: []v1beta1.ApplicationComponent{{
+			Name:       "web",
+			Properties: props(100),
+			Traits:     []v1beta1.ApplicationTrait{{Type: "scaler"}, {Type: "ingress", Properties: props(200)}},
+		}, {
+			Name: "db",
+		}},
+		Workflow: []v1beta1.WorkflowStep{{
+			Name:     "regions",
+			Type:     "step-group",
+			SubSteps: []v1beta1.WorkflowSubStep{{Name: "east", Type: "deploy", Properties: props(300)}},
+		}},
+	}}
+
+	assert.Empty(t, Limits{}.ValidateSpec(app))
+	assert.Empty(t, Limits{MaxComponents: 2, MaxTraitsPerComponent: 2, MaxPropertiesSize: 300}.ValidateSpec(app))
+
+	errs := Limits{MaxComponents: 1, MaxTraitsPerComponent: 1, MaxPropertiesSize: 150}.ValidateSpec(app)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal(t, []string{
+		"spec.components",
+		"spec.components[0].traits",
+		"spec.components[0].traits[1].properties",
+		"spec.workflow[0].subSteps[0].properties",
+	}, fields)
+}
+
+func TestValidateRendered(t *testing.T) {
+	ac := &v1alpha2.ApplicationConfiguration{Spec: v1alpha2.ApplicationConfigurationSpec{
+		Components: []v1alpha2.ApplicationConfigurationComponent{
+			{ComponentName: "web", Traits: []v1alpha2.ComponentTrait{{}, {}}},
+			{ComponentName: "db"},
+		},
+	}}
+	assert.NoError(t, Limits{}.ValidateRendered(ac))
+	assert.NoError(t, Limits{MaxRenderedObjects: 4}.ValidateRendered(ac))
+	err := Limits{MaxRenderedObjects: 3}.ValidateRendered(ac)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "4 workloads and traits are rendered, must have at most 3")
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports the spans created by the controllers to a Jaeger collector by OpenTelemetry, so the
+// operators can see which phase of the reconciliation slows down large applications.
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// DefaultSampleRatio is the default fraction of the reconciliations traced
+const DefaultSampleRatio = 0.1
+
+// Options are the options of tracing
+type Options struct {
+	// Endpoint is the URL of the Jaeger collector the spans are exported to, e.g.,
+	// http://jaeger-collector.observability:14268/api/traces. Tracing is disabled if it's empty.
+	Endpoint string
+	// SampleRatio is the fraction of the reconciliations traced, from 0 to 1
+	SampleRatio float64
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return errors.Errorf("the sample ratio of tracing must be between 0 and 1, got %v", o.SampleRatio)
+	}
+	return nil
+}
+
+// Setup sets the global tracer provider exporting the spans of the service by the options. The returned function
+// flushes the spans not exported yet and shuts the provider down. Nothing is set up if tracing is disabled.
+func Setup(o Options, service string) (func(context.Context) error, error) {
+	if len(o.Endpoint) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(o.Endpoint)))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create the Jaeger exporter")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(Options{SampleRatio: 2}, "kubevela")
+	assert.NoError(t, err, "tracing is disabled without the endpoint")
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(Options{Endpoint: "http://localhost:14268/api/traces", SampleRatio: 2}, "kubevela")
+	assert.Error(t, err)
+
+	shutdown, err = Setup(Options{Endpoint: "http://localhost:14268/api/traces", SampleRatio: DefaultSampleRatio}, "kubevela")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vault reads the secrets of the KV secrets engine of HashiCorp Vault, they're resolved into the properties
+// of the applications referring to them by valueFrom.vaultPath.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvAddress is the env var of the address of the Vault server, e.g., https://vault.vault-system:8200
+	EnvAddress = "VAULT_ADDR"
+	// EnvToken is the env var of the token authenticating the controller
+	EnvToken = "VAULT_TOKEN"
+	// EnvNamespace is the env var of the Vault Enterprise namespace, it's optional
+	EnvNamespace = "VAULT_NAMESPACE"
+	// EnvPathPrefix is the env var of the root of the paths the applications can read, default to DefaultPathPrefix
+	EnvPathPrefix = "VAULT_PATH_PREFIX"
+
+	// DefaultPathPrefix is the root of the paths the applications can read if EnvPathPrefix is not set, i.e., the
+	// version 2 of the KV secrets engine mounted at secret
+	DefaultPathPrefix = "secret/data"
+)
+
+var (
+	// ReadTimeout is the timeout of reading a secret
+	ReadTimeout = 10 * time.Second
+	// MaxResponseSize is the maximum size in bytes of the response of reading a secret
+	MaxResponseSize int64 = 1 << 20
+)
+
+// segmentPattern matches the segments of the paths of secrets, the other characters would change the request URL
+var segmentPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// DefaultClient is the client configured by the env vars, it's nil if the address of the Vault server is not set
+var DefaultClient = NewClientFromEnv()
+
+// Client reads the secrets from a Vault server by the token. The token is shared by all the applications, so an
+// application can only read the secrets under <PathPrefix>/<namespace of the application>/.
+type Client struct {
+	Address    string
+	Token      string
+	Namespace  string
+	PathPrefix string
+	HTTP       *http.Client
+}
+
+// NewClientFromEnv creates the client configured by the env vars, it returns nil if the address is not set
+func NewClientFromEnv() *Client {
+	addr := os.Getenv(EnvAddress)
+	if len(addr) == 0 {
+		return nil
+	}
+	return &Client{
+		Address:    strings.TrimSuffix(addr, "/"),
+		Token:      os.Getenv(EnvToken),
+		Namespace:  os.Getenv(EnvNamespace),
+		PathPrefix: os.Getenv(EnvPathPrefix),
+		HTTP:       &http.Client{Timeout: ReadTimeout},
+	}
+}
+
+// SplitPath splits the reference of a key of a secret into the path of the secret and the key, e.g.,
+// "secret/data/db#password" is split into "secret/data/db" and "password". The segments of the path can only contain
+// letters, digits, "_", "-" and ".", and cannot be "." or "..".
+func SplitPath(ref string) (string, string, error) {
+	idx := strings.LastIndex(ref, "#")
+	if idx <= 0 || idx == len(ref)-1 {
+		return "", "", errors.Errorf("invalid vault path %q, it must be in the form of <path>#<key>", ref)
+	}
+	path := strings.Trim(ref[:idx], "/")
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "." || seg == ".." || !segmentPattern.MatchString(seg) {
+			return "", "", errors.Errorf("invalid vault path %q, segment %q is not allowed", ref, seg)
+		}
+	}
+	return path, ref[idx+1:], nil
+}
+
+// Root returns the root of the paths the applications in the namespace can read, i.e., <PathPrefix>/<namespace>
+func (c *Client) Root(namespace string) string {
+	prefix := strings.Trim(c.PathPrefix, "/")
+	if len(prefix) == 0 {
+		prefix = DefaultPathPrefix
+	}
+	return prefix + "/" + namespace
+}
+
+// CheckScope checks the path of the secret referred to by an application in the namespace is under the root of the
+// namespace, otherwise the application could read the secrets of the other namespaces by the shared token
+func (c *Client) CheckScope(namespace, ref string) error {
+	path, _, err := SplitPath(ref)
+	if err != nil {
+		return err
+	}
+	if root := c.Root(namespace); !strings.HasPrefix(path, root+"/") {
+		return errors.Errorf("vault path %q is not allowed, the applications in namespace %s can only read the secrets under %s/", ref, namespace, root)
+	}
+	return nil
+}
+
+// Read reads the key/value pairs of the secret at the path, the data of both the version 1 and 2 of the KV secrets
+// engine are supported. The path of the version 2 includes the "data" segment, e.g., secret/data/db.
+func (c *Client) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", c.Address, strings.Join(segments, "/")), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+	if len(c.Namespace) != 0 {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read vault secret %s", path)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read vault secret %s", path)
+	}
+	if int64(len(body)) > MaxResponseSize {
+		return nil, errors.Errorf("vault secret %s exceeds the size limit of %d bytes", path, MaxResponseSize)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Errorf("vault secret %s not found", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot read vault secret %s: %s %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	secret := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, errors.Wrapf(err, "invalid vault secret %s", path)
+	}
+	// the version 2 nests the key/value pairs in the data along with the metadata of the version
+	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, versioned := secret.Data["metadata"]; versioned {
+			return data, nil
+		}
+	}
+	return secret.Data, nil
+}
+
+// ReadKey reads the value of the key of the secret referred to by the path in the form of <path>#<key> for an
+// application in the namespace, the path must be under the root of the namespace
+func (c *Client) ReadKey(ctx context.Context, namespace, ref string) (interface{}, error) {
+	if err := c.CheckScope(namespace, ref); err != nil {
+		return nil, err
+	}
+	path, key, err := SplitPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := data[key]
+	if !ok {
+		return nil, errors.Errorf("key %s not found in vault secret %s", key, path)
+	}
+	return v, nil
+}
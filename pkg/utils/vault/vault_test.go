@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPath(t *testing.T) {
+	path, key, err := SplitPath("/secret/data/db#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret/data/db", path)
+	assert.Equal(t, "password", key)
+	for _, ref := range []string{"secret/data/db", "#password", "secret/data/db#", "secret/data/../db#password",
+		"secret/data/./db#password", "secret/data//db#password", "secret/data/db?version=1#password", "secret/data/d%2Fb#password"} {
+		_, _, err = SplitPath(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	c := &Client{}
+	assert.Equal(t, "secret/data/team-a", c.Root("team-a"))
+	assert.NoError(t, c.CheckScope("team-a", "secret/data/team-a/db#password"))
+	assert.NoError(t, c.CheckScope("team-a", "/secret/data/team-a/nested/db#password"))
+	for _, ref := range []string{"secret/data/team-b/db#password", "secret/data/team-a#password",
+		"secret/data/team-ab/db#password", "secret/data/team-a/../team-b/db#password", "kv/team-a/db#password"} {
+		assert.Error(t, c.CheckScope("team-a", ref), ref)
+	}
+
+	c.PathPrefix = "/kv/"
+	assert.NoError(t, c.CheckScope("team-a", "kv/team-a/db#password"))
+	assert.Error(t, c.CheckScope("team-a", "secret/data/team-a/db#password"))
+}
+
+func TestReadKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Vault-Namespace"))
+		switch r.URL.Path {
+		case "/v1/secret/data/team-a/db":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"v2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/team-a/db":
+			_, _ = w.Write([]byte(`{"data":{"password":"v1","data":"raw"}}`))
+		case "/v1/secret/data/team-a/denied":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := &Client{Address: server.URL, Token: "token", Namespace: "team-a"}
+	ctx := context.Background()
+
+	v, err := c.ReadKey(ctx, "team-a", "secret/data/team-a/db#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", v)
+	// the data of the version 1 is not unwrapped even if it has a key named data
+	v, err = (&Client{Address: server.URL, Token: "token", Namespace: "team-a", PathPrefix: "kv"}).ReadKey(ctx, "team-a", "kv/team-a/db#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	_, err = c.ReadKey(ctx, "team-a", "secret/data/team-a/db#user")
+	assert.EqualError(t, err, "key user not found in vault secret secret/data/team-a/db")
+	_, err = c.ReadKey(ctx, "team-a", "secret/data/team-a/missing#password")
+	assert.EqualError(t, err, "vault secret secret/data/team-a/missing not found")
+	_, err = c.ReadKey(ctx, "team-a", "secret/data/team-a/denied#password")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+	// the secrets of the other namespaces are rejected before requesting the server
+	_, err = c.ReadKey(ctx, "team-b", "secret/data/team-a/db#password")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CACertName is the key of the CA certificate in the secret
+	CACertName = "ca.crt"
+	// ServerCertName is the key of the serving certificate in the secret and the name of its file in the cert dir
+	ServerCertName = "tls.crt"
+	// ServerKeyName is the key of the serving key in the secret and the name of its file in the cert dir
+	ServerKeyName = "tls.key"
+
+	caCommonName = "kubevela-webhook-ca"
+	keySize      = 2048
+)
+
+// KeyPair is the self-signed CA and the serving certificate signed by it, all PEM encoded
+type KeyPair struct {
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+}
+
+// Generate generates a self-signed CA and a serving certificate for the DNS names signed by it, both are valid
+// from notBefore for the validity
+func Generate(dnsNames []string, notBefore time.Time, validity time.Duration) (*KeyPair, error) {
+	if len(dnsNames) == 0 {
+		return nil, errors.New("no DNS name of the webhook service")
+	}
+	notAfter := notBefore.Add(validity)
+	caKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate CA key")
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create CA certificate")
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse CA certificate")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate serving key")
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create serving certificate")
+	}
+	return &KeyPair{
+		CACert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+// Validate checks the key pair serves the DNS names and is valid till refreshBefore ahead of now, it returns the
+// expiry of the serving certificate
+func (kp *KeyPair) Validate(dnsNames []string, now time.Time, refreshBefore time.Duration) (time.Time, error) {
+	ca, err := parseCert(kp.CACert)
+	if err != nil {
+		return time.Time{}, errors.WithMessage(err, "invalid CA certificate")
+	}
+	cert, err := parseCert(kp.Cert)
+	if err != nil {
+		return time.Time{}, errors.WithMessage(err, "invalid serving certificate")
+	}
+	block, _ := pem.Decode(kp.Key)
+	if block == nil {
+		return cert.NotAfter, errors.New("invalid serving key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return cert.NotAfter, errors.Wrap(err, "invalid serving key")
+	}
+	if pub, ok := cert.PublicKey.(*rsa.PublicKey); !ok || pub.N.Cmp(key.N) != 0 {
+		return cert.NotAfter, errors.New("serving key doesn't match the serving certificate")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	for _, name := range dnsNames {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: pool, CurrentTime: now}); err != nil {
+			return cert.NotAfter, errors.Wrapf(err, "serving certificate is invalid for %s", name)
+		}
+	}
+	if deadline := now.Add(refreshBefore); !deadline.Before(cert.NotAfter) || !deadline.Before(ca.NotAfter) {
+		return cert.NotAfter, errors.Errorf("serving certificate expires at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return cert.NotAfter, nil
+}
+
+// mergeCABundle puts the CA certificate ahead of the still valid certificates in the bundle, so the clients trust
+// both the serving certificate rotated and the one replaced until the webhook server reloads it
+func mergeCABundle(caCert, bundle []byte, now time.Time) []byte {
+	merged := append([]byte{}, caCert...)
+	for rest := bundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) {
+			continue
+		}
+		encoded := pem.EncodeToMemory(block)
+		if !bytes.Contains(merged, encoded) {
+			merged = append(merged, encoded...)
+		}
+	}
+	return merged
+}
+
+func parseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func newSerialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
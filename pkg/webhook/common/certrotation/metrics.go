@@ -0,0 +1,41 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	certExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kubevela_webhook_cert_expiration_timestamp_seconds",
+		Help: "The expiry of the webhook serving certificate in unix seconds.",
+	})
+	rotationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kubevela_webhook_cert_rotations_total",
+		Help: "The number of times the webhook serving certificate is rotated.",
+	})
+	refreshErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kubevela_webhook_cert_refresh_errors_total",
+		Help: "The number of failures to check or rotate the webhook serving certificate.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(certExpiry, rotationsTotal, refreshErrorsTotal)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	// DefaultValidity is the default validity of the certificates generated
+	DefaultValidity = 365 * 24 * time.Hour
+	// DefaultRefreshBefore is the default time ahead of the expiry to rotate the certificates
+	DefaultRefreshBefore = 30 * 24 * time.Hour
+	// DefaultCheckInterval is the default interval to check the certificates, it's short since the CA bundle of
+	// the webhook configurations is reset whenever they're reapplied, e.g., by helm upgrade
+	DefaultCheckInterval = time.Minute
+)
+
+// Rotator generates the webhook serving certificates into a secret and the cert dir of the webhook server, rotates
+// them before they expire and patches the CA bundle of the webhook configurations, so the webhooks don't depend on
+// cert-manager or any job patching them at installation
+type Rotator struct {
+	// Client reads and writes the secret and the webhook configurations, it must not be a cached client as the
+	// certificates are issued before the manager starts
+	Client client.Client
+	// SecretKey is the secret keeping the certificates
+	SecretKey types.NamespacedName
+	// CertDir is the dir the webhook server loads the serving certificate from, nothing is written if it's empty
+	CertDir string
+	// DNSNames are the DNS names of the webhook service
+	DNSNames []string
+	// WebhookConfigurations are the names of the mutating and validating webhook configurations to patch
+	WebhookConfigurations []string
+
+	Validity      time.Duration
+	RefreshBefore time.Duration
+	CheckInterval time.Duration
+
+	mu       sync.RWMutex
+	notAfter time.Time
+	lastErr  error
+}
+
+var _ manager.Runnable = &Rotator{}
+var _ manager.LeaderElectionRunnable = &Rotator{}
+
+// Start checks the certificates periodically until stopped
+func (r *Rotator) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(r.checkInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if err := r.Refresh(context.Background()); err != nil {
+				klog.ErrorS(err, "Failed to refresh the webhook serving certificate", "secret", r.SecretKey)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false since every replica serves the webhooks with the certificates in its own cert dir
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Refresh rotates the certificates if they're missing, invalid or about to expire, and makes sure the webhook
+// configurations trust them and the webhook server serves them
+func (r *Rotator) Refresh(ctx context.Context) error {
+	notAfter, err := r.refresh(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastErr = err
+	if err != nil {
+		refreshErrorsTotal.Inc()
+		return err
+	}
+	r.notAfter = notAfter
+	certExpiry.Set(float64(notAfter.Unix()))
+	return nil
+}
+
+func (r *Rotator) refresh(ctx context.Context) (time.Time, error) {
+	secret := &corev1.Secret{}
+	exists := true
+	if err := r.Client.Get(ctx, r.SecretKey, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return time.Time{}, errors.Wrapf(err, "cannot get secret %s", r.SecretKey)
+		}
+		exists = false
+		secret.Name, secret.Namespace = r.SecretKey.Name, r.SecretKey.Namespace
+	}
+	kp := &KeyPair{CACert: secret.Data[CACertName], Cert: secret.Data[ServerCertName], Key: secret.Data[ServerKeyName]}
+	now := time.Now()
+	notAfter, err := kp.Validate(r.DNSNames, now, r.refreshBefore())
+	if err != nil {
+		klog.InfoS("Rotate the webhook serving certificate", "secret", r.SecretKey, "reason", err.Error())
+		if kp, err = Generate(r.DNSNames, now, r.validity()); err != nil {
+			return time.Time{}, err
+		}
+		notAfter = now.Add(r.validity())
+		// trust the new CA ahead of serving the certificate signed by it
+		if err := r.patchCABundle(ctx, kp.CACert, now); err != nil {
+			return time.Time{}, err
+		}
+		if err := r.saveSecret(ctx, secret, exists, kp, notAfter, now); err != nil {
+			return time.Time{}, err
+		}
+		rotationsTotal.Inc()
+	} else if err := r.patchCABundle(ctx, kp.CACert, now); err != nil {
+		return time.Time{}, err
+	}
+	if err := r.writeCertFiles(kp); err != nil {
+		return time.Time{}, err
+	}
+	return notAfter, nil
+}
+
+func (r *Rotator) saveSecret(ctx context.Context, secret *corev1.Secret, exists bool, kp *KeyPair, notAfter, now time.Time) error {
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{CACertName: kp.CACert, ServerCertName: kp.Cert, ServerKeyName: kp.Key}
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationCertNotAfter] = notAfter.Format(time.RFC3339)
+	annotations[oam.AnnotationCertRotatedAt] = now.Format(time.RFC3339)
+	secret.SetAnnotations(annotations)
+	if !exists {
+		return errors.Wrapf(r.Client.Create(ctx, secret), "cannot create secret %s", r.SecretKey)
+	}
+	// the update conflicts if another replica rotates it at the same time, it's picked up in the next check
+	return errors.Wrapf(r.Client.Update(ctx, secret), "cannot update secret %s", r.SecretKey)
+}
+
+// patchCABundle adds the CA certificate to the CA bundle of all the webhooks in the configurations, the
+// configurations not installed are skipped
+func (r *Rotator) patchCABundle(ctx context.Context, caCert []byte, now time.Time) error {
+	for _, name := range r.WebhookConfigurations {
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, mutating); err == nil {
+			patch := client.MergeFrom(mutating.DeepCopy())
+			changed := false
+			for i := range mutating.Webhooks {
+				changed = updateCABundle(&mutating.Webhooks[i].ClientConfig, caCert, now) || changed
+			}
+			if changed {
+				if err := r.Client.Patch(ctx, mutating, patch); err != nil {
+					return errors.Wrapf(err, "cannot patch CA bundle of mutating webhook configuration %s", name)
+				}
+			}
+		} else if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot get mutating webhook configuration %s", name)
+		}
+
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, validating); err == nil {
+			patch := client.MergeFrom(validating.DeepCopy())
+			changed := false
+			for i := range validating.Webhooks {
+				changed = updateCABundle(&validating.Webhooks[i].ClientConfig, caCert, now) || changed
+			}
+			if changed {
+				if err := r.Client.Patch(ctx, validating, patch); err != nil {
+					return errors.Wrapf(err, "cannot patch CA bundle of validating webhook configuration %s", name)
+				}
+			}
+		} else if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot get validating webhook configuration %s", name)
+		}
+	}
+	return nil
+}
+
+func updateCABundle(cc *admissionregistrationv1.WebhookClientConfig, caCert []byte, now time.Time) bool {
+	if bytes.HasPrefix(cc.CABundle, caCert) {
+		return false
+	}
+	cc.CABundle = mergeCABundle(caCert, cc.CABundle, now)
+	return true
+}
+
+// writeCertFiles writes the serving certificate into the cert dir if it's changed, the webhook server reloads it
+// once the files are replaced
+func (r *Rotator) writeCertFiles(kp *KeyPair) error {
+	if len(r.CertDir) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(r.CertDir, 0750); err != nil {
+		return errors.Wrapf(err, "cannot create cert dir %s", r.CertDir)
+	}
+	// the key is written ahead of the certificate as the webhook server reloads them on the change of the latter
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{ServerKeyName, kp.Key}, {ServerCertName, kp.Cert}} {
+		path := filepath.Join(r.CertDir, f.name)
+		if current, err := ioutil.ReadFile(filepath.Clean(path)); err == nil && bytes.Equal(current, f.data) {
+			continue
+		}
+		tmp := path + ".tmp"
+		if err := ioutil.WriteFile(tmp, f.data, 0600); err != nil {
+			return errors.Wrapf(err, "cannot write %s", tmp)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return errors.Wrapf(err, "cannot replace %s", path)
+		}
+	}
+	return nil
+}
+
+// Check is a healthz.Checker failing once the serving certificate expires or it's never issued
+func (r *Rotator) Check(_ *http.Request) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.notAfter.IsZero() {
+		if r.lastErr != nil {
+			return errors.WithMessage(r.lastErr, "webhook serving certificate is not issued")
+		}
+		return errors.New("webhook serving certificate is not issued")
+	}
+	if time.Now().After(r.notAfter) {
+		return errors.Errorf("webhook serving certificate expired at %s", r.notAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (r *Rotator) validity() time.Duration {
+	if r.Validity <= 0 {
+		return DefaultValidity
+	}
+	return r.Validity
+}
+
+func (r *Rotator) refreshBefore() time.Duration {
+	refreshBefore := r.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = DefaultRefreshBefore
+	}
+	// leave the certificates valid for a while after the rotation
+	if refreshBefore >= r.validity() {
+		refreshBefore = r.validity() / 12
+	}
+	return refreshBefore
+}
+
+func (r *Rotator) checkInterval() time.Duration {
+	if r.CheckInterval <= 0 {
+		return DefaultCheckInterval
+	}
+	return r.CheckInterval
+}
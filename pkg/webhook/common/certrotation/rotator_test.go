@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var dnsNames = []string{"vela-core-webhook", "vela-core-webhook.vela-system.svc"}
+
+func TestGenerateAndValidate(t *testing.T) {
+	now := time.Now()
+	kp, err := Generate(dnsNames, now, 24*time.Hour)
+	assert.NoError(t, err)
+	notAfter, err := kp.Validate(dnsNames, now, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour).Unix(), notAfter.Unix())
+
+	_, err = kp.Validate(dnsNames, now.Add(23*time.Hour+time.Minute), time.Hour)
+	assert.Error(t, err, "about to expire")
+	_, err = kp.Validate([]string{"other.vela-system.svc"}, now, time.Hour)
+	assert.Error(t, err, "DNS name not served")
+
+	other, err := Generate(dnsNames, now, 24*time.Hour)
+	assert.NoError(t, err)
+	_, err = (&KeyPair{CACert: other.CACert, Cert: kp.Cert, Key: kp.Key}).Validate(dnsNames, now, time.Hour)
+	assert.Error(t, err, "not signed by the CA")
+	_, err = (&KeyPair{CACert: kp.CACert, Cert: kp.Cert, Key: other.Key}).Validate(dnsNames, now, time.Hour)
+	assert.Error(t, err, "key mismatch")
+	_, err = (&KeyPair{}).Validate(dnsNames, now, time.Hour)
+	assert.Error(t, err)
+
+	_, err = Generate(nil, now, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestMergeCABundle(t *testing.T) {
+	now := time.Now()
+	old, err := Generate(dnsNames, now.Add(-2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	current, err := Generate(dnsNames, now.Add(-time.Hour), 2*time.Hour)
+	assert.NoError(t, err)
+	kp, err := Generate(dnsNames, now, 2*time.Hour)
+	assert.NoError(t, err)
+
+	bundle := mergeCABundle(kp.CACert, append(append([]byte{}, current.CACert...), old.CACert...), now)
+	assert.Equal(t, append(append([]byte{}, kp.CACert...), current.CACert...), bundle, "expired CA is dropped")
+	assert.Equal(t, bundle, mergeCABundle(kp.CACert, bundle, now))
+	assert.Equal(t, kp.CACert, mergeCABundle(kp.CACert, []byte("\n"), now))
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	certDir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(certDir)
+
+	const name = "kubevela-vela-core-admission"
+	placeholder := admissionregistrationv1.WebhookClientConfig{CABundle: []byte("\n")}
+	c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme,
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mutating.core.oam.dev.v1beta1.applications", ClientConfig: placeholder}},
+		},
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "validating.core.oam.dev.v1beta1.applications", ClientConfig: placeholder}},
+		})
+	r := &Rotator{
+		Client:                c,
+		SecretKey:             types.NamespacedName{Namespace: "vela-system", Name: name},
+		CertDir:               certDir,
+		DNSNames:              dnsNames,
+		WebhookConfigurations: []string{name},
+		Validity:              24 * time.Hour,
+		RefreshBefore:         time.Hour,
+	}
+	assert.Error(t, r.Check(nil), "not issued yet")
+
+	assertIssued := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		assert.NoError(t, c.Get(ctx, r.SecretKey, secret))
+		assert.Contains(t, secret.Annotations, "app.oam.dev/cert-not-after")
+		kp := &KeyPair{CACert: secret.Data[CACertName], Cert: secret.Data[ServerCertName], Key: secret.Data[ServerKeyName]}
+		_, err := kp.Validate(dnsNames, time.Now(), time.Hour)
+		assert.NoError(t, err)
+
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		assert.NoError(t, c.Get(ctx, types.NamespacedName{Name: name}, mutating))
+		assert.Equal(t, kp.CACert, mutating.Webhooks[0].ClientConfig.CABundle[:len(kp.CACert)])
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		assert.NoError(t, c.Get(ctx, types.NamespacedName{Name: name}, validating))
+		assert.Equal(t, kp.CACert, validating.Webhooks[0].ClientConfig.CABundle[:len(kp.CACert)])
+
+		cert, err := ioutil.ReadFile(filepath.Join(certDir, ServerCertName))
+		assert.NoError(t, err)
+		assert.Equal(t, kp.Cert, cert)
+		key, err := ioutil.ReadFile(filepath.Join(certDir, ServerKeyName))
+		assert.NoError(t, err)
+		assert.Equal(t, kp.Key, key)
+		assert.NoError(t, r.Check(nil))
+		return secret
+	}
+
+	// issue the certificates
+	assert.NoError(t, r.Refresh(ctx))
+	issued := assertIssued()
+
+	// keep the valid certificates
+	assert.NoError(t, r.Refresh(ctx))
+	assert.Equal(t, issued.Data, assertIssued().Data)
+
+	// rotate the certificates about to expire
+	r.RefreshBefore = 25 * time.Hour
+	r.Validity = 48 * time.Hour
+	assert.NoError(t, r.Refresh(ctx))
+	rotated := assertIssued()
+	assert.NotEqual(t, issued.Data[CACertName], rotated.Data[CACertName])
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	assert.NoError(t, c.Get(ctx, types.NamespacedName{Name: name}, mutating))
+	assert.Equal(t, append(append([]byte{}, rotated.Data[CACertName]...), issued.Data[CACertName]...), mutating.Webhooks[0].ClientConfig.CABundle,
+		"the replaced CA is still trusted")
+}
@@ -19,6 +19,7 @@ package rollout
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -26,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/analysis"
 )
 
 // DefaultRolloutBatches set the default values for a rollout batches
@@ -50,6 +52,11 @@ func DefaultRolloutPlan(rollout *v1alpha1.RolloutPlan) {
 	if len(rollout.RolloutStrategy) == 0 {
 		rollout.RolloutStrategy = v1alpha1.IncreaseFirstRolloutStrategyType
 	}
+	// the blue-green and the A/B testing rollouts provision the whole target in one batch
+	if (rollout.RolloutStrategy == v1alpha1.BlueGreenRolloutStrategyType ||
+		rollout.RolloutStrategy == v1alpha1.ABTestingRolloutStrategyType) && rollout.RolloutBatches == nil {
+		rollout.RolloutBatches = []v1alpha1.RolloutBatch{{Replicas: intstr.FromString("100%")}}
+	}
 }
 
 // FillRolloutBatches fills the replicas in each batch depends on the total size and number of batches
@@ -85,55 +92,255 @@ func ValidateCreate(client client.Client, rollout *v1alpha1.RolloutPlan, rootPat
 	}
 
 	if rollout.RolloutStrategy != v1alpha1.IncreaseFirstRolloutStrategyType &&
-		rollout.RolloutStrategy != v1alpha1.DecreaseFirstRolloutStrategyType {
-		allErrs = append(allErrs, field.Invalid(rootPath.Child("rolloutStrategy"),
-			rollout.RolloutStrategy, "the rolloutStrategy can only be IncreaseFirst or DecreaseFirst"))
+		rollout.RolloutStrategy != v1alpha1.DecreaseFirstRolloutStrategyType &&
+		rollout.RolloutStrategy != v1alpha1.BlueGreenRolloutStrategyType &&
+		rollout.RolloutStrategy != v1alpha1.ABTestingRolloutStrategyType {
+		allErrs = append(allErrs, field.Invalid(rootPath.Child("rolloutStrategy"), rollout.RolloutStrategy,
+			"the rolloutStrategy can only be IncreaseFirst, DecreaseFirst, BlueGreen or ABTesting"))
 	}
 
+	// validate the blue-green and the A/B testing rollouts
+	allErrs = append(allErrs, validateBlueGreen(rollout, rootPath)...)
+	allErrs = append(allErrs, validateABTesting(rollout, rootPath)...)
+
 	// validate the webhooks
 	allErrs = append(allErrs, validateWebhook(rollout, rootPath)...)
 
 	// validate the rollout batches
 	allErrs = append(allErrs, validateRolloutBatches(rollout, rootPath)...)
 
+	// validate the canary analysis
+	allErrs = append(allErrs, validateCanaryMetrics(rollout, rootPath)...)
+
+	// validate the traffic routing
+	allErrs = append(allErrs, validateTrafficRouting(rollout, rootPath)...)
+
 	// TODO: The total number of num in the batches match the current target resource pod size
 	return allErrs
 }
 
 func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
-	// The webhooks in the rollout plan can only be initialize or finalize webhooks
+	// The webhooks in the rollout plan apply to the whole rollout, the batch webhooks among them are invoked
+	// for every batch
 	if rollout.RolloutWebhooks != nil {
 		webhookPath := rootPath.Child("rolloutWebhooks")
 		for i, rw := range rollout.RolloutWebhooks {
-			if rw.Type != v1alpha1.InitializeRolloutHook && rw.Type != v1alpha1.FinalizeRolloutHook {
+			switch rw.Type {
+			case v1alpha1.InitializeRolloutHook, v1alpha1.FinalizeRolloutHook, v1alpha1.PreBatchRolloutHook,
+				v1alpha1.PostBatchRolloutHook, v1alpha1.PreRollbackHook:
+			default:
 				allErrs = append(allErrs, field.Invalid(webhookPath.Index(i),
-					rw.Type, "the rollout webhook type can only be initialize or finalize webhook"))
+					rw.Type, "the rollout webhook type can only be initialize, finalize, pre or post batch "+
+						"or pre rollback webhook"))
 			}
 			// TODO: check the URL/name uniqueness?
 			if rw.Method != http.MethodPost && rw.Method != http.MethodGet && rw.Method != http.MethodPut {
 				allErrs = append(allErrs, field.Invalid(webhookPath.Index(i),
 					rw.Method, "the rollout webhook method can only be Get/PUT/POST"))
 			}
+			allErrs = append(allErrs, validateWebhookPolicy(rw, webhookPath.Index(i))...)
 		}
 	}
 
-	// The webhooks in the rollout batch can only be pre or post batch types
+	// The webhooks in the rollout batch can only be pre or post batch or pre rollback types
 	if rollout.RolloutBatches != nil {
 		batchesPath := rootPath.Child("rolloutBatches")
 		for i, rb := range rollout.RolloutBatches {
 			rolloutBatchPath := batchesPath.Index(i)
 			for j, brw := range rb.BatchRolloutWebhooks {
-				if brw.Type != v1alpha1.PostBatchRolloutHook && brw.Type != v1alpha1.PreBatchRolloutHook {
-					allErrs = append(allErrs, field.Invalid(rolloutBatchPath.Child("batchRolloutWebhooks").Index(j),
-						brw.Type, "the batch webhook type can only be pre or post batch webhook"))
+				batchWebhookPath := rolloutBatchPath.Child("batchRolloutWebhooks").Index(j)
+				if brw.Type != v1alpha1.PostBatchRolloutHook && brw.Type != v1alpha1.PreBatchRolloutHook &&
+					brw.Type != v1alpha1.PreRollbackHook {
+					allErrs = append(allErrs, field.Invalid(batchWebhookPath,
+						brw.Type, "the batch webhook type can only be pre or post batch or pre rollback webhook"))
 				}
 				// TODO: check the URL/name uniqueness?
+				allErrs = append(allErrs, validateWebhookPolicy(brw, batchWebhookPath)...)
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateWebhookPolicy checks the timeout, the retries and the failure policy of a webhook
+func validateWebhookPolicy(rw v1alpha1.RolloutWebhook, webhookPath *field.Path) (allErrs field.ErrorList) {
+	if rw.TimeoutSeconds != nil && *rw.TimeoutSeconds < 1 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("timeoutSeconds"), *rw.TimeoutSeconds,
+			"the webhook timeout must be at least one second"))
+	}
+	if rw.Retries != nil && *rw.Retries < 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("retries"), *rw.Retries,
+			"the webhook retries can't be negative"))
+	}
+	if len(rw.FailurePolicy) != 0 && rw.FailurePolicy != v1alpha1.FailWebhookFailurePolicy &&
+		rw.FailurePolicy != v1alpha1.IgnoreWebhookFailurePolicy {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("failurePolicy"), rw.FailurePolicy,
+			"the webhook failure policy can only be Fail or Ignore"))
+	}
+	return allErrs
+}
+
+func validateCanaryMetrics(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	if len(rollout.CanaryFailurePolicy) != 0 && rollout.CanaryFailurePolicy != v1alpha1.PauseCanaryFailurePolicy &&
+		rollout.CanaryFailurePolicy != v1alpha1.RollbackCanaryFailurePolicy {
+		allErrs = append(allErrs, field.Invalid(rootPath.Child("canaryFailurePolicy"),
+			rollout.CanaryFailurePolicy, "the canary failure policy can only be Pause or Rollback"))
+	}
+	for i, m := range rollout.CanaryMetric {
+		if err := analysis.ValidateMetric(m); err != nil {
+			allErrs = append(allErrs, field.Invalid(rootPath.Child("canaryMetric").Index(i), m.Name, err.Error()))
+		}
+	}
+	batchesPath := rootPath.Child("rolloutBatches")
+	for i, rb := range rollout.RolloutBatches {
+		for j, m := range rb.CanaryMetric {
+			if err := analysis.ValidateMetric(m); err != nil {
+				allErrs = append(allErrs, field.Invalid(batchesPath.Index(i).Child("canaryMetric").Index(j),
+					m.Name, err.Error()))
 			}
 		}
 	}
 	return allErrs
 }
 
+func validateBlueGreen(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	blueGreenPath := rootPath.Child("blueGreen")
+	if rollout.RolloutStrategy != v1alpha1.BlueGreenRolloutStrategyType {
+		if rollout.BlueGreen != nil {
+			allErrs = append(allErrs, field.Forbidden(blueGreenPath, "only the BlueGreen rolloutStrategy can be configured"))
+		}
+		return allErrs
+	}
+	allErrs = append(allErrs, validatePromotedBatches(rollout, rootPath)...)
+	if bg := rollout.BlueGreen; bg != nil {
+		if len(bg.ActiveService) != 0 && bg.ActiveService == bg.PreviewService {
+			allErrs = append(allErrs, field.Invalid(blueGreenPath.Child("previewService"), bg.PreviewService,
+				"the preview service has to be different from the active service"))
+		}
+		allErrs = append(allErrs, validatePromotionPolicy(bg.PromotionPolicy, blueGreenPath)...)
+	}
+	if (rollout.BlueGreen == nil || len(rollout.BlueGreen.ActiveService) == 0) && rollout.TrafficRouting == nil {
+		allErrs = append(allErrs, field.Required(blueGreenPath.Child("activeService"),
+			"the blue-green rollout needs an active service or a traffic routing to switch the traffic"))
+	}
+	return allErrs
+}
+
+func validateABTesting(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	abTestingPath := rootPath.Child("abTesting")
+	if rollout.RolloutStrategy != v1alpha1.ABTestingRolloutStrategyType {
+		if rollout.ABTesting != nil {
+			allErrs = append(allErrs, field.Forbidden(abTestingPath, "only the ABTesting rolloutStrategy can be configured"))
+		}
+		return allErrs
+	}
+	allErrs = append(allErrs, validatePromotedBatches(rollout, rootPath)...)
+	if rollout.TrafficRouting == nil {
+		allErrs = append(allErrs, field.Required(rootPath.Child("trafficRouting"),
+			"the A/B testing rollout needs a traffic routing to route the requests"))
+	} else if rollout.TrafficRouting.Provider == v1alpha1.SMITrafficRoutingProvider {
+		allErrs = append(allErrs, field.Invalid(rootPath.Child("trafficRouting", "provider"),
+			rollout.TrafficRouting.Provider, "the provider doesn't support the A/B testing rules"))
+	}
+	if rollout.ABTesting == nil || len(rollout.ABTesting.Rules) == 0 {
+		return append(allErrs, field.Required(abTestingPath.Child("rules"), "the A/B testing rollout needs rules"))
+	}
+	names := map[string]bool{}
+	for i, rule := range rollout.ABTesting.Rules {
+		rulePath := abTestingPath.Child("rules").Index(i)
+		if len(rule.Name) == 0 {
+			allErrs = append(allErrs, field.Required(rulePath.Child("name"), "the rule has to have a name"))
+		} else if names[rule.Name] {
+			allErrs = append(allErrs, field.Duplicate(rulePath.Child("name"), rule.Name))
+		}
+		names[rule.Name] = true
+		if len(rule.Headers) == 0 && rule.Cookie == nil {
+			allErrs = append(allErrs, field.Required(rulePath,
+				"the rule has to match the requests by headers or a cookie"))
+		}
+		for j, h := range rule.Headers {
+			headerPath := rulePath.Child("headers").Index(j)
+			if len(h.Name) == 0 {
+				allErrs = append(allErrs, field.Required(headerPath.Child("name"), "the header name cannot be empty"))
+			}
+			switch h.Type {
+			case "", v1alpha1.ExactHeaderMatch:
+			case v1alpha1.RegexHeaderMatch:
+				if _, err := regexp.Compile(h.Value); err != nil {
+					allErrs = append(allErrs, field.Invalid(headerPath.Child("value"), h.Value, err.Error()))
+				}
+			default:
+				allErrs = append(allErrs, field.NotSupported(headerPath.Child("type"), h.Type,
+					[]string{string(v1alpha1.ExactHeaderMatch), string(v1alpha1.RegexHeaderMatch)}))
+			}
+		}
+		if rule.Cookie != nil && len(rule.Cookie.Name) == 0 {
+			allErrs = append(allErrs, field.Required(rulePath.Child("cookie", "name"), "the cookie name cannot be empty"))
+		}
+		if rule.Weight != nil && (*rule.Weight < 0 || *rule.Weight > 100) {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("weight"), *rule.Weight,
+				"the weight has to be between 0 and 100"))
+		}
+	}
+	allErrs = append(allErrs, validatePromotionPolicy(rollout.ABTesting.PromotionPolicy, abTestingPath)...)
+	return allErrs
+}
+
+// validatePromotedBatches validates the batches of the strategies provisioning the whole target at once
+func validatePromotedBatches(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	if len(rollout.RolloutBatches) > 1 {
+		allErrs = append(allErrs, field.Invalid(rootPath.Child("rolloutBatches"), len(rollout.RolloutBatches),
+			fmt.Sprintf("the %s rollout can only have one batch", rollout.RolloutStrategy)))
+	}
+	for i, rb := range rollout.RolloutBatches {
+		if rb.TrafficWeight != nil {
+			allErrs = append(allErrs, field.Forbidden(rootPath.Child("rolloutBatches").Index(i).Child("trafficWeight"),
+				fmt.Sprintf("the %s rollout switches all the traffic when the target is promoted", rollout.RolloutStrategy)))
+		}
+	}
+	return allErrs
+}
+
+func validatePromotionPolicy(policy v1alpha1.PromotionPolicy, path *field.Path) (allErrs field.ErrorList) {
+	if policy.ScaleDownDelaySeconds != nil && *policy.ScaleDownDelaySeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("scaleDownDelaySeconds"),
+			*policy.ScaleDownDelaySeconds, "the scale down delay cannot be negative"))
+	}
+	return allErrs
+}
+
+func validateTrafficRouting(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	if routing := rollout.TrafficRouting; routing != nil {
+		routingPath := rootPath.Child("trafficRouting")
+		switch routing.Provider {
+		case v1alpha1.IstioTrafficRoutingProvider, v1alpha1.SMITrafficRoutingProvider,
+			v1alpha1.GatewayAPITrafficRoutingProvider:
+		default:
+			allErrs = append(allErrs, field.NotSupported(routingPath.Child("provider"), routing.Provider,
+				[]string{string(v1alpha1.IstioTrafficRoutingProvider), string(v1alpha1.SMITrafficRoutingProvider),
+					string(v1alpha1.GatewayAPITrafficRoutingProvider)}))
+		}
+		for name, service := range map[string]string{"service": routing.Service,
+			"stableService": routing.StableService, "canaryService": routing.CanaryService} {
+			if len(service) == 0 {
+				allErrs = append(allErrs, field.Required(routingPath.Child(name), "the service name cannot be empty"))
+			}
+		}
+		if len(routing.StableService) != 0 && routing.StableService == routing.CanaryService {
+			allErrs = append(allErrs, field.Invalid(routingPath.Child("canaryService"), routing.CanaryService,
+				"the canary service has to be different from the stable service"))
+		}
+	}
+	batchesPath := rootPath.Child("rolloutBatches")
+	for i, rb := range rollout.RolloutBatches {
+		if rb.TrafficWeight != nil && (*rb.TrafficWeight < 0 || *rb.TrafficWeight > 100) {
+			allErrs = append(allErrs, field.Invalid(batchesPath.Index(i).Child("trafficWeight"), *rb.TrafficWeight,
+				"the traffic weight has to be between 0 and 100"))
+		}
+	}
+	return allErrs
+}
+
 func validateRolloutBatches(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
 	if rollout.RolloutBatches != nil {
 		batchesPath := rootPath.Child("rolloutBatches")
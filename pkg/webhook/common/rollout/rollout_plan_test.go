@@ -155,3 +155,162 @@ func TestValidateIllegalReplicas(t *testing.T) {
 		t.Error("should invalidate negative replica value")
 	}
 }
+
+func TestValidateCanaryMetrics(t *testing.T) {
+	max := intstr.FromString("0.05")
+	valid := &v1alpha1.RolloutPlan{
+		CanaryFailurePolicy: v1alpha1.RollbackCanaryFailurePolicy,
+		CanaryMetric: []v1alpha1.CanaryMetric{
+			{
+				Name:         "error-rate",
+				Interval:     "2m",
+				Query:        `sum(rate(http_requests_total{app="{{ .Name }}",code=~"5.."}[{{ .Interval }}]))`,
+				Provider:     &v1alpha1.MetricProvider{Address: "http://prometheus:9090"},
+				MetricsRange: &v1alpha1.MetricsExpectedRange{Max: &max},
+			},
+		},
+	}
+	if errList := validateCanaryMetrics(valid, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should accept the canary metrics, got %v", errList)
+	}
+
+	min := intstr.FromString("0.1")
+	invalid := &v1alpha1.RolloutPlan{
+		CanaryFailurePolicy: "Abort",
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{
+				CanaryMetric: []v1alpha1.CanaryMetric{
+					{Name: "no-query"},
+					{Name: "bad-interval", Query: "up", Interval: "1d"},
+					{Name: "bad-range", Query: "up", MetricsRange: &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max}},
+				},
+			},
+		},
+	}
+	if errList := validateCanaryMetrics(invalid, field.NewPath("spec")); len(errList) != 4 {
+		t.Errorf("should invalidate the policy and the three metrics, got %v", errList)
+	}
+}
+
+func TestValidateTrafficRouting(t *testing.T) {
+	valid := &v1alpha1.RolloutPlan{
+		TrafficRouting: &v1alpha1.TrafficRouting{
+			Provider:      v1alpha1.IstioTrafficRoutingProvider,
+			Service:       "web",
+			StableService: "web-stable",
+			CanaryService: "web-canary",
+		},
+		RolloutBatches: []v1alpha1.RolloutBatch{{TrafficWeight: pointer.Int32Ptr(10)}, {}},
+	}
+	if errList := validateTrafficRouting(valid, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should accept the traffic routing, got %v", errList)
+	}
+
+	invalid := &v1alpha1.RolloutPlan{
+		TrafficRouting: &v1alpha1.TrafficRouting{
+			Provider:      "linkerd",
+			StableService: "web",
+			CanaryService: "web",
+		},
+		RolloutBatches: []v1alpha1.RolloutBatch{{TrafficWeight: pointer.Int32Ptr(120)}},
+	}
+	if errList := validateTrafficRouting(invalid, field.NewPath("spec")); len(errList) != 4 {
+		t.Errorf("should invalidate the provider, the service, the canary service and the weight, got %v", errList)
+	}
+}
+
+func TestValidateBlueGreen(t *testing.T) {
+	plan := &v1alpha1.RolloutPlan{
+		RolloutStrategy: v1alpha1.BlueGreenRolloutStrategyType,
+		BlueGreen: &v1alpha1.BlueGreenStrategy{
+			ActiveService:  "web",
+			PreviewService: "web-preview",
+		},
+	}
+	DefaultRolloutPlan(plan)
+	if len(plan.RolloutBatches) != 1 || plan.RolloutBatches[0].Replicas.String() != "100%" {
+		t.Errorf("should default to one batch of the whole target, got %v", plan.RolloutBatches)
+	}
+	if errList := validateBlueGreen(plan, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should accept the blue-green rollout, got %v", errList)
+	}
+
+	invalid := &v1alpha1.RolloutPlan{
+		RolloutStrategy: v1alpha1.BlueGreenRolloutStrategyType,
+		BlueGreen: &v1alpha1.BlueGreenStrategy{
+			PromotionPolicy: v1alpha1.PromotionPolicy{ScaleDownDelaySeconds: pointer.Int32Ptr(-1)},
+		},
+		RolloutBatches: []v1alpha1.RolloutBatch{{TrafficWeight: pointer.Int32Ptr(10)}, {}},
+	}
+	if errList := validateBlueGreen(invalid, field.NewPath("spec")); len(errList) != 4 {
+		t.Errorf("should invalidate the batches, the weight, the delay and the active service, got %v", errList)
+	}
+
+	canary := &v1alpha1.RolloutPlan{
+		RolloutStrategy: v1alpha1.IncreaseFirstRolloutStrategyType,
+		BlueGreen:       &v1alpha1.BlueGreenStrategy{ActiveService: "web"},
+	}
+	if errList := validateBlueGreen(canary, field.NewPath("spec")); len(errList) != 1 {
+		t.Errorf("should forbid the blue-green settings of a canary rollout, got %v", errList)
+	}
+}
+
+func TestValidateABTesting(t *testing.T) {
+	plan := &v1alpha1.RolloutPlan{
+		RolloutStrategy: v1alpha1.ABTestingRolloutStrategyType,
+		TrafficRouting: &v1alpha1.TrafficRouting{
+			Provider:      v1alpha1.IstioTrafficRoutingProvider,
+			Service:       "web",
+			StableService: "web-stable",
+			CanaryService: "web-canary",
+		},
+		ABTesting: &v1alpha1.ABTestingStrategy{
+			Rules: []v1alpha1.TrafficRule{
+				{Name: "beta", Headers: []v1alpha1.HeaderMatch{{Name: "x-beta", Value: "true"}}},
+				{Name: "mobile", Cookie: &v1alpha1.CookieMatch{Name: "platform", Value: "mobile"}, Weight: pointer.Int32Ptr(50)},
+			},
+		},
+	}
+	DefaultRolloutPlan(plan)
+	if errList := validateABTesting(plan, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should accept the A/B testing rollout, got %v", errList)
+	}
+
+	plan.TrafficRouting.Provider = v1alpha1.SMITrafficRoutingProvider
+	plan.ABTesting.Rules = []v1alpha1.TrafficRule{
+		{Name: "beta", Headers: []v1alpha1.HeaderMatch{{Name: "x-beta", Value: "(", Type: v1alpha1.RegexHeaderMatch}}},
+		{Name: "beta", Weight: pointer.Int32Ptr(120)},
+	}
+	// the provider, the regex, the duplicated name, the match and the weight
+	if errList := validateABTesting(plan, field.NewPath("spec")); len(errList) != 5 {
+		t.Errorf("should invalidate the A/B testing rollout, got %v", errList)
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	plan := &v1alpha1.RolloutPlan{
+		RolloutWebhooks: []v1alpha1.RolloutWebhook{
+			{Type: v1alpha1.PreBatchRolloutHook, Name: "tests", URL: "http://tests", Method: "POST",
+				TimeoutSeconds: pointer.Int32Ptr(30), Retries: pointer.Int32Ptr(3)},
+			{Type: v1alpha1.PreRollbackHook, Name: "pager", URL: "http://pager", Method: "POST",
+				FailurePolicy: v1alpha1.IgnoreWebhookFailurePolicy},
+		},
+		RolloutBatches: []v1alpha1.RolloutBatch{{
+			BatchRolloutWebhooks: []v1alpha1.RolloutWebhook{
+				{Type: v1alpha1.PostBatchRolloutHook, Name: "cmdb", URL: "http://cmdb"},
+			},
+		}},
+	}
+	if errList := validateWebhook(plan, field.NewPath("spec")); len(errList) != 0 {
+		t.Errorf("should accept the webhooks, got %v", errList)
+	}
+
+	plan.RolloutWebhooks[0].TimeoutSeconds = pointer.Int32Ptr(0)
+	plan.RolloutWebhooks[0].Retries = pointer.Int32Ptr(-1)
+	plan.RolloutWebhooks[1].FailurePolicy = "Retry"
+	plan.RolloutBatches[0].BatchRolloutWebhooks[0].Type = v1alpha1.FinalizeRolloutHook
+	// the timeout, the retries, the failure policy and the batch webhook type
+	if errList := validateWebhook(plan, field.NewPath("spec")); len(errList) != 4 {
+		t.Errorf("should invalidate the webhooks, got %v", errList)
+	}
+}
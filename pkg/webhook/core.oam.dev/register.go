@@ -27,15 +27,18 @@ import (
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/component"
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/componentdefinition"
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/traitdefinition"
+	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/workflowstepdefinition"
 )
 
 // Register will be called in main and register all validation handlers
 func Register(mgr manager.Manager, args controller.Args) {
+	application.RegisterMutatingHandler(mgr)
 	application.RegisterValidatingHandler(mgr, args)
 	applicationconfiguration.RegisterValidatingHandler(mgr, args)
 	componentdefinition.RegisterMutatingHandler(mgr, args)
 	componentdefinition.RegisterValidatingHandler(mgr, args)
 	traitdefinition.RegisterValidatingHandler(mgr, args)
+	workflowstepdefinition.RegisterValidatingHandler(mgr, args)
 	applicationconfiguration.RegisterMutatingHandler(mgr)
 	applicationrollout.RegisterMutatingHandler(mgr)
 	applicationrollout.RegisterValidatingHandler(mgr)
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	util "github.com/oam-dev/kubevela/pkg/utils"
+)
+
+// MutatingHandler handles Application
+type MutatingHandler struct {
+	// Decoder decodes objects
+	Decoder *admission.Decoder
+}
+
+var _ admission.Handler = &MutatingHandler{}
+
+// Handle handles admission requests.
+func (h *MutatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	app := &v1beta1.Application{}
+	if err := h.Decoder.Decode(req, app); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var oldApp *v1beta1.Application
+	if req.Operation == admissionv1beta1.Update {
+		oldApp = &v1beta1.Application{}
+		if err := h.Decoder.DecodeRaw(req.AdmissionRequest.OldObject, oldApp); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	StampApprover(app, oldApp, req.UserInfo)
+
+	marshalled, err := json.Marshal(app)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	resp := admission.PatchResponseFromRaw(req.AdmissionRequest.Object.Raw, marshalled)
+	if len(resp.Patches) > 0 {
+		klog.V(common.LogDebugWithContent).Infof("Admit Application %s/%s patches: %v", app.Namespace, app.Name,
+			util.DumpJSON(resp.Patches))
+	}
+	return resp
+}
+
+// StampApprover records the authenticated user as the approver once a workflow step is approved, so the approver
+// can't be claimed by the user approving it. The approver recorded before is kept if the step approved is unchanged.
+func StampApprover(app, oldApp *v1beta1.Application, user authenticationv1.UserInfo) {
+	var oldStep, oldApprover string
+	if oldApp != nil {
+		oldStep, oldApprover = oldApp.GetAnnotations()[oam.AnnotationApproveStep], oldApp.GetAnnotations()[oam.AnnotationApprovedBy]
+	}
+	annotations := app.GetAnnotations()
+	step := annotations[oam.AnnotationApproveStep]
+	approver := oldApprover
+	if step != oldStep {
+		approver = user.Username
+	}
+	if len(step) == 0 {
+		approver = ""
+	}
+	if approver == annotations[oam.AnnotationApprovedBy] {
+		return
+	}
+	if len(approver) == 0 {
+		delete(annotations, oam.AnnotationApprovedBy)
+		app.SetAnnotations(annotations)
+		return
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationApprovedBy] = approver
+	app.SetAnnotations(annotations)
+}
+
+var _ admission.DecoderInjector = &MutatingHandler{}
+
+// InjectDecoder injects the decoder into the MutatingHandler
+func (h *MutatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.Decoder = d
+	return nil
+}
+
+// RegisterMutatingHandler will register application mutation handler to the webhook
+func RegisterMutatingHandler(mgr manager.Manager) {
+	server := mgr.GetWebhookServer()
+	server.Register("/mutating-core-oam-dev-v1beta1-applications", &webhook.Admission{Handler: &MutatingHandler{}})
+}
@@ -33,6 +33,7 @@ import (
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/speclimit"
 )
 
 var _ admission.Handler = &ValidatingHandler{}
@@ -41,7 +42,10 @@ var _ admission.Handler = &ValidatingHandler{}
 type ValidatingHandler struct {
 	dm     discoverymapper.DiscoveryMapper
 	pd     *definition.PackageDiscover
-	Client client.Client
+	limits speclimit.Limits
+	// requireImpersonation requires the applications to declare the identities to impersonate
+	requireImpersonation bool
+	Client               client.Client
 	// Decoder decodes objects
 	Decoder *admission.Decoder
 }
@@ -80,6 +84,12 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 		if allErrs := h.ValidateCreate(ctx, app); len(allErrs) > 0 {
 			return admission.Errored(http.StatusUnprocessableEntity, allErrs.ToAggregate())
 		}
+		if allErrs := ValidateApproval(app, nil, req.UserInfo); len(allErrs) > 0 {
+			return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+		}
+		if allErrs := h.ValidateImpersonation(ctx, app, nil, req.UserInfo); len(allErrs) > 0 {
+			return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+		}
 	case admissionv1beta1.Update:
 		oldApp := &v1beta1.Application{}
 		if err := h.Decoder.DecodeRaw(req.AdmissionRequest.OldObject, oldApp); err != nil {
@@ -89,6 +99,12 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 			if allErrs := h.ValidateUpdate(ctx, app, oldApp); len(allErrs) > 0 {
 				return admission.Errored(http.StatusUnprocessableEntity, allErrs.ToAggregate())
 			}
+			if allErrs := ValidateApproval(app, oldApp, req.UserInfo); len(allErrs) > 0 {
+				return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+			}
+			if allErrs := h.ValidateImpersonation(ctx, app, oldApp, req.UserInfo); len(allErrs) > 0 {
+				return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+			}
 		}
 	default:
 		// Do nothing for DELETE and CONNECT
@@ -99,5 +115,6 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 // RegisterValidatingHandler will register application validate handler to the webhook
 func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
 	server := mgr.GetWebhookServer()
-	server.Register("/validating-core-oam-dev-v1beta1-applications", &webhook.Admission{Handler: &ValidatingHandler{dm: args.DiscoveryMapper, pd: args.PackageDiscover}})
+	server.Register("/validating-core-oam-dev-v1beta1-applications", &webhook.Admission{Handler: &ValidatingHandler{dm: args.DiscoveryMapper, pd: args.PackageDiscover, limits: args.AppSpecLimits,
+		requireImpersonation: args.RequireImpersonation}})
 }
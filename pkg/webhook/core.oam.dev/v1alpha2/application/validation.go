@@ -18,20 +18,38 @@ package application
 
 import (
 	"context"
+	"fmt"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/imagepolicy"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/quota"
+	"github.com/oam-dev/kubevela/pkg/utils/impersonate"
+	"github.com/oam-dev/kubevela/pkg/utils/speclimit"
 	"github.com/oam-dev/kubevela/pkg/webhook/common/rollout"
+	"github.com/oam-dev/kubevela/pkg/workflow"
 )
 
 // ValidateCreate validates the Application on creation
 func (h *ValidatingHandler) ValidateCreate(ctx context.Context, app *v1beta1.Application) field.ErrorList {
 	var componentErrs field.ErrorList
-	// try to generate an app file
-	appParser := appfile.NewApplicationParser(h.Client, h.dm, h.pd)
+	// reject the pathological specs before rendering them
+	limits, err := speclimit.Load(ctx, h.Client, app.Namespace, h.limits)
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("spec"), err)}
+	}
+	if errs := limits.ValidateSpec(app); len(errs) != 0 {
+		return errs
+	}
+	// try to generate an app file, the property values from the Secrets and Vault aren't read on admission since
+	// they may not exist yet
+	appParser := appfile.NewApplicationParser(h.Client, h.dm, h.pd).WithValueFromPlaceholders()
 
 	af, err := appParser.GenerateAppFile(ctx, app)
 	if err != nil {
@@ -39,12 +57,36 @@ func (h *ValidatingHandler) ValidateCreate(ctx context.Context, app *v1beta1.App
 		// cannot generate appfile, no need to validate further
 		return componentErrs
 	}
+	quotas, err := quota.Load(ctx, h.Client, app.Namespace)
+	if err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("spec"), err)}
+	}
+	if limits.MaxRenderedObjects > 0 || len(quotas) != 0 {
+		ac, comps, err := af.GenerateApplicationConfiguration()
+		if err == nil {
+			err = limits.ValidateRendered(ac)
+		}
+		if err == nil {
+			err = h.validateQuota(ctx, app, quotas, comps)
+		}
+		if err != nil {
+			componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec", "components"), len(app.Spec.Components), err.Error()))
+		}
+	}
 	if err := appParser.ValidateCUESchematicAppfile(af); err != nil {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("schematic"), app, err.Error()))
 	}
 	if v := app.GetAnnotations()[oam.AnnotationAppRollout]; len(v) != 0 && v != "true" {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("annotation:app.oam.dev/rollout-template"), app, "the annotation value of rollout-template must be true"))
 	}
+	for i, comp := range app.Spec.Components {
+		if comp.ImagePolicy == nil {
+			continue
+		}
+		if err := imagepolicy.Validate(comp.ImagePolicy); err != nil {
+			componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec", "components").Index(i).Child("imagePolicy"), comp.ImagePolicy.Image, err.Error()))
+		}
+	}
 	if app.Spec.RolloutPlan != nil {
 		componentErrs = append(componentErrs, rollout.ValidateCreate(h.Client, app.Spec.RolloutPlan, field.NewPath("rolloutPlan"))...)
 	}
@@ -58,3 +100,123 @@ func (h *ValidatingHandler) ValidateUpdate(ctx context.Context, newApp, oldApp *
 	// TODO: add more validating
 	return componentErrs
 }
+
+// validateQuota rejects the application if the usage of its rendered workloads exceeds the quotas, unless its quota
+// policy chooses to scale down the workloads, which is done by the controller
+func (h *ValidatingHandler) validateQuota(ctx context.Context, app *v1beta1.Application, quotas []quota.Quota, comps []*v1alpha2.Component) error {
+	action, err := quota.ActionOf(app)
+	if err != nil || action == quota.ActionScaleDown || len(quotas) == 0 {
+		return err
+	}
+	workloads, err := quota.WorkloadsOf(comps)
+	if err != nil {
+		return err
+	}
+	usage, err := quota.UsageOf(workloads)
+	if err != nil {
+		return err
+	}
+	return quota.Check(ctx, h.Client, app, quotas, usage)
+}
+
+// ValidateApproval validates the approval of the suspend-for-approval workflow step, only the approvers of the step
+// waiting for approval may approve it and the approver recorded must be the user approving it. The old application
+// is nil on creation.
+func ValidateApproval(newApp, oldApp *v1beta1.Application, user authenticationv1.UserInfo) field.ErrorList {
+	var oldStep, oldApprover string
+	if oldApp != nil {
+		oldStep, oldApprover = oldApp.GetAnnotations()[oam.AnnotationApproveStep], oldApp.GetAnnotations()[oam.AnnotationApprovedBy]
+	}
+	step, approver := newApp.GetAnnotations()[oam.AnnotationApproveStep], newApp.GetAnnotations()[oam.AnnotationApprovedBy]
+	path := field.NewPath("metadata", "annotations")
+	if oldApp != nil {
+		if allErrs := validateAwaitingStep(newApp, oldApp, user); len(allErrs) != 0 {
+			return allErrs
+		}
+	}
+	if step == oldStep {
+		if approver != oldApprover && len(approver) != 0 {
+			return field.ErrorList{field.Forbidden(path.Key(oam.AnnotationApprovedBy), "the approver cannot be changed")}
+		}
+		return nil
+	}
+	if len(step) == 0 {
+		return nil
+	}
+	if oldApp == nil || workflow.AwaitingApproval(oldApp) != step {
+		return field.ErrorList{field.Invalid(path.Key(oam.AnnotationApproveStep), step, "the workflow step is not waiting for approval")}
+	}
+	// the approvers are the ones declared before the approval, otherwise the user could declare themselves as an
+	// approver in the same update approving the step
+	approvals, err := workflow.ParseApprovals(oldApp.Spec.Workflow)
+	if err != nil {
+		return field.ErrorList{field.Invalid(field.NewPath("spec", "workflow"), step, err.Error())}
+	}
+	approval, ok := approvals[step]
+	if !ok {
+		return field.ErrorList{field.Invalid(path.Key(oam.AnnotationApproveStep), step, "the workflow step declares no approvers")}
+	}
+	if newApprovals, err := workflow.ParseApprovals(newApp.Spec.Workflow); err != nil || !equality.Semantic.DeepEqual(newApprovals[step], approval) {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec", "workflow"), "the approvers of the workflow step cannot be changed along with the approval")}
+	}
+	if !approval.IsApprover(user) {
+		return field.ErrorList{field.Forbidden(path.Key(oam.AnnotationApproveStep), fmt.Sprintf("user %s is not an approver of the workflow step", user.Username))}
+	}
+	if approver != user.Username {
+		return field.ErrorList{field.Forbidden(path.Key(oam.AnnotationApprovedBy), fmt.Sprintf("the approver must be the user approving the workflow step %s", user.Username))}
+	}
+	return nil
+}
+
+// validateAwaitingStep rejects the changes to the approvers, the type or the presence of the workflow step waiting
+// for approval unless the user is already an approver of the step, otherwise one could declare themselves as an
+// approver in an update and approve the step in the next
+func validateAwaitingStep(newApp, oldApp *v1beta1.Application, user authenticationv1.UserInfo) field.ErrorList {
+	name := workflow.AwaitingApproval(oldApp)
+	if len(name) == 0 {
+		return nil
+	}
+	oldStep, newStep := workflowStepNamed(oldApp.Spec.Workflow, name), workflowStepNamed(newApp.Spec.Workflow, name)
+	oldApproval, newApproval := approvalOf(oldStep), approvalOf(newStep)
+	if newStep != nil && oldStep != nil && newStep.Type == oldStep.Type && newApproval != nil &&
+		equality.Semantic.DeepEqual(newApproval, oldApproval) {
+		return nil
+	}
+	if oldApproval != nil && oldApproval.IsApprover(user) {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "workflow"),
+		fmt.Sprintf("user %s is not an approver of the workflow step %s waiting for approval, which cannot be changed then", user.Username, name))}
+}
+
+// workflowStepNamed returns the workflow step of the name, or nil if there isn't one
+func workflowStepNamed(steps []v1beta1.WorkflowStep, name string) *v1beta1.WorkflowStep {
+	for i := range steps {
+		if steps[i].Name == name {
+			return &steps[i]
+		}
+	}
+	return nil
+}
+
+// approvalOf returns the approvers of the workflow step, or nil if the step is nil, invalid or declares none
+func approvalOf(step *v1beta1.WorkflowStep) *workflow.ApprovalSpec {
+	if step == nil {
+		return nil
+	}
+	approvals, err := workflow.ParseApprovals([]v1beta1.WorkflowStep{*step})
+	if err != nil {
+		return nil
+	}
+	return approvals[step.Name]
+}
+
+// ValidateImpersonation validates the identity declared by the application to apply its resources, see
+// impersonate.Validate. The old application is nil on creation.
+func (h *ValidatingHandler) ValidateImpersonation(ctx context.Context, newApp, oldApp *v1beta1.Application, user authenticationv1.UserInfo) field.ErrorList {
+	// pass an untyped nil, a nil *Application is a non-nil metav1.Object
+	if oldApp == nil {
+		return impersonate.Validate(ctx, h.Client, newApp, nil, user, h.requireImpersonation)
+	}
+	return impersonate.Validate(ctx, h.Client, newApp, oldApp, user, h.requireImpersonation)
+}
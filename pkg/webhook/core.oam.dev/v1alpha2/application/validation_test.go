@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+func TestValidateApproval(t *testing.T) {
+	awaiting := func(approvers string) *v1beta1.Application {
+		app := &v1beta1.Application{}
+		app.Spec.Workflow = []v1beta1.WorkflowStep{{
+			Name:       "approve",
+			Type:       workflow.TypeSuspendForApproval,
+			Properties: runtime.RawExtension{Raw: []byte(`{"users":` + approvers + `}`)},
+		}}
+		app.Status.Workflow = []common.WorkflowStepStatus{{
+			Name:  "approve",
+			Type:  workflow.TypeSuspendForApproval,
+			Phase: common.WorkflowStepPhaseRunning,
+		}}
+		return app
+	}
+	approve := func(app *v1beta1.Application, user string) *v1beta1.Application {
+		app = app.DeepCopy()
+		app.SetAnnotations(map[string]string{oam.AnnotationApproveStep: "approve", oam.AnnotationApprovedBy: user})
+		return app
+	}
+	alice := authenticationv1.UserInfo{Username: "alice"}
+	mallory := authenticationv1.UserInfo{Username: "mallory"}
+
+	oldApp := awaiting(`["alice"]`)
+	assert.Empty(t, ValidateApproval(approve(oldApp, "alice"), oldApp, alice))
+	assert.NotEmpty(t, ValidateApproval(approve(oldApp, "mallory"), oldApp, mallory))
+	assert.NotEmpty(t, ValidateApproval(approve(oldApp, "bob"), oldApp, alice))
+
+	// the requester cannot declare themselves as an approver in the same update approving the step
+	newApp := approve(awaiting(`["alice","mallory"]`), "mallory")
+	assert.NotEmpty(t, ValidateApproval(newApp, oldApp, mallory))
+	// nor change the approvers along with an approval by an approver
+	newApp = approve(awaiting(`["alice","mallory"]`), "alice")
+	assert.NotEmpty(t, ValidateApproval(newApp, oldApp, alice))
+
+	// nor declare themselves as an approver in an update and approve the step in the next
+	added := awaiting(`["alice","mallory"]`)
+	assert.NotEmpty(t, ValidateApproval(added, oldApp, mallory))
+	// nor remove the step or change its type to skip the approval
+	removed := oldApp.DeepCopy()
+	removed.Spec.Workflow = nil
+	assert.NotEmpty(t, ValidateApproval(removed, oldApp, mallory))
+	retyped := oldApp.DeepCopy()
+	retyped.Spec.Workflow[0].Type = "suspend"
+	assert.NotEmpty(t, ValidateApproval(retyped, oldApp, mallory))
+	// while an approver may change the approvers, who may approve the step then
+	assert.Empty(t, ValidateApproval(added, oldApp, alice))
+	assert.Empty(t, ValidateApproval(approve(added, "mallory"), added, mallory))
+}
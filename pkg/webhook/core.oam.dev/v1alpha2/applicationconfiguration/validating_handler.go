@@ -36,8 +36,11 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/migration"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/impersonate"
 )
 
 const (
@@ -81,6 +84,9 @@ type ValidatingHandler struct {
 	Decoder *admission.Decoder
 
 	Validators []AppConfigValidator
+
+	// requireImpersonation requires the ApplicationConfigurations to declare the identities to impersonate
+	requireImpersonation bool
 }
 
 var _ admission.Handler = &ValidatingHandler{}
@@ -122,10 +128,17 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 		if allErrs := h.ValidateUpdate(ctx, app, oldApp); len(allErrs) > 0 {
 			return admission.Errored(http.StatusUnprocessableEntity, allErrs.ToAggregate())
 		}
+		// the identity declared by the ApplicationConfiguration is impersonated to apply its workloads and traits
+		if allErrs := impersonate.Validate(ctx, h.Client, app, oldApp, req.UserInfo, h.requireImpersonation); len(allErrs) > 0 {
+			return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+		}
 	case admissionv1beta1.Create:
 		if allErrs := h.ValidateCreate(ctx, app); len(allErrs) > 0 {
 			return admission.Errored(http.StatusUnprocessableEntity, allErrs.ToAggregate())
 		}
+		if allErrs := impersonate.Validate(ctx, h.Client, app, nil, req.UserInfo, h.requireImpersonation); len(allErrs) > 0 {
+			return admission.Errored(http.StatusForbidden, allErrs.ToAggregate())
+		}
 	default:
 		// Do nothing for CONNECT
 	}
@@ -154,6 +167,17 @@ func (h *ValidatingHandler) ValidateCreate(ctx context.Context, obj *v1alpha2.Ap
 			}
 		}
 	}
+	// only the AppConfigs that can be migrated are allowed to request the migration
+	if migration.Requested(obj) {
+		src, err := migration.Load(ctx, h.Client, h.Mapper, obj)
+		if err == nil {
+			_, _, err = migration.Convert(src)
+		}
+		if err != nil {
+			componentErrs = append(componentErrs, field.Invalid(field.NewPath("metadata", "annotations").Key(oam.AnnotationMigrate),
+				obj.GetAnnotations()[oam.AnnotationMigrate], fmt.Sprintf("cannot migrate to application, err = %s", err.Error())))
+		}
+	}
 	return componentErrs
 }
 
@@ -333,5 +357,6 @@ func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
 			AppConfigValidateFunc(ValidateTraitConflictFn),
 			// TODO(wonderflow): Add more validation logic here.
 		},
+		requireImpersonation: args.RequireImpersonation,
 	}})
 }
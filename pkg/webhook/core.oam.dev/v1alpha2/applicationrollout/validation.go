@@ -30,6 +30,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/webhook/common/rollout"
 )
@@ -61,6 +62,10 @@ func (h *ValidatingHandler) ValidateCreate(appRollout *v1beta1.AppRollout) field
 			// can't continue without target
 			return allErrs
 		}
+		if err := manifeststore.Load(context.Background(), h.Client, &targetAppRevision); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("targetAppRevisionName"), targetAppName, err.Error()))
+			return allErrs
+		}
 		sourceAppName := appRollout.Spec.SourceAppRevisionName
 		if sourceAppName != "" {
 			if err := h.Get(context.Background(), ktypes.NamespacedName{Namespace: appRollout.Namespace, Name: sourceAppName},
@@ -68,6 +73,8 @@ func (h *ValidatingHandler) ValidateCreate(appRollout *v1beta1.AppRollout) field
 				klog.ErrorS(err, "cannot locate source application revision", "source application revision",
 					klog.KRef(appRollout.Namespace, sourceAppName))
 				allErrs = append(allErrs, field.NotFound(fldPath.Child("sourceAppRevisionName"), sourceAppName))
+			} else if err := manifeststore.Load(context.Background(), h.Client, sourceAppRevision); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("sourceAppRevisionName"), sourceAppName, err.Error()))
 			}
 		} else {
 			sourceAppRevision = nil
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/pkg/errors"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -28,7 +29,10 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
@@ -38,6 +42,8 @@ var componentDefGVR = v1beta1.SchemeGroupVersion.WithResource("componentdefiniti
 // ValidatingHandler handles validation of component definition
 type ValidatingHandler struct {
 	Mapper discoverymapper.DiscoveryMapper
+	// PackageDiscover imports the packages discovered from the cluster when compiling the CUE template
+	PackageDiscover *definition.PackageDiscover
 
 	// Decoder decodes object
 	Decoder *admission.Decoder
@@ -61,6 +67,12 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 		if err != nil {
 			return admission.Denied(err.Error())
 		}
+		if err = ValidateCUETemplate(h.PackageDiscover, obj); err != nil {
+			return admission.Denied(err.Error())
+		}
+		if _, err = utils.ParseLocalizedTexts(obj.Annotations); err != nil {
+			return admission.Denied(err.Error())
+		}
 	}
 	return admission.ValidationResponse(true, "")
 }
@@ -77,7 +89,8 @@ func (h *ValidatingHandler) InjectDecoder(d *admission.Decoder) error {
 func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
 	server := mgr.GetWebhookServer()
 	server.Register("/validating-core-oam-dev-v1beta1-componentdefinitions", &webhook.Admission{Handler: &ValidatingHandler{
-		Mapper: args.DiscoveryMapper,
+		Mapper:          args.DiscoveryMapper,
+		PackageDiscover: args.PackageDiscover,
 	}})
 }
 
@@ -101,3 +114,19 @@ func ValidateWorkload(dm discoverymapper.DiscoveryMapper, cd *v1beta1.ComponentD
 	}
 	return nil
 }
+
+// ValidateCUETemplate validates whether the CUE template of the ComponentDefinition compiles and has the output and
+// parameter blocks, the definitions without CUE template are skipped
+func ValidateCUETemplate(pd *definition.PackageDiscover, cd *v1beta1.ComponentDefinition) error {
+	capability, err := appfile.ConvertTemplateJSON2Object(cd.Name, cd.Spec.Extension, cd.Spec.Schematic)
+	if err != nil {
+		return err
+	}
+	if capability.CueTemplate == "" {
+		return nil
+	}
+	if err := definition.ValidateTemplate(pd, common.ComponentType, capability.CueTemplate); err != nil {
+		return errors.WithMessagef(err, "invalid template of ComponentDefinition %s", cd.Name)
+	}
+	return nil
+}
@@ -188,5 +188,29 @@ var _ = Describe("Test ComponentDefinition validating handler", func() {
 			resp := handler.Handle(context.TODO(), req)
 			Expect(resp.Allowed).Should(BeTrue())
 		})
+
+		It("Test componentDefinition with invalid CUE template", func() {
+			for template, reason := range map[string]string{
+				"output: { kind: \"Deployment\"": "invalid cue template",
+				"parameter: { image: string }":   "the output block is missing",
+			} {
+				wrongCd := v1beta1.ComponentDefinition{}
+				wrongCd.SetGroupVersionKind(v1beta1.ComponentDefinitionGroupVersionKind)
+				wrongCd.SetName("wrongCd")
+				wrongCd.Spec.Workload.Type = "deployments.apps"
+				wrongCd.Spec.Schematic = &common.Schematic{CUE: &common.CUE{Template: template}}
+				wrongCdRaw, _ := json.Marshal(wrongCd)
+				req := admission.Request{
+					AdmissionRequest: admissionv1beta1.AdmissionRequest{
+						Operation: admissionv1beta1.Create,
+						Resource:  reqResource,
+						Object:    runtime.RawExtension{Raw: wrongCdRaw},
+					},
+				}
+				resp := handler.Handle(context.TODO(), req)
+				Expect(resp.Allowed).Should(BeFalse())
+				Expect(string(resp.Result.Reason)).Should(ContainSubstring(reason))
+			}
+		})
 	})
 })
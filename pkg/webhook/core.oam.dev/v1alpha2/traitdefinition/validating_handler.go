@@ -30,9 +30,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 )
 
@@ -117,6 +120,8 @@ func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
 		Mapper: args.DiscoveryMapper,
 		Validators: []TraitDefValidator{
 			TraitDefValidatorFn(ValidateDefinitionReference),
+			ValidateCUETemplate(args.PackageDiscover),
+			TraitDefValidatorFn(ValidateLocalizedTexts),
 			// add more validators here
 		},
 	}})
@@ -144,3 +149,27 @@ func ValidateDefinitionReference(_ context.Context, td v1beta1.TraitDefinition)
 	}
 	return nil
 }
+
+// ValidateLocalizedTexts validates the annotations of the localized titles and descriptions of the parameters
+func ValidateLocalizedTexts(_ context.Context, td v1beta1.TraitDefinition) error {
+	_, err := utils.ParseLocalizedTexts(td.Annotations)
+	return err
+}
+
+// ValidateCUETemplate returns a validator validating whether the CUE template of the trait definition compiles and has
+// the parameter block and the outputs or patch block, the definitions without CUE template are skipped
+func ValidateCUETemplate(pd *definition.PackageDiscover) TraitDefValidator {
+	return TraitDefValidatorFn(func(_ context.Context, td v1beta1.TraitDefinition) error {
+		capability, err := appfile.ConvertTemplateJSON2Object(td.Name, td.Spec.Extension, td.Spec.Schematic)
+		if err != nil {
+			return errors.WithMessage(err, errValidateDefRef)
+		}
+		if capability.CueTemplate == "" {
+			return nil
+		}
+		if err := definition.ValidateTemplate(pd, common.TraitType, capability.CueTemplate); err != nil {
+			return errors.WithMessagef(err, "invalid template of TraitDefinition %s", td.Name)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowstepdefinition
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+)
+
+var workflowStepDefGVR = v1beta1.SchemeGroupVersion.WithResource("workflowstepdefinitions")
+
+// ValidatingHandler handles validation of workflow step definition
+type ValidatingHandler struct {
+	// PackageDiscover imports the packages discovered from the cluster when compiling the CUE template
+	PackageDiscover *definition.PackageDiscover
+
+	// Decoder decodes object
+	Decoder *admission.Decoder
+}
+
+var _ admission.Handler = &ValidatingHandler{}
+
+// Handle validate workflow step definition
+func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := &v1beta1.WorkflowStepDefinition{}
+	if req.Resource.String() != workflowStepDefGVR.String() {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("expect resource to be %s", workflowStepDefGVR))
+	}
+
+	if req.Operation == admissionv1beta1.Create || req.Operation == admissionv1beta1.Update {
+		if err := h.Decoder.Decode(req, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := ValidateCUETemplate(h.PackageDiscover, obj); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.ValidationResponse(true, "")
+}
+
+var _ admission.DecoderInjector = &ValidatingHandler{}
+
+// InjectDecoder injects the decoder into the ValidatingHandler
+func (h *ValidatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.Decoder = d
+	return nil
+}
+
+// RegisterValidatingHandler will register WorkflowStepDefinition validation to webhook
+func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) {
+	server := mgr.GetWebhookServer()
+	server.Register("/validating-core-oam-dev-v1beta1-workflowstepdefinitions", &webhook.Admission{Handler: &ValidatingHandler{
+		PackageDiscover: args.PackageDiscover,
+	}})
+}
+
+// ValidateCUETemplate validates whether the CUE template of the WorkflowStepDefinition compiles, the definitions
+// without CUE template are skipped
+func ValidateCUETemplate(pd *definition.PackageDiscover, wd *v1beta1.WorkflowStepDefinition) error {
+	capability, err := appfile.ConvertTemplateJSON2Object(wd.Name, nil, wd.Spec.Schematic)
+	if err != nil {
+		return err
+	}
+	if capability.CueTemplate == "" {
+		return nil
+	}
+	if err := definition.ValidateTemplate(pd, common.WorkflowStepType, capability.CueTemplate); err != nil {
+		return errors.WithMessagef(err, "invalid template of WorkflowStepDefinition %s", wd.Name)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// TypeSuspendForApproval is the type of the workflow step suspending the workflow until one of the approvers
+// approves it, the approval is checked against the authenticated identity by the admission webhook
+const TypeSuspendForApproval = "suspend-for-approval"
+
+// TypeBlueGreenPromote is the type of the built-in workflow step promoting the newest revisions of the components
+// with the blue-green trait, it waits for approval without suspending the workflow if it declares approvers
+const TypeBlueGreenPromote = "blue-green-promote"
+
+// ApprovalSpec is the properties of the suspend-for-approval workflow step
+type ApprovalSpec struct {
+	// Users are the names of the users who may approve the step
+	Users []string `json:"users,omitempty"`
+	// Groups are the groups whose members may approve the step
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ParseApprovals parses the approvers of the suspend-for-approval workflow steps and the blue-green-promote steps
+// declaring approvers, keyed by step names
+func ParseApprovals(steps []v1beta1.WorkflowStep) (map[string]*ApprovalSpec, error) {
+	approvals := map[string]*ApprovalSpec{}
+	for _, step := range steps {
+		if step.Type != TypeSuspendForApproval && step.Type != TypeBlueGreenPromote {
+			continue
+		}
+		spec := &ApprovalSpec{}
+		if len(step.Properties.Raw) != 0 {
+			if err := json.Unmarshal(step.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+			}
+		}
+		if len(spec.Users) == 0 && len(spec.Groups) == 0 {
+			if step.Type == TypeBlueGreenPromote {
+				// the promotion is not gated
+				continue
+			}
+			return nil, errors.Errorf("workflow step %s has no approvers", step.Name)
+		}
+		approvals[step.Name] = spec
+	}
+	return approvals, nil
+}
+
+// IsApprover checks whether the user is one of the approvers, either by name or by group
+func (s *ApprovalSpec) IsApprover(user authenticationv1.UserInfo) bool {
+	for _, u := range s.Users {
+		if u == user.Username {
+			return true
+		}
+	}
+	for _, g := range s.Groups {
+		for _, ug := range user.Groups {
+			if g == ug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AwaitingApproval returns the name of the suspend-for-approval or blue-green-promote step waiting for approval,
+// it's empty if none
+func AwaitingApproval(app *v1beta1.Application) string {
+	for _, s := range app.Status.Workflow {
+		if s.Phase != common.WorkflowStepPhaseRunning {
+			continue
+		}
+		if s.Type == TypeSuspendForApproval || (s.Type == TypeBlueGreenPromote && s.Approval != nil) {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// Approve approves the suspend-for-approval workflow step of the application, the approver is recorded by the
+// admission webhook from the identity of the client
+func Approve(ctx context.Context, c client.Client, app *v1beta1.Application, stepName string) error {
+	patch := client.MergeFrom(app.DeepCopy())
+	annotations := app.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationApproveStep] = stepName
+	app.SetAnnotations(annotations)
+	return errors.Wrapf(c.Patch(ctx, app, patch), "cannot approve workflow step %s of application %s", stepName, app.Name)
+}
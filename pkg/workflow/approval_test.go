@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestParseApprovals(t *testing.T) {
+	steps := []v1beta1.WorkflowStep{
+		{Name: "deploy", Type: "apply"},
+		{Name: "approve", Type: TypeSuspendForApproval, Properties: runtime.RawExtension{Raw: []byte(`{"users":["alice"],"groups":["release-managers"]}`)}},
+	}
+	approvals, err := ParseApprovals(steps)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*ApprovalSpec{"approve": {Users: []string{"alice"}, Groups: []string{"release-managers"}}}, approvals)
+
+	approvals, err = ParseApprovals([]v1beta1.WorkflowStep{
+		{Name: "promote", Type: TypeBlueGreenPromote, Properties: runtime.RawExtension{Raw: []byte(`{"components":["web"],"users":["alice"]}`)}},
+		{Name: "promote-api", Type: TypeBlueGreenPromote, Properties: runtime.RawExtension{Raw: []byte(`{"components":["api"]}`)}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*ApprovalSpec{"promote": {Users: []string{"alice"}}}, approvals)
+
+	_, err = ParseApprovals([]v1beta1.WorkflowStep{{Name: "approve", Type: TypeSuspendForApproval}})
+	assert.Error(t, err)
+	_, err = ParseApprovals([]v1beta1.WorkflowStep{{Name: "approve", Type: TypeSuspendForApproval, Properties: runtime.RawExtension{Raw: []byte(`{"users":"alice"}`)}}})
+	assert.Error(t, err)
+}
+
+func TestIsApprover(t *testing.T) {
+	spec := &ApprovalSpec{Users: []string{"alice"}, Groups: []string{"release-managers"}}
+	assert.True(t, spec.IsApprover(authenticationv1.UserInfo{Username: "alice"}))
+	assert.True(t, spec.IsApprover(authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated", "release-managers"}}))
+	assert.False(t, spec.IsApprover(authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated"}}))
+}
+
+func TestAwaitingApproval(t *testing.T) {
+	app := &v1beta1.Application{}
+	assert.Equal(t, "", AwaitingApproval(app))
+	app.Status.Workflow = []common.WorkflowStepStatus{
+		{Name: "deploy", Type: "apply", Phase: common.WorkflowStepPhaseSucceeded},
+		{Name: "approve", Type: TypeSuspendForApproval, Phase: common.WorkflowStepPhaseRunning},
+	}
+	assert.Equal(t, "approve", AwaitingApproval(app))
+	app.Status.Workflow[1].Phase = common.WorkflowStepPhaseSucceeded
+	assert.Equal(t, "", AwaitingApproval(app))
+
+	app.Status.Workflow = append(app.Status.Workflow, common.WorkflowStepStatus{Name: "promote", Type: TypeBlueGreenPromote, Phase: common.WorkflowStepPhaseRunning})
+	assert.Equal(t, "", AwaitingApproval(app))
+	app.Status.Workflow[2].Approval = &common.WorkflowStepApproval{Users: []string{"alice"}}
+	assert.Equal(t, "promote", AwaitingApproval(app))
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// conditionResultField is the field the if expression of a step is evaluated into
+const conditionResultField = "result"
+
+// statusSelector matches the selectors of step names in if expressions, step names are usually not CUE identifiers,
+// e.g., status.deploy-staging.succeeded
+var statusSelector = regexp.MustCompile(`\bstatus\.([A-Za-z0-9_-]+)`)
+
+// StepConditionStatus is the status of a step referred by status.<step name> in the if expressions
+type StepConditionStatus struct {
+	Phase     common.WorkflowStepPhase `json:"phase"`
+	Succeeded bool                     `json:"succeeded"`
+	Failed    bool                     `json:"failed"`
+	Skipped   bool                     `json:"skipped"`
+}
+
+// StepConditionContext is the application context referred by context.* in the if expressions
+type StepConditionContext struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Env       string            `json:"env"`
+	Revision  string            `json:"revision"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// normalizeCondition turns the selectors of step names into index expressions, e.g., status["deploy-staging"]
+func normalizeCondition(expr string) string {
+	return statusSelector.ReplaceAllString(expr, `status["$1"]`)
+}
+
+// ValidateStepConditions checks the syntax of the if expressions of the workflow steps
+func ValidateStepConditions(steps []v1beta1.WorkflowStep) error {
+	for _, step := range steps {
+		if len(step.If) == 0 {
+			continue
+		}
+		if _, err := parser.ParseExpr("if", normalizeCondition(step.If)); err != nil {
+			return errors.Wrapf(err, "invalid if expression of workflow step %s", step.Name)
+		}
+	}
+	return nil
+}
+
+// EvaluateStepCondition evaluates the if expression of the workflow step against the statuses of the steps, the
+// context of the application and the properties of the step as inputs
+func EvaluateStepCondition(app *v1beta1.Application, step v1beta1.WorkflowStep) (bool, error) {
+	if len(step.If) == 0 {
+		return true, nil
+	}
+	statuses := make(map[string]StepConditionStatus, len(app.Spec.Workflow))
+	for _, s := range app.Spec.Workflow {
+		statuses[s.Name] = StepConditionStatus{}
+	}
+	for _, s := range app.Status.Workflow {
+		statuses[s.Name] = StepConditionStatus{
+			Phase:     s.Phase,
+			Succeeded: s.Phase == common.WorkflowStepPhaseSucceeded,
+			Failed:    IsStepFailed(s.Phase),
+			Skipped:   s.Phase == common.WorkflowStepPhaseSkipped,
+		}
+	}
+	ctx := StepConditionContext{
+		Name:      app.Name,
+		Namespace: app.Namespace,
+		Env:       app.Spec.Environment,
+		Labels:    app.Labels,
+	}
+	if app.Status.LatestRevision != nil {
+		ctx.Revision = app.Status.LatestRevision.Name
+	}
+	inputs := map[string]interface{}{}
+	if len(step.Properties.Raw) != 0 {
+		if err := json.Unmarshal(step.Properties.Raw, &inputs); err != nil {
+			return false, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+		}
+	}
+	var buff string
+	for name, v := range map[string]interface{}{"status": statuses, "context": ctx, "inputs": inputs} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return false, err
+		}
+		buff += name + ": " + string(b) + "\n"
+	}
+	buff += conditionResultField + ": " + normalizeCondition(step.If) + "\n"
+	var r cue.Runtime
+	inst, err := r.Compile("-", buff)
+	if err != nil {
+		return false, errors.Wrapf(err, "compile if expression of workflow step %s", step.Name)
+	}
+	ok, err := inst.Lookup(conditionResultField).Bool()
+	if err != nil {
+		return false, errors.Wrapf(err, "evaluate if expression of workflow step %s", step.Name)
+	}
+	return ok, nil
+}
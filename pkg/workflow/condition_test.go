@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestEvaluateStepCondition(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "web"}},
+		Spec: v1beta1.ApplicationSpec{
+			Environment: "prod",
+			Workflow: []v1beta1.WorkflowStep{
+				{Name: "deploy-staging", Type: "deploy"},
+				{Name: "verify", Type: "verify"},
+				{Name: "deploy-prod", Type: "deploy", Properties: runtime.RawExtension{Raw: []byte(`{"replicas":3}`)}},
+			},
+		},
+		Status: common.AppStatus{Workflow: []common.WorkflowStepStatus{
+			{Name: "deploy-staging", Phase: common.WorkflowStepPhaseSucceeded},
+			{Name: "verify", Phase: common.WorkflowStepPhaseSkipped},
+		}},
+	}
+	testCases := map[string]struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		"empty":          {expr: "", want: true},
+		"step succeeded": {expr: `status.deploy-staging.succeeded && context.env == "prod"`, want: true},
+		"step skipped":   {expr: `status.verify.skipped && !status.verify.failed`, want: true},
+		"step not run":   {expr: `status["deploy-prod"].phase == ""`, want: true},
+		"other env":      {expr: `context.env == "staging"`, want: false},
+		"labels":         {expr: `context.labels.team == "web"`, want: true},
+		"inputs":         {expr: `inputs.replicas > 1`, want: true},
+		"not a bool":     {expr: `context.name`, wantErr: true},
+		"unknown step":   {expr: `status.missing.succeeded`, wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			step := app.Spec.Workflow[2]
+			step.If = tc.expr
+			got, err := EvaluateStepCondition(app, step)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateStepConditions(t *testing.T) {
+	assert.NoError(t, ValidateStepConditions([]v1beta1.WorkflowStep{{Name: "deploy", If: `status.deploy-staging.succeeded`}}))
+	assert.Error(t, ValidateStepConditions([]v1beta1.WorkflowStep{{Name: "deploy", If: `status.deploy-staging.succeeded &&`}}))
+}
+
+func TestCurrentStepSkipped(t *testing.T) {
+	app := &v1beta1.Application{
+		Spec: v1beta1.ApplicationSpec{Workflow: []v1beta1.WorkflowStep{{Name: "verify"}, {Name: "deploy"}}},
+		Status: common.AppStatus{Workflow: []common.WorkflowStepStatus{
+			{Name: "verify", Phase: common.WorkflowStepPhaseSkipped},
+		}},
+	}
+	assert.Equal(t, "deploy", CurrentStep(app))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ContextName returns the name of the ConfigMap persisting the workflow context of the application, i.e., the
+// outputs exported by the workflow steps, so they survive the restarts of the controller
+func ContextName(appName string) string {
+	return appName + "-workflow-context"
+}
+
+// LoadOutputs loads the outputs exported by the workflow steps of the application, keyed by output names
+func LoadOutputs(ctx context.Context, c client.Reader, app *v1beta1.Application) (map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: ContextName(app.Name)}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]string{}, nil
+		}
+		return nil, errors.Wrapf(err, "cannot load the workflow context of application %s", app.Name)
+	}
+	outputs := make(map[string]string, len(cm.Data))
+	for k, v := range cm.Data {
+		outputs[k] = v
+	}
+	return outputs, nil
+}
+
+// NewContextConfigMap returns the ConfigMap persisting the outputs exported by the workflow steps of the application,
+// it's owned by the application so it's deleted together
+func NewContextConfigMap(app *v1beta1.Application, outputs map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ContextName(app.Name),
+			Namespace: app.Namespace,
+			Labels:    map[string]string{oam.LabelAppName: app.Name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1beta1.SchemeGroupVersion.String(),
+				Kind:       v1beta1.ApplicationKind,
+				Name:       app.Name,
+				UID:        app.UID,
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Data: outputs,
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// ValidateStepIO checks the outputs and inputs of the workflow steps, the outputs are unique in the workflow and the
+// inputs import the outputs of the previous steps
+func ValidateStepIO(steps []v1beta1.WorkflowStep) error {
+	outputs := map[string]bool{}
+	for _, step := range steps {
+		for _, in := range step.Inputs {
+			if !outputs[in.From] {
+				return errors.Errorf("input %s of workflow step %s is not exported by the previous steps", in.From, step.Name)
+			}
+			if len(in.ParameterKey) == 0 {
+				return errors.Errorf("the parameterKey of input %s of workflow step %s is required", in.From, step.Name)
+			}
+		}
+		for _, out := range step.Outputs {
+			if outputs[out.Name] {
+				return errors.Errorf("output %s of workflow step %s is already exported by another step", out.Name, step.Name)
+			}
+			if _, err := parser.ParseExpr("valueFrom", out.ValueFrom); err != nil {
+				return errors.Wrapf(err, "invalid valueFrom of output %s of workflow step %s", out.Name, step.Name)
+			}
+			outputs[out.Name] = true
+		}
+	}
+	return nil
+}
+
+// ExtractOutputs evaluates the outputs of the step against the resource it refers to, the values are encoded in JSON
+// so they keep their types when imported by the following steps. Keyed by output names.
+func ExtractOutputs(step v1beta1.WorkflowStep, obj *unstructured.Unstructured) (map[string]string, error) {
+	if len(step.Outputs) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "compile %s %s referred by workflow step %s", obj.GetKind(), obj.GetName(), step.Name)
+	}
+	outputs := make(map[string]string, len(step.Outputs))
+	for _, out := range step.Outputs {
+		expr, err := parser.ParseExpr("valueFrom", out.ValueFrom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid valueFrom of output %s", out.Name)
+		}
+		v, err := inst.Eval(expr).MarshalJSON()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot export output %s from %s of %s %s", out.Name, out.ValueFrom, obj.GetKind(), obj.GetName())
+		}
+		outputs[out.Name] = string(v)
+	}
+	return outputs, nil
+}
+
+// ResolveInputs sets the exported outputs imported by the step into its properties, the step is returned as it is
+// with the names of the outputs not exported yet if any of them is missing
+func ResolveInputs(step v1beta1.WorkflowStep, outputs map[string]string) (v1beta1.WorkflowStep, []string, error) {
+	if len(step.Inputs) == 0 {
+		return step, nil, nil
+	}
+	var missing []string
+	for _, in := range step.Inputs {
+		if _, ok := outputs[in.From]; !ok {
+			missing = append(missing, in.From)
+		}
+	}
+	if len(missing) != 0 {
+		return step, missing, nil
+	}
+	props := map[string]interface{}{}
+	if len(step.Properties.Raw) != 0 {
+		if err := json.Unmarshal(step.Properties.Raw, &props); err != nil {
+			return step, nil, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+		}
+	}
+	for _, in := range step.Inputs {
+		var v interface{}
+		if err := json.Unmarshal([]byte(outputs[in.From]), &v); err != nil {
+			return step, nil, errors.Wrapf(err, "invalid value of output %s", in.From)
+		}
+		if err := unstructured.SetNestedField(props, v, strings.Split(in.ParameterKey, ".")...); err != nil {
+			return step, nil, errors.Wrapf(err, "cannot set input %s to %s of workflow step %s", in.From, in.ParameterKey, step.Name)
+		}
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return step, nil, err
+	}
+	resolved := *step.DeepCopy()
+	resolved.Properties = runtime.RawExtension{Raw: b}
+	return resolved, nil, nil
+}
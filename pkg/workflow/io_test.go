@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestValidateStepIO(t *testing.T) {
+	producer := v1beta1.WorkflowStep{Name: "db", Outputs: []v1beta1.WorkflowStepOutput{{Name: "endpoint", ValueFrom: "status.services[0].name"}}}
+	consumer := v1beta1.WorkflowStep{Name: "web", Inputs: []v1beta1.WorkflowStepInput{{From: "endpoint", ParameterKey: "env.DB"}}}
+	assert.NoError(t, ValidateStepIO([]v1beta1.WorkflowStep{producer, consumer}))
+	assert.Error(t, ValidateStepIO([]v1beta1.WorkflowStep{consumer, producer}), "inputs of the following steps")
+	assert.Error(t, ValidateStepIO([]v1beta1.WorkflowStep{producer, producer}), "duplicated outputs")
+
+	producer.Outputs[0].ValueFrom = "status.services["
+	assert.Error(t, ValidateStepIO([]v1beta1.WorkflowStep{producer}))
+}
+
+func TestExtractOutputs(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "core.oam.dev/v1beta1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": "db", "labels": map[string]interface{}{"app.oam.dev/name": "db"}},
+		"status": map[string]interface{}{
+			"services": []interface{}{map[string]interface{}{"name": "mysql", "healthy": true}},
+			"replicas": int64(3),
+		},
+	}}
+	step := v1beta1.WorkflowStep{Name: "db", Outputs: []v1beta1.WorkflowStepOutput{
+		{Name: "service", ValueFrom: "status.services[0].name"},
+		{Name: "healthy", ValueFrom: "status.services[0].healthy"},
+		{Name: "replicas", ValueFrom: "status.replicas"},
+		{Name: "label", ValueFrom: `metadata.labels["app.oam.dev/name"]`},
+	}}
+	outputs, err := ExtractOutputs(step, obj)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"service": `"mysql"`, "healthy": "true", "replicas": "3", "label": `"db"`}, outputs)
+
+	step.Outputs = []v1beta1.WorkflowStepOutput{{Name: "missing", ValueFrom: "status.endpoint"}}
+	_, err = ExtractOutputs(step, obj)
+	assert.Error(t, err)
+}
+
+func TestResolveInputs(t *testing.T) {
+	step := v1beta1.WorkflowStep{
+		Name:       "web",
+		Properties: runtime.RawExtension{Raw: []byte(`{"name":"web","config":{"debug":true}}`)},
+		Inputs: []v1beta1.WorkflowStepInput{
+			{From: "service", ParameterKey: "config.db"},
+			{From: "replicas", ParameterKey: "replicas"},
+		},
+	}
+	resolved, missing, err := ResolveInputs(step, map[string]string{"service": `"mysql"`})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"replicas"}, missing)
+	assert.Equal(t, step, resolved)
+
+	resolved, missing, err = ResolveInputs(step, map[string]string{"service": `"mysql"`, "replicas": "3"})
+	assert.NoError(t, err)
+	assert.Empty(t, missing)
+	assert.JSONEq(t, `{"name":"web","replicas":3,"config":{"debug":true,"db":"mysql"}}`, string(resolved.Properties.Raw))
+	assert.JSONEq(t, `{"name":"web","config":{"debug":true}}`, string(step.Properties.Raw))
+}
+
+func TestLoadOutputs(t *testing.T) {
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	outputs, err := LoadOutputs(context.Background(), c, app)
+	assert.NoError(t, err)
+	assert.Empty(t, outputs)
+
+	cm := NewContextConfigMap(app, map[string]string{"service": `"mysql"`})
+	assert.Equal(t, "app-workflow-context", cm.Name)
+	assert.NoError(t, c.Create(context.Background(), cm))
+	outputs, err = LoadOutputs(context.Background(), c, app)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"service": `"mysql"`}, outputs)
+}
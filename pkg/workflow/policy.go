@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// maxBackoffShift caps the exponential backoff between retries, so the backoff doesn't overflow
+const maxBackoffShift = 16
+
+// StepPolicy is the timeout and retry policy of a workflow step
+type StepPolicy struct {
+	// Timeout is the duration each attempt of the step is allowed to run, it runs until it finishes if zero
+	Timeout time.Duration
+	// MaxRetries is the maximum number of times the step is retried
+	MaxRetries int
+	// Backoff is the duration to wait before the first retry, it doubles for each following retry
+	Backoff time.Duration
+	// OnFailure is the step the workflow continues with if the step still fails after all the retries
+	OnFailure string
+}
+
+// ParseStepPolicies parses the timeout and retry policies of the workflow steps, keyed by the step names.
+// The timeout and retry policy of a step default to the ones in the WorkflowStepDefinition of its type if any.
+func ParseStepPolicies(steps []v1beta1.WorkflowStep, defs map[string]*v1beta1.WorkflowStepDefinition) (map[string]*StepPolicy, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.Name] = i
+	}
+	policies := make(map[string]*StepPolicy, len(steps))
+	for i, step := range steps {
+		timeout, retryPolicy := step.Timeout, step.RetryPolicy
+		if def := defs[step.Type]; def != nil {
+			if len(timeout) == 0 {
+				timeout = def.Spec.Timeout
+			}
+			if retryPolicy == nil {
+				retryPolicy = def.Spec.RetryPolicy
+			}
+		}
+		policy, err := newStepPolicy(timeout, retryPolicy)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid policy of workflow step %s", step.Name)
+		}
+		if len(step.OnFailure) != 0 {
+			if j, ok := index[step.OnFailure]; !ok || j <= i {
+				return nil, errors.Errorf("onFailure of workflow step %s must be one of the following steps, got %q", step.Name, step.OnFailure)
+			}
+			policy.OnFailure = step.OnFailure
+		}
+		policies[step.Name] = policy
+	}
+	return policies, nil
+}
+
+func newStepPolicy(timeout string, retryPolicy *common.WorkflowStepRetryPolicy) (*StepPolicy, error) {
+	policy := &StepPolicy{}
+	if len(timeout) != 0 {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid timeout %q", timeout)
+		}
+		policy.Timeout = d
+	}
+	if retryPolicy == nil {
+		return policy, nil
+	}
+	if retryPolicy.MaxRetries < 0 {
+		return nil, errors.Errorf("maxRetries cannot be negative, got %d", retryPolicy.MaxRetries)
+	}
+	policy.MaxRetries = retryPolicy.MaxRetries
+	if len(retryPolicy.Backoff) != 0 {
+		d, err := time.ParseDuration(retryPolicy.Backoff)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid backoff %q", retryPolicy.Backoff)
+		}
+		policy.Backoff = d
+	}
+	return policy, nil
+}
+
+// backoff returns the duration to wait before the retry after the given number of retries
+func (p *StepPolicy) backoff(retries int) time.Duration {
+	if retries > maxBackoffShift {
+		retries = maxBackoffShift
+	}
+	return p.Backoff << uint(retries)
+}
+
+// EnforceStepPolicy enforces the timeout and retry policy on the status of a started step at the given time.
+// A running step exceeding its timeout times out, and a failed or timed out step is retrying until it's running
+// again after the backoff, or stays failed once all the retries are used up.
+// It returns the duration after which the step needs to be checked again, it's zero if there's nothing to wait for.
+func EnforceStepPolicy(policy *StepPolicy, status *common.WorkflowStepStatus, now metav1.Time) time.Duration {
+	switch status.Phase {
+	case common.WorkflowStepPhaseRunning:
+		if status.StartedAt == nil {
+			status.StartedAt = &now
+		}
+		if policy.Timeout == 0 {
+			return 0
+		}
+		if elapsed := now.Sub(status.StartedAt.Time); elapsed < policy.Timeout {
+			return policy.Timeout - elapsed
+		}
+		status.Phase = common.WorkflowStepPhaseTimedOut
+		status.Message = fmt.Sprintf("still running after the timeout %s", policy.Timeout)
+		status.FailedAt = &now
+	case common.WorkflowStepPhaseFailed, common.WorkflowStepPhaseTimedOut, common.WorkflowStepPhaseRetrying:
+		if status.FailedAt == nil {
+			status.FailedAt = &now
+		}
+	default:
+		return 0
+	}
+	if status.Retries >= policy.MaxRetries {
+		if status.Phase == common.WorkflowStepPhaseRetrying {
+			// the retry policy is changed while the step is waiting for a retry
+			status.Phase = common.WorkflowStepPhaseFailed
+		}
+		return 0
+	}
+	if wait := policy.backoff(status.Retries) - now.Sub(status.FailedAt.Time); wait > 0 {
+		if status.Phase != common.WorkflowStepPhaseRetrying {
+			reason := status.Message
+			if len(reason) == 0 {
+				reason = string(status.Phase)
+			}
+			status.Message = fmt.Sprintf("%s, retry %d/%d in %s", reason, status.Retries+1, policy.MaxRetries, wait.Round(time.Second))
+			status.Phase = common.WorkflowStepPhaseRetrying
+		}
+		return wait
+	}
+	status.Retries++
+	status.Phase = common.WorkflowStepPhaseRunning
+	status.Message = fmt.Sprintf("retry %d/%d", status.Retries, policy.MaxRetries)
+	status.StartedAt = &now
+	status.FailedAt = nil
+	return policy.Timeout
+}
+
+// IsStepFailed returns true if the step failed or timed out and won't be retried any more
+func IsStepFailed(phase common.WorkflowStepPhase) bool {
+	return phase == common.WorkflowStepPhaseFailed || phase == common.WorkflowStepPhaseTimedOut
+}
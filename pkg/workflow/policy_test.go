@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func TestParseStepPolicies(t *testing.T) {
+	defs := map[string]*v1beta1.WorkflowStepDefinition{
+		"apply": {Spec: v1beta1.WorkflowStepDefinitionSpec{
+			Timeout:     "10m",
+			RetryPolicy: &common.WorkflowStepRetryPolicy{MaxRetries: 1},
+		}},
+	}
+	steps := []v1beta1.WorkflowStep{
+		{Name: "deploy", Type: "apply", OnFailure: "rollback"},
+		{Name: "verify", Type: "apply", Timeout: "1m", RetryPolicy: &common.WorkflowStepRetryPolicy{MaxRetries: 3, Backoff: "10s"}},
+		{Name: "rollback", Type: "notify"},
+	}
+	policies, err := ParseStepPolicies(steps, defs)
+	assert.NoError(t, err)
+	assert.Equal(t, &StepPolicy{Timeout: 10 * time.Minute, MaxRetries: 1, OnFailure: "rollback"}, policies["deploy"])
+	assert.Equal(t, &StepPolicy{Timeout: time.Minute, MaxRetries: 3, Backoff: 10 * time.Second}, policies["verify"])
+	assert.Equal(t, &StepPolicy{}, policies["rollback"])
+
+	_, err = ParseStepPolicies([]v1beta1.WorkflowStep{{Name: "deploy", Timeout: "ten minutes"}}, nil)
+	assert.Error(t, err)
+	_, err = ParseStepPolicies([]v1beta1.WorkflowStep{{Name: "deploy", RetryPolicy: &common.WorkflowStepRetryPolicy{MaxRetries: -1}}}, nil)
+	assert.Error(t, err)
+	_, err = ParseStepPolicies([]v1beta1.WorkflowStep{{Name: "deploy", OnFailure: "deploy"}}, nil)
+	assert.Error(t, err)
+	_, err = ParseStepPolicies([]v1beta1.WorkflowStep{{Name: "deploy", OnFailure: "missing"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestEnforceStepPolicy(t *testing.T) {
+	policy := &StepPolicy{Timeout: time.Minute, MaxRetries: 1, Backoff: 10 * time.Second}
+	start := metav1.NewTime(time.Now())
+	status := &common.WorkflowStepStatus{Name: "deploy", Phase: common.WorkflowStepPhaseRunning}
+
+	// the step is running within the timeout
+	assert.Equal(t, time.Minute, EnforceStepPolicy(policy, status, start))
+	assert.Equal(t, &start, status.StartedAt)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+
+	// the step times out and waits for the backoff of the retry
+	timeout := metav1.NewTime(start.Add(time.Minute))
+	assert.Equal(t, 10*time.Second, EnforceStepPolicy(policy, status, timeout))
+	assert.Equal(t, common.WorkflowStepPhaseRetrying, status.Phase)
+	assert.Contains(t, status.Message, "retry 1/1")
+
+	// the step is retried after the backoff
+	retry := metav1.NewTime(timeout.Add(10 * time.Second))
+	assert.Equal(t, time.Minute, EnforceStepPolicy(policy, status, retry))
+	assert.Equal(t, common.WorkflowStepPhaseRunning, status.Phase)
+	assert.Equal(t, 1, status.Retries)
+	assert.Equal(t, &retry, status.StartedAt)
+	assert.Nil(t, status.FailedAt)
+
+	// the step fails again and all the retries are used up
+	status.Phase = common.WorkflowStepPhaseFailed
+	failed := metav1.NewTime(retry.Add(time.Second))
+	assert.Equal(t, time.Duration(0), EnforceStepPolicy(policy, status, failed))
+	assert.Equal(t, common.WorkflowStepPhaseFailed, status.Phase)
+	assert.Equal(t, &failed, status.FailedAt)
+
+	// the succeeded step is kept as it is
+	status = &common.WorkflowStepStatus{Name: "deploy", Phase: common.WorkflowStepPhaseSucceeded}
+	assert.Equal(t, time.Duration(0), EnforceStepPolicy(policy, status, start))
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, status.Phase)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// TypeStepGroup is the type of the built-in workflow step executing its sub-steps concurrently, e.g., to deploy to
+// many clusters at the same time. It succeeds once all its sub-steps succeed and fails once any of them fails.
+const TypeStepGroup = "step-group"
+
+// StepGroupSpec is the properties of the step-group step
+type StepGroupSpec struct {
+	// Concurrency is the maximum number of sub-steps running at the same time, all the sub-steps run at the same
+	// time if zero
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// ParseStepGroups validates the step-group steps of the workflow and returns their concurrency limits keyed by the
+// step names
+func ParseStepGroups(steps []v1beta1.WorkflowStep) (map[string]int, error) {
+	groups := map[string]int{}
+	for _, step := range steps {
+		if step.Type != TypeStepGroup {
+			if len(step.SubSteps) != 0 {
+				return nil, errors.Errorf("workflow step %s of type %s cannot have sub-steps, only %s can", step.Name, step.Type, TypeStepGroup)
+			}
+			continue
+		}
+		spec := &StepGroupSpec{}
+		if len(step.Properties.Raw) != 0 {
+			if err := json.Unmarshal(step.Properties.Raw, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid properties of workflow step %s", step.Name)
+			}
+		}
+		if spec.Concurrency < 0 {
+			return nil, errors.Errorf("invalid concurrency %d of workflow step %s", spec.Concurrency, step.Name)
+		}
+		if len(step.SubSteps) == 0 {
+			return nil, errors.Errorf("workflow step %s has no sub-steps", step.Name)
+		}
+		names := map[string]bool{}
+		for _, sub := range step.SubSteps {
+			if sub.Type == TypeStepGroup {
+				return nil, errors.Errorf("sub-step %s of workflow step %s cannot be a %s", sub.Name, step.Name, TypeStepGroup)
+			}
+			if names[sub.Name] {
+				return nil, errors.Errorf("duplicated sub-step %s in workflow step %s", sub.Name, step.Name)
+			}
+			names[sub.Name] = true
+		}
+		groups[step.Name] = spec.Concurrency
+	}
+	return groups, nil
+}
+
+// ScheduleSubSteps starts the sub-steps of the step group as long as the number of running ones is under the
+// concurrency limit, the others are pending. The failed sub-steps are started again if the group is running, i.e.,
+// the group is retried by its retry policy.
+func ScheduleSubSteps(group v1beta1.WorkflowStep, concurrency int, status *common.WorkflowStepStatus) {
+	last := make(map[string]common.WorkflowSubStepStatus, len(status.SubSteps))
+	for _, s := range status.SubSteps {
+		last[s.Name] = s
+	}
+	retried := status.Phase == common.WorkflowStepPhaseRunning
+	running := 0
+	subSteps := make([]common.WorkflowSubStepStatus, 0, len(group.SubSteps))
+	for _, sub := range group.SubSteps {
+		s, ok := last[sub.Name]
+		if !ok || (retried && IsStepFailed(s.Phase)) {
+			s = common.WorkflowSubStepStatus{Name: sub.Name, Type: sub.Type, Phase: common.WorkflowStepPhasePending}
+		}
+		if s.Phase == common.WorkflowStepPhaseRunning {
+			running++
+		}
+		subSteps = append(subSteps, s)
+	}
+	for i := range subSteps {
+		if subSteps[i].Phase != common.WorkflowStepPhasePending {
+			continue
+		}
+		if concurrency > 0 && running >= concurrency {
+			subSteps[i].Message = "waiting for the other sub-steps under the concurrency limit"
+			continue
+		}
+		subSteps[i].Phase = common.WorkflowStepPhaseRunning
+		subSteps[i].Message = ""
+		running++
+	}
+	status.SubSteps = subSteps
+}
+
+// SubStepStatus returns the status of the sub-step of the step group, it's nil if the sub-step is not scheduled
+func SubStepStatus(status *common.WorkflowStepStatus, subStepName string) *common.WorkflowSubStepStatus {
+	for i := range status.SubSteps {
+		if status.SubSteps[i].Name == subStepName {
+			return &status.SubSteps[i]
+		}
+	}
+	return nil
+}
+
+// StepGroupPhase aggregates the phases of the sub-steps into the phase of the step group: it's failed once any
+// sub-step fails, succeeded once all the sub-steps succeed and running otherwise
+func StepGroupPhase(status *common.WorkflowStepStatus) (common.WorkflowStepPhase, string) {
+	phase := common.WorkflowStepPhaseSucceeded
+	for _, s := range status.SubSteps {
+		if IsStepFailed(s.Phase) {
+			return common.WorkflowStepPhaseFailed, "sub-step " + s.Name + " failed: " + s.Message
+		}
+		if s.Phase != common.WorkflowStepPhaseSucceeded {
+			phase = common.WorkflowStepPhaseRunning
+		}
+	}
+	return phase, ""
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+func newStepGroup(subSteps ...string) v1beta1.WorkflowStep {
+	group := v1beta1.WorkflowStep{
+		Name:       "deploy-clusters",
+		Type:       TypeStepGroup,
+		Properties: runtime.RawExtension{Raw: []byte(`{"concurrency":2}`)},
+	}
+	for _, name := range subSteps {
+		group.SubSteps = append(group.SubSteps, v1beta1.WorkflowSubStep{Name: name, Type: "deploy"})
+	}
+	return group
+}
+
+func TestParseStepGroups(t *testing.T) {
+	groups, err := ParseStepGroups([]v1beta1.WorkflowStep{
+		newStepGroup("beijing", "hangzhou"),
+		{Name: "notify", Type: "webhook"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"deploy-clusters": 2}, groups)
+
+	_, err = ParseStepGroups([]v1beta1.WorkflowStep{newStepGroup()})
+	assert.Error(t, err)
+	_, err = ParseStepGroups([]v1beta1.WorkflowStep{newStepGroup("beijing", "beijing")})
+	assert.Error(t, err)
+	nested := newStepGroup("beijing")
+	nested.SubSteps[0].Type = TypeStepGroup
+	_, err = ParseStepGroups([]v1beta1.WorkflowStep{nested})
+	assert.Error(t, err)
+	_, err = ParseStepGroups([]v1beta1.WorkflowStep{{Name: "notify", Type: "webhook", SubSteps: newStepGroup("beijing").SubSteps}})
+	assert.Error(t, err)
+	negative := newStepGroup("beijing")
+	negative.Properties.Raw = []byte(`{"concurrency":-1}`)
+	_, err = ParseStepGroups([]v1beta1.WorkflowStep{negative})
+	assert.Error(t, err)
+}
+
+func TestScheduleSubSteps(t *testing.T) {
+	group := newStepGroup("beijing", "hangzhou", "shanghai")
+	phases := func(status *common.WorkflowStepStatus) []common.WorkflowStepPhase {
+		var phases []common.WorkflowStepPhase
+		for _, s := range status.SubSteps {
+			phases = append(phases, s.Phase)
+		}
+		return phases
+	}
+	status := &common.WorkflowStepStatus{Name: group.Name, Phase: common.WorkflowStepPhaseRunning}
+	ScheduleSubSteps(group, 2, status)
+	assert.Equal(t, []common.WorkflowStepPhase{common.WorkflowStepPhaseRunning, common.WorkflowStepPhaseRunning, common.WorkflowStepPhasePending}, phases(status))
+	phase, _ := StepGroupPhase(status)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, phase)
+
+	// a finished sub-step frees its slot
+	SubStepStatus(status, "beijing").Phase = common.WorkflowStepPhaseSucceeded
+	ScheduleSubSteps(group, 2, status)
+	assert.Equal(t, []common.WorkflowStepPhase{common.WorkflowStepPhaseSucceeded, common.WorkflowStepPhaseRunning, common.WorkflowStepPhaseRunning}, phases(status))
+
+	// the group fails once any sub-step fails
+	SubStepStatus(status, "hangzhou").Phase = common.WorkflowStepPhaseFailed
+	phase, msg := StepGroupPhase(status)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, phase)
+	assert.Contains(t, msg, "hangzhou")
+
+	// the failed sub-step is started again once the group is retried
+	status.Phase = common.WorkflowStepPhaseFailed
+	ScheduleSubSteps(group, 2, status)
+	assert.Equal(t, common.WorkflowStepPhaseFailed, SubStepStatus(status, "hangzhou").Phase)
+	status.Phase = common.WorkflowStepPhaseRunning
+	ScheduleSubSteps(group, 2, status)
+	assert.Equal(t, common.WorkflowStepPhaseRunning, SubStepStatus(status, "hangzhou").Phase)
+
+	// the group succeeds once all the sub-steps succeed
+	for i := range status.SubSteps {
+		status.SubSteps[i].Phase = common.WorkflowStepPhaseSucceeded
+	}
+	phase, _ = StepGroupPhase(status)
+	assert.Equal(t, common.WorkflowStepPhaseSucceeded, phase)
+
+	// all the sub-steps run at the same time without a concurrency limit
+	status = &common.WorkflowStepStatus{Name: group.Name, Phase: common.WorkflowStepPhaseRunning}
+	ScheduleSubSteps(group, 0, status)
+	assert.Equal(t, []common.WorkflowStepPhase{common.WorkflowStepPhaseRunning, common.WorkflowStepPhaseRunning, common.WorkflowStepPhaseRunning}, phases(status))
+}
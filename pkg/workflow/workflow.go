@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// Suspend suspends the workflow of the application before its next step, the application controller stops applying
+// resources of the application until it's resumed
+func Suspend(ctx context.Context, c client.Client, app *v1beta1.Application, reason string) error {
+	if IsSuspended(app) && app.Spec.WorkflowSuspend.Reason == reason {
+		return nil
+	}
+	patch := client.MergeFrom(app.DeepCopy())
+	app.Spec.WorkflowSuspend = &common.WorkflowSuspend{Reason: reason}
+	return errors.Wrapf(c.Patch(ctx, app, patch), "cannot suspend the workflow of application %s", app.Name)
+}
+
+// Resume resumes the suspended workflow of the application from the step it's suspended before
+func Resume(ctx context.Context, c client.Client, app *v1beta1.Application) error {
+	if !IsSuspended(app) {
+		return nil
+	}
+	patch := client.MergeFrom(app.DeepCopy())
+	app.Spec.WorkflowSuspend = nil
+	return errors.Wrapf(c.Patch(ctx, app, patch), "cannot resume the workflow of application %s", app.Name)
+}
+
+// Restart requests the workflow of the application to restart from the first step, the application controller
+// clears the status of the steps and re-syncs the Git components, see oam.AnnotationRestartWorkflow
+func Restart(ctx context.Context, c client.Client, app *v1beta1.Application, reason string) error {
+	patch := client.MergeFrom(app.DeepCopy())
+	RequestRestart(app, reason)
+	return errors.Wrapf(c.Patch(ctx, app, patch), "cannot restart the workflow of application %s", app.Name)
+}
+
+// RequestRestart sets the annotation requesting the workflow of the application to restart in place, so it can be
+// patched along with the other changes to the application
+func RequestRestart(app *v1beta1.Application, reason string) {
+	if len(reason) == 0 {
+		reason = "restarted"
+	}
+	annotations := app.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[oam.AnnotationRestartWorkflow] = reason
+	app.SetAnnotations(annotations)
+}
+
+// IsRestartRequested returns true if the workflow of the application is requested to restart from the first step
+func IsRestartRequested(app *v1beta1.Application) bool {
+	_, ok := app.GetAnnotations()[oam.AnnotationRestartWorkflow]
+	return ok
+}
+
+// IsSuspended returns true if the workflow of the application is required to be suspended
+func IsSuspended(app *v1beta1.Application) bool {
+	return app.Spec.WorkflowSuspend != nil
+}
+
+// CurrentStep returns the step the workflow of the application is at, i.e., the first workflow step neither succeeded
+// nor skipped, or the first component not dispatched if all the workflow steps succeeded. It's empty if all the steps
+// finished.
+// The workflow continues with the onFailure step of a step failed after all its retries, skipping the steps between.
+func CurrentStep(app *v1beta1.Application) string {
+	phases := make(map[string]common.WorkflowStepPhase, len(app.Status.Workflow))
+	for _, s := range app.Status.Workflow {
+		phases[s.Name] = s.Phase
+	}
+	index := make(map[string]int, len(app.Spec.Workflow))
+	for i, step := range app.Spec.Workflow {
+		index[step.Name] = i
+	}
+	for i := 0; i < len(app.Spec.Workflow); i++ {
+		step := app.Spec.Workflow[i]
+		phase := phases[step.Name]
+		if phase == common.WorkflowStepPhaseSucceeded || phase == common.WorkflowStepPhaseSkipped {
+			continue
+		}
+		if j, ok := index[step.OnFailure]; ok && j > i && IsStepFailed(phase) {
+			i = j - 1
+			continue
+		}
+		return step.Name
+	}
+	for _, node := range app.Status.DependencyGraph {
+		if node.Phase == common.ComponentDispatchPending {
+			return node.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestSuspendAndResume(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	c := fake.NewFakeClientWithScheme(scheme, app.DeepCopy())
+	ctx := context.Background()
+	key := client.ObjectKey{Name: "app", Namespace: "default"}
+
+	assert.NoError(t, c.Get(ctx, key, app))
+	assert.NoError(t, Suspend(ctx, c, app, "manual verification"))
+	got := &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, key, got))
+	assert.True(t, IsSuspended(got))
+	assert.Equal(t, "manual verification", got.Spec.WorkflowSuspend.Reason)
+
+	assert.NoError(t, Resume(ctx, c, got))
+	got = &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, key, got))
+	assert.False(t, IsSuspended(got))
+}
+
+func TestRestart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1beta1.SchemeBuilder.AddToScheme(scheme))
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	c := fake.NewFakeClientWithScheme(scheme, app.DeepCopy())
+	ctx := context.Background()
+	key := client.ObjectKey{Name: "app", Namespace: "default"}
+
+	assert.NoError(t, c.Get(ctx, key, app))
+	assert.False(t, IsRestartRequested(app))
+	assert.NoError(t, Restart(ctx, c, app, ""))
+	got := &v1beta1.Application{}
+	assert.NoError(t, c.Get(ctx, key, got))
+	assert.True(t, IsRestartRequested(got))
+	assert.Equal(t, "restarted", got.Annotations[oam.AnnotationRestartWorkflow])
+}
+
+func TestCurrentStep(t *testing.T) {
+	app := &v1beta1.Application{
+		Spec: v1beta1.ApplicationSpec{
+			Workflow: []v1beta1.WorkflowStep{{Name: "wait-db"}, {Name: "notify"}},
+		},
+		Status: common.AppStatus{
+			Workflow: []common.WorkflowStepStatus{{Name: "wait-db", Phase: common.WorkflowStepPhaseSucceeded}},
+			DependencyGraph: []common.ComponentDependency{
+				{Name: "db", Phase: common.ComponentDispatchDispatched},
+				{Name: "backend", Phase: common.ComponentDispatchPending},
+			},
+		},
+	}
+	assert.Equal(t, "notify", CurrentStep(app))
+
+	app.Status.Workflow = append(app.Status.Workflow, common.WorkflowStepStatus{Name: "notify", Phase: common.WorkflowStepPhaseSucceeded})
+	assert.Equal(t, "backend", CurrentStep(app))
+
+	app.Status.DependencyGraph[1].Phase = common.ComponentDispatchDispatched
+	assert.Equal(t, "", CurrentStep(app))
+}
+
+func TestCurrentStepOnFailure(t *testing.T) {
+	app := &v1beta1.Application{
+		Spec: v1beta1.ApplicationSpec{
+			Workflow: []v1beta1.WorkflowStep{
+				{Name: "deploy", OnFailure: "rollback"},
+				{Name: "verify"},
+				{Name: "rollback"},
+			},
+		},
+		Status: common.AppStatus{
+			Workflow: []common.WorkflowStepStatus{{Name: "deploy", Phase: common.WorkflowStepPhaseRetrying}},
+		},
+	}
+	assert.Equal(t, "deploy", CurrentStep(app))
+
+	app.Status.Workflow[0].Phase = common.WorkflowStepPhaseTimedOut
+	assert.Equal(t, "rollback", CurrentStep(app))
+
+	app.Status.Workflow[0].Phase = common.WorkflowStepPhaseSucceeded
+	assert.Equal(t, "verify", CurrentStep(app))
+}
@@ -27,6 +27,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/pkg/appstatus"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 )
 
@@ -35,6 +36,7 @@ type APIServer struct {
 	server     *http.Server
 	KubeClient client.Client
 	dm         discoverymapper.DiscoveryMapper
+	watcher    *appstatus.Watcher
 	c          common.Args
 }
 
@@ -48,16 +50,21 @@ func New(c common.Args, port, staticPath string) (*APIServer, error) {
 	if err != nil {
 		return nil, err
 	}
+	watcher, err := appstatus.NewWatcher(c.Config)
+	if err != nil {
+		return nil, err
+	}
 	s := &APIServer{
 		KubeClient: newClient,
 		dm:         dm,
+		watcher:    watcher,
 		c:          c,
 	}
+	// there's no write timeout since the status of applications is streamed in long-lived responses
 	server := &http.Server{
-		Addr:         port,
-		Handler:      s.setupRoute(staticPath),
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:        port,
+		Handler:     s.setupRoute(staticPath),
+		ReadTimeout: 5 * time.Second,
 	}
 	server.SetKeepAlivesEnabled(true)
 	s.server = server
@@ -17,9 +17,13 @@ limitations under the License.
 package apis
 
 import (
+	"encoding/json"
+
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	corev1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 )
 
@@ -101,3 +105,38 @@ type CapabilityCenterMeta struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
 }
+
+// TemplateDebugBody used for dashboard restful API server to evaluate a component definition template for debugging
+type TemplateDebugBody struct {
+	Namespace     string                 `json:"namespace,omitempty"`
+	ComponentName string                 `json:"componentName,omitempty"`
+	AppName       string                 `json:"appName,omitempty"`
+	Properties    map[string]interface{} `json:"properties,omitempty"`
+}
+
+// LiveDiffBody used for dashboard restful API server to diff an application with a revision of the living one
+type LiveDiffBody struct {
+	Application v1beta1.Application `json:"application"`
+	// Revision is the name or number of the revision to diff with, e.g., app-v3, v3 or 3, it's the latest one if empty
+	Revision string `json:"revision,omitempty"`
+}
+
+// DefinitionMeta used for dashboard restful API server to list the capabilities with the schemas of their parameters
+type DefinitionMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Type is either component or trait
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Schema is the OpenAPI v3 JSON schema of the parameters, it's absent if the schema is not generated yet
+	Schema json.RawMessage `json:"schema,omitempty" swaggertype:"object"`
+}
+
+// ApplicationStatusEvent used for dashboard restful API server to stream the status of an application
+type ApplicationStatusEvent struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Generation of the application, the status is outdated if its observedGeneration is less than it
+	Generation int64            `json:"generation"`
+	Status     common.AppStatus `json:"status"`
+}
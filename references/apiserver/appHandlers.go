@@ -24,8 +24,10 @@ import (
 
 	"github.com/oam-dev/kubevela/pkg/utils/env"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/apiserver/apis"
 	"github.com/oam-dev/kubevela/references/apiserver/util"
 	"github.com/oam-dev/kubevela/references/appfile/api"
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
 	"github.com/oam-dev/kubevela/references/common"
 )
 
@@ -136,3 +138,46 @@ func (s *APIServer) CreateApplication(c *gin.Context) {
 	msg := fmt.Sprintf("application %s is successfully created", body.Name)
 	util.AssembleResponse(c, msg, nil)
 }
+
+// DiffApp dry-runs the application in the body and diffs it with a revision of the living application
+// @tags applications
+// @ID DiffApplication
+// @Summary diffs an application with a revision of the living one, the revision is the latest one if not specified
+// @Param envName path string true "environment name"
+// @Param appName path string true "application name"
+// @Param body body apis.LiveDiffBody true "the application and the revision to diff with"
+// @Success 200 {object} apis.Response{code=int,data=dryrun.DiffEntry}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /envs/{envName}/apps/{appName}/diff [post]
+func (s *APIServer) DiffApp(c *gin.Context) {
+	var body apis.LiveDiffBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		util.HandleError(c, util.InvalidArgument, "the application diff request body is invalid")
+		return
+	}
+	envMeta, err := env.GetEnvByName(c.Param("envName"))
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	app := &body.Application
+	app.Name = c.Param("appName")
+	app.Namespace = envMeta.Namespace
+	pd, err := s.c.GetPackageDiscover()
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx := util.GetContext(c)
+	appRevision, err := dryrun.LoadAppRevision(ctx, s.KubeClient, app, body.Revision)
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	diffResult, err := dryrun.NewLiveDiffOption(s.KubeClient, s.dm, pd, nil).Diff(ctx, app, appRevision)
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, diffResult, nil)
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/references/apiserver/apis"
+	"github.com/oam-dev/kubevela/references/apiserver/util"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+// statusPollInterval is the interval to check the status of the application being streamed
+var statusPollInterval = 2 * time.Second
+
+// ListApplications lists the applications in a namespace
+// @tags applications
+// @ID ListNamespacedApplications
+// @Summary lists the applications in a namespace
+// @Param namespace path string true "namespace"
+// @Success 200 {object} apis.Response{code=int,data=[]v1beta1.Application}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps [get]
+func (s *APIServer) ListApplications(c *gin.Context) {
+	apps := new(v1beta1.ApplicationList)
+	if err := s.KubeClient.List(util.GetContext(c), apps, client.InNamespace(c.Param("namespace"))); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, apps.Items, nil)
+}
+
+// GetApplication gets an application by the namespaced name
+// @tags applications
+// @ID GetNamespacedApplication
+// @Summary gets an application
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Success 200 {object} apis.Response{code=int,data=v1beta1.Application}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName} [get]
+func (s *APIServer) GetApplication(c *gin.Context) {
+	app := new(v1beta1.Application)
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("appName")}
+	if err := s.KubeClient.Get(util.GetContext(c), key, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, app, nil)
+}
+
+// PostApplication creates an application in a namespace
+// @tags applications
+// @ID CreateNamespacedApplication
+// @Summary creates an application, the namespace in the body is ignored
+// @Param namespace path string true "namespace"
+// @Param body body v1beta1.Application true "the application"
+// @Success 200 {object} apis.Response{code=int,data=v1beta1.Application}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps [post]
+func (s *APIServer) PostApplication(c *gin.Context) {
+	var app v1beta1.Application
+	if err := c.ShouldBindJSON(&app); err != nil || app.Name == "" {
+		util.HandleError(c, util.InvalidArgument, "the application creation request body is invalid")
+		return
+	}
+	app.Namespace = c.Param("namespace")
+	app.ResourceVersion = ""
+	if err := s.KubeClient.Create(util.GetContext(c), &app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, app, nil)
+}
+
+// PutApplication updates the spec, labels and annotations of an application
+// @tags applications
+// @ID UpdateNamespacedApplication
+// @Summary updates an application, it fails on conflicts if the resourceVersion is set in the body
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Param body body v1beta1.Application true "the application"
+// @Success 200 {object} apis.Response{code=int,data=v1beta1.Application}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName} [put]
+func (s *APIServer) PutApplication(c *gin.Context) {
+	var body v1beta1.Application
+	if err := c.ShouldBindJSON(&body); err != nil {
+		util.HandleError(c, util.InvalidArgument, "the application update request body is invalid")
+		return
+	}
+	ctx := util.GetContext(c)
+	app := new(v1beta1.Application)
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("appName")}
+	if err := s.KubeClient.Get(ctx, key, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	if body.ResourceVersion != "" {
+		app.ResourceVersion = body.ResourceVersion
+	}
+	app.Labels = body.Labels
+	app.Annotations = body.Annotations
+	app.Spec = body.Spec
+	if err := s.KubeClient.Update(ctx, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, app, nil)
+}
+
+// DeleteApplication deletes an application by the namespaced name
+// @tags applications
+// @ID DeleteNamespacedApplication
+// @Summary deletes an application
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Success 200 {object} apis.Response{code=int,data=string}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName} [delete]
+func (s *APIServer) DeleteApplication(c *gin.Context) {
+	app := &v1beta1.Application{}
+	app.Namespace, app.Name = c.Param("namespace"), c.Param("appName")
+	if err := s.KubeClient.Delete(util.GetContext(c), app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, "application "+app.Name+" deleted", nil)
+}
+
+// GetApplicationResources gets the tree of the resources of an application
+// @tags applications
+// @ID GetNamespacedApplicationResources
+// @Summary gets the resources dispatched by an application and their children with the health of each resource
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Param metrics query bool false "query the CPU and memory usage of the pods from the metrics server"
+// @Success 200 {object} apis.Response{code=int,data=[]common.ResourceNode}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName}/resources [get]
+func (s *APIServer) GetApplicationResources(c *gin.Context) {
+	ctx := util.GetContext(c)
+	app := new(v1beta1.Application)
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("appName")}
+	if err := s.KubeClient.Get(ctx, key, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	opts := common.ResourceTreeOptions{Metrics: c.Query("metrics") == "true"}
+	tree, err := common.BuildResourceTree(ctx, s.KubeClient, common.NewClusterClientFn(s.KubeClient, app.Namespace), app, opts)
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, tree, nil)
+}
+
+// StreamApplicationLogs streams the merged logs of the containers of the pods behind the components of an application
+// @tags applications
+// @ID StreamNamespacedApplicationLogs
+// @Summary streams the logs of the components of an application, including the pods on the managed clusters, as server-sent events
+// @Description each line is sent as a "log" event, the stream ends when all the logs are sent, or when the client disconnects if the logs are followed
+// @Produce text/event-stream
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Param component query []string false "the components whose logs are streamed, all the components if not specified"
+// @Param container query string false "the container whose logs are streamed, all the containers if not specified"
+// @Param since query string false "only stream the logs newer than the duration, e.g., 10m"
+// @Param tail query int false "the number of the latest lines of each container to stream"
+// @Param follow query bool false "keep streaming the new logs"
+// @Param timestamps query bool false "prefix each message with its timestamp"
+// @Success 200 {object} common.LogLine
+// @Failure 400 {object} apis.Response{code=int,data=string}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName}/logs [get]
+func (s *APIServer) StreamApplicationLogs(c *gin.Context) {
+	opts := common.LogOptions{
+		Components: c.QueryArray("component"),
+		Container:  c.Query("container"),
+		Follow:     c.Query("follow") == "true",
+		Timestamps: c.Query("timestamps") == "true",
+	}
+	var err error
+	if since := c.Query("since"); len(since) != 0 {
+		if opts.Since, err = time.ParseDuration(since); err != nil {
+			util.HandleError(c, util.InvalidArgument, err.Error())
+			return
+		}
+	}
+	if tail := c.Query("tail"); len(tail) != 0 {
+		if opts.Tail, err = strconv.ParseInt(tail, 10, 64); err != nil {
+			util.HandleError(c, util.InvalidArgument, err.Error())
+			return
+		}
+	}
+	ctx := util.GetContext(c)
+	app := new(v1beta1.Application)
+	if err := s.KubeClient.Get(ctx, client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("appName")}, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	lines, err := common.StreamApplicationLogs(ctx, s.KubeClient,
+		common.NewClusterClientFn(s.KubeClient, app.Namespace),
+		common.NewClusterLogStreamFn(common.NewClusterClientsetFn(s.KubeClient, s.c.Config, app.Namespace)),
+		app, opts)
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Stream(func(w io.Writer) bool {
+		line, ok := <-lines
+		if !ok {
+			return false
+		}
+		c.SSEvent("log", line)
+		return true
+	})
+}
+
+// StreamApplicationStatus streams the status of an application as server-sent events
+// @tags applications
+// @ID StreamNamespacedApplicationStatus
+// @Summary streams the status of an application as server-sent events
+// @Description a "status" event is sent when the status changes, and a "deleted" event is sent before the stream ends if the application is deleted
+// @Produce text/event-stream
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Success 200 {object} apis.ApplicationStatusEvent
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName}/status [get]
+func (s *APIServer) StreamApplicationStatus(c *gin.Context) {
+	ctx := util.GetContext(c)
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("appName")}
+	app := new(v1beta1.Application)
+	if err := s.KubeClient.Get(ctx, key, app); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+	var last *apis.ApplicationStatusEvent
+	c.Stream(func(w io.Writer) bool {
+		if last != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-ticker.C:
+			}
+			app = new(v1beta1.Application)
+			if err := s.KubeClient.Get(ctx, key, app); err != nil {
+				if apierrors.IsNotFound(err) {
+					c.SSEvent("deleted", key.Name)
+				} else {
+					c.SSEvent("error", err.Error())
+				}
+				return false
+			}
+		}
+		event := &apis.ApplicationStatusEvent{
+			Name:       app.Name,
+			Namespace:  app.Namespace,
+			Generation: app.Generation,
+			Status:     app.Status,
+		}
+		if last == nil || !reflect.DeepEqual(last, event) {
+			c.SSEvent("status", event)
+		}
+		last = event
+		return true
+	})
+}
+
+// StreamApplicationEvents streams the changes of the status of an application as server-sent events
+// @tags applications
+// @ID StreamNamespacedApplicationEvents
+// @Summary streams the phase transitions, workflow step changes and health changes of an application as server-sent events
+// @Description the current phase, workflow steps and health are sent first, the stream ends after a "Deleted" or an "Error" event
+// @Produce text/event-stream
+// @Param namespace path string true "namespace"
+// @Param appName path string true "application name"
+// @Success 200 {object} appstatus.Event
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /namespaces/{namespace}/apps/{appName}/events [get]
+func (s *APIServer) StreamApplicationEvents(c *gin.Context) {
+	events, err := s.watcher.Watch(util.GetContext(c), c.Param("namespace"), c.Param("appName"))
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Stream(func(w io.Writer) bool {
+		e, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(string(e.Type), e)
+		return true
+	})
+}
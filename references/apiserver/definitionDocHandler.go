@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/references/apiserver/util"
+	"github.com/oam-dev/kubevela/references/docgen"
+)
+
+var docContentTypes = map[string]string{
+	docgen.FormatHTML:     "text/html; charset=utf-8",
+	docgen.FormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// ListDefinitionDocs renders the index page of the reference docs of the definitions
+// @tags definitions
+// @ID ListDefinitionDocs
+// @Summary renders the index page of the reference docs of the component and trait definitions
+// @Param namespace query string false "namespace of the definitions, it's vela-system if not specified"
+// @Param format query string false "format of the page, html or markdown, it's html if not specified"
+// @Produce text/html
+// @Success 200 {string} string
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /docs/definitions [get]
+func (s *APIServer) ListDefinitionDocs(c *gin.Context) {
+	namespace := c.DefaultQuery("namespace", types.DefaultKubeVelaNS)
+	format := c.DefaultQuery("format", docgen.FormatHTML)
+	if _, ok := docContentTypes[format]; !ok {
+		util.HandleError(c, util.InvalidArgument, fmt.Sprintf("unsupported format %q", format))
+		return
+	}
+	docs, err := docgen.Generate(util.GetContext(c), s.KubeClient, namespace, localeOf(c))
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	// the pages are linked with the same query parameters
+	query := c.Request.URL.Query()
+	link := func(doc *docgen.Doc) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("type", doc.Type)
+		return util.RootPath + util.DefinitionDocPath + "/" + url.PathEscape(doc.Name) + "?" + q.Encode()
+	}
+	var buf bytes.Buffer
+	if err := docgen.RenderIndex(&buf, docs, format, link); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, docContentTypes[format], buf.Bytes())
+}
+
+// GetDefinitionDoc renders the reference doc of a definition
+// @tags definitions
+// @ID GetDefinitionDoc
+// @Summary renders the reference doc of a component or trait definition with its parameters and an example
+// @Param definitionName path string true "name of the definition"
+// @Param namespace query string false "namespace of the definition, it's vela-system if not specified"
+// @Param type query string false "type of the definition, component or trait, the component takes precedence if not specified"
+// @Param format query string false "format of the page, html or markdown, it's html if not specified"
+// @Param locale query string false "locale of the descriptions of the parameters, e.g., zh, it's the first language in the Accept-Language header if not specified"
+// @Produce text/html
+// @Success 200 {string} string
+// @Failure 400 {object} apis.Response{code=int,data=string}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /docs/definitions/{definitionName} [get]
+func (s *APIServer) GetDefinitionDoc(c *gin.Context) {
+	namespace := c.DefaultQuery("namespace", types.DefaultKubeVelaNS)
+	format := c.DefaultQuery("format", docgen.FormatHTML)
+	if _, ok := docContentTypes[format]; !ok {
+		util.HandleError(c, util.InvalidArgument, fmt.Sprintf("unsupported format %q", format))
+		return
+	}
+	docs, err := docgen.Generate(util.GetContext(c), s.KubeClient, namespace, localeOf(c))
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	doc := docgen.Find(docs, c.Param("name"), c.Query("type"))
+	if doc == nil {
+		util.HandleError(c, util.InvalidArgument, fmt.Sprintf("definition %s is not found", c.Param("name")))
+		return
+	}
+	var buf bytes.Buffer
+	if err := docgen.Render(&buf, doc, format); err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, docContentTypes[format], buf.Bytes())
+}
@@ -17,20 +17,61 @@ limitations under the License.
 package apiserver
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/references/apiserver/apis"
 	"github.com/oam-dev/kubevela/references/apiserver/util"
 	"github.com/oam-dev/kubevela/references/common"
 )
 
+// ListDefinitions lists the component and trait definitions with the OpenAPI schemas of their parameters
+// @tags definitions
+// @ID ListDefinitions
+// @Summary lists the component and trait definitions with the OpenAPI v3 JSON schemas of their parameters
+// @Param namespace query string false "namespace of the definitions, it's vela-system if not specified"
+// @Param type query string false "type of the definitions, component or trait, all of them are listed if not specified"
+// @Param locale query string false "locale of the titles and descriptions in the schemas, e.g., zh, it's the first language in the Accept-Language header if not specified"
+// @Success 200 {object} apis.Response{code=int,data=[]apis.DefinitionMeta}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /definitions [get]
+func (s *APIServer) ListDefinitions(c *gin.Context) {
+	namespace := c.DefaultQuery("namespace", types.DefaultKubeVelaNS)
+	defType := c.Query("type")
+	schemas, err := common.ListDefinitionSchemas(util.GetContext(c), s.KubeClient, namespace, localeOf(c))
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	defs := make([]apis.DefinitionMeta, 0, len(schemas))
+	for _, d := range schemas {
+		meta := apis.DefinitionMeta{
+			Name:        d.Name,
+			Namespace:   d.Namespace,
+			Type:        strings.ToLower(string(d.Type)),
+			Description: d.Description,
+		}
+		if defType != "" && meta.Type != defType {
+			continue
+		}
+		if d.Schema != "" {
+			meta.Schema = json.RawMessage(d.Schema)
+		}
+		defs = append(defs, meta)
+	}
+	util.AssembleResponse(c, defs, nil)
+}
+
 // GetDefinition gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
 // @tags definitions
 // @ID GetDefinition
 // @Summary gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
 // @Param definitionName path string true "name of workload type or trait"
+// @Param locale query string false "locale of the titles and descriptions in the schema, e.g., zh, it's the first language in the Accept-Language header if not specified"
 // @Success 200 {object} apis.Response{code=int,data=string}
 // @Failure 500 {object} apis.Response{code=int,data=string}
 // @Router /definitions/{definitionName} [get]
@@ -41,5 +82,52 @@ func (s *APIServer) GetDefinition(c *gin.Context) {
 		util.HandleError(c, util.StatusInternalServerError, errors.New("OpenAPI v3 JSON Schema is not ready"))
 		return
 	}
-	util.AssembleResponse(c, cm.Data[types.OpenapiV3JSONSchema], nil)
+	util.AssembleResponse(c, common.LocalizedSchema(cm.Data, localeOf(c)), nil)
+}
+
+// localeOf returns the locale of the request, i.e., the locale query parameter or the first language in the
+// Accept-Language header
+func localeOf(c *gin.Context) string {
+	if locale := c.Query("locale"); len(locale) != 0 {
+		return locale
+	}
+	lang := strings.Split(c.GetHeader("Accept-Language"), ",")[0]
+	return strings.TrimSpace(strings.Split(lang, ";")[0])
+}
+
+// DebugDefinition evaluates the CUE template of a ComponentDefinition with the given properties for debugging
+// @tags definitions
+// @ID DebugDefinition
+// @Summary returns the evaluated context, parameter and intermediate values of a ComponentDefinition template, the secrets are redacted
+// @Param definitionName path string true "name of the component type"
+// @Param body body apis.TemplateDebugBody true "component properties"
+// @Success 200 {object} apis.Response{code=int,data=definition.TemplateDebugInfo}
+// @Failure 500 {object} apis.Response{code=int,data=string}
+// @Router /definitions/{definitionName}/debug [post]
+func (s *APIServer) DebugDefinition(c *gin.Context) {
+	var body apis.TemplateDebugBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		util.HandleError(c, util.InvalidArgument, "the definition debug request body is invalid")
+		return
+	}
+	pd, err := s.c.GetPackageDiscover()
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	if body.Namespace == "" {
+		body.Namespace = "default"
+	}
+	info, err := common.DebugComponentTemplate(c.Request.Context(), s.KubeClient, s.dm, pd, common.TemplateDebugOptions{
+		Namespace:     body.Namespace,
+		ComponentType: c.Param("name"),
+		ComponentName: body.ComponentName,
+		AppName:       body.AppName,
+		Properties:    body.Properties,
+	})
+	if err != nil {
+		util.HandleError(c, util.StatusInternalServerError, err.Error())
+		return
+	}
+	util.AssembleResponse(c, info, nil)
 }
@@ -97,6 +97,7 @@ func (s *APIServer) setupRoute(staticPath string) http.Handler {
 			apps.GET("", s.ListApps)
 			apps.DELETE("/:appName", s.DeleteApps)
 			apps.POST("/", s.CreateApplication)
+			apps.POST("/:appName/diff", s.DiffApp)
 
 			// component related operation
 			components := apps.Group("/:appName/components")
@@ -115,6 +116,22 @@ func (s *APIServer) setupRoute(staticPath string) http.Handler {
 			}
 		}
 	}
+	// application related api, the applications are in the namespaces rather than the envs
+	apps := api.Group(util.NamespacePath + "/:namespace" + util.ApplicationPath)
+	{
+		apps.GET("/", s.ListApplications)
+		apps.GET("", s.ListApplications)
+		apps.POST("/", s.PostApplication)
+		apps.POST("", s.PostApplication)
+		apps.GET("/:appName", s.GetApplication)
+		apps.PUT("/:appName", s.PutApplication)
+		apps.DELETE("/:appName", s.DeleteApplication)
+		apps.GET("/:appName/status", s.StreamApplicationStatus)
+		apps.GET("/:appName/events", s.StreamApplicationEvents)
+		apps.GET("/:appName/resources", s.GetApplicationResources)
+		apps.GET("/:appName/logs", s.StreamApplicationLogs)
+	}
+
 	// component related api
 	workload := api.Group(util.ComponentDefinitionPath)
 	{
@@ -168,7 +185,16 @@ func (s *APIServer) setupRoute(staticPath string) http.Handler {
 	// Definition related api
 	defs := api.Group(util.Definition)
 	{
+		defs.GET("/", s.ListDefinitions)
+		defs.GET("", s.ListDefinitions)
 		defs.GET("/:name", s.GetDefinition)
+		defs.POST("/:name/debug", s.DebugDefinition)
+	}
+	docs := api.Group(util.DefinitionDocPath)
+	{
+		docs.GET("/", s.ListDefinitionDocs)
+		docs.GET("", s.ListDefinitionDocs)
+		docs.GET("/:name", s.GetDefinitionDoc)
 	}
 
 	// version
@@ -62,6 +62,7 @@ const (
 const (
 	RootPath                = "/api"
 	EnvironmentPath         = "/envs"
+	NamespacePath           = "/namespaces"
 	ApplicationPath         = "/apps"
 	WorkloadDefinitionPath  = "/workloads"
 	ComponentDefinitionPath = "/components"
@@ -71,6 +72,7 @@ const (
 	CapabilityCenterPath    = "/capability-centers"
 	VersionPath             = "/version"
 	Definition              = "/definitions"
+	DefinitionDocPath       = "/docs/definitions"
 )
 
 // NoRoute is a handler which is invoked when there is no route matches.
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"cuelang.org/go/cue"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// IndexFileNames are the names of the index manifest of a directory-based application, the first one found is used
+var IndexFileNames = []string{"index.yaml", "index.yml", "index.json"}
+
+// applicationIndex is the fields of the index manifest besides the Application
+type applicationIndex struct {
+	// Include are the glob patterns of the component files relative to the directory, e.g., components/*.yaml,
+	// all the component files in the directory and its sub-directories are included if it's empty
+	Include []string `json:"include,omitempty"`
+}
+
+// IsApplicationDir returns true if the path is a directory-based application, i.e., a directory with an index manifest
+func IsApplicationDir(path string) bool {
+	_, err := indexFile(path)
+	return err == nil
+}
+
+func indexFile(dir string) (string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !fi.IsDir() {
+		return "", errors.Errorf("%s is not a directory", dir)
+	}
+	for _, name := range IndexFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.Errorf("no index manifest %v is found in %s", IndexFileNames, dir)
+}
+
+// LoadApplicationFromDir loads a directory-based application, so the components can be kept in separate files owned
+// by different teams. The index manifest is an Application with the policies, the workflow and optionally some
+// components, the components in the files included by it are appended in the order of the patterns and the file
+// paths. A component file is a component of the application in YAML, JSON or CUE, one component per file.
+func LoadApplicationFromDir(dir string) (*v1beta1.Application, error) {
+	index, err := indexFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(filepath.Clean(index))
+	if err != nil {
+		return nil, err
+	}
+	if b, err = yaml.YAMLToJSON(b); err != nil {
+		return nil, errors.Wrapf(err, "invalid index manifest %s", index)
+	}
+	app := &v1beta1.Application{}
+	if err := json.Unmarshal(b, app); err != nil {
+		return nil, errors.Wrapf(err, "invalid index manifest %s", index)
+	}
+	idx := &applicationIndex{}
+	if err := json.Unmarshal(b, idx); err != nil {
+		return nil, errors.Wrapf(err, "invalid index manifest %s", index)
+	}
+	files, err := componentFiles(dir, index, idx.Include)
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]string{}
+	for _, comp := range app.Spec.Components {
+		sources[comp.Name] = index
+	}
+	for _, file := range files {
+		comp, err := loadComponentFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if len(comp.Name) == 0 {
+			return nil, errors.Errorf("component in %s has no name", file)
+		}
+		if src, ok := sources[comp.Name]; ok {
+			return nil, errors.Errorf("component %s is defined in both %s and %s", comp.Name, src, file)
+		}
+		sources[comp.Name] = file
+		app.Spec.Components = append(app.Spec.Components, *comp)
+	}
+	return app, nil
+}
+
+// componentFiles returns the component files matched by the patterns, the index manifest is excluded
+func componentFiles(dir, index string, include []string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{index: true}
+	add := func(path string) {
+		if !seen[path] && isComponentFile(path) {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	if len(include) == 0 {
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() {
+				add(path)
+			}
+			return nil
+		})
+		return files, err
+	}
+	for _, pattern := range include {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid include pattern %q", pattern)
+		}
+		if len(matches) == 0 {
+			return nil, errors.Errorf("no component file matches include pattern %q", pattern)
+		}
+		sort.Strings(matches)
+		for _, path := range matches {
+			add(path)
+		}
+	}
+	return files, nil
+}
+
+func isComponentFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json", ".cue":
+		return true
+	}
+	return false
+}
+
+// loadComponentFile loads a component from the file, the CUE file is evaluated and must be concrete
+func loadComponentFile(path string) (*v1beta1.ApplicationComponent, error) {
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) == ".cue" {
+		var r cue.Runtime
+		inst, err := r.Compile(path, b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compile component file %s", path)
+		}
+		if b, err = inst.Value().MarshalJSON(); err != nil {
+			return nil, errors.Wrapf(err, "component in %s is not concrete", path)
+		}
+	} else if b, err = yaml.YAMLToJSON(b); err != nil {
+		return nil, errors.Wrapf(err, "invalid component file %s", path)
+	}
+	comp := &v1beta1.ApplicationComponent{}
+	if err := json.Unmarshal(b, comp); err != nil {
+		return nil, errors.Wrapf(err, "invalid component file %s", path)
+	}
+	return comp, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+}
+
+func TestLoadApplicationFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multifile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.False(t, IsApplicationDir(dir))
+
+	writeFiles(t, dir, map[string]string{
+		"index.yaml": `
+apiVersion: core.oam.dev/v1beta1
+kind: Application
+metadata:
+  name: website
+spec:
+  components:
+  - name: db
+    type: worker
+    properties:
+      image: mysql
+  workflow:
+  - name: apply
+    type: apply-application
+`,
+		"components/frontend.yaml": `
+name: frontend
+type: webservice
+properties:
+  image: nginx
+traits:
+- type: scaler
+  properties:
+    replicas: 2
+`,
+		"components/backend.cue": `
+name: "backend"
+type: "webservice"
+properties: {
+	image: "backend:" + _version
+	port:  8080
+}
+_version: "v1"
+`,
+		"README.md": "ignored",
+	})
+	assert.True(t, IsApplicationDir(dir))
+	assert.False(t, IsApplicationDir(filepath.Join(dir, "index.yaml")))
+
+	app, err := LoadApplicationFromDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "website", app.Name)
+	assert.Len(t, app.Spec.Workflow, 1)
+	var names []string
+	for _, comp := range app.Spec.Components {
+		names = append(names, comp.Name)
+	}
+	// the inline components go first, then the files in the order of their paths
+	assert.Equal(t, []string{"db", "backend", "frontend"}, names)
+	assert.JSONEq(t, `{"image":"backend:v1","port":8080}`, string(app.Spec.Components[1].Properties.Raw))
+	assert.Len(t, app.Spec.Components[2].Traits, 1)
+
+	desc := "include the component files by patterns in order"
+	writeFiles(t, dir, map[string]string{"index.yaml": `
+apiVersion: core.oam.dev/v1beta1
+kind: Application
+metadata:
+  name: website
+include:
+- components/frontend.yaml
+- components/*
+`})
+	app, err = LoadApplicationFromDir(dir)
+	assert.NoError(t, err, desc)
+	names = nil
+	for _, comp := range app.Spec.Components {
+		names = append(names, comp.Name)
+	}
+	assert.Equal(t, []string{"frontend", "backend"}, names, desc)
+
+	desc = "reject the components defined in multiple files"
+	writeFiles(t, dir, map[string]string{"components/frontend-copy.yaml": "name: frontend\ntype: webservice\n"})
+	_, err = LoadApplicationFromDir(dir)
+	assert.Error(t, err, desc)
+	assert.Contains(t, err.Error(), "component frontend is defined in both", desc)
+
+	desc = "reject the patterns matching nothing"
+	writeFiles(t, dir, map[string]string{"index.yaml": "metadata:\n  name: website\ninclude:\n- services/*.yaml\n"})
+	_, err = LoadApplicationFromDir(dir)
+	assert.Error(t, err, desc)
+}
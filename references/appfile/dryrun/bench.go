@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// Stages of rendering an application measured by the benchmark
+const (
+	// StageParse parses the application into an appfile, loading the definitions
+	StageParse = "parse"
+	// StageRender evaluates the templates into the ApplicationConfiguration and Components
+	StageRender = "render"
+	// StageAssemble assembles the rendered resources the way the application controller applies them
+	StageAssemble = "assemble"
+)
+
+// RenderStages are the stages in the order of rendering
+var RenderStages = []string{StageParse, StageRender, StageAssemble}
+
+// StageBenchmark is the latency and allocations of a stage of rendering
+type StageBenchmark struct {
+	Stage string        `json:"stage"`
+	P50   time.Duration `json:"p50"`
+	P99   time.Duration `json:"p99"`
+	Mean  time.Duration `json:"mean"`
+	// AllocsPerOp is the number of heap allocations per rendering
+	AllocsPerOp uint64 `json:"allocsPerOp"`
+	// BytesPerOp is the bytes allocated on heap per rendering
+	BytesPerOp uint64 `json:"bytesPerOp"`
+}
+
+// RenderBenchmark is the result of rendering an application repeatedly
+type RenderBenchmark struct {
+	Application string           `json:"application"`
+	Iterations  int              `json:"iterations"`
+	Stages      []StageBenchmark `json:"stages"`
+}
+
+// Stage returns the benchmark of the stage
+func (b *RenderBenchmark) Stage(stage string) (StageBenchmark, bool) {
+	for _, s := range b.Stages {
+		if s.Stage == stage {
+			return s, true
+		}
+	}
+	return StageBenchmark{}, false
+}
+
+type stageSample struct {
+	durations []time.Duration
+	allocs    uint64
+	bytes     uint64
+}
+
+// BenchmarkRender renders the application the given times and reports the latency and allocations of each stage.
+// The allocations are measured from the runtime memory statistics, so the benchmark should not run concurrently
+// with other workloads in the same process.
+func (d *Option) BenchmarkRender(ctx context.Context, app *v1beta1.Application, iterations int) (*RenderBenchmark, error) {
+	if iterations <= 0 {
+		return nil, errors.Errorf("iterations must be positive, got %d", iterations)
+	}
+	if app.Namespace != "" {
+		ctx = oamutil.SetNamespaceInCtx(ctx, app.Namespace)
+	}
+	samples := make(map[string]*stageSample, len(RenderStages))
+	for _, stage := range RenderStages {
+		samples[stage] = &stageSample{durations: make([]time.Duration, 0, iterations)}
+	}
+	var (
+		appFile *appfile.Appfile
+		ac      *v1alpha2.ApplicationConfiguration
+		comps   []*v1alpha2.Component
+	)
+	stages := map[string]func() error{
+		StageParse: func() (err error) {
+			parser := appfile.NewDryRunApplicationParser(d.Client, d.DiscoveryMapper, d.PackageDiscover, d.Auxiliaries)
+			appFile, err = parser.GenerateAppFile(ctx, app.DeepCopy())
+			return errors.WithMessage(err, "cannot generate appFile from application")
+		},
+		StageRender: func() (err error) {
+			ac, comps, err = appFile.GenerateApplicationConfiguration()
+			return errors.WithMessage(err, "cannot generate AppConfig and Components")
+		},
+		StageAssemble: func() error {
+			appRev, err := newDryRunAppRevision(app, ac, comps)
+			if err != nil {
+				return err
+			}
+			_, _, _, err = assemble.NewAppManifests(appRev).GroupAssembledManifests()
+			return errors.WithMessage(err, "cannot assemble resources of application")
+		},
+	}
+
+	var before, after runtime.MemStats
+	for i := 0; i < iterations; i++ {
+		for _, stage := range RenderStages {
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			err := stages[stage]()
+			elapsed := time.Since(start)
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "stage %s", stage)
+			}
+			s := samples[stage]
+			s.durations = append(s.durations, elapsed)
+			s.allocs += after.Mallocs - before.Mallocs
+			s.bytes += after.TotalAlloc - before.TotalAlloc
+		}
+	}
+
+	result := &RenderBenchmark{Application: app.Name, Iterations: iterations}
+	for _, stage := range RenderStages {
+		s := samples[stage]
+		result.Stages = append(result.Stages, StageBenchmark{
+			Stage:       stage,
+			P50:         percentile(s.durations, 0.50),
+			P99:         percentile(s.durations, 0.99),
+			Mean:        mean(s.durations),
+			AllocsPerOp: s.allocs / uint64(iterations),
+			BytesPerOp:  s.bytes / uint64(iterations),
+		})
+	}
+	return result, nil
+}
+
+// CompareRenderBenchmarks compares the benchmarks with the baseline, it returns the regressions where the p99
+// latency or the allocations per rendering of a stage grow by more than the threshold, e.g., 0.2 for 20%.
+// The applications or stages missing in the baseline are skipped.
+func CompareRenderBenchmarks(baseline, current []RenderBenchmark, threshold float64) []string {
+	base := make(map[string]*RenderBenchmark, len(baseline))
+	for i := range baseline {
+		base[baseline[i].Application] = &baseline[i]
+	}
+	var regressions []string
+	for _, cur := range current {
+		b, ok := base[cur.Application]
+		if !ok {
+			continue
+		}
+		for _, s := range cur.Stages {
+			bs, ok := b.Stage(s.Stage)
+			if !ok {
+				continue
+			}
+			if exceeds(float64(bs.P99), float64(s.P99), threshold) {
+				regressions = append(regressions, fmt.Sprintf("%s/%s: p99 %s -> %s", cur.Application, s.Stage, bs.P99, s.P99))
+			}
+			if exceeds(float64(bs.AllocsPerOp), float64(s.AllocsPerOp), threshold) {
+				regressions = append(regressions, fmt.Sprintf("%s/%s: allocs/op %d -> %d", cur.Application, s.Stage, bs.AllocsPerOp, s.AllocsPerOp))
+			}
+		}
+	}
+	return regressions
+}
+
+func exceeds(base, cur, threshold float64) bool {
+	return base > 0 && (cur-base)/base > threshold
+}
+
+// percentile returns the nearest-rank percentile of the durations
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func mean(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return sum / time.Duration(len(durations))
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// benchCorpus is the directory of the representative applications and definitions rendered by the benchmarks,
+// the definitions don't import the kube packages so they're rendered without a cluster
+const benchCorpus = "./testdata/bench"
+
+// newBenchOption creates the dry-run option rendering the corpus offline, together with the applications in it,
+// tb is either *testing.B or GinkgoT()
+func newBenchOption(tb interface{ Fatal(args ...interface{}) }) (*Option, []*v1beta1.Application) {
+	readDir := func(dir string, newObj func() oam.Object) []oam.Object {
+		fis, err := ioutil.ReadDir(filepath.Join(benchCorpus, dir))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		var objs []oam.Object
+		for _, fi := range fis {
+			obj := newObj()
+			if err := common.ReadYamlToObject(filepath.Join(benchCorpus, dir, fi.Name()), obj); err != nil {
+				tb.Fatal(err)
+			}
+			objs = append(objs, obj)
+		}
+		return objs
+	}
+	defs := readDir("definitions", func() oam.Object { return &unstructured.Unstructured{} })
+	var apps []*v1beta1.Application
+	for _, obj := range readDir("apps", func() oam.Object { return &v1beta1.Application{} }) {
+		apps = append(apps, obj.(*v1beta1.Application))
+	}
+	pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewDryRunOption(fake.NewFakeClientWithScheme(common.Scheme), nil, pd, defs), apps
+}
+
+func BenchmarkRenderApplication(b *testing.B) {
+	opt, apps := newBenchOption(b)
+	for _, app := range apps {
+		app := app
+		b.Run(app.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := opt.RenderApplication(context.Background(), app.DeepCopy()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var _ = Describe("Test benchmark rendering applications", func() {
+	It("report every stage of rendering the corpus", func() {
+		opt, apps := newBenchOption(GinkgoT())
+		Expect(apps).Should(HaveLen(3))
+		for _, app := range apps {
+			result, err := opt.BenchmarkRender(context.Background(), app, 3)
+			Expect(err).Should(BeNil())
+			Expect(result.Iterations).Should(Equal(3))
+			Expect(result.Stages).Should(HaveLen(len(RenderStages)))
+			for _, s := range result.Stages {
+				Expect(s.P99 >= s.P50).Should(BeTrue())
+				Expect(s.AllocsPerOp).ShouldNot(BeZero())
+			}
+		}
+	})
+
+	It("compute the nearest-rank percentiles", func() {
+		var durations []time.Duration
+		for i := 100; i > 0; i-- {
+			durations = append(durations, time.Duration(i)*time.Millisecond)
+		}
+		Expect(percentile(durations, 0.5)).Should(Equal(50 * time.Millisecond))
+		Expect(percentile(durations, 0.99)).Should(Equal(99 * time.Millisecond))
+		Expect(percentile(nil, 0.99)).Should(BeZero())
+	})
+
+	It("find the regressions against the baseline", func() {
+		baseline := []RenderBenchmark{{Application: "app", Stages: []StageBenchmark{
+			{Stage: StageParse, P99: 10 * time.Millisecond, AllocsPerOp: 1000},
+			{Stage: StageRender, P99: 10 * time.Millisecond, AllocsPerOp: 1000},
+		}}}
+		current := []RenderBenchmark{{Application: "app", Stages: []StageBenchmark{
+			{Stage: StageParse, P99: 11 * time.Millisecond, AllocsPerOp: 1000},
+			{Stage: StageRender, P99: 20 * time.Millisecond, AllocsPerOp: 2000},
+			{Stage: StageAssemble, P99: 20 * time.Millisecond, AllocsPerOp: 2000},
+		}}, {Application: "new-app"}}
+		regressions := CompareRenderBenchmarks(baseline, current, 0.2)
+		Expect(regressions).Should(HaveLen(2))
+		Expect(regressions[0]).Should(ContainSubstring("app/render: p99"))
+		Expect(regressions[1]).Should(ContainSubstring("app/render: allocs/op"))
+	})
+})
@@ -20,6 +20,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aryann/difflib"
@@ -30,7 +33,9 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	ctrlutil "github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
@@ -58,7 +63,18 @@ type DiffEntry struct {
 	Kind     ManifestKind         `json:"kind"`
 	DiffType DiffType             `json:"diffType,omitempty"`
 	Diffs    []difflib.DiffRecord `json:"diffs,omitempty"`
-	Subs     []*DiffEntry         `json:"subs,omitempty"`
+	// Fields are the fields changed if the object is modified
+	Fields []FieldDiff  `json:"fields,omitempty"`
+	Subs   []*DiffEntry `json:"subs,omitempty"`
+}
+
+// FieldDiff records the change of a field of a modified object
+type FieldDiff struct {
+	// Path is the path of the field, e.g., spec.template.spec.containers[0].image
+	Path     string      `json:"path"`
+	DiffType DiffType    `json:"diffType"`
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
 }
 
 // DiffType enums the type of diff
@@ -124,6 +140,7 @@ func (l *LiveDiffOption) calculateDiff(oldApp, newApp *manifest) *DiffEntry {
 	if hasChanges(appDiffs) {
 		r.DiffType = ModifyDiff
 		r.Diffs = appDiffs
+		r.Fields = diffFields(oldApp, newApp)
 	}
 
 	// check modified and removed components
@@ -164,6 +181,7 @@ func (l *LiveDiffOption) calculateDiff(oldApp, newApp *manifest) *DiffEntry {
 					diffs = diffManifest(oldAccSub, newAccSub)
 					if hasChanges(diffs) {
 						accSubDiffEntry.DiffType = ModifyDiff
+						accSubDiffEntry.Fields = diffFields(oldAccSub, newAccSub)
 					} else {
 						accSubDiffEntry.DiffType = NoDiff
 					}
@@ -362,6 +380,95 @@ func diffManifest(old, new *manifest) []difflib.DiffRecord {
 	return difflib.Diff(strings.Split(old.Data, sep), strings.Split(new.Data, sep))
 }
 
+// diffFields compares the objects of the manifests field by field, the elements of lists are compared by indexes
+func diffFields(old, new *manifest) []FieldDiff {
+	var oldObj, newObj interface{}
+	if err := yaml.Unmarshal([]byte(old.Data), &oldObj); err != nil {
+		return nil
+	}
+	if err := yaml.Unmarshal([]byte(new.Data), &newObj); err != nil {
+		return nil
+	}
+	var fields []FieldDiff
+	compareFields("", oldObj, newObj, &fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return fields
+}
+
+func compareFields(path string, old, new interface{}, fields *[]FieldDiff) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			for k, v := range o {
+				p := joinFieldPath(path, k)
+				if nv, ok := n[k]; ok {
+					compareFields(p, v, nv, fields)
+				} else {
+					*fields = append(*fields, FieldDiff{Path: p, DiffType: RemoveDiff, Old: v})
+				}
+			}
+			for k, v := range n {
+				if _, ok := o[k]; !ok {
+					*fields = append(*fields, FieldDiff{Path: joinFieldPath(path, k), DiffType: AddDiff, New: v})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(n):
+					*fields = append(*fields, FieldDiff{Path: p, DiffType: RemoveDiff, Old: o[i]})
+				case i >= len(o):
+					*fields = append(*fields, FieldDiff{Path: p, DiffType: AddDiff, New: n[i]})
+				default:
+					compareFields(p, o[i], n[i], fields)
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(old, new) {
+		*fields = append(*fields, FieldDiff{Path: path, DiffType: ModifyDiff, Old: old, New: new})
+	}
+}
+
+func joinFieldPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// LoadAppRevision gets the revision of the application to diff with. The revision can be the name of the
+// ApplicationRevision, the revision number, e.g., 3 or v3, or empty for the latest revision of the living application.
+func LoadAppRevision(ctx context.Context, c client.Reader, app *v1beta1.Application, revision string) (*v1beta1.ApplicationRevision, error) {
+	name := revision
+	if len(revision) == 0 {
+		living := &v1beta1.Application{}
+		if err := c.Get(ctx, client.ObjectKey{Name: app.Name, Namespace: app.Namespace}, living); err != nil {
+			return nil, errors.Wrapf(err, "cannot get application %q", app.Name)
+		}
+		if living.Status.LatestRevision == nil {
+			// the application has not been rendered yet
+			return nil, errors.Errorf("the application %q has no revision in the cluster", app.Name)
+		}
+		name = living.Status.LatestRevision.Name
+	} else if n, err := strconv.ParseInt(strings.TrimPrefix(revision, "v"), 10, 64); err == nil {
+		name = ctrlutil.ConstructRevisionName(app.Name, n)
+	}
+	appRevision := &v1beta1.ApplicationRevision{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: app.Namespace}, appRevision); err != nil {
+		return nil, errors.Wrapf(err, "cannot get application revision %q", name)
+	}
+	if err := manifeststore.Load(ctx, c, appRevision); err != nil {
+		return nil, err
+	}
+	return appRevision, nil
+}
+
 func extractNameFromRevisionName(r string) string {
 	s := strings.Split(r, "-")
 	return strings.Join(s[0:len(s)-1], "-")
@@ -129,6 +129,7 @@ var _ = Describe("Test Live-Diff", func() {
 			ContainSubstring("Component (myweb-1) / Trait (myingress/ingress) has been modified"),
 			ContainSubstring("Component (myweb-1) / Trait (myscaler/scaler) has no change"),
 			ContainSubstring("Component (myweb-2) has no change"),
+			ContainSubstring("# (*) spec.components[0].properties.cmd[1]: 1000 -> 2000"),
 		))
 		Expect(diffResultStr).ShouldNot(SatisfyAny(
 			ContainSubstring("removed"),
@@ -153,3 +154,34 @@ var _ = Describe("Test Live-Diff", func() {
 	})
 
 })
+
+var _ = Describe("Test diff fields", func() {
+	It("diff the fields of maps and lists", func() {
+		old := &manifest{Data: `
+spec:
+  replicas: 1
+  selector:
+    app: web
+  containers:
+  - name: web
+    image: nginx:1.19
+  - name: sidecar
+    image: envoy
+`}
+		new := &manifest{Data: `
+spec:
+  replicas: 3
+  paused: true
+  containers:
+  - name: web
+    image: nginx:1.20
+`}
+		Expect(diffFields(old, new)).Should(Equal([]FieldDiff{
+			{Path: "spec.containers[0].image", DiffType: ModifyDiff, Old: "nginx:1.19", New: "nginx:1.20"},
+			{Path: "spec.containers[1]", DiffType: RemoveDiff, Old: map[string]interface{}{"name": "sidecar", "image": "envoy"}},
+			{Path: "spec.paused", DiffType: AddDiff, New: true},
+			{Path: "spec.replicas", DiffType: ModifyDiff, Old: float64(1), New: float64(3)},
+			{Path: "spec.selector", DiffType: RemoveDiff, Old: map[string]interface{}{"app": "web"}},
+		}))
+	})
+})
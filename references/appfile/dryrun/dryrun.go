@@ -17,14 +17,21 @@ limitations under the License.
 package dryrun
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/common"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
+	ctrlutil "github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
@@ -34,6 +41,7 @@ import (
 // DryRun executes dry-run on an application
 type DryRun interface {
 	ExecuteDryRun(ctx context.Context, app *v1beta1.Application) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error)
+	RenderApplication(ctx context.Context, app *v1beta1.Application) ([]*unstructured.Unstructured, error)
 }
 
 // NewDryRunOption creates a dry-run option
@@ -69,3 +77,115 @@ func (d *Option) ExecuteDryRun(ctx context.Context, app *v1beta1.Application) (*
 	}
 	return ac, comps, nil
 }
+
+// RenderApplication renders an application into the K8s resources assembled by the application controller,
+// including the labels, annotations and namespace set by the assemble phase. Nothing is written into the cluster,
+// so the revision hash label and the owner reference, which depend on the revision and the application persisted,
+// are left out. The result is ordered by components in the application, the traits of each component are placed
+// around its workload according to their stages, so the output is deterministic.
+func (d *Option) RenderApplication(ctx context.Context, app *v1beta1.Application) ([]*unstructured.Unstructured, error) {
+	ac, comps, err := d.ExecuteDryRun(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	return AssembleManifests(app, ac, comps)
+}
+
+// AssembleManifests assembles the AppConfig and Components generated by ExecuteDryRun into the final K8s resources
+// in the order described in RenderApplication
+func AssembleManifests(app *v1beta1.Application, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) ([]*unstructured.Unstructured, error) {
+	appRev, err := newDryRunAppRevision(app, ac, comps)
+	if err != nil {
+		return nil, err
+	}
+	workloads, traits, _, err := assemble.NewAppManifests(appRev).GroupAssembledManifests()
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot assemble resources of application")
+	}
+	var objs []*unstructured.Unstructured
+	for _, acc := range ac.Spec.Components {
+		staged := traits[acc.ComponentName]
+		objs = append(objs, staged[common.PreDispatchStage]...)
+		objs = append(objs, staged[common.PreWorkloadStage]...)
+		if wl, ok := workloads[acc.ComponentName]; ok {
+			objs = append(objs, wl)
+		}
+		objs = append(objs, staged[common.PostWorkloadStage]...)
+	}
+	for _, obj := range objs {
+		stripRevisionMeta(obj)
+	}
+	return objs, nil
+}
+
+// stripRevisionMeta removes the revision hash label and the owner reference without uid set from the fake revision
+// of dry-run, they're only known after the application and its revision are persisted
+func stripRevisionMeta(obj *unstructured.Unstructured) {
+	if labels := obj.GetLabels(); len(labels) != 0 {
+		delete(labels, oam.LabelAppRevisionHash)
+		obj.SetLabels(labels)
+	}
+	var owners []metav1.OwnerReference
+	for _, owner := range obj.GetOwnerReferences() {
+		if len(owner.UID) != 0 {
+			owners = append(owners, owner)
+		}
+	}
+	obj.SetOwnerReferences(owners)
+}
+
+// newDryRunAppRevision builds an in-memory AppRevision carrying all the information required by assembling,
+// the way the application controller does before it persists the revision.
+func newDryRunAppRevision(app *v1beta1.Application, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) (*v1beta1.ApplicationRevision, error) {
+	revHash, err := ctrlutil.ComputeSpecHash(app.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compute hash of application")
+	}
+	revName := ctrlutil.ConstructRevisionName(app.Name, 1)
+	namespace := app.Namespace
+	if len(namespace) == 0 {
+		namespace = ac.Namespace
+	}
+
+	ac = ac.DeepCopy()
+	ac.SetName(app.Name)
+	ac.SetNamespace(namespace)
+	oamutil.PassLabelAndAnnotation(app, ac)
+	ac.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(app, v1beta1.ApplicationKindVersionKind)})
+	for i := range ac.Spec.Components {
+		if len(ac.Spec.Components[i].RevisionName) == 0 {
+			ac.Spec.Components[i].RevisionName = ctrlutil.ConstructRevisionName(ac.Spec.Components[i].ComponentName, 1)
+		}
+	}
+
+	appRev := &v1beta1.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revName,
+			Namespace: namespace,
+			Labels:    map[string]string{oam.LabelAppRevisionHash: revHash},
+		},
+	}
+	appRev.Spec.Application = *app.DeepCopy()
+	appRev.Spec.ApplicationConfiguration = oamutil.Object2RawExtension(ac)
+	for _, comp := range comps {
+		appRev.Spec.Components = append(appRev.Spec.Components, common.RawComponent{Raw: oamutil.Object2RawExtension(comp.DeepCopy())})
+	}
+	return appRev, nil
+}
+
+// ManifestsToYAML encodes the rendered resources into a YAML stream separated by "---"
+func ManifestsToYAML(objs []*unstructured.Unstructured) ([]byte, error) {
+	var buff bytes.Buffer
+	for i, obj := range objs {
+		if i > 0 {
+			buff.WriteString("---\n")
+		}
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshal %s %q into yaml", obj.GetKind(), obj.GetName())
+		}
+		buff.Write(b)
+	}
+	return buff.Bytes(), nil
+}
@@ -19,6 +19,7 @@ package dryrun
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/ginkgo"
@@ -26,6 +27,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 var _ = Describe("Test DryRun", func() {
@@ -59,4 +61,40 @@ var _ = Describe("Test DryRun", func() {
 		diff = cmp.Diff(expectCompYAML, string(resultCompStr))
 		Expect(diff).Should(BeEmpty())
 	})
+
+	It("Test RenderApplication", func() {
+		appYAML := readDataFromFile("./testdata/dryrun-app.yaml")
+		app := &v1beta1.Application{}
+		b, err := yaml.YAMLToJSON([]byte(appYAML))
+		Expect(err).Should(BeNil())
+		Expect(json.Unmarshal(b, app)).Should(BeNil())
+		app.SetNamespace("default")
+
+		By("Render the application")
+		objs, err := dryrunOpt.RenderApplication(context.Background(), app)
+		Expect(err).Should(BeNil())
+		var kinds []string
+		for _, obj := range objs {
+			kinds = append(kinds, obj.GetKind())
+			Expect(obj.GetNamespace()).Should(Equal("default"))
+			Expect(obj.GetLabels()).Should(HaveKeyWithValue(oam.LabelAppName, "app-dryrun"))
+			Expect(obj.GetLabels()).Should(HaveKeyWithValue(oam.LabelAppComponent, "myweb"))
+			Expect(obj.GetLabels()).ShouldNot(HaveKey(oam.LabelAppRevisionHash))
+			Expect(obj.GetOwnerReferences()).Should(BeEmpty())
+		}
+		Expect(kinds).Should(Equal([]string{"Deployment", "Service", "Ingress"}))
+		Expect(objs[0].GetName()).Should(Equal("myweb"))
+
+		By("Verify the YAML stream is deterministic")
+		result, err := ManifestsToYAML(objs)
+		Expect(err).Should(BeNil())
+		Expect(strings.Count(string(result), "---\n")).Should(Equal(2))
+		for i := 0; i < 3; i++ {
+			objs, err = dryrunOpt.RenderApplication(context.Background(), app)
+			Expect(err).Should(BeNil())
+			again, err := ManifestsToYAML(objs)
+			Expect(err).Should(BeNil())
+			Expect(cmp.Diff(string(result), string(again))).Should(BeEmpty())
+		}
+	})
 })
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strings"
 
 	"github.com/aryann/difflib"
 	"github.com/fatih/color"
@@ -57,6 +58,7 @@ type ReportDiffOption struct {
 // 'app' should be a diifEntry whose top-level is an application
 func (r *ReportDiffOption) PrintDiffReport(app *DiffEntry) {
 	_, _ = yellow.Fprintf(r.To, "---\n# Application (%s) %s\n---\n", app.Name, r.DiffMsgs[app.DiffType])
+	printFields(app.Fields, r.To)
 	printDiffs(app.Diffs, r.Context, r.To)
 
 	for _, acc := range app.Subs {
@@ -70,11 +72,50 @@ func (r *ReportDiffOption) PrintDiffReport(app *DiffEntry) {
 			default:
 				continue
 			}
+			printFields(accSub.Fields, r.To)
 			printDiffs(accSub.Diffs, r.Context, r.To)
 		}
 	}
 }
 
+// printFields prints the fields changed as comments before the diffs, the old and new values are only printed for
+// scalar fields to keep the summary short
+func printFields(fields []FieldDiff, to io.Writer) {
+	for _, f := range fields {
+		switch f.DiffType {
+		case AddDiff:
+			_, _ = green.Fprintf(to, "# (+) %s\n", f.Path)
+		case RemoveDiff:
+			_, _ = red.Fprintf(to, "# (-) %s\n", f.Path)
+		case ModifyDiff:
+			if isScalar(f.Old) && isScalar(f.New) {
+				_, _ = yellow.Fprintf(to, "# (*) %s: %v -> %v\n", f.Path, f.Old, f.New)
+			} else {
+				_, _ = yellow.Fprintf(to, "# (*) %s\n", f.Path)
+			}
+		}
+	}
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// PrintTextDiff prints the line diff from the expected text to the actual one into the writer, only the lines at
+// most context lines away from a changed line are printed if context is positive. It reports whether they differ.
+func PrintTextDiff(expected, actual string, context int, to io.Writer) bool {
+	diffs := difflib.Diff(strings.Split(expected, "\n"), strings.Split(actual, "\n"))
+	if !hasChanges(diffs) {
+		return false
+	}
+	printDiffs(diffs, context, to)
+	return true
+}
+
 func printDiffs(diffs []difflib.DiffRecord, context int, to io.Writer) {
 	if context > 0 {
 		ctx := calculateContext(diffs)
@@ -0,0 +1,334 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	velatypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/addon"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+// catalogFlags are the flags of the catalog addons are discovered from
+type catalogFlags struct {
+	v1beta1.AddonCatalog
+	secret   string
+	username string
+	password string
+}
+
+func (f *catalogFlags) add(cmd *cobra.Command) {
+	cmd.Flags().StringVar((*string)(&f.Type), "catalog-type", string(v1beta1.AddonCatalogGit), "the type of the catalog, one of git|oci|http")
+	cmd.Flags().StringVar(&f.URL, "catalog-url", "", "the Git repository, the OCI repository prefix or the base URL of the index.yaml of the catalog")
+	cmd.Flags().StringVar(&f.Ref, "catalog-ref", "", "the branch, tag or commit of the git catalog, default is master")
+	cmd.Flags().StringVar(&f.Path, "catalog-path", "", "the directory of the addons in the repository of the git catalog")
+	cmd.Flags().BoolVar(&f.PlainHTTP, "catalog-plain-http", false, "access the registry of the oci catalog through HTTP rather than HTTPS")
+}
+
+// addCredentials adds the flags of the credentials of the catalog accessed by the CLI rather than the controller
+func (f *catalogFlags) addCredentials(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.username, "catalog-username", os.Getenv("VELA_CATALOG_USERNAME"), "the username of the catalog, default is $VELA_CATALOG_USERNAME")
+	cmd.Flags().StringVar(&f.password, "catalog-password", os.Getenv("VELA_CATALOG_PASSWORD"), "the password of the catalog, default is $VELA_CATALOG_PASSWORD")
+}
+
+func (f *catalogFlags) spec() (v1beta1.AddonCatalog, error) {
+	spec := f.AddonCatalog
+	if len(spec.URL) == 0 {
+		return spec, errors.New("please specify the catalog by --catalog-url")
+	}
+	if len(f.secret) != 0 {
+		spec.SecretRef = &corev1.LocalObjectReference{Name: f.secret}
+	}
+	return spec, nil
+}
+
+// catalog creates the catalog accessed by the CLI with the credentials of the flags, the http catalog without
+// scheme is a local mirror directory
+func (f *catalogFlags) catalog() (addon.Catalog, error) {
+	spec, err := f.spec()
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type == v1beta1.AddonCatalogHTTP && !strings.Contains(spec.URL, "://") {
+		dir, err := filepath.Abs(spec.URL)
+		if err != nil {
+			return nil, err
+		}
+		spec.URL = "file://" + dir
+	}
+	var cred *oci.Credentials
+	if len(f.username) != 0 {
+		cred = &oci.Credentials{Username: f.username, Password: f.password}
+	}
+	return addon.NewCatalog(spec, cred)
+}
+
+// NewAddonCommand creates `addon` command group
+func NewAddonCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "addon",
+		Short: "Manage addons",
+		Long:  "Enable, upgrade and disable the addons discovered from Git, OCI or HTTP catalogs",
+		// the kubeconfig is loaded on demand, so the catalogs can be searched without a cluster
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		Annotations: map[string]string{
+			velatypes.TagCommandType: velatypes.TypeCap,
+		},
+	}
+	cmd.AddCommand(
+		NewAddonSearchCommand(ioStreams),
+		NewAddonMirrorCommand(ioStreams),
+		NewAddonEnableCommand(c, ioStreams),
+		NewAddonDisableCommand(c, ioStreams),
+		NewAddonListCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+// NewAddonSearchCommand creates `addon search` command to list the addons and their versions in a catalog
+func NewAddonSearchCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var catalog catalogFlags
+	cmd := &cobra.Command{
+		Use:     "search [NAME]",
+		Short:   "Search addons in a catalog",
+		Long:    "List the addons in the catalog with their versions, or the versions of the addon given",
+		Example: "vela addon search --catalog-url https://github.com/org/addons --catalog-path addons\nvela addon search ingress --catalog-type oci --catalog-url ghcr.io/org/addons",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cat, err := catalog.catalog()
+			if err != nil {
+				return err
+			}
+			names := args
+			if len(names) == 0 {
+				if names, err = cat.List(ctx); err != nil {
+					return err
+				}
+			}
+			table := newUITable()
+			table.AddRow("NAME", "LATEST", "VERSIONS")
+			for _, name := range names {
+				versions, err := cat.Versions(ctx, name)
+				if err != nil {
+					return err
+				}
+				sort.Strings(versions)
+				table.AddRow(name, addon.LatestVersion(versions), strings.Join(versions, ","))
+			}
+			ioStreams.Info(table.String())
+			return nil
+		},
+	}
+	catalog.add(cmd)
+	catalog.addCredentials(cmd)
+	return cmd
+}
+
+// NewAddonMirrorCommand creates `addon mirror` command to download the addons and their dependencies for an
+// air-gapped environment
+func NewAddonMirrorCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var catalog catalogFlags
+	var registry registryFlags
+	var dir, pushTo string
+	cmd := &cobra.Command{
+		Use:   "mirror NAME[@VERSION]...",
+		Short: "Mirror addons for air-gapped environments",
+		Long: "Download the addons and the addons they depend on transitively from the catalog, i.e., everything needed " +
+			"to enable them. The addons are written into a local directory laid out as an http catalog with the " +
+			"digests of the tarballs, or pushed to an OCI registry, e.g., the registry inside the air-gapped environment.",
+		Example: "vela addon mirror ingress@^1.2 prometheus --catalog-url https://github.com/org/addons --dir ./mirror\n" +
+			"vela addon mirror ingress --catalog-type http --catalog-url ./mirror --push-to registry.local:5000/addons --plain-http\n" +
+			"vela addon enable ingress --catalog-type oci --catalog-url registry.local:5000/addons --catalog-plain-http",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify the addons to mirror")
+			}
+			if (len(dir) == 0) == (len(pushTo) == 0) {
+				return errors.New("please specify either --dir or --push-to")
+			}
+			cat, err := catalog.catalog()
+			if err != nil {
+				return err
+			}
+			addons, err := addon.Collect(ctx, cat, args)
+			if err != nil {
+				return err
+			}
+			if len(dir) != 0 {
+				err = addon.WriteMirror(dir, addons)
+			} else {
+				err = addon.PushMirror(ctx, registry.client(), pushTo, addons)
+			}
+			if err != nil {
+				return err
+			}
+			for _, a := range addons {
+				ioStreams.Infof("Addon %s %s mirrored.\n", a.Name, a.Version)
+			}
+			return nil
+		},
+	}
+	catalog.add(cmd)
+	catalog.addCredentials(cmd)
+	registry.add(cmd)
+	cmd.Flags().StringVar(&dir, "dir", "", "the local directory to write the addons into")
+	cmd.Flags().StringVar(&pushTo, "push-to", "", "the repository prefix of the OCI registry to push the addons to, e.g., registry.local:5000/addons")
+	return cmd
+}
+
+// NewAddonEnableCommand creates `addon enable` command to enable or upgrade an addon from a catalog
+func NewAddonEnableCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var catalog catalogFlags
+	var version string
+	cmd := &cobra.Command{
+		Use:   "enable NAME",
+		Short: "Enable an addon",
+		Long: "Enable the addon from the catalog, the addons it depends on are enabled from the same catalog first. " +
+			"The catalog and the version constraint of the addon enabled already are updated.",
+		Example: "vela addon enable ingress --catalog-url https://github.com/org/addons --catalog-path addons\n" +
+			"vela addon enable ingress --catalog-type oci --catalog-url ghcr.io/org/addons --version ^1.2",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an addon")
+			}
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			spec, err := catalog.spec()
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			a := &v1beta1.Addon{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: args[0]}, a)
+			switch {
+			case apierrors.IsNotFound(err):
+				a = &v1beta1.Addon{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: args[0]},
+					Spec:       v1beta1.AddonSpec{Catalog: spec, Version: version},
+				}
+				err = k8sClient.Create(ctx, a)
+			case err == nil:
+				a.Spec = v1beta1.AddonSpec{Catalog: spec, Version: version}
+				err = k8sClient.Update(ctx, a)
+			}
+			if err != nil {
+				return err
+			}
+			ioStreams.Infof("Addon %s is being enabled, check its status by `vela addon list -n %s`.\n", a.Name, namespace)
+			return nil
+		},
+	}
+	catalog.add(cmd)
+	cmd.Flags().StringVar(&catalog.secret, "catalog-secret", "", "the Secret in the namespace with the credentials of the catalog")
+	cmd.Flags().StringVar(&version, "version", "", "the semantic version constraint of the addon, e.g., ^1.2, default is the latest version")
+	cmd.Flags().StringP(Namespace, "n", oam.SystemDefinitonNamespace, "specify the namespace to enable the addon in")
+	return cmd
+}
+
+// NewAddonDisableCommand creates `addon disable` command to disable an addon
+func NewAddonDisableCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	cmd := &cobra.Command{
+		Use:     "disable NAME",
+		Short:   "Disable an addon",
+		Long:    "Disable the addon and delete its application, it's disabled only after the addons depending on it",
+		Example: "vela addon disable ingress",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an addon")
+			}
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			a := &v1beta1.Addon{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: args[0]}}
+			if err := k8sClient.Delete(ctx, a); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			ioStreams.Infof("Addon %s is being disabled.\n", a.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringP(Namespace, "n", oam.SystemDefinitonNamespace, "specify the namespace of the addon")
+	return cmd
+}
+
+// NewAddonListCommand creates `addon list` command to list the addons enabled
+func NewAddonListCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List addons",
+		Long:    "List the addons in the namespace with their phases, versions and the latest versions in the catalogs",
+		Example: "vela addon list -n vela-system",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			addons := &v1beta1.AddonList{}
+			if err := k8sClient.List(ctx, addons, client.InNamespace(namespace)); err != nil {
+				return err
+			}
+			sort.Slice(addons.Items, func(i, j int) bool { return addons.Items[i].Name < addons.Items[j].Name })
+			table := newUITable()
+			table.AddRow("NAME", "PHASE", "VERSION", "LATEST", "DEPENDENCIES", "MESSAGE")
+			for _, a := range addons.Items {
+				table.AddRow(a.Name, a.Status.Phase, a.Status.Version, a.Status.LatestVersion, strings.Join(a.Status.Dependencies, ","), a.Status.Message)
+			}
+			ioStreams.Info(table.String())
+			return nil
+		},
+	}
+	cmd.Flags().StringP(Namespace, "n", oam.SystemDefinitonNamespace, "specify the namespace of the addons")
+	return cmd
+}
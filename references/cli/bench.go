@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	corev1beta1 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/appfile/api"
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
+)
+
+// BenchRenderCmdOptions contains `bench render` cmd options
+type BenchRenderCmdOptions struct {
+	cmdutil.IOStreams
+	ApplicationFile string
+	DefinitionFile  string
+	Iterations      int
+	Offline         bool
+	Baseline        string
+	Threshold       float64
+}
+
+// NewBenchCommand creates `bench` command group
+func NewBenchCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark utilities",
+		Long:  "Benchmark utilities to catch performance regressions before upgrades",
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeSystem,
+		},
+	}
+	cmd.AddCommand(NewBenchRenderCommand(c, ioStreams))
+	return cmd
+}
+
+// NewBenchRenderCommand creates `bench render` command
+func NewBenchRenderCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	o := &BenchRenderCmdOptions{IOStreams: ioStreams}
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Benchmark rendering applications",
+		Long: "Render applications repeatedly and report the p50/p99 latency and allocations of each stage, i.e., parse, " +
+			"render and assemble. Compare with a baseline report to fail on performance regressions.",
+		Example: "vela bench render -f ./apps -d ./definitions --offline -o json > baseline.json\n" +
+			"vela bench render -f ./apps -d ./definitions --offline --baseline baseline.json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			namespace := ""
+			if !o.Offline {
+				if err := c.SetConfig(); err != nil {
+					return err
+				}
+				velaEnv, err := GetEnv(cmd)
+				if err != nil {
+					return err
+				}
+				namespace = velaEnv.Namespace
+			}
+			results, err := BenchmarkRenderApplications(o, c, namespace)
+			if err != nil {
+				return err
+			}
+			if err := printRenderBenchmarks(results, format, ioStreams); err != nil {
+				return err
+			}
+			if len(o.Baseline) == 0 {
+				return nil
+			}
+			return compareWithBaseline(o.Baseline, results, o.Threshold)
+		},
+	}
+	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file, directory of application files or directory of an application with an index manifest")
+	cmd.Flags().StringVarP(&o.DefinitionFile, "definition", "d", "", "specify a definition file or directory, it will only be used in rendering rather than applied to K8s cluster")
+	cmd.Flags().IntVarP(&o.Iterations, "iterations", "n", 100, "times to render each application")
+	cmd.Flags().BoolVar(&o.Offline, "offline", false, "render without a cluster, all definitions must be specified by --definition and templates can't import kube packages")
+	cmd.Flags().StringVar(&o.Baseline, "baseline", "", "a report printed with -o json before, fail if any stage regresses compared with it")
+	cmd.Flags().Float64Var(&o.Threshold, "threshold", 0.2, "the ratio of growth in p99 latency or allocations regarded as a regression")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+// BenchmarkRenderApplications benchmarks rendering the applications in the file or directory
+func BenchmarkRenderApplications(o *BenchRenderCmdOptions, c common.Args, namespace string) ([]dryrun.RenderBenchmark, error) {
+	var defs []oam.Object
+	var err error
+	if o.DefinitionFile != "" {
+		if defs, err = ReadObjectsFromFile(o.DefinitionFile); err != nil {
+			return nil, err
+		}
+	}
+	apps, err := readApplicationsFromPath(o.ApplicationFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt *dryrun.Option
+	if o.Offline {
+		pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+		if err != nil {
+			return nil, err
+		}
+		opt = dryrun.NewDryRunOption(fake.NewFakeClientWithScheme(common.Scheme), nil, pd, defs)
+	} else {
+		newClient, err := c.GetClient()
+		if err != nil {
+			return nil, err
+		}
+		pd, err := c.GetPackageDiscover()
+		if err != nil {
+			return nil, err
+		}
+		dm, err := discoverymapper.New(c.Config)
+		if err != nil {
+			return nil, err
+		}
+		opt = dryrun.NewDryRunOption(newClient, dm, pd, defs)
+	}
+
+	results := make([]dryrun.RenderBenchmark, 0, len(apps))
+	for _, app := range apps {
+		if len(app.Namespace) == 0 {
+			app.Namespace = namespace
+		}
+		result, err := opt.BenchmarkRender(context.Background(), app, o.Iterations)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "benchmark application %s", app.Name)
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+func readApplicationsFromPath(path string) ([]*corev1beta1.Application, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() || api.IsApplicationDir(path) {
+		app, err := readApplicationFromFile(path)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "read application file: %s", path)
+		}
+		return []*corev1beta1.Application{app}, nil
+	}
+	fis, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var apps []*corev1beta1.Application
+	for _, fi := range fis {
+		ext := filepath.Ext(fi.Name())
+		if fi.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		app, err := readApplicationFromFile(filepath.Join(path, fi.Name()))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "read application file: %s", fi.Name())
+		}
+		apps = append(apps, app)
+	}
+	if len(apps) == 0 {
+		return nil, errors.Errorf("no application found in %s", path)
+	}
+	return apps, nil
+}
+
+func printRenderBenchmarks(results []dryrun.RenderBenchmark, format string, ioStreams cmdutil.IOStreams) error {
+	if isStructuredOutput(format) {
+		return printStructured(ioStreams, format, RenderBenchmarkOutput{
+			OutputMeta: newOutputMeta(RenderBenchmarkOutputKind),
+			Items:      results,
+		})
+	}
+	table := newUITable()
+	table.AddRow("APPLICATION", "STAGE", "P50", "P99", "MEAN", "ALLOCS/OP", "BYTES/OP")
+	for _, r := range results {
+		for _, s := range r.Stages {
+			table.AddRow(r.Application, s.Stage, s.P50, s.P99, s.Mean, s.AllocsPerOp, s.BytesPerOp)
+		}
+	}
+	ioStreams.Info(table.String())
+	return nil
+}
+
+func compareWithBaseline(path string, results []dryrun.RenderBenchmark, threshold float64) error {
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return errors.Wrap(err, "cannot read the baseline")
+	}
+	baseline := RenderBenchmarkOutput{}
+	if err := yaml.Unmarshal(b, &baseline); err != nil {
+		return errors.Wrap(err, "invalid baseline, it should be printed by `vela bench render -o json`")
+	}
+	if regressions := dryrun.CompareRenderBenchmarks(baseline.Items, results, threshold); len(regressions) != 0 {
+		return fmt.Errorf("rendering regresses by more than %.0f%%:\n%s", threshold*100, strings.Join(regressions, "\n"))
+	}
+	return nil
+}
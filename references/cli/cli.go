@@ -80,7 +80,11 @@ func NewCommand() *cobra.Command {
 		NewListCommand(commandArgs, ioStream),
 		NewDeleteCommand(commandArgs, ioStream),
 		NewAppStatusCommand(commandArgs, ioStream),
+		NewTopCommand(commandArgs, ioStream),
+		NewWorkflowCommand(commandArgs, ioStream),
+		NewRollbackCommand(commandArgs, ioStream),
 		NewExecCommand(commandArgs, ioStream),
+		NewDebugCommand(commandArgs, ioStream),
 		NewPortForwardCommand(commandArgs, ioStream),
 		NewLogsCommand(commandArgs, ioStream),
 		NewEnvCommand(commandArgs, ioStream),
@@ -88,6 +92,8 @@ func NewCommand() *cobra.Command {
 
 		// Capabilities
 		CapabilityCommandGroup(commandArgs, ioStream),
+		DefinitionCommandGroup(commandArgs, ioStream),
+		NewAddonCommand(commandArgs, ioStream),
 		NewTemplateCommand(ioStream),
 		NewTraitsCommand(commandArgs, ioStream),
 		NewComponentsCommand(commandArgs, ioStream),
@@ -95,6 +101,7 @@ func NewCommand() *cobra.Command {
 
 		// Helper
 		SystemCommandGroup(commandArgs, ioStream),
+		NewBenchCommand(commandArgs, ioStream),
 		NewDashboardCommand(commandArgs, ioStream, fake.FrontendSource),
 		NewCompletionCommand(),
 		NewVersionCommand(),
@@ -0,0 +1,463 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/debug"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+const (
+	defaultDebugImage   = "busybox"
+	debugContainerStart = 2 * time.Minute
+)
+
+// VelaDebugOptions creates options for `debug` command
+type VelaDebugOptions struct {
+	Component string
+	Pod       string
+	Image     string
+	Target    string
+	Stdin     bool
+	TTY       bool
+	Attach    bool
+
+	// Snapshot inspects the debug snapshot of the application instead of debugging a pod
+	Snapshot bool
+	// Step is the workflow step to inspect in the debug snapshot, the sub-steps are named as STEP/SUB_STEP
+	Step string
+	// Eval re-evaluates the CUE template of the step or the component with the context and parameter persisted
+	Eval bool
+	// TemplateFile overrides the CUE template to re-evaluate
+	TemplateFile string
+	format       string
+
+	VelaC     common2.Args
+	ioStreams cmdutil.IOStreams
+
+	client  client.Client
+	gateway *multicluster.ClusterGateway
+	app     *v1beta1.Application
+}
+
+// NewDebugCommand creates `debug` command to attach an ephemeral debug container to a pod of a component
+func NewDebugCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	o := &VelaDebugOptions{ioStreams: ioStreams}
+	cmd := &cobra.Command{
+		Use:   "debug APP_NAME",
+		Short: "Debug a pod of a component with an ephemeral container, or the rendering of an application",
+		Long: "Attach an ephemeral debug container to a pod of a component, the pods are resolved through the resources of " +
+			"the application, including the ones in the managed clusters. The EphemeralContainers feature must be enabled " +
+			"in the cluster of the pod.\n\n" +
+			"With --snapshot, --step or --eval, the debug snapshot of the application is inspected instead, i.e., the CUE " +
+			"context, parameters, step inputs and outputs and the manifests rendered in the last reconciliation. The " +
+			"application must be annotated with " + oam.AnnotationDebug + "=true for the snapshot to be persisted. The CUE " +
+			"template of a step or a component can be re-evaluated with --eval, optionally replaced by --template.",
+		Example: "vela debug APP_NAME --component frontend\nvela debug APP_NAME -c frontend --image nicolaka/netshoot --target frontend\n" +
+			"vela debug APP_NAME --snapshot\nvela debug APP_NAME --step deploy-prod -o json\n" +
+			"vela debug APP_NAME --step deploy-prod --eval --template ./fixed.cue",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.SetConfig(); err != nil {
+				return err
+			}
+			o.VelaC = c
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			if o.client, err = o.VelaC.GetClient(); err != nil {
+				return err
+			}
+			if o.app, err = loadRemoteApplication(o.client, env.Namespace, args[0]); err != nil {
+				return err
+			}
+			if o.Snapshot || o.Eval || len(o.Step) != 0 {
+				if o.format, err = getOutputFormat(cmd); err != nil {
+					return err
+				}
+				return o.RunSnapshot(context.Background())
+			}
+			o.gateway = multicluster.NewClusterGateway(o.client)
+			return o.Run(context.Background())
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.Flags().StringVarP(&o.Component, "component", "c", "", "the component to debug, it's asked if the application has multiple components")
+	cmd.Flags().StringVar(&o.Pod, "pod", "", "the pod of the component to debug, it's asked if the component has multiple pods")
+	cmd.Flags().StringVar(&o.Image, "image", defaultDebugImage, "the image of the debug container")
+	cmd.Flags().StringVar(&o.Target, "target", "", "the container of the pod whose process namespace is shared with the debug container")
+	cmd.Flags().BoolVarP(&o.Stdin, "stdin", "i", defaultStdin, "Pass stdin to the debug container")
+	cmd.Flags().BoolVarP(&o.TTY, "tty", "t", defaultTTY, "Stdin is a TTY")
+	cmd.Flags().BoolVar(&o.Attach, "attach", true, "attach to the debug container after it's running")
+	cmd.Flags().BoolVar(&o.Snapshot, "snapshot", false, "inspect the debug snapshot of the application instead of debugging a pod")
+	cmd.Flags().StringVar(&o.Step, "step", "", "the workflow step to inspect in the debug snapshot, the sub-steps are named as STEP/SUB_STEP")
+	cmd.Flags().BoolVar(&o.Eval, "eval", false, "re-evaluate the CUE template of the step or the component in the debug snapshot")
+	cmd.Flags().StringVar(&o.TemplateFile, "template", "", "the CUE template file re-evaluated by --eval instead of the one in the debug snapshot")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+// Run attaches the debug container to the pod of the component
+func (o *VelaDebugOptions) Run(ctx context.Context) error {
+	// a TTY is only allocated for stdin like kubectl
+	o.TTY = o.TTY && o.Stdin
+	component, err := o.componentName()
+	if err != nil {
+		return err
+	}
+	pods, err := common.ListComponentPods(ctx, o.client, o.clusterClient, o.app, component)
+	if err != nil {
+		return err
+	}
+	target, err := o.choosePod(component, pods)
+	if err != nil {
+		return err
+	}
+	if err := o.checkPermission(ctx, target); err != nil {
+		return err
+	}
+	cfg := o.VelaC.Config
+	if len(target.Cluster) != 0 {
+		cluster, err := o.getCluster(ctx, target.Cluster)
+		if err != nil {
+			return err
+		}
+		if cfg, err = o.gateway.RestConfigOf(ctx, cluster); err != nil {
+			return err
+		}
+	}
+	cs, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	pod := &target.Pod
+	container := debugContainer(o.Image, o.Target, o.Stdin, o.TTY)
+	if err := addEphemeralContainer(ctx, cs, pod, container); err != nil {
+		return err
+	}
+	o.ioStreams.Infof("Debug container %s is added to pod %s/%s%s.\n", container.Name, pod.Namespace, pod.Name, clusterSuffix(target.Cluster))
+	if !o.Attach {
+		return nil
+	}
+	if err := waitForEphemeralContainer(ctx, cs, pod, container.Name); err != nil {
+		return err
+	}
+	return o.attach(cfg, cs, pod, container.Name)
+}
+
+// RunSnapshot inspects the debug snapshot of the application, the step or the component is shown if it's specified.
+// The CUE template of the step or the component is re-evaluated with the context and parameter persisted by --eval.
+func (o *VelaDebugOptions) RunSnapshot(ctx context.Context) error {
+	s, err := debug.Load(ctx, o.client, o.app)
+	if err != nil {
+		return err
+	}
+	if o.Eval {
+		return o.eval(s)
+	}
+	format := o.format
+	if !isStructuredOutput(format) {
+		format = OutputYAML
+	}
+	switch {
+	case len(o.Step) != 0:
+		step := s.Step(o.Step)
+		if step == nil {
+			return errors.Errorf("step %s is not found in the debug snapshot of application %s", o.Step, o.app.Name)
+		}
+		return printStructured(o.ioStreams, format, step)
+	case len(o.Component) != 0:
+		comp := s.Component(o.Component)
+		if comp == nil {
+			return errors.Errorf("component %s is not found in the debug snapshot of application %s", o.Component, o.app.Name)
+		}
+		return printStructured(o.ioStreams, format, comp)
+	}
+	return printDebugSnapshot(o.app, s, o.format, o.ioStreams)
+}
+
+func (o *VelaDebugOptions) eval(s *debug.Snapshot) error {
+	var (
+		typ, template string
+		dctx          debug.Context
+		parameter     map[string]interface{}
+	)
+	switch {
+	case len(o.Step) != 0:
+		step := s.Step(o.Step)
+		if step == nil {
+			return errors.Errorf("step %s is not found in the debug snapshot of application %s", o.Step, o.app.Name)
+		}
+		typ, template, dctx, parameter = step.Type, step.Template, step.Context, step.Properties
+	case len(o.Component) != 0:
+		comp := s.Component(o.Component)
+		if comp == nil {
+			return errors.Errorf("component %s is not found in the debug snapshot of application %s", o.Component, o.app.Name)
+		}
+		typ, template, dctx, parameter = comp.Type, comp.Template, comp.Context, comp.Parameter
+	default:
+		return errors.New("please specify the step or the component to evaluate by --step or --component")
+	}
+	if len(o.TemplateFile) != 0 {
+		b, err := ioutil.ReadFile(o.TemplateFile)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read template %s", o.TemplateFile)
+		}
+		template = string(b)
+	}
+	pd, err := o.VelaC.GetPackageDiscover()
+	if err != nil {
+		return err
+	}
+	info, err := debug.Eval(pd, typ, template, dctx, parameter)
+	if err != nil {
+		return err
+	}
+	return printTemplateDebugInfo(typ, info, true, o.format, o.ioStreams)
+}
+
+func printDebugSnapshot(app *v1beta1.Application, s *debug.Snapshot, format string, ioStreams cmdutil.IOStreams) error {
+	if isStructuredOutput(format) {
+		return printStructured(ioStreams, format, DebugSnapshotOutput{
+			OutputMeta: newOutputMeta(DebugSnapshotOutputKind),
+			Name:       app.Name,
+			Namespace:  app.Namespace,
+			Snapshot:   s,
+		})
+	}
+	if len(s.Steps) != 0 {
+		table := newUITable()
+		table.AddRow("STEP", "TYPE", "PHASE", "MESSAGE")
+		for _, step := range s.Steps {
+			table.AddRow(step.Name, step.Type, step.Phase, step.Message)
+			for _, sub := range step.SubSteps {
+				table.AddRow(step.Name+"/"+sub.Name, sub.Type, sub.Phase, sub.Message)
+			}
+		}
+		ioStreams.Info(table.String())
+		ioStreams.Info()
+	}
+	table := newUITable()
+	table.AddRow("COMPONENT", "TYPE", "MANIFESTS")
+	for _, comp := range s.Components {
+		table.AddRow(comp.Name, comp.Type, len(comp.Manifests))
+	}
+	ioStreams.Info(table.String())
+	if len(s.Errors) != 0 {
+		ioStreams.Info()
+		conds := make([]string, 0, len(s.Errors))
+		for t := range s.Errors {
+			conds = append(conds, t)
+		}
+		sort.Strings(conds)
+		for _, t := range conds {
+			ioStreams.Infof("Error (%s): %s\n", t, s.Errors[t])
+		}
+	}
+	return nil
+}
+
+func (o *VelaDebugOptions) componentName() (string, error) {
+	if len(o.Component) != 0 {
+		for _, comp := range o.app.Spec.Components {
+			if comp.Name == o.Component {
+				return o.Component, nil
+			}
+		}
+		return "", errors.Errorf("component %s is not found in application %s", o.Component, o.app.Name)
+	}
+	var names []string
+	for _, comp := range o.app.Spec.Components {
+		names = append(names, comp.Name)
+	}
+	return common2.AskToChooseOneService(names)
+}
+
+// choosePod returns the pod specified by --pod, or asks to choose one if the component has multiple pods
+func (o *VelaDebugOptions) choosePod(component string, pods []common.ComponentPod) (common.ComponentPod, error) {
+	if len(pods) == 0 {
+		return common.ComponentPod{}, errors.Errorf("no pod of component %s is found", component)
+	}
+	names := make([]string, len(pods))
+	for i, p := range pods {
+		names[i] = p.Pod.Name + clusterSuffix(p.Cluster)
+		if len(o.Pod) != 0 && p.Pod.Name == o.Pod {
+			return p, nil
+		}
+	}
+	if len(o.Pod) != 0 {
+		return common.ComponentPod{}, errors.Errorf("pod %s of component %s is not found", o.Pod, component)
+	}
+	name, err := common2.AskToChooseOneService(names)
+	if err != nil {
+		return common.ComponentPod{}, err
+	}
+	for i := range names {
+		if names[i] == name {
+			return pods[i], nil
+		}
+	}
+	return common.ComponentPod{}, errors.Errorf("pod %s is not found", name)
+}
+
+func clusterSuffix(cluster string) string {
+	if len(cluster) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (cluster %s)", cluster)
+}
+
+func (o *VelaDebugOptions) getCluster(ctx context.Context, name string) (*v1beta1.Cluster, error) {
+	cluster := &v1beta1.Cluster{}
+	if err := o.client.Get(ctx, client.ObjectKey{Namespace: o.app.Namespace, Name: name}, cluster); err != nil {
+		return nil, errors.Wrapf(err, "cannot get cluster %s", name)
+	}
+	return cluster, nil
+}
+
+func (o *VelaDebugOptions) clusterClient(ctx context.Context, name string) (client.Client, error) {
+	cluster, err := o.getCluster(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return o.gateway.ClientOf(ctx, cluster)
+}
+
+// checkPermission checks whether the current user is allowed to add ephemeral containers to the pods in the
+// namespace. The pods in the managed clusters are accessed by the credentials of the clusters, so the permission of
+// the user in the namespace of the application in the control plane cluster is checked for them.
+func (o *VelaDebugOptions) checkPermission(ctx context.Context, target common.ComponentPod) error {
+	namespace := target.Pod.Namespace
+	if len(target.Cluster) != 0 {
+		namespace = o.app.Namespace
+	}
+	cs, err := kubernetes.NewForConfig(o.VelaC.Config)
+	if err != nil {
+		return err
+	}
+	review, err := cs.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "update",
+				Resource:    "pods",
+				Subresource: "ephemeralcontainers",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "cannot review the permission to debug pods")
+	}
+	if !review.Status.Allowed {
+		return errors.Errorf("you are not allowed to debug pods in namespace %s", namespace)
+	}
+	return nil
+}
+
+// debugContainer returns the ephemeral debug container, it shares the process namespace of the target container
+// if it's specified
+func debugContainer(image, target string, stdin, tty bool) corev1.EphemeralContainer {
+	return corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     "debugger-" + utilrand.String(5),
+			Image:                    image,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			Stdin:                    stdin,
+			TTY:                      tty,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: target,
+	}
+}
+
+func addEphemeralContainer(ctx context.Context, cs kubernetes.Interface, pod *corev1.Pod, container corev1.EphemeralContainer) error {
+	pods := cs.CoreV1().Pods(pod.Namespace)
+	ecs, err := pods.GetEphemeralContainers(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "cannot get the ephemeral containers of pod %s, the EphemeralContainers feature may be disabled", pod.Name)
+	}
+	ecs.EphemeralContainers = append(ecs.EphemeralContainers, container)
+	if _, err := pods.UpdateEphemeralContainers(ctx, pod.Name, ecs, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "cannot add debug container to pod %s", pod.Name)
+	}
+	return nil
+}
+
+func waitForEphemeralContainer(ctx context.Context, cs kubernetes.Interface, pod *corev1.Pod, name string) error {
+	return wait.PollImmediate(time.Second, debugContainerStart, func() (bool, error) {
+		live, err := cs.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, s := range live.Status.EphemeralContainerStatuses {
+			if s.Name != name {
+				continue
+			}
+			if s.State.Terminated != nil {
+				return false, errors.Errorf("debug container %s terminated: %s", name, s.State.Terminated.Reason)
+			}
+			return s.State.Running != nil, nil
+		}
+		return false, nil
+	})
+}
+
+// attach attaches to the debug container like `kubectl attach`
+func (o *VelaDebugOptions) attach(cfg *rest.Config, cs kubernetes.Interface, pod *corev1.Pod, name string) error {
+	req := cs.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: name,
+			Stdin:     o.Stdin,
+			Stdout:    true,
+			Stderr:    !o.TTY,
+			TTY:       o.TTY,
+		}, scheme.ParameterCodec)
+	return streamToPod(cfg, req.URL(), o.ioStreams, o.Stdin, o.TTY)
+}
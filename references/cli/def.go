@@ -0,0 +1,325 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+const (
+	// FlagDryRun is the flag to only print the result without changing the cluster
+	FlagDryRun = "dry-run"
+	// FlagRetention is the flag of the minimal age of the dangling definitions to prune
+	FlagRetention = "retention"
+	// FlagAllNamespaces is the flag to operate on all namespaces
+	FlagAllNamespaces = "all-namespaces"
+)
+
+// DefinitionCommandGroup commands for definitions management
+func DefinitionCommandGroup(c common2.Args, ioStream cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "def",
+		Short: "Manage definitions",
+		Long:  "Manage X-Definitions and their revisions in the cluster",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeCap,
+		},
+	}
+	cmd.AddCommand(
+		NewDefinitionListCommand(c, ioStream),
+		NewDefinitionPruneCommand(c, ioStream),
+		NewDefinitionDryRunCommand(c, ioStream),
+		NewDefinitionBundleCommand(c, ioStream),
+		NewDefinitionTestCommand(c, ioStream),
+		NewDefinitionInitCommand(ioStream),
+		NewDefinitionVetCommand(ioStream),
+		NewDefinitionRenderCommand(ioStream),
+		NewDefinitionApplyCommand(c, ioStream),
+		NewDefinitionDocGenCommand(c, ioStream),
+	)
+	return cmd
+}
+
+// NewDefinitionListCommand lists the definitions in the cluster
+func NewDefinitionListCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List definitions",
+		Long:    "List component, trait, policy and workflow step definitions in the cluster",
+		Example: "vela def list -A\nvela def list -o json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := getDefinitionNamespace(cmd)
+			if err != nil {
+				return err
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			defs, err := common.ListDefinitions(ctx, k8sClient, namespace)
+			if err != nil {
+				return err
+			}
+			return printDefinitionList(defs, format, ioStreams)
+		},
+	}
+	cmd.Flags().StringP(Namespace, "n", "", "specify the namespace of the definitions, default is the current env namespace")
+	cmd.Flags().BoolP(FlagAllNamespaces, "A", false, "list definitions in all namespaces")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+// NewDefinitionPruneCommand prunes the definitions and definition revisions which are not used by any application
+func NewDefinitionPruneCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Prune dangling definitions",
+		Long: "Delete definitions and definition revisions not used by any application or application revision, " +
+			"along with their capability ConfigMaps. Resources labeled with " + oam.LabelDefinitionPruneProtection +
+			"=true are never pruned.",
+		Example: `vela def prune --dry-run --retention 168h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, err := cmd.Flags().GetBool(FlagDryRun)
+			if err != nil {
+				return err
+			}
+			retention, err := cmd.Flags().GetDuration(FlagRetention)
+			if err != nil {
+				return err
+			}
+			namespace, err := getDefinitionNamespace(cmd)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			dangling, err := common.FindDanglingDefinitions(ctx, k8sClient, common.PruneOptions{
+				Namespace: namespace,
+				Retention: retention,
+			})
+			if err != nil {
+				return err
+			}
+			if len(dangling) == 0 {
+				ioStreams.Info("No dangling definitions found.")
+				return nil
+			}
+			printDanglingDefinitions(dangling, ioStreams)
+			if dryRun {
+				ioStreams.Infof("%d resources would be pruned (dry run).\n", len(dangling))
+				return nil
+			}
+			if err = common.PruneDefinitions(ctx, k8sClient, dangling); err != nil {
+				return err
+			}
+			ioStreams.Infof("Successfully pruned %d resources.\n", len(dangling))
+			return nil
+		},
+	}
+	cmd.Flags().Bool(FlagDryRun, false, "only print the definitions to prune without deleting them")
+	cmd.Flags().Duration(FlagRetention, 7*24*time.Hour, "only prune dangling definitions older than the retention")
+	cmd.Flags().StringP(Namespace, "n", "", "specify the namespace of the definitions, default is the current env namespace")
+	cmd.Flags().BoolP(FlagAllNamespaces, "A", false, "prune dangling definitions in all namespaces")
+	return cmd
+}
+
+// NewDefinitionDryRunCommand renders the CUE template of a component definition with the given properties, the
+// evaluated context, parameter and intermediate values of the template are shown with --show-context
+func NewDefinitionDryRunCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var properties, compName, appName string
+	var showContext bool
+	cmd := &cobra.Command{
+		Use:   "dry-run COMPONENT_TYPE",
+		Short: "Dry run a component definition",
+		Long: "Render the CUE template of a component definition with the given properties without applying anything. " +
+			"With --show-context, the evaluated context, parameter and intermediate values of the template are shown " +
+			"to debug why an expression is evaluated unexpectedly, the secrets are redacted.",
+		Example: `vela def dry-run webservice -p '{"image":"nginx","port":80}' --show-context`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify a component type")
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			if namespace == "" {
+				env, err := GetEnv(cmd)
+				if err != nil {
+					return err
+				}
+				namespace = env.Namespace
+			}
+			props := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(properties), &props); err != nil {
+				return errors.Wrap(err, "invalid properties")
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			dm, err := discoverymapper.New(c.Config)
+			if err != nil {
+				return err
+			}
+			pd, err := c.GetPackageDiscover()
+			if err != nil {
+				return err
+			}
+			info, err := common.DebugComponentTemplate(ctx, k8sClient, dm, pd, common.TemplateDebugOptions{
+				Namespace:     namespace,
+				ComponentType: args[0],
+				ComponentName: compName,
+				AppName:       appName,
+				Properties:    props,
+			})
+			if err != nil {
+				return err
+			}
+			return printTemplateDebugInfo(args[0], info, showContext, format, ioStreams)
+		},
+	}
+	cmd.Flags().StringVarP(&properties, "properties", "p", "{}", "the properties of the component in JSON or YAML")
+	cmd.Flags().StringVar(&compName, "name", "", "the name of the component, i.e., context.name, default is the component type")
+	cmd.Flags().StringVar(&appName, "app", "", "the name of the application, i.e., context.appName, default is the component name")
+	cmd.Flags().BoolVar(&showContext, "show-context", false, "show the evaluated context, parameter and intermediate values of the template")
+	cmd.Flags().StringP(Namespace, "n", "", "specify the namespace of the definition, default is the current env namespace")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+func getDefinitionNamespace(cmd *cobra.Command) (string, error) {
+	all, err := cmd.Flags().GetBool(FlagAllNamespaces)
+	if err != nil {
+		return "", err
+	}
+	if all {
+		return "", nil
+	}
+	namespace, err := cmd.Flags().GetString(Namespace)
+	if err != nil {
+		return "", err
+	}
+	if namespace != "" {
+		return namespace, nil
+	}
+	env, err := GetEnv(cmd)
+	if err != nil {
+		return "", err
+	}
+	return env.Namespace, nil
+}
+
+func printTemplateDebugInfo(defName string, info *definition.TemplateDebugInfo, showContext bool, format string, ioStreams cmdutil.IOStreams) error {
+	if info.Error != "" {
+		ioStreams.Errorf("Warning: the template is not evaluated completely: %s\n", info.Error)
+	}
+	if !isStructuredOutput(format) {
+		format = OutputYAML
+	}
+	if showContext {
+		return printStructured(ioStreams, format, TemplateDebugOutput{
+			OutputMeta:        newOutputMeta(TemplateDebugOutputKind),
+			Definition:        defName,
+			TemplateDebugInfo: info,
+		})
+	}
+	rendered := map[string]interface{}{definition.OutputFieldName: info.Output}
+	if len(info.Outputs) != 0 {
+		rendered[definition.OutputsFieldName] = info.Outputs
+	}
+	return printStructured(ioStreams, format, rendered)
+}
+
+func printDanglingDefinitions(dangling []common.DanglingDefinition, ioStreams cmdutil.IOStreams) {
+	table := newUITable()
+	table.AddRow("NAMESPACE", "KIND", "NAME", "TYPE", "AGE", "CONFIGMAPS")
+	for _, d := range dangling {
+		table.AddRow(d.Namespace, d.Kind, d.Name, d.Type, duration.HumanDuration(d.Age), strings.Join(d.ConfigMaps, ","))
+	}
+	ioStreams.Info(table.String())
+}
+
+func printDefinitionList(defs []common.DefinitionInfo, format string, ioStreams cmdutil.IOStreams) error {
+	if isStructuredOutput(format) {
+		out := DefinitionListOutput{
+			OutputMeta: newOutputMeta(DefinitionListOutputKind),
+			Items:      make([]DefinitionOutput, 0, len(defs)),
+		}
+		for _, d := range defs {
+			out.Items = append(out.Items, DefinitionOutput{
+				Name:           d.Name,
+				Namespace:      d.Namespace,
+				Kind:           d.Kind,
+				Type:           d.Type,
+				Description:    d.Description,
+				LatestRevision: d.LatestRevision,
+				CreatedAt:      d.CreatedAt,
+				Ready:          d.Ready,
+				Message:        d.Message,
+			})
+		}
+		return printStructured(ioStreams, format, out)
+	}
+	table := newUITable()
+	header := []interface{}{"NAMESPACE", "NAME", "TYPE", "READY", "DESCRIPTION"}
+	if format == OutputWide {
+		header = append(header, "KIND", "REVISION", "AGE", "MESSAGE")
+	}
+	table.AddRow(header...)
+	for _, d := range defs {
+		row := []interface{}{d.Namespace, d.Name, d.Type, d.Ready, d.Description}
+		if format == OutputWide {
+			row = append(row, d.Kind, d.LatestRevision, duration.HumanDuration(time.Since(d.CreatedAt.Time)), d.Message)
+		}
+		table.AddRow(row...)
+	}
+	ioStreams.Info(table.String())
+	return nil
+}
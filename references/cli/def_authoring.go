@@ -0,0 +1,268 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/cuedef"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
+)
+
+// NewDefinitionInitCommand creates `def init` command to generate a def file
+func NewDefinitionInitCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var defType, desc, from, output string
+	cmd := &cobra.Command{
+		Use:   "init NAME",
+		Short: "Initialize a definition",
+		Long: "Generate a def file, i.e., a definition in a single CUE file with a sample template of the type, or " +
+			"convert a definition in YAML to a def file with --from.",
+		Example: "vela def init my-worker -t component --desc \"My worker\" -o my-worker.cue\nvela def init --from webservice.yaml -o webservice.cue",
+		// no cluster is needed
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var src string
+			var err error
+			switch {
+			case len(from) != 0:
+				def, err := cuedef.LoadFile(from)
+				if err != nil {
+					return err
+				}
+				if src, err = cuedef.ToCUE(def); err != nil {
+					return err
+				}
+			case len(args) == 0:
+				return errors.New("please specify the name of the definition")
+			default:
+				if src, err = cuedef.Init(args[0], defType, desc); err != nil {
+					return err
+				}
+			}
+			if len(output) == 0 {
+				ioStreams.Info(src)
+				return nil
+			}
+			if err := ioutil.WriteFile(output, []byte(src), 0600); err != nil {
+				return err
+			}
+			ioStreams.Infof("Definition is written into %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&defType, "type", "t", cuedef.TypeComponent, "the type of the definition, one of "+strings.Join(cuedef.Types(), "|"))
+	cmd.Flags().StringVar(&desc, "desc", "", "the description of the definition")
+	cmd.Flags().StringVar(&from, "from", "", "convert the definition in the YAML file rather than generate a new one")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "the def file to write into, default is the standard output")
+	return cmd
+}
+
+// NewDefinitionVetCommand creates `def vet` command to validate definitions
+func NewDefinitionVetCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var properties string
+	cmd := &cobra.Command{
+		Use:   "vet FILE...",
+		Short: "Validate definitions",
+		Long: "Validate the definitions in def files or YAML files without a cluster: the name, the blocks of the " +
+			"template required by the type, the workload of a component, and the schema of the parameter. With " +
+			"--properties, the properties are validated against the parameter as well. Templates importing kube " +
+			"packages can't be validated.",
+		Example: `vela def vet my-worker.cue -p '{"image":"nginx"}'`,
+		// no cluster is needed
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify the definition files")
+			}
+			props, err := parseProperties(properties)
+			if err != nil {
+				return err
+			}
+			for _, path := range args {
+				def, err := cuedef.LoadFile(path)
+				if err != nil {
+					return err
+				}
+				if err := cuedef.Vet(def, nil); err != nil {
+					return errors.WithMessage(err, path)
+				}
+				if props != nil {
+					if err := cuedef.ValidateParameters(def, props, nil); err != nil {
+						return errors.WithMessage(err, path)
+					}
+				}
+				ioStreams.Infof("%s %s in %s is valid\n", def.GetKind(), def.GetName(), path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&properties, "properties", "p", "", "the properties to validate in JSON or YAML")
+	return cmd
+}
+
+// NewDefinitionRenderCommand creates `def render` command to render a definition
+func NewDefinitionRenderCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var properties, name, workload string
+	var crd bool
+	cmd := &cobra.Command{
+		Use:   "render FILE",
+		Short: "Render a definition",
+		Long: "Render an example of the resources a component or trait definition outputs with the properties " +
+			"without a cluster, a trait patching the workload requires the workload by --workload. With --crd, " +
+			"the definition in a def file is converted to YAML instead.",
+		Example: "vela def render my-worker.cue -p '{\"image\":\"nginx\"}'\n" +
+			"vela def render my-scaler.cue -p '{\"replicas\":3}' --workload deployment.yaml\n" +
+			"vela def render my-worker.cue --crd > my-worker.yaml",
+		// no cluster is needed
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify the definition file")
+			}
+			def, err := cuedef.LoadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if crd {
+				b, err := cuedef.ToYAML(def)
+				if err != nil {
+					return err
+				}
+				ioStreams.Info(string(b))
+				return nil
+			}
+			props, err := parseProperties(properties)
+			if err != nil {
+				return err
+			}
+			opts := cuedef.RenderOptions{Name: name, Parameter: props}
+			if len(workload) != 0 {
+				data, err := ioutil.ReadFile(filepath.Clean(workload))
+				if err != nil {
+					return err
+				}
+				opts.Workload = &unstructured.Unstructured{}
+				if err := yaml.Unmarshal(data, &opts.Workload.Object); err != nil {
+					return errors.Wrapf(err, "invalid workload in %s", workload)
+				}
+			}
+			pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+			if err != nil {
+				return err
+			}
+			objs, err := cuedef.Render(def, opts, pd)
+			if err != nil {
+				return err
+			}
+			b, err := dryrun.ManifestsToYAML(objs)
+			if err != nil {
+				return err
+			}
+			ioStreams.Info(string(b))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&properties, "properties", "p", "", "the properties of the component or trait in JSON or YAML")
+	cmd.Flags().StringVar(&name, "name", "", "the name of the component, i.e., context.name, default is the name of the definition")
+	cmd.Flags().StringVar(&workload, "workload", "", "the YAML file of the workload the trait is applied to")
+	cmd.Flags().BoolVar(&crd, "crd", false, "convert the def file to the definition in YAML rather than render it")
+	return cmd
+}
+
+// NewDefinitionApplyCommand creates `def apply` command to apply definitions to the cluster
+func NewDefinitionApplyCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var namespace string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "apply FILE...",
+		Short: "Apply definitions",
+		Long: "Validate the definitions in def files or YAML files and apply them to the cluster. With --dry-run, the " +
+			"definitions are sent to the API server in the dry-run mode, so they're checked by the admission webhooks " +
+			"without being persisted.",
+		Example: "vela def apply my-worker.cue --dry-run\nvela def apply my-worker.cue my-scaler.cue -n vela-system",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify the definition files")
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			pd, err := c.GetPackageDiscover()
+			if err != nil {
+				return err
+			}
+			defs := make([]*unstructured.Unstructured, 0, len(args))
+			for _, path := range args {
+				def, err := cuedef.LoadFile(path)
+				if err != nil {
+					return err
+				}
+				if err := cuedef.Vet(def, pd); err != nil {
+					return errors.WithMessage(err, path)
+				}
+				defs = append(defs, def)
+			}
+			for _, def := range defs {
+				result, err := cuedef.Apply(ctx, k8sClient, def, namespace, dryRun)
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					ioStreams.Infof("%s %s %s (server dry run)\n", def.GetKind(), def.GetName(), result)
+					continue
+				}
+				ioStreams.Infof("%s %s %s\n", def.GetKind(), def.GetName(), result)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, Namespace, "n", types.DefaultKubeVelaNS, "the namespace to apply the definitions into")
+	cmd.Flags().BoolVar(&dryRun, FlagDryRun, false, "validate the definitions by the API server without persisting them")
+	return cmd
+}
+
+// parseProperties parses the properties in JSON or YAML, nil means no properties are specified
+func parseProperties(properties string) (map[string]interface{}, error) {
+	if len(properties) == 0 {
+		return nil, nil
+	}
+	props := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(properties), &props); err != nil {
+		return nil, errors.Wrap(err, "invalid properties")
+	}
+	return props, nil
+}
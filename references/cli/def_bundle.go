@@ -0,0 +1,250 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/oam-dev/kubevela/pkg/bundle"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/utils/oci"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+// registryFlags are the flags to access the OCI registry of the bundles
+type registryFlags struct {
+	username  string
+	password  string
+	plainHTTP bool
+}
+
+func (f *registryFlags) add(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.username, "username", os.Getenv("VELA_REGISTRY_USERNAME"), "the username of the registry, default is $VELA_REGISTRY_USERNAME")
+	cmd.Flags().StringVar(&f.password, "password", os.Getenv("VELA_REGISTRY_PASSWORD"), "the password of the registry, default is $VELA_REGISTRY_PASSWORD")
+	cmd.Flags().BoolVar(&f.plainHTTP, "plain-http", false, "access the registry through HTTP rather than HTTPS")
+}
+
+func (f *registryFlags) client() *oci.Client {
+	return &oci.Client{Username: f.username, Password: f.password, PlainHTTP: f.plainHTTP}
+}
+
+// NewDefinitionBundleCommand creates `def bundle` command group to package definitions as OCI artifacts
+func NewDefinitionBundleCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Manage definition bundles",
+		Long:  "Package definitions into bundles stored in OCI registries, and install the bundles into the cluster",
+		// the kubeconfig is loaded on demand, so the bundles can be pushed without a cluster
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	cmd.AddCommand(
+		NewDefinitionBundlePushCommand(c, ioStreams),
+		NewDefinitionBundlePullCommand(ioStreams),
+		NewDefinitionBundleInstallCommand(c, ioStreams),
+		NewDefinitionBundleListCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+// NewDefinitionBundlePushCommand creates `def bundle push` command to push the bundle in a directory to a registry
+func NewDefinitionBundlePushCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var registry registryFlags
+	cmd := &cobra.Command{
+		Use:   "push DIR REFERENCE",
+		Short: "Push a definition bundle",
+		Long: "Package the bundle in the directory, which contains " + bundle.MetadataFile + " and the definitions in the " +
+			bundle.DefinitionsDir + " directory, and push it to the OCI registry. The CUE packages imported by the " +
+			"templates are recorded and the schemas of the parameters are generated.",
+		Example: "vela def bundle push ./web ghcr.io/org/bundles/web:1.2.0",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("please specify the directory of the bundle and the reference to push it to")
+			}
+			ref, err := oci.ParseReference(args[1])
+			if err != nil {
+				return err
+			}
+			// the packages of the CRDs are only needed by the templates importing them
+			pd, err := c.GetPackageDiscover()
+			if err != nil {
+				ioStreams.Errorf("Warning: cannot discover the CUE packages of the cluster: %v\n", err)
+			}
+			b, err := bundle.Load(args[0], pd)
+			if err != nil {
+				return err
+			}
+			if ref.Tag != b.Metadata.Version {
+				ioStreams.Infof("The tag %s is different from the version %s of the bundle.\n", ref.Tag, b.Metadata.Version)
+			}
+			digest, err := bundle.Push(ctx, registry.client(), ref, b)
+			if err != nil {
+				return err
+			}
+			ref.Digest = digest
+			ioStreams.Infof("Bundle %s %s pushed to %s\n", b.Metadata.Name, b.Metadata.Version, ref)
+			return nil
+		},
+	}
+	registry.add(cmd)
+	return cmd
+}
+
+// NewDefinitionBundlePullCommand creates `def bundle pull` command to save a bundle in a registry to a directory
+func NewDefinitionBundlePullCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var registry registryFlags
+	cmd := &cobra.Command{
+		Use:   "pull REFERENCE DIR",
+		Short: "Pull a definition bundle",
+		Long: "Pull the bundle from the OCI registry into the directory, which is verified against the digest of the " +
+			"artifact. The directory can be carried into an air-gapped environment, then installed or pushed to the " +
+			"registry there.",
+		Example: "vela def bundle pull ghcr.io/org/bundles/web:1.2.0 ./web",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("please specify the reference of the bundle and the directory to save it to")
+			}
+			ref, err := oci.ParseReference(args[0])
+			if err != nil {
+				return err
+			}
+			b, pinned, err := bundle.Pull(ctx, registry.client(), ref)
+			if err != nil {
+				return err
+			}
+			if err := bundle.Save(b, args[1]); err != nil {
+				return err
+			}
+			ioStreams.Infof("Bundle %s %s pulled from %s\n", b.Metadata.Name, b.Metadata.Version, pinned)
+			return nil
+		},
+	}
+	registry.add(cmd)
+	return cmd
+}
+
+// NewDefinitionBundleInstallCommand creates `def bundle install` command to install a bundle into the cluster
+func NewDefinitionBundleInstallCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var registry registryFlags
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "install REFERENCE|DIR",
+		Short: "Install a definition bundle",
+		Long: "Install the definitions of the bundle in the OCI registry or the local directory. The tag of the reference " +
+			"is resolved to the digest, which is recorded in the definitions to pin the version installed. The bundles " +
+			"it depends on and the CRDs of the CUE packages it imports must be installed first.",
+		Example: "vela def bundle install ghcr.io/org/bundles/web:1.2.0\nvela def bundle install ./web -n my-namespace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify the reference or the directory of the bundle")
+			}
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			pd, err := c.GetPackageDiscover()
+			if err != nil {
+				return err
+			}
+			var (
+				b      *bundle.Bundle
+				pinned string
+			)
+			if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+				if b, err = bundle.Load(args[0], pd); err != nil {
+					return err
+				}
+			} else {
+				ref, err := oci.ParseReference(args[0])
+				if err != nil {
+					return err
+				}
+				var resolved oci.Reference
+				if b, resolved, err = bundle.Pull(ctx, registry.client(), ref); err != nil {
+					return err
+				}
+				pinned = resolved.String()
+			}
+			installer := &bundle.Installer{Client: k8sClient, Packages: pd, Namespace: namespace, Force: force}
+			if err := installer.Install(ctx, b, pinned); err != nil {
+				return err
+			}
+			ioStreams.Infof("Bundle %s %s installed with %d definitions.\n", b.Metadata.Name, b.Metadata.Version, len(b.Definitions))
+			return nil
+		},
+	}
+	registry.add(cmd)
+	cmd.Flags().StringP(Namespace, "n", oam.SystemDefinitonNamespace, "specify the namespace to install the definitions into")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite the definitions of others, and allow to downgrade the bundle or to replace the artifact of the version installed")
+	return cmd
+}
+
+// NewDefinitionBundleListCommand creates `def bundle list` command to list the bundles installed
+func NewDefinitionBundleListCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List definition bundles",
+		Long:    "List the definition bundles installed in the namespace",
+		Example: "vela def bundle list -n vela-system",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := cmd.Flags().GetString(Namespace)
+			if err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			installed, err := bundle.ListInstalled(ctx, k8sClient, namespace)
+			if err != nil {
+				return err
+			}
+			var names []string
+			for name := range installed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			table := newUITable()
+			table.AddRow("NAME", "VERSION", "DEFINITIONS", "REFERENCE")
+			for _, name := range names {
+				b := installed[name]
+				table.AddRow(b.Name, b.Version, strings.Join(b.Definitions, ","), b.Ref)
+			}
+			ioStreams.Info(table.String())
+			return nil
+		},
+	}
+	cmd.Flags().StringP(Namespace, "n", oam.SystemDefinitonNamespace, "specify the namespace of the bundles")
+	return cmd
+}
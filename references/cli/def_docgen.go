@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/docgen"
+)
+
+// NewDefinitionDocGenCommand creates `def doc-gen` command to generate the reference docs of definitions
+func NewDefinitionDocGenCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var namespace, defType, format, locale, output string
+	cmd := &cobra.Command{
+		Use:   "doc-gen [NAME]",
+		Short: "Generate reference docs of definitions",
+		Long: "Generate the reference docs of the component and trait definitions installed in the cluster, with the " +
+			"parameters, their types, defaults and an example of each definition. The doc of the definition is printed " +
+			"if NAME is specified, otherwise the docs of all definitions and an index page are written into the " +
+			"directory specified by --output.",
+		Example: "vela def doc-gen webservice\nvela def doc-gen --format html --output ./docs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != docgen.FormatMarkdown && format != docgen.FormatHTML {
+				return errors.Errorf("unsupported format %q, must be one of %s|%s", format, docgen.FormatMarkdown, docgen.FormatHTML)
+			}
+			if len(args) == 0 && len(output) == 0 {
+				return errors.New("please specify the name of the definition or the directory to write the docs into by --output")
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			docs, err := docgen.Generate(ctx, k8sClient, namespace, locale)
+			if err != nil {
+				return err
+			}
+			if len(args) != 0 {
+				doc := docgen.Find(docs, args[0], defType)
+				if doc == nil {
+					return fmt.Errorf("definition %s is not found in namespace %s", args[0], namespace)
+				}
+				return docgen.Render(ioStreams.Out, doc, format)
+			}
+			if err := docgen.WriteFiles(docs, output, format); err != nil {
+				return err
+			}
+			ioStreams.Infof("Docs of %d definitions are written into %s\n", len(docs), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, Namespace, "n", types.DefaultKubeVelaNS, "the namespace of the definitions")
+	cmd.Flags().StringVarP(&defType, "type", "t", "", "the type of the definition, component or trait, the component takes precedence if not specified")
+	cmd.Flags().StringVar(&format, "format", docgen.FormatMarkdown, "the format of the docs, one of "+docgen.FormatMarkdown+"|"+docgen.FormatHTML)
+	cmd.Flags().StringVar(&locale, "locale", "", "the locale of the descriptions of the parameters, e.g., zh")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "the directory to write the docs of all definitions into")
+	return cmd
+}
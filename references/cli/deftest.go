@@ -0,0 +1,145 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/deftest"
+)
+
+// NewDefinitionTestCommand creates `def test` command to run the table-driven tests of definitions
+func NewDefinitionTestCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var update, offline bool
+	cmd := &cobra.Command{
+		Use:   "test PATH",
+		Short: "Test definitions",
+		Long: "Run the test suites of definitions in the file, or the files ending with _test.yaml in the directory " +
+			"recursively. Each case renders a component with its traits and asserts on the rendered manifests by a " +
+			"YAML golden file or CUE constraints. The definitions of a suite are used instead of the ones in the " +
+			"cluster, with --offline no cluster is needed at all.",
+		Example: "vela def test ./definitions --offline\nvela def test ./definitions/webservice_test.yaml --offline --update",
+		// the kubeconfig is loaded only if the definitions are not tested offline
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify a test suite file or directory")
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			paths, err := deftest.FindSuites(args[0])
+			if err != nil {
+				return err
+			}
+			if len(paths) == 0 {
+				return errors.Errorf("no test suites found in %s", args[0])
+			}
+			runner, err := newDefinitionTestRunner(c, offline)
+			if err != nil {
+				return err
+			}
+			runner.UpdateGolden = update
+			var results []deftest.Result
+			for _, path := range paths {
+				s, err := deftest.LoadSuite(path)
+				if err != nil {
+					return err
+				}
+				r, err := runner.RunSuite(ctx, s)
+				if err != nil {
+					return err
+				}
+				results = append(results, r...)
+			}
+			return printDefinitionTestResults(results, format, ioStreams)
+		},
+	}
+	cmd.Flags().BoolVar(&update, "update", false, "write the rendered manifests into the golden files instead of comparing them")
+	cmd.Flags().BoolVar(&offline, "offline", false, "render without a cluster, the component definitions must specify their workloads and templates can't import kube packages")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+func newDefinitionTestRunner(c common2.Args, offline bool) (*deftest.Runner, error) {
+	if offline {
+		pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+		if err != nil {
+			return nil, err
+		}
+		return &deftest.Runner{Client: fake.NewFakeClientWithScheme(common2.Scheme), PackageDiscover: pd}, nil
+	}
+	if err := c.SetConfig(); err != nil {
+		return nil, err
+	}
+	k8sClient, err := c.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	dm, err := discoverymapper.New(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	pd, err := c.GetPackageDiscover()
+	if err != nil {
+		return nil, err
+	}
+	return &deftest.Runner{Client: k8sClient, DiscoveryMapper: dm, PackageDiscover: pd}, nil
+}
+
+func printDefinitionTestResults(results []deftest.Result, format string, ioStreams cmdutil.IOStreams) error {
+	out := DefinitionTestOutput{OutputMeta: newOutputMeta(DefinitionTestOutputKind), Results: results}
+	for _, r := range results {
+		if r.Passed() {
+			out.Passed++
+		} else {
+			out.Failed++
+		}
+	}
+	if isStructuredOutput(format) {
+		if err := printStructured(ioStreams, format, out); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Passed() {
+				ioStreams.Infof("PASS %s: %s (%s)\n", r.Suite, r.Case, r.Duration.Round(time.Millisecond))
+				continue
+			}
+			ioStreams.Infof("FAIL %s: %s (%s)\n%s\n", r.Suite, r.Case, r.Duration.Round(time.Millisecond), r.Failure)
+		}
+		ioStreams.Info(fmt.Sprintf("%d passed, %d failed", out.Passed, out.Failed))
+	}
+	if out.Failed != 0 {
+		return errors.Errorf("%d of %d test cases failed", out.Failed, len(results))
+	}
+	return nil
+}
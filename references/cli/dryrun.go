@@ -20,7 +20,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -28,15 +27,16 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
 
 	corev1beta1 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/appfile/api"
 	"github.com/oam-dev/kubevela/references/appfile/dryrun"
 )
 
@@ -45,6 +45,8 @@ type DryRunCmdOptions struct {
 	cmdutil.IOStreams
 	ApplicationFile string
 	DefinitionFile  string
+	// ValuesFiles are the files of the values of the parameters of the application, the later ones take precedence
+	ValuesFiles []string
 }
 
 // NewDryRunCommand creates `dry-run` command
@@ -54,7 +56,7 @@ func NewDryRunCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 		Use:                   "dry-run",
 		DisableFlagsInUseLine: true,
 		Short:                 "Dry Run an application, and output the K8s resources as result to stdout",
-		Long:                  "Dry Run an application, and output the K8s resources as result to stdout, only CUE template supported for now",
+		Long:                  "Dry Run an application, and output the K8s resources as result to stdout, the resources are assembled the way the application controller does without writing anything to the cluster, so the revision hash label and the owner reference are left out",
 		Example:               "vela dry-run",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return c.SetConfig()
@@ -73,8 +75,9 @@ func NewDryRunCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 		},
 	}
 
-	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file name")
+	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file name, or the directory of an application with an index manifest")
 	cmd.Flags().StringVarP(&o.DefinitionFile, "definition", "d", "", "specify a definition file or directory, it will only be used in dry-run rather than applied to K8s cluster")
+	cmd.Flags().StringArrayVar(&o.ValuesFiles, "values", nil, "specify a YAML file of the values of the application parameters, it can be repeated and the later files take precedence")
 	cmd.SetOut(ioStreams.Out)
 	return cmd
 }
@@ -108,36 +111,21 @@ func DryRunApplication(cmdOption *DryRunCmdOptions, c common.Args, namespace str
 	if err != nil {
 		return buff, errors.WithMessagef(err, "read application file: %s", cmdOption.ApplicationFile)
 	}
+	if err := applyValuesFiles(app, cmdOption.ValuesFiles); err != nil {
+		return buff, err
+	}
 
 	dryRunOpt := dryrun.NewDryRunOption(newClient, dm, pd, objs)
 	ctx := oamutil.SetNamespaceInCtx(context.Background(), namespace)
-	ac, comps, err := dryRunOpt.ExecuteDryRun(ctx, app)
+	manifests, err := dryRunOpt.RenderApplication(ctx, app)
 	if err != nil {
-		return buff, errors.WithMessage(err, "generate OAM objects")
+		return buff, errors.WithMessage(err, "render K8s resources")
 	}
-
-	var components = make(map[string]runtime.RawExtension)
-	for _, comp := range comps {
-		components[comp.Name] = comp.Spec.Workload
-	}
-	for _, c := range ac.Spec.Components {
-		buff.Write([]byte(fmt.Sprintf("---\n# Application(%s) -- Comopnent(%s) \n---\n\n", ac.Name, c.ComponentName)))
-		result, err := yaml.Marshal(components[c.ComponentName])
-		if err != nil {
-			return buff, errors.WithMessage(err, "marshal result for component "+c.ComponentName+" object in yaml format")
-		}
-		buff.Write(result)
-		buff.Write([]byte("\n---\n"))
-		for _, t := range c.Traits {
-			result, err := yaml.Marshal(t.Trait)
-			if err != nil {
-				return buff, errors.WithMessage(err, "marshal result for component "+c.ComponentName+" object in yaml format")
-			}
-			buff.Write(result)
-			buff.Write([]byte("\n---\n"))
-		}
-		buff.Write([]byte("\n"))
+	result, err := dryrun.ManifestsToYAML(manifests)
+	if err != nil {
+		return buff, err
 	}
+	buff.Write(result)
 	return buff, nil
 }
 
@@ -181,6 +169,10 @@ func ReadObjectsFromFile(path string) ([]oam.Object, error) {
 }
 
 func readApplicationFromFile(filename string) (*corev1beta1.Application, error) {
+	// the components of a directory-based application are kept in separate files
+	if api.IsApplicationDir(filename) {
+		return api.LoadApplicationFromDir(filename)
+	}
 
 	fileContent, err := ioutil.ReadFile(filepath.Clean(filename))
 	if err != nil {
@@ -200,3 +192,23 @@ func readApplicationFromFile(filename string) (*corev1beta1.Application, error)
 	err = json.Unmarshal(fileContent, app)
 	return app, err
 }
+
+// applyValuesFiles sets the values of the parameters of the application from the YAML files mapping the names of the
+// parameters to their values, the later files take precedence
+func applyValuesFiles(app *corev1beta1.Application, files []string) error {
+	values := map[string]interface{}{}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Clean(f))
+		if err != nil {
+			return err
+		}
+		fileValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(b, &fileValues); err != nil {
+			return errors.Wrapf(err, "invalid values file %s", f)
+		}
+		for k, v := range fileValues {
+			values[k] = v
+		}
+	}
+	return appfile.ApplyParameterValues(app, values)
+}
@@ -19,23 +19,26 @@ package cli
 import (
 	"context"
 	"fmt"
-	"strings"
+	"net/url"
 	"time"
 
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
-	cmdexec "k8s.io/kubectl/pkg/cmd/exec"
-	k8scmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/kubectl/pkg/util/term"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
-	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/util"
-	"github.com/oam-dev/kubevela/references/appfile"
+	velacommon "github.com/oam-dev/kubevela/references/common"
 )
 
 const (
@@ -47,40 +50,37 @@ const (
 
 // VelaExecOptions creates options for `exec` command
 type VelaExecOptions struct {
-	Cmd         *cobra.Command
-	Args        []string
-	Stdin       bool
-	TTY         bool
-	ServiceName string
+	Cmd       *cobra.Command
+	Args      []string
+	Stdin     bool
+	TTY       bool
+	Component string
+	Cluster   string
+	Pod       string
+	Container string
 
 	context.Context
-	VelaC common.Args
-	Env   *types.EnvMeta
-	App   *v1beta1.Application
+	VelaC     common.Args
+	Env       *types.EnvMeta
+	App       *v1beta1.Application
+	ioStreams util.IOStreams
 
-	f             k8scmdutil.Factory
-	kcExecOptions *cmdexec.ExecOptions
-	ClientSet     kubernetes.Interface
+	client    client.Client
+	target    *componentPod
+	container string
 }
 
 // NewExecCommand creates `exec` command
 func NewExecCommand(c common.Args, ioStreams util.IOStreams) *cobra.Command {
-	o := &VelaExecOptions{
-		kcExecOptions: &cmdexec.ExecOptions{
-			StreamOptions: cmdexec.StreamOptions{
-				IOStreams: genericclioptions.IOStreams{
-					In:     ioStreams.In,
-					Out:    ioStreams.Out,
-					ErrOut: ioStreams.ErrOut,
-				},
-			},
-			Executor: &cmdexec.DefaultRemoteExecutor{},
-		},
-	}
+	o := &VelaExecOptions{ioStreams: ioStreams}
 	cmd := &cobra.Command{
 		Use:   "exec [flags] APP_NAME -- COMMAND [args...]",
 		Short: "Execute command in a container",
-		Long:  "Execute command in a container",
+		Long: "Execute command in a container of a pod of a component. A running pod of the workload of the component is " +
+			"picked, the ready and newer ones are preferred, including the pods on the managed clusters and the ones of " +
+			"the workloads created by Helm. The container is asked if the pod has multiple containers and none of them " +
+			"is the default one.",
+		Example: "vela exec APP_NAME -- sh\nvela exec APP_NAME -c frontend --container nginx -- nginx -t\nvela exec APP_NAME --cluster east -- ls /",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := c.SetConfig(); err != nil {
 				return err
@@ -123,7 +123,12 @@ func NewExecCommand(c common.Args, ioStreams util.IOStreams) *cobra.Command {
 	cmd.Flags().Duration(podRunningTimeoutFlag, defaultPodExecTimeout,
 		"The length of time (like 5s, 2m, or 3h, higher than zero) to wait until at least one pod is running",
 	)
-	cmd.Flags().StringVarP(&o.ServiceName, "svc", "s", "", "service name")
+	cmd.Flags().StringVarP(&o.Component, "component", "c", "", "the component to execute the command in, it's asked if the application has multiple components")
+	cmd.Flags().StringVarP(&o.Component, "svc", "s", "", "service name")
+	_ = cmd.Flags().MarkDeprecated("svc", "use --component instead")
+	cmd.Flags().StringVar(&o.Cluster, "cluster", "", "only pick the pods on the managed cluster")
+	cmd.Flags().StringVar(&o.Pod, "pod", "", "the pod of the component to execute the command in, a running pod is picked if not specified")
+	cmd.Flags().StringVar(&o.Container, "container", "", "the container of the pod to execute the command in, it's asked if the pod has multiple containers")
 	return cmd
 }
 
@@ -138,91 +143,163 @@ func (o *VelaExecOptions) Init(ctx context.Context, c *cobra.Command, argsIn []s
 		return err
 	}
 	o.Env = env
-	app, err := appfile.LoadApplication(env.Namespace, o.Args[0], o.VelaC)
-	if err != nil {
+	if o.client, err = o.VelaC.GetClient(); err != nil {
 		return err
 	}
-	o.App = app
-
-	cf := genericclioptions.NewConfigFlags(true)
-	cf.Namespace = &o.Env.Namespace
-	o.f = k8scmdutil.NewFactory(k8scmdutil.NewMatchVersionFlags(cf))
-
-	if o.ClientSet == nil {
-		c, err := kubernetes.NewForConfig(o.VelaC.Config)
-		if err != nil {
-			return err
-		}
-		o.ClientSet = c
-	}
-	return nil
+	o.App, err = loadRemoteApplication(o.client, env.Namespace, o.Args[0])
+	return err
 }
 
-// Complete loads data from the command environment
+// Complete picks the pod and the container to execute the command in
 func (o *VelaExecOptions) Complete() error {
-	compName, err := o.getComponentName()
+	compName, err := chooseComponent(o.App, o.Component)
 	if err != nil {
 		return err
 	}
-	podName, err := o.getPodName(compName)
+	timeout, err := o.Cmd.Flags().GetDuration(podRunningTimeoutFlag)
 	if err != nil {
 		return err
 	}
-	o.kcExecOptions.StreamOptions.Stdin = o.Stdin
-	o.kcExecOptions.StreamOptions.TTY = o.TTY
+	if o.target, err = resolveComponentPod(o.Context, o.VelaC.Config, o.client, o.App, compName, o.Cluster, o.Pod, timeout); err != nil {
+		return err
+	}
+	o.container, err = chooseContainer(&o.target.Pod, o.Container)
+	return err
+}
 
-	args := make([]string, len(o.Args))
-	copy(args, o.Args)
-	// args for kcExecOptions MUST be in such formart:
-	// [podName, COMMAND...]
-	args[0] = podName
-	return o.kcExecOptions.Complete(o.f, o.Cmd, args, 1)
+// Run executes a validated remote execution against a pod
+func (o *VelaExecOptions) Run() error {
+	// a TTY is only allocated for stdin like kubectl
+	tty := o.TTY && o.Stdin
+	pod := &o.target.Pod
+	req := o.target.clientSet.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: o.container,
+			Command:   o.Args[1:],
+			Stdin:     o.Stdin,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+	return streamToPod(o.target.config, req.URL(), o.ioStreams, o.Stdin, tty)
 }
 
-func (o *VelaExecOptions) getComponentName() (string, error) {
-	svcName := o.ServiceName
+// componentPod is the pod of a component to execute commands in or forward ports to, with the rest config and the
+// clientset of its cluster
+type componentPod struct {
+	velacommon.ComponentPod
+	config    *rest.Config
+	clientSet kubernetes.Interface
+}
 
-	if svcName != "" {
-		for _, cc := range o.App.Spec.Components {
-			if cc.Name == svcName {
-				return svcName, nil
-			}
+// chooseComponent returns the component if it's specified, or asks to choose one if the application has multiple
+// components
+func chooseComponent(app *v1beta1.Application, component string) (string, error) {
+	var names []string
+	for _, comp := range app.Spec.Components {
+		if len(component) != 0 && comp.Name == component {
+			return component, nil
 		}
-		o.Cmd.Printf("The service name '%s' is not valid\n", svcName)
+		names = append(names, comp.Name)
 	}
-
-	compName, err := common.AskToChooseOneService(appfile.GetComponents(o.App))
-	if err != nil {
-		return "", err
+	if len(component) != 0 {
+		return "", errors.Errorf("component %s is not found in application %s", component, app.Name)
 	}
-	return compName, nil
+	return common.AskToChooseOneService(names)
 }
 
-func (o *VelaExecOptions) getPodName(compName string) (string, error) {
-	podList, err := o.ClientSet.CoreV1().Pods(o.Env.Namespace).List(o.Context, v1.ListOptions{
-		LabelSelector: labels.Set(map[string]string{
-			// TODO(roywang) except core workloads, not any workloads will pass these label to pod
-			// find a rigorous way to get pod by compname
-			oam.LabelAppComponent: compName,
-		}).String(),
+// resolveComponentPod picks a running pod of the component, the pod is picked by name if it's specified. It waits
+// until the timeout for a pod to be running.
+func resolveComponentPod(ctx context.Context, cfg *rest.Config, c client.Client, app *v1beta1.Application, component, cluster, podName string, timeout time.Duration) (*componentPod, error) {
+	clientOf := velacommon.NewClusterClientFn(c, app.Namespace)
+	var picked *velacommon.ComponentPod
+	var lastErr error
+	err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		pods, err := velacommon.ListComponentPods(ctx, c, clientOf, app, component)
+		if err != nil {
+			return false, err
+		}
+		if len(podName) != 0 {
+			var named []velacommon.ComponentPod
+			for _, p := range pods {
+				if p.Pod.Name == podName {
+					named = append(named, p)
+				}
+			}
+			pods = named
+		}
+		if picked, lastErr = velacommon.SelectComponentPod(pods, cluster); lastErr != nil {
+			return false, nil
+		}
+		return true, nil
 	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		if len(podName) != 0 {
+			return nil, errors.WithMessagef(lastErr, "pod %s of component %s is not running", podName, component)
+		}
+		return nil, errors.WithMessagef(lastErr, "component %s has no pod running", component)
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if podList != nil && len(podList.Items) == 0 {
-		return "", fmt.Errorf("cannot get pods")
+	config, err := velacommon.NewClusterConfigFn(c, cfg, app.Namespace)(ctx, picked.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
 	}
-	for _, p := range podList.Items {
-		if strings.HasPrefix(p.Name, compName+"-") {
-			return p.Name, nil
+	return &componentPod{ComponentPod: *picked, config: config, clientSet: cs}, nil
+}
+
+// chooseContainer returns the container if it's specified, or the default container of the pod, or asks to choose
+// one if the pod has multiple containers
+func chooseContainer(pod *corev1.Pod, container string) (string, error) {
+	var names []string
+	for _, c := range pod.Spec.Containers {
+		if len(container) != 0 && c.Name == container {
+			return container, nil
 		}
+		names = append(names, c.Name)
+	}
+	if len(container) != 0 {
+		return "", errors.Errorf("container %s is not found in pod %s", container, pod.Name)
+	}
+	if name := velacommon.DefaultContainer(pod); len(name) != 0 {
+		return name, nil
+	}
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("Pod %s has multiple containers. Please choose one container: ", pod.Name),
+		Options: names,
 	}
-	// if no pod with name matched prefix as component name
-	// just return the first one
-	return podList.Items[0].Name, nil
+	var name string
+	if err := survey.AskOne(prompt, &name); err != nil {
+		return "", errors.Wrap(err, "choosing container err")
+	}
+	return name, nil
 }
 
-// Run executes a validated remote execution against a pod
-func (o *VelaExecOptions) Run() error {
-	return o.kcExecOptions.Run()
+// streamToPod streams the stdin, stdout and stderr to the exec or attach subresource of a pod by the url, the
+// terminal is put into the raw mode if a TTY is allocated
+func streamToPod(cfg *rest.Config, url *url.URL, ioStreams util.IOStreams, stdin, tty bool) error {
+	t := term.TTY{In: ioStreams.In, Out: ioStreams.Out, Raw: tty}
+	executor, err := remotecommand.NewSPDYExecutor(cfg, "POST", url)
+	if err != nil {
+		return err
+	}
+	streams := remotecommand.StreamOptions{Stdout: ioStreams.Out, Tty: tty}
+	if stdin {
+		streams.Stdin = ioStreams.In
+	}
+	if !tty {
+		streams.Stderr = ioStreams.ErrOut
+	}
+	return t.Safe(func() error {
+		if tty {
+			streams.TerminalSizeQueue = t.MonitorSize(t.GetSize())
+		}
+		return executor.Stream(streams)
+	})
 }
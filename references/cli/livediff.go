@@ -19,13 +19,10 @@ package cli
 import (
 	"bytes"
 	"context"
-	"fmt"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
@@ -51,7 +48,7 @@ func NewLiveDiffCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comma
 		DisableFlagsInUseLine: true,
 		Short:                 "Dry-run an application, and do diff on a specific app revison",
 		Long:                  "Dry-run an application, and do diff on a specific app revison. The provided capability definitions will be used during Dry-run. If any capabilities used in the app are not found in the provided ones, it will try to find from cluster.",
-		Example:               "vela live-diff -f app-v2.yaml -r app-v1 --context 10",
+		Example:               "vela live-diff -f app-v2.yaml -r app-v1 --context 10\nvela live-diff -f app-v2.yaml -r 3 -o json",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return c.SetConfig()
 		},
@@ -60,6 +57,21 @@ func NewLiveDiffCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comma
 			if err != nil {
 				return err
 			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if isStructuredOutput(format) {
+				diffResult, revision, err := DiffApplication(o, c, velaEnv.Namespace)
+				if err != nil {
+					return err
+				}
+				return printStructured(ioStreams, format, LiveDiffOutput{
+					OutputMeta: newOutputMeta(LiveDiffOutputKind),
+					Revision:   revision,
+					DiffEntry:  diffResult,
+				})
+			}
 			buff, err := LiveDiffApplication(o, c, velaEnv.Namespace)
 			if err != nil {
 				return err
@@ -69,10 +81,12 @@ func NewLiveDiffCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comma
 		},
 	}
 
-	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file name")
+	cmd.Flags().StringVarP(&o.ApplicationFile, "file", "f", "./app.yaml", "application file name, or the directory of an application with an index manifest")
 	cmd.Flags().StringVarP(&o.DefinitionFile, "definition", "d", "", "specify a file or directory containing capability definitions, they will only be used in dry-run rather than applied to K8s cluster")
-	cmd.Flags().StringVarP(&o.Revision, "Revision", "r", "", "specify an application Revision name, by default, it will compare with the latest Revision")
+	cmd.Flags().StringArrayVar(&o.ValuesFiles, "values", nil, "specify a YAML file of the values of the application parameters, it can be repeated and the later files take precedence")
+	cmd.Flags().StringVarP(&o.Revision, "Revision", "r", "", "specify an application Revision name or number, e.g., app-v3, v3 or 3, by default, it will compare with the latest Revision")
 	cmd.Flags().IntVarP(&o.Context, "context", "c", -1, "output number lines of context around changes, by default show all unchanged lines")
+	addOutputFlag(cmd)
 	cmd.SetOut(ioStreams.Out)
 	return cmd
 }
@@ -80,71 +94,59 @@ func NewLiveDiffCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comma
 // LiveDiffApplication can return user what would change if upgrade an application.
 func LiveDiffApplication(cmdOption *LiveDiffCmdOptions, c common.Args, namespace string) (bytes.Buffer, error) {
 	var buff = bytes.Buffer{}
+	diffResult, _, err := DiffApplication(cmdOption, c, namespace)
+	if err != nil {
+		return buff, err
+	}
+	reportDiffOpt := dryrun.NewReportDiffOption(cmdOption.Context, &buff)
+	reportDiffOpt.PrintDiffReport(diffResult)
+	return buff, nil
+}
 
+// DiffApplication dry-runs the application and diffs it with the revision specified, the revision can be a revision
+// name or number, it's the latest revision if not specified. The name of the revision diffed with is returned.
+func DiffApplication(cmdOption *LiveDiffCmdOptions, c common.Args, namespace string) (*dryrun.DiffEntry, string, error) {
 	newClient, err := c.GetClient()
 	if err != nil {
-		return buff, err
+		return nil, "", err
 	}
 	objs := []oam.Object{}
 	if cmdOption.DefinitionFile != "" {
 		objs, err = ReadObjectsFromFile(cmdOption.DefinitionFile)
 		if err != nil {
-			return buff, err
+			return nil, "", err
 		}
 	}
 	pd, err := c.GetPackageDiscover()
 	if err != nil {
-		return buff, err
+		return nil, "", err
 	}
 
 	dm, err := discoverymapper.New(c.Config)
 	if err != nil {
-		return buff, err
+		return nil, "", err
 	}
 
 	app, err := readApplicationFromFile(cmdOption.ApplicationFile)
 	if err != nil {
-		return buff, errors.WithMessagef(err, "read application file: %s", cmdOption.ApplicationFile)
+		return nil, "", errors.WithMessagef(err, "read application file: %s", cmdOption.ApplicationFile)
+	}
+	if err := applyValuesFiles(app, cmdOption.ValuesFiles); err != nil {
+		return nil, "", err
 	}
 	if app.Namespace == "" {
 		app.SetNamespace(namespace)
 	}
 
-	appRevision := &v1beta1.ApplicationRevision{}
-	if cmdOption.Revision != "" {
-		// get the Revision if user specifies
-		if err := newClient.Get(context.Background(),
-			client.ObjectKey{Name: cmdOption.Revision, Namespace: app.Namespace}, appRevision); err != nil {
-			return buff, errors.Wrapf(err, "cannot get application Revision %q", cmdOption.Revision)
-		}
-	} else {
-		// get the latest Revision of the application
-		livingApp := &v1beta1.Application{}
-		if err := newClient.Get(context.Background(),
-			client.ObjectKey{Name: app.Name, Namespace: app.Namespace}, livingApp); err != nil {
-			return buff, errors.Wrapf(err, "cannot get application %q", app.Name)
-		}
-		if livingApp.Status.LatestRevision != nil {
-			latestRevName := livingApp.Status.LatestRevision.Name
-			if err := newClient.Get(context.Background(),
-				client.ObjectKey{Name: latestRevName, Namespace: app.Namespace}, appRevision); err != nil {
-				return buff, errors.Wrapf(err, "cannot get application Revision %q", cmdOption.Revision)
-			}
-		} else {
-			// .status.latestRevision is nil, that means the app has not
-			// been rendered yet
-			return buff, fmt.Errorf("the application %q has no Revision in the cluster", app.Name)
-		}
+	appRevision, err := dryrun.LoadAppRevision(context.Background(), newClient, app, cmdOption.Revision)
+	if err != nil {
+		return nil, "", err
 	}
 
 	liveDiffOption := dryrun.NewLiveDiffOption(newClient, dm, pd, objs)
 	diffResult, err := liveDiffOption.Diff(context.Background(), app, appRevision)
 	if err != nil {
-		return buff, errors.WithMessage(err, "cannot calculate diff")
+		return nil, "", errors.WithMessage(err, "cannot calculate diff")
 	}
-
-	reportDiffOpt := dryrun.NewReportDiffOption(cmdOption.Context, &buff)
-	reportDiffOpt.PrintDiffReport(diffResult)
-
-	return buff, nil
+	return diffResult, appRevision.Name, nil
 }
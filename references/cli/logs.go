@@ -19,32 +19,31 @@ package cli
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"regexp"
-	"text/template"
-	"time"
+	"hash/fnv"
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/wercker/stern/stern"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/client-go/kubernetes"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/util"
-	"github.com/oam-dev/kubevela/references/appfile"
+	velacommon "github.com/oam-dev/kubevela/references/common"
 )
 
+// logPrefixColors are the colors of the prefixes of the log lines, the lines of a container share the same color
+var logPrefixColors = []color.Attribute{color.FgRed, color.FgGreen, color.FgYellow, color.FgBlue, color.FgMagenta, color.FgCyan}
+
 // NewLogsCommand creates `logs` command to tail logs of application
 func NewLogsCommand(c common.Args, ioStreams util.IOStreams) *cobra.Command {
 	largs := &Args{C: c}
 	cmd := &cobra.Command{}
-	cmd.Use = "logs"
+	cmd.Use = "logs APP_NAME"
 	cmd.Short = "Tail logs for application"
-	cmd.Long = "Tail logs for application"
+	cmd.Long = "Tail logs of the containers of the pods behind the components of an application, including the pods " +
+		"on the managed clusters. The lines are prefixed with the cluster, component, pod and container they come from."
+	cmd.Example = "vela logs APP_NAME\nvela logs APP_NAME -c frontend --since 1h -f\nvela logs APP_NAME --tail 100 -o json"
 	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		if err := c.SetConfig(); err != nil {
 			return err
@@ -54,141 +53,98 @@ func NewLogsCommand(c common.Args, ioStreams util.IOStreams) *cobra.Command {
 	}
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
-			ioStreams.Errorf("please specify app name")
-			return nil
+			return errors.New("please specify an application")
 		}
 		env, err := GetEnv(cmd)
 		if err != nil {
 			return err
 		}
-		app, err := appfile.LoadApplication(env.Namespace, args[0], c)
+		newClient, err := c.GetClient()
 		if err != nil {
 			return err
 		}
-		largs.App = app
-		largs.Env = env
-		ctx := context.Background()
-		if err := largs.Run(ctx, ioStreams); err != nil {
+		app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+		if err != nil {
 			return err
 		}
-		return nil
+		largs.App = app
+		largs.Env = env
+		return largs.Run(context.Background(), ioStreams)
 	}
 	cmd.Annotations = map[string]string{
 		types.TagCommandType: types.TypeApp,
 	}
 	cmd.Flags().StringVarP(&largs.Output, "output", "o", "default", "output format for logs, support: [default, raw, json]")
+	cmd.Flags().StringSliceVarP(&largs.Options.Components, "component", "c", nil, "the components whose logs are printed, all the components if not specified")
+	cmd.Flags().StringVar(&largs.Options.Container, "container", "", "the container whose logs are printed, all the containers of the pods if not specified")
+	cmd.Flags().DurationVar(&largs.Options.Since, "since", 0, "only print the logs newer than the duration, e.g., 10s, 5m or 1h, all the logs if not specified")
+	cmd.Flags().Int64Var(&largs.Options.Tail, "tail", -1, "the number of the latest lines of each container to print, all the lines if negative")
+	cmd.Flags().BoolVarP(&largs.Options.Follow, "follow", "f", false, "keep printing the new logs, including the ones of the pods created later")
+	cmd.Flags().BoolVar(&largs.Options.Timestamps, "timestamps", false, "prefix each line with its timestamp")
 	return cmd
 }
 
 // Args creates arguments for `logs` command
 type Args struct {
-	Output string
-	Env    *types.EnvMeta
-	C      common.Args
-	App    *v1beta1.Application
+	Output  string
+	Options velacommon.LogOptions
+	Env     *types.EnvMeta
+	C       common.Args
+	App     *v1beta1.Application
 }
 
-// Run refer to the implementation at https://github.com/oam-dev/stern/blob/master/stern/main.go
+// Run prints the merged logs of the application until all of them are printed, or until interrupted if they're
+// followed
 func (l *Args) Run(ctx context.Context, ioStreams util.IOStreams) error {
-
-	clientSet, err := kubernetes.NewForConfig(l.C.Config)
-	if err != nil {
-		return err
+	switch l.Output {
+	case "default", "raw", "json":
+	default:
+		return errors.Errorf("unsupported output format %s, support: [default, raw, json]", l.Output)
 	}
-	compName, err := common.AskToChooseOneService(appfile.GetComponents(l.App))
+	newClient, err := l.C.GetClient()
 	if err != nil {
 		return err
 	}
-	// TODO(wonderflow): we could get labels from service to narrow the pods scope selected
-	labelSelector := labels.Everything()
-	pod, err := regexp.Compile(compName + "-.*")
-	if err != nil {
-		return fmt.Errorf("fail to compile '%s' for logs query", compName+".*")
-	}
-	container := regexp.MustCompile(".*")
-	namespace := l.Env.Namespace
-	added, removed, err := stern.Watch(ctx, clientSet.CoreV1().Pods(namespace), pod, container, nil, stern.RUNNING, labelSelector)
+	lines, err := velacommon.StreamApplicationLogs(ctx, newClient,
+		velacommon.NewClusterClientFn(newClient, l.App.Namespace),
+		velacommon.NewClusterLogStreamFn(velacommon.NewClusterClientsetFn(newClient, l.C.Config, l.App.Namespace)),
+		l.App, l.Options)
 	if err != nil {
 		return err
 	}
-	tails := make(map[string]*stern.Tail)
-	logC := make(chan string, 1024)
-
-	go func() {
-		for {
-			select {
-			case str := <-logC:
-				ioStreams.Infonln(str)
-			case <-ctx.Done():
-				return
-			}
+	for line := range lines {
+		out, err := formatLogLine(line, l.Output)
+		if err != nil {
+			return err
 		}
-	}()
-
-	var t string
-	switch l.Output {
-	case "default":
-		if color.NoColor {
-			t = "{{.PodName}} {{.ContainerName}} {{.Message}}"
-		} else {
-			t = "{{color .PodColor .PodName}} {{color .ContainerColor .ContainerName}} {{.Message}}"
+		if len(line.Error) != 0 && l.Output != "json" {
+			ioStreams.Error(out)
+			continue
 		}
-	case "raw":
-		t = "{{.Message}}"
+		ioStreams.Info(out)
+	}
+	return nil
+}
+
+func formatLogLine(line velacommon.LogLine, output string) (string, error) {
+	switch output {
 	case "json":
-		t = "{{json .}}\n"
+		b, err := json.Marshal(line)
+		return string(b), err
+	case "raw":
+		if len(line.Error) != 0 {
+			return line.Error, nil
+		}
+		return line.Message, nil
 	}
-	funs := map[string]interface{}{
-		"json": func(in interface{}) (string, error) {
-			b, err := json.Marshal(in)
-			if err != nil {
-				return "", err
-			}
-			return string(b), nil
-		},
-		"color": func(color color.Color, text string) string {
-			return color.SprintFunc()(text)
-		},
+	prefix := line.Prefix()
+	if !color.NoColor {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(prefix))
+		prefix = color.New(logPrefixColors[h.Sum32()%uint32(len(logPrefixColors))]).Sprint(prefix)
 	}
-	template, err := template.New("log").Funcs(funs).Parse(t)
-	if err != nil {
-		return errors.Wrap(err, "unable to parse template")
+	if len(line.Error) != 0 {
+		return prefix + " " + line.Error, nil
 	}
-
-	go func() {
-		for p := range added {
-			id := p.GetID()
-			if tails[id] != nil {
-				continue
-			}
-			// 48h
-			dur, _ := time.ParseDuration("48h")
-			tail := stern.NewTail(p.Namespace, p.Pod, p.Container, template, &stern.TailOptions{
-				Timestamps:   true,
-				SinceSeconds: int64(dur.Seconds()),
-				Exclude:      nil,
-				Include:      nil,
-				Namespace:    false,
-				TailLines:    nil, // default for all logs
-			})
-			tails[id] = tail
-
-			tail.Start(ctx, clientSet.CoreV1().Pods(p.Namespace), logC)
-		}
-	}()
-
-	go func() {
-		for p := range removed {
-			id := p.GetID()
-			if tails[id] == nil {
-				continue
-			}
-			tails[id].Close()
-			delete(tails, id)
-		}
-	}()
-
-	<-ctx.Done()
-
-	return nil
+	return prefix + " " + line.Message, nil
 }
@@ -39,7 +39,7 @@ func NewListCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
 		DisableFlagsInUseLine: true,
 		Short:                 "List applications",
 		Long:                  "List all applications in cluster",
-		Example:               `vela ls`,
+		Example:               "vela ls\nvela ls -o json",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return c.SetConfig()
 		},
@@ -59,27 +59,46 @@ func NewListCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
 			if namespace == "" {
 				namespace = env.Namespace
 			}
-			return printApplicationList(ctx, newClient, namespace, ioStreams)
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			return printApplicationList(ctx, newClient, namespace, format, ioStreams)
 		},
 		Annotations: map[string]string{
 			types.TagCommandType: types.TypeApp,
 		},
 	}
 	cmd.PersistentFlags().StringP(Namespace, "n", "", "specify the namespace the application want to list, default is the current env namespace")
+	addOutputFlag(cmd)
 	return cmd
 }
 
-func printApplicationList(ctx context.Context, c client.Reader, namespace string, ioStreams cmdutil.IOStreams) error {
-	table := newUITable()
-	table.AddRow("APP", "COMPONENT", "TYPE", "TRAITS", "PHASE", "HEALTHY", "STATUS", "CREATED-TIME")
+func printApplicationList(ctx context.Context, c client.Reader, namespace, format string, ioStreams cmdutil.IOStreams) error {
 	applist := v1beta1.ApplicationList{}
-	if err := c.List(ctx, &applist, client.InNamespace(namespace)); err != nil {
-		if apierrors.IsNotFound(err) {
-			ioStreams.Info(table.String())
-			return nil
-		}
+	if err := c.List(ctx, &applist, client.InNamespace(namespace)); err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
+	if isStructuredOutput(format) {
+		out := ApplicationListOutput{
+			OutputMeta: newOutputMeta(ApplicationListOutputKind),
+			Items:      make([]ApplicationOutput, 0, len(applist.Items)),
+		}
+		for i := range applist.Items {
+			item := newApplicationOutput(&applist.Items[i])
+			// the version and kind are only set on the list
+			item.OutputMeta = OutputMeta{}
+			out.Items = append(out.Items, item)
+		}
+		return printStructured(ioStreams, format, out)
+	}
+
+	table := newUITable()
+	header := []interface{}{"APP", "COMPONENT", "TYPE", "TRAITS", "PHASE", "HEALTHY", "STATUS", "CREATED-TIME"}
+	if format == OutputWide {
+		header = append(header, "NAMESPACE", "REVISION")
+	}
+	table.AddRow(header...)
 
 	for _, a := range applist.Items {
 		for idx, cmp := range a.Spec.Components {
@@ -103,7 +122,15 @@ func printApplicationList(ctx context.Context, c client.Reader, namespace string
 			for _, tr := range cmp.Traits {
 				traits = append(traits, tr.Type)
 			}
-			table.AddRow(appName, cmp.Name, cmp.Type, strings.Join(traits, ","), a.Status.Phase, healthy, status, a.CreationTimestamp)
+			row := []interface{}{appName, cmp.Name, cmp.Type, strings.Join(traits, ","), a.Status.Phase, healthy, status, a.CreationTimestamp}
+			if format == OutputWide {
+				var revision string
+				if a.Status.LatestRevision != nil {
+					revision = a.Status.LatestRevision.Name
+				}
+				row = append(row, a.Namespace, revision)
+			}
+			table.AddRow(row...)
 		}
 	}
 	ioStreams.Info(table.String())
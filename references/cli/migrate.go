@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/migration"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+// NewMigrateCommand creates `system migrate` command to migrate ApplicationConfigurations to Applications
+func NewMigrateCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	ctx := context.Background()
+	var namespace string
+	var all, dryRun, deleteLegacy bool
+	cmd := &cobra.Command{
+		Use:   "migrate [NAME...]",
+		Short: "Migrate ApplicationConfigurations to Applications",
+		Long: "Convert the legacy ApplicationConfigurations and their Components to Applications. The workloads and " +
+			"traits are kept as they are by the raw component and the raw-trait trait, and adopted by the Applications " +
+			"without being recreated. With --delete-legacy, the ApplicationConfigurations and their Components are " +
+			"deleted with their resources orphaned once the Applications are created. It can run in a Job as well.",
+		Example: "vela system migrate my-appconfig -n default --dry-run\nvela system migrate --all -n default --delete-legacy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return errors.New("please specify the names of the application configurations or --all")
+			}
+			if err := c.SetConfig(); err != nil {
+				return err
+			}
+			k8sClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			dm, err := c.GetDiscoveryMapper()
+			if err != nil {
+				return err
+			}
+			var acs []v1alpha2.ApplicationConfiguration
+			if all {
+				list := &v1alpha2.ApplicationConfigurationList{}
+				if err := k8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+					return err
+				}
+				for _, ac := range list.Items {
+					// the AppConfigs generated by Applications are not legacy ones
+					if ownedByApp(ac) {
+						continue
+					}
+					acs = append(acs, ac)
+				}
+			}
+			for _, name := range args {
+				ac := v1alpha2.ApplicationConfiguration{}
+				if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &ac); err != nil {
+					return err
+				}
+				acs = append(acs, ac)
+			}
+			for i := range acs {
+				ac := &acs[i]
+				result, err := migration.Migrate(ctx, k8sClient, dm, ac, migration.Options{DryRun: dryRun, DeleteLegacy: deleteLegacy})
+				if err != nil {
+					return errors.WithMessagef(err, "cannot migrate application configuration %s", ac.Name)
+				}
+				for _, w := range result.Warnings {
+					ioStreams.Infof("Warning: %s\n", w)
+				}
+				switch {
+				case dryRun:
+					b, err := yaml.Marshal(result.Application)
+					if err != nil {
+						return err
+					}
+					ioStreams.Info("---\n" + string(b))
+				case result.Created:
+					ioStreams.Infof("Application configuration %s migrated to application %s\n", ac.Name, result.Application.Name)
+				default:
+					ioStreams.Infof("Application configuration %s has been migrated to application %s\n", ac.Name, result.Application.Name)
+				}
+			}
+			return nil
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeSystem,
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, Namespace, "n", "default", "the namespace of the application configurations")
+	cmd.Flags().BoolVar(&all, "all", false, "migrate all the application configurations in the namespace")
+	cmd.Flags().BoolVar(&dryRun, FlagDryRun, false, "print the converted applications without creating them")
+	cmd.Flags().BoolVar(&deleteLegacy, "delete-legacy", false, "delete the application configurations and their components with their resources orphaned after migrated")
+	return cmd
+}
+
+// ownedByApp returns whether the AppConfig is generated by an Application
+func ownedByApp(ac v1alpha2.ApplicationConfiguration) bool {
+	for _, owner := range ac.GetOwnerReferences() {
+		if owner.Kind == "Application" {
+			return true
+		}
+	}
+	return ac.GetLabels()[oam.LabelAppName] != ""
+}
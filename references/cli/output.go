@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/debug"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
+	"github.com/oam-dev/kubevela/references/common"
+	"github.com/oam-dev/kubevela/references/deftest"
+)
+
+const (
+	// FlagOutput is the flag to specify the output format
+	FlagOutput = "output"
+
+	// OutputJSON prints the versioned output struct in JSON
+	OutputJSON = "json"
+	// OutputYAML prints the versioned output struct in YAML
+	OutputYAML = "yaml"
+	// OutputWide prints the table with additional columns
+	OutputWide = "wide"
+
+	// OutputAPIVersion is the version of the output structs, it's bumped on incompatible changes of them so that
+	// scripts and UIs consuming the output can detect the change
+	OutputAPIVersion = "cli.oam.dev/v1alpha1"
+)
+
+// Kinds of the output structs
+const (
+	ApplicationListOutputKind = "ApplicationList"
+	ApplicationOutputKind     = "Application"
+	DefinitionListOutputKind  = "DefinitionList"
+	WorkflowOutputKind        = "Workflow"
+	RenderBenchmarkOutputKind = "RenderBenchmark"
+	TemplateDebugOutputKind   = "TemplateDebug"
+	DebugSnapshotOutputKind   = "DebugSnapshot"
+	LiveDiffOutputKind        = "LiveDiff"
+	ResourceTreeOutputKind    = "ResourceTree"
+	DefinitionTestOutputKind  = "DefinitionTest"
+)
+
+// OutputMeta is the version and kind of an output struct, it's omitted in the items of a list
+type OutputMeta struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+func newOutputMeta(kind string) OutputMeta {
+	return OutputMeta{APIVersion: OutputAPIVersion, Kind: kind}
+}
+
+// ApplicationListOutput is the output of `vela ls`
+type ApplicationListOutput struct {
+	OutputMeta `json:",inline"`
+	Items      []ApplicationOutput `json:"items"`
+}
+
+// ApplicationOutput is the output of `vela status`, and an item of `vela ls`
+type ApplicationOutput struct {
+	OutputMeta `json:",inline"`
+	Name       string                       `json:"name"`
+	Namespace  string                       `json:"namespace"`
+	Phase      commontypes.ApplicationPhase `json:"phase,omitempty"`
+	Revision   string                       `json:"revision,omitempty"`
+	CreatedAt  metav1.Time                  `json:"createdAt"`
+	Components []ComponentOutput            `json:"components"`
+	Workflow   []WorkflowStepOutput         `json:"workflow,omitempty"`
+}
+
+// ComponentOutput is the status of a component of an application
+type ComponentOutput struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Healthy bool              `json:"healthy"`
+	Message string            `json:"message,omitempty"`
+	Traits  []TraitOutput     `json:"traits,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// TraitOutput is the status of a trait of a component
+type TraitOutput struct {
+	Type    string `json:"type"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// WorkflowOutput is the output of `vela workflow status`
+type WorkflowOutput struct {
+	OutputMeta `json:",inline"`
+	Name       string                             `json:"name"`
+	Namespace  string                             `json:"namespace"`
+	Phase      commontypes.ApplicationPhase       `json:"phase,omitempty"`
+	Steps      []WorkflowStepOutput               `json:"steps"`
+	Suspend    *commontypes.WorkflowSuspendStatus `json:"suspend,omitempty"`
+}
+
+// WorkflowStepOutput is the status of a workflow step
+type WorkflowStepOutput struct {
+	Name     string                        `json:"name"`
+	Type     string                        `json:"type"`
+	Phase    commontypes.WorkflowStepPhase `json:"phase,omitempty"`
+	Resource string                        `json:"resource,omitempty"`
+	Retries  int                           `json:"retries,omitempty"`
+	Message  string                        `json:"message,omitempty"`
+	SubSteps []WorkflowStepOutput          `json:"subSteps,omitempty"`
+}
+
+// RenderBenchmarkOutput is the output of `vela bench render`, it's also the baseline compared with
+type RenderBenchmarkOutput struct {
+	OutputMeta `json:",inline"`
+	Items      []dryrun.RenderBenchmark `json:"items"`
+}
+
+// TemplateDebugOutput is the output of `vela def dry-run --show-context`
+type TemplateDebugOutput struct {
+	OutputMeta                    `json:",inline"`
+	Definition                    string `json:"definition"`
+	*definition.TemplateDebugInfo `json:",inline"`
+}
+
+// DebugSnapshotOutput is the output of `vela debug --snapshot`, the render intermediates of an application
+type DebugSnapshotOutput struct {
+	OutputMeta      `json:",inline"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	*debug.Snapshot `json:",inline"`
+}
+
+// LiveDiffOutput is the output of `vela live-diff`, the diff of the application with the revision in the cluster
+type LiveDiffOutput struct {
+	OutputMeta        `json:",inline"`
+	Revision          string `json:"revision"`
+	*dryrun.DiffEntry `json:",inline"`
+}
+
+// ResourceTreeOutput is the output of `vela top`, the tree of the resources of an application
+type ResourceTreeOutput struct {
+	OutputMeta `json:",inline"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace"`
+	Resources  []*common.ResourceNode `json:"resources"`
+}
+
+// DefinitionListOutput is the output of `vela def list`
+type DefinitionListOutput struct {
+	OutputMeta `json:",inline"`
+	Items      []DefinitionOutput `json:"items"`
+}
+
+// DefinitionOutput is an item of `vela def list`
+type DefinitionOutput struct {
+	Name           string                     `json:"name"`
+	Namespace      string                     `json:"namespace"`
+	Kind           string                     `json:"kind"`
+	Type           commontypes.DefinitionType `json:"type"`
+	Description    string                     `json:"description,omitempty"`
+	LatestRevision int64                      `json:"latestRevision,omitempty"`
+	CreatedAt      metav1.Time                `json:"createdAt"`
+	Ready          corev1.ConditionStatus     `json:"ready"`
+	Message        string                     `json:"message,omitempty"`
+}
+
+// DefinitionTestOutput is the output of `vela def test`
+type DefinitionTestOutput struct {
+	OutputMeta `json:",inline"`
+	Passed     int              `json:"passed"`
+	Failed     int              `json:"failed"`
+	Results    []deftest.Result `json:"results"`
+}
+
+// addOutputFlag adds the output flag to the command
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP(FlagOutput, "o", "", "output format, one of json|yaml|wide")
+}
+
+// getOutputFormat gets and validates the output format, empty means the default human-readable format
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString(FlagOutput)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "", OutputJSON, OutputYAML, OutputWide:
+		return format, nil
+	default:
+		return "", errors.Errorf("unsupported output format %q, must be one of json|yaml|wide", format)
+	}
+}
+
+// isStructuredOutput returns true if the output format is machine-readable
+func isStructuredOutput(format string) bool {
+	return format == OutputJSON || format == OutputYAML
+}
+
+// printStructured prints the output struct in JSON or YAML
+func printStructured(ioStreams cmdutil.IOStreams, format string, obj interface{}) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch format {
+	case OutputJSON:
+		b, err = json.MarshalIndent(obj, "", "  ")
+	case OutputYAML:
+		b, err = yaml.Marshal(obj)
+	default:
+		return errors.Errorf("output format %q is not machine-readable", format)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal the output in %s", format)
+	}
+	ioStreams.Info(string(b))
+	return nil
+}
+
+// newApplicationOutput converts the application and its status to the output struct
+func newApplicationOutput(app *v1beta1.Application) ApplicationOutput {
+	out := ApplicationOutput{
+		OutputMeta: newOutputMeta(ApplicationOutputKind),
+		Name:       app.Name,
+		Namespace:  app.Namespace,
+		Phase:      app.Status.Phase,
+		CreatedAt:  app.CreationTimestamp,
+		Components: make([]ComponentOutput, 0, len(app.Spec.Components)),
+		Workflow:   newWorkflowStepOutputs(app.Status.Workflow),
+	}
+	if app.Status.LatestRevision != nil {
+		out.Revision = app.Status.LatestRevision.Name
+	}
+	for _, comp := range app.Spec.Components {
+		co := ComponentOutput{Name: comp.Name, Type: comp.Type}
+		if status, found := getWorkloadStatusFromApp(app, comp.Name); found {
+			co.Healthy = status.Healthy
+			co.Message = status.Message
+			co.Outputs = status.Outputs
+			for _, tr := range status.Traits {
+				co.Traits = append(co.Traits, TraitOutput{Type: tr.Type, Healthy: tr.Healthy, Message: tr.Message})
+			}
+		}
+		out.Components = append(out.Components, co)
+	}
+	return out
+}
+
+func newWorkflowStepOutputs(steps []commontypes.WorkflowStepStatus) []WorkflowStepOutput {
+	outs := make([]WorkflowStepOutput, 0, len(steps))
+	for _, s := range steps {
+		so := WorkflowStepOutput{Name: s.Name, Type: s.Type, Phase: s.Phase, Retries: s.Retries, Message: s.Message}
+		if s.ResourceRef.Name != "" {
+			so.Resource = fmt.Sprintf("%s/%s", s.ResourceRef.Kind, s.ResourceRef.Name)
+		}
+		for _, sub := range s.SubSteps {
+			subOut := WorkflowStepOutput{Name: sub.Name, Type: sub.Type, Phase: sub.Phase, Message: sub.Message}
+			if sub.ResourceRef.Name != "" {
+				subOut.Resource = fmt.Sprintf("%s/%s", sub.ResourceRef.Kind, sub.ResourceRef.Name)
+			}
+			so.SubSteps = append(so.SubSteps, subOut)
+		}
+		outs = append(outs, so)
+	}
+	return outs
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	"github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+func TestGetOutputFormat(t *testing.T) {
+	for _, format := range []string{"", OutputJSON, OutputYAML, OutputWide} {
+		cmd := &cobra.Command{}
+		addOutputFlag(cmd)
+		assert.NoError(t, cmd.Flags().Set(FlagOutput, format))
+		got, err := getOutputFormat(cmd)
+		assert.NoError(t, err)
+		assert.Equal(t, format, got)
+	}
+	cmd := &cobra.Command{}
+	addOutputFlag(cmd)
+	assert.NoError(t, cmd.Flags().Set(FlagOutput, "xml"))
+	_, err := getOutputFormat(cmd)
+	assert.Error(t, err)
+}
+
+func TestPrintApplicationListStructured(t *testing.T) {
+	app := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta1.ApplicationSpec{
+			Components: []v1beta1.ApplicationComponent{{Name: "web", Type: "webservice"}},
+		},
+		Status: commontypes.AppStatus{
+			Phase:          commontypes.ApplicationRunning,
+			LatestRevision: &commontypes.Revision{Name: "app-v1", Revision: 1},
+			Services: []commontypes.ApplicationComponentStatus{{
+				Name:    "web",
+				Healthy: true,
+				Traits:  []commontypes.ApplicationTraitStatus{{Type: "ingress", Healthy: true, Message: "exposed"}},
+			}},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, app)
+
+	buf := new(bytes.Buffer)
+	ioStreams := util.IOStreams{Out: buf}
+	assert.NoError(t, printApplicationList(context.Background(), c, "default", OutputJSON, ioStreams))
+	var out ApplicationListOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, OutputMeta{APIVersion: OutputAPIVersion, Kind: ApplicationListOutputKind}, out.OutputMeta)
+	assert.Equal(t, 1, len(out.Items))
+	assert.Equal(t, OutputMeta{}, out.Items[0].OutputMeta)
+	assert.Equal(t, "app-v1", out.Items[0].Revision)
+	assert.Equal(t, []ComponentOutput{{
+		Name:    "web",
+		Type:    "webservice",
+		Healthy: true,
+		Traits:  []TraitOutput{{Type: "ingress", Healthy: true, Message: "exposed"}},
+	}}, out.Items[0].Components)
+
+	buf.Reset()
+	assert.NoError(t, printApplicationList(context.Background(), c, "default", OutputYAML, ioStreams))
+	out = ApplicationListOutput{}
+	assert.NoError(t, yaml.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "app", out.Items[0].Name)
+}
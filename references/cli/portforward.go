@@ -21,17 +21,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	types2 "k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 	cmdpf "k8s.io/kubectl/pkg/cmd/portforward"
@@ -41,7 +40,6 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
-	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/util"
 	"github.com/oam-dev/kubevela/references/appfile"
@@ -51,6 +49,9 @@ import (
 type VelaPortForwardOptions struct {
 	Cmd       *cobra.Command
 	Args      []string
+	Component string
+	Cluster   string
+	Pod       string
 	ioStreams util.IOStreams
 
 	context.Context
@@ -60,9 +61,9 @@ type VelaPortForwardOptions struct {
 
 	f                    k8scmdutil.Factory
 	kcPortForwardOptions *cmdpf.PortForwardOptions
-	ClientSet            kubernetes.Interface
 	Client               client.Client
 	routeTrait           bool
+	target               *componentPod
 }
 
 // NewPortForwardCommand is vela port-forward command
@@ -74,10 +75,13 @@ func NewPortForwardCommand(c common.Args, ioStreams util.IOStreams) *cobra.Comma
 		},
 	}
 	cmd := &cobra.Command{
-		Use:     "port-forward APP_NAME",
-		Short:   "Forward local ports to services in an application",
-		Long:    "Forward local ports to services in an application",
-		Example: "port-forward APP_NAME [options] [LOCAL_PORT:]REMOTE_PORT [...[LOCAL_PORT_N:]REMOTE_PORT_N]",
+		Use:   "port-forward APP_NAME",
+		Short: "Forward local ports to services in an application",
+		Long: "Forward local ports to a pod of a component in an application. A running pod of the workload of the " +
+			"component is picked, the ready and newer ones are preferred, including the pods on the managed clusters " +
+			"and the ones of the workloads created by Helm. The port of the component is forwarded if no port is specified.",
+		Example: "port-forward APP_NAME [options] [LOCAL_PORT:]REMOTE_PORT [...[LOCAL_PORT_N:]REMOTE_PORT_N]\n" +
+			"port-forward APP_NAME -c frontend --cluster east 8080:80",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := c.SetConfig(); err != nil {
 				return err
@@ -115,6 +119,9 @@ func NewPortForwardCommand(c common.Args, ioStreams util.IOStreams) *cobra.Comma
 		"The length of time (like 5s, 2m, or 3h, higher than zero) to wait until at least one pod is running",
 	)
 	cmd.Flags().BoolVar(&o.routeTrait, "route", false, "forward ports from route trait service")
+	cmd.Flags().StringVarP(&o.Component, "component", "c", "", "the component to forward ports to, it's asked if the application has multiple components")
+	cmd.Flags().StringVar(&o.Cluster, "cluster", "", "only pick the pods on the managed cluster")
+	cmd.Flags().StringVar(&o.Pod, "pod", "", "the pod of the component to forward ports to, a running pod is picked if not specified")
 	return cmd
 }
 
@@ -130,7 +137,7 @@ func (o *VelaPortForwardOptions) Init(ctx context.Context, cmd *cobra.Command, a
 	}
 	o.Env = env
 
-	app, err := appfile.LoadApplication(env.Namespace, o.Args[0], o.VelaC)
+	app, err := loadRemoteApplication(o.Client, env.Namespace, o.Args[0])
 	if err != nil {
 		return err
 	}
@@ -139,14 +146,6 @@ func (o *VelaPortForwardOptions) Init(ctx context.Context, cmd *cobra.Command, a
 	cf := genericclioptions.NewConfigFlags(true)
 	cf.Namespace = &o.Env.Namespace
 	o.f = k8scmdutil.NewFactory(k8scmdutil.NewMatchVersionFlags(cf))
-
-	if o.ClientSet == nil {
-		c, err := kubernetes.NewForConfig(o.VelaC.Config)
-		if err != nil {
-			return err
-		}
-		o.ClientSet = c
-	}
 	return nil
 }
 
@@ -167,7 +166,7 @@ func getRouteServiceName(appconfig *v1alpha2.ApplicationConfiguration, svcName s
 
 // Complete will complete the config of port-forward
 func (o *VelaPortForwardOptions) Complete() error {
-	svcName, err := common.AskToChooseOneService(appfile.GetComponents(o.App))
+	svcName, err := chooseComponent(o.App, o.Component)
 	if err != nil {
 		return err
 	}
@@ -188,23 +187,13 @@ func (o *VelaPortForwardOptions) Complete() error {
 		if len(svc.Spec.Ports) == 0 {
 			return fmt.Errorf("no port found in service %s", routeSvc)
 		}
-		val := strconv.Itoa(int(svc.Spec.Ports[0].Port))
-		if val == "80" {
-			val = "8080:80"
-		} else if val == "443" {
-			val = "8443:443"
-		}
-		o.Args = append(o.Args, val)
+		o.Args = append(o.Args, defaultPortMapping(strconv.Itoa(int(svc.Spec.Ports[0].Port))))
 		args := make([]string, len(o.Args))
 		copy(args, o.Args)
 		args[0] = "svc/" + routeSvc
 		return o.kcPortForwardOptions.Complete(o.f, o.Cmd, args)
 	}
 
-	podName, err := o.getPodName(svcName)
-	if err != nil {
-		return err
-	}
 	if len(o.Args) < 2 {
 		var found bool
 		_, configs := appfile.GetApplicationSettings(o.App, svcName)
@@ -221,12 +210,7 @@ func (o *VelaPortForwardOptions) Complete() error {
 				default:
 					return fmt.Errorf("invalid type '%s' of port %v", reflect.TypeOf(v), k)
 				}
-				if val == "80" {
-					val = "8080:80"
-				} else if val == "443" {
-					val = "8443:443"
-				}
-				o.Args = append(o.Args, val)
+				o.Args = append(o.Args, defaultPortMapping(val))
 				found = true
 			}
 		}
@@ -234,30 +218,29 @@ func (o *VelaPortForwardOptions) Complete() error {
 			return fmt.Errorf("no port found in app or arguments")
 		}
 	}
-	args := make([]string, len(o.Args))
-	copy(args, o.Args)
-	args[0] = podName
-	return o.kcPortForwardOptions.Complete(o.f, o.Cmd, args)
-}
-
-func (o *VelaPortForwardOptions) getPodName(svcName string) (string, error) {
-	podList, err := o.ClientSet.CoreV1().Pods(o.Env.Namespace).List(o.Context, v1.ListOptions{
-		LabelSelector: labels.Set(map[string]string{
-			oam.LabelAppComponent: svcName,
-		}).String(),
-	})
+	timeout, err := o.Cmd.Flags().GetDuration(podRunningTimeoutFlag)
 	if err != nil {
-		return "", err
+		return err
 	}
-	if podList != nil && len(podList.Items) == 0 {
-		return "", fmt.Errorf("cannot get pods")
+	if o.target, err = resolveComponentPod(o.Context, o.VelaC.Config, o.Client, o.App, svcName, o.Cluster, o.Pod, timeout); err != nil {
+		return err
 	}
-	for _, p := range podList.Items {
-		if strings.HasPrefix(p.Name, svcName+"-") {
-			return p.Name, nil
-		}
+	o.kcPortForwardOptions.Config = o.target.config
+	o.kcPortForwardOptions.Ports = o.Args[1:]
+	o.kcPortForwardOptions.StopChannel = make(chan struct{}, 1)
+	o.kcPortForwardOptions.ReadyChannel = make(chan struct{})
+	return nil
+}
+
+// defaultPortMapping forwards the local ports 8080 and 8443 to the privileged remote ports 80 and 443
+func defaultPortMapping(port string) string {
+	switch port {
+	case "80":
+		return "8080:80"
+	case "443":
+		return "8443:443"
 	}
-	return podList.Items[0].Name, nil
+	return port
 }
 
 // Run will execute port-forward
@@ -265,14 +248,30 @@ func (o *VelaPortForwardOptions) Run() error {
 	go func() {
 		<-o.kcPortForwardOptions.ReadyChannel
 		o.ioStreams.Info("\nForward successfully! Opening browser ...")
-		local, _ := splitPort(o.Args[1])
+		local, _ := splitPort(o.kcPortForwardOptions.Ports[0])
 		var url = "http://127.0.0.1:" + local
 		if err := OpenBrowser(url); err != nil {
 			o.ioStreams.Errorf("\nFailed to open browser: %v", err)
 		}
 	}()
 
-	return o.kcPortForwardOptions.RunPortForward()
+	if o.routeTrait {
+		return o.kcPortForwardOptions.RunPortForward()
+	}
+	pod := &o.target.Pod
+	if pod.Namespace != o.Env.Namespace || len(o.target.Cluster) != 0 {
+		o.ioStreams.Infof("Forwarding to pod %s/%s%s\n", pod.Namespace, pod.Name, clusterSuffix(o.target.Cluster))
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(o.kcPortForwardOptions.StopChannel)
+	}()
+	req := o.target.clientSet.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).
+		SubResource("portforward")
+	return o.kcPortForwardOptions.PortForwarder.ForwardPorts("POST", req.URL(), *o.kcPortForwardOptions)
 }
 
 func splitPort(port string) (local, remote string) {
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/rollback"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+// NewRollbackCommand creates `rollback` command to roll an application back to one of its revisions
+func NewRollbackCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var revision int64
+	cmd := &cobra.Command{
+		Use:   "rollback APP_NAME",
+		Short: "Roll back an application to a revision",
+		Long: "Roll back an application to one of its revisions, the manifests stored in the revision are deployed again " +
+			"and recorded as a new revision. The revisions are listed if no revision is specified.",
+		Example: "vela rollback frontend\nvela rollback frontend --revision 3",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if revision <= 0 {
+				return printRevisions(newClient, app, ioStreams)
+			}
+			rev, err := rollback.Rollback(context.Background(), newClient, app, revision)
+			if err != nil {
+				return err
+			}
+			ioStreams.Infof("Application %s is rolling back to revision %s.\n", app.Name, rev.Name)
+			return nil
+		},
+	}
+	cmd.Flags().Int64Var(&revision, "revision", 0, "the number of the revision to roll back to, e.g., 3 for revision APP_NAME-v3")
+	return cmd
+}
+
+func printRevisions(c client.Client, app *v1beta1.Application, ioStreams cmdutil.IOStreams) error {
+	revs := &v1beta1.ApplicationRevisionList{}
+	if err := c.List(context.Background(), revs, client.InNamespace(app.Namespace), client.MatchingLabels{oam.LabelAppName: app.Name}); err != nil {
+		return errors.Wrapf(err, "cannot list revisions of application %s", app.Name)
+	}
+	if len(revs.Items) == 0 {
+		ioStreams.Infof("Application %s has no revision.\n", app.Name)
+		return nil
+	}
+	revision := func(rev v1beta1.ApplicationRevision) int {
+		n, _ := utils.ExtractRevision(rev.Name)
+		return n
+	}
+	sort.Slice(revs.Items, func(i, j int) bool { return revision(revs.Items[i]) < revision(revs.Items[j]) })
+	table := newUITable()
+	table.AddRow("REVISION", "NAME", "CREATED", "ROLLED-BACK-TO", "CURRENT")
+	for _, rev := range revs.Items {
+		current := ""
+		if app.Status.LatestRevision != nil && app.Status.LatestRevision.Name == rev.Name {
+			current = "*"
+		}
+		table.AddRow(revision(rev), rev.Name, rev.CreationTimestamp.Format("2006-01-02 15:04:05"), rev.GetAnnotations()[oam.AnnotationRollbackRevision], current)
+	}
+	ioStreams.Info(table.String())
+	return nil
+}
@@ -368,7 +368,7 @@ func ShowReferenceConsole(ctx context.Context, c common.Args, ioStreams cmdutil.
 
 	var propertyConsole []plugins.ConsoleReference
 	switch capability.Category {
-	case types.HelmCategory:
+	case types.HelmCategory, types.KustomizeCategory, types.GitCategory:
 		_, propertyConsole, err = ref.GenerateHelmAndKubeProperties(ctx, capability)
 		if err != nil {
 			return err
@@ -32,6 +32,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
 	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appstatus"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
@@ -95,7 +96,7 @@ func NewAppStatusCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comm
 		Use:     "status APP_NAME",
 		Short:   "Show status of an application",
 		Long:    "Show status of an application, including workloads and traits of each service.",
-		Example: `vela status APP_NAME`,
+		Example: "vela status APP_NAME\nvela status APP_NAME -o yaml\nvela status APP_NAME --watch",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return c.SetConfig()
 		},
@@ -115,6 +116,20 @@ func NewAppStatusCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comm
 			if err != nil {
 				return err
 			}
+			if watch, _ := cmd.Flags().GetBool("watch"); watch {
+				return watchAppStatus(ctx, c, ioStreams, env.Namespace, appName)
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if isStructuredOutput(format) {
+				app, err := loadRemoteApplication(newClient, env.Namespace, appName)
+				if err != nil {
+					return err
+				}
+				return printStructured(ioStreams, format, newApplicationOutput(app))
+			}
 			return printAppStatus(ctx, newClient, ioStreams, appName, env, cmd, c)
 		},
 		Annotations: map[string]string{
@@ -122,6 +137,9 @@ func NewAppStatusCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Comm
 		},
 	}
 	cmd.Flags().StringP("svc", "s", "", "service name")
+	cmd.Flags().Bool("lint", false, "show the best practice warnings found in the rendered workloads")
+	cmd.Flags().BoolP("watch", "w", false, "watch the phase, workflow steps and health of the application until it's deleted or interrupted")
+	addOutputFlag(cmd)
 	cmd.SetOut(ioStreams.Out)
 	return cmd
 }
@@ -141,7 +159,78 @@ func printAppStatus(ctx context.Context, c client.Client, ioStreams cmdutil.IOSt
 	cmd.Printf("%s\n\n", table.String())
 
 	cmd.Printf("Services:\n\n")
-	return loopCheckStatus(ctx, c, ioStreams, appName, env)
+	if err := loopCheckStatus(ctx, c, ioStreams, appName, env); err != nil {
+		return err
+	}
+	if showLint, _ := cmd.Flags().GetBool("lint"); showLint {
+		return printLintWarnings(c, cmd, appName, namespace)
+	}
+	return nil
+}
+
+func watchAppStatus(ctx context.Context, c common.Args, ioStreams cmdutil.IOStreams, namespace, appName string) error {
+	watcher, err := appstatus.NewWatcher(c.Config)
+	if err != nil {
+		return err
+	}
+	events, err := watcher.Watch(ctx, namespace, appName)
+	if err != nil {
+		return err
+	}
+	for e := range events {
+		if e.Type == appstatus.EventError {
+			return errors.New(e.Message)
+		}
+		ioStreams.Infof("%s  %s\n", time.Now().Format("15:04:05"), formatAppStatusEvent(e))
+	}
+	return nil
+}
+
+func formatAppStatusEvent(e appstatus.Event) string {
+	var msg string
+	switch e.Type {
+	case appstatus.EventPhase:
+		msg = fmt.Sprintf("Application is %s", e.Phase)
+	case appstatus.EventWorkflowStep:
+		msg = fmt.Sprintf("Workflow step %s is %s", e.Step, e.StepPhase)
+	case appstatus.EventHealth:
+		target := "Component " + e.Component
+		if e.Trait != "" {
+			target = fmt.Sprintf("Trait %s of component %s", e.Trait, e.Component)
+		}
+		health := "healthy"
+		if !e.Healthy {
+			health = "unhealthy"
+		}
+		msg = fmt.Sprintf("%s is %s", target, health)
+	case appstatus.EventDeleted:
+		return "Application is deleted"
+	default:
+		return string(e.Type)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func printLintWarnings(c client.Client, cmd *cobra.Command, appName, namespace string) error {
+	remoteApp, err := loadRemoteApplication(c, namespace, appName)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("\nLint Warnings:\n\n")
+	if len(remoteApp.Status.LintWarnings) == 0 {
+		cmd.Printf("  No warnings\n")
+		return nil
+	}
+	table := newUITable()
+	table.AddRow("  COMPONENT", "RULE", "MESSAGE")
+	for _, w := range remoteApp.Status.LintWarnings {
+		table.AddRow("  "+w.Component, w.Rule, w.Message)
+	}
+	cmd.Printf("%s\n", table.String())
+	return nil
 }
 
 func loadRemoteApplication(c client.Client, ns string, name string) (*v1beta1.Application, error) {
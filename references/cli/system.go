@@ -86,6 +86,8 @@ func SystemCommandGroup(c common.Args, ioStream cmdutil.IOStreams) *cobra.Comman
 	cmd.AddCommand(NewDryRunCommand(c, ioStream))
 	cmd.AddCommand(NewAdminInfoCommand(ioStream))
 	cmd.AddCommand(NewCUEPackageCommand(c, ioStream))
+	cmd.AddCommand(NewUsageCommand(c, ioStream))
+	cmd.AddCommand(NewMigrateCommand(c, ioStream))
 	return cmd
 }
 
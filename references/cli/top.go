@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	common2 "github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+// NewTopCommand creates `top` command to show the resource tree of an application
+func NewTopCommand(c common2.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var metrics bool
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "top APP_NAME",
+		Short: "Show the resources of an application as a tree",
+		Long: "Show the resources dispatched by an application and their children, e.g., the ReplicaSets and Pods of a " +
+			"Deployment, with the health of each resource, including the ones in the managed clusters. The CPU and " +
+			"memory usage of the pods are shown with --metrics if the metrics server is installed.",
+		Example: "vela top APP_NAME\nvela top APP_NAME --metrics --interval 5s\nvela top APP_NAME -o json",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			opts := common.ResourceTreeOptions{Metrics: metrics}
+			for {
+				app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+				if err != nil {
+					return err
+				}
+				tree, err := common.BuildResourceTree(ctx, newClient, common.NewClusterClientFn(newClient, app.Namespace), app, opts)
+				if err != nil {
+					return err
+				}
+				if isStructuredOutput(format) {
+					return printStructured(ioStreams, format, ResourceTreeOutput{
+						OutputMeta: newOutputMeta(ResourceTreeOutputKind),
+						Name:       app.Name,
+						Namespace:  app.Namespace,
+						Resources:  tree,
+					})
+				}
+				if interval <= 0 {
+					printResourceTree(ioStreams, tree, format == OutputWide, metrics)
+					return nil
+				}
+				// clear the screen before refreshing
+				ioStreams.Infof("\033[H\033[2J")
+				printResourceTree(ioStreams, tree, format == OutputWide, metrics)
+				time.Sleep(interval)
+			}
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.Flags().BoolVar(&metrics, "metrics", false, "show the CPU and memory usage of the pods reported by the metrics server")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "refresh the tree at the interval until interrupted, e.g., 5s, it's shown once if zero")
+	addOutputFlag(cmd)
+	return cmd
+}
+
+func printResourceTree(ioStreams cmdutil.IOStreams, tree []*common.ResourceNode, wide, metrics bool) {
+	if len(tree) == 0 {
+		ioStreams.Info("No resources are dispatched by the application.")
+		return
+	}
+	table := newUITable()
+	header := []interface{}{"RESOURCE", "CLUSTER", "COMPONENT", "HEALTH", "MESSAGE", "AGE"}
+	if wide {
+		header = append(header, "CONTAINERS", "RESTARTS")
+	}
+	if metrics {
+		header = append(header, "CPU", "MEMORY")
+	}
+	table.AddRow(header...)
+	for _, node := range tree {
+		addResourceRows(table, node, "", "", wide, metrics)
+	}
+	ioStreams.Info(table.String())
+	ioStreams.Infof("\nResources: %s\n", resourceTreeSummary(tree))
+}
+
+// addResourceRows adds the rows of the node and its children, prefix is the prefix of the node and indent is the
+// prefix of its children
+func addResourceRows(table *uitable.Table, node *common.ResourceNode, prefix, indent string, wide, metrics bool) {
+	component := node.Component
+	if len(node.Trait) != 0 {
+		component += "/" + node.Trait
+	}
+	age := ""
+	if node.CreatedAt != nil {
+		age = duration.HumanDuration(time.Since(node.CreatedAt.Time))
+	}
+	row := []interface{}{prefix + node.Kind + "/" + node.Name, node.Cluster, component, node.Health.Status, node.Health.Message, age}
+	if wide || metrics {
+		var ready int
+		var restarts int32
+		cpu, memory := resource.Quantity{}, resource.Quantity{}
+		for _, c := range node.Containers {
+			if c.Ready {
+				ready++
+			}
+			restarts += c.RestartCount
+			if q, ok := c.Usage[corev1.ResourceCPU]; ok {
+				cpu.Add(q)
+			}
+			if q, ok := c.Usage[corev1.ResourceMemory]; ok {
+				memory.Add(q)
+			}
+		}
+		if wide {
+			if len(node.Containers) == 0 {
+				row = append(row, "", "")
+			} else {
+				row = append(row, fmt.Sprintf("%d/%d", ready, len(node.Containers)), restarts)
+			}
+		}
+		if metrics {
+			if len(node.Containers) == 0 {
+				row = append(row, "", "")
+			} else {
+				row = append(row, fmt.Sprintf("%dm", cpu.MilliValue()), fmt.Sprintf("%dMi", memory.Value()/(1<<20)))
+			}
+		}
+	}
+	table.AddRow(row...)
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			addResourceRows(table, child, indent+"└─ ", indent+"   ", wide, metrics)
+		} else {
+			addResourceRows(table, child, indent+"├─ ", indent+"│  ", wide, metrics)
+		}
+	}
+}
+
+// resourceTreeSummary counts the resources of the tree by their health, e.g., "3 Healthy, 1 Progressing"
+func resourceTreeSummary(tree []*common.ResourceNode) string {
+	counts := map[common.ResourceHealthStatus]int{}
+	var count func(nodes []*common.ResourceNode)
+	count = func(nodes []*common.ResourceNode) {
+		for _, n := range nodes {
+			counts[n.Health.Status]++
+			count(n.Children)
+		}
+	}
+	count(tree)
+	var parts []string
+	for _, s := range []common.ResourceHealthStatus{common.ResourceHealthy, common.ResourceProgressing, common.ResourceUnhealthy, common.ResourceMissing} {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/metering"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+// NewUsageCommand creates `system usage` command to show the usage of applications per namespace and team
+func NewUsageCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var live, csv bool
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show the usage of applications per namespace and team",
+		Long: "Show the number of applications, components, dispatched objects and requested resources per namespace " +
+			"and team from the latest UsageReport collected by the metering of KubeVela, or collect it right now if " +
+			"--live is set or there is no report.",
+		Example: "vela system usage\nvela system usage --live --csv > usage.csv",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.SetConfig(); err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			spec, err := getUsage(context.Background(), newClient, live)
+			if err != nil {
+				return err
+			}
+			if csv {
+				return metering.WriteCSV(ioStreams.Out, spec)
+			}
+			printUsage(spec, ioStreams)
+			return nil
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeSystem,
+		},
+	}
+	cmd.Flags().BoolVar(&live, "live", false, "collect the usage right now instead of reading the latest UsageReport")
+	cmd.Flags().BoolVar(&csv, "csv", false, "print the usage of each namespace in CSV")
+	return cmd
+}
+
+// getUsage returns the latest UsageReport, the usage is collected if live is set or there is no report
+func getUsage(ctx context.Context, c client.Reader, live bool) (*v1beta1.UsageReportSpec, error) {
+	if !live {
+		reports := &v1beta1.UsageReportList{}
+		err := c.List(ctx, reports)
+		if err != nil && !meta.IsNoMatchError(err) {
+			return nil, errors.Wrap(err, "cannot list usage reports")
+		}
+		if len(reports.Items) != 0 {
+			sort.Slice(reports.Items, func(i, j int) bool {
+				return reports.Items[j].Spec.CollectedAt.Before(&reports.Items[i].Spec.CollectedAt)
+			})
+			return &reports.Items[0].Spec, nil
+		}
+	}
+	return metering.Collect(ctx, c)
+}
+
+func printUsage(spec *v1beta1.UsageReportSpec, ioStreams cmdutil.IOStreams) {
+	ioStreams.Infof("Collected at %s\n", spec.CollectedAt.Format("2006-01-02 15:04:05"))
+	if len(spec.Namespaces) == 0 {
+		ioStreams.Info("No application found.")
+		return
+	}
+	table := newUITable()
+	table.AddRow("NAMESPACE", "TEAM", "APPS", "COMPONENTS", "OBJECTS", "CPU", "MEMORY")
+	for _, u := range spec.Namespaces {
+		table.AddRow(u.Namespace, u.Team, u.Applications, u.Components, u.DispatchedObjects,
+			requestOf(u.Requests, corev1.ResourceCPU), requestOf(u.Requests, corev1.ResourceMemory))
+	}
+	ioStreams.Info(table.String())
+	if len(spec.Teams) == 0 {
+		return
+	}
+	ioStreams.Info()
+	table = newUITable()
+	table.AddRow("TEAM", "APPS", "COMPONENTS", "OBJECTS", "CPU", "MEMORY")
+	for _, t := range spec.Teams {
+		table.AddRow(t.Team, t.Applications, t.Components, t.DispatchedObjects,
+			requestOf(t.Requests, corev1.ResourceCPU), requestOf(t.Requests, corev1.ResourceMemory))
+	}
+	ioStreams.Info(table.String())
+}
+
+func requestOf(requests corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := requests[name]
+	if !ok {
+		return "0"
+	}
+	return q.String()
+}
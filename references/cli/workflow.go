@@ -0,0 +1,280 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/pkg/workflow"
+)
+
+// NewWorkflowCommand creates `workflow` command group
+func NewWorkflowCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workflow",
+		Short: "Operate application workflow",
+		Long:  "Operate the workflow of an application",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.AddCommand(
+		NewWorkflowStatusCommand(c, ioStreams),
+		NewWorkflowSuspendCommand(c, ioStreams),
+		NewWorkflowResumeCommand(c, ioStreams),
+		NewWorkflowApproveCommand(c, ioStreams),
+		NewWorkflowRestartCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+// NewWorkflowStatusCommand creates `workflow status` command to show the status of the workflow steps
+func NewWorkflowStatusCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "status APP_NAME",
+		Short:   "Show status of the workflow",
+		Long:    "Show status of the workflow steps of an application",
+		Example: "vela workflow status APP_NAME\nvela workflow status APP_NAME -o json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			return printWorkflowStatus(app, format, ioStreams)
+		},
+	}
+	addOutputFlag(cmd)
+	return cmd
+}
+
+// NewWorkflowSuspendCommand creates `workflow suspend` command to suspend the workflow before its next step
+func NewWorkflowSuspendCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var reason string
+	cmd := &cobra.Command{
+		Use:     "suspend APP_NAME",
+		Short:   "Suspend the workflow",
+		Long:    "Suspend the workflow of an application before its next step, the resources deployed are kept as they are",
+		Example: "vela workflow suspend APP_NAME --reason \"manual verification\"",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if err := workflow.Suspend(context.Background(), newClient, app, reason); err != nil {
+				return err
+			}
+			ioStreams.Infof("Workflow of application %s suspended.\n", app.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "the reason why the workflow is suspended, it's recorded in the status")
+	return cmd
+}
+
+// NewWorkflowResumeCommand creates `workflow resume` command to resume the suspended workflow
+func NewWorkflowResumeCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "resume APP_NAME",
+		Short:   "Resume the suspended workflow",
+		Long:    "Resume the suspended workflow of an application from the step it's suspended before",
+		Example: "vela workflow resume APP_NAME",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if !workflow.IsSuspended(app) {
+				ioStreams.Infof("Workflow of application %s is not suspended.\n", app.Name)
+				return nil
+			}
+			if err := workflow.Resume(context.Background(), newClient, app); err != nil {
+				return err
+			}
+			ioStreams.Infof("Workflow of application %s resumed.\n", app.Name)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// NewWorkflowApproveCommand creates `workflow approve` command to approve the suspend-for-approval workflow step
+func NewWorkflowApproveCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var step string
+	cmd := &cobra.Command{
+		Use:     "approve APP_NAME",
+		Short:   "Approve the workflow step waiting for approval",
+		Long:    "Approve the suspend-for-approval workflow step of an application as the current user, only the approvers of the step are allowed",
+		Example: "vela workflow approve APP_NAME\nvela workflow approve APP_NAME --step approve-prod",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if len(step) == 0 {
+				step = workflow.AwaitingApproval(app)
+			}
+			if len(step) == 0 {
+				ioStreams.Infof("Workflow of application %s is not waiting for approval.\n", app.Name)
+				return nil
+			}
+			if err := workflow.Approve(context.Background(), newClient, app, step); err != nil {
+				return err
+			}
+			ioStreams.Infof("Workflow step %s of application %s approved.\n", step, app.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&step, "step", "", "the name of the step to approve, it defaults to the step waiting for approval")
+	return cmd
+}
+
+// NewWorkflowRestartCommand creates `workflow restart` command to restart the workflow from the first step
+func NewWorkflowRestartCommand(c common.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var reason string
+	cmd := &cobra.Command{
+		Use:     "restart APP_NAME",
+		Short:   "Restart the workflow",
+		Long:    "Restart the workflow of an application from the first step, the status of the steps is cleared and the Git components are re-synced",
+		Example: "vela workflow restart APP_NAME --reason \"config rotated\"",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("please specify an application")
+			}
+			env, err := GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			newClient, err := c.GetClient()
+			if err != nil {
+				return err
+			}
+			app, err := loadRemoteApplication(newClient, env.Namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if err := workflow.Restart(context.Background(), newClient, app, reason); err != nil {
+				return err
+			}
+			ioStreams.Infof("Workflow of application %s restarted.\n", app.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "the reason why the workflow is restarted, it's recorded in the event")
+	return cmd
+}
+
+func printWorkflowStatus(app *v1beta1.Application, format string, ioStreams cmdutil.IOStreams) error {
+	out := WorkflowOutput{
+		OutputMeta: newOutputMeta(WorkflowOutputKind),
+		Name:       app.Name,
+		Namespace:  app.Namespace,
+		Phase:      app.Status.Phase,
+		Steps:      newWorkflowStepOutputs(app.Status.Workflow),
+		Suspend:    app.Status.WorkflowSuspend,
+	}
+	if isStructuredOutput(format) {
+		return printStructured(ioStreams, format, out)
+	}
+	if out.Suspend != nil {
+		ioStreams.Infof("Suspended before step %q since %s, reason: %s\n", out.Suspend.CurrentStep, out.Suspend.SuspendedAt.Format(time.RFC3339), out.Suspend.Reason)
+	}
+	if len(out.Steps) == 0 {
+		ioStreams.Infof("Application %s has no workflow.\n", app.Name)
+		return nil
+	}
+	table := newUITable()
+	header := []interface{}{"STEP", "TYPE", "PHASE", "RETRIES"}
+	if format == OutputWide {
+		header = append(header, "RESOURCE", "MESSAGE")
+	}
+	table.AddRow(header...)
+	addRow := func(name string, s WorkflowStepOutput) {
+		row := []interface{}{name, s.Type, s.Phase, s.Retries}
+		if format == OutputWide {
+			row = append(row, s.Resource, s.Message)
+		}
+		table.AddRow(row...)
+	}
+	for _, s := range out.Steps {
+		addRow(s.Name, s)
+		// sub-steps of a step group are listed under the group
+		for _, sub := range s.SubSteps {
+			addRow(s.Name+"/"+sub.Name, sub)
+		}
+	}
+	ioStreams.Info(table.String())
+	return nil
+}
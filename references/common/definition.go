@@ -0,0 +1,435 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	pkgappfile "github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DefinitionUsage is the reverse index from definitions to the applications using them.
+// The key is "<definition type>/<definition name>", and the value is the set of applications in "<namespace>/<name>"
+// format. A definition referenced with an explicit revision, e.g., worker@v2, is recorded both under its name and
+// its revision name, e.g., worker-v2.
+type DefinitionUsage map[string]map[string]bool
+
+func usageKey(defType commontypes.DefinitionType, name string) string {
+	return fmt.Sprintf("%s/%s", defType, name)
+}
+
+func (u DefinitionUsage) record(defType commontypes.DefinitionType, typeName, app string) {
+	if len(typeName) == 0 {
+		return
+	}
+	names := []string{typeName}
+	if revName, err := util.ConvertDefinitionRevName(typeName); err == nil {
+		names = []string{strings.Split(typeName, "@")[0], revName}
+	} else if i := strings.LastIndex(typeName, "@"); i > 0 {
+		// a semantic version constraint, e.g., worker@^1.2, is recorded under the definition name
+		names = []string{typeName[:i]}
+	}
+	for _, name := range names {
+		key := usageKey(defType, name)
+		if u[key] == nil {
+			u[key] = map[string]bool{}
+		}
+		u[key][app] = true
+	}
+}
+
+// UsedBy returns the applications using the definition (or definition revision) with the given name
+func (u DefinitionUsage) UsedBy(defType commontypes.DefinitionType, name string) []string {
+	var apps []string
+	for app := range u[usageKey(defType, name)] {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+func (u DefinitionUsage) recordApplication(app *v1beta1.Application, appKey string) {
+	for _, comp := range app.Spec.Components {
+		u.record(commontypes.ComponentType, comp.Type, appKey)
+		for _, tr := range comp.Traits {
+			u.record(commontypes.TraitType, tr.Type, appKey)
+		}
+	}
+	for _, p := range app.Spec.Policies {
+		u.record(commontypes.PolicyType, p.Type, appKey)
+	}
+	for _, s := range app.Spec.Workflow {
+		u.record(commontypes.WorkflowStepType, s.Type, appKey)
+	}
+}
+
+// BuildDefinitionUsage builds the reverse index of definitions from all Applications and ApplicationRevisions
+// in the cluster, so a definition used by any historical revision is still treated as in use.
+func BuildDefinitionUsage(ctx context.Context, c client.Reader) (DefinitionUsage, error) {
+	usage := DefinitionUsage{}
+	appList := new(v1beta1.ApplicationList)
+	if err := c.List(ctx, appList); err != nil {
+		return nil, err
+	}
+	for i, app := range appList.Items {
+		usage.recordApplication(&appList.Items[i], fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+	}
+	appRevList := new(v1beta1.ApplicationRevisionList)
+	if err := c.List(ctx, appRevList); err != nil {
+		return nil, err
+	}
+	for i, appRev := range appRevList.Items {
+		appKey := fmt.Sprintf("%s/%s", appRev.Namespace, appRev.Spec.Application.Name)
+		if len(appRev.Spec.Application.Name) == 0 {
+			appKey = fmt.Sprintf("%s/%s", appRev.Namespace, appRev.Labels[oam.LabelAppName])
+		}
+		usage.recordApplication(&appRevList.Items[i].Spec.Application, appKey)
+		for name := range appRev.Spec.ComponentDefinitions {
+			usage.record(commontypes.ComponentType, name, appKey)
+		}
+		for name := range appRev.Spec.TraitDefinitions {
+			usage.record(commontypes.TraitType, name, appKey)
+		}
+	}
+	return usage, nil
+}
+
+// DanglingDefinition is a definition or definition revision which is not used by any application
+type DanglingDefinition struct {
+	Type      commontypes.DefinitionType
+	Kind      string
+	Namespace string
+	Name      string
+	Age       time.Duration
+	// ConfigMaps are the capability ConfigMaps storing the OpenAPI schema of the definition
+	ConfigMaps []string
+
+	object runtime.Object
+}
+
+// PruneOptions controls which definitions are treated as dangling
+type PruneOptions struct {
+	// Namespace limits the definitions to prune, all namespaces if it's empty
+	Namespace string
+	// Retention is the minimal age of a dangling definition to be pruned
+	Retention time.Duration
+	// Now is the time to calculate the age, it's time.Now() if unset
+	Now time.Time
+}
+
+type definitionEntry struct {
+	defType  commontypes.DefinitionType
+	kind     string
+	meta     metav1.Object
+	object   runtime.Object
+	revLabel string
+	latest   *commontypes.Revision
+	// schemaRef is the ConfigMap storing the OpenAPI v3 JSON schema of the parameters
+	schemaRef string
+}
+
+func listDefinitions(ctx context.Context, c client.Reader, namespace string) ([]definitionEntry, error) {
+	var entries []definitionEntry
+	compDefs := new(v1beta1.ComponentDefinitionList)
+	if err := c.List(ctx, compDefs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range compDefs.Items {
+		d := &compDefs.Items[i]
+		entries = append(entries, definitionEntry{defType: commontypes.ComponentType, kind: v1beta1.ComponentDefinitionKind,
+			meta: d, object: d, revLabel: oam.LabelComponentDefinitionName, latest: d.Status.LatestRevision, schemaRef: d.Status.ConfigMapRef})
+	}
+	traitDefs := new(v1beta1.TraitDefinitionList)
+	if err := c.List(ctx, traitDefs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range traitDefs.Items {
+		d := &traitDefs.Items[i]
+		entries = append(entries, definitionEntry{defType: commontypes.TraitType, kind: v1beta1.TraitDefinitionKind,
+			meta: d, object: d, revLabel: oam.LabelTraitDefinitionName, latest: d.Status.LatestRevision, schemaRef: d.Status.ConfigMapRef})
+	}
+	policyDefs := new(v1beta1.PolicyDefinitionList)
+	if err := c.List(ctx, policyDefs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range policyDefs.Items {
+		d := &policyDefs.Items[i]
+		entries = append(entries, definitionEntry{defType: commontypes.PolicyType, kind: v1beta1.PolicyDefinitionKind,
+			meta: d, object: d, revLabel: oam.LabelPolicyDefinitionName, latest: d.Status.LatestRevision})
+	}
+	stepDefs := new(v1beta1.WorkflowStepDefinitionList)
+	if err := c.List(ctx, stepDefs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range stepDefs.Items {
+		d := &stepDefs.Items[i]
+		entries = append(entries, definitionEntry{defType: commontypes.WorkflowStepType, kind: v1beta1.WorkflowStepDefinitionKind,
+			meta: d, object: d, revLabel: oam.LabelWorkflowStepDefinitionName})
+	}
+	return entries, nil
+}
+
+// DefinitionInfo is the brief information of a definition
+type DefinitionInfo struct {
+	Type           commontypes.DefinitionType
+	Kind           string
+	Namespace      string
+	Name           string
+	Description    string
+	Annotations    map[string]string
+	LatestRevision int64
+	CreatedAt      metav1.Time
+	// Ready is the status of the Ready condition maintained by the definition health checks, it's Unknown if the
+	// definition is not checked yet
+	Ready corev1.ConditionStatus
+	// Message tells why the definition is not ready
+	Message string
+	// SchemaConfigMap is the ConfigMap storing the OpenAPI v3 JSON schema of the parameters, only component and
+	// trait definitions have it
+	SchemaConfigMap string
+}
+
+// ListDefinitions lists the component, trait, policy and workflow step definitions in the namespace, or all
+// namespaces if it's empty. They're sorted by type and then by namespace and name.
+func ListDefinitions(ctx context.Context, c client.Reader, namespace string) ([]DefinitionInfo, error) {
+	entries, err := listDefinitions(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]DefinitionInfo, 0, len(entries))
+	for _, e := range entries {
+		info := DefinitionInfo{
+			Type:            e.defType,
+			Kind:            e.kind,
+			Namespace:       e.meta.GetNamespace(),
+			Name:            e.meta.GetName(),
+			Description:     e.meta.GetAnnotations()[types.AnnDescription],
+			Annotations:     e.meta.GetAnnotations(),
+			CreatedAt:       e.meta.GetCreationTimestamp(),
+			SchemaConfigMap: e.schemaRef,
+		}
+		if e.latest != nil {
+			info.LatestRevision = e.latest.Revision
+		}
+		if conditioned, ok := e.object.(oam.Conditioned); ok {
+			ready := conditioned.GetCondition(runtimev1alpha1.TypeReady)
+			info.Ready, info.Message = ready.Status, ready.Message
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Type != infos[j].Type {
+			return infos[i].Type < infos[j].Type
+		}
+		if infos[i].Namespace != infos[j].Namespace {
+			return infos[i].Namespace < infos[j].Namespace
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// DefinitionSchema is a component or trait definition with the OpenAPI v3 JSON schema of its parameters
+type DefinitionSchema struct {
+	DefinitionInfo
+	// Schema is empty if the schema of the definition is not generated yet
+	Schema string
+}
+
+// LocalizedSchema returns the OpenAPI v3 JSON schema in the data of a capability ConfigMap localized in the locale.
+// It falls back to the language of the locale, e.g., zh for zh-CN, and then to the schema not localized.
+func LocalizedSchema(data map[string]string, locale string) string {
+	for len(locale) != 0 {
+		if schema, ok := data[types.LocalizedOpenapiV3JSONSchema(locale)]; ok {
+			return schema
+		}
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return data[types.OpenapiV3JSONSchema]
+}
+
+// ListDefinitionSchemas lists the component and trait definitions in the namespace, or all namespaces if it's empty,
+// with the JSON schemas of their parameters read from the capability ConfigMaps, localized in the locale if it's
+// not empty.
+func ListDefinitionSchemas(ctx context.Context, c client.Reader, namespace, locale string) ([]DefinitionSchema, error) {
+	infos, err := ListDefinitions(ctx, c, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var schemas []DefinitionSchema
+	for _, info := range infos {
+		if info.Type != commontypes.ComponentType && info.Type != commontypes.TraitType {
+			continue
+		}
+		s := DefinitionSchema{DefinitionInfo: info}
+		if info.SchemaConfigMap != "" {
+			cm := new(corev1.ConfigMap)
+			err := c.Get(ctx, client.ObjectKey{Namespace: info.Namespace, Name: info.SchemaConfigMap}, cm)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, errors.Wrapf(err, "cannot get the schema of %s %s", info.Type, info.Name)
+			}
+			s.Schema = LocalizedSchema(cm.Data, locale)
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, nil
+}
+
+func isPruneProtected(o metav1.Object) bool {
+	return o.GetLabels()[oam.LabelDefinitionPruneProtection] == "true"
+}
+
+// FindDanglingDefinitions finds all definitions and DefinitionRevisions with zero usage and older than the retention.
+// A DefinitionRevision is dangling if its definition is dangling, or if it's neither the latest revision nor
+// referenced explicitly by any application. Definitions labeled with oam.LabelDefinitionPruneProtection are skipped
+// along with all their revisions.
+func FindDanglingDefinitions(ctx context.Context, c client.Reader, opts PruneOptions) ([]DanglingDefinition, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	usage, err := BuildDefinitionUsage(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := listDefinitions(ctx, c, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var dangling []DanglingDefinition
+	for _, e := range entries {
+		if isPruneProtected(e.meta) {
+			continue
+		}
+		defInUse := len(usage.UsedBy(e.defType, e.meta.GetName())) != 0
+		if !defInUse {
+			if age := now.Sub(e.meta.GetCreationTimestamp().Time); age >= opts.Retention {
+				dangling = append(dangling, DanglingDefinition{
+					Type:       e.defType,
+					Kind:       e.kind,
+					Namespace:  e.meta.GetNamespace(),
+					Name:       e.meta.GetName(),
+					Age:        age,
+					ConfigMaps: []string{types.CapabilityConfigMapNamePrefix + e.meta.GetName()},
+					object:     e.object,
+				})
+			}
+		}
+
+		defRevs := new(v1beta1.DefinitionRevisionList)
+		if err := c.List(ctx, defRevs, client.InNamespace(e.meta.GetNamespace()),
+			client.MatchingLabels{e.revLabel: e.meta.GetName()}); err != nil {
+			return nil, err
+		}
+		for i := range defRevs.Items {
+			rev := &defRevs.Items[i]
+			if isPruneProtected(rev) {
+				continue
+			}
+			if defInUse && (len(usage.UsedBy(e.defType, rev.Name)) != 0 || (e.latest != nil && e.latest.Name == rev.Name)) {
+				continue
+			}
+			age := now.Sub(rev.CreationTimestamp.Time)
+			if age < opts.Retention {
+				continue
+			}
+			dangling = append(dangling, DanglingDefinition{
+				Type:       e.defType,
+				Kind:       v1beta1.DefinitionRevisionKind,
+				Namespace:  rev.Namespace,
+				Name:       rev.Name,
+				Age:        age,
+				ConfigMaps: []string{types.CapabilityConfigMapNamePrefix + rev.Name},
+				object:     rev,
+			})
+		}
+	}
+	return dangling, nil
+}
+
+// PruneDefinitions deletes the dangling definitions along with their capability ConfigMaps
+func PruneDefinitions(ctx context.Context, c client.Client, dangling []DanglingDefinition) error {
+	for _, d := range dangling {
+		if err := c.Delete(ctx, d.object); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete %s %s/%s: %w", d.Kind, d.Namespace, d.Name, err)
+		}
+		for _, cmName := range d.ConfigMaps {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cmName, Namespace: d.Namespace}}
+			if err := c.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("delete capability ConfigMap %s/%s: %w", d.Namespace, cmName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// TemplateDebugOptions is the component whose definition template is evaluated for debugging
+type TemplateDebugOptions struct {
+	Namespace     string
+	ComponentType string
+	// ComponentName is context.name in the template, it's the component type if empty
+	ComponentName string
+	// AppName is context.appName in the template, it's the component name if empty
+	AppName    string
+	Properties map[string]interface{}
+}
+
+// DebugComponentTemplate evaluates the CUE template of the component definition with the properties, and returns
+// the evaluated context, parameter and intermediate values with the secrets redacted
+func DebugComponentTemplate(ctx context.Context, c client.Reader, dm discoverymapper.DiscoveryMapper, pd *definition.PackageDiscover,
+	opt TemplateDebugOptions) (*definition.TemplateDebugInfo, error) {
+	ctx = util.SetNamespaceInCtx(ctx, opt.Namespace)
+	tmpl, err := pkgappfile.LoadTemplate(ctx, dm, c, opt.ComponentType, types.TypeComponentDefinition)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load component definition %s", opt.ComponentType)
+	}
+	if len(tmpl.TemplateStr) == 0 {
+		return nil, errors.Errorf("component definition %s has no CUE template", opt.ComponentType)
+	}
+	if len(opt.ComponentName) == 0 {
+		opt.ComponentName = opt.ComponentType
+	}
+	if len(opt.AppName) == 0 {
+		opt.AppName = opt.ComponentName
+	}
+	pCtx := process.NewContext(opt.Namespace, opt.ComponentName, opt.AppName, opt.AppName+"-v1")
+	return definition.DebugWorkloadTemplate(pCtx, pd, opt.ComponentType, tmpl.TemplateStr, opt.Properties)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestFindAndPruneDanglingDefinitions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	old := metav1.NewTime(now.Add(-48 * time.Hour))
+	recent := metav1.NewTime(now.Add(-time.Hour))
+	ns := "vela-system"
+
+	objs := []runtime.Object{
+		&v1beta1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: v1beta1.ApplicationSpec{Components: []v1beta1.ApplicationComponent{{
+				Name: "comp", Type: "worker@v1",
+				Traits: []v1beta1.ApplicationTrait{{Type: "scaler"}},
+			}}},
+		},
+		&v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: ns, CreationTimestamp: old},
+			Status:     v1beta1.ComponentDefinitionStatus{LatestRevision: &commontypes.Revision{Name: "worker-v3", Revision: 3}},
+		},
+		&v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: ns, CreationTimestamp: old}},
+		&v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "fresh", Namespace: ns, CreationTimestamp: recent}},
+		&v1beta1.ComponentDefinition{ObjectMeta: metav1.ObjectMeta{Name: "protected", Namespace: ns, CreationTimestamp: old,
+			Labels: map[string]string{oam.LabelDefinitionPruneProtection: "true"}}},
+		&v1beta1.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: ns, CreationTimestamp: old}},
+		&v1beta1.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: ns, CreationTimestamp: old}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schema-unused", Namespace: ns}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schema-worker-v2", Namespace: ns}},
+	}
+	for _, rev := range []string{"worker-v1", "worker-v2", "worker-v3"} {
+		objs = append(objs, &v1beta1.DefinitionRevision{ObjectMeta: metav1.ObjectMeta{Name: rev, Namespace: ns, CreationTimestamp: old,
+			Labels: map[string]string{oam.LabelComponentDefinitionName: "worker"}}})
+	}
+	k8sClient := fake.NewFakeClientWithScheme(common.Scheme, objs...)
+
+	dangling, err := FindDanglingDefinitions(ctx, k8sClient, PruneOptions{Retention: 24 * time.Hour, Now: now})
+	assert.NilError(t, err)
+	var names []string
+	for _, d := range dangling {
+		names = append(names, d.Kind+"/"+d.Name)
+	}
+	sort.Strings(names)
+	assert.DeepEqual(t, names, []string{
+		"ComponentDefinition/unused",
+		"DefinitionRevision/worker-v2",
+		"TraitDefinition/ingress",
+	})
+
+	assert.NilError(t, PruneDefinitions(ctx, k8sClient, dangling))
+	err = k8sClient.Get(ctx, client.ObjectKey{Name: "unused", Namespace: ns}, &v1beta1.ComponentDefinition{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	err = k8sClient.Get(ctx, client.ObjectKey{Name: "schema-worker-v2", Namespace: ns}, &corev1.ConfigMap{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	assert.NilError(t, k8sClient.Get(ctx, client.ObjectKey{Name: "worker-v1", Namespace: ns}, &v1beta1.DefinitionRevision{}))
+}
+
+func TestListDefinitionSchemas(t *testing.T) {
+	ctx := context.Background()
+	ns := "vela-system"
+	k8sClient := fake.NewFakeClientWithScheme(common.Scheme,
+		&v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: ns},
+			Status:     v1beta1.ComponentDefinitionStatus{ConfigMapRef: "schema-worker"},
+		},
+		&v1beta1.TraitDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: ns},
+			Status:     v1beta1.TraitDefinitionStatus{ConfigMapRef: "schema-scaler"},
+		},
+		&v1beta1.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: ns}},
+		&v1beta1.PolicyDefinition{ObjectMeta: metav1.ObjectMeta{Name: "env-binding", Namespace: ns}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schema-worker", Namespace: ns},
+			Data: map[string]string{
+				types.OpenapiV3JSONSchema:                `{"properties":{"image":{"type":"string"}}}`,
+				types.LocalizedOpenapiV3JSONSchema("zh"): `{"properties":{"image":{"type":"string","title":"镜像"}}}`,
+			}},
+	)
+
+	schemas, err := ListDefinitionSchemas(ctx, k8sClient, ns, "")
+	assert.NilError(t, err)
+	got := map[string]string{}
+	for _, s := range schemas {
+		got[string(s.Type)+"/"+s.Name] = s.Schema
+	}
+	assert.DeepEqual(t, got, map[string]string{
+		"Component/worker": `{"properties":{"image":{"type":"string"}}}`,
+		"Trait/scaler":     "",
+		"Trait/ingress":    "",
+	})
+
+	schemas, err = ListDefinitionSchemas(ctx, k8sClient, ns, "zh-CN")
+	assert.NilError(t, err)
+	for _, s := range schemas {
+		if s.Name == "worker" {
+			assert.Equal(t, s.Schema, `{"properties":{"image":{"type":"string","title":"镜像"}}}`)
+		}
+	}
+}
+
+func TestLocalizedSchema(t *testing.T) {
+	data := map[string]string{
+		types.OpenapiV3JSONSchema:                   "default",
+		types.LocalizedOpenapiV3JSONSchema("zh"):    "zh",
+		types.LocalizedOpenapiV3JSONSchema("zh-TW"): "zh-TW",
+	}
+	assert.Equal(t, LocalizedSchema(data, ""), "default")
+	assert.Equal(t, LocalizedSchema(data, "en"), "default")
+	assert.Equal(t, LocalizedSchema(data, "zh"), "zh")
+	assert.Equal(t, LocalizedSchema(data, "zh-CN"), "zh")
+	assert.Equal(t, LocalizedSchema(data, "zh-TW"), "zh-TW")
+}
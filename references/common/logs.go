@@ -0,0 +1,313 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+)
+
+// logPodResyncInterval is the interval to resolve the pods of the components again when the logs are followed, so
+// the logs of the pods created later, e.g., by a rolling update, are streamed too
+var logPodResyncInterval = 5 * time.Second
+
+// ClusterConfigFn returns the rest config of a cluster by the name, the control plane cluster if the name is empty
+type ClusterConfigFn func(ctx context.Context, cluster string) (*rest.Config, error)
+
+// NewClusterConfigFn returns the ClusterConfigFn returning the rest config of the control plane cluster, or the ones of
+// the managed clusters in the namespace of the application through the cluster gateway
+func NewClusterConfigFn(c client.Client, cfg *rest.Config, namespace string) ClusterConfigFn {
+	gateway := multicluster.NewClusterGateway(c)
+	return func(ctx context.Context, name string) (*rest.Config, error) {
+		if len(name) == 0 {
+			return cfg, nil
+		}
+		cluster := &v1beta1.Cluster{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cluster); err != nil {
+			return nil, errors.Wrapf(err, "cannot get cluster %s", name)
+		}
+		return gateway.RestConfigOf(ctx, cluster)
+	}
+}
+
+// ClusterClientsetFn returns the clientset of a cluster by the name, the control plane cluster if the name is empty
+type ClusterClientsetFn func(ctx context.Context, cluster string) (kubernetes.Interface, error)
+
+// NewClusterClientsetFn returns the ClusterClientsetFn building the clientsets from the rest configs returned by
+// NewClusterConfigFn, the clientsets are cached by the cluster names
+func NewClusterClientsetFn(c client.Client, cfg *rest.Config, namespace string) ClusterClientsetFn {
+	configOf := NewClusterConfigFn(c, cfg, namespace)
+	clientsets := map[string]kubernetes.Interface{}
+	var mu sync.Mutex
+	return func(ctx context.Context, name string) (kubernetes.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if cs, ok := clientsets[name]; ok {
+			return cs, nil
+		}
+		config, err := configOf(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot build the clientset of cluster %q", name)
+		}
+		clientsets[name] = cs
+		return cs, nil
+	}
+}
+
+// ClusterLogStreamFn opens the stream of the logs of a container of a pod on a cluster, the control plane cluster if
+// the name is empty
+type ClusterLogStreamFn func(ctx context.Context, cluster, namespace, pod string, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+
+// NewClusterLogStreamFn returns the ClusterLogStreamFn streaming the logs through the clientsets of the clusters
+func NewClusterLogStreamFn(clientsetOf ClusterClientsetFn) ClusterLogStreamFn {
+	return func(ctx context.Context, cluster, namespace, pod string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		cs, err := clientsetOf(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+		return cs.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	}
+}
+
+// LogOptions are the options to stream the logs of an application
+type LogOptions struct {
+	// Components are the components whose logs are streamed, all the components of the application if empty
+	Components []string
+	// Container is the name of the container whose logs are streamed, all the containers of the pods if empty
+	Container string
+	// Since streams the logs newer than the duration only, all the logs if zero
+	Since time.Duration
+	// Tail is the number of the latest lines of each container streamed, all the lines if not positive
+	Tail int64
+	// Follow keeps streaming the new logs, including the ones of the pods created later, until the context is done
+	Follow bool
+	// Timestamps prefixes each message with its RFC3339 timestamp
+	Timestamps bool
+}
+
+// LogLine is a line of the logs of a container of an application, Error is set instead of Message if the logs of
+// the container cannot be streamed
+type LogLine struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Component string `json:"component"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Message   string `json:"message,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Prefix identifies where the line comes from as [cluster/]component/pod/container
+func (l LogLine) Prefix() string {
+	prefix := l.Component + "/" + l.Pod + "/" + l.Container
+	if len(l.Cluster) != 0 {
+		return l.Cluster + "/" + prefix
+	}
+	return prefix
+}
+
+// StreamApplicationLogs streams the logs of the containers of the pods behind the components of an application,
+// including the ones on the managed clusters, the lines of all the containers are merged into the returned channel
+// in the order they're read. The channel is closed when all the logs are streamed, or when the context is done if
+// the logs are followed.
+func StreamApplicationLogs(ctx context.Context, c client.Client, clientOf ClusterClientFn, streamOf ClusterLogStreamFn, app *v1beta1.Application, opts LogOptions) (<-chan LogLine, error) {
+	components := opts.Components
+	if len(components) == 0 {
+		for _, comp := range app.Spec.Components {
+			components = append(components, comp.Name)
+		}
+	}
+	for _, name := range components {
+		found := false
+		for _, comp := range app.Spec.Components {
+			found = found || comp.Name == name
+		}
+		if !found {
+			return nil, errors.Errorf("component %s not found in application %s", name, app.Name)
+		}
+	}
+	s := &logStreamer{
+		c:          c,
+		clientOf:   clientOf,
+		streamOf:   streamOf,
+		app:        app,
+		components: components,
+		opts:       opts,
+		started:    map[logTarget]bool{},
+	}
+	targets, err := s.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(chan LogLine, 1024)
+	go s.run(ctx, targets, lines)
+	return lines, nil
+}
+
+// logTarget is a container whose logs are streamed
+type logTarget struct {
+	cluster   string
+	component string
+	namespace string
+	pod       string
+	container string
+}
+
+type logStreamer struct {
+	c          client.Client
+	clientOf   ClusterClientFn
+	streamOf   ClusterLogStreamFn
+	app        *v1beta1.Application
+	components []string
+	opts       LogOptions
+
+	wg      sync.WaitGroup
+	started map[logTarget]bool
+}
+
+// resolve returns the containers of the pods of the components, the pending pods are skipped since they have no
+// logs yet
+func (s *logStreamer) resolve(ctx context.Context) ([]logTarget, error) {
+	var targets []logTarget
+	for _, comp := range s.components {
+		pods, err := ListComponentPods(ctx, s.c, s.clientOf, s.app, comp)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pods {
+			if p.Pod.Status.Phase == corev1.PodPending {
+				continue
+			}
+			for _, ctr := range p.Pod.Spec.Containers {
+				if len(s.opts.Container) != 0 && ctr.Name != s.opts.Container {
+					continue
+				}
+				targets = append(targets, logTarget{
+					cluster:   p.Cluster,
+					component: comp,
+					namespace: p.Pod.Namespace,
+					pod:       p.Pod.Name,
+					container: ctr.Name,
+				})
+			}
+		}
+	}
+	return targets, nil
+}
+
+func (s *logStreamer) run(ctx context.Context, targets []logTarget, lines chan<- LogLine) {
+	defer close(lines)
+	s.start(ctx, targets, lines)
+	if s.opts.Follow {
+		ticker := time.NewTicker(logPodResyncInterval)
+		defer ticker.Stop()
+	resync:
+		for {
+			select {
+			case <-ctx.Done():
+				break resync
+			case <-ticker.C:
+			}
+			// the pods failed to be resolved are retried at the next resync
+			if targets, err := s.resolve(ctx); err == nil {
+				s.start(ctx, targets, lines)
+			}
+		}
+	}
+	s.wg.Wait()
+}
+
+// start streams the logs of the containers not streamed yet
+func (s *logStreamer) start(ctx context.Context, targets []logTarget, lines chan<- LogLine) {
+	for _, t := range targets {
+		if s.started[t] {
+			continue
+		}
+		s.started[t] = true
+		s.wg.Add(1)
+		go s.tail(ctx, t, lines)
+	}
+}
+
+func (s *logStreamer) tail(ctx context.Context, t logTarget, lines chan<- LogLine) {
+	defer s.wg.Done()
+	line := LogLine{Cluster: t.cluster, Component: t.component, Namespace: t.namespace, Pod: t.pod, Container: t.container}
+	send := func(l LogLine) bool {
+		select {
+		case lines <- l:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	fail := func(err error) {
+		l := line
+		l.Error = errors.WithMessagef(err, "cannot stream the logs of container %s of pod %s/%s", t.container, t.namespace, t.pod).Error()
+		send(l)
+	}
+
+	podOpts := &corev1.PodLogOptions{Container: t.container, Follow: s.opts.Follow, Timestamps: s.opts.Timestamps}
+	if s.opts.Since > 0 {
+		since := int64(s.opts.Since.Seconds())
+		podOpts.SinceSeconds = &since
+	}
+	if s.opts.Tail > 0 {
+		tail := s.opts.Tail
+		podOpts.TailLines = &tail
+	}
+	stream, err := s.streamOf(ctx, t.cluster, t.namespace, t.pod, podOpts)
+	if err != nil {
+		fail(err)
+		return
+	}
+	//nolint:errcheck
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+	for {
+		msg, err := reader.ReadString('\n')
+		if len(msg) != 0 {
+			l := line
+			l.Message = strings.TrimSuffix(msg, "\n")
+			if !send(l) {
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				fail(err)
+			}
+			return
+		}
+	}
+}
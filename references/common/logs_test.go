@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestStreamApplicationLogs(t *testing.T) {
+	ctx := context.Background()
+	pod := func(ns, name, component string, phase corev1.PodPhase, containers ...string) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name,
+			Labels: map[string]string{oam.LabelAppName: "app", oam.LabelAppComponent: component}}}
+		for _, c := range containers {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: c})
+		}
+		p.Status.Phase = phase
+		return p
+	}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	app.Spec.Components = []v1beta1.ApplicationComponent{{Name: "web"}, {Name: "db"}}
+	app.Status.ResourceTracker = &runtimev1alpha1.TypedReference{Name: "default-app"}
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "default-app"}}
+	rt.Status.TrackedResources = []v1beta1.TypedReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web", Cluster: "east"},
+	}
+
+	webPod := pod("default", "web-1", "web", corev1.PodRunning, "web", "proxy")
+	pendingPod := pod("default", "web-2", "web", corev1.PodPending, "web")
+	dbPod := pod("default", "db-1", "db", corev1.PodRunning, "db")
+	eastPod := pod("prod", "web-east", "web", corev1.PodRunning, "web")
+	hub := fake.NewFakeClientWithScheme(common.Scheme, app, rt, webPod, pendingPod, dbPod)
+	east := fake.NewFakeClientWithScheme(common.Scheme, eastPod)
+	clientOf := func(_ context.Context, cluster string) (client.Client, error) {
+		assert.Equal(t, "east", cluster)
+		return east, nil
+	}
+	pods := map[string][]*corev1.Pod{"": {webPod, pendingPod, dbPod}, "east": {eastPod}}
+	streamOf := func(_ context.Context, cluster, namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		for _, p := range pods[cluster] {
+			if p.Namespace == namespace && p.Name == name {
+				return ioutil.NopCloser(strings.NewReader("fake logs\n")), nil
+			}
+		}
+		return nil, errors.Errorf("pod %s/%s not found on cluster %q", namespace, name, cluster)
+	}
+
+	collect := func(opts LogOptions) []string {
+		lines, err := StreamApplicationLogs(ctx, hub, clientOf, streamOf, app, opts)
+		assert.NilError(t, err)
+		var got []string
+		for l := range lines {
+			assert.Equal(t, "", l.Error)
+			got = append(got, l.Prefix()+" "+l.Message)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	assert.DeepEqual(t, []string{
+		"db/db-1/db fake logs",
+		"east/web/web-east/web fake logs",
+		"web/web-1/proxy fake logs",
+		"web/web-1/web fake logs",
+	}, collect(LogOptions{}))
+	assert.DeepEqual(t, []string{
+		"east/web/web-east/web fake logs",
+		"web/web-1/web fake logs",
+	}, collect(LogOptions{Components: []string{"web"}, Container: "web", Tail: 10}))
+
+	_, err := StreamApplicationLogs(ctx, hub, clientOf, streamOf, app, LogOptions{Components: []string{"cache"}})
+	assert.Error(t, err, "component cache not found in application app")
+}
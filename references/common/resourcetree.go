@@ -0,0 +1,310 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
+	"github.com/oam-dev/kubevela/pkg/multicluster"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// maxOwnerDepth is the maximum depth of the owner references followed from a pod, e.g., Pod -> ReplicaSet ->
+// Deployment is 2
+const maxOwnerDepth = 5
+
+const (
+	// helmReleaseNameAnnotation is the annotation Helm records the release of the resources it creates in
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	// defaultContainerAnnotation is the annotation kubectl reads the default container of a pod from
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+)
+
+// ClusterClientFn returns the client of a managed cluster of the application by the cluster name
+type ClusterClientFn func(ctx context.Context, cluster string) (client.Client, error)
+
+// NewClusterClientFn returns the ClusterClientFn getting the clients of the managed clusters in the namespace of the
+// application through the cluster gateway
+func NewClusterClientFn(c client.Client, namespace string) ClusterClientFn {
+	gateway := multicluster.NewClusterGateway(c)
+	return func(ctx context.Context, name string) (client.Client, error) {
+		cluster := &v1beta1.Cluster{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cluster); err != nil {
+			return nil, errors.Wrapf(err, "cannot get cluster %s", name)
+		}
+		return gateway.ClientOf(ctx, cluster)
+	}
+}
+
+// ComponentPod is a pod of a component, Cluster is the managed cluster it's in, empty for the control plane cluster
+type ComponentPod struct {
+	Cluster string
+	Pod     corev1.Pod
+}
+
+// resourceScope is a namespace of a cluster holding the resources of an application
+type resourceScope struct {
+	cluster   string
+	namespace string
+}
+
+// ListComponentPods resolves the pods of a component through the resource tree of the application, i.e., the pods
+// labeled with the application and the component, or owned by such resources directly or indirectly like the pods
+// of a Deployment. The pods of the workload created by Helm for a Helm-based component are resolved too. The namespace of the application and the clusters and namespaces of the resources tracked in
+// the ResourceTracker of the application, including the ones dispatched to managed clusters, are searched.
+func ListComponentPods(ctx context.Context, c client.Client, clientOf ClusterClientFn, app *v1beta1.Application, component string) ([]ComponentPod, error) {
+	scopes, err := resourceScopesOf(ctx, c, app)
+	if err != nil {
+		return nil, err
+	}
+	helmRelease, helmWorkload, err := helmWorkloadOf(ctx, c, app, component)
+	if err != nil {
+		return nil, err
+	}
+	var pods []ComponentPod
+	for _, scope := range scopes {
+		cc := c
+		if len(scope.cluster) != 0 {
+			if cc, err = clientOf(ctx, scope.cluster); err != nil {
+				return nil, errors.WithMessagef(err, "cannot get the client of cluster %s", scope.cluster)
+			}
+		}
+		found, err := (&podResolver{c: cc, app: app.Name, component: component, helmRelease: helmRelease,
+			helmWorkload: helmWorkload, owners: map[types.UID]bool{}}).list(ctx, scope.namespace)
+		if err != nil {
+			if len(scope.cluster) != 0 {
+				return nil, errors.WithMessagef(err, "cannot list the pods in cluster %s", scope.cluster)
+			}
+			return nil, err
+		}
+		for _, p := range found {
+			pods = append(pods, ComponentPod{Cluster: scope.cluster, Pod: p})
+		}
+	}
+	return pods, nil
+}
+
+// SelectComponentPod picks a pod of a component to execute commands in or forward ports to, the ready pods are
+// preferred to the running ones that are not ready yet, and the newest pod is picked among them. The pods not running
+// are never picked. The pods are narrowed to the ones in the cluster if it's not empty.
+func SelectComponentPod(pods []ComponentPod, cluster string) (*ComponentPod, error) {
+	var picked *ComponentPod
+	for i := range pods {
+		p := &pods[i]
+		if p.Pod.Status.Phase != corev1.PodRunning || p.Pod.DeletionTimestamp != nil {
+			continue
+		}
+		if len(cluster) != 0 && p.Cluster != cluster {
+			continue
+		}
+		if picked == nil || preferPod(&p.Pod, &picked.Pod) {
+			picked = p
+		}
+	}
+	if picked == nil {
+		if len(cluster) != 0 {
+			return nil, errors.Errorf("no running pod is found in cluster %s", cluster)
+		}
+		return nil, errors.New("no running pod is found")
+	}
+	return picked, nil
+}
+
+// preferPod checks whether the pod is preferred to the other one, a ready pod is preferred, then a newer one
+func preferPod(pod, other *corev1.Pod) bool {
+	if ready, otherReady := isPodReady(pod), isPodReady(other); ready != otherReady {
+		return ready
+	}
+	return other.CreationTimestamp.Before(&pod.CreationTimestamp)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// DefaultContainer returns the container of the pod to execute commands in when it's not specified, i.e., the one
+// annotated by kubectl.kubernetes.io/default-container, or the only container of the pod. It's empty if the pod has
+// multiple containers and none of them is the default one.
+func DefaultContainer(pod *corev1.Pod) string {
+	if name := pod.Annotations[defaultContainerAnnotation]; len(name) != 0 {
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return name
+			}
+		}
+	}
+	if len(pod.Spec.Containers) == 1 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+// helmWorkloadOf returns the HelmRelease of a Helm-based component and the name of the workload Helm creates for it
+// in the latest revision of the application, they're empty for the other components
+func helmWorkloadOf(ctx context.Context, c client.Reader, app *v1beta1.Application, component string) (string, string, error) {
+	if app.Status.LatestRevision == nil {
+		return "", "", nil
+	}
+	appRev := &v1beta1.ApplicationRevision{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Status.LatestRevision.Name}, appRev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", errors.Wrapf(err, "cannot get the revision %s of application %s", app.Status.LatestRevision.Name, app.Name)
+	}
+	if err := manifeststore.Load(ctx, c, appRev); err != nil {
+		return "", "", err
+	}
+	for _, raw := range appRev.Spec.Components {
+		comp := &v1alpha2.Component{}
+		if err := json.Unmarshal(raw.Raw.Raw, comp); err != nil {
+			return "", "", errors.Wrapf(err, "cannot decode the components in revision %s", appRev.Name)
+		}
+		if comp.Name != component || comp.Spec.Helm == nil {
+			continue
+		}
+		return assemble.HelmWorkloadName(comp)
+	}
+	return "", "", nil
+}
+
+// resourceScopesOf returns the namespace of the application followed by the other clusters and namespaces of the
+// resources tracked by the ResourceTracker of the application
+func resourceScopesOf(ctx context.Context, c client.Reader, app *v1beta1.Application) ([]resourceScope, error) {
+	scopes := []resourceScope{{namespace: app.Namespace}}
+	if app.Status.ResourceTracker == nil {
+		return scopes, nil
+	}
+	rt := &v1beta1.ResourceTracker{}
+	if err := c.Get(ctx, client.ObjectKey{Name: app.Status.ResourceTracker.Name}, rt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return scopes, nil
+		}
+		return nil, errors.Wrapf(err, "cannot get resource tracker %s", app.Status.ResourceTracker.Name)
+	}
+	if err := manifeststore.LoadResourceTracker(rt); err != nil {
+		return nil, err
+	}
+	seen := map[resourceScope]bool{scopes[0]: true}
+	var tracked []resourceScope
+	for _, ref := range rt.Status.TrackedResources {
+		scope := resourceScope{cluster: ref.Cluster, namespace: ref.Namespace}
+		// cluster-scoped resources have no pods
+		if len(scope.namespace) == 0 || seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		tracked = append(tracked, scope)
+	}
+	sort.Slice(tracked, func(i, j int) bool {
+		if tracked[i].cluster != tracked[j].cluster {
+			return tracked[i].cluster < tracked[j].cluster
+		}
+		return tracked[i].namespace < tracked[j].namespace
+	})
+	return append(scopes, tracked...), nil
+}
+
+// podResolver finds the pods belonging to a component in a namespace, owners caches whether the owners visited
+// belong to the component. helmRelease and helmWorkload are set for a Helm-based component, since the workload
+// created by Helm isn't labeled with the application and the component.
+type podResolver struct {
+	c            client.Reader
+	app          string
+	component    string
+	helmRelease  string
+	helmWorkload string
+	owners       map[types.UID]bool
+}
+
+func (r *podResolver) list(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := r.c.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrapf(err, "cannot list pods in namespace %s", namespace)
+	}
+	var pods []corev1.Pod
+	for i := range podList.Items {
+		belongs, err := r.belongs(ctx, &podList.Items[i], 0)
+		if err != nil {
+			return nil, err
+		}
+		if belongs {
+			pods = append(pods, podList.Items[i])
+		}
+	}
+	return pods, nil
+}
+
+// belongs checks whether the object or any of its owners are labeled with the application and the component, or
+// are the workload created by Helm for the component
+func (r *podResolver) belongs(ctx context.Context, obj metav1.Object, depth int) (bool, error) {
+	labels := obj.GetLabels()
+	if labels[oam.LabelAppName] == r.app && labels[oam.LabelAppComponent] == r.component {
+		return true, nil
+	}
+	if len(r.helmWorkload) != 0 && obj.GetName() == r.helmWorkload &&
+		obj.GetAnnotations()[helmReleaseNameAnnotation] == r.helmRelease {
+		return true, nil
+	}
+	if depth >= maxOwnerDepth {
+		return false, nil
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		belongs, cached := r.owners[ref.UID]
+		if !cached {
+			owner := &unstructured.Unstructured{}
+			owner.SetAPIVersion(ref.APIVersion)
+			owner.SetKind(ref.Kind)
+			err := r.c.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: ref.Name}, owner)
+			switch {
+			case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+				belongs = false
+			case err != nil:
+				return false, errors.Wrapf(err, "cannot get %s %s/%s", ref.Kind, obj.GetNamespace(), ref.Name)
+			default:
+				if belongs, err = r.belongs(ctx, owner, depth+1); err != nil {
+					return false, err
+				}
+			}
+			r.owners[ref.UID] = belongs
+		}
+		if belongs {
+			return true, nil
+		}
+	}
+	return false, nil
+}
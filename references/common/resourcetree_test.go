@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestListComponentPods(t *testing.T) {
+	ctx := context.Background()
+	appLabels := map[string]string{oam.LabelAppName: "app", oam.LabelAppComponent: "web"}
+	ownedBy := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid}}
+	}
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "deploy", Labels: appLabels}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d8f", UID: "rs",
+		OwnerReferences: ownedBy("Deployment", "web", "deploy")}}
+	pod := func(ns, name string, labels map[string]string, owners []metav1.OwnerReference) runtime.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: labels, OwnerReferences: owners}}
+	}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	app.Status.ResourceTracker = &runtimev1alpha1.TypedReference{Name: "default-app"}
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "default-app"}}
+	rt.Status.TrackedResources = []v1beta1.TypedReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web", Cluster: "east"},
+		{APIVersion: "v1", Kind: "Namespace", Name: "prod", Cluster: "east"},
+	}
+
+	hub := fake.NewFakeClientWithScheme(common.Scheme, app, rt, deploy, rs,
+		pod("default", "web-5d8f-x2b", nil, ownedBy("ReplicaSet", "web-5d8f", "rs")),
+		pod("default", "labeled", appLabels, nil),
+		pod("default", "orphan", nil, ownedBy("ReplicaSet", "deleted", "deleted")),
+		pod("default", "other", map[string]string{oam.LabelAppName: "app", oam.LabelAppComponent: "db"}, nil),
+	)
+	east := fake.NewFakeClientWithScheme(common.Scheme, pod("prod", "web-east", appLabels, nil), pod("default", "web-default", appLabels, nil))
+	clientOf := func(_ context.Context, cluster string) (client.Client, error) {
+		assert.Equal(t, "east", cluster)
+		return east, nil
+	}
+
+	pods, err := ListComponentPods(ctx, hub, clientOf, app, "web")
+	assert.NilError(t, err)
+	var got []string
+	for _, p := range pods {
+		got = append(got, p.Cluster+"/"+p.Pod.Namespace+"/"+p.Pod.Name)
+	}
+	sort.Strings(got)
+	assert.DeepEqual(t, []string{"/default/labeled", "/default/web-5d8f-x2b", "east/prod/web-east"}, got)
+}
+
+func TestListHelmComponentPods(t *testing.T) {
+	ctx := context.Background()
+	comp := &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: "podinfo"}}
+	comp.Spec.Helm = &commontypes.Helm{Release: runtime.RawExtension{
+		Raw: []byte(`{"metadata":{"name":"podinfo"},"spec":{"chart":{"spec":{"chart":"podinfo"}}}}`)}}
+	raw, err := json.Marshal(comp)
+	assert.NilError(t, err)
+	appRev := &v1beta1.ApplicationRevision{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-v1"}}
+	appRev.Spec.Components = []commontypes.RawComponent{{Raw: runtime.RawExtension{Raw: raw}}}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	app.Status.LatestRevision = &commontypes.Revision{Name: "app-v1", Revision: 1}
+
+	// the Deployment is created by Helm without the labels of the application
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "podinfo", UID: "deploy",
+		Annotations: map[string]string{"meta.helm.sh/release-name": "podinfo"}}}
+	other := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "podinfo-other", UID: "other",
+		Annotations: map[string]string{"meta.helm.sh/release-name": "podinfo"}}}
+	pod := func(name, owner string, uid types.UID) runtime.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name,
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: owner, UID: uid}}}}
+	}
+	c := fake.NewFakeClientWithScheme(common.Scheme, app, appRev, deploy, other,
+		pod("podinfo-x2b", "podinfo", "deploy"), pod("podinfo-other-k9p", "podinfo-other", "other"))
+
+	pods, err := ListComponentPods(ctx, c, nil, app, "podinfo")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(pods))
+	assert.Equal(t, "podinfo-x2b", pods[0].Pod.Name)
+}
+
+func TestSelectComponentPod(t *testing.T) {
+	now := time.Now()
+	pod := func(cluster, name string, phase corev1.PodPhase, ready bool, age time.Duration) ComponentPod {
+		p := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))}}
+		p.Status.Phase = phase
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+		return ComponentPod{Cluster: cluster, Pod: p}
+	}
+	pods := []ComponentPod{
+		pod("", "old-ready", corev1.PodRunning, true, time.Hour),
+		pod("", "new-ready", corev1.PodRunning, true, time.Minute),
+		pod("", "newest-unready", corev1.PodRunning, false, time.Second),
+		pod("", "pending", corev1.PodPending, false, 0),
+		pod("east", "east-unready", corev1.PodRunning, false, time.Hour),
+	}
+
+	picked, err := SelectComponentPod(pods, "")
+	assert.NilError(t, err)
+	assert.Equal(t, "new-ready", picked.Pod.Name)
+	picked, err = SelectComponentPod(pods, "east")
+	assert.NilError(t, err)
+	assert.Equal(t, "east-unready", picked.Pod.Name)
+	_, err = SelectComponentPod(pods, "west")
+	assert.Error(t, err, "no running pod is found in cluster west")
+	_, err = SelectComponentPod(pods[3:4], "")
+	assert.Error(t, err, "no running pod is found")
+}
+
+func TestDefaultContainer(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+	assert.Equal(t, "app", DefaultContainer(pod))
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "istio-proxy"})
+	assert.Equal(t, "", DefaultContainer(pod))
+	pod.Annotations = map[string]string{"kubectl.kubernetes.io/default-container": "app"}
+	assert.Equal(t, "app", DefaultContainer(pod))
+	pod.Annotations["kubectl.kubernetes.io/default-container"] = "missing"
+	assert.Equal(t, "", DefaultContainer(pod))
+}
@@ -0,0 +1,573 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/assemble"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/manifeststore"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// ResourceHealthStatus is the health status of a resource in the resource tree
+type ResourceHealthStatus string
+
+const (
+	// ResourceHealthy means the resource is ready
+	ResourceHealthy ResourceHealthStatus = "Healthy"
+	// ResourceProgressing means the resource is not ready yet, e.g., the replicas are rolling out
+	ResourceProgressing ResourceHealthStatus = "Progressing"
+	// ResourceUnhealthy means the resource fails, e.g., the containers of a pod are crashing
+	ResourceUnhealthy ResourceHealthStatus = "Unhealthy"
+	// ResourceMissing means the resource dispatched by the application is not found
+	ResourceMissing ResourceHealthStatus = "Missing"
+)
+
+// ResourceHealth is the health of a resource in the resource tree
+type ResourceHealth struct {
+	Status  ResourceHealthStatus `json:"status"`
+	Message string               `json:"message,omitempty"`
+}
+
+// ContainerInfo is the status and the resource usage of a container of a pod in the resource tree
+type ContainerInfo struct {
+	Name         string `json:"name"`
+	Image        string `json:"image,omitempty"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restartCount"`
+	// State is Running, Waiting or Terminated, followed by the reason if any, e.g., "Waiting: CrashLoopBackOff"
+	State string `json:"state,omitempty"`
+	// Usage is the CPU and memory usage reported by the metrics server, it's empty if the metrics are not queried
+	// or the metrics server is not installed
+	Usage corev1.ResourceList `json:"usage,omitempty"`
+}
+
+// ResourceNode is a resource in the resource tree of an application
+type ResourceNode struct {
+	// Cluster is the managed cluster the resource is in, empty for the control plane cluster
+	Cluster    string `json:"cluster,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Component is the component the resource is rendered for, and Trait is the type of the trait rendering it,
+	// they're only set for the resources dispatched by the application rather than their children
+	Component string         `json:"component,omitempty"`
+	Trait     string         `json:"trait,omitempty"`
+	Health    ResourceHealth `json:"health"`
+	// CreatedAt is the creation time of the resource, it's unset if the resource is missing
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+	// Containers are the containers of a pod
+	Containers []ContainerInfo `json:"containers,omitempty"`
+	// Children are the resources owned by the resource, e.g., the ReplicaSets of a Deployment
+	Children []*ResourceNode `json:"children,omitempty"`
+}
+
+// ResourceTreeOptions are the options to build the resource tree of an application
+type ResourceTreeOptions struct {
+	// Metrics queries the CPU and memory usage of the containers from the metrics server
+	Metrics bool
+}
+
+// builtinChildKinds are the kinds of the children of the built-in workloads discovered by the owner references
+var builtinChildKinds = map[schema.GroupKind][]commontypes.ChildResourceKind{
+	{Group: "apps", Kind: "Deployment"}:  {{APIVersion: "apps/v1", Kind: "ReplicaSet"}},
+	{Group: "apps", Kind: "ReplicaSet"}:  {{APIVersion: "v1", Kind: "Pod"}},
+	{Group: "apps", Kind: "StatefulSet"}: {{APIVersion: "v1", Kind: "Pod"}},
+	{Group: "apps", Kind: "DaemonSet"}:   {{APIVersion: "v1", Kind: "Pod"}},
+	{Group: "batch", Kind: "Job"}:        {{APIVersion: "v1", Kind: "Pod"}},
+	{Group: "batch", Kind: "CronJob"}:    {{APIVersion: "batch/v1", Kind: "Job"}},
+}
+
+var podMetricsGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+
+// BuildResourceTree returns the tree of the resources of an application. The roots are the workloads and traits
+// rendered in the latest revision of the application in the order of the components, followed by the resources
+// dispatched to the managed clusters tracked in the ResourceTracker. The children are discovered by the owner
+// references, from the built-in kinds like Deployment -> ReplicaSet -> Pod, and the child resource kinds of the
+// component definitions.
+func BuildResourceTree(ctx context.Context, c client.Client, clientOf ClusterClientFn, app *v1beta1.Application, opts ResourceTreeOptions) ([]*ResourceNode, error) {
+	roots, appRev, err := dispatchedResources(ctx, c, app)
+	if err != nil {
+		return nil, err
+	}
+	builders := map[string]*treeBuilder{}
+	var tree []*ResourceNode
+	for _, root := range roots {
+		b, ok := builders[root.cluster]
+		if !ok {
+			cc := c
+			if len(root.cluster) != 0 {
+				if cc, err = clientOf(ctx, root.cluster); err != nil {
+					return nil, errors.WithMessagef(err, "cannot get the client of cluster %s", root.cluster)
+				}
+			}
+			b = &treeBuilder{c: cc, cluster: root.cluster, app: app, appRev: appRev, opts: opts,
+				lists: map[string][]unstructured.Unstructured{}}
+			builders[root.cluster] = b
+		}
+		node, err := b.root(ctx, root.obj)
+		if err != nil {
+			if len(root.cluster) != 0 {
+				return nil, errors.WithMessagef(err, "cannot get the resources in cluster %s", root.cluster)
+			}
+			return nil, err
+		}
+		tree = append(tree, node)
+	}
+	return tree, nil
+}
+
+// dispatchedResource is a resource dispatched by the application, obj only has the identity of the resource
+// and its labels
+type dispatchedResource struct {
+	cluster string
+	obj     *unstructured.Unstructured
+}
+
+// dispatchedResources returns the resources rendered in the latest revision of the application and the ones
+// dispatched to the managed clusters, with the latest revision if any
+func dispatchedResources(ctx context.Context, c client.Reader, app *v1beta1.Application) ([]dispatchedResource, *v1beta1.ApplicationRevision, error) {
+	var roots []dispatchedResource
+	var appRev *v1beta1.ApplicationRevision
+	if app.Status.LatestRevision != nil {
+		appRev = &v1beta1.ApplicationRevision{}
+		key := client.ObjectKey{Namespace: app.Namespace, Name: app.Status.LatestRevision.Name}
+		if err := c.Get(ctx, key, appRev); err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot get the revision %s of application %s", key.Name, app.Name)
+		}
+		if err := manifeststore.Load(ctx, c, appRev); err != nil {
+			return nil, nil, err
+		}
+		workloads, traits, _, err := assemble.NewAppManifests(appRev).GroupAssembledManifests()
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "cannot assemble the resources of application %s", app.Name)
+		}
+		for _, comp := range appRev.Spec.Application.Spec.Components {
+			staged := traits[comp.Name]
+			var objs []*unstructured.Unstructured
+			objs = append(objs, staged[commontypes.PreDispatchStage]...)
+			objs = append(objs, staged[commontypes.PreWorkloadStage]...)
+			if wl, ok := workloads[comp.Name]; ok {
+				objs = append(objs, wl)
+			}
+			objs = append(objs, staged[commontypes.PostWorkloadStage]...)
+			for _, obj := range objs {
+				roots = append(roots, dispatchedResource{obj: obj})
+			}
+		}
+	}
+	if app.Status.ResourceTracker == nil {
+		return roots, appRev, nil
+	}
+	rt := &v1beta1.ResourceTracker{}
+	if err := c.Get(ctx, client.ObjectKey{Name: app.Status.ResourceTracker.Name}, rt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return roots, appRev, nil
+		}
+		return nil, nil, errors.Wrapf(err, "cannot get resource tracker %s", app.Status.ResourceTracker.Name)
+	}
+	if err := manifeststore.LoadResourceTracker(rt); err != nil {
+		return nil, nil, err
+	}
+	var remote []dispatchedResource
+	for _, ref := range rt.Status.TrackedResources {
+		if len(ref.Cluster) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(ref.APIVersion)
+		obj.SetKind(ref.Kind)
+		obj.SetNamespace(ref.Namespace)
+		obj.SetName(ref.Name)
+		remote = append(remote, dispatchedResource{cluster: ref.Cluster, obj: obj})
+	}
+	sort.SliceStable(remote, func(i, j int) bool { return remote[i].cluster < remote[j].cluster })
+	return append(roots, remote...), appRev, nil
+}
+
+// treeBuilder builds the resource tree in a cluster, lists caches the resources listed by namespace, kind and
+// selector to find the children
+type treeBuilder struct {
+	c       client.Reader
+	cluster string
+	app     *v1beta1.Application
+	appRev  *v1beta1.ApplicationRevision
+	opts    ResourceTreeOptions
+	lists   map[string][]unstructured.Unstructured
+	// noMetrics is set once the metrics server is found not installed
+	noMetrics bool
+}
+
+// root builds the node of a resource dispatched by the application with its children
+func (b *treeBuilder) root(ctx context.Context, obj *unstructured.Unstructured) (*ResourceNode, error) {
+	node := &ResourceNode{
+		Cluster:    b.cluster,
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+	lbls := obj.GetLabels()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := b.c.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}, live)
+	switch {
+	case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+		node.Component, node.Trait = lbls[oam.LabelAppComponent], traitType(lbls)
+		node.Health = ResourceHealth{Status: ResourceMissing, Message: fmt.Sprintf("%s %s is not found", obj.GetKind(), obj.GetName())}
+		return node, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "cannot get %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	// the resources in the managed clusters are only identified by the ResourceTracker
+	if len(b.cluster) != 0 {
+		lbls = live.GetLabels()
+	}
+	node.Component, node.Trait = lbls[oam.LabelAppComponent], traitType(lbls)
+	node.Health = b.dispatchedHealth(live, node.Component, node.Trait, lbls[oam.TraitTypeLabel])
+
+	kinds := builtinChildKinds[live.GroupVersionKind().GroupKind()]
+	if b.appRev != nil && len(node.Trait) == 0 {
+		if compDef, ok := b.appRev.Spec.ComponentDefinitions[lbls[oam.WorkloadTypeLabel]]; ok {
+			kinds = append(kinds, compDef.Spec.ChildResourceKinds...)
+		}
+	}
+	if err := b.fill(ctx, node, live, kinds, 0); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// dispatchedHealth returns the health of a workload or a trait checked by the application controller with the
+// health policy of its definition, or the built-in health of the resource if it's not checked
+func (b *treeBuilder) dispatchedHealth(live *unstructured.Unstructured, component, trait, traitLabel string) ResourceHealth {
+	if len(b.cluster) != 0 || traitLabel == definition.AuxiliaryWorkload {
+		return resourceHealth(live)
+	}
+	for _, svc := range b.app.Status.Services {
+		if svc.Name != component {
+			continue
+		}
+		if len(trait) == 0 {
+			return checkedHealth(svc.Healthy, svc.Message)
+		}
+		for _, t := range svc.Traits {
+			if t.Type == trait {
+				return checkedHealth(t.Healthy, t.Message)
+			}
+		}
+	}
+	return resourceHealth(live)
+}
+
+func checkedHealth(healthy bool, message string) ResourceHealth {
+	if healthy {
+		return ResourceHealth{Status: ResourceHealthy, Message: message}
+	}
+	return ResourceHealth{Status: ResourceUnhealthy, Message: message}
+}
+
+// fill sets the creation time and the containers of the node from the live resource, and adds its children
+func (b *treeBuilder) fill(ctx context.Context, node *ResourceNode, live *unstructured.Unstructured, kinds []commontypes.ChildResourceKind, depth int) error {
+	created := live.GetCreationTimestamp()
+	node.CreatedAt = &created
+	if node.APIVersion == "v1" && node.Kind == "Pod" {
+		containers, err := b.containers(ctx, live)
+		if err != nil {
+			return err
+		}
+		node.Containers = containers
+	}
+	if depth >= maxOwnerDepth {
+		return nil
+	}
+	for _, kind := range kinds {
+		items, err := b.list(ctx, live.GetNamespace(), kind)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			child := &items[i]
+			if !ownedBy(child, live) {
+				continue
+			}
+			childNode := &ResourceNode{
+				Cluster:    b.cluster,
+				APIVersion: child.GetAPIVersion(),
+				Kind:       child.GetKind(),
+				Namespace:  child.GetNamespace(),
+				Name:       child.GetName(),
+				Health:     resourceHealth(child),
+			}
+			if err := b.fill(ctx, childNode, child, builtinChildKinds[child.GroupVersionKind().GroupKind()], depth+1); err != nil {
+				return err
+			}
+			node.Children = append(node.Children, childNode)
+		}
+	}
+	return nil
+}
+
+// list lists the resources of the kind in the namespace selected by its selector, the result is cached
+func (b *treeBuilder) list(ctx context.Context, namespace string, kind commontypes.ChildResourceKind) ([]unstructured.Unstructured, error) {
+	selector := labels.SelectorFromSet(kind.Selector).String()
+	key := fmt.Sprintf("%s/%s/%s/%s", namespace, kind.APIVersion, kind.Kind, selector)
+	if items, ok := b.lists[key]; ok {
+		return items, nil
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(kind.APIVersion)
+	list.SetKind(kind.Kind + "List")
+	err := b.c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels(kind.Selector))
+	if err != nil && !meta.IsNoMatchError(err) {
+		return nil, errors.Wrapf(err, "cannot list %s in namespace %s", kind.Kind, namespace)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+	b.lists[key] = list.Items
+	return list.Items, nil
+}
+
+func ownedBy(obj, owner *unstructured.Unstructured) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// containers returns the containers of the pod with their resource usage if the metrics are queried
+func (b *treeBuilder) containers(ctx context.Context, live *unstructured.Unstructured) ([]ContainerInfo, error) {
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, pod); err != nil {
+		return nil, errors.Wrapf(err, "cannot convert pod %s", live.GetName())
+	}
+	statuses := map[string]corev1.ContainerStatus{}
+	for _, s := range pod.Status.ContainerStatuses {
+		statuses[s.Name] = s
+	}
+	var containers []ContainerInfo
+	for _, c := range pod.Spec.Containers {
+		info := ContainerInfo{Name: c.Name, Image: c.Image}
+		if s, ok := statuses[c.Name]; ok {
+			info.Ready, info.RestartCount, info.State = s.Ready, s.RestartCount, containerState(s.State)
+		}
+		containers = append(containers, info)
+	}
+	if !b.opts.Metrics || b.noMetrics {
+		return containers, nil
+	}
+	usage, err := b.podUsage(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	for i := range containers {
+		containers[i].Usage = usage[containers[i].Name]
+	}
+	return containers, nil
+}
+
+// podUsage gets the resource usage of the containers of the pod from the metrics server
+func (b *treeBuilder) podUsage(ctx context.Context, pod *corev1.Pod) (map[string]corev1.ResourceList, error) {
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(podMetricsGVK)
+	err := b.c.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, metrics)
+	switch {
+	case meta.IsNoMatchError(err):
+		b.noMetrics = true
+		return nil, nil
+	case apierrors.IsNotFound(err):
+		// the pod is not scraped yet
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "cannot get the metrics of pod %s/%s", pod.Namespace, pod.Name)
+	}
+	containers, _, _ := unstructured.NestedSlice(metrics.Object, "containers")
+	usage := map[string]corev1.ResourceList{}
+	for _, c := range containers {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(m, "name")
+		values, _, _ := unstructured.NestedStringMap(m, "usage")
+		list := corev1.ResourceList{}
+		for k, v := range values {
+			if q, err := resource.ParseQuantity(v); err == nil {
+				list[corev1.ResourceName(k)] = q
+			}
+		}
+		usage[name] = list
+	}
+	return usage, nil
+}
+
+func containerState(s corev1.ContainerState) string {
+	switch {
+	case s.Running != nil:
+		return "Running"
+	case s.Waiting != nil:
+		return withReason("Waiting", s.Waiting.Reason)
+	case s.Terminated != nil:
+		return withReason("Terminated", s.Terminated.Reason)
+	}
+	return ""
+}
+
+func withReason(state, reason string) string {
+	if len(reason) == 0 {
+		return state
+	}
+	return state + ": " + reason
+}
+
+// traitType returns the type of the trait rendering the resource, it's empty for the workloads and the auxiliary
+// workloads of the components
+func traitType(lbls map[string]string) string {
+	if t := lbls[oam.TraitTypeLabel]; t != definition.AuxiliaryWorkload {
+		return t
+	}
+	return ""
+}
+
+// resourceHealth returns the health of the built-in kinds by their status, the other resources are healthy unless
+// their Ready condition is false
+func resourceHealth(u *unstructured.Unstructured) ResourceHealth {
+	healthy := ResourceHealth{Status: ResourceHealthy}
+	replicas := func(ready, desired int32) ResourceHealth {
+		msg := fmt.Sprintf("Ready: %d/%d", ready, desired)
+		if ready < desired {
+			return ResourceHealth{Status: ResourceProgressing, Message: msg}
+		}
+		return ResourceHealth{Status: ResourceHealthy, Message: msg}
+	}
+	switch u.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		d := &appsv1.Deployment{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, d) != nil {
+			return healthy
+		}
+		for _, cond := range d.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse {
+				return ResourceHealth{Status: ResourceUnhealthy, Message: cond.Message}
+			}
+		}
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		return replicas(d.Status.ReadyReplicas, desired)
+	case schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}:
+		rs := &appsv1.ReplicaSet{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, rs) != nil {
+			return healthy
+		}
+		return replicas(rs.Status.ReadyReplicas, rs.Status.Replicas)
+	case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		sts := &appsv1.StatefulSet{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, sts) != nil {
+			return healthy
+		}
+		desired := int32(1)
+		if sts.Spec.Replicas != nil {
+			desired = *sts.Spec.Replicas
+		}
+		return replicas(sts.Status.ReadyReplicas, desired)
+	case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+		ds := &appsv1.DaemonSet{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, ds) != nil {
+			return healthy
+		}
+		return replicas(ds.Status.NumberReady, ds.Status.DesiredNumberScheduled)
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		job := &batchv1.Job{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, job) != nil {
+			return healthy
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				return ResourceHealth{Status: ResourceUnhealthy, Message: cond.Message}
+			}
+			if cond.Type == batchv1.JobComplete && cond.Status == corev1.ConditionTrue {
+				return ResourceHealth{Status: ResourceHealthy, Message: "Completed"}
+			}
+		}
+		return ResourceHealth{Status: ResourceProgressing, Message: fmt.Sprintf("Active: %d", job.Status.Active)}
+	case schema.GroupKind{Kind: "Pod"}:
+		pod := &corev1.Pod{}
+		if runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, pod) != nil {
+			return healthy
+		}
+		return podHealth(pod)
+	}
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != string(runtimev1alpha1.TypeReady) {
+			continue
+		}
+		if cond["status"] == string(corev1.ConditionFalse) {
+			msg, _ := cond["message"].(string)
+			return ResourceHealth{Status: ResourceUnhealthy, Message: msg}
+		}
+	}
+	return healthy
+}
+
+func podHealth(pod *corev1.Pod) ResourceHealth {
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Waiting != nil && (s.State.Waiting.Reason == "CrashLoopBackOff" ||
+			s.State.Waiting.Reason == "ImagePullBackOff" || s.State.Waiting.Reason == "ErrImagePull") {
+			return ResourceHealth{Status: ResourceUnhealthy, Message: s.Name + ": " + s.State.Waiting.Reason}
+		}
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return ResourceHealth{Status: ResourceHealthy, Message: "Completed"}
+	case corev1.PodFailed:
+		return ResourceHealth{Status: ResourceUnhealthy, Message: pod.Status.Message}
+	case corev1.PodRunning:
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return ResourceHealth{Status: ResourceHealthy, Message: string(pod.Status.Phase)}
+			}
+		}
+	}
+	return ResourceHealth{Status: ResourceProgressing, Message: string(pod.Status.Phase)}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"gotest.tools/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestBuildResourceTree(t *testing.T) {
+	ctx := context.Background()
+	ownedBy := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid}}
+	}
+	app := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	app.Status.ResourceTracker = &runtimev1alpha1.TypedReference{Name: "default-app"}
+	rt := &v1beta1.ResourceTracker{ObjectMeta: metav1.ObjectMeta{Name: "default-app"}}
+	rt.Status.TrackedResources = []v1beta1.TypedReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web", Cluster: "east"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "prod", Name: "web", Cluster: "east"},
+	}
+	hub := fake.NewFakeClientWithScheme(common.Scheme, app, rt)
+
+	east := fake.NewFakeClientWithScheme(common.Scheme,
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web", UID: "deploy",
+				Labels: map[string]string{oam.LabelAppName: "app", oam.LabelAppComponent: "web"}},
+			Spec:   appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(2)},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-5d8f", UID: "rs", OwnerReferences: ownedBy("Deployment", "web", "deploy")},
+			Status:     appsv1.ReplicaSetStatus{Replicas: 2, ReadyReplicas: 1},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-5d8f-a", OwnerReferences: ownedBy("ReplicaSet", "web-5d8f", "rs")},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "nginx"}}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-5d8f-b", OwnerReferences: ownedBy("ReplicaSet", "web-5d8f", "rs")},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "nginx"}}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "main", RestartCount: 3,
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}},
+			},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "other"}},
+	)
+	clientOf := func(_ context.Context, cluster string) (client.Client, error) {
+		assert.Equal(t, "east", cluster)
+		return east, nil
+	}
+
+	tree, err := BuildResourceTree(ctx, hub, clientOf, app, ResourceTreeOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(tree), 2)
+
+	deploy := tree[0]
+	assert.Equal(t, deploy.Cluster, "east")
+	assert.Equal(t, deploy.Component, "web")
+	assert.DeepEqual(t, deploy.Health, ResourceHealth{Status: ResourceProgressing, Message: "Ready: 1/2"})
+	assert.Equal(t, len(deploy.Children), 1)
+	rs := deploy.Children[0]
+	assert.Equal(t, rs.Name, "web-5d8f")
+	assert.Equal(t, len(rs.Children), 2)
+	assert.DeepEqual(t, rs.Children[0].Health, ResourceHealth{Status: ResourceHealthy, Message: "Running"})
+	assert.DeepEqual(t, rs.Children[0].Containers, []ContainerInfo{{Name: "main", Image: "nginx", Ready: true, State: "Running"}})
+	assert.DeepEqual(t, rs.Children[1].Health, ResourceHealth{Status: ResourceUnhealthy, Message: "main: CrashLoopBackOff"})
+	assert.DeepEqual(t, rs.Children[1].Containers, []ContainerInfo{{Name: "main", Image: "nginx", RestartCount: 3, State: "Waiting: CrashLoopBackOff"}})
+
+	assert.Equal(t, tree[1].Kind, "Service")
+	assert.Equal(t, tree[1].Health.Status, ResourceMissing)
+}
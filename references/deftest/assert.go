@@ -0,0 +1,82 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
+)
+
+// AssertGolden compares the YAML of the manifests with the golden file, the failure is the line diff from the
+// golden file to the rendered manifests, it's empty if they're the same
+func AssertGolden(objs []*unstructured.Unstructured, path string) (string, error) {
+	expected, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read golden file %s", path)
+	}
+	actual, err := dryrun.ManifestsToYAML(objs)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	buff.WriteString("the rendered manifests differ from golden file " + path + " (-golden +rendered):\n")
+	if !dryrun.PrintTextDiff(string(expected), string(actual), diffContext, &buff) {
+		return "", nil
+	}
+	return buff.String(), nil
+}
+
+// AssertCUE checks whether the manifests satisfy the CUE constraints, the manifests are the values of the list
+// `manifests` in the rendered order, and `resources: <kind>: <name>` as well. The constraints fail if they conflict
+// with the manifests, or any field constrained doesn't exist in the manifests.
+func AssertCUE(objs []*unstructured.Unstructured, assertion string) error {
+	manifests := make([]interface{}, 0, len(objs))
+	resources := map[string]map[string]interface{}{}
+	for _, obj := range objs {
+		manifests = append(manifests, obj.Object)
+		if resources[obj.GetKind()] == nil {
+			resources[obj.GetKind()] = map[string]interface{}{}
+		}
+		resources[obj.GetKind()][obj.GetName()] = obj.Object
+	}
+	rendered, err := json.Marshal(map[string]interface{}{"manifests": manifests, "resources": resources})
+	if err != nil {
+		return errors.Wrap(err, "cannot encode the rendered manifests")
+	}
+	// the assertion and the manifests must be compiled in the same instance to be compared
+	var r cue.Runtime
+	inst, err := r.Compile("assert", "rendered: "+string(rendered)+"\nassert: {\n"+assertion+"\n}\n")
+	if err != nil {
+		return errors.Wrap(err, "invalid assertion")
+	}
+	want, got := inst.Lookup("assert"), inst.Lookup("rendered")
+	if err := want.Unify(got).Validate(); err != nil {
+		return errors.Errorf("the rendered manifests conflict with the assertion: %v", err)
+	}
+	if err := want.Subsume(got, cue.Final()); err != nil {
+		return errors.Errorf("the rendered manifests don't satisfy the assertion, some fields asserted may be missing: %v", err)
+	}
+	return nil
+}
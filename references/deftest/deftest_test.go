@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deftest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func newRunner(t *testing.T) *Runner {
+	pd, err := definition.NewPackageDiscoverFromSnapshot(nil)
+	assert.NoError(t, err)
+	return &Runner{Client: fake.NewFakeClientWithScheme(common.Scheme), PackageDiscover: pd}
+}
+
+func TestRunSuite(t *testing.T) {
+	s, err := LoadSuite("testdata/worker_test.yaml")
+	assert.NoError(t, err)
+	results, err := newRunner(t).RunSuite(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results))
+	for _, r := range results {
+		assert.True(t, r.Passed(), "case %s: %s", r.Case, r.Failure)
+	}
+
+	s.Cases[0].Assert = `resources: Deployment: "test-worker": spec: replicas: 2`
+	s.Cases[1].ExpectError = "no such error"
+	results, err = newRunner(t).RunSuite(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Contains(t, results[0].Failure, "conflict")
+	assert.Contains(t, results[1].Failure, "no such error")
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deftest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defs, err := filepath.Abs("testdata/worker.yaml")
+	assert.NoError(t, err)
+	s := &Suite{
+		Path:        filepath.Join(dir, "worker_test.yaml"),
+		Definitions: []string{defs},
+		Cases: []Case{{
+			Name: "golden",
+			Component: v1beta1.ApplicationComponent{
+				Type:       "test-worker",
+				Properties: runtime.RawExtension{Raw: []byte(`{"image":"nginx"}`)},
+			},
+			Golden: "golden/worker.yaml",
+		}},
+	}
+
+	r := newRunner(t)
+	r.UpdateGolden = true
+	results, err := r.RunSuite(context.Background(), s)
+	assert.NoError(t, err)
+	assert.True(t, results[0].Passed(), results[0].Failure)
+	golden := filepath.Join(dir, "golden", "worker.yaml")
+	b, err := ioutil.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "image: nginx")
+
+	r.UpdateGolden = false
+	results, err = r.RunSuite(context.Background(), s)
+	assert.NoError(t, err)
+	assert.True(t, results[0].Passed(), results[0].Failure)
+
+	s.Cases[0].Component.Properties = runtime.RawExtension{Raw: []byte(`{"image":"busybox"}`)}
+	results, err = r.RunSuite(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Contains(t, results[0].Failure, "- ")
+	assert.Contains(t, results[0].Failure, "+ ")
+	assert.Contains(t, results[0].Failure, "busybox")
+}
+
+func TestAssertCUE(t *testing.T) {
+	deploy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec":       map[string]interface{}{"replicas": int64(2)},
+	}}
+	objs := []*unstructured.Unstructured{deploy}
+	assert.NoError(t, AssertCUE(objs, `resources: Deployment: web: spec: replicas: >=2`))
+	assert.NoError(t, AssertCUE(objs, `manifests: [{kind: "Deployment"}]`))
+	assert.Error(t, AssertCUE(objs, `resources: Deployment: web: spec: replicas: 3`))
+	assert.Error(t, AssertCUE(objs, `resources: Deployment: web: spec: paused: true`))
+	assert.Error(t, AssertCUE(objs, `resources: Deployment: web: {`))
+}
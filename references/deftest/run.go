@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deftest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/pkg/appfile/kube"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/references/appfile/dryrun"
+)
+
+// DefaultNamespace is the namespace the components of the test cases are rendered in
+const DefaultNamespace = "default"
+
+// diffContext is the number of the unchanged lines printed around the changed ones in the diffs of golden files
+const diffContext = 3
+
+// Runner runs the test suites. The definitions of a suite take precedence over the ones got by the client, the
+// client can be a fake one to render the definitions offline, then the component definitions must specify their
+// workloads and the templates can only import the packages served by the PackageDiscover.
+type Runner struct {
+	Client          client.Client
+	DiscoveryMapper discoverymapper.DiscoveryMapper
+	PackageDiscover *definition.PackageDiscover
+	// UpdateGolden writes the rendered manifests into the golden files instead of comparing them
+	UpdateGolden bool
+}
+
+// Result is the result of a test case, Failure explains why it fails and is empty if it passes
+type Result struct {
+	Suite    string        `json:"suite"`
+	Case     string        `json:"case"`
+	Failure  string        `json:"failure,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Passed checks whether the test case passes
+func (r Result) Passed() bool {
+	return len(r.Failure) == 0
+}
+
+// RunSuite runs all the cases of the suite in order, it fails only if the definitions of the suite can't be loaded
+func (r *Runner) RunSuite(ctx context.Context, s *Suite) ([]Result, error) {
+	var defs []oam.Object
+	for _, path := range s.Definitions {
+		objs, err := LoadDefinitions(s.resolve(path))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "test suite %s", s.Path)
+		}
+		defs = append(defs, objs...)
+	}
+	opt := dryrun.NewDryRunOption(r.Client, r.DiscoveryMapper, r.PackageDiscover, defs)
+	results := make([]Result, 0, len(s.Cases))
+	for _, c := range s.Cases {
+		start := time.Now()
+		failure := r.runCase(ctx, opt, s, c)
+		results = append(results, Result{Suite: s.Path, Case: c.Name, Failure: failure, Duration: time.Since(start)})
+	}
+	return results, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, opt *dryrun.Option, s *Suite, c Case) string {
+	objs, err := Render(ctx, opt, c.Component)
+	if len(c.ExpectError) != 0 {
+		switch {
+		case err == nil:
+			return fmt.Sprintf("expect error containing %q, but the component is rendered", c.ExpectError)
+		case !strings.Contains(err.Error(), c.ExpectError):
+			return fmt.Sprintf("expect error containing %q, got: %v", c.ExpectError, err)
+		}
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("cannot render the component: %v", err)
+	}
+	var failures []string
+	if len(c.Golden) != 0 {
+		failure, err := r.assertGolden(objs, s.resolve(c.Golden))
+		if err != nil {
+			return err.Error()
+		}
+		if len(failure) != 0 {
+			failures = append(failures, failure)
+		}
+	}
+	if len(c.Assert) != 0 {
+		if err := AssertCUE(objs, c.Assert); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	return strings.Join(failures, "\n")
+}
+
+func (r *Runner) assertGolden(objs []*unstructured.Unstructured, path string) (string, error) {
+	if !r.UpdateGolden {
+		return AssertGolden(objs, path)
+	}
+	actual, err := dryrun.ManifestsToYAML(objs)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return "", errors.Wrapf(err, "cannot create the directory of golden file %s", path)
+	}
+	return "", errors.Wrapf(ioutil.WriteFile(path, actual, 0600), "cannot write golden file %s", path)
+}
+
+// Render renders the component with its traits into the final K8s resources the way `vela dry-run` does, the
+// HelmRelease and HelmRepository of a Helm-based component are appended after the other resources. The component
+// belongs to an application with the same name in DefaultNamespace.
+func Render(ctx context.Context, opt *dryrun.Option, comp v1beta1.ApplicationComponent) ([]*unstructured.Unstructured, error) {
+	if len(comp.Name) == 0 {
+		comp.Name = comp.Type
+	}
+	app := &v1beta1.Application{}
+	app.SetGroupVersionKind(v1beta1.ApplicationKindVersionKind)
+	app.SetName(comp.Name)
+	app.SetNamespace(DefaultNamespace)
+	app.Spec.Components = []v1beta1.ApplicationComponent{comp}
+	ac, comps, err := opt.ExecuteDryRun(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := dryrun.AssembleManifests(app, ac, comps)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comps {
+		if c.Spec.Helm == nil {
+			continue
+		}
+		for _, raw := range []*runtime.RawExtension{&c.Spec.Helm.Release, &c.Spec.Helm.Repository} {
+			obj, err := oamutil.RawExtension2Unstructured(raw)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot decode the Helm resources of the component")
+			}
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// LoadDefinitions loads the definitions from a YAML file with one or more documents, or from the YAML files in a
+// directory
+func LoadDefinitions(path string) ([]oam.Object, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		fis, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, f := range fis {
+			if ext := filepath.Ext(f.Name()); !f.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, f.Name()))
+			}
+		}
+	}
+	var defs []oam.Object
+	for _, file := range files {
+		b, err := ioutil.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return nil, err
+		}
+		objs, err := kube.DecodeManifest(b)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot load definitions from %s", file)
+		}
+		for _, obj := range objs {
+			defs = append(defs, obj)
+		}
+	}
+	return defs, nil
+}
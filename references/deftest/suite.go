@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deftest runs the table-driven tests of definitions. A test suite renders the definitions under test with
+// the property fixtures of its cases offline, and asserts on the rendered manifests by YAML golden files or CUE
+// constraints.
+package deftest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+)
+
+// SuiteFileSuffixes are the suffixes of the files recognized as test suites when a directory is searched
+var SuiteFileSuffixes = []string{"_test.yaml", "_test.yml"}
+
+// Suite is a file of the test cases of definitions, e.g.,
+//
+//	definitions:
+//	- ../webservice.yaml
+//	cases:
+//	- name: default port
+//	  component:
+//	    type: webservice
+//	    properties:
+//	      image: nginx
+//	  golden: testdata/webservice-default.yaml
+//	  assert: |
+//	    resources: Deployment: webservice: spec: template: spec: containers: [{image: "nginx"}]
+type Suite struct {
+	// Path is the file the suite is loaded from, the paths in the suite are relative to its directory
+	Path string `json:"-"`
+	// Definitions are the files or directories of the definitions under test, they're used instead of the ones in
+	// the cluster with the same names
+	Definitions []string `json:"definitions,omitempty"`
+	Cases       []Case   `json:"cases"`
+}
+
+// Case is a test case rendering a component with its traits
+type Case struct {
+	Name string `json:"name"`
+	// Component is the component rendered, its name defaults to its type
+	Component v1beta1.ApplicationComponent `json:"component"`
+	// Golden is the YAML file of all the rendered manifests expected
+	Golden string `json:"golden,omitempty"`
+	// Assert is the CUE constraints the rendered manifests must satisfy, the manifests are the values of the list
+	// `manifests` in the rendered order, and `resources: <kind>: <name>` as well. Any field constrained must exist.
+	Assert string `json:"assert,omitempty"`
+	// ExpectError is a substring of the error expected when rendering, the golden file and the assertions are
+	// ignored if it's set
+	ExpectError string `json:"expectError,omitempty"`
+}
+
+// LoadSuite loads a test suite from the file
+func LoadSuite(path string) (*Suite, error) {
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read test suite %s", path)
+	}
+	s := &Suite{}
+	if err := yaml.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrapf(err, "invalid test suite %s", path)
+	}
+	s.Path = path
+	for i, c := range s.Cases {
+		if len(c.Name) == 0 {
+			return nil, errors.Errorf("case %d of test suite %s has no name", i, path)
+		}
+		if len(c.Component.Type) == 0 {
+			return nil, errors.Errorf("case %s of test suite %s has no component type", c.Name, path)
+		}
+		if len(c.Golden) == 0 && len(c.Assert) == 0 && len(c.ExpectError) == 0 {
+			return nil, errors.Errorf("case %s of test suite %s asserts nothing", c.Name, path)
+		}
+	}
+	return s, nil
+}
+
+// FindSuites returns the test suite files under the path, the path itself if it's a file
+func FindSuites(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		for _, suffix := range SuiteFileSuffixes {
+			if strings.HasSuffix(info.Name(), suffix) {
+				files = append(files, p)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// resolve returns the path relative to the directory of the suite
+func (s *Suite) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(s.Path), path)
+}
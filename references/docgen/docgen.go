@@ -0,0 +1,281 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package docgen generates the reference docs of the definitions installed in the cluster from the OpenAPI v3 JSON
+// schemas of their parameters, i.e., the ones in the capability ConfigMaps, and the annotations of the definitions.
+package docgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	commontypes "github.com/oam-dev/kubevela/apis/core.oam.dev/common"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+// Parameter is a parameter of a definition, the nested ones are flattened
+type Parameter struct {
+	// Name is the path of the parameter, the fields of objects are joined by dots and the items of arrays are
+	// marked by [], e.g., ports[].port
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Default     string   `json:"default,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Doc is the reference doc of a definition
+type Doc struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Parameters are empty if the schema of the definition is not generated yet
+	Parameters []Parameter `json:"parameters"`
+	// Example is the example of using the definition in YAML, given by the types.AnnExample annotation or generated
+	// from the required parameters
+	Example string `json:"example"`
+}
+
+// Generate generates the reference docs of the component and trait definitions in the namespace, or all namespaces
+// if it's empty. The titles and descriptions of the parameters are localized in the locale if it's not empty.
+func Generate(ctx context.Context, c client.Reader, namespace, locale string) ([]*Doc, error) {
+	schemas, err := common.ListDefinitionSchemas(ctx, c, namespace, locale)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*Doc, 0, len(schemas))
+	for _, s := range schemas {
+		doc, err := NewDoc(s)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// Find returns the doc of the definition with the name, the type is either component or trait and the component
+// takes precedence if it's empty
+func Find(docs []*Doc, name, defType string) *Doc {
+	var found *Doc
+	for _, d := range docs {
+		if d.Name != name || (len(defType) != 0 && d.Type != defType) {
+			continue
+		}
+		if found == nil || d.Type == typeName(commontypes.ComponentType) {
+			found = d
+		}
+	}
+	return found
+}
+
+func typeName(t commontypes.DefinitionType) string {
+	return strings.ToLower(string(t))
+}
+
+// NewDoc builds the reference doc of a definition from its schema
+func NewDoc(s common.DefinitionSchema) (*Doc, error) {
+	doc := &Doc{
+		Name:        s.Name,
+		Namespace:   s.Namespace,
+		Type:        typeName(s.Type),
+		Description: s.Description,
+		Parameters:  []Parameter{},
+	}
+	schema := map[string]interface{}{}
+	if len(s.Schema) != 0 {
+		if err := json.Unmarshal([]byte(s.Schema), &schema); err != nil {
+			return nil, errors.Wrapf(err, "invalid schema of %s %s", doc.Type, doc.Name)
+		}
+		doc.Parameters = flatten(schema, "")
+	}
+	doc.Example = s.Annotations[types.AnnExample]
+	if len(doc.Example) == 0 {
+		example, err := generateExample(doc, schema)
+		if err != nil {
+			return nil, err
+		}
+		doc.Example = example
+	}
+	return doc, nil
+}
+
+// flatten flattens the properties of the object schema into parameters sorted by name, the fields of an object
+// follow the object
+func flatten(schema map[string]interface{}, prefix string) []Parameter {
+	props, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []Parameter
+	for _, name := range names {
+		prop, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p := Parameter{
+			Name:        prefix + name,
+			Type:        typeOf(prop),
+			Required:    required[name],
+			Description: oneLine(stringOf(prop["description"])),
+		}
+		if def, ok := prop["default"]; ok {
+			p.Default = printValue(def)
+		}
+		if enum, ok := prop["enum"].([]interface{}); ok {
+			for _, e := range enum {
+				p.Options = append(p.Options, printValue(e))
+			}
+		}
+		params = append(params, p)
+		if _, ok := prop["properties"]; ok {
+			params = append(params, flatten(prop, p.Name+".")...)
+		}
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			if _, ok := items["properties"]; ok {
+				params = append(params, flatten(items, p.Name+"[].")...)
+			}
+		}
+	}
+	return params
+}
+
+func typeOf(schema map[string]interface{}) string {
+	t := stringOf(schema["type"])
+	switch t {
+	case "array":
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return "[]" + typeOf(items)
+		}
+		return "[]any"
+	case "object":
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			return "map[string]" + typeOf(additional)
+		}
+		return "object"
+	case "":
+		return "any"
+	}
+	return t
+}
+
+func stringOf(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func printValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// generateExample generates an example of using the definition with the required parameters, which are the
+// defaults or the zero values of their types
+func generateExample(doc *Doc, schema map[string]interface{}) (string, error) {
+	var example interface{}
+	props := sampleProperties(schema)
+	switch doc.Type {
+	case typeName(commontypes.TraitType):
+		trait := map[string]interface{}{"type": doc.Name}
+		if len(props) != 0 {
+			trait["properties"] = props
+		}
+		example = map[string]interface{}{"traits": []interface{}{trait}}
+	default:
+		comp := map[string]interface{}{"name": "my-" + doc.Name, "type": doc.Name}
+		if len(props) != 0 {
+			comp["properties"] = props
+		}
+		example = map[string]interface{}{
+			"apiVersion": "core.oam.dev/v1beta1",
+			"kind":       "Application",
+			"metadata":   map[string]interface{}{"name": "my-app"},
+			"spec":       map[string]interface{}{"components": []interface{}{comp}},
+		}
+	}
+	b, err := yaml.Marshal(example)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot generate example of %s %s", doc.Type, doc.Name)
+	}
+	return string(b), nil
+}
+
+func sampleProperties(schema map[string]interface{}) map[string]interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	list, _ := schema["required"].([]interface{})
+	sample := map[string]interface{}{}
+	for _, r := range list {
+		name, _ := r.(string)
+		prop, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sample[name] = sampleValue(prop)
+	}
+	return sample
+}
+
+func sampleValue(schema map[string]interface{}) interface{} {
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) != 0 {
+		return enum[0]
+	}
+	switch stringOf(schema["type"]) {
+	case "string":
+		return ""
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []interface{}{}
+	default:
+		return sampleProperties(schema)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docgen
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1beta1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+const workerSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {"type": "string", "title": "image", "description": "Which image | tag to use"},
+    "replicas": {"type": "integer", "default": 1},
+    "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]},
+    "env": {"type": "object", "additionalProperties": {"type": "string"}},
+    "ports": {
+      "type": "array",
+      "items": {"type": "object", "required": ["port"], "properties": {"port": {"type": "integer"}}}
+    }
+  }
+}`
+
+func newClient() client.Client {
+	ns := types.DefaultKubeVelaNS
+	return fake.NewFakeClientWithScheme(common.Scheme,
+		&v1beta1.ComponentDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: ns,
+				Annotations: map[string]string{types.AnnDescription: "Describes long-running workers"}},
+			Status: v1beta1.ComponentDefinitionStatus{ConfigMapRef: "schema-worker"},
+		},
+		&v1beta1.TraitDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: ns,
+				Annotations: map[string]string{types.AnnExample: "traits:\n  - type: scaler\n"}},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schema-worker", Namespace: ns},
+			Data: map[string]string{types.OpenapiV3JSONSchema: workerSchema}},
+	)
+}
+
+func TestGenerate(t *testing.T) {
+	docs, err := Generate(context.Background(), newClient(), types.DefaultKubeVelaNS, "")
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	worker := Find(docs, "worker", "")
+	require.NotNil(t, worker)
+	assert.Equal(t, "component", worker.Type)
+	assert.Equal(t, "Describes long-running workers", worker.Description)
+	assert.Equal(t, []Parameter{
+		{Name: "env", Type: "map[string]string"},
+		{Name: "image", Type: "string", Required: true, Description: "Which image | tag to use"},
+		{Name: "ports", Type: "[]object"},
+		{Name: "ports[].port", Type: "integer", Required: true},
+		{Name: "pullPolicy", Type: "string", Options: []string{"Always", "IfNotPresent"}},
+		{Name: "replicas", Type: "integer", Default: "1"},
+	}, worker.Parameters)
+	assert.Contains(t, worker.Example, "type: worker")
+	assert.Contains(t, worker.Example, `image: ""`)
+
+	scaler := Find(docs, "scaler", "trait")
+	require.NotNil(t, scaler)
+	assert.Empty(t, scaler.Parameters)
+	assert.Equal(t, "traits:\n  - type: scaler\n", scaler.Example)
+	assert.Nil(t, Find(docs, "scaler", "component"))
+}
+
+func TestRender(t *testing.T) {
+	docs, err := Generate(context.Background(), newClient(), types.DefaultKubeVelaNS, "")
+	require.NoError(t, err)
+	worker := Find(docs, "worker", "")
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, worker, FormatMarkdown))
+	assert.Contains(t, buf.String(), "# worker")
+	assert.Contains(t, buf.String(), "| `image` | string | true |  | Which image \\| tag to use |")
+	assert.Contains(t, buf.String(), "Options: Always, IfNotPresent.")
+
+	buf.Reset()
+	require.NoError(t, Render(&buf, worker, FormatHTML))
+	assert.Contains(t, buf.String(), "<h1>worker</h1>")
+	assert.Contains(t, buf.String(), "<td><code>ports[].port</code></td>")
+	assert.Error(t, Render(&buf, worker, "pdf"))
+
+	dir, err := ioutil.TempDir("", "docgen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, WriteFiles(docs, dir, FormatHTML))
+	index, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `<a href="component/worker.html">worker</a>`)
+	_, err = os.Stat(filepath.Join(dir, "trait", "scaler.html"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docgen
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Formats of the docs
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// IndexFile is the name of the index page, without the extension
+const IndexFile = "index"
+
+var funcs = map[string]interface{}{
+	"cell": func(s string) string {
+		return strings.ReplaceAll(s, "|", `\|`)
+	},
+	"join": strings.Join,
+}
+
+var markdownTemplate = template.Must(template.New("doc").Funcs(funcs).Parse(`# {{.Name}}
+
+{{if .Description}}{{.Description}}
+
+{{end}}Type: {{.Type}}
+
+## Parameters
+{{if .Parameters}}
+| Name | Type | Required | Default | Description |
+| --- | --- | --- | --- | --- |
+{{range .Parameters}}| ` + "`{{.Name}}`" + ` | {{cell .Type}} | {{.Required}} | {{cell .Default}} | {{cell .Description}}{{if .Options}} Options: {{cell (join .Options ", ")}}.{{end}} |
+{{end}}{{else}}
+No parameters.
+{{end}}
+## Example
+
+` + "```yaml" + `
+{{.Example}}` + "```" + `
+`))
+
+var markdownIndexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`# Definitions
+{{range $type, $docs := .}}
+## {{$type}}
+
+| Name | Description |
+| --- | --- |
+{{range $docs}}| [{{.Name}}]({{.Type}}/{{.Name}}.md) | {{cell .Description}} |
+{{end}}{{end}}`))
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 960px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 6px; text-align: left; vertical-align: top; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+</style>
+</head>
+<body>
+`
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("doc").Funcs(funcs).Parse(htmlHead + `{{with .Doc}}<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>
+{{end}}<p>Type: {{.Type}}</p>
+<h2>Parameters</h2>
+{{if .Parameters}}<table>
+<tr><th>Name</th><th>Type</th><th>Required</th><th>Default</th><th>Description</th></tr>
+{{range .Parameters}}<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{.Required}}</td><td>{{.Default}}</td><td>{{.Description}}{{if .Options}} Options: {{join .Options ", "}}.{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p>No parameters.</p>
+{{end}}<h2>Example</h2>
+<pre><code>{{.Example}}</code></pre>
+{{end}}</body>
+</html>
+`))
+
+var htmlIndexTemplate = htmltemplate.Must(htmltemplate.New("index").Parse(htmlHead + `<h1>Definitions</h1>
+{{range $type, $docs := .Docs}}<h2>{{$type}}</h2>
+<table>
+<tr><th>Name</th><th>Description</th></tr>
+{{range $docs}}<tr><td><a href="{{call $.Link .}}">{{.Name}}</a></td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// LinkFn returns the link of the doc in the index page
+type LinkFn func(doc *Doc) string
+
+// FileLink links the docs by their relative paths, i.e., <type>/<name>.html, in the index page
+func FileLink(doc *Doc) string {
+	return doc.Type + "/" + doc.Name + ".html"
+}
+
+// Render renders the doc in the format
+func Render(w io.Writer, doc *Doc, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, doc)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, map[string]interface{}{"Title": doc.Name, "Doc": doc})
+	default:
+		return errors.Errorf("unsupported format %q, must be one of %s|%s", format, FormatMarkdown, FormatHTML)
+	}
+}
+
+// RenderIndex renders the index page of the docs grouped by their types in the format, the HTML pages link the docs
+// by the link function
+func RenderIndex(w io.Writer, docs []*Doc, format string, link LinkFn) error {
+	groups := map[string][]*Doc{}
+	for _, d := range docs {
+		groups[d.Type] = append(groups[d.Type], d)
+	}
+	switch format {
+	case FormatMarkdown:
+		return markdownIndexTemplate.Execute(w, groups)
+	case FormatHTML:
+		return htmlIndexTemplate.Execute(w, struct {
+			Title string
+			Docs  map[string][]*Doc
+			Link  LinkFn
+		}{Title: "Definitions", Docs: groups, Link: link})
+	default:
+		return errors.Errorf("unsupported format %q, must be one of %s|%s", format, FormatMarkdown, FormatHTML)
+	}
+}
+
+// Extension returns the extension of the files of the format
+func Extension(format string) string {
+	if format == FormatHTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+// WriteFiles writes the docs into the directory in the format, each doc is written into <type>/<name> with the
+// extension of the format, and the index page is written into the root of the directory
+func WriteFiles(docs []*Doc, dir, format string) error {
+	write := func(path string, render func(w io.Writer) error) error {
+		var buf bytes.Buffer
+		if err := render(&buf); err != nil {
+			return errors.Wrapf(err, "cannot render %s", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, buf.Bytes(), 0600)
+	}
+	for _, d := range docs {
+		doc := d
+		path := filepath.Join(dir, doc.Type, doc.Name+Extension(format))
+		if err := write(path, func(w io.Writer) error { return Render(w, doc, format) }); err != nil {
+			return err
+		}
+	}
+	return write(filepath.Join(dir, IndexFile+Extension(format)), func(w io.Writer) error {
+		return RenderIndex(w, docs, format, FileLink)
+	})
+}
@@ -251,6 +251,14 @@ func HandleTemplate(in *runtime.RawExtension, schematic *commontypes.Schematic,
 			tmp.KubeParameter = schematic.KUBE.Parameters
 			return tmp, nil
 		}
+		if schematic.Kustomize != nil {
+			tmp.Category = types.KustomizeCategory
+			return tmp, nil
+		}
+		if schematic.Git != nil {
+			tmp.Category = types.GitCategory
+			return tmp, nil
+		}
 	}
 	if tmp.CueTemplateURI != "" {
 		b, err := common.HTTPGet(context.Background(), tmp.CueTemplateURI)
@@ -415,7 +415,7 @@ func (ref *MarkdownReference) CreateMarkdown(ctx context.Context, caps []types.C
 			if err := ref.parseParameters(cueValue, "Properties", defaultDepth); err != nil {
 				return err
 			}
-		case types.HelmCategory:
+		case types.HelmCategory, types.KustomizeCategory, types.GitCategory:
 			properties, _, err := ref.GenerateHelmAndKubeProperties(ctx, &caps[i])
 			if err != nil {
 				return fmt.Errorf("failed to retrieve `parameters` value from %s with err: %w", c.Name, err)